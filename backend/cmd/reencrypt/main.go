@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/invulnerable/backend/internal/config"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/encryption"
+	"go.uber.org/zap"
+)
+
+// reencrypt rewrites the encrypted columns (webhook URLs and notes) with DB_ENCRYPTION_KEY:
+// rows written before encryption was enabled, and rows encrypted with a key listed in
+// DB_ENCRYPTION_PREVIOUS_KEYS. Once it has run, the previous keys can be removed.
+// With -decrypt it writes the columns back in plaintext, before disabling encryption.
+// It connects with the same DB_* variables as the server, which can keep running meanwhile
+func main() {
+	decrypt := flag.Bool("decrypt", false, "decrypt the columns instead of encrypting them")
+	flag.Parse()
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(fmt.Sprintf("failed to initialize logger: %v", err))
+	}
+	defer logger.Sync()
+
+	ctx := context.Background()
+	encCfg := config.LoadEncryptionFromEnv()
+	cipher, err := encryption.LoadFromConfig(ctx, encCfg)
+	if err != nil {
+		logger.Fatal("failed to load database encryption keys", zap.Error(err))
+	}
+	if cipher == nil {
+		logger.Fatal("DB_ENCRYPTION_KEY is required")
+	}
+
+	dbCfg := config.LoadDatabaseFromEnv()
+	dbPort, err := strconv.Atoi(dbCfg.Port)
+	if err != nil {
+		logger.Fatal("invalid database port", zap.String("port", dbCfg.Port), zap.Error(err))
+	}
+
+	database, err := db.New(db.Config{
+		Host:     dbCfg.Host,
+		Port:     dbPort,
+		User:     dbCfg.User,
+		Password: dbCfg.Password,
+		DBName:   dbCfg.DBName,
+		SSLMode:  dbCfg.SSLMode,
+	})
+	if err != nil {
+		logger.Fatal("failed to connect to database", zap.Error(err))
+	}
+	defer database.Close()
+	database.SetCipher(cipher)
+
+	target := cipher
+	if *decrypt {
+		target = nil
+	}
+
+	rewritten, err := database.Reencrypt(ctx, target)
+	for column, count := range rewritten {
+		logger.Info("rewrote column values", zap.String("column", column), zap.Int("rows", count))
+	}
+	if err != nil {
+		logger.Fatal("failed to re-encrypt database columns", zap.Error(err))
+	}
+
+	logger.Info("database columns re-encrypted", zap.Bool("decrypted", *decrypt))
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// scanner scans an image with Syft and Grype where it runs, typically a CI job, and submits the
+// results to the backend with an API key (INVULNERABLE_API_KEY, one of the backend's SCANNER_API_KEYS).
+// It is the standalone counterpart of the in-cluster scanner CronJob:
+//
+//	scanner -api-url https://invulnerable.example.com -fail-on high ghcr.io/acme/api:1.4.2
+//
+// syft and grype must be on the PATH. With -fail-on the backend's gate verdict decides the exit
+// code, so vulnerabilities triaged as ignored or accepted don't break the build, and the blocking
+// ones are reported to the CI (-report). Exit codes: 0 submitted, 1 error, 2 submitted and the
+// gate failed
+func main() {
+	apiURL := flag.String("api-url", os.Getenv("INVULNERABLE_API_URL"), "backend URL (INVULNERABLE_API_URL)")
+	sbomFormat := flag.String("sbom-format", "cyclonedx", "SBOM format: cyclonedx or spdx")
+	onlyFixed := flag.Bool("only-fixed", false, "only report vulnerabilities with a fix")
+	failOn := flag.String("fail-on", "", "exit with 2 when a vulnerability has this severity or higher (critical, high, medium, low, negligible)")
+	target := flag.String("target", "", "path inside the image the scan covers, for component scans")
+	maxWait := flag.Duration("maintenance-max-wait", 15*time.Minute, "how long to wait for the backend to leave maintenance mode")
+	reportFormat := flag.String("report", reportAuto, "how to report blocking vulnerabilities: github (workflow annotations), gitlab (code quality JSON), none, or auto to detect the CI")
+	reportFile := flag.String("report-file", "gl-code-quality-report.json", "file the gitlab report is written to")
+	dockerfile := flag.String("dockerfile", "Dockerfile", "file the reported vulnerabilities are attached to")
+	delta := flag.Bool("delta", true, "ask the backend whether the results are unchanged before uploading them")
+	ingestWait := flag.Duration("ingest-max-wait", 15*time.Minute, "how long to wait for the backend to process the results before the gate")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <image>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 || *apiURL == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	apiKey := os.Getenv("INVULNERABLE_API_KEY")
+	if apiKey == "" {
+		fatalf("INVULNERABLE_API_KEY is required")
+	}
+	threshold := 0
+	if *failOn != "" {
+		if threshold = severityRank(*failOn); threshold == 0 {
+			fatalf("invalid -fail-on severity %q", *failOn)
+		}
+	}
+	if *sbomFormat != "cyclonedx" && *sbomFormat != "spdx" {
+		fatalf("invalid -sbom-format %q", *sbomFormat)
+	}
+	report, err := detectReport(*reportFormat, os.Getenv)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	image := flag.Arg(0)
+	ctx := context.Background()
+
+	fmt.Printf("Generating SBOM of %s with Syft...\n", image)
+	started := time.Now()
+	// The Syft JSON output is written alongside for the size and layers of the image
+	syftFile, err := os.CreateTemp("", "syft-*.json")
+	if err != nil {
+		fatalf("failed to write SBOM: %v", err)
+	}
+	syftFile.Close()
+	sbom, err := run(ctx, "syft", image, "-o", *sbomFormat+"-json", "-o", "syft-json="+syftFile.Name())
+	if err != nil {
+		os.Remove(syftFile.Name())
+		fatalf("Syft SBOM generation failed: %v", err)
+	}
+	var stats scanStats
+	if syftOutput, err := os.ReadFile(syftFile.Name()); err == nil {
+		stats.ImageSizeBytes, stats.LayerCount = parseSyftImageInfo(syftOutput)
+	}
+	os.Remove(syftFile.Name())
+
+	fmt.Println("Scanning SBOM with Grype...")
+	sbomFile, err := os.CreateTemp("", "sbom-*.json")
+	if err != nil {
+		fatalf("failed to write SBOM: %v", err)
+	}
+	_, err = sbomFile.Write(sbom)
+	sbomFile.Close()
+	if err != nil {
+		os.Remove(sbomFile.Name())
+		fatalf("failed to write SBOM: %v", err)
+	}
+	grypeArgs := []string{"sbom:" + sbomFile.Name(), "-o", "json"}
+	if *onlyFixed {
+		grypeArgs = append(grypeArgs, "--only-fixed")
+	}
+	grypeResult, err := run(ctx, "grype", grypeArgs...)
+	os.Remove(sbomFile.Name())
+	if err != nil {
+		fatalf("Grype scan failed: %v", err)
+	}
+	stats.DurationMS = time.Since(started).Milliseconds()
+
+	syftVersion := ""
+	if out, err := run(ctx, "syft", "version"); err == nil {
+		syftVersion = parseSyftVersion(string(out))
+	}
+
+	// A submission retried after a lost response returns the scan it created instead of another one
+	scanUUID := uuid.NewString()
+	payload, deltaPayload, summary, err := buildPayload(image, *sbomFormat, syftVersion, *target, scanUUID, sbom, grypeResult, stats)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	client := &submitter{
+		baseURL:    strings.TrimSuffix(*apiURL, "/") + "/api/v1/ci",
+		apiKey:     apiKey,
+		client:     &http.Client{Timeout: 5 * time.Minute},
+		maxWait:    *maxWait,
+		ingestWait: *ingestWait,
+		sleep:      time.Sleep,
+	}
+	var scan *scanResponse
+	if *delta && deltaPayload != nil {
+		fmt.Println("Checking whether the results are unchanged since an earlier scan...")
+		if scan, err = client.submit(ctx, deltaPayload); err == nil {
+			fmt.Println("✓ Results unchanged, scan recorded without uploading them")
+		} else if !errors.Is(err, errResultsChanged) {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+	if scan == nil {
+		fmt.Printf("Submitting results to %s...\n", client.baseURL)
+		if scan, err = client.submit(ctx, payload); err != nil {
+			fatalf("%v", err)
+		}
+	}
+	fmt.Printf("✓ Scan %d stored (%s): %s\n", scan.ID, scan.Status, summary)
+
+	if threshold == 0 {
+		return
+	}
+	if scan.IngestJob != nil {
+		// The verdict needs the results processed, which the backend does in the background
+		fmt.Println("Waiting for the backend to process the results...")
+		if err := client.waitForIngestion(ctx, scan.IngestJob.ID); err != nil {
+			fatalf("%v", err)
+		}
+	}
+	gate, err := client.gate(ctx, scan.ID, *failOn, *onlyFixed)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if err := writeReport(report, gate, image, *dockerfile, *reportFile, os.Stdout); err != nil {
+		fatalf("%v", err)
+	}
+	if !gate.Passed {
+		fmt.Printf("✗ Gate failed: %d vulnerabilities with severity %s or higher\n", len(gate.Blocking), strings.ToLower(gate.FailOn))
+		os.Exit(2)
+	}
+	fmt.Printf("✓ Gate passed: no actionable vulnerabilities with severity %s or higher\n", strings.ToLower(gate.FailOn))
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+// run runs a command and returns its standard output, its standard error goes to ours
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+func parseSyftVersion(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if version, ok := strings.CutPrefix(line, "Version:"); ok {
+			return strings.TrimSpace(version)
+		}
+	}
+	return ""
+}
+
+// scanRequest is the body of POST /api/v1/ci/scans. The documents are passed through as produced
+type scanRequest struct {
+	Image       string          `json:"image"`
+	ImageDigest *string         `json:"image_digest,omitempty"`
+	SBOMFormat  string          `json:"sbom_format"`
+	SBOMVersion *string         `json:"sbom_version,omitempty"`
+	SyftVersion *string         `json:"syft_version,omitempty"`
+	Target      *string         `json:"target,omitempty"`
+	ScanUUID    string          `json:"scan_uuid,omitempty"`
+	SBOM        json.RawMessage `json:"sbom,omitempty"`
+	GrypeResult json.RawMessage `json:"grype_result,omitempty"`
+
+	// Unchanged replaces the documents in delta submissions
+	Unchanged *unchangedResults `json:"unchanged,omitempty"`
+
+	ScanDurationMS *int64 `json:"scan_duration_ms,omitempty"`
+	ImageSizeBytes *int64 `json:"image_size_bytes,omitempty"`
+	LayerCount     *int   `json:"layer_count,omitempty"`
+}
+
+// scanStats is how long the scan took and what it scanned, for the backend's scan performance metrics
+type scanStats struct {
+	DurationMS     int64
+	ImageSizeBytes *int64
+	LayerCount     *int
+}
+
+// parseSyftImageInfo returns the size and number of layers of the image from the Syft JSON output
+func parseSyftImageInfo(output []byte) (*int64, *int) {
+	var syft struct {
+		Source struct {
+			Metadata struct {
+				ImageSize *int64          `json:"imageSize"`
+				Layers    json.RawMessage `json:"layers"`
+			} `json:"metadata"`
+		} `json:"source"`
+	}
+	if err := json.Unmarshal(output, &syft); err != nil {
+		return nil, nil
+	}
+	var layers []json.RawMessage
+	if err := json.Unmarshal(syft.Source.Metadata.Layers, &layers); err != nil || len(layers) == 0 {
+		return syft.Source.Metadata.ImageSize, nil
+	}
+	count := len(layers)
+	return syft.Source.Metadata.ImageSize, &count
+}
+
+type unchangedResults struct {
+	ResultsFingerprint string    `json:"results_fingerprint"`
+	GrypeVersion       string    `json:"grype_version"`
+	GrypeDBBuilt       time.Time `json:"grype_db_built"`
+}
+
+// grypeOutput is the part of the Grype JSON the scanner reads
+type grypeOutput struct {
+	Matches []struct {
+		Vulnerability struct {
+			Severity string `json:"severity"`
+		} `json:"vulnerability"`
+	} `json:"matches"`
+	Source struct {
+		Target struct {
+			ImageID     string   `json:"imageID"`
+			RepoDigests []string `json:"repoDigests"`
+		} `json:"target"`
+	} `json:"source"`
+}
+
+// severityCounts counts the matches of a Grype result per severity
+type severityCounts map[string]int
+
+var severities = []string{"Critical", "High", "Medium", "Low", "Negligible", "Unknown"}
+
+func (c severityCounts) String() string {
+	var parts []string
+	for _, severity := range severities {
+		if c[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c[severity], strings.ToLower(severity)))
+		}
+	}
+	if len(parts) == 0 {
+		return "no vulnerabilities"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func severityRank(severity string) int {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return 5
+	case "high":
+		return 4
+	case "medium":
+		return 3
+	case "low":
+		return 2
+	case "negligible":
+		return 1
+	}
+	return 0
+}
+
+// buildPayload returns the full submission and, when the backend can match it with an earlier scan
+// (known digest and Grype database build), the delta submission sent first. Both carry the scan_uuid
+// of the run, so the backend stores a single scan whichever of them it received
+func buildPayload(image, sbomFormat, syftVersion, target, scanUUID string, sbom, grypeResult []byte, stats scanStats) ([]byte, []byte, severityCounts, error) {
+	var sbomHeader struct {
+		BOMFormat   string `json:"bomFormat"`
+		SpecVersion string `json:"specVersion"`
+		SPDXVersion string `json:"spdxVersion"`
+	}
+	if err := json.Unmarshal(sbom, &sbomHeader); err != nil {
+		return nil, nil, nil, fmt.Errorf("Syft produced an invalid SBOM: %w", err)
+	}
+	var grype grypeOutput
+	if err := json.Unmarshal(grypeResult, &grype); err != nil {
+		return nil, nil, nil, fmt.Errorf("Grype produced an invalid result: %w", err)
+	}
+
+	req := scanRequest{
+		Image:       image,
+		SBOMFormat:  sbomFormat,
+		ScanUUID:    scanUUID,
+		SBOM:        sbom,
+		GrypeResult: grypeResult,
+	}
+	if digest := grype.Source.Target.ImageID; digest != "" {
+		req.ImageDigest = &digest
+	} else if len(grype.Source.Target.RepoDigests) > 0 {
+		req.ImageDigest = &grype.Source.Target.RepoDigests[0]
+	}
+	if version := strings.TrimSpace(sbomHeader.BOMFormat + " " + sbomHeader.SpecVersion); version != "" {
+		req.SBOMVersion = &version
+	} else if sbomHeader.SPDXVersion != "" {
+		req.SBOMVersion = &sbomHeader.SPDXVersion
+	}
+	if syftVersion != "" {
+		req.SyftVersion = &syftVersion
+	}
+	if target != "" {
+		req.Target = &target
+	}
+	if stats.DurationMS > 0 {
+		req.ScanDurationMS = &stats.DurationMS
+	}
+	req.ImageSizeBytes, req.LayerCount = stats.ImageSizeBytes, stats.LayerCount
+
+	counts := severityCounts{}
+	for _, match := range grype.Matches {
+		severity := match.Vulnerability.Severity
+		if severityRank(severity) == 0 {
+			severity = "Unknown"
+		}
+		counts[severity]++
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode scan request: %w", err)
+	}
+
+	// Fingerprinted like the backend does, see models.GrypeResult.Fingerprint
+	var results models.GrypeResult
+	if err := json.Unmarshal(grypeResult, &results); err != nil {
+		return nil, nil, nil, fmt.Errorf("Grype produced an invalid result: %w", err)
+	}
+	var deltaPayload []byte
+	if built, _ := results.Descriptor.DB.BuildInfo(); built != nil && req.ImageDigest != nil && req.SyftVersion != nil {
+		req.SBOM, req.GrypeResult = nil, nil
+		req.Unchanged = &unchangedResults{
+			ResultsFingerprint: results.Fingerprint(),
+			GrypeVersion:       results.Descriptor.Version,
+			GrypeDBBuilt:       *built,
+		}
+		if deltaPayload, err = json.Marshal(req); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to encode scan request: %w", err)
+		}
+	}
+	return payload, deltaPayload, counts, nil
+}
+
+// submitter posts scan results, waiting out maintenance mode (503 with Retry-After) like the CronJob scanner
+type submitter struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+	maxWait time.Duration
+	// ingestWait bounds the wait for results processed in the background
+	ingestWait time.Duration
+	sleep      func(time.Duration)
+}
+
+// ingestPollInterval is how often the status of a background ingest job is checked
+const ingestPollInterval = 5 * time.Second
+
+// submitAttempts is how many times a submission is sent when the backend can't be reached, its
+// scan_uuid making a retry of one the backend stored return that scan
+const (
+	submitAttempts      = 3
+	submitRetryInterval = 10 * time.Second
+)
+
+// errResultsChanged is the answer to a delta submission no earlier scan matches
+var errResultsChanged = errors.New("results changed since the last scan")
+
+type scanResponse struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	// IngestJob is set when the backend accepted the results to process them in the background
+	IngestJob *ingestJobResponse `json:"ingest_job"`
+}
+
+type ingestJobResponse struct {
+	ID     int64   `json:"id"`
+	Status string  `json:"status"`
+	Error  *string `json:"error"`
+}
+
+func (s *submitter) submit(ctx context.Context, payload []byte) (*scanResponse, error) {
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/scans", bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+		resp, err := s.client.Do(req)
+		var body []byte
+		if err == nil {
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		if err != nil {
+			if attempt >= submitAttempts || ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to submit scan results: %w", err)
+			}
+			fmt.Printf("Failed to submit scan results (%v), retrying in %s...\n", err, submitRetryInterval)
+			s.sleep(submitRetryInterval)
+			continue
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusPreconditionFailed:
+			return nil, errResultsChanged
+		case resp.StatusCode == http.StatusServiceUnavailable:
+			retryAfter := 60 * time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				retryAfter = time.Duration(seconds) * time.Second
+			}
+			if waited+retryAfter > s.maxWait {
+				return nil, fmt.Errorf("backend still in maintenance mode after %s", waited)
+			}
+			fmt.Printf("Backend in maintenance mode (HTTP 503), retrying in %s...\n", retryAfter)
+			s.sleep(retryAfter)
+			waited += retryAfter
+			continue
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
+			return nil, fmt.Errorf("backend rejected scan results (HTTP %d): %s", resp.StatusCode, errorMessage(body))
+		}
+
+		if warning := resp.Header.Get("X-Quota-Warning"); warning != "" {
+			fmt.Printf("Warning: team quota exceeded: %s\n", warning)
+		}
+		var scan scanResponse
+		if err := json.Unmarshal(body, &scan); err != nil {
+			return nil, fmt.Errorf("invalid response: %w", err)
+		}
+		return &scan, nil
+	}
+}
+
+// waitForIngestion polls an ingest job until the backend has processed the results of the scan
+func (s *submitter) waitForIngestion(ctx context.Context, jobID int64) error {
+	var waited time.Duration
+	for {
+		job, err := s.ingestJob(ctx, jobID)
+		if err != nil {
+			return err
+		}
+		switch job.Status {
+		case "completed":
+			return nil
+		case "failed":
+			message := "unknown error"
+			if job.Error != nil {
+				message = *job.Error
+			}
+			return fmt.Errorf("backend failed to process scan results: %s", message)
+		}
+		if waited+ingestPollInterval > s.ingestWait {
+			return fmt.Errorf("scan results still %s after %s", job.Status, waited)
+		}
+		s.sleep(ingestPollInterval)
+		waited += ingestPollInterval
+	}
+}
+
+func (s *submitter) ingestJob(ctx context.Context, jobID int64) (*ingestJobResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/ingest-jobs/%d", s.baseURL, jobID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest job: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get ingest job (HTTP %d): %s", resp.StatusCode, errorMessage(body))
+	}
+	var job ingestJobResponse
+	if err := json.Unmarshal(body, &job); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &job, nil
+}
+
+// gate fetches the backend's verdict on a stored scan
+func (s *submitter) gate(ctx context.Context, scanID int, failOn string, onlyFixable bool) (*gateResponse, error) {
+	query := url.Values{"fail_on": {failOn}, "only_fixable": {strconv.FormatBool(onlyFixable)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/scans/%d/gate?%s", s.baseURL, scanID, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gate verdict: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get gate verdict (HTTP %d): %s", resp.StatusCode, errorMessage(body))
+	}
+	var gate gateResponse
+	if err := json.Unmarshal(body, &gate); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &gate, nil
+}
+
+// errorMessage extracts the message of an Echo error response
+func errorMessage(body []byte) string {
+	var e struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil || e.Message == "" {
+		return strings.TrimSpace(string(body))
+	}
+	return e.Message
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildPayload(t *testing.T) {
+	sbom := []byte(`{"bomFormat":"CycloneDX","specVersion":"1.5","components":[]}`)
+	grype := []byte(`{
+		"matches": [
+			{"vulnerability": {"id": "CVE-2024-1", "severity": "Critical"}},
+			{"vulnerability": {"id": "CVE-2024-2", "severity": "High"}},
+			{"vulnerability": {"id": "CVE-2024-3", "severity": "Low"}},
+			{"vulnerability": {"id": "CVE-2024-4", "severity": ""}}
+		],
+		"source": {"target": {"imageID": "sha256:abc", "repoDigests": ["ghcr.io/acme/api@sha256:def"]}},
+		"descriptor": {"name": "grype", "version": "0.104.4", "db": {"status": {"built": "2024-05-01T04:00:00Z"}}}
+	}`)
+
+	size, layers := int64(77823477), 6
+	payload, delta, counts, err := buildPayload("ghcr.io/acme/api:1.4.2", "cyclonedx", "1.40.0", "", "5f0c9a52-3b7e-4d6a-9a41-6f2f1f8b2c11", sbom, grype,
+		scanStats{DurationMS: 42000, ImageSizeBytes: &size, LayerCount: &layers})
+	require.NoError(t, err)
+
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &req))
+	assert.Equal(t, "ghcr.io/acme/api:1.4.2", req["image"])
+	assert.Equal(t, "sha256:abc", req["image_digest"])
+	assert.Equal(t, "CycloneDX 1.5", req["sbom_version"])
+	assert.Equal(t, "1.40.0", req["syft_version"])
+	assert.NotContains(t, req, "target")
+	assert.Equal(t, "5f0c9a52-3b7e-4d6a-9a41-6f2f1f8b2c11", req["scan_uuid"])
+	assert.Len(t, req["grype_result"].(map[string]interface{})["matches"], 4)
+	assert.Equal(t, float64(42000), req["scan_duration_ms"])
+	assert.Equal(t, float64(77823477), req["image_size_bytes"])
+	assert.Equal(t, float64(6), req["layer_count"])
+
+	assert.Equal(t, "1 critical, 1 high, 1 low, 1 unknown", counts.String())
+
+	// The delta carries the fingerprint of the results instead of the documents
+	var results models.GrypeResult
+	require.NoError(t, json.Unmarshal(grype, &results))
+	var deltaReq map[string]interface{}
+	require.NoError(t, json.Unmarshal(delta, &deltaReq))
+	assert.Equal(t, "sha256:abc", deltaReq["image_digest"])
+	assert.Equal(t, req["scan_uuid"], deltaReq["scan_uuid"])
+	assert.NotContains(t, deltaReq, "sbom")
+	assert.NotContains(t, deltaReq, "grype_result")
+	assert.Equal(t, float64(42000), deltaReq["scan_duration_ms"])
+	assert.Equal(t, map[string]interface{}{
+		"results_fingerprint": results.Fingerprint(),
+		"grype_version":       "0.104.4",
+		"grype_db_built":      "2024-05-01T04:00:00Z",
+	}, deltaReq["unchanged"])
+
+	// Without a Grype database build there is nothing to match
+	_, delta, _, err = buildPayload("nginx", "cyclonedx", "1.40.0", "", "", sbom, []byte(`{"matches": []}`), scanStats{})
+	require.NoError(t, err)
+	assert.Nil(t, delta)
+
+	_, _, _, err = buildPayload("nginx", "cyclonedx", "", "", "", []byte("not json"), grype, scanStats{})
+	assert.Error(t, err)
+}
+
+func TestParseSyftImageInfo(t *testing.T) {
+	size, layers := parseSyftImageInfo([]byte(`{"source": {"type": "image", "metadata": {"imageSize": 77823477, "layers": [{"digest": "sha256:a"}, {"digest": "sha256:b"}]}}}`))
+	require.NotNil(t, size)
+	require.NotNil(t, layers)
+	assert.Equal(t, int64(77823477), *size)
+	assert.Equal(t, 2, *layers)
+
+	size, layers = parseSyftImageInfo([]byte(`{"source": {"type": "directory", "metadata": {"path": "/srv/api"}}}`))
+	assert.Nil(t, size)
+	assert.Nil(t, layers)
+}
+
+func TestSubmitter_WaitsForMaintenance(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/scans", r.URL.Path)
+		assert.Equal(t, "Bearer ci-key", r.Header.Get("Authorization"))
+		if calls == 1 {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 42, "status": "completed"}`))
+	}))
+	defer server.Close()
+
+	var slept []time.Duration
+	s := &submitter{baseURL: server.URL, apiKey: "ci-key", client: server.Client(), maxWait: time.Minute,
+		sleep: func(d time.Duration) { slept = append(slept, d) }}
+	scan, err := s.submit(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, 42, scan.ID)
+	assert.Equal(t, []time.Duration{30 * time.Second}, slept)
+
+	// Gives up once the wait would exceed maxWait
+	calls = 0
+	s.maxWait = 10 * time.Second
+	_, err = s.submit(context.Background(), []byte(`{}`))
+	assert.EqualError(t, err, "backend still in maintenance mode after 0s")
+}
+
+func TestSubmitter_WaitsForIngestion(t *testing.T) {
+	statuses := []string{"queued", "running", "completed"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ingest-jobs/7", r.URL.Path)
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		_, _ = w.Write([]byte(`{"id": 7, "scan_id": 42, "status": "` + status + `", "error": "connection reset"}`))
+	}))
+	defer server.Close()
+
+	var slept []time.Duration
+	s := &submitter{baseURL: server.URL, apiKey: "ci-key", client: server.Client(), ingestWait: time.Minute,
+		sleep: func(d time.Duration) { slept = append(slept, d) }}
+	require.NoError(t, s.waitForIngestion(context.Background(), 7))
+	assert.Equal(t, []time.Duration{ingestPollInterval, ingestPollInterval}, slept)
+
+	// A job given up fails the run, as does one still running after ingestWait
+	statuses = []string{"failed"}
+	assert.EqualError(t, s.waitForIngestion(context.Background(), 7), "backend failed to process scan results: connection reset")
+	statuses = []string{"running"}
+	s.ingestWait = 0
+	assert.EqualError(t, s.waitForIngestion(context.Background(), 7), "scan results still running after 0s")
+}
+
+func TestSubmitter_RetriesUnreachableBackend(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			// The response to the first attempt is lost
+			hijacked, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			hijacked.Close()
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": 42, "status": "completed"}`))
+	}))
+	defer server.Close()
+
+	var slept []time.Duration
+	s := &submitter{baseURL: server.URL, apiKey: "ci-key", client: server.Client(),
+		sleep: func(d time.Duration) { slept = append(slept, d) }}
+	scan, err := s.submit(context.Background(), []byte(`{"scan_uuid": "5f0c9a52-3b7e-4d6a-9a41-6f2f1f8b2c11"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 42, scan.ID)
+	assert.Equal(t, []time.Duration{submitRetryInterval}, slept)
+
+	// Gives up after submitAttempts
+	server.Close()
+	slept = nil
+	_, err = s.submit(context.Background(), []byte(`{}`))
+	assert.ErrorContains(t, err, "failed to submit scan results")
+	assert.Len(t, slept, submitAttempts-1)
+}
+
+func TestSubmitter_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message": "invalid API key"}`))
+	}))
+	defer server.Close()
+
+	s := &submitter{baseURL: server.URL, apiKey: "wrong", client: server.Client(), sleep: func(time.Duration) {}}
+	_, err := s.submit(context.Background(), []byte(`{}`))
+	assert.EqualError(t, err, "backend rejected scan results (HTTP 401): invalid API key")
+}
+
+func TestSubmitter_Gate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/scans/42/gate", r.URL.Path)
+		assert.Equal(t, "high", r.URL.Query().Get("fail_on"))
+		assert.Equal(t, "true", r.URL.Query().Get("only_fixable"))
+		_, _ = w.Write([]byte(`{"scan_id": 42, "fail_on": "High", "passed": false, "blocking": [{"cve_id": "CVE-2024-1", "severity": "Critical"}]}`))
+	}))
+	defer server.Close()
+
+	s := &submitter{baseURL: server.URL, apiKey: "ci-key", client: server.Client()}
+	gate, err := s.gate(context.Background(), 42, "high", true)
+	require.NoError(t, err)
+	assert.False(t, gate.Passed)
+	require.Len(t, gate.Blocking, 1)
+	assert.Equal(t, "CVE-2024-1", gate.Blocking[0].CVEID)
+}
+
+func TestSubmitter_ResultsChanged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		_, _ = w.Write([]byte(`{"message": "no earlier scan has these results, submit the full scan"}`))
+	}))
+	defer server.Close()
+
+	s := &submitter{baseURL: server.URL, apiKey: "ci-key", client: server.Client(), sleep: func(time.Duration) {}}
+	_, err := s.submit(context.Background(), []byte(`{}`))
+	assert.ErrorIs(t, err, errResultsChanged)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Report formats of the blocking vulnerabilities
+const (
+	reportAuto   = "auto"
+	reportNone   = "none"
+	reportGitHub = "github"
+	reportGitLab = "gitlab"
+)
+
+// gateResponse is the verdict of GET /api/v1/ci/scans/:id/gate
+type gateResponse struct {
+	ScanID   int                `json:"scan_id"`
+	FailOn   string             `json:"fail_on"`
+	Passed   bool               `json:"passed"`
+	Blocking []gateBlockingVuln `json:"blocking"`
+}
+
+type gateBlockingVuln struct {
+	CVEID          string  `json:"cve_id"`
+	Severity       string  `json:"severity"`
+	Status         string  `json:"status"`
+	PackageName    string  `json:"package_name"`
+	PackageVersion string  `json:"package_version"`
+	PackageType    *string `json:"package_type"`
+	FixVersion     *string `json:"fix_version"`
+	URL            *string `json:"url"`
+}
+
+// detectReport resolves -report, auto picks the format of the CI the scanner runs in
+func detectReport(format string, getenv func(string) string) (string, error) {
+	switch format {
+	case reportNone, reportGitHub, reportGitLab:
+		return format, nil
+	case reportAuto:
+		switch {
+		case getenv("GITHUB_ACTIONS") == "true":
+			return reportGitHub, nil
+		case getenv("GITLAB_CI") == "true":
+			return reportGitLab, nil
+		}
+		return reportNone, nil
+	}
+	return "", fmt.Errorf("invalid -report %q (must be auto, github, gitlab or none)", format)
+}
+
+// writeReport reports the blocking vulnerabilities of a gate verdict, attached to the Dockerfile
+// of the image since findings of an image have no source line of their own
+func writeReport(format string, gate *gateResponse, image, dockerfile, reportFile string, stdout io.Writer) error {
+	switch format {
+	case reportGitHub:
+		writeGitHubAnnotations(stdout, gate, image, dockerfile)
+	case reportGitLab:
+		data, err := gitLabCodeQuality(gate, image, dockerfile)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(reportFile, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write code quality report: %w", err)
+		}
+		fmt.Fprintf(stdout, "Code quality report written to %s\n", reportFile)
+	}
+	return nil
+}
+
+// describe is the message of a blocking vulnerability, shared by the report formats
+func (v gateBlockingVuln) describe(image string) string {
+	pkg := v.PackageName + " " + v.PackageVersion
+	if v.PackageType != nil {
+		pkg += " (" + *v.PackageType + ")"
+	}
+	msg := fmt.Sprintf("%s %s in %s of %s", v.Severity, v.CVEID, pkg, image)
+	if v.FixVersion != nil {
+		msg += ", fixed in " + *v.FixVersion
+	} else {
+		msg += ", no fix available"
+	}
+	if v.URL != nil {
+		msg += " - " + *v.URL
+	}
+	return msg
+}
+
+// writeGitHubAnnotations prints workflow commands GitHub Actions turns into error annotations
+// https://docs.github.com/actions/reference/workflow-commands-for-github-actions#setting-an-error-message
+func writeGitHubAnnotations(w io.Writer, gate *gateResponse, image, dockerfile string) {
+	for _, v := range gate.Blocking {
+		title := fmt.Sprintf("%s (%s) in %s", v.CVEID, v.Severity, v.PackageName)
+		fmt.Fprintf(w, "::error file=%s,line=1,title=%s::%s\n",
+			escapeGitHubProperty(dockerfile), escapeGitHubProperty(title), escapeGitHubData(v.describe(image)))
+	}
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeGitHubData(s))
+}
+
+// codeQualityIssue is an entry of a GitLab code quality report
+// https://docs.gitlab.com/ci/testing/code_quality/#code-quality-report-format
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// gitLabCodeQuality renders the blocking vulnerabilities as a code quality report. A passed gate
+// gives an empty report, so the artifact always exists
+func gitLabCodeQuality(gate *gateResponse, image, dockerfile string) ([]byte, error) {
+	issues := make([]codeQualityIssue, 0, len(gate.Blocking))
+	for _, v := range gate.Blocking {
+		// Stable across pipelines, so GitLab tracks the finding between the branch and its target
+		sum := sha256.Sum256([]byte(strings.Join([]string{image, dockerfile, v.CVEID, v.PackageName, v.PackageVersion}, "\x00")))
+		issue := codeQualityIssue{
+			Description: v.describe(image),
+			CheckName:   v.CVEID,
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Severity:    codeQualitySeverity(v.Severity),
+			Location:    codeQualityLocation{Path: dockerfile},
+		}
+		issue.Location.Lines.Begin = 1
+		issues = append(issues, issue)
+	}
+	return json.MarshalIndent(issues, "", "  ")
+}
+
+func codeQualitySeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "blocker"
+	case "high":
+		return "critical"
+	case "medium":
+		return "major"
+	case "low":
+		return "minor"
+	}
+	return "info"
+}
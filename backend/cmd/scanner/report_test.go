@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testGate() *gateResponse {
+	deb, fix, url := "deb", "3.0.2", "https://nvd.nist.gov/vuln/detail/CVE-2024-1"
+	return &gateResponse{ScanID: 42, FailOn: "High", Blocking: []gateBlockingVuln{
+		{CVEID: "CVE-2024-1", Severity: "Critical", PackageName: "openssl", PackageVersion: "3.0.1", PackageType: &deb, FixVersion: &fix, URL: &url},
+		{CVEID: "CVE-2024-2", Severity: "High", PackageName: "zlib", PackageVersion: "1.2.11"},
+	}}
+}
+
+func TestDetectReport(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	tests := []struct {
+		format string
+		env    map[string]string
+		want   string
+	}{
+		{reportAuto, map[string]string{"GITHUB_ACTIONS": "true"}, reportGitHub},
+		{reportAuto, map[string]string{"GITLAB_CI": "true"}, reportGitLab},
+		{reportAuto, nil, reportNone},
+		{reportGitLab, map[string]string{"GITHUB_ACTIONS": "true"}, reportGitLab},
+	}
+	for _, tt := range tests {
+		got, err := detectReport(tt.format, env(tt.env))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+
+	_, err := detectReport("jenkins", env(nil))
+	assert.Error(t, err)
+}
+
+func TestWriteReport_GitHub(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, writeReport(reportGitHub, testGate(), "ghcr.io/acme/api:1.4.2", "build/Dockerfile", "", &out))
+	assert.Equal(t,
+		"::error file=build/Dockerfile,line=1,title=CVE-2024-1 (Critical) in openssl::Critical CVE-2024-1 in openssl 3.0.1 (deb) of ghcr.io/acme/api:1.4.2, fixed in 3.0.2 - https://nvd.nist.gov/vuln/detail/CVE-2024-1\n"+
+			"::error file=build/Dockerfile,line=1,title=CVE-2024-2 (High) in zlib::High CVE-2024-2 in zlib 1.2.11 of ghcr.io/acme/api:1.4.2, no fix available\n",
+		out.String())
+
+	assert.Equal(t, "a%3Ab%2Cc%25%0A", escapeGitHubProperty("a:b,c%\n"))
+}
+
+func TestWriteReport_GitLab(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gl-code-quality-report.json")
+	var out bytes.Buffer
+	require.NoError(t, writeReport(reportGitLab, testGate(), "ghcr.io/acme/api:1.4.2", "Dockerfile", file, &out))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	var issues []codeQualityIssue
+	require.NoError(t, json.Unmarshal(data, &issues))
+	require.Len(t, issues, 2)
+	assert.Equal(t, "CVE-2024-1", issues[0].CheckName)
+	assert.Equal(t, "blocker", issues[0].Severity)
+	assert.Equal(t, "critical", issues[1].Severity)
+	assert.Equal(t, "Dockerfile", issues[0].Location.Path)
+	assert.Equal(t, 1, issues[0].Location.Lines.Begin)
+	assert.Len(t, issues[0].Fingerprint, 64)
+	assert.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+
+	// A passed gate still leaves a report
+	require.NoError(t, writeReport(reportGitLab, &gateResponse{Passed: true}, "nginx", "Dockerfile", file, &out))
+	data, err = os.ReadFile(file)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, string(data))
+}
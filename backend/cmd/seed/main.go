@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/invulnerable/backend/internal/config"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/seed"
+	"go.uber.org/zap"
+)
+
+// seed fills the database with reproducible demo data: images, months of scans
+// with vulnerabilities appearing and getting fixed, and a few triage decisions.
+// It connects with the same DB_* variables as the server and expects migrations to be applied.
+func main() {
+	defaults := seed.DefaultOptions()
+
+	images := flag.Int("images", defaults.Images, "number of images to generate")
+	days := flag.Int("days", defaults.Days, "days of scan history per image")
+	interval := flag.Int("interval", defaults.ScanIntervalDays, "days between scans of the same image")
+	seedValue := flag.Uint64("seed", defaults.Seed, "random seed; the same seed produces the same dataset")
+	end := flag.String("end", "", "date of the most recent scan (YYYY-MM-DD, default now)")
+	flag.Parse()
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		panic(fmt.Sprintf("failed to initialize logger: %v", err))
+	}
+	defer logger.Sync()
+
+	opts := seed.Options{
+		Images:           *images,
+		Days:             *days,
+		ScanIntervalDays: *interval,
+		Seed:             *seedValue,
+		End:              defaults.End,
+	}
+	if *end != "" {
+		opts.End, err = time.Parse("2006-01-02", *end)
+		if err != nil {
+			logger.Fatal("invalid end date", zap.String("end", *end), zap.Error(err))
+		}
+	}
+
+	dbCfg := config.LoadDatabaseFromEnv()
+	dbPort, err := strconv.Atoi(dbCfg.Port)
+	if err != nil {
+		logger.Fatal("invalid database port", zap.String("port", dbCfg.Port), zap.Error(err))
+	}
+
+	database, err := db.New(db.Config{
+		Host:     dbCfg.Host,
+		Port:     dbPort,
+		User:     dbCfg.User,
+		Password: dbCfg.Password,
+		DBName:   dbCfg.DBName,
+		SSLMode:  dbCfg.SSLMode,
+	})
+	if err != nil {
+		logger.Fatal("failed to connect to database", zap.Error(err))
+	}
+	defer database.Close()
+
+	histories := seed.Generate(opts)
+
+	stats, err := seed.NewLoader(logger, database).Load(context.Background(), histories)
+	if err != nil {
+		logger.Fatal("failed to seed database", zap.Error(err))
+	}
+
+	logger.Info("database seeded",
+		zap.Int("images", stats.Images),
+		zap.Int("scans", stats.Scans),
+		zap.Int("vulnerabilities", stats.Vulnerabilities),
+		zap.Int("triage_events", stats.TriageEvents))
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
@@ -16,10 +18,24 @@ import (
 	"github.com/invulnerable/backend/internal/api"
 	"github.com/invulnerable/backend/internal/auth"
 	"github.com/invulnerable/backend/internal/config"
+	"github.com/invulnerable/backend/internal/compliance"
 	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/encryption"
+	"github.com/invulnerable/backend/internal/eventhook"
+	"github.com/invulnerable/backend/internal/events"
+	"github.com/invulnerable/backend/internal/gitops"
+	"github.com/invulnerable/backend/internal/ingest"
 	"github.com/invulnerable/backend/internal/metrics"
+	"github.com/invulnerable/backend/internal/models"
 	"github.com/invulnerable/backend/internal/notifier"
+	"github.com/invulnerable/backend/internal/online"
+	"github.com/invulnerable/backend/internal/outbox"
+	"github.com/invulnerable/backend/internal/reports"
+	"github.com/invulnerable/backend/internal/retention"
+	"github.com/invulnerable/backend/internal/sla"
+	"github.com/invulnerable/backend/internal/stalescan"
 	"github.com/invulnerable/backend/internal/storage"
+	"github.com/invulnerable/backend/internal/worker"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap"
@@ -60,12 +76,42 @@ func main() {
 
 	logger.Info("connected to database successfully")
 
+	// Column encryption of webhook URLs and notes (DB_ENCRYPTION_KEY, optional)
+	cipher, err := encryption.LoadFromConfig(context.Background(), cfg.Encryption)
+	if err != nil {
+		logger.Fatal("failed to load database encryption keys", zap.Error(err))
+	}
+	if cipher != nil {
+		database.SetCipher(cipher)
+		logger.Info("database column encryption enabled", zap.Bool("kms", cfg.Encryption.KMS))
+	}
+
+	// Severity SLA deadlines are counted from, Grype rescoring a CVE doesn't move them by default
+	slaSeverityPolicy := getEnv("SLA_SEVERITY_POLICY", sla.SeverityAtDetection)
+	if !sla.IsSeverityPolicy(slaSeverityPolicy) {
+		logger.Fatal("invalid SLA_SEVERITY_POLICY, expected detection or current", zap.String("policy", slaSeverityPolicy))
+	}
+	database.SetSLASeverityPolicy(slaSeverityPolicy)
+
+	// Changes are streamed to the dashboards connected to this replica
+	eventBus := events.NewBus()
+	database.SetEvents(eventBus)
+
 	// Initialize S3 client for SBOM storage
 	s3Client, err := createS3Client(cfg.S3)
 	if err != nil {
 		logger.Fatal("failed to create S3 client", zap.Error(err))
 	}
 	s3Storage := storage.NewS3Storage(s3Client, cfg.S3.Bucket)
+	grypeResultStorage := storage.NewS3GrypeResultStorage(s3Client, cfg.S3.Bucket)
+	compression, err := storage.ParseEncoding(cfg.S3.Compression)
+	if err != nil {
+		logger.Fatal("invalid SBOM_S3_COMPRESSION", zap.Error(err))
+	}
+	s3Storage.SetCompression(compression)
+	grypeResultStorage.SetCompression(compression)
+	reportStorage := storage.NewS3ReportStorage(s3Client, cfg.S3.Bucket)
+	reportStorage.SetCompression(compression)
 	logger.Info("initialized S3 storage",
 		zap.String("endpoint", cfg.S3.Endpoint),
 		zap.String("bucket", cfg.S3.Bucket))
@@ -75,13 +121,70 @@ func main() {
 	scanRepo := db.NewScanRepository(database)
 	vulnRepo := db.NewVulnerabilityRepository(database)
 	sbomRepo := db.NewSBOMRepository(database, s3Storage)
+	grypeResultRepo := db.NewGrypeResultRepository(database, grypeResultStorage)
 	webhookConfigRepo := db.NewWebhookConfigRepository(database)
+	maintenanceRepo := db.NewMaintenanceRepository(database)
+	suppressionRepo := db.NewSuppressionRuleRepository(database)
+	imageScanRepo := db.NewImageScanRepository(database)
+	componentRepo := db.NewComponentRepository(database)
+	watchlistRepo := db.NewWatchlistRepository(database)
+	campaignRepo := db.NewCampaignRepository(database)
+	usageRepo := db.NewUsageRepository(database)
+	outboxRepo := db.NewOutboxRepository(database)
+	waiverRepo := db.NewWaiverRepository(database)
 
 	// Initialize services
 	analyzerSvc := analyzer.New(scanRepo, vulnRepo)
+	// Findings coming and going between scans are only marked fixed after consecutive absences
+	analyzerSvc.SetFlapDamping(vulnRepo, getEnvInt("FIX_CONFIRMATION_SCANS", 2))
 	metricsSvc := metrics.New(database, logger)
 	frontendURL := getEnv("FRONTEND_URL", "")
 	notifierSvc := notifier.New(logger, frontendURL)
+	// Webhook URLs come from scan requests and ImageScans, they must not reach cluster-internal services
+	webhookPolicy, err := notifier.NewDestinationPolicy(
+		getEnv("WEBHOOK_ALLOWED_SCHEMES", ""),
+		getEnv("WEBHOOK_DENIED_NETWORKS", ""),
+		getEnv("WEBHOOK_ALLOWED_NETWORKS", ""))
+	if err != nil {
+		logger.Fatal("invalid webhook destination policy", zap.Error(err))
+	}
+	notifierSvc.SetDestinationPolicy(webhookPolicy)
+	staleThreshold := time.Duration(getEnvInt("STALE_SCAN_THRESHOLD_HOURS", 48)) * time.Hour
+	staleMonitor := stalescan.New(logger, imageScanRepo, webhookConfigRepo, notifierSvc, staleThreshold)
+	// Raw Grype results are evidence for disputes, GRYPE_RESULT_RETENTION_DAYS=0 keeps them as long as the scan
+	grypeResultRetention := time.Duration(getEnvInt("GRYPE_RESULT_RETENTION_DAYS", 90)) * 24 * time.Hour
+	retentionPruner := retention.New(logger, imageScanRepo, scanRepo, sbomRepo, grypeResultRepo, grypeResultRetention)
+
+	// Periodic workers run on one replica at a time, under a Postgres advisory lock, and at most
+	// once per interval across replicas and restarts. An interval of 0 disables a worker
+	instance, _ := os.Hostname()
+	workerRepo := db.NewWorkerRepository(database)
+	workers := worker.New(logger, workerRepo, workerRepo, instance)
+	if checkInterval := getEnvInt("STALE_SCAN_CHECK_INTERVAL_MINUTES", 15); checkInterval > 0 {
+		workers.Register("stale-scans", time.Duration(checkInterval)*time.Minute, func(ctx context.Context, now time.Time) error {
+			_, err := staleMonitor.Check(ctx, now)
+			return err
+		})
+	}
+	if pruneInterval := getEnvInt("RETENTION_PRUNE_INTERVAL_MINUTES", 60); pruneInterval > 0 {
+		workers.Register("retention", time.Duration(pruneInterval)*time.Minute, func(ctx context.Context, now time.Time) error {
+			_, err := retentionPruner.Prune(ctx, now)
+			return err
+		})
+	}
+	// Index builds and backfills of large tables, which would lock them in a migration
+	onlineChanges := online.New(logger, db.NewOnlineChangeRepository(database), online.Changes)
+	workers.Register("online-changes", time.Minute, onlineChanges.Run)
+	// Expired waivers set their vulnerabilities back to active
+	if expiryInterval := getEnvInt("WAIVER_EXPIRY_INTERVAL_MINUTES", 15); expiryInterval > 0 {
+		workers.Register("waivers", time.Duration(expiryInterval)*time.Minute, func(ctx context.Context, now time.Time) error {
+			expired, err := waiverRepo.Expire(ctx, now)
+			if len(expired) > 0 {
+				logger.Info("expired waivers", zap.Int("count", len(expired)))
+			}
+			return err
+		})
+	}
 
 	// Check if OAuth2 is enabled in deployment
 	oauthEnabled := getEnv("OAUTH_ENABLED", "false") == "true"
@@ -105,24 +208,276 @@ func main() {
 		logger.Info("OAuth2 disabled - application running without authentication")
 	}
 
+	// Identity headers of the authenticating proxy, each one can be overridden
+	identityHeaders, err := auth.IdentityScheme(getEnv("IDENTITY_SCHEME", "oauth2-proxy"))
+	if err != nil {
+		logger.Fatal("invalid IDENTITY_SCHEME", zap.Error(err))
+	}
+	identityHeaders.Email = getEnv("IDENTITY_EMAIL_HEADER", identityHeaders.Email)
+	identityHeaders.User = getEnv("IDENTITY_USER_HEADER", identityHeaders.User)
+	identityHeaders.Groups = getEnv("IDENTITY_GROUPS_HEADER", identityHeaders.Groups)
+	identityHeaders.Token = getEnv("IDENTITY_TOKEN_HEADER", identityHeaders.Token)
+	if fallback := getEnv("IDENTITY_CLAIM_FALLBACK", ""); fallback != "" {
+		identityHeaders.ClaimFallback = fallback == "true"
+	}
+
+	// Share links grant read-only access to one scan report without an account
+	var shareSigner *auth.ShareSigner
+	if secret := getEnv("SHARE_LINK_SECRET", ""); secret != "" {
+		shareSigner = auth.NewShareSigner(secret)
+	} else {
+		logger.Info("SHARE_LINK_SECRET not set - scan share links disabled")
+	}
+
+	// API keys (name=key pairs) let CI jobs outside the cluster submit scans through /api/v1/ci
+	scannerAPIKeys, err := auth.ParseAPIKeys(getEnv("SCANNER_API_KEYS", ""))
+	if err != nil {
+		logger.Fatal("invalid SCANNER_API_KEYS", zap.Error(err))
+	}
+
+	// TLS, with client certificates required on the API when a client CA is set
+	var tlsConfig *tls.Config
+	if cfg.Server.TLSEnabled() {
+		tlsConfig, err = auth.NewServerTLSConfig(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile, cfg.Server.TLSClientCAFile)
+		if err != nil {
+			logger.Fatal("invalid TLS configuration", zap.Error(err))
+		}
+	}
+	clientIDs, err := auth.ParseClientIDs(cfg.Server.TLSAllowedSPIFFEIDs)
+	if err != nil {
+		logger.Fatal("invalid TLS_ALLOWED_SPIFFE_IDS", zap.Error(err))
+	}
+
 	// Initialize handlers
 	healthHandler := api.NewHealthHandler(database)
-	scanHandler := api.NewScanHandler(logger, imageRepo, scanRepo, vulnRepo, sbomRepo, analyzerSvc, notifierSvc)
+	eventHandler := api.NewEventHandler(logger, eventBus)
+	scanHandler := api.NewScanHandler(logger, imageRepo, scanRepo, vulnRepo, sbomRepo, grypeResultRepo, suppressionRepo, watchlistRepo, usageRepo, outboxRepo, analyzerSvc, notifierSvc)
+	scanHandler.SetEvents(eventBus)
+	// Bounds on a single scan submission, 0 disables a limit
+	ingestLimits := api.DefaultIngestLimits()
+	scanHandler.SetIngestLimits(api.IngestLimits{
+		MaxBodyBytes:    int64(getEnvInt("INGEST_MAX_BODY_MB", int(ingestLimits.MaxBodyBytes>>20))) << 20,
+		MaxMatches:      getEnvInt("INGEST_MAX_MATCHES", ingestLimits.MaxMatches),
+		HardMaxMatches:  getEnvInt("INGEST_HARD_MAX_MATCHES", ingestLimits.HardMaxMatches),
+		MaxStringLength: getEnvInt("INGEST_MAX_STRING_LENGTH", ingestLimits.MaxStringLength),
+	})
+	// Matches of a submission are persisted in parallel, bounded across submissions
+	ingestConcurrency := api.DefaultIngestConcurrency()
+	scanHandler.SetIngestConcurrency(api.IngestConcurrency{
+		Workers:    getEnvInt("INGEST_WORKERS", ingestConcurrency.Workers),
+		MaxWorkers: getEnvInt("INGEST_MAX_WORKERS", ingestConcurrency.MaxWorkers),
+		QueueSize:  getEnvInt("INGEST_QUEUE_SIZE", ingestConcurrency.QueueSize),
+	})
+	// Results of submissions are processed in the background by the ingest workers of every replica,
+	// 0 processes them in the request as before
+	ingestJobRepo := db.NewIngestJobRepository(database)
+	var ingestJobs *ingest.Pool
+	if jobWorkers := getEnvInt("INGEST_JOB_WORKERS", ingest.DefaultWorkers); jobWorkers > 0 {
+		ingestJobs = ingest.New(logger, ingestJobRepo, scanHandler.ProcessIngestJob, jobWorkers)
+		scanHandler.SetIngestJobs(ingestJobs)
+	} else {
+		logger.Info("INGEST_JOB_WORKERS is 0 - scan results processed in the request")
+	}
+	// Signed reports are audit evidence of what each scan found, unaffected by later triage
+	if keyFile := getEnv("REPORT_SIGNING_KEY_FILE", ""); keyFile != "" {
+		reportSigner, err := auth.LoadReportSigner(keyFile)
+		if err != nil {
+			logger.Fatal("invalid REPORT_SIGNING_KEY_FILE", zap.Error(err))
+		}
+		scanHandler.SetReports(db.NewScanReportRepository(database, reportStorage), reportSigner)
+		logger.Info("signed scan reports enabled", zap.String("key_id", reportSigner.KeyID()))
+	} else {
+		logger.Info("REPORT_SIGNING_KEY_FILE not set - signed scan reports disabled")
+	}
+	// Images scanned for the first time are announced, so images nobody registered don't go unnoticed
+	if url := getEnv("NEW_IMAGE_WEBHOOK_URL", ""); url != "" {
+		if err := webhookPolicy.CheckURL(url); err != nil {
+			logger.Fatal("invalid NEW_IMAGE_WEBHOOK_URL", zap.Error(err))
+		}
+		locale := getEnv("NEW_IMAGE_WEBHOOK_LOCALE", "")
+		if !notifier.IsSupportedLocale(locale) {
+			logger.Fatal("unsupported NEW_IMAGE_WEBHOOK_LOCALE", zap.String("locale", locale))
+		}
+		scanHandler.SetNewImageWebhook(notifier.WebhookConfig{
+			URL:    url,
+			Format: getEnv("NEW_IMAGE_WEBHOOK_FORMAT", "slack"),
+			Locale: locale,
+		})
+	}
+	// Machine-oriented events for workflow engines, signed with EVENT_WEBHOOK_SECRET
+	var eventWebhook *eventhook.Webhook
+	if url := getEnv("EVENT_WEBHOOK_URL", ""); url != "" {
+		eventWebhook, err = eventhook.New(logger, url, getEnv("EVENT_WEBHOOK_SECRET", ""), eventhook.ParseTypes(getEnv("EVENT_WEBHOOK_EVENTS", "")))
+		if err != nil {
+			logger.Fatal("invalid event webhook", zap.Error(err))
+		}
+		if err := scanHandler.SetEventWebhook(eventWebhook, getEnv("EVENT_WEBHOOK_FAIL_ON", "high")); err != nil {
+			logger.Fatal("invalid EVENT_WEBHOOK_FAIL_ON", zap.Error(err))
+		}
+		logger.Info("event webhook enabled", zap.String("events", getEnv("EVENT_WEBHOOK_EVENTS", "all")))
+	}
+	// Findings summaries committed to a Git repository after each scan, an evidence trail for GitOps audits
+	var gitopsCommitter *gitops.Committer
+	if repository := getEnv("GITOPS_REPOSITORY", ""); repository != "" {
+		gitopsCommitter, err = gitops.New(logger, gitops.Config{
+			APIURL:     getEnv("GITOPS_API_URL", gitops.DefaultAPIURL),
+			Repository: repository,
+			Branch:     getEnv("GITOPS_BRANCH", ""),
+			Directory:  getEnv("GITOPS_DIRECTORY", "invulnerable"),
+			Format:     getEnv("GITOPS_FORMAT", gitops.FormatYAML),
+			Token:      getEnv("GITOPS_TOKEN", ""),
+		})
+		if err != nil {
+			logger.Fatal("invalid GitOps configuration", zap.Error(err))
+		}
+		scanHandler.SetGitOps(gitopsCommitter)
+		logger.Info("scan summaries committed to Git", zap.String("repository", repository))
+	}
 	vulnHandler := api.NewVulnerabilityHandler(logger, vulnRepo, notifierSvc, webhookConfigRepo)
-	imageHandler := api.NewImageHandler(logger, imageRepo)
+	triageImportHandler := api.NewTriageImportHandler(logger, vulnRepo)
+	// Ticketing systems report ticket status changes, signed with TICKET_CALLBACK_SECRET
+	var ticketCallbackHandler *api.TicketCallbackHandler
+	if secret := getEnv("TICKET_CALLBACK_SECRET", ""); secret != "" {
+		ticketStatuses, err := api.ParseTicketStatusMap(getEnv("TICKET_CALLBACK_STATUS_MAP", api.DefaultTicketStatusMap))
+		if err != nil {
+			logger.Fatal("invalid TICKET_CALLBACK_STATUS_MAP", zap.Error(err))
+		}
+		ticketCallbackHandler = api.NewTicketCallbackHandler(logger, vulnRepo, secret, ticketStatuses)
+	}
+	imageHandler := api.NewImageHandler(logger, imageRepo, imageScanRepo, sbomRepo, grypeResultRepo, staleThreshold)
 	metricsHandler := api.NewMetricsHandler(logger, metricsSvc)
+	// Compliance profiles map framework remediation timelines (e.g. FedRAMP High in 30 days) onto the
+	// images of the ImageScans their selector matches
+	if profilesFile := getEnv("COMPLIANCE_PROFILES_FILE", ""); profilesFile != "" {
+		profiles, err := compliance.Load(profilesFile)
+		if err != nil {
+			logger.Fatal("invalid COMPLIANCE_PROFILES_FILE", zap.Error(err))
+		}
+		scanHandler.SetCompliance(profiles, imageScanRepo)
+		vulnHandler.SetCompliance(profiles, imageScanRepo)
+		metricsHandler.SetComplianceProfiles(profiles)
+		logger.Info("compliance profiles loaded", zap.Int("profiles", len(profiles)))
+	}
 	userHandler := api.NewUserHandler(logger, jwtValidator, oauthEnabled)
-	webhookConfigHandler := api.NewWebhookConfigHandler(webhookConfigRepo, logger)
+	activityHandler := api.NewActivityHandler(logger, db.NewActivityRepository(database))
+	webhookConfigHandler := api.NewWebhookConfigHandler(webhookConfigRepo, logger, webhookPolicy)
+	maintenanceHandler := api.NewMaintenanceHandler(logger, maintenanceRepo)
+	suppressionHandler := api.NewSuppressionRuleHandler(logger, suppressionRepo)
+	componentHandler := api.NewComponentHandler(logger, componentRepo)
+	watchlistHandler := api.NewWatchlistHandler(logger, watchlistRepo, webhookPolicy)
+	campaignHandler := api.NewCampaignHandler(logger, campaignRepo)
+	waiverHandler := api.NewWaiverHandler(logger, waiverRepo)
+	impactHandler := api.NewImpactHandler(logger, sbomRepo, vulnRepo)
+	bomHandler := api.NewBOMHandler(logger, sbomRepo)
+	sbomQualityHandler := api.NewSBOMQualityHandler(logger, sbomRepo)
+	usageHandler := api.NewUsageHandler(logger, usageRepo)
+	workerHandler := api.NewWorkerHandler(logger, workers)
+	onlineChangeHandler := api.NewOnlineChangeHandler(logger, onlineChanges)
+	ingestJobHandler := api.NewIngestJobHandler(logger, ingestJobRepo)
+	// Runtime snapshots for admins, with the depths of the queues of this replica
+	diagnosticsHandler := api.NewDiagnosticsHandler(logger, instance, database)
+	diagnosticsHandler.SetQueue("notification_outbox", outboxRepo.CountPending)
+	diagnosticsHandler.SetQueue("ingest_workers_busy", func(context.Context) (int, error) {
+		return scanHandler.IngestWorkersBusy(), nil
+	})
+	diagnosticsHandler.SetQueue("ingest_jobs", ingestJobRepo.CountQueued)
+	diagnosticsHandler.SetQueue("live_events", func(context.Context) (int, error) {
+		return eventBus.Pending(), nil
+	})
+	shareHandler := api.NewShareHandler(logger, shareSigner, scanRepo, frontendURL)
+	imageScanHandler := api.NewImageScanHandler(logger, imageScanRepo, imageRepo, sbomRepo, grypeResultRepo)
+	inventoryRepo := db.NewInventoryRepository(database)
+	coverageHandler := api.NewCoverageHandler(logger, inventoryRepo, staleThreshold)
+	namespaceHandler := api.NewNamespaceHandler(logger, db.NewNamespaceRepository(database), inventoryRepo, staleThreshold)
+	scannerVersionHandler := api.NewScannerVersionHandler(logger, scanRepo, api.ScannerVersionPolicy{
+		MinSyftVersion:  getEnv("SCANNER_MIN_SYFT_VERSION", ""),
+		MinGrypeVersion: getEnv("SCANNER_MIN_GRYPE_VERSION", ""),
+		MaxDBAge:        time.Duration(getEnvInt("SCANNER_MAX_DB_AGE_DAYS", 0)) * 24 * time.Hour,
+	})
+
+	// Webhook notifications are written to the outbox with the change they report and delivered
+	// by any replica, at least once
+	dispatcher := outbox.New(logger, outboxRepo)
+	dispatcher.Handle(models.OutboxKindScanCompleted, scanHandler.DeliverScanCompleted)
+	dispatcher.Handle(models.OutboxKindWatchlist, scanHandler.DeliverWatchlist)
+	dispatcher.Handle(models.OutboxKindStatusChange, vulnHandler.DeliverStatusChange)
+	dispatcher.Handle(models.OutboxKindFixAvailable, vulnHandler.DeliverFixAvailable)
+	dispatcher.Handle(models.OutboxKindImageDiscovered, scanHandler.DeliverImageDiscovered)
+	if eventWebhook != nil {
+		dispatcher.Handle(models.OutboxKindEventWebhook, eventWebhook.Deliver)
+	}
+	if gitopsCommitter != nil {
+		dispatcher.Handle(models.OutboxKindGitOpsCommit, gitopsCommitter.Deliver)
+	}
+	dispatchInterval := time.Duration(getEnvInt("NOTIFICATION_DISPATCH_INTERVAL_SECONDS", 5)) * time.Second
+	workers.Register("notification-outbox", max(dispatchInterval, time.Second), dispatcher.Dispatch)
+	workers.Register("notification-outbox-cleanup", time.Hour, func(ctx context.Context, now time.Time) error {
+		_, err := outboxRepo.DeleteFinished(ctx, now.Add(-7*24*time.Hour))
+		return err
+	})
+	workers.Register("ingest-jobs-cleanup", time.Hour, func(ctx context.Context, now time.Time) error {
+		_, err := ingestJobRepo.DeleteFinished(ctx, now.Add(-7*24*time.Hour))
+		return err
+	})
+	if eventWebhook.Enabled(eventhook.SLABreached) {
+		slaMonitor := eventhook.NewSLAMonitor(logger, eventWebhook, db.NewSLABreachRepository(database))
+		slaCheckInterval := time.Duration(getEnvInt("SLA_BREACH_CHECK_INTERVAL_MINUTES", 15)) * time.Minute
+		workers.Register("sla-breaches", max(slaCheckInterval, time.Minute), slaMonitor.Check)
+	}
+
+	// Compliance reports are generated on the cron schedules of REPORT_SCHEDULES_FILE, and on demand
+	var reportSchedules []reports.Schedule
+	if schedulesFile := getEnv("REPORT_SCHEDULES_FILE", ""); schedulesFile != "" {
+		if reportSchedules, err = reports.Load(schedulesFile); err != nil {
+			logger.Fatal("invalid REPORT_SCHEDULES_FILE", zap.Error(err))
+		}
+	}
+	complianceReportRepo := db.NewComplianceReportRepository(database)
+	objectStorage := storage.NewS3ObjectStorage(s3Client, cfg.S3.Bucket)
+	reportGenerator := reports.NewGenerator(logger, complianceReportRepo, db.NewSLABreachRepository(database), objectStorage, reportSchedules)
+	reportGenerator.SetWebhookSecret(getEnv("REPORT_WEBHOOK_SECRET", ""))
+	if host := getEnv("SMTP_HOST", ""); host != "" {
+		mailer, err := reports.NewSMTPMailer(reports.SMTPConfig{
+			Host:     host,
+			Port:     getEnvInt("SMTP_PORT", 587),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", ""),
+		})
+		if err != nil {
+			logger.Fatal("invalid SMTP configuration", zap.Error(err))
+		}
+		reportGenerator.SetMailer(mailer)
+	} else if reports.UseEmail(reportSchedules) {
+		logger.Fatal("report schedules send emails but SMTP_HOST is not set")
+	}
+	if len(reportSchedules) > 0 {
+		workers.Register("compliance-reports", time.Minute, reportGenerator.Run)
+		logger.Info("compliance report schedules loaded", zap.Int("schedules", len(reportSchedules)))
+	}
+	complianceReportHandler := api.NewComplianceReportHandler(logger, complianceReportRepo, reportGenerator, objectStorage)
+
+	// Admin users (comma-separated emails) allowed to call /admin endpoints
+	adminGuard := api.NewAdminGuard(logger, jwtValidator, oauthEnabled, getEnv("ADMIN_USERS", ""))
+	// Scan submission from CI is only served with API keys
+	var apiKeyGuard *api.APIKeyGuard
+	if scannerAPIKeys.Len() > 0 {
+		apiKeyGuard = api.NewAPIKeyGuard(logger, scannerAPIKeys)
+	}
+	clientCertGuard := api.NewClientCertGuard(logger, clientIDs)
 
 	// Initialize Echo
 	e := echo.New()
 	e.HideBanner = true
+	e.HTTPErrorHandler = api.HTTPErrorHandler(e)
 
 	// Middleware
 	e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
 		LogURI:    true,
 		LogStatus: true,
 		LogError:  true,
+		// The status of errors returned by handlers is the one the error handler responds with
+		HandleError: true,
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
 			if v.Error == nil {
 				logger.Info("request",
@@ -142,47 +497,114 @@ func main() {
 	}))
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
+	e.Use(api.IdentityMiddleware(identityHeaders))
+	e.Use(maintenanceHandler.ReadOnly)
+	// Requests are cancelled at their deadline, with the DB, S3 and webhook calls they make
+	requestTimeouts := api.DefaultRequestTimeouts()
+	requestTimeouts.Read = time.Duration(getEnvInt("REQUEST_TIMEOUT_READ_SECONDS", int(requestTimeouts.Read/time.Second))) * time.Second
+	requestTimeouts.Write = time.Duration(getEnvInt("REQUEST_TIMEOUT_WRITE_SECONDS", int(requestTimeouts.Write/time.Second))) * time.Second
+	requestTimeouts.Ingest = time.Duration(getEnvInt("REQUEST_TIMEOUT_INGEST_SECONDS", int(requestTimeouts.Ingest/time.Second))) * time.Second
+	requestTimeouts.IngestRoutes = []string{
+		"POST /api/v1/scans",
+		"POST /api/v1/ci/scans",
+		"POST /api/v1/vulnerabilities/import",
+		"POST /api/v1/suppression-rules/import",
+		"POST /api/v1/suppression-rules/bundle",
+		"PUT /api/v1/inventory/:source",
+		// Deletions cascade through every scan of an image
+		"DELETE /api/v1/scans/:id",
+		"DELETE /api/v1/images/:id",
+		"DELETE /api/v1/imagescans/:namespace/:name",
+	}
+	// Event streams and exports send for as long as the client reads them
+	requestTimeouts.Untimed = []string{"GET /api/v1/events", "GET /api/v1/vulnerabilities/export"}
+	e.Use(api.RequestTimeout(requestTimeouts))
 
 	// Health endpoints
 	e.GET("/health", healthHandler.Health)
 	e.GET("/ready", healthHandler.Ready)
 
+	// The OpenAPI document is built from the routes registered on e
+	openAPIHandler := api.NewOpenAPIHandler(logger, e)
+
 	// API routes
 	api := e.Group("/api/v1")
+	if cfg.Server.TLSClientCAFile != "" {
+		// Health endpoints stay reachable by probes, which can't present a certificate
+		api.Use(clientCertGuard.RequireClientCert)
+		logger.Info("client certificates required", zap.Int("allowed_spiffe_ids", clientIDs.Len()))
+	}
 
-	// Scans
-	api.POST("/scans", scanHandler.CreateScan)
-	api.GET("/scans", scanHandler.ListScans)
-	api.GET("/scans/:id", scanHandler.GetScan)
-	api.GET("/scans/:id/sbom", scanHandler.GetSBOM)
-	api.GET("/scans/:id/diff", scanHandler.GetScanDiff)
-
-	// Vulnerabilities
-	api.GET("/vulnerabilities", vulnHandler.ListVulnerabilities)
-	api.GET("/vulnerabilities/:cve", vulnHandler.GetVulnerabilityByCVE)
-	api.PATCH("/vulnerabilities/:id", vulnHandler.UpdateVulnerability)
-	api.PATCH("/vulnerabilities/bulk", vulnHandler.BulkUpdateVulnerabilities)
-	api.GET("/vulnerabilities/:id/history", vulnHandler.GetVulnerabilityHistory)
-
-	// Images
-	api.GET("/images", imageHandler.ListImages)
-	api.GET("/images/:id/history", imageHandler.GetImageHistory)
-
-	// Metrics
-	api.GET("/metrics", metricsHandler.GetMetrics)
+	registerRoutes(api, routeHandlers{
+		logger:                  logger,
+		adminGuard:              adminGuard,
+		apiKeyGuard:             apiKeyGuard,
+		ticketCallbackHandler:   ticketCallbackHandler,
+		openAPIHandler:          openAPIHandler,
+		scanHandler:             scanHandler,
+		ingestJobHandler:        ingestJobHandler,
+		complianceReportHandler: complianceReportHandler,
+		shareHandler:            shareHandler,
+		vulnHandler:             vulnHandler,
+		triageImportHandler:     triageImportHandler,
+		coverageHandler:         coverageHandler,
+		componentHandler:        componentHandler,
+		impactHandler:           impactHandler,
+		bomHandler:              bomHandler,
+		sbomQualityHandler:      sbomQualityHandler,
+		watchlistHandler:        watchlistHandler,
+		campaignHandler:         campaignHandler,
+		scannerVersionHandler:   scannerVersionHandler,
+		suppressionHandler:      suppressionHandler,
+		waiverHandler:           waiverHandler,
+		imageHandler:            imageHandler,
+		eventHandler:            eventHandler,
+		metricsHandler:          metricsHandler,
+		usageHandler:            usageHandler,
+		userHandler:             userHandler,
+		activityHandler:         activityHandler,
+		webhookConfigHandler:    webhookConfigHandler,
+		imageScanHandler:        imageScanHandler,
+		namespaceHandler:        namespaceHandler,
+		maintenanceHandler:      maintenanceHandler,
+		workerHandler:           workerHandler,
+		onlineChangeHandler:     onlineChangeHandler,
+		diagnosticsHandler:      diagnosticsHandler,
+	})
 
-	// User
-	api.GET("/user/me", userHandler.GetCurrentUser)
+	// Background workers (stale-scan alerts, retention, notifications)
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	defer stopMonitor()
+	workers.Start(monitorCtx)
+	if ingestJobs != nil {
+		ingestJobs.Start(monitorCtx)
+	}
 
-	// Webhook Configs
-	api.PUT("/webhook-configs/:namespace/:name", webhookConfigHandler.UpsertWebhookConfig)
-	api.GET("/webhook-configs/:namespace/:name", webhookConfigHandler.GetWebhookConfig)
-	api.DELETE("/webhook-configs/:namespace/:name", webhookConfigHandler.DeleteWebhookConfig)
+	// pprof and expvar are unauthenticated, they are only served on an address of their own.
+	// Bind it to localhost and reach it with kubectl port-forward
+	if debugAddr := getEnv("DEBUG_ADDR", ""); debugAddr != "" {
+		debugServer := &http.Server{Addr: debugAddr, Handler: diagnosticsHandler.DebugHandler(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			logger.Info("starting debug server", zap.String("addr", debugAddr))
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("debug server failed", zap.Error(err))
+			}
+		}()
+		defer debugServer.Close()
+	}
 
 	// Start server
 	port := cfg.Server.Port
 	go func() {
-		logger.Info("starting server", zap.String("port", port))
+		logger.Info("starting server", zap.String("port", port), zap.Bool("tls", tlsConfig != nil))
+		if tlsConfig != nil {
+			e.TLSServer.Addr = ":" + port
+			e.TLSServer.TLSConfig = tlsConfig
+			if err := e.StartServer(e.TLSServer); err != nil {
+				logger.Fatal("failed to start server", zap.Error(err))
+			}
+			return
+		}
 		if err := e.Start(":" + port); err != nil {
 			logger.Fatal("failed to start server", zap.Error(err))
 		}
@@ -194,6 +616,7 @@ func main() {
 	<-quit
 
 	logger.Info("shutting down server...")
+	stopMonitor()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -235,3 +658,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"github.com/invulnerable/backend/internal/api"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// routeHandlers are the handlers and guards serving the API routes
+type routeHandlers struct {
+	logger *zap.Logger
+
+	adminGuard *api.AdminGuard
+	// Scan submission from CI is disabled without it, when SCANNER_API_KEYS is not set
+	apiKeyGuard *api.APIKeyGuard
+	// Ticket callbacks are disabled without it, when TICKET_CALLBACK_SECRET is not set
+	ticketCallbackHandler *api.TicketCallbackHandler
+
+	openAPIHandler          *api.OpenAPIHandler
+	scanHandler             *api.ScanHandler
+	ingestJobHandler        *api.IngestJobHandler
+	complianceReportHandler *api.ComplianceReportHandler
+	shareHandler            *api.ShareHandler
+	vulnHandler             *api.VulnerabilityHandler
+	triageImportHandler     *api.TriageImportHandler
+	coverageHandler         *api.CoverageHandler
+	componentHandler        *api.ComponentHandler
+	impactHandler           *api.ImpactHandler
+	bomHandler              *api.BOMHandler
+	sbomQualityHandler      *api.SBOMQualityHandler
+	watchlistHandler        *api.WatchlistHandler
+	campaignHandler         *api.CampaignHandler
+	scannerVersionHandler   *api.ScannerVersionHandler
+	suppressionHandler      *api.SuppressionRuleHandler
+	waiverHandler           *api.WaiverHandler
+	imageHandler            *api.ImageHandler
+	eventHandler            *api.EventHandler
+	metricsHandler          *api.MetricsHandler
+	usageHandler            *api.UsageHandler
+	userHandler             *api.UserHandler
+	activityHandler         *api.ActivityHandler
+	webhookConfigHandler    *api.WebhookConfigHandler
+	imageScanHandler        *api.ImageScanHandler
+	namespaceHandler        *api.NamespaceHandler
+	maintenanceHandler      *api.MaintenanceHandler
+	workerHandler           *api.WorkerHandler
+	onlineChangeHandler     *api.OnlineChangeHandler
+	diagnosticsHandler      *api.DiagnosticsHandler
+}
+
+// registerRoutes registers the routes of the API on its /api/v1 group
+func registerRoutes(api *echo.Group, h routeHandlers) {
+	// API documentation
+	api.GET("/openapi.json", h.openAPIHandler.GetOpenAPI)
+	api.GET("/docs", h.openAPIHandler.GetDocs)
+
+	// Scans
+	api.POST("/scans", h.scanHandler.CreateScan)
+	api.GET("/scans", h.scanHandler.ListScans)
+	api.GET("/scans/:id", h.scanHandler.GetScan)
+	api.PATCH("/scans/:id", h.scanHandler.UpdateScanStatus)
+	api.DELETE("/scans/:id", h.scanHandler.DeleteScan, h.adminGuard.RequireAdmin)
+	api.GET("/scans/:id/sbom", h.scanHandler.GetSBOM)
+	api.GET("/scans/:id/sbom/url", h.scanHandler.GetSBOMURL)
+	api.GET("/scans/:id/grype-result", h.scanHandler.GetGrypeResult)
+	api.GET("/scans/:id/diff", h.scanHandler.GetScanDiff)
+	api.POST("/scans/:id/apply-diff", h.scanHandler.ApplyScanDiff)
+	api.GET("/scans/:id/sbom-diff", h.scanHandler.GetSBOMDiff)
+	api.GET("/scans/:id/sbom-quality", h.scanHandler.GetSBOMQuality)
+	api.GET("/scans/:id/summary", h.scanHandler.GetScanSummary)
+	api.GET("/scans/:id/gate", h.scanHandler.GetScanGate)
+	api.GET("/scans/:id/sarif", h.scanHandler.GetScanSARIF)
+	api.GET("/scans/:id/share", h.shareHandler.CreateShareLink)
+	api.GET("/scans/:id/report", h.scanHandler.GetScanReport)
+	api.GET("/ingest-jobs/:id", h.ingestJobHandler.GetIngestJob)
+	api.GET("/reports/signing-key", h.scanHandler.GetReportSigningKey)
+	api.POST("/reports", h.complianceReportHandler.GenerateReport, h.adminGuard.RequireAdmin)
+	api.GET("/reports", h.complianceReportHandler.ListReports)
+	api.GET("/reports/:id", h.complianceReportHandler.GetReport)
+	api.GET("/reports/:id/document", h.complianceReportHandler.GetReportDocument)
+
+	// Shared scan reports (exempt from OAuth by the ingress, the token is the credential)
+	api.GET("/shared/scans/:token", h.shareHandler.GetSharedScan)
+
+	// Scan submission from CI (exempt from OAuth by the ingress, the API key is the credential)
+	if h.apiKeyGuard != nil {
+		// Keys only read back the scans they submitted, and never change the status of a scan
+		ci := api.Group("/ci", h.apiKeyGuard.RequireAPIKey)
+		ci.POST("/scans", h.scanHandler.CreateScan)
+		ci.GET("/scans/:id/gate", h.scanHandler.GetScanGate)
+		ci.GET("/scans/:id/sarif", h.scanHandler.GetScanSARIF)
+		ci.GET("/ingest-jobs/:id", h.ingestJobHandler.GetIngestJob)
+	} else {
+		h.logger.Info("SCANNER_API_KEYS not set - scan submission from CI disabled")
+	}
+
+	// Ticket callbacks (exempt from OAuth by the ingress, the signature is the credential)
+	if h.ticketCallbackHandler != nil {
+		api.POST("/integrations/ticket-callback", h.ticketCallbackHandler.TicketCallback)
+	} else {
+		h.logger.Info("TICKET_CALLBACK_SECRET not set - ticket callbacks disabled")
+	}
+
+	// Vulnerabilities
+	api.GET("/vulnerabilities", h.vulnHandler.ListVulnerabilities)
+	api.GET("/vulnerabilities/export", h.vulnHandler.ExportVulnerabilities)
+	api.GET("/vulnerabilities/search", h.vulnHandler.SearchVulnerabilities)
+	api.GET("/vulnerabilities/:cve", h.vulnHandler.GetVulnerabilityByCVE)
+	api.PATCH("/vulnerabilities/:id", h.vulnHandler.UpdateVulnerability)
+	api.PATCH("/vulnerabilities/bulk", h.vulnHandler.BulkUpdateVulnerabilities)
+	api.POST("/vulnerabilities/batch-get", h.vulnHandler.BatchGetVulnerabilities)
+	api.POST("/vulnerabilities/import", h.triageImportHandler.ImportTriage)
+	api.GET("/vulnerabilities/:id/history", h.vulnHandler.GetVulnerabilityHistory)
+	api.GET("/vulnerabilities/:id/links", h.vulnHandler.ListVulnerabilityLinks)
+	api.POST("/vulnerabilities/:id/links", h.vulnHandler.CreateVulnerabilityLink)
+	api.DELETE("/vulnerabilities/:id/links/:link_id", h.vulnHandler.DeleteVulnerabilityLink)
+	api.GET("/vulnerabilities/:cve/severity-changes", h.vulnHandler.GetSeverityChanges)
+	api.GET("/vulnerabilities/:id/workloads", h.coverageHandler.GetVulnerabilityWorkloads)
+
+	// Components
+	api.GET("/components", h.componentHandler.ListComponents)
+
+	// Impact assessment
+	api.POST("/impact", h.impactHandler.AssessImpact)
+
+	// Software inventory export
+	api.GET("/export/bom", h.bomHandler.ExportBOM)
+
+	// SBOM quality
+	api.GET("/sbom-quality", h.sbomQualityHandler.ListSBOMQuality)
+
+	// Watchlist
+	api.GET("/watchlist", h.watchlistHandler.ListWatchlist)
+	api.POST("/watchlist", h.watchlistHandler.CreateWatchlistSubscription)
+	api.DELETE("/watchlist/:id", h.watchlistHandler.DeleteWatchlistSubscription)
+
+	// Campaigns
+	api.GET("/campaigns", h.campaignHandler.ListCampaigns)
+	api.POST("/campaigns", h.campaignHandler.CreateCampaign)
+	api.GET("/campaigns/:id", h.campaignHandler.GetCampaign)
+	api.PATCH("/campaigns/:id", h.campaignHandler.UpdateCampaign)
+	api.DELETE("/campaigns/:id", h.campaignHandler.DeleteCampaign)
+	api.GET("/campaigns/:id/vulnerabilities", h.campaignHandler.ListCampaignVulnerabilities)
+	api.POST("/campaigns/:id/vulnerabilities", h.campaignHandler.AddCampaignVulnerabilities)
+	api.DELETE("/campaigns/:id/vulnerabilities/:vulnerability_id", h.campaignHandler.RemoveCampaignVulnerability)
+
+	// Scanner versions
+	api.GET("/scanner-versions", h.scannerVersionHandler.ListScannerVersions)
+
+	// Suppression rules
+	api.GET("/suppression-rules", h.suppressionHandler.ListSuppressionRules)
+	api.POST("/suppression-rules", h.suppressionHandler.CreateSuppressionRule)
+	api.POST("/suppression-rules/import", h.suppressionHandler.ImportSuppressionRules)
+	api.GET("/suppression-rules/export", h.suppressionHandler.ExportSuppressionRules)
+	api.GET("/suppression-rules/bundle", h.suppressionHandler.ExportSuppressionBundle)
+	api.POST("/suppression-rules/bundle", h.suppressionHandler.ImportSuppressionBundle)
+	api.DELETE("/suppression-rules/:id", h.suppressionHandler.DeleteSuppressionRule)
+
+	// Waivers
+	api.GET("/waivers", h.waiverHandler.ListWaivers)
+	api.POST("/waivers", h.waiverHandler.CreateWaiver)
+	api.GET("/waivers/:id", h.waiverHandler.GetWaiver)
+	api.DELETE("/waivers/:id", h.waiverHandler.DeleteWaiver)
+
+	// Images
+	api.GET("/images", h.imageHandler.ListImages)
+	api.GET("/images/prioritized", h.imageHandler.ListPrioritizedImages)
+	api.GET("/images/:id", h.imageHandler.GetImage)
+	api.GET("/images/:id/history", h.imageHandler.GetImageHistory)
+	api.PATCH("/images/:id", h.imageHandler.ReviewImage)
+	api.DELETE("/images/:id", h.imageHandler.DeleteImage, h.adminGuard.RequireAdmin)
+
+	// Live updates
+	api.GET("/events", h.eventHandler.StreamEvents)
+
+	// Metrics
+	api.GET("/metrics", h.metricsHandler.GetMetrics)
+	api.GET("/metrics/vulnerability-age", h.metricsHandler.GetVulnerabilityAge)
+	api.GET("/metrics/compliance", h.metricsHandler.GetCompliance)
+	api.GET("/metrics/scan-performance", h.metricsHandler.GetScanPerformance)
+
+	// Team usage and quotas (chargeback)
+	api.GET("/usage", h.usageHandler.GetUsage)
+
+	// User
+	api.GET("/user/me", h.userHandler.GetCurrentUser)
+	api.GET("/user/me/activity", h.activityHandler.GetMyActivity)
+
+	// Webhook Configs
+	api.PUT("/webhook-configs/:namespace/:name", h.webhookConfigHandler.UpsertWebhookConfig)
+	api.GET("/webhook-configs/:namespace/:name", h.webhookConfigHandler.GetWebhookConfig)
+	api.DELETE("/webhook-configs/:namespace/:name", h.webhookConfigHandler.DeleteWebhookConfig)
+
+	// ImageScan registrations
+	api.GET("/imagescans", h.imageScanHandler.ListImageScans)
+	api.PUT("/imagescans/:namespace/:name", h.imageScanHandler.RegisterImageScan)
+	api.DELETE("/imagescans/:namespace/:name", h.imageScanHandler.UnregisterImageScan)
+	api.GET("/imagescans/:namespace/:name/sizing", h.imageScanHandler.GetImageScanSizing)
+	api.GET("/entities/:team/:service/summary", h.imageScanHandler.GetEntitySummary)
+
+	// Deployed image inventory and scan coverage
+	api.PUT("/inventory/:source", h.coverageHandler.ReplaceInventory)
+	api.GET("/coverage", h.coverageHandler.GetCoverage)
+
+	// Namespaces
+	api.GET("/namespaces", h.namespaceHandler.ListNamespaces)
+
+	// Admin
+	admin := api.Group("/admin", h.adminGuard.RequireAdmin)
+	admin.GET("/maintenance", h.maintenanceHandler.GetMaintenance)
+	admin.PUT("/maintenance", h.maintenanceHandler.SetMaintenance)
+	admin.POST("/scanner-versions/rescan", h.scannerVersionHandler.MarkDeprecatedForRescan)
+	admin.GET("/quotas", h.usageHandler.ListQuotas)
+	admin.PUT("/quotas/:namespace", h.usageHandler.SetQuota)
+	admin.DELETE("/quotas/:namespace", h.usageHandler.DeleteQuota)
+	admin.GET("/workers", h.workerHandler.ListWorkers)
+	admin.GET("/online-changes", h.onlineChangeHandler.ListOnlineChanges)
+	admin.GET("/diagnostics", h.diagnosticsHandler.GetDiagnostics)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/invulnerable/backend/internal/api"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRegisterRoutes_Documented(t *testing.T) {
+	e := echo.New()
+	// With the optional routes
+	registerRoutes(e.Group("/api/v1"), routeHandlers{
+		logger:                zap.NewNop(),
+		apiKeyGuard:           &api.APIKeyGuard{},
+		ticketCallbackHandler: &api.TicketCallbackHandler{},
+	})
+
+	routes := 0
+	for _, route := range e.Routes() {
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+		routes++
+		assert.True(t, api.IsDocumentedRoute(route), "%s %s has no operation in openAPIOperations", route.Method, route.Path)
+	}
+	assert.NotZero(t, routes)
+}
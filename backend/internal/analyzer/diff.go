@@ -3,6 +3,7 @@ package analyzer
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/invulnerable/backend/internal/db"
 	"github.com/invulnerable/backend/internal/models"
@@ -11,7 +12,7 @@ import (
 // ScanRepository defines the interface for scan repository operations
 type ScanRepository interface {
 	GetByID(ctx context.Context, id int) (*models.Scan, error)
-	GetPreviousScan(ctx context.Context, imageID int, currentScanDate string) (*models.Scan, error)
+	GetPreviousScan(ctx context.Context, imageID int, target *string, currentScanDate time.Time) (*models.Scan, error)
 	GetVulnerabilities(ctx context.Context, scanID int) ([]models.Vulnerability, error)
 }
 
@@ -20,9 +21,18 @@ type VulnerabilityRepository interface {
 	MarkAsFixed(ctx context.Context, vulnerabilityIDs []int) error
 }
 
+// FlapRepository defines the interface for tracking vulnerabilities that come and go across scans
+type FlapRepository interface {
+	RecordPresent(ctx context.Context, scanID, imageID int, target *string, vulnerabilityIDs []int) error
+	RecordAbsent(ctx context.Context, scanID, imageID int, target *string, vulnerabilityIDs []int, confirmations int) ([]int, error)
+}
+
 type Analyzer struct {
 	scanRepo ScanRepository
 	vulnRepo VulnerabilityRepository
+	flapRepo FlapRepository
+	// Consecutive scans a vulnerability must be absent from before it is marked fixed
+	fixConfirmations int
 }
 
 func New(scanRepo ScanRepository, vulnRepo VulnerabilityRepository) *Analyzer {
@@ -40,13 +50,55 @@ func NewWithRepositories(scanRepo *db.ScanRepository, vulnRepo *db.Vulnerability
 	}
 }
 
-// CompareScan compares a scan with the previous scan for the same image
+// SetFlapDamping tracks vulnerabilities flapping between scans, and only marks a vulnerability fixed
+// once it is absent from confirmations consecutive scans of the image target
+func (a *Analyzer) SetFlapDamping(repo FlapRepository, confirmations int) {
+	a.flapRepo = repo
+	a.fixConfirmations = max(confirmations, 1)
+}
+
+// CompareScan compares a scan with the previous scan for the same image, and marks the
+// vulnerabilities it proves absent as fixed
 func (a *Analyzer) CompareScan(ctx context.Context, scanID int) (*models.ScanDiff, error) {
 	return a.CompareScanWith(ctx, scanID, nil)
 }
 
-// CompareScanWith compares a scan with a specified previous scan, or the immediate previous scan if not specified
+// CompareScanWith compares a scan with a specified previous scan, or the immediate previous scan if not specified,
+// and marks the vulnerabilities it proves absent as fixed. The previous scan must be of the same image
 func (a *Analyzer) CompareScanWith(ctx context.Context, scanID int, previousScanID *int) (*models.ScanDiff, error) {
+	cmp, err := a.compare(ctx, scanID, previousScanID, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.apply(ctx, cmp, previousScanID != nil); err != nil {
+		return nil, err
+	}
+	return cmp.diff, nil
+}
+
+// Diff compares a scan with a specified previous scan, or the immediate previous scan if not specified.
+// It only reads: vulnerabilities missing from the scan are listed as fixed, not marked fixed. The
+// specified scan may be of another image, e.g. another tag to evaluate upgrading to it
+func (a *Analyzer) Diff(ctx context.Context, scanID int, previousScanID *int) (*models.ScanDiff, error) {
+	cmp, err := a.compare(ctx, scanID, previousScanID, true)
+	if err != nil {
+		return nil, err
+	}
+	return cmp.diff, nil
+}
+
+// comparison is a computed diff with what applying it needs
+type comparison struct {
+	diff            *models.ScanDiff
+	currentScan     *models.Scan
+	presentVulnIDs  []int
+	fixedVulnIDs    []int
+	hasPreviousScan bool
+}
+
+// compare computes the diff of a scan without changing anything. anyImage allows a specified previous
+// scan of another image
+func (a *Analyzer) compare(ctx context.Context, scanID int, previousScanID *int, anyImage bool) (*comparison, error) {
 	// Get current scan
 	currentScan, err := a.scanRepo.GetByID(ctx, scanID)
 	if err != nil {
@@ -62,12 +114,12 @@ func (a *Analyzer) CompareScanWith(ctx context.Context, scanID int, previousScan
 			return nil, fmt.Errorf("failed to get specified previous scan: %w", err)
 		}
 		// Verify it's for the same image
-		if previousScan.ImageID != currentScan.ImageID {
+		if !anyImage && previousScan.ImageID != currentScan.ImageID {
 			return nil, fmt.Errorf("previous scan is for a different image")
 		}
 	} else {
-		// Get the immediate previous scan for the same image
-		previousScan, err = a.scanRepo.GetPreviousScan(ctx, currentScan.ImageID, currentScan.ScanDate.Format("2006-01-02 15:04:05"))
+		// Get the immediate previous scan for the same image and target
+		previousScan, err = a.scanRepo.GetPreviousScan(ctx, currentScan.ImageID, currentScan.Target, currentScan.ScanDate)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get previous scan: %w", err)
 		}
@@ -80,17 +132,20 @@ func (a *Analyzer) CompareScanWith(ctx context.Context, scanID int, previousScan
 			return nil, fmt.Errorf("failed to get current vulnerabilities: %w", err)
 		}
 
-		return &models.ScanDiff{
-			ScanID:          scanID,
-			PreviousScanID:  0,
-			NewVulns:        currentVulns,
-			FixedVulns:      []models.Vulnerability{},
-			PersistentVulns: []models.Vulnerability{},
-			Summary: models.ScanDiffSummary{
-				NewCount:        len(currentVulns),
-				FixedCount:      0,
-				PersistentCount: 0,
+		return &comparison{
+			diff: &models.ScanDiff{
+				ScanID:          scanID,
+				PreviousScanID:  0,
+				NewVulns:        currentVulns,
+				FixedVulns:      []models.Vulnerability{},
+				PersistentVulns: []models.Vulnerability{},
+				Summary: models.ScanDiffSummary{
+					NewCount:        len(currentVulns),
+					FixedCount:      0,
+					PersistentCount: 0,
+				},
 			},
+			currentScan: currentScan,
 		}, nil
 	}
 
@@ -124,7 +179,9 @@ func (a *Analyzer) CompareScanWith(ctx context.Context, scanID int, previousScan
 	persistentVulns := []models.Vulnerability{}
 
 	// Find new and persistent vulnerabilities
+	presentVulnIDs := make([]int, 0, len(currentMap))
 	for key, vuln := range currentMap {
+		presentVulnIDs = append(presentVulnIDs, vuln.ID)
 		if _, exists := previousMap[key]; exists {
 			persistentVulns = append(persistentVulns, vuln)
 		} else {
@@ -141,27 +198,115 @@ func (a *Analyzer) CompareScanWith(ctx context.Context, scanID int, previousScan
 		}
 	}
 
-	// Mark fixed vulnerabilities in database
+	// Whether the scan can be trusted to prove the absence of the fixed vulnerabilities
+	fixedHeldReason := ""
 	if len(fixedVulnIDs) > 0 {
-		if err := a.vulnRepo.MarkAsFixed(ctx, fixedVulnIDs); err != nil {
-			return nil, fmt.Errorf("failed to mark vulnerabilities as fixed: %w", err)
-		}
+		fixedHeldReason = fixedHeld(currentScan, previousScan, len(currentVulns))
 	}
 
-	return &models.ScanDiff{
-		ScanID:          scanID,
-		PreviousScanID:  previousScan.ID,
-		NewVulns:        newVulns,
-		FixedVulns:      fixedVulns,
-		PersistentVulns: persistentVulns,
-		Summary: models.ScanDiffSummary{
-			NewCount:        len(newVulns),
-			FixedCount:      len(fixedVulns),
-			PersistentCount: len(persistentVulns),
+	return &comparison{
+		diff: &models.ScanDiff{
+			ScanID:          scanID,
+			PreviousScanID:  previousScan.ID,
+			NewVulns:        newVulns,
+			FixedVulns:      fixedVulns,
+			PersistentVulns: persistentVulns,
+			FixedHeldReason: fixedHeldReason,
+			CrossImage:      previousScan.ImageID != currentScan.ImageID,
+			Summary: models.ScanDiffSummary{
+				NewCount:        len(newVulns),
+				FixedCount:      len(fixedVulns),
+				PersistentCount: len(persistentVulns),
+			},
 		},
+		currentScan:     currentScan,
+		presentVulnIDs:  presentVulnIDs,
+		fixedVulnIDs:    fixedVulnIDs,
+		hasPreviousScan: true,
 	}, nil
 }
 
+// apply marks the fixed vulnerabilities of a diff as fixed in the database, unless the scan can't be
+// trusted to prove their absence. explicit is set when the diff is against an arbitrary earlier scan
+func (a *Analyzer) apply(ctx context.Context, cmp *comparison, explicit bool) error {
+	if !cmp.hasPreviousScan {
+		return nil
+	}
+
+	confirmedIDs := cmp.fixedVulnIDs
+	if cmp.diff.FixedHeldReason != "" {
+		confirmedIDs = nil
+	}
+
+	// Flaps are only tracked across consecutive scans, not against an arbitrary earlier one. Absences
+	// recorded by earlier scans may be confirmed by this one
+	if a.flapRepo != nil && explicit {
+		if len(confirmedIDs) > 0 {
+			cmp.diff.FixedHeldReason = "only consecutive scans mark vulnerabilities fixed"
+		}
+		confirmedIDs = nil
+	} else if a.flapRepo != nil {
+		scan := cmp.currentScan
+		if err := a.flapRepo.RecordPresent(ctx, scan.ID, scan.ImageID, scan.Target, cmp.presentVulnIDs); err != nil {
+			return fmt.Errorf("failed to record detected vulnerabilities: %w", err)
+		}
+		if cmp.diff.FixedHeldReason == "" {
+			var err error
+			confirmedIDs, err = a.flapRepo.RecordAbsent(ctx, scan.ID, scan.ImageID, scan.Target, cmp.fixedVulnIDs, a.fixConfirmations)
+			if err != nil {
+				return fmt.Errorf("failed to record absent vulnerabilities: %w", err)
+			}
+			if pending := countMissing(cmp.fixedVulnIDs, confirmedIDs); pending > 0 {
+				cmp.diff.FixedHeldReason = fmt.Sprintf("%d vulnerabilities must be absent from %d consecutive scans to be marked fixed", pending, a.fixConfirmations)
+			}
+		}
+	}
+
+	if len(confirmedIDs) > 0 {
+		if err := a.vulnRepo.MarkAsFixed(ctx, confirmedIDs); err != nil {
+			return fmt.Errorf("failed to mark vulnerabilities as fixed: %w", err)
+		}
+	}
+	return nil
+}
+
+// fixedHeld returns why vulnerabilities absent from the current scan must not be marked fixed,
+// or an empty string when the scan is complete enough to prove their absence
+func fixedHeld(current, previous *models.Scan, currentCount int) string {
+	// Partial scans may simply have missed packages
+	if current.Status != models.ScanStatusCompleted {
+		return fmt.Sprintf("scan status is %s", current.Status)
+	}
+	// Matches over the ingest limits were never stored
+	if current.MatchesDropped > 0 {
+		return fmt.Sprintf("%d matches were dropped at ingestion", current.MatchesDropped)
+	}
+	// An older vulnerability database may not know about the missing entries yet
+	if current.GrypeDBBuilt != nil && previous.GrypeDBBuilt != nil && current.GrypeDBBuilt.Before(*previous.GrypeDBBuilt) {
+		return "scan used an older Grype database than the previous scan"
+	}
+	// An unchanged image losing every finding at once points at a failed matcher
+	if currentCount == 0 && current.Digest != nil && previous.Digest != nil && *current.Digest == *previous.Digest {
+		return "scan found no vulnerabilities in an unchanged image"
+	}
+	return ""
+}
+
+// countMissing returns how many of ids are not in others
+func countMissing(ids, others []int) int {
+	found := make(map[int]bool, len(others))
+	for _, id := range others {
+		found[id] = true
+	}
+	missing := 0
+	for _, id := range ids {
+		if !found[id] {
+			missing++
+		}
+	}
+	return missing
+}
+
 // makeVulnKey creates a unique key for vulnerability comparison
 func makeVulnKey(v models.Vulnerability) string {
 	return fmt.Sprintf("%s:%s:%s", v.CVEID, v.PackageName, v.PackageVersion)
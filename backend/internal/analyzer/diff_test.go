@@ -24,8 +24,8 @@ func (m *MockScanRepo) GetByID(ctx context.Context, id int) (*models.Scan, error
 	return args.Get(0).(*models.Scan), args.Error(1)
 }
 
-func (m *MockScanRepo) GetPreviousScan(ctx context.Context, imageID int, currentScanDate string) (*models.Scan, error) {
-	args := m.Called(ctx, imageID, currentScanDate)
+func (m *MockScanRepo) GetPreviousScan(ctx context.Context, imageID int, target *string, currentScanDate time.Time) (*models.Scan, error) {
+	args := m.Called(ctx, imageID, target, currentScanDate)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -92,7 +92,7 @@ func TestAnalyzer_CompareScan_NoPreviousScan(t *testing.T) {
 	}
 
 	mockScanRepo.On("GetByID", ctx, scanID).Return(currentScan, nil)
-	mockScanRepo.On("GetPreviousScan", ctx, imageID, mock.Anything).Return(nil, nil)
+	mockScanRepo.On("GetPreviousScan", ctx, imageID, (*string)(nil), mock.Anything).Return(nil, nil)
 	mockScanRepo.On("GetVulnerabilities", ctx, scanID).Return(currentVulns, nil)
 
 	diff, err := analyzer.CompareScan(ctx, scanID)
@@ -125,6 +125,7 @@ func TestAnalyzer_CompareScan_WithPreviousScan(t *testing.T) {
 		ID:       scanID,
 		ImageID:  imageID,
 		ScanDate: now,
+		Status:   models.ScanStatusCompleted,
 	}
 
 	previousScan := &models.Scan{
@@ -147,7 +148,7 @@ func TestAnalyzer_CompareScan_WithPreviousScan(t *testing.T) {
 	}
 
 	mockScanRepo.On("GetByID", ctx, scanID).Return(currentScan, nil)
-	mockScanRepo.On("GetPreviousScan", ctx, imageID, mock.Anything).Return(previousScan, nil)
+	mockScanRepo.On("GetPreviousScan", ctx, imageID, (*string)(nil), mock.Anything).Return(previousScan, nil)
 	mockScanRepo.On("GetVulnerabilities", ctx, scanID).Return(currentVulns, nil)
 	mockScanRepo.On("GetVulnerabilities", ctx, previousScanID).Return(previousVulns, nil)
 	mockVulnRepo.On("MarkAsFixed", ctx, []int{4}).Return(nil)
@@ -174,3 +175,285 @@ func TestAnalyzer_CompareScan_WithPreviousScan(t *testing.T) {
 	mockScanRepo.AssertExpectations(t)
 	mockVulnRepo.AssertExpectations(t)
 }
+
+func TestAnalyzer_CompareScan_PassesScanDateUnformatted(t *testing.T) {
+	mockScanRepo := new(MockScanRepo)
+	mockVulnRepo := new(MockVulnRepo)
+	analyzer := New(mockScanRepo, mockVulnRepo)
+
+	ctx := context.Background()
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// Second occurrence of 01:30 on the DST fall-back day, with sub-second precision
+	scanDate := time.Date(2024, 11, 3, 1, 30, 0, 500, newYork).Add(time.Hour)
+	currentScan := &models.Scan{ID: 1, ImageID: 100, ScanDate: scanDate}
+
+	mockScanRepo.On("GetByID", ctx, 1).Return(currentScan, nil)
+	mockScanRepo.On("GetPreviousScan", ctx, 100, (*string)(nil), mock.MatchedBy(func(date time.Time) bool {
+		return date.Equal(scanDate)
+	})).Return(nil, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 1).Return([]models.Vulnerability{}, nil)
+
+	_, err = analyzer.CompareScan(ctx, 1)
+	require.NoError(t, err)
+
+	mockScanRepo.AssertExpectations(t)
+}
+
+func TestAnalyzer_CompareScan_PartialScanDoesNotMarkFixed(t *testing.T) {
+	mockScanRepo := new(MockScanRepo)
+	mockVulnRepo := new(MockVulnRepo)
+	analyzer := New(mockScanRepo, mockVulnRepo)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	currentScan := &models.Scan{ID: 2, ImageID: 100, ScanDate: now, Status: models.ScanStatusPartial}
+	previousScan := &models.Scan{ID: 1, ImageID: 100, ScanDate: now.Add(-24 * time.Hour), Status: models.ScanStatusCompleted}
+
+	mockScanRepo.On("GetByID", ctx, 2).Return(currentScan, nil)
+	mockScanRepo.On("GetPreviousScan", ctx, 100, (*string)(nil), mock.Anything).Return(previousScan, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 2).Return([]models.Vulnerability{}, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 1).Return([]models.Vulnerability{
+		{ID: 4, CVEID: "CVE-2023-4", PackageName: "pkg4", PackageVersion: "4.0"},
+	}, nil)
+
+	diff, err := analyzer.CompareScan(ctx, 2)
+	require.NoError(t, err)
+
+	// Still reported as fixed in the diff, but not persisted
+	assert.Len(t, diff.FixedVulns, 1)
+	mockVulnRepo.AssertNotCalled(t, "MarkAsFixed", mock.Anything, mock.Anything)
+}
+
+func TestAnalyzer_CompareScan_UntrustedScanDoesNotMarkFixed(t *testing.T) {
+	now := time.Now()
+	digest := "sha256:abc"
+	otherDigest := "sha256:def"
+	older := now.Add(-48 * time.Hour)
+
+	tests := []struct {
+		name     string
+		current  models.Scan
+		previous models.Scan
+		vulns    []models.Vulnerability
+		held     bool
+	}{
+		{
+			name:    "matches dropped at ingestion",
+			current: models.Scan{Status: models.ScanStatusCompleted, MatchesDropped: 3},
+			vulns:   []models.Vulnerability{{ID: 5, CVEID: "CVE-2023-5", PackageName: "pkg5", PackageVersion: "5.0"}},
+			held:    true,
+		},
+		{
+			name:     "older Grype database",
+			current:  models.Scan{Status: models.ScanStatusCompleted, GrypeDBBuilt: &older},
+			previous: models.Scan{GrypeDBBuilt: &now},
+			vulns:    []models.Vulnerability{{ID: 5, CVEID: "CVE-2023-5", PackageName: "pkg5", PackageVersion: "5.0"}},
+			held:     true,
+		},
+		{
+			name:     "unchanged image losing every finding",
+			current:  models.Scan{Status: models.ScanStatusCompleted, Digest: &digest},
+			previous: models.Scan{Digest: &digest},
+			held:     true,
+		},
+		{
+			name:     "rebuilt image losing every finding",
+			current:  models.Scan{Status: models.ScanStatusCompleted, Digest: &otherDigest, GrypeDBBuilt: &now},
+			previous: models.Scan{Digest: &digest, GrypeDBBuilt: &older},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockScanRepo := new(MockScanRepo)
+			mockVulnRepo := new(MockVulnRepo)
+			analyzer := New(mockScanRepo, mockVulnRepo)
+			ctx := context.Background()
+
+			currentScan := tt.current
+			currentScan.ID, currentScan.ImageID, currentScan.ScanDate = 2, 100, now
+			previousScan := tt.previous
+			previousScan.ID, previousScan.ImageID, previousScan.ScanDate = 1, 100, now.Add(-24*time.Hour)
+			previousScan.Status = models.ScanStatusCompleted
+
+			vulns := tt.vulns
+			if vulns == nil {
+				vulns = []models.Vulnerability{}
+			}
+			mockScanRepo.On("GetByID", ctx, 2).Return(&currentScan, nil)
+			mockScanRepo.On("GetPreviousScan", ctx, 100, (*string)(nil), mock.Anything).Return(&previousScan, nil)
+			mockScanRepo.On("GetVulnerabilities", ctx, 2).Return(vulns, nil)
+			mockScanRepo.On("GetVulnerabilities", ctx, 1).Return(append([]models.Vulnerability{
+				{ID: 4, CVEID: "CVE-2023-4", PackageName: "pkg4", PackageVersion: "4.0"},
+			}, vulns...), nil)
+			if !tt.held {
+				mockVulnRepo.On("MarkAsFixed", ctx, []int{4}).Return(nil)
+			}
+
+			diff, err := analyzer.CompareScan(ctx, 2)
+			require.NoError(t, err)
+			assert.Len(t, diff.FixedVulns, 1)
+			if tt.held {
+				assert.NotEmpty(t, diff.FixedHeldReason)
+				mockVulnRepo.AssertNotCalled(t, "MarkAsFixed", mock.Anything, mock.Anything)
+			} else {
+				assert.Empty(t, diff.FixedHeldReason)
+				mockVulnRepo.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+type MockFlapRepo struct {
+	mock.Mock
+}
+
+func (m *MockFlapRepo) RecordPresent(ctx context.Context, scanID, imageID int, target *string, vulnerabilityIDs []int) error {
+	args := m.Called(ctx, scanID, imageID, target, vulnerabilityIDs)
+	return args.Error(0)
+}
+
+func (m *MockFlapRepo) RecordAbsent(ctx context.Context, scanID, imageID int, target *string, vulnerabilityIDs []int, confirmations int) ([]int, error) {
+	args := m.Called(ctx, scanID, imageID, target, vulnerabilityIDs, confirmations)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func TestAnalyzer_CompareScan_FlapDamping(t *testing.T) {
+	mockScanRepo := new(MockScanRepo)
+	mockVulnRepo := new(MockVulnRepo)
+	mockFlapRepo := new(MockFlapRepo)
+	analyzer := New(mockScanRepo, mockVulnRepo)
+	analyzer.SetFlapDamping(mockFlapRepo, 2)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	currentScan := &models.Scan{ID: 3, ImageID: 100, ScanDate: now, Status: models.ScanStatusCompleted}
+	previousScan := &models.Scan{ID: 2, ImageID: 100, ScanDate: now.Add(-24 * time.Hour), Status: models.ScanStatusCompleted}
+
+	mockScanRepo.On("GetByID", ctx, 3).Return(currentScan, nil)
+	mockScanRepo.On("GetPreviousScan", ctx, 100, (*string)(nil), mock.Anything).Return(previousScan, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 3).Return([]models.Vulnerability{
+		{ID: 1, CVEID: "CVE-2023-1", PackageName: "pkg1", PackageVersion: "1.0"},
+	}, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 2).Return([]models.Vulnerability{
+		{ID: 1, CVEID: "CVE-2023-1", PackageName: "pkg1", PackageVersion: "1.0"},
+		{ID: 2, CVEID: "CVE-2023-2", PackageName: "pkg2", PackageVersion: "2.0"},
+	}, nil)
+
+	// CVE-2023-2 needs another scan without it, CVE-2023-9 was absent from the previous scan already
+	mockFlapRepo.On("RecordPresent", ctx, 3, 100, (*string)(nil), []int{1}).Return(nil)
+	mockFlapRepo.On("RecordAbsent", ctx, 3, 100, (*string)(nil), []int{2}, 2).Return([]int{9}, nil)
+	mockVulnRepo.On("MarkAsFixed", ctx, []int{9}).Return(nil)
+
+	diff, err := analyzer.CompareScan(ctx, 3)
+	require.NoError(t, err)
+
+	assert.Len(t, diff.FixedVulns, 1)
+	assert.Contains(t, diff.FixedHeldReason, "2 consecutive scans")
+	mockFlapRepo.AssertExpectations(t)
+	mockVulnRepo.AssertExpectations(t)
+}
+
+func TestAnalyzer_CompareScanWith_ExplicitPreviousScanWithDamping(t *testing.T) {
+	mockScanRepo := new(MockScanRepo)
+	mockVulnRepo := new(MockVulnRepo)
+	mockFlapRepo := new(MockFlapRepo)
+	analyzer := New(mockScanRepo, mockVulnRepo)
+	analyzer.SetFlapDamping(mockFlapRepo, 2)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	currentScan := &models.Scan{ID: 3, ImageID: 100, ScanDate: now, Status: models.ScanStatusCompleted}
+	previousScan := &models.Scan{ID: 1, ImageID: 100, ScanDate: now.Add(-48 * time.Hour), Status: models.ScanStatusCompleted}
+	previousScanID := 1
+
+	mockScanRepo.On("GetByID", ctx, 3).Return(currentScan, nil)
+	mockScanRepo.On("GetByID", ctx, 1).Return(previousScan, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 3).Return([]models.Vulnerability{}, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 1).Return([]models.Vulnerability{
+		{ID: 2, CVEID: "CVE-2023-2", PackageName: "pkg2", PackageVersion: "2.0"},
+	}, nil)
+
+	diff, err := analyzer.CompareScanWith(ctx, 3, &previousScanID)
+	require.NoError(t, err)
+
+	assert.Len(t, diff.FixedVulns, 1)
+	assert.NotEmpty(t, diff.FixedHeldReason)
+	mockFlapRepo.AssertNotCalled(t, "RecordPresent", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockFlapRepo.AssertNotCalled(t, "RecordAbsent", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockVulnRepo.AssertNotCalled(t, "MarkAsFixed", mock.Anything, mock.Anything)
+}
+
+func TestAnalyzer_Diff_HasNoSideEffects(t *testing.T) {
+	mockScanRepo := new(MockScanRepo)
+	mockVulnRepo := new(MockVulnRepo)
+	mockFlapRepo := new(MockFlapRepo)
+	analyzer := New(mockScanRepo, mockVulnRepo)
+	analyzer.SetFlapDamping(mockFlapRepo, 1)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	currentScan := &models.Scan{ID: 2, ImageID: 100, ScanDate: now, Status: models.ScanStatusCompleted}
+	previousScan := &models.Scan{ID: 1, ImageID: 100, ScanDate: now.Add(-24 * time.Hour), Status: models.ScanStatusCompleted}
+
+	mockScanRepo.On("GetByID", ctx, 2).Return(currentScan, nil)
+	mockScanRepo.On("GetPreviousScan", ctx, 100, (*string)(nil), mock.Anything).Return(previousScan, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 2).Return([]models.Vulnerability{
+		{ID: 1, CVEID: "CVE-2023-1", PackageName: "pkg1", PackageVersion: "1.0"},
+	}, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 1).Return([]models.Vulnerability{
+		{ID: 1, CVEID: "CVE-2023-1", PackageName: "pkg1", PackageVersion: "1.0"},
+		{ID: 2, CVEID: "CVE-2023-2", PackageName: "pkg2", PackageVersion: "2.0"},
+	}, nil)
+
+	diff, err := analyzer.Diff(ctx, 2, nil)
+	require.NoError(t, err)
+
+	assert.Len(t, diff.FixedVulns, 1)
+	assert.Len(t, diff.PersistentVulns, 1)
+	assert.Empty(t, diff.FixedHeldReason)
+	mockVulnRepo.AssertNotCalled(t, "MarkAsFixed", mock.Anything, mock.Anything)
+	mockFlapRepo.AssertNotCalled(t, "RecordPresent", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockFlapRepo.AssertNotCalled(t, "RecordAbsent", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAnalyzer_Diff_AcrossImages(t *testing.T) {
+	mockScanRepo := new(MockScanRepo)
+	mockVulnRepo := new(MockVulnRepo)
+	analyzer := New(mockScanRepo, mockVulnRepo)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	// nginx:1.26 compared with nginx:1.25
+	currentScan := &models.Scan{ID: 2, ImageID: 101, ScanDate: now, Status: models.ScanStatusCompleted}
+	otherTagScan := &models.Scan{ID: 1, ImageID: 100, ScanDate: now, Status: models.ScanStatusCompleted}
+	otherTagScanID := 1
+
+	mockScanRepo.On("GetByID", ctx, 2).Return(currentScan, nil)
+	mockScanRepo.On("GetByID", ctx, 1).Return(otherTagScan, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 2).Return([]models.Vulnerability{
+		{ID: 3, CVEID: "CVE-2023-3", PackageName: "pkg3", PackageVersion: "3.0"},
+	}, nil)
+	mockScanRepo.On("GetVulnerabilities", ctx, 1).Return([]models.Vulnerability{
+		{ID: 2, CVEID: "CVE-2023-2", PackageName: "pkg2", PackageVersion: "2.0"},
+	}, nil)
+
+	diff, err := analyzer.Diff(ctx, 2, &otherTagScanID)
+	require.NoError(t, err)
+	assert.True(t, diff.CrossImage)
+	assert.Equal(t, 1, diff.PreviousScanID)
+	assert.Len(t, diff.NewVulns, 1)
+	assert.Len(t, diff.FixedVulns, 1)
+
+	// Only scans of the image prove vulnerabilities fixed
+	_, err = analyzer.CompareScanWith(ctx, 2, &otherTagScanID)
+	assert.Error(t, err)
+	mockVulnRepo.AssertNotCalled(t, "MarkAsFixed", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// defaultActivityDays is the window of the activity of users without days
+const defaultActivityDays = 30
+
+// ActivityStore is the persistence used by the activity handler
+type ActivityStore interface {
+	ListUserActivity(ctx context.Context, user string, since time.Time, kind string, limit, offset int) ([]models.UserActivity, int, error)
+	SummarizeUserActivity(ctx context.Context, user string, since time.Time) (*models.UserActivitySummary, error)
+}
+
+// ActivityHandler reports the triage actions, comments, assignments and administrative actions of users
+type ActivityHandler struct {
+	logger *zap.Logger
+	store  ActivityStore
+}
+
+func NewActivityHandler(logger *zap.Logger, store ActivityStore) *ActivityHandler {
+	return &ActivityHandler{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// GetMyActivity handles GET /api/v1/user/me/activity?days=30&kind=triage
+// Actions are the ones recorded under the current user, latest first, with their counts over the window.
+// Without an authenticating proxy every action is recorded under "unknown"
+func (h *ActivityHandler) GetMyActivity(c echo.Context) error {
+	days := defaultActivityDays
+	if s := c.QueryParam("days"); s != "" {
+		d, err := strconv.Atoi(s)
+		if err != nil || d < 1 || d > 365 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid days parameter, expected 1 to 365")
+		}
+		days = d
+	}
+
+	kind := c.QueryParam("kind")
+	if kind != "" && !slices.Contains(models.ValidActivityKinds, kind) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid kind parameter")
+	}
+
+	limit, offset := parsePagination(c, 50)
+
+	ctx := c.Request().Context()
+	user := getUserFromHeaders(c)
+	since := time.Now().AddDate(0, 0, -days)
+
+	activity, total, err := h.store.ListUserActivity(ctx, user, since, kind, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list user activity", zap.Error(err), zap.String("user", user))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list user activity")
+	}
+	summary, err := h.store.SummarizeUserActivity(ctx, user, since)
+	if err != nil {
+		h.logger.Error("failed to summarize user activity", zap.Error(err), zap.String("user", user))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list user activity")
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		User    string                      `json:"user"`
+		Since   time.Time                   `json:"since"`
+		Days    int                         `json:"days"`
+		Summary *models.UserActivitySummary `json:"summary"`
+		Page
+	}{user, since, days, summary, newPage(activity, total, limit, offset)})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeActivityStore struct {
+	activity []models.UserActivity
+	user     string
+	since    time.Time
+	kind     string
+}
+
+func (s *fakeActivityStore) ListUserActivity(ctx context.Context, user string, since time.Time, kind string, limit, offset int) ([]models.UserActivity, int, error) {
+	s.user, s.since, s.kind = user, since, kind
+	return append([]models.UserActivity{}, s.activity...), len(s.activity), nil
+}
+
+func (s *fakeActivityStore) SummarizeUserActivity(ctx context.Context, user string, since time.Time) (*models.UserActivitySummary, error) {
+	summary := &models.UserActivitySummary{ByStatus: map[string]int{}}
+	for _, a := range s.activity {
+		if a.Kind == models.ActivityTriage {
+			summary.Triaged++
+			summary.ByStatus[*a.NewValue]++
+		}
+	}
+	return summary, nil
+}
+
+func TestActivityHandler_GetMyActivity(t *testing.T) {
+	vulnID, status := 7, models.StatusFixed
+	store := &fakeActivityStore{activity: []models.UserActivity{
+		{Kind: models.ActivityTriage, At: time.Now(), VulnerabilityID: &vulnID, NewValue: &status},
+	}}
+	handler := NewActivityHandler(zap.NewNop(), store)
+	e := echo.New()
+	e.GET("/api/v1/user/me/activity", handler.GetMyActivity)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/user/me/activity?kind=triage", nil)
+	req.Header.Set("X-Auth-Request-Email", "alice@example.com")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "alice@example.com", store.user)
+	assert.Equal(t, models.ActivityTriage, store.kind)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -30), store.since, time.Minute)
+
+	var resp struct {
+		User    string                     `json:"user"`
+		Days    int                        `json:"days"`
+		Total   int                        `json:"total"`
+		Summary models.UserActivitySummary `json:"summary"`
+		Items   []models.UserActivity      `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "alice@example.com", resp.User)
+	assert.Equal(t, 30, resp.Days)
+	assert.Equal(t, 1, resp.Total)
+	assert.Equal(t, map[string]int{models.StatusFixed: 1}, resp.Summary.ByStatus)
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, vulnID, *resp.Items[0].VulnerabilityID)
+
+	// Without an authenticating proxy, actions are recorded under unknown
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/user/me/activity?days=7", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "unknown", store.user)
+	assert.Empty(t, store.kind)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -7), store.since, time.Minute)
+
+	for _, query := range []string{"days=0", "days=366", "days=abc", "kind=likes"} {
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/user/me/activity?"+query, nil)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// AdminGuard restricts endpoints to the operators listed in ADMIN_USERS
+// When OAuth is disabled the deployment has no identities, so every caller is treated as admin
+type AdminGuard struct {
+	logger       *zap.Logger
+	jwtValidator *auth.JWTValidator
+	oauthEnabled bool
+	admins       map[string]bool
+}
+
+// NewAdminGuard creates an admin guard from a comma-separated list of admin emails
+func NewAdminGuard(logger *zap.Logger, jwtValidator *auth.JWTValidator, oauthEnabled bool, adminUsers string) *AdminGuard {
+	admins := make(map[string]bool)
+	for _, user := range strings.Split(adminUsers, ",") {
+		user = strings.ToLower(strings.TrimSpace(user))
+		if user != "" {
+			admins[user] = true
+		}
+	}
+
+	return &AdminGuard{
+		logger:       logger,
+		jwtValidator: jwtValidator,
+		oauthEnabled: oauthEnabled,
+		admins:       admins,
+	}
+}
+
+// RequireAdmin is an Echo middleware rejecting callers that are not configured admins
+func (g *AdminGuard) RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !g.oauthEnabled {
+			return next(c)
+		}
+
+		id := requestIdentity(c)
+		email := id.Email
+		if email == "" {
+			return echo.NewHTTPError(http.StatusUnauthorized, "authentication required")
+		}
+
+		// Same validation as /user/me: the header alone is not trusted
+		if g.jwtValidator == nil {
+			g.logger.Error("OAuth2 enabled but JWT validator not configured - this is a configuration error")
+			return echo.NewHTTPError(http.StatusInternalServerError, "authentication not properly configured")
+		}
+
+		token, err := g.jwtValidator.ValidateToken(id.Token)
+		if err != nil {
+			g.logger.Warn("invalid access token on admin endpoint",
+				zap.Error(err),
+				zap.String("email", email),
+				zap.String("remote_addr", c.RealIP()))
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid access token")
+		}
+
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if tokenEmail, ok := claims["email"].(string); ok && tokenEmail != email {
+				return echo.NewHTTPError(http.StatusUnauthorized, "email mismatch")
+			}
+		}
+
+		if !g.admins[strings.ToLower(email)] {
+			g.logger.Warn("non-admin user attempted admin action",
+				zap.String("email", email),
+				zap.String("path", c.Path()))
+			return echo.NewHTTPError(http.StatusForbidden, "admin privileges required")
+		}
+
+		return next(c)
+	}
+}
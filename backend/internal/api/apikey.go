@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// apiKeyNameKey is the context key of the name of the API key a request was authenticated with
+const apiKeyNameKey = "api_key_name"
+
+// headerAPIKey is an alternative to "Authorization: Bearer <key>" for clients that can't set it
+const headerAPIKey = "X-API-Key"
+
+// APIKeyGuard restricts endpoints to callers presenting one of SCANNER_API_KEYS. It protects the
+// scan submission routes reached from outside the cluster, which the ingress exempts from OAuth
+type APIKeyGuard struct {
+	logger *zap.Logger
+	keys   *auth.APIKeys
+}
+
+// NewAPIKeyGuard creates an API key guard
+func NewAPIKeyGuard(logger *zap.Logger, keys *auth.APIKeys) *APIKeyGuard {
+	return &APIKeyGuard{logger: logger, keys: keys}
+}
+
+// RequireAPIKey is an Echo middleware rejecting callers without a valid API key
+func (g *APIKeyGuard) RequireAPIKey(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(headerAPIKey)
+		if bearer, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer "); ok {
+			key = strings.TrimSpace(bearer)
+		}
+		if key == "" {
+			return echo.NewHTTPError(http.StatusUnauthorized, "API key required")
+		}
+
+		name, err := g.keys.Verify(key)
+		if err != nil {
+			g.logger.Warn("invalid API key on scan submission",
+				zap.String("path", c.Path()),
+				zap.String("remote_addr", c.RealIP()))
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid API key")
+		}
+
+		g.logger.Info("scan submission authenticated by API key",
+			zap.String("api_key", name),
+			zap.String("path", c.Path()))
+		c.Set(apiKeyNameKey, name)
+		return next(c)
+	}
+}
+
+// requestAPIKeyName returns the name of the API key a request was authenticated with, nil without one
+func requestAPIKeyName(c echo.Context) *string {
+	if name, ok := c.Get(apiKeyNameKey).(string); ok {
+		return &name
+	}
+	return nil
+}
+
+// requireScanOfAPIKey restricts requests authenticated with an API key to the scans submitted with it.
+// API keys aren't scoped to namespaces, the scans of other keys are not found rather than forbidden so
+// that their IDs can't be probed
+func requireScanOfAPIKey(c echo.Context, scan *models.Scan) error {
+	name := requestAPIKeyName(c)
+	if name == nil || (scan.APIKeyName != nil && *scan.APIKeyName == *name) {
+		return nil
+	}
+	return echo.NewHTTPError(http.StatusNotFound, "scan not found")
+}
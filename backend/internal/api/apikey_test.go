@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAPIKeyGuard_RequireAPIKey(t *testing.T) {
+	key := strings.Repeat("k", 64)
+	keys, err := auth.ParseAPIKeys("github-actions=" + key)
+	require.NoError(t, err)
+	guard := NewAPIKeyGuard(zap.NewNop(), keys)
+	handler := guard.RequireAPIKey(func(c echo.Context) error { return c.NoContent(http.StatusCreated) })
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"bearer", map[string]string{echo.HeaderAuthorization: "Bearer " + key}, http.StatusCreated},
+		{"header", map[string]string{headerAPIKey: key}, http.StatusCreated},
+		{"missing", nil, http.StatusUnauthorized},
+		{"wrong", map[string]string{echo.HeaderAuthorization: "Bearer " + strings.Repeat("x", 64)}, http.StatusUnauthorized},
+		{"basic", map[string]string{echo.HeaderAuthorization: "Basic " + key}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/ci/scans", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			err := handler(echo.New().NewContext(req, rec))
+			if tt.want == http.StatusCreated {
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.want, httpErr.Code)
+		})
+	}
+}
+
+func TestRequireScanOfAPIKey(t *testing.T) {
+	key := strings.Repeat("k", 64)
+	keys, err := auth.ParseAPIKeys("github-actions=" + key)
+	require.NoError(t, err)
+	guard := NewAPIKeyGuard(zap.NewNop(), keys)
+
+	github, gitlab := "github-actions", "gitlab-ci"
+	tests := []struct {
+		name   string
+		apiKey bool
+		scan   models.Scan
+		found  bool
+	}{
+		{"without API key", false, models.Scan{APIKeyName: &gitlab}, true},
+		{"scan of the key", true, models.Scan{APIKeyName: &github}, true},
+		{"scan of another key", true, models.Scan{APIKeyName: &gitlab}, false},
+		{"scan submitted without API key", true, models.Scan{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/ci/scans/1/gate", nil)
+			handler := func(c echo.Context) error { return requireScanOfAPIKey(c, &tt.scan) }
+			if tt.apiKey {
+				req.Header.Set(headerAPIKey, key)
+				handler = guard.RequireAPIKey(handler)
+			}
+			err := handler(echo.New().NewContext(req, httptest.NewRecorder()))
+			if tt.found {
+				assert.NoError(t, err)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusNotFound, httpErr.Code)
+		})
+	}
+}
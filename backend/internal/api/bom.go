@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/sbom"
+	"github.com/invulnerable/backend/internal/storage"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// BOMHandler exports the software inventory of all images as a single SBOM
+type BOMHandler struct {
+	logger   *zap.Logger
+	sbomRepo *db.SBOMRepository
+}
+
+func NewBOMHandler(logger *zap.Logger, sbomRepo *db.SBOMRepository) *BOMHandler {
+	return &BOMHandler{
+		logger:   logger,
+		sbomRepo: sbomRepo,
+	}
+}
+
+// ExportBOM handles GET /api/v1/export/bom?image_name=acme/
+// It merges the latest SBOM of each image and target into one CycloneDX BOM, read from the
+// component index rather than the documents in S3
+func (h *BOMHandler) ExportBOM(c echo.Context) error {
+	if format := c.QueryParam("format"); format != "" && format != "cyclonedx" {
+		return echo.NewHTTPError(http.StatusBadRequest, "unsupported export format (expected cyclonedx)")
+	}
+	var imageName *string
+	if s := strings.TrimSpace(c.QueryParam("image_name")); s != "" {
+		imageName = &s
+	}
+
+	ctx := c.Request().Context()
+	scans, err := h.sbomRepo.ListLatestSBOMScans(ctx, imageName)
+	if err != nil {
+		h.logger.Error("failed to list SBOM scans", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export BOM")
+	}
+
+	byScan := make(map[int][]models.SBOMComponent, len(scans))
+	scanIDs := make([]int, 0, len(scans))
+	for _, scan := range scans {
+		// SBOMs stored before components were indexed at ingestion are indexed on first use
+		if scan.ComponentCount == nil {
+			if err := h.sbomRepo.IndexStoredDocument(ctx, scan.ScanID); err != nil {
+				h.logger.Warn("failed to index SBOM components", zap.Error(err), zap.Int("scan_id", scan.ScanID))
+				continue
+			}
+		}
+		byScan[scan.ScanID] = []models.SBOMComponent{}
+		scanIDs = append(scanIDs, scan.ScanID)
+	}
+
+	components, err := h.sbomRepo.ListComponents(ctx, scanIDs)
+	if err != nil {
+		h.logger.Error("failed to list SBOM components", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export BOM")
+	}
+	for _, component := range components {
+		byScan[component.ScanID] = append(byScan[component.ScanID], component.SBOMComponent)
+	}
+
+	images := make([]sbom.BOMImage, 0, len(scans))
+	for _, scan := range scans {
+		// Unreadable SBOMs are listed without packages, as an unknown composition
+		images = append(images, sbom.BOMImage{SBOMScan: scan, Components: byScan[scan.ScanID]})
+	}
+
+	document, err := json.Marshal(sbom.Merge(images, "urn:uuid:"+uuid.NewString(), time.Now()))
+	if err != nil {
+		h.logger.Error("failed to render BOM", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export BOM")
+	}
+
+	// The inventory of every image is large: compressed for clients that accept it
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="invulnerable-bom.cdx.json"`)
+	return streamDocument(c, h.logger, bytes.NewReader(document), storage.EncodingIdentity)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/sbom"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBOMHandler_ExportBOM(t *testing.T) {
+	scanHandler := newTestScanHandler(t)
+	handler := NewBOMHandler(zap.NewNop(), scanHandler.sbomRepo)
+
+	for image, document := range map[string]string{
+		"acme/api:1.0": `{"bomFormat":"CycloneDX","components":[
+			{"name":"log4j-core","version":"2.14.1","purl":"pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"}]}`,
+		"other/worker:1.0": `{"bomFormat":"CycloneDX","components":[
+			{"name":"busybox","version":"1.36.1","purl":"pkg:apk/alpine/busybox@1.36.1"}]}`,
+	} {
+		rec, err := doScanRequest(t, scanHandler.CreateScan, http.MethodPost, "/api/v1/scans", map[string]interface{}{
+			"image":        image,
+			"grype_result": json.RawMessage(`{"matches":[]}`),
+			"sbom":         json.RawMessage(document),
+			"sbom_format":  "cyclonedx",
+		}, "")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	rec, err := doScanRequest(t, handler.ExportBOM, http.MethodGet, "/api/v1/export/bom", nil, "")
+	require.NoError(t, err)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "invulnerable-bom.cdx.json")
+
+	var bom sbom.BOM
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bom))
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	require.Len(t, bom.Components, 2)
+	require.Len(t, bom.Compositions, 2)
+	assert.Equal(t, sbom.AggregateComplete, bom.Compositions[0].Aggregate)
+
+	// Filtered by image name
+	rec, err = doScanRequest(t, handler.ExportBOM, http.MethodGet, "/api/v1/export/bom?image_name=ACME/", nil, "")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bom))
+	require.Len(t, bom.Components, 1)
+	assert.Equal(t, "docker.io/acme/api:1.0", bom.Components[0].Name)
+	require.Len(t, bom.Components[0].Components, 1)
+	assert.Equal(t, "log4j-core", bom.Components[0].Components[0].Name)
+
+	_, err = doScanRequest(t, handler.ExportBOM, http.MethodGet, "/api/v1/export/bom?format=spdx", nil, "")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
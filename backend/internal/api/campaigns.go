@@ -0,0 +1,319 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxCampaignVulnerabilityIDs bounds the vulnerabilities attached in one request; larger
+// initiatives are better described by a filter
+const maxCampaignVulnerabilityIDs = 500
+
+// CampaignHandler manages remediation campaigns
+type CampaignHandler struct {
+	logger *zap.Logger
+	repo   *db.CampaignRepository
+}
+
+// NewCampaignHandler creates a campaign handler
+func NewCampaignHandler(logger *zap.Logger, repo *db.CampaignRepository) *CampaignHandler {
+	return &CampaignHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// ListCampaigns handles GET /api/v1/campaigns?status=active&owner=alice@example.com
+// Each campaign comes with its progress, for the management dashboard
+func (h *CampaignHandler) ListCampaigns(c echo.Context) error {
+	var status, owner *string
+	if st := c.QueryParam("status"); st != "" {
+		if !slices.Contains(models.ValidCampaignStatuses, st) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid status parameter")
+		}
+		status = &st
+	}
+	if o := c.QueryParam("owner"); o != "" {
+		owner = &o
+	}
+
+	ctx := c.Request().Context()
+	campaigns, err := h.repo.List(ctx, status, owner)
+	if err != nil {
+		h.logger.Error("failed to list campaigns", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list campaigns")
+	}
+
+	ids := make([]int, len(campaigns))
+	for i, campaign := range campaigns {
+		ids[i] = campaign.ID
+	}
+	progress, err := h.repo.Progress(ctx, ids)
+	if err != nil {
+		h.logger.Error("failed to compute campaign progress", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list campaigns")
+	}
+
+	response := make([]models.CampaignWithProgress, len(campaigns))
+	for i, campaign := range campaigns {
+		response[i] = models.CampaignWithProgress{Campaign: campaign, Progress: progress[campaign.ID]}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// CreateCampaign handles POST /api/v1/campaigns
+func (h *CampaignHandler) CreateCampaign(c echo.Context) error {
+	var req models.CampaignRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "name is required")
+	}
+	if len(req.Name) > 255 {
+		return echo.NewHTTPError(http.StatusBadRequest, "name must be at most 255 characters")
+	}
+
+	var filter models.CampaignFilter
+	if req.Filter != nil {
+		filter = models.CampaignFilter{
+			CVEID:                trimmedOrNil(req.Filter.CVEID),
+			PackageName:          trimmedOrNil(req.Filter.PackageName),
+			PackageVersionPrefix: trimmedOrNil(req.Filter.PackageVersionPrefix),
+			Severity:             trimmedOrNil(req.Filter.Severity),
+		}
+		if filter.Severity != nil {
+			severity := normalizeSeverity(*filter.Severity)
+			if severity == "Unknown" {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid filter severity (must be critical, high, medium, low or negligible)")
+			}
+			filter.Severity = &severity
+		}
+	}
+	if filter.IsEmpty() && len(req.VulnerabilityIDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "a filter or vulnerability_ids is required")
+	}
+	if len(req.VulnerabilityIDs) > maxCampaignVulnerabilityIDs {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("cannot attach more than %d vulnerabilities at once", maxCampaignVulnerabilityIDs))
+	}
+
+	user := getUserFromHeaders(c)
+	campaign := &models.Campaign{
+		Name:           req.Name,
+		Description:    req.Description,
+		Status:         models.CampaignStatusActive,
+		Owners:         normalizeOwners(req.Owners),
+		DueAt:          req.DueAt,
+		CampaignFilter: filter,
+		CreatedBy:      &user,
+	}
+	if err := h.repo.Create(c.Request().Context(), campaign, req.VulnerabilityIDs); err != nil {
+		h.logger.Error("failed to create campaign", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create campaign")
+	}
+
+	return c.JSON(http.StatusCreated, campaign)
+}
+
+// GetCampaign handles GET /api/v1/campaigns/:id - the campaign dashboard: progress and daily burndown
+func (h *CampaignHandler) GetCampaign(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid campaign ID")
+	}
+
+	ctx := c.Request().Context()
+	campaign, err := h.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	progress, err := h.repo.Progress(ctx, []int{id})
+	if err != nil {
+		h.logger.Error("failed to compute campaign progress", zap.Error(err), zap.Int("campaign_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get campaign")
+	}
+	burndown, err := h.repo.Burndown(ctx, campaign, time.Now())
+	if err != nil {
+		h.logger.Error("failed to compute campaign burndown", zap.Error(err), zap.Int("campaign_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get campaign")
+	}
+
+	return c.JSON(http.StatusOK, models.CampaignDetails{
+		CampaignWithProgress: models.CampaignWithProgress{Campaign: *campaign, Progress: progress[id]},
+		Burndown:             burndown,
+	})
+}
+
+// UpdateCampaign handles PATCH /api/v1/campaigns/:id
+func (h *CampaignHandler) UpdateCampaign(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid campaign ID")
+	}
+
+	var update models.CampaignUpdate
+	if err := c.Bind(&update); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" || len(name) > 255 {
+			return echo.NewHTTPError(http.StatusBadRequest, "name must be 1 to 255 characters")
+		}
+		update.Name = &name
+	}
+	if update.Status != nil && !slices.Contains(models.ValidCampaignStatuses, *update.Status) {
+		return echo.NewHTTPError(http.StatusBadRequest, "status must be active, completed or cancelled")
+	}
+	if update.Owners != nil {
+		owners := normalizeOwners(*update.Owners)
+		update.Owners = &owners
+	}
+
+	ctx := c.Request().Context()
+	if _, err := h.repo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	campaign, err := h.repo.Update(ctx, id, &update)
+	if err != nil {
+		h.logger.Error("failed to update campaign", zap.Error(err), zap.Int("campaign_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update campaign")
+	}
+
+	return c.JSON(http.StatusOK, campaign)
+}
+
+// DeleteCampaign handles DELETE /api/v1/campaigns/:id
+func (h *CampaignHandler) DeleteCampaign(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid campaign ID")
+	}
+
+	if err := h.repo.Delete(c.Request().Context(), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListCampaignVulnerabilities handles GET /api/v1/campaigns/:id/vulnerabilities?status=active
+func (h *CampaignHandler) ListCampaignVulnerabilities(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid campaign ID")
+	}
+
+	limit, offset := parsePagination(c, 50)
+
+	var status *string
+	if st := c.QueryParam("status"); st != "" {
+		if !slices.Contains(models.ValidStatuses, st) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid status parameter")
+		}
+		status = &st
+	}
+
+	ctx := c.Request().Context()
+	if _, err := h.repo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	total, err := h.repo.CountVulnerabilities(ctx, id, status)
+	if err != nil {
+		h.logger.Error("failed to count campaign vulnerabilities", zap.Error(err), zap.Int("campaign_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to count campaign vulnerabilities")
+	}
+
+	vulns, err := h.repo.ListVulnerabilities(ctx, id, status, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list campaign vulnerabilities", zap.Error(err), zap.Int("campaign_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list campaign vulnerabilities")
+	}
+
+	response := newPage(vulns, total, limit, offset)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// AddCampaignVulnerabilities handles POST /api/v1/campaigns/:id/vulnerabilities
+func (h *CampaignHandler) AddCampaignVulnerabilities(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid campaign ID")
+	}
+
+	var req struct {
+		VulnerabilityIDs []int `json:"vulnerability_ids"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if len(req.VulnerabilityIDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "no vulnerability IDs provided")
+	}
+	if len(req.VulnerabilityIDs) > maxCampaignVulnerabilityIDs {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("cannot attach more than %d vulnerabilities at once", maxCampaignVulnerabilityIDs))
+	}
+
+	ctx := c.Request().Context()
+	if _, err := h.repo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	added, err := h.repo.AddVulnerabilities(ctx, id, req.VulnerabilityIDs, getUserFromHeaders(c))
+	if err != nil {
+		h.logger.Error("failed to attach campaign vulnerabilities", zap.Error(err), zap.Int("campaign_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to attach vulnerabilities")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"added_count": added,
+	})
+}
+
+// RemoveCampaignVulnerability handles DELETE /api/v1/campaigns/:id/vulnerabilities/:vulnerability_id
+func (h *CampaignHandler) RemoveCampaignVulnerability(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid campaign ID")
+	}
+	vulnID, err := strconv.Atoi(c.Param("vulnerability_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid vulnerability ID")
+	}
+
+	if err := h.repo.RemoveVulnerability(c.Request().Context(), id, vulnID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// normalizeOwners trims owners and drops empty and duplicate entries, keeping their order
+func normalizeOwners(owners []string) []string {
+	normalized := []string{}
+	for _, owner := range owners {
+		owner = strings.TrimSpace(owner)
+		if owner != "" && !slices.Contains(normalized, owner) {
+			normalized = append(normalized, owner)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCampaignHandler_CreateCampaign_Validation(t *testing.T) {
+	// Invalid requests are rejected before reaching the repository
+	handler := NewCampaignHandler(zap.NewNop(), nil)
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want string
+	}{
+		{"missing name", map[string]interface{}{"name": " ", "vulnerability_ids": []int{1}}, "name"},
+		{"no selection", map[string]interface{}{"name": "OpenSSL 3.x upgrade", "filter": map[string]interface{}{"cve_id": " "}}, "filter or vulnerability_ids"},
+		{"invalid severity", map[string]interface{}{"name": "Criticals", "filter": map[string]interface{}{"severity": "urgent"}}, "severity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.CreateCampaign, http.MethodPost, "/api/v1/campaigns", tt.body, "")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.True(t, strings.Contains(httpErr.Message.(string), tt.want), httpErr.Message)
+		})
+	}
+}
+
+func TestNormalizeOwners(t *testing.T) {
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"},
+		normalizeOwners([]string{" alice@example.com", "", "bob@example.com", "alice@example.com"}))
+	assert.Equal(t, []string{}, normalizeOwners(nil))
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ClientCertGuard restricts endpoints to callers presenting a certificate issued by
+// TLS_CLIENT_CA_FILE: scanners, the controller and the ingress. With TLS_ALLOWED_SPIFFE_IDS the
+// certificate must also carry one of the SPIFFE IDs
+type ClientCertGuard struct {
+	logger *zap.Logger
+	ids    *auth.ClientIDs
+}
+
+// NewClientCertGuard creates a client certificate guard
+func NewClientCertGuard(logger *zap.Logger, ids *auth.ClientIDs) *ClientCertGuard {
+	return &ClientCertGuard{logger: logger, ids: ids}
+}
+
+// RequireClientCert is an Echo middleware rejecting callers without an allowed client certificate
+func (g *ClientCertGuard) RequireClientCert(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state := c.Request().TLS
+		if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+			return echo.NewHTTPError(http.StatusUnauthorized, "client certificate required")
+		}
+
+		identity, ok := g.ids.Verify(state.VerifiedChains[0][0])
+		if !ok {
+			g.logger.Warn("client certificate not allowed",
+				zap.String("client", identity),
+				zap.String("path", c.Path()),
+				zap.String("remote_addr", c.RealIP()))
+			return echo.NewHTTPError(http.StatusForbidden, "client certificate not allowed")
+		}
+		return next(c)
+	}
+}
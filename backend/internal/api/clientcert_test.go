@@ -0,0 +1,56 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClientCertGuard_RequireClientCert(t *testing.T) {
+	ids, err := auth.ParseClientIDs("spiffe://cluster.local/ns/invulnerable/*")
+	require.NoError(t, err)
+	guard := NewClientCertGuard(zap.NewNop(), ids)
+	handler := guard.RequireClientCert(func(c echo.Context) error { return c.NoContent(http.StatusCreated) })
+
+	verified := func(spiffeID string) *tls.ConnectionState {
+		u, err := url.Parse(spiffeID)
+		require.NoError(t, err)
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{URIs: []*url.URL{u}}}}}
+	}
+
+	tests := []struct {
+		name  string
+		state *tls.ConnectionState
+		want  int
+	}{
+		{"allowed", verified("spiffe://cluster.local/ns/invulnerable/sa/scanner"), http.StatusCreated},
+		{"other SPIFFE ID", verified("spiffe://cluster.local/ns/default/sa/default"), http.StatusForbidden},
+		{"no certificate", &tls.ConnectionState{}, http.StatusUnauthorized},
+		{"plain HTTP", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/scans", nil)
+			req.TLS = tt.state
+			rec := httptest.NewRecorder()
+			err := handler(echo.New().NewContext(req, rec))
+			if tt.want == http.StatusCreated {
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				return
+			}
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, tt.want, httpErr.Code)
+		})
+	}
+}
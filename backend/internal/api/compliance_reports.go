@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/reports"
+	"github.com/invulnerable/backend/internal/storage"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxReportPeriodDays bounds the period of a report on demand
+const maxReportPeriodDays = 366
+
+// ComplianceReportStore reads the generated compliance reports
+type ComplianceReportStore interface {
+	GetByID(ctx context.Context, id int64) (*models.ComplianceReport, error)
+	List(ctx context.Context, schedule *string, limit, offset int) ([]models.ComplianceReport, error)
+	Count(ctx context.Context, schedule *string) (int, error)
+}
+
+// ComplianceReportHandler generates compliance reports on demand and serves the generated ones
+type ComplianceReportHandler struct {
+	logger    *zap.Logger
+	store     ComplianceReportStore
+	generator *reports.Generator
+	objects   storage.ObjectStorage
+}
+
+func NewComplianceReportHandler(logger *zap.Logger, store ComplianceReportStore, generator *reports.Generator, objects storage.ObjectStorage) *ComplianceReportHandler {
+	return &ComplianceReportHandler{
+		logger:    logger,
+		store:     store,
+		generator: generator,
+		objects:   objects,
+	}
+}
+
+// GenerateReportRequest asks for a report covering the period_days before now. With a schedule
+// the report is a report of the schedule, delivered to its destinations, and the format and
+// period default to its own; without one it is only stored
+type GenerateReportRequest struct {
+	Schedule   string `json:"schedule,omitempty"`
+	Format     string `json:"format,omitempty"`
+	PeriodDays int    `json:"period_days,omitempty"`
+}
+
+// GenerateReport handles POST /api/v1/reports
+func (h *ComplianceReportHandler) GenerateReport(c echo.Context) error {
+	var req GenerateReportRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	request := reports.Request{Format: models.ReportFormatHTML}
+	periodDays := reports.DefaultPeriodDays
+	if req.Schedule != "" {
+		schedule, ok := h.generator.Schedule(req.Schedule)
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "unknown report schedule: "+req.Schedule)
+		}
+		request.Schedule = schedule
+		request.Format = schedule.Format
+		periodDays = schedule.PeriodDays
+	}
+	if req.Format != "" {
+		if err := reports.ValidateFormat(req.Format); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		request.Format = req.Format
+	}
+	if req.PeriodDays != 0 {
+		if req.PeriodDays < 1 || req.PeriodDays > maxReportPeriodDays {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid period_days, expected 1 to 366")
+		}
+		periodDays = req.PeriodDays
+	}
+
+	now := time.Now().UTC()
+	user := getUserFromHeaders(c)
+	request.PeriodEnd = now
+	request.PeriodStart = now.AddDate(0, 0, -periodDays)
+	request.RequestedBy = &user
+
+	report, err := h.generator.Generate(c.Request().Context(), request, now)
+	if err != nil {
+		h.logger.Error("failed to generate compliance report", zap.Error(err), zap.String("schedule", req.Schedule))
+		return err
+	}
+	return c.JSON(http.StatusCreated, report)
+}
+
+// ListReports handles GET /api/v1/reports?schedule=weekly
+func (h *ComplianceReportHandler) ListReports(c echo.Context) error {
+	var schedule *string
+	if s := strings.TrimSpace(c.QueryParam("schedule")); s != "" {
+		schedule = &s
+	}
+	limit, offset := parsePagination(c, 20)
+
+	ctx := c.Request().Context()
+	list, err := h.store.List(ctx, schedule, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to list compliance reports", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list reports")
+	}
+	total, err := h.store.Count(ctx, schedule)
+	if err != nil {
+		h.logger.Error("failed to count compliance reports", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list reports")
+	}
+	return c.JSON(http.StatusOK, newPage(list, total, limit, offset))
+}
+
+// GetReport handles GET /api/v1/reports/:id
+func (h *ComplianceReportHandler) GetReport(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid report ID")
+	}
+	report, err := h.store.GetByID(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// GetReportDocument handles GET /api/v1/reports/:id/document
+func (h *ComplianceReportHandler) GetReportDocument(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid report ID")
+	}
+
+	ctx := c.Request().Context()
+	report, err := h.store.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if report.StorageKey == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "report document was not stored")
+	}
+	document, err := h.objects.Get(ctx, *report.StorageKey)
+	if err != nil {
+		h.logger.Error("failed to retrieve compliance report", zap.Error(err), zap.Int64("report_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve report document")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		mime.FormatMediaType("attachment", map[string]string{"filename": reports.FileName(report)}))
+	return c.Blob(http.StatusOK, reports.ContentType(report.Format), document)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/reports"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestComplianceReportHandler_GenerateReport_Validation(t *testing.T) {
+	// Invalid requests are rejected before generating anything
+	schedules := []reports.Schedule{{Name: "weekly", Cron: "@weekly"}}
+	require.NoError(t, reports.Validate(schedules))
+	handler := NewComplianceReportHandler(zap.NewNop(), nil, reports.NewGenerator(zap.NewNop(), nil, nil, nil, schedules), nil)
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want string
+	}{
+		{"unknown schedule", map[string]interface{}{"schedule": "daily"}, "unknown report schedule"},
+		{"invalid format", map[string]interface{}{"format": "docx"}, "invalid format"},
+		{"period too long", map[string]interface{}{"schedule": "weekly", "period_days": 400}, "period_days"},
+		{"negative period", map[string]interface{}{"period_days": -1}, "period_days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.GenerateReport, http.MethodPost, "/api/v1/reports", tt.body, "")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.True(t, strings.Contains(httpErr.Message.(string), tt.want), httpErr.Message)
+		})
+	}
+}
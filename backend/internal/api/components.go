@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ComponentHandler serves PURL-based package queries
+type ComponentHandler struct {
+	logger *zap.Logger
+	repo   *db.ComponentRepository
+}
+
+func NewComponentHandler(logger *zap.Logger, repo *db.ComponentRepository) *ComponentHandler {
+	return &ComponentHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// ListComponents handles GET /api/v1/components?purl=pkg:npm/lodash
+// It returns the matching packages with their vulnerabilities and the images they were found in
+func (h *ComponentHandler) ListComponents(c echo.Context) error {
+	purl := strings.TrimSpace(c.QueryParam("purl"))
+	if purl == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "purl is required")
+	}
+	if !strings.HasPrefix(purl, "pkg:") || !strings.Contains(purl, "/") {
+		return echo.NewHTTPError(http.StatusBadRequest, "purl must be a package URL (pkg:type/name[@version])")
+	}
+
+	components, err := h.repo.ListByPURL(c.Request().Context(), purl)
+	if err != nil {
+		h.logger.Error("failed to list components", zap.Error(err), zap.String("purl", purl))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list components")
+	}
+
+	return c.JSON(http.StatusOK, components)
+}
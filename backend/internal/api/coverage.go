@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxInventoryImages bounds a single inventory upload, enough for the Pods of a large cluster
+const maxInventoryImages = 50000
+
+// inventorySourcePattern are the names of inventory sources, used in the URL
+var inventorySourcePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9._]{0,62}[a-z0-9])?$`)
+
+// CoverageStore is the persistence used by the coverage handler
+type CoverageStore interface {
+	Replace(ctx context.Context, source string, images []models.DeployedImage) error
+	ListCoverage(ctx context.Context, namespace string) ([]models.ImageCoverage, error)
+	ListVulnerabilityWorkloads(ctx context.Context, vulnerabilityID int) (*models.VulnerabilityWorkloads, error)
+}
+
+// CoverageHandler reports which deployed images are scanned, from the inventories of deployed images
+// reported by the controller's Pod discovery or uploaded
+type CoverageHandler struct {
+	logger *zap.Logger
+	store  CoverageStore
+	// staleThreshold is the age from which the latest scan of a deployed image is stale
+	staleThreshold time.Duration
+}
+
+func NewCoverageHandler(logger *zap.Logger, store CoverageStore, staleThreshold time.Duration) *CoverageHandler {
+	return &CoverageHandler{
+		logger:         logger,
+		store:          store,
+		staleThreshold: staleThreshold,
+	}
+}
+
+// ReplaceInventory handles PUT /api/v1/inventory/:source
+// The images replace the previous inventory of the source
+func (h *CoverageHandler) ReplaceInventory(c echo.Context) error {
+	source := c.Param("source")
+	if !inventorySourcePattern.MatchString(source) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid source, expected lowercase alphanumeric characters, '-', '.' or '_'")
+	}
+
+	var req models.InventoryRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if len(req.Images) > maxInventoryImages {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("inventory has more than %d images", maxInventoryImages))
+	}
+
+	// An image listed several times in a namespace is stored once, with the workloads of every entry
+	images := make([]models.DeployedImage, 0, len(req.Images))
+	seen := make(map[[2]string]int, len(req.Images))
+	for i, image := range req.Images {
+		image.Image = strings.TrimSpace(image.Image)
+		if image.Image == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("images[%d]: image is required", i))
+		}
+		for _, w := range image.Workloads {
+			if w.Kind == "" || w.Name == "" || w.Replicas < 0 {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("images[%d]: workloads need a kind, a name and non-negative replicas", i))
+			}
+		}
+
+		key := [2]string{image.Namespace, image.Image}
+		if j, ok := seen[key]; ok {
+			images[j].Workloads = mergeWorkloads(images[j].Workloads, image.Workloads)
+			continue
+		}
+		seen[key] = len(images)
+		registry, repository, tag, digest := parseImageReference(image.Image)
+		images = append(images, models.DeployedImage{
+			Source:     source,
+			Namespace:  image.Namespace,
+			Image:      image.Image,
+			Registry:   registry,
+			Repository: repository,
+			Tag:        tag,
+			Digest:     digest,
+			Workloads:  mergeWorkloads(nil, image.Workloads),
+		})
+	}
+
+	if err := h.store.Replace(c.Request().Context(), source, images); err != nil {
+		h.logger.Error("failed to replace inventory", zap.Error(err), zap.String("source", source))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to replace inventory")
+	}
+
+	h.logger.Info("inventory replaced", zap.String("source", source), zap.Int("images", len(images)))
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"source": source,
+		"images": len(images),
+	})
+}
+
+// GetVulnerabilityWorkloads handles GET /api/v1/vulnerabilities/:id/workloads
+// It returns the deployed workloads running an image whose latest successful scan has the vulnerability
+func (h *CoverageHandler) GetVulnerabilityWorkloads(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid vulnerability ID")
+	}
+
+	workloads, err := h.store.ListVulnerabilityWorkloads(c.Request().Context(), id)
+	if err != nil {
+		h.logger.Error("failed to list vulnerability workloads", zap.Error(err), zap.Int("vulnerability_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list vulnerability workloads")
+	}
+	if workloads == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "vulnerability not found")
+	}
+
+	return c.JSON(http.StatusOK, workloads)
+}
+
+// GetCoverage handles GET /api/v1/coverage?namespace=payments&status=unscanned
+// It returns the deployed images with their latest successful scan, the blind spots first
+func (h *CoverageHandler) GetCoverage(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status != "" && status != models.CoverageScanned && status != models.CoverageStale && status != models.CoverageUnscanned {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid status, expected scanned, stale or unscanned")
+	}
+
+	images, err := h.store.ListCoverage(c.Request().Context(), c.QueryParam("namespace"))
+	if err != nil {
+		h.logger.Error("failed to list coverage", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get coverage")
+	}
+
+	now := time.Now()
+	summary := summarizeCoverage(images, now.Add(-h.staleThreshold))
+	sort.SliceStable(images, func(i, j int) bool {
+		return coverageRank[images[i].Status] < coverageRank[images[j].Status]
+	})
+	filtered := make([]models.ImageCoverage, 0, len(images))
+	for _, image := range images {
+		if status == "" || image.Status == status {
+			filtered = append(filtered, image)
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"generated_at":      now.UTC(),
+		"stale_after_hours": int(h.staleThreshold.Hours()),
+		"summary":           summary,
+		"images":            filtered,
+	})
+}
+
+// coverageRank lists deployed images unscanned first, then stale
+var coverageRank = map[string]int{models.CoverageUnscanned: 0, models.CoverageStale: 1, models.CoverageScanned: 2}
+
+// summarizeCoverage sets the status of each deployed image, scans before staleBefore being stale, and counts them
+func summarizeCoverage(images []models.ImageCoverage, staleBefore time.Time) models.CoverageSummary {
+	summary := models.CoverageSummary{Deployed: len(images)}
+	for i := range images {
+		image := &images[i]
+		switch {
+		case image.LastScanDate == nil:
+			image.Status = models.CoverageUnscanned
+			summary.Unscanned++
+		case image.LastScanDate.Before(staleBefore):
+			image.Status = models.CoverageStale
+			summary.Stale++
+		default:
+			image.Status = models.CoverageScanned
+			summary.Scanned++
+		}
+		if !image.HasImageScan {
+			summary.WithoutImageScan++
+		}
+	}
+
+	summary.CoveragePercent = 100
+	if summary.Deployed > 0 {
+		summary.CoveragePercent = math.Round(float64(summary.Scanned)*1000/float64(summary.Deployed)) / 10
+	}
+	return summary
+}
+
+// mergeWorkloads adds workloads to merged, summing the replicas of the same workload, exposed when any entry is
+func mergeWorkloads(merged, workloads []models.InventoryWorkload) []models.InventoryWorkload {
+	for _, w := range workloads {
+		found := false
+		for i := range merged {
+			if merged[i].Kind == w.Kind && merged[i].Name == w.Name {
+				merged[i].Replicas += w.Replicas
+				merged[i].Exposed = merged[i].Exposed || w.Exposed
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, w)
+		}
+	}
+	return merged
+}
+
+// parseImageReference parses an image reference as Pods use them, which may be pinned by digest
+// (nginx:1.25@sha256:..., nginx@sha256:...). References pinned by digest only have an empty tag
+func parseImageReference(image string) (registry, repository, tag string, digest *string) {
+	ref, pinned, hasDigest := strings.Cut(image, "@")
+	registry, repository, tag = parseImageName(ref)
+	if hasDigest {
+		digest = &pinned
+		if !strings.Contains(ref[strings.LastIndex(ref, "/")+1:], ":") {
+			tag = ""
+		}
+	}
+	return registry, repository, tag, digest
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeCoverageStore struct {
+	inventories map[string][]models.DeployedImage
+	coverage    []models.ImageCoverage
+	namespace   string
+	workloads   map[int]*models.VulnerabilityWorkloads
+}
+
+func (s *fakeCoverageStore) Replace(ctx context.Context, source string, images []models.DeployedImage) error {
+	s.inventories[source] = images
+	return nil
+}
+
+func (s *fakeCoverageStore) ListCoverage(ctx context.Context, namespace string) ([]models.ImageCoverage, error) {
+	s.namespace = namespace
+	return append([]models.ImageCoverage{}, s.coverage...), nil
+}
+
+func (s *fakeCoverageStore) ListVulnerabilityWorkloads(ctx context.Context, vulnerabilityID int) (*models.VulnerabilityWorkloads, error) {
+	return s.workloads[vulnerabilityID], nil
+}
+
+func newCoverageTestServer(store CoverageStore) *echo.Echo {
+	handler := NewCoverageHandler(zap.NewNop(), store, 48*time.Hour)
+
+	e := echo.New()
+	e.PUT("/api/v1/inventory/:source", handler.ReplaceInventory)
+	e.GET("/api/v1/coverage", handler.GetCoverage)
+	e.GET("/api/v1/vulnerabilities/:id/workloads", handler.GetVulnerabilityWorkloads)
+	return e
+}
+
+func TestCoverageHandler_ReplaceInventory(t *testing.T) {
+	store := &fakeCoverageStore{inventories: map[string][]models.DeployedImage{}}
+	e := newCoverageTestServer(store)
+
+	body := `{"images": [
+		{"image": "nginx:1.25", "namespace": "web", "workloads": [{"kind": "Deployment", "name": "nginx", "replicas": 2}]},
+		{"image": "nginx:1.25", "namespace": "web", "workloads": [
+			{"kind": "Deployment", "name": "nginx", "replicas": 1},
+			{"kind": "StatefulSet", "name": "cache", "replicas": 3}
+		]},
+		{"image": "ghcr.io/acme/api@sha256:abc", "namespace": "payments"},
+		{"image": "ghcr.io/acme/worker:2.0@sha256:def"}
+	]}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/inventory/cmdb", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	images := store.inventories["cmdb"]
+	require.Len(t, images, 3, "duplicates are reported once")
+	assert.Equal(t, "docker.io", images[0].Registry)
+	assert.Equal(t, "1.25", images[0].Tag)
+	assert.Equal(t, []models.InventoryWorkload{
+		{Kind: "Deployment", Name: "nginx", Replicas: 3},
+		{Kind: "StatefulSet", Name: "cache", Replicas: 3},
+	}, images[0].Workloads)
+	assert.Equal(t, "acme/api", images[1].Repository)
+	assert.Empty(t, images[1].Tag, "references pinned by digest only have no tag")
+	require.NotNil(t, images[1].Digest)
+	assert.Equal(t, "sha256:abc", *images[1].Digest)
+	assert.Equal(t, "2.0", images[2].Tag)
+	assert.Empty(t, images[2].Namespace)
+
+	for _, path := range []string{"/api/v1/inventory/CMDB", "/api/v1/inventory/cmdb-"} {
+		req = httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"images": []}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, path)
+	}
+
+	for _, body := range []string{
+		`{"images": [{"image": " "}]}`,
+		`{"images": [{"image": "nginx:1.25", "workloads": [{"kind": "Deployment", "replicas": 1}]}]}`,
+	} {
+		req = httptest.NewRequest(http.MethodPut, "/api/v1/inventory/cmdb", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func TestCoverageHandler_GetCoverage(t *testing.T) {
+	recent := time.Now().Add(-time.Hour)
+	old := time.Now().Add(-72 * time.Hour)
+	imageID := 1
+	store := &fakeCoverageStore{coverage: []models.ImageCoverage{
+		{Registry: "docker.io", Repository: "nginx", Tag: "1.25", ImageID: &imageID, LastScanDate: &recent, HasImageScan: true},
+		{Registry: "docker.io", Repository: "redis", Tag: "7.2", ImageID: &imageID, LastScanDate: &old},
+		{Registry: "ghcr.io", Repository: "acme/api", Tag: "1.0"},
+	}}
+	e := newCoverageTestServer(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/coverage?namespace=web", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "web", store.namespace)
+
+	var report struct {
+		Summary models.CoverageSummary `json:"summary"`
+		Images  []models.ImageCoverage `json:"images"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, models.CoverageSummary{Deployed: 3, Scanned: 1, Stale: 1, Unscanned: 1, WithoutImageScan: 2, CoveragePercent: 33.3}, report.Summary)
+	require.Len(t, report.Images, 3)
+	assert.Equal(t, models.CoverageUnscanned, report.Images[0].Status, "blind spots come first")
+	assert.Equal(t, models.CoverageStale, report.Images[1].Status)
+	assert.Equal(t, models.CoverageScanned, report.Images[2].Status)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/coverage?status=unscanned", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 3, report.Summary.Deployed, "the summary covers every deployed image")
+	require.Len(t, report.Images, 1)
+	assert.Equal(t, "acme/api", report.Images[0].Repository)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/coverage?status=missing", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCoverageHandler_GetVulnerabilityWorkloads(t *testing.T) {
+	store := &fakeCoverageStore{workloads: map[int]*models.VulnerabilityWorkloads{
+		7: {VulnerabilityID: 7, CVEID: "CVE-2024-0001", Namespaces: 1, Replicas: 2, Workloads: []models.Workload{
+			{Namespace: "web", Kind: "Deployment", Name: "nginx", Replicas: 2, Image: "nginx:1.25", ImageID: 1, Source: "controller"},
+		}},
+	}}
+	e := newCoverageTestServer(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/vulnerabilities/7/workloads", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got models.VulnerabilityWorkloads
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "CVE-2024-0001", got.CVEID)
+	require.Len(t, got.Workloads, 1)
+	assert.Equal(t, "nginx", got.Workloads[0].Name)
+
+	for path, code := range map[string]int{
+		"/api/v1/vulnerabilities/8/workloads":   http.StatusNotFound,
+		"/api/v1/vulnerabilities/abc/workloads": http.StatusBadRequest,
+	} {
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, code, rec.Code, path)
+	}
+}
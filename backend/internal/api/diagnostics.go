@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"maps"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// DBStatsSource reports the connection pool, implemented by db.Database
+type DBStatsSource interface {
+	Stats() sql.DBStats
+}
+
+// QueueDepth reports how many items wait in a work queue
+type QueueDepth func(ctx context.Context) (int, error)
+
+// DiagnosticsHandler reports the runtime of the replica serving the request
+type DiagnosticsHandler struct {
+	logger    *zap.Logger
+	instance  string
+	startedAt time.Time
+	database  DBStatsSource
+
+	mu     sync.Mutex
+	queues map[string]QueueDepth
+}
+
+func NewDiagnosticsHandler(logger *zap.Logger, instance string, database DBStatsSource) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		logger:    logger,
+		instance:  instance,
+		startedAt: time.Now(),
+		database:  database,
+		queues:    make(map[string]QueueDepth),
+	}
+}
+
+// SetQueue reports the depth of a work queue under name in the snapshots
+func (h *DiagnosticsHandler) SetQueue(name string, depth QueueDepth) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.queues[name] = depth
+}
+
+// Snapshot reads the runtime, the connection pool and the queue depths
+func (h *DiagnosticsHandler) Snapshot(ctx context.Context) models.Diagnostics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	d := models.Diagnostics{
+		Instance:      h.instance,
+		GoVersion:     runtime.Version(),
+		StartedAt:     h.startedAt,
+		UptimeSeconds: int64(time.Since(h.startedAt).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		Memory: models.DiagnosticsMemory{
+			HeapAllocBytes:   mem.HeapAlloc,
+			HeapInuseBytes:   mem.HeapInuse,
+			HeapObjects:      mem.HeapObjects,
+			SysBytes:         mem.Sys,
+			NumGC:            mem.NumGC,
+			PauseTotalMillis: float64(mem.PauseTotalNs) / float64(time.Millisecond),
+		},
+		Queues: make(map[string]int),
+	}
+	if mem.LastGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC))
+		d.Memory.LastGC = &lastGC
+	}
+	if h.database != nil {
+		stats := h.database.Stats()
+		d.Database = models.DiagnosticsDatabase{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitMillis:         float64(stats.WaitDuration) / float64(time.Millisecond),
+		}
+	}
+
+	h.mu.Lock()
+	queues := maps.Clone(h.queues)
+	h.mu.Unlock()
+	for name, depth := range queues {
+		n, err := depth(ctx)
+		if err != nil {
+			if d.Errors == nil {
+				d.Errors = make(map[string]string)
+			}
+			d.Errors[name] = err.Error()
+			continue
+		}
+		d.Queues[name] = n
+	}
+	return d
+}
+
+// GetDiagnostics handles GET /api/v1/admin/diagnostics
+func (h *DiagnosticsHandler) GetDiagnostics(c echo.Context) error {
+	d := h.Snapshot(c.Request().Context())
+	for name, err := range d.Errors {
+		h.logger.Warn("failed to get queue depth", zap.String("queue", name), zap.String("error", err))
+	}
+	return c.JSON(http.StatusOK, d)
+}
+
+// DebugHandler serves net/http/pprof under /debug/pprof/ and expvar under /debug/vars, with the
+// snapshot published as the diagnostics variable. It has no authentication: serve it on a port
+// only reachable from the pod, e.g. with kubectl port-forward
+func (h *DiagnosticsHandler) DebugHandler() http.Handler {
+	expvarOnce.Do(func() {
+		expvar.Publish("diagnostics", expvar.Func(func() any {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return h.Snapshot(ctx)
+		}))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// expvarOnce publishes the diagnostics variable once, expvar panics on duplicate names
+var expvarOnce sync.Once
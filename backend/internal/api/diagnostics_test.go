@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeDBStats sql.DBStats
+
+func (s fakeDBStats) Stats() sql.DBStats {
+	return sql.DBStats(s)
+}
+
+func newTestDiagnosticsHandler() *DiagnosticsHandler {
+	h := NewDiagnosticsHandler(zap.NewNop(), "backend-0", fakeDBStats{MaxOpenConnections: 25, OpenConnections: 7, InUse: 5, Idle: 2, WaitCount: 3})
+	h.SetQueue("notification_outbox", func(context.Context) (int, error) { return 12, nil })
+	h.SetQueue("broken", func(context.Context) (int, error) { return 0, errors.New("connection refused") })
+	return h
+}
+
+func TestDiagnosticsHandler_GetDiagnostics(t *testing.T) {
+	h := newTestDiagnosticsHandler()
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/admin/diagnostics", nil), rec)
+	require.NoError(t, h.GetDiagnostics(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var d models.Diagnostics
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &d))
+	assert.Equal(t, "backend-0", d.Instance)
+	assert.Positive(t, d.Goroutines)
+	assert.NotZero(t, d.Memory.HeapAllocBytes)
+	assert.Equal(t, models.DiagnosticsDatabase{MaxOpenConnections: 25, OpenConnections: 7, InUse: 5, Idle: 2, WaitCount: 3}, d.Database)
+	assert.Equal(t, map[string]int{"notification_outbox": 12}, d.Queues)
+	assert.Equal(t, map[string]string{"broken": "connection refused"}, d.Errors)
+}
+
+func TestDiagnosticsHandler_DebugHandler(t *testing.T) {
+	server := httptest.NewServer(newTestDiagnosticsHandler().DebugHandler())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/debug/pprof/")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = http.Get(server.URL + "/debug/vars")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	var vars struct {
+		Diagnostics models.Diagnostics `json:"diagnostics"`
+		MemStats    json.RawMessage    `json:"memstats"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&vars))
+	assert.Equal(t, "backend-0", vars.Diagnostics.Instance)
+	assert.NotEmpty(t, vars.MemStats)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/storage"
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const encodingGzip = "gzip"
+
+// negotiateEncoding picks the content encoding of a response from the Accept-Encoding header:
+// the offered encoding with the highest quality, the first offered on ties. Identity (no encoding,
+// returned as storage.EncodingIdentity) is the fallback, even when the client refuses it
+func negotiateEncoding(acceptEncoding string, offered ...string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		qualities[name] = q
+	}
+
+	best, bestQ := storage.EncodingIdentity, 0.0
+	for _, encoding := range offered {
+		q, ok := qualities[encoding]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// streamDocument sends a stored JSON document in the encoding the client prefers. A document
+// already stored in that encoding is streamed through without being decompressed
+func streamDocument(c echo.Context, logger *zap.Logger, body io.Reader, stored string) error {
+	offered := []string{storage.EncodingZstd, encodingGzip}
+	if stored != storage.EncodingIdentity {
+		// Preferred on ties, it costs nothing to send
+		offered = append([]string{stored}, offered...)
+	}
+	encoding := negotiateEncoding(c.Request().Header.Get(echo.HeaderAcceptEncoding), offered...)
+
+	var content io.Reader = body
+	if encoding != stored {
+		decoded, err := storage.NewDecodingReader(body, stored)
+		if err != nil {
+			logger.Error("failed to decompress stored document", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to read document")
+		}
+		defer decoded.Close()
+		content = decoded
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+	if encoding != storage.EncodingIdentity {
+		res.Header().Set(echo.HeaderContentEncoding, encoding)
+	}
+	res.WriteHeader(http.StatusOK)
+
+	var w io.Writer = res
+	var closer io.Closer
+	if encoding != stored {
+		switch encoding {
+		case storage.EncodingZstd:
+			zw, _ := zstd.NewWriter(res)
+			w, closer = zw, zw
+		case encodingGzip:
+			gw := gzip.NewWriter(res)
+			w, closer = gw, gw
+		}
+	}
+
+	// The status is sent, failures can only be logged
+	if _, err := io.Copy(w, content); err != nil {
+		logger.Warn("failed to send document", zap.Error(err))
+		return nil
+	}
+	if closer != nil {
+		if err := closer.Close(); err != nil {
+			logger.Warn("failed to send document", zap.Error(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/storage"
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", storage.EncodingIdentity},
+		{"gzip, deflate, br", "gzip"},
+		{"gzip, deflate, br, zstd", "zstd"},
+		{"zstd;q=0.5, gzip", "gzip"},
+		{"ZSTD", "zstd"},
+		{"*", "zstd"},
+		{"*;q=0.5, gzip;q=0.8", "gzip"},
+		{"zstd;q=0, gzip;q=0", storage.EncodingIdentity},
+		{"br", storage.EncodingIdentity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.want, negotiateEncoding(tt.accept, storage.EncodingZstd, encodingGzip))
+		})
+	}
+}
+
+func TestStreamDocument(t *testing.T) {
+	document := bytes.Repeat([]byte(`{"bomFormat":"CycloneDX","components":[]}`), 100)
+	enc, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	compressed := enc.EncodeAll(document, nil)
+
+	stream := func(accept string, stored []byte, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/scans/1/sbom", nil)
+		if accept != "" {
+			req.Header.Set(echo.HeaderAcceptEncoding, accept)
+		}
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		require.NoError(t, streamDocument(c, zap.NewNop(), bytes.NewReader(stored), encoding))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+		return rec
+	}
+
+	t.Run("stored compressed, passed through", func(t *testing.T) {
+		rec := stream("gzip, zstd", compressed, storage.EncodingZstd)
+		assert.Equal(t, "zstd", rec.Header().Get(echo.HeaderContentEncoding))
+		assert.Equal(t, compressed, rec.Body.Bytes())
+	})
+
+	t.Run("stored compressed, client without zstd", func(t *testing.T) {
+		rec := stream("gzip", compressed, storage.EncodingZstd)
+		assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+		r, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, document, body)
+	})
+
+	t.Run("stored compressed, client without compression", func(t *testing.T) {
+		rec := stream("", compressed, storage.EncodingZstd)
+		assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+		assert.Equal(t, document, rec.Body.Bytes())
+	})
+
+	t.Run("stored uncompressed", func(t *testing.T) {
+		rec := stream("zstd", document, storage.EncodingIdentity)
+		assert.Equal(t, "zstd", rec.Header().Get(echo.HeaderContentEncoding))
+		dec, err := zstd.NewReader(nil)
+		require.NoError(t, err)
+		body, err := dec.DecodeAll(rec.Body.Bytes(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, document, body)
+
+		rec = stream("", document, storage.EncodingIdentity)
+		assert.Equal(t, document, rec.Body.Bytes())
+	})
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// httpError maps the errors of repositories to HTTP errors, with the message of the error:
+// db.ErrNotFound to 404, db.ErrConflict to 409 and db.ErrValidation to 400. Other errors are
+// returned as they are
+func httpError(err error) error {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return err
+	}
+	for kind, code := range map[error]int{
+		db.ErrNotFound:   http.StatusNotFound,
+		db.ErrConflict:   http.StatusConflict,
+		db.ErrValidation: http.StatusBadRequest,
+	} {
+		if errors.Is(err, kind) {
+			return echo.NewHTTPError(code, err.Error()).SetInternal(err)
+		}
+	}
+	return err
+}
+
+// HTTPErrorHandler is the Echo error handler. Handlers can return repository errors as they are,
+// they are responded to with their status; any other error that isn't an echo.HTTPError is a 500
+// without details
+func HTTPErrorHandler(e *echo.Echo) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		e.DefaultHTTPErrorHandler(httpError(err), c)
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler(e)
+	e.GET("/report", func(c echo.Context) error { return db.ErrReportExists })
+	e.GET("/status", func(c echo.Context) error { return db.ValidateScanStatus("done") })
+	e.GET("/wrapped", func(c echo.Context) error { return fmt.Errorf("failed to load: %w", db.ErrNotFound) })
+	e.GET("/http", func(c echo.Context) error { return echo.NewHTTPError(http.StatusTeapot, "teapot") })
+	e.GET("/internal", func(c echo.Context) error { return errors.New("connection refused") })
+
+	for path, want := range map[string]struct {
+		code int
+		body string
+	}{
+		"/report":   {http.StatusConflict, `{"message":"scan report already exists"}`},
+		"/status":   {http.StatusBadRequest, `{"message":"` + db.ValidateScanStatus("done").Error() + `"}`},
+		"/wrapped":  {http.StatusNotFound, `{"message":"failed to load: not found"}`},
+		"/http":     {http.StatusTeapot, `{"message":"teapot"}`},
+		"/internal": {http.StatusInternalServerError, `{"message":"Internal Server Error"}`},
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want.code, rec.Code, path)
+		assert.JSONEq(t, want.body, rec.Body.String(), path)
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	assert.Nil(t, httpError(nil))
+
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, httpError(fmt.Errorf("campaign %w", db.ErrNotFound)), &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	assert.ErrorIs(t, httpErr, db.ErrNotFound, "the repository error is kept as the internal error")
+
+	internal := errors.New("connection refused")
+	assert.Same(t, internal, httpError(internal))
+}
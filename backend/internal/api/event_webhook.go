@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/invulnerable/backend/internal/eventhook"
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// SetEventWebhook sends scan.completed events, and policy.failed ones for scans failing the severity
+// gate at failOn, to webhook
+func (h *ScanHandler) SetEventWebhook(webhook *eventhook.Webhook, failOn string) error {
+	severity := normalizeSeverity(failOn)
+	if severity == "Unknown" {
+		return fmt.Errorf("invalid fail_on severity %q (must be critical, high, medium, low or negligible)", failOn)
+	}
+	h.eventWebhook = webhook
+	h.eventFailOn = severity
+	return nil
+}
+
+// scanWebhookEvents returns the events of the event webhook for a scan whose results were just
+// processed. The gate reflects triage as of now, suppression rules included
+func (h *ScanHandler) scanWebhookEvents(ctx context.Context, scan *models.Scan, image *models.Image, diff *models.ScanDiff) ([]*models.OutboxEvent, error) {
+	completed := h.eventWebhook.Enabled(eventhook.ScanCompleted)
+	if !completed && !h.eventWebhook.Enabled(eventhook.PolicyFailed) {
+		return nil, nil
+	}
+
+	vulns, err := h.scanRepo.GetVulnerabilities(ctx, scan.ID)
+	if err != nil {
+		return nil, err
+	}
+	data := eventhook.ScanData{
+		Scan:        *scan,
+		Image:       image.FullName(),
+		ImageDigest: image.Digest,
+		Gate:        evaluateGate(vulns, h.eventFailOn, false),
+	}
+	data.Gate.ScanID = scan.ID
+	data.Gate.ScanStatus = scan.Status
+	for _, v := range vulns {
+		data.Vulnerabilities.Add(v.Severity, 1)
+	}
+	if diff != nil {
+		data.Diff = &diff.Summary
+	}
+
+	var events []*models.OutboxEvent
+	if completed {
+		event, err := h.eventWebhook.NewEvent(eventhook.ScanCompleted, data)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if !data.Gate.Passed && h.eventWebhook.Enabled(eventhook.PolicyFailed) {
+		event, err := h.eventWebhook.NewEvent(eventhook.PolicyFailed, data)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+		h.logger.Info("scan failed the event webhook policy",
+			zap.Int("scan_id", scan.ID),
+			zap.String("fail_on", h.eventFailOn),
+			zap.Int("blocking", len(data.Gate.Blocking)))
+	}
+	return events, nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/events"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	// eventHeartbeat is how often an idle stream gets a comment, so proxies don't close it
+	eventHeartbeat = 15 * time.Second
+	// eventRetry is how long browsers wait before reconnecting a closed stream
+	eventRetry = 5 * time.Second
+	// eventResync is sent when a stream missed events, clients reload what they show
+	eventResync = "resync"
+)
+
+// EventHandler streams the events of the bus to the dashboard over Server-Sent Events
+type EventHandler struct {
+	logger    *zap.Logger
+	bus       *events.Bus
+	heartbeat time.Duration
+}
+
+func NewEventHandler(logger *zap.Logger, bus *events.Bus) *EventHandler {
+	return &EventHandler{
+		logger:    logger,
+		bus:       bus,
+		heartbeat: eventHeartbeat,
+	}
+}
+
+// StreamEvents handles GET /api/v1/events - an event stream of the changes made from now on,
+// optionally of the comma-separated types only
+func (h *EventHandler) StreamEvents(c echo.Context) error {
+	var types []string
+	if param := c.QueryParam("types"); param != "" {
+		for _, t := range strings.Split(param, ",") {
+			t = strings.TrimSpace(t)
+			if !slices.Contains(events.Types, t) {
+				return echo.NewHTTPError(http.StatusBadRequest, "unknown event type: "+t)
+			}
+			types = append(types, t)
+		}
+	}
+
+	sub := h.bus.Subscribe(types...)
+	defer sub.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	// The ingress would buffer the stream otherwise
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(res, "retry: %d\n\n", eventRetry.Milliseconds()); err != nil {
+		return nil
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	var missed uint64
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := io.WriteString(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		case event, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			// The stream fell behind, what the client shows may be out of date
+			if n := sub.Missed(); n > missed {
+				if _, err := fmt.Fprintf(res, "event: %s\ndata: {\"missed\":%d}\n\n", eventResync, n-missed); err != nil {
+					return nil
+				}
+				missed = n
+			}
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				h.logger.Error("failed to encode event", zap.Error(err), zap.String("type", event.Type))
+				continue
+			}
+			if _, err := fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
+
+// publishScanCreated announces the stored results of a scan
+func (h *ScanHandler) publishScanCreated(scan *models.Scan, image string, vulnerabilities int) {
+	h.events.Publish(events.ScanCreated, events.ScanCreatedData{
+		ScanID:          scan.ID,
+		ImageID:         scan.ImageID,
+		Image:           image,
+		Status:          scan.Status,
+		Vulnerabilities: vulnerabilities,
+	})
+}
+
+// publishScanDiff announces the comparison of a scan with the previous scan of its image
+func (h *ScanHandler) publishScanDiff(diff *models.ScanDiff, image string) {
+	h.events.Publish(events.ScanDiff, events.ScanDiffData{
+		ScanID:          diff.ScanID,
+		PreviousScanID:  diff.PreviousScanID,
+		Image:           image,
+		NewCount:        diff.Summary.NewCount,
+		FixedCount:      diff.Summary.FixedCount,
+		PersistentCount: diff.Summary.PersistentCount,
+	})
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/events"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// readEvent reads the next message of an event stream, up to the blank line ending it
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestEventHandler_StreamEvents(t *testing.T) {
+	bus := events.NewBus()
+	handler := NewEventHandler(zap.NewNop(), bus)
+	e := echo.New()
+	e.GET("/api/v1/events", handler.StreamEvents)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/v1/events?types=scan-created,vulnerability-status-changed")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get(echo.HeaderContentType))
+	stream := bufio.NewReader(res.Body)
+	assert.Equal(t, "retry: 5000\n", readEvent(t, stream))
+	require.Equal(t, 1, bus.Subscribers())
+
+	bus.Publish(events.ScanDiff, events.ScanDiffData{ScanID: 1})
+	bus.Publish(events.ScanCreated, events.ScanCreatedData{ScanID: 2, Image: "nginx:latest", Status: "completed"})
+	bus.Publish(events.VulnerabilityStatusChanged, events.VulnerabilityStatusChange{VulnerabilityID: 3, OldStatus: "active", NewStatus: "fixed"})
+
+	assert.Equal(t, "id: 2\nevent: scan-created\ndata: {\"scan_id\":2,\"image_id\":0,\"image\":\"nginx:latest\",\"status\":\"completed\",\"vulnerabilities\":0}\n", readEvent(t, stream))
+	assert.Equal(t, "id: 3\nevent: vulnerability-status-changed\ndata: {\"vulnerability_id\":3,\"cve_id\":\"\",\"package_name\":\"\",\"old_status\":\"active\",\"new_status\":\"fixed\"}\n", readEvent(t, stream))
+
+	// Disconnecting unsubscribes
+	res.Body.Close()
+	assert.Eventually(t, func() bool { return bus.Subscribers() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestEventHandler_StreamEvents_Heartbeat(t *testing.T) {
+	handler := NewEventHandler(zap.NewNop(), events.NewBus())
+	handler.heartbeat = 10 * time.Millisecond
+	e := echo.New()
+	e.GET("/api/v1/events", handler.StreamEvents)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/v1/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	stream := bufio.NewReader(res.Body)
+	readEvent(t, stream)
+	assert.Equal(t, ": heartbeat\n", readEvent(t, stream))
+}
+
+func TestEventHandler_StreamEvents_UnknownType(t *testing.T) {
+	handler := NewEventHandler(zap.NewNop(), events.NewBus())
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events?types=scan-deleted", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	err := handler.StreamEvents(c)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
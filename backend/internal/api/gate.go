@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const defaultGateFailOn = "High"
+
+// GetScanGate handles GET /api/v1/scans/:id/gate - whether a scan passes a severity gate, for CI
+// pipelines. Unlike the raw Grype result it reflects triage: ignored and accepted vulnerabilities,
+// set by hand or by suppression rules, never block
+func (h *ScanHandler) GetScanGate(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
+	failOn := defaultGateFailOn
+	if value := c.QueryParam("fail_on"); value != "" {
+		failOn = normalizeSeverity(value)
+		if failOn == "Unknown" {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid fail_on severity (must be critical, high, medium, low or negligible)")
+		}
+	}
+	onlyFixable := false
+	if value := c.QueryParam("only_fixable"); value != "" {
+		if onlyFixable, err = strconv.ParseBool(value); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid only_fixable parameter")
+		}
+	}
+
+	ctx := c.Request().Context()
+	scan, err := h.scanRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := requireScanOfAPIKey(c, scan); err != nil {
+		return err
+	}
+	if scan.Status != models.ScanStatusCompleted && scan.Status != models.ScanStatusPartial {
+		return echo.NewHTTPError(http.StatusConflict, "scan has no results yet (status "+scan.Status+")")
+	}
+
+	vulns, err := h.scanRepo.GetVulnerabilities(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to get vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get vulnerabilities")
+	}
+
+	gate := evaluateGate(vulns, failOn, onlyFixable)
+	gate.ScanID = scan.ID
+	gate.ScanStatus = scan.Status
+	return c.JSON(http.StatusOK, gate)
+}
+
+// evaluateGate fails when an actionable vulnerability has a severity of failOn or higher.
+// Vulnerabilities of unknown severity never block
+func evaluateGate(vulns []models.Vulnerability, failOn string, onlyFixable bool) models.ScanGate {
+	gate := models.ScanGate{FailOn: failOn, OnlyFixable: onlyFixable, Blocking: []models.GateVulnerability{}}
+	threshold := severityRank(failOn)
+	for _, v := range vulns {
+		if v.Status == models.StatusIgnored || v.Status == models.StatusAccepted {
+			continue
+		}
+		if onlyFixable && v.FixVersion == nil {
+			continue
+		}
+		gate.Actionable.Add(v.Severity, 1)
+		if rank := severityRank(v.Severity); rank == 0 || rank < threshold {
+			continue
+		}
+		gate.Blocking = append(gate.Blocking, models.GateVulnerability{
+			ID:             v.ID,
+			CVEID:          v.CVEID,
+			Severity:       v.Severity,
+			Status:         v.Status,
+			PackageName:    v.PackageName,
+			PackageVersion: v.PackageVersion,
+			PackageType:    v.PackageType,
+			FixVersion:     v.FixVersion,
+			URL:            v.URL,
+		})
+	}
+
+	sort.SliceStable(gate.Blocking, func(i, j int) bool {
+		a, b := gate.Blocking[i], gate.Blocking[j]
+		if ra, rb := severityRank(a.Severity), severityRank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		if a.CVEID != b.CVEID {
+			return a.CVEID < b.CVEID
+		}
+		return a.PackageName < b.PackageName
+	})
+	gate.Passed = len(gate.Blocking) == 0
+	return gate
+}
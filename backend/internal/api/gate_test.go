@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEvaluateGate(t *testing.T) {
+	fix := "3.0.2"
+	vulns := []models.Vulnerability{
+		{ID: 1, CVEID: "CVE-2024-0003", Severity: "High", Status: models.StatusActive, PackageName: "openssl", FixVersion: &fix},
+		{ID: 2, CVEID: "CVE-2024-0002", Severity: "Critical", Status: models.StatusInProgress, PackageName: "zlib"},
+		{ID: 3, CVEID: "CVE-2024-0001", Severity: "Critical", Status: models.StatusIgnored, PackageName: "curl"},
+		{ID: 4, CVEID: "CVE-2024-0004", Severity: "Critical", Status: models.StatusAccepted, PackageName: "glibc"},
+		{ID: 5, CVEID: "CVE-2024-0005", Severity: "Medium", Status: models.StatusActive, PackageName: "bash"},
+		{ID: 6, CVEID: "CVE-2024-0006", Severity: "Unknown", Status: models.StatusActive, PackageName: "tar"},
+		{ID: 7, CVEID: "CVE-2024-0001", Severity: "High", Status: models.StatusActive, PackageName: "libcurl"},
+	}
+
+	gate := evaluateGate(vulns, "High", false)
+	assert.False(t, gate.Passed)
+	require.Len(t, gate.Blocking, 3)
+	assert.Equal(t, []int{2, 7, 1}, []int{gate.Blocking[0].ID, gate.Blocking[1].ID, gate.Blocking[2].ID},
+		"most severe first, then by CVE")
+	assert.Equal(t, models.SeverityCounts{Critical: 1, High: 2, Medium: 1, Unknown: 1, Total: 5}, gate.Actionable)
+
+	gate = evaluateGate(vulns, "High", true)
+	require.Len(t, gate.Blocking, 1)
+	assert.Equal(t, "CVE-2024-0003", gate.Blocking[0].CVEID)
+	assert.Equal(t, 1, gate.Actionable.Total)
+
+	gate = evaluateGate(vulns, "Negligible", false)
+	assert.Len(t, gate.Blocking, 4, "unknown severities never block")
+
+	gate = evaluateGate(vulns[2:4], "Low", false)
+	assert.True(t, gate.Passed)
+	assert.NotNil(t, gate.Blocking)
+}
+
+func TestScanHandler_GetScanGate_Validation(t *testing.T) {
+	handler := NewScanHandler(zap.NewNop(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	for _, path := range []string{
+		"/api/v1/scans/1/gate?fail_on=severe",
+		"/api/v1/scans/1/gate?only_fixable=maybe",
+	} {
+		_, err := doScanRequest(t, handler.GetScanGate, http.MethodGet, path, nil, "1")
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, path)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, path)
+	}
+}
+
+// withAPIKey runs a handler as if its request was authenticated with the API key of a name
+func withAPIKey(name string, handler echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(apiKeyNameKey, name)
+		return handler(c)
+	}
+}
+
+func TestScanHandler_GetScanGate_APIKey(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	rec, err := doScanRequest(t, withAPIKey("github-actions", handler.CreateScan), http.MethodPost, "/api/v1/ci/scans", map[string]interface{}{
+		"image": "acme/api:1.0",
+		"grype_result": map[string]interface{}{"matches": []map[string]interface{}{{
+			"vulnerability": map[string]interface{}{"id": "CVE-2024-0001", "severity": "Critical"},
+			"artifact":      map[string]interface{}{"name": "openssl", "version": "3.0.11-1", "type": "deb"},
+		}}},
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var scan models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+	require.NotNil(t, scan.APIKeyName)
+	assert.Equal(t, "github-actions", *scan.APIKeyName)
+	id := strconv.Itoa(scan.ID)
+	path := "/api/v1/ci/scans/" + id + "/gate"
+
+	// The key that submitted the scan and OAuth users get the verdict
+	for _, fn := range []echo.HandlerFunc{withAPIKey("github-actions", handler.GetScanGate), handler.GetScanGate} {
+		rec, err = doScanRequest(t, fn, http.MethodGet, path, nil, id)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Other keys don't find it
+	_, err = doScanRequest(t, withAPIKey("gitlab-ci", handler.GetScanGate), http.MethodGet, path, nil, id)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
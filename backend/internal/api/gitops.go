@@ -0,0 +1,23 @@
+package api
+
+import (
+	"context"
+
+	"github.com/invulnerable/backend/internal/gitops"
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// SetGitOps commits the findings summary of each scan whose results are processed with committer
+func (h *ScanHandler) SetGitOps(committer *gitops.Committer) {
+	h.gitops = committer
+}
+
+// gitopsCommit returns the outbox event committing the summary of a scan whose results were just
+// processed, with the triage status of its findings as of now
+func (h *ScanHandler) gitopsCommit(ctx context.Context, scan *models.Scan, image *models.Image) (*models.OutboxEvent, error) {
+	vulns, err := h.scanRepo.GetVulnerabilities(ctx, scan.ID)
+	if err != nil {
+		return nil, err
+	}
+	return h.gitops.NewEvent(scan, image, vulns)
+}
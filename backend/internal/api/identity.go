@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/labstack/echo/v4"
+)
+
+// identityKey is the context key of the caller's identity resolved by IdentityMiddleware
+const identityKey = "identity"
+
+// IdentityMiddleware resolves the caller's identity from the headers of the authenticating proxy
+func IdentityMiddleware(headers auth.IdentityHeaders) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(identityKey, headers.FromRequest(c.Request()))
+			return next(c)
+		}
+	}
+}
+
+// requestIdentity returns the caller's identity, read from the OAuth2 Proxy headers outside IdentityMiddleware
+func requestIdentity(c echo.Context) auth.Identity {
+	if id, ok := c.Get(identityKey).(auth.Identity); ok {
+		return id
+	}
+	return auth.DefaultIdentityHeaders.FromRequest(c.Request())
+}
@@ -1,36 +1,48 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
 
 type ImageHandler struct {
-	logger    *zap.Logger
-	imageRepo *db.ImageRepository
+	logger        *zap.Logger
+	imageRepo     *db.ImageRepository
+	imageScanRepo *db.ImageScanRepository
+	deleter       *imageDeleter
+	// staleThreshold applies to ImageScans that don't declare their own staleAfter
+	staleThreshold time.Duration
 }
 
-func NewImageHandler(logger *zap.Logger, imageRepo *db.ImageRepository) *ImageHandler {
+func NewImageHandler(logger *zap.Logger, imageRepo *db.ImageRepository, imageScanRepo *db.ImageScanRepository, sbomRepo *db.SBOMRepository, grypeRepo *db.GrypeResultRepository, staleThreshold time.Duration) *ImageHandler {
 	return &ImageHandler{
-		logger:    logger,
-		imageRepo: imageRepo,
+		logger:         logger,
+		imageRepo:      imageRepo,
+		imageScanRepo:  imageScanRepo,
+		deleter:        newImageDeleter(logger, imageRepo, sbomRepo, grypeRepo),
+		staleThreshold: staleThreshold,
 	}
 }
 
 // ListImages handles GET /api/v1/images
 func (h *ImageHandler) ListImages(c echo.Context) error {
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit <= 0 || limit > 100 {
-		limit = 20
-	}
-
-	offset, _ := strconv.Atoi(c.QueryParam("offset"))
-	if offset < 0 {
-		offset = 0
+	limit, offset := parsePagination(c, 20)
+	sort, err := parseSort(c, db.ValidateImageSort)
+	if err != nil {
+		return err
 	}
 
 	// Parse has_fix parameter
@@ -43,26 +55,131 @@ func (h *ImageHandler) ListImages(c echo.Context) error {
 		hasFix = &hasFixBool
 	}
 
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		return err
+	}
+	if asOf != nil {
+		// Fix versions and staleness are only known as they are now
+		if hasFix != nil || c.QueryParam("stale") != "" || c.QueryParam("unreviewed") != "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "as_of can't be combined with has_fix, stale or unreviewed")
+		}
+		return h.listImagesAsOf(c, *asOf, limit, offset, sort)
+	}
+
+	if staleStr := c.QueryParam("stale"); staleStr != "" {
+		stale, err := strconv.ParseBool(staleStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid stale parameter")
+		}
+		if stale {
+			// Stale images are grouped in memory, in the order of their ImageScans
+			if sort != (db.Sort{}) {
+				return echo.NewHTTPError(http.StatusBadRequest, "sort can't be combined with stale")
+			}
+			return h.listStaleImages(c, limit, offset)
+		}
+	}
+
+	unreviewed := false
+	if unreviewedStr := c.QueryParam("unreviewed"); unreviewedStr != "" {
+		if unreviewed, err = strconv.ParseBool(unreviewedStr); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid unreviewed parameter")
+		}
+	}
+
 	// Get total count
-	total, err := h.imageRepo.Count(c.Request().Context())
+	total, err := h.imageRepo.Count(c.Request().Context(), unreviewed)
 	if err != nil {
 		h.logger.Error("failed to count images", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to count images")
 	}
 
-	images, err := h.imageRepo.List(c.Request().Context(), limit, offset, hasFix)
+	images, err := h.imageRepo.List(c.Request().Context(), limit, offset, sort, hasFix, unreviewed)
 	if err != nil {
 		h.logger.Error("failed to list images", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list images")
 	}
 
-	response := map[string]interface{}{
-		"data":   images,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
+	response := newPage(images, total, limit, offset)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// listImagesAsOf handles GET /api/v1/images?as_of=<date>
+// Counts are the vulnerabilities active at the time in the latest scans then
+func (h *ImageHandler) listImagesAsOf(c echo.Context, asOf time.Time, limit, offset int, sort db.Sort) error {
+	ctx := c.Request().Context()
+	total, err := h.imageRepo.CountAsOf(ctx, asOf)
+	if err != nil {
+		h.logger.Error("failed to count images", zap.Error(err), zap.Time("as_of", asOf))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to count images")
+	}
+
+	images, err := h.imageRepo.ListAsOf(ctx, asOf, limit, offset, sort)
+	if err != nil {
+		h.logger.Error("failed to list images", zap.Error(err), zap.Time("as_of", asOf))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list images")
+	}
+
+	return c.JSON(http.StatusOK, pageAsOf{Page: newPage(images, total, limit, offset), AsOf: asOf})
+}
+
+// listStaleImages handles GET /api/v1/images?stale=true
+// Only images scanned by an active ImageScan can be stale, so the list stays small
+// enough to group and paginate in memory
+func (h *ImageHandler) listStaleImages(c echo.Context, limit, offset int) error {
+	scans, err := h.imageScanRepo.ListStale(c.Request().Context(), h.staleThreshold, time.Now())
+	if err != nil {
+		h.logger.Error("failed to list stale images", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list stale images")
+	}
+
+	images := models.GroupStaleImages(scans)
+	response := pageOf(images, limit, offset)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// ListPrioritizedImages handles GET /api/v1/images/prioritized
+// Every active image is scored, so ranking and pagination happen in memory
+func (h *ImageHandler) ListPrioritizedImages(c echo.Context) error {
+	limit, offset := parsePagination(c, 20)
+
+	// exposed keeps the images run by internet-exposed workloads, or the others, ranked among every image
+	var exposed *bool
+	if exposedStr := c.QueryParam("exposed"); exposedStr != "" {
+		exposedBool, err := strconv.ParseBool(exposedStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid exposed parameter")
+		}
+		exposed = &exposedBool
+	}
+
+	factors, err := h.imageRepo.ListRiskFactors(c.Request().Context(), h.staleThreshold)
+	if err != nil {
+		h.logger.Error("failed to list image risk factors", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to rank images")
 	}
 
+	now := time.Now()
+	risks := make([]models.ImageRisk, len(factors))
+	for i, f := range factors {
+		risks[i] = models.ScoreImageRisk(f, now)
+	}
+	models.RankImageRisks(risks)
+	if exposed != nil {
+		filtered := risks[:0]
+		for _, risk := range risks {
+			if risk.Breakdown.DeployedExposed == *exposed {
+				filtered = append(filtered, risk)
+			}
+		}
+		risks = filtered
+	}
+
+	response := pageOf(risks, limit, offset)
+
 	return c.JSON(http.StatusOK, response)
 }
 
@@ -73,15 +190,7 @@ func (h *ImageHandler) GetImageHistory(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid image ID")
 	}
 
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit <= 0 || limit > 100 {
-		limit = 50
-	}
-
-	offset, _ := strconv.Atoi(c.QueryParam("offset"))
-	if offset < 0 {
-		offset = 0
-	}
+	limit, offset := parsePagination(c, 50)
 
 	// Parse has_fix parameter
 	var hasFix *bool
@@ -106,12 +215,239 @@ func (h *ImageHandler) GetImageHistory(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get image history")
 	}
 
-	response := map[string]interface{}{
-		"data":   scans,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
-	}
+	response := newPage(scans, total, limit, offset)
 
 	return c.JSON(http.StatusOK, response)
 }
+
+// GetImage handles GET /api/v1/images/:id
+// The image comes with its aliases, the images with the same digest pushed under other names: their
+// findings are the same
+func (h *ImageHandler) GetImage(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid image ID")
+	}
+
+	ctx := c.Request().Context()
+	image, err := h.imageRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	aliases, err := h.imageRepo.ListAliases(ctx, image)
+	if err != nil {
+		h.logger.Error("failed to list image aliases", zap.Error(err), zap.Int("image_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get image")
+	}
+	return c.JSON(http.StatusOK, models.ImageDetails{Image: *image, AlsoKnownAs: aliases})
+}
+
+// ReviewImage handles PATCH /api/v1/images/:id - assigns the team and criticality of an image.
+// An image first seen in a scan is unreviewed until it has both
+func (h *ImageHandler) ReviewImage(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid image ID")
+	}
+
+	var req models.ImageReviewRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	req.Team = trimmedOrNil(req.Team)
+	req.Criticality = trimmedOrNil(req.Criticality)
+	if req.Team == nil && req.Criticality == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "team or criticality is required")
+	}
+	if req.Criticality != nil && !slices.Contains(models.ValidImageCriticalities, *req.Criticality) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid criticality (must be low, medium, high or critical)")
+	}
+
+	ctx := c.Request().Context()
+	image, err := h.imageRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	details, err := json.Marshal(req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to review image")
+	}
+	name := image.FullName()
+	entry := &models.AuditEntry{
+		Action:       models.AuditActionImageReviewed,
+		ResourceType: "image",
+		ResourceID:   &image.ID,
+		ResourceName: &name,
+		Actor:        getUserFromHeaders(c),
+		Details:      details,
+	}
+	reviewed, err := h.imageRepo.Review(ctx, id, req.Team, req.Criticality, entry)
+	if err != nil {
+		h.logger.Error("failed to review image", zap.Error(err), zap.Int("image_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to review image")
+	}
+
+	return c.JSON(http.StatusOK, reviewed)
+}
+
+// DeleteImage handles DELETE /api/v1/images/:id?confirm=<token>
+// Without a token it answers 428 with the deletion impact and the token to send back
+func (h *ImageHandler) DeleteImage(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid image ID")
+	}
+
+	purgeOrphanVulns, err := parsePurgeOrphanVulns(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	image, err := h.imageRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// The controller would recreate the image on its next scan, so deleting it would only drop history
+	regs, err := h.imageScanRepo.ListForImage(ctx, image)
+	if err != nil {
+		h.logger.Error("failed to list imagescans", zap.Error(err), zap.Int("image_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check imagescans")
+	}
+	if len(regs) > 0 {
+		names := make([]string, len(regs))
+		for i, reg := range regs {
+			names[i] = reg.Namespace + "/" + reg.Name
+		}
+		return echo.NewHTTPError(http.StatusConflict,
+			"image is still scanned by ImageScan "+strings.Join(names, ", "))
+	}
+
+	impact, err := h.imageRepo.GetDeletionImpact(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to get deletion impact", zap.Error(err), zap.Int("image_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get deletion impact")
+	}
+	impact.ImageName = image.FullName()
+	impact.ConfirmationToken = deletionToken(impact)
+
+	confirm := c.QueryParam("confirm")
+	if confirm == "" {
+		return c.JSON(http.StatusPreconditionRequired, impact)
+	}
+	if confirm != impact.ConfirmationToken {
+		return echo.NewHTTPError(http.StatusPreconditionFailed,
+			"confirmation token does not match, the image changed since the impact was reviewed")
+	}
+
+	if err := h.deleter.delete(ctx, image, impact, purgeOrphanVulns, getUserFromHeaders(c)); err != nil {
+		h.logger.Error("failed to delete image", zap.Error(err), zap.Int("image_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete image")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// parsePurgeOrphanVulns parses the purge_orphan_vulns flag of deletions: vulnerabilities only the
+// deleted scans found are removed too, instead of being kept with their triage
+func parsePurgeOrphanVulns(c echo.Context) (bool, error) {
+	value := c.QueryParam("purge_orphan_vulns")
+	if value == "" {
+		return false, nil
+	}
+	purge, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusBadRequest, "invalid purge_orphan_vulns parameter")
+	}
+	return purge, nil
+}
+
+// deletionToken derives the confirmation token from the deletion impact
+// It only guards against deleting the wrong image or one that was scanned again after
+// the impact was reviewed; access control is left to the admin guard
+func deletionToken(impact *models.ImageDeletionImpact) string {
+	latestScanID := 0
+	if impact.LatestScanID != nil {
+		latestScanID = *impact.LatestScanID
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%s|%d|%d", impact.ImageID, impact.ImageName, impact.ScanCount, latestScanID)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// imageDeleter removes an image with its scans, SBOM metadata and SBOM documents.
+// Shared by the admin endpoint and the ImageScan Delete policy
+type imageDeleter struct {
+	logger    *zap.Logger
+	imageRepo *db.ImageRepository
+	sbomRepo  *db.SBOMRepository
+	grypeRepo *db.GrypeResultRepository
+}
+
+func newImageDeleter(logger *zap.Logger, imageRepo *db.ImageRepository, sbomRepo *db.SBOMRepository, grypeRepo *db.GrypeResultRepository) *imageDeleter {
+	return &imageDeleter{
+		logger:    logger,
+		imageRepo: imageRepo,
+		sbomRepo:  sbomRepo,
+		grypeRepo: grypeRepo,
+	}
+}
+
+func (d *imageDeleter) delete(ctx context.Context, image *models.Image, impact *models.ImageDeletionImpact, purgeOrphanVulns bool, actor string) error {
+	details, err := json.Marshal(map[string]interface{}{
+		"scan_count":         impact.ScanCount,
+		"sbom_count":         impact.SBOMCount,
+		"digest":             image.Digest,
+		"purge_orphan_vulns": purgeOrphanVulns,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode audit details: %w", err)
+	}
+	name := image.FullName()
+	entry := &models.AuditEntry{
+		Action:       models.AuditActionImageDeleted,
+		ResourceType: "image",
+		ResourceID:   &image.ID,
+		ResourceName: &name,
+		Actor:        actor,
+		Details:      details,
+	}
+
+	// Archived Grype results go away with the scans, list them while they still exist
+	var grypeScanIDs []int
+	if d.grypeRepo != nil {
+		if grypeScanIDs, err = d.grypeRepo.ListScanIDsByImage(ctx, image.ID); err != nil {
+			d.logger.Warn("failed to list archived Grype results of image", zap.Error(err), zap.Int("image_id", image.ID))
+		}
+	}
+
+	sbomScanIDs, purged, err := d.imageRepo.Delete(ctx, image.ID, purgeOrphanVulns, entry)
+	if err != nil {
+		return err
+	}
+
+	// The database is the source of truth, so documents are only removed once the deletion is committed.
+	// A failure leaves orphaned objects in the bucket but never SBOM metadata without a document
+	if err := d.sbomRepo.DeleteDocuments(ctx, sbomScanIDs); err != nil {
+		d.logger.Warn("failed to delete SBOM documents of deleted image",
+			zap.Error(err),
+			zap.Int("image_id", image.ID))
+	}
+	if d.grypeRepo != nil {
+		if err := d.grypeRepo.DeleteDocuments(ctx, grypeScanIDs); err != nil {
+			d.logger.Warn("failed to delete Grype results of deleted image",
+				zap.Error(err),
+				zap.Int("image_id", image.ID))
+		}
+	}
+
+	d.logger.Info("image deleted",
+		zap.Int("image_id", image.ID),
+		zap.String("image", name),
+		zap.Int("scans", impact.ScanCount),
+		zap.Int("vulnerabilities_purged", purged),
+		zap.String("user", actor))
+
+	return nil
+}
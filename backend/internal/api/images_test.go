@@ -2,8 +2,10 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -21,7 +23,7 @@ func TestImageHandler_ListImages(t *testing.T) {
 
 	logger := zap.NewNop()
 	imageRepo := db.NewImageRepository(database)
-	handler := NewImageHandler(logger, imageRepo)
+	handler := NewImageHandler(logger, imageRepo, db.NewImageScanRepository(database), db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}), nil, 48*time.Hour)
 
 	// Create test images
 	image1 := &models.Image{
@@ -59,7 +61,7 @@ func TestImageHandler_ListImages_WithLimit(t *testing.T) {
 
 	logger := zap.NewNop()
 	imageRepo := db.NewImageRepository(database)
-	handler := NewImageHandler(logger, imageRepo)
+	handler := NewImageHandler(logger, imageRepo, db.NewImageScanRepository(database), db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}), nil, 48*time.Hour)
 
 	// Create test images
 	for i := 0; i < 5; i++ {
@@ -89,7 +91,7 @@ func TestImageHandler_ListImages_InvalidHasFix(t *testing.T) {
 
 	logger := zap.NewNop()
 	imageRepo := db.NewImageRepository(database)
-	handler := NewImageHandler(logger, imageRepo)
+	handler := NewImageHandler(logger, imageRepo, db.NewImageScanRepository(database), db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}), nil, 48*time.Hour)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/images?has_fix=invalid", nil)
@@ -112,7 +114,7 @@ func TestImageHandler_GetImageHistory(t *testing.T) {
 	logger := zap.NewNop()
 	imageRepo := db.NewImageRepository(database)
 	scanRepo := db.NewScanRepository(database)
-	handler := NewImageHandler(logger, imageRepo)
+	handler := NewImageHandler(logger, imageRepo, db.NewImageScanRepository(database), db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}), nil, 48*time.Hour)
 
 	// Create test image
 	image := &models.Image{
@@ -151,13 +153,58 @@ func TestImageHandler_GetImageHistory(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
 
+func TestImageHandler_GetImage_Aliases(t *testing.T) {
+	database := db.SetupTestDatabase(t)
+	defer database.Close()
+
+	ctx := context.Background()
+	imageRepo := db.NewImageRepository(database)
+	handler := NewImageHandler(zap.NewNop(), imageRepo, db.NewImageScanRepository(database), db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}), nil, 48*time.Hour)
+
+	// The same digest pushed to a mirror, and another image
+	digest := "sha256:4d2b"
+	other := "sha256:9f1c"
+	image := &models.Image{Registry: "docker.io", Repository: "acme/api", Tag: "1.4.2", Digest: &digest}
+	mirror := &models.Image{Registry: "ghcr.io", Repository: "acme/api", Tag: "1.4.2", Digest: &digest}
+	unrelated := &models.Image{Registry: "docker.io", Repository: "acme/web", Tag: "1.0", Digest: &other}
+	for _, img := range []*models.Image{image, mirror, unrelated} {
+		require.NoError(t, imageRepo.Create(ctx, img))
+	}
+
+	rec, err := doScanRequest(t, handler.GetImage, http.MethodGet, "/api/v1/images/"+strconv.Itoa(image.ID), nil, strconv.Itoa(image.ID))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var details models.ImageDetails
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+	assert.Equal(t, image.ID, details.ID)
+	require.Len(t, details.AlsoKnownAs, 1)
+	assert.Equal(t, mirror.ID, details.AlsoKnownAs[0].ID)
+	assert.Equal(t, "ghcr.io", details.AlsoKnownAs[0].Registry)
+
+	images, err := imageRepo.List(ctx, 10, 0, db.Sort{}, nil, false)
+	require.NoError(t, err)
+	for _, img := range images {
+		if img.ID == unrelated.ID {
+			assert.Zero(t, img.AliasCount)
+		} else {
+			assert.Equal(t, 1, img.AliasCount)
+		}
+	}
+
+	_, err = doScanRequest(t, handler.GetImage, http.MethodGet, "/api/v1/images/999999", nil, "999999")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
 func TestImageHandler_GetImageHistory_InvalidID(t *testing.T) {
 	database := db.SetupTestDatabase(t)
 	defer database.Close()
 
 	logger := zap.NewNop()
 	imageRepo := db.NewImageRepository(database)
-	handler := NewImageHandler(logger, imageRepo)
+	handler := NewImageHandler(logger, imageRepo, db.NewImageScanRepository(database), db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}), nil, 48*time.Hour)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/images/invalid/history", nil)
@@ -181,7 +228,7 @@ func TestImageHandler_GetImageHistory_InvalidHasFix(t *testing.T) {
 
 	logger := zap.NewNop()
 	imageRepo := db.NewImageRepository(database)
-	handler := NewImageHandler(logger, imageRepo)
+	handler := NewImageHandler(logger, imageRepo, db.NewImageScanRepository(database), db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}), nil, 48*time.Hour)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/images/1/history?has_fix=notabool", nil)
@@ -198,3 +245,96 @@ func TestImageHandler_GetImageHistory_InvalidHasFix(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
 	assert.Contains(t, httpErr.Message, "invalid has_fix parameter")
 }
+
+func TestDeletionToken(t *testing.T) {
+	latest := 42
+	impact := &models.ImageDeletionImpact{ImageID: 1, ImageName: "docker.io/library/nginx:latest", ScanCount: 3, LatestScanID: &latest}
+	token := deletionToken(impact)
+	assert.Len(t, token, 16)
+	assert.Equal(t, token, deletionToken(impact))
+
+	// A new scan invalidates a token obtained before it
+	newer := 43
+	assert.NotEqual(t, token, deletionToken(&models.ImageDeletionImpact{
+		ImageID: 1, ImageName: "docker.io/library/nginx:latest", ScanCount: 4, LatestScanID: &newer,
+	}))
+	assert.NotEqual(t, token, deletionToken(&models.ImageDeletionImpact{
+		ImageID: 2, ImageName: "docker.io/library/nginx:latest", ScanCount: 3, LatestScanID: &latest,
+	}))
+}
+
+func TestImageHandler_DeleteImage(t *testing.T) {
+	database := db.SetupTestDatabase(t)
+	defer database.Close()
+
+	ctx := context.Background()
+	imageRepo := db.NewImageRepository(database)
+	imageScanRepo := db.NewImageScanRepository(database)
+	storage := &memorySBOMStorage{docs: make(map[int][]byte)}
+	sbomRepo := db.NewSBOMRepository(database, storage)
+	handler := NewImageHandler(zap.NewNop(), imageRepo, imageScanRepo, sbomRepo, nil, 48*time.Hour)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: models.ScanStatusCompleted}
+	require.NoError(t, db.NewScanRepository(database).Create(ctx, scan))
+	require.NoError(t, sbomRepo.Create(ctx, &models.SBOM{ScanID: scan.ID, Format: "cyclonedx"}, []byte("{}")))
+
+	id := strconv.Itoa(image.ID)
+
+	// Refused while an ImageScan still scans the image
+	reg := &models.ImageScanRegistration{Namespace: "default", Name: "nginx", Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageScanRepo.Upsert(ctx, reg))
+	_, err := doScanRequest(t, handler.DeleteImage, http.MethodDelete, "/api/v1/images/"+id, nil, id)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusConflict, httpErr.Code)
+	require.NoError(t, imageScanRepo.Delete(ctx, "default", "nginx"))
+
+	// Without a token the impact is returned
+	rec, err := doScanRequest(t, handler.DeleteImage, http.MethodDelete, "/api/v1/images/"+id, nil, id)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	var impact models.ImageDeletionImpact
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &impact))
+	assert.Equal(t, 1, impact.ScanCount)
+	assert.Equal(t, 1, impact.SBOMCount)
+	require.NotEmpty(t, impact.ConfirmationToken)
+
+	_, err = doScanRequest(t, handler.DeleteImage, http.MethodDelete, "/api/v1/images/"+id+"?confirm=stale", nil, id)
+	httpErr, ok = err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusPreconditionFailed, httpErr.Code)
+
+	rec, err = doScanRequest(t, handler.DeleteImage, http.MethodDelete, "/api/v1/images/"+id+"?confirm="+impact.ConfirmationToken, nil, id)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, storage.docs)
+
+	_, err = imageRepo.GetByID(ctx, image.ID)
+	assert.Error(t, err)
+}
+
+func TestImageHandler_ReviewImage_Validation(t *testing.T) {
+	// Invalid requests are rejected before reaching the repository
+	handler := NewImageHandler(zap.NewNop(), nil, nil, nil, nil, time.Hour)
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want string
+	}{
+		{"nothing to assign", map[string]interface{}{"team": " "}, "team or criticality"},
+		{"unknown criticality", map[string]interface{}{"team": "payments", "criticality": "urgent"}, "invalid criticality"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.ReviewImage, http.MethodPatch, "/api/v1/images/:id", tt.body, "1")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.Contains(t, httpErr.Message.(string), tt.want)
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/invulnerable/backend/internal/compliance"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ImageScanHandler tracks the ImageScan resources reported by the controller
+type ImageScanHandler struct {
+	logger    *zap.Logger
+	repo      *db.ImageScanRepository
+	imageRepo *db.ImageRepository
+	deleter   *imageDeleter
+}
+
+// NewImageScanHandler creates a new ImageScan handler
+func NewImageScanHandler(logger *zap.Logger, repo *db.ImageScanRepository, imageRepo *db.ImageRepository, sbomRepo *db.SBOMRepository, grypeRepo *db.GrypeResultRepository) *ImageScanHandler {
+	return &ImageScanHandler{
+		logger:    logger,
+		repo:      repo,
+		imageRepo: imageRepo,
+		deleter:   newImageDeleter(logger, imageRepo, sbomRepo, grypeRepo),
+	}
+}
+
+// ListImageScans handles GET /api/v1/imagescans?namespace=
+// Lists the registered ImageScans with the open findings of their image's latest scan
+func (h *ImageScanHandler) ListImageScans(c echo.Context) error {
+	summaries, err := h.repo.ListSummaries(c.Request().Context(), c.QueryParam("namespace"))
+	if err != nil {
+		h.logger.Error("failed to list imagescans", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list imagescans")
+	}
+
+	return c.JSON(http.StatusOK, summaries)
+}
+
+// RegisterImageScan handles PUT /api/v1/imagescans/:namespace/:name
+func (h *ImageScanHandler) RegisterImageScan(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	if namespace == "" || name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "namespace and name are required")
+	}
+
+	var req models.ImageScanRegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if req.Image == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "image is required")
+	}
+	if req.StaleAfterSeconds != nil && *req.StaleAfterSeconds <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "stale_after_seconds must be positive")
+	}
+	if req.MaxScansRetained != nil && *req.MaxScansRetained <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "max_scans_retained must be positive")
+	}
+	if req.MaxScanAgeSeconds != nil && *req.MaxScanAgeSeconds <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "max_scan_age_seconds must be positive")
+	}
+
+	if req.Exposure != nil && !models.ValidExposure(*req.Exposure) {
+		return echo.NewHTTPError(http.StatusBadRequest, "exposure must be internet, internal or isolated")
+	}
+
+	// Parsed like scan submissions so the registration matches the images table
+	registry, repository, tag := parseImageName(req.Image)
+	reg := &models.ImageScanRegistration{
+		Namespace:         namespace,
+		Name:              name,
+		Registry:          registry,
+		Repository:        repository,
+		Tag:               tag,
+		Suspended:         req.Suspended,
+		StaleAfterSeconds: req.StaleAfterSeconds,
+		MaxScansRetained:  req.MaxScansRetained,
+		MaxScanAgeSeconds: req.MaxScanAgeSeconds,
+		Exposure:          req.Exposure,
+		Labels:            compliance.Labels(req.Labels),
+	}
+	if err := h.repo.Upsert(c.Request().Context(), reg); err != nil {
+		h.logger.Error("failed to register imagescan",
+			zap.Error(err),
+			zap.String("namespace", namespace),
+			zap.String("name", name))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to register imagescan")
+	}
+
+	return c.JSON(http.StatusOK, reg)
+}
+
+// defaultSizingDays is the window of the scans sizing recommendations are made from without days
+const defaultSizingDays = 30
+
+// GetEntitySummary handles GET /api/v1/entities/:team/:service/summary
+// It sums the open findings of the ImageScans of a Backstage catalog entity, those of the team's
+// namespace named after the service or labelled with it, for developer portals
+func (h *ImageScanHandler) GetEntitySummary(c echo.Context) error {
+	team := c.Param("team")
+	service := c.Param("service")
+
+	if team == "" || service == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "team and service are required")
+	}
+
+	scans, err := h.repo.ListEntitySummaries(c.Request().Context(), team, service)
+	if err != nil {
+		h.logger.Error("failed to list entity imagescans",
+			zap.Error(err),
+			zap.String("team", team),
+			zap.String("service", service))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get entity summary")
+	}
+	if len(scans) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "no imagescan of the entity")
+	}
+
+	return c.JSON(http.StatusOK, models.NewEntitySummary(team, service, scans))
+}
+
+// GetImageScanSizing handles GET /api/v1/imagescans/:namespace/:name/sizing?days=30
+// It recommends the workspace size and memory request of the ImageScan's Jobs from the largest image
+// its recent scans reported, for the controller's auto-sizing
+func (h *ImageScanHandler) GetImageScanSizing(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	if namespace == "" || name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "namespace and name are required")
+	}
+
+	days := defaultSizingDays
+	if s := c.QueryParam("days"); s != "" {
+		d, err := strconv.Atoi(s)
+		if err != nil || d < 1 || d > 90 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid days parameter, expected 1 to 90")
+		}
+		days = d
+	}
+
+	sizing, err := h.repo.GetSizing(c.Request().Context(), namespace, name, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		h.logger.Error("failed to get imagescan sizing",
+			zap.Error(err),
+			zap.String("namespace", namespace),
+			zap.String("name", name))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get imagescan sizing")
+	}
+	if sizing.Scans == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "no recent scan of the imagescan reported its image size")
+	}
+	sizing.Recommend()
+
+	return c.JSON(http.StatusOK, sizing)
+}
+
+// UnregisterImageScan handles DELETE /api/v1/imagescans/:namespace/:name?purge=true
+// With purge (the ImageScan Delete policy) the image and its history are deleted as well,
+// unless another ImageScan still scans the same image
+func (h *ImageScanHandler) UnregisterImageScan(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	if namespace == "" || name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "namespace and name are required")
+	}
+
+	purge := false
+	if purgeStr := c.QueryParam("purge"); purgeStr != "" {
+		var err error
+		purge, err = strconv.ParseBool(purgeStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid purge parameter")
+		}
+	}
+
+	ctx := c.Request().Context()
+	reg, err := h.repo.Get(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	// Purge before unregistering, so a failed purge is retried by the controller
+	// instead of finding the registration gone
+	if purge {
+		if err := h.purgeImage(c, reg); err != nil {
+			return err
+		}
+	}
+
+	if err := h.repo.Delete(ctx, namespace, name); err != nil {
+		h.logger.Error("failed to unregister imagescan",
+			zap.Error(err),
+			zap.String("namespace", namespace),
+			zap.String("name", name))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to unregister imagescan")
+	}
+
+	h.logger.Info("imagescan unregistered",
+		zap.String("namespace", namespace),
+		zap.String("name", name),
+		zap.Bool("purge", purge))
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// purgeImage deletes the image of an ImageScan being unregistered if no other ImageScan scans it
+func (h *ImageScanHandler) purgeImage(c echo.Context, reg *models.ImageScanRegistration) error {
+	ctx := c.Request().Context()
+	image, err := h.imageRepo.GetByName(ctx, reg.Registry, reg.Repository, reg.Tag)
+	if err != nil {
+		// Never scanned, nothing to delete
+		return nil
+	}
+
+	regs, err := h.repo.ListForImage(ctx, image)
+	if err != nil {
+		h.logger.Error("failed to list imagescans", zap.Error(err), zap.Int("image_id", image.ID))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check imagescans")
+	}
+	remaining := 0
+	for _, other := range regs {
+		if other.Namespace != reg.Namespace || other.Name != reg.Name {
+			remaining++
+		}
+	}
+	if remaining > 0 {
+		h.logger.Info("image still scanned by other imagescans, keeping its data",
+			zap.Int("image_id", image.ID),
+			zap.Int("imagescans", remaining))
+		return nil
+	}
+
+	impact, err := h.imageRepo.GetDeletionImpact(ctx, image.ID)
+	if err != nil {
+		h.logger.Error("failed to get deletion impact", zap.Error(err), zap.Int("image_id", image.ID))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get deletion impact")
+	}
+
+	actor := "imagescan:" + reg.Namespace + "/" + reg.Name
+	if err := h.deleter.delete(ctx, image, impact, false, actor); err != nil {
+		h.logger.Error("failed to delete image", zap.Error(err), zap.Int("image_id", image.ID))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete image")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func unregisterImageScan(t *testing.T, handler *ImageScanHandler, namespace, name, query string) (*httptest.ResponseRecorder, error) {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/imagescans/"+namespace+"/"+name+query, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("namespace", "name")
+	c.SetParamValues(namespace, name)
+	return rec, httpError(handler.UnregisterImageScan(c))
+}
+
+func TestImageScanHandler_UnregisterWithPurge(t *testing.T) {
+	database := db.SetupTestDatabase(t)
+	defer database.Close()
+
+	ctx := context.Background()
+	imageRepo := db.NewImageRepository(database)
+	imageScanRepo := db.NewImageScanRepository(database)
+	sbomRepo := db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)})
+	handler := NewImageScanHandler(zap.NewNop(), imageScanRepo, imageRepo, sbomRepo, nil)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: models.ScanStatusCompleted}
+	require.NoError(t, db.NewScanRepository(database).Create(ctx, scan))
+
+	for _, name := range []string{"nginx", "nginx-copy"} {
+		require.NoError(t, imageScanRepo.Upsert(ctx, &models.ImageScanRegistration{
+			Namespace: "default", Name: name, Registry: "docker.io", Repository: "library/nginx", Tag: "latest",
+		}))
+	}
+
+	// Another ImageScan still scans the image, so its data is kept
+	rec, err := unregisterImageScan(t, handler, "default", "nginx", "?purge=true")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	_, err = imageRepo.GetByID(ctx, image.ID)
+	require.NoError(t, err)
+
+	rec, err = unregisterImageScan(t, handler, "default", "nginx-copy", "?purge=true")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	_, err = imageRepo.GetByID(ctx, image.ID)
+	assert.Error(t, err)
+
+	_, err = unregisterImageScan(t, handler, "default", "nginx-copy", "")
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestImageScanHandler_GetImageScanSizing(t *testing.T) {
+	database := db.SetupTestDatabase(t)
+	defer database.Close()
+
+	ctx := context.Background()
+	imageRepo := db.NewImageRepository(database)
+	handler := NewImageScanHandler(zap.NewNop(), db.NewImageScanRepository(database), imageRepo, nil, nil)
+	getSizing := func(name, query string) (*httptest.ResponseRecorder, error) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/imagescans/prod/"+name+"/sizing"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("namespace", "name")
+		c.SetParamValues("prod", name)
+		return rec, httpError(handler.GetImageScanSizing(c))
+	}
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	namespace, name, size, layers := "prod", "nginx", int64(50<<20), 5
+	require.NoError(t, db.NewScanRepository(database).Create(ctx, &models.Scan{
+		ImageID: image.ID, ScanDate: time.Now(), Status: models.ScanStatusCompleted,
+		ImageScanNamespace: &namespace, ImageScanName: &name, ImageSizeBytes: &size, LayerCount: &layers,
+	}))
+
+	rec, err := getSizing("nginx", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var sizing models.ImageScanSizing
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sizing))
+	assert.Equal(t, 1, sizing.Scans)
+	assert.Equal(t, "1Gi", sizing.WorkspaceSize)
+	assert.Equal(t, "1280Mi", sizing.MemoryRequest)
+
+	for query, code := range map[string]int{"": http.StatusNotFound, "?days=91": http.StatusBadRequest} {
+		_, err := getSizing("redis", query)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, query)
+		assert.Equal(t, code, httpErr.Code, query)
+	}
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/sbom"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxImpactFindings bounds the known findings returned with an impact report
+const maxImpactFindings = 1000
+
+// ImpactHandler answers which images contain a package, from the components of their stored SBOMs.
+// Unlike findings, this works before vulnerability databases know about a CVE
+type ImpactHandler struct {
+	logger   *zap.Logger
+	sbomRepo *db.SBOMRepository
+	vulnRepo *db.VulnerabilityRepository
+}
+
+func NewImpactHandler(logger *zap.Logger, sbomRepo *db.SBOMRepository, vulnRepo *db.VulnerabilityRepository) *ImpactHandler {
+	return &ImpactHandler{
+		logger:   logger,
+		sbomRepo: sbomRepo,
+		vulnRepo: vulnRepo,
+	}
+}
+
+// impactQuery is a package to look for. When versions is set, only those exact versions are affected
+type impactQuery struct {
+	name     *string
+	purl     *string
+	versions map[string]bool
+}
+
+// AssessImpact handles POST /api/v1/impact
+func (h *ImpactHandler) AssessImpact(c echo.Context) error {
+	var req models.ImpactRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	req.CVEID = trimmedOrNil(req.CVEID)
+	req.PackageName = trimmedOrNil(req.PackageName)
+	req.PURL = trimmedOrNil(req.PURL)
+	req.VersionRange = trimmedOrNil(req.VersionRange)
+
+	if req.CVEID == nil && req.PackageName == nil && req.PURL == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "cve_id, package_name or purl is required")
+	}
+	if req.PURL != nil && (!strings.HasPrefix(*req.PURL, "pkg:") || !strings.Contains(*req.PURL, "/")) {
+		return echo.NewHTTPError(http.StatusBadRequest, "purl must be a package URL (pkg:type/name[@version])")
+	}
+
+	var versionRange sbom.VersionRange
+	if req.VersionRange != nil {
+		var err error
+		if versionRange, err = sbom.ParseVersionRange(*req.VersionRange); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	ctx := c.Request().Context()
+	report := &models.ImpactReport{
+		Images:        []models.ImpactedImage{},
+		KnownFindings: []models.VulnerabilityWithImageInfo{},
+	}
+
+	var queries []impactQuery
+	if req.PackageName != nil || req.PURL != nil {
+		queries = append(queries, impactQuery{name: req.PackageName, purl: req.PURL})
+	}
+
+	if req.CVEID != nil {
+		findings, err := h.vulnRepo.ListWithImageInfo(ctx, maxImpactFindings, 0, db.Sort{}, nil, nil, nil, nil, nil, req.CVEID, nil, nil)
+		if err != nil {
+			h.logger.Error("failed to list known findings", zap.Error(err), zap.String("cve_id", *req.CVEID))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to assess impact")
+		}
+		if findings != nil {
+			report.KnownFindings = findings
+		}
+
+		// Without a package, the packages are taken from the findings already known for the CVE,
+		// which still finds the images that were not scanned since the CVE was published
+		if len(queries) == 0 {
+			known, err := h.vulnRepo.GetByCVE(ctx, *req.CVEID)
+			if err != nil {
+				h.logger.Error("failed to get vulnerability", zap.Error(err), zap.String("cve_id", *req.CVEID))
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to assess impact")
+			}
+			if len(known) == 0 {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity,
+					"cve_id is not in the vulnerability database yet, provide package_name or purl")
+			}
+			queries = knownPackageQueries(known)
+		}
+	}
+
+	scans, err := h.sbomRepo.ListLatestSBOMScans(ctx, nil)
+	if err != nil {
+		h.logger.Error("failed to list SBOM scans", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to assess impact")
+	}
+	report.Summary.ImagesChecked = len(scans)
+
+	scanIDs := make([]int, 0, len(scans))
+	for _, scan := range scans {
+		// SBOMs stored before components were indexed at ingestion are indexed on first use
+		if scan.ComponentCount == nil {
+			if err := h.sbomRepo.IndexStoredDocument(ctx, scan.ScanID); err != nil {
+				h.logger.Warn("failed to index SBOM components", zap.Error(err), zap.Int("scan_id", scan.ScanID))
+				report.Summary.ImagesNotIndexed++
+				continue
+			}
+		}
+		scanIDs = append(scanIDs, scan.ScanID)
+	}
+
+	matches := make(map[int][]models.SBOMComponent)
+	seen := make(map[models.ScanComponent]bool)
+	for _, q := range queries {
+		components, err := h.sbomRepo.FindComponents(ctx, scanIDs, q.name, q.purl)
+		if err != nil {
+			h.logger.Error("failed to find SBOM components", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to assess impact")
+		}
+		for _, component := range components {
+			if versionRange != nil {
+				if !versionRange.Contains(component.Version) {
+					continue
+				}
+			} else if q.versions != nil && !q.versions[component.Version] {
+				continue
+			}
+			// The same package can be listed once per location it was found at
+			if seen[component] {
+				continue
+			}
+			seen[component] = true
+			matches[component.ScanID] = append(matches[component.ScanID], component.SBOMComponent)
+		}
+	}
+
+	for _, scan := range scans {
+		components := matches[scan.ScanID]
+		if len(components) == 0 {
+			continue
+		}
+		report.Images = append(report.Images, models.ImpactedImage{
+			ImageID:    scan.ImageID,
+			ImageName:  scan.ImageName,
+			Target:     scan.Target,
+			ScanID:     scan.ScanID,
+			ScanDate:   scan.ScanDate,
+			Components: components,
+		})
+	}
+	report.Summary.ImagesAffected = len(report.Images)
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// knownPackageQueries looks for the package versions of known findings
+func knownPackageQueries(vulns []models.Vulnerability) []impactQuery {
+	versions := make(map[string]map[string]bool)
+	for _, v := range vulns {
+		if versions[v.PackageName] == nil {
+			versions[v.PackageName] = make(map[string]bool)
+		}
+		versions[v.PackageName][v.PackageVersion] = true
+	}
+
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	queries := make([]impactQuery, 0, len(names))
+	for _, name := range names {
+		queries = append(queries, impactQuery{name: &name, versions: versions[name]})
+	}
+	return queries
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestImpactHandler_AssessImpact_Validation(t *testing.T) {
+	// Invalid requests are rejected before reaching the repositories
+	handler := NewImpactHandler(zap.NewNop(), nil, nil)
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want string
+	}{
+		{"no criteria", map[string]interface{}{"cve_id": " ", "version_range": "<2.15.0"}, "cve_id, package_name or purl"},
+		{"not a purl", map[string]interface{}{"purl": "lodash@4.17.20"}, "purl"},
+		{"invalid range", map[string]interface{}{"package_name": "log4j-core", "version_range": ">=2.0 <2.15.0"}, "invalid version constraint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.AssessImpact, http.MethodPost, "/api/v1/impact", tt.body, "")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.True(t, strings.Contains(httpErr.Message.(string), tt.want), httpErr.Message)
+		})
+	}
+}
+
+func TestImpactHandler_AssessImpact(t *testing.T) {
+	scanHandler := newTestScanHandler(t)
+	handler := NewImpactHandler(zap.NewNop(), scanHandler.sbomRepo, scanHandler.vulnRepo)
+
+	for image, sbom := range map[string]string{
+		"acme/api:1.0": `{"bomFormat":"CycloneDX","components":[
+			{"name":"log4j-core","version":"2.14.1","purl":"pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"}]}`,
+		"acme/worker:1.0": `{"bomFormat":"CycloneDX","components":[
+			{"name":"log4j-core","version":"2.17.1","purl":"pkg:maven/org.apache.logging.log4j/log4j-core@2.17.1"}]}`,
+	} {
+		rec, err := doScanRequest(t, scanHandler.CreateScan, http.MethodPost, "/api/v1/scans", map[string]interface{}{
+			"image":        image,
+			"grype_result": json.RawMessage(`{"matches":[]}`),
+			"sbom":         json.RawMessage(sbom),
+			"sbom_format":  "cyclonedx",
+		}, "")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	rec, err := doScanRequest(t, handler.AssessImpact, http.MethodPost, "/api/v1/impact", map[string]interface{}{
+		"cve_id":        "CVE-2099-0001",
+		"purl":          "pkg:maven/org.apache.logging.log4j/log4j-core",
+		"version_range": ">=2.0.0, <2.15.0",
+	}, "")
+	require.NoError(t, err)
+
+	var report models.ImpactReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Empty(t, report.KnownFindings)
+	assert.Equal(t, 2, report.Summary.ImagesChecked)
+	require.Len(t, report.Images, 1)
+	assert.Equal(t, "docker.io/acme/api:1.0", report.Images[0].ImageName)
+	require.Len(t, report.Images[0].Components, 1)
+	assert.Equal(t, "2.14.1", report.Images[0].Components[0].Version)
+
+	// A CVE unknown to the vulnerability database needs a package to look for
+	_, err = doScanRequest(t, handler.AssessImpact, http.MethodPost, "/api/v1/impact",
+		map[string]interface{}{"cve_id": "CVE-2099-0001"}, "")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, httpErr.Code)
+}
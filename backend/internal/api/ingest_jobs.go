@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/ingest"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ingestJobParams is what processing the results of a scan needs besides its matches, stored with its job
+type ingestJobParams struct {
+	Image string `json:"image"` // as submitted
+	// Status and FailureReason the scan gets once its results are processed, completed or partial
+	Status           string                   `json:"status"`
+	FailureReason    *string                  `json:"failure_reason,omitempty"`
+	ImageScan        *models.ImageScanContext `json:"imagescan_context,omitempty"`
+	CachedFromScanID *int                     `json:"cached_from_scan_id,omitempty"`
+	// Unchanged results have no matches to fall back to when they can't be linked
+	Unchanged bool `json:"unchanged,omitempty"`
+	// HeldNotification is set when the scan notification was written with the scan, held until processing releases it
+	HeldNotification bool `json:"held_notification,omitempty"`
+}
+
+// ScanAccepted is the response of a scan submission whose results are processed in the background:
+// the scan, running until they are, and the job processing them
+type ScanAccepted struct {
+	*models.Scan
+	IngestJob *models.IngestJob `json:"ingest_job"`
+}
+
+// SetIngestJobs processes the results of submissions on the workers of pool, which runs ProcessIngestJob,
+// instead of in the request. Submissions are then answered 202 Accepted
+func (h *ScanHandler) SetIngestJobs(pool *ingest.Pool) {
+	h.ingestJobs = pool
+}
+
+// enqueueResults queues the processing of the matches of a stored scan
+func (h *ScanHandler) enqueueResults(ctx context.Context, scan *models.Scan, params ingestJobParams, matches []models.GrypeMatch) (*models.IngestJob, error) {
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ingest job: %w", err)
+	}
+	if matches == nil {
+		matches = []models.GrypeMatch{}
+	}
+	encodedMatches, err := json.Marshal(matches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode matches: %w", err)
+	}
+	job := &models.IngestJob{
+		ScanID:     scan.ID,
+		Params:     string(encodedParams),
+		Matches:    encodedMatches,
+		MatchCount: len(matches),
+	}
+	if err := h.ingestJobs.Enqueue(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ProcessIngestJob processes the results of a queued submission, run by the ingest workers. A scan
+// no longer running was processed by an earlier attempt, failed or deleted, and is left as it is
+func (h *ScanHandler) ProcessIngestJob(ctx context.Context, job *models.IngestJob) error {
+	var params ingestJobParams
+	if err := json.Unmarshal([]byte(job.Params), &params); err != nil {
+		return fmt.Errorf("invalid ingest job %d: %w", job.ID, err)
+	}
+	var matches []models.GrypeMatch
+	if len(job.Matches) > 0 {
+		if err := json.Unmarshal(job.Matches, &matches); err != nil {
+			return fmt.Errorf("invalid matches of ingest job %d: %w", job.ID, err)
+		}
+	}
+
+	scan, err := h.scanRepo.GetByID(ctx, job.ScanID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get scan: %w", err)
+	}
+	if scan.Status != models.ScanStatusRunning {
+		h.logger.Info("scan no longer running, skipping its results",
+			zap.Int64("ingest_job_id", job.ID),
+			zap.Int("scan_id", scan.ID),
+			zap.String("status", scan.Status))
+		return nil
+	}
+	image, err := h.imageRepo.GetByID(ctx, scan.ImageID)
+	if err != nil {
+		return fmt.Errorf("failed to get image: %w", err)
+	}
+	return h.processResults(ctx, scan, image, params, matches)
+}
+
+// IngestJobStore is the persistence used by the ingest job handler
+type IngestJobStore interface {
+	GetByID(ctx context.Context, id int64) (*models.IngestJob, error)
+}
+
+// IngestJobHandler reports the processing of the results of scan submissions
+type IngestJobHandler struct {
+	logger *zap.Logger
+	store  IngestJobStore
+}
+
+func NewIngestJobHandler(logger *zap.Logger, store IngestJobStore) *IngestJobHandler {
+	return &IngestJobHandler{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// GetIngestJob handles GET /api/v1/ingest-jobs/:id
+func (h *IngestJobHandler) GetIngestJob(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ingest job ID")
+	}
+	job, err := h.store.GetByID(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryIngestJobStore keeps the queued jobs, which tests run with ProcessIngestJob
+type memoryIngestJobStore struct {
+	mu   sync.Mutex
+	jobs []*models.IngestJob
+}
+
+func (s *memoryIngestJobStore) Enqueue(ctx context.Context, job *models.IngestJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.ID = int64(len(s.jobs) + 1)
+	job.Status = models.IngestJobQueued
+	job.CreatedAt = time.Now()
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+func (s *memoryIngestJobStore) GetByID(ctx context.Context, id int64) (*models.IngestJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 1 || int(id) > len(s.jobs) {
+		return nil, fmt.Errorf("ingest job not found: %w", db.ErrNotFound)
+	}
+	job := *s.jobs[id-1]
+	return &job, nil
+}
+
+func (s *memoryIngestJobStore) GetByScanID(ctx context.Context, scanID int) (*models.IngestJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		if s.jobs[i].ScanID == scanID {
+			job := *s.jobs[i]
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryIngestJobStore) Claim(ctx context.Context, lease time.Duration) (*models.IngestJob, error) {
+	return nil, nil
+}
+
+func (s *memoryIngestJobStore) Extend(ctx context.Context, id int64, lease time.Duration) error {
+	return nil
+}
+
+func (s *memoryIngestJobStore) Complete(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (s *memoryIngestJobStore) Fail(ctx context.Context, id int64, message string, retryAt *time.Time) error {
+	return nil
+}
+
+func TestIngestJobHandler_GetIngestJob(t *testing.T) {
+	store := &memoryIngestJobStore{}
+	require.NoError(t, store.Enqueue(context.Background(), &models.IngestJob{ScanID: 7, Params: `{}`, Matches: []byte(`[]`), MatchCount: 12}))
+	handler := NewIngestJobHandler(zap.NewNop(), store)
+
+	rec, err := doScanRequest(t, handler.GetIngestJob, http.MethodGet, "/api/v1/ingest-jobs/1", nil, "1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var job map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	assert.Equal(t, float64(7), job["scan_id"])
+	assert.Equal(t, models.IngestJobQueued, job["status"])
+	assert.Equal(t, float64(12), job["match_count"])
+	assert.NotContains(t, job, "matches")
+
+	for id, code := range map[string]int{"2": http.StatusNotFound, "x": http.StatusBadRequest} {
+		_, err := doScanRequest(t, handler.GetIngestJob, http.MethodGet, "/api/v1/ingest-jobs/"+id, nil, id)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, id)
+		assert.Equal(t, code, httpErr.Code, id)
+	}
+}
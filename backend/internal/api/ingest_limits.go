@@ -0,0 +1,146 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// IngestLimits bounds what a single scan submission may store, so a broken or malicious
+// scanner can't exhaust the database. Zero disables the corresponding limit
+type IngestLimits struct {
+	// MaxBodyBytes rejects larger request bodies with 413
+	MaxBodyBytes int64
+	// MaxMatches keeps the most severe matches of a larger result and stores the scan as partial
+	MaxMatches int
+	// HardMaxMatches rejects results with more matches with 413
+	HardMaxMatches int
+	// MaxStringLength truncates the free-text fields of a match (description, URLs, advisory links, purl)
+	MaxStringLength int
+}
+
+// DefaultIngestLimits are generous for real images, which rarely have more than a few thousand matches
+func DefaultIngestLimits() IngestLimits {
+	return IngestLimits{
+		MaxBodyBytes:    256 << 20,
+		MaxMatches:      50000,
+		HardMaxMatches:  500000,
+		MaxStringLength: 8192,
+	}
+}
+
+// Column sizes of the vulnerability identity, values that don't fit can't be stored
+const (
+	maxCVEIDLength          = 50
+	maxPackageNameLength    = 255
+	maxPackageVersionLength = 128
+	maxPackageTypeLength    = 50
+	maxFixVersionLength     = 128
+)
+
+// ingestTruncation records what applyIngestLimits removed from a result
+type ingestTruncation struct {
+	MatchesReceived int
+	MatchesDropped  int
+	FieldsTruncated int
+}
+
+// Reason describes the truncation for the scan's failure_reason, empty when no match was dropped
+func (t ingestTruncation) Reason(limits IngestLimits) string {
+	if t.MatchesDropped == 0 {
+		return ""
+	}
+	if limits.MaxMatches > 0 && t.MatchesReceived > limits.MaxMatches {
+		return fmt.Sprintf("%d of %d matches dropped: more than %d matches per scan", t.MatchesDropped, t.MatchesReceived, limits.MaxMatches)
+	}
+	return fmt.Sprintf("%d of %d matches dropped: identifiers too long to store", t.MatchesDropped, t.MatchesReceived)
+}
+
+// errTooManyMatches is returned by applyIngestLimits when the hard limit is exceeded
+type errTooManyMatches struct {
+	count, limit int
+}
+
+func (e errTooManyMatches) Error() string {
+	return fmt.Sprintf("grype result has %d matches, the limit is %d", e.count, e.limit)
+}
+
+// applyIngestLimits trims the matches of a result in place before they are processed.
+// Matches whose identifiers don't fit their columns are dropped (Postgres would reject them),
+// long free-text fields are cut, and over MaxMatches the least severe matches are dropped
+func applyIngestLimits(result *models.GrypeResult, limits IngestLimits) (ingestTruncation, error) {
+	t := ingestTruncation{MatchesReceived: len(result.Matches)}
+	if limits.HardMaxMatches > 0 && len(result.Matches) > limits.HardMaxMatches {
+		return t, errTooManyMatches{count: len(result.Matches), limit: limits.HardMaxMatches}
+	}
+
+	kept := result.Matches[:0]
+	for _, m := range result.Matches {
+		if len(m.Vulnerability.ID) > maxCVEIDLength || len(m.Artifact.Name) > maxPackageNameLength ||
+			len(m.Artifact.Version) > maxPackageVersionLength || len(m.Artifact.Type) > maxPackageTypeLength {
+			t.MatchesDropped++
+			continue
+		}
+		if m.Vulnerability.Fix != nil && len(m.Vulnerability.Fix.Versions) > 0 && len(m.Vulnerability.Fix.Versions[0]) > maxFixVersionLength {
+			// A cut fix version would name a version that doesn't exist
+			fix := *m.Vulnerability.Fix
+			fix.Versions = nil
+			m.Vulnerability.Fix = &fix
+			t.FieldsTruncated++
+		}
+		if limits.MaxStringLength > 0 {
+			t.FieldsTruncated += truncateString(&m.Vulnerability.Description, limits.MaxStringLength)
+			t.FieldsTruncated += truncateString(&m.Artifact.PURL, limits.MaxStringLength)
+			for i := range m.Vulnerability.URLs {
+				t.FieldsTruncated += truncateString(&m.Vulnerability.URLs[i], limits.MaxStringLength)
+			}
+			for i := range m.Vulnerability.Advisories {
+				t.FieldsTruncated += truncateString(&m.Vulnerability.Advisories[i].Link, limits.MaxStringLength)
+			}
+		}
+		kept = append(kept, m)
+	}
+
+	if limits.MaxMatches > 0 && len(kept) > limits.MaxMatches {
+		sort.SliceStable(kept, func(i, j int) bool {
+			return severityRank(kept[i].Vulnerability.Severity) > severityRank(kept[j].Vulnerability.Severity)
+		})
+		t.MatchesDropped += len(kept) - limits.MaxMatches
+		kept = kept[:limits.MaxMatches]
+	}
+
+	result.Matches = kept
+	return t, nil
+}
+
+// truncateString cuts s to at most max bytes on a rune boundary and returns 1 if it was cut
+func truncateString(s *string, max int) int {
+	if len(*s) <= max {
+		return 0
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart((*s)[cut]) {
+		cut--
+	}
+	*s = (*s)[:cut]
+	return 1
+}
+
+func severityRank(severity string) int {
+	switch normalizeSeverity(severity) {
+	case "Critical":
+		return 5
+	case "High":
+		return 4
+	case "Medium":
+		return 3
+	case "Low":
+		return 2
+	case "Negligible":
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func ingestMatch(id, severity string) models.GrypeMatch {
+	return models.GrypeMatch{
+		Vulnerability: models.GrypeVulnerability{ID: id, Severity: severity},
+		Artifact:      models.GrypeArtifact{Name: "openssl", Version: "3.0.11-1", Type: "deb"},
+	}
+}
+
+func TestApplyIngestLimits_KeepsMostSevere(t *testing.T) {
+	result := &models.GrypeResult{Matches: []models.GrypeMatch{
+		ingestMatch("CVE-1", "Low"),
+		ingestMatch("CVE-2", "Critical"),
+		ingestMatch("CVE-3", "Negligible"),
+		ingestMatch("CVE-4", "high"),
+	}}
+
+	limits := IngestLimits{MaxMatches: 2}
+	truncation, err := applyIngestLimits(result, limits)
+	require.NoError(t, err)
+	assert.Equal(t, ingestTruncation{MatchesReceived: 4, MatchesDropped: 2}, truncation)
+	require.Len(t, result.Matches, 2)
+	assert.Equal(t, "CVE-2", result.Matches[0].Vulnerability.ID)
+	assert.Equal(t, "CVE-4", result.Matches[1].Vulnerability.ID)
+	assert.Equal(t, "2 of 4 matches dropped: more than 2 matches per scan", truncation.Reason(limits))
+}
+
+func TestApplyIngestLimits_Fields(t *testing.T) {
+	long := ingestMatch("CVE-2024-1", "High")
+	long.Vulnerability.Description = strings.Repeat("é", 10) // 20 bytes
+	long.Vulnerability.URLs = []string{"https://example.com/" + strings.Repeat("a", 20), "https://a.io"}
+	long.Vulnerability.Fix = &models.GrypeFix{Versions: []string{strings.Repeat("1", 200)}}
+
+	badID := ingestMatch(strings.Repeat("C", 51), "High")
+	badVersion := ingestMatch("CVE-2024-2", "High")
+	badVersion.Artifact.Version = strings.Repeat("9", 129)
+
+	result := &models.GrypeResult{Matches: []models.GrypeMatch{long, badID, badVersion}}
+	truncation, err := applyIngestLimits(result, IngestLimits{MaxStringLength: 15})
+	require.NoError(t, err)
+	assert.Equal(t, ingestTruncation{MatchesReceived: 3, MatchesDropped: 2, FieldsTruncated: 3}, truncation)
+	assert.Equal(t, "2 of 3 matches dropped: identifiers too long to store", truncation.Reason(IngestLimits{}))
+
+	require.Len(t, result.Matches, 1)
+	m := result.Matches[0]
+	// Cut on a rune boundary
+	assert.Equal(t, strings.Repeat("é", 7), m.Vulnerability.Description)
+	assert.Equal(t, []string{"https://example", "https://a.io"}, m.Vulnerability.URLs)
+	assert.Empty(t, m.Vulnerability.Fix.Versions)
+}
+
+func TestApplyIngestLimits_HardLimit(t *testing.T) {
+	result := &models.GrypeResult{Matches: []models.GrypeMatch{ingestMatch("CVE-1", "Low"), ingestMatch("CVE-2", "Low")}}
+	_, err := applyIngestLimits(result, IngestLimits{HardMaxMatches: 1})
+	assert.EqualError(t, err, "grype result has 2 matches, the limit is 1")
+
+	// Zero disables every limit
+	truncation, err := applyIngestLimits(result, IngestLimits{})
+	require.NoError(t, err)
+	assert.Zero(t, truncation.MatchesDropped)
+	assert.Len(t, result.Matches, 2)
+}
+
+func TestScanHandler_CreateScan_IngestLimits(t *testing.T) {
+	// Rejected before reaching the repositories
+	handler := NewScanHandler(zap.NewNop(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	matches := make([]map[string]interface{}, 3)
+	for i := range matches {
+		matches[i] = map[string]interface{}{
+			"vulnerability": map[string]interface{}{"id": fmt.Sprintf("CVE-2024-%d", i), "severity": "High"},
+			"artifact":      map[string]interface{}{"name": "openssl", "version": "3.0.11-1", "type": "deb"},
+		}
+	}
+	body := map[string]interface{}{"image": "nginx:1.25", "grype_result": map[string]interface{}{"matches": matches}}
+
+	tests := []struct {
+		name   string
+		limits IngestLimits
+		want   string
+	}{
+		{"body too large", IngestLimits{MaxBodyBytes: 64}, "larger than 64 bytes"},
+		{"too many matches", IngestLimits{HardMaxMatches: 2}, "3 matches, the limit is 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler.SetIngestLimits(tt.limits)
+			_, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", body, "")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, httpErr.Code)
+			assert.Contains(t, httpErr.Message, tt.want)
+		})
+	}
+}
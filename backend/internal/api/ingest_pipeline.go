@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// IngestConcurrency bounds the workers persisting the matches of scan submissions, so a massive
+// image is ingested in parallel without starving the submissions of other images
+type IngestConcurrency struct {
+	// Workers persist the matches of one scan, 1 persists them one at a time
+	Workers int
+	// MaxWorkers persist matches across all submissions at once, keep it below the 25 database
+	// connections. Workers take one of them per match, so scans waiting for one get their turn
+	MaxWorkers int
+	// QueueSize matches are normalized ahead of each worker, normalizing waits when its queue is full
+	QueueSize int
+}
+
+// DefaultIngestConcurrency leaves half the database connections to the rest of the API
+func DefaultIngestConcurrency() IngestConcurrency {
+	return IngestConcurrency{
+		Workers:    4,
+		MaxWorkers: 12,
+		QueueSize:  64,
+	}
+}
+
+// ingestPool holds the MaxWorkers slots shared by the submissions being ingested
+type ingestPool struct {
+	workers int
+	queue   int
+	slots   chan struct{}
+}
+
+func newIngestPool(c IngestConcurrency) *ingestPool {
+	maxWorkers := max(c.MaxWorkers, 1)
+	return &ingestPool{
+		workers: min(max(c.Workers, 1), maxWorkers),
+		queue:   max(c.QueueSize, 1),
+		slots:   make(chan struct{}, maxWorkers),
+	}
+}
+
+// ingestMatches runs the n matches of a scan through two stages: normalize turns match i into an item and
+// the key it is stored under, in order, then persist stores it on one of the scan's workers once a
+// slot of the pool is free. Items of the same key go to the same worker in order, so they never
+// race each other. Items left when ctx is done are skipped, ingestMatches returns once all are handled
+func ingestMatches[T any](ctx context.Context, pool *ingestPool, n int, normalize func(i int) (T, string), persist func(i int, item T)) {
+	if n == 0 {
+		return
+	}
+	type job struct {
+		i    int
+		item T
+	}
+
+	var wg sync.WaitGroup
+	queues := make([]chan job, min(pool.workers, n))
+	for w := range queues {
+		queues[w] = make(chan job, pool.queue)
+		wg.Add(1)
+		go func(queue <-chan job) {
+			defer wg.Done()
+			for j := range queue {
+				select {
+				case pool.slots <- struct{}{}:
+				case <-ctx.Done():
+					continue
+				}
+				persist(j.i, j.item)
+				<-pool.slots
+			}
+		}(queues[w])
+	}
+
+	for i := 0; i < n; i++ {
+		item, key := normalize(i)
+		hash := fnv.New32a()
+		hash.Write([]byte(key))
+		queues[hash.Sum32()%uint32(len(queues))] <- job{i: i, item: item}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+}
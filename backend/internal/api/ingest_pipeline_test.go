@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// peakCounter tracks how many persists run at once
+type peakCounter struct {
+	running, peak atomic.Int32
+}
+
+func (c *peakCounter) enter() {
+	n := c.running.Add(1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	c.running.Add(-1)
+}
+
+func TestIngest_PersistsEveryItem(t *testing.T) {
+	pool := newIngestPool(IngestConcurrency{Workers: 4, MaxWorkers: 8, QueueSize: 2})
+	var scan peakCounter
+	persisted := make([]int, 100)
+	ingestMatches(context.Background(), pool, len(persisted), func(i int) (int, string) {
+		return i * 2, strconv.Itoa(i)
+	}, func(i int, item int) {
+		scan.enter()
+		persisted[i] = item
+	})
+
+	for i, item := range persisted {
+		assert.Equal(t, i*2, item)
+	}
+	assert.LessOrEqual(t, scan.peak.Load(), int32(4), "at most Workers per scan")
+	assert.Greater(t, scan.peak.Load(), int32(1), "items are persisted in parallel")
+}
+
+func TestIngest_SameKeyInOrder(t *testing.T) {
+	pool := newIngestPool(IngestConcurrency{Workers: 4, MaxWorkers: 4, QueueSize: 1})
+	var mu sync.Mutex
+	order := map[string][]int{}
+	ingestMatches(context.Background(), pool, 60, func(i int) (int, string) {
+		return i, strconv.Itoa(i % 3)
+	}, func(i int, item int) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := strconv.Itoa(i % 3)
+		order[key] = append(order[key], item)
+	})
+
+	for key, items := range order {
+		assert.IsIncreasing(t, items, "items of key %s", key)
+	}
+}
+
+func TestIngest_PoolBoundsAllScans(t *testing.T) {
+	pool := newIngestPool(IngestConcurrency{Workers: 4, MaxWorkers: 3, QueueSize: 8})
+	var all peakCounter
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ingestMatches(context.Background(), pool, 30, func(i int) (int, string) {
+				return i, strconv.Itoa(i)
+			}, func(i int, item int) {
+				all.enter()
+			})
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, all.peak.Load(), int32(3), "at most MaxWorkers across scans")
+}
+
+func TestIngest_SkipsItemsOnceCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pool := newIngestPool(IngestConcurrency{Workers: 2, MaxWorkers: 2, QueueSize: 1})
+	// Fill the pool so no item can get a slot
+	pool.slots <- struct{}{}
+	pool.slots <- struct{}{}
+
+	var persisted atomic.Int32
+	ingestMatches(ctx, pool, 10, func(i int) (int, string) {
+		return i, strconv.Itoa(i)
+	}, func(i int, item int) {
+		persisted.Add(1)
+	})
+	assert.Zero(t, persisted.Load())
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRetryAfterSeconds = 300
+	maintenanceCacheTTL      = 5 * time.Second
+	maintenancePath          = "/api/v1/admin/maintenance"
+)
+
+// MaintenanceStore is the persistence used by the maintenance handler
+type MaintenanceStore interface {
+	Get(ctx context.Context) (*models.MaintenanceMode, error)
+	Set(ctx context.Context, enabled bool, reason *string, retryAfterSeconds int, updatedBy string) (*models.MaintenanceMode, error)
+}
+
+// MaintenanceHandler toggles read-only mode and enforces it on write requests
+type MaintenanceHandler struct {
+	logger *zap.Logger
+	store  MaintenanceStore
+
+	mu       sync.RWMutex
+	cached   *models.MaintenanceMode
+	cachedAt time.Time
+}
+
+func NewMaintenanceHandler(logger *zap.Logger, store MaintenanceStore) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// GetMaintenance handles GET /api/v1/admin/maintenance
+func (h *MaintenanceHandler) GetMaintenance(c echo.Context) error {
+	mode, err := h.store.Get(c.Request().Context())
+	if err != nil {
+		h.logger.Error("failed to get maintenance mode", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get maintenance mode")
+	}
+
+	return c.JSON(http.StatusOK, mode)
+}
+
+// SetMaintenance handles PUT /api/v1/admin/maintenance
+func (h *MaintenanceHandler) SetMaintenance(c echo.Context) error {
+	var req models.MaintenanceModeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	retryAfter := defaultRetryAfterSeconds
+	if req.RetryAfterSeconds != nil {
+		if *req.RetryAfterSeconds <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "retry_after_seconds must be positive")
+		}
+		retryAfter = *req.RetryAfterSeconds
+	}
+
+	user := getUserFromHeaders(c)
+	mode, err := h.store.Set(c.Request().Context(), req.Enabled, req.Reason, retryAfter, user)
+	if err != nil {
+		h.logger.Error("failed to set maintenance mode", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to set maintenance mode")
+	}
+
+	h.mu.Lock()
+	h.cached = mode
+	h.cachedAt = time.Now()
+	h.mu.Unlock()
+
+	h.logger.Info("maintenance mode updated",
+		zap.Bool("enabled", mode.Enabled),
+		zap.Int("retry_after_seconds", mode.RetryAfterSeconds),
+		zap.String("updated_by", user))
+
+	return c.JSON(http.StatusOK, mode)
+}
+
+// ReadOnly is an Echo middleware rejecting write requests with 503 while maintenance is enabled
+// Scanners treat the 503 as retryable and keep their results for a later submission
+func (h *MaintenanceHandler) ReadOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return next(c)
+		}
+
+		// Admins must be able to turn maintenance back off
+		if c.Path() == maintenancePath {
+			return next(c)
+		}
+
+		mode := h.current(c.Request().Context())
+		if mode == nil || !mode.Enabled {
+			return next(c)
+		}
+
+		retryAfter := mode.RetryAfterSeconds
+		if retryAfter <= 0 {
+			retryAfter = defaultRetryAfterSeconds
+		}
+		c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+		message := "service is in read-only maintenance mode"
+		if mode.Reason != nil && *mode.Reason != "" {
+			message += ": " + *mode.Reason
+		}
+		return echo.NewHTTPError(http.StatusServiceUnavailable, message)
+	}
+}
+
+// current returns the maintenance state, refreshing from the store at most every few seconds
+// so that toggles made on one replica propagate to the others
+func (h *MaintenanceHandler) current(ctx context.Context) *models.MaintenanceMode {
+	h.mu.RLock()
+	if h.cached != nil && time.Since(h.cachedAt) < maintenanceCacheTTL {
+		mode := h.cached
+		h.mu.RUnlock()
+		return mode
+	}
+	h.mu.RUnlock()
+
+	mode, err := h.store.Get(ctx)
+	if err != nil {
+		// Fail open: a broken maintenance lookup must not take down writes
+		h.logger.Warn("failed to refresh maintenance mode", zap.Error(err))
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		return h.cached
+	}
+
+	h.mu.Lock()
+	h.cached = mode
+	h.cachedAt = time.Now()
+	h.mu.Unlock()
+
+	return mode
+}
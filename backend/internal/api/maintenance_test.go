@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeMaintenanceStore struct {
+	mode models.MaintenanceMode
+}
+
+func (s *fakeMaintenanceStore) Get(ctx context.Context) (*models.MaintenanceMode, error) {
+	mode := s.mode
+	return &mode, nil
+}
+
+func (s *fakeMaintenanceStore) Set(ctx context.Context, enabled bool, reason *string, retryAfterSeconds int, updatedBy string) (*models.MaintenanceMode, error) {
+	s.mode = models.MaintenanceMode{
+		Enabled:           enabled,
+		Reason:            reason,
+		RetryAfterSeconds: retryAfterSeconds,
+		UpdatedBy:         &updatedBy,
+	}
+	mode := s.mode
+	return &mode, nil
+}
+
+func newMaintenanceTestServer(store MaintenanceStore) (*echo.Echo, *MaintenanceHandler) {
+	handler := NewMaintenanceHandler(zap.NewNop(), store)
+
+	e := echo.New()
+	e.Use(handler.ReadOnly)
+	e.POST("/api/v1/scans", func(c echo.Context) error { return c.NoContent(http.StatusCreated) })
+	e.GET("/api/v1/scans", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.PUT(maintenancePath, handler.SetMaintenance)
+	return e, handler
+}
+
+func TestMaintenance_ReadOnlyRejectsWrites(t *testing.T) {
+	store := &fakeMaintenanceStore{mode: models.MaintenanceMode{Enabled: true, RetryAfterSeconds: 120}}
+	e, _ := newMaintenanceTestServer(store)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scans", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "120", rec.Header().Get("Retry-After"))
+}
+
+func TestMaintenance_ReadOnlyAllowsReads(t *testing.T) {
+	store := &fakeMaintenanceStore{mode: models.MaintenanceMode{Enabled: true, RetryAfterSeconds: 120}}
+	e, _ := newMaintenanceTestServer(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scans", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMaintenance_DisableWhileEnabled(t *testing.T) {
+	store := &fakeMaintenanceStore{mode: models.MaintenanceMode{Enabled: true, RetryAfterSeconds: 120}}
+	e, _ := newMaintenanceTestServer(store)
+
+	// The toggle endpoint itself must stay writable
+	req := httptest.NewRequest(http.MethodPut, maintenancePath, strings.NewReader(`{"enabled": false}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, store.mode.Enabled)
+
+	// Writes go through again immediately on this replica
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/scans", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
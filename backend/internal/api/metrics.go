@@ -3,7 +3,9 @@ package api
 import (
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/invulnerable/backend/internal/compliance"
 	"github.com/invulnerable/backend/internal/metrics"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
@@ -12,6 +14,9 @@ import (
 type MetricsHandler struct {
 	logger         *zap.Logger
 	metricsService *metrics.Service
+
+	// Compliance profiles, see SetComplianceProfiles
+	profiles []compliance.Profile
 }
 
 func NewMetricsHandler(logger *zap.Logger, metricsService *metrics.Service) *MetricsHandler {
@@ -21,6 +26,11 @@ func NewMetricsHandler(logger *zap.Logger, metricsService *metrics.Service) *Met
 	}
 }
 
+// SetComplianceProfiles enables the compliance metrics of the given profiles
+func (h *MetricsHandler) SetComplianceProfiles(profiles []compliance.Profile) {
+	h.profiles = profiles
+}
+
 // GetMetrics handles GET /api/v1/metrics
 func (h *MetricsHandler) GetMetrics(c echo.Context) error {
 	// Parse has_fix parameter
@@ -47,3 +57,78 @@ func (h *MetricsHandler) GetMetrics(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, metrics)
 }
+
+// GetVulnerabilityAge handles GET /api/v1/metrics/vulnerability-age?namespace=payments&has_fix=true
+// It returns the open vulnerabilities bucketed by age, severity and team, for the aging debt chart
+func (h *MetricsHandler) GetVulnerabilityAge(c echo.Context) error {
+	var hasFix *bool
+	if hasFixStr := c.QueryParam("has_fix"); hasFixStr != "" {
+		hasFixBool, err := strconv.ParseBool(hasFixStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid has_fix parameter")
+		}
+		hasFix = &hasFixBool
+	}
+
+	var namespace *string
+	if ns := c.QueryParam("namespace"); ns != "" {
+		namespace = &ns
+	}
+
+	age, err := h.metricsService.GetVulnerabilityAge(c.Request().Context(), hasFix, namespace)
+	if err != nil {
+		h.logger.Error("failed to get vulnerability age", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get vulnerability age")
+	}
+
+	return c.JSON(http.StatusOK, age)
+}
+
+// GetCompliance handles GET /api/v1/metrics/compliance
+// It returns the share of open findings within the remediation timeline of each compliance profile
+func (h *MetricsHandler) GetCompliance(c echo.Context) error {
+	if len(h.profiles) == 0 {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "compliance metrics are disabled, set COMPLIANCE_PROFILES_FILE to enable them")
+	}
+
+	now := time.Now().UTC()
+	results, err := h.metricsService.GetCompliance(c.Request().Context(), h.profiles, now)
+	if err != nil {
+		h.logger.Error("failed to get compliance metrics", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get compliance metrics")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"evaluated_at": now,
+		"profiles":     results,
+	})
+}
+
+// defaultScanPerformanceDays is the window of the scan performance metrics without days
+const defaultScanPerformanceDays = 30
+
+// GetScanPerformance handles GET /api/v1/metrics/scan-performance?namespace=payments&days=30
+// It returns the scan duration and size of every image, flagging those disproportionately slow to scan
+func (h *MetricsHandler) GetScanPerformance(c echo.Context) error {
+	days := defaultScanPerformanceDays
+	if s := c.QueryParam("days"); s != "" {
+		d, err := strconv.Atoi(s)
+		if err != nil || d < 1 || d > 90 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid days parameter, expected 1 to 90")
+		}
+		days = d
+	}
+
+	var namespace *string
+	if ns := c.QueryParam("namespace"); ns != "" {
+		namespace = &ns
+	}
+
+	performance, err := h.metricsService.GetScanPerformance(c.Request().Context(), namespace, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		h.logger.Error("failed to get scan performance", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get scan performance")
+	}
+
+	return c.JSON(http.StatusOK, performance)
+}
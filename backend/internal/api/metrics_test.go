@@ -21,7 +21,7 @@ func TestMetricsHandler_GetMetrics(t *testing.T) {
 	defer database.Close()
 
 	logger := zap.NewNop()
-	metricsService := metrics.New(database)
+	metricsService := metrics.New(database, logger)
 	handler := NewMetricsHandler(logger, metricsService)
 
 	// Create test data
@@ -83,7 +83,7 @@ func TestMetricsHandler_GetMetrics_WithHasFixFilter(t *testing.T) {
 	defer database.Close()
 
 	logger := zap.NewNop()
-	metricsService := metrics.New(database)
+	metricsService := metrics.New(database, logger)
 	handler := NewMetricsHandler(logger, metricsService)
 
 	// Create vulnerability with fix
@@ -119,7 +119,7 @@ func TestMetricsHandler_GetMetrics_InvalidHasFix(t *testing.T) {
 	defer database.Close()
 
 	logger := zap.NewNop()
-	metricsService := metrics.New(database)
+	metricsService := metrics.New(database, logger)
 	handler := NewMetricsHandler(logger, metricsService)
 
 	e := echo.New()
@@ -141,7 +141,7 @@ func TestMetricsHandler_GetMetrics_EmptyDatabase(t *testing.T) {
 	defer database.Close()
 
 	logger := zap.NewNop()
-	metricsService := metrics.New(database)
+	metricsService := metrics.New(database, logger)
 	handler := NewMetricsHandler(logger, metricsService)
 
 	e := echo.New()
@@ -157,3 +157,18 @@ func TestMetricsHandler_GetMetrics_EmptyDatabase(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), `"total_scans":0`)
 	assert.Contains(t, rec.Body.String(), `"total_vulnerabilities":0`)
 }
+
+func TestMetricsHandler_GetScanPerformance_InvalidDays(t *testing.T) {
+	handler := NewMetricsHandler(zap.NewNop(), nil)
+
+	for _, days := range []string{"0", "91", "month"} {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/metrics/scan-performance?days="+days, nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		err := handler.GetScanPerformance(c)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, days)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, days)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// defaultTrendDays is the window of the findings trend of namespaces without trend_days
+const defaultTrendDays = 7
+
+// NamespaceStore is the persistence used by the namespace handler
+type NamespaceStore interface {
+	ListSummaries(ctx context.Context, now, trendSince time.Time) ([]models.NamespaceSummary, error)
+}
+
+// NamespaceHandler rolls up coverage, findings and SLA compliance per namespace
+type NamespaceHandler struct {
+	logger   *zap.Logger
+	store    NamespaceStore
+	coverage CoverageStore
+	// staleThreshold is the age from which the latest scan of a deployed image is stale
+	staleThreshold time.Duration
+}
+
+func NewNamespaceHandler(logger *zap.Logger, store NamespaceStore, coverage CoverageStore, staleThreshold time.Duration) *NamespaceHandler {
+	return &NamespaceHandler{
+		logger:         logger,
+		store:          store,
+		coverage:       coverage,
+		staleThreshold: staleThreshold,
+	}
+}
+
+// ListNamespaces handles GET /api/v1/namespaces
+// Namespaces with an ImageScan, findings or deployed images are listed by name
+func (h *NamespaceHandler) ListNamespaces(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	trendDays := defaultTrendDays
+	if s := c.QueryParam("trend_days"); s != "" {
+		days, err := strconv.Atoi(s)
+		if err != nil || days < 1 || days > 90 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid trend_days parameter, expected 1 to 90")
+		}
+		trendDays = days
+	}
+	namespace := c.QueryParam("namespace")
+
+	now := time.Now()
+	summaries, err := h.store.ListSummaries(ctx, now, now.AddDate(0, 0, -trendDays))
+	if err != nil {
+		h.logger.Error("failed to list namespace summaries", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list namespaces")
+	}
+	images, err := h.coverage.ListCoverage(ctx, namespace)
+	if err != nil {
+		h.logger.Error("failed to list coverage", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list namespaces")
+	}
+
+	// Deployed images by namespace, an image deployed in several namespaces counts in each
+	deployed := map[string][]models.ImageCoverage{}
+	for _, image := range images {
+		for _, ns := range image.Namespaces {
+			if ns != "" {
+				deployed[ns] = append(deployed[ns], image)
+			}
+		}
+	}
+
+	result := []models.NamespaceSummary{}
+	for _, summary := range summaries {
+		if namespace != "" && summary.Namespace != namespace {
+			continue
+		}
+		summary.Coverage = summarizeCoverage(deployed[summary.Namespace], now.Add(-h.staleThreshold))
+		delete(deployed, summary.Namespace)
+		result = append(result, summary)
+	}
+	for ns, nsImages := range deployed {
+		if namespace != "" && ns != namespace {
+			continue
+		}
+		result = append(result, models.NamespaceSummary{
+			Namespace: ns,
+			Coverage:  summarizeCoverage(nsImages, now.Add(-h.staleThreshold)),
+			SLA:       models.NamespaceSLA{CompliancePercent: 100},
+			Trend:     models.NamespaceTrend{WindowDays: trendDays, Direction: models.TrendFlat},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Namespace < result[j].Namespace })
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"generated_at":      now.UTC(),
+		"stale_after_hours": int(h.staleThreshold.Hours()),
+		"trend_days":        trendDays,
+		"data":              result,
+	})
+}
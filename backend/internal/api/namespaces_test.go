@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeNamespaceStore struct {
+	summaries  []models.NamespaceSummary
+	trendSince time.Time
+}
+
+func (s *fakeNamespaceStore) ListSummaries(ctx context.Context, now, trendSince time.Time) ([]models.NamespaceSummary, error) {
+	s.trendSince = trendSince
+	return append([]models.NamespaceSummary{}, s.summaries...), nil
+}
+
+func TestNamespaceHandler_ListNamespaces(t *testing.T) {
+	recent := time.Now().Add(-time.Hour)
+	imageID := 1
+	store := &fakeNamespaceStore{summaries: []models.NamespaceSummary{
+		{Namespace: "payments", ImageScans: 2, OpenFindings: models.SeverityCounts{Critical: 1, Total: 1},
+			SLA: models.NamespaceSLA{Overdue: 1}, Trend: models.NamespaceTrend{WindowDays: 7, Detected: 1, Direction: models.TrendUp}},
+		{Namespace: "web", ImageScans: 1, SLA: models.NamespaceSLA{CompliancePercent: 100}, Trend: models.NamespaceTrend{WindowDays: 7, Direction: models.TrendFlat}},
+	}}
+	coverage := &fakeCoverageStore{coverage: []models.ImageCoverage{
+		{Repository: "nginx", Namespaces: pq.StringArray{"batch", "web"}, ImageID: &imageID, LastScanDate: &recent},
+		{Repository: "api", Namespaces: pq.StringArray{"payments"}},
+		{Repository: "worker", Namespaces: pq.StringArray{""}},
+	}}
+	handler := NewNamespaceHandler(zap.NewNop(), store, coverage, 48*time.Hour)
+	e := echo.New()
+	e.GET("/api/v1/namespaces", handler.ListNamespaces)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -7), store.trendSince, time.Minute)
+
+	var resp struct {
+		TrendDays int                       `json:"trend_days"`
+		Data      []models.NamespaceSummary `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 7, resp.TrendDays)
+	require.Len(t, resp.Data, 3, "images outside a namespace are left out")
+
+	// Namespaces only known from the inventory are listed too
+	batch, payments, web := resp.Data[0], resp.Data[1], resp.Data[2]
+	assert.Equal(t, "batch", batch.Namespace)
+	assert.Equal(t, models.CoverageSummary{Deployed: 1, Scanned: 1, WithoutImageScan: 1, CoveragePercent: 100}, batch.Coverage)
+	assert.Equal(t, models.TrendFlat, batch.Trend.Direction)
+	assert.Equal(t, 100.0, batch.SLA.CompliancePercent)
+	assert.Equal(t, "payments", payments.Namespace)
+	assert.Equal(t, 1, payments.Coverage.Unscanned)
+	assert.Equal(t, models.TrendUp, payments.Trend.Direction)
+	assert.Equal(t, 1, web.Coverage.Scanned)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/namespaces?namespace=payments&trend_days=30", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, "payments", resp.Data[0].Namespace)
+	assert.Equal(t, "payments", coverage.namespace)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -30), store.trendSince, time.Minute)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/namespaces?trend_days=0", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
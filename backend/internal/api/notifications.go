@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
+	"go.uber.org/zap"
+)
+
+// scanNotificationHold delays the notification of a scan whose results weren't processed, long
+// enough for the largest scans. Processing releases it as soon as it is done
+const scanNotificationHold = 10 * time.Minute
+
+// Payloads of the notification outbox events, decoded by the Deliver methods
+
+type scanCompletedEvent struct {
+	Image       string                 `json:"image"`
+	ImageDigest *string                `json:"image_digest,omitempty"`
+	Webhook     notifier.WebhookConfig `json:"webhook"`
+}
+
+type watchlistNotification struct {
+	SubscriptionID int                                   `json:"subscription_id"`
+	Webhook        notifier.WebhookConfig                `json:"webhook"`
+	Payload        notifier.WatchlistNotificationPayload `json:"payload"`
+}
+
+// fixAvailableNotification names the ImageScan instead of carrying its webhook, which is looked up
+// on delivery with its current settings
+type fixAvailableNotification struct {
+	Namespace string                                   `json:"namespace"`
+	Name      string                                   `json:"name"`
+	Payload   notifier.FixAvailableNotificationPayload `json:"payload"`
+}
+
+type imageDiscoveredNotification struct {
+	Webhook notifier.WebhookConfig               `json:"webhook"`
+	Payload notifier.NewImageNotificationPayload `json:"payload"`
+}
+
+type statusChangeEvent struct {
+	VulnerabilityID int    `json:"vulnerability_id"`
+	ChangedBy       string `json:"changed_by"`
+}
+
+// DeliverScanCompleted sends the webhook of a scan_completed outbox event. The counts come from
+// the vulnerabilities of the scan as stored, without the ignored and accepted ones
+func (h *ScanHandler) DeliverScanCompleted(ctx context.Context, event *models.OutboxEvent) error {
+	var e scanCompletedEvent
+	if err := event.Decode(&e); err != nil {
+		return err
+	}
+	if event.ScanID == nil {
+		return fmt.Errorf("scan_completed event %d has no scan", event.ID)
+	}
+	scanID := *event.ScanID
+
+	vulns, err := h.scanRepo.GetVulnerabilities(ctx, scanID)
+	if err != nil {
+		return fmt.Errorf("failed to get vulnerabilities of scan %d: %w", scanID, err)
+	}
+
+	// Only actionable vulnerabilities: ignored and accepted ones were triaged away
+	total := 0
+	severityCounts := notifier.SeverityCounts{}
+	for _, v := range vulns {
+		if v.Status == models.StatusIgnored || v.Status == models.StatusAccepted {
+			continue
+		}
+		if e.Webhook.OnlyFixable && v.FixVersion == nil {
+			continue
+		}
+		total++
+		switch v.Severity {
+		case "Critical":
+			severityCounts.Critical++
+		case "High":
+			severityCounts.High++
+		case "Medium":
+			severityCounts.Medium++
+		case "Low":
+			severityCounts.Low++
+		default:
+			severityCounts.Negligible++
+		}
+	}
+
+	if total == 0 {
+		h.logger.Info("no actionable vulnerabilities to notify about (all ignored/accepted)",
+			zap.Int("scan_id", scanID),
+			zap.Int("total_detected", len(vulns)))
+	}
+
+	return h.notifier.SendNotification(ctx, e.Webhook, notifier.NotificationPayload{
+		Image:          e.Image,
+		ImageDigest:    e.ImageDigest,
+		ScanID:         scanID,
+		TotalVulns:     total,
+		SeverityCounts: severityCounts,
+	})
+}
+
+// DeliverWatchlist sends the webhook of a watchlist outbox event
+func (h *ScanHandler) DeliverWatchlist(ctx context.Context, event *models.OutboxEvent) error {
+	var e watchlistNotification
+	if err := event.Decode(&e); err != nil {
+		return err
+	}
+	if err := h.notifier.SendWatchlistNotification(ctx, e.Webhook, e.Payload); err != nil {
+		return fmt.Errorf("failed to send watchlist notification to subscription %d: %w", e.SubscriptionID, err)
+	}
+	return nil
+}
+
+// DeliverImageDiscovered sends the webhook of an image_discovered outbox event
+func (h *ScanHandler) DeliverImageDiscovered(ctx context.Context, event *models.OutboxEvent) error {
+	var e imageDiscoveredNotification
+	if err := event.Decode(&e); err != nil {
+		return err
+	}
+	if event.ScanID != nil {
+		e.Payload.ScanID = *event.ScanID
+	}
+	if err := h.notifier.SendNewImageNotification(ctx, e.Webhook, e.Payload); err != nil {
+		return fmt.Errorf("failed to send new image notification for image %d: %w", e.Payload.ImageID, err)
+	}
+	return nil
+}
+
+// DeliverStatusChange sends the webhook of a status_change outbox event
+func (h *VulnerabilityHandler) DeliverStatusChange(ctx context.Context, event *models.OutboxEvent) error {
+	var e statusChangeEvent
+	if err := event.Decode(&e); err != nil {
+		return err
+	}
+	return h.sendStatusChangeWebhook(ctx, e.VulnerabilityID, e.ChangedBy)
+}
+
+// DeliverFixAvailable sends the webhook of a fix_available outbox event, unless the ImageScan no
+// longer wants fix-available notifications
+func (h *VulnerabilityHandler) DeliverFixAvailable(ctx context.Context, event *models.OutboxEvent) error {
+	var e fixAvailableNotification
+	if err := event.Decode(&e); err != nil {
+		return err
+	}
+
+	config, err := h.webhookConfigRepo.Get(ctx, e.Namespace, e.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook config of %s/%s: %w", e.Namespace, e.Name, err)
+	}
+	if config == nil || !config.FixAvailableEnabled {
+		h.logger.Info("fix-available webhooks not enabled, skipping",
+			zap.String("namespace", e.Namespace),
+			zap.String("name", e.Name))
+		return nil
+	}
+
+	webhook := notifier.WebhookConfig{
+		URL:         config.WebhookURL,
+		Format:      config.WebhookFormat,
+		MinSeverity: config.FixAvailableMinSeverity,
+		Locale:      config.Locale,
+	}
+	if err := h.notifier.SendFixAvailableNotification(ctx, webhook, e.Payload); err != nil {
+		return fmt.Errorf("failed to send fix-available notification to %s/%s: %w", e.Namespace, e.Name, err)
+	}
+	return nil
+}
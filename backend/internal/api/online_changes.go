@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// OnlineChangeStatusSource reports the online schema changes, implemented by online.Runner
+type OnlineChangeStatusSource interface {
+	Status(ctx context.Context) ([]models.OnlineChangeStatus, error)
+}
+
+// OnlineChangeHandler exposes the progress of the index builds and backfills applied in the background
+type OnlineChangeHandler struct {
+	logger  *zap.Logger
+	changes OnlineChangeStatusSource
+}
+
+func NewOnlineChangeHandler(logger *zap.Logger, changes OnlineChangeStatusSource) *OnlineChangeHandler {
+	return &OnlineChangeHandler{
+		logger:  logger,
+		changes: changes,
+	}
+}
+
+// ListOnlineChanges handles GET /api/v1/admin/online-changes
+func (h *OnlineChangeHandler) ListOnlineChanges(c echo.Context) error {
+	statuses, err := h.changes.Status(c.Request().Context())
+	if err != nil {
+		h.logger.Error("failed to get online schema change status", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get online schema change status")
+	}
+
+	return c.JSON(http.StatusOK, statuses)
+}
@@ -0,0 +1,809 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/invulnerable/backend/internal/metrics"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/openapi"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// openAPIPrefix is the prefix of the documented routes
+const openAPIPrefix = "/api/v1"
+
+var (
+	hasFixParam        = openapi.Query("has_fix", "boolean", "Only count or list vulnerabilities with (true) or without (false) a fix")
+	limitParam         = openapi.Query("limit", "integer", "Page size, 1 to 100")
+	offsetParam        = openapi.Query("offset", "integer", "Number of results to skip")
+	asOfParam          = openapi.Query("as_of", "string", "Date or RFC 3339 time to list the results as they were then")
+	previousParam      = openapi.Query("previous_scan_id", "integer", "Scan to compare with, the previous scan of the image by default")
+	previousImageParam = openapi.Query("previous_image", "string", "Image to compare with instead of previous_scan_id, e.g. another tag, by its latest scan of the same target")
+	orderParam         = openapi.Query("order", "string", "asc or desc, the natural direction of the sort field by default: latest, most severe and largest first, names A to Z")
+	paginationArgs     = []openapi.Param{limitParam, offsetParam}
+
+	purgeOrphanVulnsParam = openapi.Query("purge_orphan_vulns", "boolean", "Also delete the vulnerabilities no other scan found")
+)
+
+// openAPIOperations documents the routes of the API, keyed by method and path relative to /api/v1 as they
+// are registered. Every route of cmd/server needs one, its tests fail on a route missing here
+var openAPIOperations = map[string]openapi.Operation{
+	// API documentation
+	"GET /openapi.json": {Summary: "Get the OpenAPI document of the API"},
+	"GET /docs":         {Summary: "Browse the API in Swagger UI", ResponseType: "text/html"},
+
+	// Scans
+	"POST /scans": {
+		Summary:     "Submit scan results",
+		Description: "Scanners submit the SBOM and Grype results of an image, or register a pending scan before running it (201 with the scan). Results are processed in the background: the scan is running until they are, see GET /ingest-jobs/{id}. A submission with the scan_uuid of a stored scan returns it with 200 instead of creating another.",
+		Request:     ScanRequest{},
+		Response:    ScanAccepted{},
+		Status:      http.StatusAccepted,
+	},
+	"GET /ingest-jobs/:id": {
+		Summary:     "Get the processing of submitted scan results",
+		Description: "The job is completed once the scan has its final status, or failed after its last attempt, failing the scan.",
+		Response:    models.IngestJob{},
+	},
+	"GET /scans": {
+		Summary: "List scans",
+		Query: append([]openapi.Param{
+			openapi.Query("image_id", "integer", "Only scans of an image"),
+			openapi.Query("image", "string", "Only scans of images matching a name"),
+			openapi.Query("status", "string", "Only scans with a status: pending, running, completed, partial or failed"),
+			openapi.Query("target", "string", "Only scans of a target"),
+			hasFixParam,
+			openapi.Query("sort", "string", "Order by scan_date (default), severity, vulnerability_count or image_name"),
+			orderParam,
+		}, paginationArgs...),
+		Response:  models.ScanWithDetails{},
+		Paginated: true,
+	},
+	"GET /scans/:id": {
+		Summary: "Get a scan with its vulnerabilities",
+		Query:   []openapi.Param{hasFixParam},
+		Response: struct {
+			Scan            models.ScanWithDetails `json:"scan"`
+			Vulnerabilities []models.Vulnerability `json:"vulnerabilities"`
+		}{},
+	},
+	"PATCH /scans/:id": {
+		Summary:     "Report the status of a scan",
+		Description: "Scanners report progress and failures. Completed and partial scans are submitted with POST /scans.",
+		Request:     models.ScanStatusUpdate{},
+		Response:    models.Scan{},
+	},
+	"DELETE /scans/:id": {
+		Summary:     "Delete a scan",
+		Description: "Admins only. Removes the vulnerability links, SBOM and archived Grype result of the scan.",
+		Query:       []openapi.Param{purgeOrphanVulnsParam},
+		Status:      http.StatusNoContent,
+	},
+	"GET /scans/:id/sbom": {Summary: "Get the SBOM of a scan"},
+	"GET /scans/:id/sbom/url": {
+		Summary:     "Get a pre-signed URL downloading the SBOM of a scan from S3",
+		Description: "Large SBOMs are downloaded from S3 directly rather than through the API. The document is served as stored, compressed with a Content-Encoding of zstd when SBOM_S3_COMPRESSION is on.",
+		Query: []openapi.Param{
+			openapi.Query("expires", "integer", "Seconds the URL is valid for, 60 to 604800 (default 3600)"),
+		},
+		Response: SBOMURLResponse{},
+	},
+	"GET /scans/:id/grype-result": {Summary: "Get the raw Grype result of a scan"},
+	"GET /scans/:id/diff": {
+		Summary:     "Compare a scan with a previous one",
+		Description: "The scan compared with may be of another image, to evaluate an upgrade.",
+		Query:       []openapi.Param{previousParam, previousImageParam},
+		Response:    models.ScanDiff{},
+	},
+	"POST /scans/:id/apply-diff": {
+		Summary:     "Mark the vulnerabilities missing from a scan as fixed",
+		Query:       []openapi.Param{previousParam},
+		Response:    models.ScanDiff{},
+		Description: "Takes no body.",
+	},
+	"GET /scans/:id/sbom-diff": {
+		Summary:  "Compare the SBOM of a scan with a previous one",
+		Query:    []openapi.Param{previousParam, previousImageParam},
+		Response: models.SBOMDiff{},
+	},
+	"GET /scans/:id/sbom-quality": {
+		Summary:     "Get the quality of the SBOM of a scan",
+		Description: "Scores the SBOM from 0 to 100 by its purl, license and hash coverage, the NTIA minimum elements and its number of components for the size of the image. Low-quality SBOMs hide vulnerabilities of the packages they miss.",
+		Response:    models.SBOMQuality{},
+	},
+	"GET /sbom-quality": {
+		Summary:     "List the quality of the latest SBOM of each image",
+		Description: "Lowest score first.",
+		Query: []openapi.Param{
+			openapi.Query("low_quality", "boolean", "Only the SBOMs flagged as low quality"),
+			openapi.Query("image_name", "string", "Substring of the image name (case-insensitive)"),
+		},
+		Response: []models.SBOMQualityEntry{},
+	},
+	"GET /scans/:id/summary": {
+		Summary:  "Get the vulnerability counts of a scan",
+		Response: models.ScanSummary{},
+	},
+	"GET /scans/:id/gate": {
+		Summary: "Evaluate a severity gate on a scan",
+		Query: []openapi.Param{
+			openapi.Query("fail_on", "string", "Lowest failing severity, high by default"),
+			openapi.Query("only_fixable", "boolean", "Only fail on vulnerabilities with a fix"),
+		},
+		Response: models.ScanGate{},
+	},
+	"GET /scans/:id/sarif": {
+		Summary:     "Export the results of a scan as SARIF 2.1.0",
+		Description: "Served as an attachment, for GitHub Code Scanning and other SARIF consumers.",
+	},
+	"GET /scans/:id/report": {Summary: "Get the signed report of a scan"},
+	"GET /reports/signing-key": {
+		Summary:     "Get the public key scan reports are signed with",
+		Description: "Responds 503 when REPORT_SIGNING_KEY_FILE is not set.",
+		Response: struct {
+			KeyID       string `json:"key_id"`
+			Algorithm   string `json:"algorithm"`
+			PayloadType string `json:"payload_type"`
+			PublicKey   string `json:"public_key"`
+		}{},
+	},
+	"GET /scans/:id/share": {
+		Summary:     "Create a link sharing the report of a scan",
+		Description: "Anyone with the link can read the report until it expires, without signing in.",
+		Query: []openapi.Param{
+			openapi.Query("ttl_hours", "integer", "Lifetime of the link in hours, 1 to 720 (default 72)"),
+		},
+		Response: models.ShareLink{},
+	},
+	"GET /shared/scans/:token": {
+		Summary:     "Get a shared scan report",
+		Description: "The token of the share link is the credential. Responds 410 once it expired.",
+		Response:    models.SharedScanReport{},
+	},
+
+	// Vulnerabilities
+	"GET /vulnerabilities": {
+		Summary: "List vulnerabilities by image",
+		Query: append([]openapi.Param{
+			openapi.Query("severity", "string", "Only vulnerabilities of a severity"),
+			openapi.Query("status", "string", "Only vulnerabilities with a status"),
+			openapi.Query("image_id", "integer", "Only vulnerabilities of an image"),
+			openapi.Query("image_name", "string", "Only vulnerabilities of images matching a name"),
+			openapi.Query("cve_id", "string", "Only vulnerabilities matching a CVE ID"),
+			openapi.Query("flapping", "boolean", "Only vulnerabilities that keep coming back after being fixed (true) or the others (false)"),
+			openapi.Query("exposed", "boolean", "Only vulnerabilities of images deployed (true) or not (false) in an exposed workload"),
+			hasFixParam,
+			asOfParam,
+			openapi.Query("sort", "string", "Order by severity, first_detected_at, package_name or last_seen_at instead of vulnerability and image"),
+			orderParam,
+		}, paginationArgs...),
+		Response:  models.VulnerabilityWithImageInfo{},
+		Paginated: true,
+	},
+	"GET /vulnerabilities/search": {
+		Summary:     "Search vulnerabilities",
+		Description: "Each vulnerability is returned once, the best matches of q first. Dates stand for the start of the day in detected_after and its end in detected_before.",
+		Query: append([]openapi.Param{
+			openapi.Query("package", "string", "Only vulnerabilities of packages whose name contains this, case-insensitive"),
+			openapi.Query("cve", "string", "Only vulnerabilities whose CVE ID starts with this, case-insensitive"),
+			openapi.Query("q", "string", "Full-text search of the descriptions, in web search syntax (\"remote code\" -windows)"),
+			openapi.Query("min_severity", "string", "Only vulnerabilities of this severity or above"),
+			openapi.Query("max_severity", "string", "Only vulnerabilities of this severity or below"),
+			openapi.Query("status", "string", "Only vulnerabilities with a status"),
+			openapi.Query("detected_after", "string", "Only vulnerabilities first detected at or after this date or RFC 3339 time"),
+			openapi.Query("detected_before", "string", "Only vulnerabilities first detected at or before this date or RFC 3339 time"),
+			hasFixParam,
+		}, paginationArgs...),
+		Response:  models.Vulnerability{},
+		Paginated: true,
+	},
+	"GET /vulnerabilities/:cve": {
+		Summary:  "Get the vulnerabilities of a CVE",
+		Response: []models.Vulnerability{},
+	},
+	"PATCH /vulnerabilities/:id": {
+		Summary:  "Triage a vulnerability",
+		Request:  models.VulnerabilityUpdate{},
+		Response: models.Vulnerability{},
+	},
+	"PATCH /vulnerabilities/bulk": {
+		Summary: "Triage up to 100 vulnerabilities",
+		Request: models.BulkUpdateRequest{},
+		Response: struct {
+			UpdatedCount int     `json:"updated_count"`
+			Status       *string `json:"status"`
+		}{},
+	},
+	"POST /vulnerabilities/batch-get": {
+		Summary:  "Get vulnerabilities by ID",
+		Request:  models.BatchGetRequest{},
+		Response: models.BatchGetResponse{},
+	},
+	"POST /vulnerabilities/import": {
+		Summary:     "Import triage decisions from CSV",
+		Description: "Rows are matched by CVE, package and image. Nothing is written when a row is invalid or dry_run is set.",
+		Query:       []openapi.Param{openapi.Query("dry_run", "boolean", "Validate the rows without writing them")},
+		RequestType: "text/csv",
+		Response:    models.TriageImportResult{},
+	},
+	"GET /vulnerabilities/:cve/severity-changes": {
+		Summary:  "Get the severity changes of a CVE",
+		Response: []models.SeverityChange{},
+	},
+	"GET /vulnerabilities/:id/history": {
+		Summary:  "Get the status history of a vulnerability",
+		Response: []models.VulnerabilityHistory{},
+	},
+	"GET /vulnerabilities/:id/links": {
+		Summary: "List the links attached to a vulnerability",
+		Query: []openapi.Param{
+			openapi.Query("image_id", "integer", "Only the links of an image"),
+		},
+		Response: []models.VulnerabilityLink{},
+	},
+	"POST /vulnerabilities/:id/links": {
+		Summary:     "Attach a link to a vulnerability on an image",
+		Description: "Links such as the MR/PR fixing the vulnerability are recorded in its history and listed in its status change notifications. The vulnerability must have been found on the image.",
+		Request:     models.VulnerabilityLinkRequest{},
+		Response:    models.VulnerabilityLink{},
+		Status:      http.StatusCreated,
+	},
+	"DELETE /vulnerabilities/:id/links/:link_id": {
+		Summary: "Remove a link from a vulnerability",
+		Status:  http.StatusNoContent,
+	},
+	"GET /vulnerabilities/export": {
+		Summary:      "Export vulnerabilities as CSV or JSON",
+		Description:  "Streams every vulnerability+image combination matching the filters of GET /vulnerabilities, without its page limit, as an attachment. The CSV columns include those of triage imports, so an edited export can be imported back.",
+		Query:        []openapi.Param{openapi.Query("format", "string", "csv (default) or json")},
+		ResponseType: "text/csv",
+	},
+	"GET /vulnerabilities/:id/workloads": {
+		Summary:     "List the deployed workloads running a vulnerability",
+		Description: "Workloads of the inventory running an image whose latest successful scan has the vulnerability.",
+		Response:    models.VulnerabilityWorkloads{},
+	},
+
+	// Components and impact
+	"GET /components": {
+		Summary: "Find the packages matching a purl",
+		Query: []openapi.Param{
+			{Name: "purl", Type: "string", Description: "Package URL, pkg:type/name[@version]", Required: true},
+		},
+		Response: []models.Component{},
+	},
+	"POST /impact": {
+		Summary:     "Assess the impact of a vulnerable package",
+		Description: "Lists the images whose latest SBOM has the package, identified by a CVE, its name or purl, with the findings Grype already reported for the CVE.",
+		Request:     models.ImpactRequest{},
+		Response:    models.ImpactReport{},
+	},
+	"GET /export/bom": {
+		Summary:      "Export the latest SBOMs as one CycloneDX BOM",
+		Description:  "Merges the latest SBOM of each image and target, served as an attachment.",
+		ResponseType: "application/vnd.cyclonedx+json",
+		Query: []openapi.Param{
+			openapi.Query("format", "string", "cyclonedx, the only format"),
+			openapi.Query("image_name", "string", "Only images matching a name"),
+		},
+	},
+
+	// Watchlist
+	"GET /watchlist": {
+		Summary:  "List the watchlist subscriptions of the current user",
+		Response: []models.WatchlistSubscription{},
+	},
+	"POST /watchlist": {
+		Summary:     "Watch a CVE or package",
+		Description: "New findings matching the subscription are sent to its webhook.",
+		Request:     models.WatchlistSubscriptionRequest{},
+		Response:    models.WatchlistSubscription{},
+		Status:      http.StatusCreated,
+	},
+	"DELETE /watchlist/:id": {
+		Summary:     "Delete a watchlist subscription",
+		Description: "Users can only delete their own subscriptions.",
+		Status:      http.StatusNoContent,
+	},
+
+	// Campaigns
+	"GET /campaigns": {
+		Summary: "List remediation campaigns with their progress",
+		Query: []openapi.Param{
+			openapi.Query("status", "string", "Only campaigns with a status: active, completed or cancelled"),
+			openapi.Query("owner", "string", "Only campaigns of an owner"),
+		},
+		Response: []models.CampaignWithProgress{},
+	},
+	"POST /campaigns": {
+		Summary:  "Create a remediation campaign",
+		Request:  models.CampaignRequest{},
+		Response: models.Campaign{},
+		Status:   http.StatusCreated,
+	},
+	"GET /campaigns/:id": {
+		Summary:  "Get a campaign with its progress and daily burndown",
+		Response: models.CampaignDetails{},
+	},
+	"PATCH /campaigns/:id": {
+		Summary:  "Update a campaign",
+		Request:  models.CampaignUpdate{},
+		Response: models.Campaign{},
+	},
+	"DELETE /campaigns/:id": {
+		Summary: "Delete a campaign",
+		Status:  http.StatusNoContent,
+	},
+	"GET /campaigns/:id/vulnerabilities": {
+		Summary: "List the vulnerabilities of a campaign",
+		Query: append([]openapi.Param{
+			openapi.Query("status", "string", "Only vulnerabilities with a status"),
+		}, paginationArgs...),
+		Response:  models.Vulnerability{},
+		Paginated: true,
+	},
+	"POST /campaigns/:id/vulnerabilities": {
+		Summary: "Attach up to 500 vulnerabilities to a campaign",
+		Request: struct {
+			VulnerabilityIDs []int `json:"vulnerability_ids"`
+		}{},
+		Response: struct {
+			AddedCount int `json:"added_count"`
+		}{},
+	},
+	"DELETE /campaigns/:id/vulnerabilities/:vulnerability_id": {
+		Summary: "Remove a vulnerability from a campaign",
+		Status:  http.StatusNoContent,
+	},
+
+	// Scanner versions
+	"GET /scanner-versions": {
+		Summary:  "List the scanner versions and Grype database builds scans were made with",
+		Response: []models.ScannerVersionStats{},
+	},
+
+	// Suppression rules
+	"GET /suppression-rules": {
+		Summary:  "List suppression rules",
+		Response: []models.SuppressionRule{},
+	},
+	"POST /suppression-rules": {
+		Summary:     "Create a suppression rule",
+		Description: "An accepted-risk waiver of the matching findings, targeting at least a CVE or a package.",
+		Request:     models.SuppressionRuleRequest{},
+		Response:    models.SuppressionRule{},
+		Status:      http.StatusCreated,
+	},
+	"POST /suppression-rules/import": {
+		Summary:     "Import suppression rules from a .grype.yaml or .trivyignore",
+		Query:       []openapi.Param{openapi.Query("format", "string", "grype or trivy")},
+		RequestType: "text/plain",
+		Response:    models.SuppressionImportResult{},
+	},
+	"GET /suppression-rules/export": {
+		Summary:      "Export the suppression rules in effect as a .grype.yaml",
+		Description:  "So that local scans match what the server accepts.",
+		Query:        []openapi.Param{openapi.Query("format", "string", "grype, the only format")},
+		ResponseType: "application/yaml",
+	},
+	"GET /suppression-rules/bundle": {
+		Summary:      "Export every suppression rule and accepted or ignored vulnerability as a bundle",
+		Description:  "The same state always exports the same document, so bundles can be kept in Git.",
+		ResponseType: "application/yaml",
+	},
+	"POST /suppression-rules/bundle": {
+		Summary:     "Apply a suppression bundle",
+		Description: "Nothing is applied when the bundle is invalid.",
+		Query: []openapi.Param{
+			openapi.Query("prune", "boolean", "Also delete the rules missing from the bundle"),
+		},
+		RequestType: "application/yaml",
+		Response:    models.SuppressionBundleImportResult{},
+	},
+	"DELETE /suppression-rules/:id": {
+		Summary: "Delete a suppression rule",
+		Status:  http.StatusNoContent,
+	},
+
+	// Images
+	"GET /images": {
+		Summary: "List images with their vulnerability counts",
+		Query: append([]openapi.Param{
+			openapi.Query("stale", "boolean", "Only images not scanned within their cadence"),
+			openapi.Query("unreviewed", "boolean", "Only discovered images without a team and criticality"),
+			hasFixParam,
+			asOfParam,
+			openapi.Query("sort", "string", "Order by updated_at (default, last_scan_date with as_of), last_scan_date, severity or name; not with stale"),
+			orderParam,
+		}, paginationArgs...),
+		Response:  models.ImageWithStats{},
+		Paginated: true,
+	},
+	"GET /images/prioritized": {
+		Summary: "List images by remediation priority",
+		Query: append([]openapi.Param{
+			openapi.Query("exposed", "boolean", "Only images deployed (true) or not (false) in an exposed workload"),
+		}, paginationArgs...),
+		Response:  models.ImageRisk{},
+		Paginated: true,
+	},
+	"GET /images/:id": {
+		Summary:     "Get an image",
+		Description: "also_known_as lists the other images with the same digest, the same content pushed under another name or tag, latest scanned first.",
+		Response:    models.ImageDetails{},
+	},
+	"GET /images/:id/history": {
+		Summary:   "List the scans of an image",
+		Query:     append([]openapi.Param{hasFixParam}, paginationArgs...),
+		Response:  models.ScanWithDetails{},
+		Paginated: true,
+	},
+	"PATCH /images/:id": {
+		Summary:  "Assign the team and criticality of an image",
+		Request:  models.ImageReviewRequest{},
+		Response: models.Image{},
+	},
+	"DELETE /images/:id": {
+		Summary:     "Delete an image and its scans",
+		Description: "Admins only. Without the confirm token, responds 428 with the impact of the deletion and the token.",
+		Query: []openapi.Param{
+			openapi.Query("confirm", "string", "Confirmation token of the deletion impact"),
+			purgeOrphanVulnsParam,
+		},
+		Status: http.StatusNoContent,
+	},
+
+	// Waivers
+	"GET /waivers": {
+		Summary: "List waivers",
+		Query: []openapi.Param{
+			openapi.Query("image_id", "integer", "Only waivers of an image"),
+			openapi.Query("vulnerability_id", "integer", "Only waivers of a vulnerability"),
+			openapi.Query("expired", "boolean", "List the expired waivers instead of the ones in effect"),
+		},
+		Response: []models.Waiver{},
+	},
+	"POST /waivers": {
+		Summary:     "Waive a vulnerability on an image",
+		Description: "Accepts the vulnerability until expires_at, at most a year away. It is set back to active once the waiver expires.",
+		Request:     models.WaiverRequest{},
+		Response:    models.Waiver{},
+		Status:      http.StatusCreated,
+	},
+	"GET /waivers/:id": {
+		Summary:  "Get a waiver",
+		Response: models.Waiver{},
+	},
+	"DELETE /waivers/:id": {
+		Summary:     "Delete a waiver",
+		Description: "The vulnerability keeps its status.",
+		Status:      http.StatusNoContent,
+	},
+
+	// Metrics
+	"GET /metrics": {
+		Summary: "Get the dashboard metrics",
+		Query: []openapi.Param{
+			hasFixParam,
+			openapi.Query("image_name", "string", "Only count images matching a name"),
+		},
+		Response: metrics.DashboardMetrics{},
+	},
+	"GET /metrics/vulnerability-age": {
+		Summary: "Get the open vulnerabilities by age, severity and team",
+		Query: []openapi.Param{
+			hasFixParam,
+			openapi.Query("namespace", "string", "Only vulnerabilities of images deployed in a namespace"),
+		},
+		Response: metrics.VulnerabilityAge{},
+	},
+	"GET /metrics/scan-performance": {
+		Summary:     "Get the scan duration and size of every image",
+		Description: "Covers the successful scans that reported their duration. Images whose average duration is over 3 times the median of all images are flagged as slow and listed first.",
+		Query: []openapi.Param{
+			openapi.Query("namespace", "string", "Only scans of the ImageScans of a namespace"),
+			openapi.Query("days", "integer", "Days of the window, 1 to 90 (default 30)"),
+		},
+		Response: metrics.ScanPerformance{},
+	},
+	"GET /usage": {
+		Summary: "Get the scan usage of each team over a month",
+		Query: []openapi.Param{
+			openapi.Query("month", "string", "Calendar month in UTC as YYYY-MM, the current month by default"),
+			openapi.Query("namespace", "string", "Only the usage of a namespace"),
+		},
+		Response: models.UsageReport{},
+	},
+	"GET /metrics/compliance": {
+		Summary:     "Get the compliance of open findings with each remediation timeline",
+		Description: "Responds 503 when no compliance profiles are configured.",
+		Response: struct {
+			EvaluatedAt time.Time                    `json:"evaluated_at"`
+			Profiles    []models.FrameworkCompliance `json:"profiles"`
+		}{},
+	},
+
+	// Live updates
+	// Users
+	"GET /user/me": {
+		Summary:     "Get the current user",
+		Description: "Responds 204 when OAuth is not enabled.",
+		Response:    UserResponse{},
+	},
+	"GET /user/me/activity": {
+		Summary:     "List the actions of the current user",
+		Description: "Latest first, with their counts over the window.",
+		Query: append([]openapi.Param{
+			openapi.Query("days", "integer", "Days of the window, 1 to 365 (default 30)"),
+			openapi.Query("kind", "string", "Only list one kind of activity, the summary still covers every kind"),
+		}, paginationArgs...),
+		Response:  models.UserActivity{},
+		Paginated: true,
+	},
+
+	"GET /events": {
+		Summary: "Stream changes as Server-Sent Events",
+		Description: "Streams scan-created, scan-diff and vulnerability-status-changed events as text/event-stream, " +
+			"from the replica serving the request. A resync event reports events the stream missed by falling behind.",
+		Query: []openapi.Param{
+			openapi.Query("types", "string", "Comma-separated event types to stream, all by default"),
+		},
+	},
+
+	// Webhook configs
+	"PUT /webhook-configs/:namespace/:name": {
+		Summary: "Save the webhook config of an ImageScan",
+		Request: models.WebhookConfigRequest{},
+		Response: struct {
+			Message string `json:"message"`
+		}{},
+	},
+	"GET /webhook-configs/:namespace/:name": {
+		Summary:  "Get the webhook config of an ImageScan",
+		Response: models.WebhookConfig{},
+	},
+	"DELETE /webhook-configs/:namespace/:name": {
+		Summary: "Delete the webhook config of an ImageScan",
+		Response: struct {
+			Message string `json:"message"`
+		}{},
+	},
+
+	// ImageScans
+	"GET /imagescans": {
+		Summary:  "List the registered ImageScans with the open findings of their latest scan",
+		Query:    []openapi.Param{openapi.Query("namespace", "string", "Only the ImageScans of a namespace")},
+		Response: []models.ImageScanSummary{},
+	},
+	"PUT /imagescans/:namespace/:name": {
+		Summary:     "Register an ImageScan",
+		Description: "The controller registers the ImageScans it reconciles.",
+		Request:     models.ImageScanRegistrationRequest{},
+		Response:    models.ImageScanRegistration{},
+	},
+	"DELETE /imagescans/:namespace/:name": {
+		Summary: "Unregister an ImageScan",
+		Query: []openapi.Param{
+			openapi.Query("purge", "boolean", "Also delete the image and its history, unless another ImageScan scans it"),
+		},
+		Status: http.StatusNoContent,
+	},
+	"GET /imagescans/:namespace/:name/sizing": {
+		Summary:     "Recommend the workspace size and memory request of an ImageScan",
+		Description: "From the largest image its scans reported: twice the image size for the workspace, rounded up to a whole Gi, and 1Gi plus half the image size for memory, rounded up to 256Mi. Responds 404 when no recent scan reported the image size.",
+		Query: []openapi.Param{
+			openapi.Query("days", "integer", "Days of the scans considered, 1 to 90 (default 30)"),
+		},
+		Response: models.ImageScanSizing{},
+	},
+	"GET /entities/:team/:service/summary": {
+		Summary:     "Summarize the security health of a Backstage catalog entity",
+		Description: "Sums the open findings of the latest scans of the ImageScans in the namespace named team that are named service or labelled backstage.io/kubernetes-id=service. health is critical with an open Critical finding, warning with a High one, unknown before any of the images is scanned. Responds 404 when no ImageScan matches.",
+		Response:    models.EntitySummary{},
+	},
+
+	// Inventory and coverage
+	"PUT /inventory/:source": {
+		Summary:     "Replace the deployed images reported by a source",
+		Description: "Each source reports the images it runs with their workloads, replacing its previous inventory.",
+		Request:     models.InventoryRequest{},
+		Response: struct {
+			Source string `json:"source"`
+			Images int    `json:"images"`
+		}{},
+	},
+	"GET /coverage": {
+		Summary:     "Get the scan coverage of the deployed images",
+		Description: "Deployed images with their latest successful scan, unscanned first, then stale.",
+		Query: []openapi.Param{
+			openapi.Query("namespace", "string", "Only images deployed in a namespace"),
+			openapi.Query("status", "string", "Only images scanned, stale or unscanned"),
+		},
+		Response: struct {
+			GeneratedAt     time.Time              `json:"generated_at"`
+			StaleAfterHours int                    `json:"stale_after_hours"`
+			Summary         models.CoverageSummary `json:"summary"`
+			Images          []models.ImageCoverage `json:"images"`
+		}{},
+	},
+	"GET /namespaces": {
+		Summary:     "Summarize coverage, findings, SLA and trend per namespace",
+		Description: "Namespaces with an ImageScan, findings or deployed images, by name.",
+		Query: []openapi.Param{
+			openapi.Query("namespace", "string", "Only a namespace"),
+			openapi.Query("trend_days", "integer", "Days of the trend window, 1 to 90 (default 7)"),
+		},
+		Response: struct {
+			GeneratedAt     time.Time                 `json:"generated_at"`
+			StaleAfterHours int                       `json:"stale_after_hours"`
+			TrendDays       int                       `json:"trend_days"`
+			Data            []models.NamespaceSummary `json:"data"`
+		}{},
+	},
+
+	// Integrations
+	"POST /integrations/ticket-callback": {
+		Summary:     "Apply the status of a ticket to its vulnerabilities",
+		Description: "Called by the automation of a ticketing system, signed with TICKET_CALLBACK_SECRET in X-Invulnerable-Signature. Statuses without a mapping are acknowledged and ignored.",
+		Request:     models.TicketCallbackRequest{},
+		Response:    models.TicketCallbackResult{},
+	},
+
+	// Compliance reports
+	"POST /reports": {
+		Summary:     "Generate a compliance report",
+		Description: "Reports the vulnerability posture, the SLA compliance and the vulnerabilities found and fixed over the period_days before now, as HTML or PDF. With a schedule of REPORT_SCHEDULES_FILE the report is delivered to its destinations, otherwise it is only stored. Admin only.",
+		Request:     GenerateReportRequest{},
+		Response:    models.ComplianceReport{},
+		Status:      http.StatusCreated,
+	},
+	"GET /reports": {
+		Summary:     "List the generated compliance reports",
+		Description: "Most recent first.",
+		Query: append([]openapi.Param{
+			openapi.Query("schedule", "string", "Only the reports of a schedule"),
+		}, paginationArgs...),
+		Response:  models.ComplianceReport{},
+		Paginated: true,
+	},
+	"GET /reports/:id": {
+		Summary:  "Get a compliance report",
+		Response: models.ComplianceReport{},
+	},
+	"GET /reports/:id/document": {
+		Summary:      "Download the document of a compliance report",
+		Description:  "text/html or application/pdf, by the format of the report. Responds 404 when it couldn't be stored.",
+		ResponseType: "application/octet-stream",
+	},
+
+	// Admin
+	"GET /admin/maintenance": {
+		Summary:  "Get the maintenance mode",
+		Response: models.MaintenanceMode{},
+	},
+	"PUT /admin/maintenance": {
+		Summary:     "Turn the maintenance mode on or off",
+		Description: "In maintenance, the API is read-only: writes respond 503.",
+		Request:     models.MaintenanceModeRequest{},
+		Response:    models.MaintenanceMode{},
+	},
+	"POST /admin/scanner-versions/rescan": {
+		Summary:     "Flag the scans of deprecated scanner versions for rescan",
+		Description: "Only the latest scan of each image is flagged, older scans are superseded anyway. Takes no body.",
+		Response:    RescanResult{},
+	},
+	"GET /admin/quotas": {
+		Summary:  "List the scan quotas of the teams",
+		Response: []models.TeamQuota{},
+	},
+	"PUT /admin/quotas/:namespace": {
+		Summary:  "Set the scan quota of a team",
+		Request:  models.TeamQuotaRequest{},
+		Response: models.TeamQuota{},
+	},
+	"DELETE /admin/quotas/:namespace": {
+		Summary: "Remove the scan quota of a team",
+		Status:  http.StatusNoContent,
+	},
+	"GET /admin/workers": {
+		Summary:  "List the background workers with their last runs",
+		Response: []models.WorkerStatus{},
+	},
+	"GET /admin/online-changes": {
+		Summary:  "List the online schema changes with their progress",
+		Response: []models.OnlineChangeStatus{},
+	},
+	"GET /admin/diagnostics": {
+		Summary:  "Get the diagnostics of the replica serving the request",
+		Response: models.Diagnostics{},
+	},
+}
+
+// openAPISpec is what the document of the API is built from besides its routes
+var openAPISpec = openapi.Spec{
+	Info: openapi.Info{
+		Title:   "Invulnerable API",
+		Version: "v1",
+	},
+	Prefix:     openAPIPrefix,
+	Operations: openAPIOperations,
+	Schemes: map[string]openapi.SecurityScheme{
+		"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "OIDC access token, when OAuth is enabled"},
+		"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "Scanner API key"},
+	},
+	Security: []openapi.SecurityRule{
+		{Prefix: "/ci", Scheme: "apiKey"},
+		{Prefix: "/shared"},
+		{Prefix: "/integrations/ticket-callback"},
+		{Prefix: "/openapi.json"},
+		{Prefix: "/docs"},
+	},
+	DefaultScheme: "bearer",
+	Mounts:        []string{"/ci"},
+}
+
+// IsDocumentedRoute reports whether a route under /api/v1 has an operation in the OpenAPI document, so a
+// route registered without one can be caught
+func IsDocumentedRoute(route *echo.Route) bool {
+	path, ok := strings.CutPrefix(route.Path, openAPIPrefix)
+	if !ok {
+		return false
+	}
+	_, _, documented := openAPISpec.Operation(route.Method, path)
+	return documented
+}
+
+// OpenAPIHandler serves the OpenAPI document of the API and Swagger UI
+type OpenAPIHandler struct {
+	logger *zap.Logger
+	echo   *echo.Echo
+
+	once sync.Once
+	doc  *openapi.Document
+}
+
+func NewOpenAPIHandler(logger *zap.Logger, e *echo.Echo) *OpenAPIHandler {
+	return &OpenAPIHandler{
+		logger: logger,
+		echo:   e,
+	}
+}
+
+// GetOpenAPI handles GET /api/v1/openapi.json
+// The document is built from the routes on the first request, once they are all registered
+func (h *OpenAPIHandler) GetOpenAPI(c echo.Context) error {
+	h.once.Do(func() {
+		doc, unmatched := openapi.Build(openAPISpec, h.echo.Routes())
+		for _, key := range unmatched {
+			h.logger.Warn("documented API operation has no route", zap.String("operation", key))
+		}
+		h.doc = doc
+	})
+	return c.JSON(http.StatusOK, h.doc)
+}
+
+// GetDocs handles GET /api/v1/docs - Swagger UI for the OpenAPI document, loaded from a CDN
+func (h *OpenAPIHandler) GetDocs(c echo.Context) error {
+	return c.HTML(http.StatusOK, swaggerUIPage)
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Invulnerable API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
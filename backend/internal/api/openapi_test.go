@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestOpenAPIHandler_GetOpenAPI(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	e := echo.New()
+	handler := NewOpenAPIHandler(zap.New(core), e)
+
+	// Every documented operation gets a route
+	group := e.Group(openAPIPrefix)
+	noop := func(c echo.Context) error { return nil }
+	for key := range openAPIOperations {
+		method, path, _ := strings.Cut(key, " ")
+		group.Add(method, path, noop)
+	}
+	group.GET("/openapi.json", handler.GetOpenAPI)
+
+	req := httptest.NewRequest(http.MethodGet, openAPIPrefix+"/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Zero(t, logs.Len(), "every documented operation has a route")
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.1.0", doc["openapi"])
+
+	paths := doc["paths"].(map[string]any)
+	for _, path := range []string{"/scans", "/scans/{id}", "/vulnerabilities", "/images/{id}", "/metrics", "/webhook-configs/{namespace}/{name}"} {
+		assert.Contains(t, paths, path)
+	}
+
+	// Every reference resolves to a component schema
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				assert.Contains(t, schemas, strings.TrimPrefix(ref, "#/components/schemas/"))
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+	assert.Contains(t, schemas, "ScanWithDetailsPage")
+}
+
+func TestOpenAPIHandler_ReportsUnmatchedOperations(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	e := echo.New()
+	handler := NewOpenAPIHandler(zap.New(core), e)
+	// Served outside the prefix, so that no operation has a route
+	e.GET("/openapi.json", handler.GetOpenAPI)
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, len(openAPIOperations), logs.FilterMessage("documented API operation has no route").Len())
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/labstack/echo/v4"
+)
+
+// maxPageLimit is the largest page of the list endpoints
+const maxPageLimit = 100
+
+// Page is the envelope of the list endpoints: a page of the results and where it stands in all of them
+type Page struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+	// NextOffset is the offset of the next page, nil on the last one
+	NextOffset *int `json:"next_offset"`
+}
+
+// pageAsOf is a page of the results of a point-in-time query
+type pageAsOf struct {
+	Page
+	AsOf time.Time `json:"as_of"`
+}
+
+// newPage wraps a page of results, total counts all of them
+func newPage(items interface{}, total, limit, offset int) Page {
+	page := Page{Items: items, Total: total, Limit: limit, Offset: offset}
+	if next := offset + limit; next < total {
+		page.NextOffset = &next
+	}
+	return page
+}
+
+// pageOf paginates results held in memory
+func pageOf[T any](items []T, limit, offset int) Page {
+	total := len(items)
+	start := min(offset, total)
+	end := min(start+limit, total)
+	return newPage(items[start:end], total, limit, offset)
+}
+
+// parsePagination reads the limit and offset parameters. A missing or out of range limit is defaultLimit,
+// a missing or negative offset is 0
+func parsePagination(c echo.Context, defaultLimit int) (limit, offset int) {
+	limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	if limit <= 0 || limit > maxPageLimit {
+		limit = defaultLimit
+	}
+
+	offset, _ = strconv.Atoi(c.QueryParam("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// parseSort reads the sort and order parameters, checked with the validate function of the list.
+// Without them the list keeps its default order
+func parseSort(c echo.Context, validate func(db.Sort) error) (db.Sort, error) {
+	sort := db.Sort{Field: c.QueryParam("sort"), Order: c.QueryParam("order")}
+	if sort == (db.Sort{}) {
+		return sort, nil
+	}
+	if err := validate(sort); err != nil {
+		return db.Sort{}, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return sort, nil
+}
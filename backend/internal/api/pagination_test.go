@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPage(t *testing.T) {
+	page := newPage([]int{1, 2}, 5, 2, 0)
+	require.NotNil(t, page.NextOffset)
+	assert.Equal(t, 2, *page.NextOffset)
+
+	page = newPage([]int{5}, 5, 2, 4)
+	assert.Nil(t, page.NextOffset, "last page")
+
+	page = pageOf([]string{"a", "b", "c"}, 2, 2)
+	assert.Equal(t, []string{"c"}, page.Items)
+	assert.Equal(t, 3, page.Total)
+	assert.Nil(t, page.NextOffset)
+
+	page = pageOf([]string{"a"}, 2, 10)
+	assert.Equal(t, []string{}, page.Items, "offsets past the end are an empty page")
+}
+
+func TestParsePagination(t *testing.T) {
+	e := echo.New()
+	for query, want := range map[string][2]int{
+		"":                     {20, 0},
+		"?limit=50&offset=100": {50, 100},
+		"?limit=500":           {20, 0},
+		"?limit=0&offset=-1":   {20, 0},
+		"?limit=abc":           {20, 0},
+	} {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/"+query, nil), httptest.NewRecorder())
+		limit, offset := parsePagination(c, 20)
+		assert.Equal(t, want, [2]int{limit, offset}, query)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	e := echo.New()
+	parse := func(query string) (db.Sort, error) {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/"+query, nil), httptest.NewRecorder())
+		return parseSort(c, db.ValidateVulnerabilitySort)
+	}
+
+	sort, err := parse("")
+	require.NoError(t, err)
+	assert.Equal(t, db.Sort{}, sort)
+
+	sort, err = parse("?sort=package_name&order=desc")
+	require.NoError(t, err)
+	assert.Equal(t, db.Sort{Field: "package_name", Order: "desc"}, sort)
+
+	for _, query := range []string{"?sort=cvss", "?sort=severity&order=up", "?order=asc", "?sort=severity%3BDROP+TABLE+scans"} {
+		_, err := parse(query)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, query)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, query)
+	}
+}
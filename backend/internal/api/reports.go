@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/invulnerable/backend/internal/compliance"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// SetReports enables signed scan reports, generated once the results of a scan are processed
+func (h *ScanHandler) SetReports(repo *db.ScanReportRepository, signer *auth.ReportSigner) {
+	h.reportRepo = repo
+	h.reportSigner = signer
+}
+
+// SetCompliance evaluates compliance profiles in scan reports, selected by the labels of the ImageScans
+// of the scanned image
+func (h *ScanHandler) SetCompliance(profiles []compliance.Profile, imageScanRepo *db.ImageScanRepository) {
+	h.profiles = profiles
+	h.imageScanRepo = imageScanRepo
+}
+
+// createReport signs and stores the report of a scan whose results were just processed.
+// It is evidence and never fails the submission
+func (h *ScanHandler) createReport(ctx context.Context, scan *models.Scan, imageName string) {
+	report, err := h.buildReport(ctx, scan, imageName, time.Now())
+	if err != nil {
+		h.logger.Warn("failed to build scan report", zap.Error(err), zap.Int("scan_id", scan.ID))
+		return
+	}
+	payload, err := json.Marshal(report)
+	if err != nil {
+		h.logger.Warn("failed to encode scan report", zap.Error(err), zap.Int("scan_id", scan.ID))
+		return
+	}
+	document, err := json.Marshal(h.reportSigner.Sign(payload))
+	if err != nil {
+		h.logger.Warn("failed to encode scan report", zap.Error(err), zap.Int("scan_id", scan.ID))
+		return
+	}
+	if _, err := h.reportRepo.Create(ctx, scan.ID, h.reportSigner.KeyID(), document); err != nil {
+		h.logger.Warn("failed to store scan report", zap.Error(err), zap.Int("scan_id", scan.ID))
+	}
+}
+
+// buildReport records the summary, findings and default gate verdict of a scan as they are now
+func (h *ScanHandler) buildReport(ctx context.Context, scan *models.Scan, imageName string, now time.Time) (*models.ScanReport, error) {
+	summary, err := h.scanRepo.GetSummary(ctx, scan.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scan summary: %w", err)
+	}
+	vulns, err := h.scanRepo.GetVulnerabilities(ctx, scan.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vulnerabilities: %w", err)
+	}
+
+	findings := make([]models.ReportFinding, 0, len(vulns))
+	for _, v := range vulns {
+		findings = append(findings, models.ReportFinding{
+			ID:             v.ID,
+			CVEID:          v.CVEID,
+			Severity:       v.Severity,
+			Status:         v.Status,
+			PackageName:    v.PackageName,
+			PackageVersion: v.PackageVersion,
+			PackageType:    v.PackageType,
+			PURL:           v.PURL,
+			FixVersion:     v.FixVersion,
+			KnownExploited: v.KnownExploited,
+			URL:            v.URL,
+		})
+	}
+
+	verdict := evaluateGate(vulns, defaultGateFailOn, false)
+	verdict.ScanID = scan.ID
+	verdict.ScanStatus = scan.Status
+
+	var frameworks []models.FrameworkCompliance
+	if len(h.profiles) > 0 {
+		labels, err := h.imageScanRepo.ListLabelsByImage(ctx, []int{scan.ImageID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get imagescan labels: %w", err)
+		}
+		var open []compliance.Finding
+		for _, v := range vulns {
+			if v.Status == models.StatusActive || v.Status == models.StatusInProgress {
+				open = append(open, compliance.Finding{ImageID: scan.ImageID, Severity: v.Severity, FirstDetectedAt: v.FirstDetectedAt})
+			}
+		}
+		frameworks = compliance.Evaluate(h.profiles, map[int][][]string{scan.ImageID: labels[scan.ImageID]}, open, now)
+	}
+
+	return &models.ScanReport{
+		SchemaVersion: models.ScanReportVersion,
+		GeneratedAt:   now.UTC(),
+		ImageName:     imageName,
+		Scan:          *scan,
+		Summary:       *summary,
+		Findings:      findings,
+		Verdict:       verdict,
+		Compliance:    frameworks,
+	}, nil
+}
+
+// GetScanReport handles GET /api/v1/scans/:id/report
+// It returns the signed report envelope exactly as stored
+func (h *ScanHandler) GetScanReport(c echo.Context) error {
+	if h.reportRepo == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "scan reports are disabled, set REPORT_SIGNING_KEY_FILE to enable them")
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
+	document, err := h.reportRepo.GetDocumentByScanID(c.Request().Context(), id)
+	if err != nil {
+		h.logger.Error("failed to get scan report", zap.Error(err), zap.Int("scan_id", id))
+		return echo.NewHTTPError(http.StatusNotFound, "scan report not found")
+	}
+
+	return c.JSONBlob(http.StatusOK, document)
+}
+
+// GetReportSigningKey handles GET /api/v1/reports/signing-key
+// It returns the public key scan reports are verified with
+func (h *ScanHandler) GetReportSigningKey(c echo.Context) error {
+	if h.reportSigner == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "scan reports are disabled, set REPORT_SIGNING_KEY_FILE to enable them")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"key_id":       h.reportSigner.KeyID(),
+		"algorithm":    "ed25519",
+		"payload_type": auth.ReportPayloadType,
+		"public_key":   h.reportSigner.PublicKeyPEM(),
+	})
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/analyzer"
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestScanHandler_Reports_Disabled(t *testing.T) {
+	handler := NewScanHandler(zap.NewNop(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	for _, fn := range []echo.HandlerFunc{handler.GetScanReport, handler.GetReportSigningKey} {
+		_, err := doScanRequest(t, fn, http.MethodGet, "/api/v1/scans/1/report", nil, "1")
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+	}
+}
+
+func TestScanHandler_Reports(t *testing.T) {
+	database := db.SetupTestDatabase(t)
+	scanRepo := db.NewScanRepository(database)
+	vulnRepo := db.NewVulnerabilityRepository(database)
+	handler := NewScanHandler(zap.NewNop(), db.NewImageRepository(database), scanRepo, vulnRepo,
+		db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}), nil, nil, nil, nil, nil,
+		analyzer.New(scanRepo, vulnRepo), notifier.New(zap.NewNop(), ""))
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	storage := &memorySBOMStorage{docs: make(map[int][]byte)}
+	handler.SetReports(db.NewScanReportRepository(database, storage), auth.NewReportSigner(private))
+
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", map[string]interface{}{
+		"image": "acme/api:1.0",
+		"grype_result": map[string]interface{}{"matches": []map[string]interface{}{{
+			"vulnerability": map[string]interface{}{"id": "CVE-2024-0001", "severity": "Critical"},
+			"artifact":      map[string]interface{}{"name": "openssl", "version": "3.0.11-1", "type": "deb"},
+		}}},
+		"sbom":        json.RawMessage(`{"bomFormat":"CycloneDX","components":[]}`),
+		"sbom_format": "cyclonedx",
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var scan models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+
+	rec, err = doScanRequest(t, handler.GetScanReport, http.MethodGet, "/api/v1/scans/:id/report", nil, strconv.Itoa(scan.ID))
+	require.NoError(t, err)
+	var envelope auth.Envelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+	payload, err := auth.VerifyEnvelope(public, envelope)
+	require.NoError(t, err)
+
+	var report models.ScanReport
+	require.NoError(t, json.Unmarshal(payload, &report))
+	assert.Equal(t, scan.ID, report.Scan.ID)
+	assert.Equal(t, "docker.io/acme/api:1.0", report.ImageName)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, "CVE-2024-0001", report.Findings[0].CVEID)
+	assert.False(t, report.Verdict.Passed)
+	assert.Equal(t, 1, report.Summary.BySeverity.Critical)
+
+	// A report is never replaced
+	stored := storage.docs[scan.ID]
+	handler.createReport(context.Background(), &scan, "docker.io/acme/api:1.0")
+	assert.Equal(t, stored, storage.docs[scan.ID])
+
+	// A document replaced in the bucket is rejected
+	storage.docs[scan.ID] = []byte(`{}`)
+	_, err = doScanRequest(t, handler.GetScanReport, http.MethodGet, "/api/v1/scans/:id/report", nil, strconv.Itoa(scan.ID))
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
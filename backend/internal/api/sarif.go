@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/invulnerable/backend/internal/export"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// GetScanSARIF handles GET /api/v1/scans/:id/sarif - the vulnerabilities of a scan as a SARIF 2.1.0 log,
+// for GitHub Code Scanning and other SARIF consumers
+func (h *ScanHandler) GetScanSARIF(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
+	ctx := c.Request().Context()
+	scan, err := h.scanRepo.GetWithDetails(ctx, id, nil)
+	if err != nil {
+		return err
+	}
+	if err := requireScanOfAPIKey(c, &scan.Scan); err != nil {
+		return err
+	}
+	if !scan.HasResults() {
+		return echo.NewHTTPError(http.StatusConflict, "scan has no results yet (status "+scan.Status+")")
+	}
+
+	vulns, err := h.scanRepo.GetVulnerabilities(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to get vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get vulnerabilities")
+	}
+
+	digest := scan.Digest
+	if digest == nil {
+		digest = scan.ImageDigest
+	}
+	log := export.SARIF(export.SARIFScan{
+		ID:           scan.ID,
+		ImageName:    scan.ImageName,
+		Digest:       digest,
+		GrypeVersion: scan.GrypeVersion,
+	}, vulns)
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="scan-%d.sarif"`, scan.ID))
+	return c.JSON(http.StatusOK, log)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanHandler_GetScanSARIF_APIKey(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	rec, err := doScanRequest(t, withAPIKey("github-actions", handler.CreateScan), http.MethodPost, "/api/v1/ci/scans", map[string]interface{}{
+		"image": "acme/api:1.0",
+		"grype_result": map[string]interface{}{"matches": []map[string]interface{}{{
+			"vulnerability": map[string]interface{}{"id": "CVE-2024-0001", "severity": "High"},
+			"artifact":      map[string]interface{}{"name": "openssl", "version": "3.0.11-1", "type": "deb"},
+		}}},
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var scan models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+	id := strconv.Itoa(scan.ID)
+	path := "/api/v1/ci/scans/" + id + "/sarif"
+
+	rec, err = doScanRequest(t, withAPIKey("github-actions", handler.GetScanSARIF), http.MethodGet, path, nil, id)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "CVE-2024-0001")
+
+	// The findings of the scan aren't served to other keys
+	rec, err = doScanRequest(t, withAPIKey("gitlab-ci", handler.GetScanSARIF), http.MethodGet, path, nil, id)
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	assert.NotContains(t, rec.Body.String(), "CVE-2024-0001")
+}
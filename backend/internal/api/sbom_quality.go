@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/sbom"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// evaluateSBOMQuality scores the SBOM submitted with a scan. A low-quality SBOM is logged: the
+// scan reports only the vulnerabilities of the packages it lists
+func (h *ScanHandler) evaluateSBOMQuality(ctx context.Context, scan *models.Scan, document io.ReadSeeker) {
+	quality, err := sbom.EvaluateQuality(document, scan.ImageSizeBytes)
+	if err == nil {
+		err = h.sbomRepo.SetQuality(ctx, scan.ID, quality)
+	}
+	if err != nil {
+		h.logger.Warn("failed to evaluate SBOM quality", zap.Error(err), zap.Int("scan_id", scan.ID))
+		return
+	}
+	if quality.LowQuality {
+		h.logger.Warn("low-quality SBOM, vulnerabilities may be missed",
+			zap.Int("scan_id", scan.ID),
+			zap.Int("score", quality.Score),
+			zap.Strings("flags", quality.Flags))
+	}
+}
+
+// GetSBOMQuality handles GET /api/v1/scans/:id/sbom-quality
+func (h *ScanHandler) GetSBOMQuality(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
+	ctx := c.Request().Context()
+	quality, err := h.sbomRepo.GetQuality(ctx, id)
+	if err != nil {
+		return err
+	}
+	if quality == nil {
+		// Stored before SBOMs were scored at ingestion
+		if quality, err = h.evaluateStoredSBOM(ctx, id); err != nil {
+			return err
+		}
+	}
+	return c.JSON(http.StatusOK, quality)
+}
+
+// evaluateStoredSBOM scores and stores the quality of the stored SBOM of a scan, returning an HTTP
+// error when it can't be parsed
+func (h *ScanHandler) evaluateStoredSBOM(ctx context.Context, scanID int) (*models.SBOMQuality, error) {
+	scan, err := h.scanRepo.GetByID(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+	document, err := h.sbomRepo.GetDocumentByScanID(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+	quality, err := sbom.EvaluateDocumentQuality(document, scan.ImageSizeBytes)
+	if err != nil {
+		h.logger.Error("failed to parse SBOM", zap.Error(err), zap.Int("scan_id", scanID))
+		return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("SBOM of scan %d could not be parsed", scanID))
+	}
+	if err := h.sbomRepo.SetQuality(ctx, scanID, quality); err != nil {
+		return nil, err
+	}
+	return quality, nil
+}
+
+// SBOMQualityHandler reports the quality of the SBOMs images are currently scanned with
+type SBOMQualityHandler struct {
+	logger   *zap.Logger
+	sbomRepo *db.SBOMRepository
+}
+
+func NewSBOMQualityHandler(logger *zap.Logger, sbomRepo *db.SBOMRepository) *SBOMQualityHandler {
+	return &SBOMQualityHandler{
+		logger:   logger,
+		sbomRepo: sbomRepo,
+	}
+}
+
+// ListSBOMQuality handles GET /api/v1/sbom-quality?low_quality=true&image_name=acme/
+// It lists the quality of the latest SBOM of each image and target, lowest score first
+func (h *SBOMQualityHandler) ListSBOMQuality(c echo.Context) error {
+	lowQuality := false
+	if s := c.QueryParam("low_quality"); s != "" {
+		var err error
+		if lowQuality, err = strconv.ParseBool(s); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid low_quality (expected true or false)")
+		}
+	}
+	var imageName *string
+	if s := strings.TrimSpace(c.QueryParam("image_name")); s != "" {
+		imageName = &s
+	}
+
+	ctx := c.Request().Context()
+	scans, err := h.sbomRepo.ListLatestSBOMScans(ctx, imageName)
+	if err != nil {
+		h.logger.Error("failed to list SBOM scans", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list SBOM quality")
+	}
+
+	entries := []models.SBOMQualityEntry{}
+	for _, scan := range scans {
+		// SBOMs stored before their quality was scored at ingestion are evaluated on first use
+		var quality models.SBOMQuality
+		if scan.Quality == nil {
+			evaluated, err := h.sbomRepo.EvaluateStoredDocument(ctx, scan.ScanID)
+			if err != nil {
+				h.logger.Warn("failed to evaluate SBOM quality", zap.Error(err), zap.Int("scan_id", scan.ScanID))
+				continue
+			}
+			quality = *evaluated
+		} else if err := json.Unmarshal(scan.Quality, &quality); err != nil {
+			h.logger.Warn("failed to decode SBOM quality", zap.Error(err), zap.Int("scan_id", scan.ScanID))
+			continue
+		}
+		if lowQuality && !quality.LowQuality {
+			continue
+		}
+		entries = append(entries, models.SBOMQualityEntry{
+			ScanID:    scan.ScanID,
+			ImageID:   scan.ImageID,
+			ImageName: scan.ImageName,
+			Target:    scan.Target,
+			ScanDate:  scan.ScanDate,
+			Quality:   quality,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Quality.Score < entries[j].Quality.Score
+	})
+	return c.JSON(http.StatusOK, entries)
+}
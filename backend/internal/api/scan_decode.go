@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/jsonstream"
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// scanSubmission is the body of a scan submission spooled to a temporary file. The SBOM and the
+// Grype result are streamed from the file to storage, and only the matches are decoded, one at a
+// time, so the memory a submission takes doesn't grow with the size of its documents
+type scanSubmission struct {
+	file *os.File
+	// The sections of the file holding the documents, nil when they weren't submitted
+	sbom, grypeResult *io.SectionReader
+}
+
+// spoolScanSubmission copies a request body to a temporary file, the caller closes the submission
+func spoolScanSubmission(body io.Reader) (*scanSubmission, error) {
+	file, err := os.CreateTemp("", "invulnerable-scan-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	s := &scanSubmission{file: file}
+	if _, err := io.Copy(file, body); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return s, nil
+}
+
+// Close removes the temporary file
+func (s *scanSubmission) Close() error {
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// SBOM returns a reader of the submitted SBOM, empty when there was none. Each reader reads on its
+// own, so the document can be stored and indexed at once
+func (s *scanSubmission) SBOM() *io.SectionReader {
+	return s.open(s.sbom)
+}
+
+// GrypeResult returns a reader of the Grype result as submitted, empty when there was none
+func (s *scanSubmission) GrypeResult() *io.SectionReader {
+	return s.open(s.grypeResult)
+}
+
+func (s *scanSubmission) open(section *io.SectionReader) *io.SectionReader {
+	if section == nil {
+		return io.NewSectionReader(s.file, 0, 0)
+	}
+	return io.NewSectionReader(section, 0, section.Size())
+}
+
+// Decode decodes the submission into req like json.Unmarshal, except for req.SBOM which is left
+// in the file. Over maxMatches matches (zero for no limit) the rest of the matches are only
+// counted and errTooManyMatches is returned
+func (s *scanSubmission) Decode(req *ScanRequest, maxMatches int) error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// An empty body is an empty request, as with c.Bind
+	if info, err := s.file.Stat(); err != nil || info.Size() == 0 {
+		return err
+	}
+
+	// The other fields are small, they are decoded together once the documents are read
+	fields := map[string]json.RawMessage{}
+	dec := json.NewDecoder(s.file)
+	err := jsonstream.Object(dec, func(key string) error {
+		switch {
+		case strings.EqualFold(key, "sbom"):
+			section, err := s.section(dec, func() error { return jsonstream.Skip(dec) })
+			s.sbom = section
+			return err
+		case strings.EqualFold(key, "grype_result"):
+			var result models.GrypeResult
+			section, err := s.section(dec, func() error { return decodeGrypeResult(dec, &result, maxMatches) })
+			s.grypeResult, req.GrypeResult = section, result
+			return err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		fields[key] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, req)
+}
+
+// section reads the value of the key just read with read, and returns the section of the file
+// it was read from, nil for null
+func (s *scanSubmission) section(dec *json.Decoder, read func() error) (*io.SectionReader, error) {
+	start, first, err := jsonstream.ValueOffset(s.file, dec.InputOffset())
+	if err != nil {
+		return nil, err
+	}
+	if err := read(); err != nil {
+		return nil, err
+	}
+	if first == 'n' {
+		return nil, nil
+	}
+	return io.NewSectionReader(s.file, start, dec.InputOffset()-start), nil
+}
+
+// decodeGrypeResult decodes a Grype result one match at a time. Over maxMatches the remaining
+// matches are skipped, only to report how many there were
+func decodeGrypeResult(dec *json.Decoder, result *models.GrypeResult, maxMatches int) error {
+	count := 0
+	err := jsonstream.Object(dec, func(key string) error {
+		switch {
+		case strings.EqualFold(key, "matches"):
+			return jsonstream.Array(dec, func() error {
+				count++
+				if maxMatches > 0 && count > maxMatches {
+					result.Matches = nil
+					return jsonstream.Skip(dec)
+				}
+				var match models.GrypeMatch
+				if err := dec.Decode(&match); err != nil {
+					return err
+				}
+				result.Matches = append(result.Matches, match)
+				return nil
+			})
+		case strings.EqualFold(key, "source"):
+			return dec.Decode(&result.Source)
+		case strings.EqualFold(key, "descriptor"):
+			return dec.Decode(&result.Descriptor)
+		case strings.EqualFold(key, "distro"):
+			return dec.Decode(&result.Distro)
+		}
+		return jsonstream.Skip(dec)
+	})
+	if err != nil {
+		return err
+	}
+	if maxMatches > 0 && count > maxMatches {
+		return errTooManyMatches{count: count, limit: maxMatches}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeScanSubmission(t *testing.T, body string, maxMatches int) (*scanSubmission, ScanRequest, error) {
+	t.Helper()
+
+	submission, err := spoolScanSubmission(strings.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { submission.Close() })
+
+	var req ScanRequest
+	err = submission.Decode(&req, maxMatches)
+	return submission, req, err
+}
+
+func readSection(t *testing.T, r io.Reader) string {
+	t.Helper()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestScanSubmission_Decode(t *testing.T) {
+	sbom := `{"bomFormat": "CycloneDX", "components": [{"name": "openssl"}]}`
+	grypeResult := `{
+		"matches": [
+			{"vulnerability": {"id": "CVE-2023-1234", "severity": "High"}, "artifact": {"name": "openssl", "version": "1.1.1", "type": "deb"}},
+			{"vulnerability": {"id": "CVE-2023-5678", "severity": "Low"}, "artifact": {"name": "zlib", "version": "1.2", "type": "deb"}}
+		],
+		"ignoredMatches": [],
+		"descriptor": {"name": "grype", "version": "0.74.0"},
+		"distro": {"name": "debian", "version": "12"}
+	}`
+	body := `{"image": "nginx:1.25", "sbom" : ` + sbom + `, "grype_result":` + grypeResult + `, "sbom_format": "cyclonedx", "status": "partial"}`
+
+	submission, req, err := decodeScanSubmission(t, body, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, "nginx:1.25", req.Image)
+	assert.Equal(t, "cyclonedx", req.SBOMFormat)
+	assert.Equal(t, "partial", req.Status)
+	assert.Empty(t, req.SBOM, "the SBOM is left in the file")
+	require.Len(t, req.GrypeResult.Matches, 2)
+	assert.Equal(t, "CVE-2023-5678", req.GrypeResult.Matches[1].Vulnerability.ID)
+	assert.Equal(t, "0.74.0", req.GrypeResult.Descriptor.Version)
+	require.NotNil(t, req.GrypeResult.Distro)
+	assert.Equal(t, "debian", req.GrypeResult.Distro.Name)
+
+	// The documents are stored as submitted, readers don't share their position
+	first := submission.SBOM()
+	assert.Equal(t, sbom, readSection(t, first))
+	assert.Equal(t, sbom, readSection(t, submission.SBOM()))
+	assert.Equal(t, grypeResult, readSection(t, submission.GrypeResult()))
+}
+
+func TestScanSubmission_DecodeWithoutDocuments(t *testing.T) {
+	submission, req, err := decodeScanSubmission(t, `{"image": "nginx", "sbom": null, "status": "running"}`, 0)
+	require.NoError(t, err)
+	assert.Nil(t, submission.sbom)
+	assert.Nil(t, submission.grypeResult)
+	assert.Zero(t, submission.SBOM().Size())
+	assert.Empty(t, req.GrypeResult.Matches)
+
+	submission, _, err = decodeScanSubmission(t, ``, 0)
+	require.NoError(t, err)
+	assert.Nil(t, submission.sbom)
+}
+
+func TestScanSubmission_DecodeTooManyMatches(t *testing.T) {
+	match := `{"vulnerability": {"id": "CVE-2023-1234"}, "artifact": {"name": "openssl"}}`
+	body := `{"grype_result": {"matches": [` + strings.Repeat(match+",", 4) + match + `]}}`
+
+	_, _, err := decodeScanSubmission(t, body, 3)
+	var tooMany errTooManyMatches
+	require.ErrorAs(t, err, &tooMany)
+	assert.Equal(t, errTooManyMatches{count: 5, limit: 3}, tooMany)
+
+	_, req, err := decodeScanSubmission(t, body, 5)
+	require.NoError(t, err)
+	assert.Len(t, req.GrypeResult.Matches, 5)
+}
+
+func TestScanSubmission_DecodeInvalid(t *testing.T) {
+	for _, body := range []string{`not json`, `[]`, `{"image": 1}`, `{"sbom": {"bomFormat": `, `{"grype_result": {"matches": {}}}`} {
+		_, _, err := decodeScanSubmission(t, body, 0)
+		assert.Error(t, err, body)
+	}
+}
+
+func TestSpoolScanSubmission(t *testing.T) {
+	body := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader(`{"image": "nginx"}`)), 8)
+	_, err := spoolScanSubmission(body)
+	var tooLarge *http.MaxBytesError
+	require.True(t, errors.As(err, &tooLarge))
+
+	submission, err := spoolScanSubmission(strings.NewReader(`{}`))
+	require.NoError(t, err)
+	name := submission.file.Name()
+	require.NoError(t, submission.Close())
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err), "the temporary file is removed")
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ScannerVersionStore is the persistence used by the scanner version handler
+type ScannerVersionStore interface {
+	GetScannerVersionStats(ctx context.Context) ([]models.ScannerVersionStats, error)
+	ListLatestScans(ctx context.Context) ([]models.ScanWithDetails, error)
+	MarkForRescan(ctx context.Context, scanIDs []int) error
+}
+
+// ScannerVersionPolicy defines which tool versions and database builds are too old to trust
+// Empty minimum versions and a zero MaxDBAge disable the corresponding check
+type ScannerVersionPolicy struct {
+	MinSyftVersion  string
+	MinGrypeVersion string
+	MaxDBAge        time.Duration
+}
+
+// Evaluate returns why a scan made with these versions is deprecated, or nil if it is not
+// The database age is measured at scan time: a scan using a DB that was fresh back then is still valid
+func (p ScannerVersionPolicy) Evaluate(syftVersion, grypeVersion *string, dbBuilt *time.Time, scanDate time.Time) []string {
+	var reasons []string
+
+	if p.MinSyftVersion != "" {
+		if syftVersion == nil {
+			reasons = append(reasons, "unknown syft version")
+		} else if compareVersions(*syftVersion, p.MinSyftVersion) < 0 {
+			reasons = append(reasons, fmt.Sprintf("syft %s is older than %s", *syftVersion, p.MinSyftVersion))
+		}
+	}
+
+	if p.MinGrypeVersion != "" {
+		if grypeVersion == nil {
+			reasons = append(reasons, "unknown grype version")
+		} else if compareVersions(*grypeVersion, p.MinGrypeVersion) < 0 {
+			reasons = append(reasons, fmt.Sprintf("grype %s is older than %s", *grypeVersion, p.MinGrypeVersion))
+		}
+	}
+
+	if p.MaxDBAge > 0 {
+		if dbBuilt == nil {
+			reasons = append(reasons, "unknown grype database build")
+		} else if age := scanDate.Sub(*dbBuilt); age > p.MaxDBAge {
+			reasons = append(reasons, fmt.Sprintf("grype database was %d days old at scan time", int(age.Hours()/24)))
+		}
+	}
+
+	return reasons
+}
+
+// compareVersions compares dotted versions numerically, ignoring a leading "v" and any
+// pre-release or build suffix. Missing parts count as zero so "0.74" equals "0.74.0"
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// ScannerVersionHandler reports which scanner versions produced the stored results
+type ScannerVersionHandler struct {
+	logger *zap.Logger
+	store  ScannerVersionStore
+	policy ScannerVersionPolicy
+}
+
+func NewScannerVersionHandler(logger *zap.Logger, store ScannerVersionStore, policy ScannerVersionPolicy) *ScannerVersionHandler {
+	return &ScannerVersionHandler{
+		logger: logger,
+		store:  store,
+		policy: policy,
+	}
+}
+
+// RescanResult is the response of POST /api/v1/admin/scanner-versions/rescan
+type RescanResult struct {
+	Marked int            `json:"marked"`
+	Images []RescanTarget `json:"images"`
+}
+
+// RescanTarget is an image whose latest scan was flagged for rescan
+type RescanTarget struct {
+	ImageID   int      `json:"image_id"`
+	ImageName string   `json:"image_name"`
+	ScanID    int      `json:"scan_id"`
+	Reasons   []string `json:"reasons"`
+}
+
+// ListScannerVersions handles GET /api/v1/scanner-versions
+func (h *ScannerVersionHandler) ListScannerVersions(c echo.Context) error {
+	stats, err := h.store.GetScannerVersionStats(c.Request().Context())
+	if err != nil {
+		h.logger.Error("failed to get scanner version stats", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get scanner versions")
+	}
+
+	for i := range stats {
+		// Groups span a day of DB builds, so the most recent scan decides if the group is still in use
+		reasons := h.policy.Evaluate(stats[i].SyftVersion, stats[i].GrypeVersion, stats[i].GrypeDBBuilt, stats[i].LastScanDate)
+		stats[i].Deprecated = len(reasons) > 0
+		stats[i].DeprecationReasons = reasons
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// MarkDeprecatedForRescan handles POST /api/v1/admin/scanner-versions/rescan
+// Only the latest scan of each image is flagged: older scans are superseded anyway
+func (h *ScannerVersionHandler) MarkDeprecatedForRescan(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	scans, err := h.store.ListLatestScans(ctx)
+	if err != nil {
+		h.logger.Error("failed to list latest scans", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list latest scans")
+	}
+
+	result := RescanResult{Images: []RescanTarget{}}
+	var scanIDs []int
+	for _, scan := range scans {
+		reasons := h.policy.Evaluate(scan.SyftVersion, scan.GrypeVersion, scan.GrypeDBBuilt, scan.ScanDate)
+		if len(reasons) == 0 {
+			continue
+		}
+		scanIDs = append(scanIDs, scan.ID)
+		result.Images = append(result.Images, RescanTarget{
+			ImageID:   scan.ImageID,
+			ImageName: scan.ImageName,
+			ScanID:    scan.ID,
+			Reasons:   reasons,
+		})
+	}
+
+	if err := h.store.MarkForRescan(ctx, scanIDs); err != nil {
+		h.logger.Error("failed to mark scans for rescan", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to mark scans for rescan")
+	}
+	result.Marked = len(scanIDs)
+
+	h.logger.Info("marked scans for rescan",
+		zap.Int("count", result.Marked),
+		zap.String("user", getUserFromHeaders(c)))
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeScannerVersionStore struct {
+	stats  []models.ScannerVersionStats
+	latest []models.ScanWithDetails
+	marked []int
+}
+
+func (s *fakeScannerVersionStore) GetScannerVersionStats(ctx context.Context) ([]models.ScannerVersionStats, error) {
+	return s.stats, nil
+}
+
+func (s *fakeScannerVersionStore) ListLatestScans(ctx context.Context) ([]models.ScanWithDetails, error) {
+	return s.latest, nil
+}
+
+func (s *fakeScannerVersionStore) MarkForRescan(ctx context.Context, scanIDs []int) error {
+	s.marked = append(s.marked, scanIDs...)
+	return nil
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.74.0", "0.74.0", 0},
+		{"v0.74.0", "0.74.0", 0},
+		{"0.74", "0.74.0", 0},
+		{"0.73.9", "0.74.0", -1},
+		{"0.100.0", "0.74.0", 1},
+		{"1.0.0-rc1", "1.0.0", 0},
+		{"1.18.1", "1.2.0", 1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, compareVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+	}
+}
+
+func TestScannerVersionPolicy_Evaluate(t *testing.T) {
+	policy := ScannerVersionPolicy{
+		MinSyftVersion:  "1.0.0",
+		MinGrypeVersion: "0.74.0",
+		MaxDBAge:        3 * 24 * time.Hour,
+	}
+	scanDate := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	freshDB := scanDate.Add(-24 * time.Hour)
+	staleDB := scanDate.Add(-10 * 24 * time.Hour)
+
+	assert.Empty(t, policy.Evaluate(stringPtr("1.18.1"), stringPtr("0.86.1"), &freshDB, scanDate))
+
+	reasons := policy.Evaluate(stringPtr("0.99.0"), stringPtr("0.65.0"), &staleDB, scanDate)
+	assert.Equal(t, []string{
+		"syft 0.99.0 is older than 1.0.0",
+		"grype 0.65.0 is older than 0.74.0",
+		"grype database was 10 days old at scan time",
+	}, reasons)
+
+	assert.Equal(t, []string{"unknown grype database build"},
+		policy.Evaluate(stringPtr("1.18.1"), stringPtr("0.86.1"), nil, scanDate))
+
+	// An empty policy never deprecates anything
+	assert.Empty(t, ScannerVersionPolicy{}.Evaluate(nil, nil, nil, scanDate))
+}
+
+func TestListScannerVersions_MarksDeprecatedGroups(t *testing.T) {
+	now := time.Now()
+	store := &fakeScannerVersionStore{stats: []models.ScannerVersionStats{
+		{GrypeVersion: stringPtr("0.86.1"), ScanCount: 10, LastScanDate: now},
+		{GrypeVersion: stringPtr("0.65.0"), ScanCount: 3, LastScanDate: now},
+	}}
+	handler := NewScannerVersionHandler(zap.NewNop(), store, ScannerVersionPolicy{MinGrypeVersion: "0.74.0"})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scanner-versions", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler.ListScannerVersions(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var stats []models.ScannerVersionStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	require.Len(t, stats, 2)
+	assert.False(t, stats[0].Deprecated)
+	assert.True(t, stats[1].Deprecated)
+	assert.Equal(t, []string{"grype 0.65.0 is older than 0.74.0"}, stats[1].DeprecationReasons)
+}
+
+func TestMarkDeprecatedForRescan(t *testing.T) {
+	now := time.Now()
+	current := models.ScanWithDetails{ImageName: "docker.io/library/nginx:1.25"}
+	current.ID, current.ImageID, current.ScanDate = 11, 1, now
+	current.GrypeVersion = stringPtr("0.86.1")
+	outdated := models.ScanWithDetails{ImageName: "docker.io/library/redis:7.2"}
+	outdated.ID, outdated.ImageID, outdated.ScanDate = 12, 2, now
+	outdated.GrypeVersion = stringPtr("0.65.0")
+
+	store := &fakeScannerVersionStore{latest: []models.ScanWithDetails{current, outdated}}
+	handler := NewScannerVersionHandler(zap.NewNop(), store, ScannerVersionPolicy{MinGrypeVersion: "0.74.0"})
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/scanner-versions/rescan", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, handler.MarkDeprecatedForRescan(e.NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []int{12}, store.marked)
+
+	var result RescanResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.Marked)
+	require.Len(t, result.Images, 1)
+	assert.Equal(t, "docker.io/library/redis:7.2", result.Images[0].ImageName)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -3,16 +3,28 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/invulnerable/backend/internal/analyzer"
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/invulnerable/backend/internal/compliance"
 	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/eventhook"
+	"github.com/invulnerable/backend/internal/events"
+	"github.com/invulnerable/backend/internal/gitops"
+	"github.com/invulnerable/backend/internal/ingest"
 	"github.com/invulnerable/backend/internal/models"
 	"github.com/invulnerable/backend/internal/notifier"
+	"github.com/invulnerable/backend/internal/sbom"
+	"github.com/invulnerable/backend/internal/sla"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
@@ -23,8 +35,41 @@ type ScanHandler struct {
 	scanRepo  *db.ScanRepository
 	vulnRepo  *db.VulnerabilityRepository
 	sbomRepo  *db.SBOMRepository
+	grypeRepo *db.GrypeResultRepository
+	ruleRepo  *db.SuppressionRuleRepository
+	watchRepo *db.WatchlistRepository
+	usageRepo *db.UsageRepository
+	outbox    *db.OutboxRepository
 	analyzer  *analyzer.Analyzer
 	notifier  *notifier.Notifier
+	limits    IngestLimits
+
+	// Workers persisting the matches of submissions, see SetIngestConcurrency
+	ingestPool *ingestPool
+
+	// Background processing of the results of submissions, processed in the request when nil, see SetIngestJobs
+	ingestJobs *ingest.Pool
+
+	// Signed scan reports, disabled when nil, see SetReports
+	reportRepo   *db.ScanReportRepository
+	reportSigner *auth.ReportSigner
+
+	// Compliance profiles evaluated in scan reports, see SetCompliance
+	profiles      []compliance.Profile
+	imageScanRepo *db.ImageScanRepository
+
+	// Webhook alerted of images seen for the first time, disabled when nil, see SetNewImageWebhook
+	newImageWebhook *notifier.WebhookConfig
+
+	// Live updates of the dashboard, nil publishes nothing, see SetEvents
+	events *events.Bus
+
+	// Outgoing event webhook and the severity its policy fails on, disabled when nil, see SetEventWebhook
+	eventWebhook *eventhook.Webhook
+	eventFailOn  string
+
+	// Commits findings summaries to a Git repository, disabled when nil, see SetGitOps
+	gitops *gitops.Committer
 }
 
 func NewScanHandler(
@@ -33,6 +78,11 @@ func NewScanHandler(
 	scanRepo *db.ScanRepository,
 	vulnRepo *db.VulnerabilityRepository,
 	sbomRepo *db.SBOMRepository,
+	grypeRepo *db.GrypeResultRepository,
+	ruleRepo *db.SuppressionRuleRepository,
+	watchRepo *db.WatchlistRepository,
+	usageRepo *db.UsageRepository,
+	outbox *db.OutboxRepository,
 	analyzer *analyzer.Analyzer,
 	notifier *notifier.Notifier,
 ) *ScanHandler {
@@ -42,22 +92,88 @@ func NewScanHandler(
 		scanRepo:  scanRepo,
 		vulnRepo:  vulnRepo,
 		sbomRepo:  sbomRepo,
+		grypeRepo: grypeRepo,
+		ruleRepo:  ruleRepo,
+		watchRepo: watchRepo,
+		usageRepo: usageRepo,
+		outbox:    outbox,
 		analyzer:  analyzer,
 		notifier:  notifier,
+		limits:    DefaultIngestLimits(),
+
+		ingestPool: newIngestPool(DefaultIngestConcurrency()),
 	}
 }
 
+// SetIngestLimits replaces DefaultIngestLimits for scan submissions
+func (h *ScanHandler) SetIngestLimits(limits IngestLimits) {
+	h.limits = limits
+}
+
+// SetIngestConcurrency replaces DefaultIngestConcurrency for scan submissions
+func (h *ScanHandler) SetIngestConcurrency(concurrency IngestConcurrency) {
+	h.ingestPool = newIngestPool(concurrency)
+}
+
+// IngestWorkersBusy returns how many slots of the ingest pool are persisting a match
+func (h *ScanHandler) IngestWorkersBusy() int {
+	return len(h.ingestPool.slots)
+}
+
+// SetEvents publishes the scans created and their diffs on bus
+func (h *ScanHandler) SetEvents(bus *events.Bus) {
+	h.events = bus
+}
+
+// SetNewImageWebhook alerts webhook whenever a scan is submitted for an image never seen before
+func (h *ScanHandler) SetNewImageWebhook(webhook notifier.WebhookConfig) {
+	h.newImageWebhook = &webhook
+}
+
 type ScanRequest struct {
 	Image            string                   `json:"image"`
 	ImageDigest      *string                  `json:"image_digest,omitempty"`
 	GrypeResult      models.GrypeResult       `json:"grype_result"`
-	SBOM             json.RawMessage          `json:"sbom"`
+	SBOM             json.RawMessage          `json:"sbom"` // Left in the spooled body by CreateScan, see scanSubmission
 	SBOMFormat       string                   `json:"sbom_format"`
 	SBOMVersion      *string                  `json:"sbom_version,omitempty"`
 	SyftVersion      *string                  `json:"syft_version,omitempty"`
 	WebhookConfig    *WebhookConfig           `json:"webhook_config,omitempty"`
 	SLAConfig        *SLAConfig               `json:"sla_config,omitempty"`
 	ImageScanContext *models.ImageScanContext `json:"imagescan_context,omitempty"`
+
+	// Target is the path inside the image that was scanned (e.g. /usr/local/bin/app or /srv/api),
+	// for fat images triaged per component. Omitted when the whole image was scanned
+	Target *string `json:"target,omitempty"`
+
+	// Lifecycle: scanners register a scan as running before scanning (no results),
+	// then submit results with the returned scan_id. Status defaults to completed.
+	ScanID        *int    `json:"scan_id,omitempty"`
+	Status        string  `json:"status,omitempty"`
+	FailureReason *string `json:"failure_reason,omitempty"`
+
+	// ScanUUID identifies the scan the request creates, chosen by the scanner for each run. A
+	// submission retried with it is answered 200 with the scan the first attempt created
+	ScanUUID *string `json:"scan_uuid,omitempty"`
+
+	// Unchanged is a delta submission sent instead of sbom and grype_result: if an earlier scan of
+	// the digest has the same results, the scan is recorded against them, otherwise the backend
+	// answers 412 and the scanner submits the full results
+	Unchanged *UnchangedResults `json:"unchanged,omitempty"`
+
+	// Reported by the scanner: how long generating the SBOM and matching it took, and the
+	// uncompressed size and layers of the image, taken from the Grype result when omitted
+	ScanDurationMS *int64 `json:"scan_duration_ms,omitempty"`
+	ImageSizeBytes *int64 `json:"image_size_bytes,omitempty"`
+	LayerCount     *int   `json:"layer_count,omitempty"`
+}
+
+// UnchangedResults identifies the results of a scan without sending them
+type UnchangedResults struct {
+	// ResultsFingerprint is models.GrypeResult.Fingerprint of the Grype result
+	ResultsFingerprint string    `json:"results_fingerprint"`
+	GrypeVersion       string    `json:"grype_version"`
+	GrypeDBBuilt       time.Time `json:"grype_db_built"`
 }
 
 type WebhookConfig struct {
@@ -65,6 +181,7 @@ type WebhookConfig struct {
 	Format      string `json:"format"`
 	MinSeverity string `json:"min_severity"`
 	OnlyFixable bool   `json:"only_fixable"`
+	Locale      string `json:"locale,omitempty"`
 }
 
 type SLAConfig struct {
@@ -72,12 +189,41 @@ type SLAConfig struct {
 	High     int `json:"high"`
 	Medium   int `json:"medium"`
 	Low      int `json:"low"`
+	// IANA timezone the SLA days are counted in, DefaultTimeZone when empty
+	TimeZone string `json:"time_zone,omitempty"`
+	// BusinessDays counts SLA days as Monday to Friday, skipping Holidays (YYYY-MM-DD)
+	BusinessDays bool     `json:"business_days,omitempty"`
+	Holidays     []string `json:"holidays,omitempty"`
 }
 
 // CreateScan handles POST /api/v1/scans - receives scan results from CronJob
 func (h *ScanHandler) CreateScan(c echo.Context) error {
+	if limit := h.limits.MaxBodyBytes; limit > 0 {
+		if c.Request().ContentLength > limit {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is larger than %d bytes", limit))
+		}
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, limit)
+	}
+
+	// The documents are streamed from the spooled body, they are never held in memory
+	submission, err := spoolScanSubmission(c.Request().Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit))
+		}
+		h.logger.Error("failed to spool request body", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read request body")
+	}
+	defer submission.Close()
+
 	var req ScanRequest
-	if err := c.Bind(&req); err != nil {
+	if err := submission.Decode(&req, h.limits.HardMaxMatches); err != nil {
+		var tooMany errTooManyMatches
+		if errors.As(err, &tooMany) {
+			h.logger.Warn("rejected scan over the ingest limits", zap.Error(err))
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+		}
 		h.logger.Error("failed to bind request", zap.Error(err))
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
@@ -95,6 +241,102 @@ func (h *ScanHandler) CreateScan(c echo.Context) error {
 
 	ctx := c.Request().Context()
 
+	status := req.Status
+	if status == "" {
+		status = models.ScanStatusCompleted
+	}
+	if err := db.ValidateScanStatus(status); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	// Pending, running and failed only register the scan; results come later or never
+	hasResults := status == models.ScanStatusCompleted || status == models.ScanStatusPartial
+	if !hasResults && req.ScanID != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "use PATCH /scans/:id to change the status of an existing scan")
+	}
+
+	// Looked up before anything is stored, so a retried submission has no effect
+	var scanUUID *string
+	if req.ScanUUID != nil {
+		if req.ScanID != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "scan_uuid identifies a new scan, it can't be combined with scan_id")
+		}
+		parsed, err := uuid.Parse(*req.ScanUUID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid scan_uuid")
+		}
+		normalized := parsed.String()
+		scanUUID = &normalized
+		existing, err := h.scanRepo.GetByUUID(ctx, normalized)
+		if err == nil {
+			return h.resubmitted(c, existing, req.Image)
+		}
+		if !errors.Is(err, db.ErrNotFound) {
+			h.logger.Error("failed to look up scan by scan_uuid", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to look up scan")
+		}
+	}
+
+	target, err := normalizeTarget(req.Target)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if (req.ScanDurationMS != nil && *req.ScanDurationMS < 0) || (req.ImageSizeBytes != nil && *req.ImageSizeBytes < 0) ||
+		(req.LayerCount != nil && *req.LayerCount < 0) {
+		return echo.NewHTTPError(http.StatusBadRequest, "scan_duration_ms, image_size_bytes and layer_count must not be negative")
+	}
+
+	// A delta submission is only accepted when an earlier scan has the same results and SBOM,
+	// nothing is stored otherwise
+	var unchanged *models.Scan
+	if req.Unchanged != nil {
+		if status != models.ScanStatusCompleted {
+			return echo.NewHTTPError(http.StatusBadRequest, "unchanged results must be submitted as completed")
+		}
+		if req.ImageDigest == nil || *req.ImageDigest == "" || req.SyftVersion == nil || *req.SyftVersion == "" ||
+			req.Unchanged.ResultsFingerprint == "" || req.Unchanged.GrypeDBBuilt.IsZero() {
+			return echo.NewHTTPError(http.StatusBadRequest, "unchanged results require image_digest, syft_version, results_fingerprint and grype_db_built")
+		}
+		if submission.sbom != nil || len(req.GrypeResult.Matches) > 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "unchanged results are submitted without sbom and grype_result")
+		}
+		unchanged, err = h.scanRepo.FindUnchangedResults(ctx, *req.ImageDigest, target, req.Unchanged.GrypeDBBuilt,
+			req.Unchanged.ResultsFingerprint, *req.SyftVersion, req.SBOMFormat)
+		if err != nil {
+			h.logger.Error("failed to look up unchanged scan results", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to look up unchanged scan results")
+		}
+		if unchanged == nil {
+			return echo.NewHTTPError(http.StatusPreconditionFailed, "no earlier scan has these results, submit the full scan")
+		}
+	}
+
+	// Limits apply before anything is stored. A result missing matches can't prove they were fixed,
+	// so it is stored as partial
+	var truncation ingestTruncation
+	var fingerprint string
+	if hasResults {
+		// Fingerprinted as submitted, the way scanners compute it for delta submissions
+		fingerprint = req.GrypeResult.Fingerprint()
+		if truncation, err = applyIngestLimits(&req.GrypeResult, h.limits); err != nil {
+			h.logger.Warn("rejected scan over the ingest limits", zap.String("image", req.Image), zap.Error(err))
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+		}
+		if truncation.MatchesDropped > 0 || truncation.FieldsTruncated > 0 {
+			h.logger.Warn("scan results truncated by ingest limits",
+				zap.String("image", req.Image),
+				zap.Int("matches_received", truncation.MatchesReceived),
+				zap.Int("matches_dropped", truncation.MatchesDropped),
+				zap.Int("fields_truncated", truncation.FieldsTruncated))
+		}
+		if truncation.MatchesDropped > 0 {
+			status = models.ScanStatusPartial
+			if req.FailureReason == nil {
+				reason := truncation.Reason(h.limits)
+				req.FailureReason = &reason
+			}
+		}
+	}
+
 	// Parse image name (registry/repository:tag)
 	registry, repository, tag := parseImageName(req.Image)
 
@@ -110,6 +352,30 @@ func (h *ScanHandler) CreateScan(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create/update image")
 	}
 
+	// Images nobody registered show up as soon as they are scanned, unreviewed until assigned a team and a criticality.
+	// The notification is written with the scan and sent right away
+	var imageEvent *models.OutboxEvent
+	if image.Discovered {
+		h.logger.Info("new image discovered", zap.Int("image_id", image.ID), zap.String("image", image.FullName()))
+		if h.outbox != nil && h.newImageWebhook != nil {
+			e := imageDiscoveredNotification{
+				Webhook: *h.newImageWebhook,
+				Payload: notifier.NewImageNotificationPayload{
+					ImageName:   image.FullName(),
+					ImageID:     image.ID,
+					ImageDigest: image.Digest,
+				},
+			}
+			if req.ImageScanContext != nil {
+				e.Payload.ImageScan = req.ImageScanContext.Namespace + "/" + req.ImageScanContext.Name
+			}
+			if imageEvent, err = models.NewOutboxEvent(models.OutboxKindImageDiscovered, e); err != nil {
+				h.logger.Error("failed to create new image notification", zap.Error(err))
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to create scan")
+			}
+		}
+	}
+
 	// Create scan record
 	// Use Syft version from request if provided
 	var syftVersion *string
@@ -118,30 +384,71 @@ func (h *ScanHandler) CreateScan(c echo.Context) error {
 	}
 
 	// Grype version comes from the Grype result descriptor
-	grypeVersion := &req.GrypeResult.Descriptor.Version
+	var grypeVersion *string
+	if req.GrypeResult.Descriptor.Version != "" || hasResults {
+		grypeVersion = &req.GrypeResult.Descriptor.Version
+	}
+	if unchanged != nil {
+		grypeVersion = &req.Unchanged.GrypeVersion
+	}
 
 	// Set SLA values with defaults
 	slaCritical := 7
 	slaHigh := 30
 	slaMedium := 90
 	slaLow := 180
+	slaTimeZone := sla.DefaultTimeZone
+	var slaBusinessDays bool
+	var slaHolidays []string
 	if req.SLAConfig != nil {
 		slaCritical = req.SLAConfig.Critical
 		slaHigh = req.SLAConfig.High
 		slaMedium = req.SLAConfig.Medium
 		slaLow = req.SLAConfig.Low
+		if req.SLAConfig.TimeZone != "" {
+			if _, err := sla.LoadLocation(req.SLAConfig.TimeZone); err != nil {
+				// The results are still stored, with due dates in UTC
+				h.logger.Warn("ignoring invalid SLA timezone", zap.String("image", req.Image), zap.Error(err))
+			} else {
+				slaTimeZone = req.SLAConfig.TimeZone
+			}
+		}
+		slaBusinessDays = req.SLAConfig.BusinessDays
+		if _, err := sla.NewCalendar(slaBusinessDays, req.SLAConfig.Holidays); err != nil {
+			// Without the holidays deadlines come earlier, never later
+			h.logger.Warn("ignoring invalid SLA holidays", zap.String("image", req.Image), zap.Error(err))
+		} else {
+			slaHolidays = req.SLAConfig.Holidays
+		}
+	}
+
+	// Grype database build, used to find scans made with stale vulnerability data
+	grypeDBBuilt, grypeDBSchema := req.GrypeResult.Descriptor.DB.BuildInfo()
+	if unchanged != nil {
+		grypeDBBuilt, grypeDBSchema = &req.Unchanged.GrypeDBBuilt, unchanged.GrypeDBSchema
 	}
 
 	scan := &models.Scan{
-		ImageID:      image.ID,
-		ScanDate:     time.Now(),
-		SyftVersion:  syftVersion,
-		GrypeVersion: grypeVersion,
-		Status:       "completed",
-		SLACritical:  slaCritical,
-		SLAHigh:      slaHigh,
-		SLAMedium:    slaMedium,
-		SLALow:       slaLow,
+		ImageID:         image.ID,
+		ScanDate:        time.Now(),
+		SyftVersion:     syftVersion,
+		GrypeVersion:    grypeVersion,
+		GrypeDBBuilt:    grypeDBBuilt,
+		GrypeDBSchema:   grypeDBSchema,
+		Status:          status,
+		FailureReason:   req.FailureReason,
+		SLACritical:     slaCritical,
+		SLAHigh:         slaHigh,
+		SLAMedium:       slaMedium,
+		SLALow:          slaLow,
+		SLATimeZone:     slaTimeZone,
+		SLABusinessDays: slaBusinessDays,
+		SLAHolidays:     slaHolidays,
+		MatchesDropped:  truncation.MatchesDropped,
+		FieldsTruncated: truncation.FieldsTruncated,
+	}
+	if hasResults {
+		scan.MatchesReceived = &truncation.MatchesReceived
 	}
 
 	// Add ImageScan context if provided
@@ -150,28 +457,312 @@ func (h *ScanHandler) CreateScan(c echo.Context) error {
 		scan.ImageScanName = &req.ImageScanContext.Name
 	}
 
-	if err := h.scanRepo.Create(ctx, scan); err != nil {
-		h.logger.Error("failed to create scan", zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create scan")
+	if req.ImageDigest != nil && *req.ImageDigest != "" {
+		scan.Digest = req.ImageDigest
+	}
+
+	scan.Target = target
+	scan.ScanUUID = scanUUID
+	scan.APIKeyName = requestAPIKeyName(c)
+
+	// Distroless and scratch images have no distribution, which is stored as such
+	var idLike []string
+	scan.DistroName, scan.DistroVersion, idLike = req.GrypeResult.DistroInfo()
+	scan.DistroIDLike = idLike
+	if unchanged != nil {
+		scan.DistroName, scan.DistroVersion, scan.DistroIDLike = unchanged.DistroName, unchanged.DistroVersion, unchanged.DistroIDLike
+		scan.MatchesReceived = unchanged.MatchesReceived
+	}
+
+	scan.ScanDurationMS, scan.ImageSizeBytes, scan.LayerCount = req.ScanDurationMS, req.ImageSizeBytes, req.LayerCount
+	if scan.ImageSizeBytes == nil && scan.LayerCount == nil {
+		// The earlier scan of an unchanged digest scanned the same image
+		scan.ImageSizeBytes, scan.LayerCount = req.GrypeResult.ImageInfo()
+		if unchanged != nil {
+			scan.ImageSizeBytes, scan.LayerCount = unchanged.ImageSizeBytes, unchanged.LayerCount
+		}
+	}
+
+	// A digest scanned with the same Grype database build has the same findings, so an earlier
+	// scan with identical results can be linked instead of processing every match again.
+	// Looked up before the scan is stored, so it never finds itself
+	var cacheSource *models.Scan
+	if unchanged != nil {
+		scan.ResultsFingerprint = &req.Unchanged.ResultsFingerprint
+		cacheSource = unchanged
+	} else if hasResults {
+		scan.ResultsFingerprint = &fingerprint
+		if status == models.ScanStatusCompleted && scan.Digest != nil && grypeDBBuilt != nil {
+			var err error
+			if cacheSource, err = h.scanRepo.FindCachedResults(ctx, *scan.Digest, scan.Target, *grypeDBBuilt, fingerprint); err != nil {
+				h.logger.Warn("failed to look up cached scan results", zap.Error(err))
+			}
+		}
 	}
 
-	// Store SBOM
-	sbom := &models.SBOM{
-		ScanID:  scan.ID,
-		Format:  req.SBOMFormat,
-		Version: req.SBOMVersion,
+	// The scan notification is written with the scan and held until its results are processed.
+	// If this replica dies before, it is still sent once the hold expires
+	var scanEvent *models.OutboxEvent
+	if hasResults && h.outbox != nil && req.WebhookConfig != nil && req.WebhookConfig.URL != "" {
+		var err error
+		scanEvent, err = models.NewOutboxEvent(models.OutboxKindScanCompleted, scanCompletedEvent{
+			Image:       req.Image,
+			ImageDigest: req.ImageDigest,
+			Webhook: notifier.WebhookConfig{
+				URL:         req.WebhookConfig.URL,
+				Format:      req.WebhookConfig.Format,
+				MinSeverity: req.WebhookConfig.MinSeverity,
+				OnlyFixable: req.WebhookConfig.OnlyFixable,
+				Locale:      req.WebhookConfig.Locale,
+			},
+		})
+		if err != nil {
+			h.logger.Error("failed to create scan notification", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to create scan")
+		}
+		scanEvent.AvailableAt = time.Now().Add(scanNotificationHold)
 	}
 
-	if err := h.sbomRepo.Create(ctx, sbom, []byte(req.SBOM)); err != nil {
-		h.logger.Error("failed to create SBOM", zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create SBOM")
+	// Queued results leave the scan running until they are processed
+	if hasResults && h.ingestJobs != nil {
+		scan.Status = models.ScanStatusRunning
 	}
 
+	if req.ScanID != nil {
+		// Attach results to the scan registered when the scanner started
+		existing, err := h.scanRepo.GetByID(ctx, *req.ScanID)
+		if err != nil {
+			return err
+		}
+		if existing.ImageID != image.ID {
+			return echo.NewHTTPError(http.StatusBadRequest, "scan_id belongs to a different image")
+		}
+		if existing.IsFinished() {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("scan already %s", existing.Status))
+		}
+
+		scan.ID = existing.ID
+		scan.ScanDate = existing.ScanDate
+		scan.CreatedAt = existing.CreatedAt
+		if err := h.scanRepo.Complete(ctx, scan, scanEvent, imageEvent); err != nil {
+			h.logger.Error("failed to complete scan", zap.Error(err), zap.Int("scan_id", scan.ID))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to complete scan")
+		}
+	} else {
+		// Quotas apply to new scans only, results of a registered scan are always accepted
+		if h.usageRepo != nil && scan.ImageScanNamespace != nil {
+			quota, exceeded, err := checkQuota(ctx, h.usageRepo, *scan.ImageScanNamespace, time.Now())
+			if err != nil {
+				h.logger.Warn("failed to check team quota", zap.Error(err), zap.String("namespace", *scan.ImageScanNamespace))
+			} else if len(exceeded) > 0 {
+				if quota.Enforce {
+					return echo.NewHTTPError(http.StatusTooManyRequests,
+						fmt.Sprintf("quota exceeded for namespace %s: %s", *scan.ImageScanNamespace, strings.Join(exceeded, ", ")))
+				}
+				h.logger.Warn("team quota exceeded",
+					zap.String("namespace", *scan.ImageScanNamespace),
+					zap.Strings("exceeded", exceeded))
+				c.Response().Header().Set("X-Quota-Warning", strings.Join(exceeded, ", "))
+			}
+		}
+
+		if err := h.scanRepo.Create(ctx, scan, scanEvent, imageEvent); err != nil {
+			// A concurrent attempt of the submission stored the scan first
+			if scanUUID != nil && errors.Is(err, db.ErrConflict) {
+				if existing, err := h.scanRepo.GetByUUID(ctx, *scanUUID); err == nil {
+					return h.resubmitted(c, existing, req.Image)
+				}
+			}
+			h.logger.Error("failed to create scan", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to create scan")
+		}
+	}
+
+	if !hasResults {
+		h.logger.Info("scan registered",
+			zap.Int("scan_id", scan.ID),
+			zap.String("image", req.Image),
+			zap.String("status", scan.Status))
+		return c.JSON(http.StatusCreated, scan)
+	}
+
+	// Store SBOM, an unchanged scan shares the document of the scan it matched
+	if unchanged != nil {
+		if _, err := h.sbomRepo.Share(ctx, scan.ID, unchanged.ID); err != nil {
+			h.logger.Error("failed to share SBOM", zap.Error(err), zap.Int("source_scan_id", unchanged.ID))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to create SBOM")
+		}
+	} else {
+		sbom := &models.SBOM{
+			ScanID:  scan.ID,
+			Format:  req.SBOMFormat,
+			Version: req.SBOMVersion,
+		}
+
+		if err := h.sbomRepo.CreateFrom(ctx, sbom, submission.SBOM()); err != nil {
+			h.logger.Error("failed to create SBOM", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to create SBOM")
+		}
+
+		// The component index only serves impact assessments, which index missing SBOMs themselves,
+		// and the quality is evaluated on first read when it is missing. The SBOM is parsed while
+		// the matches are persisted, the response waits for both
+		var indexing sync.WaitGroup
+		indexing.Add(1)
+		go func() {
+			defer indexing.Done()
+			if err := h.sbomRepo.IndexComponentsFrom(ctx, scan.ID, submission.SBOM()); err != nil {
+				h.logger.Warn("failed to index SBOM components", zap.Error(err), zap.Int("scan_id", scan.ID))
+			}
+			h.evaluateSBOMQuality(ctx, scan, submission.SBOM())
+		}()
+		defer indexing.Wait()
+	}
+
+	// Archive the Grype result as submitted, it is evidence and never fails the submission
+	if h.grypeRepo != nil && submission.grypeResult != nil {
+		if _, err := h.grypeRepo.CreateFrom(ctx, scan.ID, submission.GrypeResult()); err != nil {
+			h.logger.Warn("failed to archive raw Grype result", zap.Error(err), zap.Int("scan_id", scan.ID))
+		}
+	}
+
+	// Matches are processed in the background when there are ingest workers, the scan stays running
+	// until they are. Otherwise they are processed before the response
+	params := ingestJobParams{
+		Image:            req.Image,
+		Status:           status,
+		FailureReason:    req.FailureReason,
+		ImageScan:        req.ImageScanContext,
+		Unchanged:        unchanged != nil,
+		HeldNotification: scanEvent != nil,
+	}
+	if cacheSource != nil {
+		params.CachedFromScanID = &cacheSource.ID
+	}
+	if h.ingestJobs != nil {
+		job, err := h.enqueueResults(ctx, scan, params, req.GrypeResult.Matches)
+		if err != nil {
+			h.logger.Error("failed to queue scan results", zap.Error(err), zap.Int("scan_id", scan.ID))
+			reason := "failed to queue results"
+			if err := h.scanRepo.UpdateStatus(ctx, scan.ID, models.ScanStatusFailed, &reason); err != nil {
+				h.logger.Error("failed to mark scan as failed", zap.Error(err), zap.Int("scan_id", scan.ID))
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to queue scan results")
+		}
+		h.logger.Info("scan results queued",
+			zap.Int("scan_id", scan.ID),
+			zap.Int64("ingest_job_id", job.ID),
+			zap.String("image", req.Image),
+			zap.Int("matches", job.MatchCount))
+		c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/api/v1/ingest-jobs/%d", job.ID))
+		return c.JSON(http.StatusAccepted, ScanAccepted{Scan: scan, IngestJob: job})
+	}
+	if err := h.processResults(ctx, scan, image, params, req.GrypeResult.Matches); err != nil {
+		h.logger.Error("failed to process scan results", zap.Error(err), zap.Int("scan_id", scan.ID))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to process scan results")
+	}
+	return c.JSON(http.StatusCreated, scan)
+}
+
+// resubmitted answers a submission retried with the scan_uuid of a stored scan with the scan, and
+// the job processing its results while it runs. The scan_uuid of a scan of another image is a conflict
+func (h *ScanHandler) resubmitted(c echo.Context, scan *models.Scan, imageName string) error {
+	ctx := c.Request().Context()
+	image, err := h.imageRepo.GetByID(ctx, scan.ImageID)
+	if err != nil {
+		return err
+	}
+	registry, repository, tag := parseImageName(imageName)
+	if image.Registry != registry || image.Repository != repository || image.Tag != tag {
+		return echo.NewHTTPError(http.StatusConflict, "scan_uuid was already used for a scan of another image")
+	}
+
+	h.logger.Info("scan already submitted",
+		zap.Int("scan_id", scan.ID),
+		zap.String("scan_uuid", *scan.ScanUUID),
+		zap.String("status", scan.Status))
+	if scan.Status == models.ScanStatusRunning && h.ingestJobs != nil {
+		job, err := h.ingestJobs.GetByScanID(ctx, scan.ID)
+		if err != nil {
+			h.logger.Warn("failed to get ingest job", zap.Error(err), zap.Int("scan_id", scan.ID))
+		} else if job != nil {
+			return c.JSON(http.StatusOK, ScanAccepted{Scan: scan, IngestJob: job})
+		}
+	}
+	return c.JSON(http.StatusOK, scan)
+}
+
+// processResults persists the matches of a stored scan, compares it with the previous scan and
+// queues the notifications it triggers. A scan stored as running gets the status of params once
+// its matches are persisted. Failing matches are logged and skipped: an error means the results
+// weren't processed, and the scan is still running unless it was marked failed
+func (h *ScanHandler) processResults(ctx context.Context, scan *models.Scan, image *models.Image, params ingestJobParams, submitted []models.GrypeMatch) error {
 	// Track which vulnerabilities we've already reverted in this scan to avoid duplicates
 	revertedVulns := make(map[string]bool)
 
-	// Process vulnerabilities
-	for _, match := range req.GrypeResult.Matches {
+	// Findings whose fix version changed, for watchlist subscribers, and findings that had no fix
+	// version before, for the ImageScans of every image with them
+	var fixChanges []notifier.WatchlistEvent
+	var fixesAvailable []int
+
+	// Waivers accept matching findings that nobody triaged yet
+	var rules []models.SuppressionRule
+	if h.ruleRepo != nil {
+		var err error
+		if rules, err = h.ruleRepo.ListActive(ctx, time.Now()); err != nil {
+			h.logger.Warn("failed to load suppression rules, findings will not be suppressed", zap.Error(err))
+		}
+	}
+
+	matches := submitted
+	if params.CachedFromScanID != nil {
+		sourceScanID := *params.CachedFromScanID
+		vulns, err := h.scanRepo.LinkCachedResults(ctx, scan.ID, sourceScanID, time.Now(), scan.ImageScanNamespace, scan.ImageScanName)
+		if err != nil && params.Unchanged {
+			// There are no matches to fall back to. Without findings the scan would mark every
+			// vulnerability of the image as fixed, it is failed so the next scan submits in full
+			h.logger.Error("failed to link unchanged scan results", zap.Error(err), zap.Int("scan_id", scan.ID))
+			reason := "failed to link unchanged results of scan " + strconv.Itoa(sourceScanID)
+			if err := h.scanRepo.UpdateStatus(ctx, scan.ID, models.ScanStatusFailed, &reason); err != nil {
+				h.logger.Error("failed to mark scan as failed", zap.Error(err), zap.Int("scan_id", scan.ID))
+			}
+			return fmt.Errorf("%s: %w", reason, err)
+		} else if err != nil {
+			h.logger.Warn("failed to link cached scan results, processing matches",
+				zap.Error(err),
+				zap.Int("scan_id", scan.ID),
+				zap.Int("source_scan_id", sourceScanID))
+		} else {
+			for i := range vulns {
+				vuln := &vulns[i]
+				if h.revertManuallyFixed(ctx, vuln) {
+					vuln.Status = models.StatusActive
+				}
+				if vuln.Status == models.StatusActive {
+					h.applySuppressionRules(ctx, vuln, rules)
+				}
+			}
+			scan.CachedFromScanID = &sourceScanID
+			matches = nil
+			h.logger.Info("scan results linked from cache",
+				zap.Int("scan_id", scan.ID),
+				zap.Int("source_scan_id", sourceScanID),
+				zap.Int("vulnerabilities", len(vulns)))
+		}
+	}
+
+	// Matches are normalized in order and persisted by the workers of the scan, see ingestMatches. Each
+	// records what changed in its own result, merged in match order once they are all persisted
+	type matchResult struct {
+		fixChange    *notifier.WatchlistEvent
+		fixAvailable int
+	}
+	results := make([]matchResult, len(matches))
+	var revertedMu sync.Mutex
+
+	ingestMatches(ctx, h.ingestPool, len(matches), func(i int) (*models.Vulnerability, string) {
+		match := matches[i]
+
 		// Determine fix version
 		var fixVersion *string
 		if match.Vulnerability.Fix != nil && len(match.Vulnerability.Fix.Versions) > 0 {
@@ -185,38 +776,59 @@ func (h *ScanHandler) CreateScan(c echo.Context) error {
 			url = &match.Vulnerability.URLs[0]
 		}
 
+		var purl *string
+		if match.Artifact.PURL != "" {
+			purl = &match.Artifact.PURL
+		}
+
+		var advisories json.RawMessage
+		if len(match.Vulnerability.Advisories) > 0 {
+			advisories, _ = json.Marshal(match.Vulnerability.Advisories)
+		}
+
 		vuln := &models.Vulnerability{
 			CVEID:           match.Vulnerability.ID,
 			PackageName:     match.Artifact.Name,
 			PackageVersion:  match.Artifact.Version,
 			PackageType:     &match.Artifact.Type,
+			PURL:            purl,
 			Severity:        normalizeSeverity(match.Vulnerability.Severity),
 			FixVersion:      fixVersion,
 			URL:             url,
 			Description:     &match.Vulnerability.Description,
+			Advisories:      advisories,
+			KnownExploited:  len(match.Vulnerability.KnownExploited) > 0,
 			Status:          "active",
 			FirstDetectedAt: time.Now(),
 			LastSeenAt:      time.Now(),
 		}
 
 		// Add ImageScan context if provided (will update on every scan via Upsert)
-		if req.ImageScanContext != nil {
-			vuln.ImageScanNamespace = &req.ImageScanContext.Namespace
-			vuln.ImageScanName = &req.ImageScanContext.Name
+		if params.ImageScan != nil {
+			vuln.ImageScanNamespace = &params.ImageScan.Namespace
+			vuln.ImageScanName = &params.ImageScan.Name
 			h.logger.Debug("assigning ImageScan context to vulnerability",
 				zap.String("cve_id", vuln.CVEID),
-				zap.String("namespace", req.ImageScanContext.Namespace),
-				zap.String("name", req.ImageScanContext.Name))
+				zap.String("namespace", params.ImageScan.Namespace),
+				zap.String("name", params.ImageScan.Name))
 		} else {
 			h.logger.Warn("no ImageScan context to assign to vulnerability",
 				zap.String("cve_id", vuln.CVEID))
 		}
 
+		return vuln, vulnKey(vuln)
+	}, func(i int, vuln *models.Vulnerability) {
 		// Check if vulnerability already exists
 		existing, err := h.vulnRepo.GetByUniqueKey(ctx, vuln.CVEID, vuln.PackageName, vuln.PackageVersion)
 		if err != nil {
 			h.logger.Error("failed to check existing vulnerability", zap.Error(err))
-			continue
+			return
+		}
+
+		// Status the vulnerability has once this match is persisted
+		currentStatus := models.StatusActive
+		if existing != nil {
+			currentStatus = existing.Status
 		}
 
 		if existing != nil {
@@ -235,166 +847,329 @@ func (h *ScanHandler) CreateScan(c echo.Context) error {
 				zap.String("current_status", existing.Status),
 				zap.String("updated_by", updatedByStr))
 
-			// Check if this CVE was manually marked as fixed
-			// If so, revert it back to active since it's still being detected
-			// We revert if: status is "fixed" AND (updated_by is NULL OR updated_by is not "system")
-			// This handles both manually fixed CVEs and CVEs fixed before the audit migration
-
-			// Create unique key for this vulnerability (cve_id + package_name + package_version)
-			vulnKey := fmt.Sprintf("%s|%s|%s", vuln.CVEID, vuln.PackageName, vuln.PackageVersion)
-
-			shouldRevert := existing.Status == models.StatusFixed &&
-				(existing.UpdatedBy == nil || *existing.UpdatedBy != "system") &&
-				!revertedVulns[vulnKey] // Only revert once per scan
-
-			if shouldRevert {
-				h.logger.Info("reverting fixed CVE back to active (still detected in scan)",
-					zap.String("cve_id", vuln.CVEID),
-					zap.String("package", vuln.PackageName),
-					zap.String("previous_updated_by", updatedByStr))
-
-				// Revert status to active
-				newStatus := models.StatusActive
-				updateCtx := &models.VulnerabilityUpdateWithContext{
-					Status:    &newStatus,
-					UpdatedBy: "system",
-				}
+			// Only revert once per scan. Matches of a key are persisted by the same worker, the
+			// lock only guards the map
+			key := vulnKey(vuln)
+			revertedMu.Lock()
+			reverted := revertedVulns[key]
+			revertedMu.Unlock()
+			if !reverted && h.revertManuallyFixed(ctx, existing) {
+				// Mark as reverted to prevent duplicate history entries
+				revertedMu.Lock()
+				revertedVulns[key] = true
+				revertedMu.Unlock()
+				currentStatus = models.StatusActive
+			}
 
-				if err := h.vulnRepo.Update(ctx, existing.ID, updateCtx); err != nil {
-					h.logger.Error("failed to revert manually fixed CVE",
-						zap.Error(err),
-						zap.Int("vuln_id", existing.ID))
-				} else {
-					// Mark as reverted to prevent duplicate history entries
-					revertedVulns[vulnKey] = true
+			if !sameFixVersion(existing.FixVersion, vuln.FixVersion) {
+				event := watchlistEvent(models.WatchlistEventFixChanged, vuln)
+				event.PreviousFixVersion = existing.FixVersion
+				results[i].fixChange = &event
+				if existing.FixVersion == nil {
+					results[i].fixAvailable = existing.ID
 				}
-				// Note: Update() method already creates history entry, no need to duplicate
 			}
 		}
 
 		if err := h.vulnRepo.Upsert(ctx, vuln); err != nil {
 			h.logger.Error("failed to upsert vulnerability", zap.Error(err))
-			continue
+			return
+		}
+
+		// Grype rescored the CVE, SLA deadlines keep the severity at first detection by default
+		if existing != nil && existing.Severity != vuln.Severity {
+			h.logger.Info("vulnerability severity changed",
+				zap.String("cve_id", vuln.CVEID),
+				zap.String("package", vuln.PackageName),
+				zap.String("old_severity", existing.Severity),
+				zap.String("new_severity", vuln.Severity),
+				zap.Int("scan_id", scan.ID))
+			if err := h.vulnRepo.RecordSeverityChange(ctx, scan.ID, vuln.ID, vuln.CVEID, existing.Severity, vuln.Severity); err != nil {
+				h.logger.Error("failed to record severity change", zap.Error(err), zap.Int("vulnerability_id", vuln.ID))
+			}
 		}
 
 		// Link vulnerability to scan
 		if err := h.vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID); err != nil {
 			h.logger.Error("failed to link vulnerability to scan", zap.Error(err))
 		}
+
+		if currentStatus == models.StatusActive {
+			h.applySuppressionRules(ctx, vuln, rules)
+		}
+	})
+
+	for _, result := range results {
+		if result.fixChange != nil {
+			fixChanges = append(fixChanges, *result.fixChange)
+		}
+		if result.fixAvailable != 0 {
+			fixesAvailable = append(fixesAvailable, result.fixAvailable)
+		}
+	}
+	if ctx.Err() != nil {
+		// Matches were skipped, the results are processed again
+		return ctx.Err()
+	}
+
+	// Comparing needs the final status, partial scans never mark vulnerabilities as fixed
+	if scan.Status != params.Status {
+		if err := h.scanRepo.UpdateStatus(ctx, scan.ID, params.Status, params.FailureReason); err != nil {
+			return fmt.Errorf("failed to update scan status: %w", err)
+		}
+		scan.Status, scan.FailureReason = params.Status, params.FailureReason
 	}
 
 	// Automatically compare with previous scan to mark fixed vulnerabilities
 	// This must happen synchronously before webhook notification to ensure accurate counts
-	if _, err := h.analyzer.CompareScan(ctx, scan.ID); err != nil {
+	diff, err := h.analyzer.CompareScan(ctx, scan.ID)
+	if err != nil {
 		// Log error but don't fail the scan - this is a best-effort optimization
 		h.logger.Warn("failed to auto-compare scan with previous scan",
 			zap.Error(err),
 			zap.Int("scan_id", scan.ID))
 	}
 
-	// Send webhook notification if configured
-	if req.WebhookConfig != nil && req.WebhookConfig.URL != "" {
-		go func() {
-			// Get all vulnerabilities for this scan with their current status from the database
-			// This allows us to filter out ignored/accepted CVEs
-			vulnsFromDB, err := h.scanRepo.GetVulnerabilities(context.Background(), scan.ID)
-			if err != nil {
-				h.logger.Error("failed to get vulnerabilities for webhook notification",
-					zap.Error(err),
-					zap.Int("scan_id", scan.ID))
-				return
-			}
+	if diff != nil {
+		h.publishScanDiff(diff, params.Image)
+	}
+
+	// The report records the results as processed, later triage doesn't change it
+	if h.reportRepo != nil {
+		h.createReport(ctx, scan, image.FullName())
+	}
 
-			// Create a map of CVE+Package to status for quick lookup
-			vulnStatusMap := make(map[string]string)
-			for _, v := range vulnsFromDB {
-				key := fmt.Sprintf("%s|%s|%s", v.CVEID, v.PackageName, v.PackageVersion)
-				vulnStatusMap[key] = v.Status
+	// Findings new to this image and fix changes go to the subscribers watching them, and the
+	// held scan notification is released now that the results are processed
+	var events []*models.OutboxEvent
+	if h.watchRepo != nil && h.outbox != nil {
+		changes := fixChanges
+		if diff != nil {
+			for i := range diff.NewVulns {
+				changes = append(changes, watchlistEvent(models.WatchlistEventDetected, &diff.NewVulns[i]))
+			}
+		}
+		if len(changes) > 0 {
+			if events, err = h.watchlistNotifications(ctx, params.Image, scan.ID, changes); err != nil {
+				h.logger.Error("failed to list watchlist subscriptions", zap.Error(err), zap.Int("scan_id", scan.ID))
 			}
+		}
+	}
+	if h.outbox != nil && len(fixesAvailable) > 0 {
+		notifications, err := h.fixAvailableNotifications(ctx, scan.ID, fixesAvailable)
+		if err != nil {
+			h.logger.Error("failed to list fix-available notifications", zap.Error(err), zap.Int("scan_id", scan.ID))
+		}
+		events = append(events, notifications...)
+	}
+	if h.outbox != nil && h.eventWebhook != nil {
+		webhookEvents, err := h.scanWebhookEvents(ctx, scan, image, diff)
+		if err != nil {
+			h.logger.Error("failed to create event webhook events", zap.Error(err), zap.Int("scan_id", scan.ID))
+		}
+		events = append(events, webhookEvents...)
+	}
+	if h.outbox != nil && h.gitops != nil {
+		commit, err := h.gitopsCommit(ctx, scan, image)
+		if err != nil {
+			h.logger.Error("failed to render scan summary", zap.Error(err), zap.Int("scan_id", scan.ID))
+		} else {
+			events = append(events, commit)
+		}
+	}
+	if h.outbox != nil && (params.HeldNotification || len(events) > 0) {
+		if err := h.outbox.ReleaseScan(ctx, &scan.ID, events...); err != nil {
+			h.logger.Error("failed to queue scan notifications", zap.Error(err), zap.Int("scan_id", scan.ID))
+		}
+	}
 
-			// Filter matches to exclude ignored/accepted CVEs
-			matchesToNotify := []models.GrypeMatch{}
-			for _, match := range req.GrypeResult.Matches {
-				key := fmt.Sprintf("%s|%s|%s", match.Vulnerability.ID, match.Artifact.Name, match.Artifact.Version)
-				status, exists := vulnStatusMap[key]
+	h.logger.Info("scan created successfully",
+		zap.Int("scan_id", scan.ID),
+		zap.String("image", params.Image),
+		zap.Int("vulnerabilities", len(submitted)))
+	h.publishScanCreated(scan, params.Image, len(submitted))
+	return nil
+}
 
-				// Skip if CVE is ignored or accepted (triaged as not actionable)
-				if exists && (status == models.StatusIgnored || status == models.StatusAccepted) {
-					continue
-				}
+// revertManuallyFixed reverts a CVE marked as fixed back to active, since it is still being detected.
+// We revert if: status is "fixed" AND (updated_by is NULL OR updated_by is not "system")
+// This handles both manually fixed CVEs and CVEs fixed before the audit migration
+func (h *ScanHandler) revertManuallyFixed(ctx context.Context, existing *models.Vulnerability) bool {
+	if existing.Status != models.StatusFixed || (existing.UpdatedBy != nil && *existing.UpdatedBy == "system") {
+		return false
+	}
 
-				// Apply onlyFixable filter if configured
-				if req.WebhookConfig.OnlyFixable && len(match.Vulnerability.Fix.Versions) == 0 {
-					continue
-				}
+	updatedByStr := "nil"
+	if existing.UpdatedBy != nil {
+		updatedByStr = *existing.UpdatedBy
+	}
+	h.logger.Info("reverting fixed CVE back to active (still detected in scan)",
+		zap.String("cve_id", existing.CVEID),
+		zap.String("package", existing.PackageName),
+		zap.String("previous_updated_by", updatedByStr))
+
+	// Note: Update() method already creates history entry, no need to duplicate
+	newStatus := models.StatusActive
+	updateCtx := &models.VulnerabilityUpdateWithContext{
+		Status:    &newStatus,
+		UpdatedBy: "system",
+	}
+	if err := h.vulnRepo.Update(ctx, existing.ID, updateCtx); err != nil {
+		h.logger.Error("failed to revert manually fixed CVE",
+			zap.Error(err),
+			zap.Int("vuln_id", existing.ID))
+		return false
+	}
+	return true
+}
 
-				matchesToNotify = append(matchesToNotify, match)
-			}
+// applySuppressionRules accepts the vulnerability if a waiver matches it
+func (h *ScanHandler) applySuppressionRules(ctx context.Context, vuln *models.Vulnerability, rules []models.SuppressionRule) {
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(vuln) {
+			continue
+		}
 
-			// Calculate severity counts for notification (only for actionable vulnerabilities)
-			severityCounts := notifier.SeverityCounts{}
-			for _, match := range matchesToNotify {
-				switch match.Vulnerability.Severity {
-				case "Critical":
-					severityCounts.Critical++
-				case "High":
-					severityCounts.High++
-				case "Medium":
-					severityCounts.Medium++
-				case "Low":
-					severityCounts.Low++
-				default:
-					severityCounts.Negligible++
-				}
-			}
+		status := models.StatusAccepted
+		notes := fmt.Sprintf("Suppressed by rule #%d", rule.ID)
+		if rule.Reason != nil {
+			notes += ": " + *rule.Reason
+		}
+		update := &models.VulnerabilityUpdateWithContext{
+			Status:    &status,
+			Notes:     &notes,
+			UpdatedBy: "suppression-rule",
+		}
+		if err := h.vulnRepo.Update(ctx, vuln.ID, update); err != nil {
+			h.logger.Error("failed to apply suppression rule",
+				zap.Error(err),
+				zap.Int("vuln_id", vuln.ID),
+				zap.Int("rule_id", rule.ID))
+		}
+		return
+	}
+}
 
-			webhookConfig := notifier.WebhookConfig{
-				URL:         req.WebhookConfig.URL,
-				Format:      req.WebhookConfig.Format,
-				MinSeverity: req.WebhookConfig.MinSeverity,
-				OnlyFixable: req.WebhookConfig.OnlyFixable,
-			}
+// watchlistNotifications builds one notification per matching watchlist subscription, with the
+// events of the scan it watches
+func (h *ScanHandler) watchlistNotifications(ctx context.Context, imageName string, scanID int, events []notifier.WatchlistEvent) ([]*models.OutboxEvent, error) {
+	cveIDs := make([]string, 0, len(events))
+	packageNames := make([]string, 0, len(events))
+	for _, event := range events {
+		cveIDs = append(cveIDs, event.CVEID)
+		packageNames = append(packageNames, event.PackageName)
+	}
 
-			notificationPayload := notifier.NotificationPayload{
-				Image:          req.Image,
-				ImageDigest:    req.ImageDigest,
-				ScanID:         scan.ID,
-				TotalVulns:     len(matchesToNotify),
-				SeverityCounts: severityCounts,
-			}
+	subs, err := h.watchRepo.ListMatching(ctx, cveIDs, packageNames)
+	if err != nil {
+		return nil, err
+	}
 
-			if err := h.notifier.SendNotification(context.Background(), webhookConfig, notificationPayload); err != nil {
-				h.logger.Error("failed to send webhook notification",
-					zap.Error(err),
-					zap.String("webhook_url", req.WebhookConfig.URL),
-					zap.Int("scan_id", scan.ID))
-			} else if len(matchesToNotify) == 0 {
-				h.logger.Info("no actionable vulnerabilities to notify about (all ignored/accepted)",
-					zap.Int("scan_id", scan.ID),
-					zap.Int("total_detected", len(req.GrypeResult.Matches)),
-					zap.Int("actionable", 0))
+	var notifications []*models.OutboxEvent
+	for i := range subs {
+		sub := &subs[i]
+		var matched []notifier.WatchlistEvent
+		for _, event := range events {
+			if sub.Matches(&models.Vulnerability{CVEID: event.CVEID, PackageName: event.PackageName}) {
+				matched = append(matched, event)
 			}
-		}()
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		notification, err := models.NewOutboxEvent(models.OutboxKindWatchlist, watchlistNotification{
+			SubscriptionID: sub.ID,
+			Webhook:        notifier.WebhookConfig{URL: sub.WebhookURL, Format: sub.WebhookFormat, Locale: sub.Locale},
+			Payload: notifier.WatchlistNotificationPayload{
+				WatchCVE:     sub.CVEID,
+				WatchPackage: sub.PackageName,
+				ImageName:    imageName,
+				ScanID:       scanID,
+				Events:       matched,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
 	}
+	return notifications, nil
+}
 
-	h.logger.Info("scan created successfully",
-		zap.Int("scan_id", scan.ID),
-		zap.String("image", req.Image),
-		zap.Int("vulnerabilities", len(req.GrypeResult.Matches)))
+// fixAvailableNotifications builds one notification per ImageScan with fix-available notifications
+// enabled whose images have findings that gained a fix version in the scan
+func (h *ScanHandler) fixAvailableNotifications(ctx context.Context, scanID int, vulnerabilityIDs []int) ([]*models.OutboxEvent, error) {
+	targets, err := h.vulnRepo.ListFixAvailableTargets(ctx, vulnerabilityIDs)
+	if err != nil {
+		return nil, err
+	}
 
-	return c.JSON(http.StatusCreated, scan)
+	var notifications []*models.OutboxEvent
+	for start := 0; start < len(targets); {
+		// Targets are ordered by ImageScan
+		end := start
+		e := fixAvailableNotification{
+			Namespace: targets[start].Namespace,
+			Name:      targets[start].Name,
+			Payload: notifier.FixAvailableNotificationPayload{
+				ImageScan: targets[start].Namespace + "/" + targets[start].Name,
+				ScanID:    scanID,
+			},
+		}
+		for ; end < len(targets) && targets[end].Namespace == e.Namespace && targets[end].Name == e.Name; end++ {
+			target := &targets[end]
+			e.Payload.Fixes = append(e.Payload.Fixes, notifier.FixAvailableEvent{
+				CVEID:          target.CVEID,
+				PackageName:    target.PackageName,
+				PackageVersion: target.PackageVersion,
+				Severity:       target.Severity,
+				FixVersion:     target.FixVersion,
+				AffectedImages: target.AffectedImages,
+				Images:         target.Images,
+			})
+		}
+		start = end
+
+		notification, err := models.NewOutboxEvent(models.OutboxKindFixAvailable, e)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
 }
 
-// ListScans handles GET /api/v1/scans
-func (h *ScanHandler) ListScans(c echo.Context) error {
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit <= 0 || limit > 100 {
-		limit = 20
+func watchlistEvent(kind string, vuln *models.Vulnerability) notifier.WatchlistEvent {
+	return notifier.WatchlistEvent{
+		Kind:           kind,
+		CVEID:          vuln.CVEID,
+		PackageName:    vuln.PackageName,
+		PackageVersion: vuln.PackageVersion,
+		Severity:       vuln.Severity,
+		FixVersion:     vuln.FixVersion,
 	}
+}
+
+// vulnKey is the unique key of a vulnerability (cve_id + package_name + package_version)
+func vulnKey(vuln *models.Vulnerability) string {
+	return fmt.Sprintf("%s|%s|%s", vuln.CVEID, vuln.PackageName, vuln.PackageVersion)
+}
 
-	offset, _ := strconv.Atoi(c.QueryParam("offset"))
-	if offset < 0 {
-		offset = 0
+func sameFixVersion(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ListScans handles GET /api/v1/scans
+func (h *ScanHandler) ListScans(c echo.Context) error {
+	limit, offset := parsePagination(c, 20)
+	sort, err := parseSort(c, db.ValidateScanSort)
+	if err != nil {
+		return err
 	}
 
 	var imageID *int
@@ -420,25 +1195,39 @@ func (h *ScanHandler) ListScans(c echo.Context) error {
 		hasFix = &hasFixBool
 	}
 
+	// Parse status parameter (e.g. status=failed to find scans to retry)
+	var status *string
+	if statusStr := c.QueryParam("status"); statusStr != "" {
+		if err := db.ValidateScanStatus(statusStr); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		status = &statusStr
+	}
+
+	// Parse target parameter (scans of one component of the image)
+	var target *string
+	if targetStr := c.QueryParam("target"); targetStr != "" {
+		normalized, err := normalizeTarget(&targetStr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		target = normalized
+	}
+
 	// Get total count
-	total, err := h.scanRepo.Count(c.Request().Context(), imageID, imageName)
+	total, err := h.scanRepo.Count(c.Request().Context(), imageID, imageName, status, target)
 	if err != nil {
 		h.logger.Error("failed to count scans", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to count scans")
 	}
 
-	scans, err := h.scanRepo.List(c.Request().Context(), limit, offset, imageID, imageName, hasFix)
+	scans, err := h.scanRepo.List(c.Request().Context(), limit, offset, sort, imageID, imageName, status, target, hasFix)
 	if err != nil {
 		h.logger.Error("failed to list scans", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list scans")
 	}
 
-	response := map[string]interface{}{
-		"data":   scans,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
-	}
+	response := newPage(scans, total, limit, offset)
 
 	return c.JSON(http.StatusOK, response)
 }
@@ -462,8 +1251,7 @@ func (h *ScanHandler) GetScan(c echo.Context) error {
 
 	scan, err := h.scanRepo.GetWithDetails(c.Request().Context(), id, hasFix)
 	if err != nil {
-		h.logger.Error("failed to get scan", zap.Error(err))
-		return echo.NewHTTPError(http.StatusNotFound, "scan not found")
+		return err
 	}
 
 	// Get vulnerabilities for this scan
@@ -481,50 +1269,386 @@ func (h *ScanHandler) GetScan(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// GetScanSummary handles GET /api/v1/scans/:id/summary
+func (h *ScanHandler) GetScanSummary(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
+	if _, err := h.scanRepo.GetByID(c.Request().Context(), id); err != nil {
+		return err
+	}
+
+	summary, err := h.scanRepo.GetSummary(c.Request().Context(), id)
+	if err != nil {
+		h.logger.Error("failed to get scan summary", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get scan summary")
+	}
+
+	return c.JSON(http.StatusOK, summary)
+}
+
+// UpdateScanStatus handles PATCH /api/v1/scans/:id - scanners report progress and failures
+func (h *ScanHandler) UpdateScanStatus(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
+	var req models.ScanStatusUpdate
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	// Completed and partial require results, which are submitted with POST /scans
+	if req.Status != models.ScanStatusRunning && req.Status != models.ScanStatusFailed {
+		return echo.NewHTTPError(http.StatusBadRequest, "status must be running or failed")
+	}
+
+	ctx := c.Request().Context()
+	scan, err := h.scanRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if scan.IsFinished() {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("scan already %s", scan.Status))
+	}
+
+	if err := h.scanRepo.UpdateStatus(ctx, id, req.Status, req.FailureReason); err != nil {
+		h.logger.Error("failed to update scan status", zap.Error(err), zap.Int("scan_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update scan status")
+	}
+
+	if req.Status == models.ScanStatusFailed {
+		reason := ""
+		if req.FailureReason != nil {
+			reason = *req.FailureReason
+		}
+		h.logger.Warn("scanner reported failed scan",
+			zap.Int("scan_id", id),
+			zap.String("failure_reason", reason))
+	}
+
+	scan.Status = req.Status
+	scan.FailureReason = req.FailureReason
+	return c.JSON(http.StatusOK, scan)
+}
+
+// DeleteScan handles DELETE /api/v1/scans/:id - removes a scan with its vulnerability links, SBOM and archived
+// Grype result, and with purge_orphan_vulns=true the vulnerabilities no other scan found
+func (h *ScanHandler) DeleteScan(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+	purgeOrphanVulns, err := parsePurgeOrphanVulns(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	if _, err := h.scanRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	actor := getUserFromHeaders(c)
+	sbomScanIDs, purged, err := h.scanRepo.Delete(ctx, id, purgeOrphanVulns, actor)
+	if err != nil {
+		h.logger.Error("failed to delete scan", zap.Error(err), zap.Int("scan_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete scan")
+	}
+
+	// Documents are only removed once the deletion is committed, like for image deletion
+	if err := h.sbomRepo.DeleteDocuments(ctx, sbomScanIDs); err != nil {
+		h.logger.Warn("failed to delete SBOM document of deleted scan", zap.Error(err), zap.Int("scan_id", id))
+	}
+	if h.grypeRepo != nil {
+		if err := h.grypeRepo.DeleteDocuments(ctx, []int{id}); err != nil {
+			h.logger.Warn("failed to delete Grype result of deleted scan", zap.Error(err), zap.Int("scan_id", id))
+		}
+	}
+
+	h.logger.Info("scan deleted",
+		zap.Int("scan_id", id),
+		zap.Int("vulnerabilities_purged", purged),
+		zap.String("user", actor))
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 // GetSBOM handles GET /api/v1/scans/:id/sbom
+// The document is sent zstd or gzip encoded when Accept-Encoding allows it
 func (h *ScanHandler) GetSBOM(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
 	}
 
-	document, err := h.sbomRepo.GetDocumentByScanID(c.Request().Context(), id)
+	body, encoding, err := h.sbomRepo.OpenDocumentByScanID(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// SBOMs are several MB: compressed for clients that accept it, passed through when stored compressed
+	return streamDocument(c, h.logger, body, encoding)
+}
+
+// Bounds of the expires parameter of GET /api/v1/scans/:id/sbom/url, in seconds. S3 signatures
+// are valid for at most a week
+const (
+	defaultSBOMURLExpiry = 3600
+	minSBOMURLExpiry     = 60
+	maxSBOMURLExpiry     = 7 * 24 * 3600
+)
+
+// SBOMURLResponse is a pre-signed URL downloading the SBOM of a scan from S3
+type SBOMURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GetSBOMURL handles GET /api/v1/scans/:id/sbom/url?expires=3600
+// It returns a pre-signed URL so large SBOMs are downloaded from S3 rather than through the API.
+// The document is served as stored, with a Content-Encoding of zstd when stored compressed
+func (h *ScanHandler) GetSBOMURL(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+	expires := defaultSBOMURLExpiry
+	if s := c.QueryParam("expires"); s != "" {
+		expires, err = strconv.Atoi(s)
+		if err != nil || expires < minSBOMURLExpiry || expires > maxSBOMURLExpiry {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid expires, expected %d to %d seconds", minSBOMURLExpiry, maxSBOMURLExpiry))
+		}
+	}
+
+	expiresIn := time.Duration(expires) * time.Second
+	expiresAt := time.Now().UTC().Add(expiresIn).Truncate(time.Second)
+	url, err := h.sbomRepo.GetPresignedURL(c.Request().Context(), id, expiresIn)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, SBOMURLResponse{URL: url, ExpiresAt: expiresAt})
+}
+
+// GetGrypeResult handles GET /api/v1/scans/:id/grype-result
+// It returns the Grype JSON exactly as the scanner submitted it
+func (h *ScanHandler) GetGrypeResult(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
+	document, err := h.grypeRepo.GetDocumentByScanID(c.Request().Context(), id)
 	if err != nil {
-		h.logger.Error("failed to get SBOM", zap.Error(err))
-		return echo.NewHTTPError(http.StatusNotFound, "SBOM not found")
+		return err
 	}
 
 	return c.JSONBlob(http.StatusOK, document)
 }
 
-// GetScanDiff handles GET /api/v1/scans/:id/diff?previous_scan_id=<id>
+// GetScanDiff handles GET /api/v1/scans/:id/diff?previous_scan_id=<id>|previous_image=<name>
+// It is read-only: vulnerabilities missing from the scan are listed as fixed, POST apply-diff marks them.
+// The previous scan may be of another image, to evaluate upgrading from one tag to another
 func (h *ScanHandler) GetScanDiff(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
 	}
 
+	ctx := c.Request().Context()
+	scan, err := h.scanRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	previous, err := h.findScanToCompare(c, scan)
+	if err != nil {
+		return err
+	}
+	var previousScanID *int
+	if previous != nil {
+		previousScanID = &previous.ID
+	}
+
+	diff, err := h.analyzer.Diff(ctx, id, previousScanID)
+	if err != nil {
+		h.logger.Error("failed to compare scan", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to compare scan")
+	}
+
+	return c.JSON(http.StatusOK, diff)
+}
+
+// ApplyScanDiff handles POST /api/v1/scans/:id/apply-diff?previous_scan_id=<id>
+// It marks the vulnerabilities the scan proves absent as fixed, as ingesting the scan does
+func (h *ScanHandler) ApplyScanDiff(c echo.Context) error {
+	id, previousScanID, err := parseScanDiffParams(c)
+	if err != nil {
+		return err
+	}
+
+	diff, err := h.analyzer.CompareScanWith(c.Request().Context(), id, previousScanID)
+	if err != nil {
+		h.logger.Error("failed to apply scan diff", zap.Error(err), zap.Int("scan_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to apply scan diff")
+	}
+
+	return c.JSON(http.StatusOK, diff)
+}
+
+// parseScanDiffParams reads the scan ID and the optional previous_scan_id of a diff request
+func parseScanDiffParams(c echo.Context) (int, *int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, nil, echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
 	// Optional previous_scan_id query parameter
 	var previousScanID *int
 	if prevIDStr := c.QueryParam("previous_scan_id"); prevIDStr != "" {
 		prevID, err := strconv.Atoi(prevIDStr)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid previous_scan_id")
+			return 0, nil, echo.NewHTTPError(http.StatusBadRequest, "invalid previous_scan_id")
 		}
 		previousScanID = &prevID
 	}
+	return id, previousScanID, nil
+}
 
-	diff, err := h.analyzer.CompareScanWith(c.Request().Context(), id, previousScanID)
+// findScanToCompare returns the scan a diff request compares with: previous_scan_id, or the latest
+// scan with results of the image previous_image and the target of scan. Nil when neither is set
+func (h *ScanHandler) findScanToCompare(c echo.Context, scan *models.Scan) (*models.Scan, error) {
+	ctx := c.Request().Context()
+	prevIDStr, imageName := c.QueryParam("previous_scan_id"), c.QueryParam("previous_image")
+	switch {
+	case prevIDStr != "" && imageName != "":
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "previous_scan_id and previous_image are mutually exclusive")
+
+	case prevIDStr != "":
+		prevID, err := strconv.Atoi(prevIDStr)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid previous_scan_id")
+		}
+		previous, err := h.scanRepo.GetByID(ctx, prevID)
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "previous scan not found")
+		} else if err != nil {
+			return nil, err
+		}
+		return previous, nil
+
+	case imageName != "":
+		registry, repository, tag := parseImageName(imageName)
+		image, err := h.imageRepo.GetByName(ctx, registry, repository, tag)
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "previous image not found")
+		} else if err != nil {
+			return nil, err
+		}
+		// Scans of other targets cover other parts of the image
+		previous, err := h.scanRepo.GetPreviousScan(ctx, image.ID, scan.Target, time.Now())
+		if err != nil {
+			h.logger.Error("failed to get latest scan", zap.Error(err), zap.Int("image_id", image.ID))
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get latest scan of previous image")
+		}
+		if previous == nil {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "previous image has no scan with results")
+		}
+		return previous, nil
+	}
+	return nil, nil
+}
+
+// GetSBOMDiff handles GET /api/v1/scans/:id/sbom-diff?previous_scan_id=<id>|previous_image=<name>
+// It compares the packages of the two SBOMs, independent of vulnerabilities
+func (h *ScanHandler) GetSBOMDiff(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.Error("failed to compare scan", zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to compare scan")
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
+	ctx := c.Request().Context()
+	scan, err := h.scanRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Defaults to the previous scan of the same image and target, like GetScanDiff
+	previous, err := h.findScanToCompare(c, scan)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		previous, err = h.scanRepo.GetPreviousScan(ctx, scan.ImageID, scan.Target, scan.ScanDate)
+		if err != nil {
+			h.logger.Error("failed to get previous scan", zap.Error(err), zap.Int("scan_id", id))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to compare SBOMs")
+		}
 	}
 
+	current, err := h.sbomComponents(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Without a previous scan, every package is new
+	previousComponents := []models.SBOMComponent{}
+	previousID := 0
+	if previous != nil {
+		previousID = previous.ID
+		if previousComponents, err = h.sbomComponents(ctx, previous.ID); err != nil {
+			return err
+		}
+	}
+
+	diff := sbom.Compare(previousComponents, current)
+	diff.ScanID = id
+	diff.PreviousScanID = previousID
 	return c.JSON(http.StatusOK, diff)
 }
 
+// sbomComponents loads and parses the SBOM of a scan, returning an HTTP error on failure
+func (h *ScanHandler) sbomComponents(ctx context.Context, scanID int) ([]models.SBOMComponent, error) {
+	document, err := h.sbomRepo.GetDocumentByScanID(ctx, scanID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("SBOM of scan %d not found", scanID))
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get SBOM of scan %d: %w", scanID, err)
+	}
+
+	components, err := sbom.ParseComponents(document)
+	if err != nil {
+		h.logger.Error("failed to parse SBOM", zap.Error(err), zap.Int("scan_id", scanID))
+		return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("SBOM of scan %d could not be parsed", scanID))
+	}
+	return components, nil
+}
+
 // Helper functions
 
+// maxTargetLength matches the scans.target column
+const maxTargetLength = 1024
+
+// normalizeTarget cleans a scan target so /srv/api/ and /srv//api match the same scans.
+// An empty target means the whole image
+func normalizeTarget(target *string) (*string, error) {
+	if target == nil || strings.TrimSpace(*target) == "" {
+		return nil, nil
+	}
+	cleaned := strings.TrimSpace(*target)
+	if !strings.HasPrefix(cleaned, "/") {
+		return nil, fmt.Errorf("target must be an absolute path inside the image")
+	}
+	cleaned = path.Clean(cleaned)
+	if len(cleaned) > maxTargetLength {
+		return nil, fmt.Errorf("target must be at most %d characters", maxTargetLength)
+	}
+	return &cleaned, nil
+}
+
 func parseImageName(fullName string) (registry, repository, tag string) {
 	// Default tag
 	tag = "latest"
@@ -573,6 +1697,8 @@ func normalizeSeverity(severity string) string {
 		return "Medium"
 	case "LOW":
 		return "Low"
+	case "NEGLIGIBLE":
+		return "Negligible"
 	default:
 		return "Unknown"
 	}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/analyzer"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/notifier"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Baseline for ingesting the mixed Grype fixture into an image that already has history
+const baselineCreateScan = 250 * time.Millisecond
+
+// memorySBOMStorage keeps SBOM documents in memory so benchmarks don't need S3
+type memorySBOMStorage struct {
+	mu   sync.Mutex
+	docs map[int][]byte
+}
+
+func (s *memorySBOMStorage) Store(ctx context.Context, scanID int, document []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[scanID] = document
+	return nil
+}
+
+func (s *memorySBOMStorage) Retrieve(ctx context.Context, scanID int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.docs[scanID], nil
+}
+
+func (s *memorySBOMStorage) Delete(ctx context.Context, scanID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.docs, scanID)
+	return nil
+}
+
+func (s *memorySBOMStorage) GetPresignedURL(ctx context.Context, scanID int, expiresIn time.Duration) (string, error) {
+	return fmt.Sprintf("https://s3.test/scans/%d/sbom.json?X-Amz-Expires=%d", scanID, int(expiresIn.Seconds())), nil
+}
+
+func (s *memorySBOMStorage) Exists(ctx context.Context, scanID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.docs[scanID]
+	return ok, nil
+}
+
+func BenchmarkCreateScan(b *testing.B) {
+	database := db.SetupTestDatabase(b)
+	logger := zap.NewNop()
+
+	imageRepo := db.NewImageRepository(database)
+	scanRepo := db.NewScanRepository(database)
+	vulnRepo := db.NewVulnerabilityRepository(database)
+	sbomRepo := db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)})
+	handler := NewScanHandler(logger, imageRepo, scanRepo, vulnRepo, sbomRepo, nil, db.NewSuppressionRuleRepository(database), nil, nil, nil,
+		analyzer.New(scanRepo, vulnRepo), notifier.New(logger, ""))
+
+	body, err := json.Marshal(ScanRequest{
+		Image:       "docker.io/library/nginx:bench",
+		GrypeResult: loadGrypeFixture(b, "grype-output-mixed.json"),
+		SBOM:        json.RawMessage(`{"bomFormat":"CycloneDX","specVersion":"1.5","components":[]}`),
+		SBOMFormat:  "cyclonedx",
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	e := echo.New()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/scans", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		if err := handler.CreateScan(e.NewContext(req, rec)); err != nil {
+			b.Fatal(err)
+		}
+		if rec.Code != http.StatusCreated {
+			b.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	db.CheckBaseline(b, baselineCreateScan)
+}
@@ -12,7 +12,7 @@ import (
 )
 
 // loadGrypeFixture loads a Grype output fixture from testdata
-func loadGrypeFixture(t *testing.T, filename string) models.GrypeResult {
+func loadGrypeFixture(t testing.TB, filename string) models.GrypeResult {
 	t.Helper()
 
 	fixturePath := filepath.Join("..", "testdata", filename)
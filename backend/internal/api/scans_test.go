@@ -2,17 +2,48 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/invulnerable/backend/internal/analyzer"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/ingest"
 	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
+func TestNormalizeTarget(t *testing.T) {
+	target, err := normalizeTarget(nil)
+	require.NoError(t, err)
+	assert.Nil(t, target)
+
+	blank := "  "
+	target, err = normalizeTarget(&blank)
+	require.NoError(t, err)
+	assert.Nil(t, target)
+
+	messy := " /srv//api/ "
+	target, err = normalizeTarget(&messy)
+	require.NoError(t, err)
+	require.NotNil(t, target)
+	assert.Equal(t, "/srv/api", *target)
+
+	relative := "srv/api"
+	_, err = normalizeTarget(&relative)
+	assert.Error(t, err)
+}
+
 func TestParseImageName(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -81,6 +112,7 @@ func TestNormalizeSeverity(t *testing.T) {
 		{"medium", "Medium"},
 		{"LOW", "Low"},
 		{"low", "Low"},
+		{"Negligible", "Negligible"},
 		{"unknown", "Unknown"},
 		{"", "Unknown"},
 	}
@@ -158,3 +190,630 @@ func TestScanHandler_CreateScan_ValidRequest(t *testing.T) {
 // 4. Verify scan summary is correct
 //
 // Currently blocked by need to mock: S3 storage, analyzer, notifier
+
+func newTestScanHandler(t *testing.T) *ScanHandler {
+	t.Helper()
+
+	handler, _ := newTestScanHandlerWithDatabase(t)
+	return handler
+}
+
+func newTestScanHandlerWithDatabase(t *testing.T) (*ScanHandler, *db.Database) {
+	t.Helper()
+
+	database := db.SetupTestDatabase(t)
+	logger := zap.NewNop()
+	scanRepo := db.NewScanRepository(database)
+	vulnRepo := db.NewVulnerabilityRepository(database)
+	return NewScanHandler(logger, db.NewImageRepository(database), scanRepo, vulnRepo,
+		db.NewSBOMRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}),
+		db.NewGrypeResultRepository(database, &memorySBOMStorage{docs: make(map[int][]byte)}),
+		db.NewSuppressionRuleRepository(database), db.NewWatchlistRepository(database), db.NewUsageRepository(database), db.NewOutboxRepository(database),
+		analyzer.New(scanRepo, vulnRepo), notifier.New(logger, "")), database
+}
+
+func doScanRequest(t *testing.T, handlerFn echo.HandlerFunc, method, path string, body interface{}, id string) (*httptest.ResponseRecorder, error) {
+	t.Helper()
+
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if id != "" {
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+	}
+	// Repository errors get their status as from the error handler
+	return rec, httpError(handlerFn(c))
+}
+
+func TestScanHandler_Lifecycle_RunningThenFailed(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans",
+		map[string]interface{}{"image": "nginx:1.25", "status": "running"}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var scan models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+	assert.Equal(t, models.ScanStatusRunning, scan.Status)
+
+	rec, err = doScanRequest(t, handler.UpdateScanStatus, http.MethodPatch, "/api/v1/scans/:id",
+		map[string]interface{}{"status": "failed", "failure_reason": "syft exited with code 1"}, strconv.Itoa(scan.ID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Failed scans are listed with the status filter
+	rec, err = doScanRequest(t, handler.ListScans, http.MethodGet, "/api/v1/scans?status=failed", nil, "")
+	require.NoError(t, err)
+	var list struct {
+		Items []models.ScanWithDetails `json:"items"`
+		Total int                      `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Equal(t, 1, list.Total)
+	require.NotNil(t, list.Items[0].FailureReason)
+	assert.Equal(t, "syft exited with code 1", *list.Items[0].FailureReason)
+
+	// A finished scan can't change status again
+	_, err = doScanRequest(t, handler.UpdateScanStatus, http.MethodPatch, "/api/v1/scans/:id",
+		map[string]interface{}{"status": "running"}, strconv.Itoa(scan.ID))
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusConflict, httpErr.Code)
+}
+
+func TestScanHandler_Lifecycle_RunningThenCompleted(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans",
+		map[string]interface{}{"image": "nginx:1.25", "status": "running"}, "")
+	require.NoError(t, err)
+	var registered models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &registered))
+
+	rec, err = doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+		Image:       "nginx:1.25",
+		ScanID:      &registered.ID,
+		GrypeResult: loadGrypeFixture(t, "grype-output-mixed.json"),
+		SBOM:        json.RawMessage(`{}`),
+		SBOMFormat:  "cyclonedx",
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var completed models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &completed))
+	assert.Equal(t, registered.ID, completed.ID, "results attach to the registered scan")
+	assert.Equal(t, models.ScanStatusCompleted, completed.Status)
+
+	// Submitting results twice is rejected
+	_, err = doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+		Image:  "nginx:1.25",
+		ScanID: &registered.ID,
+	}, "")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusConflict, httpErr.Code)
+}
+
+func TestScanHandler_GetGrypeResult(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	// Fields the backend doesn't model are archived too
+	grypeResult := json.RawMessage(`{"matches":[],"ignoredMatches":[{"vulnerability":{"id":"CVE-2023-0001"}}],"descriptor":{"name":"grype","version":"0.74.0"}}`)
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", map[string]interface{}{
+		"image":        "nginx:1.25",
+		"grype_result": grypeResult,
+		"sbom":         json.RawMessage(`{}`),
+		"sbom_format":  "cyclonedx",
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var scan models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+
+	rec, err = doScanRequest(t, handler.GetGrypeResult, http.MethodGet, "/api/v1/scans/:id/grype-result", nil, strconv.Itoa(scan.ID))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(grypeResult), rec.Body.String())
+
+	_, err = doScanRequest(t, handler.GetGrypeResult, http.MethodGet, "/api/v1/scans/:id/grype-result", nil, strconv.Itoa(scan.ID+1))
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestScanHandler_GetSBOMDiff(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	createScan := func(sbom string) int {
+		rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", map[string]interface{}{
+			"image":        "nginx:1.25",
+			"grype_result": json.RawMessage(`{"matches":[]}`),
+			"sbom":         json.RawMessage(sbom),
+			"sbom_format":  "cyclonedx",
+		}, "")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var scan models.Scan
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+		return scan.ID
+	}
+
+	firstID := createScan(`{"bomFormat":"CycloneDX","components":[
+		{"name":"openssl","version":"3.0.11-1","purl":"pkg:deb/debian/openssl@3.0.11-1"},
+		{"name":"curl","version":"7.88.1","purl":"pkg:deb/debian/curl@7.88.1"}]}`)
+	secondID := createScan(`{"bomFormat":"CycloneDX","components":[
+		{"name":"openssl","version":"3.0.13-1","purl":"pkg:deb/debian/openssl@3.0.13-1"},
+		{"name":"zlib1g","version":"1.2.13","purl":"pkg:deb/debian/zlib1g@1.2.13"}]}`)
+
+	rec, err := doScanRequest(t, handler.GetSBOMDiff, http.MethodGet, "/api/v1/scans/:id/sbom-diff", nil, strconv.Itoa(secondID))
+	require.NoError(t, err)
+
+	var diff models.SBOMDiff
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &diff))
+	assert.Equal(t, firstID, diff.PreviousScanID)
+	require.Len(t, diff.Upgraded, 1)
+	assert.Equal(t, "3.0.11-1", diff.Upgraded[0].PreviousVersion)
+	assert.Equal(t, "3.0.13-1", diff.Upgraded[0].Version)
+	require.Len(t, diff.Added, 1)
+	assert.Equal(t, "zlib1g", diff.Added[0].Name)
+	require.Len(t, diff.Removed, 1)
+	assert.Equal(t, "curl", diff.Removed[0].Name)
+
+	// The first scan has nothing to compare with
+	rec, err = doScanRequest(t, handler.GetSBOMDiff, http.MethodGet, "/api/v1/scans/:id/sbom-diff", nil, strconv.Itoa(firstID))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &diff))
+	assert.Equal(t, 0, diff.PreviousScanID)
+	assert.Equal(t, 2, diff.Summary.AddedCount)
+}
+
+func TestScanHandler_GetSBOMQuality(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	// Syft found one package without a purl in a 500 MB image
+	size := int64(500 << 20)
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+		Image:          "nginx:1.25",
+		GrypeResult:    models.GrypeResult{Matches: []models.GrypeMatch{}},
+		SBOM:           json.RawMessage(`{"bomFormat":"CycloneDX","components":[{"name":"app","version":"1.0"}]}`),
+		SBOMFormat:     "cyclonedx",
+		ImageSizeBytes: &size,
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var scan models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+
+	rec, err = doScanRequest(t, handler.GetSBOMQuality, http.MethodGet, "/api/v1/scans/:id/sbom-quality", nil, strconv.Itoa(scan.ID))
+	require.NoError(t, err)
+	var quality models.SBOMQuality
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &quality))
+	assert.True(t, quality.LowQuality)
+	assert.Contains(t, quality.Flags, models.SBOMQualitySparse)
+	assert.Contains(t, quality.Flags, models.SBOMQualityMissingPURLs)
+
+	// The latest SBOM of the image is listed as low quality
+	list := NewSBOMQualityHandler(zap.NewNop(), handler.sbomRepo)
+	rec, err = doScanRequest(t, list.ListSBOMQuality, http.MethodGet, "/api/v1/sbom-quality?low_quality=true", nil, "")
+	require.NoError(t, err)
+	var entries []models.SBOMQualityEntry
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, scan.ID, entries[0].ScanID)
+	assert.Equal(t, quality, entries[0].Quality)
+
+	_, err = doScanRequest(t, handler.GetSBOMQuality, http.MethodGet, "/api/v1/scans/:id/sbom-quality", nil, "999999")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestScanHandler_GetSBOMURL(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+		Image:       "nginx:1.25",
+		GrypeResult: models.GrypeResult{Matches: []models.GrypeMatch{}},
+		SBOM:        json.RawMessage(`{"bomFormat":"CycloneDX","components":[]}`),
+		SBOMFormat:  "cyclonedx",
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var scan models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+
+	before := time.Now().UTC()
+	rec, err = doScanRequest(t, handler.GetSBOMURL, http.MethodGet, "/api/v1/scans/:id/sbom/url?expires=600", nil, strconv.Itoa(scan.ID))
+	require.NoError(t, err)
+	var response SBOMURLResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, fmt.Sprintf("https://s3.test/scans/%d/sbom.json?X-Amz-Expires=600", scan.ID), response.URL)
+	assert.WithinDuration(t, before.Add(10*time.Minute), response.ExpiresAt, 2*time.Second)
+
+	// An hour by default
+	rec, err = doScanRequest(t, handler.GetSBOMURL, http.MethodGet, "/api/v1/scans/:id/sbom/url", nil, strconv.Itoa(scan.ID))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Contains(t, response.URL, "X-Amz-Expires=3600")
+
+	_, err = doScanRequest(t, handler.GetSBOMURL, http.MethodGet, "/api/v1/scans/:id/sbom/url", nil, "999999")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestScanHandler_GetSBOMURL_ExpiresValidation(t *testing.T) {
+	handler := NewScanHandler(zap.NewNop(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	for _, expires := range []string{"soon", "0", "59", "604801"} {
+		t.Run(expires, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.GetSBOMURL, http.MethodGet, "/api/v1/scans/:id/sbom/url?expires="+expires, nil, "1")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		})
+	}
+}
+
+func TestScanHandler_GetScanDiff_PreviousImage(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	createScan := func(image, cve string) int {
+		rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", map[string]interface{}{
+			"image": image,
+			"grype_result": models.GrypeResult{Matches: []models.GrypeMatch{{
+				Vulnerability: models.GrypeVulnerability{ID: cve, Severity: "High"},
+				Artifact:      models.GrypeArtifact{Name: "openssl", Version: "3.0.11-1", Type: "deb"},
+			}}},
+			"sbom":        json.RawMessage(`{}`),
+			"sbom_format": "cyclonedx",
+		}, "")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var scan models.Scan
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &scan))
+		return scan.ID
+	}
+	currentID := createScan("nginx:1.25", "CVE-2023-0001")
+	candidateID := createScan("nginx:1.26", "CVE-2023-0002")
+
+	rec, err := doScanRequest(t, handler.GetScanDiff, http.MethodGet, "/api/v1/scans/:id/diff?previous_image=nginx:1.25", nil, strconv.Itoa(candidateID))
+	require.NoError(t, err)
+	var diff models.ScanDiff
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &diff))
+	assert.Equal(t, currentID, diff.PreviousScanID)
+	assert.True(t, diff.CrossImage)
+	require.Len(t, diff.NewVulns, 1)
+	assert.Equal(t, "CVE-2023-0002", diff.NewVulns[0].CVEID)
+	require.Len(t, diff.FixedVulns, 1)
+	assert.Equal(t, "CVE-2023-0001", diff.FixedVulns[0].CVEID)
+
+	_, err = doScanRequest(t, handler.GetScanDiff, http.MethodGet, "/api/v1/scans/:id/diff?previous_image=nginx:1.24", nil, strconv.Itoa(candidateID))
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestScanHandler_UpdateScanStatus_RejectsCompleted(t *testing.T) {
+	handler := NewScanHandler(zap.NewNop(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	_, err := doScanRequest(t, handler.UpdateScanStatus, http.MethodPatch, "/api/v1/scans/:id",
+		map[string]interface{}{"status": "completed"}, "1")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestScanHandler_CreateScan_AppliesSuppressionRules(t *testing.T) {
+	handler := newTestScanHandler(t)
+	ctx := context.Background()
+
+	cveID, reason := "CVE-2024-1234", "not reachable"
+	require.NoError(t, handler.ruleRepo.Upsert(ctx, &models.SuppressionRule{
+		CVEID:  &cveID,
+		Reason: &reason,
+		Source: models.SuppressionSourceManual,
+	}))
+	// Expired waivers no longer apply
+	expiredCVE := "CVE-2024-5678"
+	expired := time.Now().Add(-time.Hour)
+	require.NoError(t, handler.ruleRepo.Upsert(ctx, &models.SuppressionRule{
+		CVEID:     &expiredCVE,
+		ExpiresAt: &expired,
+		Source:    models.SuppressionSourceManual,
+	}))
+
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+		Image:       "nginx:1.25",
+		GrypeResult: loadGrypeFixture(t, "grype-output-mixed.json"),
+		SBOM:        json.RawMessage(`{}`),
+		SBOMFormat:  "cyclonedx",
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	suppressed, err := handler.vulnRepo.GetByUniqueKey(ctx, "CVE-2024-1234", "openssl", "1.1.1n-0+deb11u3")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusAccepted, suppressed.Status)
+	require.NotNil(t, suppressed.Notes)
+	assert.Contains(t, *suppressed.Notes, "not reachable")
+
+	notSuppressed, err := handler.vulnRepo.GetByUniqueKey(ctx, "CVE-2024-5678", "curl", "7.74.0-1.3+deb11u7")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, notSuppressed.Status)
+}
+
+func TestScanHandler_CreateScan_TruncatedResultsArePartial(t *testing.T) {
+	handler := newTestScanHandler(t)
+	handler.SetIngestLimits(IngestLimits{MaxMatches: 2})
+
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+		Image:       "nginx:1.25",
+		GrypeResult: loadGrypeFixture(t, "grype-output-mixed.json"),
+		SBOM:        json.RawMessage(`{}`),
+		SBOMFormat:  "cyclonedx",
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var created models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, models.ScanStatusPartial, created.Status)
+	assert.Equal(t, 2, created.MatchesDropped)
+
+	stored, err := handler.scanRepo.GetByID(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.MatchesReceived)
+	assert.Equal(t, 4, *stored.MatchesReceived)
+	assert.Equal(t, 2, stored.MatchesDropped)
+	require.NotNil(t, stored.FailureReason)
+	assert.Contains(t, *stored.FailureReason, "more than 2 matches per scan")
+
+	vulns, err := handler.scanRepo.GetVulnerabilities(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.Len(t, vulns, 2)
+	for _, v := range vulns {
+		assert.Contains(t, []string{"Critical", "High"}, v.Severity)
+	}
+}
+
+func TestScanHandler_CreateScan_ProcessedInBackground(t *testing.T) {
+	handler, database := newTestScanHandlerWithDatabase(t)
+	jobs := db.NewIngestJobRepository(database)
+	// Not started, the queued job is run below
+	handler.SetIngestJobs(ingest.New(zap.NewNop(), jobs, handler.ProcessIngestJob, 1))
+
+	scanUUID := "0e7d4c1a-8f2b-4b3e-9c5d-2a6f7e8b9c10"
+	submission := ScanRequest{
+		Image:       "nginx:1.25",
+		GrypeResult: loadGrypeFixture(t, "grype-output-mixed.json"),
+		SBOM:        json.RawMessage(`{}`),
+		SBOMFormat:  "cyclonedx",
+		ScanUUID:    &scanUUID,
+	}
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", submission, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	var accepted ScanAccepted
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &accepted))
+	assert.Equal(t, models.ScanStatusRunning, accepted.Status)
+	require.NotNil(t, accepted.IngestJob)
+	assert.Equal(t, 4, accepted.IngestJob.MatchCount)
+	assert.Equal(t, "/api/v1/ingest-jobs/"+strconv.FormatInt(accepted.IngestJob.ID, 10), rec.Header().Get(echo.HeaderLocation))
+
+	// Retried before its results are processed, the submission returns the job processing them
+	rec, err = doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", submission, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var retried ScanAccepted
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &retried))
+	assert.Equal(t, accepted.ID, retried.ID)
+	require.NotNil(t, retried.IngestJob)
+	assert.Equal(t, accepted.IngestJob.ID, retried.IngestJob.ID)
+
+	ctx := context.Background()
+	vulns, err := handler.scanRepo.GetVulnerabilities(ctx, accepted.ID)
+	require.NoError(t, err)
+	assert.Empty(t, vulns)
+
+	job, err := jobs.Claim(ctx, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	require.NoError(t, handler.ProcessIngestJob(ctx, job))
+
+	stored, err := handler.scanRepo.GetByID(ctx, accepted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScanStatusCompleted, stored.Status)
+	vulns, err = handler.scanRepo.GetVulnerabilities(ctx, accepted.ID)
+	require.NoError(t, err)
+	assert.Len(t, vulns, 4)
+
+	// Run again after its lease expired, the processed scan is left as it is
+	require.NoError(t, handler.ProcessIngestJob(ctx, job))
+}
+
+func TestScanHandler_CreateScan_ScanUUID(t *testing.T) {
+	handler := newTestScanHandler(t)
+	scanUUID := "5f0c9a52-3b7e-4d6a-9a41-6f2f1f8b2c11"
+	submission := func(image, id string) ScanRequest {
+		return ScanRequest{
+			Image:       image,
+			GrypeResult: loadGrypeFixture(t, "grype-output-mixed.json"),
+			SBOM:        json.RawMessage(`{}`),
+			SBOMFormat:  "cyclonedx",
+			ScanUUID:    &id,
+		}
+	}
+
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", submission("nginx:1.25", scanUUID), "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.NotNil(t, created.ScanUUID)
+	assert.Equal(t, scanUUID, *created.ScanUUID)
+
+	// A retried submission, however the scan_uuid is written, returns the stored scan
+	rec, err = doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", submission("nginx:1.25", strings.ToUpper(scanUUID)), "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var retried models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &retried))
+	assert.Equal(t, created.ID, retried.ID)
+
+	vulns, err := handler.scanRepo.GetVulnerabilities(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Len(t, vulns, 4)
+
+	// The scan_uuid of a scan of another image is refused
+	_, err = doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", submission("redis:7", scanUUID), "")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusConflict, httpErr.Code)
+}
+
+func TestScanHandler_CreateScan_ScanUUIDValidation(t *testing.T) {
+	handler := NewScanHandler(zap.NewNop(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	invalid, valid, scanID := "not-a-uuid", "5f0c9a52-3b7e-4d6a-9a41-6f2f1f8b2c11", 42
+
+	tests := []struct {
+		name string
+		req  ScanRequest
+	}{
+		{"invalid", ScanRequest{Image: "nginx", ScanUUID: &invalid}},
+		{"with scan_id", ScanRequest{Image: "nginx", ScanID: &scanID, ScanUUID: &valid}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", tt.req, "")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		})
+	}
+}
+
+func TestScanHandler_CreateScan_RecordsScanPerformance(t *testing.T) {
+	handler := newTestScanHandler(t)
+
+	// The size and layers come from the Grype result of the image when the scanner doesn't report them
+	duration := int64(42000)
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+		Image:          "nginx:1.25",
+		GrypeResult:    loadGrypeFixture(t, "grype-output-mixed.json"),
+		SBOM:           json.RawMessage(`{}`),
+		SBOMFormat:     "cyclonedx",
+		ScanDurationMS: &duration,
+	}, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var created models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	stored, err := handler.scanRepo.GetByID(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.ScanDurationMS)
+	require.NotNil(t, stored.ImageSizeBytes)
+	require.NotNil(t, stored.LayerCount)
+	assert.Equal(t, duration, *stored.ScanDurationMS)
+	assert.Positive(t, *stored.ImageSizeBytes)
+	assert.Positive(t, *stored.LayerCount)
+
+	negative := int64(-1)
+	_, err = doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+		Image: "nginx:1.25", Status: models.ScanStatusRunning, ImageSizeBytes: &negative,
+	}, "")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestScanHandler_CreateScan_UnchangedValidation(t *testing.T) {
+	handler := NewScanHandler(zap.NewNop(), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	digest, syft := "sha256:abc", "1.40.0"
+	unchanged := &UnchangedResults{ResultsFingerprint: "f00d", GrypeVersion: "0.104.4", GrypeDBBuilt: time.Now()}
+
+	tests := []struct {
+		name string
+		req  ScanRequest
+	}{
+		{"partial", ScanRequest{Image: "nginx", ImageDigest: &digest, SyftVersion: &syft, Status: models.ScanStatusPartial, Unchanged: unchanged}},
+		{"without digest", ScanRequest{Image: "nginx", SyftVersion: &syft, Unchanged: unchanged}},
+		{"without fingerprint", ScanRequest{Image: "nginx", ImageDigest: &digest, SyftVersion: &syft, Unchanged: &UnchangedResults{GrypeDBBuilt: time.Now()}}},
+		{"with documents", ScanRequest{Image: "nginx", ImageDigest: &digest, SyftVersion: &syft, SBOM: json.RawMessage(`{}`), Unchanged: unchanged}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", tt.req, "")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		})
+	}
+}
+
+func TestScanHandler_CreateScan_Unchanged(t *testing.T) {
+	handler := newTestScanHandler(t)
+	ctx := context.Background()
+
+	digest, syft := "sha256:abc123", "1.40.0"
+	built := "2024-05-01T04:00:00Z"
+	grypeResult := loadGrypeFixture(t, "grype-output-mixed.json")
+	grypeResult.Descriptor.DB = &models.GrypeDBDescriptor{Built: built}
+	sbom := json.RawMessage(`{"bomFormat":"CycloneDX","components":[{"name":"openssl","version":"1.1.1","purl":"pkg:deb/debian/openssl@1.1.1"}]}`)
+
+	rec, err := doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+		Image: "nginx:1.25", ImageDigest: &digest, SyftVersion: &syft,
+		GrypeResult: grypeResult, SBOM: sbom, SBOMFormat: "cyclonedx",
+	}, "")
+	require.NoError(t, err)
+	var full models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &full))
+
+	builtAt, err := time.Parse(time.RFC3339, built)
+	require.NoError(t, err)
+	delta := func(fingerprint string) (*httptest.ResponseRecorder, error) {
+		return doScanRequest(t, handler.CreateScan, http.MethodPost, "/api/v1/scans", ScanRequest{
+			Image: "nginx:1.25", ImageDigest: &digest, SyftVersion: &syft, SBOMFormat: "cyclonedx",
+			Unchanged: &UnchangedResults{ResultsFingerprint: fingerprint, GrypeVersion: "0.104.4", GrypeDBBuilt: builtAt},
+		}, "")
+	}
+
+	// Other results: the scanner has to send them
+	_, err = delta("beef")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusPreconditionFailed, httpErr.Code)
+
+	rec, err = delta(grypeResult.Fingerprint())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var recorded models.Scan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recorded))
+	assert.NotEqual(t, full.ID, recorded.ID)
+	require.NotNil(t, recorded.CachedFromScanID)
+	assert.Equal(t, full.ID, *recorded.CachedFromScanID)
+
+	fullVulns, err := handler.scanRepo.GetVulnerabilities(ctx, full.ID)
+	require.NoError(t, err)
+	vulns, err := handler.scanRepo.GetVulnerabilities(ctx, recorded.ID)
+	require.NoError(t, err)
+	assert.Len(t, vulns, len(fullVulns))
+
+	// The SBOM document is shared, not stored again
+	shared, err := handler.sbomRepo.GetByScanID(ctx, recorded.ID)
+	require.NoError(t, err)
+	assert.Equal(t, full.ID, shared.StorageScanID())
+	document, err := handler.sbomRepo.GetDocumentByScanID(ctx, recorded.ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(sbom), string(document))
+}
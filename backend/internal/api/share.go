@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultShareTTL = 72 * time.Hour
+	maxShareTTL     = 30 * 24 * time.Hour
+)
+
+// ShareHandler mints share links for scan reports and serves them to link holders.
+// The shared endpoint is the only one reachable without OAuth, the ingress exempts its path
+type ShareHandler struct {
+	logger      *zap.Logger
+	signer      *auth.ShareSigner
+	scanRepo    *db.ScanRepository
+	frontendURL string
+}
+
+// NewShareHandler creates a share handler. A nil signer disables share links
+func NewShareHandler(logger *zap.Logger, signer *auth.ShareSigner, scanRepo *db.ScanRepository, frontendURL string) *ShareHandler {
+	return &ShareHandler{
+		logger:      logger,
+		signer:      signer,
+		scanRepo:    scanRepo,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// CreateShareLink handles GET /api/v1/scans/:id/share?ttl_hours=72
+func (h *ShareHandler) CreateShareLink(c echo.Context) error {
+	if h.signer == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "share links are disabled, set SHARE_LINK_SECRET to enable them")
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid scan ID")
+	}
+
+	ttl := defaultShareTTL
+	if ttlStr := c.QueryParam("ttl_hours"); ttlStr != "" {
+		hours, err := strconv.Atoi(ttlStr)
+		if err != nil || hours <= 0 || time.Duration(hours)*time.Hour > maxShareTTL {
+			return echo.NewHTTPError(http.StatusBadRequest, "ttl_hours must be between 1 and 720")
+		}
+		ttl = time.Duration(hours) * time.Hour
+	}
+
+	if _, err := h.scanRepo.GetByID(c.Request().Context(), id); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	token := h.signer.Sign(id, expiresAt)
+
+	// Links can't be revoked one by one, so who shared what is kept in the logs
+	h.logger.Info("scan share link created",
+		zap.Int("scan_id", id),
+		zap.String("user", getUserFromHeaders(c)),
+		zap.Time("expires_at", expiresAt))
+
+	return c.JSON(http.StatusOK, models.ShareLink{
+		ScanID:    id,
+		URL:       h.frontendURL + "/api/v1/shared/scans/" + token,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// GetSharedScan handles GET /api/v1/shared/scans/:token, without authentication
+func (h *ShareHandler) GetSharedScan(c echo.Context) error {
+	if h.signer == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "share link not found")
+	}
+
+	id, expiresAt, err := h.signer.Verify(c.Param("token"), time.Now())
+	if errors.Is(err, auth.ErrExpiredShareToken) {
+		return echo.NewHTTPError(http.StatusGone, "share link expired")
+	}
+	if err != nil {
+		h.logger.Warn("invalid share token", zap.String("remote_addr", c.RealIP()))
+		return echo.NewHTTPError(http.StatusNotFound, "share link not found")
+	}
+
+	ctx := c.Request().Context()
+	scan, err := h.scanRepo.GetWithDetails(ctx, id, nil)
+	if errors.Is(err, db.ErrNotFound) {
+		// The scan was pruned or deleted after the link was shared
+		return echo.NewHTTPError(http.StatusNotFound, "share link not found")
+	} else if err != nil {
+		return err
+	}
+
+	vulns, err := h.scanRepo.GetVulnerabilities(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to get vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get vulnerabilities")
+	}
+
+	// Triage notes and the people who wrote them are internal
+	for i := range vulns {
+		vulns[i].Notes = nil
+		vulns[i].UpdatedBy = nil
+	}
+
+	return c.JSON(http.StatusOK, models.SharedScanReport{
+		Scan:            scan,
+		Vulnerabilities: vulns,
+		ExpiresAt:       expiresAt,
+	})
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestShareHandler_CreateShareLink_Validation(t *testing.T) {
+	disabled := NewShareHandler(zap.NewNop(), nil, nil, "")
+	_, err := doScanRequest(t, disabled.CreateShareLink, http.MethodGet, "/api/v1/scans/:id/share", nil, "1")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+
+	handler := NewShareHandler(zap.NewNop(), auth.NewShareSigner("secret"), nil, "")
+	for _, ttl := range []string{"0", "-1", "721", "soon"} {
+		_, err := doScanRequest(t, handler.CreateShareLink, http.MethodGet, "/api/v1/scans/:id/share?ttl_hours="+ttl, nil, "1")
+		require.ErrorAs(t, err, &httpErr, ttl)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, ttl)
+	}
+}
+
+func TestShareHandler_GetSharedScan_RejectsTokens(t *testing.T) {
+	signer := auth.NewShareSigner("secret")
+	handler := NewShareHandler(zap.NewNop(), signer, nil, "")
+
+	tests := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"expired", signer.Sign(1, time.Now().Add(-time.Minute)), http.StatusGone},
+		{"other secret", auth.NewShareSigner("other").Sign(1, time.Now().Add(time.Hour)), http.StatusNotFound},
+		{"malformed", "1", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/shared/scans/"+tt.token, nil), httptest.NewRecorder())
+			c.SetParamNames("token")
+			c.SetParamValues(tt.token)
+
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, handler.GetSharedScan(c), &httpErr)
+			assert.Equal(t, tt.code, httpErr.Code)
+		})
+	}
+}
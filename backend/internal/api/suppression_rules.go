@@ -0,0 +1,236 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/invulnerable/backend/internal/bundle"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/ignorefile"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxIgnoreFileSize bounds imported ignore files; real ones are a few kilobytes
+const maxIgnoreFileSize = 1 << 20
+
+// maxBundleSize bounds imported suppression bundles, which also hold the waivers of every vulnerability
+const maxBundleSize = 10 << 20
+
+// SuppressionRuleHandler manages accepted-risk waivers
+type SuppressionRuleHandler struct {
+	logger *zap.Logger
+	repo   *db.SuppressionRuleRepository
+}
+
+func NewSuppressionRuleHandler(logger *zap.Logger, repo *db.SuppressionRuleRepository) *SuppressionRuleHandler {
+	return &SuppressionRuleHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// ListSuppressionRules handles GET /api/v1/suppression-rules
+func (h *SuppressionRuleHandler) ListSuppressionRules(c echo.Context) error {
+	rules, err := h.repo.List(c.Request().Context())
+	if err != nil {
+		h.logger.Error("failed to list suppression rules", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list suppression rules")
+	}
+
+	return c.JSON(http.StatusOK, rules)
+}
+
+// CreateSuppressionRule handles POST /api/v1/suppression-rules
+func (h *SuppressionRuleHandler) CreateSuppressionRule(c echo.Context) error {
+	var req models.SuppressionRuleRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	if req.CVEID == nil && req.PackageName == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "cve_id or package_name is required")
+	}
+	if req.FixState != nil && !slices.Contains(models.ValidFixStates, *req.FixState) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid fix_state")
+	}
+
+	user := getUserFromHeaders(c)
+	rule := &models.SuppressionRule{
+		CVEID:          req.CVEID,
+		PackageName:    req.PackageName,
+		PackageVersion: req.PackageVersion,
+		PackageType:    req.PackageType,
+		FixState:       req.FixState,
+		Reason:         req.Reason,
+		ExpiresAt:      req.ExpiresAt,
+		Source:         models.SuppressionSourceManual,
+		CreatedBy:      &user,
+	}
+	if err := h.repo.Upsert(c.Request().Context(), rule); err != nil {
+		h.logger.Error("failed to create suppression rule", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create suppression rule")
+	}
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteSuppressionRule handles DELETE /api/v1/suppression-rules/:id
+func (h *SuppressionRuleHandler) DeleteSuppressionRule(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid suppression rule ID")
+	}
+
+	if err := h.repo.Delete(c.Request().Context(), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ImportSuppressionRules handles POST /api/v1/suppression-rules/import?format=grype|trivy
+// The request body is the raw .grype.yaml or .trivyignore file
+func (h *SuppressionRuleHandler) ImportSuppressionRules(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "format is required (grype or trivy)")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxIgnoreFileSize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read request body")
+	}
+	if len(data) > maxIgnoreFileSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "ignore file too large")
+	}
+
+	parsed, err := ignorefile.Parse(format, data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	user := getUserFromHeaders(c)
+	for i := range parsed.Rules {
+		parsed.Rules[i].CreatedBy = &user
+	}
+
+	if len(parsed.Rules) > 0 {
+		if err := h.repo.Import(c.Request().Context(), parsed.Rules); err != nil {
+			h.logger.Error("failed to import suppression rules", zap.Error(err), zap.String("format", format))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to import suppression rules")
+		}
+	}
+
+	h.logger.Info("imported suppression rules",
+		zap.String("format", format),
+		zap.Int("imported", len(parsed.Rules)),
+		zap.Int("skipped", len(parsed.Skipped)),
+		zap.String("user", user))
+
+	return c.JSON(http.StatusOK, models.SuppressionImportResult{
+		Format:   format,
+		Imported: len(parsed.Rules),
+		Rules:    parsed.Rules,
+		Skipped:  parsed.Skipped,
+	})
+}
+
+// ExportSuppressionRules handles GET /api/v1/suppression-rules/export?format=grype
+// Only rules still in effect are exported, so local scans match what the server accepts today
+func (h *SuppressionRuleHandler) ExportSuppressionRules(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = ignorefile.FormatGrype
+	}
+	if format != ignorefile.FormatGrype {
+		return echo.NewHTTPError(http.StatusBadRequest, "unsupported export format (expected grype)")
+	}
+
+	rules, err := h.repo.ListActive(c.Request().Context(), time.Now())
+	if err != nil {
+		h.logger.Error("failed to list suppression rules", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list suppression rules")
+	}
+
+	data, err := ignorefile.RenderGrype(rules)
+	if err != nil {
+		h.logger.Error("failed to render grype config", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export suppression rules")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename=".grype.yaml"`)
+	return c.Blob(http.StatusOK, "application/yaml", data)
+}
+
+// ExportSuppressionBundle handles GET /api/v1/suppression-rules/bundle
+// The bundle holds every rule, expired ones included, and the vulnerabilities accepted or ignored, sorted so
+// the same state always exports the same document
+func (h *SuppressionRuleHandler) ExportSuppressionBundle(c echo.Context) error {
+	ctx := c.Request().Context()
+	rules, err := h.repo.List(ctx)
+	if err != nil {
+		h.logger.Error("failed to list suppression rules", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export suppression bundle")
+	}
+	waivers, err := h.repo.ListWaivers(ctx)
+	if err != nil {
+		h.logger.Error("failed to list waivers", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export suppression bundle")
+	}
+
+	data, err := bundle.Render(rules, waivers)
+	if err != nil {
+		h.logger.Error("failed to render suppression bundle", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export suppression bundle")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="suppressions.yaml"`)
+	return c.Blob(http.StatusOK, "application/yaml", data)
+}
+
+// ImportSuppressionBundle handles POST /api/v1/suppression-rules/bundle?prune=true
+// The request body is a bundle exported by GET /api/v1/suppression-rules/bundle. With prune, rules missing
+// from the bundle are deleted so the instance ends with exactly the bundle's rules
+func (h *SuppressionRuleHandler) ImportSuppressionBundle(c echo.Context) error {
+	prune := false
+	if value := c.QueryParam("prune"); value != "" {
+		var err error
+		if prune, err = strconv.ParseBool(value); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid prune parameter")
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxBundleSize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read request body")
+	}
+	if len(data) > maxBundleSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "suppression bundle too large")
+	}
+
+	rules, waivers, err := bundle.Parse(data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	user := getUserFromHeaders(c)
+	result, err := h.repo.ImportBundle(c.Request().Context(), rules, waivers, prune, user)
+	if err != nil {
+		h.logger.Error("failed to import suppression bundle", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to import suppression bundle")
+	}
+
+	h.logger.Info("imported suppression bundle",
+		zap.Int("rules", result.Rules),
+		zap.Int("rules_pruned", result.RulesPruned),
+		zap.Int("waivers", result.Waivers),
+		zap.Int("waivers_pending", len(result.WaiversPending)),
+		zap.String("user", user))
+
+	return c.JSON(http.StatusOK, result)
+}
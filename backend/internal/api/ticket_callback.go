@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/eventhook"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxTicketCallbackSize bounds callback bodies, which list vulnerabilities, not their data
+	maxTicketCallbackSize = 1 << 20
+	// maxTicketCallbackVulnerabilities is the bulk update limit
+	maxTicketCallbackVulnerabilities = 100
+)
+
+// DefaultTicketStatusMap maps the closing and working statuses of Jira and ServiceNow workflows
+const DefaultTicketStatusMap = "done=fixed,closed=fixed,resolved=fixed,in progress=in_progress,work in progress=in_progress"
+
+// ParseTicketStatusMap parses comma-separated ticket_status=vulnerability_status pairs. Ticket statuses are
+// matched case-insensitively
+func ParseTicketStatusMap(list string) (map[string]string, error) {
+	statuses := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		ticketStatus, status, ok := strings.Cut(pair, "=")
+		ticketStatus = strings.ToLower(strings.TrimSpace(ticketStatus))
+		status = strings.TrimSpace(status)
+		if !ok || ticketStatus == "" {
+			return nil, fmt.Errorf("invalid ticket status mapping %q, expected ticket_status=vulnerability_status", pair)
+		}
+		if !slices.Contains(models.ValidStatuses, status) {
+			return nil, fmt.Errorf("invalid vulnerability status %q for ticket status %q (must be one of %s)",
+				status, ticketStatus, strings.Join(models.ValidStatuses, ", "))
+		}
+		statuses[ticketStatus] = status
+	}
+	return statuses, nil
+}
+
+// TicketCallbackStore is the persistence used by the ticket callback handler
+type TicketCallbackStore interface {
+	MatchTriageImport(ctx context.Context, cveID, packageName, imageName string) ([]int, *int, error)
+	ApplyTicketCallback(ctx context.Context, callback *models.TicketCallback, update *models.VulnerabilityUpdateWithContext, events ...*models.OutboxEvent) (bool, error)
+}
+
+// TicketCallbackHandler applies the status changes of tickets tracking vulnerabilities, reported by the
+// automation of ticketing systems. Callbacks are signed with the scheme of the event webhook
+type TicketCallbackHandler struct {
+	logger   *zap.Logger
+	store    TicketCallbackStore
+	secret   []byte
+	statuses map[string]string
+}
+
+func NewTicketCallbackHandler(logger *zap.Logger, store TicketCallbackStore, secret string, statuses map[string]string) *TicketCallbackHandler {
+	return &TicketCallbackHandler{
+		logger:   logger,
+		store:    store,
+		secret:   []byte(secret),
+		statuses: statuses,
+	}
+}
+
+// defaultTicketEventID identifies a callback sent without an event ID by its signed timestamp and body.
+// Only a redelivery of the same request has the same ID, a ticket reopened and closed again doesn't
+func defaultTicketEventID(timestamp string, body []byte) string {
+	sum := sha256.Sum256(append([]byte(timestamp+"."), body...))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// TicketCallback handles POST /api/v1/integrations/ticket-callback
+// The ticket status is mapped to a vulnerability status applied to the vulnerabilities of the ticket.
+// Statuses without a mapping are acknowledged and ignored, and a callback already applied is not applied again
+func (h *TicketCallbackHandler) TicketCallback(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxTicketCallbackSize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read request body")
+	}
+	if len(body) > maxTicketCallbackSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "request body too large")
+	}
+	header := c.Request().Header
+	if err := eventhook.Verify(h.secret, header.Get(eventhook.HeaderTimestamp), body, header.Get(eventhook.HeaderSignature), time.Now()); err != nil {
+		h.logger.Warn("rejected ticket callback", zap.Error(err), zap.String("remote_ip", c.RealIP()))
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+
+	var req models.TicketCallbackRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if req.TicketID == "" || req.Status == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "ticket_id and status are required")
+	}
+	if len(req.VulnerabilityIDs) == 0 && len(req.CVEIDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "vulnerability_ids or cve_ids is required")
+	}
+	if req.EventID == "" {
+		req.EventID = defaultTicketEventID(header.Get(eventhook.HeaderTimestamp), body)
+	}
+
+	result := models.TicketCallbackResult{TicketCallback: models.TicketCallback{
+		EventID:          req.EventID,
+		TicketID:         req.TicketID,
+		TicketStatus:     req.Status,
+		VulnerabilityIDs: pq.Int64Array{},
+	}}
+	status, ok := h.statuses[strings.ToLower(strings.TrimSpace(req.Status))]
+	if !ok {
+		h.logger.Info("ticket status not mapped, ignoring callback",
+			zap.String("ticket_id", req.TicketID),
+			zap.String("ticket_status", req.Status))
+		return c.JSON(http.StatusOK, result)
+	}
+	result.Status = status
+
+	ctx := c.Request().Context()
+	ids := slices.Clone(req.VulnerabilityIDs)
+	for _, cveID := range req.CVEIDs {
+		matched, _, err := h.store.MatchTriageImport(ctx, cveID, "", req.Image)
+		if err != nil {
+			h.logger.Error("failed to match ticket vulnerabilities", zap.Error(err), zap.String("cve_id", cveID))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to match vulnerabilities")
+		}
+		ids = append(ids, matched...)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if len(ids) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "no vulnerability matches the ticket")
+	}
+	if len(ids) > maxTicketCallbackVulnerabilities {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("cannot update more than %d vulnerabilities at once", maxTicketCallbackVulnerabilities))
+	}
+	for _, id := range ids {
+		result.VulnerabilityIDs = append(result.VulnerabilityIDs, int64(id))
+	}
+
+	// The ticket is the author in the history, and of the status change webhooks
+	updatedBy := "ticket:" + req.TicketID
+	events := make([]*models.OutboxEvent, 0, len(ids))
+	for _, id := range ids {
+		event, err := models.NewOutboxEvent(models.OutboxKindStatusChange, statusChangeEvent{VulnerabilityID: id, ChangedBy: updatedBy})
+		if err != nil {
+			h.logger.Error("failed to create status change notification", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to apply ticket callback")
+		}
+		events = append(events, event)
+	}
+
+	update := &models.VulnerabilityUpdateWithContext{Notes: req.Notes, UpdatedBy: updatedBy}
+	duplicate, err := h.store.ApplyTicketCallback(ctx, &result.TicketCallback, update, events...)
+	if err != nil {
+		return fmt.Errorf("failed to apply ticket callback %s: %w", req.EventID, err)
+	}
+	result.Duplicate = duplicate
+	result.Applied = !duplicate
+	h.logger.Info("ticket callback processed",
+		zap.String("event_id", req.EventID),
+		zap.String("ticket_id", req.TicketID),
+		zap.String("status", status),
+		zap.Int("vulnerabilities", len(ids)),
+		zap.Bool("duplicate", duplicate))
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/eventhook"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeTicketCallbackStore struct {
+	// Vulnerability IDs by CVE and image name
+	vulns     map[[2]string][]int
+	callbacks map[string]models.TicketCallback
+	updates   []models.VulnerabilityUpdateWithContext
+	events    []*models.OutboxEvent
+}
+
+func (s *fakeTicketCallbackStore) MatchTriageImport(ctx context.Context, cveID, packageName, imageName string) ([]int, *int, error) {
+	return s.vulns[[2]string{cveID, imageName}], nil, nil
+}
+
+func (s *fakeTicketCallbackStore) ApplyTicketCallback(ctx context.Context, callback *models.TicketCallback, update *models.VulnerabilityUpdateWithContext, events ...*models.OutboxEvent) (bool, error) {
+	if recorded, ok := s.callbacks[callback.EventID]; ok {
+		*callback = recorded
+		return true, nil
+	}
+	s.callbacks[callback.EventID] = *callback
+	s.updates = append(s.updates, *update)
+	s.events = append(s.events, events...)
+	return false, nil
+}
+
+func postTicketCallback(e *echo.Echo, secret, body string) *httptest.ResponseRecorder {
+	return postTicketCallbackAt(e, secret, time.Now(), body)
+}
+
+func postTicketCallbackAt(e *echo.Echo, secret string, at time.Time, body string) *httptest.ResponseRecorder {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/integrations/ticket-callback", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(eventhook.HeaderTimestamp, timestamp)
+	req.Header.Set(eventhook.HeaderSignature, eventhook.Sign([]byte(secret), timestamp, []byte(body)))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTicketCallbackHandler_TicketCallback(t *testing.T) {
+	store := &fakeTicketCallbackStore{
+		vulns: map[[2]string][]int{
+			{"CVE-2024-0001", ""}:                             {1, 2},
+			{"CVE-2024-0002", "docker.io/library/nginx:1.25"}: {3},
+		},
+		callbacks: map[string]models.TicketCallback{},
+	}
+	statuses, err := ParseTicketStatusMap(DefaultTicketStatusMap)
+	require.NoError(t, err)
+	handler := NewTicketCallbackHandler(zap.NewNop(), store, "secret", statuses)
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler(e)
+	e.POST("/api/v1/integrations/ticket-callback", handler.TicketCallback)
+
+	// Vulnerabilities by ID and by CVE, the status matched case-insensitively
+	body := `{"event_id":"jira-1001","ticket_id":"SEC-42","status":"Done","vulnerability_ids":[2,5],"cve_ids":["CVE-2024-0001"],"notes":"Upgraded in SEC-42"}`
+	rec := postTicketCallback(e, "secret", body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var result models.TicketCallbackResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, result.Applied)
+	assert.False(t, result.Duplicate)
+	assert.Equal(t, models.StatusFixed, result.Status)
+	assert.Equal(t, []int64{1, 2, 5}, []int64(result.VulnerabilityIDs))
+
+	require.Len(t, store.updates, 1)
+	assert.Equal(t, "ticket:SEC-42", store.updates[0].UpdatedBy)
+	assert.Equal(t, "Upgraded in SEC-42", *store.updates[0].Notes)
+	require.Len(t, store.events, 3)
+	assert.Equal(t, models.OutboxKindStatusChange, store.events[0].Kind)
+
+	// The sender retrying the callback doesn't apply it again
+	rec = postTicketCallback(e, "secret", body)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.False(t, result.Applied)
+	assert.True(t, result.Duplicate)
+	assert.Len(t, store.updates, 1)
+
+	// Without an event ID the timestamp and body identify the callback
+	sent := time.Now()
+	body = `{"ticket_id":"SEC-43","status":"In Progress","cve_ids":["CVE-2024-0002"],"image":"docker.io/library/nginx:1.25"}`
+	rec = postTicketCallbackAt(e, "secret", sent, body)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, defaultTicketEventID(strconv.FormatInt(sent.Unix(), 10), []byte(body)), result.EventID)
+	assert.True(t, result.Applied)
+	assert.Equal(t, models.StatusInProgress, result.Status)
+	assert.Equal(t, []int64{3}, []int64(result.VulnerabilityIDs))
+
+	// A redelivery of the request is a duplicate, the ticket reaching the status again later is not
+	rec = postTicketCallbackAt(e, "secret", sent, body)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, result.Duplicate)
+	rec = postTicketCallbackAt(e, "secret", sent.Add(-time.Minute), body)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, result.Applied)
+	assert.Len(t, store.updates, 3)
+
+	// Statuses without a mapping are acknowledged and ignored
+	rec = postTicketCallback(e, "secret", `{"ticket_id":"SEC-44","status":"To Do","vulnerability_ids":[1]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.False(t, result.Applied)
+	assert.Len(t, store.updates, 3)
+
+	rec = postTicketCallback(e, "other", `{"ticket_id":"SEC-45","status":"Done","vulnerability_ids":[1]}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = postTicketCallback(e, "secret", `{"ticket_id":"SEC-45","status":"Done"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = postTicketCallback(e, "secret", `{"ticket_id":"SEC-45","status":"Done","cve_ids":["CVE-2024-9999"]}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Len(t, store.updates, 3)
+}
+
+func TestParseTicketStatusMap(t *testing.T) {
+	statuses, err := ParseTicketStatusMap(" Done = fixed, Won't Do=ignored ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"done": models.StatusFixed, "won't do": models.StatusIgnored}, statuses)
+
+	_, err = ParseTicketStatusMap("done")
+	assert.Error(t, err)
+	_, err = ParseTicketStatusMap("done=closed")
+	assert.ErrorContains(t, err, "invalid vulnerability status")
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequestTimeouts bounds how long a request may run. The deadline is set on the request context,
+// which handlers pass to the database, S3 and webhooks, so requests stuck on a slow dependency
+// are cancelled instead of piling up. Zero disables a timeout
+type RequestTimeouts struct {
+	// Read applies to GET and HEAD requests
+	Read time.Duration
+	// Write applies to the other requests
+	Write time.Duration
+	// Ingest applies to IngestRoutes, which store whole scans
+	Ingest time.Duration
+	// IngestRoutes and Untimed are routes as registered, e.g. "POST /api/v1/scans". Untimed routes
+	// stream for as long as the client stays connected
+	IngestRoutes []string
+	Untimed      []string
+}
+
+// DefaultRequestTimeouts give reads enough time for large listings and exports, and scan
+// submissions enough for images with tens of thousands of matches
+func DefaultRequestTimeouts() RequestTimeouts {
+	return RequestTimeouts{
+		Read:   30 * time.Second,
+		Write:  60 * time.Second,
+		Ingest: 10 * time.Minute,
+	}
+}
+
+// timeout returns the timeout of a route, zero for none
+func (t RequestTimeouts) timeout(method, path string) time.Duration {
+	route := method + " " + path
+	for _, untimed := range t.Untimed {
+		if route == untimed {
+			return 0
+		}
+	}
+	for _, ingest := range t.IngestRoutes {
+		if route == ingest {
+			return t.Ingest
+		}
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return t.Read
+	}
+	return t.Write
+}
+
+// RequestTimeout is the middleware applying timeouts. A request still running at its deadline
+// fails with 504, whatever error the handler returned once its calls were cancelled
+func RequestTimeout(timeouts RequestTimeouts) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			timeout := timeouts.timeout(c.Request().Method, c.Path())
+			if timeout <= 0 {
+				return next(c)
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			err := next(c)
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Response().Committed {
+				// Echo responds with an internal error that is an echo.HTTPError, so the handler error is wrapped
+				return echo.NewHTTPError(http.StatusGatewayTimeout, fmt.Sprintf("request timed out after %s", timeout)).
+					SetInternal(fmt.Errorf("handler failed: %w", err))
+			}
+			return err
+		}
+	}
+}
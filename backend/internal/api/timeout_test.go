@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	timeouts := RequestTimeouts{
+		Read:         20 * time.Millisecond,
+		Write:        time.Minute,
+		Ingest:       time.Hour,
+		IngestRoutes: []string{"POST /api/v1/scans"},
+		Untimed:      []string{"GET /api/v1/events"},
+	}
+	e := echo.New()
+	e.Use(RequestTimeout(timeouts))
+
+	// A handler blocked on a dependency until its context is cancelled
+	blocked := func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list scans")
+	}
+	deadline := func(c echo.Context) error {
+		d, ok := c.Request().Context().Deadline()
+		if !ok {
+			return c.String(http.StatusOK, "none")
+		}
+		return c.String(http.StatusOK, time.Until(d).Round(time.Minute).String())
+	}
+	e.GET("/api/v1/scans", blocked)
+	e.POST("/api/v1/scans", deadline)
+	e.POST("/api/v1/campaigns", deadline)
+	e.GET("/api/v1/events", deadline)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/scans", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Contains(t, rec.Body.String(), "request timed out after 20ms")
+
+	for route, want := range map[string]string{
+		"POST /api/v1/scans":     "1h0m0s",
+		"POST /api/v1/campaigns": "1m0s",
+		"GET /api/v1/events":     "none",
+	} {
+		method, path, _ := strings.Cut(route, " ")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, route)
+		assert.Equal(t, want, rec.Body.String(), route)
+	}
+}
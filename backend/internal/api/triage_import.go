@@ -0,0 +1,268 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxTriageImportSize bounds imported CSV files, a spreadsheet of a few thousand decisions
+	maxTriageImportSize = 5 << 20
+	maxTriageImportRows = 5000
+)
+
+// triageImportColumns are the columns of a triage import, cve and one of the others are required
+var triageImportColumns = []string{"cve", "package", "image", "status", "notes", "justification"}
+
+// TriageImportStore is the persistence used by the triage import handler
+type TriageImportStore interface {
+	MatchTriageImport(ctx context.Context, cveID, packageName, imageName string) ([]int, *int, error)
+	ImportTriage(ctx context.Context, updates []models.TriageImportUpdate, events ...*models.OutboxEvent) error
+}
+
+// TriageImportHandler imports triage decisions kept outside invulnerable, e.g. in spreadsheets
+type TriageImportHandler struct {
+	logger *zap.Logger
+	store  TriageImportStore
+}
+
+func NewTriageImportHandler(logger *zap.Logger, store TriageImportStore) *TriageImportHandler {
+	return &TriageImportHandler{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// triageImportRow is a parsed row of a triage import
+type triageImportRow struct {
+	line                 int
+	cveID, pkg, image    string
+	status               *string
+	notes, justification string
+}
+
+// ImportTriage handles POST /api/v1/vulnerabilities/import?dry_run=true
+// The request body is a CSV with a header row. Rows are matched to the stored vulnerabilities by CVE,
+// and by package and image when set, then every matched row is applied in one transaction. A file with
+// an invalid row is rejected with 422 and nothing is applied; rows matching nothing are reported and skipped
+func (h *TriageImportHandler) ImportTriage(c echo.Context) error {
+	dryRun := c.QueryParam("dry_run") == "true"
+
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, maxTriageImportSize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read request body")
+	}
+	if len(data) > maxTriageImportSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "CSV file too large")
+	}
+
+	rows, err := parseTriageCSV(data)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if len(rows) > maxTriageImportRows {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("cannot import more than %d rows at once", maxTriageImportRows))
+	}
+
+	ctx := c.Request().Context()
+	user := getUserFromHeaders(c)
+	result := models.TriageImportResult{DryRun: dryRun, Rows: make([]models.TriageImportRowResult, 0, len(rows))}
+	updates := []models.TriageImportUpdate{}
+	updated := map[int]bool{}
+	var events []*models.OutboxEvent
+
+	for _, row := range rows {
+		rowResult := models.TriageImportRowResult{Row: row.line, CVEID: row.cveID, PackageName: row.pkg, Image: row.image}
+		if err := row.validate(); err != nil {
+			rowResult.Result = models.TriageImportInvalid
+			rowResult.Error = err.Error()
+			result.Invalid++
+			result.Rows = append(result.Rows, rowResult)
+			continue
+		}
+
+		imageName := ""
+		if row.image != "" {
+			ref, _, _ := strings.Cut(row.image, "@")
+			registry, repository, tag := parseImageName(ref)
+			imageName = registry + "/" + repository + ":" + tag
+		}
+		ids, imageID, err := h.store.MatchTriageImport(ctx, row.cveID, row.pkg, imageName)
+		if err != nil {
+			h.logger.Error("failed to match triage import row", zap.Error(err), zap.Int("row", row.line))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to import triage decisions")
+		}
+		if len(ids) == 0 {
+			rowResult.Result = models.TriageImportNotFound
+			result.NotFound++
+			result.Rows = append(result.Rows, rowResult)
+			continue
+		}
+
+		rowResult.Result = models.TriageImportUpdated
+		rowResult.VulnerabilityIDs = ids
+		result.Rows = append(result.Rows, rowResult)
+
+		update := models.TriageImportUpdate{VulnerabilityIDs: ids, Update: models.VulnerabilityUpdateWithContext{
+			Status:    row.status,
+			Notes:     triageNotes(row.notes, row.justification),
+			UpdatedBy: user,
+			ImageID:   imageID,
+		}}
+		if imageName != "" {
+			update.Update.ImageName = &imageName
+		}
+		updates = append(updates, update)
+
+		for _, id := range ids {
+			updated[id] = true
+			if row.status == nil {
+				continue
+			}
+			// One status change webhook per vulnerability and row, sent by the outbox dispatcher
+			event, err := models.NewOutboxEvent(models.OutboxKindStatusChange, statusChangeEvent{VulnerabilityID: id, ChangedBy: user})
+			if err != nil {
+				h.logger.Error("failed to create status change notification", zap.Error(err))
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to import triage decisions")
+			}
+			events = append(events, event)
+		}
+	}
+	result.Updated = len(updated)
+
+	if result.Invalid > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, result)
+	}
+	if dryRun || len(updates) == 0 {
+		return c.JSON(http.StatusOK, result)
+	}
+
+	if err := h.store.ImportTriage(ctx, updates, events...); err != nil {
+		h.logger.Error("failed to import triage decisions", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to import triage decisions")
+	}
+	result.Applied = true
+
+	h.logger.Info("imported triage decisions",
+		zap.Int("rows", len(rows)),
+		zap.Int("updated", result.Updated),
+		zap.Int("not_found", result.NotFound),
+		zap.String("user", user))
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// parseTriageCSV parses the rows of a triage import. Columns are found by their header, in any order and case,
+// and unknown columns are ignored
+func parseTriageCSV(data []byte) ([]triageImportRow, error) {
+	// Spreadsheets export UTF-8 with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, dup := columns[name]; dup && slices.Contains(triageImportColumns, name) {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["cve"]; !ok {
+		return nil, errors.New("missing cve column")
+	}
+	_, hasStatus := columns["status"]
+	_, hasNotes := columns["notes"]
+	_, hasJustification := columns["justification"]
+	if !hasStatus && !hasNotes && !hasJustification {
+		return nil, errors.New("missing status, notes or justification column")
+	}
+
+	rows := []triageImportRow{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		// Skip blank lines left by spreadsheets, e.g. a row of empty cells
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		row := triageImportRow{
+			line:          line,
+			cveID:         field("cve"),
+			pkg:           field("package"),
+			image:         field("image"),
+			notes:         field("notes"),
+			justification: field("justification"),
+		}
+		if status := field("status"); status != "" {
+			// Accept the labels shown in the UI, e.g. "In Progress"
+			status = strings.ReplaceAll(strings.ToLower(status), " ", "_")
+			row.status = &status
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// validate checks that a row can be applied
+func (r *triageImportRow) validate() error {
+	if r.cveID == "" {
+		return errors.New("cve is required")
+	}
+	if r.status == nil && r.notes == "" && r.justification == "" {
+		return errors.New("status, notes or justification is required")
+	}
+	if r.status != nil && !slices.Contains(models.ValidStatuses, *r.status) {
+		return fmt.Errorf("invalid status %q, expected one of %s", *r.status, strings.Join(models.ValidStatuses, ", "))
+	}
+	return nil
+}
+
+// triageNotes returns the notes a row sets, with its justification, nil to leave the notes unchanged
+func triageNotes(notes, justification string) *string {
+	switch {
+	case justification == "" && notes == "":
+		return nil
+	case justification == "":
+		return &notes
+	case notes == "":
+		notes = "Justification: " + justification
+	default:
+		notes += "\n\nJustification: " + justification
+	}
+	return &notes
+}
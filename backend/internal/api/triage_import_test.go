@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeTriageImportStore struct {
+	// Vulnerability IDs by CVE, package and image name
+	vulns   map[[3]string][]int
+	updates []models.TriageImportUpdate
+	events  []*models.OutboxEvent
+}
+
+func (s *fakeTriageImportStore) MatchTriageImport(ctx context.Context, cveID, packageName, imageName string) ([]int, *int, error) {
+	var imageID *int
+	if imageName != "" {
+		id := 1
+		imageID = &id
+	}
+	return s.vulns[[3]string{cveID, packageName, imageName}], imageID, nil
+}
+
+func (s *fakeTriageImportStore) ImportTriage(ctx context.Context, updates []models.TriageImportUpdate, events ...*models.OutboxEvent) error {
+	s.updates = updates
+	s.events = events
+	return nil
+}
+
+func postTriageImport(e *echo.Echo, query, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/vulnerabilities/import"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("X-Auth-Request-Email", "alice@example.com")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTriageImportHandler_ImportTriage(t *testing.T) {
+	store := &fakeTriageImportStore{vulns: map[[3]string][]int{
+		{"CVE-2024-0001", "", ""}:                                    {1, 2},
+		{"CVE-2024-0002", "openssl", "docker.io/library/nginx:1.25"}: {3},
+	}}
+	handler := NewTriageImportHandler(zap.NewNop(), store)
+	e := echo.New()
+	e.POST("/api/v1/vulnerabilities/import", handler.ImportTriage)
+
+	// Columns in any order and case, with a byte order mark and a status as labelled in the UI
+	body := "\xef\xbb\xbfCVE,Status,Notes,Package,Image,Justification,Owner\n" +
+		"CVE-2024-0001,Accepted,Risk accepted by the CISO,,,,bob\n" +
+		"CVE-2024-0002,In Progress,,openssl,library/nginx:1.25,,bob\n" +
+		",,,,,,\n" +
+		"CVE-2024-0003,fixed,,,,,bob\n" +
+		"CVE-2024-0001,,,,,vulnerable_code_not_in_execute_path,bob\n"
+
+	rec := postTriageImport(e, "?dry_run=true", body)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, store.updates, "dry runs apply nothing")
+
+	rec = postTriageImport(e, "", body)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result models.TriageImportResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, result.Applied)
+	assert.Equal(t, 3, result.Updated)
+	assert.Equal(t, 1, result.NotFound)
+	require.Len(t, result.Rows, 4, "blank lines are skipped")
+	assert.Equal(t, models.TriageImportRowResult{
+		Row: 3, CVEID: "CVE-2024-0002", PackageName: "openssl", Image: "library/nginx:1.25",
+		Result: models.TriageImportUpdated, VulnerabilityIDs: []int{3},
+	}, result.Rows[1])
+	assert.Equal(t, models.TriageImportNotFound, result.Rows[2].Result)
+	assert.Equal(t, 5, result.Rows[2].Row)
+
+	require.Len(t, store.updates, 3)
+	first := store.updates[0].Update
+	assert.Equal(t, models.StatusAccepted, *first.Status)
+	assert.Equal(t, "Risk accepted by the CISO", *first.Notes)
+	assert.Equal(t, "alice@example.com", first.UpdatedBy)
+	second := store.updates[1].Update
+	assert.Equal(t, models.StatusInProgress, *second.Status)
+	assert.Nil(t, second.Notes, "empty notes are left unchanged")
+	require.NotNil(t, second.ImageID)
+	assert.Equal(t, "docker.io/library/nginx:1.25", *second.ImageName)
+	third := store.updates[2].Update
+	assert.Nil(t, third.Status)
+	assert.Equal(t, "Justification: vulnerable_code_not_in_execute_path", *third.Notes)
+	assert.Len(t, store.events, 3, "one status change per vulnerability")
+
+	// An invalid row rejects the whole file
+	store.updates = nil
+	rec = postTriageImport(e, "", "cve,status\nCVE-2024-0001,fixed\nCVE-2024-0001,wontfix\n,fixed\nCVE-2024-0001,\n")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.False(t, result.Applied)
+	assert.Equal(t, 3, result.Invalid)
+	assert.Contains(t, result.Rows[1].Error, "invalid status")
+	assert.Equal(t, "cve is required", result.Rows[2].Error)
+	assert.Nil(t, store.updates)
+
+	for _, body := range []string{"", "package,status\nopenssl,fixed\n", "cve,image\nCVE-2024-0001,nginx\n", "cve,status,status\n"} {
+		rec = postTriageImport(e, "", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func TestTriageNotes(t *testing.T) {
+	assert.Nil(t, triageNotes("", ""))
+	assert.Equal(t, "patched", *triageNotes("patched", ""))
+	assert.Equal(t, "patched\n\nJustification: component_not_present", *triageNotes("patched", "component_not_present"))
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// UsageStore is the persistence used by the usage handler
+type UsageStore interface {
+	GetTeamUsage(ctx context.Context, from, to time.Time, namespace *string) ([]models.TeamUsage, error)
+	ListQuotas(ctx context.Context) ([]models.TeamQuota, error)
+	GetQuota(ctx context.Context, namespace string) (*models.TeamQuota, error)
+	UpsertQuota(ctx context.Context, namespace string, req *models.TeamQuotaRequest, updatedBy string) (*models.TeamQuota, error)
+	DeleteQuota(ctx context.Context, namespace string) error
+}
+
+// UsageHandler reports per-team usage for chargeback and manages soft quotas
+type UsageHandler struct {
+	logger *zap.Logger
+	store  UsageStore
+}
+
+func NewUsageHandler(logger *zap.Logger, store UsageStore) *UsageHandler {
+	return &UsageHandler{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// monthRange returns the bounds of a YYYY-MM month in UTC, the current month when empty
+func monthRange(month string, now time.Time) (time.Time, time.Time, error) {
+	start := time.Date(now.UTC().Year(), now.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+	if month != "" {
+		var err error
+		if start, err = time.Parse("2006-01", month); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// GetUsage handles GET /api/v1/usage
+func (h *UsageHandler) GetUsage(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	from, to, err := monthRange(c.QueryParam("month"), time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid month (expected YYYY-MM)")
+	}
+
+	var namespace *string
+	if ns := c.QueryParam("namespace"); ns != "" {
+		namespace = &ns
+	}
+
+	teams, err := h.store.GetTeamUsage(ctx, from, to, namespace)
+	if err != nil {
+		h.logger.Error("failed to get team usage", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team usage")
+	}
+
+	quotas, err := h.store.ListQuotas(ctx)
+	if err != nil {
+		h.logger.Error("failed to list team quotas", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team usage")
+	}
+	byNamespace := make(map[string]*models.TeamQuota, len(quotas))
+	for i := range quotas {
+		byNamespace[quotas[i].Namespace] = &quotas[i]
+	}
+
+	for i := range teams {
+		teams[i].Exceeded = []string{}
+		if quota, ok := byNamespace[teams[i].Namespace]; ok {
+			teams[i].Quota = quota
+			teams[i].Exceeded = quota.Exceeded(teams[i])
+		}
+	}
+
+	return c.JSON(http.StatusOK, models.UsageReport{
+		Month: from.Format("2006-01"),
+		From:  from,
+		To:    to,
+		Teams: teams,
+	})
+}
+
+// ListQuotas handles GET /api/v1/admin/quotas
+func (h *UsageHandler) ListQuotas(c echo.Context) error {
+	quotas, err := h.store.ListQuotas(c.Request().Context())
+	if err != nil {
+		h.logger.Error("failed to list team quotas", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list team quotas")
+	}
+
+	return c.JSON(http.StatusOK, quotas)
+}
+
+// SetQuota handles PUT /api/v1/admin/quotas/:namespace
+func (h *UsageHandler) SetQuota(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	var req models.TeamQuotaRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if (req.MaxScansPerMonth != nil && *req.MaxScansPerMonth < 0) ||
+		(req.MaxSBOMStorageBytes != nil && *req.MaxSBOMStorageBytes < 0) ||
+		(req.MaxVulnerabilities != nil && *req.MaxVulnerabilities < 0) {
+		return echo.NewHTTPError(http.StatusBadRequest, "quota limits must not be negative")
+	}
+
+	user := getUserFromHeaders(c)
+	quota, err := h.store.UpsertQuota(c.Request().Context(), namespace, &req, user)
+	if err != nil {
+		h.logger.Error("failed to set team quota", zap.Error(err), zap.String("namespace", namespace))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to set team quota")
+	}
+
+	h.logger.Info("team quota updated",
+		zap.String("namespace", namespace),
+		zap.Bool("enforce", quota.Enforce),
+		zap.String("updated_by", user))
+
+	return c.JSON(http.StatusOK, quota)
+}
+
+// DeleteQuota handles DELETE /api/v1/admin/quotas/:namespace
+func (h *UsageHandler) DeleteQuota(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	if err := h.store.DeleteQuota(c.Request().Context(), namespace); err != nil {
+		return err
+	}
+
+	h.logger.Info("team quota removed",
+		zap.String("namespace", namespace),
+		zap.String("removed_by", getUserFromHeaders(c)))
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// checkQuota returns the quota of a team and the limits it would exceed with one more scan,
+// or a nil quota when the team has none
+func checkQuota(ctx context.Context, store UsageStore, namespace string, now time.Time) (*models.TeamQuota, []string, error) {
+	quota, err := store.GetQuota(ctx, namespace)
+	if err != nil || quota == nil {
+		return nil, nil, err
+	}
+
+	from, to, _ := monthRange("", now)
+	usage, err := store.GetTeamUsage(ctx, from, to, &namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := models.TeamUsage{Namespace: namespace}
+	if len(usage) > 0 {
+		current = usage[0]
+	}
+	current.Scans++
+	return quota, quota.Exceeded(current), nil
+}
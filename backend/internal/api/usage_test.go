@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeUsageStore struct {
+	usage  []models.TeamUsage
+	quotas map[string]models.TeamQuota
+	from   time.Time
+}
+
+func (s *fakeUsageStore) GetTeamUsage(ctx context.Context, from, to time.Time, namespace *string) ([]models.TeamUsage, error) {
+	s.from = from
+	usage := []models.TeamUsage{}
+	for _, u := range s.usage {
+		if namespace == nil || u.Namespace == *namespace {
+			usage = append(usage, u)
+		}
+	}
+	return usage, nil
+}
+
+func (s *fakeUsageStore) ListQuotas(ctx context.Context) ([]models.TeamQuota, error) {
+	quotas := []models.TeamQuota{}
+	for _, q := range s.quotas {
+		quotas = append(quotas, q)
+	}
+	return quotas, nil
+}
+
+func (s *fakeUsageStore) GetQuota(ctx context.Context, namespace string) (*models.TeamQuota, error) {
+	q, ok := s.quotas[namespace]
+	if !ok {
+		return nil, nil
+	}
+	return &q, nil
+}
+
+func (s *fakeUsageStore) UpsertQuota(ctx context.Context, namespace string, req *models.TeamQuotaRequest, updatedBy string) (*models.TeamQuota, error) {
+	q := models.TeamQuota{Namespace: namespace, MaxScansPerMonth: req.MaxScansPerMonth, Enforce: req.Enforce, UpdatedBy: &updatedBy}
+	s.quotas[namespace] = q
+	return &q, nil
+}
+
+func (s *fakeUsageStore) DeleteQuota(ctx context.Context, namespace string) error {
+	delete(s.quotas, namespace)
+	return nil
+}
+
+func newUsageTestServer(store UsageStore) *echo.Echo {
+	handler := NewUsageHandler(zap.NewNop(), store)
+
+	e := echo.New()
+	e.GET("/api/v1/usage", handler.GetUsage)
+	e.PUT("/api/v1/admin/quotas/:namespace", handler.SetQuota)
+	return e
+}
+
+func TestUsageHandler_GetUsage(t *testing.T) {
+	maxScans := 10
+	store := &fakeUsageStore{
+		usage: []models.TeamUsage{
+			{Namespace: "payments", Scans: 12, SBOMStorageBytes: 2048},
+			{Namespace: "search", Scans: 3},
+		},
+		quotas: map[string]models.TeamQuota{"payments": {Namespace: "payments", MaxScansPerMonth: &maxScans}},
+	}
+	e := newUsageTestServer(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/usage?month=2026-02", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var report models.UsageReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, "2026-02", report.Month)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), report.To.UTC())
+	require.Len(t, report.Teams, 2)
+	assert.Equal(t, []string{models.QuotaScansPerMonth}, report.Teams[0].Exceeded)
+	assert.Nil(t, report.Teams[1].Quota)
+	assert.Empty(t, report.Teams[1].Exceeded)
+}
+
+func TestUsageHandler_GetUsage_InvalidMonth(t *testing.T) {
+	e := newUsageTestServer(&fakeUsageStore{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/usage?month=02-2026", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestUsageHandler_SetQuota(t *testing.T) {
+	store := &fakeUsageStore{quotas: map[string]models.TeamQuota{}}
+	e := newUsageTestServer(store)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/quotas/payments", strings.NewReader(`{"max_scans_per_month": -1}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/admin/quotas/payments", strings.NewReader(`{"max_scans_per_month": 100, "enforce": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, store.quotas["payments"].Enforce)
+}
+
+func TestCheckQuota(t *testing.T) {
+	maxScans := 10
+	store := &fakeUsageStore{
+		usage:  []models.TeamUsage{{Namespace: "payments", Scans: 10}},
+		quotas: map[string]models.TeamQuota{"payments": {Namespace: "payments", MaxScansPerMonth: &maxScans}},
+	}
+	now := time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)
+
+	// The scan being submitted is the eleventh of the month
+	quota, exceeded, err := checkQuota(context.Background(), store, "payments", now)
+	require.NoError(t, err)
+	require.NotNil(t, quota)
+	assert.Equal(t, []string{models.QuotaScansPerMonth}, exceeded)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), store.from)
+
+	quota, exceeded, err = checkQuota(context.Background(), store, "search", now)
+	require.NoError(t, err)
+	assert.Nil(t, quota)
+	assert.Empty(t, exceeded)
+}
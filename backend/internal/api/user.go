@@ -24,16 +24,16 @@ func NewUserHandler(logger *zap.Logger, jwtValidator *auth.JWTValidator, oauthEn
 }
 
 type UserResponse struct {
-	Email    string `json:"email"`
-	Username string `json:"username,omitempty"`
+	Email    string   `json:"email"`
+	Username string   `json:"username,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
 }
 
 // GetCurrentUser handles GET /api/v1/user/me - returns current authenticated user info
 func (h *UserHandler) GetCurrentUser(c echo.Context) error {
-	// OAuth2 Proxy injects user information in headers
-	email := c.Request().Header.Get("X-Auth-Request-Email")
-	username := c.Request().Header.Get("X-Auth-Request-User")
-	accessToken := c.Request().Header.Get("X-Auth-Request-Access-Token")
+	// The authenticating proxy injects user information in headers
+	id := requestIdentity(c)
+	email, username, accessToken := id.Email, id.User, id.Token
 
 	// If OAuth is not enabled, return 204 (no user info available)
 	if !h.oauthEnabled {
@@ -42,7 +42,7 @@ func (h *UserHandler) GetCurrentUser(c echo.Context) error {
 	}
 
 	// OAuth is enabled - authentication is REQUIRED
-	// If no email header, OAuth2 Proxy is misconfigured
+	// If no email header, the proxy is misconfigured
 	if email == "" {
 		h.logger.Warn("OAuth2 enabled but no authentication headers present",
 			zap.String("remote_addr", c.RealIP()))
@@ -87,5 +87,6 @@ func (h *UserHandler) GetCurrentUser(c echo.Context) error {
 	return c.JSON(http.StatusOK, UserResponse{
 		Email:    email,
 		Username: username,
+		Groups:   id.Groups,
 	})
 }
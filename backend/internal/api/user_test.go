@@ -5,15 +5,16 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/invulnerable/backend/internal/auth"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
-func TestUserHandler_GetCurrentUser_WithAuthHeaders(t *testing.T) {
+func TestUserHandler_GetCurrentUser_OAuthDisabled(t *testing.T) {
 	logger := zap.NewNop()
-	handler := NewUserHandler(logger)
+	handler := NewUserHandler(logger, nil, false)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/user/me", nil)
@@ -25,14 +26,13 @@ func TestUserHandler_GetCurrentUser_WithAuthHeaders(t *testing.T) {
 	err := handler.GetCurrentUser(c)
 	require.NoError(t, err)
 
-	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.Contains(t, rec.Body.String(), `"email":"test@example.com"`)
-	assert.Contains(t, rec.Body.String(), `"username":"testuser"`)
+	// Without OAuth there is no user identity to report
+	assert.Equal(t, http.StatusNoContent, rec.Code)
 }
 
 func TestUserHandler_GetCurrentUser_WithoutAuthHeaders(t *testing.T) {
 	logger := zap.NewNop()
-	handler := NewUserHandler(logger)
+	handler := NewUserHandler(logger, nil, true)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/user/me", nil)
@@ -40,14 +40,16 @@ func TestUserHandler_GetCurrentUser_WithoutAuthHeaders(t *testing.T) {
 	c := e.NewContext(req, rec)
 
 	err := handler.GetCurrentUser(c)
-	require.NoError(t, err)
+	require.Error(t, err)
 
-	assert.Equal(t, http.StatusNoContent, rec.Code)
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, httpErr.Code)
 }
 
-func TestUserHandler_GetCurrentUser_OnlyEmail(t *testing.T) {
+func TestUserHandler_GetCurrentUser_MissingValidator(t *testing.T) {
 	logger := zap.NewNop()
-	handler := NewUserHandler(logger)
+	handler := NewUserHandler(logger, nil, true)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/user/me", nil)
@@ -56,8 +58,33 @@ func TestUserHandler_GetCurrentUser_OnlyEmail(t *testing.T) {
 	c := e.NewContext(req, rec)
 
 	err := handler.GetCurrentUser(c)
+	require.Error(t, err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusInternalServerError, httpErr.Code)
+}
+
+func TestUserHandler_GetCurrentUser_IdentityScheme(t *testing.T) {
+	handler := NewUserHandler(zap.NewNop(), nil, true)
+	headers, err := auth.IdentityScheme("authelia")
 	require.NoError(t, err)
 
-	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.Contains(t, rec.Body.String(), `"email":"test@example.com"`)
+	e := echo.New()
+	e.Use(IdentityMiddleware(headers))
+	e.GET("/api/v1/user/me", handler.GetCurrentUser)
+
+	// OAuth2 Proxy headers don't authenticate behind another proxy
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/user/me", nil)
+	req.Header.Set("X-Auth-Request-Email", "test@example.com")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// The email is found, then the token must be validated
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/user/me", nil)
+	req.Header.Set("Remote-Email", "test@example.com")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 }
@@ -2,11 +2,14 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/invulnerable/backend/internal/compliance"
 	"github.com/invulnerable/backend/internal/db"
 	"github.com/invulnerable/backend/internal/models"
 	"github.com/invulnerable/backend/internal/notifier"
@@ -14,11 +17,18 @@ import (
 	"go.uber.org/zap"
 )
 
+// maxBatchGetIDs bounds POST /vulnerabilities/batch-get, enough for a full multi-select page
+const maxBatchGetIDs = 500
+
 type VulnerabilityHandler struct {
 	logger            *zap.Logger
 	vulnRepo          *db.VulnerabilityRepository
 	notifier          *notifier.Notifier
 	webhookConfigRepo *db.WebhookConfigRepository
+
+	// Compliance profiles tagged on listed vulnerabilities, see SetCompliance
+	profiles      []compliance.Profile
+	imageScanRepo *db.ImageScanRepository
 }
 
 func NewVulnerabilityHandler(
@@ -35,98 +45,302 @@ func NewVulnerabilityHandler(
 	}
 }
 
-// getUserFromHeaders extracts user identity from OAuth2 Proxy headers for audit trails
+// SetCompliance tags listed vulnerabilities with the compliance profiles applying to them, selected
+// by the labels of the ImageScans of their image
+func (h *VulnerabilityHandler) SetCompliance(profiles []compliance.Profile, imageScanRepo *db.ImageScanRepository) {
+	h.profiles = profiles
+	h.imageScanRepo = imageScanRepo
+}
+
+// tagFrameworks sets the compliance profiles covering each vulnerability on its image
+func (h *VulnerabilityHandler) tagFrameworks(ctx context.Context, vulns []models.VulnerabilityWithImageInfo) error {
+	if len(h.profiles) == 0 || len(vulns) == 0 {
+		return nil
+	}
+	imageIDs := make([]int, 0, len(vulns))
+	for _, v := range vulns {
+		imageIDs = append(imageIDs, v.ImageID)
+	}
+	labels, err := h.imageScanRepo.ListLabelsByImage(ctx, imageIDs)
+	if err != nil {
+		return err
+	}
+	for i := range vulns {
+		vulns[i].Frameworks = compliance.Applicable(h.profiles, labels[vulns[i].ImageID], vulns[i].Severity)
+	}
+	return nil
+}
+
+// getUserFromHeaders extracts user identity from the proxy's identity headers for audit trails
 // NOTE: This function is for audit logging only, NOT for authentication/authorization.
 // For authentication, use the access token validation pattern (see user.go GetCurrentUser).
 // It's safe to return "unknown" here since this is just for tracking who made changes.
 func getUserFromHeaders(c echo.Context) string {
-	// Try X-Auth-Request-Email first (more specific)
-	if email := c.Request().Header.Get("X-Auth-Request-Email"); email != "" {
-		return email
+	id := requestIdentity(c)
+	// Try the email first (more specific)
+	if id.Email != "" {
+		return id.Email
 	}
-	// Fall back to X-Auth-Request-User
-	if user := c.Request().Header.Get("X-Auth-Request-User"); user != "" {
-		return user
+	// Fall back to the user name
+	if id.User != "" {
+		return id.User
 	}
-	// When OAuth2 Proxy is not deployed, return "unknown" (not an error)
+	// When no authenticating proxy is deployed, return "unknown" (not an error)
 	return "unknown"
 }
 
 // ListVulnerabilities handles GET /api/v1/vulnerabilities
 // Returns vulnerabilities with image context for compliance tracking
 func (h *VulnerabilityHandler) ListVulnerabilities(c echo.Context) error {
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit <= 0 || limit > 100 {
-		limit = 100
+	limit, offset := parsePagination(c, 100)
+	f, err := parseVulnerabilityFilters(c)
+	if err != nil {
+		return err
+	}
+	if f.asOf != nil {
+		return h.listVulnerabilitiesAsOf(c, *f.asOf, limit, offset, f.sort, f.severity, f.status, f.imageID, f.imageName, f.cveID)
+	}
+
+	// Get total count
+	total, err := h.vulnRepo.CountWithImageInfo(c.Request().Context(), f.severity, f.status, f.hasFix, f.imageID, f.imageName, f.cveID, f.flapping, f.exposed)
+	if err != nil {
+		h.logger.Error("failed to count vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to count vulnerabilities")
 	}
 
-	offset, _ := strconv.Atoi(c.QueryParam("offset"))
-	if offset < 0 {
-		offset = 0
+	// Use ListWithImageInfo to get vulnerability+image combinations for compliance
+	vulns, err := h.vulnRepo.ListWithImageInfo(c.Request().Context(), limit, offset, f.sort, f.severity, f.status, f.hasFix, f.imageID, f.imageName, f.cveID, f.flapping, f.exposed)
+	if err != nil {
+		h.logger.Error("failed to list vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list vulnerabilities")
+	}
+	if err := h.tagFrameworks(c.Request().Context(), vulns); err != nil {
+		h.logger.Error("failed to tag compliance frameworks", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list vulnerabilities")
 	}
 
-	var severity, status *string
+	response := newPage(vulns, total, limit, offset)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// vulnerabilityFilters are the filters of the vulnerability+image list, shared with its export
+type vulnerabilityFilters struct {
+	sort              db.Sort
+	severity, status  *string
+	hasFix            *bool
+	imageID           *int
+	imageName, cveID  *string
+	flapping, exposed *bool
+	// asOf lists the findings of the latest scans at the time instead, with the status they had
+	asOf *time.Time
+}
+
+// parseVulnerabilityFilters parses the filters of GET /api/v1/vulnerabilities
+func parseVulnerabilityFilters(c echo.Context) (*vulnerabilityFilters, error) {
+	sort, err := parseSort(c, db.ValidateVulnerabilitySort)
+	if err != nil {
+		return nil, err
+	}
+	f := &vulnerabilityFilters{sort: sort}
 	if s := c.QueryParam("severity"); s != "" {
-		severity = &s
+		f.severity = &s
 	}
 	if st := c.QueryParam("status"); st != "" {
-		status = &st
+		f.status = &st
 	}
 
 	// Parse has_fix parameter
-	var hasFix *bool
 	if hasFixStr := c.QueryParam("has_fix"); hasFixStr != "" {
 		hasFixBool, err := strconv.ParseBool(hasFixStr)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid has_fix parameter")
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid has_fix parameter")
 		}
-		hasFix = &hasFixBool
+		f.hasFix = &hasFixBool
 	}
 
 	// Parse image_id parameter for filtering by image
-	var imageID *int
 	if imageIDStr := c.QueryParam("image_id"); imageIDStr != "" {
 		id, err := strconv.Atoi(imageIDStr)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid image_id parameter")
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid image_id parameter")
 		}
-		imageID = &id
+		f.imageID = &id
 	}
 
 	// Parse image_name parameter for filtering by image name
-	var imageName *string
 	if imageNameStr := c.QueryParam("image_name"); imageNameStr != "" {
-		imageName = &imageNameStr
+		f.imageName = &imageNameStr
 	}
 
 	// Parse cve_id parameter for filtering by specific CVE
-	var cveID *string
 	if cveIDStr := c.QueryParam("cve_id"); cveIDStr != "" {
-		cveID = &cveIDStr
+		f.cveID = &cveIDStr
 	}
 
-	// Get total count
-	total, err := h.vulnRepo.CountWithImageInfo(c.Request().Context(), severity, status, hasFix, imageID, imageName, cveID)
+	// Parse flapping parameter for investigating findings that come and go across scans
+	if flappingStr := c.QueryParam("flapping"); flappingStr != "" {
+		flappingBool, err := strconv.ParseBool(flappingStr)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid flapping parameter")
+		}
+		f.flapping = &flappingBool
+	}
+
+	// Parse exposed parameter for findings on images run by internet-exposed workloads
+	if exposedStr := c.QueryParam("exposed"); exposedStr != "" {
+		exposedBool, err := strconv.ParseBool(exposedStr)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid exposed parameter")
+		}
+		f.exposed = &exposedBool
+	}
+
+	asOf, err := parseAsOf(c)
 	if err != nil {
-		h.logger.Error("failed to count vulnerabilities", zap.Error(err))
+		return nil, err
+	}
+	// Fix versions, flaps and deployments are only known as they are now
+	if asOf != nil && (f.hasFix != nil || f.flapping != nil || f.exposed != nil) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "as_of can't be combined with has_fix, flapping or exposed")
+	}
+	f.asOf = asOf
+	return f, nil
+}
+
+// listVulnerabilitiesAsOf handles GET /api/v1/vulnerabilities?as_of=<date>
+// It reconstructs the findings of the latest scans at the time, with the status they had
+func (h *VulnerabilityHandler) listVulnerabilitiesAsOf(c echo.Context, asOf time.Time, limit, offset int, sort db.Sort, severity, status *string, imageID *int, imageName, cveID *string) error {
+	ctx := c.Request().Context()
+	total, err := h.vulnRepo.CountWithImageInfoAsOf(ctx, asOf, severity, status, imageID, imageName, cveID)
+	if err != nil {
+		h.logger.Error("failed to count vulnerabilities", zap.Error(err), zap.Time("as_of", asOf))
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to count vulnerabilities")
 	}
 
-	// Use ListWithImageInfo to get vulnerability+image combinations for compliance
-	vulns, err := h.vulnRepo.ListWithImageInfo(c.Request().Context(), limit, offset, severity, status, hasFix, imageID, imageName, cveID)
+	vulns, err := h.vulnRepo.ListWithImageInfoAsOf(ctx, asOf, limit, offset, sort, severity, status, imageID, imageName, cveID)
 	if err != nil {
-		h.logger.Error("failed to list vulnerabilities", zap.Error(err))
+		h.logger.Error("failed to list vulnerabilities", zap.Error(err), zap.Time("as_of", asOf))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list vulnerabilities")
+	}
+	if err := h.tagFrameworks(ctx, vulns); err != nil {
+		h.logger.Error("failed to tag compliance frameworks", zap.Error(err), zap.Time("as_of", asOf))
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list vulnerabilities")
 	}
 
-	response := map[string]interface{}{
-		"data":   vulns,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
+	return c.JSON(http.StatusOK, pageAsOf{Page: newPage(vulns, total, limit, offset), AsOf: asOf})
+}
+
+// parseAsOf parses the as_of parameter of point-in-time queries, an RFC 3339 time or a date (YYYY-MM-DD)
+// standing for the end of that day in UTC
+func parseAsOf(c echo.Context) (*time.Time, error) {
+	return parseTimeParam(c, "as_of", true)
+}
+
+// parseTimeParam parses a query parameter holding an RFC 3339 time or a date (YYYY-MM-DD), standing
+// for the start of that day in UTC or its end with endOfDay
+func parseTimeParam(c echo.Context, name string, endOfDay bool) (*time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
 	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s parameter, expected YYYY-MM-DD or an RFC 3339 time", name))
+	}
+	if endOfDay {
+		// Postgres keeps microseconds
+		day = day.AddDate(0, 0, 1).Add(-time.Microsecond)
+	}
+	return &day, nil
+}
 
-	return c.JSON(http.StatusOK, response)
+// SearchVulnerabilities handles GET /api/v1/vulnerabilities/search
+// Unlike ListVulnerabilities it returns each vulnerability once, whatever the images it was found on
+func (h *VulnerabilityHandler) SearchVulnerabilities(c echo.Context) error {
+	limit, offset := parsePagination(c, 100)
+	search, err := parseVulnerabilitySearch(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	total, err := h.vulnRepo.CountSearch(ctx, search)
+	if err != nil {
+		h.logger.Error("failed to count vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to search vulnerabilities")
+	}
+	vulns, err := h.vulnRepo.Search(ctx, search, limit, offset)
+	if err != nil {
+		h.logger.Error("failed to search vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to search vulnerabilities")
+	}
+
+	return c.JSON(http.StatusOK, newPage(vulns, total, limit, offset))
+}
+
+// searchSeverities are the severities of vulnerabilities from the lowest, indexed by severityRank
+var searchSeverities = []string{"Unknown", "Negligible", "Low", "Medium", "High", "Critical"}
+
+// parseVulnerabilitySearch parses the criteria of GET /api/v1/vulnerabilities/search
+func parseVulnerabilitySearch(c echo.Context) (*models.VulnerabilitySearch, error) {
+	search := &models.VulnerabilitySearch{}
+	optional := func(name string) *string {
+		if value := strings.TrimSpace(c.QueryParam(name)); value != "" {
+			return &value
+		}
+		return nil
+	}
+	search.PackageName = optional("package")
+	search.CVEPrefix = optional("cve")
+	search.Text = optional("q")
+	search.Status = optional("status")
+
+	if hasFixStr := c.QueryParam("has_fix"); hasFixStr != "" {
+		hasFix, err := strconv.ParseBool(hasFixStr)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid has_fix parameter")
+		}
+		search.HasFix = &hasFix
+	}
+
+	// A severity range, both ends included
+	lowest, highest := 0, len(searchSeverities)-1
+	for _, bound := range []struct {
+		name string
+		rank *int
+	}{{"min_severity", &lowest}, {"max_severity", &highest}} {
+		value := c.QueryParam(bound.name)
+		if value == "" {
+			continue
+		}
+		severity := normalizeSeverity(value)
+		if severity == "Unknown" && !strings.EqualFold(value, "unknown") {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s parameter", bound.name))
+		}
+		*bound.rank = severityRank(severity)
+	}
+	if lowest > highest {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "min_severity is above max_severity")
+	}
+	if lowest > 0 || highest < len(searchSeverities)-1 {
+		search.Severities = searchSeverities[lowest : highest+1]
+	}
+
+	var err error
+	if search.DetectedAfter, err = parseTimeParam(c, "detected_after", false); err != nil {
+		return nil, err
+	}
+	if search.DetectedBefore, err = parseTimeParam(c, "detected_before", true); err != nil {
+		return nil, err
+	}
+	if search.DetectedAfter != nil && search.DetectedBefore != nil && search.DetectedAfter.After(*search.DetectedBefore) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "detected_after is after detected_before")
+	}
+	return search, nil
 }
 
 // GetVulnerabilityByCVE handles GET /api/v1/vulnerabilities/:cve
@@ -169,7 +383,14 @@ func (h *VulnerabilityHandler) UpdateVulnerability(c echo.Context) error {
 		UpdatedBy: updatedBy,
 	}
 
-	if err := h.vulnRepo.Update(c.Request().Context(), id, updateWithContext); err != nil {
+	// The status change webhook is sent by the outbox dispatcher (if applicable)
+	event, err := models.NewOutboxEvent(models.OutboxKindStatusChange, statusChangeEvent{VulnerabilityID: id, ChangedBy: updatedBy})
+	if err != nil {
+		h.logger.Error("failed to create status change notification", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update vulnerability")
+	}
+
+	if err := h.vulnRepo.Update(c.Request().Context(), id, updateWithContext, event); err != nil {
 		h.logger.Error("failed to update vulnerability", zap.Error(err))
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update vulnerability")
 	}
@@ -181,9 +402,6 @@ func (h *VulnerabilityHandler) UpdateVulnerability(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get vulnerability")
 	}
 
-	// Send status change webhook notification in background (if applicable)
-	go h.sendStatusChangeWebhook(context.Background(), id, updatedBy)
-
 	return c.JSON(http.StatusOK, vuln)
 }
 
@@ -212,14 +430,20 @@ func (h *VulnerabilityHandler) BulkUpdateVulnerabilities(c echo.Context) error {
 		UpdatedBy: updatedBy,
 	}
 
-	if err := h.vulnRepo.BulkUpdate(c.Request().Context(), req.VulnerabilityIDs, updateWithContext); err != nil {
-		h.logger.Error("failed to bulk update vulnerabilities", zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update vulnerabilities")
+	// One status change webhook per vulnerability, sent by the outbox dispatcher
+	events := make([]*models.OutboxEvent, 0, len(req.VulnerabilityIDs))
+	for _, vulnID := range req.VulnerabilityIDs {
+		event, err := models.NewOutboxEvent(models.OutboxKindStatusChange, statusChangeEvent{VulnerabilityID: vulnID, ChangedBy: updatedBy})
+		if err != nil {
+			h.logger.Error("failed to create status change notification", zap.Error(err))
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to update vulnerabilities")
+		}
+		events = append(events, event)
 	}
 
-	// Send status change webhook notifications for each vulnerability in background
-	for _, vulnID := range req.VulnerabilityIDs {
-		go h.sendStatusChangeWebhook(context.Background(), vulnID, updatedBy)
+	if err := h.vulnRepo.BulkUpdate(c.Request().Context(), req.VulnerabilityIDs, updateWithContext, events...); err != nil {
+		h.logger.Error("failed to bulk update vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update vulnerabilities")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -228,6 +452,60 @@ func (h *VulnerabilityHandler) BulkUpdateVulnerabilities(c echo.Context) error {
 	})
 }
 
+// BatchGetVulnerabilities handles POST /api/v1/vulnerabilities/batch-get
+func (h *VulnerabilityHandler) BatchGetVulnerabilities(c echo.Context) error {
+	var req models.BatchGetRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("failed to bind request", zap.Error(err))
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	if len(req.IDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "no vulnerability IDs provided")
+	}
+
+	if len(req.IDs) > maxBatchGetIDs {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("cannot fetch more than %d vulnerabilities at once", maxBatchGetIDs))
+	}
+
+	vulns, err := h.vulnRepo.GetByIDs(c.Request().Context(), req.IDs)
+	if err != nil {
+		h.logger.Error("failed to batch get vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get vulnerabilities")
+	}
+
+	found := make(map[int]bool, len(vulns))
+	for _, v := range vulns {
+		found[v.ID] = true
+	}
+	missing := []int{}
+	for _, id := range req.IDs {
+		if !found[id] {
+			missing = append(missing, id)
+			found[id] = true // Report duplicates once
+		}
+	}
+
+	return c.JSON(http.StatusOK, models.BatchGetResponse{
+		Data:       vulns,
+		MissingIDs: missing,
+	})
+}
+
+// GetSeverityChanges handles GET /api/v1/vulnerabilities/:cve/severity-changes
+// It returns the severities scans reported for the CVE's vulnerabilities over time, latest first
+func (h *VulnerabilityHandler) GetSeverityChanges(c echo.Context) error {
+	cveID := c.Param("cve")
+
+	changes, err := h.vulnRepo.ListSeverityChanges(c.Request().Context(), cveID)
+	if err != nil {
+		h.logger.Error("failed to list severity changes", zap.Error(err), zap.String("cve_id", cveID))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list severity changes")
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
+
 // GetVulnerabilityHistory handles GET /api/v1/vulnerabilities/:id/history
 func (h *VulnerabilityHandler) GetVulnerabilityHistory(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -244,33 +522,26 @@ func (h *VulnerabilityHandler) GetVulnerabilityHistory(c echo.Context) error {
 	return c.JSON(http.StatusOK, history)
 }
 
-// sendStatusChangeWebhook sends webhook notification for vulnerability status changes
-// This runs in a background goroutine and logs errors without failing the request
-func (h *VulnerabilityHandler) sendStatusChangeWebhook(ctx context.Context, vulnID int, changedBy string) {
+// sendStatusChangeWebhook sends webhook notification for vulnerability status changes.
+// It returns nil when no webhook applies, and an error when the delivery should be retried
+func (h *VulnerabilityHandler) sendStatusChangeWebhook(ctx context.Context, vulnID int, changedBy string) error {
 	// Get ImageScan context for this vulnerability
 	imageScanCtx, err := h.vulnRepo.GetImageScanInfoForWebhook(ctx, vulnID)
 	if err != nil {
-		h.logger.Error("failed to get ImageScan info for webhook",
-			zap.Error(err),
-			zap.Int("vulnerability_id", vulnID))
-		return
+		return fmt.Errorf("failed to get ImageScan info for webhook: %w", err)
 	}
 
 	// If no ImageScan context, skip webhook (vulnerability wasn't discovered by an ImageScan)
 	if imageScanCtx == nil {
 		h.logger.Info("no ImageScan context for vulnerability, skipping webhook",
 			zap.Int("vulnerability_id", vulnID))
-		return
+		return nil
 	}
 
 	// Get webhook config from database
 	webhookConfig, err := h.webhookConfigRepo.Get(ctx, imageScanCtx.Namespace, imageScanCtx.Name)
 	if err != nil {
-		h.logger.Error("failed to get webhook config",
-			zap.Error(err),
-			zap.String("namespace", imageScanCtx.Namespace),
-			zap.String("name", imageScanCtx.Name))
-		return
+		return fmt.Errorf("failed to get webhook config of %s/%s: %w", imageScanCtx.Namespace, imageScanCtx.Name, err)
 	}
 
 	// If no config or status change webhooks not enabled, skip
@@ -280,25 +551,19 @@ func (h *VulnerabilityHandler) sendStatusChangeWebhook(ctx context.Context, vuln
 			zap.String("name", imageScanCtx.Name),
 			zap.Bool("config_exists", webhookConfig != nil),
 			zap.Bool("enabled", webhookConfig != nil && webhookConfig.StatusChangeEnabled))
-		return
+		return nil
 	}
 
 	// Get vulnerability details
 	vuln, err := h.vulnRepo.GetByID(ctx, vulnID)
 	if err != nil {
-		h.logger.Error("failed to get vulnerability for webhook",
-			zap.Error(err),
-			zap.Int("vulnerability_id", vulnID))
-		return
+		return fmt.Errorf("failed to get vulnerability for webhook: %w", err)
 	}
 
 	// Get history to find old status
 	history, err := h.vulnRepo.GetHistory(ctx, vulnID)
 	if err != nil {
-		h.logger.Error("failed to get vulnerability history for webhook",
-			zap.Error(err),
-			zap.Int("vulnerability_id", vulnID))
-		return
+		return fmt.Errorf("failed to get vulnerability history for webhook: %w", err)
 	}
 
 	// Find previous status from history - look for the most recent status change entry
@@ -346,6 +611,21 @@ func (h *VulnerabilityHandler) sendStatusChangeWebhook(ctx context.Context, vuln
 		Timestamp:       time.Now(),
 	}
 
+	// Open vulnerabilities are reminded of their deadline, in the ImageScan's timezone
+	if vuln.Status == models.StatusActive || vuln.Status == models.StatusInProgress {
+		deadline, err := h.vulnRepo.GetSLADeadline(ctx, vulnID)
+		if err != nil {
+			h.logger.Warn("could not compute SLA deadline for vulnerability",
+				zap.Int("vulnerability_id", vulnID),
+				zap.Error(err))
+		} else {
+			payload.SLADueDate = deadline.DueDate
+			payload.SLATimeZone = deadline.DueAt.Location().String()
+		}
+	}
+
+	payload.Advisories, payload.FixLinks = h.notificationLinks(ctx, vuln)
+
 	// Build webhook config for notifier
 	notifierConfig := notifier.StatusChangeWebhookConfig{
 		URL:                webhookConfig.WebhookURL,
@@ -354,6 +634,7 @@ func (h *VulnerabilityHandler) sendStatusChangeWebhook(ctx context.Context, vuln
 		OnlyFixable:        webhookConfig.StatusChangeOnlyFixable,
 		StatusTransitions:  webhookConfig.StatusChangeTransitions,
 		IncludeNoteChanges: webhookConfig.StatusChangeIncludeNotes,
+		Locale:             webhookConfig.Locale,
 	}
 
 	// Log what we're about to send
@@ -368,11 +649,7 @@ func (h *VulnerabilityHandler) sendStatusChangeWebhook(ctx context.Context, vuln
 
 	// Send notification (filtering happens in notifier)
 	if err := h.notifier.SendStatusChangeNotification(ctx, notifierConfig, payload); err != nil {
-		h.logger.Error("failed to send status change webhook",
-			zap.Error(err),
-			zap.Int("vulnerability_id", vulnID),
-			zap.String("webhook_url", webhookConfig.WebhookURL))
-		return
+		return fmt.Errorf("failed to send status change webhook: %w", err)
 	}
 
 	h.logger.Info("status change webhook sent successfully",
@@ -380,4 +657,35 @@ func (h *VulnerabilityHandler) sendStatusChangeWebhook(ctx context.Context, vuln
 		zap.String("cve_id", vuln.CVEID),
 		zap.String("old_status", oldStatus),
 		zap.String("new_status", vuln.Status))
+	return nil
+}
+
+// notificationLinks returns the advisories of a vulnerability and the links users attached to it on
+// any image. Links that can't be read are left out of the notification rather than delaying it
+func (h *VulnerabilityHandler) notificationLinks(ctx context.Context, vuln *models.Vulnerability) (advisories, fixLinks []notifier.Link) {
+	if len(vuln.Advisories) > 0 {
+		var reported []models.GrypeAdvisory
+		if err := json.Unmarshal(vuln.Advisories, &reported); err != nil {
+			h.logger.Warn("could not decode advisories of vulnerability", zap.Int("vulnerability_id", vuln.ID), zap.Error(err))
+		}
+		for _, a := range reported {
+			if a.Link != "" {
+				advisories = append(advisories, notifier.Link{Title: a.ID, URL: a.Link})
+			}
+		}
+	}
+
+	links, err := h.vulnRepo.ListLinks(ctx, vuln.ID, nil)
+	if err != nil {
+		h.logger.Warn("could not list links of vulnerability", zap.Int("vulnerability_id", vuln.ID), zap.Error(err))
+		return advisories, nil
+	}
+	for _, l := range links {
+		title := l.URL
+		if l.Title != nil {
+			title = *l.Title
+		}
+		fixLinks = append(fixLinks, notifier.Link{Title: title, URL: l.URL})
+	}
+	return advisories, fixLinks
 }
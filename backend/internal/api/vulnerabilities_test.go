@@ -0,0 +1,143 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBatchGetVulnerabilities_Validation(t *testing.T) {
+	handler := NewVulnerabilityHandler(zap.NewNop(), nil, nil, nil)
+
+	tooMany := make([]int, maxBatchGetIDs+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+
+	for name, ids := range map[string][]int{"empty": {}, "too many": tooMany} {
+		t.Run(name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.BatchGetVulnerabilities, http.MethodPost, "/api/v1/vulnerabilities/batch-get",
+				map[string]interface{}{"ids": ids}, "")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		})
+	}
+}
+
+func TestCreateVulnerabilityLink_Validation(t *testing.T) {
+	handler := NewVulnerabilityHandler(zap.NewNop(), nil, nil, nil)
+
+	long := strings.Repeat("x", maxLinkTitleLength+1)
+	tests := map[string]models.VulnerabilityLinkRequest{
+		"without image":  {URL: "https://git.example.com/acme/web/-/merge_requests/42"},
+		"without url":    {ImageID: 1},
+		"relative url":   {ImageID: 1, URL: "/acme/web/pull/42"},
+		"javascript url": {ImageID: 1, URL: "javascript:alert(1)"},
+		"long url":       {ImageID: 1, URL: "https://git.example.com/" + strings.Repeat("x", maxLinkURLLength)},
+		"long title":     {ImageID: 1, URL: "https://git.example.com/acme/web/pull/42", Title: &long},
+	}
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.CreateVulnerabilityLink, http.MethodPost, "/api/v1/vulnerabilities/:id/links", req, "1")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		})
+	}
+}
+
+func TestParseAsOf(t *testing.T) {
+	e := echo.New()
+	parse := func(query string) (*time.Time, error) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/vulnerabilities?"+query, nil)
+		return parseAsOf(e.NewContext(req, httptest.NewRecorder()))
+	}
+
+	asOf, err := parse("")
+	require.NoError(t, err)
+	assert.Nil(t, asOf)
+
+	// A date covers the whole day
+	asOf, err = parse("as_of=2024-06-01")
+	require.NoError(t, err)
+	require.NotNil(t, asOf)
+	assert.Equal(t, time.Date(2024, 6, 1, 23, 59, 59, 999999000, time.UTC), *asOf)
+
+	asOf, err = parse("as_of=2024-06-01T12:00:00%2B02:00")
+	require.NoError(t, err)
+	require.NotNil(t, asOf)
+	assert.True(t, asOf.Equal(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)))
+
+	_, err = parse("as_of=June")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+}
+
+func TestListVulnerabilities_AsOfWithCurrentOnlyFilters(t *testing.T) {
+	handler := NewVulnerabilityHandler(zap.NewNop(), nil, nil, nil)
+	e := echo.New()
+
+	for _, query := range []string{"as_of=2024-06-01&has_fix=true", "as_of=2024-06-01&flapping=true"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/vulnerabilities?"+query, nil)
+		err := handler.ListVulnerabilities(e.NewContext(req, httptest.NewRecorder()))
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, query)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, query)
+	}
+}
+
+func TestParseVulnerabilitySearch(t *testing.T) {
+	e := echo.New()
+	parse := func(query string) (*models.VulnerabilitySearch, error) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/vulnerabilities/search?"+query, nil)
+		return parseVulnerabilitySearch(e.NewContext(req, httptest.NewRecorder()))
+	}
+
+	search, err := parse("")
+	require.NoError(t, err)
+	assert.Equal(t, &models.VulnerabilitySearch{}, search)
+
+	search, err = parse("package=ssl&cve=CVE-2024-&q=remote+code&min_severity=medium&max_severity=HIGH&has_fix=true&detected_after=2024-06-01&detected_before=2024-06-30")
+	require.NoError(t, err)
+	require.NotNil(t, search.PackageName)
+	assert.Equal(t, "ssl", *search.PackageName)
+	require.NotNil(t, search.CVEPrefix)
+	assert.Equal(t, "CVE-2024-", *search.CVEPrefix)
+	require.NotNil(t, search.Text)
+	assert.Equal(t, "remote code", *search.Text)
+	assert.Equal(t, []string{"Medium", "High"}, search.Severities)
+	require.NotNil(t, search.HasFix)
+	assert.True(t, *search.HasFix)
+	require.NotNil(t, search.DetectedAfter)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), *search.DetectedAfter)
+	require.NotNil(t, search.DetectedBefore)
+	assert.Equal(t, time.Date(2024, 6, 30, 23, 59, 59, 999999000, time.UTC), *search.DetectedBefore)
+
+	// An open range leaves out the severities below
+	search, err = parse("min_severity=High")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"High", "Critical"}, search.Severities)
+
+	for _, query := range []string{
+		"min_severity=severe",
+		"min_severity=Critical&max_severity=Low",
+		"detected_after=yesterday",
+		"detected_after=2024-07-01&detected_before=2024-06-01",
+		"has_fix=maybe",
+	} {
+		_, err := parse(query)
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, query)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, query)
+	}
+}
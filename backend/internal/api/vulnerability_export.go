@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/invulnerable/backend/internal/export"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// exportChunkSize is the number of vulnerability+image combinations an export reads and writes at a time
+const exportChunkSize = 1000
+
+// ExportVulnerabilities handles GET /api/v1/vulnerabilities/export?format=csv|json
+// It streams every vulnerability+image combination matching the filters of ListVulnerabilities, without
+// its page limit. Rows are read and written a chunk at a time, so large exports aren't held in memory
+func (h *VulnerabilityHandler) ExportVulnerabilities(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	// The writer only writes once the headers below are sent
+	res := c.Response()
+	writer, err := export.NewVulnerabilityWriter(res, format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	f, err := parseVulnerabilityFilters(c)
+	if err != nil {
+		return err
+	}
+
+	// The first chunk is read before the status is sent, so that a failing query is still reported
+	ctx := c.Request().Context()
+	vulns, err := h.exportChunk(ctx, f, 0)
+	if err != nil {
+		h.logger.Error("failed to export vulnerabilities", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export vulnerabilities")
+	}
+
+	res.Header().Set(echo.HeaderContentType, export.ContentType(format))
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+export.FileName(format, time.Now())+`"`)
+	res.WriteHeader(http.StatusOK)
+
+	// The status is sent, failures can only be logged
+	for offset := 0; ; {
+		if err := writer.Write(vulns); err != nil {
+			h.logger.Warn("failed to send vulnerability export", zap.Error(err))
+			return nil
+		}
+		res.Flush()
+		if len(vulns) < exportChunkSize {
+			break
+		}
+		offset += len(vulns)
+		if vulns, err = h.exportChunk(ctx, f, offset); err != nil {
+			h.logger.Error("failed to export vulnerabilities", zap.Error(err), zap.Int("offset", offset))
+			return nil
+		}
+	}
+	if err := writer.Close(); err != nil {
+		h.logger.Warn("failed to send vulnerability export", zap.Error(err))
+	}
+	return nil
+}
+
+// exportChunk reads the chunk of an export starting at offset, tagged with its compliance frameworks
+func (h *VulnerabilityHandler) exportChunk(ctx context.Context, f *vulnerabilityFilters, offset int) ([]models.VulnerabilityWithImageInfo, error) {
+	var vulns []models.VulnerabilityWithImageInfo
+	var err error
+	if f.asOf != nil {
+		vulns, err = h.vulnRepo.ListWithImageInfoAsOf(ctx, *f.asOf, exportChunkSize, offset, f.sort, f.severity, f.status, f.imageID, f.imageName, f.cveID)
+	} else {
+		vulns, err = h.vulnRepo.ListWithImageInfo(ctx, exportChunkSize, offset, f.sort, f.severity, f.status, f.hasFix, f.imageID, f.imageName, f.cveID, f.flapping, f.exposed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := h.tagFrameworks(ctx, vulns); err != nil {
+		return nil, err
+	}
+	return vulns, nil
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/export"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestVulnerabilityHandler_ExportVulnerabilities(t *testing.T) {
+	scanHandler := newTestScanHandler(t)
+	handler := NewVulnerabilityHandler(zap.NewNop(), scanHandler.vulnRepo, nil, nil)
+
+	for image, cves := range map[string][]string{
+		"acme/api:1.0":    {"CVE-2024-0001", "CVE-2024-0002"},
+		"acme/worker:1.0": {"CVE-2024-0001"},
+		"other/cache:7.2": {"CVE-2023-0003"},
+	} {
+		matches := []map[string]interface{}{}
+		for _, cve := range cves {
+			matches = append(matches, map[string]interface{}{
+				"vulnerability": map[string]interface{}{"id": cve, "severity": "High"},
+				"artifact": map[string]interface{}{"name": "openssl", "version": "3.0.11-1", "type": "deb",
+					"purl": "pkg:deb/debian/openssl@3.0.11-1"},
+			})
+		}
+		rec, err := doScanRequest(t, scanHandler.CreateScan, http.MethodPost, "/api/v1/scans", map[string]interface{}{
+			"image":        image,
+			"grype_result": map[string]interface{}{"matches": matches},
+		}, "")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	// Every vulnerability+image combination, without the page limit
+	rec, err := doScanRequest(t, handler.ExportVulnerabilities, http.MethodGet, "/api/v1/vulnerabilities/export?limit=1", nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "attachment; filename=\"vulnerabilities-")
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, export.VulnerabilityColumns, records[0])
+	purl := slices.Index(records[0], "purl")
+	require.NotEqual(t, -1, purl)
+	for _, record := range records[1:] {
+		assert.Equal(t, "pkg:deb/debian/openssl@3.0.11-1", record[purl])
+	}
+
+	// With the filters of the list
+	rec, err = doScanRequest(t, handler.ExportVulnerabilities, http.MethodGet,
+		"/api/v1/vulnerabilities/export?format=json&image_name=acme/&cve_id=CVE-2024-0001", nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	var exported []models.VulnerabilityWithImageInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &exported))
+	require.Len(t, exported, 2)
+	for _, v := range exported {
+		assert.Equal(t, "CVE-2024-0001", v.CVEID)
+		assert.True(t, strings.HasPrefix(v.ImageName, "docker.io/acme/"), v.ImageName)
+		require.NotNil(t, v.PURL)
+		assert.Equal(t, "pkg:deb/debian/openssl@3.0.11-1", *v.PURL)
+	}
+
+	// Nothing matching is an empty document
+	rec, err = doScanRequest(t, handler.ExportVulnerabilities, http.MethodGet,
+		"/api/v1/vulnerabilities/export?format=json&severity=Critical", nil, "")
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+}
+
+func TestExportVulnerabilities_Validation(t *testing.T) {
+	handler := NewVulnerabilityHandler(zap.NewNop(), nil, nil, nil)
+	e := echo.New()
+
+	for _, query := range []string{"format=xlsx", "has_fix=maybe", "image_id=web", "as_of=2024-06-01&exposed=true", "sort=cvss", "sort=severity&order=up"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/vulnerabilities/export?"+query, nil)
+		rec := httptest.NewRecorder()
+		err := handler.ExportVulnerabilities(e.NewContext(req, rec))
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, query)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, query)
+		// Rejected before anything is written
+		assert.Empty(t, rec.Body.String(), query)
+	}
+}
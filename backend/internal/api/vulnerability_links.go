@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Bounds of the links attached to vulnerabilities, the title matches the vulnerability_links.title column
+const (
+	maxLinkURLLength   = 2048
+	maxLinkTitleLength = 255
+)
+
+// ListVulnerabilityLinks handles GET /api/v1/vulnerabilities/:id/links?image_id=1
+func (h *VulnerabilityHandler) ListVulnerabilityLinks(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid vulnerability ID")
+	}
+	var imageID *int
+	if value := c.QueryParam("image_id"); value != "" {
+		image, err := strconv.Atoi(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid image_id parameter")
+		}
+		imageID = &image
+	}
+
+	links, err := h.vulnRepo.ListLinks(c.Request().Context(), id, imageID)
+	if err != nil {
+		h.logger.Error("failed to list vulnerability links", zap.Error(err), zap.Int("vulnerability_id", id))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list links")
+	}
+
+	return c.JSON(http.StatusOK, links)
+}
+
+// CreateVulnerabilityLink handles POST /api/v1/vulnerabilities/:id/links
+// The link, such as the MR/PR fixing the vulnerability, is attached to the vulnerability on an image
+func (h *VulnerabilityHandler) CreateVulnerabilityLink(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid vulnerability ID")
+	}
+	var req models.VulnerabilityLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	if req.ImageID <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "image_id is required")
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if err := validateLinkURL(req.URL); err != nil {
+		return err
+	}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		req.Title = nil
+		if len(title) > maxLinkTitleLength {
+			return echo.NewHTTPError(http.StatusBadRequest, "title must be at most 255 characters")
+		}
+		if title != "" {
+			req.Title = &title
+		}
+	}
+
+	user := getUserFromHeaders(c)
+	link := &models.VulnerabilityLink{
+		VulnerabilityID: id,
+		ImageID:         req.ImageID,
+		URL:             req.URL,
+		Title:           req.Title,
+		CreatedBy:       &user,
+	}
+	if err := h.vulnRepo.CreateLink(c.Request().Context(), link); err != nil {
+		return err
+	}
+
+	h.logger.Info("attached link to vulnerability",
+		zap.Int("link_id", link.ID),
+		zap.Int("vulnerability_id", link.VulnerabilityID),
+		zap.Int("image_id", link.ImageID),
+		zap.String("user", user))
+
+	return c.JSON(http.StatusCreated, link)
+}
+
+// DeleteVulnerabilityLink handles DELETE /api/v1/vulnerabilities/:id/links/:link_id
+func (h *VulnerabilityHandler) DeleteVulnerabilityLink(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid vulnerability ID")
+	}
+	linkID, err := strconv.Atoi(c.Param("link_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid link ID")
+	}
+
+	if err := h.vulnRepo.DeleteLink(c.Request().Context(), id, linkID, getUserFromHeaders(c)); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// validateLinkURL accepts absolute http and https URLs, the ones the UI and notifications can open
+func validateLinkURL(link string) error {
+	if link == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "url is required")
+	}
+	if len(link) > maxLinkURLLength {
+		return echo.NewHTTPError(http.StatusBadRequest, "url must be at most 2048 characters")
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "url must be an http or https URL")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxWaiverDuration bounds how far in the future a waiver can expire, so every accepted risk is reassessed
+const maxWaiverDuration = 366 * 24 * time.Hour
+
+// WaiverHandler manages the waivers of vulnerabilities on images
+type WaiverHandler struct {
+	logger *zap.Logger
+	repo   *db.WaiverRepository
+}
+
+// NewWaiverHandler creates a waiver handler
+func NewWaiverHandler(logger *zap.Logger, repo *db.WaiverRepository) *WaiverHandler {
+	return &WaiverHandler{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// ListWaivers handles GET /api/v1/waivers?image_id=1&vulnerability_id=2&expired=true
+// Without expired, only the waivers in effect are listed
+func (h *WaiverHandler) ListWaivers(c echo.Context) error {
+	var filter models.WaiverFilter
+	for name, target := range map[string]**int{"image_id": &filter.ImageID, "vulnerability_id": &filter.VulnerabilityID} {
+		if value := c.QueryParam(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid "+name+" parameter")
+			}
+			*target = &id
+		}
+	}
+	if value := c.QueryParam("expired"); value != "" {
+		expired, err := strconv.ParseBool(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid expired parameter")
+		}
+		filter.Expired = expired
+	}
+
+	waivers, err := h.repo.List(c.Request().Context(), &filter)
+	if err != nil {
+		h.logger.Error("failed to list waivers", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list waivers")
+	}
+
+	return c.JSON(http.StatusOK, waivers)
+}
+
+// CreateWaiver handles POST /api/v1/waivers
+// The vulnerability is accepted until the waiver expires, then set back to active
+func (h *WaiverHandler) CreateWaiver(c echo.Context) error {
+	var req models.WaiverRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	if req.VulnerabilityID <= 0 || req.ImageID <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "vulnerability_id and image_id are required")
+	}
+	req.Justification = strings.TrimSpace(req.Justification)
+	if req.Justification == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "justification is required")
+	}
+	if req.ExpiresAt == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "expires_at is required")
+	}
+	now := time.Now()
+	if !req.ExpiresAt.After(now) {
+		return echo.NewHTTPError(http.StatusBadRequest, "expires_at must be in the future")
+	}
+	if req.ExpiresAt.After(now.Add(maxWaiverDuration)) {
+		return echo.NewHTTPError(http.StatusBadRequest, "expires_at must be within a year")
+	}
+
+	user := getUserFromHeaders(c)
+	waiver := &models.Waiver{
+		VulnerabilityID: req.VulnerabilityID,
+		ImageID:         req.ImageID,
+		Justification:   req.Justification,
+		ExpiresAt:       *req.ExpiresAt,
+		CreatedBy:       &user,
+	}
+	if err := h.repo.Create(c.Request().Context(), waiver); err != nil {
+		return err
+	}
+
+	h.logger.Info("created waiver",
+		zap.Int("waiver_id", waiver.ID),
+		zap.Int("vulnerability_id", waiver.VulnerabilityID),
+		zap.Int("image_id", waiver.ImageID),
+		zap.Time("expires_at", waiver.ExpiresAt),
+		zap.String("user", user))
+
+	return c.JSON(http.StatusCreated, waiver)
+}
+
+// GetWaiver handles GET /api/v1/waivers/:id
+func (h *WaiverHandler) GetWaiver(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid waiver ID")
+	}
+
+	waiver, err := h.repo.GetByID(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, waiver)
+}
+
+// DeleteWaiver handles DELETE /api/v1/waivers/:id
+// The vulnerability keeps its status, set it back to active to reopen it
+func (h *WaiverHandler) DeleteWaiver(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid waiver ID")
+	}
+
+	if err := h.repo.Delete(c.Request().Context(), id); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWaiverHandler_CreateWaiver_Validation(t *testing.T) {
+	// Invalid requests are rejected before reaching the repository
+	handler := NewWaiverHandler(zap.NewNop(), nil)
+
+	nextWeek := time.Now().AddDate(0, 0, 7)
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want string
+	}{
+		{"missing image", map[string]interface{}{"vulnerability_id": 1, "justification": "not reachable", "expires_at": nextWeek}, "image_id"},
+		{"missing justification", map[string]interface{}{"vulnerability_id": 1, "image_id": 2, "justification": " ", "expires_at": nextWeek}, "justification"},
+		{"missing expiry", map[string]interface{}{"vulnerability_id": 1, "image_id": 2, "justification": "not reachable"}, "expires_at is required"},
+		{"past expiry", map[string]interface{}{"vulnerability_id": 1, "image_id": 2, "justification": "not reachable", "expires_at": time.Now().Add(-time.Hour)}, "future"},
+		{"distant expiry", map[string]interface{}{"vulnerability_id": 1, "image_id": 2, "justification": "not reachable", "expires_at": time.Now().AddDate(2, 0, 0)}, "within a year"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.CreateWaiver, http.MethodPost, "/api/v1/waivers", tt.body, "")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.True(t, strings.Contains(httpErr.Message.(string), tt.want), httpErr.Message)
+		})
+	}
+}
+
+func TestWaiverHandler_ListWaivers_Validation(t *testing.T) {
+	handler := NewWaiverHandler(zap.NewNop(), nil)
+
+	for _, query := range []string{"image_id=web", "vulnerability_id=x", "expired=maybe"} {
+		_, err := doScanRequest(t, handler.ListWaivers, http.MethodGet, "/api/v1/waivers?"+query, nil, "")
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, query)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, query)
+	}
+}
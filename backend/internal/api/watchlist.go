@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// WatchlistHandler manages the CVE and package subscriptions of the current user
+type WatchlistHandler struct {
+	logger *zap.Logger
+	repo   *db.WatchlistRepository
+	policy *notifier.DestinationPolicy
+}
+
+// NewWatchlistHandler creates a watchlist handler. Webhook URLs are checked against policy when it is set
+func NewWatchlistHandler(logger *zap.Logger, repo *db.WatchlistRepository, policy *notifier.DestinationPolicy) *WatchlistHandler {
+	return &WatchlistHandler{
+		logger: logger,
+		repo:   repo,
+		policy: policy,
+	}
+}
+
+// ListWatchlist handles GET /api/v1/watchlist
+func (h *WatchlistHandler) ListWatchlist(c echo.Context) error {
+	subs, err := h.repo.ListBySubscriber(c.Request().Context(), getUserFromHeaders(c))
+	if err != nil {
+		h.logger.Error("failed to list watchlist subscriptions", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list watchlist subscriptions")
+	}
+
+	return c.JSON(http.StatusOK, subs)
+}
+
+// CreateWatchlistSubscription handles POST /api/v1/watchlist
+func (h *WatchlistHandler) CreateWatchlistSubscription(c echo.Context) error {
+	var req models.WatchlistSubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	req.CVEID = trimmedOrNil(req.CVEID)
+	req.PackageName = trimmedOrNil(req.PackageName)
+	if req.CVEID == nil && req.PackageName == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "cve_id or package_name is required")
+	}
+	if u, err := url.Parse(req.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "webhook_url must be an http(s) URL")
+	}
+	if h.policy != nil {
+		if err := h.policy.CheckURL(req.WebhookURL); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	if req.WebhookFormat == "" {
+		req.WebhookFormat = "slack"
+	}
+	if req.WebhookFormat != "slack" && req.WebhookFormat != "teams" {
+		return echo.NewHTTPError(http.StatusBadRequest, "webhook_format must be slack or teams")
+	}
+	if req.Locale == "" {
+		req.Locale = notifier.DefaultLocale
+	}
+	if !notifier.IsSupportedLocale(req.Locale) {
+		return echo.NewHTTPError(http.StatusBadRequest, "locale must be one of "+strings.Join(notifier.Locales, ", "))
+	}
+
+	sub := &models.WatchlistSubscription{
+		CVEID:         req.CVEID,
+		PackageName:   req.PackageName,
+		Subscriber:    getUserFromHeaders(c),
+		WebhookURL:    req.WebhookURL,
+		WebhookFormat: req.WebhookFormat,
+		Locale:        req.Locale,
+	}
+	if err := h.repo.Upsert(c.Request().Context(), sub); err != nil {
+		h.logger.Error("failed to create watchlist subscription", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create watchlist subscription")
+	}
+
+	return c.JSON(http.StatusCreated, sub)
+}
+
+// DeleteWatchlistSubscription handles DELETE /api/v1/watchlist/:id
+// Users can only remove their own subscriptions
+func (h *WatchlistHandler) DeleteWatchlistSubscription(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid watchlist subscription ID")
+	}
+
+	if err := h.repo.Delete(c.Request().Context(), id, getUserFromHeaders(c)); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func trimmedOrNil(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*s)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/invulnerable/backend/internal/notifier"
+)
+
+func TestWatchlistHandler_CreateWatchlistSubscription_Validation(t *testing.T) {
+	// Invalid requests are rejected before reaching the repository
+	handler := NewWatchlistHandler(zap.NewNop(), nil, nil)
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want string
+	}{
+		{"no criteria", map[string]interface{}{"cve_id": " ", "webhook_url": "https://hooks.slack.com/x"}, "cve_id or package_name"},
+		{"missing webhook", map[string]interface{}{"cve_id": "CVE-2024-3094"}, "webhook_url"},
+		{"not http", map[string]interface{}{"cve_id": "CVE-2024-3094", "webhook_url": "file:///etc/passwd"}, "webhook_url"},
+		{"unknown format", map[string]interface{}{"package_name": "xz-utils", "webhook_url": "https://example.com/hook", "webhook_format": "discord"}, "webhook_format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := doScanRequest(t, handler.CreateWatchlistSubscription, http.MethodPost, "/api/v1/watchlist", tt.body, "")
+			var httpErr *echo.HTTPError
+			require.ErrorAs(t, err, &httpErr)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.True(t, strings.Contains(httpErr.Message.(string), tt.want), httpErr.Message)
+		})
+	}
+}
+
+func TestWatchlistHandler_CreateWatchlistSubscription_DeniedDestination(t *testing.T) {
+	handler := NewWatchlistHandler(zap.NewNop(), nil, notifier.DefaultDestinationPolicy())
+
+	for _, url := range []string{"http://169.254.169.254/latest/meta-data/", "http://localhost:9090/", "http://10.0.0.5/hook"} {
+		body := map[string]interface{}{"cve_id": "CVE-2024-3094", "webhook_url": url}
+		_, err := doScanRequest(t, handler.CreateWatchlistSubscription, http.MethodPost, "/api/v1/watchlist", body, "")
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr, url)
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code, url)
+	}
+}
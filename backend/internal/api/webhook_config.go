@@ -2,9 +2,11 @@ package api
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/invulnerable/backend/internal/db"
 	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
@@ -13,13 +15,16 @@ import (
 type WebhookConfigHandler struct {
 	repo   *db.WebhookConfigRepository
 	logger *zap.Logger
+	policy *notifier.DestinationPolicy
 }
 
-// NewWebhookConfigHandler creates a new webhook config handler
-func NewWebhookConfigHandler(repo *db.WebhookConfigRepository, logger *zap.Logger) *WebhookConfigHandler {
+// NewWebhookConfigHandler creates a new webhook config handler. Webhook URLs are checked
+// against policy when it is set
+func NewWebhookConfigHandler(repo *db.WebhookConfigRepository, logger *zap.Logger, policy *notifier.DestinationPolicy) *WebhookConfigHandler {
 	return &WebhookConfigHandler{
 		repo:   repo,
 		logger: logger,
+		policy: policy,
 	}
 }
 
@@ -45,6 +50,11 @@ func (h *WebhookConfigHandler) UpsertWebhookConfig(c echo.Context) error {
 	if req.WebhookURL == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "webhook_url is required")
 	}
+	if h.policy != nil {
+		if err := h.policy.CheckURL(req.WebhookURL); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
 
 	// Set defaults
 	if req.WebhookFormat == "" {
@@ -59,6 +69,15 @@ func (h *WebhookConfigHandler) UpsertWebhookConfig(c echo.Context) error {
 	if req.StatusChangeTransitions == nil {
 		req.StatusChangeTransitions = []string{}
 	}
+	if req.FixAvailableMinSeverity == "" {
+		req.FixAvailableMinSeverity = "High"
+	}
+	if req.Locale == "" {
+		req.Locale = notifier.DefaultLocale
+	}
+	if !notifier.IsSupportedLocale(req.Locale) {
+		return echo.NewHTTPError(http.StatusBadRequest, "locale must be one of "+strings.Join(notifier.Locales, ", "))
+	}
 
 	err := h.repo.Upsert(c.Request().Context(), namespace, name, &req)
 	if err != nil {
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// WorkerStatusSource reports the background workers, implemented by worker.Registry
+type WorkerStatusSource interface {
+	Status(ctx context.Context) ([]models.WorkerStatus, error)
+}
+
+// WorkerHandler exposes the status of the periodic background workers
+type WorkerHandler struct {
+	logger  *zap.Logger
+	workers WorkerStatusSource
+}
+
+func NewWorkerHandler(logger *zap.Logger, workers WorkerStatusSource) *WorkerHandler {
+	return &WorkerHandler{
+		logger:  logger,
+		workers: workers,
+	}
+}
+
+// ListWorkers handles GET /api/v1/admin/workers
+func (h *WorkerHandler) ListWorkers(c echo.Context) error {
+	statuses, err := h.workers.Status(c.Request().Context())
+	if err != nil {
+		h.logger.Error("failed to get worker status", zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get worker status")
+	}
+
+	return c.JSON(http.StatusOK, statuses)
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// minAPIKeyLength rejects keys short enough to guess, openssl rand -hex 32 gives 64
+const minAPIKeyLength = 32
+
+// APIKeys authenticates scanners submitting results from outside the cluster, such as CI jobs.
+// Each key has a name, recorded in logs, so a leaked key can be identified and rotated
+type APIKeys struct {
+	// Keys are compared by hash, in constant time, so neither their length nor content leaks
+	hashes map[[sha256.Size]byte]string
+}
+
+// ParseAPIKeys parses a comma-separated list of name=key pairs
+func ParseAPIKeys(list string) (*APIKeys, error) {
+	keys := &APIKeys{hashes: make(map[[sha256.Size]byte]string)}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(entry, "=")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("API key entries must be name=key")
+		}
+		if len(key) < minAPIKeyLength {
+			return nil, fmt.Errorf("API key %q must be at least %d characters", name, minAPIKeyLength)
+		}
+		hash := sha256.Sum256([]byte(key))
+		if _, exists := keys.hashes[hash]; exists {
+			return nil, fmt.Errorf("API key %q is listed twice", name)
+		}
+		keys.hashes[hash] = name
+	}
+	return keys, nil
+}
+
+// Len returns the number of keys
+func (k *APIKeys) Len() int {
+	return len(k.hashes)
+}
+
+// Verify returns the name of the key
+func (k *APIKeys) Verify(key string) (string, error) {
+	hash := sha256.Sum256([]byte(key))
+	name := ""
+	for candidate, candidateName := range k.hashes {
+		if subtle.ConstantTimeCompare(hash[:], candidate[:]) == 1 {
+			name = candidateName
+		}
+	}
+	if name == "" {
+		return "", ErrInvalidAPIKey
+	}
+	return name, nil
+}
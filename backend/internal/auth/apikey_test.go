@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	ciKey := strings.Repeat("a", 64)
+	otherKey := strings.Repeat("b", 32)
+	keys, err := ParseAPIKeys("github-actions=" + ciKey + ", gitlab = " + otherKey + ",")
+	require.NoError(t, err)
+	assert.Equal(t, 2, keys.Len())
+
+	name, err := keys.Verify(ciKey)
+	require.NoError(t, err)
+	assert.Equal(t, "github-actions", name)
+	name, err = keys.Verify(otherKey)
+	require.NoError(t, err)
+	assert.Equal(t, "gitlab", name)
+
+	for _, key := range []string{"", ciKey[:63], ciKey + "a", "github-actions"} {
+		_, err := keys.Verify(key)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, key)
+	}
+
+	empty, err := ParseAPIKeys("")
+	require.NoError(t, err)
+	assert.Zero(t, empty.Len())
+	_, err = empty.Verify(ciKey)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestParseAPIKeys_Invalid(t *testing.T) {
+	key := strings.Repeat("a", 32)
+	for _, list := range []string{
+		key,
+		"=" + key,
+		"ci=short",
+		"ci=" + key + ",other=" + key,
+	} {
+		_, err := ParseAPIKeys(list)
+		assert.Error(t, err, list)
+	}
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IdentityHeaders are the request headers an authenticating proxy sets with the caller's identity
+type IdentityHeaders struct {
+	Email  string
+	User   string
+	Groups string // comma-separated
+	// Token carries the access token the JWT validator checks. Authorization carries it as a Bearer token
+	Token string
+	// ClaimFallback reads the email, user and groups missing from the headers from the token claims
+	// (email, preferred_username then sub, groups). They are only trusted once the token is validated,
+	// which the email check of authenticated endpoints does
+	ClaimFallback bool
+}
+
+// identitySchemes are the header conventions of the supported proxies
+var identitySchemes = map[string]IdentityHeaders{
+	"oauth2-proxy": {
+		Email: "X-Auth-Request-Email", User: "X-Auth-Request-User", Groups: "X-Auth-Request-Groups",
+		Token: "X-Auth-Request-Access-Token",
+	},
+	// Forward auth headers, with the Bearer token of Authelia's OpenID Connect provider
+	"authelia": {
+		Email: "Remote-Email", User: "Remote-User", Groups: "Remote-Groups",
+		Token: "Authorization", ClaimFallback: true,
+	},
+	// Claim headers of jwt_claims_headers, with the assertion signed by Pomerium
+	"pomerium": {
+		Email: "X-Pomerium-Claim-Email", User: "X-Pomerium-Claim-User", Groups: "X-Pomerium-Claim-Groups",
+		Token: "X-Pomerium-Jwt-Assertion", ClaimFallback: true,
+	},
+	// RequestAuthentication with forwardOriginalToken: the identity comes from the token claims,
+	// or from headers set with outputClaimToHeaders
+	"istio": {
+		Token: "Authorization", ClaimFallback: true,
+	},
+}
+
+// DefaultIdentityHeaders are the headers of OAuth2 Proxy, deployed by the Helm chart
+var DefaultIdentityHeaders = identitySchemes["oauth2-proxy"]
+
+// IdentityScheme returns the identity headers of a proxy: oauth2-proxy, authelia, pomerium or istio
+func IdentityScheme(name string) (IdentityHeaders, error) {
+	headers, ok := identitySchemes[name]
+	if !ok {
+		names := make([]string, 0, len(identitySchemes))
+		for n := range identitySchemes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return IdentityHeaders{}, fmt.Errorf("unknown identity scheme %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	return headers, nil
+}
+
+// Identity is the caller's identity as set by the proxy. It is not authenticated until Token is validated
+type Identity struct {
+	Email  string
+	User   string
+	Groups []string
+	// Token is the access token, without the Bearer prefix
+	Token string
+}
+
+// FromRequest reads the identity of a request
+func (h IdentityHeaders) FromRequest(r *http.Request) Identity {
+	id := Identity{
+		Email: headerValue(r, h.Email),
+		User:  headerValue(r, h.User),
+	}
+	if groups := headerValue(r, h.Groups); groups != "" {
+		id.Groups = splitGroups(groups)
+	}
+	if token := headerValue(r, h.Token); token != "" {
+		if scheme, bearer, ok := strings.Cut(token, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			token = strings.TrimSpace(bearer)
+		}
+		id.Token = token
+	}
+
+	if h.ClaimFallback && id.Token != "" && (id.Email == "" || id.User == "" || id.Groups == nil) {
+		claims := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(id.Token, claims); err == nil {
+			if id.Email == "" {
+				id.Email, _ = claims["email"].(string)
+			}
+			if id.User == "" {
+				if id.User, _ = claims["preferred_username"].(string); id.User == "" {
+					id.User, _ = claims["sub"].(string)
+				}
+			}
+			if id.Groups == nil {
+				id.Groups = claimGroups(claims["groups"])
+			}
+		}
+	}
+	return id
+}
+
+func headerValue(r *http.Request, name string) string {
+	if name == "" {
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get(name))
+}
+
+func splitGroups(list string) []string {
+	groups := []string{}
+	for _, group := range strings.Split(list, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// claimGroups reads a groups claim, a list or a comma-separated string
+func claimGroups(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return splitGroups(v)
+	case []interface{}:
+		groups := []string{}
+		for _, g := range v {
+			if s, ok := g.(string); ok && s != "" {
+				groups = append(groups, s)
+			}
+		}
+		return groups
+	}
+	return nil
+}
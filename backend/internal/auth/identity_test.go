@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityHeaders_FromRequest(t *testing.T) {
+	t.Run("oauth2-proxy headers", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Auth-Request-Email", "alice@example.com")
+		req.Header.Set("X-Auth-Request-User", "alice")
+		req.Header.Set("X-Auth-Request-Groups", "sec, platform,")
+		req.Header.Set("X-Auth-Request-Access-Token", "token")
+
+		id := DefaultIdentityHeaders.FromRequest(req)
+		assert.Equal(t, Identity{Email: "alice@example.com", User: "alice", Groups: []string{"sec", "platform"}, Token: "token"}, id)
+	})
+
+	t.Run("claim fallback", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"email": "bob@example.com", "sub": "c0ffee", "groups": []string{"dev"},
+		}).SignedString([]byte("secret"))
+		require.NoError(t, err)
+
+		headers, err := IdentityScheme("istio")
+		require.NoError(t, err)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		// Headers of other proxies are ignored
+		req.Header.Set("X-Auth-Request-Email", "mallory@example.com")
+
+		id := headers.FromRequest(req)
+		assert.Equal(t, Identity{Email: "bob@example.com", User: "c0ffee", Groups: []string{"dev"}, Token: token}, id)
+
+		// Headers win over claims
+		headers, err = IdentityScheme("authelia")
+		require.NoError(t, err)
+		req.Header.Set("Remote-User", "bob")
+		id = headers.FromRequest(req)
+		assert.Equal(t, "bob", id.User)
+		assert.Equal(t, "bob@example.com", id.Email)
+	})
+
+	t.Run("without claim fallback", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Auth-Request-Access-Token", "not-a-jwt")
+		id := DefaultIdentityHeaders.FromRequest(req)
+		assert.Empty(t, id.Email)
+		assert.Nil(t, id.Groups)
+	})
+}
+
+func TestIdentityScheme_Unknown(t *testing.T) {
+	_, err := IdentityScheme("basic")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "authelia, istio, oauth2-proxy, pomerium")
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spiffeScheme prefixes the URI SAN identifying a workload, e.g. spiffe://cluster.local/ns/invulnerable/sa/scanner
+const spiffeScheme = "spiffe://"
+
+// NewServerTLSConfig returns the TLS configuration of the API server. The certificate is read
+// again when its file changes, so renewals (e.g. by cert-manager) don't need a restart.
+// With a client CA, client certificates are verified against it when presented; the routes
+// requiring one check the verified chain, so health probes work without a certificate
+func NewServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	certs := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := certs.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}
+
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in client CA %s", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// certReloader loads a certificate and key again when the certificate file is modified
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.certFile)
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("failed to read TLS certificate: %w", err)
+	}
+	if r.cert != nil && info.ModTime().Equal(r.modTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// The key may not be written yet, keep the previous certificate until it is
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	r.cert, r.modTime = &cert, info.ModTime()
+	return r.cert, nil
+}
+
+// ClientIDs restricts the client certificates accepted by the API to SPIFFE IDs (URI SANs).
+// An ID ending with * allows the IDs starting with it, e.g. spiffe://cluster.local/ns/invulnerable/*
+type ClientIDs struct {
+	exact    map[string]bool
+	prefixes []string
+}
+
+// ParseClientIDs parses a comma-separated list of SPIFFE IDs
+func ParseClientIDs(list string) (*ClientIDs, error) {
+	ids := &ClientIDs{exact: make(map[string]bool)}
+	for _, id := range strings.Split(list, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if !strings.HasPrefix(id, spiffeScheme) || len(id) == len(spiffeScheme) {
+			return nil, fmt.Errorf("client ID %q must be a SPIFFE ID (spiffe://trust-domain/path)", id)
+		}
+		if prefix, ok := strings.CutSuffix(id, "*"); ok {
+			ids.prefixes = append(ids.prefixes, prefix)
+		} else {
+			ids.exact[id] = true
+		}
+	}
+	return ids, nil
+}
+
+// Len returns the number of IDs
+func (c *ClientIDs) Len() int {
+	return len(c.exact) + len(c.prefixes)
+}
+
+// Verify returns the identity of a verified client certificate, its SPIFFE ID or else its
+// subject common name, and whether it is allowed. Any certificate is allowed without IDs
+func (c *ClientIDs) Verify(cert *x509.Certificate) (string, bool) {
+	identity := cert.Subject.CommonName
+	for _, uri := range cert.URIs {
+		if uri.Scheme != "spiffe" {
+			continue
+		}
+		identity = uri.String()
+		if c.exact[identity] {
+			return identity, true
+		}
+		for _, prefix := range c.prefixes {
+			if strings.HasPrefix(identity, prefix) {
+				return identity, true
+			}
+		}
+	}
+	return identity, c.Len() == 0
+}
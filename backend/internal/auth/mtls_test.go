@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCert writes a self-signed certificate and its key to dir
+func writeTestCert(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestNewServerTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "backend.invulnerable.svc")
+
+	config, err := NewServerTLSConfig(certFile, keyFile, certFile)
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, config.ClientAuth)
+	assert.NotNil(t, config.ClientCAs)
+
+	cert, err := config.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "backend.invulnerable.svc", leaf.Subject.CommonName)
+
+	// A renewed certificate is served without a restart
+	writeTestCert(t, dir, "renewed.invulnerable.svc")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	cert, err = config.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "renewed.invulnerable.svc", leaf.Subject.CommonName)
+
+	// Without a client CA, client certificates aren't requested
+	config, err = NewServerTLSConfig(certFile, keyFile, "")
+	require.NoError(t, err)
+	assert.Equal(t, tls.NoClientCert, config.ClientAuth)
+
+	_, err = NewServerTLSConfig(certFile, keyFile, keyFile)
+	assert.Error(t, err, "a key is not a CA")
+	_, err = NewServerTLSConfig(filepath.Join(dir, "missing.crt"), keyFile, "")
+	assert.Error(t, err)
+}
+
+func TestClientIDs(t *testing.T) {
+	ids, err := ParseClientIDs("spiffe://cluster.local/ns/invulnerable/sa/controller, spiffe://cluster.local/ns/scans/*,")
+	require.NoError(t, err)
+	assert.Equal(t, 2, ids.Len())
+
+	withURI := func(commonName, uri string) *x509.Certificate {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		if uri != "" {
+			u, err := url.Parse(uri)
+			require.NoError(t, err)
+			cert.URIs = []*url.URL{u}
+		}
+		return cert
+	}
+
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		identity string
+		allowed  bool
+	}{
+		{"exact", withURI("", "spiffe://cluster.local/ns/invulnerable/sa/controller"), "spiffe://cluster.local/ns/invulnerable/sa/controller", true},
+		{"prefix", withURI("", "spiffe://cluster.local/ns/scans/sa/scanner"), "spiffe://cluster.local/ns/scans/sa/scanner", true},
+		{"other", withURI("", "spiffe://cluster.local/ns/default/sa/default"), "spiffe://cluster.local/ns/default/sa/default", false},
+		{"no SPIFFE ID", withURI("ingress", ""), "ingress", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, allowed := ids.Verify(tt.cert)
+			assert.Equal(t, tt.identity, identity)
+			assert.Equal(t, tt.allowed, allowed)
+		})
+	}
+
+	// Without IDs, any certificate verified by the CA is allowed
+	empty, err := ParseClientIDs("")
+	require.NoError(t, err)
+	_, allowed := empty.Verify(withURI("ingress", ""))
+	assert.True(t, allowed)
+
+	for _, list := range []string{"controller", "spiffe://", "https://cluster.local/controller"} {
+		_, err := ParseClientIDs(list)
+		assert.Error(t, err, list)
+	}
+}
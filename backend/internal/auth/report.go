@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ReportPayloadType is the payload type of signed scan reports
+const ReportPayloadType = "application/vnd.invulnerable.scan-report+json"
+
+var ErrInvalidReportSignature = errors.New("invalid report signature")
+
+// Envelope is a DSSE envelope (github.com/secure-systems-lab/dsse): the payload is signed as is,
+// so verifying doesn't depend on how the JSON is serialized
+type Envelope struct {
+	PayloadType string              `json:"payloadType"`
+	Payload     string              `json:"payload"` // base64
+	Signatures  []EnvelopeSignature `json:"signatures"`
+}
+
+type EnvelopeSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"` // base64
+}
+
+// ReportSigner signs scan reports with an Ed25519 key, whose public key auditors verify them with
+type ReportSigner struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// LoadReportSigner reads a PEM encoded PKCS #8 Ed25519 private key, such as one generated with
+// openssl genpkey -algorithm ed25519
+func LoadReportSigner(keyFile string) (*ReportSigner, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in report signing key %s", keyFile)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid report signing key: %w", err)
+	}
+	ed25519Key, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("report signing key must be an Ed25519 key")
+	}
+	return NewReportSigner(ed25519Key), nil
+}
+
+func NewReportSigner(key ed25519.PrivateKey) *ReportSigner {
+	return &ReportSigner{key: key, keyID: ReportKeyID(key.Public().(ed25519.PublicKey))}
+}
+
+// ReportKeyID identifies a public key: the hex SHA-256 of its PKIX encoding, truncated
+func ReportKeyID(public ed25519.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(public)
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeyID is the identifier of the public key in the envelopes
+func (s *ReportSigner) KeyID() string {
+	return s.keyID
+}
+
+// PublicKeyPEM returns the public key reports are verified with
+func (s *ReportSigner) PublicKeyPEM() string {
+	der, _ := x509.MarshalPKIXPublicKey(s.key.Public())
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// Sign wraps a report in a signed envelope
+func (s *ReportSigner) Sign(payload []byte) Envelope {
+	sig := ed25519.Sign(s.key, preAuthEncoding(ReportPayloadType, payload))
+	return Envelope{
+		PayloadType: ReportPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []EnvelopeSignature{{KeyID: s.keyID, Sig: base64.StdEncoding.EncodeToString(sig)}},
+	}
+}
+
+// VerifyEnvelope returns the payload of an envelope signed by the public key
+func VerifyEnvelope(public ed25519.PublicKey, envelope Envelope) ([]byte, error) {
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, ErrInvalidReportSignature
+	}
+	message := preAuthEncoding(envelope.PayloadType, payload)
+	for _, signature := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err == nil && ed25519.Verify(public, message, sig) {
+			return payload, nil
+		}
+	}
+	return nil, ErrInvalidReportSignature
+}
+
+// preAuthEncoding is the DSSE PAE of a payload, binding its type to the signature
+func preAuthEncoding(payloadType string, payload []byte) []byte {
+	return append([]byte(fmt.Sprintf("DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))), payload...)
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportSigner(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "report.key")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	signer, err := LoadReportSigner(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, ReportKeyID(public), signer.KeyID())
+	assert.Contains(t, signer.PublicKeyPEM(), "BEGIN PUBLIC KEY")
+
+	envelope := signer.Sign([]byte(`{"scan":{"id":42}}`))
+	assert.Equal(t, ReportPayloadType, envelope.PayloadType)
+	require.Len(t, envelope.Signatures, 1)
+	assert.Equal(t, signer.KeyID(), envelope.Signatures[0].KeyID)
+
+	payload, err := VerifyEnvelope(public, envelope)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"scan":{"id":42}}`, string(payload))
+
+	// An altered report or payload type doesn't verify
+	tampered := envelope
+	tampered.Payload = base64.StdEncoding.EncodeToString([]byte(`{"scan":{"id":43}}`))
+	_, err = VerifyEnvelope(public, tampered)
+	assert.ErrorIs(t, err, ErrInvalidReportSignature)
+	tampered = envelope
+	tampered.PayloadType = "application/json"
+	_, err = VerifyEnvelope(public, tampered)
+	assert.ErrorIs(t, err, ErrInvalidReportSignature)
+
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = VerifyEnvelope(other, envelope)
+	assert.ErrorIs(t, err, ErrInvalidReportSignature)
+
+	_, err = LoadReportSigner(filepath.Join(t.TempDir(), "missing.key"))
+	assert.Error(t, err)
+}
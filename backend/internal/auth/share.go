@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidShareToken = errors.New("invalid share token")
+	ErrExpiredShareToken = errors.New("share token expired")
+)
+
+// ShareSigner signs the tokens of read-only share links. Tokens are stateless: a link stays valid
+// until it expires, and rotating the secret revokes every link at once
+type ShareSigner struct {
+	secret []byte
+}
+
+func NewShareSigner(secret string) *ShareSigner {
+	return &ShareSigner{secret: []byte(secret)}
+}
+
+// Sign returns a token granting read access to a scan until expiresAt
+func (s *ShareSigner) Sign(scanID int, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%d.%d", scanID, expiresAt.Unix())
+	return payload + "." + s.signature(payload)
+}
+
+// Verify returns the scan a token grants access to, and when the access ends
+func (s *ShareSigner) Verify(token string, now time.Time) (int, time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, time.Time{}, ErrInvalidShareToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.signature(payload))) {
+		return 0, time.Time{}, ErrInvalidShareToken
+	}
+
+	scanID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, time.Time{}, ErrInvalidShareToken
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, ErrInvalidShareToken
+	}
+	expiresAt := time.Unix(expiry, 0).UTC()
+	if !now.Before(expiresAt) {
+		return 0, time.Time{}, ErrExpiredShareToken
+	}
+	return scanID, expiresAt, nil
+}
+
+func (s *ShareSigner) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("scan-share:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareSigner(t *testing.T) {
+	signer := NewShareSigner("secret")
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	token := signer.Sign(42, now.Add(time.Hour))
+
+	scanID, expiresAt, err := signer.Verify(token, now)
+	require.NoError(t, err)
+	assert.Equal(t, 42, scanID)
+	assert.Equal(t, now.Add(time.Hour), expiresAt)
+
+	_, _, err = signer.Verify(token, now.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrExpiredShareToken)
+
+	// Another scan or expiry under the same signature
+	for _, forged := range []string{
+		"43" + token[2:],
+		token[:3] + "9" + token[4:],
+		token + "x",
+		"42.1705316400",
+	} {
+		_, _, err := signer.Verify(forged, now)
+		assert.ErrorIs(t, err, ErrInvalidShareToken, forged)
+	}
+
+	_, _, err = NewShareSigner("rotated").Verify(token, now)
+	assert.ErrorIs(t, err, ErrInvalidShareToken)
+}
@@ -0,0 +1,195 @@
+// Package bundle reads and writes suppression bundles: the suppression rules and waivers of an instance
+// as a versioned YAML document, so the ones validated on staging can be promoted to production or kept in Git
+package bundle
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Version and kind of the bundles written, the only ones read
+const (
+	APIVersion = "invulnerable.io/v1"
+	Kind       = "SuppressionBundle"
+)
+
+// Bundle is the suppression state of an instance. Entries are identified by their criteria, never by
+// database IDs, and sorted by them, so exporting the same state always gives the same document
+type Bundle struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Rules      []Rule   `yaml:"rules"`
+	Waivers    []Waiver `yaml:"waivers"`
+}
+
+// Rule is a suppression rule of a bundle
+type Rule struct {
+	CVEID          string     `yaml:"cve_id,omitempty"`
+	PackageName    string     `yaml:"package_name,omitempty"`
+	PackageVersion string     `yaml:"package_version,omitempty"`
+	PackageType    string     `yaml:"package_type,omitempty"`
+	FixState       string     `yaml:"fix_state,omitempty"`
+	Reason         string     `yaml:"reason,omitempty"`
+	ExpiresAt      *time.Time `yaml:"expires_at,omitempty"`
+	Source         string     `yaml:"source,omitempty"`
+}
+
+// Waiver is a vulnerability accepted or ignored by hand
+type Waiver struct {
+	CVEID          string `yaml:"cve_id"`
+	PackageName    string `yaml:"package_name"`
+	PackageVersion string `yaml:"package_version"`
+	Status         string `yaml:"status"`
+	Notes          string `yaml:"notes,omitempty"`
+}
+
+// Render writes rules and waivers as a bundle
+func Render(rules []models.SuppressionRule, waivers []models.SuppressionWaiver) ([]byte, error) {
+	b := Bundle{APIVersion: APIVersion, Kind: Kind, Rules: []Rule{}, Waivers: []Waiver{}}
+	for _, r := range rules {
+		rule := Rule{
+			CVEID:          deref(r.CVEID),
+			PackageName:    deref(r.PackageName),
+			PackageVersion: deref(r.PackageVersion),
+			PackageType:    deref(r.PackageType),
+			FixState:       deref(r.FixState),
+			Reason:         deref(r.Reason),
+			Source:         r.Source,
+		}
+		if r.ExpiresAt != nil {
+			expires := r.ExpiresAt.UTC()
+			rule.ExpiresAt = &expires
+		}
+		b.Rules = append(b.Rules, rule)
+	}
+	for _, w := range waivers {
+		b.Waivers = append(b.Waivers, Waiver{
+			CVEID:          w.CVEID,
+			PackageName:    w.PackageName,
+			PackageVersion: w.PackageVersion,
+			Status:         w.Status,
+			Notes:          deref(w.Notes),
+		})
+	}
+	slices.SortFunc(b.Rules, compareRules)
+	slices.SortFunc(b.Waivers, compareWaivers)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(b); err != nil {
+		return nil, fmt.Errorf("failed to render suppression bundle: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to render suppression bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Parse reads a bundle. Unlike ignore files, a bundle with an invalid entry is rejected as a whole: it
+// describes the exact state to reproduce
+func Parse(data []byte) ([]models.SuppressionRule, []models.SuppressionWaiver, error) {
+	var b Bundle
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&b); err != nil {
+		return nil, nil, fmt.Errorf("invalid suppression bundle: %w", err)
+	}
+	if b.APIVersion != APIVersion || b.Kind != Kind {
+		return nil, nil, fmt.Errorf("unsupported bundle %s %s (expected %s %s)", b.APIVersion, b.Kind, APIVersion, Kind)
+	}
+
+	rules := make([]models.SuppressionRule, 0, len(b.Rules))
+	seen := map[string]bool{}
+	for i, r := range b.Rules {
+		rule := models.SuppressionRule{
+			CVEID:          optional(r.CVEID),
+			PackageName:    optional(r.PackageName),
+			PackageVersion: optional(r.PackageVersion),
+			PackageType:    optional(r.PackageType),
+			FixState:       optional(r.FixState),
+			Reason:         optional(r.Reason),
+			ExpiresAt:      r.ExpiresAt,
+			Source:         r.Source,
+		}
+		if rule.CVEID == nil && rule.PackageName == nil {
+			return nil, nil, fmt.Errorf("rules[%d]: rule needs a cve_id or a package_name", i)
+		}
+		if rule.FixState != nil && !slices.Contains(models.ValidFixStates, *rule.FixState) {
+			return nil, nil, fmt.Errorf("rules[%d]: invalid fix_state %q", i, *rule.FixState)
+		}
+		if rule.Source == "" {
+			rule.Source = models.SuppressionSourceManual
+		}
+		key := strings.Join([]string{r.CVEID, r.PackageName, r.PackageVersion, r.PackageType, r.FixState}, "\x00")
+		if seen[key] {
+			return nil, nil, fmt.Errorf("rules[%d]: duplicate rule", i)
+		}
+		seen[key] = true
+		rules = append(rules, rule)
+	}
+
+	waivers := make([]models.SuppressionWaiver, 0, len(b.Waivers))
+	seen = map[string]bool{}
+	for i, w := range b.Waivers {
+		if w.CVEID == "" || w.PackageName == "" || w.PackageVersion == "" {
+			return nil, nil, fmt.Errorf("waivers[%d]: cve_id, package_name and package_version are required", i)
+		}
+		if w.Status != models.StatusAccepted && w.Status != models.StatusIgnored {
+			return nil, nil, fmt.Errorf("waivers[%d]: invalid status %q (expected accepted or ignored)", i, w.Status)
+		}
+		key := strings.Join([]string{w.CVEID, w.PackageName, w.PackageVersion}, "\x00")
+		if seen[key] {
+			return nil, nil, fmt.Errorf("waivers[%d]: duplicate waiver", i)
+		}
+		seen[key] = true
+		waivers = append(waivers, models.SuppressionWaiver{
+			CVEID:          w.CVEID,
+			PackageName:    w.PackageName,
+			PackageVersion: w.PackageVersion,
+			Status:         w.Status,
+			Notes:          optional(w.Notes),
+		})
+	}
+	return rules, waivers, nil
+}
+
+func compareRules(a, b Rule) int {
+	return cmp.Or(
+		cmp.Compare(a.CVEID, b.CVEID),
+		cmp.Compare(a.PackageName, b.PackageName),
+		cmp.Compare(a.PackageVersion, b.PackageVersion),
+		cmp.Compare(a.PackageType, b.PackageType),
+		cmp.Compare(a.FixState, b.FixState),
+	)
+}
+
+func compareWaivers(a, b Waiver) int {
+	return cmp.Or(
+		cmp.Compare(a.CVEID, b.CVEID),
+		cmp.Compare(a.PackageName, b.PackageName),
+		cmp.Compare(a.PackageVersion, b.PackageVersion),
+	)
+}
+
+func optional(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,91 @@
+package bundle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_RoundTrip(t *testing.T) {
+	cveA, cveB, pkg, reason, notes := "CVE-2024-0001", "CVE-2024-0002", "openssl", "not reachable", "accepted until Q3"
+	expires := time.Date(2024, 9, 30, 0, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	rules := []models.SuppressionRule{
+		{ID: 9, CVEID: &cveB, Source: models.SuppressionSourceTrivy},
+		{ID: 3, CVEID: &cveA, PackageName: &pkg, Reason: &reason, ExpiresAt: &expires, Source: models.SuppressionSourceManual},
+	}
+	waivers := []models.SuppressionWaiver{
+		{CVEID: cveB, PackageName: "zlib", PackageVersion: "1.2.11", Status: models.StatusIgnored},
+		{CVEID: cveA, PackageName: pkg, PackageVersion: "3.0.7", Status: models.StatusAccepted, Notes: &notes},
+	}
+
+	data, err := Render(rules, waivers)
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: invulnerable.io/v1
+kind: SuppressionBundle
+rules:
+  - cve_id: CVE-2024-0001
+    package_name: openssl
+    reason: not reachable
+    expires_at: 2024-09-29T22:00:00Z
+    source: manual
+  - cve_id: CVE-2024-0002
+    source: trivy
+waivers:
+  - cve_id: CVE-2024-0001
+    package_name: openssl
+    package_version: 3.0.7
+    status: accepted
+    notes: accepted until Q3
+  - cve_id: CVE-2024-0002
+    package_name: zlib
+    package_version: 1.2.11
+    status: ignored
+`, string(data), "sorted by criteria, without IDs, times in UTC")
+
+	// The order of the state doesn't change the bundle
+	reordered, err := Render([]models.SuppressionRule{rules[1], rules[0]}, []models.SuppressionWaiver{waivers[1], waivers[0]})
+	require.NoError(t, err)
+	assert.Equal(t, data, reordered)
+
+	parsedRules, parsedWaivers, err := Parse(data)
+	require.NoError(t, err)
+	require.Len(t, parsedRules, 2)
+	assert.Equal(t, cveA, *parsedRules[0].CVEID)
+	assert.Equal(t, reason, *parsedRules[0].Reason)
+	require.NotNil(t, parsedRules[0].ExpiresAt)
+	assert.True(t, expires.Equal(*parsedRules[0].ExpiresAt))
+	assert.Nil(t, parsedRules[1].PackageName)
+	require.Len(t, parsedWaivers, 2)
+	assert.Equal(t, notes, *parsedWaivers[0].Notes)
+	assert.Nil(t, parsedWaivers[1].Notes)
+}
+
+func TestRender_Empty(t *testing.T) {
+	data, err := Render(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "apiVersion: invulnerable.io/v1\nkind: SuppressionBundle\nrules: []\nwaivers: []\n", string(data))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	header := "apiVersion: invulnerable.io/v1\nkind: SuppressionBundle\n"
+	for name, data := range map[string]string{
+		"version":        "apiVersion: invulnerable.io/v2\nkind: SuppressionBundle\n",
+		"unknown field":  header + "policies: []\n",
+		"no criteria":    header + "rules:\n  - reason: everything\n",
+		"fix state":      header + "rules:\n  - cve_id: CVE-2024-0001\n    fix_state: maybe\n",
+		"duplicate rule": header + "rules:\n  - cve_id: CVE-2024-0001\n  - cve_id: CVE-2024-0001\n",
+		"waiver key":     header + "waivers:\n  - cve_id: CVE-2024-0001\n    status: accepted\n",
+		"waiver status":  header + "waivers:\n  - {cve_id: CVE-2024-0001, package_name: zlib, package_version: '1.0', status: fixed}\n",
+		"invalid YAML":   header + "rules: [unterminated",
+	} {
+		_, _, err := Parse([]byte(data))
+		assert.Error(t, err, name)
+	}
+
+	rules, _, err := Parse([]byte(header + "rules:\n  - package_name: lodash\n"))
+	require.NoError(t, err)
+	assert.Equal(t, models.SuppressionSourceManual, rules[0].Source, "rules without a source are manual")
+}
@@ -0,0 +1,178 @@
+// Package compliance maps the remediation timelines of compliance frameworks (PCI DSS, SOC 2, FedRAMP...)
+// onto vulnerabilities. A profile applies to the images of the ImageScans whose labels match its selector,
+// and requires their findings to be remediated within a number of days per severity
+package compliance
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/sla"
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is a framework requirement on remediation timelines
+type Profile struct {
+	Name        string `yaml:"name" json:"name"`
+	Framework   string `yaml:"framework" json:"framework"`
+	Requirement string `yaml:"requirement,omitempty" json:"requirement,omitempty"`
+	// Selector lists the ImageScan labels the profile applies to, empty applies to every image
+	Selector        map[string]string `yaml:"selector,omitempty" json:"selector,omitempty"`
+	RemediationDays RemediationDays   `yaml:"remediationDays" json:"remediation_days"`
+}
+
+// RemediationDays are the calendar days a framework allows to remediate a finding per severity.
+// 0 leaves the severity out of the framework. Negligible and unknown findings get the Low timeline
+type RemediationDays struct {
+	Critical int `yaml:"critical" json:"critical"`
+	High     int `yaml:"high" json:"high"`
+	Medium   int `yaml:"medium" json:"medium"`
+	Low      int `yaml:"low" json:"low"`
+}
+
+// file is the format of the profiles file
+type file struct {
+	Profiles []Profile `yaml:"profiles"`
+}
+
+// Load reads the profiles file, a YAML document with a list of profiles
+func Load(path string) ([]Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compliance profiles: %w", err)
+	}
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse compliance profiles: %w", err)
+	}
+	if err := Validate(f.Profiles); err != nil {
+		return nil, err
+	}
+	return f.Profiles, nil
+}
+
+// Validate checks that profiles have a unique name, a framework and non-negative timelines
+func Validate(profiles []Profile) error {
+	names := make(map[string]bool, len(profiles))
+	for i, p := range profiles {
+		if p.Name == "" || p.Framework == "" {
+			return fmt.Errorf("compliance profile %d: name and framework are required", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("compliance profile %q is defined twice", p.Name)
+		}
+		names[p.Name] = true
+		d := p.RemediationDays
+		if d.Critical < 0 || d.High < 0 || d.Medium < 0 || d.Low < 0 {
+			return fmt.Errorf("compliance profile %q: remediation days can't be negative", p.Name)
+		}
+	}
+	return nil
+}
+
+// Days returns the remediation timeline of a severity, 0 when the framework doesn't cover it
+func (p Profile) Days(severity string) int {
+	d := p.RemediationDays
+	return sla.Days(severity, d.Critical, d.High, d.Medium, d.Low)
+}
+
+// Matches reports whether the labels of an ImageScan, as key=value pairs, match the selector
+func (p Profile) Matches(labels []string) bool {
+	for key, value := range p.Selector {
+		found := false
+		for _, label := range labels {
+			if label == key+"="+value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// AppliesTo reports whether the profile applies to an image, given the labels of each ImageScan
+// scanning it. Profiles with a selector never apply to images outside ImageScans
+func (p Profile) AppliesTo(labelSets [][]string) bool {
+	if len(p.Selector) == 0 {
+		return true
+	}
+	for _, labels := range labelSets {
+		if p.Matches(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// Applicable returns the names of the profiles covering a finding of a severity in an image, in the
+// order they are defined
+func Applicable(profiles []Profile, labelSets [][]string, severity string) []string {
+	var names []string
+	for _, p := range profiles {
+		if p.Days(severity) > 0 && p.AppliesTo(labelSets) {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// Labels converts Kubernetes labels to the sorted key=value pairs they are stored as
+func Labels(labels map[string]string) []string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// Finding is an open finding of an image
+type Finding struct {
+	ImageID         int       `db:"image_id"`
+	Severity        string    `db:"severity"`
+	FirstDetectedAt time.Time `db:"first_detected_at"`
+}
+
+// Evaluate computes the compliance of each profile at now with the open findings of the images it applies
+// to. images holds the labels of the ImageScans of every image evaluated
+func Evaluate(profiles []Profile, images map[int][][]string, findings []Finding, now time.Time) []models.FrameworkCompliance {
+	results := make([]models.FrameworkCompliance, 0, len(profiles))
+	for _, p := range profiles {
+		result := models.FrameworkCompliance{Profile: p.Name, Framework: p.Framework, Requirement: p.Requirement}
+		applies := make(map[int]bool, len(images))
+		for imageID, labelSets := range images {
+			if p.AppliesTo(labelSets) {
+				applies[imageID] = true
+				result.Images++
+			}
+		}
+		for _, f := range findings {
+			days := p.Days(f.Severity)
+			if !applies[f.ImageID] || days == 0 {
+				continue
+			}
+			result.OpenFindings++
+			if !now.Before(sla.Calendar{}.DeadlineFor(f.FirstDetectedAt, days, time.UTC).DueAt) {
+				result.Overdue++
+			}
+		}
+		result.CompliancePercent = percent(result.OpenFindings-result.Overdue, result.OpenFindings)
+		results = append(results, result)
+	}
+	return results
+}
+
+// percent is the share of part in total with one decimal, 100 when there is nothing to comply with
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
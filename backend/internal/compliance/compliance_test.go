@@ -0,0 +1,102 @@
+package compliance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+profiles:
+  - name: fedramp-high
+    framework: FedRAMP
+    requirement: RA-5
+    selector:
+      compliance.invulnerable.io/fedramp: high
+    remediationDays: {critical: 15, high: 30, medium: 90, low: 180}
+  - name: soc2
+    framework: SOC 2
+    remediationDays: {critical: 30}
+`), 0o600))
+
+	profiles, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, "fedramp-high", profiles[0].Name)
+	assert.Equal(t, map[string]string{"compliance.invulnerable.io/fedramp": "high"}, profiles[0].Selector)
+	assert.Equal(t, 30, profiles[0].Days("High"))
+	assert.Equal(t, 180, profiles[0].Days("Negligible"))
+	assert.Equal(t, 0, profiles[1].Days("High"))
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(nil))
+	assert.Error(t, Validate([]Profile{{Name: "pci"}}))
+	assert.Error(t, Validate([]Profile{{Name: "pci", Framework: "PCI DSS"}, {Name: "pci", Framework: "PCI DSS"}}))
+	assert.Error(t, Validate([]Profile{{Name: "pci", Framework: "PCI DSS", RemediationDays: RemediationDays{High: -1}}}))
+}
+
+func TestApplicable(t *testing.T) {
+	profiles := []Profile{
+		{Name: "fedramp-high", Selector: map[string]string{"fedramp": "high"}, RemediationDays: RemediationDays{Critical: 15, High: 30}},
+		{Name: "soc2", RemediationDays: RemediationDays{Critical: 30, High: 60, Medium: 90}},
+	}
+	fedramp := [][]string{Labels(map[string]string{"team": "payments", "fedramp": "high"})}
+
+	assert.Equal(t, []string{"fedramp-high", "soc2"}, Applicable(profiles, fedramp, "High"))
+	assert.Equal(t, []string{"soc2"}, Applicable(profiles, fedramp, "Medium"))
+	// Outside ImageScans only profiles without a selector apply
+	assert.Equal(t, []string{"soc2"}, Applicable(profiles, nil, "Critical"))
+	assert.Equal(t, []string{"soc2"}, Applicable(profiles, [][]string{{"fedramp=moderate"}}, "Critical"))
+	assert.Empty(t, Applicable(profiles, fedramp, "Low"))
+}
+
+func TestEvaluate(t *testing.T) {
+	profiles := []Profile{
+		{Name: "fedramp-high", Framework: "FedRAMP", Selector: map[string]string{"fedramp": "high"}, RemediationDays: RemediationDays{Critical: 15, High: 30}},
+		{Name: "soc2", Framework: "SOC 2", RemediationDays: RemediationDays{Critical: 30}},
+		{Name: "pci", Framework: "PCI DSS", Selector: map[string]string{"pci": "true"}, RemediationDays: RemediationDays{Critical: 30}},
+	}
+	images := map[int][][]string{
+		1: {{"fedramp=high"}},
+		2: nil,
+	}
+	now := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	findings := []Finding{
+		// Due at the end of January 31st: overdue from now on
+		{ImageID: 1, Severity: "High", FirstDetectedAt: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{ImageID: 1, Severity: "High", FirstDetectedAt: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)},
+		{ImageID: 1, Severity: "Critical", FirstDetectedAt: time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{ImageID: 2, Severity: "Critical", FirstDetectedAt: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{ImageID: 2, Severity: "Medium", FirstDetectedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	results := Evaluate(profiles, images, findings, now)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "fedramp-high", results[0].Profile)
+	assert.Equal(t, "FedRAMP", results[0].Framework)
+	assert.Equal(t, 1, results[0].Images)
+	assert.Equal(t, 3, results[0].OpenFindings)
+	assert.Equal(t, 1, results[0].Overdue)
+	assert.Equal(t, 66.7, results[0].CompliancePercent)
+
+	// Medium isn't covered by SOC 2 here
+	assert.Equal(t, 2, results[1].Images)
+	assert.Equal(t, 2, results[1].OpenFindings)
+	assert.Equal(t, 1, results[1].Overdue)
+	assert.Equal(t, 50.0, results[1].CompliancePercent)
+
+	assert.Equal(t, 0, results[2].Images)
+	assert.Equal(t, 0, results[2].OpenFindings)
+	assert.Equal(t, 100.0, results[2].CompliancePercent)
+}
@@ -3,13 +3,15 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Database DatabaseConfig
-	S3       S3Config
-	Server   ServerConfig
+	Database   DatabaseConfig
+	Encryption EncryptionConfig
+	S3         S3Config
+	Server     ServerConfig
 }
 
 // DatabaseConfig holds database connection settings
@@ -30,37 +32,77 @@ type S3Config struct {
 	AccessKey string
 	SecretKey string
 	UseSSL    bool
+	// Compression of new documents at rest: zstd (default) or none
+	Compression string
+}
+
+// EncryptionConfig holds the keys encrypting webhook URLs and notes in the database
+type EncryptionConfig struct {
+	Key          string   // base64 AES-256 key, or AWS KMS data key ciphertext blob with KMS
+	PreviousKeys []string // keys being rotated out, still used to decrypt
+	KMS          bool
+	KMSRegion    string
+}
+
+// Keys returns the encryption keys, the one encrypting first, or none when encryption is disabled
+func (c EncryptionConfig) Keys() []string {
+	if c.Key == "" {
+		return nil
+	}
+	return append([]string{c.Key}, c.PreviousKeys...)
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
 	Port string
+	// TLS is served with TLSCertFile and TLSKeyFile, reloaded when they change
+	TLSCertFile string
+	TLSKeyFile  string
+	// With TLSClientCAFile the API requires client certificates issued by it
+	TLSClientCAFile string
+	// SPIFFE IDs allowed to call the API (comma-separated), empty allows any client certificate from the CA
+	TLSAllowedSPIFFEIDs string
+}
+
+// TLSEnabled reports whether the server terminates TLS
+func (c ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != ""
 }
 
 // LoadFromEnv loads configuration from environment variables
 func LoadFromEnv() (*Config, error) {
 	config := &Config{
-		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "invulnerable"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
-		},
+		Database:   LoadDatabaseFromEnv(),
+		Encryption: LoadEncryptionFromEnv(),
 		S3: S3Config{
-			Endpoint:  getEnv("SBOM_S3_ENDPOINT", ""),
-			Bucket:    getEnv("SBOM_S3_BUCKET", "invulnerable"),
-			Region:    getEnv("SBOM_S3_REGION", "us-east-1"),
-			AccessKey: getEnv("SBOM_S3_ACCESS_KEY", ""),
-			SecretKey: getEnv("SBOM_S3_SECRET_KEY", ""),
-			UseSSL:    getEnv("SBOM_S3_USE_SSL", "true") == "true",
+			Endpoint:    getEnv("SBOM_S3_ENDPOINT", ""),
+			Bucket:      getEnv("SBOM_S3_BUCKET", "invulnerable"),
+			Region:      getEnv("SBOM_S3_REGION", "us-east-1"),
+			AccessKey:   getEnv("SBOM_S3_ACCESS_KEY", ""),
+			SecretKey:   getEnv("SBOM_S3_SECRET_KEY", ""),
+			UseSSL:      getEnv("SBOM_S3_USE_SSL", "true") == "true",
+			Compression: getEnv("SBOM_S3_COMPRESSION", "zstd"),
 		},
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
+			Port:                getEnv("PORT", "8080"),
+			TLSCertFile:         getEnv("TLS_CERT_FILE", ""),
+			TLSKeyFile:          getEnv("TLS_KEY_FILE", ""),
+			TLSClientCAFile:     getEnv("TLS_CLIENT_CA_FILE", ""),
+			TLSAllowedSPIFFEIDs: getEnv("TLS_ALLOWED_SPIFFE_IDS", ""),
 		},
 	}
 
+	// Validate TLS settings
+	if (config.Server.TLSCertFile == "") != (config.Server.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if config.Server.TLSClientCAFile != "" && !config.Server.TLSEnabled() {
+		return nil, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if config.Server.TLSAllowedSPIFFEIDs != "" && config.Server.TLSClientCAFile == "" {
+		return nil, fmt.Errorf("TLS_ALLOWED_SPIFFE_IDS requires TLS_CLIENT_CA_FILE")
+	}
+
 	// Validate required S3 settings
 	if config.S3.Endpoint == "" {
 		return nil, fmt.Errorf("SBOM_S3_ENDPOINT is required")
@@ -75,6 +117,32 @@ func LoadFromEnv() (*Config, error) {
 	return config, nil
 }
 
+// LoadDatabaseFromEnv loads only the database settings, for tools that don't need S3
+func LoadDatabaseFromEnv() DatabaseConfig {
+	return DatabaseConfig{
+		Host:     getEnv("DB_HOST", "localhost"),
+		Port:     getEnv("DB_PORT", "5432"),
+		User:     getEnv("DB_USER", "postgres"),
+		Password: getEnv("DB_PASSWORD", "postgres"),
+		DBName:   getEnv("DB_NAME", "invulnerable"),
+		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+	}
+}
+
+// LoadEncryptionFromEnv loads the column encryption keys. Without DB_ENCRYPTION_KEY columns are stored in plaintext
+func LoadEncryptionFromEnv() EncryptionConfig {
+	var previous []string
+	if keys := getEnv("DB_ENCRYPTION_PREVIOUS_KEYS", ""); keys != "" {
+		previous = strings.Split(keys, ",")
+	}
+	return EncryptionConfig{
+		Key:          getEnv("DB_ENCRYPTION_KEY", ""),
+		PreviousKeys: previous,
+		KMS:          getEnv("DB_ENCRYPTION_KMS", "false") == "true",
+		KMSRegion:    getEnv("DB_ENCRYPTION_KMS_REGION", ""),
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
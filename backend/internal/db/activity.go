@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// userActivity gathers the actions recorded for an actor ($1) since a time ($2): status and notes changes
+// from the vulnerability history, vulnerabilities added to campaigns and audit log entries
+const userActivity = `
+	WITH activity AS (
+		SELECT
+			CASE h.field_name WHEN 'notes' THEN 'comment' ELSE 'triage' END AS kind,
+			h.changed_at AS at, h.vulnerability_id, v.cve_id::text AS cve_id, h.image_name::text AS image_name,
+			h.old_value, h.new_value, NULL::text AS action,
+			NULL::text AS resource_type, NULL::integer AS resource_id, NULL::text AS resource_name
+		FROM vulnerability_history h
+		JOIN vulnerabilities v ON v.id = h.vulnerability_id
+		WHERE h.changed_by = $1 AND h.changed_at >= $2 AND h.field_name IN ('status', 'notes')
+		UNION ALL
+		SELECT
+			'assignment', cv.added_at, cv.vulnerability_id, v.cve_id, NULL,
+			NULL, NULL, NULL,
+			'campaign', c.id, c.name
+		FROM campaign_vulnerabilities cv
+		JOIN campaigns c ON c.id = cv.campaign_id
+		JOIN vulnerabilities v ON v.id = cv.vulnerability_id
+		WHERE cv.added_by = $1 AND cv.added_at >= $2
+		UNION ALL
+		SELECT
+			'admin', a.created_at, NULL, NULL, NULL,
+			NULL, NULL, a.action,
+			a.resource_type, a.resource_id, a.resource_name
+		FROM audit_log a
+		WHERE a.actor = $1 AND a.created_at >= $2
+	)
+`
+
+type ActivityRepository struct {
+	db *Database
+}
+
+func NewActivityRepository(db *Database) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// ListUserActivity returns the actions of a user since the given time, latest first, and how many there
+// are in total. An empty kind returns every kind of action
+func (r *ActivityRepository) ListUserActivity(ctx context.Context, user string, since time.Time, kind string, limit, offset int) ([]models.UserActivity, int, error) {
+	var total int
+	countQuery := userActivity + `SELECT COUNT(*) FROM activity WHERE $3 = '' OR kind = $3`
+	if err := r.db.GetContext(ctx, &total, countQuery, user, since, kind); err != nil {
+		return nil, 0, err
+	}
+
+	activity := []models.UserActivity{}
+	query := userActivity + `
+		SELECT * FROM activity
+		WHERE $3 = '' OR kind = $3
+		ORDER BY at DESC
+		LIMIT $4 OFFSET $5
+	`
+	if err := r.db.SelectContext(ctx, &activity, query, user, since, kind, limit, offset); err != nil {
+		return nil, 0, err
+	}
+	for i := range activity {
+		if activity[i].Kind != models.ActivityComment {
+			continue
+		}
+		// Notes history is encrypted like the notes
+		if err := r.db.decrypt(activity[i].OldValue); err != nil {
+			return nil, 0, fmt.Errorf("failed to decrypt notes history: %w", err)
+		}
+		if err := r.db.decrypt(activity[i].NewValue); err != nil {
+			return nil, 0, fmt.Errorf("failed to decrypt notes history: %w", err)
+		}
+	}
+	return activity, total, nil
+}
+
+// SummarizeUserActivity counts the actions of a user since the given time
+func (r *ActivityRepository) SummarizeUserActivity(ctx context.Context, user string, since time.Time) (*models.UserActivitySummary, error) {
+	var rows []struct {
+		Kind   string  `db:"kind"`
+		Status *string `db:"status"`
+		Count  int     `db:"count"`
+	}
+	query := userActivity + `
+		SELECT kind, CASE WHEN kind = 'triage' THEN new_value END AS status, COUNT(*) AS count
+		FROM activity
+		GROUP BY 1, 2
+	`
+	if err := r.db.SelectContext(ctx, &rows, query, user, since); err != nil {
+		return nil, err
+	}
+
+	summary := &models.UserActivitySummary{ByStatus: map[string]int{}}
+	for _, row := range rows {
+		switch row.Kind {
+		case models.ActivityTriage:
+			summary.Triaged += row.Count
+			if row.Status != nil {
+				summary.ByStatus[*row.Status] += row.Count
+			}
+		case models.ActivityComment:
+			summary.Commented += row.Count
+		case models.ActivityAssignment:
+			summary.Assigned += row.Count
+		case models.ActivityAdmin:
+			summary.Admin += row.Count
+		}
+	}
+
+	distinctQuery := userActivity + `SELECT COUNT(DISTINCT vulnerability_id) FROM activity`
+	if err := r.db.GetContext(ctx, &summary.Vulnerabilities, distinctQuery, user, since); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
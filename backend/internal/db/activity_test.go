@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityRepository(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	vulnRepo := NewVulnerabilityRepository(db)
+	campaignRepo := NewCampaignRepository(db)
+	repo := NewActivityRepository(db)
+
+	now := time.Now()
+	newVuln := func(cve string) *models.Vulnerability {
+		vuln := &models.Vulnerability{
+			CVEID: cve, PackageName: "openssl", PackageVersion: "3.0.7", Severity: "High",
+			Status: "active", FirstDetectedAt: now, LastSeenAt: now,
+		}
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		return vuln
+	}
+	first, second := newVuln("CVE-2024-0001"), newVuln("CVE-2024-0002")
+
+	alice, bob := "alice@example.com", "bob@example.com"
+	fixed, notes := models.StatusFixed, "patched in 3.0.8"
+	require.NoError(t, vulnRepo.BulkUpdate(ctx, []int{first.ID, second.ID}, &models.VulnerabilityUpdateWithContext{Status: &fixed, UpdatedBy: alice}))
+	require.NoError(t, vulnRepo.Update(ctx, first.ID, &models.VulnerabilityUpdateWithContext{Notes: &notes, UpdatedBy: alice}))
+	require.NoError(t, vulnRepo.Update(ctx, second.ID, &models.VulnerabilityUpdateWithContext{Notes: &notes, UpdatedBy: bob}))
+
+	campaign := &models.Campaign{Name: "OpenSSL upgrade", Status: models.CampaignStatusActive, Owners: []string{alice}}
+	require.NoError(t, campaignRepo.Create(ctx, campaign, nil))
+	_, err := campaignRepo.AddVulnerabilities(ctx, campaign.ID, []int{second.ID}, alice)
+	require.NoError(t, err)
+
+	imageName := "docker.io/nginx:1.25"
+	require.NoError(t, insertAuditEntry(ctx, db, &models.AuditEntry{
+		Action: models.AuditActionImageDeleted, ResourceType: "image", ResourceName: &imageName, Actor: alice,
+	}))
+
+	since := now.Add(-time.Hour)
+	activity, total, err := repo.ListUserActivity(ctx, alice, since, "", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 5, total)
+	require.Len(t, activity, 5)
+	assert.Equal(t, models.ActivityAdmin, activity[0].Kind, "latest first")
+	require.NotNil(t, activity[0].Action)
+	assert.Equal(t, models.AuditActionImageDeleted, *activity[0].Action)
+	assert.Equal(t, models.ActivityAssignment, activity[1].Kind)
+	require.NotNil(t, activity[1].ResourceName)
+	assert.Equal(t, "OpenSSL upgrade", *activity[1].ResourceName)
+	assert.Equal(t, models.ActivityComment, activity[2].Kind)
+	require.NotNil(t, activity[2].NewValue)
+	assert.Equal(t, notes, *activity[2].NewValue)
+
+	comments, total, err := repo.ListUserActivity(ctx, alice, since, models.ActivityComment, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, comments, 1)
+	assert.Equal(t, first.CVEID, *comments[0].CVEID)
+
+	summary, err := repo.SummarizeUserActivity(ctx, alice, since)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Triaged)
+	assert.Equal(t, 1, summary.Commented)
+	assert.Equal(t, 1, summary.Assigned)
+	assert.Equal(t, 1, summary.Admin)
+	assert.Equal(t, 2, summary.Vulnerabilities)
+	assert.Equal(t, map[string]int{models.StatusFixed: 2}, summary.ByStatus)
+
+	// Actions before the window are left out
+	activity, total, err = repo.ListUserActivity(ctx, bob, now.Add(time.Hour), "", 10, 0)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, activity)
+}
@@ -0,0 +1,21 @@
+package db
+
+import (
+	"context"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// insertAuditEntry records an action using the caller's transaction, so the entry
+// only exists if the action it describes was committed
+func insertAuditEntry(ctx context.Context, q sqlx.QueryerContext, entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (action, resource_type, resource_id, resource_name, actor, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, created_at
+	`
+	return q.QueryRowxContext(ctx, query,
+		entry.Action, entry.ResourceType, entry.ResourceID, entry.ResourceName, entry.Actor, []byte(entry.Details),
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
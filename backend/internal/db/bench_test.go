@@ -0,0 +1,101 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/seed"
+	"go.uber.org/zap"
+)
+
+// Baselines for the heavy read queries against the default benchmark dataset.
+// They are deliberately generous: the goal is to catch order-of-magnitude
+// regressions such as a lost index or an accidental N+1, not small drifts.
+const (
+	baselineImageList         = 50 * time.Millisecond
+	baselineScanList          = 50 * time.Millisecond
+	baselineVulnListWithImage = 100 * time.Millisecond
+	baselineVulnCount         = 50 * time.Millisecond
+	baselineScanHistory       = 25 * time.Millisecond
+)
+
+// setupBenchmarkDatabase starts a database seeded with a reproducible dataset
+func setupBenchmarkDatabase(b *testing.B) *db.Database {
+	b.Helper()
+
+	database := db.SetupTestDatabase(b)
+
+	opts := seed.Options{
+		Images:           30,
+		Days:             60,
+		ScanIntervalDays: 1,
+		Seed:             42,
+		End:              time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if _, err := seed.NewLoader(zap.NewNop(), database).Load(context.Background(), seed.Generate(opts)); err != nil {
+		b.Fatalf("failed to seed benchmark database: %v", err)
+	}
+
+	return database
+}
+
+func BenchmarkRepositories(b *testing.B) {
+	database := setupBenchmarkDatabase(b)
+	ctx := context.Background()
+
+	imageRepo := db.NewImageRepository(database)
+	scanRepo := db.NewScanRepository(database)
+	vulnRepo := db.NewVulnerabilityRepository(database)
+
+	b.Run("ImageList", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := imageRepo.List(ctx, 20, 0, db.Sort{}, nil, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+		db.CheckBaseline(b, baselineImageList)
+	})
+
+	b.Run("ScanList", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := scanRepo.List(ctx, 20, 0, db.Sort{}, nil, nil, nil, nil, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+		db.CheckBaseline(b, baselineScanList)
+	})
+
+	b.Run("VulnerabilityListWithImageInfo", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := vulnRepo.ListWithImageInfo(ctx, 50, 0, db.Sort{}, nil, nil, nil, nil, nil, nil, nil, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+		db.CheckBaseline(b, baselineVulnListWithImage)
+	})
+
+	b.Run("VulnerabilityCountWithImageInfo", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := vulnRepo.CountWithImageInfo(ctx, nil, nil, nil, nil, nil, nil, nil, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+		db.CheckBaseline(b, baselineVulnCount)
+	})
+
+	b.Run("ImageScanHistory", func(b *testing.B) {
+		images, err := imageRepo.List(ctx, 1, 0, db.Sort{}, nil, false)
+		if err != nil || len(images) == 0 {
+			b.Fatalf("failed to pick an image: %v", err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := imageRepo.GetScanHistory(ctx, images[0].ID, 20, 0, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+		db.CheckBaseline(b, baselineScanHistory)
+	})
+}
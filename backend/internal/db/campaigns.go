@@ -0,0 +1,339 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// CampaignRepository handles database operations for remediation campaigns
+type CampaignRepository struct {
+	db *Database
+}
+
+// NewCampaignRepository creates a new campaign repository
+func NewCampaignRepository(db *Database) *CampaignRepository {
+	return &CampaignRepository{db: db}
+}
+
+// campaignMembers selects the vulnerabilities of campaigns: the attached ones and, for campaigns
+// with a filter, the ones matching all of its criteria. Callers append a WHERE clause on c.id
+const campaignMembers = `
+	SELECT c.id as campaign_id, v.id, v.severity, v.status, v.first_detected_at
+	FROM campaigns c
+	JOIN vulnerabilities v ON EXISTS (
+		SELECT 1 FROM campaign_vulnerabilities cv WHERE cv.campaign_id = c.id AND cv.vulnerability_id = v.id
+	) OR (
+		COALESCE(c.filter_cve_id, c.filter_package_name, c.filter_package_version_prefix, c.filter_severity) IS NOT NULL
+		AND (c.filter_cve_id IS NULL OR v.cve_id = c.filter_cve_id)
+		AND (c.filter_package_name IS NULL OR v.package_name = c.filter_package_name)
+		AND (c.filter_package_version_prefix IS NULL OR starts_with(v.package_version, c.filter_package_version_prefix))
+		AND (c.filter_severity IS NULL OR v.severity = c.filter_severity)
+	)
+`
+
+// Create stores a campaign and attaches its initial vulnerabilities in one transaction
+func (r *CampaignRepository) Create(ctx context.Context, campaign *models.Campaign, vulnerabilityIDs []int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if campaign.Owners == nil {
+		campaign.Owners = pq.StringArray{}
+	}
+	query := `
+		INSERT INTO campaigns (
+			name, description, status, owners, due_at,
+			filter_cve_id, filter_package_name, filter_package_version_prefix, filter_severity,
+			created_by, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+	if err := tx.QueryRowxContext(ctx, query,
+		campaign.Name, campaign.Description, campaign.Status, campaign.Owners, campaign.DueAt,
+		campaign.CVEID, campaign.PackageName, campaign.PackageVersionPrefix, campaign.Severity,
+		campaign.CreatedBy,
+	).Scan(&campaign.ID, &campaign.CreatedAt, &campaign.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	if _, err := attachVulnerabilities(ctx, tx, campaign.ID, vulnerabilityIDs, campaign.CreatedBy); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByID retrieves a campaign
+func (r *CampaignRepository) GetByID(ctx context.Context, id int) (*models.Campaign, error) {
+	var campaign models.Campaign
+	if err := r.db.GetContext(ctx, &campaign, `SELECT * FROM campaigns WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("campaign")
+		}
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// List returns the campaigns, optionally only those with a status or owned by someone,
+// earliest due first
+func (r *CampaignRepository) List(ctx context.Context, status, owner *string) ([]models.Campaign, error) {
+	query := `
+		SELECT * FROM campaigns
+		WHERE ($1::text IS NULL OR status = $1)
+			AND ($2::text IS NULL OR $2 = ANY(owners))
+		ORDER BY due_at ASC NULLS LAST, id
+	`
+	campaigns := []models.Campaign{}
+	if err := r.db.SelectContext(ctx, &campaigns, query, status, owner); err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// Update changes the fields set in the update
+func (r *CampaignRepository) Update(ctx context.Context, id int, update *models.CampaignUpdate) (*models.Campaign, error) {
+	var owners interface{}
+	if update.Owners != nil {
+		owners = pq.StringArray(*update.Owners)
+	}
+
+	query := `
+		UPDATE campaigns SET
+			name = COALESCE($2, name),
+			description = COALESCE($3, description),
+			status = COALESCE($4, status),
+			owners = COALESCE($5, owners),
+			due_at = COALESCE($6, due_at),
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`
+	var campaign models.Campaign
+	if err := r.db.GetContext(ctx, &campaign, query,
+		id, update.Name, update.Description, update.Status, owners, update.DueAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("campaign")
+		}
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// Delete removes a campaign. Its vulnerabilities are left as they are
+func (r *CampaignRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM campaigns WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound("campaign")
+	}
+	return nil
+}
+
+// AddVulnerabilities attaches vulnerabilities to a campaign and returns how many were not attached yet.
+// Unknown vulnerability IDs are skipped
+func (r *CampaignRepository) AddVulnerabilities(ctx context.Context, id int, vulnerabilityIDs []int, addedBy string) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	added, err := attachVulnerabilities(ctx, tx, id, vulnerabilityIDs, &addedBy)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE campaigns SET updated_at = NOW() WHERE id = $1`, id); err != nil {
+		return 0, fmt.Errorf("failed to update campaign: %w", err)
+	}
+
+	return added, tx.Commit()
+}
+
+func attachVulnerabilities(ctx context.Context, tx *sqlx.Tx, id int, vulnerabilityIDs []int, addedBy *string) (int, error) {
+	if len(vulnerabilityIDs) == 0 {
+		return 0, nil
+	}
+	query := `
+		INSERT INTO campaign_vulnerabilities (campaign_id, vulnerability_id, added_by, added_at)
+		SELECT $1, id, $3, NOW() FROM vulnerabilities WHERE id = ANY($2)
+		ON CONFLICT (campaign_id, vulnerability_id) DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, query, id, pq.Array(vulnerabilityIDs), addedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to attach vulnerabilities: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
+
+// RemoveVulnerability detaches a vulnerability from a campaign. A vulnerability matching the
+// campaign's filter stays part of it
+func (r *CampaignRepository) RemoveVulnerability(ctx context.Context, id, vulnerabilityID int) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM campaign_vulnerabilities WHERE campaign_id = $1 AND vulnerability_id = $2`, id, vulnerabilityID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &domainError{kind: ErrNotFound, message: "vulnerability not attached to campaign"}
+	}
+	return nil
+}
+
+// Progress counts the vulnerabilities of each campaign by status. Campaigns without
+// vulnerabilities are left out of the map
+func (r *CampaignRepository) Progress(ctx context.Context, ids []int) (map[int]models.CampaignProgress, error) {
+	query := `
+		WITH members AS (` + campaignMembers + ` WHERE c.id = ANY($1))
+		SELECT
+			campaign_id,
+			COUNT(*) as total,
+			COUNT(*) FILTER (WHERE status = 'active') as active,
+			COUNT(*) FILTER (WHERE status = 'in_progress') as in_progress,
+			COUNT(*) FILTER (WHERE status = 'fixed') as fixed,
+			COUNT(*) FILTER (WHERE status = 'ignored') as ignored,
+			COUNT(*) FILTER (WHERE status = 'accepted') as accepted,
+			COUNT(*) FILTER (WHERE status IN ('active', 'in_progress') AND severity = 'Critical') as open_critical,
+			COUNT(*) FILTER (WHERE status IN ('active', 'in_progress') AND severity = 'High') as open_high,
+			COUNT(*) FILTER (WHERE status IN ('active', 'in_progress') AND severity = 'Medium') as open_medium,
+			COUNT(*) FILTER (WHERE status IN ('active', 'in_progress') AND severity = 'Low') as open_low,
+			COUNT(*) FILTER (WHERE status IN ('active', 'in_progress') AND severity = 'Negligible') as open_negligible,
+			COUNT(*) FILTER (WHERE status IN ('active', 'in_progress')) as open_total
+		FROM members
+		GROUP BY campaign_id
+	`
+	rows := []struct {
+		CampaignID int `db:"campaign_id"`
+		models.CampaignProgress
+		OpenCritical   int `db:"open_critical"`
+		OpenHigh       int `db:"open_high"`
+		OpenMedium     int `db:"open_medium"`
+		OpenLow        int `db:"open_low"`
+		OpenNegligible int `db:"open_negligible"`
+		OpenTotal      int `db:"open_total"`
+	}{}
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+
+	progress := make(map[int]models.CampaignProgress, len(ids))
+	for _, row := range rows {
+		p := row.CampaignProgress
+		p.OpenBy = models.SeverityCounts{
+			Critical:   row.OpenCritical,
+			High:       row.OpenHigh,
+			Medium:     row.OpenMedium,
+			Low:        row.OpenLow,
+			Negligible: row.OpenNegligible,
+			Unknown:    row.OpenTotal - row.OpenCritical - row.OpenHigh - row.OpenMedium - row.OpenLow - row.OpenNegligible,
+			Total:      row.OpenTotal,
+		}
+		p.SetPercentFixed()
+		progress[row.CampaignID] = p
+	}
+	return progress, nil
+}
+
+// Burndown returns the open and fixed vulnerabilities of a campaign at the end of each day, from the
+// day it was created (at most a year back) to now. Past statuses come from the vulnerability history
+func (r *CampaignRepository) Burndown(ctx context.Context, campaign *models.Campaign, now time.Time) ([]models.CampaignBurndownPoint, error) {
+	query := `
+		WITH members AS (` + campaignMembers + ` WHERE c.id = $1),
+		days AS (
+			SELECT day::date + 1 as day_end, to_char(day, 'YYYY-MM-DD') as date
+			FROM generate_series(GREATEST($2::date, $3::date - 364), $3::date, INTERVAL '1 day') day
+		),
+		statuses AS (
+			SELECT
+				d.date,
+				COALESCE(
+					(SELECT h.new_value FROM vulnerability_history h
+					 WHERE h.vulnerability_id = m.id AND h.field_name = 'status' AND h.changed_at < d.day_end
+					 ORDER BY h.changed_at DESC LIMIT 1),
+					(SELECT h.old_value FROM vulnerability_history h
+					 WHERE h.vulnerability_id = m.id AND h.field_name = 'status'
+					 ORDER BY h.changed_at ASC LIMIT 1),
+					m.status
+				) as status
+			FROM days d
+			JOIN members m ON m.first_detected_at < d.day_end
+		)
+		SELECT
+			d.date,
+			COUNT(s.status) FILTER (WHERE s.status IN ('active', 'in_progress')) as open,
+			COUNT(s.status) FILTER (WHERE s.status = 'fixed') as fixed
+		FROM days d
+		LEFT JOIN statuses s ON s.date = d.date
+		GROUP BY d.date
+		ORDER BY d.date
+	`
+	points := []models.CampaignBurndownPoint{}
+	if err := r.db.SelectContext(ctx, &points, query, campaign.ID, campaign.CreatedAt, now); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// CountVulnerabilities counts the vulnerabilities of a campaign, optionally only those with a status
+func (r *CampaignRepository) CountVulnerabilities(ctx context.Context, id int, status *string) (int, error) {
+	query := `
+		WITH members AS (` + campaignMembers + ` WHERE c.id = $1)
+		SELECT COUNT(*) FROM members WHERE $2::text IS NULL OR status = $2
+	`
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, id, status).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListVulnerabilities returns the vulnerabilities of a campaign, most severe first
+func (r *CampaignRepository) ListVulnerabilities(ctx context.Context, id int, status *string, limit, offset int) ([]models.Vulnerability, error) {
+	query := `
+		WITH members AS (` + campaignMembers + ` WHERE c.id = $1)
+		SELECT v.* FROM vulnerabilities v
+		WHERE v.id IN (SELECT id FROM members) AND ($2::text IS NULL OR v.status = $2)
+		ORDER BY
+			CASE v.severity
+				WHEN 'Critical' THEN 1
+				WHEN 'High' THEN 2
+				WHEN 'Medium' THEN 3
+				WHEN 'Low' THEN 4
+				ELSE 5
+			END,
+			v.cve_id, v.package_name, v.id
+		LIMIT $3 OFFSET $4
+	`
+	vulns := []models.Vulnerability{}
+	if err := r.db.SelectContext(ctx, &vulns, query, id, status, limit, offset); err != nil {
+		return nil, err
+	}
+	if err := r.db.decryptVulnerabilities(vulns); err != nil {
+		return nil, err
+	}
+	return vulns, nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaignRepository(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewCampaignRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+
+	now := time.Now()
+	newVuln := func(cve, pkg, version, severity string) *models.Vulnerability {
+		vuln := &models.Vulnerability{
+			CVEID: cve, PackageName: pkg, PackageVersion: version, Severity: severity,
+			Status: "active", FirstDetectedAt: now, LastSeenAt: now,
+		}
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		return vuln
+	}
+	openssl3 := newVuln("CVE-2024-0001", "openssl", "3.0.7", "Critical")
+	openssl31 := newVuln("CVE-2024-0002", "openssl", "3.1.1", "High")
+	newVuln("CVE-2024-0003", "openssl", "1.1.1", "High")
+	zlib := newVuln("CVE-2024-0004", "zlib", "1.2.11", "Medium")
+
+	// The filter selects OpenSSL 3.x, zlib is attached by hand
+	pkg, prefix, owner := "openssl", "3.", "alice@example.com"
+	campaign := &models.Campaign{
+		Name:           "OpenSSL 3.x upgrade",
+		Status:         models.CampaignStatusActive,
+		Owners:         []string{owner},
+		CampaignFilter: models.CampaignFilter{PackageName: &pkg, PackageVersionPrefix: &prefix},
+		CreatedBy:      &owner,
+	}
+	require.NoError(t, repo.Create(ctx, campaign, []int{zlib.ID}))
+
+	count, err := repo.CountVulnerabilities(ctx, campaign.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	vulns, err := repo.ListVulnerabilities(ctx, campaign.ID, nil, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, vulns, 3)
+	assert.Equal(t, []int{openssl3.ID, openssl31.ID, zlib.ID}, []int{vulns[0].ID, vulns[1].ID, vulns[2].ID})
+
+	require.NoError(t, vulnRepo.MarkAsFixed(ctx, []int{openssl3.ID}))
+
+	progress, err := repo.Progress(ctx, []int{campaign.ID})
+	require.NoError(t, err)
+	p := progress[campaign.ID]
+	assert.Equal(t, 3, p.Total)
+	assert.Equal(t, 1, p.Fixed)
+	assert.Equal(t, 2, p.Active)
+	assert.Equal(t, 33.3, p.PercentFixed)
+	assert.Equal(t, 1, p.OpenBy.High)
+	assert.Equal(t, 1, p.OpenBy.Medium)
+
+	burndown, err := repo.Burndown(ctx, campaign, now)
+	require.NoError(t, err)
+	require.NotEmpty(t, burndown)
+	last := burndown[len(burndown)-1]
+	assert.Equal(t, 2, last.Open)
+	assert.Equal(t, 1, last.Fixed)
+
+	owned, err := repo.List(ctx, nil, &owner)
+	require.NoError(t, err)
+	require.Len(t, owned, 1)
+
+	// Detaching only removes attached vulnerabilities
+	assert.Error(t, repo.RemoveVulnerability(ctx, campaign.ID, openssl31.ID))
+	assert.NoError(t, repo.RemoveVulnerability(ctx, campaign.ID, zlib.ID))
+
+	status := models.CampaignStatusCompleted
+	updated, err := repo.Update(ctx, campaign.ID, &models.CampaignUpdate{Status: &status})
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignStatusCompleted, updated.Status)
+	assert.Equal(t, "OpenSSL 3.x upgrade", updated.Name)
+
+	require.NoError(t, repo.Delete(ctx, campaign.ID))
+	_, err = repo.GetByID(ctx, campaign.ID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// ComplianceReportRepository records the generated compliance reports and reads what they report:
+// the vulnerability posture and the vulnerabilities found and fixed over a period
+type ComplianceReportRepository struct {
+	db *Database
+}
+
+// NewComplianceReportRepository creates a new compliance report repository
+func NewComplianceReportRepository(db *Database) *ComplianceReportRepository {
+	return &ComplianceReportRepository{db: db}
+}
+
+// Create records a generated report. A scheduled report of a period already recorded, by another
+// replica, is an ErrConflict
+func (r *ComplianceReportRepository) Create(ctx context.Context, report *models.ComplianceReport) error {
+	if report.Destinations == nil {
+		report.Destinations = pq.StringArray{}
+	}
+	if report.Errors == nil {
+		report.Errors = pq.StringArray{}
+	}
+	query := `
+		INSERT INTO compliance_reports (schedule, format, period_start, period_end, size_bytes, storage_key,
+			destinations, errors, requested_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		report.Schedule, report.Format, report.PeriodStart, report.PeriodEnd, report.SizeBytes, report.StorageKey,
+		report.Destinations, report.Errors, report.RequestedBy,
+	).Scan(&report.ID, &report.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return conflict("report of this schedule and period already exists")
+		}
+		return fmt.Errorf("failed to record compliance report: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a report
+func (r *ComplianceReportRepository) GetByID(ctx context.Context, id int64) (*models.ComplianceReport, error) {
+	var report models.ComplianceReport
+	if err := r.db.GetContext(ctx, &report, `SELECT * FROM compliance_reports WHERE id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("compliance report")
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// List returns the reports, most recent first. schedule filters them by schedule
+func (r *ComplianceReportRepository) List(ctx context.Context, schedule *string, limit, offset int) ([]models.ComplianceReport, error) {
+	query := `
+		SELECT * FROM compliance_reports
+		WHERE ($1::text IS NULL OR schedule = $1)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	reports := []models.ComplianceReport{}
+	if err := r.db.SelectContext(ctx, &reports, query, schedule, limit, offset); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// Count returns the number of reports List filters
+func (r *ComplianceReportRepository) Count(ctx context.Context, schedule *string) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM compliance_reports WHERE ($1::text IS NULL OR schedule = $1)`, schedule)
+	return count, err
+}
+
+// LastPeriodEnd returns the end of the period of the last report of a schedule, nil before its first
+func (r *ComplianceReportRepository) LastPeriodEnd(ctx context.Context, schedule string) (*time.Time, error) {
+	var end *time.Time
+	if err := r.db.GetContext(ctx, &end, `SELECT MAX(period_end) FROM compliance_reports WHERE schedule = $1`, schedule); err != nil {
+		return nil, fmt.Errorf("failed to get the last report of %s: %w", schedule, err)
+	}
+	return end, nil
+}
+
+// openVulnerabilities are the open vulnerabilities (active or in progress) found by the latest scan of
+// each image and target, with the identity of the image: its digest, shared by its aliases
+const openVulnerabilities = `
+	WITH latest AS (
+		SELECT DISTINCT ON (s.image_id, s.target) s.id, COALESCE(i.digest, 'image:' || i.id) AS image
+		FROM scans s
+		JOIN images i ON i.id = s.image_id
+		WHERE s.status IN ('completed', 'partial')
+		ORDER BY s.image_id, s.target, s.scan_date DESC, s.id DESC
+	),
+	open AS (
+		SELECT DISTINCT l.image, v.id, v.severity, v.known_exploited, v.fix_version IS NOT NULL AS fixable
+		FROM latest l
+		JOIN scan_vulnerabilities sv ON sv.scan_id = l.id
+		JOIN vulnerabilities v ON v.id = sv.vulnerability_id
+		WHERE v.status IN ('active', 'in_progress')
+	)
+`
+
+// Posture returns the open vulnerabilities of the images as of their latest scans, by severity. Aliases
+// are counted as one image
+func (r *ComplianceReportRepository) Posture(ctx context.Context) (*models.ReportPosture, error) {
+	posture := &models.ReportPosture{}
+	query := openVulnerabilities + `
+		SELECT
+			(SELECT COUNT(DISTINCT image) FROM latest) AS images,
+			(SELECT COUNT(DISTINCT image) FROM open WHERE severity = 'Critical') AS images_with_critical,
+			(SELECT COUNT(DISTINCT id) FROM open) AS open_vulnerabilities,
+			(SELECT COUNT(DISTINCT id) FROM open WHERE known_exploited) AS known_exploited
+	`
+	if err := r.db.GetContext(ctx, posture, query); err != nil {
+		return nil, fmt.Errorf("failed to get vulnerability posture: %w", err)
+	}
+
+	query = openVulnerabilities + `
+		SELECT severity, COUNT(DISTINCT id) AS open, COUNT(DISTINCT id) FILTER (WHERE fixable) AS fixable
+		FROM open
+		GROUP BY severity
+		ORDER BY ` + severityRank + ` DESC
+	`
+	posture.Severities = []models.ReportSeverityRow{}
+	if err := r.db.SelectContext(ctx, &posture.Severities, query); err != nil {
+		return nil, fmt.Errorf("failed to count open vulnerabilities: %w", err)
+	}
+	return posture, nil
+}
+
+// Trends counts the vulnerabilities first detected and fixed on each day from start to end, in UTC
+func (r *ComplianceReportRepository) Trends(ctx context.Context, start, end time.Time) ([]models.ReportTrend, error) {
+	query := `
+		SELECT
+			to_char(day AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS date,
+			(SELECT COUNT(*) FROM vulnerabilities v
+				WHERE v.first_detected_at >= GREATEST(day, $1::timestamptz) AND v.first_detected_at < LEAST(day + INTERVAL '1 day', $2::timestamptz)) AS new,
+			(SELECT COUNT(*) FROM vulnerabilities v
+				WHERE v.status = 'fixed'
+					AND v.remediation_date >= GREATEST(day, $1::timestamptz) AND v.remediation_date < LEAST(day + INTERVAL '1 day', $2::timestamptz)) AS fixed
+		FROM generate_series(date_trunc('day', $1::timestamptz, 'UTC'), $2::timestamptz - INTERVAL '1 microsecond', INTERVAL '1 day') AS day
+		ORDER BY day
+	`
+	trends := []models.ReportTrend{}
+	if err := r.db.SelectContext(ctx, &trends, query, start, end); err != nil {
+		return nil, fmt.Errorf("failed to count new and fixed vulnerabilities: %w", err)
+	}
+	return trends, nil
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// ComponentRepository queries packages by PURL. Only packages with findings are known,
+// since PURLs come from the artifacts of Grype matches
+type ComponentRepository struct {
+	db *Database
+}
+
+func NewComponentRepository(db *Database) *ComponentRepository {
+	return &ComponentRepository{db: db}
+}
+
+// ListByPURL returns the components matching a PURL, grouped by their full PURL.
+// Qualifiers and subpaths are ignored unless the query has some, and a query without
+// a version (pkg:npm/lodash) matches every version
+func (r *ComponentRepository) ListByPURL(ctx context.Context, purl string) ([]models.Component, error) {
+	conditions := []string{"v.purl = $1"}
+	args := []interface{}{purl}
+	if !strings.ContainsAny(purl, "?#") {
+		prefixes := []string{"?", "#"}
+		if !strings.Contains(strings.TrimPrefix(purl, "pkg:"), "@") {
+			prefixes = append(prefixes, "@")
+		}
+		for _, prefix := range prefixes {
+			args = append(args, escapeLike(purl)+prefix+"%")
+			conditions = append(conditions, fmt.Sprintf("v.purl LIKE $%d", len(args)))
+		}
+	}
+
+	vulns := []models.Vulnerability{}
+	query := `SELECT v.* FROM vulnerabilities v WHERE ` + strings.Join(conditions, " OR ") +
+		` ORDER BY v.purl, v.cve_id`
+	if err := r.db.SelectContext(ctx, &vulns, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list vulnerabilities by purl: %w", err)
+	}
+	if err := r.db.decryptVulnerabilities(vulns); err != nil {
+		return nil, err
+	}
+
+	components := []models.Component{}
+	index := make(map[string]int)
+	ids := make([]int, 0, len(vulns))
+	for _, v := range vulns {
+		ids = append(ids, v.ID)
+		i, ok := index[*v.PURL]
+		if !ok {
+			i = len(components)
+			index[*v.PURL] = i
+			components = append(components, models.Component{
+				PURL:            *v.PURL,
+				Name:            v.PackageName,
+				Version:         v.PackageVersion,
+				Type:            v.PackageType,
+				Images:          []models.ComponentImage{},
+				Vulnerabilities: []models.Vulnerability{},
+			})
+		}
+		components[i].Vulnerabilities = append(components[i].Vulnerabilities, v)
+	}
+	if len(ids) == 0 {
+		return components, nil
+	}
+
+	var rows []struct {
+		PURL string `db:"purl"`
+		models.ComponentImage
+	}
+	query = `
+		SELECT DISTINCT ON (v.purl, i.id)
+			v.purl,
+			i.id as image_id,
+			i.registry || '/' || i.repository || ':' || i.tag as image_name,
+			s.id as latest_scan_id,
+			s.scan_date as latest_scan_date
+		FROM vulnerabilities v
+		JOIN scan_vulnerabilities sv ON sv.vulnerability_id = v.id
+		JOIN scans s ON s.id = sv.scan_id
+		JOIN images i ON i.id = s.image_id
+		WHERE v.id = ANY($1)
+		ORDER BY v.purl, i.id, s.scan_date DESC
+	`
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("failed to list component images: %w", err)
+	}
+	for _, row := range rows {
+		i := index[row.PURL]
+		components[i].Images = append(components[i].Images, row.ComponentImage)
+	}
+
+	return components, nil
+}
+
+// escapeLike escapes the LIKE wildcards, which are valid in PURL names (e.g. pkg:pypi/typing_extensions)
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
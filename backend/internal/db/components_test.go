@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponentRepository_ListByPURL(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	repo := NewComponentRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "acme/web", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: models.ScanStatusCompleted}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+
+	for _, v := range []struct{ cve, name, version, purl string }{
+		{"CVE-2021-23337", "lodash", "4.17.20", "pkg:npm/lodash@4.17.20"},
+		{"CVE-2020-8203", "lodash", "4.17.15", "pkg:npm/lodash@4.17.15"},
+		{"CVE-2020-28500", "lodash", "4.17.20", "pkg:npm/lodash@4.17.20"},
+		// Shares the prefix but is another package
+		{"CVE-2019-10744", "lodash.merge", "4.6.1", "pkg:npm/lodash.merge@4.6.1"},
+	} {
+		purl := v.purl
+		vuln := &models.Vulnerability{
+			CVEID: v.cve, PackageName: v.name, PackageVersion: v.version, PURL: &purl,
+			Severity: "High", Status: models.StatusActive, FirstDetectedAt: time.Now(), LastSeenAt: time.Now(),
+		}
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+	}
+
+	components, err := repo.ListByPURL(ctx, "pkg:npm/lodash")
+	require.NoError(t, err)
+	require.Len(t, components, 2)
+	assert.Equal(t, "pkg:npm/lodash@4.17.15", components[0].PURL)
+	assert.Equal(t, "pkg:npm/lodash@4.17.20", components[1].PURL)
+	assert.Len(t, components[1].Vulnerabilities, 2)
+	require.Len(t, components[1].Images, 1)
+	assert.Equal(t, scan.ID, components[1].Images[0].LatestScanID)
+
+	components, err = repo.ListByPURL(ctx, "pkg:npm/lodash@4.17.15")
+	require.NoError(t, err)
+	require.Len(t, components, 1)
+	assert.Equal(t, "CVE-2020-8203", components[0].Vulnerabilities[0].CVEID)
+}
@@ -5,12 +5,23 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/invulnerable/backend/internal/encryption"
+	"github.com/invulnerable/backend/internal/events"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
 type Database struct {
 	*sqlx.DB
+
+	// cipher encrypts sensitive columns, nil stores them in plaintext
+	cipher *encryption.Cipher
+
+	// slaSeverity is the sla.Severity policy SLA deadlines are computed with
+	slaSeverity string
+
+	// events receives the changes committed, nil publishes nothing
+	events *events.Bus
 }
 
 type Config struct {
@@ -44,7 +55,17 @@ func New(cfg Config) (*Database, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Database{db}, nil
+	return &Database{DB: db}, nil
+}
+
+// SetEvents publishes the vulnerability status changes committed on bus
+func (d *Database) SetEvents(bus *events.Bus) {
+	d.events = bus
+}
+
+// SetSLASeverityPolicy sets the severity SLA deadlines are counted from, sla.SeverityAtDetection by default
+func (d *Database) SetSLASeverityPolicy(policy string) {
+	d.slaSeverity = policy
 }
 
 func (d *Database) Close() error {
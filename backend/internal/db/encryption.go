@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/invulnerable/backend/internal/encryption"
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// SetCipher enables encryption of webhook URLs and notes. Plaintext rows written before
+// stay readable until Reencrypt rewrites them
+func (d *Database) SetCipher(c *encryption.Cipher) {
+	d.cipher = c
+}
+
+func (d *Database) encrypt(value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encrypted, err := d.cipher.Encrypt(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &encrypted, nil
+}
+
+// decrypt decrypts a column value in place
+func (d *Database) decrypt(value *string) error {
+	if value == nil {
+		return nil
+	}
+	plaintext, err := d.cipher.Decrypt(*value)
+	if err != nil {
+		return err
+	}
+	*value = plaintext
+	return nil
+}
+
+func (d *Database) decryptVulnerabilities(vulns []models.Vulnerability) error {
+	for i := range vulns {
+		if err := d.decrypt(vulns[i].Notes); err != nil {
+			return fmt.Errorf("failed to decrypt notes of vulnerability %d: %w", vulns[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// encryptedColumn is a column holding sensitive values. Only rows matching where are encrypted
+type encryptedColumn struct {
+	table  string
+	column string
+	where  string
+}
+
+var encryptedColumns = []encryptedColumn{
+	{table: "imagescan_webhook_configs", column: "webhook_url"},
+	{table: "watchlist_subscriptions", column: "webhook_url"},
+	{table: "vulnerabilities", column: "notes"},
+	{table: "vulnerability_history", column: "old_value", where: "field_name = 'notes'"},
+	{table: "vulnerability_history", column: "new_value", where: "field_name = 'notes'"},
+	{table: "notification_outbox", column: "payload", where: "status = 'pending'"},
+}
+
+const reencryptBatchSize = 500
+
+// Reencrypt rewrites the sensitive columns with target: plaintext values and values encrypted
+// with a previous key are encrypted with its primary key, or decrypted when target is nil.
+// Values are read with the cipher of the database, which must hold every key still in use.
+// It returns the number of values rewritten per table.column and can be run on a live database
+func (d *Database) Reencrypt(ctx context.Context, target *encryption.Cipher) (map[string]int, error) {
+	rewritten := make(map[string]int, len(encryptedColumns))
+	for _, col := range encryptedColumns {
+		name := col.table + "." + col.column
+		where := col.column + " IS NOT NULL"
+		if col.where != "" {
+			where += " AND " + col.where
+		}
+		selectQuery := fmt.Sprintf(`SELECT id, %s AS value FROM %s WHERE id > $1 AND %s ORDER BY id LIMIT $2`,
+			col.column, col.table, where)
+		// Only rows unchanged since they were read, a concurrent update already wrote the current format
+		updateQuery := fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE id = $2 AND %s = $3`, col.table, col.column, col.column)
+
+		lastID := 0
+		for {
+			var rows []struct {
+				ID    int    `db:"id"`
+				Value string `db:"value"`
+			}
+			if err := d.SelectContext(ctx, &rows, selectQuery, lastID, reencryptBatchSize); err != nil {
+				return rewritten, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			for _, row := range rows {
+				lastID = row.ID
+				if !target.NeedsReencryption(row.Value) {
+					continue
+				}
+				plaintext, err := d.cipher.Decrypt(row.Value)
+				if err != nil {
+					return rewritten, fmt.Errorf("failed to decrypt %s of row %d: %w", name, row.ID, err)
+				}
+				value, err := target.Encrypt(plaintext)
+				if err != nil {
+					return rewritten, err
+				}
+				result, err := d.ExecContext(ctx, updateQuery, value, row.ID, row.Value)
+				if err != nil {
+					return rewritten, fmt.Errorf("failed to update %s of row %d: %w", name, row.ID, err)
+				}
+				if n, _ := result.RowsAffected(); n > 0 {
+					rewritten[name]++
+				}
+			}
+			if len(rows) < reencryptBatchSize {
+				break
+			}
+		}
+	}
+	return rewritten, nil
+}
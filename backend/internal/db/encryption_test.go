@@ -0,0 +1,82 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/encryption"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedColumns(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	vulnRepo := NewVulnerabilityRepository(db)
+	webhookRepo := NewWebhookConfigRepository(db)
+
+	// Written before encryption was enabled
+	require.NoError(t, webhookRepo.Upsert(ctx, "payments", "api", &models.WebhookConfigRequest{
+		WebhookURL: "https://hooks.slack.com/services/T0/B0/legacy", WebhookFormat: "slack",
+	}))
+	vuln := &models.Vulnerability{CVEID: "CVE-2024-0001", PackageName: "openssl", PackageVersion: "3.0.0", Severity: "High", Status: models.StatusActive}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	legacyNotes := "waiting for upstream"
+	require.NoError(t, vulnRepo.Update(ctx, vuln.ID, &models.VulnerabilityUpdateWithContext{Notes: &legacyNotes, UpdatedBy: "alice"}))
+
+	oldKey := bytes.Repeat([]byte{1}, encryption.KeySize)
+	oldCipher, err := encryption.New(oldKey)
+	require.NoError(t, err)
+	db.SetCipher(oldCipher)
+
+	notes := "token in ticket SEC-42"
+	require.NoError(t, vulnRepo.Update(ctx, vuln.ID, &models.VulnerabilityUpdateWithContext{Notes: &notes, UpdatedBy: "alice"}))
+
+	var stored string
+	require.NoError(t, db.GetContext(ctx, &stored, `SELECT notes FROM vulnerabilities WHERE id = $1`, vuln.ID))
+	assert.True(t, encryption.IsEncrypted(stored))
+
+	got, err := vulnRepo.GetByID(ctx, vuln.ID)
+	require.NoError(t, err)
+	assert.Equal(t, notes, *got.Notes)
+
+	history, err := vulnRepo.GetHistory(ctx, vuln.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, notes, *history[0].NewValue)
+	assert.Equal(t, legacyNotes, *history[0].OldValue)
+
+	// Plaintext rows are still read
+	config, err := webhookRepo.Get(ctx, "payments", "api")
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.slack.com/services/T0/B0/legacy", config.WebhookURL)
+
+	// Rotate: everything ends up encrypted with the new key
+	rotated, err := encryption.New(bytes.Repeat([]byte{2}, encryption.KeySize), oldKey)
+	require.NoError(t, err)
+	db.SetCipher(rotated)
+	rewritten, err := db.Reencrypt(ctx, rotated)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rewritten["imagescan_webhook_configs.webhook_url"])
+	assert.Equal(t, 1, rewritten["vulnerabilities.notes"])
+
+	newOnly, err := encryption.New(bytes.Repeat([]byte{2}, encryption.KeySize))
+	require.NoError(t, err)
+	db.SetCipher(newOnly)
+	config, err = webhookRepo.Get(ctx, "payments", "api")
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.slack.com/services/T0/B0/legacy", config.WebhookURL)
+	history, err = vulnRepo.GetHistory(ctx, vuln.ID)
+	require.NoError(t, err)
+	assert.Equal(t, legacyNotes, *history[1].NewValue)
+
+	// Decrypting writes plaintext back
+	_, err = db.Reencrypt(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.GetContext(ctx, &stored, `SELECT notes FROM vulnerabilities WHERE id = $1`, vuln.ID))
+	assert.Equal(t, notes, stored)
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of errors returned by repositories, matched with errors.Is. The API maps them to HTTP
+// statuses, any other error is an internal one
+var (
+	// ErrNotFound is returned when the requested row doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write conflicts with an existing row
+	ErrConflict = errors.New("conflict")
+	// ErrValidation is returned for values the database can't store
+	ErrValidation = errors.New("validation failed")
+)
+
+// domainError is an error of one of the kinds above, with a message meant for API clients
+type domainError struct {
+	kind    error
+	message string
+}
+
+func (e *domainError) Error() string {
+	return e.message
+}
+
+func (e *domainError) Is(target error) bool {
+	return target == e.kind
+}
+
+// notFound returns the ErrNotFound error of what, e.g. "scan not found"
+func notFound(what string) error {
+	return &domainError{kind: ErrNotFound, message: what + " not found"}
+}
+
+// conflict returns an ErrConflict error
+func conflict(message string) error {
+	return &domainError{kind: ErrConflict, message: message}
+}
+
+// validationError returns an ErrValidation error
+func validationError(format string, args ...interface{}) error {
+	return &domainError{kind: ErrValidation, message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,21 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainErrors(t *testing.T) {
+	err := notFound("scan")
+	assert.EqualError(t, err, "scan not found")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, fmt.Errorf("failed to get scan: %w", err), ErrNotFound)
+	assert.False(t, errors.Is(err, ErrConflict))
+
+	assert.ErrorIs(t, ErrReportExists, ErrConflict)
+	assert.ErrorIs(t, ValidateStatus("done"), ErrValidation)
+	assert.NoError(t, ValidateStatus("fixed"))
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/storage"
+)
+
+// GrypeResultRepository archives the raw Grype result of scans, metadata in the database and
+// the document in S3 like SBOMs
+type GrypeResultRepository struct {
+	db      *Database
+	storage storage.GrypeResultStorage
+}
+
+func NewGrypeResultRepository(db *Database, s3Storage storage.GrypeResultStorage) *GrypeResultRepository {
+	return &GrypeResultRepository{
+		db:      db,
+		storage: s3Storage,
+	}
+}
+
+// Create stores the document in S3 and its metadata in the database
+func (r *GrypeResultRepository) Create(ctx context.Context, scanID int, document []byte) (*models.GrypeResultArchive, error) {
+	return r.CreateFrom(ctx, scanID, io.NewSectionReader(bytes.NewReader(document), 0, int64(len(document))))
+}
+
+// CreateFrom is Create with the document read from a file, streamed to S3
+func (r *GrypeResultRepository) CreateFrom(ctx context.Context, scanID int, document *io.SectionReader) (*models.GrypeResultArchive, error) {
+	if err := storage.StoreDocument(ctx, r.storage, scanID, document); err != nil {
+		return nil, fmt.Errorf("failed to store Grype result in S3: %w", err)
+	}
+
+	sizeBytes := document.Size()
+	archive := &models.GrypeResultArchive{ScanID: scanID, SizeBytes: &sizeBytes}
+	query := `
+		INSERT INTO grype_results (scan_id, size_bytes, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (scan_id)
+		DO UPDATE SET size_bytes = EXCLUDED.size_bytes, created_at = EXCLUDED.created_at
+		RETURNING id, created_at
+	`
+	if err := r.db.QueryRowContext(ctx, query, scanID, sizeBytes).Scan(&archive.ID, &archive.CreatedAt); err != nil {
+		// Rollback: delete from S3 if database insert failed
+		_ = r.storage.Delete(ctx, scanID)
+		return nil, fmt.Errorf("failed to store Grype result metadata: %w", err)
+	}
+
+	return archive, nil
+}
+
+// GetByScanID retrieves the metadata of the archived result of a scan
+func (r *GrypeResultRepository) GetByScanID(ctx context.Context, scanID int) (*models.GrypeResultArchive, error) {
+	var archive models.GrypeResultArchive
+	query := `SELECT * FROM grype_results WHERE scan_id = $1`
+	if err := r.db.GetContext(ctx, &archive, query, scanID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("grype result")
+		}
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// GetDocumentByScanID retrieves the archived Grype JSON of a scan from S3
+func (r *GrypeResultRepository) GetDocumentByScanID(ctx context.Context, scanID int) ([]byte, error) {
+	// Verify the result is archived, it may have expired
+	if _, err := r.GetByScanID(ctx, scanID); err != nil {
+		return nil, err
+	}
+
+	document, err := r.storage.Retrieve(ctx, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Grype result from S3: %w", err)
+	}
+	return document, nil
+}
+
+// ListScanIDsByImage returns the scans of an image with an archived result
+func (r *GrypeResultRepository) ListScanIDsByImage(ctx context.Context, imageID int) ([]int, error) {
+	scanIDs := []int{}
+	query := `
+		SELECT g.scan_id FROM grype_results g
+		JOIN scans s ON s.id = g.scan_id
+		WHERE s.image_id = $1
+		ORDER BY g.scan_id
+	`
+	if err := r.db.SelectContext(ctx, &scanIDs, query, imageID); err != nil {
+		return nil, fmt.Errorf("failed to list archived Grype results: %w", err)
+	}
+	return scanIDs, nil
+}
+
+// Expire removes the metadata of results archived before the cutoff and returns their scan IDs,
+// so the caller deletes the documents once the rows are gone
+func (r *GrypeResultRepository) Expire(ctx context.Context, before time.Time) ([]int, error) {
+	scanIDs := []int{}
+	query := `DELETE FROM grype_results WHERE created_at < $1 RETURNING scan_id`
+	if err := r.db.SelectContext(ctx, &scanIDs, query, before); err != nil {
+		return nil, fmt.Errorf("failed to expire Grype results: %w", err)
+	}
+	return scanIDs, nil
+}
+
+// DeleteDocuments removes Grype result documents from S3 whose metadata is already gone.
+// It keeps going after a failure so one missing object does not leave the others behind
+func (r *GrypeResultRepository) DeleteDocuments(ctx context.Context, scanIDs []int) error {
+	var errs []error
+	for _, scanID := range scanIDs {
+		if err := r.storage.Delete(ctx, scanID); err != nil {
+			errs = append(errs, fmt.Errorf("scan %d: %w", scanID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/invulnerable/backend/internal/models"
 )
@@ -16,17 +17,29 @@ func NewImageRepository(db *Database) *ImageRepository {
 	return &ImageRepository{db: db}
 }
 
+// Create gets or creates the image, updating its digest. img.Discovered tells whether it was created
 func (r *ImageRepository) Create(ctx context.Context, img *models.Image) error {
+	// A new image starts paused if it is only scanned by suspended ImageScans,
+	// since those may have been registered before the image was first scanned.
+	// xmax is only 0 for a row the upsert inserted
 	query := `
-		INSERT INTO images (registry, repository, tag, digest, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		INSERT INTO images (registry, repository, tag, digest, monitoring, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, CASE
+			WHEN EXISTS (
+				SELECT 1 FROM imagescans WHERE registry = $1 AND repository = $2 AND tag = $3
+			) AND NOT EXISTS (
+				SELECT 1 FROM imagescans WHERE registry = $1 AND repository = $2 AND tag = $3 AND NOT suspended
+			) THEN 'paused'
+			ELSE 'active'
+		END, NOW(), NOW())
 		ON CONFLICT (registry, repository, tag)
 		DO UPDATE SET digest = EXCLUDED.digest, updated_at = NOW()
-		RETURNING id, created_at, updated_at
+		RETURNING id, monitoring, team, criticality, reviewed_at, reviewed_by, created_at, updated_at, xmax = 0
 	`
 	return r.db.QueryRowContext(ctx, query,
 		img.Registry, img.Repository, img.Tag, img.Digest,
-	).Scan(&img.ID, &img.CreatedAt, &img.UpdatedAt)
+	).Scan(&img.ID, &img.Monitoring, &img.Team, &img.Criticality, &img.ReviewedAt, &img.ReviewedBy,
+		&img.CreatedAt, &img.UpdatedAt, &img.Discovered)
 }
 
 func (r *ImageRepository) GetByID(ctx context.Context, id int) (*models.Image, error) {
@@ -34,7 +47,7 @@ func (r *ImageRepository) GetByID(ctx context.Context, id int) (*models.Image, e
 	query := `SELECT * FROM images WHERE id = $1`
 	if err := r.db.GetContext(ctx, &img, query, id); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("image not found")
+			return nil, notFound("image")
 		}
 		return nil, err
 	}
@@ -46,23 +59,57 @@ func (r *ImageRepository) GetByName(ctx context.Context, registry, repository, t
 	query := `SELECT * FROM images WHERE registry = $1 AND repository = $2 AND tag = $3`
 	if err := r.db.GetContext(ctx, &img, query, registry, repository, tag); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("image not found")
+			return nil, notFound("image")
 		}
 		return nil, err
 	}
 	return &img, nil
 }
 
-func (r *ImageRepository) Count(ctx context.Context) (int, error) {
-	query := `SELECT COUNT(*) FROM images`
+// aliasCount counts the other images with the digest of image i
+const aliasCount = `(SELECT COUNT(*) FROM images a WHERE a.digest = i.digest AND a.id <> i.id)`
+
+// ListAliases returns the other images with the digest of img, the names the same content is pushed
+// under, latest scanned first. An image without a digest has none
+func (r *ImageRepository) ListAliases(ctx context.Context, img *models.Image) ([]models.ImageAlias, error) {
+	aliases := []models.ImageAlias{}
+	if img.Digest == nil || *img.Digest == "" {
+		return aliases, nil
+	}
+	query := `
+		SELECT a.id, a.registry, a.repository, a.tag,
+			(SELECT MAX(s.scan_date) FROM scans s WHERE s.image_id = a.id) AS last_scan_date
+		FROM images a
+		WHERE a.digest = $1 AND a.id <> $2
+		ORDER BY last_scan_date DESC NULLS LAST, a.id
+	`
+	if err := r.db.SelectContext(ctx, &aliases, query, *img.Digest, img.ID); err != nil {
+		return nil, fmt.Errorf("failed to list aliases of image %d: %w", img.ID, err)
+	}
+	return aliases, nil
+}
+
+// Count returns the number of images, only the unreviewed ones with unreviewed
+func (r *ImageRepository) Count(ctx context.Context, unreviewed bool) (int, error) {
+	query := `SELECT COUNT(*) FROM images WHERE NOT $1 OR reviewed_at IS NULL`
 	var count int
-	if err := r.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+	if err := r.db.QueryRowContext(ctx, query, unreviewed).Scan(&count); err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-func (r *ImageRepository) List(ctx context.Context, limit, offset int, hasFix *bool) ([]models.ImageWithStats, error) {
+// List returns a page of the images with their stats, ordered by sort or latest updated first, only the
+// unreviewed ones with unreviewed
+func (r *ImageRepository) List(ctx context.Context, limit, offset int, sort Sort, hasFix *bool, unreviewed bool) ([]models.ImageWithStats, error) {
+	orderBy := ` ORDER BY i.updated_at DESC`
+	if sort != (Sort{}) {
+		var err error
+		if orderBy, err = imageSort.orderBy(sort, "i.id"); err != nil {
+			return nil, err
+		}
+	}
+
 	// Build fix filter
 	fixFilter := "1=1"
 	if hasFix != nil {
@@ -81,17 +128,69 @@ func (r *ImageRepository) List(ctx context.Context, limit, offset int, hasFix *b
 			COUNT(DISTINCT CASE WHEN v.severity = 'Critical' AND v.status = 'active' AND ` + fixFilter + ` THEN v.id END) as critical_count,
 			COUNT(DISTINCT CASE WHEN v.severity = 'High' AND v.status = 'active' AND ` + fixFilter + ` THEN v.id END) as high_count,
 			COUNT(DISTINCT CASE WHEN v.severity = 'Medium' AND v.status = 'active' AND ` + fixFilter + ` THEN v.id END) as medium_count,
-			COUNT(DISTINCT CASE WHEN v.severity = 'Low' AND v.status = 'active' AND ` + fixFilter + ` THEN v.id END) as low_count
+			COUNT(DISTINCT CASE WHEN v.severity = 'Low' AND v.status = 'active' AND ` + fixFilter + ` THEN v.id END) as low_count,
+			` + aliasCount + ` as alias_count
 		FROM images i
 		LEFT JOIN scans s ON s.image_id = i.id
 		LEFT JOIN scan_vulnerabilities sv ON sv.scan_id = s.id
 		LEFT JOIN vulnerabilities v ON v.id = sv.vulnerability_id
+		WHERE NOT $3 OR i.reviewed_at IS NULL
 		GROUP BY i.id
-		ORDER BY i.updated_at DESC
+	` + orderBy + `
 		LIMIT $1 OFFSET $2
 	`
 	images := []models.ImageWithStats{}
-	if err := r.db.SelectContext(ctx, &images, query, limit, offset); err != nil {
+	if err := r.db.SelectContext(ctx, &images, query, limit, offset, unreviewed); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// CountAsOf returns the number of images scanned by asOf
+func (r *ImageRepository) CountAsOf(ctx context.Context, asOf time.Time) (int, error) {
+	query := `SELECT COUNT(DISTINCT image_id) FROM scans WHERE scan_date <= $1`
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, asOf).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListAsOf reconstructs the images scanned by asOf as they were then: their scans until then, and the vulnerabilities
+// of their latest successful scans then that were active at the time, ordered by sort or latest scanned first
+func (r *ImageRepository) ListAsOf(ctx context.Context, asOf time.Time, limit, offset int, sort Sort) ([]models.ImageWithStats, error) {
+	orderBy := ` ORDER BY last_scan_date DESC NULLS LAST, i.id`
+	if sort != (Sort{}) {
+		var err error
+		if orderBy, err = imageSort.orderBy(sort, "i.id"); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		SELECT
+			i.*,
+			(SELECT COUNT(*) FROM scans cs WHERE cs.image_id = i.id AND cs.scan_date <= $1) as scan_count,
+			(SELECT MAX(cs.scan_date) FROM scans cs WHERE cs.image_id = i.id AND cs.scan_date <= $1) as last_scan_date,
+			COUNT(DISTINCT CASE WHEN snap.severity = 'Critical' AND snap.status = 'active' THEN snap.vulnerability_id END) as critical_count,
+			COUNT(DISTINCT CASE WHEN snap.severity = 'High' AND snap.status = 'active' THEN snap.vulnerability_id END) as high_count,
+			COUNT(DISTINCT CASE WHEN snap.severity = 'Medium' AND snap.status = 'active' THEN snap.vulnerability_id END) as medium_count,
+			COUNT(DISTINCT CASE WHEN snap.severity = 'Low' AND snap.status = 'active' THEN snap.vulnerability_id END) as low_count,
+			` + aliasCount + ` as alias_count
+		FROM images i
+		LEFT JOIN (
+			SELECT l.image_id, v.id as vulnerability_id, v.severity, ` + statusAsOf + ` as status
+			FROM (` + latestScansAsOf + `) l
+			JOIN scan_vulnerabilities sv ON sv.scan_id = l.id
+			JOIN vulnerabilities v ON v.id = sv.vulnerability_id
+		) snap ON snap.image_id = i.id
+		WHERE EXISTS (SELECT 1 FROM scans cs WHERE cs.image_id = i.id AND cs.scan_date <= $1)
+		GROUP BY i.id
+	` + orderBy + `
+		LIMIT $2 OFFSET $3
+	`
+	images := []models.ImageWithStats{}
+	if err := r.db.SelectContext(ctx, &images, query, asOf, limit, offset); err != nil {
 		return nil, err
 	}
 	return images, nil
@@ -142,3 +241,208 @@ func (r *ImageRepository) GetScanHistory(ctx context.Context, imageID int, limit
 	}
 	return scans, nil
 }
+
+// ListRiskFactors returns the risk factors of every active image: the open findings of the latest
+// successful scan of each target, counted once per vulnerability, the most exposed tier declared by
+// its ImageScans, whether an internet-exposed workload runs it and the shortest stale threshold of the
+// unsuspended ones. Paused images are left out
+func (r *ImageRepository) ListRiskFactors(ctx context.Context, defaultStaleThreshold time.Duration) ([]models.ImageRiskFactors, error) {
+	query := `
+		WITH latest AS (
+			SELECT DISTINCT ON (image_id, target) id, image_id
+			FROM scans
+			WHERE status IN ('completed', 'partial')
+			ORDER BY image_id, target, scan_date DESC
+		),
+		open_findings AS (
+			SELECT DISTINCT l.image_id, v.id, v.severity, v.known_exploited, v.fix_version
+			FROM latest l
+			JOIN scan_vulnerabilities sv ON sv.scan_id = l.id
+			JOIN vulnerabilities v ON v.id = sv.vulnerability_id
+			WHERE v.status IN ('active', 'in_progress')
+		),
+		findings AS (
+			SELECT
+				image_id,
+				COUNT(*) FILTER (WHERE severity = 'Critical') as critical_count,
+				COUNT(*) FILTER (WHERE severity = 'High') as high_count,
+				COUNT(*) FILTER (WHERE severity = 'Medium') as medium_count,
+				COUNT(*) FILTER (WHERE severity = 'Low') as low_count,
+				COUNT(*) FILTER (WHERE severity = 'Negligible') as negligible_count,
+				COUNT(*) FILTER (WHERE severity NOT IN ('Critical', 'High', 'Medium', 'Low', 'Negligible')) as unknown_count,
+				COUNT(*) FILTER (WHERE known_exploited) as known_exploited_count,
+				COUNT(*) FILTER (WHERE fix_version IS NOT NULL) as fixable_count
+			FROM open_findings
+			GROUP BY image_id
+		),
+		last_success AS (
+			SELECT image_id, MAX(scan_date) as scan_date
+			FROM scans
+			WHERE status IN ('completed', 'partial')
+			GROUP BY image_id
+		),
+		registrations AS (
+			SELECT
+				registry, repository, tag,
+				MAX(CASE exposure WHEN 'internet' THEN 3 WHEN 'internal' THEN 2 WHEN 'isolated' THEN 1 END) as exposure_rank,
+				MIN(COALESCE(stale_after_seconds, $1)) FILTER (WHERE NOT suspended) as stale_after_seconds
+			FROM imagescans
+			GROUP BY registry, repository, tag
+		)
+		SELECT
+			i.id as image_id,
+			i.registry || '/' || i.repository || ':' || i.tag as image_name,
+			i.created_at as image_created_at,
+			CASE r.exposure_rank WHEN 3 THEN 'internet' WHEN 2 THEN 'internal' WHEN 1 THEN 'isolated' END as exposure,
+			` + deployedExposed + ` as deployed_exposed,
+			ls.scan_date as last_successful_scan_date,
+			COALESCE(r.stale_after_seconds, $1) as stale_after_seconds,
+			COALESCE(f.critical_count, 0) as critical_count,
+			COALESCE(f.high_count, 0) as high_count,
+			COALESCE(f.medium_count, 0) as medium_count,
+			COALESCE(f.low_count, 0) as low_count,
+			COALESCE(f.negligible_count, 0) as negligible_count,
+			COALESCE(f.unknown_count, 0) as unknown_count,
+			COALESCE(f.known_exploited_count, 0) as known_exploited_count,
+			COALESCE(f.fixable_count, 0) as fixable_count
+		FROM images i
+		LEFT JOIN findings f ON f.image_id = i.id
+		LEFT JOIN last_success ls ON ls.image_id = i.id
+		LEFT JOIN registrations r ON r.registry = i.registry AND r.repository = i.repository AND r.tag = i.tag
+		WHERE i.monitoring = 'active'
+		ORDER BY i.id
+	`
+	factors := []models.ImageRiskFactors{}
+	if err := r.db.SelectContext(ctx, &factors, query, int(defaultStaleThreshold.Seconds())); err != nil {
+		return nil, err
+	}
+	return factors, nil
+}
+
+// GetDeletionImpact counts the scans and SBOMs that deleting the image would remove
+func (r *ImageRepository) GetDeletionImpact(ctx context.Context, imageID int) (*models.ImageDeletionImpact, error) {
+	query := `
+		SELECT
+			COUNT(s.id) as scan_count,
+			COUNT(sb.id) as sbom_count,
+			MAX(s.id) as latest_scan_id
+		FROM scans s
+		LEFT JOIN sboms sb ON sb.scan_id = s.id
+		WHERE s.image_id = $1
+	`
+	impact := &models.ImageDeletionImpact{ImageID: imageID}
+	if err := r.db.QueryRowContext(ctx, query, imageID).Scan(
+		&impact.ScanCount, &impact.SBOMCount, &impact.LatestScanID,
+	); err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// Review assigns the team and criticality of an image, nil leaves them unchanged. The image is reviewed
+// once it has both, by the first reviewer to complete them. The change is recorded in the audit log
+func (r *ImageRepository) Review(ctx context.Context, imageID int, team, criticality *string, entry *models.AuditEntry) (*models.Image, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var img models.Image
+	query := `
+		UPDATE images
+		SET team = COALESCE($2, team),
+			criticality = COALESCE($3, criticality),
+			reviewed_at = CASE
+				WHEN reviewed_at IS NULL AND COALESCE($2, team) IS NOT NULL AND COALESCE($3, criticality) IS NOT NULL THEN NOW()
+				ELSE reviewed_at
+			END,
+			reviewed_by = CASE
+				WHEN reviewed_at IS NULL AND COALESCE($2, team) IS NOT NULL AND COALESCE($3, criticality) IS NOT NULL THEN $4
+				ELSE reviewed_by
+			END,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`
+	if err := tx.GetContext(ctx, &img, query, imageID, team, criticality, entry.Actor); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("image")
+		}
+		return nil, err
+	}
+
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &img, nil
+}
+
+// Delete removes the image and records the deletion in the audit log in one transaction.
+// Scans, scan links and SBOM metadata are removed by the foreign key cascades; the SBOM
+// documents no other scan shares are returned so the caller can remove them from S3.
+// With purgeOrphanVulns, the vulnerabilities no scan of another image found are removed too
+// and their number is returned
+func (r *ImageRepository) Delete(ctx context.Context, imageID int, purgeOrphanVulns bool, entry *models.AuditEntry) ([]int, int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sbomScanIDs := []int{}
+	query := `
+		SELECT DISTINCT COALESCE(sb.document_scan_id, sb.scan_id) FROM sboms sb
+		JOIN scans s ON s.id = sb.scan_id
+		WHERE s.image_id = $1
+		ORDER BY 1
+	`
+	if err := tx.SelectContext(ctx, &sbomScanIDs, query, imageID); err != nil {
+		return nil, 0, fmt.Errorf("failed to list SBOMs: %w", err)
+	}
+
+	var vulnIDs []int
+	if purgeOrphanVulns {
+		query := `
+			SELECT DISTINCT sv.vulnerability_id FROM scan_vulnerabilities sv
+			JOIN scans s ON s.id = sv.scan_id
+			WHERE s.image_id = $1
+		`
+		if err := tx.SelectContext(ctx, &vulnIDs, query, imageID); err != nil {
+			return nil, 0, fmt.Errorf("failed to list vulnerabilities: %w", err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM images WHERE id = $1`, imageID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to delete image: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, 0, err
+	}
+	if rows == 0 {
+		return nil, 0, notFound("image")
+	}
+	// Another image with the same digest may share the documents
+	if sbomScanIDs, err = unreferencedDocuments(ctx, tx, sbomScanIDs); err != nil {
+		return nil, 0, err
+	}
+	purged, err := purgeOrphanVulnerabilities(ctx, tx, vulnIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return nil, 0, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return sbomScanIDs, purged, nil
+}
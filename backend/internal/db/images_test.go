@@ -29,6 +29,8 @@ func TestImageRepository_Create(t *testing.T) {
 	assert.NotZero(t, image.ID)
 	assert.NotZero(t, image.CreatedAt)
 	assert.NotZero(t, image.UpdatedAt)
+	assert.True(t, image.Discovered)
+	assert.Nil(t, image.ReviewedAt, "new images are unreviewed")
 }
 
 func TestImageRepository_Create_UpdateDigest(t *testing.T) {
@@ -65,6 +67,59 @@ func TestImageRepository_Create_UpdateDigest(t *testing.T) {
 	// Should have same ID (updated, not inserted)
 	assert.Equal(t, firstID, image2.ID)
 	assert.Equal(t, &digest2, image2.Digest)
+	assert.False(t, image2.Discovered)
+}
+
+func TestImageRepository_Review(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewImageRepository(db)
+	image := &models.Image{Registry: "docker.io", Repository: "acme/shadow", Tag: "latest"}
+	require.NoError(t, repo.Create(ctx, image))
+
+	unreviewed, err := repo.Count(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, unreviewed)
+
+	audit := func() *models.AuditEntry {
+		return &models.AuditEntry{Action: models.AuditActionImageReviewed, ResourceType: "image", ResourceID: &image.ID, Actor: "alice@example.com"}
+	}
+
+	// A team alone doesn't review the image
+	team := "payments"
+	reviewed, err := repo.Review(ctx, image.ID, &team, nil, audit())
+	require.NoError(t, err)
+	assert.Equal(t, &team, reviewed.Team)
+	assert.Nil(t, reviewed.ReviewedAt)
+
+	criticality := models.ImageCriticalityHigh
+	reviewed, err = repo.Review(ctx, image.ID, nil, &criticality, audit())
+	require.NoError(t, err)
+	assert.Equal(t, &team, reviewed.Team)
+	assert.Equal(t, &criticality, reviewed.Criticality)
+	require.NotNil(t, reviewed.ReviewedAt)
+	assert.Equal(t, "alice@example.com", *reviewed.ReviewedBy)
+
+	unreviewed, err = repo.Count(ctx, true)
+	require.NoError(t, err)
+	assert.Zero(t, unreviewed)
+	images, err := repo.List(ctx, 10, 0, Sort{}, nil, true)
+	require.NoError(t, err)
+	assert.Empty(t, images)
+
+	// Later changes keep the first review
+	other := "platform"
+	entry := audit()
+	entry.Actor = "bob@example.com"
+	changed, err := repo.Review(ctx, image.ID, &other, nil, entry)
+	require.NoError(t, err)
+	assert.Equal(t, &other, changed.Team)
+	assert.Equal(t, "alice@example.com", *changed.ReviewedBy)
+
+	_, err = repo.Review(ctx, 999999, &team, nil, audit())
+	assert.Error(t, err)
 }
 
 func TestImageRepository_GetByID(t *testing.T) {
@@ -148,7 +203,7 @@ func TestImageRepository_List(t *testing.T) {
 	require.NoError(t, err)
 
 	// List images
-	images, err := repo.List(context.Background(), 10, 0, nil)
+	images, err := repo.List(context.Background(), 10, 0, Sort{}, nil, false)
 	require.NoError(t, err)
 	assert.Len(t, images, 2)
 
@@ -254,3 +309,149 @@ func TestImageRepository_GetScanHistory_EmptyResult(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, scans, 0)
 }
+
+func TestImageRepository_Delete(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	sbomRepo := NewSBOMRepository(db, &noopSBOMStorage{})
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, repo.Create(ctx, image))
+
+	var lastScanID int
+	for i := 0; i < 2; i++ {
+		scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: models.ScanStatusCompleted}
+		require.NoError(t, scanRepo.Create(ctx, scan))
+		lastScanID = scan.ID
+	}
+	require.NoError(t, sbomRepo.Create(ctx, &models.SBOM{ScanID: lastScanID, Format: "cyclonedx"}, []byte("{}")))
+
+	impact, err := repo.GetDeletionImpact(ctx, image.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, impact.ScanCount)
+	assert.Equal(t, 1, impact.SBOMCount)
+	require.NotNil(t, impact.LatestScanID)
+	assert.Equal(t, lastScanID, *impact.LatestScanID)
+
+	name := image.FullName()
+	entry := &models.AuditEntry{
+		Action:       models.AuditActionImageDeleted,
+		ResourceType: "image",
+		ResourceID:   &image.ID,
+		ResourceName: &name,
+		Actor:        "admin@example.com",
+	}
+	sbomScanIDs, purged, err := repo.Delete(ctx, image.ID, false, entry)
+	require.NoError(t, err)
+	assert.Equal(t, []int{lastScanID}, sbomScanIDs)
+	assert.Zero(t, purged)
+	assert.NotZero(t, entry.ID)
+
+	_, err = repo.GetByID(ctx, image.ID)
+	assert.Error(t, err)
+	_, err = scanRepo.GetByID(ctx, lastScanID)
+	assert.Error(t, err)
+	_, err = sbomRepo.GetByScanID(ctx, lastScanID)
+	assert.Error(t, err)
+
+	_, _, err = repo.Delete(ctx, image.ID, false, entry)
+	assert.Error(t, err)
+}
+
+// noopSBOMStorage satisfies storage.SBOMStorage for tests that only check SBOM metadata
+type noopSBOMStorage struct{}
+
+func (noopSBOMStorage) Store(ctx context.Context, scanID int, document []byte) error { return nil }
+func (noopSBOMStorage) Retrieve(ctx context.Context, scanID int) ([]byte, error)     { return nil, nil }
+func (noopSBOMStorage) Delete(ctx context.Context, scanID int) error                 { return nil }
+func (noopSBOMStorage) Exists(ctx context.Context, scanID int) (bool, error)         { return true, nil }
+func (noopSBOMStorage) GetPresignedURL(ctx context.Context, scanID int, expiresIn time.Duration) (string, error) {
+	return "", nil
+}
+
+func TestImageRepository_ListRiskFactors(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	imageScanRepo := NewImageScanRepository(db)
+	now := time.Now()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, repo.Create(ctx, image))
+	unscanned := &models.Image{Registry: "docker.io", Repository: "library/alpine", Tag: "3.18"}
+	require.NoError(t, repo.Create(ctx, unscanned))
+
+	internet, isolated, day := models.ExposureInternet, models.ExposureIsolated, 86400
+	for _, reg := range []*models.ImageScanRegistration{
+		{Namespace: "prod", Name: "nginx", Exposure: &internet, StaleAfterSeconds: &day},
+		{Namespace: "batch", Name: "nginx", Exposure: &isolated},
+	} {
+		reg.Registry, reg.Repository, reg.Tag = "docker.io", "library/nginx", "latest"
+		require.NoError(t, imageScanRepo.Upsert(ctx, reg))
+	}
+
+	// Only the findings of the latest successful scan count, fixed ones are not open
+	older := &models.Scan{ImageID: image.ID, ScanDate: now.Add(-2 * time.Hour), Status: models.ScanStatusCompleted}
+	latest := &models.Scan{ImageID: image.ID, ScanDate: now.Add(-time.Hour), Status: models.ScanStatusCompleted}
+	failed := &models.Scan{ImageID: image.ID, ScanDate: now, Status: models.ScanStatusFailed}
+	for _, scan := range []*models.Scan{older, latest, failed} {
+		require.NoError(t, scanRepo.Create(ctx, scan))
+	}
+	fix := "3.0.12"
+	for _, v := range []struct {
+		vuln  *models.Vulnerability
+		scans []*models.Scan
+	}{
+		{&models.Vulnerability{CVEID: "CVE-2023-0001", PackageName: "openssl", PackageVersion: "3.0.11", Severity: "Critical", Status: models.StatusActive, KnownExploited: true, FixVersion: &fix}, []*models.Scan{older, latest}},
+		{&models.Vulnerability{CVEID: "CVE-2023-0002", PackageName: "zlib", PackageVersion: "1.2.13", Severity: "High", Status: models.StatusInProgress}, []*models.Scan{latest}},
+		{&models.Vulnerability{CVEID: "CVE-2023-0003", PackageName: "curl", PackageVersion: "8.4.0", Severity: "High", Status: models.StatusFixed}, []*models.Scan{latest}},
+		{&models.Vulnerability{CVEID: "CVE-2023-0004", PackageName: "expat", PackageVersion: "2.5.0", Severity: "Medium", Status: models.StatusActive}, []*models.Scan{older}},
+	} {
+		v.vuln.FirstDetectedAt, v.vuln.LastSeenAt = now, now
+		require.NoError(t, vulnRepo.Upsert(ctx, v.vuln))
+		for _, scan := range v.scans {
+			require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, v.vuln.ID))
+		}
+	}
+
+	// An Ingress routes to the Deployment running alpine
+	require.NoError(t, NewInventoryRepository(db).Replace(ctx, models.InventorySourceController, []models.DeployedImage{
+		{Namespace: "web", Image: "alpine:3.18", Registry: "docker.io", Repository: "library/alpine", Tag: "3.18",
+			Workloads: []models.InventoryWorkload{{Kind: "Deployment", Name: "web", Replicas: 2, Exposed: true}}},
+		{Namespace: "web", Image: "nginx:latest", Registry: "docker.io", Repository: "library/nginx", Tag: "latest",
+			Workloads: []models.InventoryWorkload{{Kind: "Deployment", Name: "proxy", Replicas: 2}}},
+	}))
+
+	factors, err := repo.ListRiskFactors(ctx, 48*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, factors, 2)
+
+	nginx := factors[0]
+	assert.Equal(t, image.ID, nginx.ImageID)
+	require.NotNil(t, nginx.Exposure)
+	assert.Equal(t, models.ExposureInternet, *nginx.Exposure, "the most exposed tier wins")
+	assert.Equal(t, day, nginx.StaleAfterSeconds, "the shortest threshold wins")
+	require.NotNil(t, nginx.LastSuccessfulScanDate)
+	assert.WithinDuration(t, latest.ScanDate, *nginx.LastSuccessfulScanDate, time.Second)
+	assert.Equal(t, 1, nginx.Critical)
+	assert.Equal(t, 1, nginx.High)
+	assert.Zero(t, nginx.Medium)
+	assert.Equal(t, 1, nginx.KnownExploited)
+	assert.Equal(t, 1, nginx.Fixable)
+	assert.False(t, nginx.DeployedExposed)
+
+	alpine := factors[1]
+	assert.Nil(t, alpine.Exposure)
+	assert.Nil(t, alpine.LastSuccessfulScanDate)
+	assert.Equal(t, 48*3600, alpine.StaleAfterSeconds)
+	assert.Zero(t, alpine.Critical+alpine.High+alpine.KnownExploited)
+	assert.True(t, alpine.DeployedExposed)
+}
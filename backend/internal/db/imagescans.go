@@ -0,0 +1,336 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ImageScanRepository handles database operations for ImageScan registrations
+type ImageScanRepository struct {
+	db *Database
+}
+
+// NewImageScanRepository creates a new ImageScan repository
+func NewImageScanRepository(db *Database) *ImageScanRepository {
+	return &ImageScanRepository{db: db}
+}
+
+// Upsert registers an ImageScan, or updates the image it points to, its suspension, retention, exposure and labels.
+// The monitoring state of the affected images is updated in the same transaction
+func (r *ImageScanRepository) Upsert(ctx context.Context, reg *models.ImageScanRegistration) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The ImageScan may have been pointed to another image, which then needs updating as well
+	var previous models.ImageScanRegistration
+	err = tx.GetContext(ctx, &previous,
+		`SELECT * FROM imagescans WHERE namespace = $1 AND name = $2 FOR UPDATE`, reg.Namespace, reg.Name)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	hadPrevious := err == nil
+
+	query := `
+		INSERT INTO imagescans (
+			namespace, name, registry, repository, tag, suspended,
+			stale_after_seconds, max_scans_retained, max_scan_age_seconds, exposure, labels, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		ON CONFLICT (namespace, name)
+		DO UPDATE SET
+			registry = EXCLUDED.registry,
+			repository = EXCLUDED.repository,
+			tag = EXCLUDED.tag,
+			suspended = EXCLUDED.suspended,
+			stale_after_seconds = EXCLUDED.stale_after_seconds,
+			max_scans_retained = EXCLUDED.max_scans_retained,
+			max_scan_age_seconds = EXCLUDED.max_scan_age_seconds,
+			exposure = EXCLUDED.exposure,
+			labels = EXCLUDED.labels,
+			updated_at = NOW()
+		RETURNING stale_notified_at, created_at, updated_at
+	`
+	if err := tx.QueryRowContext(ctx, query,
+		reg.Namespace, reg.Name, reg.Registry, reg.Repository, reg.Tag, reg.Suspended,
+		reg.StaleAfterSeconds, reg.MaxScansRetained, reg.MaxScanAgeSeconds, reg.Exposure, labelsArray(reg.Labels),
+	).Scan(&reg.StaleNotifiedAt, &reg.CreatedAt, &reg.UpdatedAt); err != nil {
+		return err
+	}
+
+	if err := syncImageMonitoring(ctx, tx, reg.Registry, reg.Repository, reg.Tag); err != nil {
+		return err
+	}
+	if hadPrevious && (previous.Registry != reg.Registry || previous.Repository != reg.Repository || previous.Tag != reg.Tag) {
+		if err := syncImageMonitoring(ctx, tx, previous.Registry, previous.Repository, previous.Tag); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// syncImageMonitoring pauses an image when all the ImageScans scanning it are suspended,
+// and resumes it otherwise (including when no ImageScan scans it anymore)
+func syncImageMonitoring(ctx context.Context, tx *sqlx.Tx, registry, repository, tag string) error {
+	query := `
+		UPDATE images SET monitoring = CASE
+			WHEN EXISTS (
+				SELECT 1 FROM imagescans
+				WHERE registry = $1 AND repository = $2 AND tag = $3
+			) AND NOT EXISTS (
+				SELECT 1 FROM imagescans
+				WHERE registry = $1 AND repository = $2 AND tag = $3 AND NOT suspended
+			) THEN 'paused'
+			ELSE 'active'
+		END
+		WHERE registry = $1 AND repository = $2 AND tag = $3
+	`
+	if _, err := tx.ExecContext(ctx, query, registry, repository, tag); err != nil {
+		return fmt.Errorf("failed to update image monitoring: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves an ImageScan registration
+func (r *ImageScanRepository) Get(ctx context.Context, namespace, name string) (*models.ImageScanRegistration, error) {
+	var reg models.ImageScanRegistration
+	query := `SELECT * FROM imagescans WHERE namespace = $1 AND name = $2`
+	if err := r.db.GetContext(ctx, &reg, query, namespace, name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("imagescan")
+		}
+		return nil, err
+	}
+	return &reg, nil
+}
+
+// Delete removes an ImageScan registration and resumes its image if it was the last suspended one
+func (r *ImageScanRepository) Delete(ctx context.Context, namespace, name string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var reg models.ImageScanRegistration
+	query := `DELETE FROM imagescans WHERE namespace = $1 AND name = $2 RETURNING *`
+	if err := tx.GetContext(ctx, &reg, query, namespace, name); err != nil {
+		if err == sql.ErrNoRows {
+			return notFound("imagescan")
+		}
+		return err
+	}
+
+	if err := syncImageMonitoring(ctx, tx, reg.Registry, reg.Repository, reg.Tag); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// imageScanSummaries selects the ImageScans as models.ImageScanSummary, with the open findings of
+// the latest successful scan of their whole image
+const imageScanSummaries = `
+	WITH latest AS (
+		SELECT DISTINCT ON (image_id) id, image_id, scan_date
+		FROM scans
+		WHERE status IN ('completed', 'partial') AND target IS NULL
+		ORDER BY image_id, scan_date DESC
+	),
+	findings AS (
+		SELECT
+			l.id as scan_id,
+			COUNT(DISTINCT v.id) FILTER (WHERE v.severity = 'Critical') as critical_count,
+			COUNT(DISTINCT v.id) FILTER (WHERE v.severity = 'High') as high_count,
+			COUNT(DISTINCT v.id) FILTER (WHERE v.severity = 'Medium') as medium_count,
+			COUNT(DISTINCT v.id) FILTER (WHERE v.severity = 'Low') as low_count
+		FROM latest l
+		JOIN scan_vulnerabilities sv ON sv.scan_id = l.id
+		JOIN vulnerabilities v ON v.id = sv.vulnerability_id
+		WHERE v.status IN ('active', 'in_progress')
+		GROUP BY l.id
+	)
+	SELECT
+		r.namespace, r.name, r.suspended,
+		r.registry || '/' || r.repository || ':' || r.tag as image_name,
+		i.id as image_id,
+		l.id as latest_scan_id,
+		l.scan_date as latest_scan_date,
+		COALESCE(f.critical_count, 0) as critical_count,
+		COALESCE(f.high_count, 0) as high_count,
+		COALESCE(f.medium_count, 0) as medium_count,
+		COALESCE(f.low_count, 0) as low_count
+	FROM imagescans r
+	LEFT JOIN images i ON i.registry = r.registry AND i.repository = r.repository AND i.tag = r.tag
+	LEFT JOIN latest l ON l.image_id = i.id
+	LEFT JOIN findings f ON f.scan_id = l.id
+`
+
+// ListSummaries returns the ImageScans of a namespace, or of all namespaces when it is empty, with the
+// open findings of the latest successful scan of their whole image, ordered by namespace and name
+func (r *ImageScanRepository) ListSummaries(ctx context.Context, namespace string) ([]models.ImageScanSummary, error) {
+	query := imageScanSummaries
+	args := []interface{}{}
+	if namespace != "" {
+		query += ` WHERE r.namespace = $1`
+		args = append(args, namespace)
+	}
+	query += ` ORDER BY r.namespace, r.name`
+
+	summaries := []models.ImageScanSummary{}
+	if err := r.db.SelectContext(ctx, &summaries, query, args...); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// ListEntitySummaries returns the summaries of the ImageScans of a Backstage catalog entity: those of
+// its namespace named after it or labelled with its models.BackstageEntityLabel, ordered by name
+func (r *ImageScanRepository) ListEntitySummaries(ctx context.Context, namespace, entity string) ([]models.ImageScanSummary, error) {
+	query := imageScanSummaries + `
+		WHERE r.namespace = $1 AND (r.name = $2 OR $3 = ANY(r.labels))
+		ORDER BY r.name
+	`
+	summaries := []models.ImageScanSummary{}
+	if err := r.db.SelectContext(ctx, &summaries, query, namespace, entity, models.BackstageEntityLabel+"="+entity); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// ListForImage returns the ImageScans that still scan the given image
+func (r *ImageScanRepository) ListForImage(ctx context.Context, img *models.Image) ([]models.ImageScanRegistration, error) {
+	query := `
+		SELECT * FROM imagescans
+		WHERE registry = $1 AND repository = $2 AND tag = $3
+		ORDER BY namespace, name
+	`
+	regs := []models.ImageScanRegistration{}
+	if err := r.db.SelectContext(ctx, &regs, query, img.Registry, img.Repository, img.Tag); err != nil {
+		return nil, err
+	}
+	return regs, nil
+}
+
+// ListLabelsByImage returns the labels of the ImageScans scanning each of the given images. Images
+// outside ImageScans are left out
+func (r *ImageScanRepository) ListLabelsByImage(ctx context.Context, imageIDs []int) (map[int][][]string, error) {
+	labels := map[int][][]string{}
+	if len(imageIDs) == 0 {
+		return labels, nil
+	}
+	query := `
+		SELECT i.id AS image_id, isc.labels
+		FROM images i
+		JOIN imagescans isc ON isc.registry = i.registry AND isc.repository = i.repository AND isc.tag = i.tag
+		WHERE i.id = ANY($1)
+	`
+	rows := []struct {
+		ImageID int            `db:"image_id"`
+		Labels  pq.StringArray `db:"labels"`
+	}{}
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(imageIDs)); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		labels[row.ImageID] = append(labels[row.ImageID], row.Labels)
+	}
+	return labels, nil
+}
+
+// labelsArray stores missing labels as an empty array, the column is NOT NULL
+func labelsArray(labels pq.StringArray) pq.StringArray {
+	if labels == nil {
+		return pq.StringArray{}
+	}
+	return labels
+}
+
+// ListStale returns the ImageScans whose image has had no successful scan within their threshold,
+// ordered by image. Suspended ImageScans and paused images are never stale
+func (r *ImageScanRepository) ListStale(ctx context.Context, defaultThreshold time.Duration, now time.Time) ([]models.StaleImageScan, error) {
+	query := `
+		WITH last_success AS (
+			SELECT image_id, MAX(scan_date) as scan_date
+			FROM scans
+			WHERE status IN ('completed', 'partial')
+			GROUP BY image_id
+		)
+		SELECT
+			r.namespace,
+			r.name,
+			i.id as image_id,
+			i.registry || '/' || i.repository || ':' || i.tag as image_name,
+			i.created_at as image_created_at,
+			ls.scan_date as last_successful_scan_date,
+			COALESCE(r.stale_after_seconds, $1) as stale_after_seconds,
+			r.stale_notified_at
+		FROM imagescans r
+		JOIN images i ON i.registry = r.registry AND i.repository = r.repository AND i.tag = r.tag
+		LEFT JOIN last_success ls ON ls.image_id = i.id
+		WHERE NOT r.suspended
+			AND i.monitoring = 'active'
+			AND COALESCE(ls.scan_date, i.created_at) + COALESCE(r.stale_after_seconds, $1) * INTERVAL '1 second' < $2
+		ORDER BY i.id, r.namespace, r.name
+	`
+	stale := []models.StaleImageScan{}
+	if err := r.db.SelectContext(ctx, &stale, query, int(defaultThreshold.Seconds()), now); err != nil {
+		return nil, err
+	}
+	return stale, nil
+}
+
+// MarkStaleNotified records that the owning webhook was told the image is stale
+func (r *ImageScanRepository) MarkStaleNotified(ctx context.Context, namespace, name string, at time.Time) error {
+	query := `UPDATE imagescans SET stale_notified_at = $3 WHERE namespace = $1 AND name = $2`
+	_, err := r.db.ExecContext(ctx, query, namespace, name, at)
+	return err
+}
+
+// ListRetentionPolicies returns the retention of every registered image with at least one limit.
+// A limit only applies when all the ImageScans of the image declare it, and the largest one wins
+func (r *ImageScanRepository) ListRetentionPolicies(ctx context.Context) ([]models.RetentionPolicy, error) {
+	query := `
+		SELECT
+			i.id as image_id,
+			i.registry || '/' || i.repository || ':' || i.tag as image_name,
+			CASE WHEN bool_and(r.max_scans_retained IS NOT NULL) THEN MAX(r.max_scans_retained) END as max_scans_retained,
+			CASE WHEN bool_and(r.max_scan_age_seconds IS NOT NULL) THEN MAX(r.max_scan_age_seconds) END as max_scan_age_seconds
+		FROM imagescans r
+		JOIN images i ON i.registry = r.registry AND i.repository = r.repository AND i.tag = r.tag
+		GROUP BY i.id
+		HAVING bool_and(r.max_scans_retained IS NOT NULL) OR bool_and(r.max_scan_age_seconds IS NOT NULL)
+		ORDER BY i.id
+	`
+	policies := []models.RetentionPolicy{}
+	if err := r.db.SelectContext(ctx, &policies, query); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetSizing returns the number of scans of an ImageScan since a time that reported the size of
+// its image, with the largest size and layer count they reported
+func (r *ImageScanRepository) GetSizing(ctx context.Context, namespace, name string, since time.Time) (*models.ImageScanSizing, error) {
+	sizing := models.ImageScanSizing{Namespace: namespace, Name: name, Since: since}
+	query := `
+		SELECT COUNT(*) AS scans,
+			COALESCE(MAX(image_size_bytes), 0) AS image_size_bytes,
+			COALESCE(MAX(layer_count), 0) AS layer_count
+		FROM scans
+		WHERE imagescan_namespace = $1 AND imagescan_name = $2 AND scan_date > $3
+			AND image_size_bytes IS NOT NULL`
+	if err := r.db.GetContext(ctx, &sizing, query, namespace, name, since); err != nil {
+		return nil, err
+	}
+	return &sizing, nil
+}
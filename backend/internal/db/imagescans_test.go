@@ -0,0 +1,272 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageScanRepository_SyncsImageMonitoring(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	repo := NewImageScanRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	assert.Equal(t, models.ImageMonitoringActive, image.Monitoring)
+
+	monitoring := func() string {
+		img, err := imageRepo.GetByID(ctx, image.ID)
+		require.NoError(t, err)
+		return img.Monitoring
+	}
+	register := func(name string, suspended bool) {
+		require.NoError(t, repo.Upsert(ctx, &models.ImageScanRegistration{
+			Namespace: "default", Name: name,
+			Registry: "docker.io", Repository: "library/nginx", Tag: "latest",
+			Suspended: suspended,
+		}))
+	}
+
+	register("nginx", true)
+	assert.Equal(t, models.ImageMonitoringPaused, monitoring())
+
+	// One active ImageScan keeps the image monitored
+	register("nginx-copy", false)
+	assert.Equal(t, models.ImageMonitoringActive, monitoring())
+
+	require.NoError(t, repo.Delete(ctx, "default", "nginx-copy"))
+	assert.Equal(t, models.ImageMonitoringPaused, monitoring())
+
+	// Pointing the ImageScan at another image resumes the previous one
+	require.NoError(t, repo.Upsert(ctx, &models.ImageScanRegistration{
+		Namespace: "default", Name: "nginx",
+		Registry: "docker.io", Repository: "library/nginx", Tag: "1.25",
+		Suspended: true,
+	}))
+	assert.Equal(t, models.ImageMonitoringActive, monitoring())
+
+	// Images first scanned after a suspended registration start paused
+	newImage := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}
+	require.NoError(t, imageRepo.Create(ctx, newImage))
+	assert.Equal(t, models.ImageMonitoringPaused, newImage.Monitoring)
+}
+
+func TestImageScanRepository_ListStale(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	repo := NewImageScanRepository(db)
+	now := time.Now()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	// A recent failure doesn't count as a successful scan
+	for _, scan := range []*models.Scan{
+		{ImageID: image.ID, ScanDate: now.Add(-72 * time.Hour), Status: models.ScanStatusCompleted},
+		{ImageID: image.ID, ScanDate: now.Add(-time.Hour), Status: models.ScanStatusFailed},
+	} {
+		require.NoError(t, scanRepo.Create(ctx, scan))
+	}
+
+	weekly := 7 * 24 * 3600
+	for _, reg := range []*models.ImageScanRegistration{
+		{Namespace: "prod", Name: "nginx"},
+		{Namespace: "weekly", Name: "nginx", StaleAfterSeconds: &weekly},
+		{Namespace: "paused", Name: "nginx", Suspended: true},
+	} {
+		reg.Registry, reg.Repository, reg.Tag = "docker.io", "library/nginx", "latest"
+		require.NoError(t, repo.Upsert(ctx, reg))
+	}
+
+	stale, err := repo.ListStale(ctx, 48*time.Hour, now)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, "prod", stale[0].Namespace)
+	assert.Equal(t, 48*3600, stale[0].StaleAfterSeconds)
+	require.NotNil(t, stale[0].LastSuccessfulScanDate)
+	assert.False(t, stale[0].Notified())
+
+	require.NoError(t, repo.MarkStaleNotified(ctx, "prod", "nginx", now))
+	stale, err = repo.ListStale(ctx, 48*time.Hour, now)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.True(t, stale[0].Notified())
+}
+
+func TestImageScanRepository_ListRetentionPolicies(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	repo := NewImageScanRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	five, ten, day := 5, 10, 86400
+	for _, reg := range []*models.ImageScanRegistration{
+		{Namespace: "prod", Name: "nginx", MaxScansRetained: &five, MaxScanAgeSeconds: &day},
+		{Namespace: "staging", Name: "nginx", MaxScansRetained: &ten},
+	} {
+		reg.Registry, reg.Repository, reg.Tag = "docker.io", "library/nginx", "latest"
+		require.NoError(t, repo.Upsert(ctx, reg))
+	}
+
+	// The most lenient count wins, and the age limit is dropped since staging keeps scans forever
+	policies, err := repo.ListRetentionPolicies(ctx)
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, image.ID, policies[0].ImageID)
+	require.NotNil(t, policies[0].MaxScansRetained)
+	assert.Equal(t, 10, *policies[0].MaxScansRetained)
+	assert.Nil(t, policies[0].MaxScanAgeSeconds)
+
+	// An ImageScan without retention disables pruning for the image
+	require.NoError(t, repo.Upsert(ctx, &models.ImageScanRegistration{
+		Namespace: "dev", Name: "nginx",
+		Registry: "docker.io", Repository: "library/nginx", Tag: "latest",
+	}))
+	policies, err = repo.ListRetentionPolicies(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, policies)
+}
+
+func TestImageScanRepository_ListSummaries(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	repo := NewImageScanRepository(db)
+	now := time.Now()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	for _, reg := range []*models.ImageScanRegistration{
+		{Namespace: "prod", Name: "nginx", Registry: "docker.io", Repository: "library/nginx", Tag: "latest"},
+		{Namespace: "prod", Name: "redis", Registry: "docker.io", Repository: "library/redis", Tag: "7.2"},
+		{Namespace: "staging", Name: "nginx", Registry: "docker.io", Repository: "library/nginx", Tag: "latest"},
+	} {
+		require.NoError(t, repo.Upsert(ctx, reg))
+	}
+
+	// Failed scans are skipped, and only open findings count
+	latest := &models.Scan{ImageID: image.ID, ScanDate: now.Add(-time.Hour), Status: models.ScanStatusCompleted}
+	failed := &models.Scan{ImageID: image.ID, ScanDate: now, Status: models.ScanStatusFailed}
+	for _, scan := range []*models.Scan{latest, failed} {
+		require.NoError(t, scanRepo.Create(ctx, scan))
+	}
+	for _, vuln := range []*models.Vulnerability{
+		{CVEID: "CVE-2023-0001", PackageName: "openssl", PackageVersion: "3.0.11", Severity: "Critical", Status: models.StatusActive},
+		{CVEID: "CVE-2023-0002", PackageName: "zlib", PackageVersion: "1.2.13", Severity: "High", Status: models.StatusInProgress},
+		{CVEID: "CVE-2023-0003", PackageName: "curl", PackageVersion: "8.4.0", Severity: "High", Status: models.StatusIgnored},
+	} {
+		vuln.FirstDetectedAt, vuln.LastSeenAt = now, now
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		require.NoError(t, vulnRepo.LinkToScan(ctx, latest.ID, vuln.ID))
+	}
+
+	summaries, err := repo.ListSummaries(ctx, "prod")
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+
+	nginx := summaries[0]
+	assert.Equal(t, "nginx", nginx.Name)
+	assert.Equal(t, "docker.io/library/nginx:latest", nginx.ImageName)
+	require.NotNil(t, nginx.LatestScanID)
+	assert.Equal(t, latest.ID, *nginx.LatestScanID)
+	assert.Equal(t, 1, nginx.Critical)
+	assert.Equal(t, 1, nginx.High)
+
+	// Not scanned yet
+	redis := summaries[1]
+	assert.Nil(t, redis.ImageID)
+	assert.Nil(t, redis.LatestScanID)
+	assert.Zero(t, redis.Critical)
+
+	all, err := repo.ListSummaries(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func TestImageScanRepository_ListEntitySummaries(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewImageScanRepository(db)
+	label := models.BackstageEntityLabel + "=checkout"
+	for _, reg := range []*models.ImageScanRegistration{
+		{Namespace: "payments", Name: "checkout", Registry: "ghcr.io", Repository: "acme/checkout", Tag: "1.4"},
+		{Namespace: "payments", Name: "checkout-worker", Registry: "ghcr.io", Repository: "acme/worker", Tag: "1.4", Labels: pq.StringArray{"app=worker", label}},
+		{Namespace: "payments", Name: "ledger", Registry: "ghcr.io", Repository: "acme/ledger", Tag: "2.0"},
+		// Another team's service of the same name
+		{Namespace: "shop", Name: "cart", Registry: "ghcr.io", Repository: "acme/cart", Tag: "3.1", Labels: pq.StringArray{label}},
+	} {
+		require.NoError(t, repo.Upsert(ctx, reg))
+	}
+
+	summaries, err := repo.ListEntitySummaries(ctx, "payments", "checkout")
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "checkout", summaries[0].Name)
+	assert.Equal(t, "checkout-worker", summaries[1].Name)
+
+	summaries, err = repo.ListEntitySummaries(ctx, "payments", "cart")
+	require.NoError(t, err)
+	assert.Empty(t, summaries)
+}
+
+func TestImageScanRepository_GetSizing(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	repo := NewImageScanRepository(db)
+	now := time.Now()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	namespace, name, other := "prod", "nginx", "nginx-canary"
+	scan := func(imagescan *string, date time.Time, size int64, layers int) *models.Scan {
+		return &models.Scan{ImageID: image.ID, ScanDate: date, Status: models.ScanStatusCompleted,
+			ImageScanNamespace: &namespace, ImageScanName: imagescan, ImageSizeBytes: &size, LayerCount: &layers}
+	}
+	for _, s := range []*models.Scan{
+		scan(&name, now.Add(-time.Hour), 180<<20, 7),
+		scan(&name, now.Add(-24*time.Hour), 150<<20, 6),
+		// Too old, of another ImageScan, or without a size
+		scan(&name, now.AddDate(0, 0, -40), 4<<30, 30),
+		scan(&other, now, 2<<30, 12),
+		{ImageID: image.ID, ScanDate: now, Status: models.ScanStatusFailed, ImageScanNamespace: &namespace, ImageScanName: &name},
+	} {
+		require.NoError(t, scanRepo.Create(ctx, s))
+	}
+
+	sizing, err := repo.GetSizing(ctx, namespace, name, now.AddDate(0, 0, -30))
+	require.NoError(t, err)
+	assert.Equal(t, 2, sizing.Scans)
+	assert.Equal(t, int64(180<<20), sizing.ImageSizeBytes)
+	assert.Equal(t, 7, sizing.LayerCount)
+
+	sizing, err = repo.GetSizing(ctx, "staging", name, now.AddDate(0, 0, -30))
+	require.NoError(t, err)
+	assert.Zero(t, sizing.Scans)
+}
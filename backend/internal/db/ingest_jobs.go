@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// ingestJobColumns are the columns of a job but its matches, which only the worker running it reads
+const ingestJobColumns = `id, scan_id, status, params, match_count, attempts, available_at, locked_until, error, created_at, started_at, completed_at`
+
+// IngestJobRepository stores the processing of scan submissions until the ingest workers run it
+type IngestJobRepository struct {
+	db *Database
+}
+
+// NewIngestJobRepository creates a new ingest job repository
+func NewIngestJobRepository(db *Database) *IngestJobRepository {
+	return &IngestJobRepository{db: db}
+}
+
+// Enqueue stores a job, claimable as soon as it is committed
+func (r *IngestJobRepository) Enqueue(ctx context.Context, job *models.IngestJob) error {
+	query := `
+		INSERT INTO ingest_jobs (scan_id, params, matches, match_count)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, available_at, created_at
+	`
+	return r.db.QueryRowContext(ctx, query, job.ScanID, job.Params, job.Matches, job.MatchCount).
+		Scan(&job.ID, &job.Status, &job.AvailableAt, &job.CreatedAt)
+}
+
+// GetByID returns a job without its matches
+func (r *IngestJobRepository) GetByID(ctx context.Context, id int64) (*models.IngestJob, error) {
+	var job models.IngestJob
+	query := `SELECT ` + ingestJobColumns + ` FROM ingest_jobs WHERE id = $1`
+	if err := r.db.GetContext(ctx, &job, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("ingest job")
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetByScanID returns the latest job of a scan without its matches, nil when there is none
+func (r *IngestJobRepository) GetByScanID(ctx context.Context, scanID int) (*models.IngestJob, error) {
+	var job models.IngestJob
+	query := `SELECT ` + ingestJobColumns + ` FROM ingest_jobs WHERE scan_id = $1 ORDER BY id DESC LIMIT 1`
+	if err := r.db.GetContext(ctx, &job, query, scanID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Claim leases the oldest queued job to the caller, nil when there is none. Jobs run by another
+// worker are skipped, and a job whose lease expired (its replica died) is claimed again
+func (r *IngestJobRepository) Claim(ctx context.Context, lease time.Duration) (*models.IngestJob, error) {
+	query := `
+		UPDATE ingest_jobs j
+		SET status = 'running', attempts = j.attempts + 1, locked_until = NOW() + make_interval(secs => $1),
+			started_at = COALESCE(j.started_at, NOW())
+		FROM (
+			SELECT id FROM ingest_jobs
+			WHERE (status = 'queued' AND available_at <= NOW())
+				OR (status = 'running' AND locked_until < NOW())
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE j.id = due.id
+		RETURNING j.*
+	`
+	var job models.IngestJob
+	if err := r.db.GetContext(ctx, &job, query, lease.Seconds()); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Extend renews the lease of a running job
+func (r *IngestJobRepository) Extend(ctx context.Context, id int64, lease time.Duration) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE ingest_jobs SET locked_until = NOW() + make_interval(secs => $2)
+		WHERE id = $1 AND status = 'running'
+	`, id, lease.Seconds())
+	return err
+}
+
+// Complete records a job as processed and drops its matches
+func (r *IngestJobRepository) Complete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE ingest_jobs
+		SET status = 'completed', matches = NULL, locked_until = NULL, error = NULL, completed_at = NOW()
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+// Fail records a failed attempt. The job is tried again at retryAt, or given up when nil: its
+// matches are dropped and its scan, still running, fails in the same transaction
+func (r *IngestJobRepository) Fail(ctx context.Context, id int64, message string, retryAt *time.Time) error {
+	if retryAt != nil {
+		_, err := r.db.ExecContext(ctx, `
+			UPDATE ingest_jobs SET status = 'queued', available_at = $2, locked_until = NULL, error = $3
+			WHERE id = $1
+		`, id, *retryAt, message)
+		return err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var scanID int
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE ingest_jobs
+		SET status = 'failed', matches = NULL, locked_until = NULL, error = $2, completed_at = NOW()
+		WHERE id = $1
+		RETURNING scan_id
+	`, id, message).Scan(&scanID); err != nil {
+		if err == sql.ErrNoRows {
+			return notFound("ingest job")
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE scans SET status = 'failed', failure_reason = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`, scanID, "failed to process results: "+message); err != nil {
+		return fmt.Errorf("failed to mark scan as failed: %w", err)
+	}
+	return tx.Commit()
+}
+
+// CountQueued returns the number of jobs waiting for or being processed
+func (r *IngestJobRepository) CountQueued(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM ingest_jobs WHERE status IN ('queued', 'running')`)
+	return count, err
+}
+
+// DeleteFinished removes the jobs completed or given up before the cutoff and returns how many
+func (r *IngestJobRepository) DeleteFinished(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM ingest_jobs
+		WHERE status IN ('completed', 'failed') AND completed_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
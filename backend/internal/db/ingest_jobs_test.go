@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestJobRepository(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewIngestJobRepository(db)
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scans := make([]*models.Scan, 2)
+	for i := range scans {
+		scans[i] = &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: models.ScanStatusRunning}
+		require.NoError(t, scanRepo.Create(ctx, scans[i]))
+	}
+
+	first := &models.IngestJob{ScanID: scans[0].ID, Params: `{"status":"completed"}`, Matches: []byte(`[{}]`), MatchCount: 1}
+	require.NoError(t, repo.Enqueue(ctx, first))
+	assert.Equal(t, models.IngestJobQueued, first.Status)
+	second := &models.IngestJob{ScanID: scans[1].ID, Params: `{"status":"completed"}`, Matches: []byte(`[]`)}
+	require.NoError(t, repo.Enqueue(ctx, second))
+
+	// Oldest first, with the matches, and leased
+	claimed, err := repo.Claim(ctx, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, first.ID, claimed.ID)
+	assert.Equal(t, models.IngestJobRunning, claimed.Status)
+	assert.Equal(t, 1, claimed.Attempts)
+	assert.Equal(t, `[{}]`, string(claimed.Matches))
+	assert.NotNil(t, claimed.StartedAt)
+
+	claimed, err = repo.Claim(ctx, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, second.ID, claimed.ID)
+
+	claimed, err = repo.Claim(ctx, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, claimed)
+
+	// Completed jobs drop their matches
+	require.NoError(t, repo.Complete(ctx, first.ID))
+	job, err := repo.GetByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.IngestJobCompleted, job.Status)
+	assert.NotNil(t, job.CompletedAt)
+	var matches []byte
+	require.NoError(t, db.GetContext(ctx, &matches, `SELECT matches FROM ingest_jobs WHERE id = $1`, first.ID))
+	assert.Nil(t, matches)
+
+	// A retry is queued again, giving up fails the scan
+	retryAt := time.Now().Add(-time.Second)
+	require.NoError(t, repo.Fail(ctx, second.ID, "connection reset", &retryAt))
+	claimed, err = repo.Claim(ctx, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, 2, claimed.Attempts)
+	assert.Equal(t, "connection reset", *claimed.Error)
+
+	require.NoError(t, repo.Fail(ctx, second.ID, "connection reset", nil))
+	job, err = repo.GetByID(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.IngestJobFailed, job.Status)
+	scan, err := scanRepo.GetByID(ctx, scans[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScanStatusFailed, scan.Status)
+	assert.Equal(t, "failed to process results: connection reset", *scan.FailureReason)
+
+	count, err := repo.CountQueued(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	_, err = repo.GetByID(ctx, 999)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	deleted, err := repo.DeleteFinished(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// InventoryRepository handles the inventory of deployed images and their scan coverage
+type InventoryRepository struct {
+	db *Database
+}
+
+// NewInventoryRepository creates a new inventory repository
+func NewInventoryRepository(db *Database) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// Replace replaces the inventory of a source with the given images
+func (r *InventoryRepository) Replace(ctx context.Context, source string, images []models.DeployedImage) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM deployed_images WHERE source = $1`, source); err != nil {
+		return fmt.Errorf("failed to clear inventory: %w", err)
+	}
+
+	if len(images) > 0 {
+		namespaces := make([]string, len(images))
+		refs := make([]string, len(images))
+		registries := make([]string, len(images))
+		repositories := make([]string, len(images))
+		tags := make([]string, len(images))
+		digests := make([]string, len(images))
+		for i, image := range images {
+			namespaces[i] = image.Namespace
+			refs[i] = image.Image
+			registries[i] = image.Registry
+			repositories[i] = image.Repository
+			tags[i] = image.Tag
+			if image.Digest != nil {
+				digests[i] = *image.Digest
+			}
+		}
+		query := `
+			INSERT INTO deployed_images (source, namespace, image, registry, repository, tag, digest, reported_at)
+			SELECT $1, namespace, image, registry, repository, tag, NULLIF(digest, ''), NOW()
+			FROM UNNEST($2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[])
+				AS i(namespace, image, registry, repository, tag, digest)
+			ON CONFLICT (source, namespace, image) DO NOTHING
+			RETURNING id, namespace, image
+		`
+		var inserted []struct {
+			ID        int    `db:"id"`
+			Namespace string `db:"namespace"`
+			Image     string `db:"image"`
+		}
+		if err := tx.SelectContext(ctx, &inserted, query, source, pq.Array(namespaces), pq.Array(refs), pq.Array(registries),
+			pq.Array(repositories), pq.Array(tags), pq.Array(digests)); err != nil {
+			return fmt.Errorf("failed to store inventory: %w", err)
+		}
+
+		ids := make(map[[2]string]int, len(inserted))
+		for _, row := range inserted {
+			ids[[2]string{row.Namespace, row.Image}] = row.ID
+		}
+		var imageIDs, replicas []int
+		var kinds, names []string
+		var exposed []bool
+		for _, image := range images {
+			id, ok := ids[[2]string{image.Namespace, image.Image}]
+			if !ok {
+				continue
+			}
+			for _, w := range image.Workloads {
+				imageIDs = append(imageIDs, id)
+				kinds = append(kinds, w.Kind)
+				names = append(names, w.Name)
+				replicas = append(replicas, w.Replicas)
+				exposed = append(exposed, w.Exposed)
+			}
+		}
+		if len(imageIDs) > 0 {
+			query = `
+				INSERT INTO deployed_workloads (deployed_image_id, kind, name, replicas, exposed)
+				SELECT * FROM UNNEST($1::int[], $2::text[], $3::text[], $4::int[], $5::boolean[])
+				ON CONFLICT (deployed_image_id, kind, name) DO NOTHING
+			`
+			if _, err := tx.ExecContext(ctx, query, pq.Array(imageIDs), pq.Array(kinds), pq.Array(names), pq.Array(replicas),
+				pq.Array(exposed)); err != nil {
+				return fmt.Errorf("failed to store inventory workloads: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListCoverage returns the deployed images, optionally of a namespace, with the latest successful scan of
+// the image matching each reference: by tag, or by digest for references pinned by digest only.
+// Statuses are left to the caller
+func (r *InventoryRepository) ListCoverage(ctx context.Context, namespace string) ([]models.ImageCoverage, error) {
+	query := `
+		WITH deployed AS (
+			SELECT registry, repository, tag, digest,
+				COALESCE(array_agg(DISTINCT namespace ORDER BY namespace) FILTER (WHERE namespace <> ''), '{}') AS namespaces,
+				array_agg(DISTINCT source ORDER BY source) AS sources,
+				MAX(reported_at) AS last_reported_at
+			FROM deployed_images
+			WHERE $1 = '' OR namespace = $1
+			GROUP BY registry, repository, tag, digest
+		)
+		SELECT d.registry, d.repository, d.tag, d.digest, d.namespaces, d.sources, d.last_reported_at,
+			i.id AS image_id,
+			(SELECT MAX(s.scan_date) FROM scans s WHERE s.image_id = i.id AND s.status IN ('completed', 'partial')) AS last_scan_date,
+			EXISTS (
+				SELECT 1 FROM imagescans isc
+				WHERE isc.registry = d.registry AND isc.repository = d.repository AND isc.tag IN (d.tag, i.tag)
+			) AS has_imagescan
+		FROM deployed d
+		LEFT JOIN LATERAL (
+			SELECT img.id, img.tag
+			FROM images img
+			WHERE img.registry = d.registry AND img.repository = d.repository
+				AND (img.tag = d.tag OR (d.digest IS NOT NULL AND img.digest = d.digest))
+			ORDER BY (img.tag = d.tag) DESC, img.updated_at DESC
+			LIMIT 1
+		) i ON true
+		ORDER BY d.registry, d.repository, d.tag, d.digest
+	`
+	coverage := []models.ImageCoverage{}
+	if err := r.db.SelectContext(ctx, &coverage, query, namespace); err != nil {
+		return nil, err
+	}
+	return coverage, nil
+}
+
+// ListVulnerabilityWorkloads returns the deployed workloads running the images whose latest successful scan
+// of a target has the vulnerability, nil when the vulnerability doesn't exist
+func (r *InventoryRepository) ListVulnerabilityWorkloads(ctx context.Context, vulnerabilityID int) (*models.VulnerabilityWorkloads, error) {
+	result := &models.VulnerabilityWorkloads{VulnerabilityID: vulnerabilityID, Workloads: []models.Workload{}}
+	if err := r.db.GetContext(ctx, &result.CVEID, `SELECT cve_id FROM vulnerabilities WHERE id = $1`, vulnerabilityID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	query := `
+		WITH latest AS (
+			SELECT DISTINCT ON (s.image_id, COALESCE(s.target, '')) s.id, s.image_id
+			FROM scans s
+			WHERE s.status IN ('completed', 'partial')
+			ORDER BY s.image_id, COALESCE(s.target, ''), s.scan_date DESC
+		), affected AS (
+			SELECT DISTINCT i.id, i.registry, i.repository, i.tag, i.digest
+			FROM latest l
+			JOIN scan_vulnerabilities sv ON sv.scan_id = l.id AND sv.vulnerability_id = $1
+			JOIN images i ON i.id = l.image_id
+		)
+		SELECT DISTINCT ON (d.namespace, w.kind, w.name, d.image)
+			d.namespace, w.kind, w.name, w.replicas, w.exposed, d.image, a.id AS image_id, d.source, d.reported_at
+		FROM affected a
+		JOIN deployed_images d ON d.registry = a.registry AND d.repository = a.repository
+			AND (d.tag = a.tag OR (d.digest IS NOT NULL AND d.digest = a.digest))
+		JOIN deployed_workloads w ON w.deployed_image_id = d.id
+		ORDER BY d.namespace, w.kind, w.name, d.image, d.reported_at DESC
+	`
+	if err := r.db.SelectContext(ctx, &result.Workloads, query, vulnerabilityID); err != nil {
+		return nil, err
+	}
+
+	// A workload running several affected images counts once
+	namespaces := map[string]bool{}
+	workloads := map[[3]string]bool{}
+	for _, w := range result.Workloads {
+		namespaces[w.Namespace] = true
+		if key := [3]string{w.Namespace, w.Kind, w.Name}; !workloads[key] {
+			workloads[key] = true
+			result.Replicas += w.Replicas
+			if w.Exposed {
+				result.Exposed++
+			}
+		}
+	}
+	result.Namespaces = len(namespaces)
+	return result, nil
+}
+
+// deployedExposed matches images i running in a workload behind an Ingress or a LoadBalancer Service,
+// by tag, or by digest for references pinned by digest only
+const deployedExposed = `EXISTS (
+	SELECT 1 FROM deployed_images d
+	JOIN deployed_workloads w ON w.deployed_image_id = d.id AND w.exposed
+	WHERE d.registry = i.registry AND d.repository = i.repository
+		AND (d.tag = i.tag OR (d.digest IS NOT NULL AND d.digest = i.digest))
+)`
+
+// exposedFilter restricts rows of images i to those running in an internet-exposed workload, or not
+func exposedFilter(exposed *bool) string {
+	if exposed == nil {
+		return ""
+	}
+	if *exposed {
+		return " AND " + deployedExposed
+	}
+	return " AND NOT " + deployedExposed
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventoryRepository_ListCoverage(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	imageScanRepo := NewImageScanRepository(db)
+	repo := NewInventoryRepository(db)
+
+	digest := "sha256:abc"
+	scanned := &models.Image{Registry: "docker.io", Repository: "nginx", Tag: "1.25"}
+	require.NoError(t, imageRepo.Create(ctx, scanned))
+	pinned := &models.Image{Registry: "ghcr.io", Repository: "acme/api", Tag: "1.0", Digest: &digest}
+	require.NoError(t, imageRepo.Create(ctx, pinned))
+	scanDate := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	require.NoError(t, scanRepo.Create(ctx, &models.Scan{ImageID: scanned.ID, ScanDate: scanDate, Status: "completed"}))
+	require.NoError(t, scanRepo.Create(ctx, &models.Scan{ImageID: pinned.ID, ScanDate: scanDate, Status: "failed"}))
+	require.NoError(t, imageScanRepo.Upsert(ctx, &models.ImageScanRegistration{
+		Namespace: "web", Name: "nginx", Registry: "docker.io", Repository: "nginx", Tag: "1.25",
+	}))
+
+	require.NoError(t, repo.Replace(ctx, models.InventorySourceController, []models.DeployedImage{
+		{Namespace: "web", Image: "nginx:1.25", Registry: "docker.io", Repository: "nginx", Tag: "1.25"},
+		{Namespace: "payments", Image: "ghcr.io/acme/api@sha256:abc", Registry: "ghcr.io", Repository: "acme/api", Digest: &digest},
+		{Namespace: "payments", Image: "redis:7.2", Registry: "docker.io", Repository: "redis", Tag: "7.2"},
+	}))
+	require.NoError(t, repo.Replace(ctx, "cmdb", []models.DeployedImage{
+		{Namespace: "batch", Image: "nginx:1.25", Registry: "docker.io", Repository: "nginx", Tag: "1.25"},
+	}))
+
+	coverage, err := repo.ListCoverage(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, coverage, 3)
+
+	nginx := coverage[0]
+	assert.Equal(t, "nginx", nginx.Repository)
+	assert.Equal(t, []string{"batch", "web"}, []string(nginx.Namespaces))
+	assert.Equal(t, []string{"cmdb", "controller"}, []string(nginx.Sources))
+	require.NotNil(t, nginx.LastScanDate)
+	assert.True(t, scanDate.Equal(*nginx.LastScanDate))
+	assert.True(t, nginx.HasImageScan)
+
+	// Matched by digest, but never scanned successfully
+	redis, api := coverage[1], coverage[2]
+	assert.Equal(t, "redis", redis.Repository)
+	assert.Nil(t, redis.ImageID)
+	require.NotNil(t, api.ImageID)
+	assert.Equal(t, pinned.ID, *api.ImageID)
+	assert.Nil(t, api.LastScanDate)
+	assert.False(t, api.HasImageScan)
+
+	// A new report replaces the previous inventory of its source only
+	require.NoError(t, repo.Replace(ctx, models.InventorySourceController, nil))
+	coverage, err = repo.ListCoverage(ctx, "batch")
+	require.NoError(t, err)
+	require.Len(t, coverage, 1)
+	assert.Equal(t, []string{"cmdb"}, []string(coverage[0].Sources))
+	coverage, err = repo.ListCoverage(ctx, "payments")
+	require.NoError(t, err)
+	assert.Empty(t, coverage)
+}
+
+func TestInventoryRepository_ListVulnerabilityWorkloads(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	repo := NewInventoryRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "nginx", Tag: "1.25"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: "completed"}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+	vuln := &models.Vulnerability{
+		CVEID: "CVE-2024-0001", PackageName: "openssl", PackageVersion: "3.0.7", Severity: "Critical",
+		Status: "active", FirstDetectedAt: time.Now(), LastSeenAt: time.Now(),
+	}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+
+	// The same Deployment is reported by two sources, the latest report wins
+	require.NoError(t, repo.Replace(ctx, "cmdb", []models.DeployedImage{
+		{Namespace: "web", Image: "nginx:1.25", Registry: "docker.io", Repository: "nginx", Tag: "1.25",
+			Workloads: []models.InventoryWorkload{{Kind: "Deployment", Name: "nginx", Replicas: 5}}},
+	}))
+	require.NoError(t, repo.Replace(ctx, models.InventorySourceController, []models.DeployedImage{
+		{Namespace: "web", Image: "nginx:1.25", Registry: "docker.io", Repository: "nginx", Tag: "1.25",
+			Workloads: []models.InventoryWorkload{{Kind: "Deployment", Name: "nginx", Replicas: 3, Exposed: true}}},
+		{Namespace: "batch", Image: "docker.io/library/nginx:1.25", Registry: "docker.io", Repository: "nginx", Tag: "1.25",
+			Workloads: []models.InventoryWorkload{{Kind: "Job", Name: "rotate", Replicas: 1}}},
+		{Namespace: "web", Image: "redis:7.2", Registry: "docker.io", Repository: "redis", Tag: "7.2",
+			Workloads: []models.InventoryWorkload{{Kind: "StatefulSet", Name: "redis", Replicas: 3}}},
+	}))
+
+	result, err := repo.ListVulnerabilityWorkloads(ctx, vuln.ID)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, "CVE-2024-0001", result.CVEID)
+	assert.Equal(t, 2, result.Namespaces)
+	assert.Equal(t, 4, result.Replicas)
+	require.Len(t, result.Workloads, 2)
+	assert.Equal(t, "batch", result.Workloads[0].Namespace)
+	assert.Equal(t, "rotate", result.Workloads[0].Name)
+	assert.Equal(t, image.ID, result.Workloads[0].ImageID)
+	assert.Equal(t, models.InventorySourceController, result.Workloads[1].Source)
+	assert.Equal(t, 3, result.Workloads[1].Replicas)
+	assert.True(t, result.Workloads[1].Exposed)
+	assert.Equal(t, 1, result.Exposed)
+
+	// Findings on images run by exposed workloads
+	exposed := true
+	vulns, err := vulnRepo.ListWithImageInfo(ctx, 10, 0, Sort{}, nil, nil, nil, nil, nil, nil, nil, &exposed)
+	require.NoError(t, err)
+	require.Len(t, vulns, 1)
+	assert.Equal(t, image.ID, vulns[0].ImageID)
+	exposed = false
+	count, err := vulnRepo.CountWithImageInfo(ctx, nil, nil, nil, nil, nil, nil, nil, &exposed)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	result, err = repo.ListVulnerabilityWorkloads(ctx, vuln.ID+1)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
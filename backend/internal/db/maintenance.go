@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// MaintenanceRepository handles the maintenance mode singleton row
+type MaintenanceRepository struct {
+	db *Database
+}
+
+// NewMaintenanceRepository creates a new maintenance repository
+func NewMaintenanceRepository(db *Database) *MaintenanceRepository {
+	return &MaintenanceRepository{db: db}
+}
+
+// Get returns the current maintenance state
+// A missing row is treated as maintenance disabled
+func (r *MaintenanceRepository) Get(ctx context.Context) (*models.MaintenanceMode, error) {
+	var mode models.MaintenanceMode
+	query := `
+		SELECT enabled, reason, retry_after_seconds, updated_by, updated_at
+		FROM maintenance_mode
+		WHERE id = 1
+	`
+	if err := r.db.GetContext(ctx, &mode, query); err != nil {
+		if err == sql.ErrNoRows {
+			return &models.MaintenanceMode{RetryAfterSeconds: 300}, nil
+		}
+		return nil, fmt.Errorf("failed to get maintenance mode: %w", err)
+	}
+	return &mode, nil
+}
+
+// Set updates the maintenance state and returns the stored row
+func (r *MaintenanceRepository) Set(ctx context.Context, enabled bool, reason *string, retryAfterSeconds int, updatedBy string) (*models.MaintenanceMode, error) {
+	var mode models.MaintenanceMode
+	query := `
+		INSERT INTO maintenance_mode (id, enabled, reason, retry_after_seconds, updated_by, updated_at)
+		VALUES (1, $1, $2, $3, $4, NOW())
+		ON CONFLICT (id)
+		DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			reason = EXCLUDED.reason,
+			retry_after_seconds = EXCLUDED.retry_after_seconds,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING enabled, reason, retry_after_seconds, updated_by, updated_at
+	`
+	if err := r.db.GetContext(ctx, &mode, query, enabled, reason, retryAfterSeconds, updatedBy); err != nil {
+		return nil, fmt.Errorf("failed to set maintenance mode: %w", err)
+	}
+	return &mode, nil
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/sla"
+	"github.com/lib/pq"
+)
+
+type NamespaceRepository struct {
+	db *Database
+}
+
+func NewNamespaceRepository(db *Database) *NamespaceRepository {
+	return &NamespaceRepository{db: db}
+}
+
+// ListSummaries returns the ImageScans, open findings, SLA compliance at now and findings trend since the given
+// time of every namespace with an ImageScan or findings. Findings belong to the namespace of the ImageScan that
+// last found them, findings of images scanned outside ImageScans are left out. Coverage is left to the caller
+func (r *NamespaceRepository) ListSummaries(ctx context.Context, now, trendSince time.Time) ([]models.NamespaceSummary, error) {
+	summaries := map[string]*models.NamespaceSummary{}
+	summary := func(namespace string) *models.NamespaceSummary {
+		s, ok := summaries[namespace]
+		if !ok {
+			s = &models.NamespaceSummary{Namespace: namespace}
+			summaries[namespace] = s
+		}
+		return s
+	}
+
+	var imageScans []struct {
+		Namespace string `db:"namespace"`
+		Count     int    `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &imageScans, `SELECT namespace, COUNT(*) AS count FROM imagescans GROUP BY namespace`); err != nil {
+		return nil, err
+	}
+	for _, row := range imageScans {
+		summary(row.Namespace).ImageScans = row.Count
+	}
+
+	// The SLA of a finding is the one of the latest scan that found it
+	findingQuery := `
+		SELECT
+			v.imagescan_namespace AS namespace, v.severity, v.initial_severity, v.first_detected_at,
+			s.sla_critical, s.sla_high, s.sla_medium, s.sla_low, s.sla_time_zone, s.sla_business_days, s.sla_holidays
+		FROM vulnerabilities v
+		JOIN LATERAL (
+			SELECT s.sla_critical, s.sla_high, s.sla_medium, s.sla_low, s.sla_time_zone, s.sla_business_days, s.sla_holidays
+			FROM scan_vulnerabilities sv
+			JOIN scans s ON s.id = sv.scan_id
+			WHERE sv.vulnerability_id = v.id
+			ORDER BY s.scan_date DESC
+			LIMIT 1
+		) s ON TRUE
+		WHERE v.status IN ('active', 'in_progress') AND v.imagescan_namespace IS NOT NULL
+	`
+	var findings []struct {
+		Namespace       string         `db:"namespace"`
+		Severity        string         `db:"severity"`
+		InitialSeverity string         `db:"initial_severity"`
+		FirstDetectedAt time.Time      `db:"first_detected_at"`
+		SLACritical     int            `db:"sla_critical"`
+		SLAHigh         int            `db:"sla_high"`
+		SLAMedium       int            `db:"sla_medium"`
+		SLALow          int            `db:"sla_low"`
+		SLATimeZone     string         `db:"sla_time_zone"`
+		SLABusinessDays bool           `db:"sla_business_days"`
+		SLAHolidays     pq.StringArray `db:"sla_holidays"`
+	}
+	if err := r.db.SelectContext(ctx, &findings, findingQuery); err != nil {
+		return nil, err
+	}
+	for _, f := range findings {
+		s := summary(f.Namespace)
+		s.OpenFindings.Add(f.Severity, 1)
+
+		loc, err := sla.LoadLocation(f.SLATimeZone)
+		if err != nil {
+			// Timezones are validated when scans are stored, this is a zone removed from tzdata since
+			loc = time.UTC
+		}
+		// Holidays are validated when scans are stored and read back from a DATE[] column
+		calendar, _ := sla.NewCalendar(f.SLABusinessDays, f.SLAHolidays)
+		days := sla.Days(sla.Severity(r.db.slaSeverity, f.InitialSeverity, f.Severity), f.SLACritical, f.SLAHigh, f.SLAMedium, f.SLALow)
+		if now.Before(calendar.DeadlineFor(f.FirstDetectedAt, days, loc).DueAt) {
+			s.SLA.WithinSLA++
+		} else {
+			s.SLA.Overdue++
+		}
+	}
+
+	trendQuery := `
+		SELECT
+			imagescan_namespace AS namespace,
+			COUNT(*) FILTER (WHERE first_detected_at > $1) AS detected,
+			COUNT(*) FILTER (WHERE status = 'fixed' AND remediation_date > $1) AS fixed
+		FROM vulnerabilities
+		WHERE imagescan_namespace IS NOT NULL AND (first_detected_at > $1 OR remediation_date > $1)
+		GROUP BY imagescan_namespace
+	`
+	var trends []struct {
+		Namespace string `db:"namespace"`
+		Detected  int    `db:"detected"`
+		Fixed     int    `db:"fixed"`
+	}
+	if err := r.db.SelectContext(ctx, &trends, trendQuery, trendSince); err != nil {
+		return nil, err
+	}
+	for _, row := range trends {
+		s := summary(row.Namespace)
+		s.Trend.Detected, s.Trend.Fixed = row.Detected, row.Fixed
+	}
+
+	result := make([]models.NamespaceSummary, 0, len(summaries))
+	for _, s := range summaries {
+		s.SLA.CompliancePercent = 100
+		if s.OpenFindings.Total > 0 {
+			s.SLA.CompliancePercent = math.Round(float64(s.SLA.WithinSLA)*1000/float64(s.OpenFindings.Total)) / 10
+		}
+		s.Trend.WindowDays = int(math.Round(now.Sub(trendSince).Hours() / 24))
+		switch {
+		case s.Trend.Detected > s.Trend.Fixed:
+			s.Trend.Direction = models.TrendUp
+		case s.Trend.Detected < s.Trend.Fixed:
+			s.Trend.Direction = models.TrendDown
+		default:
+			s.Trend.Direction = models.TrendFlat
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Namespace < result[j].Namespace })
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceRepository_ListSummaries(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	imageScanRepo := NewImageScanRepository(db)
+	repo := NewNamespaceRepository(db)
+	now := time.Now()
+
+	for _, reg := range []*models.ImageScanRegistration{
+		{Namespace: "payments", Name: "api", Registry: "ghcr.io", Repository: "acme/api", Tag: "1.0"},
+		{Namespace: "payments", Name: "worker", Registry: "ghcr.io", Repository: "acme/worker", Tag: "1.0"},
+		{Namespace: "web", Name: "nginx", Registry: "docker.io", Repository: "nginx", Tag: "1.25"},
+	} {
+		require.NoError(t, imageScanRepo.Upsert(ctx, reg))
+	}
+
+	image := &models.Image{Registry: "ghcr.io", Repository: "acme/api", Tag: "1.0"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scan := &models.Scan{ImageID: image.ID, ScanDate: now, Status: models.ScanStatusCompleted,
+		SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180, SLATimeZone: "UTC"}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+
+	payments, ci := "payments", (*string)(nil)
+	newVuln := func(cve, severity string, detected time.Time, namespace *string) *models.Vulnerability {
+		vuln := &models.Vulnerability{
+			CVEID: cve, PackageName: "openssl", PackageVersion: "3.0.7", Severity: severity,
+			Status: models.StatusActive, FirstDetectedAt: detected, LastSeenAt: now, ImageScanNamespace: namespace,
+		}
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+		return vuln
+	}
+	// The critical is past its 7-day SLA, the high within its 30 days
+	newVuln("CVE-2024-0001", "Critical", now.AddDate(0, 0, -20), &payments)
+	newVuln("CVE-2024-0002", "High", now.AddDate(0, 0, -2), &payments)
+	fixed := newVuln("CVE-2024-0003", "High", now.AddDate(0, 0, -40), &payments)
+	_, err := db.ExecContext(ctx, `UPDATE vulnerabilities SET status = 'fixed', remediation_date = NOW() WHERE id = $1`, fixed.ID)
+	require.NoError(t, err)
+	newVuln("CVE-2024-0004", "Critical", now, ci)
+
+	summaries, err := repo.ListSummaries(ctx, now, now.AddDate(0, 0, -7))
+	require.NoError(t, err)
+	require.Len(t, summaries, 2, "findings outside ImageScans are left out")
+
+	p, w := summaries[0], summaries[1]
+	assert.Equal(t, "payments", p.Namespace)
+	assert.Equal(t, 2, p.ImageScans)
+	assert.Equal(t, models.SeverityCounts{Critical: 1, High: 1, Total: 2}, p.OpenFindings)
+	assert.Equal(t, models.NamespaceSLA{WithinSLA: 1, Overdue: 1, CompliancePercent: 50}, p.SLA)
+	assert.Equal(t, models.NamespaceTrend{WindowDays: 7, Detected: 1, Fixed: 1, Direction: models.TrendFlat}, p.Trend)
+
+	assert.Equal(t, "web", w.Namespace)
+	assert.Equal(t, 1, w.ImageScans)
+	assert.Zero(t, w.OpenFindings.Total)
+	assert.Equal(t, 100.0, w.SLA.CompliancePercent)
+	assert.Equal(t, models.TrendFlat, w.Trend.Direction)
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/online"
+	"github.com/lib/pq"
+)
+
+// OnlineChangeRepository applies online schema changes and records their progress
+type OnlineChangeRepository struct {
+	db *Database
+}
+
+// NewOnlineChangeRepository creates a new online change repository
+func NewOnlineChangeRepository(db *Database) *OnlineChangeRepository {
+	return &OnlineChangeRepository{db: db}
+}
+
+// ListProgress returns the progress of the changes that ran
+func (r *OnlineChangeRepository) ListProgress(ctx context.Context) ([]models.OnlineChange, error) {
+	progress := []models.OnlineChange{}
+	if err := r.db.SelectContext(ctx, &progress, `SELECT * FROM online_changes ORDER BY name`); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// SaveProgress records the progress of a change
+func (r *OnlineChangeRepository) SaveProgress(ctx context.Context, progress *models.OnlineChange) error {
+	query := `
+		INSERT INTO online_changes (name, status, last_id, max_id, rows_updated, error, started_at, updated_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), $8)
+		ON CONFLICT (name) DO UPDATE SET
+			status = EXCLUDED.status,
+			last_id = EXCLUDED.last_id,
+			max_id = EXCLUDED.max_id,
+			rows_updated = EXCLUDED.rows_updated,
+			error = EXCLUDED.error,
+			updated_at = NOW(),
+			completed_at = EXCLUDED.completed_at
+		RETURNING updated_at
+	`
+	return r.db.QueryRowContext(ctx, query,
+		progress.Name, progress.Status, progress.LastID, progress.MaxID, progress.RowsUpdated,
+		progress.Error, progress.StartedAt, progress.CompletedAt,
+	).Scan(&progress.UpdatedAt)
+}
+
+// BuildIndex runs a CREATE INDEX CONCURRENTLY statement. A build that failed leaves an invalid index
+// behind, which IF NOT EXISTS would keep, so it is dropped first. Neither can run in a transaction
+func (r *OnlineChangeRepository) BuildIndex(ctx context.Context, name, statement string) error {
+	var valid bool
+	err := r.db.GetContext(ctx, &valid, `
+		SELECT i.indisvalid FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		WHERE c.relname = $1 AND pg_table_is_visible(c.oid)
+	`, name)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check index %s: %w", name, err)
+	}
+	if err == nil && !valid {
+		if _, err := r.db.ExecContext(ctx, `DROP INDEX CONCURRENTLY IF EXISTS `+pq.QuoteIdentifier(name)); err != nil {
+			return fmt.Errorf("failed to drop invalid index %s: %w", name, err)
+		}
+	}
+
+	if _, err := r.db.ExecContext(ctx, statement); err != nil {
+		return fmt.Errorf("failed to build index %s: %w", name, err)
+	}
+	return nil
+}
+
+// MaxID returns the highest ID of a table, 0 when it is empty
+func (r *OnlineChangeRepository) MaxID(ctx context.Context, table string) (int64, error) {
+	var maxID int64
+	query := `SELECT COALESCE(MAX(id), 0) FROM ` + pq.QuoteIdentifier(table)
+	if err := r.db.GetContext(ctx, &maxID, query); err != nil {
+		return 0, fmt.Errorf("failed to get the highest ID of %s: %w", table, err)
+	}
+	return maxID, nil
+}
+
+// BackfillBatch updates the rows of a backfill with IDs in (fromID, toID]
+func (r *OnlineChangeRepository) BackfillBatch(ctx context.Context, backfill *online.Backfill, fromID, toID int64) (int64, error) {
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE id > $1 AND id <= $2 AND (%s)`,
+		pq.QuoteIdentifier(backfill.Table), backfill.Set, backfill.Where)
+	result, err := r.db.ExecContext(ctx, query, fromID, toID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill %s: %w", backfill.Table, err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/online"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestOnlineChangeRepository(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewOnlineChangeRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+
+	now := time.Now()
+	for _, cve := range []string{"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0003"} {
+		require.NoError(t, vulnRepo.Upsert(ctx, &models.Vulnerability{
+			CVEID: cve, PackageName: "openssl", PackageVersion: "3.0.7", Severity: "High",
+			Status: models.StatusActive, FirstDetectedAt: now, LastSeenAt: now,
+		}))
+	}
+	// Vulnerabilities known before migration 041 have no initial severity
+	_, err := db.ExecContext(ctx, `UPDATE vulnerabilities SET initial_severity = '' WHERE cve_id <> 'CVE-2024-0002'`)
+	require.NoError(t, err)
+
+	// An index whose build failed is invalid, it is rebuilt
+	_, err = db.ExecContext(ctx, `CREATE INDEX idx_vulnerabilities_purl ON vulnerabilities(purl text_pattern_ops)`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `UPDATE pg_index SET indisvalid = false WHERE indexrelid = 'idx_vulnerabilities_purl'::regclass`)
+	require.NoError(t, err)
+
+	runner := online.New(zap.NewNop(), repo, online.Changes)
+	require.NoError(t, runner.Run(ctx, now))
+
+	var initial []string
+	require.NoError(t, db.SelectContext(ctx, &initial, `SELECT initial_severity FROM vulnerabilities ORDER BY id`))
+	assert.Equal(t, []string{"High", "High", "High"}, initial)
+
+	var valid bool
+	require.NoError(t, db.GetContext(ctx, &valid, `SELECT indisvalid FROM pg_index WHERE indexrelid = 'idx_vulnerabilities_purl'::regclass`))
+	assert.True(t, valid)
+
+	statuses, err := runner.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(online.Changes))
+	for _, status := range statuses {
+		assert.Equal(t, models.OnlineChangeCompleted, status.Status, status.Name)
+		if status.Name == "vulnerabilities_initial_severity" {
+			assert.Equal(t, int64(2), status.Progress.RowsUpdated)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// OutboxRepository stores webhook notifications until the dispatcher delivers them
+type OutboxRepository struct {
+	db *Database
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *Database) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// insertOutboxEvents writes events in the transaction of the change they report.
+// Payloads hold webhook URLs and notes, so they are encrypted like those columns
+func (d *Database) insertOutboxEvents(ctx context.Context, tx *sqlx.Tx, scanID *int, events []*models.OutboxEvent) error {
+	for _, event := range events {
+		if event == nil {
+			continue
+		}
+		if scanID != nil {
+			event.ScanID = scanID
+		}
+		payload, err := d.encrypt(&event.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s event: %w", event.Kind, err)
+		}
+		var availableAt *time.Time
+		if !event.AvailableAt.IsZero() {
+			availableAt = &event.AvailableAt
+		}
+		if err := tx.QueryRowxContext(ctx, `
+			INSERT INTO notification_outbox (kind, scan_id, payload, available_at)
+			VALUES ($1, $2, $3, COALESCE($4, NOW()))
+			RETURNING id, status, available_at, created_at
+		`, event.Kind, event.ScanID, *payload, availableAt).Scan(&event.ID, &event.Status, &event.AvailableAt, &event.CreatedAt); err != nil {
+			return fmt.Errorf("failed to write %s event: %w", event.Kind, err)
+		}
+	}
+	return nil
+}
+
+// Enqueue writes events that aren't part of another change
+func (r *OutboxRepository) Enqueue(ctx context.Context, events ...*models.OutboxEvent) error {
+	return r.ReleaseScan(ctx, nil, events...)
+}
+
+// ReleaseScan writes the events produced by processing the results of a scan, and makes the events
+// held when the scan was stored deliverable, in one transaction
+func (r *OutboxRepository) ReleaseScan(ctx context.Context, scanID *int, events ...*models.OutboxEvent) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.db.insertOutboxEvents(ctx, tx, scanID, events); err != nil {
+		return err
+	}
+	if scanID != nil {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE notification_outbox SET available_at = NOW()
+			WHERE scan_id = $1 AND status = 'pending' AND available_at > NOW()
+		`, *scanID); err != nil {
+			return fmt.Errorf("failed to release scan events: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Claim leases up to limit deliverable events to the caller. Events being delivered by another
+// dispatcher are skipped, and an event whose lease expired (its dispatcher died) is claimed again
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	query := `
+		UPDATE notification_outbox o
+		SET attempts = o.attempts + 1, locked_until = NOW() + make_interval(secs => $2)
+		FROM (
+			SELECT id FROM notification_outbox
+			WHERE status = 'pending' AND available_at <= NOW()
+				AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE o.id = due.id
+		RETURNING o.*
+	`
+	events := []models.OutboxEvent{}
+	if err := r.db.SelectContext(ctx, &events, query, limit, lease.Seconds()); err != nil {
+		return nil, err
+	}
+	for i := range events {
+		if err := r.db.decrypt(&events[i].Payload); err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s event %d: %w", events[i].Kind, events[i].ID, err)
+		}
+	}
+	return events, nil
+}
+
+// MarkSent records the delivery of an event
+func (r *OutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notification_outbox
+		SET status = 'sent', sent_at = NOW(), locked_until = NULL, last_error = NULL
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+// MarkFailed records a failed delivery. The event is tried again at retryAt, or given up when nil
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, message string, retryAt *time.Time) error {
+	status := models.OutboxStatusPending
+	if retryAt == nil {
+		status = models.OutboxStatusFailed
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notification_outbox
+		SET status = $2, available_at = COALESCE($3, available_at), locked_until = NULL, last_error = $4
+		WHERE id = $1
+	`, id, status, retryAt, message)
+	return err
+}
+
+// CountPending returns the number of events waiting to be delivered
+func (r *OutboxRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM notification_outbox WHERE status = 'pending'`)
+	return count, err
+}
+
+// DeleteFinished removes the events sent or given up before the cutoff and returns how many
+func (r *OutboxRepository) DeleteFinished(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM notification_outbox
+		WHERE status != 'pending' AND COALESCE(sent_at, available_at) < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxRepository_ScanEventsHeldUntilReleased(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewOutboxRepository(db)
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	// Written with the scan, held until its results are processed
+	held, err := models.NewOutboxEvent(models.OutboxKindScanCompleted, map[string]string{"image": "nginx:latest"})
+	require.NoError(t, err)
+	held.AvailableAt = time.Now().Add(10 * time.Minute)
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: models.ScanStatusCompleted}
+	require.NoError(t, scanRepo.Create(ctx, scan, held))
+	assert.NotZero(t, held.ID)
+	assert.Equal(t, scan.ID, *held.ScanID)
+
+	claimed, err := repo.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	watch, err := models.NewOutboxEvent(models.OutboxKindWatchlist, map[string]int{"subscription_id": 1})
+	require.NoError(t, err)
+	require.NoError(t, repo.ReleaseScan(ctx, &scan.ID, watch))
+
+	claimed, err = repo.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, models.OutboxKindScanCompleted, claimed[0].Kind)
+	assert.JSONEq(t, `{"image":"nginx:latest"}`, claimed[0].Payload)
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.Equal(t, models.OutboxKindWatchlist, claimed[1].Kind)
+
+	// Leased: not claimed again until the lease expires
+	claimed, err = repo.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+}
+
+func TestOutboxRepository_Deliveries(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewOutboxRepository(db)
+
+	sent, err := models.NewOutboxEvent(models.OutboxKindStatusChange, map[string]int{"vulnerability_id": 1})
+	require.NoError(t, err)
+	retried, err := models.NewOutboxEvent(models.OutboxKindStatusChange, map[string]int{"vulnerability_id": 2})
+	require.NoError(t, err)
+	failed, err := models.NewOutboxEvent(models.OutboxKindStatusChange, map[string]int{"vulnerability_id": 3})
+	require.NoError(t, err)
+	require.NoError(t, repo.Enqueue(ctx, sent, retried, failed))
+	pending, err := repo.CountPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, pending)
+
+	// An expired lease is claimed again, like after a dispatcher died mid-delivery
+	claimed, err := repo.Claim(ctx, 10, -time.Second)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+	claimed, err = repo.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+	assert.Equal(t, 2, claimed[0].Attempts)
+
+	require.NoError(t, repo.MarkSent(ctx, sent.ID))
+	retryAt := time.Now().Add(time.Hour)
+	require.NoError(t, repo.MarkFailed(ctx, retried.ID, "webhook returned non-2xx status: 503", &retryAt))
+	require.NoError(t, repo.MarkFailed(ctx, failed.ID, "connection refused", nil))
+
+	claimed, err = repo.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "sent and given up events are done, the retry isn't due")
+
+	// Only finished events are cleaned up
+	deleted, err := repo.DeleteFinished(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+}
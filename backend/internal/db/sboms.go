@@ -1,12 +1,20 @@
 package db
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/sbom"
 	"github.com/invulnerable/backend/internal/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type SBOMRepository struct {
@@ -23,20 +31,26 @@ func NewSBOMRepository(db *Database, s3Storage storage.SBOMStorage) *SBOMReposit
 
 // Create stores SBOM metadata in database and document in S3
 func (r *SBOMRepository) Create(ctx context.Context, sbom *models.SBOM, document []byte) error {
+	return r.CreateFrom(ctx, sbom, io.NewSectionReader(bytes.NewReader(document), 0, int64(len(document))))
+}
+
+// CreateFrom is Create with the document read from a file, streamed to S3
+func (r *SBOMRepository) CreateFrom(ctx context.Context, sbom *models.SBOM, document *io.SectionReader) error {
 	// Store document in S3 first
-	if err := r.storage.Store(ctx, sbom.ScanID, document); err != nil {
+	if err := storage.StoreDocument(ctx, r.storage, sbom.ScanID, document); err != nil {
 		return fmt.Errorf("failed to store SBOM in S3: %w", err)
 	}
 
 	// Calculate size
-	sizeBytes := int64(len(document))
+	sizeBytes := document.Size()
 
 	// Store metadata in database
 	query := `
 		INSERT INTO sboms (scan_id, format, version, size_bytes, created_at)
 		VALUES ($1, $2, $3, $4, NOW())
 		ON CONFLICT (scan_id)
-		DO UPDATE SET format = EXCLUDED.format, version = EXCLUDED.version, size_bytes = EXCLUDED.size_bytes
+		DO UPDATE SET format = EXCLUDED.format, version = EXCLUDED.version, size_bytes = EXCLUDED.size_bytes, component_count = NULL, document_scan_id = NULL,
+			quality_score = NULL, quality = NULL
 		RETURNING id, created_at
 	`
 	if err := r.db.QueryRowContext(ctx, query,
@@ -51,13 +65,49 @@ func (r *SBOMRepository) Create(ctx context.Context, sbom *models.SBOM, document
 	return nil
 }
 
+// Share gives a scan the SBOM of an earlier scan with the same contents: the metadata, quality and
+// component index are copied, the document in S3 is shared rather than stored again
+func (r *SBOMRepository) Share(ctx context.Context, scanID, sourceScanID int) (*models.SBOM, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sbom models.SBOM
+	query := `
+		INSERT INTO sboms (scan_id, format, version, size_bytes, component_count, document_scan_id, quality_score, quality, created_at)
+		SELECT $1, format, version, size_bytes, component_count, COALESCE(document_scan_id, scan_id), quality_score, quality, NOW()
+		FROM sboms WHERE scan_id = $2
+		RETURNING *
+	`
+	if err := tx.GetContext(ctx, &sbom, query, scanID, sourceScanID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("SBOM")
+		}
+		return nil, fmt.Errorf("failed to share SBOM: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO sbom_components (scan_id, name, version, type, purl)
+		SELECT $1, name, version, type, purl FROM sbom_components WHERE scan_id = $2
+	`, scanID, sourceScanID); err != nil {
+		return nil, fmt.Errorf("failed to copy SBOM components: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &sbom, nil
+}
+
 // GetByScanID retrieves SBOM metadata from database
 func (r *SBOMRepository) GetByScanID(ctx context.Context, scanID int) (*models.SBOM, error) {
 	var sbom models.SBOM
 	query := `SELECT * FROM sboms WHERE scan_id = $1`
 	if err := r.db.GetContext(ctx, &sbom, query, scanID); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("SBOM not found")
+			return nil, notFound("SBOM")
 		}
 		return nil, err
 	}
@@ -67,12 +117,13 @@ func (r *SBOMRepository) GetByScanID(ctx context.Context, scanID int) (*models.S
 // GetDocumentByScanID retrieves the SBOM document from S3
 func (r *SBOMRepository) GetDocumentByScanID(ctx context.Context, scanID int) ([]byte, error) {
 	// Verify SBOM exists in database
-	if _, err := r.GetByScanID(ctx, scanID); err != nil {
+	sbom, err := r.GetByScanID(ctx, scanID)
+	if err != nil {
 		return nil, err
 	}
 
 	// Retrieve document from S3
-	document, err := r.storage.Retrieve(ctx, scanID)
+	document, err := r.storage.Retrieve(ctx, sbom.StorageScanID())
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve SBOM document from S3: %w", err)
 	}
@@ -80,15 +131,32 @@ func (r *SBOMRepository) GetDocumentByScanID(ctx context.Context, scanID int) ([
 	return document, nil
 }
 
-// GetPresignedURL generates a pre-signed URL for direct SBOM download
-func (r *SBOMRepository) GetPresignedURL(ctx context.Context, scanID int) (string, error) {
+// OpenDocumentByScanID streams the SBOM document from S3 as stored, with its content encoding
+// (storage.EncodingZstd or storage.EncodingIdentity). The caller closes the reader
+func (r *SBOMRepository) OpenDocumentByScanID(ctx context.Context, scanID int) (io.ReadCloser, string, error) {
 	// Verify SBOM exists in database
-	if _, err := r.GetByScanID(ctx, scanID); err != nil {
+	sbom, err := r.GetByScanID(ctx, scanID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, encoding, err := storage.OpenDocument(ctx, r.storage, sbom.StorageScanID())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to retrieve SBOM document from S3: %w", err)
+	}
+
+	return body, encoding, nil
+}
+
+// GetPresignedURL generates a pre-signed URL for direct SBOM download, valid for expiresIn
+func (r *SBOMRepository) GetPresignedURL(ctx context.Context, scanID int, expiresIn time.Duration) (string, error) {
+	// Verify SBOM exists in database
+	sbom, err := r.GetByScanID(ctx, scanID)
+	if err != nil {
 		return "", err
 	}
 
-	// Generate presigned URL (valid for 1 hour)
-	url, err := r.storage.GetPresignedURL(ctx, scanID, 3600)
+	url, err := r.storage.GetPresignedURL(ctx, sbom.StorageScanID(), expiresIn)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
 	}
@@ -96,18 +164,250 @@ func (r *SBOMRepository) GetPresignedURL(ctx context.Context, scanID int) (strin
 	return url, nil
 }
 
-// Delete removes SBOM from both S3 and database
+// IndexComponents stores the packages listed in the SBOM document of a scan, replacing
+// any previous index, so they can be searched without reading the documents from S3
+func (r *SBOMRepository) IndexComponents(ctx context.Context, scanID int, document []byte) error {
+	return r.IndexComponentsFrom(ctx, scanID, bytes.NewReader(document))
+}
+
+// IndexComponentsFrom is IndexComponents with the document read from a file a package at a time
+func (r *SBOMRepository) IndexComponentsFrom(ctx context.Context, scanID int, document io.ReadSeeker) error {
+	components, err := sbom.ReadComponents(document)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(components))
+	versions := make([]string, len(components))
+	types := make([]string, len(components))
+	purls := make([]string, len(components))
+	for i, c := range components {
+		names[i], versions[i], types[i], purls[i] = c.Name, c.Version, c.Type, c.PURL
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sbom_components WHERE scan_id = $1`, scanID); err != nil {
+		return fmt.Errorf("failed to clear SBOM components: %w", err)
+	}
+
+	query := `
+		INSERT INTO sbom_components (scan_id, name, version, type, purl)
+		SELECT $1, name, version, NULLIF(type, ''), NULLIF(purl, '')
+		FROM unnest($2::text[], $3::text[], $4::text[], $5::text[]) AS c(name, version, type, purl)
+	`
+	if _, err := tx.ExecContext(ctx, query, scanID,
+		pq.Array(names), pq.Array(versions), pq.Array(types), pq.Array(purls),
+	); err != nil {
+		return fmt.Errorf("failed to store SBOM components: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE sboms SET component_count = $2 WHERE scan_id = $1`, scanID, len(components))
+	if err != nil {
+		return fmt.Errorf("failed to update SBOM component count: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return notFound("SBOM")
+	}
+
+	return tx.Commit()
+}
+
+// ListLatestSBOMScans returns the latest scan with an SBOM of each image and target,
+// which is what the images currently contain. imageName filters the images by a substring
+// of their name (case-insensitive)
+func (r *SBOMRepository) ListLatestSBOMScans(ctx context.Context, imageName *string) ([]models.SBOMScan, error) {
+	var scans []models.SBOMScan
+	query := `
+		SELECT DISTINCT ON (s.image_id, s.target)
+			s.id AS scan_id, s.image_id, i.registry || '/' || i.repository || ':' || i.tag AS image_name,
+			i.digest, s.target, s.status, s.scan_date, sb.component_count, sb.quality
+		FROM scans s
+		JOIN images i ON s.image_id = i.id
+		JOIN sboms sb ON sb.scan_id = s.id
+		WHERE s.status IN ('completed', 'partial')
+	`
+	args := []interface{}{}
+	if imageName != nil {
+		args = append(args, "%"+escapeLike(*imageName)+"%")
+		query += fmt.Sprintf(" AND (i.registry || '/' || i.repository || ':' || i.tag) ILIKE $%d", len(args))
+	}
+	query += " ORDER BY s.image_id, s.target, s.scan_date DESC, s.id DESC"
+	if err := r.db.SelectContext(ctx, &scans, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list SBOM scans: %w", err)
+	}
+	return scans, nil
+}
+
+// IndexStoredDocument indexes the components of a scan's stored SBOM document, for SBOMs
+// stored before components were indexed at ingestion
+func (r *SBOMRepository) IndexStoredDocument(ctx context.Context, scanID int) error {
+	document, err := r.GetDocumentByScanID(ctx, scanID)
+	if err != nil {
+		return err
+	}
+	return r.IndexComponents(ctx, scanID, document)
+}
+
+// SetQuality stores the quality of the SBOM of a scan
+func (r *SBOMRepository) SetQuality(ctx context.Context, scanID int, quality *models.SBOMQuality) error {
+	document, err := json.Marshal(quality)
+	if err != nil {
+		return fmt.Errorf("failed to encode SBOM quality: %w", err)
+	}
+	result, err := r.db.ExecContext(ctx, `UPDATE sboms SET quality_score = $2, quality = $3 WHERE scan_id = $1`,
+		scanID, quality.Score, document)
+	if err != nil {
+		return fmt.Errorf("failed to update SBOM quality: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return notFound("SBOM")
+	}
+	return nil
+}
+
+// GetQuality returns the quality of the SBOM of a scan, nil for SBOMs stored before their quality
+// was scored at ingestion
+func (r *SBOMRepository) GetQuality(ctx context.Context, scanID int) (*models.SBOMQuality, error) {
+	sbom, err := r.GetByScanID(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+	if sbom.Quality == nil {
+		return nil, nil
+	}
+	var quality models.SBOMQuality
+	if err := json.Unmarshal(sbom.Quality, &quality); err != nil {
+		return nil, fmt.Errorf("failed to decode SBOM quality: %w", err)
+	}
+	return &quality, nil
+}
+
+// EvaluateStoredDocument scores the stored SBOM document of a scan against the size of its image
+// and stores its quality
+func (r *SBOMRepository) EvaluateStoredDocument(ctx context.Context, scanID int) (*models.SBOMQuality, error) {
+	document, err := r.GetDocumentByScanID(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+	var imageSizeBytes *int64
+	if err := r.db.GetContext(ctx, &imageSizeBytes, `SELECT image_size_bytes FROM scans WHERE id = $1`, scanID); err != nil {
+		return nil, fmt.Errorf("failed to get image size: %w", err)
+	}
+	quality, err := sbom.EvaluateDocumentQuality(document, imageSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.SetQuality(ctx, scanID, quality); err != nil {
+		return nil, err
+	}
+	return quality, nil
+}
+
+// ListComponents returns the indexed components of the given scans, ordered by scan
+func (r *SBOMRepository) ListComponents(ctx context.Context, scanIDs []int) ([]models.ScanComponent, error) {
+	components := []models.ScanComponent{}
+	if len(scanIDs) == 0 {
+		return components, nil
+	}
+
+	query := `
+		SELECT scan_id, name, version, COALESCE(type, '') AS type, COALESCE(purl, '') AS purl
+		FROM sbom_components
+		WHERE scan_id = ANY($1)
+		ORDER BY scan_id, name, version, purl
+	`
+	if err := r.db.SelectContext(ctx, &components, query, pq.Array(scanIDs)); err != nil {
+		return nil, fmt.Errorf("failed to list SBOM components: %w", err)
+	}
+	return components, nil
+}
+
+// FindComponents returns the indexed components of the given scans matching a package name
+// (case-insensitive) or a PURL. A PURL without version matches every version of the package
+func (r *SBOMRepository) FindComponents(ctx context.Context, scanIDs []int, name, purl *string) ([]models.ScanComponent, error) {
+	components := []models.ScanComponent{}
+	if len(scanIDs) == 0 || (name == nil && purl == nil) {
+		return components, nil
+	}
+
+	query := `
+		SELECT scan_id, name, version, COALESCE(type, '') AS type, COALESCE(purl, '') AS purl
+		FROM sbom_components
+		WHERE scan_id = ANY($1)
+	`
+	args := []interface{}{pq.Array(scanIDs)}
+	if name != nil {
+		args = append(args, *name)
+		query += fmt.Sprintf(" AND LOWER(name) = LOWER($%d)", len(args))
+	}
+	if purl != nil {
+		args = append(args, *purl, escapeLike(*purl)+"@%")
+		query += fmt.Sprintf(" AND (purl = $%d OR purl LIKE $%d)", len(args)-1, len(args))
+	}
+	query += " ORDER BY scan_id, name, version"
+
+	if err := r.db.SelectContext(ctx, &components, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to find SBOM components: %w", err)
+	}
+	return components, nil
+}
+
+// Delete removes SBOM from both S3 and database. A document shared with other scans stays in S3
 func (r *SBOMRepository) Delete(ctx context.Context, scanID int) error {
-	// Delete from S3 first
-	if err := r.storage.Delete(ctx, scanID); err != nil {
-		return fmt.Errorf("failed to delete SBOM from S3: %w", err)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Delete metadata from database
-	query := `DELETE FROM sboms WHERE scan_id = $1`
-	if _, err := r.db.ExecContext(ctx, query, scanID); err != nil {
+	var documents []int
+	query := `DELETE FROM sboms WHERE scan_id = $1 RETURNING COALESCE(document_scan_id, scan_id)`
+	if err := tx.SelectContext(ctx, &documents, query, scanID); err != nil {
 		return fmt.Errorf("failed to delete SBOM metadata: %w", err)
 	}
+	if documents, err = unreferencedDocuments(ctx, tx, documents); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
-	return nil
+	return r.DeleteDocuments(ctx, documents)
+}
+
+// unreferencedDocuments filters the SBOM documents no SBOM refers to anymore, once the metadata
+// of the deleted scans is gone in the transaction; the others are still shared
+func unreferencedDocuments(ctx context.Context, tx *sqlx.Tx, documents []int) ([]int, error) {
+	unreferenced := []int{}
+	if len(documents) == 0 {
+		return unreferenced, nil
+	}
+	query := `
+		SELECT d FROM unnest($1::int[]) AS d
+		WHERE NOT EXISTS (SELECT 1 FROM sboms WHERE COALESCE(document_scan_id, scan_id) = d)
+		ORDER BY d
+	`
+	if err := tx.SelectContext(ctx, &unreferenced, query, pq.Array(documents)); err != nil {
+		return nil, fmt.Errorf("failed to list unreferenced SBOM documents: %w", err)
+	}
+	return unreferenced, nil
+}
+
+// DeleteDocuments removes SBOM documents from S3 whose metadata is already gone,
+// e.g. after the scans were removed by a cascade. It keeps going after a failure
+// so one missing object does not leave the others behind. The IDs are the scans the
+// documents are stored under, see models.SBOM.StorageScanID
+func (r *SBOMRepository) DeleteDocuments(ctx context.Context, scanIDs []int) error {
+	var errs []error
+	for _, scanID := range scanIDs {
+		if err := r.storage.Delete(ctx, scanID); err != nil {
+			errs = append(errs, fmt.Errorf("scan %d: %w", scanID, err))
+		}
+	}
+	return errors.Join(errs...)
 }
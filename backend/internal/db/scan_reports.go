@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/storage"
+)
+
+// ErrReportExists is returned when a scan already has a report, which is never replaced. It is an
+// ErrConflict
+var ErrReportExists = conflict("scan report already exists")
+
+// ScanReportRepository stores the signed reports of scans, metadata in the database and the
+// document in S3 like SBOMs. Reports are written once and kept when their scan is deleted
+type ScanReportRepository struct {
+	db      *Database
+	storage storage.ReportStorage
+}
+
+func NewScanReportRepository(db *Database, s3Storage storage.ReportStorage) *ScanReportRepository {
+	return &ScanReportRepository{
+		db:      db,
+		storage: s3Storage,
+	}
+}
+
+// Create records the report of a scan and stores its document in S3. The metadata is written first,
+// so an existing report is never overwritten in the bucket
+func (r *ScanReportRepository) Create(ctx context.Context, scanID int, keyID string, document []byte) (*models.ScanReportArchive, error) {
+	sum := sha256.Sum256(document)
+	sizeBytes := int64(len(document))
+	archive := &models.ScanReportArchive{ScanID: scanID, KeyID: keyID, SHA256: hex.EncodeToString(sum[:]), SizeBytes: &sizeBytes}
+
+	query := `
+		INSERT INTO scan_reports (scan_id, key_id, sha256, size_bytes, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (scan_id) DO NOTHING
+		RETURNING id, created_at
+	`
+	if err := r.db.QueryRowContext(ctx, query, scanID, keyID, archive.SHA256, sizeBytes).Scan(&archive.ID, &archive.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReportExists
+		}
+		return nil, fmt.Errorf("failed to store scan report metadata: %w", err)
+	}
+
+	if err := r.storage.Store(ctx, scanID, document); err != nil {
+		// Rollback: without a document the report can be generated again
+		if _, delErr := r.db.ExecContext(ctx, `DELETE FROM scan_reports WHERE id = $1`, archive.ID); delErr != nil {
+			return nil, fmt.Errorf("failed to store scan report in S3: %w (metadata left behind: %v)", err, delErr)
+		}
+		return nil, fmt.Errorf("failed to store scan report in S3: %w", err)
+	}
+
+	return archive, nil
+}
+
+// GetByScanID retrieves the metadata of the report of a scan
+func (r *ScanReportRepository) GetByScanID(ctx context.Context, scanID int) (*models.ScanReportArchive, error) {
+	var archive models.ScanReportArchive
+	query := `SELECT * FROM scan_reports WHERE scan_id = $1`
+	if err := r.db.GetContext(ctx, &archive, query, scanID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("scan report")
+		}
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// GetDocumentByScanID retrieves the signed report of a scan from S3. A document that doesn't match
+// the digest recorded when it was stored is rejected
+func (r *ScanReportRepository) GetDocumentByScanID(ctx context.Context, scanID int) ([]byte, error) {
+	archive, err := r.GetByScanID(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := r.storage.Retrieve(ctx, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve scan report from S3: %w", err)
+	}
+	if sum := sha256.Sum256(document); hex.EncodeToString(sum[:]) != archive.SHA256 {
+		return nil, fmt.Errorf("scan report of scan %d doesn't match its recorded digest", scanID)
+	}
+	return document, nil
+}
@@ -3,10 +3,15 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/invulnerable/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type ScanRepository struct {
@@ -17,16 +22,214 @@ func NewScanRepository(db *Database) *ScanRepository {
 	return &ScanRepository{db: db}
 }
 
-func (r *ScanRepository) Create(ctx context.Context, scan *models.Scan) error {
+// ValidateScanStatus checks a scan lifecycle status
+func ValidateScanStatus(status string) error {
+	for _, valid := range models.ValidScanStatuses {
+		if status == valid {
+			return nil
+		}
+	}
+	return validationError("invalid scan status: %s (must be one of: %v)", status, models.ValidScanStatuses)
+}
+
+// Create stores a scan. Events are written to the notification outbox in the same transaction. A
+// scan_uuid already stored is an ErrConflict once its unique index is built
+func (r *ScanRepository) Create(ctx context.Context, scan *models.Scan, events ...*models.OutboxEvent) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO scans (image_id, scan_date, syft_version, grype_version, status, sla_critical, sla_high, sla_medium, sla_low, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		INSERT INTO scans (image_id, scan_date, syft_version, grype_version, grype_db_built, grype_db_schema, status, failure_reason, sla_critical, sla_high, sla_medium, sla_low, sla_time_zone, sla_business_days, sla_holidays, digest, results_fingerprint, target, distro_name, distro_version, distro_id_like, imagescan_namespace, imagescan_name, matches_received, matches_dropped, fields_truncated, scan_duration_ms, image_size_bytes, layer_count, scan_uuid, api_key_name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, COALESCE(NULLIF($13, ''), 'UTC'), $14, COALESCE($15::date[], '{}'), $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, NOW(), NOW())
 		RETURNING id, created_at, updated_at
 	`
-	return r.db.QueryRowContext(ctx, query,
-		scan.ImageID, scan.ScanDate, scan.SyftVersion, scan.GrypeVersion, scan.Status,
-		scan.SLACritical, scan.SLAHigh, scan.SLAMedium, scan.SLALow,
-	).Scan(&scan.ID, &scan.CreatedAt, &scan.UpdatedAt)
+	if err := tx.QueryRowContext(ctx, query,
+		scan.ImageID, scan.ScanDate, scan.SyftVersion, scan.GrypeVersion, scan.GrypeDBBuilt, scan.GrypeDBSchema,
+		scan.Status, scan.FailureReason,
+		scan.SLACritical, scan.SLAHigh, scan.SLAMedium, scan.SLALow, scan.SLATimeZone,
+		scan.SLABusinessDays, scan.SLAHolidays,
+		scan.Digest, scan.ResultsFingerprint, scan.Target,
+		scan.DistroName, scan.DistroVersion, scan.DistroIDLike,
+		scan.ImageScanNamespace, scan.ImageScanName,
+		scan.MatchesReceived, scan.MatchesDropped, scan.FieldsTruncated,
+		scan.ScanDurationMS, scan.ImageSizeBytes, scan.LayerCount, scan.ScanUUID, scan.APIKeyName,
+	).Scan(&scan.ID, &scan.CreatedAt, &scan.UpdatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_scans_scan_uuid" {
+			return conflict("a scan was already submitted with this scan_uuid")
+		}
+		return err
+	}
+	if err := r.db.insertOutboxEvents(ctx, tx, &scan.ID, events); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Complete stores the results metadata of a scan registered earlier as pending or running.
+// Events are written to the notification outbox in the same transaction
+func (r *ScanRepository) Complete(ctx context.Context, scan *models.Scan, events ...*models.OutboxEvent) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE scans
+		SET syft_version = $1, grype_version = $2, grype_db_built = $3, grype_db_schema = $4,
+			status = $5, failure_reason = $6,
+			sla_critical = $7, sla_high = $8, sla_medium = $9, sla_low = $10, sla_time_zone = COALESCE(NULLIF($11, ''), 'UTC'),
+			sla_business_days = $12, sla_holidays = COALESCE($13::date[], '{}'),
+			digest = $14, results_fingerprint = $15, target = $16,
+			distro_name = $17, distro_version = $18, distro_id_like = $19,
+			imagescan_namespace = COALESCE($20, imagescan_namespace), imagescan_name = COALESCE($21, imagescan_name),
+			matches_received = $22, matches_dropped = $23, fields_truncated = $24,
+			scan_duration_ms = $25, image_size_bytes = $26, layer_count = $27, updated_at = NOW()
+		WHERE id = $28
+		RETURNING updated_at
+	`
+	if err := tx.QueryRowContext(ctx, query,
+		scan.SyftVersion, scan.GrypeVersion, scan.GrypeDBBuilt, scan.GrypeDBSchema,
+		scan.Status, scan.FailureReason,
+		scan.SLACritical, scan.SLAHigh, scan.SLAMedium, scan.SLALow, scan.SLATimeZone,
+		scan.SLABusinessDays, scan.SLAHolidays,
+		scan.Digest, scan.ResultsFingerprint, scan.Target,
+		scan.DistroName, scan.DistroVersion, scan.DistroIDLike,
+		scan.ImageScanNamespace, scan.ImageScanName,
+		scan.MatchesReceived, scan.MatchesDropped, scan.FieldsTruncated,
+		scan.ScanDurationMS, scan.ImageSizeBytes, scan.LayerCount, scan.ID,
+	).Scan(&scan.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return notFound("scan")
+		}
+		return err
+	}
+	if err := r.db.insertOutboxEvents(ctx, tx, &scan.ID, events); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetByUUID returns the scan submitted with a scan_uuid, the first one if it was stored again before
+// the unique index was built
+func (r *ScanRepository) GetByUUID(ctx context.Context, scanUUID string) (*models.Scan, error) {
+	var scan models.Scan
+	query := `SELECT * FROM scans WHERE scan_uuid = $1 ORDER BY id LIMIT 1`
+	if err := r.db.GetContext(ctx, &scan, query, scanUUID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("scan")
+		}
+		return nil, err
+	}
+	return &scan, nil
+}
+
+// FindCachedResults returns the latest completed scan of the digest and target made with the same Grype
+// database build and identical results, or nil if there is none
+func (r *ScanRepository) FindCachedResults(ctx context.Context, digest string, target *string, grypeDBBuilt time.Time, fingerprint string) (*models.Scan, error) {
+	var scan models.Scan
+	query := `
+		SELECT * FROM scans
+		WHERE digest = $1 AND target IS NOT DISTINCT FROM $2 AND grype_db_built = $3 AND results_fingerprint = $4
+			AND status = 'completed'
+		ORDER BY scan_date DESC, id DESC
+		LIMIT 1
+	`
+	if err := r.db.GetContext(ctx, &scan, query, digest, target, grypeDBBuilt, fingerprint); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &scan, nil
+}
+
+// FindUnchangedResults returns the latest completed scan FindCachedResults would reuse whose SBOM
+// was generated in the same format by the same Syft version, so it has the same contents as the
+// SBOM of a new scan of the digest, or nil if there is none
+func (r *ScanRepository) FindUnchangedResults(ctx context.Context, digest string, target *string, grypeDBBuilt time.Time, fingerprint, syftVersion, sbomFormat string) (*models.Scan, error) {
+	var scan models.Scan
+	query := `
+		SELECT s.* FROM scans s
+		JOIN sboms sb ON sb.scan_id = s.id
+		WHERE s.digest = $1 AND s.target IS NOT DISTINCT FROM $2 AND s.grype_db_built = $3 AND s.results_fingerprint = $4
+			AND s.status = 'completed' AND s.syft_version = $5 AND sb.format = $6
+		ORDER BY s.scan_date DESC, s.id DESC
+		LIMIT 1
+	`
+	if err := r.db.GetContext(ctx, &scan, query, digest, target, grypeDBBuilt, fingerprint, syftVersion, sbomFormat); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &scan, nil
+}
+
+// LinkCachedResults links the findings of the source scan to a new scan and refreshes them like
+// an upsert would (last seen time and ImageScan context), in one transaction.
+// It returns the linked vulnerabilities so callers can apply status changes
+func (r *ScanRepository) LinkCachedResults(ctx context.Context, scanID, sourceScanID int, seenAt time.Time, imageScanNamespace, imageScanName *string) ([]models.Vulnerability, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO scan_vulnerabilities (scan_id, vulnerability_id, created_at)
+		SELECT $1, vulnerability_id, NOW() FROM scan_vulnerabilities WHERE scan_id = $2
+		ON CONFLICT (scan_id, vulnerability_id) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, scanID, sourceScanID); err != nil {
+		return nil, fmt.Errorf("failed to link cached findings: %w", err)
+	}
+
+	vulns := []models.Vulnerability{}
+	query = `
+		UPDATE vulnerabilities v
+		SET last_seen_at = $2, imagescan_namespace = $3, imagescan_name = $4, updated_at = NOW()
+		FROM scan_vulnerabilities sv
+		WHERE sv.vulnerability_id = v.id AND sv.scan_id = $1
+		RETURNING v.*
+	`
+	if err := tx.SelectContext(ctx, &vulns, query, scanID, seenAt, imageScanNamespace, imageScanName); err != nil {
+		return nil, fmt.Errorf("failed to refresh cached findings: %w", err)
+	}
+	if err := r.db.decryptVulnerabilities(vulns); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE scans SET cached_from_scan_id = $1, updated_at = NOW() WHERE id = $2`, sourceScanID, scanID); err != nil {
+		return nil, fmt.Errorf("failed to record cache source: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return vulns, nil
+}
+
+// UpdateStatus moves a scan to a new lifecycle status
+func (r *ScanRepository) UpdateStatus(ctx context.Context, id int, status string, failureReason *string) error {
+	query := `UPDATE scans SET status = $1, failure_reason = $2, updated_at = NOW() WHERE id = $3`
+	result, err := r.db.ExecContext(ctx, query, status, failureReason, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound("scan")
+	}
+	return nil
 }
 
 func (r *ScanRepository) GetByID(ctx context.Context, id int) (*models.Scan, error) {
@@ -34,7 +237,7 @@ func (r *ScanRepository) GetByID(ctx context.Context, id int) (*models.Scan, err
 	query := `SELECT * FROM scans WHERE id = $1`
 	if err := r.db.GetContext(ctx, &scan, query, id); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("scan not found")
+			return nil, notFound("scan")
 		}
 		return nil, err
 	}
@@ -72,18 +275,18 @@ func (r *ScanRepository) GetWithDetails(ctx context.Context, id int, hasFix *boo
 	var scan models.ScanWithDetails
 	if err := r.db.GetContext(ctx, &scan, query, id); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("scan not found")
+			return nil, notFound("scan")
 		}
 		return nil, err
 	}
 	return &scan, nil
 }
 
-func (r *ScanRepository) Count(ctx context.Context, imageID *int, imageName *string) (int, error) {
+func (r *ScanRepository) Count(ctx context.Context, imageID *int, imageName *string, status *string, target *string) (int, error) {
 	query := `SELECT COUNT(*) FROM scans s`
 	args := []interface{}{}
 
-	if imageID != nil || imageName != nil {
+	if imageID != nil || imageName != nil || status != nil || target != nil {
 		query += ` JOIN images i ON i.id = s.image_id WHERE 1=1`
 
 		if imageID != nil {
@@ -95,6 +298,16 @@ func (r *ScanRepository) Count(ctx context.Context, imageID *int, imageName *str
 			query += ` AND (i.registry || '/' || i.repository || ':' || i.tag) ILIKE $` + fmt.Sprintf("%d", len(args)+1)
 			args = append(args, "%"+*imageName+"%")
 		}
+
+		if status != nil {
+			query += ` AND s.status = $` + fmt.Sprintf("%d", len(args)+1)
+			args = append(args, *status)
+		}
+
+		if target != nil {
+			query += ` AND s.target = $` + fmt.Sprintf("%d", len(args)+1)
+			args = append(args, *target)
+		}
 	}
 
 	var count int
@@ -104,7 +317,8 @@ func (r *ScanRepository) Count(ctx context.Context, imageID *int, imageName *str
 	return count, nil
 }
 
-func (r *ScanRepository) List(ctx context.Context, limit, offset int, imageID *int, imageName *string, hasFix *bool) ([]models.ScanWithDetails, error) {
+// List returns a page of the scans with their image and counts of findings, ordered by sort, latest first by default
+func (r *ScanRepository) List(ctx context.Context, limit, offset int, sort Sort, imageID *int, imageName *string, status *string, target *string, hasFix *bool) ([]models.ScanWithDetails, error) {
 	// Build fix filter
 	fixFilter := "1=1"
 	if hasFix != nil {
@@ -144,11 +358,29 @@ func (r *ScanRepository) List(ctx context.Context, limit, offset int, imageID *i
 		args = append(args, "%"+*imageName+"%")
 	}
 
+	if status != nil {
+		conditions = append(conditions, fmt.Sprintf("s.status = $%d", len(args)+1))
+		args = append(args, *status)
+	}
+
+	if target != nil {
+		conditions = append(conditions, fmt.Sprintf("s.target = $%d", len(args)+1))
+		args = append(args, *target)
+	}
+
 	if len(conditions) > 0 {
 		query += ` WHERE ` + strings.Join(conditions, " AND ")
 	}
 
-	query += ` GROUP BY s.id, i.registry, i.repository, i.tag, i.digest ORDER BY s.scan_date DESC LIMIT $` + fmt.Sprintf("%d", len(args)+1) + ` OFFSET $` + fmt.Sprintf("%d", len(args)+2)
+	orderBy := ` ORDER BY s.scan_date DESC`
+	if sort != (Sort{}) {
+		var err error
+		if orderBy, err = scanSort.orderBy(sort, "s.id DESC"); err != nil {
+			return nil, err
+		}
+	}
+
+	query += ` GROUP BY s.id, i.registry, i.repository, i.tag, i.digest` + orderBy + ` LIMIT $` + fmt.Sprintf("%d", len(args)+1) + ` OFFSET $` + fmt.Sprintf("%d", len(args)+2)
 	args = append(args, limit, offset)
 
 	scans := []models.ScanWithDetails{}
@@ -158,15 +390,20 @@ func (r *ScanRepository) List(ctx context.Context, limit, offset int, imageID *i
 	return scans, nil
 }
 
-func (r *ScanRepository) GetPreviousScan(ctx context.Context, imageID int, currentScanDate string) (*models.Scan, error) {
+// GetPreviousScan returns the latest scan with results of the image and target strictly before currentScanDate.
+// Scans of other targets cover other parts of the image, so comparing with them would mark findings as fixed.
+// Pending, running and failed scans have no vulnerabilities and would make every finding look new.
+// The date is passed as time.Time so the driver sends an absolute instant; formatted
+// strings lose the timezone and compare wrong across DST transitions.
+func (r *ScanRepository) GetPreviousScan(ctx context.Context, imageID int, target *string, currentScanDate time.Time) (*models.Scan, error) {
 	var scan models.Scan
 	query := `
 		SELECT * FROM scans
-		WHERE image_id = $1 AND scan_date < $2
+		WHERE image_id = $1 AND target IS NOT DISTINCT FROM $2 AND scan_date < $3 AND status IN ('completed', 'partial')
 		ORDER BY scan_date DESC
 		LIMIT 1
 	`
-	if err := r.db.GetContext(ctx, &scan, query, imageID, currentScanDate); err != nil {
+	if err := r.db.GetContext(ctx, &scan, query, imageID, target, currentScanDate); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No previous scan
 		}
@@ -175,6 +412,302 @@ func (r *ScanRepository) GetPreviousScan(ctx context.Context, imageID int, curre
 	return &scan, nil
 }
 
+// GetScannerVersionStats groups scans with results by tool versions and Grype database build day
+func (r *ScanRepository) GetScannerVersionStats(ctx context.Context) ([]models.ScannerVersionStats, error) {
+	query := `
+		WITH latest AS (
+			SELECT DISTINCT ON (image_id) id
+			FROM scans
+			WHERE status IN ('completed', 'partial')
+			ORDER BY image_id, scan_date DESC
+		)
+		SELECT
+			s.syft_version,
+			s.grype_version,
+			date_trunc('day', s.grype_db_built) as grype_db_built,
+			COUNT(*) as scan_count,
+			COUNT(DISTINCT s.image_id) as image_count,
+			COUNT(l.id) as latest_for_images,
+			MIN(s.scan_date) as first_scan_date,
+			MAX(s.scan_date) as last_scan_date
+		FROM scans s
+		LEFT JOIN latest l ON l.id = s.id
+		WHERE s.status IN ('completed', 'partial')
+		GROUP BY s.syft_version, s.grype_version, date_trunc('day', s.grype_db_built)
+		ORDER BY last_scan_date DESC
+	`
+	stats := []models.ScannerVersionStats{}
+	if err := r.db.SelectContext(ctx, &stats, query); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ListLatestScans returns the most recent scan with results of every image
+func (r *ScanRepository) ListLatestScans(ctx context.Context) ([]models.ScanWithDetails, error) {
+	query := `
+		SELECT DISTINCT ON (s.image_id)
+			s.*,
+			i.registry || '/' || i.repository || ':' || i.tag as image_name,
+			i.digest as image_digest,
+			0 as vulnerability_count, 0 as critical_count, 0 as high_count, 0 as medium_count, 0 as low_count
+		FROM scans s
+		JOIN images i ON i.id = s.image_id
+		WHERE s.status IN ('completed', 'partial')
+		ORDER BY s.image_id, s.scan_date DESC
+	`
+	scans := []models.ScanWithDetails{}
+	if err := r.db.SelectContext(ctx, &scans, query); err != nil {
+		return nil, err
+	}
+	return scans, nil
+}
+
+// MarkForRescan flags scans whose results should be refreshed
+func (r *ScanRepository) MarkForRescan(ctx context.Context, scanIDs []int) error {
+	if len(scanIDs) == 0 {
+		return nil
+	}
+	query := `UPDATE scans SET needs_rescan = true, updated_at = NOW() WHERE id = ANY($1) AND needs_rescan = false`
+	_, err := r.db.ExecContext(ctx, query, pq.Array(scanIDs))
+	return err
+}
+
+// Delete removes a scan and records the deletion in the audit log in one transaction. Scan links, SBOM
+// metadata and the archived Grype result are removed by the foreign key cascades; the SBOM documents
+// no other scan shares are returned so the caller can remove them from S3. With purgeOrphanVulns, the
+// vulnerabilities no other scan found are removed too and their number is returned
+func (r *ScanRepository) Delete(ctx context.Context, scanID int, purgeOrphanVulns bool, actor string) ([]int, int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var scan struct {
+		ImageID   int    `db:"image_id"`
+		ImageName string `db:"image_name"`
+		Status    string `db:"status"`
+	}
+	query := `
+		SELECT s.image_id, s.status,
+			CASE WHEN i.registry != '' THEN i.registry || '/' ELSE '' END || i.repository || ':' || i.tag as image_name
+		FROM scans s
+		JOIN images i ON i.id = s.image_id
+		WHERE s.id = $1
+		FOR UPDATE OF s
+	`
+	if err := tx.GetContext(ctx, &scan, query, scanID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, 0, notFound("scan")
+		}
+		return nil, 0, fmt.Errorf("failed to get scan: %w", err)
+	}
+
+	sbomScanIDs := []int{}
+	if err := tx.SelectContext(ctx, &sbomScanIDs,
+		`SELECT DISTINCT COALESCE(document_scan_id, scan_id) FROM sboms WHERE scan_id = $1 ORDER BY 1`, scanID); err != nil {
+		return nil, 0, fmt.Errorf("failed to list SBOMs: %w", err)
+	}
+
+	var vulnIDs []int
+	if purgeOrphanVulns {
+		if err := tx.SelectContext(ctx, &vulnIDs,
+			`SELECT vulnerability_id FROM scan_vulnerabilities WHERE scan_id = $1`, scanID); err != nil {
+			return nil, 0, fmt.Errorf("failed to list vulnerabilities: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scans WHERE id = $1`, scanID); err != nil {
+		return nil, 0, fmt.Errorf("failed to delete scan: %w", err)
+	}
+	// Later scans of the digest may share the document
+	if sbomScanIDs, err = unreferencedDocuments(ctx, tx, sbomScanIDs); err != nil {
+		return nil, 0, err
+	}
+	purged, err := purgeOrphanVulnerabilities(ctx, tx, vulnIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	details, err := json.Marshal(map[string]interface{}{
+		"image_id":               scan.ImageID,
+		"status":                 scan.Status,
+		"vulnerabilities_purged": purged,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to encode audit details: %w", err)
+	}
+	entry := &models.AuditEntry{
+		Action:       models.AuditActionScanDeleted,
+		ResourceType: "scan",
+		ResourceID:   &scanID,
+		ResourceName: &scan.ImageName,
+		Actor:        actor,
+		Details:      details,
+	}
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return nil, 0, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return sbomScanIDs, purged, nil
+}
+
+// purgeOrphanVulnerabilities removes the vulnerabilities among ids that no scan links anymore, once the
+// deleted scans are gone in the transaction, with their history
+func purgeOrphanVulnerabilities(ctx context.Context, tx *sqlx.Tx, ids []int) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	query := `
+		DELETE FROM vulnerabilities v
+		WHERE v.id = ANY($1)
+			AND NOT EXISTS (SELECT 1 FROM scan_vulnerabilities sv WHERE sv.vulnerability_id = v.id)
+	`
+	result, err := tx.ExecContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge orphan vulnerabilities: %w", err)
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(purged), nil
+}
+
+// Prune deletes the finished scans of an image that fall outside its retention policy, records
+// the deletion in the audit log and returns the pruned scan IDs and the SBOM documents to delete.
+// Scans of each target are counted separately, and the latest scan and the latest successful scan of each
+// target are always kept, so the image never loses its current results
+func (r *ScanRepository) Prune(ctx context.Context, policy *models.RetentionPolicy, now time.Time, actor string) ([]int, []int, error) {
+	var cutoff *time.Time
+	if policy.MaxScanAgeSeconds != nil {
+		t := now.Add(-time.Duration(*policy.MaxScanAgeSeconds) * time.Second)
+		cutoff = &t
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	scanIDs := []int{}
+	query := `
+		WITH ranked AS (
+			SELECT id, status, scan_date,
+				ROW_NUMBER() OVER (PARTITION BY target ORDER BY scan_date DESC, id DESC) as position
+			FROM scans
+			WHERE image_id = $1
+		),
+		last_success AS (
+			SELECT DISTINCT ON (target) id FROM scans
+			WHERE image_id = $1 AND status IN ('completed', 'partial')
+			ORDER BY target, scan_date DESC, id DESC
+		)
+		SELECT id FROM ranked
+		WHERE position > 1
+			AND status IN ('completed', 'partial', 'failed')
+			AND id NOT IN (SELECT id FROM last_success)
+			AND (position > $2::int OR scan_date < $3::timestamptz)
+		ORDER BY id
+	`
+	if err := tx.SelectContext(ctx, &scanIDs, query, policy.ImageID, policy.MaxScansRetained, cutoff); err != nil {
+		return nil, nil, fmt.Errorf("failed to list scans to prune: %w", err)
+	}
+	if len(scanIDs) == 0 {
+		return scanIDs, []int{}, nil
+	}
+
+	sbomScanIDs := []int{}
+	if err := tx.SelectContext(ctx, &sbomScanIDs,
+		`SELECT DISTINCT COALESCE(document_scan_id, scan_id) FROM sboms WHERE scan_id = ANY($1) ORDER BY 1`, pq.Array(scanIDs)); err != nil {
+		return nil, nil, fmt.Errorf("failed to list SBOMs: %w", err)
+	}
+
+	// Vulnerability links and SBOM metadata cascade with the scans
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scans WHERE id = ANY($1)`, pq.Array(scanIDs)); err != nil {
+		return nil, nil, fmt.Errorf("failed to delete scans: %w", err)
+	}
+	// Documents shared with the scans that are kept stay
+	if sbomScanIDs, err = unreferencedDocuments(ctx, tx, sbomScanIDs); err != nil {
+		return nil, nil, err
+	}
+
+	details, err := json.Marshal(map[string]interface{}{
+		"scan_ids":             scanIDs,
+		"max_scans_retained":   policy.MaxScansRetained,
+		"max_scan_age_seconds": policy.MaxScanAgeSeconds,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode audit details: %w", err)
+	}
+	entry := &models.AuditEntry{
+		Action:       models.AuditActionScansPruned,
+		ResourceType: "image",
+		ResourceID:   &policy.ImageID,
+		ResourceName: &policy.ImageName,
+		Actor:        actor,
+		Details:      details,
+	}
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return nil, nil, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return scanIDs, sbomScanIDs, nil
+}
+
+// GetSummary computes the severity distribution of a scan by package type and fix availability
+func (r *ScanRepository) GetSummary(ctx context.Context, scanID int) (*models.ScanSummary, error) {
+	query := `
+		SELECT
+			COALESCE(v.package_type, 'unknown') as package_type,
+			v.severity,
+			v.fix_version IS NOT NULL as has_fix,
+			COUNT(*) as count
+		FROM scan_vulnerabilities sv
+		JOIN vulnerabilities v ON v.id = sv.vulnerability_id
+		WHERE sv.scan_id = $1
+		GROUP BY 1, 2, 3
+		ORDER BY 1
+	`
+	var rows []struct {
+		PackageType string `db:"package_type"`
+		Severity    string `db:"severity"`
+		HasFix      bool   `db:"has_fix"`
+		Count       int    `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, scanID); err != nil {
+		return nil, err
+	}
+
+	summary := &models.ScanSummary{ScanID: scanID, ByPackageType: []models.PackageTypeSummary{}}
+	for _, row := range rows {
+		summary.BySeverity.Add(row.Severity, row.Count)
+		if row.HasFix {
+			summary.ByFixAvailability.Fixable.Add(row.Severity, row.Count)
+		} else {
+			summary.ByFixAvailability.Unfixable.Add(row.Severity, row.Count)
+		}
+
+		// Rows are ordered by package type, so each type is contiguous
+		last := len(summary.ByPackageType) - 1
+		if last < 0 || summary.ByPackageType[last].PackageType != row.PackageType {
+			summary.ByPackageType = append(summary.ByPackageType, models.PackageTypeSummary{PackageType: row.PackageType})
+			last++
+		}
+		summary.ByPackageType[last].Add(row.Severity, row.Count)
+	}
+
+	return summary, nil
+}
+
 func (r *ScanRepository) GetVulnerabilities(ctx context.Context, scanID int) ([]models.Vulnerability, error) {
 	query := `
 		SELECT
@@ -183,10 +716,14 @@ func (r *ScanRepository) GetVulnerabilities(ctx context.Context, scanID int) ([]
 			v.package_name,
 			v.package_version,
 			v.package_type,
+			v.purl,
 			v.severity,
 			v.fix_version,
+			v.fix_became_available_at,
 			v.url,
 			v.description,
+			v.advisories,
+			v.known_exploited,
 			v.status,
 			-- Calculate first detection for this specific image
 			(
@@ -218,5 +755,8 @@ func (r *ScanRepository) GetVulnerabilities(ctx context.Context, scanID int) ([]
 	if err := r.db.SelectContext(ctx, &vulns, query, scanID); err != nil {
 		return nil, err
 	}
+	if err := r.db.decryptVulnerabilities(vulns); err != nil {
+		return nil, err
+	}
 	return vulns, nil
 }
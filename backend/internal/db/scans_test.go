@@ -48,6 +48,31 @@ func TestScanRepository_Create(t *testing.T) {
 	assert.NotZero(t, scan.UpdatedAt)
 }
 
+func TestScanRepository_GetByUUID(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(context.Background(), image))
+
+	scanUUID := "5f0c9a52-3b7e-4d6a-9a41-6f2f1f8b2c11"
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: "completed", ScanUUID: &scanUUID}
+	require.NoError(t, repo.Create(context.Background(), scan))
+	require.NoError(t, repo.Create(context.Background(), &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: "completed"}))
+
+	found, err := repo.GetByUUID(context.Background(), scanUUID)
+	require.NoError(t, err)
+	assert.Equal(t, scan.ID, found.ID)
+	require.NotNil(t, found.ScanUUID)
+	assert.Equal(t, scanUUID, *found.ScanUUID)
+
+	_, err = repo.GetByUUID(context.Background(), "0e7d4c1a-8f2b-4b3e-9c5d-2a6f7e8b9c10")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
 func TestScanRepository_GetWithDetails(t *testing.T) {
 	db := SetupTestDatabase(t)
 	defer db.Close()
@@ -155,7 +180,7 @@ func TestScanRepository_List(t *testing.T) {
 	}
 
 	// List scans
-	scans, err := repo.List(context.Background(), 10, 0, nil, nil, nil)
+	scans, err := repo.List(context.Background(), 10, 0, Sort{}, nil, nil, nil, nil, nil)
 	require.NoError(t, err)
 	assert.Len(t, scans, 2)
 }
@@ -199,7 +224,7 @@ func TestScanRepository_List_FilterByImage(t *testing.T) {
 	}
 
 	// Filter by image1
-	scans, err := repo.List(context.Background(), 10, 0, &image1.ID, nil, nil)
+	scans, err := repo.List(context.Background(), 10, 0, Sort{}, &image1.ID, nil, nil, nil, nil)
 	require.NoError(t, err)
 	assert.Len(t, scans, 1)
 	assert.Equal(t, image1.ID, scans[0].ImageID)
@@ -297,8 +322,426 @@ func TestScanRepository_GetPreviousScan(t *testing.T) {
 	err = repo.Create(context.Background(), scan2)
 	require.NoError(t, err)
 
-	// Get previous scan (using RFC3339 format with timezone)
-	previous, err := repo.GetPreviousScan(context.Background(), image.ID, scan2.ScanDate.Format(time.RFC3339))
+	// Get previous scan
+	previous, err := repo.GetPreviousScan(context.Background(), image.ID, nil, scan2.ScanDate)
 	require.NoError(t, err)
 	assert.Equal(t, scan1.ID, previous.ID)
 }
+
+func TestScanRepository_GetPreviousScan_AcrossDST(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		tag     string
+		earlier time.Time
+		later   time.Time
+	}{
+		{
+			// Clocks jump from 02:00 EST to 03:00 EDT: 03:15 local is after 01:30 local
+			name:    "spring forward",
+			tag:     "spring",
+			earlier: time.Date(2024, 3, 10, 1, 30, 0, 0, newYork),
+			later:   time.Date(2024, 3, 10, 3, 15, 0, 0, newYork),
+		},
+		{
+			// Clocks fall back from 02:00 EDT to 01:00 EST: 01:10 EST is after 01:30 EDT
+			// even though its wall clock reads earlier
+			name:    "fall back",
+			tag:     "fall",
+			earlier: time.Date(2024, 11, 3, 1, 30, 0, 0, newYork),
+			later:   time.Date(2024, 11, 3, 1, 30, 0, 0, newYork).Add(40 * time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := &models.Image{
+				Registry:   "docker.io",
+				Repository: "library/nginx",
+				Tag:        tt.tag,
+			}
+			require.NoError(t, imageRepo.Create(context.Background(), image))
+
+			first := &models.Scan{ImageID: image.ID, ScanDate: tt.earlier, Status: "completed", SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180}
+			require.NoError(t, repo.Create(context.Background(), first))
+			second := &models.Scan{ImageID: image.ID, ScanDate: tt.later, Status: "completed", SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180}
+			require.NoError(t, repo.Create(context.Background(), second))
+
+			previous, err := repo.GetPreviousScan(context.Background(), image.ID, nil, tt.later)
+			require.NoError(t, err)
+			require.NotNil(t, previous)
+			assert.Equal(t, first.ID, previous.ID)
+
+			// Nothing precedes the first scan
+			previous, err = repo.GetPreviousScan(context.Background(), image.ID, nil, tt.earlier)
+			require.NoError(t, err)
+			assert.Nil(t, previous)
+
+			// Scan dates read back from the database are used as-is
+			stored, err := repo.GetByID(context.Background(), second.ID)
+			require.NoError(t, err)
+			previous, err = repo.GetPreviousScan(context.Background(), image.ID, nil, stored.ScanDate)
+			require.NoError(t, err)
+			require.NotNil(t, previous)
+			assert.Equal(t, first.ID, previous.ID)
+		})
+	}
+}
+
+func TestScanRepository_GetPreviousScan_SkipsScansWithoutResults(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(context.Background(), image))
+
+	now := time.Now()
+	completed := &models.Scan{ImageID: image.ID, ScanDate: now.Add(-3 * time.Hour), Status: models.ScanStatusCompleted, SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180}
+	require.NoError(t, repo.Create(context.Background(), completed))
+
+	reason := "grype database download failed"
+	failed := &models.Scan{ImageID: image.ID, ScanDate: now.Add(-2 * time.Hour), Status: models.ScanStatusRunning, SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180}
+	require.NoError(t, repo.Create(context.Background(), failed))
+	require.NoError(t, repo.UpdateStatus(context.Background(), failed.ID, models.ScanStatusFailed, &reason))
+
+	stored, err := repo.GetByID(context.Background(), failed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScanStatusFailed, stored.Status)
+	assert.Equal(t, reason, *stored.FailureReason)
+
+	// The failed scan has no vulnerabilities and must not serve as diff baseline
+	previous, err := repo.GetPreviousScan(context.Background(), image.ID, nil, now)
+	require.NoError(t, err)
+	require.NotNil(t, previous)
+	assert.Equal(t, completed.ID, previous.ID)
+
+	status := models.ScanStatusFailed
+	count, err := repo.Count(context.Background(), nil, nil, &status, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestScanRepository_GetPreviousScan_PerTarget(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "acme/platform", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	now := time.Now()
+	api, worker := "/srv/api", "/srv/worker"
+	whole := &models.Scan{ImageID: image.ID, ScanDate: now.Add(-3 * time.Hour), Status: models.ScanStatusCompleted}
+	apiScan := &models.Scan{ImageID: image.ID, ScanDate: now.Add(-2 * time.Hour), Status: models.ScanStatusCompleted, Target: &api}
+	workerScan := &models.Scan{ImageID: image.ID, ScanDate: now.Add(-time.Hour), Status: models.ScanStatusCompleted, Target: &worker}
+	for _, scan := range []*models.Scan{whole, apiScan, workerScan} {
+		require.NoError(t, repo.Create(ctx, scan))
+	}
+
+	// Each target is compared with its own history only
+	previous, err := repo.GetPreviousScan(ctx, image.ID, &api, now)
+	require.NoError(t, err)
+	require.NotNil(t, previous)
+	assert.Equal(t, apiScan.ID, previous.ID)
+
+	previous, err = repo.GetPreviousScan(ctx, image.ID, nil, now)
+	require.NoError(t, err)
+	require.NotNil(t, previous)
+	assert.Equal(t, whole.ID, previous.ID)
+
+	count, err := repo.Count(ctx, &image.ID, nil, nil, &worker)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestScanRepository_GetSummary(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	ctx := context.Background()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	scan := &models.Scan{ImageID: image.ID, Status: "completed", SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180}
+	require.NoError(t, repo.Create(ctx, scan))
+
+	deb, npm := "deb", "npm"
+	fix := "3.0.13"
+	vulns := []*models.Vulnerability{
+		{CVEID: "CVE-2023-0001", PackageName: "openssl", PackageVersion: "3.0.11", PackageType: &deb, Severity: "Critical", FixVersion: &fix},
+		{CVEID: "CVE-2023-0002", PackageName: "openssl", PackageVersion: "3.0.11", PackageType: &deb, Severity: "High"},
+		{CVEID: "CVE-2023-0003", PackageName: "lodash", PackageVersion: "4.17.15", PackageType: &npm, Severity: "High", FixVersion: &fix},
+		{CVEID: "CVE-2023-0004", PackageName: "mystery", PackageVersion: "1.0", Severity: "Negligible"},
+	}
+	for _, vuln := range vulns {
+		vuln.Status = "active"
+		vuln.FirstDetectedAt = time.Now()
+		vuln.LastSeenAt = time.Now()
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+	}
+
+	summary, err := repo.GetSummary(ctx, scan.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, scan.ID, summary.ScanID)
+	assert.Equal(t, models.SeverityCounts{Critical: 1, High: 2, Negligible: 1, Total: 4}, summary.BySeverity)
+	assert.Equal(t, models.SeverityCounts{Critical: 1, High: 1, Total: 2}, summary.ByFixAvailability.Fixable)
+	assert.Equal(t, models.SeverityCounts{High: 1, Negligible: 1, Total: 2}, summary.ByFixAvailability.Unfixable)
+
+	require.Len(t, summary.ByPackageType, 3)
+	assert.Equal(t, "deb", summary.ByPackageType[0].PackageType)
+	assert.Equal(t, 2, summary.ByPackageType[0].Total)
+	assert.Equal(t, "npm", summary.ByPackageType[1].PackageType)
+	assert.Equal(t, "unknown", summary.ByPackageType[2].PackageType)
+	assert.Equal(t, 1, summary.ByPackageType[2].Negligible)
+}
+
+func TestScanRepository_Prune(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	repo := NewScanRepository(db)
+	sbomRepo := NewSBOMRepository(db, &noopSBOMStorage{})
+	now := time.Now()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	scans := []*models.Scan{
+		{ImageID: image.ID, ScanDate: now.Add(-5 * 24 * time.Hour), Status: models.ScanStatusCompleted},
+		{ImageID: image.ID, ScanDate: now.Add(-4 * 24 * time.Hour), Status: models.ScanStatusCompleted},
+		{ImageID: image.ID, ScanDate: now.Add(-3 * 24 * time.Hour), Status: models.ScanStatusFailed},
+		{ImageID: image.ID, ScanDate: now.Add(-2 * 24 * time.Hour), Status: models.ScanStatusCompleted},
+		{ImageID: image.ID, ScanDate: now.Add(-time.Hour), Status: models.ScanStatusFailed},
+	}
+	for _, scan := range scans {
+		require.NoError(t, repo.Create(ctx, scan))
+	}
+	require.NoError(t, sbomRepo.Create(ctx, &models.SBOM{ScanID: scans[0].ID, Format: "cyclonedx"}, []byte("{}")))
+
+	// The age limit alone only removes the scans older than the cutoff
+	maxAge := int((84 * time.Hour).Seconds())
+	policy := &models.RetentionPolicy{ImageID: image.ID, ImageName: image.FullName(), MaxScanAgeSeconds: &maxAge}
+	pruned, sbomScanIDs, err := repo.Prune(ctx, policy, now, "system:retention")
+	require.NoError(t, err)
+	assert.Equal(t, []int{scans[0].ID, scans[1].ID}, pruned)
+	assert.Equal(t, []int{scans[0].ID}, sbomScanIDs)
+
+	// Keeping a single scan still keeps the latest successful one behind the failed latest scan
+	keep := 1
+	policy = &models.RetentionPolicy{ImageID: image.ID, ImageName: image.FullName(), MaxScansRetained: &keep}
+	pruned, sbomScanIDs, err = repo.Prune(ctx, policy, now, "system:retention")
+	require.NoError(t, err)
+	assert.Equal(t, []int{scans[2].ID}, pruned)
+	assert.Empty(t, sbomScanIDs)
+
+	for _, scan := range scans[3:] {
+		_, err := repo.GetByID(ctx, scan.ID)
+		assert.NoError(t, err)
+	}
+
+	// Nothing left to prune
+	pruned, _, err = repo.Prune(ctx, policy, now, "system:retention")
+	require.NoError(t, err)
+	assert.Empty(t, pruned)
+}
+
+func TestScanRepository_PruneKeepsSharedSBOMs(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	repo := NewScanRepository(db)
+	sbomRepo := NewSBOMRepository(db, &noopSBOMStorage{})
+	now := time.Now()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	scans := []*models.Scan{
+		{ImageID: image.ID, ScanDate: now.Add(-5 * 24 * time.Hour), Status: models.ScanStatusCompleted},
+		{ImageID: image.ID, ScanDate: now.Add(-4 * 24 * time.Hour), Status: models.ScanStatusCompleted},
+		{ImageID: image.ID, ScanDate: now.Add(-time.Hour), Status: models.ScanStatusCompleted},
+	}
+	for _, scan := range scans {
+		require.NoError(t, repo.Create(ctx, scan))
+	}
+	require.NoError(t, sbomRepo.Create(ctx, &models.SBOM{ScanID: scans[0].ID, Format: "cyclonedx"}, []byte("{}")))
+	require.NoError(t, sbomRepo.IndexComponents(ctx, scans[0].ID, []byte(`{"bomFormat":"CycloneDX","components":[{"name":"openssl","version":"3.0.11"}]}`)))
+
+	// Shares resolve to the scan that stored the document, even from a share
+	_, err := sbomRepo.Share(ctx, scans[1].ID, scans[0].ID)
+	require.NoError(t, err)
+	shared, err := sbomRepo.Share(ctx, scans[2].ID, scans[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, scans[0].ID, shared.StorageScanID())
+	require.NotNil(t, shared.ComponentCount)
+	assert.Equal(t, 1, *shared.ComponentCount)
+	openssl := "openssl"
+	components, err := sbomRepo.FindComponents(ctx, []int{scans[2].ID}, &openssl, nil)
+	require.NoError(t, err)
+	assert.Len(t, components, 1)
+
+	// The document outlives the scan that stored it while the latest scan shares it
+	maxAge := int((84 * time.Hour).Seconds())
+	policy := &models.RetentionPolicy{ImageID: image.ID, ImageName: image.FullName(), MaxScanAgeSeconds: &maxAge}
+	pruned, documents, err := repo.Prune(ctx, policy, now, "system:retention")
+	require.NoError(t, err)
+	assert.Equal(t, []int{scans[0].ID, scans[1].ID}, pruned)
+	assert.Empty(t, documents)
+
+	name := image.FullName()
+	documents, _, err = imageRepo.Delete(ctx, image.ID, false, &models.AuditEntry{
+		Action: models.AuditActionImageDeleted, ResourceType: "image", ResourceID: &image.ID, ResourceName: &name, Actor: "admin@example.com",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{scans[0].ID}, documents, "deleted once nothing shares it")
+}
+
+func TestScanRepository_Delete(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	repo := NewScanRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	sbomRepo := NewSBOMRepository(db, &noopSBOMStorage{})
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scans := []*models.Scan{
+		{ImageID: image.ID, ScanDate: time.Now().Add(-time.Hour), Status: models.ScanStatusCompleted},
+		{ImageID: image.ID, ScanDate: time.Now(), Status: models.ScanStatusCompleted},
+	}
+	for _, scan := range scans {
+		require.NoError(t, repo.Create(ctx, scan))
+	}
+	require.NoError(t, sbomRepo.Create(ctx, &models.SBOM{ScanID: scans[1].ID, Format: "cyclonedx"}, []byte("{}")))
+
+	// The first vulnerability is found by both scans, the second by the deleted scan only
+	var vulns []*models.Vulnerability
+	for _, cve := range []string{"CVE-2024-0001", "CVE-2024-0002"} {
+		vuln := &models.Vulnerability{
+			CVEID: cve, PackageName: "openssl", PackageVersion: "3.0.0", Severity: "High", Status: models.StatusActive,
+			FirstDetectedAt: time.Now(), LastSeenAt: time.Now(),
+		}
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		require.NoError(t, vulnRepo.LinkToScan(ctx, scans[1].ID, vuln.ID))
+		vulns = append(vulns, vuln)
+	}
+	require.NoError(t, vulnRepo.LinkToScan(ctx, scans[0].ID, vulns[0].ID))
+
+	documents, purged, err := repo.Delete(ctx, scans[1].ID, true, "admin@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []int{scans[1].ID}, documents)
+	assert.Equal(t, 1, purged)
+
+	_, err = repo.GetByID(ctx, scans[1].ID)
+	assert.Error(t, err)
+	_, err = sbomRepo.GetByScanID(ctx, scans[1].ID)
+	assert.Error(t, err)
+	kept, err := vulnRepo.GetByUniqueKey(ctx, "CVE-2024-0001", "openssl", "3.0.0")
+	require.NoError(t, err)
+	assert.NotNil(t, kept, "still found by the other scan")
+	orphan, err := vulnRepo.GetByUniqueKey(ctx, "CVE-2024-0002", "openssl", "3.0.0")
+	require.NoError(t, err)
+	assert.Nil(t, orphan)
+
+	// Without purging, vulnerabilities outlive the scans that found them
+	documents, purged, err = repo.Delete(ctx, scans[0].ID, false, "admin@example.com")
+	require.NoError(t, err)
+	assert.Empty(t, documents)
+	assert.Zero(t, purged)
+	kept, err = vulnRepo.GetByUniqueKey(ctx, "CVE-2024-0001", "openssl", "3.0.0")
+	require.NoError(t, err)
+	assert.NotNil(t, kept)
+
+	_, _, err = repo.Delete(ctx, scans[0].ID, false, "admin@example.com")
+	assert.Error(t, err)
+}
+
+func TestScanRepository_CachedResults(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	ctx := context.Background()
+
+	digest := "sha256:abc123"
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest", Digest: &digest}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	built := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	fingerprint := "f00d"
+	source := &models.Scan{ImageID: image.ID, ScanDate: time.Now().Add(-time.Hour), Status: models.ScanStatusCompleted,
+		Digest: &digest, GrypeDBBuilt: &built, ResultsFingerprint: &fingerprint}
+	require.NoError(t, repo.Create(ctx, source))
+
+	vuln := &models.Vulnerability{
+		CVEID: "CVE-2023-0001", PackageName: "openssl", PackageVersion: "3.0.11", Severity: "High",
+		Status: "active", FirstDetectedAt: time.Now(), LastSeenAt: time.Now().Add(-time.Hour),
+	}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	require.NoError(t, vulnRepo.LinkToScan(ctx, source.ID, vuln.ID))
+
+	// Another Grype database build or other results never hit the cache
+	cached, err := repo.FindCachedResults(ctx, digest, nil, built.Add(24*time.Hour), fingerprint)
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+	cached, err = repo.FindCachedResults(ctx, digest, nil, built, "beef")
+	require.NoError(t, err)
+	assert.Nil(t, cached)
+
+	cached, err = repo.FindCachedResults(ctx, digest, nil, built, fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	assert.Equal(t, source.ID, cached.ID)
+
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: models.ScanStatusCompleted,
+		Digest: &digest, GrypeDBBuilt: &built, ResultsFingerprint: &fingerprint}
+	require.NoError(t, repo.Create(ctx, scan))
+
+	namespace, name := "default", "nginx"
+	seenAt := time.Now()
+	linked, err := repo.LinkCachedResults(ctx, scan.ID, source.ID, seenAt, &namespace, &name)
+	require.NoError(t, err)
+	require.Len(t, linked, 1)
+	assert.Equal(t, vuln.ID, linked[0].ID)
+	assert.WithinDuration(t, seenAt, linked[0].LastSeenAt, time.Second)
+	require.NotNil(t, linked[0].ImageScanName)
+	assert.Equal(t, name, *linked[0].ImageScanName)
+
+	vulns, err := repo.GetVulnerabilities(ctx, scan.ID)
+	require.NoError(t, err)
+	assert.Len(t, vulns, 1)
+
+	stored, err := repo.GetByID(ctx, scan.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.CachedFromScanID)
+	assert.Equal(t, source.ID, *stored.CachedFromScanID)
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/sla"
+	"github.com/lib/pq"
+)
+
+// SLABreachRepository finds the open vulnerabilities past their SLA deadline and records them once
+type SLABreachRepository struct {
+	db *Database
+}
+
+// NewSLABreachRepository creates a new SLA breach repository
+func NewSLABreachRepository(db *Database) *SLABreachRepository {
+	return &SLABreachRepository{db: db}
+}
+
+// ListNew returns the open vulnerabilities past their deadline at now that weren't recorded yet.
+// The SLA of a vulnerability is the one of the latest scan that found it, like the namespace summaries
+func (r *SLABreachRepository) ListNew(ctx context.Context, now time.Time) ([]models.SLABreach, error) {
+	deadlines, err := r.listDeadlines(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	breaches := []models.SLABreach{}
+	for _, d := range deadlines {
+		if !now.Before(d.DueAt) {
+			breaches = append(breaches, d)
+		}
+	}
+	return breaches, nil
+}
+
+// ListDeadlines returns every open vulnerability with its SLA deadline, breached or not
+func (r *SLABreachRepository) ListDeadlines(ctx context.Context) ([]models.SLABreach, error) {
+	return r.listDeadlines(ctx, false)
+}
+
+// listDeadlines returns the open vulnerabilities with their deadline, leaving out those whose breach
+// is recorded with unrecorded
+func (r *SLABreachRepository) listDeadlines(ctx context.Context, unrecorded bool) ([]models.SLABreach, error) {
+	query := `
+		SELECT
+			v.id, v.cve_id, v.package_name, v.package_version, v.severity, v.initial_severity, v.status,
+			v.fix_version, v.first_detected_at, v.imagescan_namespace, v.imagescan_name,
+			s.id AS scan_id, s.image_id, i.registry || '/' || i.repository || ':' || i.tag AS image_name,
+			s.sla_critical, s.sla_high, s.sla_medium, s.sla_low, s.sla_time_zone, s.sla_business_days, s.sla_holidays
+		FROM vulnerabilities v
+		JOIN LATERAL (
+			SELECT s.id, s.image_id, s.sla_critical, s.sla_high, s.sla_medium, s.sla_low, s.sla_time_zone,
+				s.sla_business_days, s.sla_holidays
+			FROM scan_vulnerabilities sv
+			JOIN scans s ON s.id = sv.scan_id
+			WHERE sv.vulnerability_id = v.id
+			ORDER BY s.scan_date DESC
+			LIMIT 1
+		) s ON TRUE
+		JOIN images i ON i.id = s.image_id
+		WHERE v.status IN ('active', 'in_progress')
+			AND (NOT $1 OR NOT EXISTS (SELECT 1 FROM sla_breaches b WHERE b.vulnerability_id = v.id))
+	`
+	var findings []struct {
+		ID                 int            `db:"id"`
+		CVEID              string         `db:"cve_id"`
+		PackageName        string         `db:"package_name"`
+		PackageVersion     string         `db:"package_version"`
+		Severity           string         `db:"severity"`
+		InitialSeverity    string         `db:"initial_severity"`
+		Status             string         `db:"status"`
+		FixVersion         *string        `db:"fix_version"`
+		FirstDetectedAt    time.Time      `db:"first_detected_at"`
+		ImageScanNamespace *string        `db:"imagescan_namespace"`
+		ImageScanName      *string        `db:"imagescan_name"`
+		ScanID             int            `db:"scan_id"`
+		ImageID            int            `db:"image_id"`
+		ImageName          string         `db:"image_name"`
+		SLACritical        int            `db:"sla_critical"`
+		SLAHigh            int            `db:"sla_high"`
+		SLAMedium          int            `db:"sla_medium"`
+		SLALow             int            `db:"sla_low"`
+		SLATimeZone        string         `db:"sla_time_zone"`
+		SLABusinessDays    bool           `db:"sla_business_days"`
+		SLAHolidays        pq.StringArray `db:"sla_holidays"`
+	}
+	if err := r.db.SelectContext(ctx, &findings, query, unrecorded); err != nil {
+		return nil, err
+	}
+
+	deadlines := []models.SLABreach{}
+	for _, f := range findings {
+		loc, err := sla.LoadLocation(f.SLATimeZone)
+		if err != nil {
+			// Timezones are validated when scans are stored, this is a zone removed from tzdata since
+			loc = time.UTC
+		}
+		// Holidays are validated when scans are stored and read back from a DATE[] column
+		calendar, _ := sla.NewCalendar(f.SLABusinessDays, f.SLAHolidays)
+		severity := sla.Severity(r.db.slaSeverity, f.InitialSeverity, f.Severity)
+		days := sla.Days(severity, f.SLACritical, f.SLAHigh, f.SLAMedium, f.SLALow)
+		deadline := calendar.DeadlineFor(f.FirstDetectedAt, days, loc)
+		deadlines = append(deadlines, models.SLABreach{
+			VulnerabilityID:    f.ID,
+			CVEID:              f.CVEID,
+			PackageName:        f.PackageName,
+			PackageVersion:     f.PackageVersion,
+			Severity:           f.Severity,
+			SLASeverity:        severity,
+			Status:             f.Status,
+			FixVersion:         f.FixVersion,
+			ImageID:            f.ImageID,
+			ImageName:          f.ImageName,
+			ImageScanNamespace: f.ImageScanNamespace,
+			ImageScanName:      f.ImageScanName,
+			ScanID:             f.ScanID,
+			FirstDetectedAt:    f.FirstDetectedAt,
+			SLADays:            days,
+			DueDate:            deadline.DueDate,
+			DueAt:              deadline.DueAt,
+		})
+	}
+	return deadlines, nil
+}
+
+// Record records breaches with their events, events[i] being the one of breaches[i] or nil, in one
+// transaction. A breach recorded meanwhile, by another replica, is skipped with its event. It returns
+// how many breaches were recorded
+func (r *SLABreachRepository) Record(ctx context.Context, breaches []models.SLABreach, events []*models.OutboxEvent) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	recorded := 0
+	for i := range breaches {
+		b := &breaches[i]
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO sla_breaches (vulnerability_id, image_id, due_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (vulnerability_id) DO NOTHING
+		`, b.VulnerabilityID, b.ImageID, b.DueAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record SLA breach of vulnerability %d: %w", b.VulnerabilityID, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		recorded++
+		if i < len(events) {
+			if err := r.db.insertOutboxEvents(ctx, tx, nil, events[i:i+1]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit SLA breaches: %w", err)
+	}
+	return recorded, nil
+}
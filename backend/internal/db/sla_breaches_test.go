@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLABreachRepository(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	outboxRepo := NewOutboxRepository(db)
+	repo := NewSLABreachRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "acme/web", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	now := time.Now()
+	detected := now.AddDate(0, 0, -10)
+	scan := &models.Scan{ImageID: image.ID, ScanDate: now, Status: models.ScanStatusCompleted,
+		SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180, SLATimeZone: "UTC"}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+	newVuln := func(cve, severity, status string) *models.Vulnerability {
+		vuln := &models.Vulnerability{
+			CVEID: cve, PackageName: "openssl", PackageVersion: "3.0.7", Severity: severity,
+			Status: status, FirstDetectedAt: detected, LastSeenAt: now,
+		}
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+		return vuln
+	}
+	overdue := newVuln("CVE-2024-0001", "Critical", models.StatusActive)
+	newVuln("CVE-2024-0002", "High", models.StatusActive)       // due in 20 days
+	newVuln("CVE-2024-0003", "Critical", models.StatusAccepted) // triaged away
+
+	breaches, err := repo.ListNew(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, breaches, 1)
+	breach := breaches[0]
+	assert.Equal(t, overdue.ID, breach.VulnerabilityID)
+	assert.Equal(t, "Critical", breach.SLASeverity)
+	assert.Equal(t, 7, breach.SLADays)
+	assert.Equal(t, image.ID, breach.ImageID)
+	assert.Equal(t, "docker.io/acme/web:latest", breach.ImageName)
+	assert.Equal(t, scan.ID, breach.ScanID)
+	assert.True(t, breach.DueAt.Before(now))
+
+	event, err := models.NewOutboxEvent(models.OutboxKindEventWebhook, breach)
+	require.NoError(t, err)
+	recorded, err := repo.Record(ctx, breaches, []*models.OutboxEvent{event})
+	require.NoError(t, err)
+	assert.Equal(t, 1, recorded)
+	pending, err := outboxRepo.CountPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pending)
+
+	// A breach is recorded and announced once
+	breaches, err = repo.ListNew(ctx, now)
+	require.NoError(t, err)
+	assert.Empty(t, breaches)
+	recorded, err = repo.Record(ctx, []models.SLABreach{breach}, []*models.OutboxEvent{event})
+	require.NoError(t, err)
+	assert.Equal(t, 0, recorded)
+	pending, err = outboxRepo.CountPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pending)
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"sort"
+	"strings"
+)
+
+// Sort orders a list by one of the fields it allows. The zero value keeps the default order of the list
+type Sort struct {
+	// Field is the field to sort by, empty for the default order
+	Field string
+	// Order is asc or desc, empty for the default direction of the field
+	Order string
+}
+
+// sortField is a field lists can be sorted by: the SQL expressions ordering it, most significant
+// first, and its direction without an order
+type sortField struct {
+	columns []string
+	desc    bool
+}
+
+// sortFields are the fields a list can be sorted by, by name
+type sortFields map[string]sortField
+
+// severityRank orders severities from the least to the most severe
+const severityRank = `CASE severity
+	WHEN 'Critical' THEN 4
+	WHEN 'High' THEN 3
+	WHEN 'Medium' THEN 2
+	WHEN 'Low' THEN 1
+	ELSE 0
+END`
+
+// vulnerabilitySort are the fields vulnerability+image combinations can be sorted by. The columns are
+// those of the rows read, the queries are wrapped to sort them
+var vulnerabilitySort = sortFields{
+	"severity":          {columns: []string{severityRank}, desc: true},
+	"first_detected_at": {columns: []string{"first_detected_at_for_image"}, desc: true},
+	"package_name":      {columns: []string{"package_name", "package_version"}},
+	"last_seen_at":      {columns: []string{"last_seen_at"}, desc: true},
+}
+
+// scanSort are the fields scans can be sorted by; severity orders by their counts of findings
+var scanSort = sortFields{
+	"scan_date":           {columns: []string{"s.scan_date"}, desc: true},
+	"severity":            {columns: []string{"critical_count", "high_count", "medium_count", "low_count"}, desc: true},
+	"vulnerability_count": {columns: []string{"vulnerability_count"}, desc: true},
+	"image_name":          {columns: []string{"image_name"}},
+}
+
+// imageSort are the fields images can be sorted by; severity orders by their counts of active findings
+var imageSort = sortFields{
+	"updated_at":     {columns: []string{"i.updated_at"}, desc: true},
+	"last_scan_date": {columns: []string{"last_scan_date"}, desc: true},
+	"severity":       {columns: []string{"critical_count", "high_count", "medium_count", "low_count"}, desc: true},
+	"name":           {columns: []string{"i.registry", "i.repository", "i.tag"}},
+}
+
+// ValidateVulnerabilitySort checks the sort of a list of vulnerability+image combinations
+func ValidateVulnerabilitySort(s Sort) error {
+	_, err := vulnerabilitySort.orderBy(s, "")
+	return err
+}
+
+// ValidateScanSort checks the sort of a list of scans
+func ValidateScanSort(s Sort) error {
+	_, err := scanSort.orderBy(s, "")
+	return err
+}
+
+// ValidateImageSort checks the sort of a list of images
+func ValidateImageSort(s Sort) error {
+	_, err := imageSort.orderBy(s, "")
+	return err
+}
+
+// orderBy returns the ORDER BY clause of s, followed by tiebreak so pages don't overlap.
+// Fields and orders are checked against the list's own, so nothing from the request reaches the SQL
+func (f sortFields) orderBy(s Sort, tiebreak string) (string, error) {
+	if s.Field == "" {
+		return "", validationError("order requires sort")
+	}
+	field, ok := f[s.Field]
+	if !ok {
+		return "", validationError("invalid sort: %s (must be one of: %s)", s.Field, strings.Join(f.names(), ", "))
+	}
+	desc := field.desc
+	switch s.Order {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		return "", validationError("invalid order: %s (must be asc or desc)", s.Order)
+	}
+
+	direction := " ASC NULLS FIRST"
+	if desc {
+		direction = " DESC NULLS LAST"
+	}
+	columns := make([]string, 0, len(field.columns)+1)
+	for _, column := range field.columns {
+		columns = append(columns, column+direction)
+	}
+	return " ORDER BY " + strings.Join(append(columns, tiebreak), ", "), nil
+}
+
+// names returns the fields in a stable order for error messages
+func (f sortFields) names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
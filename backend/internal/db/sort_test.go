@@ -0,0 +1,30 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortFields_OrderBy(t *testing.T) {
+	orderBy, err := scanSort.orderBy(Sort{Field: "severity"}, "s.id DESC")
+	require.NoError(t, err)
+	assert.Equal(t, " ORDER BY critical_count DESC NULLS LAST, high_count DESC NULLS LAST, medium_count DESC NULLS LAST, low_count DESC NULLS LAST, s.id DESC", orderBy)
+
+	orderBy, err = scanSort.orderBy(Sort{Field: "scan_date", Order: "asc"}, "s.id DESC")
+	require.NoError(t, err)
+	assert.Equal(t, " ORDER BY s.scan_date ASC NULLS FIRST, s.id DESC", orderBy)
+
+	// Names sort ascending without an order
+	orderBy, err = imageSort.orderBy(Sort{Field: "name"}, "i.id")
+	require.NoError(t, err)
+	assert.Equal(t, " ORDER BY i.registry ASC NULLS FIRST, i.repository ASC NULLS FIRST, i.tag ASC NULLS FIRST, i.id", orderBy)
+
+	_, err = vulnerabilitySort.orderBy(Sort{Field: "scan_date"}, "id")
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.EqualError(t, err, "invalid sort: scan_date (must be one of: first_detected_at, last_seen_at, package_name, severity)")
+	assert.ErrorIs(t, ValidateImageSort(Sort{Field: "name", Order: "random"}), ErrValidation)
+	assert.ErrorIs(t, ValidateScanSort(Sort{Order: "asc"}), ErrValidation)
+	assert.NoError(t, ValidateVulnerabilitySort(Sort{Field: "last_seen_at", Order: "asc"}))
+}
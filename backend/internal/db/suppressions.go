@@ -0,0 +1,224 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// SuppressionRuleRepository handles database operations for suppression rules
+type SuppressionRuleRepository struct {
+	db *Database
+}
+
+// NewSuppressionRuleRepository creates a new suppression rule repository
+func NewSuppressionRuleRepository(db *Database) *SuppressionRuleRepository {
+	return &SuppressionRuleRepository{db: db}
+}
+
+// upsertSuppressionRuleQuery matches the idx_suppression_rules_criteria expressions,
+// so a rule with the same criteria is refreshed instead of duplicated
+const upsertSuppressionRuleQuery = `
+	INSERT INTO suppression_rules (
+		cve_id, package_name, package_version, package_type, fix_state,
+		reason, expires_at, source, created_by, created_at, updated_at
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+	ON CONFLICT ((COALESCE(cve_id, '')), (COALESCE(package_name, '')), (COALESCE(package_version, '')),
+		(COALESCE(package_type, '')), (COALESCE(fix_state, '')))
+	DO UPDATE SET
+		reason = EXCLUDED.reason,
+		expires_at = EXCLUDED.expires_at,
+		source = EXCLUDED.source,
+		updated_at = NOW()
+	RETURNING id, created_by, created_at, updated_at
+`
+
+// Upsert creates a rule, or updates the reason and expiry of the rule with the same criteria
+func (r *SuppressionRuleRepository) Upsert(ctx context.Context, rule *models.SuppressionRule) error {
+	return r.upsert(ctx, r.db, rule)
+}
+
+// Import upserts all rules in one transaction, so a failing entry leaves no partial import
+func (r *SuppressionRuleRepository) Import(ctx context.Context, rules []models.SuppressionRule) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i := range rules {
+		if err := r.upsert(ctx, tx, &rules[i]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SuppressionRuleRepository) upsert(ctx context.Context, q sqlx.QueryerContext, rule *models.SuppressionRule) error {
+	return q.QueryRowxContext(ctx, upsertSuppressionRuleQuery,
+		rule.CVEID, rule.PackageName, rule.PackageVersion, rule.PackageType, rule.FixState,
+		rule.Reason, rule.ExpiresAt, rule.Source, rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+// List returns all rules, including expired ones
+func (r *SuppressionRuleRepository) List(ctx context.Context) ([]models.SuppressionRule, error) {
+	query := `SELECT * FROM suppression_rules ORDER BY id`
+	rules := []models.SuppressionRule{}
+	if err := r.db.SelectContext(ctx, &rules, query); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ListActive returns the rules that have not expired at the given time
+func (r *SuppressionRuleRepository) ListActive(ctx context.Context, now time.Time) ([]models.SuppressionRule, error) {
+	query := `SELECT * FROM suppression_rules WHERE expires_at IS NULL OR expires_at > $1 ORDER BY id`
+	rules := []models.SuppressionRule{}
+	if err := r.db.SelectContext(ctx, &rules, query, now); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Delete removes a rule. Vulnerabilities it already accepted keep their status
+func (r *SuppressionRuleRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM suppression_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound("suppression rule")
+	}
+	return nil
+}
+
+// ListWaivers returns the vulnerabilities accepted or ignored, with their notes
+func (r *SuppressionRuleRepository) ListWaivers(ctx context.Context) ([]models.SuppressionWaiver, error) {
+	query := `
+		SELECT cve_id, package_name, package_version, status, notes
+		FROM vulnerabilities
+		WHERE status IN ('accepted', 'ignored')
+		ORDER BY cve_id, package_name, package_version
+	`
+	waivers := []models.SuppressionWaiver{}
+	if err := r.db.SelectContext(ctx, &waivers, query); err != nil {
+		return nil, err
+	}
+	for i := range waivers {
+		if err := r.db.decrypt(waivers[i].Notes); err != nil {
+			return nil, fmt.Errorf("failed to decrypt notes: %w", err)
+		}
+	}
+	return waivers, nil
+}
+
+// ImportBundle applies a suppression bundle in one transaction. Rules are upserted by their criteria and, with
+// prune, the rules missing from the bundle are deleted. Waivers set the status and notes of the vulnerability
+// they identify, recorded in its history under importedBy; waivers of vulnerabilities not found here are pending.
+// The import is recorded in the audit log
+func (r *SuppressionRuleRepository) ImportBundle(ctx context.Context, rules []models.SuppressionRule, waivers []models.SuppressionWaiver, prune bool, importedBy string) (*models.SuppressionBundleImportResult, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &models.SuppressionBundleImportResult{WaiversPending: []string{}}
+	ids := make([]int64, 0, len(rules))
+	for i := range rules {
+		if rules[i].CreatedBy == nil {
+			rules[i].CreatedBy = &importedBy
+		}
+		if err := r.upsert(ctx, tx, &rules[i]); err != nil {
+			return nil, err
+		}
+		ids = append(ids, int64(rules[i].ID))
+	}
+	result.Rules = len(rules)
+
+	if prune {
+		res, err := tx.ExecContext(ctx, `DELETE FROM suppression_rules WHERE id <> ALL($1)`, pq.Array(ids))
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune suppression rules: %w", err)
+		}
+		pruned, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		result.RulesPruned = int(pruned)
+	}
+
+	vulnRepo := &VulnerabilityRepository{db: r.db}
+	var changes statusChanges
+	for _, waiver := range waivers {
+		var current models.Vulnerability
+		err := tx.GetContext(ctx, &current,
+			`SELECT * FROM vulnerabilities WHERE cve_id = $1 AND package_name = $2 AND package_version = $3`,
+			waiver.CVEID, waiver.PackageName, waiver.PackageVersion)
+		if err == sql.ErrNoRows {
+			result.WaiversPending = append(result.WaiversPending, waiver.CVEID+" "+waiver.PackageName+"@"+waiver.PackageVersion)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vulnerability: %w", err)
+		}
+		if err := r.db.decrypt(current.Notes); err != nil {
+			return nil, fmt.Errorf("failed to decrypt notes: %w", err)
+		}
+
+		// Waivers already in place are left untouched, so importing a bundle twice changes nothing
+		notes := waiver.Notes
+		if notes == nil {
+			notes = new(string)
+		}
+		currentNotes := ""
+		if current.Notes != nil {
+			currentNotes = *current.Notes
+		}
+		if current.Status == waiver.Status && currentNotes == *notes {
+			result.WaiversUnchanged++
+			continue
+		}
+		status := waiver.Status
+		waiverChanges, err := vulnRepo.bulkUpdate(ctx, tx, []int{current.ID}, &models.VulnerabilityUpdateWithContext{
+			Status: &status, Notes: notes, UpdatedBy: importedBy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, waiverChanges...)
+		result.Waivers++
+	}
+
+	details, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertAuditEntry(ctx, tx, &models.AuditEntry{
+		Action:       models.AuditActionSuppressionsImported,
+		ResourceType: "suppression_rules",
+		Actor:        importedBy,
+		Details:      details,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	r.db.publishStatusChanges(changes)
+	return result, nil
+}
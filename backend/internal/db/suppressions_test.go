@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuppressionRuleRepository_ImportIsIdempotent(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewSuppressionRuleRepository(db)
+	ctx := context.Background()
+
+	cveID, pkg := "CVE-2023-0001", "openssl"
+	first, second := "first reason", "updated reason"
+	require.NoError(t, repo.Import(ctx, []models.SuppressionRule{
+		{CVEID: &cveID, Reason: &first, Source: models.SuppressionSourceGrype},
+		{CVEID: &cveID, PackageName: &pkg, Source: models.SuppressionSourceGrype},
+	}))
+
+	expires := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	require.NoError(t, repo.Import(ctx, []models.SuppressionRule{
+		{CVEID: &cveID, Reason: &second, ExpiresAt: &expires, Source: models.SuppressionSourceTrivy},
+	}))
+
+	rules, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 2, "same criteria update the existing rule")
+	assert.Equal(t, second, *rules[0].Reason)
+	assert.Equal(t, models.SuppressionSourceTrivy, rules[0].Source)
+	require.NotNil(t, rules[0].ExpiresAt)
+	assert.True(t, expires.Equal(*rules[0].ExpiresAt))
+}
+
+func TestSuppressionRuleRepository_ListActive(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewSuppressionRuleRepository(db)
+	ctx := context.Background()
+
+	activeCVE, expiredCVE := "CVE-2023-0001", "CVE-2023-0002"
+	expired := time.Now().Add(-time.Hour)
+	require.NoError(t, repo.Upsert(ctx, &models.SuppressionRule{CVEID: &activeCVE, Source: models.SuppressionSourceManual}))
+	require.NoError(t, repo.Upsert(ctx, &models.SuppressionRule{CVEID: &expiredCVE, ExpiresAt: &expired, Source: models.SuppressionSourceManual}))
+
+	rules, err := repo.ListActive(ctx, time.Now())
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, activeCVE, *rules[0].CVEID)
+
+	require.NoError(t, repo.Delete(ctx, rules[0].ID))
+	assert.Error(t, repo.Delete(ctx, rules[0].ID))
+}
+
+func TestSuppressionRuleRepository_ImportBundle(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewSuppressionRuleRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	ctx := context.Background()
+
+	stale, kept := "CVE-2023-0001", "CVE-2023-0002"
+	require.NoError(t, repo.Upsert(ctx, &models.SuppressionRule{CVEID: &stale, Source: models.SuppressionSourceManual}))
+	require.NoError(t, repo.Upsert(ctx, &models.SuppressionRule{CVEID: &kept, Source: models.SuppressionSourceManual}))
+
+	vuln := &models.Vulnerability{
+		CVEID: "CVE-2024-0001", PackageName: "openssl", PackageVersion: "3.0.7", Severity: "High",
+		Status: models.StatusActive, FirstDetectedAt: time.Now(), LastSeenAt: time.Now(),
+	}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+
+	reason, notes := "promoted from staging", "accepted until Q3"
+	rules := func() []models.SuppressionRule {
+		return []models.SuppressionRule{{CVEID: &kept, Reason: &reason, Source: models.SuppressionSourceManual}}
+	}
+	waivers := []models.SuppressionWaiver{
+		{CVEID: "CVE-2024-0001", PackageName: "openssl", PackageVersion: "3.0.7", Status: models.StatusAccepted, Notes: &notes},
+		{CVEID: "CVE-2024-0002", PackageName: "zlib", PackageVersion: "1.2.11", Status: models.StatusIgnored},
+	}
+
+	result, err := repo.ImportBundle(ctx, rules(), waivers, true, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Rules)
+	assert.Equal(t, 1, result.RulesPruned)
+	assert.Equal(t, 1, result.Waivers)
+	assert.Equal(t, []string{"CVE-2024-0002 zlib@1.2.11"}, result.WaiversPending)
+
+	list, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, reason, *list[0].Reason)
+
+	updated, err := vulnRepo.GetByID(ctx, vuln.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusAccepted, updated.Status)
+	assert.Equal(t, notes, *updated.Notes)
+	assert.NotNil(t, updated.RemediationDate)
+
+	exported, err := repo.ListWaivers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, waivers[:1], exported)
+
+	// Importing the same bundle again changes nothing
+	result, err = repo.ImportBundle(ctx, rules(), waivers, true, "alice")
+	require.NoError(t, err)
+	assert.Zero(t, result.RulesPruned)
+	assert.Zero(t, result.Waivers)
+	assert.Equal(t, 1, result.WaiversUnchanged)
+	history, err := vulnRepo.GetHistory(ctx, vuln.ID)
+	require.NoError(t, err)
+	assert.Len(t, history, 2, "status and notes changed once")
+}
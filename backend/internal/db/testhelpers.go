@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -17,7 +18,7 @@ import (
 )
 
 // SetupTestDatabase creates a PostgreSQL testcontainer and runs migrations
-func SetupTestDatabase(t *testing.T) *Database {
+func SetupTestDatabase(t testing.TB) *Database {
 	t.Helper()
 
 	ctx := context.Background()
@@ -66,6 +67,22 @@ func SetupTestDatabase(t *testing.T) *Database {
 	return &Database{DB: db}
 }
 
+// CheckBaseline fails a benchmark whose average time per operation exceeds maxPerOp.
+// Baselines are only enforced when BENCH_ENFORCE_BASELINE=true, so ad-hoc runs on slow
+// machines still report numbers instead of failing.
+func CheckBaseline(b *testing.B, maxPerOp time.Duration) {
+	b.Helper()
+
+	if os.Getenv("BENCH_ENFORCE_BASELINE") != "true" || b.N == 0 {
+		return
+	}
+
+	perOp := b.Elapsed() / time.Duration(b.N)
+	if perOp > maxPerOp {
+		b.Fatalf("performance regression: %s/op exceeds baseline of %s/op", perOp, maxPerOp)
+	}
+}
+
 func runMigrations(connStr string) error {
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
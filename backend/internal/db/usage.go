@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// UsageRepository handles team usage accounting and quotas
+type UsageRepository struct {
+	db *Database
+}
+
+// NewUsageRepository creates a new usage repository
+func NewUsageRepository(db *Database) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// GetTeamUsage returns the usage of every team with scans or a quota, or of a single team.
+// Scans are counted between from and to, SBOM storage and vulnerabilities over the scans still stored.
+// Scans that didn't come from an ImageScan aren't charged to any team
+func (r *UsageRepository) GetTeamUsage(ctx context.Context, from, to time.Time, namespace *string) ([]models.TeamUsage, error) {
+	query := `
+		WITH period_scans AS (
+			SELECT imagescan_namespace AS namespace, COUNT(*) AS scans
+			FROM scans
+			WHERE imagescan_namespace IS NOT NULL AND scan_date >= $1 AND scan_date < $2
+			GROUP BY imagescan_namespace
+		), storage AS (
+			-- A document shared by unchanged scans is stored once
+			SELECT namespace, SUM(size_bytes) AS sbom_storage_bytes
+			FROM (
+				SELECT DISTINCT ON (s.imagescan_namespace, COALESCE(sb.document_scan_id, sb.scan_id))
+					s.imagescan_namespace AS namespace, COALESCE(sb.size_bytes, 0) AS size_bytes
+				FROM scans s
+				JOIN sboms sb ON sb.scan_id = s.id
+				WHERE s.imagescan_namespace IS NOT NULL
+			) documents
+			GROUP BY namespace
+		), tracked AS (
+			SELECT s.imagescan_namespace AS namespace, COUNT(DISTINCT sv.vulnerability_id) AS vulnerabilities_tracked
+			FROM scans s
+			JOIN scan_vulnerabilities sv ON sv.scan_id = s.id
+			WHERE s.imagescan_namespace IS NOT NULL
+			GROUP BY s.imagescan_namespace
+		), teams AS (
+			SELECT namespace FROM period_scans
+			UNION SELECT namespace FROM storage
+			UNION SELECT namespace FROM tracked
+			UNION SELECT namespace FROM team_quotas
+		)
+		SELECT t.namespace,
+			COALESCE(p.scans, 0) AS scans,
+			COALESCE(st.sbom_storage_bytes, 0) AS sbom_storage_bytes,
+			COALESCE(tr.vulnerabilities_tracked, 0) AS vulnerabilities_tracked
+		FROM teams t
+		LEFT JOIN period_scans p ON p.namespace = t.namespace
+		LEFT JOIN storage st ON st.namespace = t.namespace
+		LEFT JOIN tracked tr ON tr.namespace = t.namespace
+		WHERE $3::text IS NULL OR t.namespace = $3
+		ORDER BY t.namespace
+	`
+
+	usage := []models.TeamUsage{}
+	if err := r.db.SelectContext(ctx, &usage, query, from, to, namespace); err != nil {
+		return nil, fmt.Errorf("failed to get team usage: %w", err)
+	}
+	return usage, nil
+}
+
+// ListQuotas returns the quotas of all teams
+func (r *UsageRepository) ListQuotas(ctx context.Context) ([]models.TeamQuota, error) {
+	quotas := []models.TeamQuota{}
+	if err := r.db.SelectContext(ctx, &quotas, `SELECT * FROM team_quotas ORDER BY namespace`); err != nil {
+		return nil, fmt.Errorf("failed to list team quotas: %w", err)
+	}
+	return quotas, nil
+}
+
+// GetQuota returns the quota of a team, or nil when it has none
+func (r *UsageRepository) GetQuota(ctx context.Context, namespace string) (*models.TeamQuota, error) {
+	var quota models.TeamQuota
+	err := r.db.GetContext(ctx, &quota, `SELECT * FROM team_quotas WHERE namespace = $1`, namespace)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team quota: %w", err)
+	}
+	return &quota, nil
+}
+
+// UpsertQuota sets the quota of a team and returns the stored row
+func (r *UsageRepository) UpsertQuota(ctx context.Context, namespace string, req *models.TeamQuotaRequest, updatedBy string) (*models.TeamQuota, error) {
+	var quota models.TeamQuota
+	query := `
+		INSERT INTO team_quotas (namespace, max_scans_per_month, max_sbom_storage_bytes, max_vulnerabilities, enforce, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (namespace)
+		DO UPDATE SET
+			max_scans_per_month = EXCLUDED.max_scans_per_month,
+			max_sbom_storage_bytes = EXCLUDED.max_sbom_storage_bytes,
+			max_vulnerabilities = EXCLUDED.max_vulnerabilities,
+			enforce = EXCLUDED.enforce,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING *
+	`
+	if err := r.db.GetContext(ctx, &quota, query,
+		namespace, req.MaxScansPerMonth, req.MaxSBOMStorageBytes, req.MaxVulnerabilities, req.Enforce, updatedBy,
+	); err != nil {
+		return nil, fmt.Errorf("failed to set team quota: %w", err)
+	}
+	return &quota, nil
+}
+
+// DeleteQuota removes the quota of a team
+func (r *UsageRepository) DeleteQuota(ctx context.Context, namespace string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM team_quotas WHERE namespace = $1`, namespace)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound("quota")
+	}
+	return nil
+}
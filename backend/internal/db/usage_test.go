@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageRepository(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewUsageRepository(db)
+	scanRepo := NewScanRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, NewImageRepository(db).Create(ctx, image))
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	payments, name := "payments", "nginx"
+	for _, scanDate := range []time.Time{now, now, from.AddDate(0, -1, 0)} {
+		scan := &models.Scan{ImageID: image.ID, ScanDate: scanDate, Status: models.ScanStatusCompleted,
+			SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180, ImageScanNamespace: &payments, ImageScanName: &name}
+		require.NoError(t, scanRepo.Create(ctx, scan))
+	}
+	// Scans without an ImageScan aren't charged to a team
+	require.NoError(t, scanRepo.Create(ctx, &models.Scan{ImageID: image.ID, ScanDate: now, Status: models.ScanStatusCompleted}))
+
+	maxScans := 1
+	_, err := repo.UpsertQuota(ctx, "search", &models.TeamQuotaRequest{MaxScansPerMonth: &maxScans}, "admin@example.com")
+	require.NoError(t, err)
+	quota, err := repo.UpsertQuota(ctx, "payments", &models.TeamQuotaRequest{MaxScansPerMonth: &maxScans, Enforce: true}, "admin@example.com")
+	require.NoError(t, err)
+	assert.True(t, quota.Enforce)
+
+	usage, err := repo.GetTeamUsage(ctx, from, from.AddDate(0, 1, 0), nil)
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, "payments", usage[0].Namespace)
+	assert.Equal(t, 2, usage[0].Scans)
+	// Teams with a quota are listed even without scans
+	assert.Equal(t, models.TeamUsage{Namespace: "search"}, usage[1])
+
+	usage, err = repo.GetTeamUsage(ctx, from, from.AddDate(0, 1, 0), &payments)
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+
+	got, err := repo.GetQuota(ctx, "payments")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, []string{models.QuotaScansPerMonth}, got.Exceeded(usage[0]))
+
+	require.NoError(t, repo.DeleteQuota(ctx, "payments"))
+	assert.Error(t, repo.DeleteQuota(ctx, "payments"))
+	got, err = repo.GetQuota(ctx, "payments")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/invulnerable/backend/internal/events"
 	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/sla"
+	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
 
@@ -24,40 +27,55 @@ func ValidateStatus(status string) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("invalid status: %s (must be one of: %v)", status, models.ValidStatuses)
+	return validationError("invalid status: %s (must be one of: %v)", status, models.ValidStatuses)
 }
 
 func (r *VulnerabilityRepository) Upsert(ctx context.Context, vuln *models.Vulnerability) error {
 	query := `
 		INSERT INTO vulnerabilities (
-			cve_id, package_name, package_version, package_type,
-			severity, fix_version, url, description, status,
+			cve_id, package_name, package_version, package_type, purl,
+			severity, initial_severity, fix_version, url, description, known_exploited, status,
 			first_detected_at, last_seen_at,
-			imagescan_namespace, imagescan_name,
+			imagescan_namespace, imagescan_name, advisories,
 			created_at, updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW(), NOW())
 		ON CONFLICT (cve_id, package_name, package_version)
 		DO UPDATE SET
 			last_seen_at = EXCLUDED.last_seen_at,
+			-- Rows created before PURLs were stored pick one up on their next scan
+			purl = COALESCE(EXCLUDED.purl, vulnerabilities.purl),
+			-- initial_severity stays the one of the first detection
 			severity = EXCLUDED.severity,
 			fix_version = EXCLUDED.fix_version,
+			-- vulnerabilities.fix_version is the version before this scan
+			fix_became_available_at = CASE
+				WHEN vulnerabilities.fix_version IS NULL AND EXCLUDED.fix_version IS NOT NULL THEN NOW()
+				ELSE vulnerabilities.fix_became_available_at
+			END,
 			url = EXCLUDED.url,
 			description = EXCLUDED.description,
+			known_exploited = EXCLUDED.known_exploited,
+			advisories = EXCLUDED.advisories,
 			-- Always update ImageScan context to current scanner
 			-- This prevents orphaned CVEs when ImageScans are renamed/moved
 			-- and enables webhooks for old vulnerabilities without context
 			imagescan_namespace = EXCLUDED.imagescan_namespace,
 			imagescan_name = EXCLUDED.imagescan_name,
 			updated_at = NOW()
-		RETURNING id, created_at, updated_at, imagescan_namespace, imagescan_name
+		RETURNING id, initial_severity, created_at, updated_at, imagescan_namespace, imagescan_name, fix_became_available_at
 	`
+	// Without advisories the column is NULL, database/sql would send an empty document
+	var advisories interface{}
+	if len(vuln.Advisories) > 0 {
+		advisories = []byte(vuln.Advisories)
+	}
 	return r.db.QueryRowContext(ctx, query,
-		vuln.CVEID, vuln.PackageName, vuln.PackageVersion, vuln.PackageType,
-		vuln.Severity, vuln.FixVersion, vuln.URL, vuln.Description, vuln.Status,
+		vuln.CVEID, vuln.PackageName, vuln.PackageVersion, vuln.PackageType, vuln.PURL,
+		vuln.Severity, vuln.FixVersion, vuln.URL, vuln.Description, vuln.KnownExploited, vuln.Status,
 		vuln.FirstDetectedAt, vuln.LastSeenAt,
-		vuln.ImageScanNamespace, vuln.ImageScanName,
-	).Scan(&vuln.ID, &vuln.CreatedAt, &vuln.UpdatedAt, &vuln.ImageScanNamespace, &vuln.ImageScanName)
+		vuln.ImageScanNamespace, vuln.ImageScanName, advisories,
+	).Scan(&vuln.ID, &vuln.InitialSeverity, &vuln.CreatedAt, &vuln.UpdatedAt, &vuln.ImageScanNamespace, &vuln.ImageScanName, &vuln.FixBecameAvailableAt)
 }
 
 func (r *VulnerabilityRepository) GetByID(ctx context.Context, id int) (*models.Vulnerability, error) {
@@ -65,19 +83,41 @@ func (r *VulnerabilityRepository) GetByID(ctx context.Context, id int) (*models.
 	query := `SELECT * FROM vulnerabilities WHERE id = $1`
 	if err := r.db.GetContext(ctx, &vuln, query, id); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("vulnerability not found")
+			return nil, notFound("vulnerability")
 		}
 		return nil, err
 	}
+	if err := r.db.decrypt(vuln.Notes); err != nil {
+		return nil, fmt.Errorf("failed to decrypt notes: %w", err)
+	}
 	return &vuln, nil
 }
 
+// GetByIDs returns the vulnerabilities with the given IDs ordered by ID, unknown IDs are left out
+func (r *VulnerabilityRepository) GetByIDs(ctx context.Context, ids []int) ([]models.Vulnerability, error) {
+	vulns := []models.Vulnerability{}
+	if len(ids) == 0 {
+		return vulns, nil
+	}
+	query := `SELECT * FROM vulnerabilities WHERE id = ANY($1) ORDER BY id`
+	if err := r.db.SelectContext(ctx, &vulns, query, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	if err := r.db.decryptVulnerabilities(vulns); err != nil {
+		return nil, err
+	}
+	return vulns, nil
+}
+
 func (r *VulnerabilityRepository) GetByCVE(ctx context.Context, cveID string) ([]models.Vulnerability, error) {
 	vulns := []models.Vulnerability{}
 	query := `SELECT * FROM vulnerabilities WHERE cve_id = $1 ORDER BY package_name, package_version`
 	if err := r.db.SelectContext(ctx, &vulns, query, cveID); err != nil {
 		return nil, err
 	}
+	if err := r.db.decryptVulnerabilities(vulns); err != nil {
+		return nil, err
+	}
 	return vulns, nil
 }
 
@@ -124,11 +164,101 @@ func (r *VulnerabilityRepository) List(ctx context.Context, limit, offset int, s
 	if err := r.db.SelectContext(ctx, &vulns, query, args...); err != nil {
 		return nil, err
 	}
+	if err := r.db.decryptVulnerabilities(vulns); err != nil {
+		return nil, err
+	}
+	return vulns, nil
+}
+
+// descriptionDocument is the full-text document of a vulnerability description, as indexed by idx_vulnerabilities_description_fts
+const descriptionDocument = `to_tsvector('english', COALESCE(description, ''))`
+
+// searchConditions builds the WHERE clause of a vulnerability search, with its arguments
+func searchConditions(search *models.VulnerabilitySearch) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+
+	if search.PackageName != nil {
+		args = append(args, "%"+*search.PackageName+"%")
+		where += fmt.Sprintf(" AND package_name ILIKE $%d", len(args))
+	}
+	if search.CVEPrefix != nil {
+		args = append(args, *search.CVEPrefix+"%")
+		where += fmt.Sprintf(" AND cve_id ILIKE $%d", len(args))
+	}
+	if search.Text != nil {
+		args = append(args, *search.Text)
+		where += fmt.Sprintf(" AND %s @@ websearch_to_tsquery('english', $%d)", descriptionDocument, len(args))
+	}
+	if len(search.Severities) > 0 {
+		args = append(args, pq.Array(search.Severities))
+		where += fmt.Sprintf(" AND severity = ANY($%d)", len(args))
+	}
+	if search.Status != nil {
+		args = append(args, *search.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if search.HasFix != nil {
+		if *search.HasFix {
+			where += " AND fix_version IS NOT NULL"
+		} else {
+			where += " AND fix_version IS NULL"
+		}
+	}
+	if search.DetectedAfter != nil {
+		args = append(args, *search.DetectedAfter)
+		where += fmt.Sprintf(" AND first_detected_at >= $%d", len(args))
+	}
+	if search.DetectedBefore != nil {
+		args = append(args, *search.DetectedBefore)
+		where += fmt.Sprintf(" AND first_detected_at <= $%d", len(args))
+	}
+	return where, args
+}
+
+// CountSearch returns the number of vulnerabilities matching a search
+func (r *VulnerabilityRepository) CountSearch(ctx context.Context, search *models.VulnerabilitySearch) (int, error) {
+	where, args := searchConditions(search)
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM vulnerabilities"+where, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Search returns the vulnerabilities matching a search, the best matches of the description first
+// for a full-text search, then by severity and the most recently detected
+func (r *VulnerabilityRepository) Search(ctx context.Context, search *models.VulnerabilitySearch, limit, offset int) ([]models.Vulnerability, error) {
+	where, args := searchConditions(search)
+	query := "SELECT * FROM vulnerabilities" + where + " ORDER BY"
+	if search.Text != nil {
+		args = append(args, *search.Text)
+		query += fmt.Sprintf(" ts_rank(%s, websearch_to_tsquery('english', $%d)) DESC,", descriptionDocument, len(args))
+	}
+	query += `
+		CASE severity
+			WHEN 'Critical' THEN 1
+			WHEN 'High' THEN 2
+			WHEN 'Medium' THEN 3
+			WHEN 'Low' THEN 4
+			ELSE 5
+		END,
+		first_detected_at DESC, id`
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	vulns := []models.Vulnerability{}
+	if err := r.db.SelectContext(ctx, &vulns, query, args...); err != nil {
+		return nil, err
+	}
+	if err := r.db.decryptVulnerabilities(vulns); err != nil {
+		return nil, err
+	}
 	return vulns, nil
 }
 
 // CountWithImageInfo returns the total count of vulnerability+image combinations matching filters
-func (r *VulnerabilityRepository) CountWithImageInfo(ctx context.Context, severity, status *string, hasFix *bool, imageID *int, imageName, cveID *string) (int, error) {
+func (r *VulnerabilityRepository) CountWithImageInfo(ctx context.Context, severity, status *string, hasFix *bool, imageID *int, imageName, cveID *string, flapping, exposed *bool) (int, error) {
 	query := `
 		SELECT COUNT(DISTINCT (v.id, i.id))
 		FROM vulnerabilities v
@@ -178,6 +308,9 @@ func (r *VulnerabilityRepository) CountWithImageInfo(ctx context.Context, severi
 		args = append(args, *cveID)
 	}
 
+	query += flappingFilter(flapping)
+	query += exposedFilter(exposed)
+
 	var count int
 	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
 		return 0, err
@@ -186,8 +319,8 @@ func (r *VulnerabilityRepository) CountWithImageInfo(ctx context.Context, severi
 }
 
 // ListWithImageInfo returns vulnerabilities with image context for compliance tracking
-// Each row represents a unique vulnerability+image combination
-func (r *VulnerabilityRepository) ListWithImageInfo(ctx context.Context, limit, offset int, severity, status *string, hasFix *bool, imageID *int, imageName, cveID *string) ([]models.VulnerabilityWithImageInfo, error) {
+// Each row represents a unique vulnerability+image combination, ordered by sort
+func (r *VulnerabilityRepository) ListWithImageInfo(ctx context.Context, limit, offset int, sort Sort, severity, status *string, hasFix *bool, imageID *int, imageName, cveID *string, flapping, exposed *bool) ([]models.VulnerabilityWithImageInfo, error) {
 	// This query returns one row per image+vulnerability combination
 	// showing when the vulnerability was first detected on that specific image
 	query := `
@@ -197,10 +330,14 @@ func (r *VulnerabilityRepository) ListWithImageInfo(ctx context.Context, limit,
 			v.package_name,
 			v.package_version,
 			v.package_type,
+			v.purl,
 			v.severity,
+			v.initial_severity,
 			v.fix_version,
+			v.fix_became_available_at,
 			v.url,
 			v.description,
+			v.advisories,
 			v.status,
 			v.last_seen_at,
 			v.remediation_date,
@@ -210,13 +347,21 @@ func (r *VulnerabilityRepository) ListWithImageInfo(ctx context.Context, limit,
 			i.id as image_id,
 			i.registry || '/' || i.repository || ':' || i.tag as image_name,
 			i.digest as image_digest,
+			i.monitoring as image_monitoring,
 			MIN(s.scan_date) OVER (PARTITION BY v.id, i.id) as first_detected_at_for_image,
 			FIRST_VALUE(s.id) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as latest_scan_id,
 			FIRST_VALUE(s.scan_date) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as latest_scan_date,
 			FIRST_VALUE(s.sla_critical) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as sla_critical,
 			FIRST_VALUE(s.sla_high) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as sla_high,
 			FIRST_VALUE(s.sla_medium) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as sla_medium,
-			FIRST_VALUE(s.sla_low) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as sla_low
+			FIRST_VALUE(s.sla_low) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as sla_low,
+			FIRST_VALUE(s.sla_time_zone) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as sla_time_zone,
+			FIRST_VALUE(s.sla_business_days) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as sla_business_days,
+			FIRST_VALUE(s.sla_holidays) OVER (PARTITION BY v.id, i.id ORDER BY s.scan_date DESC) as sla_holidays,
+			COALESCE((
+				SELECT SUM(f.flap_count) FROM vulnerability_flaps f
+				WHERE f.vulnerability_id = v.id AND f.image_id = i.id
+			), 0) as flap_count
 		FROM vulnerabilities v
 		JOIN scan_vulnerabilities sv ON sv.vulnerability_id = v.id
 		JOIN scans s ON s.id = sv.scan_id
@@ -265,6 +410,9 @@ func (r *VulnerabilityRepository) ListWithImageInfo(ctx context.Context, limit,
 		argCount++
 	}
 
+	query += flappingFilter(flapping)
+	query += exposedFilter(exposed)
+
 	query += ` ORDER BY
 		v.id, i.id,
 		CASE v.severity
@@ -275,6 +423,15 @@ func (r *VulnerabilityRepository) ListWithImageInfo(ctx context.Context, limit,
 			ELSE 5
 		END`
 
+	if sort != (Sort{}) {
+		// DISTINCT ON orders by the combination first, the rows are sorted once deduplicated
+		orderBy, err := vulnerabilitySort.orderBy(sort, "id, image_id")
+		if err != nil {
+			return nil, err
+		}
+		query = `SELECT * FROM (` + query + `) combinations` + orderBy
+	}
+
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCount, argCount+1)
 	args = append(args, limit, offset)
 
@@ -282,10 +439,196 @@ func (r *VulnerabilityRepository) ListWithImageInfo(ctx context.Context, limit,
 	if err := r.db.SelectContext(ctx, &vulns, query, args...); err != nil {
 		return nil, err
 	}
+	for i := range vulns {
+		if err := r.db.decrypt(vulns[i].Notes); err != nil {
+			return nil, fmt.Errorf("failed to decrypt notes of vulnerability %d: %w", vulns[i].ID, err)
+		}
+		setSLADeadline(&vulns[i], r.db.slaSeverity)
+	}
 	return vulns, nil
 }
 
-func (r *VulnerabilityRepository) Update(ctx context.Context, id int, update *models.VulnerabilityUpdateWithContext) error {
+// latestScansAsOf lists the latest successful scan of each image target at the time in $1
+const latestScansAsOf = `
+	SELECT DISTINCT ON (s.image_id, COALESCE(s.target, '')) s.*
+	FROM scans s
+	WHERE s.scan_date <= $1 AND s.status IN ('completed', 'partial')
+	ORDER BY s.image_id, COALESCE(s.target, ''), s.scan_date DESC
+`
+
+// statusAsOf is the status vulnerability v had at the time in $1, from its history: the value set by
+// the last change before that time, or before the first change after it, or its current status
+const statusAsOf = `COALESCE(
+	(SELECT h.new_value FROM vulnerability_history h
+		WHERE h.vulnerability_id = v.id AND h.field_name = 'status' AND h.changed_at <= $1
+		ORDER BY h.changed_at DESC, h.id DESC LIMIT 1),
+	(SELECT h.old_value FROM vulnerability_history h
+		WHERE h.vulnerability_id = v.id AND h.field_name = 'status' AND h.changed_at > $1
+		ORDER BY h.changed_at, h.id LIMIT 1),
+	v.status
+)`
+
+// snapshotWithImageInfo builds the query of vulnerability+image combinations found by the latest
+// scans of each image at asOf, with their status at the time
+func snapshotWithImageInfo(asOf time.Time, severity, status *string, imageID *int, imageName, cveID *string) (string, []interface{}) {
+	query := `
+		SELECT * FROM (
+			SELECT DISTINCT ON (v.id, i.id)
+				v.id,
+				v.cve_id,
+				v.package_name,
+				v.package_version,
+				v.package_type,
+				v.purl,
+				v.severity,
+				v.initial_severity,
+				v.fix_version,
+				v.fix_became_available_at,
+				v.url,
+				v.description,
+				v.advisories,
+				` + statusAsOf + ` as status,
+				l.scan_date as last_seen_at,
+				CASE WHEN v.remediation_date <= $1 THEN v.remediation_date END as remediation_date,
+				v.notes,
+				v.created_at,
+				v.updated_at,
+				i.id as image_id,
+				i.registry || '/' || i.repository || ':' || i.tag as image_name,
+				i.digest as image_digest,
+				i.monitoring as image_monitoring,
+				(
+					SELECT MIN(fs.scan_date) FROM scans fs
+					JOIN scan_vulnerabilities fsv ON fsv.scan_id = fs.id
+					WHERE fs.image_id = i.id AND fsv.vulnerability_id = v.id AND fs.scan_date <= $1
+				) as first_detected_at_for_image,
+				l.id as latest_scan_id,
+				l.scan_date as latest_scan_date,
+				l.sla_critical,
+				l.sla_high,
+				l.sla_medium,
+				l.sla_low,
+				l.sla_time_zone,
+				l.sla_business_days,
+				l.sla_holidays
+			FROM (` + latestScansAsOf + `) l
+			JOIN scan_vulnerabilities sv ON sv.scan_id = l.id
+			JOIN vulnerabilities v ON v.id = sv.vulnerability_id
+			JOIN images i ON i.id = l.image_id
+			WHERE 1=1
+	`
+
+	args := []interface{}{asOf}
+	argCount := 2
+
+	if severity != nil {
+		query += fmt.Sprintf(" AND v.severity = $%d", argCount)
+		args = append(args, *severity)
+		argCount++
+	}
+
+	if imageID != nil {
+		query += fmt.Sprintf(" AND i.id = $%d", argCount)
+		args = append(args, *imageID)
+		argCount++
+	}
+
+	if imageName != nil {
+		query += fmt.Sprintf(" AND (i.registry || '/' || i.repository || ':' || i.tag) ILIKE $%d", argCount)
+		args = append(args, "%"+*imageName+"%")
+		argCount++
+	}
+
+	if cveID != nil {
+		query += fmt.Sprintf(" AND v.cve_id = $%d", argCount)
+		args = append(args, *cveID)
+		argCount++
+	}
+
+	query += ` ORDER BY v.id, i.id, l.scan_date DESC
+		) snapshot
+		WHERE 1=1`
+
+	if status != nil {
+		query += fmt.Sprintf(" AND status = $%d", argCount)
+		args = append(args, *status)
+	}
+
+	return query, args
+}
+
+// CountWithImageInfoAsOf returns the count of vulnerability+image combinations found at asOf matching filters
+func (r *VulnerabilityRepository) CountWithImageInfoAsOf(ctx context.Context, asOf time.Time, severity, status *string, imageID *int, imageName, cveID *string) (int, error) {
+	snapshot, args := snapshotWithImageInfo(asOf, severity, status, imageID, imageName, cveID)
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM (`+snapshot+`) counted`, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListWithImageInfoAsOf reconstructs the vulnerability+image combinations as they were at asOf: the
+// findings of the latest successful scan of each image target then, with the status they had, ordered by sort
+func (r *VulnerabilityRepository) ListWithImageInfoAsOf(ctx context.Context, asOf time.Time, limit, offset int, sort Sort, severity, status *string, imageID *int, imageName, cveID *string) ([]models.VulnerabilityWithImageInfo, error) {
+	query, args := snapshotWithImageInfo(asOf, severity, status, imageID, imageName, cveID)
+	orderBy := " ORDER BY id, image_id"
+	if sort != (Sort{}) {
+		var err error
+		if orderBy, err = vulnerabilitySort.orderBy(sort, "id, image_id"); err != nil {
+			return nil, err
+		}
+	}
+	query += orderBy + fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	vulns := []models.VulnerabilityWithImageInfo{}
+	if err := r.db.SelectContext(ctx, &vulns, query, args...); err != nil {
+		return nil, err
+	}
+	for i := range vulns {
+		if err := r.db.decrypt(vulns[i].Notes); err != nil {
+			return nil, fmt.Errorf("failed to decrypt notes of vulnerability %d: %w", vulns[i].ID, err)
+		}
+		setSLADeadline(&vulns[i], r.db.slaSeverity)
+	}
+	return vulns, nil
+}
+
+// flappingFilter restricts vulnerability+image rows to those flapping on that image, or not flapping
+func flappingFilter(flapping *bool) string {
+	if flapping == nil {
+		return ""
+	}
+	exists := fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM vulnerability_flaps f
+		WHERE f.vulnerability_id = v.id AND f.image_id = i.id AND f.flap_count >= %d
+	)`, models.FlappingThreshold)
+	if *flapping {
+		return " AND " + exists
+	}
+	return " AND NOT " + exists
+}
+
+// setSLADeadline fills the SLA due date of a vulnerability with the timezone and calendar of its latest scan,
+// from the severity chosen by the sla.Severity policy
+func setSLADeadline(v *models.VulnerabilityWithImageInfo, policy string) {
+	loc, err := sla.LoadLocation(v.SLATimeZone)
+	if err != nil {
+		// Timezones are validated when scans are stored, this is a zone removed from tzdata since
+		loc = time.UTC
+	}
+	// Holidays are validated when scans are stored and read back from a DATE[] column
+	calendar, _ := sla.NewCalendar(v.SLABusinessDays, v.SLAHolidays)
+	v.SLASeverity = sla.Severity(policy, v.InitialSeverity, v.Severity)
+	days := sla.Days(v.SLASeverity, v.SLACritical, v.SLAHigh, v.SLAMedium, v.SLALow)
+	deadline := calendar.DeadlineFor(v.FirstDetectedAt, days, loc)
+	v.SLADueDate = deadline.DueDate
+	v.SLADueAt = &deadline.DueAt
+}
+
+// Update changes the status and notes of a vulnerability and records the changes in its history.
+// Events are written to the notification outbox in the same transaction
+func (r *VulnerabilityRepository) Update(ctx context.Context, id int, update *models.VulnerabilityUpdateWithContext, events ...*models.OutboxEvent) error {
 	// Validate status if provided
 	if update.Status != nil {
 		if err := ValidateStatus(*update.Status); err != nil {
@@ -293,11 +636,23 @@ func (r *VulnerabilityRepository) Update(ctx context.Context, id int, update *mo
 		}
 	}
 
-	// Get current state for audit trail
-	current, err := r.GetByID(ctx, id)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Get current state for audit trail, locked so concurrent updates can't interleave with its history
+	var current models.Vulnerability
+	if err := tx.GetContext(ctx, &current, `SELECT * FROM vulnerabilities WHERE id = $1 FOR UPDATE`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return notFound("vulnerability")
+		}
 		return fmt.Errorf("failed to get current vulnerability: %w", err)
 	}
+	if err := r.db.decrypt(current.Notes); err != nil {
+		return fmt.Errorf("failed to decrypt notes: %w", err)
+	}
 
 	// Build dynamic update query
 	query := `UPDATE vulnerabilities SET updated_at = NOW()`
@@ -325,8 +680,12 @@ func (r *VulnerabilityRepository) Update(ctx context.Context, id int, update *mo
 	}
 
 	if update.Notes != nil {
+		notes, err := r.db.encrypt(update.Notes)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt notes: %w", err)
+		}
 		query += fmt.Sprintf(", notes = $%d", argCount)
-		args = append(args, *update.Notes)
+		args = append(args, *notes)
 		argCount++
 	}
 
@@ -339,17 +698,14 @@ func (r *VulnerabilityRepository) Update(ctx context.Context, id int, update *mo
 	query += fmt.Sprintf(" WHERE id = $%d", argCount)
 	args = append(args, id)
 
-	_, err = r.db.ExecContext(ctx, query, args...)
-	if err != nil {
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
 		return err
 	}
 
 	// Create audit trail entries
+	history := historyBatch{}
 	if update.Status != nil && current.Status != *update.Status {
-		if err := r.CreateHistoryEntry(ctx, id, "status", &current.Status, update.Status, update.UpdatedBy, update.ImageID, update.ImageName); err != nil {
-			// Log error but don't fail the update
-			return fmt.Errorf("warning: failed to create status history: %w", err)
-		}
+		history.add(id, "status", current.Status, *update.Status)
 	}
 
 	if update.Notes != nil {
@@ -357,32 +713,42 @@ func (r *VulnerabilityRepository) Update(ctx context.Context, id int, update *mo
 		if current.Notes != nil {
 			oldNotes = *current.Notes
 		}
-		newNotes := *update.Notes
-		if oldNotes != newNotes {
-			if err := r.CreateHistoryEntry(ctx, id, "notes", &oldNotes, &newNotes, update.UpdatedBy, update.ImageID, update.ImageName); err != nil {
-				return fmt.Errorf("warning: failed to create notes history: %w", err)
+		if oldNotes != *update.Notes {
+			// The audit trail holds the notes too, so it is encrypted like them
+			oldValue, err := r.db.encrypt(&oldNotes)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt notes history: %w", err)
 			}
+			newValue, err := r.db.encrypt(update.Notes)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt notes history: %w", err)
+			}
+			history.add(id, "notes", *oldValue, *newValue)
 		}
 	}
 
+	if err := history.insert(ctx, tx, update.UpdatedBy, update.ImageID, update.ImageName); err != nil {
+		return fmt.Errorf("failed to create history: %w", err)
+	}
+	if err := r.db.insertOutboxEvents(ctx, tx, nil, events); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if update.Status != nil && current.Status != *update.Status {
+		r.db.publishStatusChanges(statusChanges{statusChange(&current, *update.Status, update.UpdatedBy)})
+	}
 	return nil
 }
 
-func (r *VulnerabilityRepository) BulkUpdate(ctx context.Context, ids []int, update *models.VulnerabilityUpdateWithContext) error {
+// BulkUpdate applies the same change to several vulnerabilities, like Update
+func (r *VulnerabilityRepository) BulkUpdate(ctx context.Context, ids []int, update *models.VulnerabilityUpdateWithContext, events ...*models.OutboxEvent) error {
 	if len(ids) == 0 {
 		return nil
 	}
 
-	// Get current state for all vulnerabilities (for audit trail)
-	currentStates := make(map[int]*models.Vulnerability)
-	for _, id := range ids {
-		current, err := r.GetByID(ctx, id)
-		if err != nil {
-			return fmt.Errorf("failed to get vulnerability %d: %w", id, err)
-		}
-		currentStates[id] = current
-	}
-
 	// Validate status if provided
 	if update.Status != nil {
 		if err := ValidateStatus(*update.Status); err != nil {
@@ -390,6 +756,125 @@ func (r *VulnerabilityRepository) BulkUpdate(ctx context.Context, ids []int, upd
 		}
 	}
 
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	changes, err := r.bulkUpdate(ctx, tx, ids, update)
+	if err != nil {
+		return err
+	}
+	if err := r.db.insertOutboxEvents(ctx, tx, nil, events); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.db.publishStatusChanges(changes)
+	return nil
+}
+
+// ImportTriage applies the updates of a triage import in one transaction, none is applied if one fails.
+// Events are written to the notification outbox in the same transaction
+func (r *VulnerabilityRepository) ImportTriage(ctx context.Context, updates []models.TriageImportUpdate, events ...*models.OutboxEvent) error {
+	for _, u := range updates {
+		if u.Update.Status != nil {
+			if err := ValidateStatus(*u.Update.Status); err != nil {
+				return err
+			}
+		}
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Rows are applied in file order, a vulnerability matched by several rows ends with the last one
+	var changes statusChanges
+	for i := range updates {
+		if len(updates[i].VulnerabilityIDs) == 0 {
+			continue
+		}
+		rowChanges, err := r.bulkUpdate(ctx, tx, updates[i].VulnerabilityIDs, &updates[i].Update)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, rowChanges...)
+	}
+	if err := r.db.insertOutboxEvents(ctx, tx, nil, events); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.db.publishStatusChanges(changes)
+	return nil
+}
+
+// MatchTriageImport returns the vulnerabilities with the CVE, narrowed to a package and to the ones a scan of an
+// image found when they are not empty, and the ID of the image. The image name is registry/repository:tag
+func (r *VulnerabilityRepository) MatchTriageImport(ctx context.Context, cveID, packageName, imageName string) ([]int, *int, error) {
+	var imageID *int
+	if imageName != "" {
+		var id int
+		err := r.db.GetContext(ctx, &id, `SELECT id FROM images WHERE registry || '/' || repository || ':' || tag = $1`, imageName)
+		if err == sql.ErrNoRows {
+			return []int{}, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		imageID = &id
+	}
+
+	ids := []int{}
+	query := `
+		SELECT v.id FROM vulnerabilities v
+		WHERE v.cve_id = $1
+			AND ($2 = '' OR v.package_name = $2)
+			AND ($3::integer IS NULL OR EXISTS (
+				SELECT 1 FROM scan_vulnerabilities sv
+				JOIN scans s ON s.id = sv.scan_id
+				WHERE sv.vulnerability_id = v.id AND s.image_id = $3
+			))
+		ORDER BY v.id
+	`
+	if err := r.db.SelectContext(ctx, &ids, query, cveID, packageName, imageID); err != nil {
+		return nil, nil, err
+	}
+	return ids, imageID, nil
+}
+
+// bulkUpdate applies a change to vulnerabilities and records it in their history using the caller's transaction.
+// It returns the status changes, to publish once the transaction is committed
+func (r *VulnerabilityRepository) bulkUpdate(ctx context.Context, tx *sqlx.Tx, ids []int, update *models.VulnerabilityUpdateWithContext) (statusChanges, error) {
+	// Get current state for all vulnerabilities in one query (for audit trail)
+	// Rows are locked so concurrent updates can't interleave with the history we write
+	current := []models.Vulnerability{}
+	selectQuery := `SELECT * FROM vulnerabilities WHERE id = ANY($1) ORDER BY id FOR UPDATE`
+	if err := tx.SelectContext(ctx, &current, selectQuery, pq.Array(ids)); err != nil {
+		return nil, fmt.Errorf("failed to get vulnerabilities: %w", err)
+	}
+	if err := r.db.decryptVulnerabilities(current); err != nil {
+		return nil, err
+	}
+
+	found := make(map[int]bool, len(current))
+	for _, vuln := range current {
+		found[vuln.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return nil, notFound(fmt.Sprintf("vulnerability %d", id))
+		}
+	}
+
 	// Build dynamic update query
 	query := `UPDATE vulnerabilities SET updated_at = NOW()`
 	args := []interface{}{}
@@ -414,8 +899,12 @@ func (r *VulnerabilityRepository) BulkUpdate(ctx context.Context, ids []int, upd
 	}
 
 	if update.Notes != nil {
+		notes, err := r.db.encrypt(update.Notes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt notes: %w", err)
+		}
 		query += fmt.Sprintf(", notes = $%d", argCount)
-		args = append(args, *update.Notes)
+		args = append(args, *notes)
 		argCount++
 	}
 
@@ -428,34 +917,102 @@ func (r *VulnerabilityRepository) BulkUpdate(ctx context.Context, ids []int, upd
 	query += fmt.Sprintf(" WHERE id = ANY($%d)", argCount)
 	args = append(args, pq.Array(ids))
 
-	_, err := r.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return err
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, err
 	}
 
-	// Create audit trail entries for each vulnerability
-	for _, id := range ids {
-		current := currentStates[id]
-
-		if update.Status != nil && current.Status != *update.Status {
-			_ = r.CreateHistoryEntry(ctx, id, "status", &current.Status, update.Status, update.UpdatedBy, update.ImageID, update.ImageName)
-			// Ignore error - audit trail is best-effort
+	// Create audit trail entries for every changed field in one insert
+	history := historyBatch{}
+	var changes statusChanges
+	for i := range current {
+		vuln := &current[i]
+		if update.Status != nil && vuln.Status != *update.Status {
+			history.add(vuln.ID, "status", vuln.Status, *update.Status)
+			changes = append(changes, statusChange(vuln, *update.Status, update.UpdatedBy))
 		}
 
 		if update.Notes != nil {
 			oldNotes := ""
-			if current.Notes != nil {
-				oldNotes = *current.Notes
+			if vuln.Notes != nil {
+				oldNotes = *vuln.Notes
 			}
-			newNotes := *update.Notes
-			if oldNotes != newNotes {
-				_ = r.CreateHistoryEntry(ctx, id, "notes", &oldNotes, &newNotes, update.UpdatedBy, update.ImageID, update.ImageName)
-				// Ignore error - audit trail is best-effort
+			if oldNotes != *update.Notes {
+				// The audit trail holds the notes too, so it is encrypted like them
+				oldValue, err := r.db.encrypt(&oldNotes)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encrypt notes history: %w", err)
+				}
+				newValue, err := r.db.encrypt(update.Notes)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encrypt notes history: %w", err)
+				}
+				history.add(vuln.ID, "notes", *oldValue, *newValue)
 			}
 		}
 	}
 
-	return nil
+	if err := history.insert(ctx, tx, update.UpdatedBy, update.ImageID, update.ImageName); err != nil {
+		return nil, fmt.Errorf("failed to create history: %w", err)
+	}
+	return changes, nil
+}
+
+// statusChanges are published on the event bus once their transaction is committed
+type statusChanges []events.VulnerabilityStatusChange
+
+// statusChange is the event of a vulnerability changing to status
+func statusChange(vuln *models.Vulnerability, status, updatedBy string) events.VulnerabilityStatusChange {
+	return events.VulnerabilityStatusChange{
+		VulnerabilityID: vuln.ID,
+		CVEID:           vuln.CVEID,
+		PackageName:     vuln.PackageName,
+		OldStatus:       vuln.Status,
+		NewStatus:       status,
+		UpdatedBy:       updatedBy,
+	}
+}
+
+// publishStatusChanges publishes committed status changes on the event bus
+func (d *Database) publishStatusChanges(changes statusChanges) {
+	for _, change := range changes {
+		d.events.Publish(events.VulnerabilityStatusChanged, change)
+	}
+}
+
+// historyBatch collects vulnerability_history rows sharing the same author and image context
+// so they can be written with a single INSERT
+type historyBatch struct {
+	vulnerabilityIDs []int64
+	fieldNames       []string
+	oldValues        []string
+	newValues        []string
+}
+
+func (b *historyBatch) add(vulnerabilityID int, fieldName, oldValue, newValue string) {
+	b.vulnerabilityIDs = append(b.vulnerabilityIDs, int64(vulnerabilityID))
+	b.fieldNames = append(b.fieldNames, fieldName)
+	b.oldValues = append(b.oldValues, oldValue)
+	b.newValues = append(b.newValues, newValue)
+}
+
+func (b *historyBatch) insert(ctx context.Context, tx *sqlx.Tx, changedBy string, imageID *int, imageName *string) error {
+	if len(b.vulnerabilityIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO vulnerability_history (
+			vulnerability_id, field_name, old_value, new_value,
+			changed_by, changed_at, image_id, image_name
+		)
+		SELECT h.vulnerability_id, h.field_name, h.old_value, h.new_value, $5, NOW(), $6, $7
+		FROM unnest($1::int[], $2::text[], $3::text[], $4::text[])
+			AS h(vulnerability_id, field_name, old_value, new_value)
+	`
+	_, err := tx.ExecContext(ctx, query,
+		pq.Array(b.vulnerabilityIDs), pq.Array(b.fieldNames), pq.Array(b.oldValues), pq.Array(b.newValues),
+		changedBy, imageID, imageName)
+	return err
 }
 
 func (r *VulnerabilityRepository) MarkAsFixed(ctx context.Context, vulnerabilityIDs []int) error {
@@ -463,33 +1020,116 @@ func (r *VulnerabilityRepository) MarkAsFixed(ctx context.Context, vulnerability
 		return nil
 	}
 
-	// Get current state for audit trail
-	currentStates := make(map[int]string)
-	for _, id := range vulnerabilityIDs {
-		current, err := r.GetByID(ctx, id)
-		if err == nil && current.Status != models.StatusFixed {
-			currentStates[id] = current.Status
-		}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
+	// Update and capture the previous status in the same statement so the audit
+	// trail always matches the rows that actually changed
 	query := `
-		UPDATE vulnerabilities
+		UPDATE vulnerabilities v
 		SET status = 'fixed', remediation_date = $1, updated_at = NOW(), updated_by = 'system'
-		WHERE id = ANY($2) AND status != 'fixed'
+		FROM (
+			SELECT id, status FROM vulnerabilities
+			WHERE id = ANY($2) AND status != 'fixed'
+			FOR UPDATE
+		) old
+		WHERE v.id = old.id
+		RETURNING v.id, old.status
 	`
-	_, err := r.db.ExecContext(ctx, query, time.Now(), pq.Array(vulnerabilityIDs))
-	if err != nil {
+	changed := []struct {
+		ID     int    `db:"id"`
+		Status string `db:"status"`
+	}{}
+	if err := tx.SelectContext(ctx, &changed, query, time.Now(), pq.Array(vulnerabilityIDs)); err != nil {
 		return err
 	}
 
 	// Create audit entries for automatic fixes
-	for id, oldStatus := range currentStates {
-		newStatus := models.StatusFixed
-		_ = r.CreateHistoryEntry(ctx, id, "status", &oldStatus, &newStatus, "system", nil, nil)
-		// Ignore error - audit trail is best-effort
+	history := historyBatch{}
+	for _, row := range changed {
+		history.add(row.ID, "status", row.Status, models.StatusFixed)
+	}
+	if err := history.insert(ctx, tx, "system", nil, nil); err != nil {
+		return fmt.Errorf("failed to create history: %w", err)
 	}
 
-	return nil
+	return tx.Commit()
+}
+
+// RecordPresent records that a scan of an image target detected the vulnerabilities. Those that were
+// absent from earlier scans came back and count as a flap
+func (r *VulnerabilityRepository) RecordPresent(ctx context.Context, scanID, imageID int, target *string, vulnerabilityIDs []int) error {
+	if len(vulnerabilityIDs) == 0 {
+		return nil
+	}
+	query := `
+		UPDATE vulnerability_flaps
+		SET absent_scans = 0, flap_count = flap_count + 1, last_flapped_at = NOW(),
+			last_scan_id = $1, updated_at = NOW()
+		WHERE image_id = $2 AND target = $3 AND vulnerability_id = ANY($4)
+			AND absent_scans > 0 AND last_scan_id < $1
+	`
+	_, err := r.db.ExecContext(ctx, query, scanID, imageID, flapTarget(target), pq.Array(vulnerabilityIDs))
+	return err
+}
+
+// RecordAbsent records a scan of an image target trusted to prove the absence of the vulnerabilities
+// the previous scan detected. Vulnerabilities already absent from earlier scans stay absent unless
+// RecordPresent was called for them first. It returns the vulnerabilities this scan made absent from
+// confirmations consecutive scans, which can be marked fixed. Recording the same scan again is a no-op
+func (r *VulnerabilityRepository) RecordAbsent(ctx context.Context, scanID, imageID int, target *string, vulnerabilityIDs []int, confirmations int) ([]int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	still := `
+		UPDATE vulnerability_flaps
+		SET absent_scans = absent_scans + 1, last_scan_id = $1, updated_at = NOW()
+		WHERE image_id = $2 AND target = $3 AND absent_scans > 0 AND last_scan_id < $1
+	`
+	if _, err := tx.ExecContext(ctx, still, scanID, imageID, flapTarget(target)); err != nil {
+		return nil, fmt.Errorf("failed to record absent vulnerabilities: %w", err)
+	}
+
+	if len(vulnerabilityIDs) > 0 {
+		absent := `
+			INSERT INTO vulnerability_flaps (vulnerability_id, image_id, target, absent_scans, last_scan_id, updated_at)
+			SELECT id, $2, $3, 1, $1, NOW() FROM UNNEST($4::int[]) AS id
+			ON CONFLICT (vulnerability_id, image_id, target) DO UPDATE SET
+				absent_scans = vulnerability_flaps.absent_scans + 1,
+				last_scan_id = EXCLUDED.last_scan_id,
+				updated_at = NOW()
+			WHERE vulnerability_flaps.last_scan_id < EXCLUDED.last_scan_id
+		`
+		if _, err := tx.ExecContext(ctx, absent, scanID, imageID, flapTarget(target), pq.Array(vulnerabilityIDs)); err != nil {
+			return nil, fmt.Errorf("failed to record absent vulnerabilities: %w", err)
+		}
+	}
+
+	confirmed := []int{}
+	query := `
+		SELECT vulnerability_id FROM vulnerability_flaps
+		WHERE image_id = $1 AND target = $2 AND last_scan_id = $3 AND absent_scans = $4
+		ORDER BY vulnerability_id
+	`
+	if err := tx.SelectContext(ctx, &confirmed, query, imageID, flapTarget(target), scanID, confirmations); err != nil {
+		return nil, fmt.Errorf("failed to list confirmed absences: %w", err)
+	}
+
+	return confirmed, tx.Commit()
+}
+
+// flapTarget is the target key of flap records, scans of the whole image have none
+func flapTarget(target *string) string {
+	if target == nil {
+		return ""
+	}
+	return *target
 }
 
 func (r *VulnerabilityRepository) LinkToScan(ctx context.Context, scanID, vulnerabilityID int) error {
@@ -511,6 +1151,9 @@ func (r *VulnerabilityRepository) GetByUniqueKey(ctx context.Context, cveID, pac
 		}
 		return nil, err
 	}
+	if err := r.db.decrypt(vuln.Notes); err != nil {
+		return nil, fmt.Errorf("failed to decrypt notes: %w", err)
+	}
 	return &vuln, nil
 }
 
@@ -522,6 +1165,16 @@ func (r *VulnerabilityRepository) CreateHistoryEntry(ctx context.Context, vulner
 		)
 		VALUES ($1, $2, $3, $4, $5, NOW(), $6, $7)
 	`
+	if fieldName == "notes" {
+		// The audit trail holds the notes too, so it is encrypted like them
+		var err error
+		if oldValue, err = r.db.encrypt(oldValue); err != nil {
+			return fmt.Errorf("failed to encrypt notes history: %w", err)
+		}
+		if newValue, err = r.db.encrypt(newValue); err != nil {
+			return fmt.Errorf("failed to encrypt notes history: %w", err)
+		}
+	}
 	_, err := r.db.ExecContext(ctx, query,
 		vulnerabilityID, fieldName, oldValue, newValue,
 		changedBy, imageID, imageName)
@@ -543,9 +1196,56 @@ func (r *VulnerabilityRepository) GetHistory(ctx context.Context, vulnerabilityI
 	if history == nil {
 		history = []models.VulnerabilityHistory{}
 	}
+	for i := range history {
+		if history[i].FieldName != "notes" {
+			continue
+		}
+		if err := r.db.decrypt(history[i].OldValue); err != nil {
+			return nil, fmt.Errorf("failed to decrypt notes history: %w", err)
+		}
+		if err := r.db.decrypt(history[i].NewValue); err != nil {
+			return nil, fmt.Errorf("failed to decrypt notes history: %w", err)
+		}
+	}
 	return history, nil
 }
 
+// RecordSeverityChange records a scan reporting another severity for a vulnerability, with the build
+// of the Grype DB that scan used
+func (r *VulnerabilityRepository) RecordSeverityChange(ctx context.Context, scanID, vulnerabilityID int, cveID, oldSeverity, newSeverity string) error {
+	query := `
+		INSERT INTO vulnerability_severity_changes (
+			vulnerability_id, cve_id, old_severity, new_severity, scan_id, grype_db_built, changed_at
+		)
+		SELECT $1, $2, $3, $4, s.id, s.grype_db_built, NOW()
+		FROM scans s WHERE s.id = $5
+	`
+	_, err := r.db.ExecContext(ctx, query, vulnerabilityID, cveID, oldSeverity, newSeverity, scanID)
+	return err
+}
+
+// ListSeverityChanges returns the severity changes of the vulnerabilities of a CVE, latest first
+func (r *VulnerabilityRepository) ListSeverityChanges(ctx context.Context, cveID string) ([]models.SeverityChange, error) {
+	changes := []models.SeverityChange{}
+	query := `
+		SELECT
+			c.id, c.vulnerability_id, c.cve_id, v.package_name, v.package_version,
+			COALESCE(c.old_severity, '') AS old_severity, COALESCE(c.new_severity, '') AS new_severity,
+			c.scan_id, i.registry || '/' || i.repository || ':' || i.tag AS image_name,
+			c.grype_db_built, c.changed_at
+		FROM vulnerability_severity_changes c
+		JOIN vulnerabilities v ON v.id = c.vulnerability_id
+		LEFT JOIN scans s ON s.id = c.scan_id
+		LEFT JOIN images i ON i.id = s.image_id
+		WHERE c.cve_id = $1
+		ORDER BY c.changed_at DESC, c.id DESC
+	`
+	if err := r.db.SelectContext(ctx, &changes, query, cveID); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
 // GetImageScanInfoForWebhook retrieves ImageScan context for webhook notification
 func (r *VulnerabilityRepository) GetImageScanInfoForWebhook(ctx context.Context, vulnID int) (*models.ImageScanContext, error) {
 	var namespace, name sql.NullString
@@ -569,6 +1269,53 @@ func (r *VulnerabilityRepository) GetImageScanInfoForWebhook(ctx context.Context
 	}, nil
 }
 
+// ListFixAvailableTargets returns, for findings that just gained a fix version, the ImageScans to
+// notify: those with fix-available notifications enabled whose image has the open finding in the
+// latest successful scan of a target. Each target also counts every image with an open fixable
+// finding of the same CVE, whichever package version and ImageScan
+func (r *VulnerabilityRepository) ListFixAvailableTargets(ctx context.Context, vulnerabilityIDs []int) ([]models.FixAvailableTarget, error) {
+	query := `
+		WITH latest AS (
+			SELECT DISTINCT ON (image_id, target) id, image_id, imagescan_namespace, imagescan_name
+			FROM scans
+			WHERE status IN ('completed', 'partial')
+			ORDER BY image_id, target, scan_date DESC
+		),
+		fixable AS (
+			SELECT DISTINCT v.id as vulnerability_id, v.cve_id, l.image_id, l.imagescan_namespace, l.imagescan_name
+			FROM latest l
+			JOIN scan_vulnerabilities sv ON sv.scan_id = l.id
+			JOIN vulnerabilities v ON v.id = sv.vulnerability_id
+			WHERE v.cve_id IN (SELECT cve_id FROM vulnerabilities WHERE id = ANY($1))
+				AND v.fix_version IS NOT NULL
+				AND v.status IN ('active', 'in_progress')
+		),
+		affected AS (
+			SELECT cve_id, COUNT(DISTINCT image_id) as affected_images
+			FROM fixable
+			GROUP BY cve_id
+		)
+		SELECT
+			w.namespace, w.name,
+			v.id as vulnerability_id, v.cve_id, v.package_name, v.package_version, v.severity, v.fix_version,
+			ARRAY_AGG(DISTINCT i.registry || '/' || i.repository || ':' || i.tag) as images,
+			a.affected_images
+		FROM fixable f
+		JOIN vulnerabilities v ON v.id = f.vulnerability_id
+		JOIN images i ON i.id = f.image_id
+		JOIN affected a ON a.cve_id = f.cve_id
+		JOIN imagescan_webhook_configs w ON w.namespace = f.imagescan_namespace AND w.name = f.imagescan_name
+		WHERE f.vulnerability_id = ANY($1) AND w.fix_available_enabled
+		GROUP BY w.namespace, w.name, v.id, a.affected_images
+		ORDER BY w.namespace, w.name, v.cve_id, v.package_name, v.package_version
+	`
+	targets := []models.FixAvailableTarget{}
+	if err := r.db.SelectContext(ctx, &targets, query, pq.Array(vulnerabilityIDs)); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
 // GetImageNameForVulnerability retrieves a representative image name for a vulnerability
 func (r *VulnerabilityRepository) GetImageNameForVulnerability(ctx context.Context, vulnID int) (string, error) {
 	var imageName string
@@ -587,3 +1334,79 @@ func (r *VulnerabilityRepository) GetImageNameForVulnerability(ctx context.Conte
 	}
 	return imageName, nil
 }
+
+// GetSLADeadline returns the SLA deadline of a vulnerability on the image it was most recently
+// found on, with the SLA and timezone of that image's latest scan
+func (r *VulnerabilityRepository) GetSLADeadline(ctx context.Context, vulnID int) (*sla.Deadline, error) {
+	var v models.VulnerabilityWithImageInfo
+	query := `
+		SELECT v.severity, v.initial_severity,
+			(SELECT MIN(fs.scan_date)
+			 FROM scans fs
+			 JOIN scan_vulnerabilities fsv ON fsv.scan_id = fs.id
+			 WHERE fsv.vulnerability_id = v.id AND fs.image_id = s.image_id) as first_detected_at_for_image,
+			s.sla_critical, s.sla_high, s.sla_medium, s.sla_low, s.sla_time_zone,
+			s.sla_business_days, s.sla_holidays
+		FROM vulnerabilities v
+		JOIN scan_vulnerabilities sv ON sv.vulnerability_id = v.id
+		JOIN scans s ON s.id = sv.scan_id
+		WHERE v.id = $1
+		ORDER BY s.created_at DESC
+		LIMIT 1
+	`
+	if err := r.db.GetContext(ctx, &v, query, vulnID); err != nil {
+		return nil, err
+	}
+	setSLADeadline(&v, r.db.slaSeverity)
+	return &sla.Deadline{DueDate: v.SLADueDate, DueAt: *v.SLADueAt}, nil
+}
+
+// ApplyTicketCallback applies the status of a ticket callback to its vulnerabilities and records the callback,
+// in one transaction. A callback whose event was already recorded is not applied: callback is replaced with the
+// recorded one and it returns true
+func (r *VulnerabilityRepository) ApplyTicketCallback(ctx context.Context, callback *models.TicketCallback, update *models.VulnerabilityUpdateWithContext, events ...*models.OutboxEvent) (bool, error) {
+	if err := ValidateStatus(callback.Status); err != nil {
+		return false, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &callback.ReceivedAt, `
+		INSERT INTO ticket_callbacks (event_id, ticket_id, ticket_status, status, vulnerability_ids)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (event_id) DO NOTHING
+		RETURNING received_at
+	`, callback.EventID, callback.TicketID, callback.TicketStatus, callback.Status, callback.VulnerabilityIDs)
+	if err == sql.ErrNoRows {
+		if err := tx.GetContext(ctx, callback, `SELECT * FROM ticket_callbacks WHERE event_id = $1`, callback.EventID); err != nil {
+			return false, fmt.Errorf("failed to get ticket callback %s: %w", callback.EventID, err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record ticket callback: %w", err)
+	}
+
+	ids := make([]int, len(callback.VulnerabilityIDs))
+	for i, id := range callback.VulnerabilityIDs {
+		ids[i] = int(id)
+	}
+	update.Status = &callback.Status
+	changes, err := r.bulkUpdate(ctx, tx, ids, update)
+	if err != nil {
+		return false, err
+	}
+	if err := r.db.insertOutboxEvents(ctx, tx, nil, events); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	r.db.publishStatusChanges(changes)
+	return false, nil
+}
@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/sla"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -140,6 +142,51 @@ func TestVulnerabilityRepository_List_FilterBySeverity(t *testing.T) {
 	assert.Equal(t, "Critical", list[0].Severity)
 }
 
+func TestVulnerabilityRepository_Search(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewVulnerabilityRepository(db)
+	ctx := context.Background()
+
+	str := func(s string) *string { return &s }
+	now := time.Now()
+	for _, vuln := range []*models.Vulnerability{
+		{CVEID: "CVE-2024-0001", PackageName: "libssl3", PackageVersion: "3.0.2", Severity: "Critical", Status: "active",
+			Description: str("Remote code execution in the TLS handshake"), FirstDetectedAt: now.AddDate(0, 0, -2), LastSeenAt: now},
+		{CVEID: "CVE-2024-0002", PackageName: "openssl", PackageVersion: "1.1.1", Severity: "Medium", Status: "active",
+			Description: str("Denial of service when parsing certificates"), FirstDetectedAt: now.AddDate(0, 0, -40), LastSeenAt: now},
+		{CVEID: "CVE-2023-0003", PackageName: "curl", PackageVersion: "7.74.0", Severity: "Low", Status: "active",
+			Description: str("Remote attackers can execute code through crafted URLs"), FirstDetectedAt: now.AddDate(0, 0, -2), LastSeenAt: now},
+	} {
+		require.NoError(t, repo.Upsert(ctx, vuln))
+	}
+
+	search := func(s models.VulnerabilitySearch) []string {
+		t.Helper()
+		vulns, err := repo.Search(ctx, &s, 10, 0)
+		require.NoError(t, err)
+		count, err := repo.CountSearch(ctx, &s)
+		require.NoError(t, err)
+		assert.Equal(t, len(vulns), count)
+		ids := []string{}
+		for _, v := range vulns {
+			ids = append(ids, v.CVEID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"CVE-2024-0001", "CVE-2024-0002"}, search(models.VulnerabilitySearch{PackageName: str("SSL")}))
+	assert.Equal(t, []string{"CVE-2024-0001", "CVE-2024-0002"}, search(models.VulnerabilitySearch{CVEPrefix: str("cve-2024-")}))
+	assert.Equal(t, []string{"CVE-2024-0001", "CVE-2023-0003"}, search(models.VulnerabilitySearch{Text: str("remote code execution")}))
+	assert.Equal(t, []string{"CVE-2024-0002", "CVE-2023-0003"}, search(models.VulnerabilitySearch{Severities: []string{"Medium", "Low"}}))
+
+	weekAgo := now.AddDate(0, 0, -7)
+	assert.Equal(t, []string{"CVE-2024-0002"}, search(models.VulnerabilitySearch{DetectedBefore: &weekAgo}))
+	assert.Equal(t, []string{"CVE-2024-0001"}, search(models.VulnerabilitySearch{DetectedAfter: &weekAgo, PackageName: str("ssl")}))
+	assert.Empty(t, search(models.VulnerabilitySearch{Text: str("remote"), Severities: []string{"Medium"}}))
+}
+
 func TestVulnerabilityRepository_List_FilterByStatus(t *testing.T) {
 	db := SetupTestDatabase(t)
 	defer db.Close()
@@ -313,6 +360,114 @@ func TestVulnerabilityRepository_MarkAsFixed(t *testing.T) {
 	assert.Equal(t, "system", *history[0].ChangedBy)
 }
 
+func TestVulnerabilityRepository_MarkAsFixed_AlreadyFixedHasNoNewHistory(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewVulnerabilityRepository(db)
+
+	vuln := &models.Vulnerability{
+		CVEID:           "CVE-2023-0003",
+		PackageName:     "openssl",
+		PackageVersion:  "3.0.0",
+		Severity:        "High",
+		Status:          "in_progress",
+		FirstDetectedAt: time.Now(),
+		LastSeenAt:      time.Now(),
+	}
+	require.NoError(t, repo.Upsert(context.Background(), vuln))
+
+	require.NoError(t, repo.MarkAsFixed(context.Background(), []int{vuln.ID}))
+	// Second call must not touch the row or duplicate the audit entry
+	require.NoError(t, repo.MarkAsFixed(context.Background(), []int{vuln.ID}))
+
+	history, err := repo.GetHistory(context.Background(), vuln.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "in_progress", *history[0].OldValue)
+	assert.Equal(t, "fixed", *history[0].NewValue)
+}
+
+func TestVulnerabilityRepository_BulkUpdate(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewVulnerabilityRepository(db)
+
+	var ids []int
+	for i, pkg := range []string{"openssl", "curl", "zlib"} {
+		vuln := &models.Vulnerability{
+			CVEID:           "CVE-2023-100" + string(rune('0'+i)),
+			PackageName:     pkg,
+			PackageVersion:  "1.0.0",
+			Severity:        "High",
+			Status:          "active",
+			FirstDetectedAt: time.Now(),
+			LastSeenAt:      time.Now(),
+		}
+		require.NoError(t, repo.Upsert(context.Background(), vuln))
+		ids = append(ids, vuln.ID)
+	}
+
+	status := models.StatusAccepted
+	notes := "accepted for legacy system"
+	err := repo.BulkUpdate(context.Background(), ids, &models.VulnerabilityUpdateWithContext{
+		Status:    &status,
+		Notes:     &notes,
+		UpdatedBy: "test-user",
+	})
+	require.NoError(t, err)
+
+	for _, id := range ids {
+		retrieved, err := repo.GetByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusAccepted, retrieved.Status)
+		assert.NotNil(t, retrieved.RemediationDate)
+
+		// One status and one notes entry per vulnerability
+		history, err := repo.GetHistory(context.Background(), id)
+		require.NoError(t, err)
+		assert.Len(t, history, 2)
+		for _, entry := range history {
+			assert.Equal(t, "test-user", *entry.ChangedBy)
+		}
+	}
+}
+
+func TestVulnerabilityRepository_BulkUpdate_MissingIDRollsBack(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewVulnerabilityRepository(db)
+
+	vuln := &models.Vulnerability{
+		CVEID:           "CVE-2023-2000",
+		PackageName:     "openssl",
+		PackageVersion:  "1.1.1",
+		Severity:        "Critical",
+		Status:          "active",
+		FirstDetectedAt: time.Now(),
+		LastSeenAt:      time.Now(),
+	}
+	require.NoError(t, repo.Upsert(context.Background(), vuln))
+
+	status := models.StatusIgnored
+	err := repo.BulkUpdate(context.Background(), []int{vuln.ID, 999999}, &models.VulnerabilityUpdateWithContext{
+		Status:    &status,
+		UpdatedBy: "test-user",
+	})
+	require.Error(t, err)
+
+	// Nothing was applied
+	retrieved, err := repo.GetByID(context.Background(), vuln.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, retrieved.Status)
+
+	history, err := repo.GetHistory(context.Background(), vuln.ID)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
 func TestVulnerabilityRepository_LinkToScan(t *testing.T) {
 	db := SetupTestDatabase(t)
 	defer db.Close()
@@ -402,3 +557,566 @@ func TestVulnerabilityRepository_GetByUniqueKey_NotFound(t *testing.T) {
 	require.NoError(t, err, "GetByUniqueKey should not return error when vulnerability doesn't exist")
 	assert.Nil(t, retrieved, "GetByUniqueKey should return nil when vulnerability doesn't exist")
 }
+
+func TestVulnerabilityRepository_GetByIDs(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	repo := NewVulnerabilityRepository(db)
+
+	var ids []int
+	for _, pkg := range []string{"openssl", "curl"} {
+		vuln := &models.Vulnerability{
+			CVEID:           "CVE-2023-3000",
+			PackageName:     pkg,
+			PackageVersion:  "1.0.0",
+			Severity:        "High",
+			Status:          "active",
+			FirstDetectedAt: time.Now(),
+			LastSeenAt:      time.Now(),
+		}
+		require.NoError(t, repo.Upsert(context.Background(), vuln))
+		ids = append(ids, vuln.ID)
+	}
+
+	vulns, err := repo.GetByIDs(context.Background(), []int{ids[1], 999999, ids[0]})
+	require.NoError(t, err)
+	require.Len(t, vulns, 2)
+	assert.Equal(t, ids[0], vulns[0].ID)
+	assert.Equal(t, ids[1], vulns[1].ID)
+}
+
+func TestVulnerabilityRepository_ListWithImageInfo_SLADeadline(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	vulnRepo := NewVulnerabilityRepository(db)
+	scanRepo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+	ctx := context.Background()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	// Monday 23:30 in Berlin, already Tuesday in UTC+9
+	scanDate := time.Date(2026, 3, 2, 22, 30, 0, 0, time.UTC)
+	scan := &models.Scan{
+		ImageID:     image.ID,
+		ScanDate:    scanDate,
+		Status:      "completed",
+		SLACritical: 7,
+		SLAHigh:     30,
+		SLAMedium:   90,
+		SLALow:      180,
+		SLATimeZone: "Europe/Berlin",
+	}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+
+	vuln := &models.Vulnerability{
+		CVEID:           "CVE-2023-1234",
+		PackageName:     "openssl",
+		PackageVersion:  "1.1.1",
+		Severity:        "Critical",
+		Status:          "active",
+		FirstDetectedAt: scanDate,
+		LastSeenAt:      scanDate,
+	}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+
+	vulns, err := vulnRepo.ListWithImageInfo(ctx, 10, 0, Sort{}, nil, nil, nil, &image.ID, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, vulns, 1)
+	assert.Equal(t, "Europe/Berlin", vulns[0].SLATimeZone)
+	assert.Equal(t, "2026-03-09", vulns[0].SLADueDate)
+	require.NotNil(t, vulns[0].SLADueAt)
+	assert.True(t, vulns[0].SLADueAt.Equal(time.Date(2026, 3, 9, 23, 0, 0, 0, time.UTC)))
+
+	deadline, err := vulnRepo.GetSLADeadline(ctx, vuln.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-09", deadline.DueDate)
+	assert.Equal(t, "Europe/Berlin", deadline.DueAt.Location().String())
+}
+
+func TestVulnerabilityRepository_ListWithImageInfo_Sort(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	vulnRepo := NewVulnerabilityRepository(db)
+	scanRepo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+	ctx := context.Background()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: "completed"}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+
+	for i, v := range []struct{ pkg, severity string }{{"zlib", "Low"}, {"openssl", "Critical"}, {"curl", "Medium"}} {
+		vuln := &models.Vulnerability{
+			CVEID:           fmt.Sprintf("CVE-2024-%d", i),
+			PackageName:     v.pkg,
+			PackageVersion:  "1.0",
+			Severity:        v.severity,
+			Status:          "active",
+			FirstDetectedAt: scan.ScanDate,
+			LastSeenAt:      scan.ScanDate.Add(time.Duration(i) * time.Hour),
+		}
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+	}
+
+	packages := func(sort Sort) []string {
+		vulns, err := vulnRepo.ListWithImageInfo(ctx, 10, 0, sort, nil, nil, nil, &image.ID, nil, nil, nil, nil)
+		require.NoError(t, err)
+		names := []string{}
+		for _, v := range vulns {
+			names = append(names, v.PackageName)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"openssl", "curl", "zlib"}, packages(Sort{Field: "severity"}))
+	assert.Equal(t, []string{"zlib", "curl", "openssl"}, packages(Sort{Field: "severity", Order: "asc"}))
+	assert.Equal(t, []string{"curl", "openssl", "zlib"}, packages(Sort{Field: "package_name"}))
+	assert.Equal(t, []string{"curl", "openssl", "zlib"}, packages(Sort{Field: "last_seen_at"}))
+
+	_, err := vulnRepo.ListWithImageInfo(ctx, 10, 0, Sort{Field: "cvss"}, nil, nil, nil, nil, nil, nil, nil, nil)
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestVulnerabilityRepository_SeverityChange(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	vulnRepo := NewVulnerabilityRepository(db)
+	scanRepo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+	ctx := context.Background()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	firstDate := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	dbBuilt := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	first := &models.Scan{ImageID: image.ID, ScanDate: firstDate, Status: "completed", SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180}
+	second := &models.Scan{ImageID: image.ID, ScanDate: firstDate.AddDate(0, 0, 10), Status: "completed", GrypeDBBuilt: &dbBuilt, SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180}
+	require.NoError(t, scanRepo.Create(ctx, first))
+	require.NoError(t, scanRepo.Create(ctx, second))
+
+	vuln := &models.Vulnerability{
+		CVEID:           "CVE-2023-1234",
+		PackageName:     "openssl",
+		PackageVersion:  "1.1.1",
+		Severity:        "Medium",
+		Status:          "active",
+		FirstDetectedAt: firstDate,
+		LastSeenAt:      firstDate,
+	}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	require.NoError(t, vulnRepo.LinkToScan(ctx, first.ID, vuln.ID))
+	assert.Equal(t, "Medium", vuln.InitialSeverity)
+
+	// The second scan's Grype DB rescored the CVE
+	vuln.Severity = "Critical"
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	require.NoError(t, vulnRepo.LinkToScan(ctx, second.ID, vuln.ID))
+	require.NoError(t, vulnRepo.RecordSeverityChange(ctx, second.ID, vuln.ID, vuln.CVEID, "Medium", "Critical"))
+	assert.Equal(t, "Medium", vuln.InitialSeverity)
+
+	// The deadline stays the one of the severity at first detection
+	vulns, err := vulnRepo.ListWithImageInfo(ctx, 10, 0, Sort{}, nil, nil, nil, &image.ID, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, vulns, 1)
+	assert.Equal(t, "Critical", vulns[0].Severity)
+	assert.Equal(t, "Medium", vulns[0].SLASeverity)
+	assert.Equal(t, "2026-05-31", vulns[0].SLADueDate)
+
+	db.SetSLASeverityPolicy(sla.SeverityCurrent)
+	vulns, err = vulnRepo.ListWithImageInfo(ctx, 10, 0, Sort{}, nil, nil, nil, &image.ID, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, vulns, 1)
+	assert.Equal(t, "Critical", vulns[0].SLASeverity)
+	assert.Equal(t, "2026-03-09", vulns[0].SLADueDate)
+
+	changes, err := vulnRepo.ListSeverityChanges(ctx, "CVE-2023-1234")
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "Medium", changes[0].OldSeverity)
+	assert.Equal(t, "Critical", changes[0].NewSeverity)
+	assert.Equal(t, "openssl", changes[0].PackageName)
+	require.NotNil(t, changes[0].ScanID)
+	assert.Equal(t, second.ID, *changes[0].ScanID)
+	require.NotNil(t, changes[0].ImageName)
+	assert.Equal(t, "docker.io/library/nginx:latest", *changes[0].ImageName)
+	require.NotNil(t, changes[0].GrypeDBBuilt)
+	assert.True(t, changes[0].GrypeDBBuilt.Equal(dbBuilt))
+
+	changes, err = vulnRepo.ListSeverityChanges(ctx, "CVE-2099-0001")
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestVulnerabilityRepository_ListWithImageInfo_BusinessDaySLA(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	vulnRepo := NewVulnerabilityRepository(db)
+	scanRepo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+	ctx := context.Background()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	// Thursday before Christmas, two holidays and a weekend in between
+	scanDate := time.Date(2026, 12, 24, 9, 0, 0, 0, time.UTC)
+	scan := &models.Scan{
+		ImageID:         image.ID,
+		ScanDate:        scanDate,
+		Status:          "completed",
+		SLACritical:     2,
+		SLAHigh:         30,
+		SLAMedium:       90,
+		SLALow:          180,
+		SLABusinessDays: true,
+		SLAHolidays:     []string{"2026-12-25", "2026-12-28"},
+	}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+
+	vuln := &models.Vulnerability{
+		CVEID:           "CVE-2023-1234",
+		PackageName:     "openssl",
+		PackageVersion:  "1.1.1",
+		Severity:        "Critical",
+		Status:          "active",
+		FirstDetectedAt: scanDate,
+		LastSeenAt:      scanDate,
+	}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+
+	vulns, err := vulnRepo.ListWithImageInfo(ctx, 10, 0, Sort{}, nil, nil, nil, &image.ID, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, vulns, 1)
+	assert.True(t, vulns[0].SLABusinessDays)
+	assert.Equal(t, "2026-12-30", vulns[0].SLADueDate)
+}
+
+func TestVulnerabilityRepository_FixBecameAvailable(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	vulnRepo := NewVulnerabilityRepository(db)
+	scanRepo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+	webhookRepo := NewWebhookConfigRepository(db)
+	ctx := context.Background()
+
+	namespace, name := "payments", "api"
+	require.NoError(t, webhookRepo.Upsert(ctx, namespace, name, &models.WebhookConfigRequest{
+		WebhookURL: "https://hooks.example.com/a", WebhookFormat: "slack", ScanMinSeverity: "High",
+		StatusChangeMinSeverity: "High", StatusChangeTransitions: []string{},
+		FixAvailableEnabled: true, FixAvailableMinSeverity: "High", Locale: "en",
+	}))
+
+	// Two images have the finding, one of them scanned by the ImageScan
+	vuln := &models.Vulnerability{
+		CVEID: "CVE-2024-5535", PackageName: "openssl", PackageVersion: "3.0.13", Severity: "High",
+		Status: "active", FirstDetectedAt: time.Now(), LastSeenAt: time.Now(),
+	}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	assert.Nil(t, vuln.FixBecameAvailableAt)
+
+	for i, tag := range []string{"v1", "v2"} {
+		image := &models.Image{Registry: "docker.io", Repository: "acme/api", Tag: tag}
+		require.NoError(t, imageRepo.Create(ctx, image))
+		scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: "completed", SLACritical: 7, SLAHigh: 30, SLAMedium: 90, SLALow: 180}
+		if i == 0 {
+			scan.ImageScanNamespace, scan.ImageScanName = &namespace, &name
+		}
+		require.NoError(t, scanRepo.Create(ctx, scan))
+		require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+	}
+
+	fix := "3.0.14"
+	vuln.FixVersion = &fix
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	require.NotNil(t, vuln.FixBecameAvailableAt)
+	first := *vuln.FixBecameAvailableAt
+
+	// Seeing the fix again keeps the time it became available
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	assert.True(t, first.Equal(*vuln.FixBecameAvailableAt))
+
+	targets, err := vulnRepo.ListFixAvailableTargets(ctx, []int{vuln.ID})
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, "payments", targets[0].Namespace)
+	assert.Equal(t, "3.0.14", targets[0].FixVersion)
+	assert.Equal(t, 2, targets[0].AffectedImages)
+	assert.Equal(t, []string{"docker.io/acme/api:v1"}, []string(targets[0].Images))
+}
+
+func TestVulnerabilityRepository_RecordFlaps(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	vulnRepo := NewVulnerabilityRepository(db)
+	scanRepo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+	ctx := context.Background()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: "completed"}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+
+	vuln := &models.Vulnerability{
+		CVEID:           "CVE-2023-1234",
+		PackageName:     "openssl",
+		PackageVersion:  "1.1.1",
+		Severity:        "High",
+		Status:          "active",
+		FirstDetectedAt: time.Now(),
+		LastSeenAt:      time.Now(),
+	}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+	require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+	ids := []int{vuln.ID}
+
+	// Absent once: not confirmed yet, and recording the same scan again changes nothing
+	confirmed, err := vulnRepo.RecordAbsent(ctx, 2, image.ID, nil, ids, 2)
+	require.NoError(t, err)
+	assert.Empty(t, confirmed)
+	confirmed, err = vulnRepo.RecordAbsent(ctx, 2, image.ID, nil, ids, 2)
+	require.NoError(t, err)
+	assert.Empty(t, confirmed)
+
+	// Back before being confirmed: one flap, and the absences start over
+	require.NoError(t, vulnRepo.RecordPresent(ctx, 3, image.ID, nil, ids))
+	confirmed, err = vulnRepo.RecordAbsent(ctx, 4, image.ID, nil, ids, 2)
+	require.NoError(t, err)
+	assert.Empty(t, confirmed)
+
+	// Still absent from the next scan, which doesn't report it as newly absent
+	confirmed, err = vulnRepo.RecordAbsent(ctx, 5, image.ID, nil, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, ids, confirmed)
+
+	// Scans of another target are tracked apart
+	target := "/usr/local/bin/app"
+	confirmed, err = vulnRepo.RecordAbsent(ctx, 6, image.ID, &target, ids, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ids, confirmed)
+
+	flapping := true
+	vulns, err := vulnRepo.ListWithImageInfo(ctx, 10, 0, Sort{}, nil, nil, nil, &image.ID, nil, nil, &flapping, nil)
+	require.NoError(t, err)
+	assert.Empty(t, vulns)
+
+	require.NoError(t, vulnRepo.RecordPresent(ctx, 7, image.ID, nil, ids))
+	vulns, err = vulnRepo.ListWithImageInfo(ctx, 10, 0, Sort{}, nil, nil, nil, &image.ID, nil, nil, &flapping, nil)
+	require.NoError(t, err)
+	require.Len(t, vulns, 1)
+	assert.Equal(t, 2, vulns[0].FlapCount)
+	count, err := vulnRepo.CountWithImageInfo(ctx, nil, nil, nil, &image.ID, nil, nil, &flapping, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	flapping = false
+	count, err = vulnRepo.CountWithImageInfo(ctx, nil, nil, nil, &image.ID, nil, nil, &flapping, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestVulnerabilityRepository_ListWithImageInfoAsOf(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	vulnRepo := NewVulnerabilityRepository(db)
+	scanRepo := NewScanRepository(db)
+	imageRepo := NewImageRepository(db)
+	ctx := context.Background()
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+
+	first := &models.Scan{ImageID: image.ID, ScanDate: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC), Status: "completed"}
+	require.NoError(t, scanRepo.Create(ctx, first))
+	second := &models.Scan{ImageID: image.ID, ScanDate: time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC), Status: "completed"}
+	require.NoError(t, scanRepo.Create(ctx, second))
+
+	openssl := &models.Vulnerability{CVEID: "CVE-2024-0001", PackageName: "openssl", PackageVersion: "3.0.0", Severity: "Critical", Status: "active", FirstDetectedAt: first.ScanDate, LastSeenAt: first.ScanDate}
+	curl := &models.Vulnerability{CVEID: "CVE-2024-0002", PackageName: "curl", PackageVersion: "8.0.0", Severity: "High", Status: "active", FirstDetectedAt: first.ScanDate, LastSeenAt: second.ScanDate}
+	for _, v := range []*models.Vulnerability{openssl, curl} {
+		require.NoError(t, vulnRepo.Upsert(ctx, v))
+		require.NoError(t, vulnRepo.LinkToScan(ctx, first.ID, v.ID))
+	}
+	require.NoError(t, vulnRepo.LinkToScan(ctx, second.ID, curl.ID))
+	require.NoError(t, vulnRepo.MarkAsFixed(ctx, []int{openssl.ID}))
+
+	// Before the second scan both were found, and openssl wasn't fixed yet
+	juneFirst := time.Date(2024, 6, 1, 23, 59, 59, 0, time.UTC)
+	vulns, err := vulnRepo.ListWithImageInfoAsOf(ctx, juneFirst, 10, 0, Sort{}, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, vulns, 2)
+	for _, v := range vulns {
+		assert.Equal(t, models.StatusActive, v.Status)
+		assert.Equal(t, first.ID, v.LatestScanID)
+		assert.Nil(t, v.RemediationDate)
+	}
+
+	active := models.StatusActive
+	count, err := vulnRepo.CountWithImageInfoAsOf(ctx, juneFirst, nil, &active, &image.ID, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Now: only the second scan counts, and openssl is fixed
+	vulns, err = vulnRepo.ListWithImageInfoAsOf(ctx, time.Now(), 10, 0, Sort{}, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, vulns, 1)
+	assert.Equal(t, curl.ID, vulns[0].ID)
+	assert.Equal(t, second.ID, vulns[0].LatestScanID)
+
+	// Nothing was scanned yet
+	count, err = vulnRepo.CountWithImageInfoAsOf(ctx, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	images, err := imageRepo.ListAsOf(ctx, juneFirst, 10, 0, Sort{})
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	assert.Equal(t, 1, images[0].ScanCount)
+	assert.Equal(t, 1, images[0].CriticalCount)
+	assert.Equal(t, 1, images[0].HighCount)
+	total, err := imageRepo.CountAsOf(ctx, juneFirst)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func TestVulnerabilityRepository_ImportTriage(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	scan := &models.Scan{ImageID: image.ID, ScanDate: time.Now(), Status: "completed"}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+
+	newVuln := func(pkg, version string) *models.Vulnerability {
+		vuln := &models.Vulnerability{
+			CVEID: "CVE-2024-0001", PackageName: pkg, PackageVersion: version, Severity: "High",
+			Status: "active", FirstDetectedAt: time.Now(), LastSeenAt: time.Now(),
+		}
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		return vuln
+	}
+	onImage, openssl, zlib := newVuln("openssl", "3.0.7"), newVuln("openssl", "3.0.8"), newVuln("zlib", "1.2.11")
+	require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, onImage.ID))
+
+	ids, imageID, err := vulnRepo.MatchTriageImport(ctx, "CVE-2024-0001", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, []int{onImage.ID, openssl.ID, zlib.ID}, ids)
+	assert.Nil(t, imageID)
+
+	ids, _, err = vulnRepo.MatchTriageImport(ctx, "CVE-2024-0001", "openssl", "")
+	require.NoError(t, err)
+	assert.Equal(t, []int{onImage.ID, openssl.ID}, ids)
+
+	ids, imageID, err = vulnRepo.MatchTriageImport(ctx, "CVE-2024-0001", "", "docker.io/library/nginx:1.25")
+	require.NoError(t, err)
+	assert.Equal(t, []int{onImage.ID}, ids)
+	require.NotNil(t, imageID)
+	assert.Equal(t, image.ID, *imageID)
+
+	ids, _, err = vulnRepo.MatchTriageImport(ctx, "CVE-2024-0001", "", "docker.io/library/redis:7.2")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// Rows are applied in order in one transaction
+	accepted, fixed, notes := models.StatusAccepted, models.StatusFixed, "Risk accepted"
+	require.NoError(t, vulnRepo.ImportTriage(ctx, []models.TriageImportUpdate{
+		{VulnerabilityIDs: []int{onImage.ID, openssl.ID}, Update: models.VulnerabilityUpdateWithContext{Status: &accepted, Notes: &notes, UpdatedBy: "alice"}},
+		{VulnerabilityIDs: []int{openssl.ID}, Update: models.VulnerabilityUpdateWithContext{Status: &fixed, UpdatedBy: "alice"}},
+	}))
+	vuln, err := vulnRepo.GetByID(ctx, onImage.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusAccepted, vuln.Status)
+	require.NotNil(t, vuln.Notes)
+	assert.Equal(t, notes, *vuln.Notes)
+	vuln, err = vulnRepo.GetByID(ctx, openssl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFixed, vuln.Status)
+	history, err := vulnRepo.GetHistory(ctx, openssl.ID)
+	require.NoError(t, err)
+	assert.Len(t, history, 3)
+
+	// Nothing is applied when a row fails
+	err = vulnRepo.ImportTriage(ctx, []models.TriageImportUpdate{
+		{VulnerabilityIDs: []int{zlib.ID}, Update: models.VulnerabilityUpdateWithContext{Status: &fixed, UpdatedBy: "alice"}},
+		{VulnerabilityIDs: []int{zlib.ID + 100}, Update: models.VulnerabilityUpdateWithContext{Status: &fixed, UpdatedBy: "alice"}},
+	})
+	require.Error(t, err)
+	vuln, err = vulnRepo.GetByID(ctx, zlib.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, vuln.Status)
+}
+
+func TestVulnerabilityRepository_ApplyTicketCallback(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	vulnRepo := NewVulnerabilityRepository(db)
+
+	vuln := &models.Vulnerability{
+		CVEID: "CVE-2024-0001", PackageName: "openssl", PackageVersion: "3.0.7", Severity: "High",
+		Status: models.StatusActive, FirstDetectedAt: time.Now(), LastSeenAt: time.Now(),
+	}
+	require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+
+	callback := &models.TicketCallback{
+		EventID: "jira-1001", TicketID: "SEC-42", TicketStatus: "Done", Status: models.StatusFixed,
+		VulnerabilityIDs: []int64{int64(vuln.ID)},
+	}
+	duplicate, err := vulnRepo.ApplyTicketCallback(ctx, callback, &models.VulnerabilityUpdateWithContext{UpdatedBy: "ticket:SEC-42"})
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.False(t, callback.ReceivedAt.IsZero())
+
+	stored, err := vulnRepo.GetByID(ctx, vuln.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFixed, stored.Status)
+	require.NotNil(t, stored.UpdatedBy)
+	assert.Equal(t, "ticket:SEC-42", *stored.UpdatedBy)
+
+	// Reopened meanwhile, the retried callback is not applied again
+	active := models.StatusActive
+	require.NoError(t, vulnRepo.BulkUpdate(ctx, []int{vuln.ID}, &models.VulnerabilityUpdateWithContext{Status: &active, UpdatedBy: "alice"}))
+	retry := &models.TicketCallback{
+		EventID: "jira-1001", TicketID: "SEC-42", TicketStatus: "Done", Status: models.StatusFixed,
+		VulnerabilityIDs: []int64{int64(vuln.ID)},
+	}
+	duplicate, err = vulnRepo.ApplyTicketCallback(ctx, retry, &models.VulnerabilityUpdateWithContext{UpdatedBy: "ticket:SEC-42"})
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+	assert.True(t, retry.ReceivedAt.Equal(callback.ReceivedAt))
+	stored, err = vulnRepo.GetByID(ctx, vuln.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, stored.Status)
+
+	// A callback for a missing vulnerability isn't recorded
+	_, err = vulnRepo.ApplyTicketCallback(ctx, &models.TicketCallback{
+		EventID: "jira-1002", TicketID: "SEC-43", TicketStatus: "Done", Status: models.StatusFixed,
+		VulnerabilityIDs: []int64{int64(vuln.ID + 100)},
+	}, &models.VulnerabilityUpdateWithContext{UpdatedBy: "ticket:SEC-43"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	var count int
+	require.NoError(t, db.GetContext(ctx, &count, `SELECT COUNT(*) FROM ticket_callbacks`))
+	assert.Equal(t, 1, count)
+}
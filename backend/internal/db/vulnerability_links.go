@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// selectLinks selects links with the name of their image. Callers append a WHERE clause on l
+const selectLinks = `
+	SELECT l.*, CONCAT(i.registry, '/', i.repository, ':', i.tag) as image_name
+	FROM vulnerability_links l
+	JOIN images i ON i.id = l.image_id
+`
+
+// CreateLink attaches a link to a vulnerability on an image, recording it in the history of the
+// vulnerability with the image as context. The vulnerability must have been found by a scan of the
+// image, and the image can't have the same link already
+func (r *VulnerabilityRepository) CreateLink(ctx context.Context, link *models.VulnerabilityLink) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var image struct {
+		Name  string `db:"name"`
+		Found bool   `db:"found"`
+	}
+	err = tx.GetContext(ctx, &image, `
+		SELECT CONCAT(i.registry, '/', i.repository, ':', i.tag) as name, EXISTS (
+			SELECT 1 FROM scan_vulnerabilities sv
+			JOIN scans s ON s.id = sv.scan_id
+			WHERE sv.vulnerability_id = $2 AND s.image_id = i.id
+		) as found
+		FROM images i WHERE i.id = $1
+	`, link.ImageID, link.VulnerabilityID)
+	if err == sql.ErrNoRows {
+		return notFound("image")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get image: %w", err)
+	}
+	if !image.Found {
+		return validationError("vulnerability %d was not found on image %d", link.VulnerabilityID, link.ImageID)
+	}
+
+	query := `
+		INSERT INTO vulnerability_links (vulnerability_id, image_id, url, title, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRowxContext(ctx, query,
+		link.VulnerabilityID, link.ImageID, link.URL, link.Title, link.CreatedBy,
+	).Scan(&link.ID, &link.CreatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return conflict("the vulnerability already has this link on the image")
+		}
+		return fmt.Errorf("failed to create link: %w", err)
+	}
+	link.ImageName = image.Name
+
+	if err := insertLinkHistory(ctx, tx, link, nil, &link.URL, link.CreatedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListLinks returns the links of a vulnerability, of one image when imageID is set, oldest first
+func (r *VulnerabilityRepository) ListLinks(ctx context.Context, vulnerabilityID int, imageID *int) ([]models.VulnerabilityLink, error) {
+	query := selectLinks + `
+		WHERE l.vulnerability_id = $1 AND ($2::integer IS NULL OR l.image_id = $2)
+		ORDER BY l.created_at, l.id
+	`
+	links := []models.VulnerabilityLink{}
+	if err := r.db.SelectContext(ctx, &links, query, vulnerabilityID, imageID); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// DeleteLink removes a link of a vulnerability, recording it in the history of the vulnerability
+func (r *VulnerabilityRepository) DeleteLink(ctx context.Context, vulnerabilityID, linkID int, deletedBy string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var link models.VulnerabilityLink
+	err = tx.GetContext(ctx, &link, `
+		WITH deleted AS (
+			DELETE FROM vulnerability_links WHERE id = $1 AND vulnerability_id = $2
+			RETURNING *
+		)
+		SELECT l.*, CONCAT(i.registry, '/', i.repository, ':', i.tag) as image_name
+		FROM deleted l
+		JOIN images i ON i.id = l.image_id
+	`, linkID, vulnerabilityID)
+	if err == sql.ErrNoRows {
+		return notFound("link")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete link: %w", err)
+	}
+
+	if err := insertLinkHistory(ctx, tx, &link, &link.URL, nil, &deletedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertLinkHistory records a link added to or removed from a vulnerability in its history
+func insertLinkHistory(ctx context.Context, tx *sqlx.Tx, link *models.VulnerabilityLink, oldValue, newValue, changedBy *string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO vulnerability_history (
+			vulnerability_id, field_name, old_value, new_value,
+			changed_by, changed_at, image_id, image_name
+		)
+		VALUES ($1, $2, $3, $4, $5, NOW(), $6, $7)
+	`, link.VulnerabilityID, models.LinkHistoryField, oldValue, newValue, changedBy, link.ImageID, link.ImageName)
+	if err != nil {
+		return fmt.Errorf("failed to record link history: %w", err)
+	}
+	return nil
+}
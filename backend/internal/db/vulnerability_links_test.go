@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVulnerabilityRepository_Links(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	repo := NewVulnerabilityRepository(db)
+
+	web := &models.Image{Registry: "docker.io", Repository: "acme/web", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, web))
+	other := &models.Image{Registry: "docker.io", Repository: "acme/other", Tag: "latest"}
+	require.NoError(t, imageRepo.Create(ctx, other))
+
+	now := time.Now()
+	vuln := &models.Vulnerability{
+		CVEID: "CVE-2024-0001", PackageName: "openssl", PackageVersion: "3.0.7", Severity: "High",
+		Status: models.StatusActive, FirstDetectedAt: now, LastSeenAt: now,
+		Advisories: json.RawMessage(`[{"id":"DSA-5417-1","link":"https://security-tracker.debian.org/tracker/DSA-5417-1"}]`),
+	}
+	require.NoError(t, repo.Upsert(ctx, vuln))
+	scan := &models.Scan{ImageID: web.ID, ScanDate: now, Status: models.ScanStatusCompleted}
+	require.NoError(t, scanRepo.Create(ctx, scan))
+	require.NoError(t, repo.LinkToScan(ctx, scan.ID, vuln.ID))
+
+	stored, err := repo.GetByID(ctx, vuln.ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(vuln.Advisories), string(stored.Advisories))
+
+	// A scan without advisories clears them
+	vuln.Advisories = nil
+	require.NoError(t, repo.Upsert(ctx, vuln))
+	stored, err = repo.GetByID(ctx, vuln.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.Advisories)
+
+	user := "alice@example.com"
+	title := "Bump openssl to 3.0.9"
+	link := &models.VulnerabilityLink{
+		VulnerabilityID: vuln.ID, ImageID: web.ID, URL: "https://git.example.com/acme/web/-/merge_requests/42",
+		Title: &title, CreatedBy: &user,
+	}
+	require.NoError(t, repo.CreateLink(ctx, link))
+	assert.Equal(t, "docker.io/acme/web:latest", link.ImageName)
+
+	duplicate := *link
+	assert.ErrorIs(t, repo.CreateLink(ctx, &duplicate), ErrConflict)
+	elsewhere := *link
+	elsewhere.ImageID = other.ID
+	assert.ErrorIs(t, repo.CreateLink(ctx, &elsewhere), ErrValidation)
+
+	links, err := repo.ListLinks(ctx, vuln.ID, &web.ID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, link.URL, links[0].URL)
+	assert.Equal(t, title, *links[0].Title)
+	links, err = repo.ListLinks(ctx, vuln.ID, &other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+
+	require.NoError(t, repo.DeleteLink(ctx, vuln.ID, link.ID, "bob@example.com"))
+	assert.ErrorIs(t, repo.DeleteLink(ctx, vuln.ID, link.ID, "bob@example.com"), ErrNotFound)
+
+	// Adding and removing the link are in the history, latest first
+	history, err := repo.GetHistory(ctx, vuln.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, models.LinkHistoryField, history[0].FieldName)
+	assert.Equal(t, link.URL, *history[0].OldValue)
+	assert.Nil(t, history[0].NewValue)
+	assert.Equal(t, "bob@example.com", *history[0].ChangedBy)
+	assert.Nil(t, history[1].OldValue)
+	assert.Equal(t, link.URL, *history[1].NewValue)
+	assert.Equal(t, web.ID, *history[1].ImageID)
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// WaiverRepository handles database operations for waivers
+type WaiverRepository struct {
+	db *Database
+}
+
+// NewWaiverRepository creates a new waiver repository
+func NewWaiverRepository(db *Database) *WaiverRepository {
+	return &WaiverRepository{db: db}
+}
+
+// selectWaivers selects waivers with the CVE and package of their vulnerability and the name of their image.
+// Callers append a WHERE clause on w
+const selectWaivers = `
+	SELECT w.*, v.cve_id, v.package_name, CONCAT(i.registry, '/', i.repository, ':', i.tag) as image_name
+	FROM waivers w
+	JOIN vulnerabilities v ON v.id = w.vulnerability_id
+	JOIN images i ON i.id = w.image_id
+`
+
+// Create stores a waiver and accepts its vulnerability, recording the change in its history with the image
+// as context. The vulnerability must have been found by a scan of the image, and the image can't have
+// another waiver of it in effect
+func (r *WaiverRepository) Create(ctx context.Context, waiver *models.Waiver) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var image struct {
+		Name  string `db:"name"`
+		Found bool   `db:"found"`
+	}
+	err = tx.GetContext(ctx, &image, `
+		SELECT CONCAT(i.registry, '/', i.repository, ':', i.tag) as name, EXISTS (
+			SELECT 1 FROM scan_vulnerabilities sv
+			JOIN scans s ON s.id = sv.scan_id
+			WHERE sv.vulnerability_id = $2 AND s.image_id = i.id
+		) as found
+		FROM images i WHERE i.id = $1
+	`, waiver.ImageID, waiver.VulnerabilityID)
+	if err == sql.ErrNoRows {
+		return notFound("image")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get image: %w", err)
+	}
+	if !image.Found {
+		return validationError("vulnerability %d was not found on image %d", waiver.VulnerabilityID, waiver.ImageID)
+	}
+
+	query := `
+		INSERT INTO waivers (vulnerability_id, image_id, justification, expires_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRowxContext(ctx, query,
+		waiver.VulnerabilityID, waiver.ImageID, waiver.Justification, waiver.ExpiresAt, waiver.CreatedBy,
+	).Scan(&waiver.ID, &waiver.CreatedAt); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return conflict("the image already has a waiver of this vulnerability in effect")
+		}
+		return fmt.Errorf("failed to create waiver: %w", err)
+	}
+
+	createdBy := ""
+	if waiver.CreatedBy != nil {
+		createdBy = *waiver.CreatedBy
+	}
+	status := models.StatusAccepted
+	vulnRepo := &VulnerabilityRepository{db: r.db}
+	changes, err := vulnRepo.bulkUpdate(ctx, tx, []int{waiver.VulnerabilityID}, &models.VulnerabilityUpdateWithContext{
+		Status: &status, UpdatedBy: createdBy, ImageID: &waiver.ImageID, ImageName: &image.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.db.publishStatusChanges(changes)
+
+	stored, err := r.GetByID(ctx, waiver.ID)
+	if err != nil {
+		return err
+	}
+	*waiver = *stored
+	return nil
+}
+
+// GetByID retrieves a waiver
+func (r *WaiverRepository) GetByID(ctx context.Context, id int) (*models.Waiver, error) {
+	var waiver models.Waiver
+	if err := r.db.GetContext(ctx, &waiver, selectWaivers+` WHERE w.id = $1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("waiver")
+		}
+		return nil, err
+	}
+	return &waiver, nil
+}
+
+// List returns the waivers in effect, or the expired ones, soonest to expire first
+func (r *WaiverRepository) List(ctx context.Context, filter *models.WaiverFilter) ([]models.Waiver, error) {
+	query := selectWaivers + `
+		WHERE (w.expired_at IS NOT NULL) = $1
+			AND ($2::integer IS NULL OR w.image_id = $2)
+			AND ($3::integer IS NULL OR w.vulnerability_id = $3)
+		ORDER BY w.expires_at, w.id
+	`
+	waivers := []models.Waiver{}
+	if err := r.db.SelectContext(ctx, &waivers, query, filter.Expired, filter.ImageID, filter.VulnerabilityID); err != nil {
+		return nil, err
+	}
+	return waivers, nil
+}
+
+// Delete removes a waiver. Its vulnerability keeps its status
+func (r *WaiverRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM waivers WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound("waiver")
+	}
+	return nil
+}
+
+// Expire marks the waivers whose expiry passed at now as expired, and sets their vulnerabilities back to
+// active under models.WaiverExpiryActor, with the image in the history. A vulnerability is left alone
+// when it is no longer accepted, or while a waiver of another image still covers it. It returns the
+// waivers expired
+func (r *WaiverRepository) Expire(ctx context.Context, now time.Time) ([]models.Waiver, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	expired := []models.Waiver{}
+	query := `
+		WITH expired AS (
+			UPDATE waivers SET expired_at = $1
+			WHERE expired_at IS NULL AND expires_at <= $1
+			RETURNING *
+		)
+		SELECT w.*, v.cve_id, v.package_name, CONCAT(i.registry, '/', i.repository, ':', i.tag) as image_name
+		FROM expired w
+		JOIN vulnerabilities v ON v.id = w.vulnerability_id
+		JOIN images i ON i.id = w.image_id
+		ORDER BY w.id
+	`
+	if err := tx.SelectContext(ctx, &expired, query, now); err != nil {
+		return nil, fmt.Errorf("failed to expire waivers: %w", err)
+	}
+
+	covered := []int{}
+	if len(expired) > 0 {
+		ids := make([]int64, len(expired))
+		for i := range expired {
+			ids[i] = int64(expired[i].VulnerabilityID)
+		}
+		if err := tx.SelectContext(ctx, &covered, `
+			SELECT DISTINCT vulnerability_id FROM waivers
+			WHERE expired_at IS NULL AND vulnerability_id = ANY($1)
+		`, pq.Array(ids)); err != nil {
+			return nil, fmt.Errorf("failed to list waivers in effect: %w", err)
+		}
+	}
+	skip := make(map[int]bool, len(covered))
+	for _, id := range covered {
+		skip[id] = true
+	}
+
+	vulnRepo := &VulnerabilityRepository{db: r.db}
+	var changes statusChanges
+	for i := range expired {
+		waiver := &expired[i]
+		if skip[waiver.VulnerabilityID] {
+			continue
+		}
+		// Each vulnerability is reverted once, even when waivers of several images expire together
+		skip[waiver.VulnerabilityID] = true
+
+		var status string
+		if err := tx.GetContext(ctx, &status, `SELECT status FROM vulnerabilities WHERE id = $1 FOR UPDATE`, waiver.VulnerabilityID); err != nil {
+			return nil, fmt.Errorf("failed to get vulnerability: %w", err)
+		}
+		if status != models.StatusAccepted {
+			continue
+		}
+
+		active := models.StatusActive
+		waiverChanges, err := vulnRepo.bulkUpdate(ctx, tx, []int{waiver.VulnerabilityID}, &models.VulnerabilityUpdateWithContext{
+			Status: &active, UpdatedBy: models.WaiverExpiryActor, ImageID: &waiver.ImageID, ImageName: &waiver.ImageName,
+		})
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, waiverChanges...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	r.db.publishStatusChanges(changes)
+	return expired, nil
+}
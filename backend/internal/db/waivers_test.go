@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaiverRepository(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	imageRepo := NewImageRepository(db)
+	scanRepo := NewScanRepository(db)
+	vulnRepo := NewVulnerabilityRepository(db)
+	repo := NewWaiverRepository(db)
+
+	newImage := func(repository string) *models.Image {
+		image := &models.Image{Registry: "docker.io", Repository: repository, Tag: "latest"}
+		require.NoError(t, imageRepo.Create(ctx, image))
+		return image
+	}
+	web, worker, other := newImage("acme/web"), newImage("acme/worker"), newImage("acme/other")
+
+	now := time.Now()
+	newVuln := func(cve string, images ...*models.Image) *models.Vulnerability {
+		vuln := &models.Vulnerability{
+			CVEID: cve, PackageName: "openssl", PackageVersion: "3.0.7", Severity: "High",
+			Status: models.StatusActive, FirstDetectedAt: now, LastSeenAt: now,
+		}
+		require.NoError(t, vulnRepo.Upsert(ctx, vuln))
+		for _, image := range images {
+			scan := &models.Scan{ImageID: image.ID, ScanDate: now, Status: models.ScanStatusCompleted}
+			require.NoError(t, scanRepo.Create(ctx, scan))
+			require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+		}
+		return vuln
+	}
+	single := newVuln("CVE-2024-0001", web)
+	shared := newVuln("CVE-2024-0002", web, worker)
+
+	user := "alice@example.com"
+	waive := func(vuln *models.Vulnerability, image *models.Image, expiresAt time.Time) (*models.Waiver, error) {
+		waiver := &models.Waiver{
+			VulnerabilityID: vuln.ID, ImageID: image.ID, Justification: "not reachable from the network",
+			ExpiresAt: expiresAt, CreatedBy: &user,
+		}
+		return waiver, repo.Create(ctx, waiver)
+	}
+
+	soon := now.Add(time.Hour)
+	later := now.Add(48 * time.Hour)
+	waiver, err := waive(single, web, soon)
+	require.NoError(t, err)
+	assert.Equal(t, "CVE-2024-0001", waiver.CVEID)
+	assert.Equal(t, "docker.io/acme/web:latest", waiver.ImageName)
+
+	stored, err := vulnRepo.GetByID(ctx, single.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusAccepted, stored.Status)
+
+	_, err = waive(single, web, later)
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = waive(single, other, later)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	_, err = waive(shared, web, soon)
+	require.NoError(t, err)
+	_, err = waive(shared, worker, later)
+	require.NoError(t, err)
+
+	inEffect, err := repo.List(ctx, &models.WaiverFilter{ImageID: &web.ID})
+	require.NoError(t, err)
+	assert.Len(t, inEffect, 2)
+
+	// Two hours later the waivers of web expired, the worker one still covers the shared vulnerability
+	expired, err := repo.Expire(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, expired, 2)
+
+	stored, err = vulnRepo.GetByID(ctx, single.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, stored.Status)
+	stored, err = vulnRepo.GetByID(ctx, shared.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusAccepted, stored.Status)
+
+	history, err := vulnRepo.GetHistory(ctx, single.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	assert.Equal(t, models.WaiverExpiryActor, *history[0].ChangedBy)
+	assert.Equal(t, models.StatusActive, *history[0].NewValue)
+
+	expired, err = repo.Expire(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	list, err := repo.List(ctx, &models.WaiverFilter{Expired: true})
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	// The vulnerability can be waived again on the image once the previous waiver expired
+	_, err = waive(single, web, later)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Delete(ctx, waiver.ID))
+	assert.ErrorIs(t, repo.Delete(ctx, waiver.ID), ErrNotFound)
+}
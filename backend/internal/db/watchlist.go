@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/lib/pq"
+)
+
+// WatchlistRepository handles database operations for watchlist subscriptions
+type WatchlistRepository struct {
+	db *Database
+}
+
+// NewWatchlistRepository creates a new watchlist repository
+func NewWatchlistRepository(db *Database) *WatchlistRepository {
+	return &WatchlistRepository{db: db}
+}
+
+// Upsert creates a subscription, or updates the webhook of the subscriber's subscription
+// with the same criteria
+func (r *WatchlistRepository) Upsert(ctx context.Context, sub *models.WatchlistSubscription) error {
+	query := `
+		INSERT INTO watchlist_subscriptions (cve_id, package_name, subscriber, webhook_url, webhook_format, locale, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (subscriber, (COALESCE(cve_id, '')), (COALESCE(package_name, '')))
+		DO UPDATE SET
+			webhook_url = EXCLUDED.webhook_url,
+			webhook_format = EXCLUDED.webhook_format,
+			locale = EXCLUDED.locale
+		RETURNING id, created_at
+	`
+	webhookURL, err := r.db.encrypt(&sub.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook URL: %w", err)
+	}
+	return r.db.QueryRowContext(ctx, query,
+		sub.CVEID, sub.PackageName, sub.Subscriber, *webhookURL, sub.WebhookFormat, sub.Locale,
+	).Scan(&sub.ID, &sub.CreatedAt)
+}
+
+// ListBySubscriber returns the subscriptions of a user
+func (r *WatchlistRepository) ListBySubscriber(ctx context.Context, subscriber string) ([]models.WatchlistSubscription, error) {
+	query := `SELECT * FROM watchlist_subscriptions WHERE subscriber = $1 ORDER BY id`
+	subs := []models.WatchlistSubscription{}
+	if err := r.db.SelectContext(ctx, &subs, query, subscriber); err != nil {
+		return nil, err
+	}
+	if err := r.decryptWebhookURLs(subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// ListMatching returns the subscriptions that may match findings with the given CVEs or packages.
+// Subscriptions on a CVE in a package still have to be checked with Matches
+func (r *WatchlistRepository) ListMatching(ctx context.Context, cveIDs, packageNames []string) ([]models.WatchlistSubscription, error) {
+	subs := []models.WatchlistSubscription{}
+	if len(cveIDs) == 0 && len(packageNames) == 0 {
+		return subs, nil
+	}
+	query := `
+		SELECT * FROM watchlist_subscriptions
+		WHERE cve_id = ANY($1) OR package_name = ANY($2)
+		ORDER BY id
+	`
+	if err := r.db.SelectContext(ctx, &subs, query, pq.Array(cveIDs), pq.Array(packageNames)); err != nil {
+		return nil, err
+	}
+	if err := r.decryptWebhookURLs(subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// Delete removes a subscription of the given subscriber
+func (r *WatchlistRepository) Delete(ctx context.Context, id int, subscriber string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM watchlist_subscriptions WHERE id = $1 AND subscriber = $2`, id, subscriber)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound("watchlist subscription")
+	}
+	return nil
+}
+
+func (r *WatchlistRepository) decryptWebhookURLs(subs []models.WatchlistSubscription) error {
+	for i := range subs {
+		if err := r.db.decrypt(&subs[i].WebhookURL); err != nil {
+			return fmt.Errorf("failed to decrypt webhook URL of subscription %d: %w", subs[i].ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchlistRepository(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewWatchlistRepository(db)
+
+	cve, pkg := "CVE-2024-3094", "openssl"
+	byCVE := &models.WatchlistSubscription{CVEID: &cve, Subscriber: "alice@example.com", WebhookURL: "https://hooks.example.com/a", WebhookFormat: "slack"}
+	byPackage := &models.WatchlistSubscription{PackageName: &pkg, Subscriber: "bob@example.com", WebhookURL: "https://hooks.example.com/b", WebhookFormat: "teams"}
+	require.NoError(t, repo.Upsert(ctx, byCVE))
+	require.NoError(t, repo.Upsert(ctx, byPackage))
+
+	// Subscribing again updates the webhook in place
+	again := &models.WatchlistSubscription{CVEID: &cve, Subscriber: "alice@example.com", WebhookURL: "https://hooks.example.com/a2", WebhookFormat: "slack"}
+	require.NoError(t, repo.Upsert(ctx, again))
+	assert.Equal(t, byCVE.ID, again.ID)
+
+	subs, err := repo.ListBySubscriber(ctx, "alice@example.com")
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "https://hooks.example.com/a2", subs[0].WebhookURL)
+
+	matching, err := repo.ListMatching(ctx, []string{"CVE-2023-0001"}, []string{"openssl"})
+	require.NoError(t, err)
+	require.Len(t, matching, 1)
+	assert.Equal(t, byPackage.ID, matching[0].ID)
+
+	// Only the subscriber can remove a subscription
+	assert.Error(t, repo.Delete(ctx, byPackage.ID, "alice@example.com"))
+	assert.NoError(t, repo.Delete(ctx, byPackage.ID, "bob@example.com"))
+}
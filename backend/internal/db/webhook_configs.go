@@ -28,9 +28,11 @@ func (r *WebhookConfigRepository) Upsert(ctx context.Context, namespace, name st
 			scan_min_severity, scan_only_fixable,
 			status_change_enabled, status_change_min_severity, status_change_only_fixable,
 			status_change_transitions, status_change_include_notes,
+			fix_available_enabled, fix_available_min_severity,
+			locale,
 			created_at, updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())
 		ON CONFLICT (namespace, name)
 		DO UPDATE SET
 			webhook_url = EXCLUDED.webhook_url,
@@ -42,15 +44,25 @@ func (r *WebhookConfigRepository) Upsert(ctx context.Context, namespace, name st
 			status_change_only_fixable = EXCLUDED.status_change_only_fixable,
 			status_change_transitions = EXCLUDED.status_change_transitions,
 			status_change_include_notes = EXCLUDED.status_change_include_notes,
+			fix_available_enabled = EXCLUDED.fix_available_enabled,
+			fix_available_min_severity = EXCLUDED.fix_available_min_severity,
+			locale = EXCLUDED.locale,
 			updated_at = NOW()
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	webhookURL, err := r.db.encrypt(&req.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook URL: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, query,
 		namespace, name,
-		req.WebhookURL, req.WebhookFormat,
+		*webhookURL, req.WebhookFormat,
 		req.ScanMinSeverity, req.ScanOnlyFixable,
 		req.StatusChangeEnabled, req.StatusChangeMinSeverity, req.StatusChangeOnlyFixable,
 		pq.Array(req.StatusChangeTransitions), req.StatusChangeIncludeNotes,
+		req.FixAvailableEnabled, req.FixAvailableMinSeverity,
+		req.Locale,
 	)
 
 	return err
@@ -64,6 +76,8 @@ func (r *WebhookConfigRepository) Get(ctx context.Context, namespace, name strin
 			scan_min_severity, scan_only_fixable,
 			status_change_enabled, status_change_min_severity, status_change_only_fixable,
 			status_change_transitions, status_change_include_notes,
+			fix_available_enabled, fix_available_min_severity,
+			locale,
 			created_at, updated_at
 		FROM imagescan_webhook_configs
 		WHERE namespace = $1 AND name = $2
@@ -76,6 +90,8 @@ func (r *WebhookConfigRepository) Get(ctx context.Context, namespace, name strin
 		&config.ScanMinSeverity, &config.ScanOnlyFixable,
 		&config.StatusChangeEnabled, &config.StatusChangeMinSeverity, &config.StatusChangeOnlyFixable,
 		pq.Array(&config.StatusChangeTransitions), &config.StatusChangeIncludeNotes,
+		&config.FixAvailableEnabled, &config.FixAvailableMinSeverity,
+		&config.Locale,
 		&config.CreatedAt, &config.UpdatedAt,
 	)
 
@@ -85,6 +101,9 @@ func (r *WebhookConfigRepository) Get(ctx context.Context, namespace, name strin
 	if err != nil {
 		return nil, fmt.Errorf("failed to get webhook config: %w", err)
 	}
+	if err := r.db.decrypt(&config.WebhookURL); err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook URL: %w", err)
+	}
 
 	return config, nil
 }
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// WorkerRepository records background worker runs and holds their leader locks
+type WorkerRepository struct {
+	db *Database
+}
+
+// NewWorkerRepository creates a new worker repository
+func NewWorkerRepository(db *Database) *WorkerRepository {
+	return &WorkerRepository{db: db}
+}
+
+// workerLockKey is the advisory lock key of a worker, namespaced so it can't collide with other locks
+func workerLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("invulnerable:worker:" + name))
+	return int64(h.Sum64())
+}
+
+// TryLock takes the advisory lock of a worker without waiting, and reports whether it was free.
+// The lock belongs to a dedicated connection, so it is released by unlock or when the replica dies
+func (r *WorkerRepository) TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get a connection for the worker lock: %w", err)
+	}
+
+	key := workerLockKey(name)
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to take the worker lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	return func() {
+		// Closing the connection would release the lock too, unlocking first keeps it out of the pool locked
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, key)
+		conn.Close()
+	}, true, nil
+}
+
+// GetRun returns the last run of a worker, or nil if it never ran
+func (r *WorkerRepository) GetRun(ctx context.Context, name string) (*models.WorkerRun, error) {
+	var run models.WorkerRun
+	if err := r.db.GetContext(ctx, &run, `SELECT * FROM worker_runs WHERE name = $1`, name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns returns the last run of every worker
+func (r *WorkerRepository) ListRuns(ctx context.Context) ([]models.WorkerRun, error) {
+	runs := []models.WorkerRun{}
+	if err := r.db.SelectContext(ctx, &runs, `SELECT * FROM worker_runs ORDER BY name`); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// StartRun records that a worker started running on an instance
+func (r *WorkerRepository) StartRun(ctx context.Context, name, instance string, startedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO worker_runs (name, status, instance, last_started_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE
+		SET status = EXCLUDED.status, instance = EXCLUDED.instance, last_started_at = EXCLUDED.last_started_at
+	`, name, models.WorkerStatusRunning, instance, startedAt)
+	return err
+}
+
+// FinishRun records the outcome of the run started last
+func (r *WorkerRepository) FinishRun(ctx context.Context, name string, finishedAt time.Time, runErr error) error {
+	status := models.WorkerStatusSucceeded
+	var message *string
+	if runErr != nil {
+		status = models.WorkerStatusFailed
+		m := runErr.Error()
+		message = &m
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE worker_runs
+		SET status = $2,
+			last_finished_at = $3,
+			last_succeeded_at = CASE WHEN $2 = 'succeeded' THEN $3 ELSE last_succeeded_at END,
+			last_duration_ms = (EXTRACT(EPOCH FROM ($3 - last_started_at)) * 1000)::bigint,
+			last_error = $4,
+			run_count = run_count + 1,
+			failure_count = failure_count + CASE WHEN $2 = 'failed' THEN 1 ELSE 0 END
+		WHERE name = $1
+	`, name, status, finishedAt, message)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return notFound("worker run")
+	}
+	return nil
+}
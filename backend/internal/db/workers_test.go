@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerRepository_Lock(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewWorkerRepository(db)
+
+	unlock, acquired, err := repo.TryLock(ctx, "retention")
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// Held on its own connection, so any other session sees it taken
+	_, acquired, err = repo.TryLock(ctx, "retention")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	otherUnlock, acquired, err := repo.TryLock(ctx, "stale-scans")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	otherUnlock()
+
+	unlock()
+	unlock, acquired, err = repo.TryLock(ctx, "retention")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	unlock()
+}
+
+func TestWorkerRepository_Runs(t *testing.T) {
+	db := SetupTestDatabase(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewWorkerRepository(db)
+
+	run, err := repo.GetRun(ctx, "retention")
+	require.NoError(t, err)
+	assert.Nil(t, run)
+
+	started := time.Now().UTC().Truncate(time.Millisecond)
+	require.NoError(t, repo.StartRun(ctx, "retention", "backend-0", started))
+	require.NoError(t, repo.FinishRun(ctx, "retention", started.Add(1500*time.Millisecond), nil))
+
+	run, err = repo.GetRun(ctx, "retention")
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, models.WorkerStatusSucceeded, run.Status)
+	assert.Equal(t, "backend-0", run.Instance)
+	require.NotNil(t, run.LastDurationMs)
+	assert.Equal(t, int64(1500), *run.LastDurationMs)
+	require.NotNil(t, run.LastSucceededAt)
+
+	// A failure keeps the last success
+	require.NoError(t, repo.StartRun(ctx, "retention", "backend-1", started.Add(time.Hour)))
+	require.NoError(t, repo.FinishRun(ctx, "retention", started.Add(time.Hour+time.Second), errors.New("timeout")))
+
+	runs, err := repo.ListRuns(ctx)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, models.WorkerStatusFailed, runs[0].Status)
+	assert.Equal(t, "backend-1", runs[0].Instance)
+	require.NotNil(t, runs[0].LastError)
+	assert.Equal(t, "timeout", *runs[0].LastError)
+	assert.Equal(t, int64(2), runs[0].RunCount)
+	assert.Equal(t, int64(1), runs[0].FailureCount)
+	assert.WithinDuration(t, started.Add(1500*time.Millisecond), *runs[0].LastSucceededAt, time.Millisecond)
+
+	assert.EqualError(t, repo.FinishRun(ctx, "unknown", time.Now(), nil), "worker run not found")
+}
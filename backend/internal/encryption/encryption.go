@@ -0,0 +1,135 @@
+// Package encryption encrypts sensitive database columns with AES-256-GCM
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the size of keys in bytes (AES-256)
+const KeySize = 32
+
+// prefix marks encrypted values, followed by the key ID and the base64 nonce and ciphertext:
+// enc:v1:<key id>:<base64>. Values without it are plaintext written before encryption was enabled
+const prefix = "enc:v1:"
+
+// Cipher encrypts values with its primary key, and decrypts values written with the primary
+// or any previous key so keys can be rotated. A nil Cipher stores values in plaintext
+type Cipher struct {
+	primary *key
+	keys    map[string]*key
+}
+
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// New creates a cipher encrypting with primary. Values encrypted with the previous keys
+// can still be decrypted until they are re-encrypted
+func New(primary []byte, previous ...[]byte) (*Cipher, error) {
+	c := &Cipher{keys: make(map[string]*key)}
+	for i, raw := range append([][]byte{primary}, previous...) {
+		k, err := newKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			c.primary = k
+		}
+		c.keys[k.id] = k
+	}
+	return c, nil
+}
+
+func newKey(raw []byte) (*key, error) {
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// The ID only tells keys apart, it reveals nothing usable about the key
+	sum := sha256.Sum256(raw)
+	return &key{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+// ParseKey decodes a base64 key, as generated with `openssl rand -base64 32`
+func ParseKey(encoded string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	if len(raw) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(raw))
+	}
+	return raw, nil
+}
+
+// IsEncrypted reports whether a stored value is encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt encrypts a value with the primary key. The nonce is random, so the same
+// value encrypts differently every time
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if c == nil {
+		return plaintext, nil
+	}
+	nonce := make([]byte, c.primary.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	// The key ID is authenticated, so a ciphertext can't be replayed under another key
+	sealed := c.primary.aead.Seal(nonce, nonce, []byte(plaintext), []byte(c.primary.id))
+	return prefix + c.primary.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a stored value. Plaintext values are returned as they are
+func (c *Cipher) Decrypt(value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return value, nil
+	}
+	if c == nil {
+		return "", fmt.Errorf("value is encrypted but no encryption key is configured")
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	k, ok := c.keys[id]
+	if !ok {
+		return "", fmt.Errorf("value is encrypted with unknown key %s", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < k.aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+	plaintext, err := k.aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReencryption reports whether a stored value is plaintext or encrypted with a previous key
+func (c *Cipher) NeedsReencryption(value string) bool {
+	if c == nil {
+		return IsEncrypted(value)
+	}
+	return !strings.HasPrefix(value, prefix+c.primary.id+":")
+}
@@ -0,0 +1,113 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c, err := New(bytes.Repeat([]byte{1}, KeySize))
+	require.NoError(t, err)
+
+	url := "https://hooks.slack.com/services/T000/B000/XXXX"
+	encrypted, err := c.Encrypt(url)
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "hooks.slack.com")
+
+	again, err := c.Encrypt(url)
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	decrypted, err := c.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, url, decrypted)
+
+	// Rows written before encryption was enabled are read as they are
+	plaintext, err := c.Decrypt("legacy note")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy note", plaintext)
+	assert.True(t, c.NeedsReencryption("legacy note"))
+	assert.False(t, c.NeedsReencryption(encrypted))
+}
+
+func TestCipher_Rotation(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, KeySize), bytes.Repeat([]byte{2}, KeySize)
+	old, err := New(oldKey)
+	require.NoError(t, err)
+	encrypted, err := old.Encrypt("secret")
+	require.NoError(t, err)
+
+	rotated, err := New(newKey, oldKey)
+	require.NoError(t, err)
+	decrypted, err := rotated.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", decrypted)
+	assert.True(t, rotated.NeedsReencryption(encrypted))
+
+	// Without the old key the value can't be read
+	withoutOld, err := New(newKey)
+	require.NoError(t, err)
+	_, err = withoutOld.Decrypt(encrypted)
+	assert.Error(t, err)
+}
+
+func TestCipher_Tampered(t *testing.T) {
+	c, err := New(bytes.Repeat([]byte{1}, KeySize))
+	require.NoError(t, err)
+	encrypted, err := c.Encrypt("secret")
+	require.NoError(t, err)
+
+	tampered := []byte(encrypted)
+	tampered[len(tampered)-2] ^= 1
+	_, err = c.Decrypt(string(tampered))
+	assert.Error(t, err)
+}
+
+func TestCipher_Nil(t *testing.T) {
+	var c *Cipher
+	value, err := c.Encrypt("note")
+	require.NoError(t, err)
+	assert.Equal(t, "note", value)
+
+	_, err = c.Decrypt("enc:v1:0000:AAAA")
+	assert.Error(t, err)
+}
+
+type fakeKMS struct{}
+
+// DecryptKey "unwraps" a key by flipping its bits
+func (fakeKMS) DecryptKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	key := make([]byte, len(wrapped))
+	for i, b := range wrapped {
+		key[i] = ^b
+	}
+	return key, nil
+}
+
+func TestLoad(t *testing.T) {
+	c, err := Load(context.Background(), []string{"", " "}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	_, err = Load(context.Background(), []string{base64.StdEncoding.EncodeToString([]byte("short"))}, nil)
+	assert.Error(t, err)
+
+	key := bytes.Repeat([]byte{3}, KeySize)
+	wrapped := bytes.Repeat([]byte{^byte(3)}, KeySize)
+	fromKMS, err := Load(context.Background(), []string{base64.StdEncoding.EncodeToString(wrapped)}, fakeKMS{})
+	require.NoError(t, err)
+	fromEnv, err := Load(context.Background(), []string{base64.StdEncoding.EncodeToString(key)}, nil)
+	require.NoError(t, err)
+
+	encrypted, err := fromKMS.Encrypt("secret")
+	require.NoError(t, err)
+	decrypted, err := fromEnv.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", decrypted)
+}
@@ -0,0 +1,90 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/invulnerable/backend/internal/config"
+)
+
+// KeyDecrypter unwraps data keys encrypted with a KMS master key (envelope encryption)
+type KeyDecrypter interface {
+	DecryptKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// AWSKMS unwraps data keys generated with `aws kms generate-data-key --key-spec AES_256`
+type AWSKMS struct {
+	client *kms.Client
+}
+
+// NewAWSKMS creates a KMS client from the default AWS credential chain (environment, IRSA, instance role)
+func NewAWSKMS(ctx context.Context, region string) (*AWSKMS, error) {
+	opts := []func(*awsconfig.LoadOptions) error{}
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return &AWSKMS{client: kms.NewFromConfig(cfg)}, nil
+}
+
+// DecryptKey decrypts a data key ciphertext blob. The master key is read from the blob
+func (k *AWSKMS) DecryptKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	out, err := k.client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: wrapped})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key with KMS: %w", err)
+	}
+	return out.Plaintext, nil
+}
+
+// Load builds a cipher from base64 keys, the first one encrypting. With a KMS the keys are
+// base64 data key ciphertext blobs. Without keys encryption is disabled and the cipher is nil
+func Load(ctx context.Context, keys []string, decrypter KeyDecrypter) (*Cipher, error) {
+	var raw [][]byte
+	for _, encoded := range keys {
+		encoded = strings.TrimSpace(encoded)
+		if encoded == "" {
+			continue
+		}
+		if decrypter == nil {
+			k, err := ParseKey(encoded)
+			if err != nil {
+				return nil, err
+			}
+			raw = append(raw, k)
+			continue
+		}
+		wrapped, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KMS data key: %w", err)
+		}
+		k, err := decrypter.DecryptKey(ctx, wrapped)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, k)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return New(raw[0], raw[1:]...)
+}
+
+// LoadFromConfig builds the cipher configured with the DB_ENCRYPTION_* variables
+func LoadFromConfig(ctx context.Context, cfg config.EncryptionConfig) (*Cipher, error) {
+	var decrypter KeyDecrypter
+	if cfg.KMS && cfg.Key != "" {
+		client, err := NewAWSKMS(ctx, cfg.KMSRegion)
+		if err != nil {
+			return nil, err
+		}
+		decrypter = client
+	}
+	return Load(ctx, cfg.Keys(), decrypter)
+}
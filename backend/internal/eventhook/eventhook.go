@@ -0,0 +1,212 @@
+// Package eventhook sends machine-oriented events to the outgoing event webhook, so workflow engines
+// (Argo Events, n8n) can orchestrate follow-up actions. Unlike notification webhooks, set per scan or
+// ImageScan and formatted for chat, there is one event webhook, configured by the operator, receiving
+// full JSON payloads signed with HMAC-SHA256. Events go through the notification outbox, so they are
+// delivered at least once: receivers deduplicate on the delivery ID
+package eventhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// Event types
+const (
+	// ScanCompleted is sent when the results of a scan are processed, with ScanData
+	ScanCompleted = "scan.completed"
+	// PolicyFailed is sent with ScanCompleted when the scan fails the severity gate, with ScanData
+	PolicyFailed = "policy.failed"
+	// SLABreached is sent once per open vulnerability past its SLA deadline, with models.SLABreach
+	SLABreached = "sla.breached"
+)
+
+// Types are the event types the webhook can be subscribed to
+var Types = []string{ScanCompleted, PolicyFailed, SLABreached}
+
+// Headers of the webhook requests
+const (
+	HeaderEvent    = "X-Invulnerable-Event"
+	HeaderDelivery = "X-Invulnerable-Delivery"
+	// HeaderTimestamp is the Unix time the request was signed at, receivers reject old ones to stop replays
+	HeaderTimestamp = "X-Invulnerable-Timestamp"
+	// HeaderSignature is "sha256=" and the hex HMAC-SHA256 of the timestamp, a dot and the body
+	HeaderSignature = "X-Invulnerable-Signature"
+)
+
+// webhookTimeout bounds the delivery of an event, connection included
+const webhookTimeout = 10 * time.Second
+
+// MaxSignatureAge is how far from now the timestamp of a signed request Verify accepts can be
+const MaxSignatureAge = 5 * time.Minute
+
+// Event is the body of a webhook request
+type Event struct {
+	// ID is the same for every delivery of the event
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ScanData is the data of ScanCompleted and PolicyFailed events
+type ScanData struct {
+	Scan        models.Scan `json:"scan"`
+	Image       string      `json:"image"`
+	ImageDigest *string     `json:"image_digest,omitempty"`
+	// Vulnerabilities found by the scan, triaged or not; the gate counts the actionable ones
+	Vulnerabilities models.SeverityCounts `json:"vulnerabilities"`
+	// Diff with the previous scan of the image, unset for its first scan
+	Diff *models.ScanDiffSummary `json:"diff,omitempty"`
+	Gate models.ScanGate         `json:"gate"`
+}
+
+// queuedEvent is the outbox payload of an event, the ID and time come from the outbox
+type queuedEvent struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// Webhook sends events to the URL of the event webhook. A nil Webhook is disabled
+type Webhook struct {
+	logger     *zap.Logger
+	url        string
+	secret     []byte
+	types      []string
+	httpClient *http.Client
+}
+
+// New creates a webhook sending the given event types. The URL is set by the operator, not by scan
+// requests, so unlike notification webhooks it may point inside the cluster (an Argo Events EventSource)
+func New(logger *zap.Logger, rawURL, secret string, types []string) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid event webhook URL %q", rawURL)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("the event webhook requires a signing secret")
+	}
+	for _, t := range types {
+		if !slices.Contains(Types, t) {
+			return nil, fmt.Errorf("unknown event type %q (must be one of %s)", t, strings.Join(Types, ", "))
+		}
+	}
+	return &Webhook{
+		logger:     logger,
+		url:        rawURL,
+		secret:     []byte(secret),
+		types:      types,
+		httpClient: &http.Client{Timeout: webhookTimeout},
+	}, nil
+}
+
+// ParseTypes parses a comma-separated list of event types, every type when empty
+func ParseTypes(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return Types
+	}
+	var types []string
+	for _, t := range strings.Split(list, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// Enabled reports whether events of a type are sent
+func (w *Webhook) Enabled(eventType string) bool {
+	return w != nil && slices.Contains(w.types, eventType)
+}
+
+// NewEvent creates the outbox event of an event, written with the change it reports
+func (w *Webhook) NewEvent(eventType string, data any) (*models.OutboxEvent, error) {
+	return models.NewOutboxEvent(models.OutboxKindEventWebhook, queuedEvent{Type: eventType, Data: data})
+}
+
+// Deliver sends an event_webhook outbox event. The ID of the outbox event identifies the event
+func (w *Webhook) Deliver(ctx context.Context, event *models.OutboxEvent) error {
+	var queued struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := event.Decode(&queued); err != nil {
+		return err
+	}
+	if !w.Enabled(queued.Type) {
+		// Unsubscribed since the event was written
+		w.logger.Info("event type not enabled, skipping", zap.String("type", queued.Type), zap.Int64("event_id", event.ID))
+		return nil
+	}
+
+	body, err := json.Marshal(Event{
+		ID:   strconv.FormatInt(event.ID, 10),
+		Type: queued.Type,
+		Time: event.CreatedAt.UTC(),
+		Data: queued.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", queued.Type, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create event webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, queued.Type)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(event.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(w.secret, timestamp, body))
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s event: %w", queued.Type, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("event webhook returned non-2xx status: %d", resp.StatusCode)
+	}
+
+	w.logger.Info("event webhook sent", zap.String("type", queued.Type), zap.Int64("event_id", event.ID))
+	return nil
+}
+
+// Sign returns the HeaderSignature of a request body signed at timestamp
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the HeaderSignature of a request body, and that it was signed within MaxSignatureAge of
+// now so a captured request can't be replayed. Inbound callbacks use the scheme of the event webhook
+func Verify(secret []byte, timestamp string, body []byte, signature string, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s", HeaderTimestamp)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > MaxSignatureAge || age < -MaxSignatureAge {
+		return fmt.Errorf("%s is too far from now", HeaderTimestamp)
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return fmt.Errorf("invalid %s", HeaderSignature)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package eventhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(zap.NewNop(), "https://n8n.example.com/webhook/invulnerable", "secret", Types)
+	require.NoError(t, err)
+
+	_, err = New(zap.NewNop(), "ftp://n8n.example.com", "secret", Types)
+	assert.Error(t, err)
+	_, err = New(zap.NewNop(), "https://n8n.example.com", "", Types)
+	assert.ErrorContains(t, err, "secret")
+	_, err = New(zap.NewNop(), "https://n8n.example.com", "secret", []string{"scan.deleted"})
+	assert.ErrorContains(t, err, "unknown event type")
+}
+
+func TestParseTypes(t *testing.T) {
+	assert.Equal(t, Types, ParseTypes(""))
+	assert.Equal(t, []string{ScanCompleted, SLABreached}, ParseTypes(" scan.completed, ,sla.breached"))
+}
+
+func TestWebhook_Enabled(t *testing.T) {
+	var disabled *Webhook
+	assert.False(t, disabled.Enabled(ScanCompleted))
+
+	w, err := New(zap.NewNop(), "https://n8n.example.com", "secret", []string{PolicyFailed})
+	require.NoError(t, err)
+	assert.True(t, w.Enabled(PolicyFailed))
+	assert.False(t, w.Enabled(ScanCompleted))
+}
+
+func TestWebhook_Deliver(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	w, err := New(zap.NewNop(), server.URL, "secret", []string{ScanCompleted})
+	require.NoError(t, err)
+
+	event, err := w.NewEvent(ScanCompleted, ScanData{Scan: models.Scan{ID: 42}, Image: "docker.io/library/nginx:1.25"})
+	require.NoError(t, err)
+	event.ID = 7
+	event.CreatedAt = time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, w.Deliver(context.Background(), event))
+
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, ScanCompleted, header.Get(HeaderEvent))
+	assert.Equal(t, "7", header.Get(HeaderDelivery))
+	assert.Equal(t, Sign([]byte("secret"), header.Get(HeaderTimestamp), body), header.Get(HeaderSignature))
+
+	var sent Event
+	require.NoError(t, json.Unmarshal(body, &sent))
+	assert.Equal(t, "7", sent.ID)
+	assert.Equal(t, ScanCompleted, sent.Type)
+	assert.Equal(t, event.CreatedAt, sent.Time)
+	var data ScanData
+	require.NoError(t, json.Unmarshal(sent.Data, &data))
+	assert.Equal(t, 42, data.Scan.ID)
+	assert.Equal(t, "docker.io/library/nginx:1.25", data.Image)
+
+	// Events of a type unsubscribed since they were written are dropped
+	body = nil
+	event, err = w.NewEvent(SLABreached, models.SLABreach{VulnerabilityID: 1})
+	require.NoError(t, err)
+	require.NoError(t, w.Deliver(context.Background(), event))
+	assert.Nil(t, body)
+}
+
+func TestWebhook_DeliverFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	w, err := New(zap.NewNop(), server.URL, "secret", Types)
+	require.NoError(t, err)
+	event, err := w.NewEvent(ScanCompleted, ScanData{})
+	require.NoError(t, err)
+	assert.ErrorContains(t, w.Deliver(context.Background(), event), "503")
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1710072000.{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=2f35e8284bd032f6e52f61f1ad7fc73c184ecd4fc4e4c00b27f428689a29f805",
+		Sign([]byte("secret"), "1710072000", []byte("{}")))
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1710072000, 0)
+	body := []byte(`{"ticket_id":"SEC-42","status":"Done"}`)
+	signature := Sign(secret, "1710072000", body)
+
+	assert.NoError(t, Verify(secret, "1710072000", body, signature, now))
+	assert.NoError(t, Verify(secret, "1710072000", body, signature, now.Add(MaxSignatureAge)))
+	assert.ErrorContains(t, Verify(secret, "1710072000", body, signature, now.Add(MaxSignatureAge+time.Second)), "too far")
+	assert.ErrorContains(t, Verify(secret, "1710072000", []byte(`{"ticket_id":"SEC-43","status":"Done"}`), signature, now), HeaderSignature)
+	assert.ErrorContains(t, Verify([]byte("other"), "1710072000", body, signature, now), HeaderSignature)
+	assert.ErrorContains(t, Verify(secret, "yesterday", body, signature, now), HeaderTimestamp)
+	assert.Error(t, Verify(secret, "1710072000", body, "", now))
+}
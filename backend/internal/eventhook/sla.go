@@ -0,0 +1,64 @@
+package eventhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// announceWindow is how long after its deadline a breach is still announced. Older ones are recorded
+// without an event, so subscribing to sla.breached doesn't send one per vulnerability already overdue
+const announceWindow = 7 * 24 * time.Hour
+
+// BreachStore finds the vulnerabilities past their SLA deadline and records them once
+type BreachStore interface {
+	ListNew(ctx context.Context, now time.Time) ([]models.SLABreach, error)
+	// Record records the breaches with their events, events[i] being the one of breaches[i] or nil
+	Record(ctx context.Context, breaches []models.SLABreach, events []*models.OutboxEvent) (int, error)
+}
+
+// SLAMonitor periodically sends a SLABreached event for each open vulnerability past its deadline
+type SLAMonitor struct {
+	logger  *zap.Logger
+	webhook *Webhook
+	store   BreachStore
+}
+
+// NewSLAMonitor creates a monitor sending breaches to webhook
+func NewSLAMonitor(logger *zap.Logger, webhook *Webhook, store BreachStore) *SLAMonitor {
+	return &SLAMonitor{
+		logger:  logger,
+		webhook: webhook,
+		store:   store,
+	}
+}
+
+// Check records the breaches found since the last check and writes their events to the outbox
+func (m *SLAMonitor) Check(ctx context.Context, now time.Time) error {
+	breaches, err := m.store.ListNew(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to list SLA breaches: %w", err)
+	}
+	if len(breaches) == 0 {
+		return nil
+	}
+
+	events := make([]*models.OutboxEvent, len(breaches))
+	for i := range breaches {
+		if now.Sub(breaches[i].DueAt) > announceWindow {
+			continue
+		}
+		if events[i], err = m.webhook.NewEvent(SLABreached, breaches[i]); err != nil {
+			return err
+		}
+	}
+	recorded, err := m.store.Record(ctx, breaches, events)
+	if err != nil {
+		return err
+	}
+	m.logger.Info("recorded SLA breaches", zap.Int("breaches", recorded))
+	return nil
+}
@@ -0,0 +1,49 @@
+package eventhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeBreachStore struct {
+	breaches []models.SLABreach
+	recorded []models.SLABreach
+	events   []*models.OutboxEvent
+}
+
+func (s *fakeBreachStore) ListNew(ctx context.Context, now time.Time) ([]models.SLABreach, error) {
+	return s.breaches, nil
+}
+
+func (s *fakeBreachStore) Record(ctx context.Context, breaches []models.SLABreach, events []*models.OutboxEvent) (int, error) {
+	s.recorded = append(s.recorded, breaches...)
+	s.events = append(s.events, events...)
+	return len(breaches), nil
+}
+
+func TestSLAMonitor_Check(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := &fakeBreachStore{breaches: []models.SLABreach{
+		{VulnerabilityID: 1, CVEID: "CVE-2024-0001", DueAt: now.Add(-time.Hour)},
+		// Overdue long before the check, e.g. before sla.breached was subscribed to
+		{VulnerabilityID: 2, CVEID: "CVE-2023-0002", DueAt: now.AddDate(0, 0, -30)},
+	}}
+	w, err := New(zap.NewNop(), "https://n8n.example.com", "secret", Types)
+	require.NoError(t, err)
+
+	require.NoError(t, NewSLAMonitor(zap.NewNop(), w, store).Check(context.Background(), now))
+
+	require.Len(t, store.recorded, 2)
+	require.Len(t, store.events, 2)
+	require.NotNil(t, store.events[0])
+	assert.Equal(t, models.OutboxKindEventWebhook, store.events[0].Kind)
+	assert.Contains(t, store.events[0].Payload, `"type":"sla.breached"`)
+	assert.Contains(t, store.events[0].Payload, "CVE-2024-0001")
+	assert.Nil(t, store.events[1])
+}
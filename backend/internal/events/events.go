@@ -0,0 +1,166 @@
+// Package events is an in-process bus of the changes the dashboard shows, streamed to it over
+// Server-Sent Events so it updates live instead of polling. Delivery is best effort: a replica only
+// publishes the changes it makes, and a subscriber that falls behind misses events rather than
+// holding up the publisher. Durable notifications go through the outbox instead
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	// ScanCreated is published when the results of a scan are stored, with ScanCreatedData
+	ScanCreated = "scan-created"
+	// ScanDiff is published when a scan was compared with the previous scan of its image, with ScanDiffData
+	ScanDiff = "scan-diff"
+	// VulnerabilityStatusChanged is published for each vulnerability whose status changed, with
+	// VulnerabilityStatusChange
+	VulnerabilityStatusChanged = "vulnerability-status-changed"
+)
+
+// Types are the event types subscribers can filter on
+var Types = []string{ScanCreated, ScanDiff, VulnerabilityStatusChanged}
+
+// DefaultBuffer is how many events a subscriber may fall behind by before missing some
+const DefaultBuffer = 64
+
+// Event is a change published on the bus. ID increases with every event published
+type Event struct {
+	ID   uint64
+	Type string
+	Time time.Time
+	Data any
+}
+
+// ScanCreatedData is the data of ScanCreated events
+type ScanCreatedData struct {
+	ScanID          int    `json:"scan_id"`
+	ImageID         int    `json:"image_id"`
+	Image           string `json:"image"`
+	Status          string `json:"status"`
+	Vulnerabilities int    `json:"vulnerabilities"`
+}
+
+// ScanDiffData is the data of ScanDiff events
+type ScanDiffData struct {
+	ScanID          int    `json:"scan_id"`
+	PreviousScanID  int    `json:"previous_scan_id"`
+	Image           string `json:"image"`
+	NewCount        int    `json:"new_count"`
+	FixedCount      int    `json:"fixed_count"`
+	PersistentCount int    `json:"persistent_count"`
+}
+
+// VulnerabilityStatusChange is the data of VulnerabilityStatusChanged events
+type VulnerabilityStatusChange struct {
+	VulnerabilityID int    `json:"vulnerability_id"`
+	CVEID           string `json:"cve_id"`
+	PackageName     string `json:"package_name"`
+	OldStatus       string `json:"old_status"`
+	NewStatus       string `json:"new_status"`
+	UpdatedBy       string `json:"updated_by,omitempty"`
+}
+
+// Bus fans events out to its subscribers. A nil Bus drops everything published on it
+type Bus struct {
+	buffer int
+
+	mu          sync.Mutex
+	lastID      uint64
+	subscribers map[*Subscription]struct{}
+}
+
+// NewBus creates a bus whose subscribers may fall behind by DefaultBuffer events
+func NewBus() *Bus {
+	return &Bus{
+		buffer:      DefaultBuffer,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events of the types it subscribed to on C until it is closed
+type Subscription struct {
+	C <-chan Event
+
+	bus    *Bus
+	c      chan Event
+	types  map[string]bool
+	missed uint64
+}
+
+// Subscribe subscribes to events of the given types, of every type when there are none.
+// The subscription must be closed once done with
+func (b *Bus) Subscribe(types ...string) *Subscription {
+	c := make(chan Event, b.buffer)
+	s := &Subscription{C: c, bus: b, c: c}
+	if len(types) > 0 {
+		s.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Close unsubscribes and closes C. Closing twice is a no-op
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subscribers[s]; ok {
+		delete(s.bus.subscribers, s)
+		close(s.c)
+	}
+}
+
+// Missed returns how many events the subscription missed because it fell behind
+func (s *Subscription) Missed() uint64 {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.missed
+}
+
+// Publish sends an event to the subscribers of its type without waiting for them
+func (b *Bus) Publish(eventType string, data any) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Time: time.Now(), Data: data}
+	for s := range b.subscribers {
+		if s.types != nil && !s.types[eventType] {
+			continue
+		}
+		select {
+		case s.c <- event:
+		default:
+			s.missed++
+		}
+	}
+}
+
+// Subscribers returns the number of open subscriptions
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+// Pending returns the number of events published but not yet received, across subscriptions
+func (b *Bus) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for s := range b.subscribers {
+		n += len(s.c)
+	}
+	return n
+}
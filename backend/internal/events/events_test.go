@@ -0,0 +1,64 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus_PublishesToSubscribersOfTheType(t *testing.T) {
+	bus := NewBus()
+	all := bus.Subscribe()
+	defer all.Close()
+	scans := bus.Subscribe(ScanCreated)
+	defer scans.Close()
+
+	bus.Publish(VulnerabilityStatusChanged, VulnerabilityStatusChange{VulnerabilityID: 1})
+	bus.Publish(ScanCreated, ScanCreatedData{ScanID: 2})
+
+	first := <-all.C
+	assert.Equal(t, uint64(1), first.ID)
+	assert.Equal(t, VulnerabilityStatusChanged, first.Type)
+	second := <-all.C
+	assert.Equal(t, uint64(2), second.ID)
+
+	event := <-scans.C
+	assert.Equal(t, ScanCreated, event.Type)
+	assert.Equal(t, ScanCreatedData{ScanID: 2}, event.Data)
+	assert.Empty(t, scans.C)
+}
+
+func TestBus_DropsEventsOfSubscribersFallingBehind(t *testing.T) {
+	bus := NewBus()
+	bus.buffer = 2
+	s := bus.Subscribe()
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(ScanCreated, nil)
+	}
+	assert.Len(t, s.C, 2)
+	assert.Equal(t, 2, bus.Pending())
+	assert.Equal(t, uint64(3), s.Missed())
+}
+
+func TestSubscription_Close(t *testing.T) {
+	bus := NewBus()
+	s := bus.Subscribe()
+	require.Equal(t, 1, bus.Subscribers())
+
+	s.Close()
+	s.Close()
+	assert.Zero(t, bus.Subscribers())
+	_, open := <-s.C
+	assert.False(t, open)
+
+	// Nothing is sent on closed subscriptions
+	bus.Publish(ScanCreated, nil)
+}
+
+func TestBus_NilDropsEvents(t *testing.T) {
+	var bus *Bus
+	assert.NotPanics(t, func() { bus.Publish(ScanCreated, nil) })
+}
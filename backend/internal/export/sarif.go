@@ -0,0 +1,231 @@
+// Package export converts stored scan results to the formats of other tools
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// SARIF version and schema of the logs written
+const (
+	SARIFVersion = "2.1.0"
+	SARIFSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIFLog is a SARIF 2.1.0 log, with the fields GitHub Code Scanning and other consumers read
+type SARIFLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool       SARIFTool      `json:"tool"`
+	Results    []SARIFResult  `json:"results"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule describes a CVE, the results of its packages refer to it
+type SARIFRule struct {
+	ID                   string              `json:"id"`
+	Name                 string              `json:"name,omitempty"`
+	ShortDescription     SARIFMessage        `json:"shortDescription"`
+	FullDescription      *SARIFMessage       `json:"fullDescription,omitempty"`
+	HelpURI              string              `json:"helpUri,omitempty"`
+	Help                 *SARIFMessage       `json:"help,omitempty"`
+	DefaultConfiguration SARIFConfiguration  `json:"defaultConfiguration"`
+	Properties           SARIFRuleProperties `json:"properties"`
+}
+
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+// SARIFRuleProperties carries the severity GitHub ranks security alerts by
+type SARIFRuleProperties struct {
+	SecuritySeverity string   `json:"security-severity"`
+	Tags             []string `json:"tags"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult is a vulnerable package, one per vulnerability of the scan
+type SARIFResult struct {
+	RuleID       string             `json:"ruleId"`
+	Level        string             `json:"level"`
+	Message      SARIFMessage       `json:"message"`
+	Locations    []SARIFLocation    `json:"locations"`
+	Suppressions []SARIFSuppression `json:"suppressions,omitempty"`
+	Properties   map[string]any     `json:"properties,omitempty"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type SARIFLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind"`
+}
+
+// SARIFSuppression marks a result triaged away in invulnerable
+type SARIFSuppression struct {
+	Kind          string `json:"kind"`
+	Status        string `json:"status"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// SARIFScan is the scan the results come from
+type SARIFScan struct {
+	ID           int
+	ImageName    string
+	Digest       *string
+	GrypeVersion *string
+}
+
+// SARIF converts the vulnerabilities of a scan to a SARIF log with a run of Grype. Rules are the CVEs and
+// results their vulnerable packages, located in the image since stored matches keep no file paths.
+// Ignored and accepted vulnerabilities are kept as suppressed results, so consumers close their alerts
+func SARIF(scan SARIFScan, vulns []models.Vulnerability) SARIFLog {
+	driver := SARIFDriver{
+		Name:           "grype",
+		InformationURI: "https://github.com/anchore/grype",
+		Rules:          []SARIFRule{},
+	}
+	if scan.GrypeVersion != nil {
+		driver.Version = *scan.GrypeVersion
+	}
+
+	rules := map[string]bool{}
+	results := make([]SARIFResult, 0, len(vulns))
+	for _, v := range vulns {
+		if !rules[v.CVEID] {
+			rules[v.CVEID] = true
+			driver.Rules = append(driver.Rules, sarifRule(v))
+		}
+		results = append(results, sarifResult(scan, v))
+	}
+	sort.Slice(driver.Rules, func(i, j int) bool { return driver.Rules[i].ID < driver.Rules[j].ID })
+
+	run := SARIFRun{
+		Tool:       SARIFTool{Driver: driver},
+		Results:    results,
+		Properties: map[string]any{"scanId": scan.ID, "image": scan.ImageName},
+	}
+	if scan.Digest != nil {
+		run.Properties["digest"] = *scan.Digest
+	}
+	return SARIFLog{Version: SARIFVersion, Schema: SARIFSchema, Runs: []SARIFRun{run}}
+}
+
+func sarifRule(v models.Vulnerability) SARIFRule {
+	rule := SARIFRule{
+		ID:                   v.CVEID,
+		Name:                 v.CVEID,
+		ShortDescription:     SARIFMessage{Text: fmt.Sprintf("%s %s vulnerability", v.CVEID, v.Severity)},
+		DefaultConfiguration: SARIFConfiguration{Level: SARIFLevel(v.Severity)},
+		Properties: SARIFRuleProperties{
+			SecuritySeverity: SecuritySeverity(v.Severity),
+			Tags:             []string{"security", "vulnerability", strings.ToLower(v.Severity)},
+		},
+	}
+	if v.Description != nil && *v.Description != "" {
+		rule.FullDescription = &SARIFMessage{Text: *v.Description}
+	}
+	if v.URL != nil && *v.URL != "" {
+		rule.HelpURI = *v.URL
+		rule.Help = &SARIFMessage{Text: "See " + *v.URL}
+	}
+	return rule
+}
+
+func sarifResult(scan SARIFScan, v models.Vulnerability) SARIFResult {
+	message := fmt.Sprintf("%s %s in %s@%s", v.Severity, v.CVEID, v.PackageName, v.PackageVersion)
+	if v.FixVersion != nil {
+		message += ", fixed in " + *v.FixVersion
+	}
+
+	pkg := SARIFLogicalLocation{Name: v.PackageName + "@" + v.PackageVersion, Kind: "package"}
+	if v.PURL != nil {
+		pkg.FullyQualifiedName = *v.PURL
+	}
+	result := SARIFResult{
+		RuleID:  v.CVEID,
+		Level:   SARIFLevel(v.Severity),
+		Message: SARIFMessage{Text: message},
+		Locations: []SARIFLocation{{
+			PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: scan.ImageName}},
+			LogicalLocations: []SARIFLogicalLocation{pkg},
+		}},
+		Properties: map[string]any{
+			"vulnerabilityId": v.ID,
+			"packageName":     v.PackageName,
+			"packageVersion":  v.PackageVersion,
+			"status":          v.Status,
+			"knownExploited":  v.KnownExploited,
+		},
+	}
+	if v.FixVersion != nil {
+		result.Properties["fixVersion"] = *v.FixVersion
+	}
+	if v.Status == models.StatusIgnored || v.Status == models.StatusAccepted {
+		// Notes stay out of the log, they are encrypted at rest and SARIF files get uploaded elsewhere
+		result.Suppressions = []SARIFSuppression{{Kind: "external", Status: "accepted", Justification: "marked " + v.Status + " in invulnerable"}}
+	}
+	return result
+}
+
+// SARIFLevel maps a severity to a SARIF level: error for critical and high, warning for medium, note below
+func SARIFLevel(severity string) string {
+	switch severity {
+	case "Critical", "High":
+		return "error"
+	case "Medium":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// SecuritySeverity maps a severity to a score in the CVSS range GitHub buckets security alerts by:
+// 9.0 and above is critical, 7.0 high, 4.0 medium, 0.1 low
+func SecuritySeverity(severity string) string {
+	switch severity {
+	case "Critical":
+		return "9.5"
+	case "High":
+		return "8.0"
+	case "Medium":
+		return "5.5"
+	case "Low":
+		return "2.0"
+	default:
+		return "0.0"
+	}
+}
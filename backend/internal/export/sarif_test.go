@@ -0,0 +1,82 @@
+package export
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestSARIF(t *testing.T) {
+	scan := SARIFScan{ID: 42, ImageName: "docker.io/library/nginx:1.25", Digest: strPtr("sha256:4f1c0ffee"), GrypeVersion: strPtr("0.74.0")}
+	vulns := []models.Vulnerability{
+		{ID: 1, CVEID: "CVE-2024-0001", PackageName: "openssl", PackageVersion: "3.0.1", PURL: strPtr("pkg:deb/debian/openssl@3.0.1"),
+			Severity: "Critical", FixVersion: strPtr("3.0.2"), URL: strPtr("https://nvd.nist.gov/vuln/detail/CVE-2024-0001"),
+			Description: strPtr("Buffer overflow"), Status: models.StatusActive, KnownExploited: true},
+		{ID: 2, CVEID: "CVE-2024-0001", PackageName: "libssl3", PackageVersion: "3.0.1", Severity: "Critical", Status: models.StatusActive},
+		{ID: 3, CVEID: "CVE-2023-0002", PackageName: "zlib", PackageVersion: "1.2.11", Severity: "Medium",
+			Status: models.StatusAccepted, Notes: strPtr("internal only")},
+	}
+
+	log := SARIF(scan, vulns)
+	assert.Equal(t, SARIFVersion, log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+
+	assert.Equal(t, "grype", run.Tool.Driver.Name)
+	assert.Equal(t, "0.74.0", run.Tool.Driver.Version)
+	assert.Equal(t, "sha256:4f1c0ffee", run.Properties["digest"])
+
+	// One rule per CVE, sorted
+	require.Len(t, run.Tool.Driver.Rules, 2)
+	assert.Equal(t, "CVE-2023-0002", run.Tool.Driver.Rules[0].ID)
+	critical := run.Tool.Driver.Rules[1]
+	assert.Equal(t, "CVE-2024-0001", critical.ID)
+	assert.Equal(t, "error", critical.DefaultConfiguration.Level)
+	assert.Equal(t, "9.5", critical.Properties.SecuritySeverity)
+	assert.Equal(t, "https://nvd.nist.gov/vuln/detail/CVE-2024-0001", critical.HelpURI)
+	require.NotNil(t, critical.FullDescription)
+	assert.Equal(t, "Buffer overflow", critical.FullDescription.Text)
+
+	// One result per vulnerable package, located in the image
+	require.Len(t, run.Results, 3)
+	openssl := run.Results[0]
+	assert.Equal(t, "CVE-2024-0001", openssl.RuleID)
+	assert.Equal(t, "error", openssl.Level)
+	assert.Equal(t, "Critical CVE-2024-0001 in openssl@3.0.1, fixed in 3.0.2", openssl.Message.Text)
+	assert.Equal(t, "docker.io/library/nginx:1.25", openssl.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, "pkg:deb/debian/openssl@3.0.1", openssl.Locations[0].LogicalLocations[0].FullyQualifiedName)
+	assert.Empty(t, openssl.Suppressions)
+
+	// Accepted vulnerabilities are suppressed, without their notes
+	zlib := run.Results[2]
+	assert.Equal(t, "warning", zlib.Level)
+	require.Len(t, zlib.Suppressions, 1)
+	assert.Equal(t, "external", zlib.Suppressions[0].Kind)
+	assert.Equal(t, "accepted", zlib.Suppressions[0].Status)
+	assert.NotContains(t, zlib.Suppressions[0].Justification, "internal only")
+}
+
+func TestSARIF_NoVulnerabilities(t *testing.T) {
+	data, err := json.Marshal(SARIF(SARIFScan{ID: 1, ImageName: "alpine:3.19"}, nil))
+	require.NoError(t, err)
+
+	// Consumers require the arrays even when empty
+	var log map[string]any
+	require.NoError(t, json.Unmarshal(data, &log))
+	run := log["runs"].([]any)[0].(map[string]any)
+	assert.Equal(t, []any{}, run["results"])
+	assert.Equal(t, []any{}, run["tool"].(map[string]any)["driver"].(map[string]any)["rules"])
+	assert.Equal(t, "https://json.schemastore.org/sarif-2.1.0.json", log["$schema"])
+}
+
+func TestSARIFLevel(t *testing.T) {
+	tests := map[string]string{"Critical": "error", "High": "error", "Medium": "warning", "Low": "note", "Negligible": "note", "Unknown": "note"}
+	for severity, level := range tests {
+		assert.Equal(t, level, SARIFLevel(severity), severity)
+	}
+}
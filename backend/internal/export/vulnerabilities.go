@@ -0,0 +1,169 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// Formats of vulnerability exports
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// VulnerabilityColumns are the columns of CSV vulnerability exports. cve, package, image, status and
+// notes are those of triage imports, so an edited export can be imported back
+var VulnerabilityColumns = []string{
+	"cve", "package", "package_version", "package_type", "purl", "severity", "status", "fix_version",
+	"image", "image_digest", "first_detected_at", "last_seen_at", "sla_due_date", "frameworks", "notes",
+}
+
+// VulnerabilityWriter writes the vulnerability+image combinations of an export as they are read,
+// a chunk at a time. Close ends the document
+type VulnerabilityWriter interface {
+	Write(vulns []models.VulnerabilityWithImageInfo) error
+	Close() error
+}
+
+// NewVulnerabilityWriter returns the writer of a format, csv or json
+func NewVulnerabilityWriter(w io.Writer, format string) (VulnerabilityWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvVulnerabilityWriter{w: csv.NewWriter(w)}, nil
+	case FormatJSON:
+		return &jsonVulnerabilityWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("invalid format: %s (must be csv or json)", format)
+	}
+}
+
+// ContentType returns the content type of the exports of a format
+func ContentType(format string) string {
+	if format == FormatJSON {
+		return "application/json; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// csvVulnerabilityWriter writes a header row, then a row per vulnerability+image combination
+type csvVulnerabilityWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (cw *csvVulnerabilityWriter) Write(vulns []models.VulnerabilityWithImageInfo) error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	for i := range vulns {
+		if err := cw.w.Write(vulnerabilityRecord(&vulns[i])); err != nil {
+			return err
+		}
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvVulnerabilityWriter) Close() error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// writeHeader writes the header row once, an empty export still has it
+func (cw *csvVulnerabilityWriter) writeHeader() error {
+	if cw.header {
+		return nil
+	}
+	cw.header = true
+	return cw.w.Write(VulnerabilityColumns)
+}
+
+// vulnerabilityRecord is the row of a vulnerability+image combination, in VulnerabilityColumns order
+func vulnerabilityRecord(v *models.VulnerabilityWithImageInfo) []string {
+	optional := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	record := []string{
+		v.CVEID,
+		v.PackageName,
+		v.PackageVersion,
+		optional(v.PackageType),
+		optional(v.PURL),
+		v.Severity,
+		v.Status,
+		optional(v.FixVersion),
+		v.ImageName,
+		optional(v.ImageDigest),
+		v.FirstDetectedAt.UTC().Format(time.RFC3339),
+		v.LastSeenAt.UTC().Format(time.RFC3339),
+		v.SLADueDate,
+		strings.Join(v.Frameworks, ";"),
+		optional(v.Notes),
+	}
+	for i, cell := range record {
+		record[i] = escapeFormula(cell)
+	}
+	return record
+}
+
+// escapeFormula keeps spreadsheets from evaluating a cell as a formula. Package names and notes come
+// from scanned images and users, a leading quote makes the cell text
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// jsonVulnerabilityWriter writes a JSON array of vulnerability+image combinations
+type jsonVulnerabilityWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (jw *jsonVulnerabilityWriter) Write(vulns []models.VulnerabilityWithImageInfo) error {
+	for i := range vulns {
+		element, err := json.Marshal(&vulns[i])
+		if err != nil {
+			return err
+		}
+		separator := ","
+		if jw.count == 0 {
+			separator = "["
+		}
+		if _, err := io.WriteString(jw.w, separator); err != nil {
+			return err
+		}
+		if _, err := jw.w.Write(element); err != nil {
+			return err
+		}
+		jw.count++
+	}
+	return nil
+}
+
+func (jw *jsonVulnerabilityWriter) Close() error {
+	end := "]\n"
+	if jw.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(jw.w, end)
+	return err
+}
+
+// FileName returns the file name of an export made at a time, such as vulnerabilities-2026-10-14.csv
+func FileName(format string, at time.Time) string {
+	return "vulnerabilities-" + at.UTC().Format("2006-01-02") + "." + format
+}
@@ -0,0 +1,105 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportedVulnerabilities() []models.VulnerabilityWithImageInfo {
+	detected := time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)
+	return []models.VulnerabilityWithImageInfo{
+		{
+			Vulnerability: models.Vulnerability{
+				ID: 1, CVEID: "CVE-2024-0001", PackageName: "openssl", PackageVersion: "3.0.1", PackageType: strPtr("deb"),
+				PURL:     strPtr("pkg:deb/debian/openssl@3.0.1?arch=amd64"),
+				Severity: "Critical", FixVersion: strPtr("3.0.2"), Status: models.StatusActive,
+				LastSeenAt: detected.Add(48 * time.Hour), Notes: strPtr("=HYPERLINK(\"https://example.com\")"),
+			},
+			ImageID: 7, ImageName: "docker.io/library/nginx:1.25", ImageDigest: strPtr("sha256:4f1c0ffee"),
+			FirstDetectedAt: detected, SLADueDate: "2024-01-17", Frameworks: []string{"fedramp-high", "pci-dss"},
+		},
+		{
+			Vulnerability: models.Vulnerability{
+				ID: 2, CVEID: "CVE-2023-0002", PackageName: "zlib", PackageVersion: "1.2.11", Severity: "Medium",
+				Status: models.StatusAccepted, LastSeenAt: detected,
+			},
+			ImageID: 8, ImageName: "docker.io/library/redis:7.2", FirstDetectedAt: detected, SLADueDate: "2024-04-09",
+		},
+	}
+}
+
+func TestVulnerabilityWriter_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewVulnerabilityWriter(&buf, FormatCSV)
+	require.NoError(t, err)
+
+	// Written in chunks
+	vulns := exportedVulnerabilities()
+	require.NoError(t, w.Write(vulns[:1]))
+	require.NoError(t, w.Write(vulns[1:]))
+	require.NoError(t, w.Write(nil))
+	require.NoError(t, w.Close())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, VulnerabilityColumns, records[0])
+	row := map[string]string{}
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	assert.Equal(t, "CVE-2024-0001", row["cve"])
+	assert.Equal(t, "openssl", row["package"])
+	assert.Equal(t, "pkg:deb/debian/openssl@3.0.1?arch=amd64", row["purl"])
+	assert.Equal(t, "docker.io/library/nginx:1.25", row["image"])
+	assert.Equal(t, "2024-01-10T08:00:00Z", row["first_detected_at"])
+	assert.Equal(t, "fedramp-high;pci-dss", row["frameworks"])
+	// Notes aren't evaluated as a formula
+	assert.Equal(t, `'=HYPERLINK("https://example.com")`, row["notes"])
+	assert.Equal(t, "CVE-2023-0002", records[2][0])
+	// Without a PURL the cell is empty
+	assert.Equal(t, "", records[2][4])
+}
+
+func TestVulnerabilityWriter_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewVulnerabilityWriter(&buf, FormatJSON)
+	require.NoError(t, err)
+
+	vulns := exportedVulnerabilities()
+	require.NoError(t, w.Write(vulns[:1]))
+	require.NoError(t, w.Write(vulns[1:]))
+	require.NoError(t, w.Close())
+
+	var exported []models.VulnerabilityWithImageInfo
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	require.Len(t, exported, 2)
+	assert.Equal(t, "CVE-2024-0001", exported[0].CVEID)
+	require.NotNil(t, exported[0].PURL)
+	assert.Equal(t, "pkg:deb/debian/openssl@3.0.1?arch=amd64", *exported[0].PURL)
+	assert.Nil(t, exported[1].PURL)
+	assert.Equal(t, 8, exported[1].ImageID)
+}
+
+func TestVulnerabilityWriter_Empty(t *testing.T) {
+	for format, want := range map[string]string{
+		FormatCSV:  "cve,package,package_version,package_type,purl,severity,status,fix_version,image,image_digest,first_detected_at,last_seen_at,sla_due_date,frameworks,notes\n",
+		FormatJSON: "[]\n",
+	} {
+		var buf bytes.Buffer
+		w, err := NewVulnerabilityWriter(&buf, format)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		assert.Equal(t, want, buf.String(), format)
+	}
+
+	_, err := NewVulnerabilityWriter(&bytes.Buffer{}, "xlsx")
+	assert.Error(t, err)
+}
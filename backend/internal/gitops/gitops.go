@@ -0,0 +1,238 @@
+// Package gitops commits a findings summary of each image to a Git repository after its scans, as a
+// reviewable and versioned evidence trail for GitOps audits: the history of a summary file is the
+// history of the scans of its image. Commits are created through the GitHub API (github.com or GitHub
+// Enterprise), which signs those of GitHub Apps so they show as verified. Summaries go through the
+// notification outbox, rendered when the results are processed, so they are committed at least once
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// DefaultAPIURL is the API of github.com
+const DefaultAPIURL = "https://api.github.com"
+
+// requestTimeout bounds each request to the GitHub API
+const requestTimeout = 30 * time.Second
+
+// Config configures the repository summaries are committed to
+type Config struct {
+	// APIURL is the GitHub API, DefaultAPIURL or the /api/v3 URL of a GitHub Enterprise server
+	APIURL string
+	// Repository is owner/name
+	Repository string
+	// Branch is the branch committed to, the default branch of the repository when empty
+	Branch string
+	// Directory holds the summaries, the root of the repository when empty
+	Directory string
+	// Format is FormatYAML or FormatMarkdown
+	Format string
+	// Token authenticates the commits: a GitHub App installation token for signed commits, or a
+	// personal access token, with write access to the contents of the repository
+	Token string
+}
+
+// Committer commits the summaries of scans. A nil Committer is disabled
+type Committer struct {
+	logger     *zap.Logger
+	config     Config
+	httpClient *http.Client
+}
+
+// commit is the outbox payload of a summary, rendered when the results were processed
+type commit struct {
+	Path    string `json:"path"`
+	Content []byte `json:"content"`
+	Message string `json:"message"`
+}
+
+// New creates a committer to the repository of config
+func New(logger *zap.Logger, config Config) (*Committer, error) {
+	if config.APIURL == "" {
+		config.APIURL = DefaultAPIURL
+	}
+	u, err := url.Parse(config.APIURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid GitHub API URL %q", config.APIURL)
+	}
+	config.APIURL = strings.TrimSuffix(config.APIURL, "/")
+	if owner, name, ok := strings.Cut(config.Repository, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid repository %q (must be owner/name)", config.Repository)
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("committing summaries requires a GitHub token")
+	}
+	if config.Format == "" {
+		config.Format = FormatYAML
+	}
+	if config.Format != FormatYAML && config.Format != FormatMarkdown {
+		return nil, fmt.Errorf("unknown summary format %q (must be %s or %s)", config.Format, FormatYAML, FormatMarkdown)
+	}
+	return &Committer{
+		logger:     logger,
+		config:     config,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// NewEvent renders the summary of a scan of image into the outbox event committing it, written with
+// the results it reports
+func (c *Committer) NewEvent(scan *models.Scan, image *models.Image, vulns []models.Vulnerability) (*models.OutboxEvent, error) {
+	summary := NewSummary(scan, image, vulns)
+	content, err := summary.Render(c.config.Format)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewOutboxEvent(models.OutboxKindGitOpsCommit, commit{
+		Path:    Path(c.config.Directory, image, scan.Target, c.config.Format),
+		Content: content,
+		Message: summary.message(),
+	})
+}
+
+// Deliver commits the summary of a gitops_commit outbox event. A summary already in the repository, the
+// event being delivered again, isn't committed twice
+func (c *Committer) Deliver(ctx context.Context, event *models.OutboxEvent) error {
+	var e commit
+	if err := event.Decode(&e); err != nil {
+		return err
+	}
+
+	current, sha, err := c.getFile(ctx, e.Path)
+	if err != nil {
+		return err
+	}
+	if sha != "" && bytes.Equal(current, e.Content) {
+		c.logger.Info("summary unchanged, skipping commit", zap.String("path", e.Path), zap.Int64("event_id", event.ID))
+		return nil
+	}
+
+	body := map[string]string{
+		"message": e.Message,
+		"content": base64.StdEncoding.EncodeToString(e.Content),
+	}
+	if c.config.Branch != "" {
+		body["branch"] = c.config.Branch
+	}
+	if sha != "" {
+		// Without the blob it replaces, GitHub refuses to update the file
+		body["sha"] = sha
+	}
+	resp, err := c.do(ctx, http.MethodPut, e.Path, nil, body)
+	if err != nil {
+		return fmt.Errorf("failed to commit %s: %w", e.Path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		// 409 when the file changed since it was read, the retry reads it again
+		return fmt.Errorf("GitHub returned status %d committing %s: %s", resp.StatusCode, e.Path, readError(resp.Body))
+	}
+
+	var result struct {
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid response committing %s: %w", e.Path, err)
+	}
+	c.logger.Info("scan summary committed",
+		zap.String("repository", c.config.Repository),
+		zap.String("path", e.Path),
+		zap.String("commit", result.Commit.SHA),
+		zap.Int64("event_id", event.ID))
+	return nil
+}
+
+// getFile returns the content of a file on the branch and the SHA of its blob, no SHA when it doesn't exist
+func (c *Committer) getFile(ctx context.Context, path string) ([]byte, string, error) {
+	query := url.Values{}
+	if c.config.Branch != "" {
+		query.Set("ref", c.config.Branch)
+	}
+	resp, err := c.do(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("GitHub returned status %d getting %s: %s", resp.StatusCode, path, readError(resp.Body))
+	}
+
+	var file struct {
+		SHA      string `json:"sha"`
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&file); err != nil {
+		return nil, "", fmt.Errorf("invalid response getting %s: %w", path, err)
+	}
+	if file.Encoding != "base64" {
+		// Files over 1 MB come without their content, they are committed again
+		return nil, file.SHA, nil
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid content of %s: %w", path, err)
+	}
+	return content, file.SHA, nil
+}
+
+// do sends a request to the contents API of a file of the repository
+func (c *Committer) do(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	segments := strings.Split(path, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	endpoint := c.config.APIURL + "/repos/" + c.config.Repository + "/contents/" + strings.Join(segments, "/")
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.httpClient.Do(req)
+}
+
+// readError returns the message of a GitHub error response
+func readError(body io.Reader) string {
+	var e struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(body, 4096))
+	if json.Unmarshal(data, &e) == nil && e.Message != "" {
+		return e.Message
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,173 @@
+package gitops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeGitHub serves the contents API of one repository, keeping the files committed
+type fakeGitHub struct {
+	mu      sync.Mutex
+	files   map[string][]byte
+	commits []map[string]string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/repos/acme/evidence/contents/")
+	if !ok || r.URL.Query().Get("ref") != "" && r.URL.Query().Get("ref") != "audit" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		content, ok := f.files[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"sha":      blobSHA(content),
+			"encoding": "base64",
+			// The API wraps the content every 60 characters
+			"content": wrap(base64.StdEncoding.EncodeToString(content)),
+		})
+	case http.MethodPut:
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if current, ok := f.files[path]; ok && body["sha"] != blobSHA(current) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message": "does not match"}`))
+			return
+		}
+		content, _ := base64.StdEncoding.DecodeString(body["content"])
+		f.files[path] = content
+		f.commits = append(f.commits, body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"commit": {"sha": "3f1c9e2"}}`))
+	}
+}
+
+func blobSHA(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func wrap(s string) string {
+	var lines []string
+	for len(s) > 60 {
+		lines = append(lines, s[:60])
+		s = s[60:]
+	}
+	return strings.Join(append(lines, s), "\n")
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(zap.NewNop(), Config{Repository: "acme/evidence", Token: "token"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAPIURL, c.config.APIURL)
+	assert.Equal(t, FormatYAML, c.config.Format)
+
+	_, err = New(zap.NewNop(), Config{Repository: "evidence", Token: "token"})
+	assert.ErrorContains(t, err, "owner/name")
+	_, err = New(zap.NewNop(), Config{Repository: "acme/evidence"})
+	assert.ErrorContains(t, err, "token")
+	_, err = New(zap.NewNop(), Config{Repository: "acme/evidence", Token: "token", Format: "html"})
+	assert.ErrorContains(t, err, "unknown summary format")
+	_, err = New(zap.NewNop(), Config{APIURL: "github.example.com", Repository: "acme/evidence", Token: "token"})
+	assert.ErrorContains(t, err, "invalid GitHub API URL")
+}
+
+func TestPath(t *testing.T) {
+	image := &models.Image{Registry: "ghcr.io", Repository: "acme/api", Tag: "v1.2"}
+	assert.Equal(t, "invulnerable/ghcr.io/acme/api/v1.2.yaml", Path("/invulnerable/", image, nil, FormatYAML))
+
+	target := "/srv/api"
+	assert.Equal(t, "ghcr.io/acme/api/v1.2_srv-api.md", Path("", image, &target, FormatMarkdown))
+}
+
+func TestNewSummary(t *testing.T) {
+	fix := "3.0.13"
+	digest := "sha256:abc"
+	scan := &models.Scan{ID: 42, ScanDate: time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC), Status: models.ScanStatusCompleted}
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25", Digest: &digest}
+	summary := NewSummary(scan, image, []models.Vulnerability{
+		{CVEID: "CVE-2024-2", PackageName: "zlib", PackageVersion: "1.2", Severity: "Low", Status: models.StatusActive},
+		{CVEID: "CVE-2024-9", PackageName: "openssl", PackageVersion: "3.0.1", Severity: "Critical", FixVersion: &fix, Status: models.StatusActive},
+		{CVEID: "CVE-2024-1", PackageName: "curl", PackageVersion: "8.0", Severity: "Critical", Status: models.StatusAccepted},
+	})
+
+	assert.Equal(t, "docker.io/library/nginx:1.25", summary.Image)
+	assert.Equal(t, Counts{Critical: 2, Low: 1, Total: 3}, summary.Counts)
+	require.Len(t, summary.Findings, 3)
+	assert.Equal(t, []string{"CVE-2024-1", "CVE-2024-9", "CVE-2024-2"}, []string{summary.Findings[0].CVEID, summary.Findings[1].CVEID, summary.Findings[2].CVEID})
+
+	rendered, err := summary.Render(FormatYAML)
+	require.NoError(t, err)
+	assert.Contains(t, string(rendered), "scan_id: 42\n")
+	assert.Contains(t, string(rendered), "fix_version: 3.0.13\n")
+
+	rendered, err = summary.Render(FormatMarkdown)
+	require.NoError(t, err)
+	assert.Contains(t, string(rendered), "# docker.io/library/nginx:1.25\n")
+	assert.Contains(t, string(rendered), "| CVE-2024-9 | openssl | 3.0.1 | Critical | 3.0.13 | active |\n")
+	assert.Contains(t, summary.message(), "Scan 42 of docker.io/library/nginx:1.25: 2 critical, 0 high")
+}
+
+func TestCommitter_Deliver(t *testing.T) {
+	github := &fakeGitHub{files: map[string][]byte{}}
+	server := httptest.NewServer(github)
+	defer server.Close()
+
+	c, err := New(zap.NewNop(), Config{APIURL: server.URL, Repository: "acme/evidence", Branch: "audit", Directory: "scans", Token: "token"})
+	require.NoError(t, err)
+	image := &models.Image{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}
+	vulns := []models.Vulnerability{{CVEID: "CVE-2024-9", PackageName: "openssl", PackageVersion: "3.0.1", Severity: "High", Status: models.StatusActive}}
+	ctx := context.Background()
+
+	first, err := c.NewEvent(&models.Scan{ID: 41, Status: models.ScanStatusCompleted}, image, vulns)
+	require.NoError(t, err)
+	require.NoError(t, c.Deliver(ctx, first))
+	require.Len(t, github.commits, 1)
+	assert.Equal(t, "audit", github.commits[0]["branch"])
+	assert.Empty(t, github.commits[0]["sha"], "new file")
+	assert.Contains(t, github.commits[0]["message"], "Scan 41 of docker.io/library/nginx:1.25: 0 critical, 1 high")
+	assert.Contains(t, string(github.files["scans/docker.io/library/nginx/1.25.yaml"]), "cve_id: CVE-2024-9")
+
+	// Delivered again, e.g. after a crash, it isn't committed twice
+	require.NoError(t, c.Deliver(ctx, first))
+	assert.Len(t, github.commits, 1)
+
+	// The next scan updates the file
+	second, err := c.NewEvent(&models.Scan{ID: 42, Status: models.ScanStatusCompleted}, image, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Deliver(ctx, second))
+	require.Len(t, github.commits, 2)
+	assert.NotEmpty(t, github.commits[1]["sha"])
+	assert.Contains(t, string(github.files["scans/docker.io/library/nginx/1.25.yaml"]), "scan_id: 42")
+
+	// Errors are retried by the outbox
+	c.config.Token = "revoked"
+	third, err := c.NewEvent(&models.Scan{ID: 43, Status: models.ScanStatusCompleted}, image, vulns)
+	require.NoError(t, err)
+	assert.ErrorContains(t, c.Deliver(ctx, third), "status 401")
+}
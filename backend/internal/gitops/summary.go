@@ -0,0 +1,209 @@
+package gitops
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the summaries
+const (
+	FormatYAML     = "yaml"
+	FormatMarkdown = "markdown"
+)
+
+// Summary is the findings of the latest scan of an image target, committed as one file
+type Summary struct {
+	Image    string    `yaml:"image"`
+	Digest   string    `yaml:"digest,omitempty"`
+	Target   string    `yaml:"target,omitempty"`
+	ScanID   int       `yaml:"scan_id"`
+	ScanDate time.Time `yaml:"scan_date"`
+	Status   string    `yaml:"status"`
+	Counts   Counts    `yaml:"vulnerabilities"`
+	Findings []Finding `yaml:"findings"`
+}
+
+// Counts are the findings by severity, triaged or not
+type Counts struct {
+	Critical   int `yaml:"critical"`
+	High       int `yaml:"high"`
+	Medium     int `yaml:"medium"`
+	Low        int `yaml:"low"`
+	Negligible int `yaml:"negligible"`
+	Unknown    int `yaml:"unknown"`
+	Total      int `yaml:"total"`
+}
+
+// Finding is a vulnerability found by the scan, with its triage status when the results were processed
+type Finding struct {
+	CVEID          string `yaml:"cve_id"`
+	PackageName    string `yaml:"package"`
+	PackageVersion string `yaml:"version"`
+	Severity       string `yaml:"severity"`
+	FixVersion     string `yaml:"fix_version,omitempty"`
+	Status         string `yaml:"status"`
+}
+
+// NewSummary summarizes a scan of image and the vulnerabilities it found. Findings are sorted, most
+// severe first, so consecutive commits only differ by what changed
+func NewSummary(scan *models.Scan, image *models.Image, vulns []models.Vulnerability) Summary {
+	summary := Summary{
+		Image:    image.FullName(),
+		ScanID:   scan.ID,
+		ScanDate: scan.ScanDate.UTC(),
+		Status:   scan.Status,
+		Findings: make([]Finding, 0, len(vulns)),
+	}
+	if image.Digest != nil {
+		summary.Digest = *image.Digest
+	}
+	if scan.Target != nil {
+		summary.Target = *scan.Target
+	}
+
+	var counts models.SeverityCounts
+	for _, v := range vulns {
+		counts.Add(v.Severity, 1)
+		finding := Finding{
+			CVEID:          v.CVEID,
+			PackageName:    v.PackageName,
+			PackageVersion: v.PackageVersion,
+			Severity:       v.Severity,
+			Status:         v.Status,
+		}
+		if v.FixVersion != nil {
+			finding.FixVersion = *v.FixVersion
+		}
+		summary.Findings = append(summary.Findings, finding)
+	}
+	summary.Counts = Counts(counts)
+	summary.Counts.Total = len(vulns)
+
+	sort.SliceStable(summary.Findings, func(i, j int) bool {
+		a, b := summary.Findings[i], summary.Findings[j]
+		if ra, rb := severityRank(a.Severity), severityRank(b.Severity); ra != rb {
+			return ra > rb
+		}
+		if a.CVEID != b.CVEID {
+			return a.CVEID < b.CVEID
+		}
+		return a.PackageName < b.PackageName
+	})
+	return summary
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case "Critical":
+		return 5
+	case "High":
+		return 4
+	case "Medium":
+		return 3
+	case "Low":
+		return 2
+	case "Negligible":
+		return 1
+	}
+	return 0
+}
+
+// unsafePathChars are replaced in the file names of summaries
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Path returns the path of the summary of an image target in directory, one file per image
+// target so its Git history is the history of its scans
+func Path(directory string, image *models.Image, target *string, format string) string {
+	name := unsafePathChars.ReplaceAllString(image.Tag, "-")
+	if target != nil && *target != "" {
+		name += "_" + strings.Trim(unsafePathChars.ReplaceAllString(*target, "-"), "-")
+	}
+	ext := ".yaml"
+	if format == FormatMarkdown {
+		ext = ".md"
+	}
+	parts := []string{}
+	if directory = strings.Trim(directory, "/"); directory != "" {
+		parts = append(parts, directory)
+	}
+	if image.Registry != "" {
+		parts = append(parts, unsafePathChars.ReplaceAllString(image.Registry, "-"))
+	}
+	for _, segment := range strings.Split(image.Repository, "/") {
+		parts = append(parts, unsafePathChars.ReplaceAllString(segment, "-"))
+	}
+	return strings.Join(append(parts, name+ext), "/")
+}
+
+// Render renders the summary in a format
+func (s Summary) Render(format string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(s); err != nil {
+			return nil, fmt.Errorf("failed to render summary: %w", err)
+		}
+		return buf.Bytes(), nil
+	case FormatMarkdown:
+		return s.markdown(), nil
+	}
+	return nil, fmt.Errorf("unknown summary format %q (must be %s or %s)", format, FormatYAML, FormatMarkdown)
+}
+
+func (s Summary) markdown() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", s.Image)
+	if s.Digest != "" {
+		fmt.Fprintf(&b, "- Digest: `%s`\n", s.Digest)
+	}
+	if s.Target != "" {
+		fmt.Fprintf(&b, "- Target: `%s`\n", s.Target)
+	}
+	fmt.Fprintf(&b, "- Scan: %d (%s), %s\n\n", s.ScanID, s.Status, s.ScanDate.Format(time.RFC3339))
+
+	b.WriteString("| Critical | High | Medium | Low | Negligible | Unknown | Total |\n")
+	b.WriteString("|---|---|---|---|---|---|---|\n")
+	c := s.Counts
+	fmt.Fprintf(&b, "| %d | %d | %d | %d | %d | %d | %d |\n", c.Critical, c.High, c.Medium, c.Low, c.Negligible, c.Unknown, c.Total)
+
+	if len(s.Findings) == 0 {
+		b.WriteString("\nNo vulnerabilities found.\n")
+		return []byte(b.String())
+	}
+	b.WriteString("\n| CVE | Package | Version | Severity | Fix | Status |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, f := range s.Findings {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+			markdownCell(f.CVEID), markdownCell(f.PackageName), markdownCell(f.PackageVersion),
+			f.Severity, markdownCell(f.FixVersion), f.Status)
+	}
+	return []byte(b.String())
+}
+
+// markdownCell escapes the pipes of a table cell
+func markdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", `\|`)
+}
+
+// message returns the commit message of the summary
+func (s Summary) message() string {
+	image := s.Image
+	if s.Target != "" {
+		image += " (" + s.Target + ")"
+	}
+	message := fmt.Sprintf("Scan %d of %s: %d critical, %d high\n\n%d vulnerabilities found, %d medium, %d low\n",
+		s.ScanID, image, s.Counts.Critical, s.Counts.High, s.Counts.Total, s.Counts.Medium, s.Counts.Low)
+	if s.Digest != "" {
+		message += "Digest: " + s.Digest + "\n"
+	}
+	return message
+}
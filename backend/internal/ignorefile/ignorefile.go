@@ -0,0 +1,261 @@
+// Package ignorefile converts scanner ignore files into suppression rules
+package ignorefile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported formats
+const (
+	FormatGrype = "grype"
+	FormatTrivy = "trivy"
+)
+
+// trivyExpiryLayout is the date layout of the exp: suffix in .trivyignore
+const trivyExpiryLayout = "2006-01-02"
+
+// Result holds the rules parsed from a file and the entries that could not be converted
+type Result struct {
+	Rules   []models.SuppressionRule
+	Skipped []string
+}
+
+// Parse converts an ignore file of the given format
+func Parse(format string, data []byte) (*Result, error) {
+	switch format {
+	case FormatGrype:
+		return ParseGrype(data)
+	case FormatTrivy:
+		return ParseTrivy(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q (expected %s or %s)", format, FormatGrype, FormatTrivy)
+	}
+}
+
+// GrypeConfig is the subset of .grype.yaml holding ignore rules
+type GrypeConfig struct {
+	Ignore []GrypeIgnoreRule `yaml:"ignore"`
+}
+
+// GrypeIgnoreRule mirrors an entry of the grype "ignore" list
+type GrypeIgnoreRule struct {
+	Vulnerability    string              `yaml:"vulnerability,omitempty"`
+	Reason           string              `yaml:"reason,omitempty"`
+	Namespace        string              `yaml:"namespace,omitempty"`
+	FixState         string              `yaml:"fix-state,omitempty"`
+	Package          *GrypeIgnorePackage `yaml:"package,omitempty"`
+	VexStatus        string              `yaml:"vex-status,omitempty"`
+	VexJustification string              `yaml:"vex-justification,omitempty"`
+	MatchType        string              `yaml:"match-type,omitempty"`
+}
+
+// GrypeIgnorePackage mirrors the package criteria of a grype ignore rule
+type GrypeIgnorePackage struct {
+	Name         string `yaml:"name,omitempty"`
+	Version      string `yaml:"version,omitempty"`
+	Language     string `yaml:"language,omitempty"`
+	Type         string `yaml:"type,omitempty"`
+	Location     string `yaml:"location,omitempty"`
+	UpstreamName string `yaml:"upstream-name,omitempty"`
+}
+
+// ParseGrype converts the ignore rules of a .grype.yaml file
+// Rules using criteria we don't store (location, namespace, VEX...) are skipped rather than
+// imported without them, since dropping a criterion would suppress more than the team intended
+func ParseGrype(data []byte) (*Result, error) {
+	var config GrypeConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid grype config: %w", err)
+	}
+
+	result := &Result{Rules: []models.SuppressionRule{}, Skipped: []string{}}
+	for i, entry := range config.Ignore {
+		label := fmt.Sprintf("ignore[%d]", i)
+		if entry.Vulnerability != "" {
+			label += " " + entry.Vulnerability
+		}
+
+		if unsupported := entry.unsupportedCriteria(); len(unsupported) > 0 {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: unsupported criteria %s", label, strings.Join(unsupported, ", ")))
+			continue
+		}
+		if entry.FixState != "" && !slices.Contains(models.ValidFixStates, entry.FixState) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: invalid fix-state %q", label, entry.FixState))
+			continue
+		}
+
+		rule := models.SuppressionRule{
+			CVEID:    optional(entry.Vulnerability),
+			FixState: optional(entry.FixState),
+			Reason:   optional(entry.Reason),
+			Source:   models.SuppressionSourceGrype,
+		}
+		if entry.Package != nil {
+			rule.PackageName = optional(entry.Package.Name)
+			rule.PackageVersion = optional(entry.Package.Version)
+			rule.PackageType = optional(entry.Package.Type)
+		}
+		if rule.CVEID == nil && rule.PackageName == nil {
+			result.Skipped = append(result.Skipped, label+": rule needs a vulnerability or a package name")
+			continue
+		}
+
+		result.Rules = append(result.Rules, rule)
+	}
+
+	return result, nil
+}
+
+func (r GrypeIgnoreRule) unsupportedCriteria() []string {
+	var unsupported []string
+	if r.Namespace != "" {
+		unsupported = append(unsupported, "namespace")
+	}
+	if r.VexStatus != "" || r.VexJustification != "" {
+		unsupported = append(unsupported, "vex-status")
+	}
+	if r.MatchType != "" {
+		unsupported = append(unsupported, "match-type")
+	}
+	if r.Package != nil {
+		if r.Package.Language != "" {
+			unsupported = append(unsupported, "package.language")
+		}
+		if r.Package.Location != "" {
+			unsupported = append(unsupported, "package.location")
+		}
+		if r.Package.UpstreamName != "" {
+			unsupported = append(unsupported, "package.upstream-name")
+		}
+	}
+	return unsupported
+}
+
+// ParseTrivy converts a plain-text .trivyignore file
+// Each non-comment line is a vulnerability ID, optionally followed by exp:YYYY-MM-DD.
+// The comment block right above an entry becomes its reason
+func ParseTrivy(data []byte) (*Result, error) {
+	result := &Result{Rules: []models.SuppressionRule{}, Skipped: []string{}}
+	var comments []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			comments = nil
+			continue
+		case strings.HasPrefix(line, "#"):
+			if comment := strings.TrimSpace(strings.TrimPrefix(line, "#")); comment != "" {
+				comments = append(comments, comment)
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		rule := models.SuppressionRule{
+			CVEID:  &fields[0],
+			Reason: optional(strings.Join(comments, " ")),
+			Source: models.SuppressionSourceTrivy,
+		}
+		comments = nil
+
+		valid := true
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "#") {
+				break
+			}
+			value, ok := strings.CutPrefix(field, "exp:")
+			if !ok {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("line %d: unknown option %q", lineNo, field))
+				valid = false
+				break
+			}
+			expires, err := time.Parse(trivyExpiryLayout, value)
+			if err != nil {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("line %d: invalid expiry date %q", lineNo, value))
+				valid = false
+				break
+			}
+			rule.ExpiresAt = &expires
+		}
+		if valid {
+			result.Rules = append(result.Rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid trivyignore file: %w", err)
+	}
+
+	return result, nil
+}
+
+func optional(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// RenderGrype renders rules as a .grype.yaml ignore list
+// Grype has no expiry, so it is written as a comment next to the rule it applies to
+func RenderGrype(rules []models.SuppressionRule) ([]byte, error) {
+	list := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, rule := range rules {
+		entry := GrypeIgnoreRule{
+			Vulnerability: deref(rule.CVEID),
+			Reason:        deref(rule.Reason),
+			FixState:      deref(rule.FixState),
+		}
+		if rule.PackageName != nil || rule.PackageVersion != nil || rule.PackageType != nil {
+			entry.Package = &GrypeIgnorePackage{
+				Name:    deref(rule.PackageName),
+				Version: deref(rule.PackageVersion),
+				Type:    deref(rule.PackageType),
+			}
+		}
+
+		var node yaml.Node
+		if err := node.Encode(entry); err != nil {
+			return nil, fmt.Errorf("failed to encode rule %d: %w", rule.ID, err)
+		}
+		node.HeadComment = fmt.Sprintf("rule #%d", rule.ID)
+		if rule.ExpiresAt != nil {
+			node.HeadComment += ", expires " + rule.ExpiresAt.UTC().Format(trivyExpiryLayout)
+		}
+		list.Content = append(list.Content, &node)
+	}
+
+	doc := &yaml.Node{
+		Kind:        yaml.MappingNode,
+		HeadComment: "Generated by invulnerable from the server's suppression rules, do not edit",
+		Content:     []*yaml.Node{{Kind: yaml.ScalarNode, Value: "ignore"}, list},
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to render grype config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to render grype config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,125 @@
+package ignorefile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGrype(t *testing.T) {
+	data := []byte(`
+ignore:
+  - vulnerability: CVE-2023-0001
+    reason: not reachable from the network
+  - vulnerability: CVE-2023-0002
+    fix-state: not-fixed
+    package:
+      name: openssl
+      version: 3.0.11
+      type: deb
+  - package:
+      name: lodash
+  - vulnerability: CVE-2023-0003
+    package:
+      location: "/usr/lib/node_modules/**"
+  - fix-state: wont-fix
+`)
+
+	result, err := ParseGrype(data)
+	require.NoError(t, err)
+	require.Len(t, result.Rules, 3)
+
+	assert.Equal(t, "CVE-2023-0001", *result.Rules[0].CVEID)
+	assert.Equal(t, "not reachable from the network", *result.Rules[0].Reason)
+	assert.Equal(t, models.SuppressionSourceGrype, result.Rules[0].Source)
+
+	assert.Equal(t, "openssl", *result.Rules[1].PackageName)
+	assert.Equal(t, "3.0.11", *result.Rules[1].PackageVersion)
+	assert.Equal(t, "deb", *result.Rules[1].PackageType)
+	assert.Equal(t, models.FixStateNotFixed, *result.Rules[1].FixState)
+
+	assert.Nil(t, result.Rules[2].CVEID)
+	assert.Equal(t, "lodash", *result.Rules[2].PackageName)
+
+	require.Len(t, result.Skipped, 2)
+	assert.Contains(t, result.Skipped[0], "package.location")
+	assert.Contains(t, result.Skipped[1], "needs a vulnerability or a package name")
+}
+
+func TestParseGrype_InvalidYAML(t *testing.T) {
+	_, err := ParseGrype([]byte("ignore: [unterminated"))
+	assert.Error(t, err)
+}
+
+func TestParseTrivy(t *testing.T) {
+	data := []byte(`# Accepted until the base image is rebuilt
+# Tracked in SEC-42
+CVE-2023-0001 exp:2024-06-30
+
+CVE-2023-0002
+GHSA-xxxx-yyyy-zzzz # trailing comment
+
+CVE-2023-0003 exp:next-week
+`)
+
+	result, err := ParseTrivy(data)
+	require.NoError(t, err)
+	require.Len(t, result.Rules, 3)
+
+	first := result.Rules[0]
+	assert.Equal(t, "CVE-2023-0001", *first.CVEID)
+	assert.Equal(t, "Accepted until the base image is rebuilt Tracked in SEC-42", *first.Reason)
+	require.NotNil(t, first.ExpiresAt)
+	assert.Equal(t, time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC), *first.ExpiresAt)
+	assert.Equal(t, models.SuppressionSourceTrivy, first.Source)
+
+	// A blank line separates comments from the next entry
+	assert.Equal(t, "CVE-2023-0002", *result.Rules[1].CVEID)
+	assert.Nil(t, result.Rules[1].Reason)
+	assert.Equal(t, "GHSA-xxxx-yyyy-zzzz", *result.Rules[2].CVEID)
+
+	require.Len(t, result.Skipped, 1)
+	assert.Contains(t, result.Skipped[0], "line 8")
+}
+
+func TestParse_UnsupportedFormat(t *testing.T) {
+	_, err := Parse("snyk", nil)
+	assert.Error(t, err)
+}
+
+func TestRenderGrype_RoundTrip(t *testing.T) {
+	cveID, reason := "CVE-2023-0001", "not reachable"
+	pkg, version, fixState := "openssl", "3.0.11", models.FixStateNotFixed
+	expires := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	rules := []models.SuppressionRule{
+		{ID: 1, CVEID: &cveID, Reason: &reason, ExpiresAt: &expires},
+		{ID: 2, PackageName: &pkg, PackageVersion: &version, FixState: &fixState},
+	}
+
+	data, err := RenderGrype(rules)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# rule #1, expires 2024-06-30")
+
+	parsed, err := ParseGrype(data)
+	require.NoError(t, err)
+	assert.Empty(t, parsed.Skipped)
+	require.Len(t, parsed.Rules, 2)
+	assert.Equal(t, cveID, *parsed.Rules[0].CVEID)
+	assert.Equal(t, reason, *parsed.Rules[0].Reason)
+	assert.Nil(t, parsed.Rules[0].PackageName)
+	assert.Equal(t, pkg, *parsed.Rules[1].PackageName)
+	assert.Equal(t, version, *parsed.Rules[1].PackageVersion)
+	assert.Equal(t, fixState, *parsed.Rules[1].FixState)
+}
+
+func TestRenderGrype_Empty(t *testing.T) {
+	data, err := RenderGrype(nil)
+	require.NoError(t, err)
+
+	parsed, err := ParseGrype(data)
+	require.NoError(t, err)
+	assert.Empty(t, parsed.Rules)
+}
@@ -0,0 +1,218 @@
+// Package ingest processes the results of scan submissions in the background. A submission stores
+// its scan as running and queues a job with the matches, answered right away, and the workers of
+// every replica claim the queued jobs. A job whose replica died is claimed again once its lease
+// expires, so processing must be safe to run again
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// Defaults of the pool
+const (
+	DefaultWorkers     = 2
+	DefaultLease       = 2 * time.Minute
+	DefaultMaxAttempts = 3
+
+	// pollInterval is how often idle workers look for jobs queued by other replicas
+	pollInterval = 5 * time.Second
+	// Retries back off from retryBaseDelay, doubling each attempt
+	retryBaseDelay = 30 * time.Second
+)
+
+// Store holds the jobs, shared by every replica
+type Store interface {
+	Enqueue(ctx context.Context, job *models.IngestJob) error
+	// GetByScanID returns the latest job of a scan, nil when there is none
+	GetByScanID(ctx context.Context, scanID int) (*models.IngestJob, error)
+	// Claim leases the next queued job, nil when there is none
+	Claim(ctx context.Context, lease time.Duration) (*models.IngestJob, error)
+	Extend(ctx context.Context, id int64, lease time.Duration) error
+	Complete(ctx context.Context, id int64) error
+	// Fail retries the job at retryAt, or gives it up and fails its scan when nil
+	Fail(ctx context.Context, id int64, message string, retryAt *time.Time) error
+}
+
+// Processor processes the results of a job. An error retries the job later
+type Processor func(ctx context.Context, job *models.IngestJob) error
+
+// Pool runs the queued jobs on a fixed number of workers
+type Pool struct {
+	logger      *zap.Logger
+	store       Store
+	process     Processor
+	workers     int
+	lease       time.Duration
+	maxAttempts int
+
+	// wake tells an idle worker a job was queued on this replica
+	wake chan struct{}
+	busy atomic.Int32
+}
+
+// New creates a pool of workers with the default lease and attempts
+func New(logger *zap.Logger, store Store, process Processor, workers int) *Pool {
+	workers = max(workers, 1)
+	return &Pool{
+		logger:      logger,
+		store:       store,
+		process:     process,
+		workers:     workers,
+		lease:       DefaultLease,
+		maxAttempts: DefaultMaxAttempts,
+		wake:        make(chan struct{}, workers),
+	}
+}
+
+// Enqueue stores a job and wakes a worker of this replica to run it
+func (p *Pool) Enqueue(ctx context.Context, job *models.IngestJob) error {
+	if err := p.store.Enqueue(ctx, job); err != nil {
+		return fmt.Errorf("failed to queue ingest job: %w", err)
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+		// Every worker is already awake, one of them claims the job next
+	}
+	return nil
+}
+
+// GetByScanID returns the latest job of a scan, nil when there is none
+func (p *Pool) GetByScanID(ctx context.Context, scanID int) (*models.IngestJob, error) {
+	return p.store.GetByScanID(ctx, scanID)
+}
+
+// Busy returns how many workers are running a job
+func (p *Pool) Busy() int {
+	return int(p.busy.Load())
+}
+
+// Start runs the workers until the context is cancelled. A job interrupted by the cancellation
+// keeps its lease, and is claimed again once it expires
+func (p *Pool) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		go p.loop(ctx)
+	}
+}
+
+func (p *Pool) loop(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		// Jobs are run until none is left, then the worker waits for one
+		for p.runNext(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims and runs one job, and reports whether there was one
+func (p *Pool) runNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	job, err := p.store.Claim(ctx, p.lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logger.Warn("failed to claim ingest job", zap.Error(err))
+		}
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	p.busy.Add(1)
+	defer p.busy.Add(-1)
+	p.run(ctx, job)
+	return true
+}
+
+func (p *Pool) run(ctx context.Context, job *models.IngestJob) {
+	// The lease is renewed while the job runs, however long its results take
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(p.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := p.store.Extend(ctx, job.ID, p.lease); err != nil && ctx.Err() == nil {
+					p.logger.Warn("failed to extend ingest job lease", zap.Int64("job_id", job.ID), zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	started := time.Now()
+	err := p.safeProcess(ctx, job)
+	if ctx.Err() != nil {
+		// Shutting down, the job runs again once its lease expires
+		return
+	}
+	if err == nil {
+		if err := p.store.Complete(ctx, job.ID); err != nil {
+			// The lease expires and the job runs again, which processing allows
+			p.logger.Warn("failed to record ingest job completion", zap.Int64("job_id", job.ID), zap.Error(err))
+		}
+		p.logger.Info("ingest job completed",
+			zap.Int64("job_id", job.ID),
+			zap.Int("scan_id", job.ScanID),
+			zap.Int("matches", job.MatchCount),
+			zap.Duration("duration", time.Since(started)))
+		return
+	}
+
+	var retryAt *time.Time
+	if job.Attempts < p.maxAttempts {
+		next := time.Now().Add(retryDelay(job.Attempts))
+		retryAt = &next
+	}
+	fields := []zap.Field{
+		zap.Int64("job_id", job.ID),
+		zap.Int("scan_id", job.ScanID),
+		zap.Int("attempts", job.Attempts),
+		zap.Error(err),
+	}
+	if retryAt != nil {
+		p.logger.Warn("ingest job failed, retrying", append(fields, zap.Time("retry_at", *retryAt))...)
+	} else {
+		p.logger.Error("ingest job failed, giving up", fields...)
+	}
+	if err := p.store.Fail(ctx, job.ID, err.Error(), retryAt); err != nil {
+		p.logger.Warn("failed to record ingest job failure", zap.Int64("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// safeProcess runs the processor, a panic fails the attempt instead of the worker
+func (p *Pool) safeProcess(ctx context.Context, job *models.IngestJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return p.process(ctx, job)
+}
+
+// retryDelay is the wait after the given number of attempts
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+	}
+	return delay
+}
@@ -0,0 +1,159 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStore claims queued jobs in order, like the ingest_jobs table
+type fakeStore struct {
+	mu   sync.Mutex
+	jobs []*models.IngestJob
+}
+
+func (s *fakeStore) Enqueue(ctx context.Context, job *models.IngestJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.ID = int64(len(s.jobs) + 1)
+	job.Status = models.IngestJobQueued
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+func (s *fakeStore) GetByScanID(ctx context.Context, scanID int) (*models.IngestJob, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) Claim(ctx context.Context, lease time.Duration) (*models.IngestJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if job.Status == models.IngestJobQueued && !job.AvailableAt.After(time.Now()) {
+			job.Status = models.IngestJobRunning
+			job.Attempts++
+			claimed := *job
+			return &claimed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeStore) Extend(ctx context.Context, id int64, lease time.Duration) error {
+	return nil
+}
+
+func (s *fakeStore) Complete(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[id-1].Status = models.IngestJobCompleted
+	return nil
+}
+
+func (s *fakeStore) Fail(ctx context.Context, id int64, message string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[id-1]
+	job.Error = &message
+	if retryAt == nil {
+		job.Status = models.IngestJobFailed
+	} else {
+		job.Status, job.AvailableAt = models.IngestJobQueued, *retryAt
+	}
+	return nil
+}
+
+func (s *fakeStore) status(id int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id-1].Status
+}
+
+func TestPool_RunsQueuedJobs(t *testing.T) {
+	store := &fakeStore{}
+	processed := make(chan int, 2)
+	pool := New(zap.NewNop(), store, func(ctx context.Context, job *models.IngestJob) error {
+		processed <- job.ScanID
+		return nil
+	}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.Start(ctx)
+
+	// Enqueuing wakes the worker, without waiting for its next poll
+	for _, scanID := range []int{7, 8} {
+		require.NoError(t, pool.Enqueue(ctx, &models.IngestJob{ScanID: scanID}))
+	}
+	for _, want := range []int{7, 8} {
+		select {
+		case scanID := <-processed:
+			assert.Equal(t, want, scanID)
+		case <-time.After(time.Second):
+			t.Fatal("job not processed")
+		}
+	}
+	assert.Eventually(t, func() bool { return store.status(2) == models.IngestJobCompleted }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, models.IngestJobCompleted, store.status(1))
+}
+
+func TestPool_RetriesFailedJobs(t *testing.T) {
+	store := &fakeStore{}
+	calls := 0
+	pool := New(zap.NewNop(), store, func(ctx context.Context, job *models.IngestJob) error {
+		calls++
+		if calls == 2 {
+			panic("nil map")
+		}
+		return errors.New("connection reset")
+	}, 1)
+	ctx := context.Background()
+	require.NoError(t, store.Enqueue(ctx, &models.IngestJob{ScanID: 7}))
+
+	require.True(t, pool.runNext(ctx))
+	job := store.jobs[0]
+	assert.Equal(t, models.IngestJobQueued, job.Status)
+	assert.Equal(t, "connection reset", *job.Error)
+	assert.True(t, job.AvailableAt.After(time.Now()), "retried after a delay")
+
+	// Not run again before the retry
+	assert.False(t, pool.runNext(ctx))
+
+	// A panic fails the attempt, and the last attempt gives the job up
+	for i := 2; i <= DefaultMaxAttempts; i++ {
+		job.AvailableAt = time.Time{}
+		require.True(t, pool.runNext(ctx))
+	}
+	assert.Equal(t, DefaultMaxAttempts, calls)
+	assert.Equal(t, models.IngestJobFailed, job.Status)
+	assert.Equal(t, "connection reset", *job.Error)
+	assert.Equal(t, 0, pool.Busy())
+}
+
+func TestPool_LeavesInterruptedJobs(t *testing.T) {
+	store := &fakeStore{}
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := New(zap.NewNop(), store, func(ctx context.Context, job *models.IngestJob) error {
+		cancel()
+		return ctx.Err()
+	}, 1)
+	require.NoError(t, store.Enqueue(context.Background(), &models.IngestJob{ScanID: 7}))
+
+	// Neither failed nor completed, it is claimed again once its lease expires
+	require.True(t, pool.runNext(ctx))
+	assert.Equal(t, models.IngestJobRunning, store.jobs[0].Status)
+	assert.Nil(t, store.jobs[0].Error)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, retryDelay(1))
+	assert.Equal(t, time.Minute, retryDelay(2))
+	assert.Equal(t, 2*time.Minute, retryDelay(3))
+}
@@ -0,0 +1,102 @@
+// Package jsonstream walks JSON documents token by token, so documents too large to hold in
+// memory are decoded one value at a time
+package jsonstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Object reads an object, calling field with each key. field must consume the value, with
+// dec.Decode, Skip or another walk. A null is read as an object without keys
+func Object(dec *json.Decoder, field func(key string) error) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return nil
+	}
+	if t != json.Delim('{') {
+		return fmt.Errorf("expected an object, found %v", t)
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := field(t.(string)); err != nil {
+			return err
+		}
+	}
+	// The closing brace
+	_, err = dec.Token()
+	return err
+}
+
+// Array reads an array, calling element before each element, which it must consume.
+// A null is read as an empty array
+func Array(dec *json.Decoder, element func() error) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return nil
+	}
+	if t != json.Delim('[') {
+		return fmt.Errorf("expected an array, found %v", t)
+	}
+	for dec.More() {
+		if err := element(); err != nil {
+			return err
+		}
+	}
+	// The closing bracket
+	_, err = dec.Token()
+	return err
+}
+
+// Skip consumes the next value without decoding it
+func Skip(dec *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// ValueOffset returns the offset in r of the value following the key of an object that ends at
+// offset, dec.InputOffset() after reading the key, and the first byte of the value
+func ValueOffset(r io.ReaderAt, offset int64) (int64, byte, error) {
+	buf := make([]byte, 64)
+	for {
+		n, err := r.ReadAt(buf, offset)
+		for _, b := range buf[:n] {
+			switch b {
+			case ' ', '\t', '\r', '\n', ':':
+				offset++
+			default:
+				return offset, b, nil
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, 0, err
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package jsonstream
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObject(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{"name": "nginx", "tags": ["1.25", "latest"], "labels": {"a": {"b": [1]}}, "size": 3}`))
+	var name string
+	var tags []string
+	var keys []string
+	err := Object(dec, func(key string) error {
+		keys = append(keys, key)
+		switch key {
+		case "name":
+			return dec.Decode(&name)
+		case "tags":
+			return Array(dec, func() error {
+				var tag string
+				if err := dec.Decode(&tag); err != nil {
+					return err
+				}
+				tags = append(tags, tag)
+				return nil
+			})
+		}
+		return Skip(dec)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "tags", "labels", "size"}, keys)
+	assert.Equal(t, "nginx", name)
+	assert.Equal(t, []string{"1.25", "latest"}, tags)
+	assert.False(t, dec.More())
+}
+
+func TestObject_Null(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`null`))
+	require.NoError(t, Object(dec, func(key string) error {
+		t.Fatalf("unexpected key %s", key)
+		return nil
+	}))
+	dec = json.NewDecoder(strings.NewReader(`null`))
+	require.NoError(t, Array(dec, func() error {
+		t.Fatal("unexpected element")
+		return nil
+	}))
+}
+
+func TestObject_Invalid(t *testing.T) {
+	for _, document := range []string{`[]`, `"sbom"`, `{"a": 1`, ``} {
+		dec := json.NewDecoder(strings.NewReader(document))
+		assert.Error(t, Object(dec, func(string) error { return Skip(dec) }), document)
+	}
+	dec := json.NewDecoder(strings.NewReader(`{}`))
+	assert.Error(t, Array(dec, func() error { return Skip(dec) }))
+}
+
+func TestValueOffset(t *testing.T) {
+	document := `{"sbom" :
+		{"bomFormat": "CycloneDX"}, "image": null}`
+	r := strings.NewReader(document)
+	dec := json.NewDecoder(r)
+	var sections []string
+	err := Object(dec, func(key string) error {
+		start, first, err := ValueOffset(strings.NewReader(document), dec.InputOffset())
+		if err != nil {
+			return err
+		}
+		if err := Skip(dec); err != nil {
+			return err
+		}
+		sections = append(sections, string(first)+"|"+document[start:dec.InputOffset()])
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{|{"bomFormat": "CycloneDX"}`, `n|null`}, sections)
+
+	_, _, err = ValueOffset(strings.NewReader(`{"sbom": `), 7)
+	assert.Error(t, err)
+}
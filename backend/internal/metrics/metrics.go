@@ -2,8 +2,13 @@ package metrics
 
 import (
 	"context"
+	"sort"
+	"time"
 
+	"github.com/invulnerable/backend/internal/compliance"
 	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
@@ -20,12 +25,27 @@ func New(database *db.Database, logger *zap.Logger) *Service {
 }
 
 type DashboardMetrics struct {
+	// TotalImages counts images with the same digest once, AliasImages is the number left out
 	TotalImages           int            `json:"total_images"`
+	AliasImages           int            `json:"alias_images"`
 	TotalScans            int            `json:"total_scans"`
 	TotalVulnerabilities  int            `json:"total_vulnerabilities"`
 	ActiveVulnerabilities int            `json:"active_vulnerabilities"`
 	SeverityCounts        SeverityCounts `json:"severity_counts"`
 	RecentScans           int            `json:"recent_scans_24h"`
+	Distros               []DistroCount  `json:"distros"`
+}
+
+// imageIdentity identifies image i by its digest, so the same content pushed under several names
+// (aliases) is counted once. Images without a digest are their own identity
+const imageIdentity = `COALESCE(i.digest, 'image:' || i.id)`
+
+// DistroCount is the number of images whose latest scan detected a distribution.
+// Images without one (distroless, scratch) have their own bucket with a null name
+type DistroCount struct {
+	Name    *string `db:"name" json:"name"`
+	Version *string `db:"version" json:"version"`
+	Images  int     `db:"images" json:"images"`
 }
 
 type SeverityCounts struct {
@@ -45,21 +65,27 @@ func (s *Service) GetDashboardMetrics(ctx context.Context, hasFix *bool, imageNa
 		imageNamePattern = "%" + *imageName + "%"
 	}
 
-	// Total images (with optional filter)
+	// Total images (with optional filter), aliases counted once
+	var images struct {
+		Names      int `db:"names"`
+		Identities int `db:"identities"`
+	}
 	if hasImageFilter {
-		err := s.db.GetContext(ctx, &metrics.TotalImages,
-			"SELECT COUNT(*) FROM images i WHERE (COALESCE(i.registry, '') || '/' || COALESCE(i.repository, '') || ':' || COALESCE(i.tag, '')) LIKE $1",
+		err := s.db.GetContext(ctx, &images,
+			"SELECT COUNT(*) AS names, COUNT(DISTINCT "+imageIdentity+") AS identities FROM images i WHERE (COALESCE(i.registry, '') || '/' || COALESCE(i.repository, '') || ':' || COALESCE(i.tag, '')) LIKE $1",
 			imageNamePattern)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		err := s.db.GetContext(ctx, &metrics.TotalImages,
-			"SELECT COUNT(*) FROM images i")
+		err := s.db.GetContext(ctx, &images,
+			"SELECT COUNT(*) AS names, COUNT(DISTINCT "+imageIdentity+") AS identities FROM images i")
 		if err != nil {
 			return nil, err
 		}
 	}
+	metrics.TotalImages = images.Identities
+	metrics.AliasImages = images.Names - images.Identities
 
 	// Total scans (with optional image filter)
 	if hasImageFilter {
@@ -200,5 +226,272 @@ func (s *Service) GetDashboardMetrics(ctx context.Context, hasFix *bool, imageNa
 		}
 	}
 
+	// Distributions of the latest scan with results of each image, aliases counted once
+	distroQuery := `
+		WITH latest AS (
+			SELECT DISTINCT ON (` + imageIdentity + `) s.distro_name, s.distro_version
+			FROM scans s
+			JOIN images i ON s.image_id = i.id
+			WHERE s.status IN ('completed', 'partial')
+				AND ($1 = '' OR (COALESCE(i.registry, '') || '/' || COALESCE(i.repository, '') || ':' || COALESCE(i.tag, '')) LIKE $1)
+			ORDER BY ` + imageIdentity + `, s.scan_date DESC
+		)
+		SELECT distro_name as name, distro_version as version, COUNT(*) as images
+		FROM latest
+		GROUP BY distro_name, distro_version
+		ORDER BY images DESC, name NULLS LAST, version
+	`
+	metrics.Distros = []DistroCount{}
+	if err := s.db.SelectContext(ctx, &metrics.Distros, distroQuery, imageNamePattern); err != nil {
+		return nil, err
+	}
+
 	return metrics, nil
 }
+
+// AgeBuckets counts open vulnerabilities by days since they were first detected
+type AgeBuckets struct {
+	Days0To7   int `db:"days_0_7" json:"0_7d"`
+	Days8To30  int `db:"days_8_30" json:"8_30d"`
+	Days31To90 int `db:"days_31_90" json:"31_90d"`
+	Over90Days int `db:"over_90_days" json:"over_90d"`
+	Total      int `db:"total" json:"total"`
+}
+
+func (b *AgeBuckets) add(other AgeBuckets) {
+	b.Days0To7 += other.Days0To7
+	b.Days8To30 += other.Days8To30
+	b.Days31To90 += other.Days31To90
+	b.Over90Days += other.Over90Days
+	b.Total += other.Total
+}
+
+// VulnerabilityAge is the aging debt of open (active or in progress) vulnerabilities per team
+type VulnerabilityAge struct {
+	Teams []TeamVulnerabilityAge `json:"teams"`
+	Total AgeBuckets             `json:"total"`
+}
+
+// TeamVulnerabilityAge is the aging debt of a team, the namespace of the ImageScan that last found
+// the vulnerabilities. A nil namespace holds those of images scanned outside ImageScans (e.g. from CI)
+type TeamVulnerabilityAge struct {
+	Namespace  *string               `json:"namespace"`
+	BySeverity map[string]AgeBuckets `json:"by_severity"`
+	Total      AgeBuckets            `json:"total"`
+}
+
+// GetVulnerabilityAge buckets open vulnerabilities by age, severity and team in a single pass
+func (s *Service) GetVulnerabilityAge(ctx context.Context, hasFix *bool, namespace *string) (*VulnerabilityAge, error) {
+	query := `
+		SELECT
+			v.imagescan_namespace AS namespace,
+			v.severity,
+			COUNT(*) FILTER (WHERE v.first_detected_at > NOW() - INTERVAL '8 days') AS days_0_7,
+			COUNT(*) FILTER (WHERE v.first_detected_at <= NOW() - INTERVAL '8 days' AND v.first_detected_at > NOW() - INTERVAL '31 days') AS days_8_30,
+			COUNT(*) FILTER (WHERE v.first_detected_at <= NOW() - INTERVAL '31 days' AND v.first_detected_at > NOW() - INTERVAL '91 days') AS days_31_90,
+			COUNT(*) FILTER (WHERE v.first_detected_at <= NOW() - INTERVAL '91 days') AS over_90_days,
+			COUNT(*) AS total
+		FROM vulnerabilities v
+		WHERE v.status IN ('active', 'in_progress')
+	`
+	var args []interface{}
+	if hasFix != nil {
+		if *hasFix {
+			query += " AND v.fix_version IS NOT NULL"
+		} else {
+			query += " AND v.fix_version IS NULL"
+		}
+	}
+	if namespace != nil {
+		args = append(args, *namespace)
+		query += " AND v.imagescan_namespace = $1"
+	}
+	query += " GROUP BY v.imagescan_namespace, v.severity ORDER BY v.imagescan_namespace NULLS LAST, v.severity"
+
+	var rows []struct {
+		Namespace *string `db:"namespace"`
+		Severity  string  `db:"severity"`
+		AgeBuckets
+	}
+	if err := s.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	age := &VulnerabilityAge{Teams: []TeamVulnerabilityAge{}}
+	for _, row := range rows {
+		last := len(age.Teams) - 1
+		if last < 0 || !sameNamespace(age.Teams[last].Namespace, row.Namespace) {
+			age.Teams = append(age.Teams, TeamVulnerabilityAge{Namespace: row.Namespace, BySeverity: map[string]AgeBuckets{}})
+			last++
+		}
+		team := &age.Teams[last]
+		team.BySeverity[row.Severity] = row.AgeBuckets
+		team.Total.add(row.AgeBuckets)
+		age.Total.add(row.AgeBuckets)
+	}
+	return age, nil
+}
+
+// GetCompliance evaluates compliance profiles at now against the open (active or in progress) findings of
+// the latest successful scan of every image, first detected when the image was first found with them
+func (s *Service) GetCompliance(ctx context.Context, profiles []compliance.Profile, now time.Time) ([]models.FrameworkCompliance, error) {
+	imageQuery := `
+		SELECT i.id AS image_id, isc.labels
+		FROM images i
+		LEFT JOIN imagescans isc ON isc.registry = i.registry AND isc.repository = i.repository AND isc.tag = i.tag
+		WHERE EXISTS (SELECT 1 FROM scans s WHERE s.image_id = i.id AND s.status IN ('completed', 'partial'))
+	`
+	var imageRows []struct {
+		ImageID int            `db:"image_id"`
+		Labels  pq.StringArray `db:"labels"`
+	}
+	if err := s.db.SelectContext(ctx, &imageRows, imageQuery); err != nil {
+		return nil, err
+	}
+	images := make(map[int][][]string, len(imageRows))
+	for _, row := range imageRows {
+		// Images outside ImageScans have no labels, only profiles without a selector apply to them
+		labelSets := images[row.ImageID]
+		if row.Labels != nil {
+			labelSets = append(labelSets, row.Labels)
+		}
+		images[row.ImageID] = labelSets
+	}
+
+	findingQuery := `
+		WITH latest AS (
+			SELECT DISTINCT ON (s.image_id, COALESCE(s.target, '')) s.id, s.image_id
+			FROM scans s
+			WHERE s.status IN ('completed', 'partial')
+			ORDER BY s.image_id, COALESCE(s.target, ''), s.scan_date DESC
+		)
+		SELECT
+			l.image_id,
+			v.severity,
+			(SELECT MIN(fs.scan_date)
+			 FROM scans fs
+			 JOIN scan_vulnerabilities fsv ON fsv.scan_id = fs.id
+			 WHERE fsv.vulnerability_id = v.id AND fs.image_id = l.image_id) AS first_detected_at
+		FROM latest l
+		JOIN scan_vulnerabilities sv ON sv.scan_id = l.id
+		JOIN vulnerabilities v ON v.id = sv.vulnerability_id
+		WHERE v.status IN ('active', 'in_progress')
+		GROUP BY l.image_id, v.id
+	`
+	var findings []compliance.Finding
+	if err := s.db.SelectContext(ctx, &findings, findingQuery); err != nil {
+		return nil, err
+	}
+
+	return compliance.Evaluate(profiles, images, findings, now), nil
+}
+
+// SlowScanFactor is how many times the median of the average scan durations of all images an image's
+// average takes to be flagged as slow
+const SlowScanFactor = 3
+
+// ScanPerformance is how long the scans reporting their duration took over a window, per image
+type ScanPerformance struct {
+	Since time.Time `json:"since"`
+	Scans int       `json:"scans"`
+	// MedianDurationMS is the median of the average durations of the images
+	MedianDurationMS int64                  `json:"median_duration_ms"`
+	SlowFactor       int                    `json:"slow_factor"`
+	Images           []ImageScanPerformance `json:"images"`
+}
+
+// ImageScanPerformance is the scan duration of an image (and target) with its size at the latest scan,
+// under the ImageScan of that scan to size its workspace and resources
+type ImageScanPerformance struct {
+	ImageID            int     `db:"image_id" json:"image_id"`
+	ImageName          string  `db:"image_name" json:"image_name"`
+	Target             *string `db:"target" json:"target,omitempty"`
+	ImageScanNamespace *string `db:"imagescan_namespace" json:"imagescan_namespace,omitempty"`
+	ImageScanName      *string `db:"imagescan_name" json:"imagescan_name,omitempty"`
+	Scans              int     `db:"scans" json:"scans"`
+	AvgDurationMS      int64   `db:"avg_duration_ms" json:"avg_duration_ms"`
+	MaxDurationMS      int64   `db:"max_duration_ms" json:"max_duration_ms"`
+	ImageSizeBytes     *int64  `db:"image_size_bytes" json:"image_size_bytes,omitempty"`
+	LayerCount         *int    `db:"layer_count" json:"layer_count,omitempty"`
+	// Slow is set when the average duration is over SlowScanFactor times the median
+	Slow bool `db:"-" json:"slow"`
+}
+
+// GetScanPerformance aggregates the durations of the successful scans since the given time, optionally of
+// the ImageScans of a namespace. Slow images come first, then the longest to scan
+func (s *Service) GetScanPerformance(ctx context.Context, namespace *string, since time.Time) (*ScanPerformance, error) {
+	args := []interface{}{since}
+	filter := ""
+	if namespace != nil {
+		args = append(args, *namespace)
+		filter = " AND s.imagescan_namespace = $2"
+	}
+	query := `
+		WITH recent AS (
+			SELECT s.image_id, COALESCE(s.target, '') AS target_key, s.target, s.scan_date, s.scan_duration_ms,
+				s.image_size_bytes, s.layer_count, s.imagescan_namespace, s.imagescan_name
+			FROM scans s
+			WHERE s.status IN ('completed', 'partial') AND s.scan_duration_ms IS NOT NULL AND s.scan_date >= $1` + filter + `
+		),
+		latest AS (
+			SELECT DISTINCT ON (image_id, target_key) *
+			FROM recent
+			ORDER BY image_id, target_key, scan_date DESC
+		)
+		SELECT
+			l.image_id,
+			COALESCE(i.registry, '') || '/' || COALESCE(i.repository, '') || ':' || COALESCE(i.tag, '') AS image_name,
+			l.target, l.imagescan_namespace, l.imagescan_name,
+			COUNT(*) AS scans,
+			ROUND(AVG(r.scan_duration_ms))::BIGINT AS avg_duration_ms,
+			MAX(r.scan_duration_ms) AS max_duration_ms,
+			l.image_size_bytes, l.layer_count
+		FROM recent r
+		JOIN latest l ON l.image_id = r.image_id AND l.target_key = r.target_key
+		JOIN images i ON i.id = l.image_id
+		GROUP BY l.image_id, l.target_key, l.target, l.imagescan_namespace, l.imagescan_name, l.image_size_bytes, l.layer_count,
+			i.registry, i.repository, i.tag
+	`
+	images := []ImageScanPerformance{}
+	if err := s.db.SelectContext(ctx, &images, query, args...); err != nil {
+		return nil, err
+	}
+
+	performance := &ScanPerformance{Since: since, SlowFactor: SlowScanFactor, Images: images}
+	if len(images) == 0 {
+		return performance, nil
+	}
+	durations := make([]int64, len(images))
+	for i, image := range images {
+		durations[i] = image.AvgDurationMS
+		performance.Scans += image.Scans
+	}
+	performance.MedianDurationMS = median(durations)
+	for i := range images {
+		images[i].Slow = images[i].AvgDurationMS > SlowScanFactor*performance.MedianDurationMS
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].Slow != images[j].Slow {
+			return images[i].Slow
+		}
+		return images[i].AvgDurationMS > images[j].AvgDurationMS
+	})
+	return performance, nil
+}
+
+func median(values []int64) int64 {
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func sameNamespace(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
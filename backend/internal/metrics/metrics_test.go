@@ -5,17 +5,19 @@ import (
 	"testing"
 	"time"
 
+	"github.com/invulnerable/backend/internal/compliance"
 	"github.com/invulnerable/backend/internal/db"
 	"github.com/invulnerable/backend/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestGetDashboardMetrics(t *testing.T) {
 	database := db.SetupTestDatabase(t)
 	defer database.Close()
 
-	service := New(database)
+	service := New(database, zap.NewNop())
 	imageRepo := db.NewImageRepository(database)
 	scanRepo := db.NewScanRepository(database)
 	vulnRepo := db.NewVulnerabilityRepository(database)
@@ -40,14 +42,17 @@ func TestGetDashboardMetrics(t *testing.T) {
 	require.NoError(t, err)
 
 	// Recent scan for image 1
+	distroName, distroVersion := "debian", "12"
 	scan1 := &models.Scan{
-		ImageID:     image1.ID,
-		ScanDate:    time.Now(),
-		Status:      "completed",
-		SLACritical: 7,
-		SLAHigh:     30,
-		SLAMedium:   90,
-		SLALow:      180,
+		ImageID:       image1.ID,
+		ScanDate:      time.Now(),
+		Status:        "completed",
+		SLACritical:   7,
+		SLAHigh:       30,
+		SLAMedium:     90,
+		SLALow:        180,
+		DistroName:    &distroName,
+		DistroVersion: &distroVersion,
 	}
 	err = scanRepo.Create(context.Background(), scan1)
 	require.NoError(t, err)
@@ -111,7 +116,7 @@ func TestGetDashboardMetrics(t *testing.T) {
 	}
 
 	// Get metrics
-	metrics, err := service.GetDashboardMetrics(context.Background(), nil)
+	metrics, err := service.GetDashboardMetrics(context.Background(), nil, nil)
 	require.NoError(t, err)
 
 	// Assertions
@@ -124,13 +129,50 @@ func TestGetDashboardMetrics(t *testing.T) {
 	assert.Equal(t, 1, metrics.SeverityCounts.Medium)
 	assert.Equal(t, 1, metrics.SeverityCounts.Low)
 	assert.Equal(t, 1, metrics.RecentScans) // Only scan1 is within 24 hours
+
+	// Image 2 has no distribution and gets its own bucket
+	assert.Equal(t, []DistroCount{
+		{Name: &distroName, Version: &distroVersion, Images: 1},
+		{Images: 1},
+	}, metrics.Distros)
+}
+
+func TestGetDashboardMetrics_Aliases(t *testing.T) {
+	database := db.SetupTestDatabase(t)
+	defer database.Close()
+
+	ctx := context.Background()
+	service := New(database, zap.NewNop())
+	imageRepo := db.NewImageRepository(database)
+	scanRepo := db.NewScanRepository(database)
+
+	// The same digest pushed under two names, and an image without a digest
+	digest := "sha256:4d2b"
+	distroName, distroVersion := "alpine", "3.20"
+	for _, img := range []*models.Image{
+		{Registry: "docker.io", Repository: "acme/api", Tag: "1.4.2", Digest: &digest},
+		{Registry: "ghcr.io", Repository: "acme/api", Tag: "1.4.2", Digest: &digest},
+	} {
+		require.NoError(t, imageRepo.Create(ctx, img))
+		require.NoError(t, scanRepo.Create(ctx, &models.Scan{
+			ImageID: img.ID, ScanDate: time.Now(), Status: "completed", Digest: &digest,
+			DistroName: &distroName, DistroVersion: &distroVersion,
+		}))
+	}
+	require.NoError(t, imageRepo.Create(ctx, &models.Image{Registry: "docker.io", Repository: "acme/web", Tag: "dev"}))
+
+	metrics, err := service.GetDashboardMetrics(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, metrics.TotalImages)
+	assert.Equal(t, 1, metrics.AliasImages)
+	assert.Equal(t, []DistroCount{{Name: &distroName, Version: &distroVersion, Images: 1}}, metrics.Distros)
 }
 
 func TestGetDashboardMetrics_WithFixFilter(t *testing.T) {
 	database := db.SetupTestDatabase(t)
 	defer database.Close()
 
-	service := New(database)
+	service := New(database, zap.NewNop())
 	vulnRepo := db.NewVulnerabilityRepository(database)
 
 	// Create vulnerabilities with and without fixes
@@ -164,7 +206,7 @@ func TestGetDashboardMetrics_WithFixFilter(t *testing.T) {
 
 	// Get metrics with hasFix=true
 	hasFix := true
-	metrics, err := service.GetDashboardMetrics(context.Background(), &hasFix)
+	metrics, err := service.GetDashboardMetrics(context.Background(), &hasFix, nil)
 	require.NoError(t, err)
 	assert.Equal(t, 1, metrics.TotalVulnerabilities)
 	assert.Equal(t, 1, metrics.ActiveVulnerabilities)
@@ -173,7 +215,7 @@ func TestGetDashboardMetrics_WithFixFilter(t *testing.T) {
 
 	// Get metrics with hasFix=false
 	hasFix = false
-	metrics, err = service.GetDashboardMetrics(context.Background(), &hasFix)
+	metrics, err = service.GetDashboardMetrics(context.Background(), &hasFix, nil)
 	require.NoError(t, err)
 	assert.Equal(t, 1, metrics.TotalVulnerabilities)
 	assert.Equal(t, 1, metrics.ActiveVulnerabilities)
@@ -185,9 +227,9 @@ func TestGetDashboardMetrics_EmptyDatabase(t *testing.T) {
 	database := db.SetupTestDatabase(t)
 	defer database.Close()
 
-	service := New(database)
+	service := New(database, zap.NewNop())
 
-	metrics, err := service.GetDashboardMetrics(context.Background(), nil)
+	metrics, err := service.GetDashboardMetrics(context.Background(), nil, nil)
 	require.NoError(t, err)
 
 	// All metrics should be zero
@@ -201,3 +243,171 @@ func TestGetDashboardMetrics_EmptyDatabase(t *testing.T) {
 	assert.Equal(t, 0, metrics.SeverityCounts.Low)
 	assert.Equal(t, 0, metrics.RecentScans)
 }
+
+func TestGetVulnerabilityAge(t *testing.T) {
+	database := db.SetupTestDatabase(t)
+	defer database.Close()
+
+	service := New(database, zap.NewNop())
+	vulnRepo := db.NewVulnerabilityRepository(database)
+
+	payments := "payments"
+	fixVersion := "1.2.0"
+	now := time.Now()
+	vulns := []*models.Vulnerability{
+		{CVEID: "CVE-2024-0001", Severity: "Critical", Status: models.StatusActive, FirstDetectedAt: now.Add(-2 * 24 * time.Hour), ImageScanNamespace: &payments, FixVersion: &fixVersion},
+		{CVEID: "CVE-2024-0002", Severity: "Critical", Status: models.StatusInProgress, FirstDetectedAt: now.Add(-20 * 24 * time.Hour), ImageScanNamespace: &payments},
+		{CVEID: "CVE-2024-0003", Severity: "High", Status: models.StatusActive, FirstDetectedAt: now.Add(-60 * 24 * time.Hour), ImageScanNamespace: &payments},
+		{CVEID: "CVE-2024-0004", Severity: "High", Status: models.StatusActive, FirstDetectedAt: now.Add(-200 * 24 * time.Hour)},
+		// Closed vulnerabilities carry no debt
+		{CVEID: "CVE-2024-0005", Severity: "High", Status: models.StatusFixed, FirstDetectedAt: now.Add(-200 * 24 * time.Hour), ImageScanNamespace: &payments},
+		{CVEID: "CVE-2024-0006", Severity: "Low", Status: models.StatusAccepted, FirstDetectedAt: now, ImageScanNamespace: &payments},
+	}
+	for i, vuln := range vulns {
+		vuln.PackageName = "openssl"
+		vuln.PackageVersion = "1.1.1"
+		vuln.LastSeenAt = now
+		require.NoError(t, vulnRepo.Upsert(context.Background(), vuln), i)
+	}
+
+	age, err := service.GetVulnerabilityAge(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, AgeBuckets{Days0To7: 1, Days8To30: 1, Days31To90: 1, Over90Days: 1, Total: 4}, age.Total)
+	require.Len(t, age.Teams, 2)
+	assert.Equal(t, &payments, age.Teams[0].Namespace)
+	assert.Equal(t, AgeBuckets{Days0To7: 1, Days8To30: 1, Total: 2}, age.Teams[0].BySeverity["Critical"])
+	assert.Equal(t, AgeBuckets{Days31To90: 1, Total: 1}, age.Teams[0].BySeverity["High"])
+	assert.Equal(t, 3, age.Teams[0].Total.Total)
+	assert.Nil(t, age.Teams[1].Namespace, "vulnerabilities found outside ImageScans come last")
+	assert.Equal(t, AgeBuckets{Over90Days: 1, Total: 1}, age.Teams[1].Total)
+
+	hasFix := true
+	age, err = service.GetVulnerabilityAge(context.Background(), &hasFix, &payments)
+	require.NoError(t, err)
+	require.Len(t, age.Teams, 1)
+	assert.Equal(t, AgeBuckets{Days0To7: 1, Total: 1}, age.Total)
+}
+
+func TestGetCompliance(t *testing.T) {
+	database := db.SetupTestDatabase(t)
+	defer database.Close()
+
+	ctx := context.Background()
+	service := New(database, zap.NewNop())
+	imageRepo := db.NewImageRepository(database)
+	scanRepo := db.NewScanRepository(database)
+	vulnRepo := db.NewVulnerabilityRepository(database)
+	imageScanRepo := db.NewImageScanRepository(database)
+
+	regulated := &models.Image{Registry: "docker.io", Repository: "acme/payments", Tag: "1.0"}
+	require.NoError(t, imageRepo.Create(ctx, regulated))
+	other := &models.Image{Registry: "docker.io", Repository: "acme/blog", Tag: "1.0"}
+	require.NoError(t, imageRepo.Create(ctx, other))
+	require.NoError(t, imageScanRepo.Upsert(ctx, &models.ImageScanRegistration{
+		Namespace: "payments", Name: "api", Registry: "docker.io", Repository: "acme/payments", Tag: "1.0",
+		Labels: compliance.Labels(map[string]string{"fedramp": "high"}),
+	}))
+
+	now := time.Now()
+	link := func(image *models.Image, scanDate time.Time, vulns ...*models.Vulnerability) {
+		scan := &models.Scan{ImageID: image.ID, ScanDate: scanDate, Status: "completed"}
+		require.NoError(t, scanRepo.Create(ctx, scan))
+		for _, vuln := range vulns {
+			require.NoError(t, vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID))
+		}
+	}
+	vuln := func(cveID, severity, status string) *models.Vulnerability {
+		v := &models.Vulnerability{CVEID: cveID, PackageName: "openssl", PackageVersion: "3.0.0", Severity: severity,
+			Status: status, FirstDetectedAt: now, LastSeenAt: now}
+		require.NoError(t, vulnRepo.Upsert(ctx, v))
+		return v
+	}
+	overdue := vuln("CVE-2024-0001", "High", models.StatusActive)
+	recent := vuln("CVE-2024-0002", "High", models.StatusInProgress)
+	accepted := vuln("CVE-2024-0003", "High", models.StatusAccepted)
+	gone := vuln("CVE-2024-0004", "Critical", models.StatusActive)
+
+	// The regulated image has had the overdue finding for 40 days, the other finding only since its latest scan.
+	// The finding gone from its latest scan no longer counts
+	link(regulated, now.Add(-40*24*time.Hour), overdue, gone)
+	link(regulated, now.Add(-time.Hour), overdue, recent, accepted)
+	link(other, now.Add(-40*24*time.Hour), overdue)
+
+	profiles := []compliance.Profile{
+		{Name: "fedramp-high", Framework: "FedRAMP", Selector: map[string]string{"fedramp": "high"}, RemediationDays: compliance.RemediationDays{Critical: 15, High: 30}},
+		{Name: "soc2", Framework: "SOC 2", RemediationDays: compliance.RemediationDays{High: 60}},
+	}
+	results, err := service.GetCompliance(ctx, profiles, now)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, models.FrameworkCompliance{Profile: "fedramp-high", Framework: "FedRAMP", Images: 1, OpenFindings: 2, Overdue: 1, CompliancePercent: 50}, results[0])
+	assert.Equal(t, models.FrameworkCompliance{Profile: "soc2", Framework: "SOC 2", Images: 2, OpenFindings: 3, Overdue: 0, CompliancePercent: 100}, results[1])
+}
+
+func TestGetScanPerformance(t *testing.T) {
+	database := db.SetupTestDatabase(t)
+	defer database.Close()
+
+	ctx := context.Background()
+	service := New(database, zap.NewNop())
+	imageRepo := db.NewImageRepository(database)
+	scanRepo := db.NewScanRepository(database)
+
+	payments := "payments"
+	now := time.Now()
+	durations := map[string][]int64{
+		"api":    {20000, 40000},
+		"worker": {25000},
+		"ml":     {300000, 200000},
+	}
+	for _, repository := range []string{"api", "worker", "ml"} {
+		image := &models.Image{Registry: "ghcr.io", Repository: "acme/" + repository, Tag: "1.0"}
+		require.NoError(t, imageRepo.Create(ctx, image))
+		for i, duration := range durations[repository] {
+			size, layers := int64(100+i)<<20, 5+i
+			scan := &models.Scan{ImageID: image.ID, ScanDate: now.Add(time.Duration(i-3) * time.Hour), Status: models.ScanStatusCompleted,
+				ScanDurationMS: &duration, ImageSizeBytes: &size, LayerCount: &layers, ImageScanNamespace: &payments}
+			require.NoError(t, scanRepo.Create(ctx, scan))
+		}
+	}
+	// Scans reporting no duration, failed or outside the window are left out
+	image := &models.Image{Registry: "ghcr.io", Repository: "acme/api", Tag: "0.9"}
+	require.NoError(t, imageRepo.Create(ctx, image))
+	duration := int64(900000)
+	for _, scan := range []*models.Scan{
+		{ImageID: image.ID, ScanDate: now, Status: models.ScanStatusCompleted},
+		{ImageID: image.ID, ScanDate: now, Status: models.ScanStatusFailed, ScanDurationMS: &duration},
+		{ImageID: image.ID, ScanDate: now.AddDate(0, 0, -40), Status: models.ScanStatusCompleted, ScanDurationMS: &duration},
+	} {
+		require.NoError(t, scanRepo.Create(ctx, scan))
+	}
+
+	performance, err := service.GetScanPerformance(ctx, nil, now.AddDate(0, 0, -30))
+	require.NoError(t, err)
+	assert.Equal(t, 5, performance.Scans)
+	assert.Equal(t, int64(30000), performance.MedianDurationMS)
+	require.Len(t, performance.Images, 3)
+
+	ml := performance.Images[0]
+	assert.Equal(t, "ghcr.io/acme/ml:1.0", ml.ImageName)
+	assert.True(t, ml.Slow)
+	assert.Equal(t, 2, ml.Scans)
+	assert.Equal(t, int64(250000), ml.AvgDurationMS)
+	assert.Equal(t, int64(300000), ml.MaxDurationMS)
+	require.NotNil(t, ml.ImageSizeBytes)
+	assert.Equal(t, int64(101<<20), *ml.ImageSizeBytes, "size of the latest scan")
+	assert.Equal(t, 6, *ml.LayerCount)
+	assert.Equal(t, &payments, ml.ImageScanNamespace)
+	assert.Equal(t, "ghcr.io/acme/api:1.0", performance.Images[1].ImageName)
+	assert.False(t, performance.Images[1].Slow)
+
+	other := "search"
+	performance, err = service.GetScanPerformance(ctx, &other, now.AddDate(0, 0, -30))
+	require.NoError(t, err)
+	assert.Empty(t, performance.Images)
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, int64(3), median([]int64{5, 1, 3}))
+	assert.Equal(t, int64(2), median([]int64{4, 1}))
+}
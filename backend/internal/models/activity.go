@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Kinds of user activity
+const (
+	ActivityTriage     = "triage"     // status change of a vulnerability
+	ActivityComment    = "comment"    // notes change of a vulnerability
+	ActivityAssignment = "assignment" // vulnerability added to a campaign
+	ActivityAdmin      = "admin"      // administrative action of the audit log
+)
+
+var ValidActivityKinds = []string{ActivityTriage, ActivityComment, ActivityAssignment, ActivityAdmin}
+
+// UserActivity is an action of a user, gathered from the vulnerability history, the campaign
+// vulnerabilities and the audit log
+type UserActivity struct {
+	Kind string    `db:"kind" json:"kind"`
+	At   time.Time `db:"at" json:"at"`
+	// Vulnerability triaged, commented or assigned
+	VulnerabilityID *int    `db:"vulnerability_id" json:"vulnerability_id,omitempty"`
+	CVEID           *string `db:"cve_id" json:"cve_id,omitempty"`
+	ImageName       *string `db:"image_name" json:"image_name,omitempty"` // image context of the change
+	// Status or notes before and after a triage or a comment
+	OldValue *string `db:"old_value" json:"old_value,omitempty"`
+	NewValue *string `db:"new_value" json:"new_value,omitempty"`
+	// Audit log action of an admin activity, e.g. image.deleted
+	Action *string `db:"action" json:"action,omitempty"`
+	// Campaign of an assignment, resource of an admin activity
+	ResourceType *string `db:"resource_type" json:"resource_type,omitempty"`
+	ResourceID   *int    `db:"resource_id" json:"resource_id,omitempty"`
+	ResourceName *string `db:"resource_name" json:"resource_name,omitempty"`
+}
+
+// UserActivitySummary counts the activity of a user over a window, their triage throughput
+type UserActivitySummary struct {
+	Triaged   int `json:"triaged"`
+	Commented int `json:"commented"`
+	Assigned  int `json:"assigned"`
+	Admin     int `json:"admin"`
+	// Distinct vulnerabilities triaged, commented or assigned
+	Vulnerabilities int `json:"vulnerabilities"`
+	// Triage actions by the status they set
+	ByStatus map[string]int `json:"by_status"`
+}
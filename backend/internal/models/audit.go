@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit log actions
+const (
+	AuditActionImageDeleted         = "image.deleted"
+	AuditActionImageReviewed        = "image.reviewed"
+	AuditActionScanDeleted          = "scan.deleted"
+	AuditActionScansPruned          = "scans.pruned"
+	AuditActionSuppressionsImported = "suppressions.imported"
+)
+
+// AuditEntry records an administrative action
+type AuditEntry struct {
+	ID           int             `db:"id" json:"id"`
+	Action       string          `db:"action" json:"action"`
+	ResourceType string          `db:"resource_type" json:"resource_type"`
+	ResourceID   *int            `db:"resource_id" json:"resource_id,omitempty"`
+	ResourceName *string         `db:"resource_name" json:"resource_name,omitempty"`
+	Actor        string          `db:"actor" json:"actor"`
+	Details      json.RawMessage `db:"details" json:"details,omitempty"`
+	CreatedAt    time.Time       `db:"created_at" json:"created_at"`
+}
+
+// ImageDeletionImpact describes what DELETE /api/v1/images/:id removes
+// The confirmation token is derived from these values, so it stops matching
+// as soon as a new scan arrives and the caller has to review the impact again
+type ImageDeletionImpact struct {
+	ImageID           int    `json:"image_id"`
+	ImageName         string `json:"image_name"`
+	ScanCount         int    `json:"scan_count"`
+	SBOMCount         int    `json:"sbom_count"`
+	LatestScanID      *int   `json:"latest_scan_id,omitempty"`
+	ConfirmationToken string `json:"confirmation_token"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Campaign statuses
+const (
+	CampaignStatusActive    = "active"
+	CampaignStatusCompleted = "completed"
+	CampaignStatusCancelled = "cancelled"
+)
+
+var ValidCampaignStatuses = []string{CampaignStatusActive, CampaignStatusCompleted, CampaignStatusCancelled}
+
+// Campaign groups vulnerabilities into a remediation initiative, e.g. "OpenSSL 3.x upgrade Q3".
+// Its vulnerabilities are the ones attached to it and, when it has a filter, every vulnerability
+// matching all of the filter's criteria, including ones detected after the campaign started
+type Campaign struct {
+	ID             int            `db:"id" json:"id"`
+	Name           string         `db:"name" json:"name"`
+	Description    *string        `db:"description" json:"description,omitempty"`
+	Status         string         `db:"status" json:"status"`
+	Owners         pq.StringArray `db:"owners" json:"owners"`
+	DueAt          *time.Time     `db:"due_at" json:"due_at,omitempty"`
+	CampaignFilter `json:"filter"`
+	CreatedBy      *string   `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CampaignFilter selects vulnerabilities matching all of its criteria. An empty filter matches nothing
+type CampaignFilter struct {
+	CVEID                *string `db:"filter_cve_id" json:"cve_id,omitempty"`
+	PackageName          *string `db:"filter_package_name" json:"package_name,omitempty"`
+	PackageVersionPrefix *string `db:"filter_package_version_prefix" json:"package_version_prefix,omitempty"`
+	Severity             *string `db:"filter_severity" json:"severity,omitempty"`
+}
+
+// IsEmpty reports whether the filter has no criteria
+func (f *CampaignFilter) IsEmpty() bool {
+	return f.CVEID == nil && f.PackageName == nil && f.PackageVersionPrefix == nil && f.Severity == nil
+}
+
+// CampaignRequest is the API request format for creating a campaign
+type CampaignRequest struct {
+	Name             string          `json:"name"`
+	Description      *string         `json:"description,omitempty"`
+	Owners           []string        `json:"owners,omitempty"`
+	DueAt            *time.Time      `json:"due_at,omitempty"`
+	Filter           *CampaignFilter `json:"filter,omitempty"`
+	VulnerabilityIDs []int           `json:"vulnerability_ids,omitempty"`
+}
+
+// CampaignUpdate is the API request format for updating a campaign. Omitted fields are unchanged,
+// owners replace the current ones
+type CampaignUpdate struct {
+	Name        *string    `json:"name,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Status      *string    `json:"status,omitempty"`
+	Owners      *[]string  `json:"owners,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+}
+
+// CampaignProgress counts the vulnerabilities of a campaign by status
+type CampaignProgress struct {
+	Total        int            `db:"total" json:"total"`
+	Active       int            `db:"active" json:"active"`
+	InProgress   int            `db:"in_progress" json:"in_progress"`
+	Fixed        int            `db:"fixed" json:"fixed"`
+	Ignored      int            `db:"ignored" json:"ignored"`
+	Accepted     int            `db:"accepted" json:"accepted"`
+	PercentFixed float64        `db:"-" json:"percent_fixed"`
+	OpenBy       SeverityCounts `db:"-" json:"open_by_severity"` // active and in progress
+}
+
+// CampaignWithProgress is a campaign on the GET /api/v1/campaigns dashboard
+type CampaignWithProgress struct {
+	Campaign
+	Progress CampaignProgress `json:"progress"`
+}
+
+// CampaignBurndownPoint is the state of a campaign's vulnerabilities at the end of a day
+type CampaignBurndownPoint struct {
+	Date  string `db:"date" json:"date"` // YYYY-MM-DD
+	Open  int    `db:"open" json:"open"`
+	Fixed int    `db:"fixed" json:"fixed"`
+}
+
+// CampaignDetails is the response of GET /api/v1/campaigns/:id
+type CampaignDetails struct {
+	CampaignWithProgress
+	Burndown []CampaignBurndownPoint `json:"burndown"`
+}
+
+// SetPercentFixed computes the share of fixed vulnerabilities, rounded to one decimal
+func (p *CampaignProgress) SetPercentFixed() {
+	if p.Total == 0 {
+		p.PercentFixed = 0
+		return
+	}
+	p.PercentFixed = math.Round(float64(p.Fixed)*1000/float64(p.Total)) / 10
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCampaignProgress_SetPercentFixed(t *testing.T) {
+	p := CampaignProgress{Total: 3, Fixed: 1}
+	p.SetPercentFixed()
+	assert.Equal(t, 33.3, p.PercentFixed)
+
+	empty := CampaignProgress{}
+	empty.SetPercentFixed()
+	assert.Zero(t, empty.PercentFixed)
+}
+
+func TestCampaignFilter_IsEmpty(t *testing.T) {
+	assert.True(t, (&CampaignFilter{}).IsEmpty())
+
+	pkg := "openssl"
+	assert.False(t, (&CampaignFilter{PackageName: &pkg}).IsEmpty())
+}
@@ -0,0 +1,14 @@
+package models
+
+// FrameworkCompliance is how the open findings of the images a compliance profile applies to meet its
+// remediation timelines
+type FrameworkCompliance struct {
+	Profile      string `json:"profile"`
+	Framework    string `json:"framework"`
+	Requirement  string `json:"requirement,omitempty"`
+	Images       int    `json:"images"`
+	OpenFindings int    `json:"open_findings"`
+	Overdue      int    `json:"overdue"`
+	// Share of the open findings still within their timeline, 100 without open findings
+	CompliancePercent float64 `json:"compliance_percent"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Formats of compliance reports
+const (
+	ReportFormatHTML = "html"
+	ReportFormatPDF  = "pdf"
+)
+
+// ComplianceReport is a generated periodic report on the vulnerabilities of every image, stored in S3
+// and delivered to the destinations of its schedule
+type ComplianceReport struct {
+	ID          int64     `db:"id" json:"id"`
+	Schedule    *string   `db:"schedule" json:"schedule,omitempty"` // nil for reports generated on demand
+	Format      string    `db:"format" json:"format"`
+	PeriodStart time.Time `db:"period_start" json:"period_start"`
+	PeriodEnd   time.Time `db:"period_end" json:"period_end"`
+	SizeBytes   int64     `db:"size_bytes" json:"size_bytes"`
+	// StorageKey is the S3 key of the document, nil when it is only delivered
+	StorageKey *string `db:"storage_key" json:"-"`
+	// Destinations lists where the report was delivered: s3, webhook and the email recipients
+	Destinations pq.StringArray `db:"destinations" json:"destinations"`
+	// Errors lists the deliveries that failed, the report is not generated again for them
+	Errors      pq.StringArray `db:"errors" json:"errors,omitempty"`
+	RequestedBy *string        `db:"requested_by" json:"requested_by,omitempty"`
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+}
+
+// ReportData is the content of a compliance report over a period
+type ReportData struct {
+	Title       string        `json:"title"`
+	Locale      string        `json:"locale"` // language of the text, one of the notification locales
+	GeneratedAt time.Time     `json:"generated_at"`
+	PeriodStart time.Time     `json:"period_start"`
+	PeriodEnd   time.Time     `json:"period_end"`
+	Posture     ReportPosture `json:"posture"`
+	SLA         ReportSLA     `json:"sla"`
+	Trends      []ReportTrend `json:"trends"`
+}
+
+// ReportPosture is the vulnerability posture at the end of the period: the open vulnerabilities
+// (active or in progress) by severity
+type ReportPosture struct {
+	Images              int                 `db:"images" json:"images"`
+	ImagesWithCritical  int                 `db:"images_with_critical" json:"images_with_critical"`
+	OpenVulnerabilities int                 `db:"open_vulnerabilities" json:"open_vulnerabilities"`
+	KnownExploited      int                 `db:"known_exploited" json:"known_exploited"`
+	Severities          []ReportSeverityRow `json:"severities"`
+}
+
+// ReportSeverityRow counts the open vulnerabilities of a severity
+type ReportSeverityRow struct {
+	Severity string `db:"severity" json:"severity"`
+	Open     int    `db:"open" json:"open"`
+	Fixable  int    `db:"fixable" json:"fixable"`
+}
+
+// ReportSLA is the SLA compliance of the open vulnerabilities at the end of the period
+type ReportSLA struct {
+	WithinSLA  int            `json:"within_sla"`
+	Breached   int            `json:"breached"`
+	Compliance float64        `json:"compliance"` // percentage of open vulnerabilities within their SLA, 100 without any
+	Severities []ReportSLARow `json:"severities"`
+	Oldest     []SLABreach    `json:"oldest_breaches"` // the longest overdue, at most 10
+}
+
+// ReportSLARow is the SLA compliance of the open vulnerabilities of an SLA severity
+type ReportSLARow struct {
+	Severity  string `json:"severity"`
+	WithinSLA int    `json:"within_sla"`
+	Breached  int    `json:"breached"`
+}
+
+// ReportTrend counts the vulnerabilities found and fixed on a day of the period
+type ReportTrend struct {
+	Date  string `db:"date" json:"date"` // YYYY-MM-DD in UTC
+	New   int    `db:"new" json:"new"`
+	Fixed int    `db:"fixed" json:"fixed"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Component is a vulnerable package identified by its PURL, with the images it was found in
+type Component struct {
+	PURL            string           `json:"purl"`
+	Name            string           `json:"name"`
+	Version         string           `json:"version"`
+	Type            *string          `json:"type,omitempty"`
+	Images          []ComponentImage `json:"images"`
+	Vulnerabilities []Vulnerability  `json:"vulnerabilities"`
+}
+
+// ComponentImage is an image a component was found in, with the latest scan that reported it
+type ComponentImage struct {
+	ImageID        int       `db:"image_id" json:"image_id"`
+	ImageName      string    `db:"image_name" json:"image_name"`
+	LatestScanID   int       `db:"latest_scan_id" json:"latest_scan_id"`
+	LatestScanDate time.Time `db:"latest_scan_date" json:"latest_scan_date"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// InventorySourceController is the source of the images reported by the controller's Pod discovery
+const InventorySourceController = "controller"
+
+// DeployedImage is an image reported running in a cluster
+type DeployedImage struct {
+	ID         int       `db:"id" json:"id"`
+	Source     string    `db:"source" json:"source"`
+	Namespace  string    `db:"namespace" json:"namespace,omitempty"`
+	Image      string    `db:"image" json:"image"`
+	Registry   string    `db:"registry" json:"registry"`
+	Repository string    `db:"repository" json:"repository"`
+	Tag        string    `db:"tag" json:"tag"`
+	Digest     *string   `db:"digest" json:"digest,omitempty"`
+	ReportedAt time.Time `db:"reported_at" json:"reported_at"`
+	// Workloads running the image in the namespace
+	Workloads []InventoryWorkload `db:"-" json:"workloads,omitempty"`
+}
+
+// InventoryRequest is an inventory of deployed images, replacing the previous one of its source
+type InventoryRequest struct {
+	Images []InventoryImage `json:"images"`
+}
+
+// InventoryImage is an image of an inventory and the namespace running it
+type InventoryImage struct {
+	Image     string              `json:"image"`
+	Namespace string              `json:"namespace,omitempty"`
+	Workloads []InventoryWorkload `json:"workloads,omitempty"`
+}
+
+// InventoryWorkload is a workload running an image of an inventory
+type InventoryWorkload struct {
+	Kind string `json:"kind"` // Deployment, StatefulSet, DaemonSet, ReplicaSet, Job or Pod
+	Name string `json:"name"`
+	// Running and pending Pods of the workload
+	Replicas int `json:"replicas"`
+	// Whether an Ingress or a LoadBalancer Service routes to the workload
+	Exposed bool `json:"exposed,omitempty"`
+}
+
+// Coverage statuses of deployed images
+const (
+	CoverageScanned   = "scanned"   // the latest successful scan is recent
+	CoverageStale     = "stale"     // the latest successful scan is older than the stale threshold
+	CoverageUnscanned = "unscanned" // never scanned successfully
+)
+
+// ImageCoverage is how a deployed image is covered by scans
+type ImageCoverage struct {
+	Registry   string         `db:"registry" json:"registry"`
+	Repository string         `db:"repository" json:"repository"`
+	Tag        string         `db:"tag" json:"tag,omitempty"`
+	Digest     *string        `db:"digest" json:"digest,omitempty"`
+	Namespaces pq.StringArray `db:"namespaces" json:"namespaces"`
+	Sources    pq.StringArray `db:"sources" json:"sources"`
+	// Latest report of the image by any source
+	LastReportedAt time.Time `db:"last_reported_at" json:"last_reported_at"`
+	// Scanned image matching the reference (by tag, or by digest when pinned), nil when never scanned
+	ImageID      *int       `db:"image_id" json:"image_id,omitempty"`
+	LastScanDate *time.Time `db:"last_scan_date" json:"last_scan_date,omitempty"`
+	// Whether an ImageScan scans the image, otherwise only ad hoc or CI scans cover it
+	HasImageScan bool   `db:"has_imagescan" json:"has_imagescan"`
+	Status       string `db:"-" json:"status"`
+}
+
+// CoverageSummary counts deployed images by coverage
+type CoverageSummary struct {
+	Deployed         int `json:"deployed"`
+	Scanned          int `json:"scanned"`
+	Stale            int `json:"stale"`
+	Unscanned        int `json:"unscanned"`
+	WithoutImageScan int `json:"without_imagescan"`
+	// Share of deployed images with a recent scan, 100 without deployed images
+	CoveragePercent float64 `json:"coverage_percent"`
+}
+
+// Workload is a deployed workload running an image
+type Workload struct {
+	Namespace  string    `db:"namespace" json:"namespace"`
+	Kind       string    `db:"kind" json:"kind"`
+	Name       string    `db:"name" json:"name"`
+	Replicas   int       `db:"replicas" json:"replicas"`
+	Exposed    bool      `db:"exposed" json:"exposed"` // behind an Ingress or a LoadBalancer Service
+	Image      string    `db:"image" json:"image"`     // as deployed
+	ImageID    int       `db:"image_id" json:"image_id"`
+	Source     string    `db:"source" json:"source"`
+	ReportedAt time.Time `db:"reported_at" json:"reported_at"`
+}
+
+// VulnerabilityWorkloads are the workloads running the images whose latest successful scan has a vulnerability
+type VulnerabilityWorkloads struct {
+	VulnerabilityID int        `json:"vulnerability_id"`
+	CVEID           string     `json:"cve_id"`
+	Namespaces      int        `json:"namespaces"`
+	Replicas        int        `json:"replicas"`
+	Exposed         int        `json:"exposed"` // internet-exposed workloads
+	Workloads       []Workload `json:"workloads"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Diagnostics is a snapshot of the runtime of one backend replica, to debug memory leaks and
+// stalls in production
+type Diagnostics struct {
+	Instance      string              `json:"instance"`
+	GoVersion     string              `json:"go_version"`
+	StartedAt     time.Time           `json:"started_at"`
+	UptimeSeconds int64               `json:"uptime_seconds"`
+	Goroutines    int                 `json:"goroutines"`
+	GOMAXPROCS    int                 `json:"gomaxprocs"`
+	Memory        DiagnosticsMemory   `json:"memory"`
+	Database      DiagnosticsDatabase `json:"database"`
+	// Queues are the depths of the work queues of the replica by name. A queue that failed to
+	// report is left out and named in Errors
+	Queues map[string]int    `json:"queues"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// DiagnosticsMemory is the heap and garbage collector state, from runtime.MemStats
+type DiagnosticsMemory struct {
+	HeapAllocBytes   uint64     `json:"heap_alloc_bytes"`
+	HeapInuseBytes   uint64     `json:"heap_inuse_bytes"`
+	HeapObjects      uint64     `json:"heap_objects"`
+	SysBytes         uint64     `json:"sys_bytes"`
+	NumGC            uint32     `json:"num_gc"`
+	LastGC           *time.Time `json:"last_gc,omitempty"`
+	PauseTotalMillis float64    `json:"pause_total_ms"`
+}
+
+// DiagnosticsDatabase is the state of the connection pool, from sql.DBStats
+type DiagnosticsDatabase struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitMillis         float64 `json:"wait_ms"`
+}
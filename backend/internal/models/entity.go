@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// BackstageEntityLabel links ImageScans to a Backstage catalog entity: the label the Kubernetes plugin of
+// Backstage selects resources with, from the backstage.io/kubernetes-id annotation of the entity
+const BackstageEntityLabel = "backstage.io/kubernetes-id"
+
+// Security health of an entity, from the open findings of its images
+const (
+	EntityHealthCritical = "critical" // an open Critical finding
+	EntityHealthWarning  = "warning"  // an open High finding
+	EntityHealthOK       = "ok"
+	EntityHealthUnknown  = "unknown" // none of its images was scanned yet
+)
+
+// EntitySummary is the security health of a Backstage catalog entity for
+// GET /api/v1/entities/:team/:service/summary, from the ImageScans of its namespace linked to it
+type EntitySummary struct {
+	Team    string `json:"team"`
+	Service string `json:"service"`
+	Health  string `json:"health"`
+	// Open findings of the latest scans of its images, as listed in Images
+	Critical       int                `json:"critical_count"`
+	High           int                `json:"high_count"`
+	Medium         int                `json:"medium_count"`
+	Low            int                `json:"low_count"`
+	LatestScanDate *time.Time         `json:"latest_scan_date,omitempty"`
+	Images         []ImageScanSummary `json:"images"`
+}
+
+// NewEntitySummary sums the open findings of the ImageScans of an entity. An image scanned by
+// several of them is counted once
+func NewEntitySummary(team, service string, scans []ImageScanSummary) EntitySummary {
+	summary := EntitySummary{Team: team, Service: service, Health: EntityHealthUnknown, Images: scans}
+	counted := map[int]bool{}
+	for _, scan := range scans {
+		if scan.LatestScanID == nil || counted[*scan.LatestScanID] {
+			continue
+		}
+		counted[*scan.LatestScanID] = true
+		summary.Critical += scan.Critical
+		summary.High += scan.High
+		summary.Medium += scan.Medium
+		summary.Low += scan.Low
+		if summary.LatestScanDate == nil || scan.LatestScanDate.After(*summary.LatestScanDate) {
+			summary.LatestScanDate = scan.LatestScanDate
+		}
+	}
+
+	switch {
+	case len(counted) == 0:
+	case summary.Critical > 0:
+		summary.Health = EntityHealthCritical
+	case summary.High > 0:
+		summary.Health = EntityHealthWarning
+	default:
+		summary.Health = EntityHealthOK
+	}
+	return summary
+}
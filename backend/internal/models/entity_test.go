@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEntitySummary(t *testing.T) {
+	scanID, otherScanID := 7, 9
+	june, july := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	api := ImageScanSummary{Name: "checkout-api", LatestScanID: &scanID, LatestScanDate: &june, High: 2, Medium: 5}
+	// Another ImageScan of the same image
+	apiCanary := ImageScanSummary{Name: "checkout-api-canary", LatestScanID: &scanID, LatestScanDate: &june, High: 2, Medium: 5}
+	worker := ImageScanSummary{Name: "checkout-worker", LatestScanID: &otherScanID, LatestScanDate: &july, Low: 1}
+	pending := ImageScanSummary{Name: "checkout-cron"}
+
+	summary := NewEntitySummary("payments", "checkout", []ImageScanSummary{api, apiCanary, worker, pending})
+	assert.Equal(t, EntityHealthWarning, summary.Health)
+	assert.Equal(t, [4]int{0, 2, 5, 1}, [4]int{summary.Critical, summary.High, summary.Medium, summary.Low})
+	assert.Equal(t, &july, summary.LatestScanDate)
+	assert.Len(t, summary.Images, 4)
+
+	worker.Critical = 1
+	assert.Equal(t, EntityHealthCritical, NewEntitySummary("payments", "checkout", []ImageScanSummary{worker}).Health)
+	assert.Equal(t, EntityHealthOK, NewEntitySummary("payments", "checkout", []ImageScanSummary{{LatestScanID: &scanID, LatestScanDate: &june}}).Health)
+	assert.Equal(t, EntityHealthUnknown, NewEntitySummary("payments", "checkout", []ImageScanSummary{pending}).Health)
+}
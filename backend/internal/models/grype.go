@@ -1,5 +1,14 @@
 package models
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
 // GrypeResult represents the structure of Grype's JSON output
 type GrypeResult struct {
 	Matches    []GrypeMatch    `json:"matches"`
@@ -25,6 +34,8 @@ type GrypeVulnerability struct {
 	Cvss        []GrypeCVSS     `json:"cvss,omitempty"`
 	Fix         *GrypeFix       `json:"fix,omitempty"`
 	Advisories  []GrypeAdvisory `json:"advisories,omitempty"`
+	// Entries of the CISA Known Exploited Vulnerabilities catalog, reported by Grype database v6
+	KnownExploited []GrypeKnownExploited `json:"knownExploited,omitempty"`
 }
 
 type GrypeRelatedVuln struct {
@@ -49,6 +60,12 @@ type GrypeAdvisory struct {
 	Link string `json:"link,omitempty"`
 }
 
+type GrypeKnownExploited struct {
+	CVE                        string `json:"cve"`
+	DateAdded                  string `json:"dateAdded,omitempty"`
+	KnownRansomwareCampaignUse string `json:"knownRansomwareCampaignUse,omitempty"`
+}
+
 type GrypeMatchDetail struct {
 	Type       string                 `json:"type"`
 	Matcher    string                 `json:"matcher"`
@@ -81,6 +98,66 @@ type GrypeDescriptor struct {
 	Name          string                 `json:"name"`
 	Version       string                 `json:"version"`
 	Configuration map[string]interface{} `json:"configuration,omitempty"`
+	DB            *GrypeDBDescriptor     `json:"db,omitempty"`
+}
+
+// GrypeDBDescriptor describes the vulnerability database a Grype run used.
+// Grype >= 0.88 nests the build information under "status".
+type GrypeDBDescriptor struct {
+	Built         string             `json:"built,omitempty"`
+	SchemaVersion interface{}        `json:"schemaVersion,omitempty"` // int in older releases, "v6.0.2" in newer ones
+	Status        *GrypeDBDescriptor `json:"status,omitempty"`
+}
+
+// BuildInfo returns the database build time and schema version, whichever layout was used
+func (d *GrypeDBDescriptor) BuildInfo() (built *time.Time, schema *string) {
+	if d == nil {
+		return nil, nil
+	}
+	if d.Built == "" && d.Status != nil {
+		return d.Status.BuildInfo()
+	}
+
+	if d.Built != "" {
+		if t, err := time.Parse(time.RFC3339, d.Built); err == nil {
+			built = &t
+		}
+	}
+	if d.SchemaVersion != nil {
+		s := fmt.Sprint(d.SchemaVersion)
+		schema = &s
+	}
+	return built, schema
+}
+
+// Fingerprint hashes the match fields the backend stores, independently of their order.
+// Two submissions with the same fingerprint produce the same findings, so scanner-side
+// filtering (e.g. only fixable vulnerabilities) never lets one reuse the other's results.
+// Scanners compute it too for delta submissions (scanner/scanner.sh does it with jq): a change
+// here makes their next delta submissions miss and upload the full results once
+func (r *GrypeResult) Fingerprint() string {
+	lines := make([]string, 0, len(r.Matches))
+	for _, m := range r.Matches {
+		var fix, url string
+		if m.Vulnerability.Fix != nil && len(m.Vulnerability.Fix.Versions) > 0 {
+			fix = m.Vulnerability.Fix.Versions[0]
+		}
+		if len(m.Vulnerability.URLs) > 0 {
+			url = m.Vulnerability.URLs[0]
+		}
+		lines = append(lines, strings.Join([]string{
+			m.Vulnerability.ID, m.Artifact.Name, m.Artifact.Version, m.Artifact.Type,
+			m.Vulnerability.Severity, fix, url, m.Vulnerability.Description,
+		}, "\x00"))
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 type GrypeDistro struct {
@@ -88,3 +165,47 @@ type GrypeDistro struct {
 	Version string   `json:"version"`
 	IDLike  []string `json:"idLike,omitempty"`
 }
+
+// DistroInfo returns the detected distribution. Grype reports an empty distro for distroless
+// and scratch images, which is returned as no distribution rather than empty strings
+func (r *GrypeResult) DistroInfo() (name, version *string, idLike []string) {
+	if r.Distro == nil || strings.TrimSpace(r.Distro.Name) == "" {
+		return nil, nil, nil
+	}
+	n := strings.TrimSpace(r.Distro.Name)
+	name = &n
+	if v := strings.TrimSpace(r.Distro.Version); v != "" {
+		version = &v
+	}
+	for _, id := range r.Distro.IDLike {
+		if id = strings.TrimSpace(id); id != "" {
+			idLike = append(idLike, id)
+		}
+	}
+	return name, version, idLike
+}
+
+// ImageInfo returns the size and number of layers of the scanned image, which Grype reports when
+// it scanned the image itself. Unset for SBOM and directory sources
+func (r *GrypeResult) ImageInfo() (sizeBytes *int64, layers *int) {
+	if r.Source == nil || r.Source.Type != "image" {
+		return nil, nil
+	}
+	if size, ok := r.Source.Target["imageSize"].(float64); ok && size > 0 {
+		s := int64(size)
+		sizeBytes = &s
+	}
+	if list, ok := r.Source.Target["layers"].([]interface{}); ok && len(list) > 0 {
+		n := len(list)
+		layers = &n
+	}
+	return sizeBytes, layers
+}
+
+// GrypeResultArchive is the metadata of the raw Grype result stored for a scan
+type GrypeResultArchive struct {
+	ID        int       `db:"id" json:"id"`
+	ScanID    int       `db:"scan_id" json:"scan_id"`
+	SizeBytes *int64    `db:"size_bytes" json:"size_bytes,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
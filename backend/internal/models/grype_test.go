@@ -57,3 +57,122 @@ func TestGrypeVulnerability_WithFix(t *testing.T) {
 	assert.Len(t, vuln.Fix.Versions, 2)
 	assert.Equal(t, "1.2.3", vuln.Fix.Versions[0])
 }
+
+func TestGrypeVulnerability_KnownExploited(t *testing.T) {
+	data := `{
+		"id": "CVE-2021-44228",
+		"severity": "Critical",
+		"knownExploited": [{"cve": "CVE-2021-44228", "dateAdded": "2021-12-10", "knownRansomwareCampaignUse": "Known"}]
+	}`
+
+	var vuln GrypeVulnerability
+	require.NoError(t, json.Unmarshal([]byte(data), &vuln))
+	require.Len(t, vuln.KnownExploited, 1)
+	assert.Equal(t, "2021-12-10", vuln.KnownExploited[0].DateAdded)
+	assert.Equal(t, "Known", vuln.KnownExploited[0].KnownRansomwareCampaignUse)
+}
+
+func TestGrypeDBDescriptor_BuildInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		descriptor string
+		wantBuilt  string
+		wantSchema string
+	}{
+		{
+			name:       "flat layout",
+			descriptor: `{"name":"grype","version":"0.74.0","db":{"built":"2024-01-15T01:30:00Z","schemaVersion":5}}`,
+			wantBuilt:  "2024-01-15T01:30:00Z",
+			wantSchema: "5",
+		},
+		{
+			name:       "nested status layout",
+			descriptor: `{"name":"grype","version":"0.87.0","db":{"status":{"built":"2025-02-01T04:12:00Z","schemaVersion":"v6.0.2"}}}`,
+			wantBuilt:  "2025-02-01T04:12:00Z",
+			wantSchema: "v6.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var descriptor GrypeDescriptor
+			require.NoError(t, json.Unmarshal([]byte(tt.descriptor), &descriptor))
+
+			built, schema := descriptor.DB.BuildInfo()
+			require.NotNil(t, built)
+			require.NotNil(t, schema)
+			assert.Equal(t, tt.wantBuilt, built.UTC().Format("2006-01-02T15:04:05Z"))
+			assert.Equal(t, tt.wantSchema, *schema)
+		})
+	}
+}
+
+func TestGrypeDBDescriptor_BuildInfoMissing(t *testing.T) {
+	var descriptor GrypeDescriptor
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"grype","version":"0.65.0"}`), &descriptor))
+
+	built, schema := descriptor.DB.BuildInfo()
+	assert.Nil(t, built)
+	assert.Nil(t, schema)
+}
+
+func TestGrypeResult_Fingerprint(t *testing.T) {
+	openssl := GrypeMatch{
+		Vulnerability: GrypeVulnerability{ID: "CVE-2023-1234", Severity: "High", Fix: &GrypeFix{Versions: []string{"1.1.1w"}}},
+		Artifact:      GrypeArtifact{Name: "openssl", Version: "1.1.1", Type: "deb"},
+	}
+	zlib := GrypeMatch{
+		Vulnerability: GrypeVulnerability{ID: "CVE-2023-5678", Severity: "Medium"},
+		Artifact:      GrypeArtifact{Name: "zlib", Version: "1.2.13", Type: "deb"},
+	}
+
+	both := &GrypeResult{Matches: []GrypeMatch{openssl, zlib}}
+	reordered := &GrypeResult{Matches: []GrypeMatch{zlib, openssl}}
+	assert.Equal(t, both.Fingerprint(), reordered.Fingerprint())
+
+	// Filtering out unfixable findings changes the results
+	onlyFixable := &GrypeResult{Matches: []GrypeMatch{openssl}}
+	assert.NotEqual(t, both.Fingerprint(), onlyFixable.Fingerprint())
+
+	rated := zlib
+	rated.Vulnerability.Severity = "High"
+	assert.NotEqual(t, both.Fingerprint(), (&GrypeResult{Matches: []GrypeMatch{openssl, rated}}).Fingerprint())
+}
+
+func TestGrypeResult_DistroInfo(t *testing.T) {
+	result := &GrypeResult{Distro: &GrypeDistro{Name: "debian", Version: "12", IDLike: []string{"", "debian"}}}
+	name, version, idLike := result.DistroInfo()
+	require.NotNil(t, name)
+	require.NotNil(t, version)
+	assert.Equal(t, "debian", *name)
+	assert.Equal(t, "12", *version)
+	assert.Equal(t, []string{"debian"}, idLike)
+
+	// Distroless and scratch images report an empty distro
+	for _, result := range []*GrypeResult{{}, {Distro: &GrypeDistro{}}} {
+		name, version, idLike := result.DistroInfo()
+		assert.Nil(t, name)
+		assert.Nil(t, version)
+		assert.Nil(t, idLike)
+	}
+}
+
+func TestGrypeResult_ImageInfo(t *testing.T) {
+	var result GrypeResult
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"matches": [],
+		"source": {"type": "image", "target": {"imageSize": 77823477, "layers": [{"digest": "sha256:a"}, {"digest": "sha256:b"}]}}
+	}`), &result))
+	size, layers := result.ImageInfo()
+	require.NotNil(t, size)
+	require.NotNil(t, layers)
+	assert.Equal(t, int64(77823477), *size)
+	assert.Equal(t, 2, *layers)
+
+	// Scans of an SBOM don't know the image
+	for _, result := range []*GrypeResult{{}, {Source: &GrypeSource{Type: "sbom", Target: map[string]interface{}{"imageSize": 1.0}}}} {
+		size, layers := result.ImageInfo()
+		assert.Nil(t, size)
+		assert.Nil(t, layers)
+	}
+}
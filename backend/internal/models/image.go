@@ -3,13 +3,43 @@ package models
 import "time"
 
 type Image struct {
-	ID         int       `db:"id" json:"id"`
-	Registry   string    `db:"registry" json:"registry"`
-	Repository string    `db:"repository" json:"repository"`
-	Tag        string    `db:"tag" json:"tag"`
-	Digest     *string   `db:"digest" json:"digest,omitempty"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+	ID         int     `db:"id" json:"id"`
+	Registry   string  `db:"registry" json:"registry"`
+	Repository string  `db:"repository" json:"repository"`
+	Tag        string  `db:"tag" json:"tag"`
+	Digest     *string `db:"digest" json:"digest,omitempty"`
+	Monitoring string  `db:"monitoring" json:"monitoring"` // active, paused
+	// Assigned on review, an image first seen in a scan has neither until then
+	Team        *string    `db:"team" json:"team,omitempty"`
+	Criticality *string    `db:"criticality" json:"criticality,omitempty"`
+	ReviewedAt  *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"` // unset while unreviewed
+	ReviewedBy  *string    `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	// Discovered is set by ImageRepository.Create when the image was never seen before
+	Discovered bool `db:"-" json:"-"`
+}
+
+// Image monitoring states
+const (
+	ImageMonitoringActive = "active"
+	ImageMonitoringPaused = "paused" // Every ImageScan scanning the image is suspended
+)
+
+// Image criticalities, how much the business depends on an image
+const (
+	ImageCriticalityLow      = "low"
+	ImageCriticalityMedium   = "medium"
+	ImageCriticalityHigh     = "high"
+	ImageCriticalityCritical = "critical"
+)
+
+var ValidImageCriticalities = []string{ImageCriticalityLow, ImageCriticalityMedium, ImageCriticalityHigh, ImageCriticalityCritical}
+
+// ImageReviewRequest is the request body for PATCH /api/v1/images/:id. Omitted fields are unchanged
+type ImageReviewRequest struct {
+	Team        *string `json:"team,omitempty"`
+	Criticality *string `json:"criticality,omitempty"`
 }
 
 type ImageWithStats struct {
@@ -20,6 +50,24 @@ type ImageWithStats struct {
 	HighCount     int        `db:"high_count" json:"high_count"`
 	MediumCount   int        `db:"medium_count" json:"medium_count"`
 	LowCount      int        `db:"low_count" json:"low_count"`
+	// AliasCount is the number of other images with the same digest, see ImageAlias
+	AliasCount int `db:"alias_count" json:"alias_count"`
+}
+
+// ImageAlias is another image with the same digest: the same content pushed under another name or
+// tag, whose findings are the same
+type ImageAlias struct {
+	ID           int        `db:"id" json:"id"`
+	Registry     string     `db:"registry" json:"registry"`
+	Repository   string     `db:"repository" json:"repository"`
+	Tag          string     `db:"tag" json:"tag"`
+	LastScanDate *time.Time `db:"last_scan_date" json:"last_scan_date,omitempty"`
+}
+
+// ImageDetails is an image with the other names it is known as
+type ImageDetails struct {
+	Image
+	AlsoKnownAs []ImageAlias `json:"also_known_as"`
 }
 
 func (i *Image) FullName() string {
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ImageScanRegistration is an ImageScan resource known to the controller
+type ImageScanRegistration struct {
+	Namespace         string     `db:"namespace" json:"namespace"`
+	Name              string     `db:"name" json:"name"`
+	Registry          string     `db:"registry" json:"registry"`
+	Repository        string     `db:"repository" json:"repository"`
+	Tag               string     `db:"tag" json:"tag"`
+	Suspended         bool       `db:"suspended" json:"suspended"`
+	StaleAfterSeconds *int       `db:"stale_after_seconds" json:"stale_after_seconds,omitempty"`
+	StaleNotifiedAt   *time.Time `db:"stale_notified_at" json:"stale_notified_at,omitempty"`
+	MaxScansRetained  *int       `db:"max_scans_retained" json:"max_scans_retained,omitempty"`
+	MaxScanAgeSeconds *int       `db:"max_scan_age_seconds" json:"max_scan_age_seconds,omitempty"`
+	Exposure          *string    `db:"exposure" json:"exposure,omitempty"`
+	// metadata.labels of the ImageScan, as sorted key=value pairs
+	Labels    pq.StringArray `db:"labels" json:"labels"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// ImageScanRegistrationRequest is the API request format sent by the controller
+type ImageScanRegistrationRequest struct {
+	Image             string  `json:"image"`
+	Suspended         bool    `json:"suspended"`
+	StaleAfterSeconds *int    `json:"stale_after_seconds,omitempty"`
+	MaxScansRetained  *int    `json:"max_scans_retained,omitempty"`
+	MaxScanAgeSeconds *int    `json:"max_scan_age_seconds,omitempty"`
+	Exposure          *string `json:"exposure,omitempty"`
+	// metadata.labels of the ImageScan, matched by compliance profiles
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ImageScanSummary is an ImageScan with the open findings of the latest successful scan of its
+// whole image, listed by GET /api/v1/imagescans. The scan fields are empty until the image is scanned
+type ImageScanSummary struct {
+	Namespace      string     `db:"namespace" json:"namespace"`
+	Name           string     `db:"name" json:"name"`
+	ImageName      string     `db:"image_name" json:"image_name"`
+	Suspended      bool       `db:"suspended" json:"suspended"`
+	ImageID        *int       `db:"image_id" json:"image_id,omitempty"`
+	LatestScanID   *int       `db:"latest_scan_id" json:"latest_scan_id,omitempty"`
+	LatestScanDate *time.Time `db:"latest_scan_date" json:"latest_scan_date,omitempty"`
+	Critical       int        `db:"critical_count" json:"critical_count"`
+	High           int        `db:"high_count" json:"high_count"`
+	Medium         int        `db:"medium_count" json:"medium_count"`
+	Low            int        `db:"low_count" json:"low_count"`
+}
+
+// RetentionPolicy is the scan retention of an image, combined from the ImageScans scanning it.
+// The most lenient hint wins, so one ImageScan can't prune history another one keeps
+type RetentionPolicy struct {
+	ImageID           int    `db:"image_id" json:"image_id"`
+	ImageName         string `db:"image_name" json:"image_name"`
+	MaxScansRetained  *int   `db:"max_scans_retained" json:"max_scans_retained,omitempty"`
+	MaxScanAgeSeconds *int   `db:"max_scan_age_seconds" json:"max_scan_age_seconds,omitempty"`
+}
+
+// StaleImageScan is an ImageScan whose image has not been scanned successfully for longer than expected
+type StaleImageScan struct {
+	Namespace              string     `db:"namespace" json:"namespace"`
+	Name                   string     `db:"name" json:"name"`
+	ImageID                int        `db:"image_id" json:"image_id"`
+	ImageName              string     `db:"image_name" json:"image_name"`
+	ImageCreatedAt         time.Time  `db:"image_created_at" json:"-"`
+	LastSuccessfulScanDate *time.Time `db:"last_successful_scan_date" json:"last_successful_scan_date,omitempty"`
+	StaleAfterSeconds      int        `db:"stale_after_seconds" json:"stale_after_seconds"`
+	StaleNotifiedAt        *time.Time `db:"stale_notified_at" json:"stale_notified_at,omitempty"`
+}
+
+// StaleSince is when the image crossed the threshold. An image that never had a
+// successful scan is measured from when it was first seen
+func (s *StaleImageScan) StaleSince() time.Time {
+	since := s.ImageCreatedAt
+	if s.LastSuccessfulScanDate != nil {
+		since = *s.LastSuccessfulScanDate
+	}
+	return since.Add(time.Duration(s.StaleAfterSeconds) * time.Second)
+}
+
+// Notified reports whether an alert was already sent for the current outage,
+// i.e. after the last successful scan
+func (s *StaleImageScan) Notified() bool {
+	if s.StaleNotifiedAt == nil {
+		return false
+	}
+	return s.LastSuccessfulScanDate == nil || s.StaleNotifiedAt.After(*s.LastSuccessfulScanDate)
+}
+
+// StaleImage groups the stale ImageScans of an image for GET /api/v1/images?stale=true
+type StaleImage struct {
+	ImageID                int        `json:"image_id"`
+	ImageName              string     `json:"image_name"`
+	LastSuccessfulScanDate *time.Time `json:"last_successful_scan_date,omitempty"`
+	StaleAfterSeconds      int        `json:"stale_after_seconds"`
+	StaleSince             time.Time  `json:"stale_since"`
+	ImageScans             []string   `json:"imagescans"`
+}
+
+// GroupStaleImages collapses ImageScan rows ordered by image into one entry per image,
+// keeping the strictest threshold
+func GroupStaleImages(scans []StaleImageScan) []StaleImage {
+	images := []StaleImage{}
+	for i := range scans {
+		scan := &scans[i]
+		ref := scan.Namespace + "/" + scan.Name
+		if n := len(images); n > 0 && images[n-1].ImageID == scan.ImageID {
+			last := &images[n-1]
+			last.ImageScans = append(last.ImageScans, ref)
+			if scan.StaleAfterSeconds < last.StaleAfterSeconds {
+				last.StaleAfterSeconds = scan.StaleAfterSeconds
+				last.StaleSince = scan.StaleSince()
+			}
+			continue
+		}
+		images = append(images, StaleImage{
+			ImageID:                scan.ImageID,
+			ImageName:              scan.ImageName,
+			LastSuccessfulScanDate: scan.LastSuccessfulScanDate,
+			StaleAfterSeconds:      scan.StaleAfterSeconds,
+			StaleSince:             scan.StaleSince(),
+			ImageScans:             []string{ref},
+		})
+	}
+	return images
+}
+
+// Sizing recommendation rules. Extracting an image takes about twice its size on disk, and the
+// scanner needs about a gibibyte of memory for the Grype database besides the packages of the image
+const (
+	sizingWorkspaceFactor   = 2
+	sizingMemoryBaseBytes   = 1 << 30
+	sizingWorkspaceRounding = 1 << 30
+	sizingMemoryRounding    = 256 << 20
+)
+
+// ImageScanSizing is the workspace and memory recommended for the Jobs of an ImageScan, from the
+// largest image its recent scans reported
+type ImageScanSizing struct {
+	Namespace      string    `json:"namespace"`
+	Name           string    `json:"name"`
+	Since          time.Time `json:"since"`
+	Scans          int       `db:"scans" json:"scans"`
+	ImageSizeBytes int64     `db:"image_size_bytes" json:"image_size_bytes"`
+	LayerCount     int       `db:"layer_count" json:"layer_count"`
+	// As Kubernetes quantities, e.g. 2Gi and 1280Mi
+	WorkspaceSize string `json:"workspace_size"`
+	MemoryRequest string `json:"memory_request"`
+}
+
+// Recommend sets the workspace size and memory request from the image size: twice the image
+// rounded up to a whole Gi, and the memory base plus half the image rounded up to 256Mi
+func (s *ImageScanSizing) Recommend() {
+	workspace := roundUp(s.ImageSizeBytes*sizingWorkspaceFactor, sizingWorkspaceRounding)
+	s.WorkspaceSize = fmt.Sprintf("%dGi", max(workspace, sizingWorkspaceRounding)>>30)
+	memory := roundUp(sizingMemoryBaseBytes+s.ImageSizeBytes/2, sizingMemoryRounding)
+	s.MemoryRequest = fmt.Sprintf("%dMi", memory>>20)
+}
+
+func roundUp(n, unit int64) int64 {
+	return (n + unit - 1) / unit * unit
+}
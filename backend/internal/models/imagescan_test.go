@@ -0,0 +1,58 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleImageScan_Notified(t *testing.T) {
+	lastScan := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	before, after := lastScan.Add(-time.Hour), lastScan.Add(72*time.Hour)
+
+	assert.False(t, (&StaleImageScan{LastSuccessfulScanDate: &lastScan}).Notified())
+	assert.True(t, (&StaleImageScan{LastSuccessfulScanDate: &lastScan, StaleNotifiedAt: &after}).Notified())
+	// Alerted for an earlier outage, the scanner recovered and then stopped again
+	assert.False(t, (&StaleImageScan{LastSuccessfulScanDate: &lastScan, StaleNotifiedAt: &before}).Notified())
+	assert.True(t, (&StaleImageScan{StaleNotifiedAt: &before}).Notified())
+}
+
+func TestGroupStaleImages(t *testing.T) {
+	lastScan := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	created := lastScan.Add(-24 * time.Hour)
+	day, week := 86400, 7*86400
+
+	images := GroupStaleImages([]StaleImageScan{
+		{Namespace: "prod", Name: "nginx", ImageID: 1, ImageName: "docker.io/library/nginx:latest", LastSuccessfulScanDate: &lastScan, StaleAfterSeconds: week},
+		{Namespace: "staging", Name: "nginx", ImageID: 1, ImageName: "docker.io/library/nginx:latest", LastSuccessfulScanDate: &lastScan, StaleAfterSeconds: day},
+		{Namespace: "prod", Name: "redis", ImageID: 2, ImageName: "docker.io/library/redis:7.2", ImageCreatedAt: created, StaleAfterSeconds: day},
+	})
+
+	require.Len(t, images, 2)
+	assert.Equal(t, []string{"prod/nginx", "staging/nginx"}, images[0].ImageScans)
+	assert.Equal(t, day, images[0].StaleAfterSeconds)
+	assert.Equal(t, lastScan.Add(24*time.Hour), images[0].StaleSince)
+
+	// Never scanned successfully: measured from when the image was first seen
+	assert.Nil(t, images[1].LastSuccessfulScanDate)
+	assert.Equal(t, lastScan, images[1].StaleSince)
+}
+
+func TestImageScanSizing_Recommend(t *testing.T) {
+	for _, tc := range []struct {
+		imageSize         int64
+		workspace, memory string
+	}{
+		// A 50MB image needs neither the 10Gi default workspace nor more than the base memory
+		{imageSize: 50 << 20, workspace: "1Gi", memory: "1280Mi"},
+		{imageSize: 1 << 30, workspace: "2Gi", memory: "1536Mi"},
+		{imageSize: 3<<30 + 1, workspace: "7Gi", memory: "2560Mi"},
+	} {
+		sizing := &ImageScanSizing{ImageSizeBytes: tc.imageSize}
+		sizing.Recommend()
+		assert.Equal(t, tc.workspace, sizing.WorkspaceSize, tc.imageSize)
+		assert.Equal(t, tc.memory, sizing.MemoryRequest, tc.imageSize)
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ImpactRequest asks which images contain a package, identified by the CVE it is affected by
+// or by its name or PURL. VersionRange narrows the affected versions (e.g. ">=2.0.0, <2.15.0")
+type ImpactRequest struct {
+	CVEID        *string `json:"cve_id,omitempty"`
+	PackageName  *string `json:"package_name,omitempty"`
+	PURL         *string `json:"purl,omitempty"`
+	VersionRange *string `json:"version_range,omitempty"`
+}
+
+// ImpactReport lists the images whose current SBOM contains an affected package, along with the
+// findings Grype already reported for the CVE
+type ImpactReport struct {
+	Images        []ImpactedImage              `json:"images"`
+	KnownFindings []VulnerabilityWithImageInfo `json:"known_findings"`
+	Summary       ImpactSummary                `json:"summary"`
+}
+
+// ImpactedImage is an image with the affected packages found in the SBOM of its latest scan
+type ImpactedImage struct {
+	ImageID    int             `json:"image_id"`
+	ImageName  string          `json:"image_name"`
+	Target     *string         `json:"target,omitempty"`
+	ScanID     int             `json:"scan_id"`
+	ScanDate   time.Time       `json:"scan_date"`
+	Components []SBOMComponent `json:"components"`
+}
+
+type ImpactSummary struct {
+	ImagesChecked    int `json:"images_checked"`
+	ImagesAffected   int `json:"images_affected"`
+	ImagesNotIndexed int `json:"images_not_indexed"` // SBOMs that could not be read, so their images are unknown
+}
+
+// SBOMScan is the latest scan with an SBOM of an image and target
+type SBOMScan struct {
+	ScanID         int             `db:"scan_id"`
+	ImageID        int             `db:"image_id"`
+	ImageName      string          `db:"image_name"`
+	Digest         *string         `db:"digest"`
+	Target         *string         `db:"target"`
+	Status         string          `db:"status"`
+	ScanDate       time.Time       `db:"scan_date"`
+	ComponentCount *int            `db:"component_count"`
+	Quality        json.RawMessage `db:"quality"` // nil until the quality of the SBOM is evaluated
+}
+
+// ScanComponent is an indexed SBOM component with the scan it belongs to
+type ScanComponent struct {
+	ScanID int `db:"scan_id"`
+	SBOMComponent
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Statuses of ingest jobs
+const (
+	IngestJobQueued    = "queued"
+	IngestJobRunning   = "running"
+	IngestJobCompleted = "completed"
+	IngestJobFailed    = "failed" // gave up after the maximum number of attempts
+)
+
+// IngestJob is the processing of the matches of a scan submission, queued by POST /api/v1/scans
+// and run by the ingest workers
+type IngestJob struct {
+	ID          int64      `db:"id" json:"id"`
+	ScanID      int        `db:"scan_id" json:"scan_id"`
+	Status      string     `db:"status" json:"status"`
+	Params      string     `db:"params" json:"-"`  // JSON
+	Matches     []byte     `db:"matches" json:"-"` // JSON array of the matches, nil once the job is finished
+	MatchCount  int        `db:"match_count" json:"match_count"`
+	Attempts    int        `db:"attempts" json:"attempts"`
+	AvailableAt time.Time  `db:"available_at" json:"-"`
+	LockedUntil *time.Time `db:"locked_until" json:"-"`
+	Error       *string    `db:"error" json:"error,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	StartedAt   *time.Time `db:"started_at" json:"started_at,omitempty"`
+	CompletedAt *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// IsFinished reports whether the job completed or was given up
+func (j *IngestJob) IsFinished() bool {
+	return j.Status == IngestJobCompleted || j.Status == IngestJobFailed
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// MaintenanceMode represents the cluster-wide read-only toggle
+type MaintenanceMode struct {
+	Enabled           bool      `db:"enabled" json:"enabled"`
+	Reason            *string   `db:"reason" json:"reason,omitempty"`
+	RetryAfterSeconds int       `db:"retry_after_seconds" json:"retry_after_seconds"`
+	UpdatedBy         *string   `db:"updated_by" json:"updated_by,omitempty"`
+	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// MaintenanceModeRequest is the API request format for toggling maintenance mode
+type MaintenanceModeRequest struct {
+	Enabled           bool    `json:"enabled"`
+	Reason            *string `json:"reason,omitempty"`
+	RetryAfterSeconds *int    `json:"retry_after_seconds,omitempty"`
+}
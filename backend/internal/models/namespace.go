@@ -0,0 +1,36 @@
+package models
+
+// Trend directions of a namespace's open findings
+const (
+	TrendUp   = "up"   // more findings were detected than fixed
+	TrendDown = "down" // more findings were fixed than detected
+	TrendFlat = "flat"
+)
+
+// NamespaceSummary rolls up the scans and findings of a namespace, the unit platform teams own
+type NamespaceSummary struct {
+	Namespace  string `json:"namespace"`
+	ImageScans int    `json:"imagescans"`
+	// Coverage of the images the inventory reports deployed in the namespace
+	Coverage CoverageSummary `json:"coverage"`
+	// Open (active or in progress) findings last found by an ImageScan of the namespace
+	OpenFindings SeverityCounts `json:"open_findings"`
+	SLA          NamespaceSLA   `json:"sla"`
+	Trend        NamespaceTrend `json:"trend"`
+}
+
+// NamespaceSLA is how the open findings of a namespace meet their SLA
+type NamespaceSLA struct {
+	WithinSLA int `json:"within_sla"`
+	Overdue   int `json:"overdue"`
+	// Share of the open findings within their SLA, 100 without open findings
+	CompliancePercent float64 `json:"compliance_percent"`
+}
+
+// NamespaceTrend compares the findings detected and fixed in a namespace over the trend window
+type NamespaceTrend struct {
+	WindowDays int    `json:"window_days"`
+	Detected   int    `json:"detected"`
+	Fixed      int    `json:"fixed"`
+	Direction  string `json:"direction"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Online schema change statuses
+const (
+	OnlineChangePending   = "pending"
+	OnlineChangeRunning   = "running"
+	OnlineChangeCompleted = "completed"
+	OnlineChangeFailed    = "failed"
+)
+
+// Online schema change kinds
+const (
+	OnlineChangeIndex    = "index"
+	OnlineChangeBackfill = "backfill"
+)
+
+// OnlineChange is the progress of a schema change applied in the background, shared by every replica.
+// Backfills update the rows with IDs up to MaxID, the highest one when they started
+type OnlineChange struct {
+	Name        string     `db:"name" json:"name"`
+	Status      string     `db:"status" json:"status"` // running, completed, failed
+	LastID      int64      `db:"last_id" json:"last_id"`
+	MaxID       int64      `db:"max_id" json:"max_id"`
+	RowsUpdated int64      `db:"rows_updated" json:"rows_updated"`
+	Error       *string    `db:"error" json:"error,omitempty"`
+	StartedAt   time.Time  `db:"started_at" json:"started_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	CompletedAt *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// OnlineChangeStatus is a schema change and its progress. Changes that never ran are pending
+type OnlineChangeStatus struct {
+	Name            string        `json:"name"`
+	Kind            string        `json:"kind"` // index or backfill
+	Table           string        `json:"table"`
+	Status          string        `json:"status"`
+	PercentComplete float64       `json:"percent_complete"`
+	Progress        *OnlineChange `json:"progress,omitempty"`
+}
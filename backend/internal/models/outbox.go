@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Kinds of notification outbox events
+const (
+	OutboxKindScanCompleted   = "scan_completed"
+	OutboxKindWatchlist       = "watchlist"
+	OutboxKindStatusChange    = "status_change"
+	OutboxKindFixAvailable    = "fix_available"
+	OutboxKindImageDiscovered = "image_discovered"
+	OutboxKindEventWebhook    = "event_webhook"
+	OutboxKindGitOpsCommit    = "gitops_commit"
+)
+
+// Delivery statuses of outbox events
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed" // gave up after the maximum number of attempts
+)
+
+// OutboxEvent is a notification written with the change it reports and delivered later
+type OutboxEvent struct {
+	ID          int64      `db:"id" json:"id"`
+	Kind        string     `db:"kind" json:"kind"`
+	ScanID      *int       `db:"scan_id" json:"scan_id,omitempty"`
+	Payload     string     `db:"payload" json:"-"` // JSON
+	Status      string     `db:"status" json:"status"`
+	Attempts    int        `db:"attempts" json:"attempts"`
+	AvailableAt time.Time  `db:"available_at" json:"available_at"`
+	LockedUntil *time.Time `db:"locked_until" json:"-"`
+	LastError   *string    `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	SentAt      *time.Time `db:"sent_at" json:"sent_at,omitempty"`
+}
+
+// NewOutboxEvent creates an event of the given kind with payload encoded as JSON.
+// A zero AvailableAt makes it deliverable as soon as it is committed
+func NewOutboxEvent(kind string, payload interface{}) (*OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", kind, err)
+	}
+	return &OutboxEvent{Kind: kind, Payload: string(data)}, nil
+}
+
+// Decode decodes the payload of the event into v
+func (e *OutboxEvent) Decode(v interface{}) error {
+	if err := json.Unmarshal([]byte(e.Payload), v); err != nil {
+		return fmt.Errorf("invalid payload of %s event %d: %w", e.Kind, e.ID, err)
+	}
+	return nil
+}
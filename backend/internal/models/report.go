@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// ScanReportVersion is the version of the ScanReport schema
+const ScanReportVersion = 1
+
+// ScanReport is the record of a scan's results as they were once processed. It is signed and
+// stored once, so later triage of the findings doesn't change what the scan found
+type ScanReport struct {
+	SchemaVersion int             `json:"schema_version"`
+	GeneratedAt   time.Time       `json:"generated_at"`
+	ImageName     string          `json:"image_name"`
+	Scan          Scan            `json:"scan"`
+	Summary       ScanSummary     `json:"summary"`
+	Findings      []ReportFinding `json:"findings"`
+	Verdict       ScanGate        `json:"verdict"`
+	// Compliance of the image's open findings with the profiles applying to it
+	Compliance []FrameworkCompliance `json:"compliance,omitempty"`
+}
+
+// ReportFinding is a vulnerability of a scan with its status when the report was generated
+type ReportFinding struct {
+	ID             int     `json:"id"`
+	CVEID          string  `json:"cve_id"`
+	Severity       string  `json:"severity"`
+	Status         string  `json:"status"`
+	PackageName    string  `json:"package_name"`
+	PackageVersion string  `json:"package_version"`
+	PackageType    *string `json:"package_type,omitempty"`
+	PURL           *string `json:"purl,omitempty"`
+	FixVersion     *string `json:"fix_version,omitempty"`
+	KnownExploited bool    `json:"known_exploited"`
+	URL            *string `json:"url,omitempty"`
+}
+
+// ScanReportArchive is the metadata of the signed report stored for a scan
+type ScanReportArchive struct {
+	ID        int       `db:"id" json:"id"`
+	ScanID    int       `db:"scan_id" json:"scan_id"`
+	KeyID     string    `db:"key_id" json:"key_id"`
+	SHA256    string    `db:"sha256" json:"sha256"`
+	SizeBytes *int64    `db:"size_bytes" json:"size_bytes,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
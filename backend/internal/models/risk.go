@@ -0,0 +1,233 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Exposure tiers of an image, declared by the ImageScans scanning it
+const (
+	ExposureInternet = "internet"
+	ExposureInternal = "internal"
+	ExposureIsolated = "isolated"
+	ExposureUnknown  = "unknown" // no ImageScan declares it, weighed like internal
+)
+
+// ValidExposure reports whether an ImageScan may declare the exposure tier
+func ValidExposure(exposure string) bool {
+	switch exposure {
+	case ExposureInternet, ExposureInternal, ExposureIsolated:
+		return true
+	}
+	return false
+}
+
+// Multipliers of the image risk score per exposure tier
+var riskExposureMultipliers = map[string]float64{
+	ExposureInternet: 2,
+	ExposureInternal: 1,
+	ExposureIsolated: 0.5,
+	ExposureUnknown:  1,
+}
+
+// Weights of the image risk score. Negligible and unknown findings don't count
+const (
+	riskCriticalPoints = 10
+	riskHighPoints     = 5
+	riskMediumPoints   = 2
+	riskLowPoints      = 0.5
+	// riskKnownExploitedPoints is added for each open finding listed in the KEV catalog
+	riskKnownExploitedPoints = 25
+	// riskStalenessPerDay is added to the staleness multiplier per day past the stale threshold:
+	// findings published since the last successful scan are missing from the count
+	riskStalenessPerDay = 0.1
+	riskMaxStaleness    = 2
+)
+
+// ImageRiskFactors are the inputs of an image's risk score: the open findings of the latest
+// successful scan of each target, the exposure and the last successful scan
+type ImageRiskFactors struct {
+	ImageID                int        `db:"image_id"`
+	ImageName              string     `db:"image_name"`
+	ImageCreatedAt         time.Time  `db:"image_created_at"`
+	Exposure               *string    `db:"exposure"`
+	LastSuccessfulScanDate *time.Time `db:"last_successful_scan_date"`
+	StaleAfterSeconds      int        `db:"stale_after_seconds"`
+	Critical               int        `db:"critical_count"`
+	High                   int        `db:"high_count"`
+	Medium                 int        `db:"medium_count"`
+	Low                    int        `db:"low_count"`
+	Negligible             int        `db:"negligible_count"`
+	Unknown                int        `db:"unknown_count"`
+	KnownExploited         int        `db:"known_exploited_count"`
+	Fixable                int        `db:"fixable_count"`
+	// DeployedExposed is set when the inventory reports a workload behind an Ingress or a
+	// LoadBalancer Service running the image, which makes it internet-facing whatever is declared
+	DeployedExposed bool `db:"deployed_exposed"`
+}
+
+// ImageRisk is an image ranked by GET /api/v1/images/prioritized
+type ImageRisk struct {
+	Rank        int           `json:"rank"`
+	ImageID     int           `json:"image_id"`
+	ImageName   string        `json:"image_name"`
+	Score       float64       `json:"score"`
+	Breakdown   RiskBreakdown `json:"breakdown"`
+	Explanation []string      `json:"explanation"`
+}
+
+// RiskBreakdown shows how an image's score was computed:
+// score = (findings_score + known_exploited_score) × exposure_multiplier × staleness_multiplier
+type RiskBreakdown struct {
+	OpenFindings           SeverityCounts `json:"open_findings"`
+	Fixable                int            `json:"fixable"`
+	FindingsScore          float64        `json:"findings_score"`
+	KnownExploited         int            `json:"known_exploited"`
+	KnownExploitedScore    float64        `json:"known_exploited_score"`
+	Exposure               string         `json:"exposure"`
+	DeployedExposed        bool           `json:"deployed_exposed"`
+	ExposureMultiplier     float64        `json:"exposure_multiplier"`
+	LastSuccessfulScanDate *time.Time     `json:"last_successful_scan_date,omitempty"`
+	DaysStale              float64        `json:"days_stale"` // days past the stale threshold
+	StalenessMultiplier    float64        `json:"staleness_multiplier"`
+}
+
+// ScoreImageRisk computes the risk score of an image and explains it
+func ScoreImageRisk(f ImageRiskFactors, now time.Time) ImageRisk {
+	b := RiskBreakdown{
+		OpenFindings: SeverityCounts{
+			Critical:   f.Critical,
+			High:       f.High,
+			Medium:     f.Medium,
+			Low:        f.Low,
+			Negligible: f.Negligible,
+			Unknown:    f.Unknown,
+			Total:      f.Critical + f.High + f.Medium + f.Low + f.Negligible + f.Unknown,
+		},
+		Fixable:                f.Fixable,
+		KnownExploited:         f.KnownExploited,
+		Exposure:               ExposureUnknown,
+		LastSuccessfulScanDate: f.LastSuccessfulScanDate,
+	}
+	b.FindingsScore = float64(f.Critical)*riskCriticalPoints + float64(f.High)*riskHighPoints +
+		float64(f.Medium)*riskMediumPoints + float64(f.Low)*riskLowPoints
+	b.KnownExploitedScore = float64(f.KnownExploited * riskKnownExploitedPoints)
+
+	if f.DeployedExposed {
+		b.Exposure = ExposureInternet
+		b.DeployedExposed = true
+	} else if f.Exposure != nil && ValidExposure(*f.Exposure) {
+		b.Exposure = *f.Exposure
+	}
+	b.ExposureMultiplier = riskExposureMultipliers[b.Exposure]
+
+	// An image that never had a successful scan is measured from when it was first seen
+	since := f.ImageCreatedAt
+	if f.LastSuccessfulScanDate != nil {
+		since = *f.LastSuccessfulScanDate
+	}
+	staleSince := since.Add(time.Duration(f.StaleAfterSeconds) * time.Second)
+	if now.After(staleSince) {
+		b.DaysStale = roundRisk(now.Sub(staleSince).Hours() / 24)
+	}
+	b.StalenessMultiplier = roundRisk(math.Min(1+b.DaysStale*riskStalenessPerDay, riskMaxStaleness))
+
+	score := (b.FindingsScore + b.KnownExploitedScore) * b.ExposureMultiplier * b.StalenessMultiplier
+	return ImageRisk{
+		ImageID:     f.ImageID,
+		ImageName:   f.ImageName,
+		Score:       roundRisk(score),
+		Breakdown:   b,
+		Explanation: explainRisk(b, f.StaleAfterSeconds),
+	}
+}
+
+// RankImageRisks orders images by descending score, then by known exploited and critical findings,
+// and numbers them from 1
+func RankImageRisks(risks []ImageRisk) {
+	sort.SliceStable(risks, func(i, j int) bool {
+		a, b := risks[i], risks[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Breakdown.KnownExploited != b.Breakdown.KnownExploited {
+			return a.Breakdown.KnownExploited > b.Breakdown.KnownExploited
+		}
+		if a.Breakdown.OpenFindings.Critical != b.Breakdown.OpenFindings.Critical {
+			return a.Breakdown.OpenFindings.Critical > b.Breakdown.OpenFindings.Critical
+		}
+		return a.ImageName < b.ImageName
+	})
+	for i := range risks {
+		risks[i].Rank = i + 1
+	}
+}
+
+// explainRisk describes each factor that contributed to the score, in the order they apply
+func explainRisk(b RiskBreakdown, staleAfterSeconds int) []string {
+	explanation := []string{}
+
+	counts := []string{}
+	for _, c := range []struct {
+		n        int
+		severity string
+	}{
+		{b.OpenFindings.Critical, "critical"},
+		{b.OpenFindings.High, "high"},
+		{b.OpenFindings.Medium, "medium"},
+		{b.OpenFindings.Low, "low"},
+	} {
+		if c.n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", c.n, c.severity))
+		}
+	}
+	if len(counts) > 0 {
+		explanation = append(explanation, fmt.Sprintf("%s open findings (%d fixable): %s points",
+			strings.Join(counts, ", "), b.Fixable, formatRisk(b.FindingsScore)))
+	} else {
+		explanation = append(explanation, "no open findings of low severity or above")
+	}
+
+	if b.KnownExploited > 0 {
+		explanation = append(explanation, fmt.Sprintf("%d known exploited (CISA KEV): +%s points",
+			b.KnownExploited, formatRisk(b.KnownExploitedScore)))
+	}
+
+	switch b.Exposure {
+	case ExposureInternet:
+		if b.DeployedExposed {
+			explanation = append(explanation, fmt.Sprintf("internet-facing, run by a workload behind an Ingress or a LoadBalancer: ×%s",
+				formatRisk(b.ExposureMultiplier)))
+		} else {
+			explanation = append(explanation, fmt.Sprintf("internet-facing: ×%s", formatRisk(b.ExposureMultiplier)))
+		}
+	case ExposureIsolated:
+		explanation = append(explanation, fmt.Sprintf("isolated: ×%s", formatRisk(b.ExposureMultiplier)))
+	case ExposureUnknown:
+		explanation = append(explanation, "exposure not declared, weighed as internal")
+	}
+
+	if b.DaysStale > 0 {
+		threshold := formatRisk(float64(staleAfterSeconds)/3600) + "h"
+		last := "never scanned successfully"
+		if b.LastSuccessfulScanDate != nil {
+			last = "not scanned successfully"
+		}
+		explanation = append(explanation, fmt.Sprintf("%s for %s days past the %s stale threshold: ×%s",
+			last, formatRisk(b.DaysStale), threshold, formatRisk(b.StalenessMultiplier)))
+	}
+
+	return explanation
+}
+
+// roundRisk rounds scores and multipliers to two decimals
+func roundRisk(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func formatRisk(v float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreImageRisk(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	internet, isolated := ExposureInternet, ExposureIsolated
+	scanned := now.Add(-time.Hour)
+
+	t.Run("weighted findings and known exploited", func(t *testing.T) {
+		risk := ScoreImageRisk(ImageRiskFactors{
+			ImageID: 1, ImageName: "docker.io/library/nginx:latest", Exposure: &internet,
+			LastSuccessfulScanDate: &scanned, StaleAfterSeconds: 48 * 3600,
+			Critical: 2, High: 3, Medium: 1, Low: 2, Negligible: 4, KnownExploited: 1, Fixable: 5,
+		}, now)
+
+		assert.Equal(t, 38.0, risk.Breakdown.FindingsScore)
+		assert.Equal(t, 25.0, risk.Breakdown.KnownExploitedScore)
+		assert.Equal(t, 2.0, risk.Breakdown.ExposureMultiplier)
+		assert.Equal(t, 1.0, risk.Breakdown.StalenessMultiplier)
+		assert.Equal(t, 126.0, risk.Score)
+		assert.Equal(t, 12, risk.Breakdown.OpenFindings.Total)
+		assert.Equal(t, []string{
+			"2 critical, 3 high, 1 medium, 2 low open findings (5 fixable): 38 points",
+			"1 known exploited (CISA KEV): +25 points",
+			"internet-facing: ×2",
+		}, risk.Explanation)
+	})
+
+	t.Run("exposed workload overrides the declared exposure", func(t *testing.T) {
+		risk := ScoreImageRisk(ImageRiskFactors{
+			Exposure: &isolated, DeployedExposed: true, LastSuccessfulScanDate: &scanned, StaleAfterSeconds: 48 * 3600, High: 1,
+		}, now)
+
+		assert.Equal(t, ExposureInternet, risk.Breakdown.Exposure)
+		assert.True(t, risk.Breakdown.DeployedExposed)
+		assert.Equal(t, 10.0, risk.Score)
+		assert.Equal(t, "internet-facing, run by a workload behind an Ingress or a LoadBalancer: ×2", risk.Explanation[1])
+	})
+
+	t.Run("stale and isolated", func(t *testing.T) {
+		old := now.Add(-(48 + 36) * time.Hour)
+		risk := ScoreImageRisk(ImageRiskFactors{
+			Exposure: &isolated, LastSuccessfulScanDate: &old, StaleAfterSeconds: 48 * 3600, High: 4,
+		}, now)
+
+		assert.Equal(t, 1.5, risk.Breakdown.DaysStale)
+		assert.Equal(t, 1.15, risk.Breakdown.StalenessMultiplier)
+		assert.Equal(t, 11.5, risk.Score)
+		assert.Equal(t, "not scanned successfully for 1.5 days past the 48h stale threshold: ×1.15", risk.Explanation[2])
+	})
+
+	t.Run("staleness is capped", func(t *testing.T) {
+		risk := ScoreImageRisk(ImageRiskFactors{
+			ImageCreatedAt: now.Add(-90 * 24 * time.Hour), StaleAfterSeconds: 3600, Critical: 1,
+		}, now)
+
+		assert.Equal(t, ExposureUnknown, risk.Breakdown.Exposure)
+		assert.Equal(t, 1.0, risk.Breakdown.ExposureMultiplier)
+		assert.Equal(t, 2.0, risk.Breakdown.StalenessMultiplier)
+		assert.Equal(t, 20.0, risk.Score)
+		assert.Contains(t, risk.Explanation, "exposure not declared, weighed as internal")
+		assert.Contains(t, risk.Explanation[2], "never scanned successfully")
+	})
+
+	t.Run("no findings", func(t *testing.T) {
+		risk := ScoreImageRisk(ImageRiskFactors{LastSuccessfulScanDate: &scanned, StaleAfterSeconds: 3600 * 48, Negligible: 3}, now)
+		assert.Zero(t, risk.Score)
+		assert.Equal(t, "no open findings of low severity or above", risk.Explanation[0])
+	})
+}
+
+func TestRankImageRisks(t *testing.T) {
+	risks := []ImageRisk{
+		{ImageName: "c", Score: 10},
+		{ImageName: "b", Score: 50},
+		{ImageName: "d", Score: 50, Breakdown: RiskBreakdown{KnownExploited: 1}},
+		{ImageName: "a", Score: 10},
+	}
+	RankImageRisks(risks)
+
+	names := []string{}
+	for i, risk := range risks {
+		assert.Equal(t, i+1, risk.Rank)
+		names = append(names, risk.ImageName)
+	}
+	assert.Equal(t, []string{"d", "b", "a", "c"}, names)
+}
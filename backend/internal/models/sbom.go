@@ -1,16 +1,123 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // SBOM represents SBOM metadata stored in database
 // The actual SBOM document is stored in S3 at path: scans/{scan_id}/sbom.json
 type SBOM struct {
-	ID        int       `db:"id" json:"id"`
-	ScanID    int       `db:"scan_id" json:"scan_id"`
-	Format    string    `db:"format" json:"format"` // cyclonedx or spdx
-	Version   *string   `db:"version" json:"version,omitempty"`
-	SizeBytes *int64    `db:"size_bytes" json:"size_bytes,omitempty"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID             int       `db:"id" json:"id"`
+	ScanID         int       `db:"scan_id" json:"scan_id"`
+	Format         string    `db:"format" json:"format"` // cyclonedx or spdx
+	Version        *string   `db:"version" json:"version,omitempty"`
+	SizeBytes      *int64    `db:"size_bytes" json:"size_bytes,omitempty"`
+	ComponentCount *int      `db:"component_count" json:"component_count,omitempty"`   // nil until the packages are indexed
+	DocumentScanID *int      `db:"document_scan_id" json:"document_scan_id,omitempty"` // set when the document of an earlier scan is shared
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	// The quality of the document, see SBOMQuality. Nil until it is evaluated
+	QualityScore *int            `db:"quality_score" json:"quality_score,omitempty"`
+	Quality      json.RawMessage `db:"quality" json:"quality,omitempty"`
+}
+
+// StorageScanID is the scan the document is stored under in S3
+func (s *SBOM) StorageScanID() int {
+	if s.DocumentScanID != nil {
+		return *s.DocumentScanID
+	}
+	return s.ScanID
+}
+
+// SBOMComponent is a package listed in an SBOM document
+type SBOMComponent struct {
+	Name    string `db:"name" json:"name"`
+	Version string `db:"version" json:"version"`
+	Type    string `db:"type" json:"type,omitempty"` // purl type (deb, npm, golang...) when known
+	PURL    string `db:"purl" json:"purl,omitempty"`
+}
+
+// SBOMComponentChange is a package whose version differs between two scans
+type SBOMComponentChange struct {
+	Name            string `json:"name"`
+	Type            string `json:"type,omitempty"`
+	PURL            string `json:"purl,omitempty"`
+	PreviousVersion string `json:"previous_version"`
+	Version         string `json:"version"`
+}
+
+// SBOMDiff lists the package changes between the SBOMs of two scans, independent of vulnerabilities
+type SBOMDiff struct {
+	ScanID         int                   `json:"scan_id"`
+	PreviousScanID int                   `json:"previous_scan_id"`
+	Added          []SBOMComponent       `json:"added"`
+	Removed        []SBOMComponent       `json:"removed"`
+	Upgraded       []SBOMComponentChange `json:"upgraded"`
+	Summary        SBOMDiffSummary       `json:"summary"`
+}
+
+type SBOMDiffSummary struct {
+	AddedCount     int `json:"added_count"`
+	RemovedCount   int `json:"removed_count"`
+	UpgradedCount  int `json:"upgraded_count"`
+	UnchangedCount int `json:"unchanged_count"`
+}
+
+// SBOMQualityLowScore is the score below which an SBOM is flagged as low quality: Grype only matches
+// the packages an SBOM lists, so vulnerabilities of the packages Syft missed go unreported
+const SBOMQualityLowScore = 50
+
+// Quality flags of an SBOM, each lowering its score
+const (
+	SBOMQualityEmpty           = "empty"            // no components
+	SBOMQualitySparse          = "sparse"           // few components for the size of the image
+	SBOMQualityMissingPURLs    = "missing_purls"    // components without a purl can't be matched
+	SBOMQualityMissingLicenses = "missing_licenses" // components without a license
+	SBOMQualityMissingHashes   = "missing_hashes"   // components without a hash
+	SBOMQualityNTIAIncomplete  = "ntia_incomplete"  // NTIA minimum elements missing
+)
+
+// SBOMQuality scores how complete an SBOM document is, from 0 to 100
+type SBOMQuality struct {
+	Score      int  `json:"score"`
+	LowQuality bool `json:"low_quality"`
+
+	ComponentCount int `json:"component_count"`
+	// ComponentsPerMB is the number of components per MB of uncompressed image, nil when the
+	// size of the image is unknown
+	ComponentsPerMB *float64 `json:"components_per_mb,omitempty"`
+	// Shares of the components with a purl, a license and a hash, from 0 to 1
+	PURLCoverage    float64 `json:"purl_coverage"`
+	LicenseCoverage float64 `json:"license_coverage"`
+	HashCoverage    float64 `json:"hash_coverage"`
+
+	NTIA  NTIAElements `json:"ntia"`
+	Flags []string     `json:"flags"`
+}
+
+// NTIAElements are the NTIA minimum elements of an SBOM found in the document. Component fields
+// are present when nearly all components have them
+type NTIAElements struct {
+	Supplier         bool `json:"supplier"`
+	ComponentName    bool `json:"component_name"`
+	Version          bool `json:"version"`
+	UniqueIdentifier bool `json:"unique_identifier"` // purl or CPE
+	Dependencies     bool `json:"dependencies"`
+	Author           bool `json:"author"`
+	Timestamp        bool `json:"timestamp"`
+}
+
+// Complete tells whether every minimum element is present
+func (e NTIAElements) Complete() bool {
+	return e.Supplier && e.ComponentName && e.Version && e.UniqueIdentifier && e.Dependencies && e.Author && e.Timestamp
+}
+
+// SBOMQualityEntry is the quality of the latest SBOM of an image and target
+type SBOMQualityEntry struct {
+	ScanID    int         `json:"scan_id"`
+	ImageID   int         `json:"image_id"`
+	ImageName string      `json:"image_name"`
+	Target    *string     `json:"target,omitempty"`
+	ScanDate  time.Time   `json:"scan_date"`
+	Quality   SBOMQuality `json:"quality"`
 }
@@ -1,22 +1,50 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
 
 type Scan struct {
-	ID                 int       `db:"id" json:"id"`
-	ImageID            int       `db:"image_id" json:"image_id"`
-	ScanDate           time.Time `db:"scan_date" json:"scan_date"`
-	SyftVersion        *string   `db:"syft_version" json:"syft_version,omitempty"`
-	GrypeVersion       *string   `db:"grype_version" json:"grype_version,omitempty"`
-	Status             string    `db:"status" json:"status"`
-	SLACritical        int       `db:"sla_critical" json:"sla_critical"`
-	SLAHigh            int       `db:"sla_high" json:"sla_high"`
-	SLAMedium          int       `db:"sla_medium" json:"sla_medium"`
-	SLALow             int       `db:"sla_low" json:"sla_low"`
-	ImageScanNamespace *string   `db:"imagescan_namespace" json:"imagescan_namespace,omitempty"`
-	ImageScanName      *string   `db:"imagescan_name" json:"imagescan_name,omitempty"`
-	CreatedAt          time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt          time.Time `db:"updated_at" json:"updated_at"`
+	ID                 int        `db:"id" json:"id"`
+	ImageID            int        `db:"image_id" json:"image_id"`
+	ScanDate           time.Time  `db:"scan_date" json:"scan_date"`
+	SyftVersion        *string    `db:"syft_version" json:"syft_version,omitempty"`
+	GrypeVersion       *string    `db:"grype_version" json:"grype_version,omitempty"`
+	GrypeDBBuilt       *time.Time `db:"grype_db_built" json:"grype_db_built,omitempty"`
+	GrypeDBSchema      *string    `db:"grype_db_schema" json:"grype_db_schema,omitempty"`
+	NeedsRescan        bool       `db:"needs_rescan" json:"needs_rescan"`
+	Status             string     `db:"status" json:"status"` // pending, running, completed, failed, partial
+	FailureReason      *string    `db:"failure_reason" json:"failure_reason,omitempty"`
+	SLACritical        int        `db:"sla_critical" json:"sla_critical"`
+	SLAHigh            int        `db:"sla_high" json:"sla_high"`
+	SLAMedium          int        `db:"sla_medium" json:"sla_medium"`
+	SLALow             int        `db:"sla_low" json:"sla_low"`
+	SLATimeZone        string     `db:"sla_time_zone" json:"sla_time_zone"`
+	ImageScanNamespace *string    `db:"imagescan_namespace" json:"imagescan_namespace,omitempty"`
+	ImageScanName      *string    `db:"imagescan_name" json:"imagescan_name,omitempty"`
+	Digest             *string    `db:"digest" json:"digest,omitempty"`
+	Target             *string    `db:"target" json:"target,omitempty"`
+	ScanUUID           *string    `db:"scan_uuid" json:"scan_uuid,omitempty"`       // chosen by the scanner, a retried submission returns this scan
+	APIKeyName         *string    `db:"api_key_name" json:"api_key_name,omitempty"` // SCANNER_API_KEYS entry of scans submitted from CI
+	// Distribution Grype detected, unset for distroless and scratch images
+	DistroName         *string        `db:"distro_name" json:"distro_name,omitempty"`
+	DistroVersion      *string        `db:"distro_version" json:"distro_version,omitempty"`
+	DistroIDLike       pq.StringArray `db:"distro_id_like" json:"distro_id_like,omitempty"`
+	SLABusinessDays    bool           `db:"sla_business_days" json:"sla_business_days"` // weekends and SLAHolidays (YYYY-MM-DD) don't count
+	SLAHolidays        pq.StringArray `db:"sla_holidays" json:"sla_holidays,omitempty"`
+	ResultsFingerprint *string        `db:"results_fingerprint" json:"-"`
+	CachedFromScanID   *int           `db:"cached_from_scan_id" json:"cached_from_scan_id,omitempty"`
+	MatchesReceived    *int           `db:"matches_received" json:"matches_received,omitempty"`
+	MatchesDropped     int            `db:"matches_dropped" json:"matches_dropped"` // over the ingest limits, see FailureReason
+	FieldsTruncated    int            `db:"fields_truncated" json:"fields_truncated"`
+	// Reported by the scanner, unset for scans submitted without them
+	ScanDurationMS *int64    `db:"scan_duration_ms" json:"scan_duration_ms,omitempty"`
+	ImageSizeBytes *int64    `db:"image_size_bytes" json:"image_size_bytes,omitempty"`
+	LayerCount     *int      `db:"layer_count" json:"layer_count,omitempty"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
 }
 
 type ScanWithDetails struct {
@@ -36,7 +64,12 @@ type ScanDiff struct {
 	NewVulns        []Vulnerability `json:"new_vulnerabilities"`
 	FixedVulns      []Vulnerability `json:"fixed_vulnerabilities"`
 	PersistentVulns []Vulnerability `json:"persistent_vulnerabilities"`
-	Summary         ScanDiffSummary `json:"summary"`
+	// Why some fixed vulnerabilities weren't marked fixed: the scan can't prove their absence, or damping
+	// waits for more scans without them
+	FixedHeldReason string `json:"fixed_held_reason,omitempty"`
+	// Set when the previous scan is of another image, e.g. another tag of the repository
+	CrossImage bool            `json:"cross_image,omitempty"`
+	Summary    ScanDiffSummary `json:"summary"`
 }
 
 type ScanDiffSummary struct {
@@ -44,3 +77,118 @@ type ScanDiffSummary struct {
 	FixedCount      int `json:"fixed_count"`
 	PersistentCount int `json:"persistent_count"`
 }
+
+// Scan lifecycle statuses
+const (
+	ScanStatusPending   = "pending"
+	ScanStatusRunning   = "running"
+	ScanStatusCompleted = "completed"
+	ScanStatusFailed    = "failed"
+	ScanStatusPartial   = "partial" // Results were submitted but the scanner reported missing coverage
+)
+
+var ValidScanStatuses = []string{ScanStatusPending, ScanStatusRunning, ScanStatusCompleted, ScanStatusFailed, ScanStatusPartial}
+
+// ScanStatusUpdate is the request body for PATCH /api/v1/scans/:id
+type ScanStatusUpdate struct {
+	Status        string  `json:"status"`
+	FailureReason *string `json:"failure_reason,omitempty"`
+}
+
+// HasResults reports whether the scan carries vulnerability results usable for diffs
+func (s *Scan) HasResults() bool {
+	return s.Status == ScanStatusCompleted || s.Status == ScanStatusPartial
+}
+
+// IsFinished reports whether the scan reached a terminal status
+func (s *Scan) IsFinished() bool {
+	return s.HasResults() || s.Status == ScanStatusFailed
+}
+
+// ScannerVersionStats aggregates scans made with the same tool versions and database build
+type ScannerVersionStats struct {
+	SyftVersion        *string    `db:"syft_version" json:"syft_version"`
+	GrypeVersion       *string    `db:"grype_version" json:"grype_version"`
+	GrypeDBBuilt       *time.Time `db:"grype_db_built" json:"grype_db_built"`
+	ScanCount          int        `db:"scan_count" json:"scan_count"`
+	ImageCount         int        `db:"image_count" json:"image_count"`
+	LatestForImages    int        `db:"latest_for_images" json:"latest_for_images"` // Images whose most recent scan used this combination
+	FirstScanDate      time.Time  `db:"first_scan_date" json:"first_scan_date"`
+	LastScanDate       time.Time  `db:"last_scan_date" json:"last_scan_date"`
+	Deprecated         bool       `db:"-" json:"deprecated"`
+	DeprecationReasons []string   `db:"-" json:"deprecation_reasons,omitempty"`
+}
+
+// SeverityCounts counts vulnerabilities per Grype severity
+type SeverityCounts struct {
+	Critical   int `json:"critical"`
+	High       int `json:"high"`
+	Medium     int `json:"medium"`
+	Low        int `json:"low"`
+	Negligible int `json:"negligible"`
+	Unknown    int `json:"unknown"`
+	Total      int `json:"total"`
+}
+
+// Add counts n vulnerabilities of the given severity, unrecognized severities count as unknown
+func (c *SeverityCounts) Add(severity string, n int) {
+	switch severity {
+	case "Critical":
+		c.Critical += n
+	case "High":
+		c.High += n
+	case "Medium":
+		c.Medium += n
+	case "Low":
+		c.Low += n
+	case "Negligible":
+		c.Negligible += n
+	default:
+		c.Unknown += n
+	}
+	c.Total += n
+}
+
+// PackageTypeSummary is the severity distribution of one package type (deb, npm, go-module...)
+type PackageTypeSummary struct {
+	PackageType string `json:"package_type"`
+	SeverityCounts
+}
+
+// FixAvailabilitySummary splits the severity distribution by whether a fix version exists
+type FixAvailabilitySummary struct {
+	Fixable   SeverityCounts `json:"fixable"`
+	Unfixable SeverityCounts `json:"unfixable"`
+}
+
+// ScanSummary is the response of GET /api/v1/scans/:id/summary
+type ScanSummary struct {
+	ScanID            int                    `json:"scan_id"`
+	BySeverity        SeverityCounts         `json:"by_severity"`
+	ByPackageType     []PackageTypeSummary   `json:"by_package_type"`
+	ByFixAvailability FixAvailabilitySummary `json:"by_fix_availability"`
+}
+
+// ScanGate is the response of GET /api/v1/scans/:id/gate, the verdict a CI pipeline fails the build on
+type ScanGate struct {
+	ScanID      int                 `json:"scan_id"`
+	ScanStatus  string              `json:"scan_status"`
+	FailOn      string              `json:"fail_on"`
+	OnlyFixable bool                `json:"only_fixable"`
+	Passed      bool                `json:"passed"`
+	Actionable  SeverityCounts      `json:"actionable"` // Vulnerabilities of the scan that aren't ignored or accepted
+	Blocking    []GateVulnerability `json:"blocking"`   // Actionable vulnerabilities at or above FailOn, most severe first
+}
+
+// GateVulnerability is a vulnerability that fails a scan gate
+type GateVulnerability struct {
+	ID             int     `json:"id"`
+	CVEID          string  `json:"cve_id"`
+	Severity       string  `json:"severity"`
+	Status         string  `json:"status"`
+	PackageName    string  `json:"package_name"`
+	PackageVersion string  `json:"package_version"`
+	PackageType    *string `json:"package_type,omitempty"`
+	FixVersion     *string `json:"fix_version,omitempty"`
+	URL            *string `json:"url,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverityCounts_Add(t *testing.T) {
+	var counts SeverityCounts
+	counts.Add("Critical", 2)
+	counts.Add("High", 1)
+	counts.Add("Negligible", 1)
+	counts.Add("", 3)
+
+	assert.Equal(t, SeverityCounts{Critical: 2, High: 1, Negligible: 1, Unknown: 3, Total: 7}, counts)
+}
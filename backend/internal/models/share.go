@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ShareLink is a signed URL granting unauthenticated read-only access to one scan report
+type ShareLink struct {
+	ScanID    int       `json:"scan_id"`
+	URL       string    `json:"url"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SharedScanReport is the scan report served to share link holders
+type SharedScanReport struct {
+	Scan            *ScanWithDetails `json:"scan"`
+	Vulnerabilities []Vulnerability  `json:"vulnerabilities"`
+	ExpiresAt       time.Time        `json:"expires_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// SLABreach is an open vulnerability past its SLA deadline, counted with the SLA of the latest scan
+// that found it. It is the data of sla.breached events
+type SLABreach struct {
+	VulnerabilityID    int       `json:"vulnerability_id"`
+	CVEID              string    `json:"cve_id"`
+	PackageName        string    `json:"package_name"`
+	PackageVersion     string    `json:"package_version"`
+	Severity           string    `json:"severity"`
+	SLASeverity        string    `json:"sla_severity"` // the severity the SLA is counted from, see SLA_SEVERITY_POLICY
+	Status             string    `json:"status"`
+	FixVersion         *string   `json:"fix_version,omitempty"`
+	ImageID            int       `json:"image_id"`
+	ImageName          string    `json:"image_name"`
+	ImageScanNamespace *string   `json:"imagescan_namespace,omitempty"`
+	ImageScanName      *string   `json:"imagescan_name,omitempty"`
+	ScanID             int       `json:"scan_id"`
+	FirstDetectedAt    time.Time `json:"first_detected_at"`
+	SLADays            int       `json:"sla_days"`
+	DueDate            string    `json:"due_date"` // YYYY-MM-DD in the SLA timezone of the scan
+	DueAt              time.Time `json:"due_at"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+// Suppression rule sources
+const (
+	SuppressionSourceManual = "manual"
+	SuppressionSourceGrype  = "grype"
+	SuppressionSourceTrivy  = "trivy"
+)
+
+// Grype fix states a rule can be restricted to
+const (
+	FixStateFixed    = "fixed"
+	FixStateNotFixed = "not-fixed"
+	FixStateWontFix  = "wont-fix"
+	FixStateUnknown  = "unknown"
+)
+
+var ValidFixStates = []string{FixStateFixed, FixStateNotFixed, FixStateWontFix, FixStateUnknown}
+
+// SuppressionRule is an accepted-risk waiver. Empty criteria match anything,
+// but a rule always targets at least a CVE or a package
+type SuppressionRule struct {
+	ID             int        `db:"id" json:"id"`
+	CVEID          *string    `db:"cve_id" json:"cve_id,omitempty"`
+	PackageName    *string    `db:"package_name" json:"package_name,omitempty"`
+	PackageVersion *string    `db:"package_version" json:"package_version,omitempty"`
+	PackageType    *string    `db:"package_type" json:"package_type,omitempty"`
+	FixState       *string    `db:"fix_state" json:"fix_state,omitempty"`
+	Reason         *string    `db:"reason" json:"reason,omitempty"`
+	ExpiresAt      *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	Source         string     `db:"source" json:"source"`
+	CreatedBy      *string    `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// SuppressionRuleRequest is the API request format for creating a rule
+type SuppressionRuleRequest struct {
+	CVEID          *string    `json:"cve_id,omitempty"`
+	PackageName    *string    `json:"package_name,omitempty"`
+	PackageVersion *string    `json:"package_version,omitempty"`
+	PackageType    *string    `json:"package_type,omitempty"`
+	FixState       *string    `json:"fix_state,omitempty"`
+	Reason         *string    `json:"reason,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// SuppressionImportResult is the response of POST /api/v1/suppression-rules/import
+type SuppressionImportResult struct {
+	Format   string            `json:"format"`
+	Imported int               `json:"imported"`
+	Rules    []SuppressionRule `json:"rules"`
+	Skipped  []string          `json:"skipped"` // Entries that can't be expressed as a rule, with the reason
+}
+
+// SuppressionWaiver is a vulnerability triaged as accepted or ignored, keyed by what identifies it on
+// any instance rather than by ID
+type SuppressionWaiver struct {
+	CVEID          string  `db:"cve_id" json:"cve_id"`
+	PackageName    string  `db:"package_name" json:"package_name"`
+	PackageVersion string  `db:"package_version" json:"package_version"`
+	Status         string  `db:"status" json:"status"` // accepted or ignored
+	Notes          *string `db:"notes" json:"notes,omitempty"`
+}
+
+// SuppressionBundleImportResult is the response of POST /api/v1/suppression-rules/bundle
+type SuppressionBundleImportResult struct {
+	Rules       int `json:"rules"`        // rules created or updated
+	RulesPruned int `json:"rules_pruned"` // rules missing from the bundle, deleted with prune=true
+	Waivers     int `json:"waivers"`      // vulnerabilities whose status or notes changed
+	// Waivers already applied, and the ones of vulnerabilities this instance hasn't found yet
+	WaiversUnchanged int      `json:"waivers_unchanged"`
+	WaiversPending   []string `json:"waivers_pending"`
+}
+
+// IsExpired reports whether the rule stopped applying at the given time
+func (r *SuppressionRule) IsExpired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// Matches reports whether the rule applies to the vulnerability
+// Grype only distinguishes "fixed" from the other fix states through the fix version,
+// so not-fixed, wont-fix and unknown all match vulnerabilities without one
+func (r *SuppressionRule) Matches(v *Vulnerability) bool {
+	if r.CVEID != nil && *r.CVEID != v.CVEID {
+		return false
+	}
+	if r.PackageName != nil && *r.PackageName != v.PackageName {
+		return false
+	}
+	if r.PackageVersion != nil && *r.PackageVersion != v.PackageVersion {
+		return false
+	}
+	if r.PackageType != nil && (v.PackageType == nil || *r.PackageType != *v.PackageType) {
+		return false
+	}
+	if r.FixState != nil {
+		hasFix := v.FixVersion != nil && *v.FixVersion != ""
+		if hasFix != (*r.FixState == FixStateFixed) {
+			return false
+		}
+	}
+	return true
+}
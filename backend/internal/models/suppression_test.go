@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuppressionRule_Matches(t *testing.T) {
+	deb := "deb"
+	fix := "3.0.13"
+	vuln := &Vulnerability{CVEID: "CVE-2023-0001", PackageName: "openssl", PackageVersion: "3.0.11", PackageType: &deb, FixVersion: &fix}
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		rule SuppressionRule
+		want bool
+	}{
+		{"cve only", SuppressionRule{CVEID: str("CVE-2023-0001")}, true},
+		{"other cve", SuppressionRule{CVEID: str("CVE-2023-9999")}, false},
+		{"package for any cve", SuppressionRule{PackageName: str("openssl")}, true},
+		{"other package version", SuppressionRule{PackageName: str("openssl"), PackageVersion: str("1.1.1")}, false},
+		{"package type", SuppressionRule{CVEID: str("CVE-2023-0001"), PackageType: str("deb")}, true},
+		{"other package type", SuppressionRule{CVEID: str("CVE-2023-0001"), PackageType: str("npm")}, false},
+		{"fixed", SuppressionRule{CVEID: str("CVE-2023-0001"), FixState: str(FixStateFixed)}, true},
+		{"not fixed", SuppressionRule{CVEID: str("CVE-2023-0001"), FixState: str(FixStateNotFixed)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.Matches(vuln))
+		})
+	}
+}
+
+func TestSuppressionRule_IsExpired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	assert.False(t, (&SuppressionRule{}).IsExpired(now))
+	assert.True(t, (&SuppressionRule{ExpiresAt: &past}).IsExpired(now))
+	assert.False(t, (&SuppressionRule{ExpiresAt: &future}).IsExpired(now))
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TicketCallbackRequest is the body of POST /api/v1/integrations/ticket-callback, sent by the automation
+// of a ticketing system when a ticket changes status
+type TicketCallbackRequest struct {
+	// EventID identifies the callback, one already applied is acknowledged without being applied again.
+	// Defaults to a hash of the timestamp and body of the request, so only redeliveries of a request are duplicates
+	EventID  string `json:"event_id,omitempty"`
+	TicketID string `json:"ticket_id"`
+	Status   string `json:"status"` // status of the ticket, mapped to a vulnerability status
+	// The vulnerabilities of the ticket: by ID, and by CVE, narrowed to the ones found on Image when set
+	VulnerabilityIDs []int    `json:"vulnerability_ids,omitempty"`
+	CVEIDs           []string `json:"cve_ids,omitempty"`
+	Image            string   `json:"image,omitempty"` // registry/repository:tag
+	Notes            *string  `json:"notes,omitempty"`
+}
+
+// TicketCallback is a callback applied to vulnerabilities
+type TicketCallback struct {
+	EventID          string        `db:"event_id" json:"event_id"`
+	TicketID         string        `db:"ticket_id" json:"ticket_id"`
+	TicketStatus     string        `db:"ticket_status" json:"ticket_status"`
+	Status           string        `db:"status" json:"status"` // vulnerability status the ticket status maps to
+	VulnerabilityIDs pq.Int64Array `db:"vulnerability_ids" json:"vulnerability_ids"`
+	ReceivedAt       time.Time     `db:"received_at" json:"received_at"`
+}
+
+// TicketCallbackResult is the response of POST /api/v1/integrations/ticket-callback
+type TicketCallbackResult struct {
+	TicketCallback
+	// Applied is false for a ticket status without a mapping and for a duplicate callback
+	Applied   bool `json:"applied"`
+	Duplicate bool `json:"duplicate"` // the event was already applied, TicketCallback is what it applied
+}
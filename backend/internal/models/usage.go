@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Quota limits, as reported in TeamUsage.Exceeded
+const (
+	QuotaScansPerMonth    = "scans_per_month"
+	QuotaSBOMStorageBytes = "sbom_storage_bytes"
+	QuotaVulnerabilities  = "vulnerabilities"
+)
+
+// TeamUsage is the usage of a team, the Kubernetes namespace of its ImageScans.
+// Scans are counted over the report period, storage and vulnerabilities are current totals
+type TeamUsage struct {
+	Namespace              string     `db:"namespace" json:"namespace"`
+	Scans                  int        `db:"scans" json:"scans"`
+	SBOMStorageBytes       int64      `db:"sbom_storage_bytes" json:"sbom_storage_bytes"`
+	VulnerabilitiesTracked int        `db:"vulnerabilities_tracked" json:"vulnerabilities_tracked"`
+	Quota                  *TeamQuota `db:"-" json:"quota,omitempty"`
+	Exceeded               []string   `db:"-" json:"exceeded"`
+}
+
+// UsageReport is the usage of every team over a calendar month (UTC)
+type UsageReport struct {
+	Month string      `json:"month"` // YYYY-MM
+	From  time.Time   `json:"from"`
+	To    time.Time   `json:"to"`
+	Teams []TeamUsage `json:"teams"`
+}
+
+// TeamQuota is a soft quota of a team. Unset limits are unlimited
+type TeamQuota struct {
+	Namespace           string    `db:"namespace" json:"namespace"`
+	MaxScansPerMonth    *int      `db:"max_scans_per_month" json:"max_scans_per_month,omitempty"`
+	MaxSBOMStorageBytes *int64    `db:"max_sbom_storage_bytes" json:"max_sbom_storage_bytes,omitempty"`
+	MaxVulnerabilities  *int      `db:"max_vulnerabilities" json:"max_vulnerabilities,omitempty"`
+	Enforce             bool      `db:"enforce" json:"enforce"` // reject new scans instead of only warning
+	UpdatedBy           *string   `db:"updated_by" json:"updated_by,omitempty"`
+	UpdatedAt           time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// TeamQuotaRequest is the API request format for setting a team quota
+type TeamQuotaRequest struct {
+	MaxScansPerMonth    *int   `json:"max_scans_per_month,omitempty"`
+	MaxSBOMStorageBytes *int64 `json:"max_sbom_storage_bytes,omitempty"`
+	MaxVulnerabilities  *int   `json:"max_vulnerabilities,omitempty"`
+	Enforce             bool   `json:"enforce"`
+}
+
+// Exceeded returns the limits the usage is over
+func (q *TeamQuota) Exceeded(usage TeamUsage) []string {
+	exceeded := []string{}
+	if q.MaxScansPerMonth != nil && usage.Scans > *q.MaxScansPerMonth {
+		exceeded = append(exceeded, QuotaScansPerMonth)
+	}
+	if q.MaxSBOMStorageBytes != nil && usage.SBOMStorageBytes > *q.MaxSBOMStorageBytes {
+		exceeded = append(exceeded, QuotaSBOMStorageBytes)
+	}
+	if q.MaxVulnerabilities != nil && usage.VulnerabilitiesTracked > *q.MaxVulnerabilities {
+		exceeded = append(exceeded, QuotaVulnerabilities)
+	}
+	return exceeded
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamQuota_Exceeded(t *testing.T) {
+	maxScans, maxVulns := 100, 500
+	maxStorage := int64(1 << 30)
+	quota := &TeamQuota{MaxScansPerMonth: &maxScans, MaxSBOMStorageBytes: &maxStorage, MaxVulnerabilities: &maxVulns}
+
+	assert.Empty(t, quota.Exceeded(TeamUsage{Scans: 100, SBOMStorageBytes: 1 << 30, VulnerabilitiesTracked: 500}))
+	assert.Equal(t, []string{QuotaScansPerMonth, QuotaVulnerabilities},
+		quota.Exceeded(TeamUsage{Scans: 101, SBOMStorageBytes: 1 << 20, VulnerabilitiesTracked: 501}))
+
+	// Unset limits are unlimited
+	assert.Empty(t, (&TeamQuota{}).Exceeded(TeamUsage{Scans: 1 << 20, SBOMStorageBytes: 1 << 40}))
+}
@@ -1,27 +1,39 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
 
 type Vulnerability struct {
-	ID                 int        `db:"id" json:"id"`
-	CVEID              string     `db:"cve_id" json:"cve_id"`
-	PackageName        string     `db:"package_name" json:"package_name"`
-	PackageVersion     string     `db:"package_version" json:"package_version"`
-	PackageType        *string    `db:"package_type" json:"package_type,omitempty"`
-	Severity           string     `db:"severity" json:"severity"`
-	FixVersion         *string    `db:"fix_version" json:"fix_version,omitempty"`
-	URL                *string    `db:"url" json:"url,omitempty"`
-	Description        *string    `db:"description" json:"description,omitempty"`
-	Status             string     `db:"status" json:"status"` // active, in_progress, fixed, ignored, accepted
-	FirstDetectedAt    time.Time  `db:"first_detected_at" json:"first_detected_at"`
-	LastSeenAt         time.Time  `db:"last_seen_at" json:"last_seen_at"`
-	RemediationDate    *time.Time `db:"remediation_date" json:"remediation_date,omitempty"`
-	Notes              *string    `db:"notes" json:"notes,omitempty"`
-	UpdatedBy          *string    `db:"updated_by" json:"updated_by,omitempty"`
-	ImageScanNamespace *string    `db:"imagescan_namespace" json:"imagescan_namespace,omitempty"`
-	ImageScanName      *string    `db:"imagescan_name" json:"imagescan_name,omitempty"`
-	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt          time.Time  `db:"updated_at" json:"updated_at"`
+	ID                   int        `db:"id" json:"id"`
+	CVEID                string     `db:"cve_id" json:"cve_id"`
+	PackageName          string     `db:"package_name" json:"package_name"`
+	PackageVersion       string     `db:"package_version" json:"package_version"`
+	PackageType          *string    `db:"package_type" json:"package_type,omitempty"`
+	PURL                 *string    `db:"purl" json:"purl,omitempty"`
+	Severity             string     `db:"severity" json:"severity"`
+	InitialSeverity      string     `db:"initial_severity" json:"initial_severity"` // severity when first detected, scanners may rescore the CVE since
+	FixVersion           *string    `db:"fix_version" json:"fix_version,omitempty"`
+	FixBecameAvailableAt *time.Time `db:"fix_became_available_at" json:"fix_became_available_at,omitempty"` // when a scan reported a fix after scans without one
+	URL                  *string    `db:"url" json:"url,omitempty"`
+	Description          *string    `db:"description" json:"description,omitempty"`
+	KnownExploited       bool       `db:"known_exploited" json:"known_exploited"` // listed in the CISA KEV catalog
+	Status               string     `db:"status" json:"status"`                   // active, in_progress, fixed, ignored, accepted
+	FirstDetectedAt      time.Time  `db:"first_detected_at" json:"first_detected_at"`
+	LastSeenAt           time.Time  `db:"last_seen_at" json:"last_seen_at"`
+	RemediationDate      *time.Time `db:"remediation_date" json:"remediation_date,omitempty"`
+	Notes                *string    `db:"notes" json:"notes,omitempty"`
+	UpdatedBy            *string    `db:"updated_by" json:"updated_by,omitempty"`
+	ImageScanNamespace   *string    `db:"imagescan_namespace" json:"imagescan_namespace,omitempty"`
+	ImageScanName        *string    `db:"imagescan_name" json:"imagescan_name,omitempty"`
+	CreatedAt            time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt            time.Time  `db:"updated_at" json:"updated_at"`
+
+	// Advisories reported by the latest scan, a JSON array of GrypeAdvisory
+	Advisories json.RawMessage `db:"advisories" json:"advisories,omitempty"`
 }
 
 type VulnerabilityUpdate struct {
@@ -43,6 +55,7 @@ type VulnerabilityWithImageInfo struct {
 	ImageID         int       `db:"image_id" json:"image_id"`
 	ImageName       string    `db:"image_name" json:"image_name"`
 	ImageDigest     *string   `db:"image_digest" json:"image_digest,omitempty"`
+	ImageMonitoring string    `db:"image_monitoring" json:"image_monitoring"`             // paused images are left out of SLA evaluation
 	FirstDetectedAt time.Time `db:"first_detected_at_for_image" json:"first_detected_at"` // Override to be per-image
 	LatestScanID    int       `db:"latest_scan_id" json:"latest_scan_id"`
 	LatestScanDate  time.Time `db:"latest_scan_date" json:"latest_scan_date"`
@@ -50,6 +63,19 @@ type VulnerabilityWithImageInfo struct {
 	SLAHigh         int       `db:"sla_high" json:"sla_high"`
 	SLAMedium       int       `db:"sla_medium" json:"sla_medium"`
 	SLALow          int       `db:"sla_low" json:"sla_low"`
+	SLATimeZone     string    `db:"sla_time_zone" json:"sla_time_zone"`
+	SLABusinessDays bool      `db:"sla_business_days" json:"sla_business_days"`
+	// Holidays skipped by business-day SLAs
+	SLAHolidays pq.StringArray `db:"sla_holidays" json:"-"`
+	// Deadline of the vulnerability on this image, computed in SLATimeZone from the SLA of SLASeverity
+	SLADueDate string     `db:"-" json:"sla_due_date"` // YYYY-MM-DD, the last day to remediate
+	SLADueAt   *time.Time `db:"-" json:"sla_due_at"`   // end of SLADueDate, with the timezone offset
+	// Severity the SLA is counted from: the initial or the current one, depending on SLA_SEVERITY_POLICY
+	SLASeverity string `db:"-" json:"sla_severity"`
+	// Times the vulnerability came back on this image after scans without it
+	FlapCount int `db:"flap_count" json:"flap_count"`
+	// Compliance profiles whose remediation timeline covers the vulnerability on this image
+	Frameworks []string `db:"-" json:"frameworks,omitempty"`
 }
 
 // VulnerabilityHistory represents an audit record for vulnerability changes
@@ -65,6 +91,22 @@ type VulnerabilityHistory struct {
 	ImageName       *string   `db:"image_name" json:"image_name,omitempty"`
 }
 
+// SeverityChange records a scan reporting another severity for a vulnerability than the previous scans,
+// as Grype rescores CVEs between DB builds
+type SeverityChange struct {
+	ID              int        `db:"id" json:"id"`
+	VulnerabilityID int        `db:"vulnerability_id" json:"vulnerability_id"`
+	CVEID           string     `db:"cve_id" json:"cve_id"`
+	PackageName     string     `db:"package_name" json:"package_name"`
+	PackageVersion  string     `db:"package_version" json:"package_version"`
+	OldSeverity     string     `db:"old_severity" json:"old_severity"`
+	NewSeverity     string     `db:"new_severity" json:"new_severity"`
+	ScanID          *int       `db:"scan_id" json:"scan_id,omitempty"`
+	ImageName       *string    `db:"image_name" json:"image_name,omitempty"`
+	GrypeDBBuilt    *time.Time `db:"grype_db_built" json:"grype_db_built,omitempty"`
+	ChangedAt       time.Time  `db:"changed_at" json:"changed_at"`
+}
+
 // VulnerabilityUpdateWithContext extends update with user context and audit trail information
 type VulnerabilityUpdateWithContext struct {
 	Status    *string `json:"status,omitempty"`
@@ -81,6 +123,29 @@ type BulkUpdateRequest struct {
 	Notes            *string `json:"notes,omitempty"`
 }
 
+// BatchGetRequest for fetching multiple vulnerabilities at once
+type BatchGetRequest struct {
+	IDs []int `json:"ids"`
+}
+
+// BatchGetResponse returns the found vulnerabilities and the requested IDs that don't exist
+type BatchGetResponse struct {
+	Data       []Vulnerability `json:"data"`
+	MissingIDs []int           `json:"missing_ids"`
+}
+
+// VulnerabilitySearch selects vulnerabilities matching all of its criteria, nil criteria match everything
+type VulnerabilitySearch struct {
+	PackageName    *string  // substring of the package name, case-insensitive
+	CVEPrefix      *string  // prefix of the CVE ID, case-insensitive
+	Text           *string  // full-text search of the description, in web search syntax
+	Severities     []string // any of the severities
+	Status         *string
+	HasFix         *bool
+	DetectedAfter  *time.Time // first detected at or after
+	DetectedBefore *time.Time // first detected at or before
+}
+
 // Valid status values
 const (
 	StatusActive     = "active"
@@ -92,8 +157,46 @@ const (
 
 var ValidStatuses = []string{StatusActive, StatusInProgress, StatusFixed, StatusIgnored, StatusAccepted}
 
+// FlappingThreshold is the number of times a vulnerability must come back on an image after scans
+// without it to be flapping there, as matcher nondeterminism does
+const FlappingThreshold = 2
+
 // ImageScanContext provides ImageScan information for linking vulnerabilities
 type ImageScanContext struct {
 	Namespace string `json:"namespace"`
 	Name      string `json:"name"`
 }
+
+// Results of the rows of a triage import
+const (
+	TriageImportUpdated  = "updated"   // the matching vulnerabilities were updated
+	TriageImportNotFound = "not_found" // no stored vulnerability matches the row
+	TriageImportInvalid  = "invalid"   // the row can't be applied, the import is rejected
+)
+
+// TriageImportUpdate is the change a row of a triage import applies to the vulnerabilities it matches
+type TriageImportUpdate struct {
+	VulnerabilityIDs []int
+	Update           VulnerabilityUpdateWithContext
+}
+
+// TriageImportRowResult is the outcome of a row of a triage import
+type TriageImportRowResult struct {
+	Row              int    `json:"row"` // line of the CSV, the header is line 1
+	CVEID            string `json:"cve"`
+	PackageName      string `json:"package,omitempty"`
+	Image            string `json:"image,omitempty"`
+	Result           string `json:"result"`
+	VulnerabilityIDs []int  `json:"vulnerability_ids,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// TriageImportResult is the response of POST /api/v1/vulnerabilities/import
+type TriageImportResult struct {
+	DryRun   bool                    `json:"dry_run"`
+	Applied  bool                    `json:"applied"`
+	Updated  int                     `json:"updated"`   // vulnerabilities updated, or to update on a dry run
+	NotFound int                     `json:"not_found"` // rows without a matching vulnerability
+	Invalid  int                     `json:"invalid"`
+	Rows     []TriageImportRowResult `json:"rows"`
+}
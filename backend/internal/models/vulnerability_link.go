@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// LinkHistoryField is the field of the vulnerability history recording the links added to a
+// vulnerability, with the URL as new value, and removed from it, with the URL as old value
+const LinkHistoryField = "link"
+
+// VulnerabilityLink is a link a user attached to a vulnerability on an image, such as the MR/PR
+// fixing it or the internal ticket tracking it
+type VulnerabilityLink struct {
+	ID              int       `db:"id" json:"id"`
+	VulnerabilityID int       `db:"vulnerability_id" json:"vulnerability_id"`
+	ImageID         int       `db:"image_id" json:"image_id"`
+	URL             string    `db:"url" json:"url"`
+	Title           *string   `db:"title" json:"title,omitempty"`
+	CreatedBy       *string   `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+	ImageName       string    `db:"image_name" json:"image_name"`
+}
+
+// VulnerabilityLinkRequest is the API request format for attaching a link to a vulnerability
+type VulnerabilityLinkRequest struct {
+	ImageID int     `json:"image_id"`
+	URL     string  `json:"url"`
+	Title   *string `json:"title,omitempty"`
+}
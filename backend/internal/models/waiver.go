@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// WaiverExpiryActor is recorded in the history of vulnerabilities reverted by an expired waiver
+const WaiverExpiryActor = "system:waivers"
+
+// Waiver accepts the risk of a vulnerability on an image until ExpiresAt. The status of a vulnerability
+// is shared by the images it is found on, the image records where the risk was assessed
+type Waiver struct {
+	ID              int        `db:"id" json:"id"`
+	VulnerabilityID int        `db:"vulnerability_id" json:"vulnerability_id"`
+	ImageID         int        `db:"image_id" json:"image_id"`
+	Justification   string     `db:"justification" json:"justification"`
+	ExpiresAt       time.Time  `db:"expires_at" json:"expires_at"`
+	ExpiredAt       *time.Time `db:"expired_at" json:"expired_at,omitempty"` // set once the expiry job reverted it
+	CreatedBy       *string    `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	CVEID           string     `db:"cve_id" json:"cve_id"`
+	PackageName     string     `db:"package_name" json:"package_name"`
+	ImageName       string     `db:"image_name" json:"image_name"`
+}
+
+// WaiverRequest is the API request format for creating a waiver
+type WaiverRequest struct {
+	VulnerabilityID int        `json:"vulnerability_id"`
+	ImageID         int        `json:"image_id"`
+	Justification   string     `json:"justification"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+}
+
+// WaiverFilter narrows the waivers listed
+type WaiverFilter struct {
+	ImageID         *int
+	VulnerabilityID *int
+	Expired         bool // list the expired waivers instead of the ones in effect
+}
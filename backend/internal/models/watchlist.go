@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Watchlist events
+const (
+	WatchlistEventDetected   = "detected"    // a scan found the CVE or package on an image it wasn't on before
+	WatchlistEventFixChanged = "fix_changed" // the fix version of a finding changed, usually a fix became available
+)
+
+// WatchlistSubscription asks for a notification whenever a CVE or a package shows up on an image
+// or its fix changes. A subscription targets a CVE, a package, or a CVE in a package
+type WatchlistSubscription struct {
+	ID            int       `db:"id" json:"id"`
+	CVEID         *string   `db:"cve_id" json:"cve_id,omitempty"`
+	PackageName   *string   `db:"package_name" json:"package_name,omitempty"`
+	Subscriber    string    `db:"subscriber" json:"subscriber"`
+	WebhookURL    string    `db:"webhook_url" json:"webhook_url"`
+	WebhookFormat string    `db:"webhook_format" json:"webhook_format"`
+	Locale        string    `db:"locale" json:"locale"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+}
+
+// WatchlistSubscriptionRequest is the API request format for subscribing
+type WatchlistSubscriptionRequest struct {
+	CVEID         *string `json:"cve_id,omitempty"`
+	PackageName   *string `json:"package_name,omitempty"`
+	WebhookURL    string  `json:"webhook_url"`
+	WebhookFormat string  `json:"webhook_format,omitempty"`
+	Locale        string  `json:"locale,omitempty"`
+}
+
+// Matches reports whether a finding is covered by the subscription
+func (s *WatchlistSubscription) Matches(v *Vulnerability) bool {
+	if s.CVEID != nil && *s.CVEID != v.CVEID {
+		return false
+	}
+	if s.PackageName != nil && *s.PackageName != v.PackageName {
+		return false
+	}
+	return s.CVEID != nil || s.PackageName != nil
+}
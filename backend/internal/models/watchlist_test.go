@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchlistSubscription_Matches(t *testing.T) {
+	vuln := &Vulnerability{CVEID: "CVE-2024-3094", PackageName: "xz-utils", PackageVersion: "5.6.0"}
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		sub  WatchlistSubscription
+		want bool
+	}{
+		{"cve", WatchlistSubscription{CVEID: str("CVE-2024-3094")}, true},
+		{"other cve", WatchlistSubscription{CVEID: str("CVE-2024-0001")}, false},
+		{"package", WatchlistSubscription{PackageName: str("xz-utils")}, true},
+		{"cve in package", WatchlistSubscription{CVEID: str("CVE-2024-3094"), PackageName: str("xz-utils")}, true},
+		{"cve in other package", WatchlistSubscription{CVEID: str("CVE-2024-3094"), PackageName: str("liblzma5")}, false},
+		{"no criteria", WatchlistSubscription{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.sub.Matches(vuln))
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
 
 // WebhookConfig represents a webhook configuration synced from an ImageScan CRD
 type WebhookConfig struct {
@@ -16,6 +20,9 @@ type WebhookConfig struct {
 	StatusChangeOnlyFixable  bool      `db:"status_change_only_fixable" json:"status_change_only_fixable"`
 	StatusChangeTransitions  []string  `db:"status_change_transitions" json:"status_change_transitions"`
 	StatusChangeIncludeNotes bool      `db:"status_change_include_notes" json:"status_change_include_notes"`
+	FixAvailableEnabled      bool      `db:"fix_available_enabled" json:"fix_available_enabled"`
+	FixAvailableMinSeverity  string    `db:"fix_available_min_severity" json:"fix_available_min_severity"`
+	Locale                   string    `db:"locale" json:"locale"`
 	CreatedAt                time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt                time.Time `db:"updated_at" json:"updated_at"`
 }
@@ -31,4 +38,22 @@ type WebhookConfigRequest struct {
 	StatusChangeOnlyFixable  bool     `json:"status_change_only_fixable"`
 	StatusChangeTransitions  []string `json:"status_change_transitions"`
 	StatusChangeIncludeNotes bool     `json:"status_change_include_notes"`
+	FixAvailableEnabled      bool     `json:"fix_available_enabled"`
+	FixAvailableMinSeverity  string   `json:"fix_available_min_severity"`
+	Locale                   string   `json:"locale"`
+}
+
+// FixAvailableTarget is a finding that gained a fix version, on the images of an ImageScan whose
+// webhook config asks for fix-available notifications
+type FixAvailableTarget struct {
+	Namespace       string         `db:"namespace"`
+	Name            string         `db:"name"`
+	VulnerabilityID int            `db:"vulnerability_id"`
+	CVEID           string         `db:"cve_id"`
+	PackageName     string         `db:"package_name"`
+	PackageVersion  string         `db:"package_version"`
+	Severity        string         `db:"severity"`
+	FixVersion      string         `db:"fix_version"`
+	Images          pq.StringArray `db:"images"`          // images of the ImageScan with the finding
+	AffectedImages  int            `db:"affected_images"` // images with an open fixable finding of the CVE
 }
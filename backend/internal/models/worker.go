@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Worker run statuses
+const (
+	WorkerStatusRunning   = "running"
+	WorkerStatusSucceeded = "succeeded"
+	WorkerStatusFailed    = "failed"
+)
+
+// WorkerRun is the last run of a periodic background worker, whichever replica ran it
+type WorkerRun struct {
+	Name            string     `db:"name" json:"name"`
+	Status          string     `db:"status" json:"status"` // running, succeeded, failed
+	Instance        string     `db:"instance" json:"instance"`
+	LastStartedAt   time.Time  `db:"last_started_at" json:"last_started_at"`
+	LastFinishedAt  *time.Time `db:"last_finished_at" json:"last_finished_at,omitempty"`
+	LastSucceededAt *time.Time `db:"last_succeeded_at" json:"last_succeeded_at,omitempty"`
+	LastDurationMs  *int64     `db:"last_duration_ms" json:"last_duration_ms,omitempty"`
+	LastError       *string    `db:"last_error" json:"last_error,omitempty"`
+	RunCount        int64      `db:"run_count" json:"run_count"`
+	FailureCount    int64      `db:"failure_count" json:"failure_count"`
+}
+
+// WorkerStatus is a worker registered on this replica and its last run. NextRunAt is when it is
+// next due on any replica
+type WorkerStatus struct {
+	Name      string     `json:"name"`
+	Interval  string     `json:"interval"`
+	NextRunAt time.Time  `json:"next_run_at"`
+	LastRun   *WorkerRun `json:"last_run,omitempty"`
+}
@@ -0,0 +1,193 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultDeniedNetworks are the destinations webhooks can't reach by default: loopback, private,
+// link-local (including cloud metadata endpoints), carrier-grade NAT and other non-public ranges
+var DefaultDeniedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// DestinationPolicy restricts where webhooks are sent, so URLs from scan requests and
+// ImageScans can't be used to reach services inside the cluster
+type DestinationPolicy struct {
+	AllowedSchemes  []string
+	DeniedNetworks  []netip.Prefix
+	AllowedNetworks []netip.Prefix // exceptions to DeniedNetworks, e.g. an internal chat server
+}
+
+// DefaultDestinationPolicy allows http and https to public addresses
+func DefaultDestinationPolicy() *DestinationPolicy {
+	return &DestinationPolicy{
+		AllowedSchemes: []string{"https", "http"},
+		DeniedNetworks: DefaultDeniedNetworks,
+	}
+}
+
+// NewDestinationPolicy builds a policy from comma-separated lists: the allowed schemes (http and https
+// when empty), networks denied on top of DefaultDeniedNetworks, and networks allowed despite being denied
+func NewDestinationPolicy(schemes, denied, allowed string) (*DestinationPolicy, error) {
+	policy := DefaultDestinationPolicy()
+	if schemes != "" {
+		policy.AllowedSchemes = nil
+		for _, scheme := range strings.Split(schemes, ",") {
+			if scheme = strings.ToLower(strings.TrimSpace(scheme)); scheme != "" {
+				policy.AllowedSchemes = append(policy.AllowedSchemes, scheme)
+			}
+		}
+	}
+	extra, err := ParsePrefixes(denied)
+	if err != nil {
+		return nil, err
+	}
+	policy.DeniedNetworks = append(slices.Clone(DefaultDeniedNetworks), extra...)
+	if policy.AllowedNetworks, err = ParsePrefixes(allowed); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// ParsePrefixes parses a comma-separated list of CIDRs or IP addresses
+func ParsePrefixes(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// CheckURL validates a webhook URL without resolving it: the scheme, and the host when it is
+// an IP address. Host names are checked when the webhook is sent, against what they resolve to
+func (p *DestinationPolicy) CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL")
+	}
+	if !slices.Contains(p.AllowedSchemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("webhook URL scheme must be one of %s", strings.Join(p.AllowedSchemes, ", "))
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return fmt.Errorf("webhook URL host %s is not allowed", host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.checkAddr(addr)
+	}
+	return nil
+}
+
+func (p *DestinationPolicy) checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, prefix := range p.AllowedNetworks {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	for _, prefix := range p.DeniedNetworks {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("webhook destination %s is in denied network %s", addr, prefix)
+		}
+	}
+	return nil
+}
+
+// resolver is the DNS lookup used by the dialer, replaced in tests
+type resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// dialContext resolves the host, checks every address, then connects to the checked addresses
+// only, so a DNS answer changing between check and connect can't reach a denied network
+func (p *DestinationPolicy) dialContext(dialer *net.Dialer, lookup resolver) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		var addrs []netip.Addr
+		if addr, err := netip.ParseAddr(host); err == nil {
+			addrs = []netip.Addr{addr}
+		} else if addrs, err = lookup.LookupNetIP(ctx, "ip", host); err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no addresses found for %s", host)
+		}
+		// One denied address rejects the host, rather than connecting until one is allowed
+		for _, addr := range addrs {
+			if err := p.checkAddr(addr); err != nil {
+				return nil, err
+			}
+		}
+
+		var lastErr error
+		for _, addr := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// httpClient returns a client enforcing the policy on every connection and redirect.
+// Proxies from the environment are not used, they would connect on the client's behalf
+func (p *DestinationPolicy) httpClient(timeout time.Duration, lookup resolver) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = p.dialContext(&net.Dialer{Timeout: timeout}, lookup)
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return p.CheckURL(req.URL.String())
+		},
+	}
+}
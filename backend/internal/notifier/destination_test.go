@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDestinationPolicy_CheckURL(t *testing.T) {
+	policy := DefaultDestinationPolicy()
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://hooks.slack.com/services/T0/B0/x", true},
+		{"http://203.0.113.10:8080/hook", true},
+		{"ftp://hooks.example.com/x", false},
+		{"file:///etc/passwd", false},
+		{"http://localhost:8080/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://10.0.0.12/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://[::1]/hook", false},
+		{"http://[::ffff:192.168.1.1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+	}
+	for _, tt := range tests {
+		err := policy.CheckURL(tt.url)
+		assert.Equal(t, tt.allowed, err == nil, "%s: %v", tt.url, err)
+	}
+
+	// Exceptions take precedence over the deny-list
+	policy.AllowedNetworks = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}
+	assert.NoError(t, policy.CheckURL("http://10.0.0.12/hook"))
+	assert.Error(t, policy.CheckURL("http://10.0.1.12/hook"))
+}
+
+func TestNewDestinationPolicy(t *testing.T) {
+	policy, err := NewDestinationPolicy("HTTPS", "203.0.113.0/24", "10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https"}, policy.AllowedSchemes)
+	assert.Error(t, policy.CheckURL("http://hooks.example.com/x"))
+	assert.Error(t, policy.CheckURL("https://203.0.113.10/x"))
+	assert.NoError(t, policy.CheckURL("https://10.1.2.3/x"))
+	assert.Error(t, policy.CheckURL("https://10.1.2.4/x"))
+
+	_, err = NewDestinationPolicy("", "not-a-network", "")
+	assert.Error(t, err)
+}
+
+type fakeResolver map[string][]netip.Addr
+
+func (r fakeResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	return r[host], nil
+}
+
+func TestDestinationPolicy_PinsResolvedAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	port := server.URL[len("http://127.0.0.1:"):]
+
+	lookup := fakeResolver{
+		// A public name resolving to a denied address, as with DNS rebinding
+		"hooks.example.com":    {netip.MustParseAddr("203.0.113.10"), netip.MustParseAddr("127.0.0.1")},
+		"internal.example.com": {netip.MustParseAddr("127.0.0.1")},
+	}
+
+	client := DefaultDestinationPolicy().httpClient(time.Second, lookup)
+	for _, host := range []string{"hooks.example.com", "internal.example.com"} {
+		_, err := client.Post("http://"+host+":"+port+"/hook", "application/json", nil)
+		require.Error(t, err, host)
+		assert.Contains(t, err.Error(), "denied network")
+	}
+
+	// Allowed, the connection goes to the address that was checked
+	policy := DefaultDestinationPolicy()
+	policy.AllowedNetworks = []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}
+	resp, err := policy.httpClient(time.Second, lookup).Post("http://internal.example.com:"+port+"/hook", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestDestinationPolicy_Redirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	policy := DefaultDestinationPolicy()
+	policy.AllowedNetworks = []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}
+	_, err := policy.httpClient(time.Second, fakeResolver{}).Get(server.URL)
+	var urlErr *url.Error
+	require.ErrorAs(t, err, &urlErr)
+	assert.Contains(t, err.Error(), "denied network")
+}
+
+func TestNotifier_SetDestinationPolicy(t *testing.T) {
+	n := New(zap.NewNop(), "")
+	n.SetDestinationPolicy(DefaultDestinationPolicy())
+
+	err := n.sendWebhook(context.Background(), "http://169.254.169.254/latest/meta-data/", map[string]string{"text": "hi"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "denied network")
+}
@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is used when a webhook config doesn't set a locale, and for messages
+// missing from a translation
+const DefaultLocale = "en"
+
+// Locales lists the languages notifications can be written in, one bundle per file in locales/
+var Locales = []string{"en", "fr", "de"}
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var bundle = loadBundle()
+
+func loadBundle() *i18n.Bundle {
+	b := i18n.NewBundle(language.English)
+	b.RegisterUnmarshalFunc("json", json.Unmarshal)
+	for _, locale := range Locales {
+		if _, err := b.LoadMessageFileFS(localeFiles, "locales/"+locale+".json"); err != nil {
+			panic(fmt.Sprintf("failed to load %s notification messages: %v", locale, err))
+		}
+	}
+	return b
+}
+
+// IsSupportedLocale reports whether notifications can be written in a locale. Empty means the default
+func IsSupportedLocale(locale string) bool {
+	if locale == "" {
+		return true
+	}
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// translator renders notification text in one locale
+type translator struct {
+	localizer *i18n.Localizer
+}
+
+func newTranslator(locale string) translator {
+	return translator{localizer: i18n.NewLocalizer(bundle, locale, DefaultLocale)}
+}
+
+// text renders a message. data is a map or struct of the template fields, nil for plain text
+func (t translator) text(id string, data interface{}) string {
+	return t.localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: data})
+}
+
+// plural renders a message with plural forms, exposing count to the template as .Count
+func (t translator) plural(id string, count int, data map[string]interface{}) string {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["Count"] = count
+	return t.localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: data, PluralCount: count})
+}
+
+// Translator renders the messages of the bundles for the text written outside notifications,
+// such as the compliance reports
+type Translator struct {
+	translator
+}
+
+// NewTranslator creates a translator of locale, falling back to DefaultLocale
+func NewTranslator(locale string) Translator {
+	return Translator{translator: newTranslator(locale)}
+}
+
+// Text renders a message. data is a map or struct of the template fields, nil for plain text
+func (t Translator) Text(id string, data interface{}) string {
+	return t.text(id, data)
+}
+
+func (t translator) localize(config *i18n.LocalizeConfig) string {
+	// A message missing from a translation still renders in English, along with an error
+	msg, err := t.localizer.Localize(config)
+	if msg == "" && err != nil {
+		return config.MessageID
+	}
+	return msg
+}
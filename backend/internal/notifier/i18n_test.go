@@ -0,0 +1,56 @@
+package notifier
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocales_Complete(t *testing.T) {
+	messages := func(locale string) map[string]interface{} {
+		data, err := localeFiles.ReadFile("locales/" + locale + ".json")
+		require.NoError(t, err)
+		var parsed map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &parsed))
+		return parsed
+	}
+
+	english := messages(DefaultLocale)
+	for _, locale := range Locales {
+		translated := messages(locale)
+		for id := range english {
+			assert.Contains(t, translated, id, "%s is missing %s", locale, id)
+		}
+		for id := range translated {
+			assert.Contains(t, english, id, "%s has unknown message %s", locale, id)
+		}
+	}
+}
+
+func TestTranslator(t *testing.T) {
+	payload := NotificationPayload{Image: "nginx:1.25", TotalVulns: 1, SeverityCounts: SeverityCounts{High: 1}}
+	n := New(nil, "")
+
+	assert.Equal(t, "⚠️ Found 1 vulnerability in `nginx:1.25`", n.buildSlackPayload(payload, newTranslator("")).Text)
+	assert.Equal(t, "⚠️ 1 vulnérabilité trouvée dans `nginx:1.25`", n.buildSlackPayload(payload, newTranslator("fr")).Text)
+
+	payload.TotalVulns = 3
+	teams := n.buildTeamsPayload(payload, newTranslator("de"))
+	assert.Equal(t, "3 Schwachstellen gefunden", teams.Summary)
+	assert.Equal(t, "Kritisch", teams.Sections[0].Facts[0].Name)
+
+	// Unknown locales fall back to English
+	assert.Equal(t, "never", describeLastScan(nil, newTranslator("pt-BR")))
+
+	stale := n.buildSlackStaleScanPayload(StaleScanNotificationPayload{ImageName: "redis:7", StaleAfter: 48 * time.Hour}, newTranslator("fr"))
+	assert.Equal(t, "⏰ Aucune analyse réussie de `redis:7` depuis plus de 48h0m0s", stale.Text)
+}
+
+func TestIsSupportedLocale(t *testing.T) {
+	assert.True(t, IsSupportedLocale(""))
+	assert.True(t, IsSupportedLocale("de"))
+	assert.False(t, IsSupportedLocale("pt"))
+}
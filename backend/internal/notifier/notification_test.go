@@ -251,3 +251,106 @@ func TestNew(t *testing.T) {
 	assert.NotNil(t, n.httpClient)
 	assert.Equal(t, "http://test.local", n.frontendURL)
 }
+
+func TestSendWatchlistNotification(t *testing.T) {
+	var received SlackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := New(zap.NewNop(), "http://localhost:3000")
+	fix := "5.6.1"
+	cve := "CVE-2024-3094"
+	payload := WatchlistNotificationPayload{
+		WatchCVE:  &cve,
+		ImageName: "docker.io/acme/api:latest",
+		ScanID:    42,
+		Events: []WatchlistEvent{
+			{Kind: "detected", CVEID: "CVE-2024-3094", PackageName: "xz-utils", PackageVersion: "5.6.0", Severity: "Critical"},
+			{Kind: "fix_changed", CVEID: "CVE-2024-3094", PackageName: "liblzma5", PackageVersion: "5.6.0", Severity: "Critical", FixVersion: &fix},
+		},
+	}
+
+	require.NoError(t, n.SendWatchlistNotification(context.Background(), WebhookConfig{URL: server.URL, Format: "slack"}, payload))
+
+	assert.Contains(t, received.Text, "CVE-2024-3094")
+	require.Len(t, received.Attachments, 1)
+	fields := received.Attachments[0].Fields
+	require.Len(t, fields, 3)
+	assert.Contains(t, fields[1].Value, "xz-utils 5.6.0 (Critical): detected, fix not available")
+	assert.Contains(t, fields[1].Value, "liblzma5 5.6.0 (Critical): fix available in 5.6.1")
+	assert.Contains(t, fields[2].Value, "http://localhost:3000/scans/42")
+}
+
+func TestSendWatchlistNotification_NoEvents(t *testing.T) {
+	n := New(zap.NewNop(), "")
+	// No request is made, so the invalid URL is never used
+	err := n.SendWatchlistNotification(context.Background(), WebhookConfig{URL: "://invalid"}, WatchlistNotificationPayload{})
+	assert.NoError(t, err)
+}
+
+func TestSendFixAvailableNotification(t *testing.T) {
+	var received SlackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := New(zap.NewNop(), "http://localhost:3000")
+	payload := FixAvailableNotificationPayload{
+		ImageScan: "payments/api",
+		ScanID:    42,
+		Fixes: []FixAvailableEvent{
+			{CVEID: "CVE-2024-5535", PackageName: "openssl", PackageVersion: "3.0.13", Severity: "High", FixVersion: "3.0.14",
+				AffectedImages: 12, Images: []string{"docker.io/acme/api:latest"}},
+			{CVEID: "CVE-2024-0001", PackageName: "zlib", PackageVersion: "1.2.13", Severity: "Low", FixVersion: "1.3"},
+		},
+	}
+
+	config := WebhookConfig{URL: server.URL, Format: "slack", MinSeverity: "High"}
+	require.NoError(t, n.SendFixAvailableNotification(context.Background(), config, payload))
+
+	// The low severity finding is below the threshold
+	assert.Equal(t, "🔧 Fix now available for CVE-2024-5535 affecting 12 images", received.Text)
+	require.Len(t, received.Attachments, 1)
+	fields := received.Attachments[0].Fields
+	require.Len(t, fields, 4)
+	assert.Equal(t, "docker.io/acme/api:latest", fields[1].Value)
+	assert.Equal(t, "• CVE-2024-5535 in openssl 3.0.13 (High): fix available in 3.0.14, 12 images affected", fields[2].Value)
+	assert.Contains(t, fields[3].Value, "http://localhost:3000/scans/42")
+}
+
+func TestSendFixAvailableNotification_BelowThreshold(t *testing.T) {
+	n := New(zap.NewNop(), "")
+	// No request is made, so the invalid URL is never used
+	payload := FixAvailableNotificationPayload{Fixes: []FixAvailableEvent{{CVEID: "CVE-2024-0001", Severity: "Medium"}}}
+	err := n.SendFixAvailableNotification(context.Background(), WebhookConfig{URL: "://invalid", MinSeverity: "Critical"}, payload)
+	assert.NoError(t, err)
+}
+
+func TestSendNewImageNotification(t *testing.T) {
+	var received TeamsPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := New(zap.NewNop(), "http://localhost:3000")
+	digest := "sha256:4f1c0ffee"
+	payload := NewImageNotificationPayload{ImageName: "docker.io/acme/shadow:latest", ImageID: 7, ImageDigest: &digest, ScanID: 42}
+
+	require.NoError(t, n.SendNewImageNotification(context.Background(), WebhookConfig{URL: server.URL, Format: "teams"}, payload))
+
+	assert.Equal(t, "🆕 New Image: docker.io/acme/shadow:latest", received.Title)
+	require.Len(t, received.Sections, 1)
+	facts := received.Sections[0].Facts
+	require.Len(t, facts, 3)
+	assert.Equal(t, "none, submitted directly", facts[1].Value)
+	assert.Equal(t, digest, facts[2].Value)
+	require.Len(t, received.PotentialAction, 1)
+	assert.Equal(t, "http://localhost:3000/images/7", received.PotentialAction[0].Targets[0].URI)
+}
@@ -5,16 +5,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+// webhookTimeout bounds the delivery of a webhook, connection included
+const webhookTimeout = 10 * time.Second
+
 type Notifier struct {
 	logger      *zap.Logger
 	httpClient  *http.Client
 	frontendURL string
+	policy      *DestinationPolicy
 }
 
 func New(logger *zap.Logger, frontendURL string) *Notifier {
@@ -22,17 +28,28 @@ func New(logger *zap.Logger, frontendURL string) *Notifier {
 		logger:      logger,
 		frontendURL: frontendURL,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: webhookTimeout,
 		},
 	}
 }
 
+// SetDestinationPolicy restricts the URLs webhooks are sent to. Without a policy any URL is used
+func (n *Notifier) SetDestinationPolicy(policy *DestinationPolicy) {
+	n.policy = policy
+	if policy == nil {
+		n.httpClient = &http.Client{Timeout: webhookTimeout}
+		return
+	}
+	n.httpClient = policy.httpClient(webhookTimeout, net.DefaultResolver)
+}
+
 // WebhookConfig represents webhook configuration from scan request
 type WebhookConfig struct {
 	URL         string `json:"url"`
 	Format      string `json:"format"`
 	MinSeverity string `json:"min_severity"`
 	OnlyFixable bool   `json:"only_fixable"`
+	Locale      string `json:"locale,omitempty"` // language of the notification text, DefaultLocale when empty
 }
 
 // NotificationPayload contains data for webhook notification
@@ -85,14 +102,15 @@ func (n *Notifier) SendNotification(ctx context.Context, config WebhookConfig, p
 		payload.ScanURL = fmt.Sprintf("%s/scans/%d", n.frontendURL, payload.ScanID)
 	}
 
+	t := newTranslator(config.Locale)
 	var webhookPayload interface{}
 
 	switch config.Format {
 	case "teams":
-		webhookPayload = n.buildTeamsPayload(payload)
+		webhookPayload = n.buildTeamsPayload(payload, t)
 	default:
 		// Default to Slack format for backward compatibility
-		webhookPayload = n.buildSlackPayload(payload)
+		webhookPayload = n.buildSlackPayload(payload, t)
 	}
 
 	return n.sendWebhook(ctx, config.URL, webhookPayload)
@@ -140,6 +158,12 @@ func (n *Notifier) sendWebhook(ctx context.Context, url string, payload interfac
 		return fmt.Errorf("failed to marshal webhook payload: %w", err)
 	}
 
+	if n.policy != nil {
+		if err := n.policy.CheckURL(url); err != nil {
+			return err
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
 	if err != nil {
 		return fmt.Errorf("failed to create webhook request: %w", err)
@@ -179,6 +203,21 @@ type StatusChangeNotificationPayload struct {
 	VulnerabilityID int
 	VulnURL         string
 	Timestamp       time.Time
+
+	// SLA deadline of vulnerabilities that are still open: last day to remediate (YYYY-MM-DD)
+	// in the ImageScan's SLA timezone. Empty when the vulnerability is closed
+	SLADueDate  string
+	SLATimeZone string
+
+	// Advisories reported by the scanner, and the links users attached such as the MR/PR fixing it
+	Advisories []Link
+	FixLinks   []Link
+}
+
+// Link is a link of a notification, shown as its title
+type Link struct {
+	Title string
+	URL   string
 }
 
 // StatusChangeWebhookConfig extends webhook config for status changes
@@ -189,6 +228,7 @@ type StatusChangeWebhookConfig struct {
 	OnlyFixable        bool
 	StatusTransitions  []string
 	IncludeNoteChanges bool
+	Locale             string
 }
 
 // SendStatusChangeNotification sends webhook for vulnerability status changes
@@ -224,12 +264,13 @@ func (n *Notifier) SendStatusChangeNotification(ctx context.Context, config Stat
 		payload.VulnURL = fmt.Sprintf("%s/vulnerabilities/%d", n.frontendURL, payload.VulnerabilityID)
 	}
 
+	t := newTranslator(config.Locale)
 	var webhookPayload interface{}
 	switch config.Format {
 	case "teams":
-		webhookPayload = n.buildTeamsStatusChangePayload(payload)
+		webhookPayload = n.buildTeamsStatusChangePayload(payload, t)
 	default:
-		webhookPayload = n.buildSlackStatusChangePayload(payload)
+		webhookPayload = n.buildSlackStatusChangePayload(payload, t)
 	}
 
 	return n.sendWebhook(ctx, config.URL, webhookPayload)
@@ -258,3 +299,257 @@ func contains(slice []string, item string) bool {
 	}
 	return false
 }
+
+// StaleScanNotificationPayload contains data for stale-scan alerts
+type StaleScanNotificationPayload struct {
+	ImageName              string
+	ImageID                int
+	ImageScan              string // namespace/name of the ImageScan expected to scan the image
+	LastSuccessfulScanDate *time.Time
+	StaleAfter             time.Duration
+	ImageURL               string
+}
+
+// SendStaleScanNotification alerts the ImageScan's webhook that its image is no longer being scanned
+// Severity filters don't apply: a silent scanner hides findings of every severity
+func (n *Notifier) SendStaleScanNotification(ctx context.Context, config WebhookConfig, payload StaleScanNotificationPayload) error {
+	if n.frontendURL != "" && payload.ImageURL == "" {
+		payload.ImageURL = fmt.Sprintf("%s/images/%d", n.frontendURL, payload.ImageID)
+	}
+
+	t := newTranslator(config.Locale)
+	var webhookPayload interface{}
+	switch config.Format {
+	case "teams":
+		webhookPayload = n.buildTeamsStaleScanPayload(payload, t)
+	default:
+		webhookPayload = n.buildSlackStaleScanPayload(payload, t)
+	}
+
+	return n.sendWebhook(ctx, config.URL, webhookPayload)
+}
+
+// describeLastScan renders the last successful scan for stale-scan alerts
+func describeLastScan(lastScan *time.Time, t translator) string {
+	if lastScan == nil {
+		return t.text("Never", nil)
+	}
+	return lastScan.UTC().Format(time.RFC3339)
+}
+
+// NewImageNotificationPayload contains data for alerts about images seen for the first time
+type NewImageNotificationPayload struct {
+	ImageName   string
+	ImageID     int
+	ImageDigest *string
+	ImageScan   string // namespace/name of the ImageScan that submitted the scan, empty for direct submissions
+	ScanID      int
+	ImageURL    string
+}
+
+// SendNewImageNotification alerts that a scan was submitted for an image never seen before, which stays
+// unreviewed until it is assigned a team and a criticality. Severity filters don't apply: the image is the news
+func (n *Notifier) SendNewImageNotification(ctx context.Context, config WebhookConfig, payload NewImageNotificationPayload) error {
+	if n.frontendURL != "" && payload.ImageURL == "" {
+		payload.ImageURL = fmt.Sprintf("%s/images/%d", n.frontendURL, payload.ImageID)
+	}
+
+	t := newTranslator(config.Locale)
+	var webhookPayload interface{}
+	switch config.Format {
+	case "teams":
+		webhookPayload = n.buildTeamsNewImagePayload(payload, t)
+	default:
+		webhookPayload = n.buildSlackNewImagePayload(payload, t)
+	}
+
+	return n.sendWebhook(ctx, config.URL, webhookPayload)
+}
+
+// describeImageScan renders the ImageScan a new image was submitted by
+func describeImageScan(imageScan string, t translator) string {
+	if imageScan == "" {
+		return t.text("SubmittedDirectly", nil)
+	}
+	return imageScan
+}
+
+// WatchlistNotificationPayload contains the findings of one scan matching a watchlist subscription
+type WatchlistNotificationPayload struct {
+	WatchCVE     *string // what the subscription targets: a CVE, a package, or a CVE in a package
+	WatchPackage *string
+	ImageName    string
+	ScanID       int
+	ScanURL      string
+	Events       []WatchlistEvent
+}
+
+// WatchlistEvent is a finding that triggered a watchlist notification
+type WatchlistEvent struct {
+	Kind               string // detected or fix_changed
+	CVEID              string
+	PackageName        string
+	PackageVersion     string
+	Severity           string
+	FixVersion         *string
+	PreviousFixVersion *string
+}
+
+// SendWatchlistNotification sends the subscriber the findings of a scan matching their watchlist.
+// Severity filters don't apply: subscribers asked for these specific CVEs or packages
+func (n *Notifier) SendWatchlistNotification(ctx context.Context, config WebhookConfig, payload WatchlistNotificationPayload) error {
+	if len(payload.Events) == 0 {
+		return nil
+	}
+
+	if n.frontendURL != "" && payload.ScanURL == "" {
+		payload.ScanURL = fmt.Sprintf("%s/scans/%d", n.frontendURL, payload.ScanID)
+	}
+
+	t := newTranslator(config.Locale)
+	var webhookPayload interface{}
+	switch config.Format {
+	case "teams":
+		webhookPayload = n.buildTeamsWatchlistPayload(payload, t)
+	default:
+		webhookPayload = n.buildSlackWatchlistPayload(payload, t)
+	}
+
+	return n.sendWebhook(ctx, config.URL, webhookPayload)
+}
+
+// describeWatch renders what a watchlist subscription targets
+func describeWatch(payload WatchlistNotificationPayload, t translator) string {
+	switch {
+	case payload.WatchCVE != nil && payload.WatchPackage != nil:
+		return t.text("WatchCVEInPackage", map[string]interface{}{"CVEID": *payload.WatchCVE, "Package": *payload.WatchPackage})
+	case payload.WatchCVE != nil:
+		return *payload.WatchCVE
+	case payload.WatchPackage != nil:
+		return t.text("WatchPackage", map[string]interface{}{"Package": *payload.WatchPackage})
+	}
+	return ""
+}
+
+// describeWatchlistEvent renders a watchlist event as one line
+func describeWatchlistEvent(event WatchlistEvent, t translator) string {
+	data := map[string]interface{}{
+		"Finding": t.text("WatchlistFinding", map[string]interface{}{
+			"CVEID": event.CVEID, "Package": event.PackageName, "Version": event.PackageVersion, "Severity": event.Severity,
+		}),
+		"FixVersion": describeFixVersion(event.FixVersion, t),
+	}
+	switch {
+	case event.Kind == "fix_changed" && event.PreviousFixVersion == nil:
+		return t.text("WatchlistFixAvailable", data)
+	case event.Kind == "fix_changed":
+		data["PreviousFixVersion"] = *event.PreviousFixVersion
+		return t.text("WatchlistFixChanged", data)
+	default:
+		return t.text("WatchlistDetected", data)
+	}
+}
+
+// FixAvailableNotificationPayload contains the findings of an ImageScan's images that gained a fix
+// version in a scan
+type FixAvailableNotificationPayload struct {
+	ImageScan string // namespace/name
+	ScanID    int    // scan that reported the fixes
+	ScanURL   string
+	Fixes     []FixAvailableEvent
+}
+
+// FixAvailableEvent is a finding that had no fix version in earlier scans and has one now
+type FixAvailableEvent struct {
+	CVEID          string
+	PackageName    string
+	PackageVersion string
+	Severity       string
+	FixVersion     string
+	AffectedImages int      // images with an open fixable finding of the CVE, all ImageScans included
+	Images         []string // images of the ImageScan with the finding
+}
+
+// SendFixAvailableNotification tells the ImageScan's webhook that findings of its images can now be
+// fixed. Findings below the minimum severity are left out
+func (n *Notifier) SendFixAvailableNotification(ctx context.Context, config WebhookConfig, payload FixAvailableNotificationPayload) error {
+	fixes := payload.Fixes[:0:0]
+	for _, fix := range payload.Fixes {
+		if n.shouldNotifyStatusChange(config.MinSeverity, fix.Severity) {
+			fixes = append(fixes, fix)
+		}
+	}
+	if len(fixes) == 0 {
+		n.logger.Info("no fix-available findings meet severity threshold, skipping notification",
+			zap.String("min_severity", config.MinSeverity),
+			zap.String("imagescan", payload.ImageScan))
+		return nil
+	}
+	payload.Fixes = fixes
+
+	if n.frontendURL != "" && payload.ScanURL == "" {
+		payload.ScanURL = fmt.Sprintf("%s/scans/%d", n.frontendURL, payload.ScanID)
+	}
+
+	t := newTranslator(config.Locale)
+	var webhookPayload interface{}
+	switch config.Format {
+	case "teams":
+		webhookPayload = n.buildTeamsFixAvailablePayload(payload, t)
+	default:
+		webhookPayload = n.buildSlackFixAvailablePayload(payload, t)
+	}
+
+	return n.sendWebhook(ctx, config.URL, webhookPayload)
+}
+
+// describeFixesAvailable renders the headline of a fix-available notification
+func describeFixesAvailable(payload FixAvailableNotificationPayload, t translator) string {
+	if len(payload.Fixes) == 1 {
+		fix := payload.Fixes[0]
+		return t.plural("FixAvailableText", fix.AffectedImages, map[string]interface{}{"CVEID": fix.CVEID})
+	}
+	return t.plural("FixesAvailableText", len(payload.Fixes), map[string]interface{}{"ImageScan": payload.ImageScan})
+}
+
+// describeFixAvailable renders a finding that gained a fix as one line
+func describeFixAvailable(fix FixAvailableEvent, t translator) string {
+	return t.plural("FixAvailableFinding", fix.AffectedImages, map[string]interface{}{
+		"Finding": t.text("WatchlistFinding", map[string]interface{}{
+			"CVEID": fix.CVEID, "Package": fix.PackageName, "Version": fix.PackageVersion, "Severity": fix.Severity,
+		}),
+		"FixVersion": fix.FixVersion,
+	})
+}
+
+// describeSLADue tells the timezone with the due date, since the day ends at different times per team
+func describeSLADue(payload StatusChangeNotificationPayload, t translator) string {
+	return t.text("SLADueValue", map[string]interface{}{"Date": payload.SLADueDate, "TimeZone": payload.SLATimeZone})
+}
+
+// describeSlackLinks writes links one per line in Slack's link syntax, where the title can't hold <, > or |
+func describeSlackLinks(links []Link) string {
+	lines := make([]string, len(links))
+	for i, l := range links {
+		title := strings.NewReplacer("<", "", ">", "", "|", "-").Replace(l.Title)
+		lines[i] = fmt.Sprintf("<%s|%s>", l.URL, title)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeMarkdownLinks writes links one per line in Markdown, as Teams renders facts
+func describeMarkdownLinks(links []Link) string {
+	lines := make([]string, len(links))
+	for i, l := range links {
+		title := strings.NewReplacer("[", "(", "]", ")").Replace(l.Title)
+		lines[i] = fmt.Sprintf("[%s](%s)", title, l.URL)
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+func describeFixVersion(fixVersion *string, t translator) string {
+	if fixVersion == nil {
+		return t.text("FixNotAvailable", nil)
+	}
+	return *fixVersion
+}
@@ -1,6 +1,9 @@
 package notifier
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type SlackPayload struct {
 	Text        string            `json:"text"`
@@ -19,28 +22,28 @@ type SlackField struct {
 	Short bool   `json:"short"`
 }
 
-func (n *Notifier) buildSlackPayload(payload NotificationPayload) SlackPayload {
+func (n *Notifier) buildSlackPayload(payload NotificationPayload, t translator) SlackPayload {
 	// Determine color based on severity
 	color := n.getSeverityColor(payload.SeverityCounts)
 
 	// Build summary text
 	var summaryText string
 	if payload.TotalVulns == 0 {
-		summaryText = fmt.Sprintf("✅ No vulnerabilities found in `%s`", payload.Image)
+		summaryText = t.text("ScanClean", map[string]interface{}{"Image": payload.Image})
 	} else {
-		summaryText = fmt.Sprintf("⚠️ Found %d vulnerabilities in `%s`", payload.TotalVulns, payload.Image)
+		summaryText = t.plural("ScanFound", payload.TotalVulns, map[string]interface{}{"Image": payload.Image})
 	}
 
 	fields := []SlackField{
-		{Title: "Critical", Value: fmt.Sprintf("%d", payload.SeverityCounts.Critical), Short: true},
-		{Title: "High", Value: fmt.Sprintf("%d", payload.SeverityCounts.High), Short: true},
-		{Title: "Medium", Value: fmt.Sprintf("%d", payload.SeverityCounts.Medium), Short: true},
-		{Title: "Low", Value: fmt.Sprintf("%d", payload.SeverityCounts.Low), Short: true},
+		{Title: t.text("SeverityCritical", nil), Value: fmt.Sprintf("%d", payload.SeverityCounts.Critical), Short: true},
+		{Title: t.text("SeverityHigh", nil), Value: fmt.Sprintf("%d", payload.SeverityCounts.High), Short: true},
+		{Title: t.text("SeverityMedium", nil), Value: fmt.Sprintf("%d", payload.SeverityCounts.Medium), Short: true},
+		{Title: t.text("SeverityLow", nil), Value: fmt.Sprintf("%d", payload.SeverityCounts.Low), Short: true},
 	}
 
 	if payload.ImageDigest != nil {
 		fields = append(fields, SlackField{
-			Title: "Digest",
+			Title: t.text("Digest", nil),
 			Value: *payload.ImageDigest,
 			Short: false,
 		})
@@ -49,8 +52,8 @@ func (n *Notifier) buildSlackPayload(payload NotificationPayload) SlackPayload {
 	// Add scan URL if available
 	if payload.ScanURL != "" {
 		fields = append(fields, SlackField{
-			Title: "View Scan",
-			Value: fmt.Sprintf("<%s|View full scan results>", payload.ScanURL),
+			Title: t.text("ViewScan", nil),
+			Value: fmt.Sprintf("<%s|%s>", payload.ScanURL, t.text("ViewFullScanResultsLink", nil)),
 			Short: false,
 		})
 	}
@@ -60,7 +63,7 @@ func (n *Notifier) buildSlackPayload(payload NotificationPayload) SlackPayload {
 		Attachments: []SlackAttachment{
 			{
 				Color:  color,
-				Text:   "Vulnerability Summary",
+				Text:   t.text("VulnerabilitySummary", nil),
 				Fields: fields,
 			},
 		},
@@ -80,37 +83,59 @@ func (n *Notifier) getSeverityColor(counts SeverityCounts) string {
 	return "good" // Green
 }
 
-func (n *Notifier) buildSlackStatusChangePayload(payload StatusChangeNotificationPayload) SlackPayload {
+func (n *Notifier) buildSlackStatusChangePayload(payload StatusChangeNotificationPayload, t translator) SlackPayload {
 	// Determine color based on new status
 	color := n.getStatusChangeColor(payload.NewStatus)
 
 	// Build summary text
-	summaryText := fmt.Sprintf("🔔 Vulnerability status changed: `%s` in `%s`",
-		payload.CVEID, payload.ImageName)
+	summaryText := t.text("StatusChangedText", map[string]interface{}{"CVEID": payload.CVEID, "Image": payload.ImageName})
 
 	// Build fields
 	fields := []SlackField{
-		{Title: "CVE ID", Value: payload.CVEID, Short: true},
-		{Title: "Severity", Value: payload.Severity, Short: true},
-		{Title: "Package", Value: fmt.Sprintf("%s (%s)", payload.PackageName, payload.PackageVersion), Short: false},
-		{Title: "Status Change", Value: fmt.Sprintf("%s → %s", payload.OldStatus, payload.NewStatus), Short: true},
-		{Title: "Changed By", Value: payload.ChangedBy, Short: true},
+		{Title: t.text("CVEID", nil), Value: payload.CVEID, Short: true},
+		{Title: t.text("Severity", nil), Value: payload.Severity, Short: true},
+		{Title: t.text("Package", nil), Value: fmt.Sprintf("%s (%s)", payload.PackageName, payload.PackageVersion), Short: false},
+		{Title: t.text("StatusChange", nil), Value: fmt.Sprintf("%s → %s", payload.OldStatus, payload.NewStatus), Short: true},
+		{Title: t.text("ChangedBy", nil), Value: payload.ChangedBy, Short: true},
+	}
+
+	if payload.SLADueDate != "" {
+		fields = append(fields, SlackField{
+			Title: t.text("SLADue", nil),
+			Value: describeSLADue(payload, t),
+			Short: true,
+		})
 	}
 
 	// Add notes if present
 	if payload.Notes != nil && *payload.Notes != "" {
 		fields = append(fields, SlackField{
-			Title: "Notes",
+			Title: t.text("Notes", nil),
 			Value: *payload.Notes,
 			Short: false,
 		})
 	}
 
+	if len(payload.Advisories) > 0 {
+		fields = append(fields, SlackField{
+			Title: t.text("Advisories", nil),
+			Value: describeSlackLinks(payload.Advisories),
+			Short: false,
+		})
+	}
+	if len(payload.FixLinks) > 0 {
+		fields = append(fields, SlackField{
+			Title: t.text("FixLinks", nil),
+			Value: describeSlackLinks(payload.FixLinks),
+			Short: false,
+		})
+	}
+
 	// Add vulnerability URL if available
 	if payload.VulnURL != "" {
 		fields = append(fields, SlackField{
-			Title: "View Details",
-			Value: fmt.Sprintf("<%s|View vulnerability details>", payload.VulnURL),
+			Title: t.text("ViewDetails", nil),
+			Value: fmt.Sprintf("<%s|%s>", payload.VulnURL, t.text("ViewVulnerabilityDetailsLink", nil)),
 			Short: false,
 		})
 	}
@@ -120,7 +145,7 @@ func (n *Notifier) buildSlackStatusChangePayload(payload StatusChangeNotificatio
 		Attachments: []SlackAttachment{
 			{
 				Color:  color,
-				Text:   "Status Change Details",
+				Text:   t.text("StatusChangeDetails", nil),
 				Fields: fields,
 			},
 		},
@@ -143,3 +168,134 @@ func (n *Notifier) getStatusChangeColor(status string) string {
 		return "#757575" // Default gray
 	}
 }
+
+func (n *Notifier) buildSlackStaleScanPayload(payload StaleScanNotificationPayload, t translator) SlackPayload {
+	summaryText := t.text("StaleScanText", map[string]interface{}{"Image": payload.ImageName, "StaleAfter": payload.StaleAfter})
+
+	fields := []SlackField{
+		{Title: t.text("Image", nil), Value: payload.ImageName, Short: false},
+		{Title: t.text("ImageScan", nil), Value: payload.ImageScan, Short: true},
+		{Title: t.text("LastSuccessfulScan", nil), Value: describeLastScan(payload.LastSuccessfulScanDate, t), Short: true},
+	}
+
+	if payload.ImageURL != "" {
+		fields = append(fields, SlackField{
+			Title: t.text("ViewDetails", nil),
+			Value: fmt.Sprintf("<%s|%s>", payload.ImageURL, t.text("ViewScanHistoryLink", nil)),
+			Short: false,
+		})
+	}
+
+	return SlackPayload{
+		Text: summaryText,
+		Attachments: []SlackAttachment{
+			{
+				Color:  "warning",
+				Text:   t.text("CheckScannerJobs", nil),
+				Fields: fields,
+			},
+		},
+	}
+}
+
+func (n *Notifier) buildSlackNewImagePayload(payload NewImageNotificationPayload, t translator) SlackPayload {
+	summaryText := t.text("NewImageText", map[string]interface{}{"Image": payload.ImageName})
+
+	fields := []SlackField{
+		{Title: t.text("Image", nil), Value: payload.ImageName, Short: false},
+		{Title: t.text("ImageScan", nil), Value: describeImageScan(payload.ImageScan, t), Short: true},
+	}
+	if payload.ImageDigest != nil && *payload.ImageDigest != "" {
+		fields = append(fields, SlackField{Title: t.text("Digest", nil), Value: *payload.ImageDigest, Short: true})
+	}
+
+	if payload.ImageURL != "" {
+		fields = append(fields, SlackField{
+			Title: t.text("ViewDetails", nil),
+			Value: fmt.Sprintf("<%s|%s>", payload.ImageURL, t.text("ViewImageLink", nil)),
+			Short: false,
+		})
+	}
+
+	return SlackPayload{
+		Text: summaryText,
+		Attachments: []SlackAttachment{
+			{
+				Color:  "#9C27B0", // Purple
+				Text:   t.text("AssignTeamAndCriticality", nil),
+				Fields: fields,
+			},
+		},
+	}
+}
+
+func (n *Notifier) buildSlackWatchlistPayload(payload WatchlistNotificationPayload, t translator) SlackPayload {
+	summaryText := t.text("WatchlistText", map[string]interface{}{"Watch": describeWatch(payload, t), "Image": payload.ImageName})
+
+	lines := make([]string, 0, len(payload.Events))
+	for _, event := range payload.Events {
+		lines = append(lines, "• "+describeWatchlistEvent(event, t))
+	}
+
+	fields := []SlackField{
+		{Title: t.text("Image", nil), Value: payload.ImageName, Short: false},
+		{Title: t.text("Findings", nil), Value: strings.Join(lines, "\n"), Short: false},
+	}
+
+	if payload.ScanURL != "" {
+		fields = append(fields, SlackField{
+			Title: t.text("ViewScan", nil),
+			Value: fmt.Sprintf("<%s|%s>", payload.ScanURL, t.text("ViewFullScanResultsLink", nil)),
+			Short: false,
+		})
+	}
+
+	return SlackPayload{
+		Text: summaryText,
+		Attachments: []SlackAttachment{
+			{
+				Color:  "#2196F3", // Blue
+				Text:   t.text("WatchedCVEOrPackage", nil),
+				Fields: fields,
+			},
+		},
+	}
+}
+
+func (n *Notifier) buildSlackFixAvailablePayload(payload FixAvailableNotificationPayload, t translator) SlackPayload {
+	lines := make([]string, 0, len(payload.Fixes))
+	images := []string{}
+	for _, fix := range payload.Fixes {
+		lines = append(lines, "• "+describeFixAvailable(fix, t))
+		for _, image := range fix.Images {
+			if !contains(images, image) {
+				images = append(images, image)
+			}
+		}
+	}
+
+	fields := []SlackField{
+		{Title: t.text("ImageScan", nil), Value: payload.ImageScan, Short: true},
+		{Title: t.text("Image", nil), Value: strings.Join(images, "\n"), Short: true},
+		{Title: t.text("Findings", nil), Value: strings.Join(lines, "\n"), Short: false},
+	}
+
+	if payload.ScanURL != "" {
+		fields = append(fields, SlackField{
+			Title: t.text("ViewScan", nil),
+			Value: fmt.Sprintf("<%s|%s>", payload.ScanURL, t.text("ViewFullScanResultsLink", nil)),
+			Short: false,
+		})
+	}
+
+	return SlackPayload{
+		Text: describeFixesAvailable(payload, t),
+		Attachments: []SlackAttachment{
+			{
+				Color:  "#4CAF50", // Green
+				Text:   t.text("FixAvailableSummary", nil),
+				Fields: fields,
+			},
+		},
+	}
+}
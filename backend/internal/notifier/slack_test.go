@@ -76,7 +76,7 @@ func TestBuildSlackPayload(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := n.buildSlackPayload(tt.payload)
+			result := n.buildSlackPayload(tt.payload, newTranslator(DefaultLocale))
 
 			assert.Equal(t, tt.wantText, result.Text)
 			assert.Len(t, result.Attachments, 1)
@@ -129,7 +129,7 @@ func TestBuildSlackPayload_FieldValues(t *testing.T) {
 		ScanID: 1,
 	}
 
-	result := n.buildSlackPayload(payload)
+	result := n.buildSlackPayload(payload, newTranslator(DefaultLocale))
 
 	// Find and verify each severity field
 	fields := result.Attachments[0].Fields
@@ -150,3 +150,57 @@ func TestBuildSlackPayload_FieldValues(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildSlackStatusChangePayload_SLADue(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	n := New(logger, "")
+
+	payload := StatusChangeNotificationPayload{
+		CVEID:       "CVE-2024-1234",
+		Severity:    "Critical",
+		OldStatus:   "active",
+		NewStatus:   "in_progress",
+		SLADueDate:  "2026-03-09",
+		SLATimeZone: "Europe/Berlin",
+	}
+
+	result := n.buildSlackStatusChangePayload(payload, newTranslator("fr"))
+	assert.Contains(t, result.Attachments[0].Fields, SlackField{
+		Title: "Échéance SLA",
+		Value: "2026-03-09, fin de journée Europe/Berlin",
+		Short: true,
+	})
+
+	// Closed vulnerabilities have no deadline
+	payload.NewStatus, payload.SLADueDate = "fixed", ""
+	result = n.buildSlackStatusChangePayload(payload, newTranslator(DefaultLocale))
+	for _, field := range result.Attachments[0].Fields {
+		assert.NotEqual(t, "SLA Due", field.Title)
+	}
+}
+
+func TestBuildSlackStatusChangePayload_Links(t *testing.T) {
+	n := New(zap.NewNop(), "")
+
+	payload := StatusChangeNotificationPayload{
+		CVEID:      "CVE-2024-1234",
+		Severity:   "High",
+		OldStatus:  "active",
+		NewStatus:  "fixed",
+		Advisories: []Link{{Title: "DSA-5417-1", URL: "https://security-tracker.debian.org/tracker/DSA-5417-1"}},
+		FixLinks: []Link{
+			{Title: "Bump openssl | 3.0.9", URL: "https://git.example.com/acme/web/-/merge_requests/42"},
+			{Title: "https://github.com/acme/api/pull/7", URL: "https://github.com/acme/api/pull/7"},
+		},
+	}
+
+	result := n.buildSlackStatusChangePayload(payload, newTranslator(DefaultLocale))
+	assert.Contains(t, result.Attachments[0].Fields, SlackField{
+		Title: "Advisories",
+		Value: "<https://security-tracker.debian.org/tracker/DSA-5417-1|DSA-5417-1>",
+	})
+	assert.Contains(t, result.Attachments[0].Fields, SlackField{
+		Title: "Fix Links",
+		Value: "<https://git.example.com/acme/web/-/merge_requests/42|Bump openssl - 3.0.9>\n<https://github.com/acme/api/pull/7|https://github.com/acme/api/pull/7>",
+	})
+}
@@ -35,29 +35,29 @@ type TeamsFact struct {
 	Value string `json:"value"`
 }
 
-func (n *Notifier) buildTeamsPayload(payload NotificationPayload) TeamsPayload {
+func (n *Notifier) buildTeamsPayload(payload NotificationPayload, t translator) TeamsPayload {
 	color := n.getTeamsColor(payload.SeverityCounts)
 
 	var title, summary string
 	if payload.TotalVulns == 0 {
-		title = fmt.Sprintf("✅ Image Scan Passed: %s", payload.Image)
-		summary = "No vulnerabilities found"
+		title = t.text("ScanPassedTitle", map[string]interface{}{"Image": payload.Image})
+		summary = t.text("NoVulnerabilitiesFound", nil)
 	} else {
-		title = fmt.Sprintf("Image Scan Results: %s", payload.Image)
-		summary = fmt.Sprintf("Found %d vulnerabilities", payload.TotalVulns)
+		title = t.text("ScanResultsTitle", map[string]interface{}{"Image": payload.Image})
+		summary = t.plural("FoundVulnerabilities", payload.TotalVulns, nil)
 	}
 
 	facts := []TeamsFact{
-		{Name: "Critical", Value: fmt.Sprintf("%d", payload.SeverityCounts.Critical)},
-		{Name: "High", Value: fmt.Sprintf("%d", payload.SeverityCounts.High)},
-		{Name: "Medium", Value: fmt.Sprintf("%d", payload.SeverityCounts.Medium)},
-		{Name: "Low", Value: fmt.Sprintf("%d", payload.SeverityCounts.Low)},
-		{Name: "Total Vulnerabilities", Value: fmt.Sprintf("%d", payload.TotalVulns)},
+		{Name: t.text("SeverityCritical", nil), Value: fmt.Sprintf("%d", payload.SeverityCounts.Critical)},
+		{Name: t.text("SeverityHigh", nil), Value: fmt.Sprintf("%d", payload.SeverityCounts.High)},
+		{Name: t.text("SeverityMedium", nil), Value: fmt.Sprintf("%d", payload.SeverityCounts.Medium)},
+		{Name: t.text("SeverityLow", nil), Value: fmt.Sprintf("%d", payload.SeverityCounts.Low)},
+		{Name: t.text("TotalVulnerabilities", nil), Value: fmt.Sprintf("%d", payload.TotalVulns)},
 	}
 
 	if payload.ImageDigest != nil {
 		facts = append(facts, TeamsFact{
-			Name:  "Image Digest",
+			Name:  t.text("ImageDigest", nil),
 			Value: *payload.ImageDigest,
 		})
 	}
@@ -70,7 +70,7 @@ func (n *Notifier) buildTeamsPayload(payload NotificationPayload) TeamsPayload {
 		Title:      title,
 		Sections: []TeamsSection{
 			{
-				ActivityTitle: "Vulnerability Summary",
+				ActivityTitle: t.text("VulnerabilitySummary", nil),
 				Facts:         facts,
 			},
 		},
@@ -81,7 +81,7 @@ func (n *Notifier) buildTeamsPayload(payload NotificationPayload) TeamsPayload {
 		teamsPayload.PotentialAction = []TeamsAction{
 			{
 				Type: "OpenUri",
-				Name: "View Scan Results",
+				Name: t.text("ViewScanResults", nil),
 				Targets: []TeamsTarget{
 					{
 						OS:  "default",
@@ -108,29 +108,49 @@ func (n *Notifier) getTeamsColor(counts SeverityCounts) string {
 	return "00FF00" // Green
 }
 
-func (n *Notifier) buildTeamsStatusChangePayload(payload StatusChangeNotificationPayload) TeamsPayload {
+func (n *Notifier) buildTeamsStatusChangePayload(payload StatusChangeNotificationPayload, t translator) TeamsPayload {
 	color := n.getTeamsStatusChangeColor(payload.NewStatus)
 
-	title := fmt.Sprintf("🔔 Vulnerability Status Changed: %s", payload.CVEID)
-	summary := fmt.Sprintf("Status changed from %s to %s", payload.OldStatus, payload.NewStatus)
+	title := t.text("StatusChangedTitle", map[string]interface{}{"CVEID": payload.CVEID})
+	summary := t.text("StatusChangedSummary", map[string]interface{}{"OldStatus": payload.OldStatus, "NewStatus": payload.NewStatus})
 
 	facts := []TeamsFact{
-		{Name: "CVE ID", Value: payload.CVEID},
-		{Name: "Severity", Value: payload.Severity},
-		{Name: "Package", Value: fmt.Sprintf("%s (%s)", payload.PackageName, payload.PackageVersion)},
-		{Name: "Image", Value: payload.ImageName},
-		{Name: "Status Change", Value: fmt.Sprintf("%s → %s", payload.OldStatus, payload.NewStatus)},
-		{Name: "Changed By", Value: payload.ChangedBy},
+		{Name: t.text("CVEID", nil), Value: payload.CVEID},
+		{Name: t.text("Severity", nil), Value: payload.Severity},
+		{Name: t.text("Package", nil), Value: fmt.Sprintf("%s (%s)", payload.PackageName, payload.PackageVersion)},
+		{Name: t.text("Image", nil), Value: payload.ImageName},
+		{Name: t.text("StatusChange", nil), Value: fmt.Sprintf("%s → %s", payload.OldStatus, payload.NewStatus)},
+		{Name: t.text("ChangedBy", nil), Value: payload.ChangedBy},
+	}
+
+	if payload.SLADueDate != "" {
+		facts = append(facts, TeamsFact{
+			Name:  t.text("SLADue", nil),
+			Value: describeSLADue(payload, t),
+		})
 	}
 
 	// Add notes if present
 	if payload.Notes != nil && *payload.Notes != "" {
 		facts = append(facts, TeamsFact{
-			Name:  "Notes",
+			Name:  t.text("Notes", nil),
 			Value: *payload.Notes,
 		})
 	}
 
+	if len(payload.Advisories) > 0 {
+		facts = append(facts, TeamsFact{
+			Name:  t.text("Advisories", nil),
+			Value: describeMarkdownLinks(payload.Advisories),
+		})
+	}
+	if len(payload.FixLinks) > 0 {
+		facts = append(facts, TeamsFact{
+			Name:  t.text("FixLinks", nil),
+			Value: describeMarkdownLinks(payload.FixLinks),
+		})
+	}
+
 	teamsPayload := TeamsPayload{
 		Type:       "MessageCard",
 		Context:    "https://schema.org/extensions",
@@ -139,7 +159,7 @@ func (n *Notifier) buildTeamsStatusChangePayload(payload StatusChangeNotificatio
 		Title:      title,
 		Sections: []TeamsSection{
 			{
-				ActivityTitle: "Status Change Details",
+				ActivityTitle: t.text("StatusChangeDetails", nil),
 				Facts:         facts,
 			},
 		},
@@ -150,7 +170,7 @@ func (n *Notifier) buildTeamsStatusChangePayload(payload StatusChangeNotificatio
 		teamsPayload.PotentialAction = []TeamsAction{
 			{
 				Type: "OpenUri",
-				Name: "View Vulnerability Details",
+				Name: t.text("ViewVulnerabilityDetails", nil),
 				Targets: []TeamsTarget{
 					{
 						OS:  "default",
@@ -180,3 +200,167 @@ func (n *Notifier) getTeamsStatusChangeColor(status string) string {
 		return "757575" // Default gray
 	}
 }
+
+func (n *Notifier) buildTeamsStaleScanPayload(payload StaleScanNotificationPayload, t translator) TeamsPayload {
+	title := t.text("StaleScanTitle", map[string]interface{}{"Image": payload.ImageName})
+	summary := t.text("StaleScanSummary", map[string]interface{}{"StaleAfter": payload.StaleAfter})
+
+	teamsPayload := TeamsPayload{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    summary,
+		ThemeColor: "FFA500", // Orange
+		Title:      title,
+		Sections: []TeamsSection{
+			{
+				ActivityTitle: t.text("CheckScannerJobs", nil),
+				Facts: []TeamsFact{
+					{Name: t.text("Image", nil), Value: payload.ImageName},
+					{Name: t.text("ImageScan", nil), Value: payload.ImageScan},
+					{Name: t.text("LastSuccessfulScan", nil), Value: describeLastScan(payload.LastSuccessfulScanDate, t)},
+				},
+			},
+		},
+	}
+
+	if payload.ImageURL != "" {
+		teamsPayload.PotentialAction = []TeamsAction{
+			{
+				Type: "OpenUri",
+				Name: t.text("ViewScanHistory", nil),
+				Targets: []TeamsTarget{
+					{
+						OS:  "default",
+						URI: payload.ImageURL,
+					},
+				},
+			},
+		}
+	}
+
+	return teamsPayload
+}
+
+func (n *Notifier) buildTeamsNewImagePayload(payload NewImageNotificationPayload, t translator) TeamsPayload {
+	facts := []TeamsFact{
+		{Name: t.text("Image", nil), Value: payload.ImageName},
+		{Name: t.text("ImageScan", nil), Value: describeImageScan(payload.ImageScan, t)},
+	}
+	if payload.ImageDigest != nil && *payload.ImageDigest != "" {
+		facts = append(facts, TeamsFact{Name: t.text("Digest", nil), Value: *payload.ImageDigest})
+	}
+
+	teamsPayload := TeamsPayload{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    t.text("NewImageSummary", nil),
+		ThemeColor: "9C27B0", // Purple
+		Title:      t.text("NewImageTitle", map[string]interface{}{"Image": payload.ImageName}),
+		Sections: []TeamsSection{
+			{
+				ActivityTitle: t.text("AssignTeamAndCriticality", nil),
+				Facts:         facts,
+			},
+		},
+	}
+
+	if payload.ImageURL != "" {
+		teamsPayload.PotentialAction = []TeamsAction{
+			{
+				Type: "OpenUri",
+				Name: t.text("ViewImage", nil),
+				Targets: []TeamsTarget{
+					{
+						OS:  "default",
+						URI: payload.ImageURL,
+					},
+				},
+			},
+		}
+	}
+
+	return teamsPayload
+}
+
+func (n *Notifier) buildTeamsWatchlistPayload(payload WatchlistNotificationPayload, t translator) TeamsPayload {
+	watch := describeWatch(payload, t)
+	title := t.text("WatchlistTitle", map[string]interface{}{"Watch": watch})
+
+	facts := make([]TeamsFact, 0, len(payload.Events)+1)
+	facts = append(facts, TeamsFact{Name: t.text("Image", nil), Value: payload.ImageName})
+	for _, event := range payload.Events {
+		facts = append(facts, TeamsFact{Name: event.CVEID, Value: describeWatchlistEvent(event, t)})
+	}
+
+	teamsPayload := TeamsPayload{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    t.text("WatchlistSummary", map[string]interface{}{"Watch": watch, "Image": payload.ImageName}),
+		ThemeColor: "2196F3", // Blue
+		Title:      title,
+		Sections: []TeamsSection{
+			{
+				ActivityTitle: t.text("WatchedCVEOrPackage", nil),
+				Facts:         facts,
+			},
+		},
+	}
+
+	if payload.ScanURL != "" {
+		teamsPayload.PotentialAction = []TeamsAction{
+			{
+				Type: "OpenUri",
+				Name: t.text("ViewScan", nil),
+				Targets: []TeamsTarget{
+					{
+						OS:  "default",
+						URI: payload.ScanURL,
+					},
+				},
+			},
+		}
+	}
+
+	return teamsPayload
+}
+
+func (n *Notifier) buildTeamsFixAvailablePayload(payload FixAvailableNotificationPayload, t translator) TeamsPayload {
+	title := describeFixesAvailable(payload, t)
+
+	facts := make([]TeamsFact, 0, len(payload.Fixes)+1)
+	facts = append(facts, TeamsFact{Name: t.text("ImageScan", nil), Value: payload.ImageScan})
+	for _, fix := range payload.Fixes {
+		facts = append(facts, TeamsFact{Name: fix.CVEID, Value: describeFixAvailable(fix, t)})
+	}
+
+	teamsPayload := TeamsPayload{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    title,
+		ThemeColor: "4CAF50", // Green
+		Title:      title,
+		Sections: []TeamsSection{
+			{
+				ActivityTitle: t.text("FixAvailableSummary", nil),
+				Facts:         facts,
+			},
+		},
+	}
+
+	if payload.ScanURL != "" {
+		teamsPayload.PotentialAction = []TeamsAction{
+			{
+				Type: "OpenUri",
+				Name: t.text("ViewScan", nil),
+				Targets: []TeamsTarget{
+					{
+						OS:  "default",
+						URI: payload.ScanURL,
+					},
+				},
+			},
+		}
+	}
+
+	return teamsPayload
+}
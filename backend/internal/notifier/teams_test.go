@@ -81,7 +81,7 @@ func TestBuildTeamsPayload(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := n.buildTeamsPayload(tt.payload)
+			result := n.buildTeamsPayload(tt.payload, newTranslator(DefaultLocale))
 
 			assert.Equal(t, "MessageCard", result.Type)
 			assert.Equal(t, "https://schema.org/extensions", result.Context)
@@ -136,7 +136,7 @@ func TestBuildTeamsPayload_FactValues(t *testing.T) {
 		ScanID: 1,
 	}
 
-	result := n.buildTeamsPayload(payload)
+	result := n.buildTeamsPayload(payload, newTranslator(DefaultLocale))
 
 	// Find and verify each fact
 	facts := result.Sections[0].Facts
@@ -185,8 +185,46 @@ func TestBuildTeamsPayload_Summary(t *testing.T) {
 				ScanID:     1,
 			}
 
-			result := n.buildTeamsPayload(payload)
+			result := n.buildTeamsPayload(payload, newTranslator(DefaultLocale))
 			assert.Equal(t, tt.wantSummary, result.Summary)
 		})
 	}
 }
+
+func TestBuildTeamsStatusChangePayload_SLADue(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	n := New(logger, "")
+
+	payload := StatusChangeNotificationPayload{
+		CVEID:       "CVE-2024-1234",
+		Severity:    "High",
+		OldStatus:   "fixed",
+		NewStatus:   "active",
+		SLADueDate:  "2026-04-01",
+		SLATimeZone: "America/New_York",
+	}
+
+	result := n.buildTeamsStatusChangePayload(payload, newTranslator(DefaultLocale))
+	assert.Contains(t, result.Sections[0].Facts, TeamsFact{Name: "SLA Due", Value: "2026-04-01, end of day America/New_York"})
+}
+
+func TestBuildTeamsStatusChangePayload_Links(t *testing.T) {
+	n := New(zap.NewNop(), "")
+
+	payload := StatusChangeNotificationPayload{
+		CVEID:     "CVE-2024-1234",
+		Severity:  "High",
+		OldStatus: "active",
+		NewStatus: "in_progress",
+		FixLinks:  []Link{{Title: "Bump openssl [3.0.9]", URL: "https://git.example.com/acme/web/-/merge_requests/42"}},
+	}
+
+	result := n.buildTeamsStatusChangePayload(payload, newTranslator("fr"))
+	assert.Contains(t, result.Sections[0].Facts, TeamsFact{
+		Name:  "Liens de correction",
+		Value: "[Bump openssl (3.0.9)](https://git.example.com/acme/web/-/merge_requests/42)",
+	})
+	for _, fact := range result.Sections[0].Facts {
+		assert.NotEqual(t, "Avis de sécurité", fact.Name, "vulnerabilities without advisories have no advisories fact")
+	}
+}
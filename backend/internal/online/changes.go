@@ -0,0 +1,46 @@
+package online
+
+// Changes are the online schema changes of the backend, applied in order. Append new ones at the end;
+// the migration adding a column or changing a query keeps a comment pointing at the change here
+var Changes = []Change{
+	// Migration 004
+	{Name: "idx_vulnerabilities_imagescan", Index: &Index{
+		Table: "vulnerabilities", Definition: "vulnerabilities(imagescan_namespace, imagescan_name)"}},
+	// Migration 008
+	{Name: "idx_scans_status", Index: &Index{
+		Table: "scans", Definition: "scans(status)"}},
+	// Migration 009
+	{Name: "idx_scans_tool_versions", Index: &Index{
+		Table: "scans", Definition: "scans(syft_version, grype_version)"}},
+	{Name: "idx_scans_needs_rescan", Index: &Index{
+		Table: "scans", Definition: "scans(needs_rescan) WHERE needs_rescan = true"}},
+	// Migration 015
+	{Name: "idx_scans_result_cache", Index: &Index{
+		Table: "scans", Definition: "scans(digest, grype_db_built, results_fingerprint) WHERE status = 'completed'"}},
+	// Migration 016
+	{Name: "idx_scans_image_target_date", Index: &Index{
+		Table: "scans", Definition: "scans(image_id, target, scan_date DESC)"}},
+	// Migration 019: text_pattern_ops supports the prefix matches of version-less PURL queries
+	{Name: "idx_vulnerabilities_purl", Index: &Index{
+		Table: "vulnerabilities", Definition: "vulnerabilities(purl text_pattern_ops)"}},
+	// Migration 025
+	{Name: "idx_scans_imagescan_namespace", Index: &Index{
+		Table: "scans", Definition: "scans(imagescan_namespace, scan_date) WHERE imagescan_namespace IS NOT NULL"}},
+	// Migration 033
+	{Name: "idx_vulnerabilities_fix_became_available", Index: &Index{
+		Table: "vulnerabilities", Definition: "vulnerabilities(fix_became_available_at) WHERE fix_became_available_at IS NOT NULL"}},
+	// Migration 041: the severity at first detection of vulnerabilities known before the migration is lost,
+	// they get the severity they have when the backfill runs
+	{Name: "vulnerabilities_initial_severity", Backfill: &Backfill{
+		Table: "vulnerabilities", Set: "initial_severity = severity", Where: "initial_severity = ''"}},
+	// Migration 042
+	{Name: "idx_vulnerabilities_package_name_trgm", Index: &Index{
+		Table: "vulnerabilities", Definition: "vulnerabilities USING GIN (package_name gin_trgm_ops)"}},
+	{Name: "idx_vulnerabilities_cve_id_trgm", Index: &Index{
+		Table: "vulnerabilities", Definition: "vulnerabilities USING GIN (cve_id gin_trgm_ops)"}},
+	{Name: "idx_vulnerabilities_description_fts", Index: &Index{
+		Table: "vulnerabilities", Definition: "vulnerabilities USING GIN (to_tsvector('english', COALESCE(description, '')))"}},
+	// Migration 049: until the index is valid, only the lookup of CreateScan deduplicates submissions
+	{Name: "idx_scans_scan_uuid", Index: &Index{
+		Table: "scans", Definition: "scans(scan_uuid) WHERE scan_uuid IS NOT NULL", Unique: true}},
+}
@@ -0,0 +1,228 @@
+// Package online applies the schema changes that would lock large tables if they ran in a migration:
+// index builds and backfills. Migrations run before the backend starts, in one transaction per file,
+// so they keep to changes that are quick under lock. The changes here run in the background once the
+// backend is up, on one replica at a time, and resume where they stopped after a failure or a restart
+package online
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// defaultBatchSize is how many IDs a backfill batch covers; each batch is its own transaction, so
+// the rows it locks are held for well under a second
+const defaultBatchSize = 5000
+
+// Change is a schema change, either an index or a backfill
+type Change struct {
+	// Name identifies the change in its recorded progress, it must never change
+	Name     string
+	Index    *Index
+	Backfill *Backfill
+}
+
+// Index is built with CREATE INDEX CONCURRENTLY, which doesn't block writes to the table
+type Index struct {
+	Table string
+	// Definition follows ON, e.g. "vulnerabilities(purl text_pattern_ops)", with its WHERE clause if partial
+	Definition string
+	// Unique builds a unique index, whose writes of duplicates fail once it is valid
+	Unique bool
+}
+
+// Backfill sets a column of the existing rows of a table in batches of IDs. The application must
+// already write the column of new rows, the backfill only covers the rows up to the highest ID when
+// it started
+type Backfill struct {
+	Table string
+	// Set is the SET clause of the update, Where selects the rows still to update
+	Set   string
+	Where string
+	// BatchSize is how many IDs a batch covers, defaultBatchSize when 0
+	BatchSize int64
+}
+
+// Kind returns the kind of the change, index or backfill
+func (c *Change) Kind() string {
+	if c.Index != nil {
+		return models.OnlineChangeIndex
+	}
+	return models.OnlineChangeBackfill
+}
+
+// Table returns the table the change applies to
+func (c *Change) Table() string {
+	if c.Index != nil {
+		return c.Index.Table
+	}
+	return c.Backfill.Table
+}
+
+// Statement returns the statement building the index. The change name is the index name
+func (c *Change) Statement() string {
+	unique := ""
+	if c.Index.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX CONCURRENTLY IF NOT EXISTS %s ON %s", unique, c.Name, c.Index.Definition)
+}
+
+// Store applies the changes and records their progress
+type Store interface {
+	ListProgress(ctx context.Context) ([]models.OnlineChange, error)
+	SaveProgress(ctx context.Context, progress *models.OnlineChange) error
+	// BuildIndex runs the statement building an index, after dropping an invalid index left by a failed build
+	BuildIndex(ctx context.Context, name, statement string) error
+	MaxID(ctx context.Context, table string) (int64, error)
+	// BackfillBatch updates the rows of the backfill with IDs in (fromID, toID] and returns how many it updated
+	BackfillBatch(ctx context.Context, backfill *Backfill, fromID, toID int64) (int64, error)
+}
+
+// Runner applies the changes in order
+type Runner struct {
+	logger  *zap.Logger
+	store   Store
+	changes []Change
+}
+
+// New creates a runner of changes
+func New(logger *zap.Logger, store Store, changes []Change) *Runner {
+	return &Runner{
+		logger:  logger,
+		store:   store,
+		changes: changes,
+	}
+}
+
+// Run applies the changes that aren't completed. A failing change is recorded and retried on the next
+// run, the following changes are still applied
+func (r *Runner) Run(ctx context.Context, now time.Time) error {
+	progress, err := r.progress(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range r.changes {
+		change := &r.changes[i]
+		p := progress[change.Name]
+		if p != nil && p.Status == models.OnlineChangeCompleted {
+			continue
+		}
+		if p == nil {
+			p = &models.OnlineChange{Name: change.Name, StartedAt: now}
+		}
+
+		if err := r.apply(ctx, change, p); err != nil {
+			if ctx.Err() != nil {
+				// Stopped by a shutdown, the change resumes on the next run
+				return ctx.Err()
+			}
+			r.logger.Error("online schema change failed", zap.String("change", change.Name), zap.Error(err))
+			message := err.Error()
+			p.Status = models.OnlineChangeFailed
+			p.Error = &message
+			if err := r.store.SaveProgress(ctx, p); err != nil {
+				return fmt.Errorf("failed to record online schema change: %w", err)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", change.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Runner) apply(ctx context.Context, change *Change, p *models.OnlineChange) error {
+	p.Status = models.OnlineChangeRunning
+	p.Error = nil
+	if change.Index == nil && p.MaxID == 0 && p.LastID == 0 {
+		maxID, err := r.store.MaxID(ctx, change.Backfill.Table)
+		if err != nil {
+			return err
+		}
+		p.MaxID = maxID
+	}
+	if err := r.store.SaveProgress(ctx, p); err != nil {
+		return err
+	}
+	r.logger.Info("applying online schema change", zap.String("change", change.Name), zap.String("kind", change.Kind()))
+
+	if change.Index != nil {
+		if err := r.store.BuildIndex(ctx, change.Name, change.Statement()); err != nil {
+			return err
+		}
+	} else {
+		batch := change.Backfill.BatchSize
+		if batch <= 0 {
+			batch = defaultBatchSize
+		}
+		for p.LastID < p.MaxID {
+			toID := min(p.LastID+batch, p.MaxID)
+			updated, err := r.store.BackfillBatch(ctx, change.Backfill, p.LastID, toID)
+			if err != nil {
+				return err
+			}
+			p.LastID = toID
+			p.RowsUpdated += updated
+			if err := r.store.SaveProgress(ctx, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	completedAt := time.Now()
+	p.Status = models.OnlineChangeCompleted
+	p.CompletedAt = &completedAt
+	if err := r.store.SaveProgress(ctx, p); err != nil {
+		return err
+	}
+	r.logger.Info("online schema change completed", zap.String("change", change.Name), zap.Int64("rows_updated", p.RowsUpdated))
+	return nil
+}
+
+// Status returns every change with its progress, in the order they are applied
+func (r *Runner) Status(ctx context.Context) ([]models.OnlineChangeStatus, error) {
+	progress, err := r.progress(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]models.OnlineChangeStatus, len(r.changes))
+	for i := range r.changes {
+		change := &r.changes[i]
+		status := models.OnlineChangeStatus{
+			Name:     change.Name,
+			Kind:     change.Kind(),
+			Table:    change.Table(),
+			Status:   models.OnlineChangePending,
+			Progress: progress[change.Name],
+		}
+		if p := status.Progress; p != nil {
+			status.Status = p.Status
+			switch {
+			case p.Status == models.OnlineChangeCompleted:
+				status.PercentComplete = 100
+			case p.MaxID > 0:
+				status.PercentComplete = float64(p.LastID) * 100 / float64(p.MaxID)
+			}
+		}
+		statuses[i] = status
+	}
+	return statuses, nil
+}
+
+func (r *Runner) progress(ctx context.Context) (map[string]*models.OnlineChange, error) {
+	list, err := r.store.ListProgress(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get online schema change progress: %w", err)
+	}
+	progress := make(map[string]*models.OnlineChange, len(list))
+	for i := range list {
+		progress[list[i].Name] = &list[i]
+	}
+	return progress, nil
+}
@@ -0,0 +1,125 @@
+package online
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	progress  map[string]models.OnlineChange
+	built     []string
+	failBuild map[string]error
+	maxID     int64
+	batches   [][2]int64
+	// failAfter fails the backfill batch starting at this ID once
+	failAfter int64
+}
+
+func (s *fakeStore) ListProgress(ctx context.Context) ([]models.OnlineChange, error) {
+	list := []models.OnlineChange{}
+	for _, p := range s.progress {
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+func (s *fakeStore) SaveProgress(ctx context.Context, progress *models.OnlineChange) error {
+	s.progress[progress.Name] = *progress
+	return nil
+}
+
+func (s *fakeStore) BuildIndex(ctx context.Context, name, statement string) error {
+	if err := s.failBuild[name]; err != nil {
+		return err
+	}
+	s.built = append(s.built, statement)
+	return nil
+}
+
+func (s *fakeStore) MaxID(ctx context.Context, table string) (int64, error) {
+	return s.maxID, nil
+}
+
+func (s *fakeStore) BackfillBatch(ctx context.Context, backfill *Backfill, fromID, toID int64) (int64, error) {
+	if s.failAfter > 0 && fromID == s.failAfter {
+		s.failAfter = 0
+		return 0, errors.New("deadlock detected")
+	}
+	s.batches = append(s.batches, [2]int64{fromID, toID})
+	return toID - fromID, nil
+}
+
+func TestRunner_Run(t *testing.T) {
+	store := &fakeStore{
+		progress:  map[string]models.OnlineChange{},
+		failBuild: map[string]error{"idx_broken": errors.New("column does not exist")},
+		maxID:     25,
+		failAfter: 20,
+	}
+	changes := []Change{
+		{Name: "idx_scans_status", Index: &Index{Table: "scans", Definition: "scans(status)"}},
+		{Name: "idx_broken", Index: &Index{Table: "scans", Definition: "scans(missing)"}},
+		{Name: "scans_backfill", Backfill: &Backfill{Table: "scans", Set: "x = y", Where: "x IS NULL", BatchSize: 10}},
+	}
+	runner := New(zap.NewNop(), store, changes)
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	// The broken index and the interrupted backfill fail, the index before them is built
+	err := runner.Run(context.Background(), now)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "idx_broken")
+	assert.Contains(t, err.Error(), "scans_backfill")
+	assert.Equal(t, []string{"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_scans_status ON scans(status)"}, store.built)
+
+	statuses, err := runner.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.Equal(t, models.OnlineChangeCompleted, statuses[0].Status)
+	assert.Equal(t, float64(100), statuses[0].PercentComplete)
+	assert.Equal(t, models.OnlineChangeFailed, statuses[1].Status)
+	assert.Equal(t, "column does not exist", *statuses[1].Progress.Error)
+	assert.Equal(t, models.OnlineChangeFailed, statuses[2].Status)
+	assert.Equal(t, float64(80), statuses[2].PercentComplete)
+
+	// The next run resumes the backfill after its last batch, and doesn't build the index again
+	delete(store.failBuild, "idx_broken")
+	require.NoError(t, runner.Run(context.Background(), now.Add(time.Minute)))
+	assert.Len(t, store.built, 2)
+	assert.Equal(t, [][2]int64{{0, 10}, {10, 20}, {20, 25}}, store.batches)
+
+	backfill := store.progress["scans_backfill"]
+	assert.Equal(t, models.OnlineChangeCompleted, backfill.Status)
+	assert.Equal(t, int64(25), backfill.RowsUpdated)
+	assert.Nil(t, backfill.Error)
+	assert.Equal(t, now, backfill.StartedAt)
+}
+
+func TestChanges(t *testing.T) {
+	names := map[string]bool{}
+	for _, change := range Changes {
+		assert.False(t, names[change.Name], "duplicate change %s", change.Name)
+		names[change.Name] = true
+		require.True(t, (change.Index == nil) != (change.Backfill == nil), change.Name)
+
+		if change.Index != nil {
+			assert.True(t, strings.HasPrefix(change.Index.Definition, change.Index.Table), change.Name)
+			assert.True(t, strings.HasPrefix(change.Name, "idx_"+change.Index.Table), change.Name)
+		} else {
+			assert.NotEmpty(t, change.Backfill.Set, change.Name)
+			assert.NotEmpty(t, change.Backfill.Where, change.Name)
+		}
+	}
+}
+
+func TestChange_Statement(t *testing.T) {
+	change := Change{Name: "idx_scans_scan_uuid", Index: &Index{Table: "scans", Definition: "scans(scan_uuid) WHERE scan_uuid IS NOT NULL", Unique: true}}
+	assert.Equal(t, "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_scans_scan_uuid ON scans(scan_uuid) WHERE scan_uuid IS NOT NULL", change.Statement())
+}
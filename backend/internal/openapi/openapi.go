@@ -0,0 +1,380 @@
+// Package openapi documents the routes of an Echo server as an OpenAPI 3.1 document. Paths come from the
+// routes registered on the server, so the document never misses one, and operations registered next to
+// the handlers add what routes don't tell: summaries, parameters and the Go types of bodies
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Version is the OpenAPI version of the documents built
+const Version = "3.1.0"
+
+// Operation documents a route, keyed by its method and path relative to the prefix, e.g. "GET /scans/:id"
+type Operation struct {
+	Summary     string
+	Description string
+	Query       []Param
+	// Request and Response are values of the Go types of the JSON bodies, e.g. models.Scan{}
+	Request  any
+	Response any
+	// Paginated wraps Response, the type of the items, in the page envelope of list endpoints
+	Paginated bool
+	// RequestType and ResponseType are the content types of bodies that aren't JSON, e.g. text/csv
+	RequestType  string
+	ResponseType string
+	// Status is the success status, 200 when unset
+	Status int
+}
+
+// Param is a query parameter
+type Param struct {
+	Name        string
+	Type        string // string, integer, boolean or number
+	Description string
+	Required    bool
+}
+
+// Query returns an optional query parameter
+func Query(name, typ, description string) Param {
+	return Param{Name: name, Type: typ, Description: description}
+}
+
+// SecurityRule applies a security scheme to the routes under a path prefix, relative to the document
+// prefix. An empty scheme makes the routes public
+type SecurityRule struct {
+	Prefix string
+	Scheme string
+}
+
+// Spec is what a document is built from besides the routes
+type Spec struct {
+	Info       Info
+	Prefix     string // path prefix of the documented routes, e.g. /api/v1
+	Operations map[string]Operation
+	Schemes    map[string]SecurityScheme
+	// Security rules are tried in order, routes matching none use DefaultScheme
+	Security      []SecurityRule
+	DefaultScheme string
+	// Mounts are prefixes of groups serving routes again, e.g. with another authentication. Their routes
+	// are documented by the operation of the route without the prefix
+	Mounts []string
+}
+
+// Document is an OpenAPI 3.1 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers"`
+	Tags       []Tag               `json:"tags"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem holds the operations of a path by lowercase method
+type PathItem map[string]*OperationObject
+
+type OperationObject struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Operation returns the operation documenting a route with its key, given the path of the route relative to
+// the prefix. Routes under a mount are documented by the operation of the route without the mount
+func (spec Spec) Operation(method, path string) (string, Operation, bool) {
+	key := method + " " + path
+	if op, ok := spec.Operations[key]; ok {
+		return key, op, true
+	}
+	for _, mount := range spec.Mounts {
+		if rest, ok := strings.CutPrefix(path, mount+"/"); ok {
+			key = method + " /" + rest
+			op, ok := spec.Operations[key]
+			return key, op, ok
+		}
+	}
+	return key, Operation{}, false
+}
+
+var pathParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// Build documents the routes under the prefix of the spec. It also returns the operations of the spec
+// matching no route, so the ones left behind by a route change can be reported
+func Build(spec Spec, routes []*echo.Route) (*Document, []string) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    spec.Info,
+		Servers: []Server{{URL: spec.Prefix}},
+		Tags:    []Tag{},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{"Error": errorSchema},
+			SecuritySchemes: spec.Schemes,
+		},
+	}
+	schemas := newSchemas(doc.Components.Schemas)
+
+	// Routes are sorted so generated operation IDs don't depend on the registration order, mounted routes
+	// last so the handler names go to the routes they are documented by
+	sorted := make([]*echo.Route, 0, len(routes))
+	for _, route := range routes {
+		if strings.HasPrefix(route.Path, spec.Prefix+"/") && isMethod(route.Method) {
+			sorted = append(sorted, route)
+		}
+	}
+	mounted := func(route *echo.Route) bool {
+		path := strings.TrimPrefix(route.Path, spec.Prefix)
+		for _, mount := range spec.Mounts {
+			if strings.HasPrefix(path, mount+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if mi, mj := mounted(sorted[i]), mounted(sorted[j]); mi != mj {
+			return mj
+		}
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+
+	used := map[string]bool{}
+	operationIDs := map[string]bool{}
+	tags := map[string]bool{}
+	for _, route := range sorted {
+		path := strings.TrimPrefix(route.Path, spec.Prefix)
+		key, op, documented := spec.Operation(route.Method, path)
+		if documented {
+			used[key] = true
+		}
+
+		handler := handlerName(route.Name)
+		if op.Summary == "" {
+			op.Summary = sentence(handler)
+		}
+		tag := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+		tags[tag] = true
+
+		operationID := handler
+		if operationID == "" || operationIDs[operationID] {
+			// Handlers serving several routes, e.g. with and without an API key
+			operationID = tag + strings.ReplaceAll(sentenceCase(handler), " ", "")
+		}
+		for n := 2; operationIDs[operationID]; n++ {
+			operationID = fmt.Sprintf("%s%d", strings.TrimRight(operationID, "0123456789"), n)
+		}
+		operationIDs[operationID] = true
+
+		object := &OperationObject{
+			OperationID: operationID,
+			Summary:     op.Summary,
+			Description: op.Description,
+			Tags:        []string{tag},
+			Responses:   responses(op, schemas),
+			Security:    security(spec, path),
+		}
+		for _, name := range pathParam.FindAllStringSubmatch(path, -1) {
+			object.Parameters = append(object.Parameters, Parameter{Name: name[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		for _, param := range op.Query {
+			object.Parameters = append(object.Parameters, Parameter{
+				Name: param.Name, In: "query", Description: param.Description, Required: param.Required,
+				Schema: &Schema{Type: param.Type},
+			})
+		}
+		if body := requestBody(route.Method, op, schemas); body != nil {
+			object.RequestBody = body
+		}
+
+		openAPIPath := pathParam.ReplaceAllString(path, "{$1}")
+		if doc.Paths[openAPIPath] == nil {
+			doc.Paths[openAPIPath] = PathItem{}
+		}
+		doc.Paths[openAPIPath][strings.ToLower(route.Method)] = object
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+
+	var unmatched []string
+	for key := range spec.Operations {
+		if !used[key] {
+			unmatched = append(unmatched, key)
+		}
+	}
+	sort.Strings(unmatched)
+	return doc, unmatched
+}
+
+var errorSchema = &Schema{
+	Type:       "object",
+	Properties: map[string]*Schema{"message": {Type: "string"}},
+	Required:   []string{"message"},
+}
+
+func responses(op Operation, schemas *schemas) map[string]Response {
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status)}
+	switch {
+	case op.ResponseType != "":
+		success.Content = map[string]MediaType{op.ResponseType: {Schema: &Schema{}}}
+	case op.Response != nil && op.Paginated:
+		success.Content = jsonContent(schemas.page(reflect.TypeOf(op.Response)))
+	case op.Response != nil:
+		success.Content = jsonContent(schemas.of(reflect.TypeOf(op.Response)))
+	case status != http.StatusNoContent:
+		success.Content = jsonContent(&Schema{})
+	}
+	return map[string]Response{
+		fmt.Sprint(status): success,
+		"default":          {Description: "Error", Content: jsonContent(&Schema{Ref: "#/components/schemas/Error"})},
+	}
+}
+
+func requestBody(method string, op Operation, schemas *schemas) *RequestBody {
+	switch {
+	case op.RequestType != "":
+		return &RequestBody{Required: true, Content: map[string]MediaType{op.RequestType: {Schema: &Schema{Type: "string"}}}}
+	case op.Request != nil:
+		return &RequestBody{Required: true, Content: jsonContent(schemas.of(reflect.TypeOf(op.Request)))}
+	case method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch:
+		return &RequestBody{Content: jsonContent(&Schema{})}
+	}
+	return nil
+}
+
+func security(spec Spec, path string) []map[string][]string {
+	scheme := spec.DefaultScheme
+	for _, rule := range spec.Security {
+		if path == rule.Prefix || strings.HasPrefix(path, rule.Prefix+"/") {
+			scheme = rule.Scheme
+			break
+		}
+	}
+	if scheme == "" {
+		return []map[string][]string{}
+	}
+	return []map[string][]string{{scheme: {}}}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{echo.MIMEApplicationJSON: {Schema: schema}}
+}
+
+func isMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// handlerName is the method or function name of an Echo route name,
+// e.g. ListScans for github.com/invulnerable/backend/internal/api.(*ScanHandler).ListScans-fm
+func handlerName(routeName string) string {
+	name := strings.TrimSuffix(routeName, "-fm")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		return ""
+	}
+	return name
+}
+
+// sentence turns a handler name into a summary, e.g. GetScanSARIF into "Get scan SARIF"
+func sentence(name string) string {
+	words := strings.Fields(sentenceCase(name))
+	for i, word := range words {
+		if i > 0 && word != strings.ToUpper(word) || len(word) == 1 {
+			words[i] = strings.ToLower(word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// sentenceCase splits a camel case name into words, keeping acronyms together, e.g. GetScanSARIF into "Get Scan SARIF"
+func sentenceCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
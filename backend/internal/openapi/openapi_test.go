@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	ID        int               `json:"id"`
+	Name      *string           `json:"name"`
+	Tags      []string          `json:"tags,omitempty"`
+	Labels    map[string]string `json:"labels"`
+	CreatedAt time.Time         `json:"created_at"`
+	Secret    string            `json:"-"`
+	Parent    *testItem         `json:"parent,omitempty"`
+}
+
+type testItemWithCount struct {
+	testItem
+	Count int `json:"count"`
+}
+
+type testHandler struct{}
+
+func (testHandler) ListItems(c echo.Context) error  { return nil }
+func (testHandler) GetItem(c echo.Context) error    { return nil }
+func (testHandler) CreateItem(c echo.Context) error { return nil }
+
+func testRoutes() []*echo.Route {
+	e := echo.New()
+	h := testHandler{}
+	api := e.Group("/api/v1")
+	api.GET("/items", h.ListItems)
+	api.GET("/items/:id", h.GetItem)
+	api.POST("/items", h.CreateItem)
+	api.POST("/ci/items", h.CreateItem)
+	api.GET("/shared/items/:token", h.GetItem)
+	e.GET("/health", h.ListItems)
+	return e.Routes()
+}
+
+func testSpec(ops map[string]Operation) Spec {
+	return Spec{
+		Info:       Info{Title: "Test", Version: "v1"},
+		Prefix:     "/api/v1",
+		Operations: ops,
+		Schemes: map[string]SecurityScheme{
+			"bearer": {Type: "http", Scheme: "bearer"},
+			"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+		},
+		Security:      []SecurityRule{{Prefix: "/ci", Scheme: "apiKey"}, {Prefix: "/shared"}},
+		DefaultScheme: "bearer",
+		Mounts:        []string{"/ci"},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	doc, unmatched := Build(testSpec(map[string]Operation{
+		"GET /items": {
+			Summary:   "List items",
+			Query:     []Param{Query("limit", "integer", "Page size")},
+			Response:  testItemWithCount{},
+			Paginated: true,
+		},
+		"POST /items": {Request: testItem{}, Response: testItem{}, Status: http.StatusCreated},
+		"GET /gone":   {Summary: "Removed route"},
+	}), testRoutes())
+
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, []string{"GET /gone"}, unmatched)
+	assert.NotContains(t, doc.Paths, "/health")
+	require.Contains(t, doc.Paths, "/items/{id}")
+
+	list := doc.Paths["/items"]["get"]
+	require.NotNil(t, list)
+	assert.Equal(t, "ListItems", list.OperationID)
+	assert.Equal(t, "List items", list.Summary)
+	assert.Equal(t, []string{"items"}, list.Tags)
+	assert.Equal(t, []map[string][]string{{"bearer": {}}}, list.Security)
+	assert.Equal(t, "#/components/schemas/testItemWithCountPage", list.Responses["200"].Content["application/json"].Schema.Ref)
+	assert.Contains(t, list.Responses, "default")
+
+	get := doc.Paths["/items/{id}"]["get"]
+	require.NotNil(t, get)
+	assert.Equal(t, "Get item", get.Summary)
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}, get.Parameters[0])
+
+	create := doc.Paths["/items"]["post"]
+	require.NotNil(t, create)
+	assert.Contains(t, create.Responses, "201")
+	assert.Equal(t, "#/components/schemas/testItem", create.RequestBody.Content["application/json"].Schema.Ref)
+
+	// Mounted routes share the operation, with their own ID and security
+	ci := doc.Paths["/ci/items"]["post"]
+	require.NotNil(t, ci)
+	assert.Equal(t, "ciCreateItem", ci.OperationID)
+	assert.Contains(t, ci.Responses, "201")
+	assert.Equal(t, []map[string][]string{{"apiKey": {}}}, ci.Security)
+
+	shared := doc.Paths["/shared/items/{token}"]["get"]
+	require.NotNil(t, shared)
+	assert.Equal(t, "sharedGetItem", shared.OperationID)
+	assert.Empty(t, shared.Security)
+
+	_, err := json.Marshal(doc)
+	require.NoError(t, err)
+}
+
+func TestSpec_Operation(t *testing.T) {
+	spec := testSpec(map[string]Operation{"POST /items": {Summary: "Create an item"}})
+
+	key, op, ok := spec.Operation(http.MethodPost, "/items")
+	assert.True(t, ok)
+	assert.Equal(t, "POST /items", key)
+	assert.Equal(t, "Create an item", op.Summary)
+
+	// Mounted routes are documented by the route without the mount
+	key, _, ok = spec.Operation(http.MethodPost, "/ci/items")
+	assert.True(t, ok)
+	assert.Equal(t, "POST /items", key)
+
+	_, _, ok = spec.Operation(http.MethodGet, "/items")
+	assert.False(t, ok)
+	_, _, ok = spec.Operation(http.MethodGet, "/ci/items")
+	assert.False(t, ok)
+}
+
+func TestSchemas(t *testing.T) {
+	components := map[string]*Schema{}
+	s := newSchemas(components)
+	ref := s.of(reflect.TypeOf(testItemWithCount{}))
+	assert.Equal(t, "#/components/schemas/testItemWithCount", ref.Ref)
+
+	schema := components["testItemWithCount"]
+	require.NotNil(t, schema)
+	assert.Equal(t, []string{"id", "labels", "created_at", "count"}, schema.Required)
+	assert.NotContains(t, schema.Properties, "Secret")
+	assert.NotContains(t, schema.Properties, "-")
+	assert.Equal(t, &Schema{Type: "integer"}, schema.Properties["id"])
+	assert.Equal(t, &Schema{Type: []string{"string", "null"}}, schema.Properties["name"])
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, schema.Properties["tags"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}, schema.Properties["labels"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, schema.Properties["created_at"])
+	// Recursive types refer to their component
+	assert.Equal(t, "#/components/schemas/testItem", schema.Properties["parent"].Ref)
+	assert.Contains(t, components, "testItem")
+}
+
+func TestSentence(t *testing.T) {
+	assert.Equal(t, "List scans", sentence("ListScans"))
+	assert.Equal(t, "Get scan SARIF", sentence("GetScanSARIF"))
+	assert.Equal(t, "Get Scan SARIF", sentenceCase("GetScanSARIF"))
+	assert.Equal(t, "GetScan", handlerName("github.com/invulnerable/backend/internal/api.(*ScanHandler).GetScan-fm"))
+	assert.Equal(t, "", handlerName(""))
+}
@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema as OpenAPI 3.1 uses it. Type is a string, or a list of them for nullable values
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas reflects Go types into the component schemas of a document, structs are referenced by name
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas(components map[string]*Schema) *schemas {
+	return &schemas{components: components, names: map[reflect.Type]string{}}
+}
+
+// of returns the schema of a type
+func (s *schemas) of(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawJSONType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.of(t.Elem())
+		if typ, ok := schema.Type.(string); ok {
+			schema.Type = []string{typ, "null"}
+		}
+		return schema
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			// Anonymous structs have no name to be referenced by
+			schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+			s.fields(t, schema)
+			return schema
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	}
+	// Interfaces can hold anything
+	return &Schema{}
+}
+
+// page returns the schema of a page of items, the envelope of list endpoints. Items are structs
+func (s *schemas) page(item reflect.Type) *Schema {
+	for item.Kind() == reflect.Pointer {
+		item = item.Elem()
+	}
+	items := s.of(item)
+	name := s.names[item] + "Page"
+	if _, ok := s.components[name]; !ok {
+		s.components[name] = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"items":       {Type: "array", Items: items},
+				"total":       {Type: "integer"},
+				"limit":       {Type: "integer"},
+				"offset":      {Type: "integer"},
+				"next_offset": {Type: []string{"integer", "null"}},
+			},
+			Required: []string{"items", "total", "limit", "offset", "next_offset"},
+		}
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// component adds the schema of a struct to the components, once, and returns its name
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := s.name(t)
+	s.names[t] = name
+
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	// Registered before the fields so recursive types refer to themselves
+	s.components[name] = schema
+	s.fields(t, schema)
+	return name
+}
+
+// fields adds the JSON fields of a struct to a schema, the ones of embedded structs included
+func (s *schemas) fields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.fields(embedded, schema)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = s.of(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// name is the component name of a struct: its type name, prefixed with its package when another
+// package has a type of the same name
+func (s *schemas) name(t reflect.Type) string {
+	name := t.Name()
+	for other, taken := range s.names {
+		if taken == name && other != t {
+			pkg := t.PkgPath()
+			pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+			return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+		}
+	}
+	return name
+}
@@ -0,0 +1,133 @@
+// Package outbox delivers the notifications written to the notification outbox. Events are written
+// in the transaction of the change they report, so a replica restarting mid-request doesn't lose
+// them, and any replica's dispatcher delivers them at least once
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// Defaults of the dispatcher
+const (
+	DefaultBatchSize   = 50
+	DefaultLease       = 2 * time.Minute
+	DefaultMaxAttempts = 10
+
+	// Retries back off from retryBaseDelay, doubling up to retryMaxDelay
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+)
+
+// Store holds the events, shared by every replica
+type Store interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, message string, retryAt *time.Time) error
+}
+
+// Handler delivers an event of one kind. An error retries the event later
+type Handler func(ctx context.Context, event *models.OutboxEvent) error
+
+// Dispatcher claims deliverable events and hands them to the handler of their kind
+type Dispatcher struct {
+	logger      *zap.Logger
+	store       Store
+	batchSize   int
+	lease       time.Duration
+	maxAttempts int
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// New creates a dispatcher with the default batch size, lease and attempts
+func New(logger *zap.Logger, store Store) *Dispatcher {
+	return &Dispatcher{
+		logger:      logger,
+		store:       store,
+		batchSize:   DefaultBatchSize,
+		lease:       DefaultLease,
+		maxAttempts: DefaultMaxAttempts,
+		handlers:    make(map[string]Handler),
+	}
+}
+
+// Handle registers the handler of a kind of event
+func (d *Dispatcher) Handle(kind string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[kind] = handler
+}
+
+// Dispatch delivers the deliverable events until none is left. It is run by a worker
+func (d *Dispatcher) Dispatch(ctx context.Context, now time.Time) error {
+	for {
+		events, err := d.store.Claim(ctx, d.batchSize, d.lease)
+		if err != nil {
+			return fmt.Errorf("failed to claim outbox events: %w", err)
+		}
+		for i := range events {
+			d.deliver(ctx, &events[i])
+		}
+		if len(events) < d.batchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, event *models.OutboxEvent) {
+	d.mu.RLock()
+	handler, ok := d.handlers[event.Kind]
+	d.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler for %s events", event.Kind)
+	} else {
+		err = handler(ctx, event)
+	}
+
+	if err == nil {
+		if err := d.store.MarkSent(ctx, event.ID); err != nil {
+			// The lease expires and the event is delivered again, which at-least-once allows
+			d.logger.Warn("failed to record outbox event delivery", zap.Int64("event_id", event.ID), zap.Error(err))
+		}
+		return
+	}
+
+	// Unknown kinds are given up right away, retrying can't help
+	var retryAt *time.Time
+	if ok && event.Attempts < d.maxAttempts {
+		next := time.Now().Add(retryDelay(event.Attempts))
+		retryAt = &next
+	}
+	fields := []zap.Field{
+		zap.Int64("event_id", event.ID),
+		zap.String("kind", event.Kind),
+		zap.Int("attempts", event.Attempts),
+		zap.Error(err),
+	}
+	if retryAt != nil {
+		d.logger.Warn("outbox event delivery failed, retrying", append(fields, zap.Time("retry_at", *retryAt))...)
+	} else {
+		d.logger.Error("outbox event delivery failed, giving up", fields...)
+	}
+	if err := d.store.MarkFailed(ctx, event.ID, err.Error(), retryAt); err != nil {
+		d.logger.Warn("failed to record outbox event failure", zap.Int64("event_id", event.ID), zap.Error(err))
+	}
+}
+
+// retryDelay is the wait after the given number of attempts
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStore claims every pending event whose time has come, like the outbox table
+type fakeStore struct {
+	mu     sync.Mutex
+	events []*models.OutboxEvent
+	now    time.Time
+}
+
+func (s *fakeStore) add(kind string, payload interface{}) *models.OutboxEvent {
+	event, err := models.NewOutboxEvent(kind, payload)
+	if err != nil {
+		panic(err)
+	}
+	event.ID = int64(len(s.events) + 1)
+	event.Status = models.OutboxStatusPending
+	s.events = append(s.events, event)
+	return event
+}
+
+func (s *fakeStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	claimed := []models.OutboxEvent{}
+	for _, event := range s.events {
+		if len(claimed) == limit {
+			break
+		}
+		if event.Status != models.OutboxStatusPending || event.AvailableAt.After(s.now) ||
+			(event.LockedUntil != nil && event.LockedUntil.After(s.now)) {
+			continue
+		}
+		event.Attempts++
+		until := s.now.Add(lease)
+		event.LockedUntil = &until
+		claimed = append(claimed, *event)
+	}
+	return claimed, nil
+}
+
+func (s *fakeStore) MarkSent(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event := s.events[id-1]
+	event.Status, event.LockedUntil = models.OutboxStatusSent, nil
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(ctx context.Context, id int64, message string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event := s.events[id-1]
+	event.LockedUntil, event.LastError = nil, &message
+	if retryAt == nil {
+		event.Status = models.OutboxStatusFailed
+	} else {
+		event.AvailableAt = *retryAt
+	}
+	return nil
+}
+
+func TestDispatcher_DeliversEveryBatch(t *testing.T) {
+	store := &fakeStore{now: time.Now()}
+	for i := 0; i < DefaultBatchSize+5; i++ {
+		store.add(models.OutboxKindWatchlist, map[string]int{"n": i})
+	}
+
+	d := New(zap.NewNop(), store)
+	var delivered []int
+	d.Handle(models.OutboxKindWatchlist, func(ctx context.Context, event *models.OutboxEvent) error {
+		var payload map[string]int
+		require.NoError(t, event.Decode(&payload))
+		delivered = append(delivered, payload["n"])
+		return nil
+	})
+
+	require.NoError(t, d.Dispatch(context.Background(), store.now))
+	assert.Len(t, delivered, DefaultBatchSize+5)
+	for _, event := range store.events {
+		assert.Equal(t, models.OutboxStatusSent, event.Status)
+	}
+}
+
+func TestDispatcher_RetriesFailedDeliveries(t *testing.T) {
+	store := &fakeStore{now: time.Now()}
+	event := store.add(models.OutboxKindScanCompleted, struct{}{})
+
+	d := New(zap.NewNop(), store)
+	fail := true
+	calls := 0
+	d.Handle(models.OutboxKindScanCompleted, func(ctx context.Context, event *models.OutboxEvent) error {
+		calls++
+		if fail {
+			return errors.New("webhook returned non-2xx status: 503")
+		}
+		return nil
+	})
+
+	require.NoError(t, d.Dispatch(context.Background(), store.now))
+	assert.Equal(t, models.OutboxStatusPending, event.Status)
+	assert.Equal(t, "webhook returned non-2xx status: 503", *event.LastError)
+	assert.True(t, event.AvailableAt.After(store.now), "retried after a delay")
+
+	// Not delivered again before the retry
+	require.NoError(t, d.Dispatch(context.Background(), store.now))
+	assert.Equal(t, 1, calls)
+
+	fail = false
+	store.now = event.AvailableAt
+	require.NoError(t, d.Dispatch(context.Background(), store.now))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, models.OutboxStatusSent, event.Status)
+}
+
+func TestDispatcher_GivesUp(t *testing.T) {
+	store := &fakeStore{now: time.Now()}
+	exhausted := store.add(models.OutboxKindStatusChange, struct{}{})
+	exhausted.Attempts = DefaultMaxAttempts - 1
+	unknown := store.add("unknown", struct{}{})
+
+	d := New(zap.NewNop(), store)
+	d.Handle(models.OutboxKindStatusChange, func(ctx context.Context, event *models.OutboxEvent) error {
+		return errors.New("connection refused")
+	})
+
+	require.NoError(t, d.Dispatch(context.Background(), store.now))
+	assert.Equal(t, models.OutboxStatusFailed, exhausted.Status)
+	assert.Equal(t, models.OutboxStatusFailed, unknown.Status)
+	assert.Equal(t, 1, unknown.Attempts)
+	assert.Equal(t, "no handler for unknown events", *unknown.LastError)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, retryDelay(1))
+	assert.Equal(t, time.Minute, retryDelay(2))
+	assert.Equal(t, 4*time.Minute, retryDelay(4))
+	assert.Equal(t, time.Hour, retryDelay(9))
+}
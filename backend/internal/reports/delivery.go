@@ -0,0 +1,161 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/eventhook"
+)
+
+// deliveryTimeout bounds a webhook delivery
+const deliveryTimeout = 30 * time.Second
+
+// HeaderSchedule is the schedule of the report a webhook request delivers
+const HeaderSchedule = "X-Invulnerable-Report-Schedule"
+
+// Document is a rendered report
+type Document struct {
+	Name        string // file name, such as weekly-2026-10-12.pdf
+	ContentType string
+	Body        []byte
+}
+
+// Mailer sends reports by email
+type Mailer interface {
+	Send(ctx context.Context, to []string, subject, text string, attachment Document) error
+}
+
+// SMTPConfig is the SMTP server reports are sent through
+type SMTPConfig struct {
+	Host     string
+	Port     int    // 587 when zero
+	Username string // PLAIN authentication when set
+	Password string
+	From     string
+}
+
+// SMTPMailer sends reports through an SMTP server, with STARTTLS when the server offers it
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+// NewSMTPMailer creates a mailer sending through the server of config
+func NewSMTPMailer(config SMTPConfig) (*SMTPMailer, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("SMTP host is required")
+	}
+	if config.From == "" {
+		return nil, fmt.Errorf("SMTP sender address is required")
+	}
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	return &SMTPMailer{config: config}, nil
+}
+
+// Send sends text with the document attached. net/smtp has no context, the server timeouts apply
+func (m *SMTPMailer) Send(_ context.Context, to []string, subject, text string, attachment Document) error {
+	message, err := buildMessage(m.config.From, to, subject, text, attachment)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+	addr := m.config.Host + ":" + strconv.Itoa(m.config.Port)
+	if err := smtp.SendMail(addr, auth, m.config.From, to, message); err != nil {
+		return fmt.Errorf("failed to send report email: %w", err)
+	}
+	return nil
+}
+
+// buildMessage builds a multipart/mixed message of a text part and the attached document
+func buildMessage(from string, to []string, subject, text string, attachment Document) ([]byte, error) {
+	for _, address := range append([]string{from}, to...) {
+		if strings.ContainsAny(address, "\r\n") {
+			return nil, fmt.Errorf("invalid email address %q", address)
+		}
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(part, text); err != nil {
+		return nil, err
+	}
+	part, err = writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {attachment.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(attachment.Body)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return nil, err
+		}
+		encoded = encoded[76:]
+	}
+	if _, err := io.WriteString(part, encoded); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", writer.Boundary())
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// postWebhook posts the document to a webhook, signed with eventhook.Sign when secret is set
+func postWebhook(ctx context.Context, client *http.Client, url string, secret []byte, schedule string, document Document, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(document.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", document.ContentType)
+	req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": document.Name}))
+	req.Header.Set(HeaderSchedule, schedule)
+	if len(secret) > 0 {
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		req.Header.Set(eventhook.HeaderTimestamp, timestamp)
+		req.Header.Set(eventhook.HeaderSignature, eventhook.Sign(secret, timestamp, document.Body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver report: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("report webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
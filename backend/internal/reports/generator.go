@@ -0,0 +1,290 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
+	"github.com/invulnerable/backend/internal/storage"
+	"go.uber.org/zap"
+)
+
+// maxOldestBreaches is the number of longest overdue vulnerabilities a report lists
+const maxOldestBreaches = 10
+
+// slaSeverities are the severities with an SLA of their own, in the order reports list them. The
+// others, such as Negligible, have the Low SLA and are counted with it like sla.Days does
+var slaSeverities = []string{"Critical", "High", "Medium", "Low"}
+
+// Store records the reports and reads what they report
+type Store interface {
+	Create(ctx context.Context, report *models.ComplianceReport) error
+	LastPeriodEnd(ctx context.Context, schedule string) (*time.Time, error)
+	Posture(ctx context.Context) (*models.ReportPosture, error)
+	Trends(ctx context.Context, start, end time.Time) ([]models.ReportTrend, error)
+}
+
+// DeadlineStore lists the open vulnerabilities with their SLA deadline
+type DeadlineStore interface {
+	ListDeadlines(ctx context.Context) ([]models.SLABreach, error)
+}
+
+// Request asks for a report
+type Request struct {
+	// Schedule is delivered the report, nil for a report on demand which is only stored
+	Schedule    *Schedule
+	Format      string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	RequestedBy *string
+}
+
+// Generator generates the reports of the schedules when they are due, and on demand
+type Generator struct {
+	logger        *zap.Logger
+	store         Store
+	deadlines     DeadlineStore
+	objects       storage.ObjectStorage
+	schedules     []Schedule
+	mailer        Mailer
+	httpClient    *http.Client
+	webhookSecret []byte
+	started       time.Time
+}
+
+// NewGenerator creates a generator of the validated schedules. A schedule without reports yet
+// generates its first one at its first activation from now on
+func NewGenerator(logger *zap.Logger, store Store, deadlines DeadlineStore, objects storage.ObjectStorage, schedules []Schedule) *Generator {
+	return &Generator{
+		logger:     logger,
+		store:      store,
+		deadlines:  deadlines,
+		objects:    objects,
+		schedules:  schedules,
+		httpClient: &http.Client{Timeout: deliveryTimeout},
+		started:    time.Now(),
+	}
+}
+
+// SetMailer sets how the reports of schedules with email recipients are sent
+func (g *Generator) SetMailer(mailer Mailer) {
+	g.mailer = mailer
+}
+
+// SetWebhookSecret signs the webhook deliveries with secret
+func (g *Generator) SetWebhookSecret(secret string) {
+	g.webhookSecret = []byte(secret)
+}
+
+// Schedules returns the schedules
+func (g *Generator) Schedules() []Schedule {
+	return g.schedules
+}
+
+// Schedule returns the schedule named name
+func (g *Generator) Schedule(name string) (*Schedule, bool) {
+	for i := range g.schedules {
+		if g.schedules[i].Name == name {
+			return &g.schedules[i], true
+		}
+	}
+	return nil, false
+}
+
+// Run generates the reports of the schedules due at now, it is the job of the compliance-reports worker
+func (g *Generator) Run(ctx context.Context, now time.Time) error {
+	var errs []error
+	for i := range g.schedules {
+		schedule := &g.schedules[i]
+		last, err := g.store.LastPeriodEnd(ctx, schedule.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		from := g.started
+		if last != nil {
+			from = *last
+		}
+		due := schedule.due(from, now)
+		if due.IsZero() {
+			continue
+		}
+
+		report, err := g.Generate(ctx, Request{
+			Schedule:    schedule,
+			Format:      schedule.Format,
+			PeriodStart: due.AddDate(0, 0, -schedule.PeriodDays),
+			PeriodEnd:   due,
+		}, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("report %s: %w", schedule.Name, err))
+			continue
+		}
+		g.logger.Info("generated compliance report",
+			zap.String("schedule", schedule.Name),
+			zap.Int64("report_id", report.ID),
+			zap.Time("period_end", report.PeriodEnd),
+			zap.Strings("destinations", report.Destinations),
+			zap.Strings("errors", report.Errors))
+	}
+	return errors.Join(errs...)
+}
+
+// Generate generates, stores and delivers a report, and records it. A delivery that fails is recorded
+// in the errors of the report, a report on demand that can't be stored is an error
+func (g *Generator) Generate(ctx context.Context, req Request, now time.Time) (*models.ComplianceReport, error) {
+	data, err := g.collect(ctx, req, now)
+	if err != nil {
+		return nil, err
+	}
+	body, contentType, err := Render(data, req.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	name := "on-demand"
+	report := &models.ComplianceReport{
+		Format:      req.Format,
+		PeriodStart: req.PeriodStart,
+		PeriodEnd:   req.PeriodEnd,
+		SizeBytes:   int64(len(body)),
+		RequestedBy: req.RequestedBy,
+	}
+	if req.Schedule != nil {
+		name = req.Schedule.Name
+		report.Schedule = &name
+	}
+	document := Document{
+		Name:        FileName(report),
+		ContentType: contentType,
+		Body:        body,
+	}
+
+	key := fmt.Sprintf("compliance-reports/%s/%s.%s", name, now.UTC().Format("20060102T150405.000000000Z"), req.Format)
+	if err := g.objects.Put(ctx, key, body, contentType); err != nil {
+		if req.Schedule == nil {
+			return nil, fmt.Errorf("failed to store report: %w", err)
+		}
+		report.Errors = append(report.Errors, "s3: "+err.Error())
+	} else {
+		report.StorageKey = &key
+		report.Destinations = append(report.Destinations, "s3")
+	}
+
+	if req.Schedule != nil {
+		g.deliver(ctx, req.Schedule, data, document, report, now)
+	}
+	if err := g.store.Create(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// deliver delivers a scheduled report to the webhook and the email recipients of its schedule
+func (g *Generator) deliver(ctx context.Context, schedule *Schedule, data *models.ReportData, document Document, report *models.ComplianceReport, now time.Time) {
+	if schedule.Webhook != "" {
+		if err := postWebhook(ctx, g.httpClient, schedule.Webhook, g.webhookSecret, schedule.Name, document, now); err != nil {
+			report.Errors = append(report.Errors, "webhook: "+err.Error())
+		} else {
+			report.Destinations = append(report.Destinations, "webhook")
+		}
+	}
+	if len(schedule.Email) > 0 {
+		if g.mailer == nil {
+			report.Errors = append(report.Errors, "email: SMTP is not configured")
+			return
+		}
+		r := newReport(data)
+		if err := g.mailer.Send(ctx, schedule.Email, r.EmailSubject(), r.EmailText(document.Name), document); err != nil {
+			report.Errors = append(report.Errors, "email: "+err.Error())
+		} else {
+			report.Destinations = append(report.Destinations, schedule.Email...)
+		}
+	}
+}
+
+// collect reads the content of a report
+func (g *Generator) collect(ctx context.Context, req Request, now time.Time) (*models.ReportData, error) {
+	posture, err := g.store.Posture(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trends, err := g.store.Trends(ctx, req.PeriodStart, req.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+	deadlines, err := g.deadlines.ListDeadlines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SLA deadlines: %w", err)
+	}
+
+	locale := notifier.DefaultLocale
+	if req.Schedule != nil {
+		locale = req.Schedule.Locale
+	}
+	title := notifier.NewTranslator(locale).Text("ReportTitle", nil)
+	if req.Schedule != nil && req.Schedule.Title != "" {
+		title = req.Schedule.Title
+	}
+	return &models.ReportData{
+		Title:       title,
+		Locale:      locale,
+		GeneratedAt: now,
+		PeriodStart: req.PeriodStart,
+		PeriodEnd:   req.PeriodEnd,
+		Posture:     *posture,
+		SLA:         slaCompliance(deadlines, req.PeriodEnd),
+		Trends:      trends,
+	}, nil
+}
+
+// slaCompliance counts the open vulnerabilities within and past their deadline at the end of the period
+func slaCompliance(deadlines []models.SLABreach, at time.Time) models.ReportSLA {
+	rows := make(map[string]*models.ReportSLARow, len(slaSeverities))
+	report := models.ReportSLA{Severities: []models.ReportSLARow{}, Oldest: []models.SLABreach{}}
+	for _, d := range deadlines {
+		severity := d.SLASeverity
+		if severity != "Critical" && severity != "High" && severity != "Medium" {
+			severity = "Low"
+		}
+		row, ok := rows[severity]
+		if !ok {
+			row = &models.ReportSLARow{Severity: severity}
+			rows[severity] = row
+		}
+		if at.Before(d.DueAt) {
+			report.WithinSLA++
+			row.WithinSLA++
+		} else {
+			report.Breached++
+			row.Breached++
+			report.Oldest = append(report.Oldest, d)
+		}
+	}
+
+	for _, severity := range slaSeverities {
+		if row, ok := rows[severity]; ok {
+			report.Severities = append(report.Severities, *row)
+		}
+	}
+	report.Compliance = 100
+	if total := report.WithinSLA + report.Breached; total > 0 {
+		report.Compliance = float64(report.WithinSLA) * 100 / float64(total)
+	}
+	sort.SliceStable(report.Oldest, func(i, j int) bool {
+		if !report.Oldest[i].DueAt.Equal(report.Oldest[j].DueAt) {
+			return report.Oldest[i].DueAt.Before(report.Oldest[j].DueAt)
+		}
+		return strings.Compare(report.Oldest[i].CVEID, report.Oldest[j].CVEID) < 0
+	})
+	if len(report.Oldest) > maxOldestBreaches {
+		report.Oldest = report.Oldest[:maxOldestBreaches]
+	}
+	return report
+}
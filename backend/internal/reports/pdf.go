@@ -0,0 +1,193 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// The PDF reports are text documents on A4 pages, written with the standard fonts every reader
+// has, so they need neither a PDF library nor embedded fonts
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	pageMargin   = 50
+	fontSize     = 10
+	headingSize  = 14
+	titleSize    = 20
+	lineSpacing  = 1.4
+	pdfMaxLength = 82 // characters of a 10pt Courier line between the margins, Helvetica lines are wrapped at it too
+)
+
+// Fonts of the PDF reports, the resource names of their page objects
+const (
+	fontRegular = "F1" // Helvetica
+	fontBold    = "F2" // Helvetica-Bold
+	fontMono    = "F3" // Courier, for the tables
+)
+
+// pdfLine is a line of text of a PDF report
+type pdfLine struct {
+	font string
+	size float64
+	text string
+}
+
+// pdfLines lays out a report as lines of text
+func pdfLines(r report) []pdfLine {
+	var lines []pdfLine
+	text := func(font string, size float64, s string) {
+		for _, t := range wrap(s, pdfMaxLength) {
+			lines = append(lines, pdfLine{font: font, size: size, text: t})
+		}
+	}
+	blank := func() { lines = append(lines, pdfLine{font: fontRegular, size: fontSize}) }
+	heading := func(id string) {
+		blank()
+		text(fontBold, headingSize, r.T(id))
+	}
+	// Table rows are aligned with spaces, they fit on a line and are not wrapped
+	row := func(format string, args ...any) {
+		lines = append(lines, pdfLine{font: fontMono, size: fontSize, text: fmt.Sprintf(format, args...)})
+	}
+
+	text(fontBold, titleSize, r.Title)
+	text(fontRegular, fontSize, r.Period())
+
+	heading("ReportPosture")
+	text(fontRegular, fontSize, r.PostureSummary())
+	blank()
+	row("%-12s %10s %10s", r.T("Severity"), r.T("ReportOpen"), r.T("ReportFixable"))
+	for _, s := range r.Posture.Severities {
+		row("%-12s %10d %10d", r.Severity(s.Severity), s.Open, s.Fixable)
+	}
+
+	heading("ReportSLA")
+	text(fontRegular, fontSize, r.SLASummary())
+	blank()
+	row("%-12s %10s %10s", r.T("Severity"), r.T("ReportWithinSLA"), r.T("ReportBreached"))
+	for _, s := range r.SLA.Severities {
+		row("%-12s %10d %10d", r.Severity(s.Severity), s.WithinSLA, s.Breached)
+	}
+	if len(r.SLA.Oldest) > 0 {
+		blank()
+		text(fontBold, fontSize, r.T("ReportLongestOverdue"))
+		row("%-18s %-30s %-10s %-10s %7s", r.T("CVEID"), r.T("Image"), r.T("Severity"), r.T("ReportDue"), r.T("ReportOverdue"))
+		for _, b := range r.SLA.Oldest {
+			row("%-18s %-30s %-10s %-10s %6dd", truncate(b.CVEID, 18), truncate(b.ImageName, 30),
+				truncate(r.Severity(b.SLASeverity), 10), b.DueDate, overdue(r.PeriodEnd, b.DueAt))
+			row("  %s", truncate(strings.TrimSpace(b.PackageName+" "+b.PackageVersion), pdfMaxLength-2))
+		}
+	}
+
+	heading("ReportTrends")
+	text(fontRegular, fontSize, r.TrendsSummary())
+	blank()
+	row("%-12s %10s %10s", r.T("ReportDate"), r.T("ReportNew"), r.T("ReportFixed"))
+	for _, t := range r.Trends {
+		row("%-12s %10d %10d", t.Date, t.New, t.Fixed)
+	}
+	return lines
+}
+
+// wrap splits s into lines of at most n characters, between words
+func wrap(s string, n int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > n {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
+
+// truncate shortens s to n characters
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "~"
+	}
+	return s
+}
+
+// renderPDF writes the lines of a report on as many pages as they take
+func renderPDF(r report) []byte {
+	var pages []string
+	var page strings.Builder
+	y := float64(pageHeight - pageMargin)
+	for _, line := range pdfLines(r) {
+		height := line.size * lineSpacing
+		if y-height < pageMargin && page.Len() > 0 {
+			pages = append(pages, page.String())
+			page.Reset()
+			y = pageHeight - pageMargin
+		}
+		y -= height
+		if line.text != "" {
+			fmt.Fprintf(&page, "BT /%s %g Tf %d %.1f Td (%s) Tj ET\n", line.font, line.size, pageMargin, y, pdfString(line.text))
+		}
+	}
+	pages = append(pages, page.String())
+
+	// Objects 1 to 5 are the catalog, the page tree and the fonts, then each page and its content
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // the page tree, once the pages are numbered
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	}
+	kids := make([]string, 0, len(pages))
+	for _, content := range pages {
+		pageID := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /%s 3 0 R /%s 4 0 R /%s 5 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, fontRegular, fontBold, fontMono, pageID+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfString escapes text for a PDF string literal in WinAnsiEncoding, replacing the characters
+// outside Latin-1 with a question mark
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,209 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
+)
+
+// Render renders a report in format, in the locale of the report
+func Render(data *models.ReportData, format string) ([]byte, string, error) {
+	switch format {
+	case models.ReportFormatHTML:
+		var buf bytes.Buffer
+		if err := htmlReport.Execute(&buf, newReport(data)); err != nil {
+			return nil, "", fmt.Errorf("failed to render report: %w", err)
+		}
+		return buf.Bytes(), ContentType(format), nil
+	case models.ReportFormatPDF:
+		return renderPDF(newReport(data)), ContentType(format), nil
+	default:
+		return nil, "", ValidateFormat(format)
+	}
+}
+
+// ContentType returns the content type of the documents of a format
+func ContentType(format string) string {
+	if format == models.ReportFormatPDF {
+		return "application/pdf"
+	}
+	return "text/html; charset=utf-8"
+}
+
+// FileName returns the file name of the document of a report, such as weekly-2026-10-12.pdf
+func FileName(report *models.ComplianceReport) string {
+	name := "on-demand"
+	if report.Schedule != nil {
+		name = *report.Schedule
+	}
+	return fmt.Sprintf("%s-%s.%s", name, report.PeriodEnd.UTC().Format("2006-01-02"), report.Format)
+}
+
+// report is the content of a report with its text, written through the notification bundles
+// in the locale of the report for both the HTML and the PDF documents
+type report struct {
+	*models.ReportData
+	translator notifier.Translator
+}
+
+func newReport(data *models.ReportData) report {
+	return report{ReportData: data, translator: notifier.NewTranslator(data.Locale)}
+}
+
+// Lang is the language of the document
+func (r report) Lang() string {
+	if r.Locale == "" {
+		return notifier.DefaultLocale
+	}
+	return r.Locale
+}
+
+// T renders a message without fields, such as a heading
+func (r report) T(id string) string {
+	return r.translator.Text(id, nil)
+}
+
+// Severity translates a severity, one without a message, such as Negligible, is written as it is
+func (r report) Severity(severity string) string {
+	id := "Severity" + severity
+	if text := r.T(id); text != id {
+		return text
+	}
+	return severity
+}
+
+// Period is the period of the report and when it was generated
+func (r report) Period() string {
+	return r.translator.Text("ReportPeriod", map[string]string{
+		"Start":       date(r.PeriodStart),
+		"End":         date(r.PeriodEnd),
+		"GeneratedAt": r.GeneratedAt.UTC().Format("2006-01-02 15:04 UTC"),
+	})
+}
+
+// PostureSummary sums up the open vulnerabilities
+func (r report) PostureSummary() string {
+	return r.translator.Text("ReportPostureSummary", r.Posture)
+}
+
+// SLASummary sums up the SLA compliance
+func (r report) SLASummary() string {
+	return r.translator.Text("ReportSLASummary", map[string]interface{}{
+		"Compliance": fmt.Sprintf("%.1f", r.SLA.Compliance),
+		"WithinSLA":  r.SLA.WithinSLA,
+		"Breached":   r.SLA.Breached,
+	})
+}
+
+// TrendsSummary sums up the vulnerabilities found and fixed over the period
+func (r report) TrendsSummary() string {
+	found, fixed := totals(r.Trends)
+	return r.translator.Text("ReportTrendsSummary", map[string]int{"New": found, "Fixed": fixed})
+}
+
+// EmailSubject is the subject of the email a report is attached to
+func (r report) EmailSubject() string {
+	return r.translator.Text("ReportEmailSubject", map[string]string{
+		"Title": r.Title,
+		"Start": date(r.PeriodStart),
+		"End":   date(r.PeriodEnd),
+	})
+}
+
+// EmailText is the text of the email the document of a report is attached to
+func (r report) EmailText(document string) string {
+	found, fixed := totals(r.Trends)
+	return r.translator.Text("ReportEmailText", map[string]interface{}{
+		"Document":            document,
+		"OpenVulnerabilities": r.Posture.OpenVulnerabilities,
+		"Compliance":          fmt.Sprintf("%.1f", r.SLA.Compliance),
+		"New":                 found,
+		"Fixed":               fixed,
+	})
+}
+
+// date formats the day of t in UTC
+func date(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// totals sums the trends of a period
+func totals(trends []models.ReportTrend) (found, fixed int) {
+	for _, t := range trends {
+		found += t.New
+		fixed += t.Fixed
+	}
+	return found, fixed
+}
+
+// overdue is how long ago a deadline passed at the end of the period, in days
+func overdue(periodEnd, dueAt time.Time) int {
+	return int(periodEnd.Sub(dueAt).Hours() / 24)
+}
+
+// htmlReport is executed with a report, whose methods write its text
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"overdue": overdue,
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2937; margin: 2rem; }
+h1 { margin-bottom: 0; }
+h2 { margin-top: 2rem; border-bottom: 1px solid #e5e7eb; }
+.period { color: #6b7280; }
+table { border-collapse: collapse; margin-top: 0.5rem; }
+th, td { border: 1px solid #e5e7eb; padding: 0.25rem 0.75rem; text-align: left; }
+th { background: #f9fafb; }
+td.number { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="period">{{.Period}}</p>
+
+<h2>{{.T "ReportPosture"}}</h2>
+<p>{{.PostureSummary}}</p>
+<table>
+<tr><th>{{.T "Severity"}}</th><th>{{.T "ReportOpen"}}</th><th>{{.T "ReportFixable"}}</th></tr>
+{{- range .Posture.Severities}}
+<tr><td>{{$.Severity .Severity}}</td><td class="number">{{.Open}}</td><td class="number">{{.Fixable}}</td></tr>
+{{- end}}
+</table>
+
+<h2>{{.T "ReportSLA"}}</h2>
+<p>{{.SLASummary}}</p>
+<table>
+<tr><th>{{.T "Severity"}}</th><th>{{.T "ReportWithinSLA"}}</th><th>{{.T "ReportBreached"}}</th></tr>
+{{- range .SLA.Severities}}
+<tr><td>{{$.Severity .Severity}}</td><td class="number">{{.WithinSLA}}</td><td class="number">{{.Breached}}</td></tr>
+{{- end}}
+</table>
+{{- if .SLA.Oldest}}
+<h3>{{.T "ReportLongestOverdue"}}</h3>
+<table>
+<tr><th>{{.T "CVEID"}}</th><th>{{.T "Package"}}</th><th>{{.T "Image"}}</th><th>{{.T "Severity"}}</th><th>{{.T "ReportDue"}}</th><th>{{.T "ReportDaysOverdue"}}</th></tr>
+{{- $end := .PeriodEnd}}
+{{- range .SLA.Oldest}}
+<tr><td>{{.CVEID}}</td><td>{{.PackageName}} {{.PackageVersion}}</td><td>{{.ImageName}}</td><td>{{$.Severity .SLASeverity}}</td><td>{{.DueDate}}</td><td class="number">{{overdue $end .DueAt}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2>{{.T "ReportTrends"}}</h2>
+<p>{{.TrendsSummary}}</p>
+<table>
+<tr><th>{{.T "ReportDate"}}</th><th>{{.T "ReportNew"}}</th><th>{{.T "ReportFixed"}}</th></tr>
+{{- range .Trends}}
+<tr><td>{{.Date}}</td><td class="number">{{.New}}</td><td class="number">{{.Fixed}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,140 @@
+// Package reports generates periodic compliance reports: the vulnerability posture, the SLA compliance
+// and the vulnerabilities found and fixed over a period, as HTML or PDF documents. Reports are stored
+// in S3 and delivered to a webhook or by email, on the cron schedules of a YAML file or on demand
+package reports
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
+	"github.com/invulnerable/backend/internal/sla"
+	"github.com/pacokleitz/invulnerable/pkg/cron"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPeriodDays is the period a report covers when its schedule or request doesn't set one
+const DefaultPeriodDays = 7
+
+// Schedule generates a report at each activation of its cron expression, covering the days before it
+type Schedule struct {
+	Name string `yaml:"name" json:"name"`
+	// Title is the title of the reports, "Vulnerability report" in Locale when empty
+	Title string `yaml:"title,omitempty" json:"title,omitempty"`
+	// Locale is the language of the reports and their emails, one of the notification locales
+	Locale string `yaml:"locale,omitempty" json:"locale"`
+	// Cron is a 5-field cron expression or a macro such as @weekly, in TimeZone
+	Cron     string `yaml:"cron" json:"cron"`
+	TimeZone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	// PeriodDays is the number of days before each activation the report covers
+	PeriodDays int    `yaml:"periodDays,omitempty" json:"period_days"`
+	Format     string `yaml:"format,omitempty" json:"format"`
+	// Webhook receives the document in a POST, signed like the event webhook when a secret is set
+	Webhook string `yaml:"webhook,omitempty" json:"-"`
+	// Email lists the recipients the document is sent to as an attachment
+	Email []string `yaml:"email,omitempty" json:"email,omitempty"`
+
+	schedule *cron.Schedule
+	location *time.Location
+}
+
+// file is the format of the schedules file
+type file struct {
+	Schedules []Schedule `yaml:"schedules"`
+}
+
+// Load reads the schedules file, a YAML document with a list of schedules
+func Load(path string) ([]Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report schedules: %w", err)
+	}
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse report schedules: %w", err)
+	}
+	if err := Validate(f.Schedules); err != nil {
+		return nil, err
+	}
+	return f.Schedules, nil
+}
+
+// Validate checks the schedules and fills in their defaults
+func Validate(schedules []Schedule) error {
+	names := make(map[string]bool, len(schedules))
+	for i := range schedules {
+		s := &schedules[i]
+		if s.Name == "" {
+			return fmt.Errorf("report schedule %d: name is required", i)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("report schedule %q is defined twice", s.Name)
+		}
+		names[s.Name] = true
+
+		schedule, err := cron.Parse(s.Cron)
+		if err != nil {
+			return fmt.Errorf("report schedule %q: invalid cron: %w", s.Name, err)
+		}
+		s.schedule = schedule
+		if s.location, err = sla.LoadLocation(s.TimeZone); err != nil {
+			return fmt.Errorf("report schedule %q: %w", s.Name, err)
+		}
+		if s.PeriodDays < 0 {
+			return fmt.Errorf("report schedule %q: periodDays can't be negative", s.Name)
+		}
+		if s.PeriodDays == 0 {
+			s.PeriodDays = DefaultPeriodDays
+		}
+		if s.Format == "" {
+			s.Format = models.ReportFormatHTML
+		}
+		if err := ValidateFormat(s.Format); err != nil {
+			return fmt.Errorf("report schedule %q: %w", s.Name, err)
+		}
+		if s.Locale == "" {
+			s.Locale = notifier.DefaultLocale
+		}
+		if !notifier.IsSupportedLocale(s.Locale) {
+			return fmt.Errorf("report schedule %q: locale must be one of %s", s.Name, strings.Join(notifier.Locales, ", "))
+		}
+		if s.Webhook != "" {
+			if u, err := url.Parse(s.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("report schedule %q: invalid webhook URL", s.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateFormat checks the format of a report
+func ValidateFormat(format string) error {
+	if format != models.ReportFormatHTML && format != models.ReportFormatPDF {
+		return fmt.Errorf("invalid format: %s (must be html or pdf)", format)
+	}
+	return nil
+}
+
+// UseEmail tells whether any of the schedules sends its reports by email
+func UseEmail(schedules []Schedule) bool {
+	for _, s := range schedules {
+		if len(s.Email) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// due returns the last activation of the schedule after last and up to now, zero when there is none.
+// Activations missed while the backend was down are not caught up: only the latest is reported
+func (s *Schedule) due(last, now time.Time) time.Time {
+	var due time.Time
+	for next := s.schedule.Next(last.In(s.location)); !next.IsZero() && !next.After(now); next = s.schedule.Next(next) {
+		due = next
+	}
+	return due
+}
@@ -0,0 +1,337 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/eventhook"
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeStore records the reports in memory and reports a fixed posture
+type fakeStore struct {
+	mu      sync.Mutex
+	reports []models.ComplianceReport
+}
+
+func (s *fakeStore) Create(_ context.Context, report *models.ComplianceReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report.ID = int64(len(s.reports) + 1)
+	s.reports = append(s.reports, *report)
+	return nil
+}
+
+func (s *fakeStore) LastPeriodEnd(_ context.Context, schedule string) (*time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var last *time.Time
+	for _, r := range s.reports {
+		if r.Schedule != nil && *r.Schedule == schedule && (last == nil || r.PeriodEnd.After(*last)) {
+			end := r.PeriodEnd
+			last = &end
+		}
+	}
+	return last, nil
+}
+
+func (s *fakeStore) Posture(context.Context) (*models.ReportPosture, error) {
+	return &models.ReportPosture{
+		Images: 3, ImagesWithCritical: 1, OpenVulnerabilities: 12, KnownExploited: 2,
+		Severities: []models.ReportSeverityRow{{Severity: "Critical", Open: 2, Fixable: 1}, {Severity: "High", Open: 10, Fixable: 4}},
+	}, nil
+}
+
+func (s *fakeStore) Trends(_ context.Context, start, end time.Time) ([]models.ReportTrend, error) {
+	var trends []models.ReportTrend
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		trends = append(trends, models.ReportTrend{Date: day.Format("2006-01-02"), New: 2, Fixed: 1})
+	}
+	return trends, nil
+}
+
+type fakeDeadlines []models.SLABreach
+
+func (d fakeDeadlines) ListDeadlines(context.Context) ([]models.SLABreach, error) {
+	return d, nil
+}
+
+type fakeObjects struct {
+	objects map[string][]byte
+}
+
+func (o *fakeObjects) Put(_ context.Context, key string, document []byte, _ string) error {
+	o.objects[key] = document
+	return nil
+}
+
+func (o *fakeObjects) Get(_ context.Context, key string) ([]byte, error) {
+	return o.objects[key], nil
+}
+
+type fakeMailer struct {
+	to         []string
+	subject    string
+	text       string
+	attachment Document
+}
+
+func (m *fakeMailer) Send(_ context.Context, to []string, subject, text string, attachment Document) error {
+	m.to, m.subject, m.text, m.attachment = to, subject, text, attachment
+	return nil
+}
+
+func writeSchedules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schedules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	schedules, err := Load(writeSchedules(t, `
+schedules:
+  - name: weekly
+    cron: "0 8 * * 1"
+    timezone: Europe/Paris
+    format: pdf
+    locale: fr
+    webhook: https://reports.example.com/hook
+    email: [secops@example.com]
+  - name: monthly
+    cron: "@monthly"
+    periodDays: 30
+`))
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	assert.Equal(t, DefaultPeriodDays, schedules[0].PeriodDays)
+	assert.Equal(t, models.ReportFormatPDF, schedules[0].Format)
+	assert.Equal(t, models.ReportFormatHTML, schedules[1].Format)
+	assert.Equal(t, "fr", schedules[0].Locale)
+	assert.Equal(t, "en", schedules[1].Locale)
+	assert.Equal(t, 30, schedules[1].PeriodDays)
+	assert.True(t, UseEmail(schedules))
+
+	for name, content := range map[string]string{
+		"no name":   "schedules: [{cron: '@daily'}]",
+		"duplicate": "schedules: [{name: a, cron: '@daily'}, {name: a, cron: '@weekly'}]",
+		"cron":      "schedules: [{name: a, cron: '0 25 * * *'}]",
+		"timezone":  "schedules: [{name: a, cron: '@daily', timezone: Mars/Olympus}]",
+		"format":    "schedules: [{name: a, cron: '@daily', format: docx}]",
+		"webhook":   "schedules: [{name: a, cron: '@daily', webhook: 'ftp://example.com'}]",
+		"period":    "schedules: [{name: a, cron: '@daily', periodDays: -1}]",
+		"locale":    "schedules: [{name: a, cron: '@daily', locale: pt}]",
+	} {
+		_, err := Load(writeSchedules(t, content))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestScheduleDue(t *testing.T) {
+	schedules := []Schedule{{Name: "weekly", Cron: "0 8 * * 1"}}
+	require.NoError(t, Validate(schedules))
+	s := &schedules[0]
+
+	// Monday 2026-10-12 08:00 UTC
+	monday := time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)
+	assert.True(t, s.due(monday.Add(-time.Hour), monday.Add(-time.Minute)).IsZero())
+	assert.Equal(t, monday, s.due(monday.Add(-time.Hour), monday.Add(time.Minute)))
+	assert.True(t, s.due(monday, monday.Add(time.Hour)).IsZero(), "already reported")
+	// Missed activations only report the latest
+	assert.Equal(t, monday, s.due(monday.AddDate(0, 0, -15), monday.Add(time.Minute)))
+}
+
+func TestRender(t *testing.T) {
+	start := time.Date(2026, 10, 5, 8, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	store := &fakeStore{}
+	posture, _ := store.Posture(context.Background())
+	trends, _ := store.Trends(context.Background(), start, end)
+	data := &models.ReportData{
+		Title:       "Posture <weekly>",
+		GeneratedAt: end,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Posture:     *posture,
+		SLA: slaCompliance([]models.SLABreach{
+			{CVEID: "CVE-2026-0001", ImageName: "acme/api:1.0 (été)", SLASeverity: "Critical", DueDate: "2026-10-01", DueAt: end.AddDate(0, 0, -11)},
+		}, end),
+		Trends: trends,
+	}
+
+	html, contentType, err := Render(data, models.ReportFormatHTML)
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", contentType)
+	assert.Contains(t, string(html), "Posture &lt;weekly&gt;")
+	assert.Contains(t, string(html), "2026-10-05 to 2026-10-12")
+	assert.Contains(t, string(html), "14 new and 7 fixed over the period")
+	assert.Contains(t, string(html), "CVE-2026-0001")
+
+	pdf, contentType, err := Render(data, models.ReportFormatPDF)
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", contentType)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), "(CVE-2026-0001")
+	assert.Contains(t, string(pdf), `\351t\351`, "Latin-1 characters are escaped")
+
+	// startxref points at the cross-reference table, whose entries point at the objects
+	offset, err := strconv.Atoi(regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(string(pdf))[1])
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf[offset:], []byte("xref\n")))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(string(pdf), -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		assert.True(t, bytes.HasPrefix(pdf[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+	}
+
+	_, _, err = Render(data, "docx")
+	assert.Error(t, err)
+
+	// The text is written in the locale of the report
+	data.Locale = "fr"
+	html, _, err = Render(data, models.ReportFormatHTML)
+	require.NoError(t, err)
+	assert.Contains(t, string(html), `<html lang="fr">`)
+	assert.Contains(t, string(html), "Du 2026-10-05 au 2026-10-12")
+	assert.Contains(t, string(html), "<h2>Posture de vulnérabilité</h2>")
+	assert.Contains(t, string(html), "<td>Critique</td>")
+	assert.Contains(t, string(html), "14 nouvelles et 7 corrigées sur la période")
+	pdf, _, err = Render(data, models.ReportFormatPDF)
+	require.NoError(t, err)
+	assert.Contains(t, string(pdf), `(Conformit\351 SLA)`)
+}
+
+func TestRenderPDFPages(t *testing.T) {
+	end := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	data := &models.ReportData{Title: "Quarter", PeriodStart: end.AddDate(0, 0, -90), PeriodEnd: end}
+	data.Trends, _ = (&fakeStore{}).Trends(context.Background(), data.PeriodStart, data.PeriodEnd)
+
+	pdf := renderPDF(newReport(data))
+	assert.Contains(t, string(pdf), "/Count 3 >>", "90 days of trends take 3 pages")
+}
+
+func TestSLACompliance(t *testing.T) {
+	at := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	report := slaCompliance([]models.SLABreach{
+		{CVEID: "CVE-1", SLASeverity: "High", DueAt: at.AddDate(0, 0, -1)},
+		{CVEID: "CVE-2", SLASeverity: "High", DueAt: at.AddDate(0, 0, 1)},
+		{CVEID: "CVE-3", SLASeverity: "Critical", DueAt: at.AddDate(0, 0, -5)},
+		{CVEID: "CVE-4", SLASeverity: "Negligible", DueAt: at},
+	}, at)
+
+	assert.Equal(t, 1, report.WithinSLA)
+	assert.Equal(t, 3, report.Breached)
+	assert.Equal(t, 25.0, report.Compliance)
+	assert.Equal(t, []models.ReportSLARow{
+		{Severity: "Critical", Breached: 1},
+		{Severity: "High", WithinSLA: 1, Breached: 1},
+		{Severity: "Low", Breached: 1},
+	}, report.Severities)
+	require.Len(t, report.Oldest, 3)
+	assert.Equal(t, "CVE-3", report.Oldest[0].CVEID)
+
+	assert.Equal(t, 100.0, slaCompliance(nil, at).Compliance)
+}
+
+func TestGeneratorRun(t *testing.T) {
+	var mu sync.Mutex
+	var received []byte
+	var receivedHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received, _ = io.ReadAll(r.Body)
+		receivedHeaders = r.Header
+	}))
+	defer server.Close()
+
+	schedules := []Schedule{{Name: "weekly", Cron: "0 8 * * 1", Format: models.ReportFormatPDF, Locale: "de", Webhook: server.URL, Email: []string{"secops@example.com"}}}
+	require.NoError(t, Validate(schedules))
+	store := &fakeStore{}
+	objects := &fakeObjects{objects: map[string][]byte{}}
+	mailer := &fakeMailer{}
+	generator := NewGenerator(zap.NewNop(), store, fakeDeadlines{}, objects, schedules)
+	generator.SetMailer(mailer)
+	generator.SetWebhookSecret("secret")
+	monday := time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)
+	generator.started = monday.Add(-time.Hour)
+
+	require.NoError(t, generator.Run(context.Background(), monday.Add(-time.Minute)))
+	assert.Empty(t, store.reports, "not due yet")
+
+	now := monday.Add(time.Minute)
+	require.NoError(t, generator.Run(context.Background(), now))
+	require.Len(t, store.reports, 1)
+	report := store.reports[0]
+	assert.Equal(t, "weekly", *report.Schedule)
+	assert.Equal(t, monday.AddDate(0, 0, -7), report.PeriodStart)
+	assert.Equal(t, monday, report.PeriodEnd)
+	assert.Equal(t, []string{"s3", "webhook", "secops@example.com"}, []string(report.Destinations))
+	assert.Empty(t, report.Errors)
+
+	require.NotNil(t, report.StorageKey)
+	document := objects.objects[*report.StorageKey]
+	assert.True(t, bytes.HasPrefix(document, []byte("%PDF-")))
+	assert.Equal(t, int64(len(document)), report.SizeBytes)
+
+	mu.Lock()
+	assert.Equal(t, document, received)
+	assert.Equal(t, "application/pdf", receivedHeaders.Get("Content-Type"))
+	assert.Equal(t, "weekly", receivedHeaders.Get(HeaderSchedule))
+	assert.NoError(t, eventhook.Verify([]byte("secret"), receivedHeaders.Get(eventhook.HeaderTimestamp), received,
+		receivedHeaders.Get(eventhook.HeaderSignature), now))
+	mu.Unlock()
+
+	assert.Equal(t, []string{"secops@example.com"}, mailer.to)
+	assert.Equal(t, "weekly-2026-10-12.pdf", mailer.attachment.Name)
+	assert.Equal(t, "Schwachstellenbericht, 2026-10-05 bis 2026-10-12", mailer.subject)
+	assert.Contains(t, mailer.text, "weekly-2026-10-12.pdf ist angehängt.")
+
+	require.NoError(t, generator.Run(context.Background(), now.Add(time.Hour)))
+	assert.Len(t, store.reports, 1, "already generated")
+}
+
+func TestGenerateOnDemand(t *testing.T) {
+	store := &fakeStore{}
+	objects := &fakeObjects{objects: map[string][]byte{}}
+	generator := NewGenerator(zap.NewNop(), store, fakeDeadlines{}, objects, nil)
+	end := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	requestedBy := "alice"
+
+	report, err := generator.Generate(context.Background(), Request{
+		Format: models.ReportFormatHTML, PeriodStart: end.AddDate(0, 0, -7), PeriodEnd: end, RequestedBy: &requestedBy,
+	}, end)
+	require.NoError(t, err)
+	assert.Nil(t, report.Schedule)
+	assert.Equal(t, []string{"s3"}, []string(report.Destinations))
+	assert.Equal(t, &requestedBy, report.RequestedBy)
+	require.NotNil(t, report.StorageKey)
+	assert.Contains(t, string(objects.objects[*report.StorageKey]), "<h1>Vulnerability report</h1>")
+}
+
+func TestBuildMessage(t *testing.T) {
+	message, err := buildMessage("reports@example.com", []string{"a@example.com", "b@example.com"}, "Weekly report",
+		"attached", Document{Name: "weekly.pdf", ContentType: "application/pdf", Body: []byte("%PDF-1.4")})
+	require.NoError(t, err)
+	assert.Contains(t, string(message), "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, string(message), `filename=weekly.pdf`)
+	assert.Contains(t, string(message), "JVBERi0xLjQ=")
+
+	_, err = buildMessage("reports@example.com", []string{"a@example.com\r\nBcc: x@example.com"}, "s", "t", Document{})
+	assert.Error(t, err)
+}
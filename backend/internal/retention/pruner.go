@@ -0,0 +1,119 @@
+// Package retention prunes scan history according to the retention declared on ImageScans,
+// and expires archived raw Grype results
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// Actor is recorded in the audit log for scans removed by the pruner
+const Actor = "system:retention"
+
+// Policies lists the retention of the registered images
+type Policies interface {
+	ListRetentionPolicies(ctx context.Context) ([]models.RetentionPolicy, error)
+}
+
+// Scans deletes the scans of an image that fall outside its retention
+type Scans interface {
+	Prune(ctx context.Context, policy *models.RetentionPolicy, now time.Time, actor string) ([]int, []int, error)
+}
+
+// Documents removes the stored SBOM documents of pruned scans
+type Documents interface {
+	DeleteDocuments(ctx context.Context, scanIDs []int) error
+}
+
+// RawResults removes archived raw Grype results, of pruned scans or once they expire
+type RawResults interface {
+	Documents
+	Expire(ctx context.Context, before time.Time) ([]int, error)
+}
+
+// Pruner periodically enforces the retention of every registered image
+type Pruner struct {
+	logger     *zap.Logger
+	policies   Policies
+	scans      Scans
+	documents  Documents
+	rawResults RawResults
+	// rawResultRetention is how long raw Grype results are kept, 0 keeps them as long as the scan
+	rawResultRetention time.Duration
+}
+
+// New creates a pruner
+func New(logger *zap.Logger, policies Policies, scans Scans, documents Documents, rawResults RawResults, rawResultRetention time.Duration) *Pruner {
+	return &Pruner{
+		logger:             logger,
+		policies:           policies,
+		scans:              scans,
+		documents:          documents,
+		rawResults:         rawResults,
+		rawResultRetention: rawResultRetention,
+	}
+}
+
+// Prune applies the retention of every image and returns how many scans were deleted.
+// A failing image is logged and retried on the next run
+func (p *Pruner) Prune(ctx context.Context, now time.Time) (int, error) {
+	policies, err := p.policies.ListRetentionPolicies(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for i := range policies {
+		policy := &policies[i]
+		scanIDs, sbomScanIDs, err := p.scans.Prune(ctx, policy, now, Actor)
+		if err != nil {
+			p.logger.Error("failed to prune scans", zap.Error(err),
+				zap.Int("image_id", policy.ImageID), zap.String("image", policy.ImageName))
+			continue
+		}
+		if len(scanIDs) == 0 {
+			continue
+		}
+
+		// Documents are only removed once the scans are gone, like for image deletion
+		if err := p.documents.DeleteDocuments(ctx, sbomScanIDs); err != nil {
+			p.logger.Warn("failed to delete SBOM documents of pruned scans", zap.Error(err),
+				zap.Int("image_id", policy.ImageID))
+		}
+		if err := p.rawResults.DeleteDocuments(ctx, scanIDs); err != nil {
+			p.logger.Warn("failed to delete Grype results of pruned scans", zap.Error(err),
+				zap.Int("image_id", policy.ImageID))
+		}
+
+		p.logger.Info("pruned scans",
+			zap.Int("image_id", policy.ImageID),
+			zap.String("image", policy.ImageName),
+			zap.Int("scans", len(scanIDs)))
+		pruned += len(scanIDs)
+	}
+
+	if p.rawResultRetention > 0 {
+		p.expireRawResults(ctx, now.Add(-p.rawResultRetention))
+	}
+
+	return pruned, nil
+}
+
+// expireRawResults removes the raw Grype results archived before the cutoff, the scans stay
+func (p *Pruner) expireRawResults(ctx context.Context, before time.Time) {
+	scanIDs, err := p.rawResults.Expire(ctx, before)
+	if err != nil {
+		p.logger.Error("failed to expire Grype results", zap.Error(err))
+		return
+	}
+	if len(scanIDs) == 0 {
+		return
+	}
+	if err := p.rawResults.DeleteDocuments(ctx, scanIDs); err != nil {
+		p.logger.Warn("failed to delete expired Grype results", zap.Error(err))
+	}
+	p.logger.Info("expired Grype results", zap.Int("scans", len(scanIDs)), zap.Time("before", before))
+}
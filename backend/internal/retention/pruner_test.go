@@ -0,0 +1,100 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakePolicies []models.RetentionPolicy
+
+func (p fakePolicies) ListRetentionPolicies(ctx context.Context) ([]models.RetentionPolicy, error) {
+	return p, nil
+}
+
+type fakeScans struct {
+	pruned map[int][]int
+	sboms  map[int][]int
+	failed map[int]bool
+	actors []string
+}
+
+func (s *fakeScans) Prune(ctx context.Context, policy *models.RetentionPolicy, now time.Time, actor string) ([]int, []int, error) {
+	if s.failed[policy.ImageID] {
+		return nil, nil, errors.New("connection reset")
+	}
+	s.actors = append(s.actors, actor)
+	return s.pruned[policy.ImageID], s.sboms[policy.ImageID], nil
+}
+
+type fakeDocuments struct {
+	deleted []int
+}
+
+func (d *fakeDocuments) DeleteDocuments(ctx context.Context, scanIDs []int) error {
+	d.deleted = append(d.deleted, scanIDs...)
+	return nil
+}
+
+type fakeRawResults struct {
+	fakeDocuments
+	archived map[int]time.Time
+}
+
+func (r *fakeRawResults) Expire(ctx context.Context, before time.Time) ([]int, error) {
+	var expired []int
+	for scanID, at := range r.archived {
+		if at.Before(before) {
+			expired = append(expired, scanID)
+			delete(r.archived, scanID)
+		}
+	}
+	return expired, nil
+}
+
+func TestPruner_Prune(t *testing.T) {
+	keep := 5
+	policies := fakePolicies{
+		{ImageID: 1, ImageName: "docker.io/library/nginx:latest", MaxScansRetained: &keep},
+		{ImageID: 2, ImageName: "docker.io/library/redis:7.2", MaxScansRetained: &keep},
+		{ImageID: 3, ImageName: "docker.io/library/alpine:3.19", MaxScansRetained: &keep},
+	}
+	scans := &fakeScans{
+		pruned: map[int][]int{1: {10, 11, 12}, 3: {30}},
+		sboms:  map[int][]int{1: {11}},
+		failed: map[int]bool{2: true},
+	}
+	documents := &fakeDocuments{}
+	rawResults := &fakeRawResults{}
+
+	pruner := New(zap.NewNop(), policies, scans, documents, rawResults, 0)
+	pruned, err := pruner.Prune(context.Background(), time.Now())
+	require.NoError(t, err)
+
+	// The failing image doesn't stop the others
+	assert.Equal(t, 4, pruned)
+	assert.Equal(t, []int{11}, documents.deleted)
+	assert.Equal(t, []string{Actor, Actor}, scans.actors)
+	assert.ElementsMatch(t, []int{10, 11, 12, 30}, rawResults.deleted)
+}
+
+func TestPruner_ExpiresRawResults(t *testing.T) {
+	now := time.Now()
+	rawResults := &fakeRawResults{archived: map[int]time.Time{
+		1: now.Add(-100 * 24 * time.Hour),
+		2: now.Add(-24 * time.Hour),
+	}}
+
+	pruner := New(zap.NewNop(), fakePolicies{}, &fakeScans{}, &fakeDocuments{}, rawResults, 90*24*time.Hour)
+	_, err := pruner.Prune(context.Background(), now)
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{1}, rawResults.deleted)
+	assert.Contains(t, rawResults.archived, 2)
+}
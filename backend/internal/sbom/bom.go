@@ -0,0 +1,154 @@
+package sbom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// BOMSpecVersion is the CycloneDX version of merged BOMs
+const BOMSpecVersion = "1.5"
+
+// BOM is a CycloneDX JSON document, with the fields a merged inventory uses
+type BOM struct {
+	BOMFormat    string           `json:"bomFormat"`
+	SpecVersion  string           `json:"specVersion"`
+	SerialNumber string           `json:"serialNumber"`
+	Version      int              `json:"version"`
+	Metadata     BOMMetadata      `json:"metadata"`
+	Components   []BOMComponent   `json:"components"`
+	Compositions []BOMComposition `json:"compositions"`
+}
+
+type BOMMetadata struct {
+	Timestamp  string        `json:"timestamp"`
+	Tools      BOMTools      `json:"tools"`
+	Properties []BOMProperty `json:"properties,omitempty"`
+}
+
+type BOMTools struct {
+	Components []BOMComponent `json:"components"`
+}
+
+type BOMComponent struct {
+	Type       string         `json:"type"`
+	BOMRef     string         `json:"bom-ref,omitempty"`
+	Name       string         `json:"name"`
+	Version    string         `json:"version,omitempty"`
+	PURL       string         `json:"purl,omitempty"`
+	Hashes     []BOMHash      `json:"hashes,omitempty"`
+	Properties []BOMProperty  `json:"properties,omitempty"`
+	Components []BOMComponent `json:"components,omitempty"`
+}
+
+type BOMHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type BOMProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// BOMComposition states how complete the package list of the assembled components is
+type BOMComposition struct {
+	Aggregate  string   `json:"aggregate"`
+	Assemblies []string `json:"assemblies"`
+}
+
+// CycloneDX composition aggregates
+const (
+	AggregateComplete   = "complete"
+	AggregateIncomplete = "incomplete"
+	AggregateUnknown    = "unknown"
+)
+
+// BOMImage is an image merged into a BOM with the packages of its latest SBOM.
+// Components is nil when the SBOM could not be read
+type BOMImage struct {
+	models.SBOMScan
+	Components []models.SBOMComponent
+}
+
+// Merge builds a single CycloneDX BOM listing each image as a container component with its
+// packages nested inside. Each image gets a composition: complete for a completed scan, incomplete
+// when the scanner reported missing coverage, unknown when its SBOM could not be read
+func Merge(images []BOMImage, serialNumber string, timestamp time.Time) *BOM {
+	bom := &BOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  BOMSpecVersion,
+		SerialNumber: serialNumber,
+		Version:      1,
+		Metadata: BOMMetadata{
+			Timestamp: timestamp.UTC().Format(time.RFC3339),
+			Tools:     BOMTools{Components: []BOMComponent{{Type: "application", Name: "invulnerable"}}},
+			Properties: []BOMProperty{
+				{Name: "invulnerable:image_count", Value: strconv.Itoa(len(images))},
+			},
+		},
+		Components:   make([]BOMComponent, 0, len(images)),
+		Compositions: make([]BOMComposition, 0, len(images)),
+	}
+
+	for _, image := range images {
+		ref := fmt.Sprintf("image:%d", image.ImageID)
+		if image.Target != nil {
+			ref += "/" + *image.Target
+		}
+
+		container := BOMComponent{
+			Type:   "container",
+			BOMRef: ref,
+			Name:   image.ImageName,
+			Properties: []BOMProperty{
+				{Name: "invulnerable:image_id", Value: strconv.Itoa(image.ImageID)},
+				{Name: "invulnerable:scan_id", Value: strconv.Itoa(image.ScanID)},
+				{Name: "invulnerable:scan_date", Value: image.ScanDate.UTC().Format(time.RFC3339)},
+			},
+		}
+		if image.Target != nil {
+			container.Properties = append(container.Properties, BOMProperty{Name: "invulnerable:target", Value: *image.Target})
+		}
+		if image.Digest != nil {
+			if hex, ok := strings.CutPrefix(*image.Digest, "sha256:"); ok {
+				container.Hashes = []BOMHash{{Alg: "SHA-256", Content: hex}}
+			}
+		}
+
+		// The same package can be listed once per location it was found at
+		seen := make(map[models.SBOMComponent]bool)
+		for _, c := range image.Components {
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+			key := c.PURL
+			if key == "" {
+				key = c.Type + "/" + c.Name + "@" + c.Version
+			}
+			container.Components = append(container.Components, BOMComponent{
+				Type:    "library",
+				BOMRef:  ref + "#" + key,
+				Name:    c.Name,
+				Version: c.Version,
+				PURL:    c.PURL,
+			})
+		}
+		bom.Components = append(bom.Components, container)
+
+		aggregate := AggregateComplete
+		switch {
+		case image.Components == nil:
+			aggregate = AggregateUnknown
+		case image.Status == models.ScanStatusPartial:
+			aggregate = AggregateIncomplete
+		}
+		bom.Compositions = append(bom.Compositions, BOMComposition{Aggregate: aggregate, Assemblies: []string{ref}})
+	}
+
+	return bom
+}
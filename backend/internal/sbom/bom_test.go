@@ -0,0 +1,50 @@
+package sbom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	scanDate := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	digest := "sha256:4f1c0ffee"
+	target := "frontend"
+	log4j := models.SBOMComponent{Name: "log4j-core", Version: "2.14.1", Type: "maven", PURL: "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"}
+
+	bom := Merge([]BOMImage{
+		{
+			SBOMScan:   models.SBOMScan{ScanID: 340, ImageID: 12, ImageName: "docker.io/acme/api:1.0", Digest: &digest, Status: models.ScanStatusCompleted, ScanDate: scanDate},
+			Components: []models.SBOMComponent{log4j, log4j, {Name: "busybox", Version: "1.36.1"}},
+		},
+		{
+			SBOMScan:   models.SBOMScan{ScanID: 341, ImageID: 13, ImageName: "docker.io/acme/web:1.0", Target: &target, Status: models.ScanStatusPartial, ScanDate: scanDate},
+			Components: []models.SBOMComponent{},
+		},
+		{SBOMScan: models.SBOMScan{ScanID: 342, ImageID: 14, ImageName: "docker.io/acme/old:1.0", Status: models.ScanStatusCompleted, ScanDate: scanDate}},
+	}, "urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79", scanDate)
+
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Equal(t, "2024-01-15T10:30:00Z", bom.Metadata.Timestamp)
+	require.Len(t, bom.Components, 3)
+
+	api := bom.Components[0]
+	assert.Equal(t, "container", api.Type)
+	assert.Equal(t, "image:12", api.BOMRef)
+	assert.Equal(t, []BOMHash{{Alg: "SHA-256", Content: "4f1c0ffee"}}, api.Hashes)
+	require.Len(t, api.Components, 2, "a package listed twice is merged")
+	assert.Equal(t, "image:12#pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1", api.Components[0].BOMRef)
+	assert.Equal(t, "image:12#/busybox@1.36.1", api.Components[1].BOMRef)
+
+	assert.Equal(t, "image:13/frontend", bom.Components[1].BOMRef)
+	assert.Contains(t, bom.Components[1].Properties, BOMProperty{Name: "invulnerable:target", Value: "frontend"})
+
+	assert.Equal(t, []BOMComposition{
+		{Aggregate: AggregateComplete, Assemblies: []string{"image:12"}},
+		{Aggregate: AggregateIncomplete, Assemblies: []string{"image:13/frontend"}},
+		{Aggregate: AggregateUnknown, Assemblies: []string{"image:14"}},
+	}, bom.Compositions)
+}
@@ -0,0 +1,327 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/jsonstream"
+	"github.com/invulnerable/backend/internal/models"
+)
+
+const (
+	// An SBOM is sparse below sparseComponentsPerMB components per MB of an image of at least
+	// sparseMinImageSize: Syft found little in it, often because it doesn't know its package manager.
+	// Static binaries in small images legitimately list few packages
+	sparseComponentsPerMB = 0.1
+	sparseMinImageSize    = 50 << 20
+
+	// A component element of the NTIA minimum elements is present when this share of the components has it
+	ntiaMinimumCoverage = 0.9
+	// Below these shares of components without them, an SBOM is flagged
+	purlMinimumCoverage    = 0.9
+	licenseMinimumCoverage = 0.5
+	hashMinimumCoverage    = 0.5
+)
+
+// Points of each criterion in the score, out of 100
+const (
+	densityPoints = 30
+	purlPoints    = 25
+	licensePoints = 10
+	hashPoints    = 5
+	ntiaPoints    = 30
+)
+
+type qualityComponent struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	PURL      string `json:"purl"`
+	CPE       string `json:"cpe"`
+	Publisher string `json:"publisher"`
+	Supplier  *struct {
+		Name string `json:"name"`
+	} `json:"supplier"`
+	Licenses   []json.RawMessage  `json:"licenses"`
+	Hashes     []json.RawMessage  `json:"hashes"`
+	Components []qualityComponent `json:"components"`
+}
+
+type qualityMetadata struct {
+	Timestamp string            `json:"timestamp"`
+	Authors   []json.RawMessage `json:"authors"`
+	Tools     json.RawMessage   `json:"tools"`
+}
+
+type qualityDependency struct {
+	DependsOn []string `json:"dependsOn"`
+}
+
+type qualityPackage struct {
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo"`
+	Supplier         string            `json:"supplier"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	Checksums        []json.RawMessage `json:"checksums"`
+	ExternalRefs     []struct {
+		ReferenceType string `json:"referenceType"`
+	} `json:"externalRefs"`
+}
+
+type qualityCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type qualityRelationship struct {
+	RelationshipType string `json:"relationshipType"`
+}
+
+// tally counts the components of a document with each of the elements scored
+type tally struct {
+	components, supplier, version, uniqueID, purl, license, hash int
+	dependencies, author, timestamp                              bool
+}
+
+// EvaluateQuality scores a CycloneDX or SPDX JSON document by the PURLs, licenses and hashes of its
+// components, the NTIA minimum elements it has, and its number of components for the size of the
+// image, nil when the size is unknown. The document is read a component at a time
+func EvaluateQuality(document io.ReadSeeker, imageSizeBytes *int64) (*models.SBOMQuality, error) {
+	bomFormat, spdxVersion, err := readFormat(document)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(document)
+
+	var t tally
+	switch {
+	case strings.EqualFold(bomFormat, "CycloneDX"):
+		if err := t.readCycloneDX(dec); err != nil {
+			return nil, fmt.Errorf("invalid CycloneDX document: %w", err)
+		}
+	case spdxVersion != "":
+		if err := t.readSPDX(dec); err != nil {
+			return nil, fmt.Errorf("invalid SPDX document: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported SBOM format (expected CycloneDX or SPDX JSON)")
+	}
+	return t.quality(imageSizeBytes), nil
+}
+
+// EvaluateDocumentQuality is EvaluateQuality for a document in memory
+func EvaluateDocumentQuality(document []byte, imageSizeBytes *int64) (*models.SBOMQuality, error) {
+	return EvaluateQuality(bytes.NewReader(document), imageSizeBytes)
+}
+
+func (t *tally) readCycloneDX(dec *json.Decoder) error {
+	var walk func([]qualityComponent)
+	walk = func(list []qualityComponent) {
+		for _, c := range list {
+			if c.Name != "" {
+				supplier := c.Publisher != "" || (c.Supplier != nil && c.Supplier.Name != "")
+				t.add(supplier, c.Version != "", c.PURL != "", c.CPE != "", len(c.Licenses) > 0, len(c.Hashes) > 0)
+			}
+			walk(c.Components)
+		}
+	}
+
+	return jsonstream.Object(dec, func(key string) error {
+		switch {
+		case strings.EqualFold(key, "metadata"):
+			var metadata qualityMetadata
+			if err := dec.Decode(&metadata); err != nil {
+				return err
+			}
+			t.timestamp = metadata.Timestamp != ""
+			t.author = len(metadata.Authors) > 0 || hasTools(metadata.Tools)
+			return nil
+		case strings.EqualFold(key, "components"):
+			return jsonstream.Array(dec, func() error {
+				var c qualityComponent
+				if err := dec.Decode(&c); err != nil {
+					return err
+				}
+				walk([]qualityComponent{c})
+				return nil
+			})
+		case strings.EqualFold(key, "dependencies"):
+			return jsonstream.Array(dec, func() error {
+				var d qualityDependency
+				if err := dec.Decode(&d); err != nil {
+					return err
+				}
+				t.dependencies = t.dependencies || len(d.DependsOn) > 0
+				return nil
+			})
+		}
+		return jsonstream.Skip(dec)
+	})
+}
+
+func (t *tally) readSPDX(dec *json.Decoder) error {
+	return jsonstream.Object(dec, func(key string) error {
+		switch {
+		case strings.EqualFold(key, "creationInfo"):
+			var info qualityCreationInfo
+			if err := dec.Decode(&info); err != nil {
+				return err
+			}
+			t.timestamp = info.Created != ""
+			t.author = len(info.Creators) > 0
+			return nil
+		case strings.EqualFold(key, "packages"):
+			return jsonstream.Array(dec, func() error {
+				var p qualityPackage
+				if err := dec.Decode(&p); err != nil {
+					return err
+				}
+				if p.Name == "" {
+					return nil
+				}
+				var purl, cpe bool
+				for _, ref := range p.ExternalRefs {
+					purl = purl || ref.ReferenceType == "purl"
+					cpe = cpe || strings.HasPrefix(ref.ReferenceType, "cpe")
+				}
+				license := spdxAsserted(p.LicenseConcluded) || spdxAsserted(p.LicenseDeclared)
+				t.add(spdxAsserted(p.Supplier), p.VersionInfo != "", purl, cpe, license, len(p.Checksums) > 0)
+				return nil
+			})
+		case strings.EqualFold(key, "relationships"):
+			return jsonstream.Array(dec, func() error {
+				var r qualityRelationship
+				if err := dec.Decode(&r); err != nil {
+					return err
+				}
+				// Every document describes its packages, the others relate packages to each other
+				switch r.RelationshipType {
+				case "", "DESCRIBES", "DESCRIBED_BY":
+				default:
+					t.dependencies = true
+				}
+				return nil
+			})
+		}
+		return jsonstream.Skip(dec)
+	})
+}
+
+func (t *tally) add(supplier, version, purl, cpe, license, hash bool) {
+	t.components++
+	t.supplier += count(supplier)
+	t.version += count(version)
+	t.uniqueID += count(purl || cpe)
+	t.purl += count(purl)
+	t.license += count(license)
+	t.hash += count(hash)
+}
+
+func count(present bool) int {
+	if present {
+		return 1
+	}
+	return 0
+}
+
+// hasTools tells whether the CycloneDX tools of the metadata list any, as an array before 1.5
+// or as components and services since
+func hasTools(tools json.RawMessage) bool {
+	var list []json.RawMessage
+	if json.Unmarshal(tools, &list) == nil {
+		return len(list) > 0
+	}
+	var object struct {
+		Components []json.RawMessage `json:"components"`
+		Services   []json.RawMessage `json:"services"`
+	}
+	if json.Unmarshal(tools, &object) == nil {
+		return len(object.Components) > 0 || len(object.Services) > 0
+	}
+	return false
+}
+
+// spdxAsserted tells whether an SPDX field has a value, NOASSERTION stating it is unknown
+func spdxAsserted(value string) bool {
+	return value != "" && value != "NOASSERTION"
+}
+
+func (t *tally) coverage(n int) float64 {
+	if t.components == 0 {
+		return 0
+	}
+	return float64(n) / float64(t.components)
+}
+
+func (t *tally) quality(imageSizeBytes *int64) *models.SBOMQuality {
+	q := &models.SBOMQuality{
+		ComponentCount:  t.components,
+		PURLCoverage:    round(t.coverage(t.purl)),
+		LicenseCoverage: round(t.coverage(t.license)),
+		HashCoverage:    round(t.coverage(t.hash)),
+		NTIA: models.NTIAElements{
+			Supplier:         t.components > 0 && t.coverage(t.supplier) >= ntiaMinimumCoverage,
+			ComponentName:    t.components > 0,
+			Version:          t.components > 0 && t.coverage(t.version) >= ntiaMinimumCoverage,
+			UniqueIdentifier: t.components > 0 && t.coverage(t.uniqueID) >= ntiaMinimumCoverage,
+			Dependencies:     t.dependencies,
+			Author:           t.author,
+			Timestamp:        t.timestamp,
+		},
+		Flags: []string{},
+	}
+
+	sparse := false
+	if imageSizeBytes != nil && *imageSizeBytes > 0 {
+		perMB := float64(t.components) / (float64(*imageSizeBytes) / (1 << 20))
+		sparse = *imageSizeBytes >= sparseMinImageSize && perMB < sparseComponentsPerMB
+		perMB = round(perMB)
+		q.ComponentsPerMB = &perMB
+	}
+
+	if t.components == 0 {
+		q.Flags = append(q.Flags, models.SBOMQualityEmpty)
+		q.LowQuality = true
+		return q
+	}
+	score := float64(ntiaPoints) * float64(ntiaCount(q.NTIA)) / 7
+	if sparse {
+		q.Flags = append(q.Flags, models.SBOMQualitySparse)
+	} else {
+		score += densityPoints
+	}
+	score += purlPoints*q.PURLCoverage + licensePoints*q.LicenseCoverage + hashPoints*q.HashCoverage
+
+	if q.PURLCoverage < purlMinimumCoverage {
+		q.Flags = append(q.Flags, models.SBOMQualityMissingPURLs)
+	}
+	if q.LicenseCoverage < licenseMinimumCoverage {
+		q.Flags = append(q.Flags, models.SBOMQualityMissingLicenses)
+	}
+	if q.HashCoverage < hashMinimumCoverage {
+		q.Flags = append(q.Flags, models.SBOMQualityMissingHashes)
+	}
+	if !q.NTIA.Complete() {
+		q.Flags = append(q.Flags, models.SBOMQualityNTIAIncomplete)
+	}
+
+	q.Score = int(math.Round(score))
+	// A sparse SBOM hides vulnerabilities whatever else it lists
+	q.LowQuality = sparse || q.Score < models.SBOMQualityLowScore
+	return q
+}
+
+// ntiaCount returns the number of minimum elements present
+func ntiaCount(e models.NTIAElements) int {
+	return count(e.Supplier) + count(e.ComponentName) + count(e.Version) + count(e.UniqueIdentifier) +
+		count(e.Dependencies) + count(e.Author) + count(e.Timestamp)
+}
+
+// round keeps two decimals of a share
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
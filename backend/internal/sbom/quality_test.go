@@ -0,0 +1,102 @@
+package sbom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateQuality_CycloneDX(t *testing.T) {
+	document := `{
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.5",
+		"metadata": {"timestamp": "2026-10-01T12:00:00Z", "tools": {"components": [{"type": "application", "name": "syft"}]}},
+		"components": [
+			{"type": "library", "name": "libc6", "version": "2.36-9", "purl": "pkg:deb/debian/libc6@2.36-9",
+				"supplier": {"name": "Debian"}, "licenses": [{"license": {"id": "LGPL-2.1"}}],
+				"hashes": [{"alg": "SHA-256", "content": "ab"}]},
+			{"type": "library", "name": "app", "version": "1.0.0", "purl": "pkg:npm/app@1.0.0", "publisher": "acme",
+				"licenses": [{"license": {"id": "MIT"}}], "hashes": [{"alg": "SHA-256", "content": "cd"}],
+				"components": [{"type": "library", "name": "lodash", "version": "4.17.21", "purl": "pkg:npm/lodash@4.17.21",
+					"supplier": {"name": "lodash"}, "licenses": [{"license": {"id": "MIT"}}], "hashes": [{"alg": "SHA-1", "content": "ef"}]}]}
+		],
+		"dependencies": [{"ref": "app", "dependsOn": ["lodash"]}]
+	}`
+	size := int64(20 << 20)
+
+	quality, err := EvaluateQuality(strings.NewReader(document), &size)
+	require.NoError(t, err)
+	assert.Equal(t, 3, quality.ComponentCount)
+	assert.Equal(t, 1.0, quality.PURLCoverage)
+	assert.True(t, quality.NTIA.Complete(), "%+v", quality.NTIA)
+	assert.Equal(t, 100, quality.Score)
+	assert.False(t, quality.LowQuality)
+	assert.Empty(t, quality.Flags)
+	require.NotNil(t, quality.ComponentsPerMB)
+	assert.Equal(t, 0.15, *quality.ComponentsPerMB)
+}
+
+func TestEvaluateQuality_Sparse(t *testing.T) {
+	// Syft found two packages in a 500 MB image
+	document := `{
+		"bomFormat": "CycloneDX",
+		"metadata": {"timestamp": "2026-10-01T12:00:00Z", "tools": [{"name": "syft"}]},
+		"components": [
+			{"name": "busybox", "version": "1.36", "purl": "pkg:apk/alpine/busybox@1.36", "licenses": [{"license": {"id": "GPL-2.0"}}]},
+			{"name": "app"}
+		]
+	}`
+	size := int64(500 << 20)
+
+	quality, err := EvaluateQuality(strings.NewReader(document), &size)
+	require.NoError(t, err)
+	assert.True(t, quality.LowQuality)
+	assert.Equal(t, []string{models.SBOMQualitySparse, models.SBOMQualityMissingPURLs, models.SBOMQualityMissingHashes, models.SBOMQualityNTIAIncomplete}, quality.Flags)
+	assert.Equal(t, 0.5, quality.PURLCoverage)
+	assert.False(t, quality.NTIA.Version)
+	assert.True(t, quality.NTIA.Author)
+
+	// The same SBOM is not sparse without the size of the image
+	quality, err = EvaluateQuality(strings.NewReader(document), nil)
+	require.NoError(t, err)
+	assert.NotContains(t, quality.Flags, models.SBOMQualitySparse)
+	assert.Nil(t, quality.ComponentsPerMB)
+}
+
+func TestEvaluateQuality_SPDX(t *testing.T) {
+	document := `{
+		"spdxVersion": "SPDX-2.3",
+		"creationInfo": {"created": "2026-10-01T12:00:00Z", "creators": ["Tool: syft-1.0.0"]},
+		"packages": [
+			{"name": "openssl", "versionInfo": "3.0.11-1", "supplier": "Organization: Debian", "licenseConcluded": "NOASSERTION",
+				"licenseDeclared": "Apache-2.0", "externalRefs": [{"referenceType": "purl", "referenceLocator": "pkg:deb/debian/openssl@3.0.11-1"}]},
+			{"name": "zlib", "versionInfo": "1.3", "supplier": "NOASSERTION", "licenseConcluded": "NOASSERTION",
+				"externalRefs": [{"referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:zlib:zlib:1.3:*:*:*:*:*:*:*"}]}
+		],
+		"relationships": [
+			{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-openssl"}
+		]
+	}`
+
+	quality, err := EvaluateQuality(strings.NewReader(document), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, quality.ComponentCount)
+	assert.Equal(t, 0.5, quality.LicenseCoverage)
+	assert.Equal(t, models.NTIAElements{ComponentName: true, Version: true, UniqueIdentifier: true, Author: true, Timestamp: true}, quality.NTIA)
+	assert.Contains(t, quality.Flags, models.SBOMQualityNTIAIncomplete)
+	assert.Equal(t, 69, quality.Score)
+}
+
+func TestEvaluateQuality_Empty(t *testing.T) {
+	quality, err := EvaluateDocumentQuality([]byte(`{"bomFormat": "CycloneDX", "components": []}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, quality.Score)
+	assert.True(t, quality.LowQuality)
+	assert.Equal(t, []string{models.SBOMQualityEmpty}, quality.Flags)
+
+	_, err = EvaluateDocumentQuality([]byte(`{"packages": []}`), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,248 @@
+// Package sbom reads the package list of CycloneDX and SPDX JSON documents
+// and compares the packages of two SBOMs
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/invulnerable/backend/internal/jsonstream"
+	"github.com/invulnerable/backend/internal/models"
+)
+
+type cycloneDXComponent struct {
+	Name       string               `json:"name"`
+	Version    string               `json:"version"`
+	PURL       string               `json:"purl"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type spdxPackage struct {
+	Name         string `json:"name"`
+	VersionInfo  string `json:"versionInfo"`
+	ExternalRefs []struct {
+		ReferenceType    string `json:"referenceType"`
+		ReferenceLocator string `json:"referenceLocator"`
+	} `json:"externalRefs"`
+}
+
+// ParseComponents returns the packages listed in a CycloneDX or SPDX JSON document.
+// The format is detected from the document, since older scans may not have recorded it
+func ParseComponents(document []byte) ([]models.SBOMComponent, error) {
+	return ReadComponents(bytes.NewReader(document))
+}
+
+// ReadComponents is ParseComponents reading the document one package at a time, so only the
+// packages are held in memory. The document is read twice, the format may come after the packages
+func ReadComponents(document io.ReadSeeker) ([]models.SBOMComponent, error) {
+	bomFormat, spdxVersion, err := readFormat(document)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(document)
+
+	switch {
+	case strings.EqualFold(bomFormat, "CycloneDX"):
+		components := []models.SBOMComponent{}
+		var walk func([]cycloneDXComponent)
+		walk = func(list []cycloneDXComponent) {
+			for _, c := range list {
+				if c.Name != "" {
+					components = append(components, newComponent(c.Name, c.Version, c.PURL))
+				}
+				// Nested components are packages vendored inside their parent
+				walk(c.Components)
+			}
+		}
+		err := readList(dec, "components", func() error {
+			var c cycloneDXComponent
+			if err := dec.Decode(&c); err != nil {
+				return err
+			}
+			walk([]cycloneDXComponent{c})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid CycloneDX document: %w", err)
+		}
+		return components, nil
+
+	case spdxVersion != "":
+		components := []models.SBOMComponent{}
+		err := readList(dec, "packages", func() error {
+			var p spdxPackage
+			if err := dec.Decode(&p); err != nil {
+				return err
+			}
+			if p.Name == "" {
+				return nil
+			}
+			var purl string
+			for _, ref := range p.ExternalRefs {
+				if ref.ReferenceType == "purl" {
+					purl = ref.ReferenceLocator
+					break
+				}
+			}
+			components = append(components, newComponent(p.Name, p.VersionInfo, purl))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid SPDX document: %w", err)
+		}
+		return components, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported SBOM format (expected CycloneDX or SPDX JSON)")
+	}
+}
+
+// readFormat returns the bomFormat of a CycloneDX document or the spdxVersion of an SPDX one,
+// and rewinds the document to be read again
+func readFormat(document io.ReadSeeker) (bomFormat, spdxVersion string, err error) {
+	dec := json.NewDecoder(document)
+	err = jsonstream.Object(dec, func(key string) error {
+		switch {
+		case strings.EqualFold(key, "bomFormat"):
+			return dec.Decode(&bomFormat)
+		case strings.EqualFold(key, "spdxVersion"):
+			return dec.Decode(&spdxVersion)
+		}
+		return jsonstream.Skip(dec)
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("invalid SBOM document: %w", err)
+	}
+	if _, err := document.Seek(0, io.SeekStart); err != nil {
+		return "", "", fmt.Errorf("failed to rewind SBOM document: %w", err)
+	}
+	return bomFormat, spdxVersion, nil
+}
+
+// readList calls element for each element of the top-level list of the document named key
+func readList(dec *json.Decoder, key string, element func() error) error {
+	return jsonstream.Object(dec, func(k string) error {
+		if strings.EqualFold(k, key) {
+			return jsonstream.Array(dec, element)
+		}
+		return jsonstream.Skip(dec)
+	})
+}
+
+func newComponent(name, version, purl string) models.SBOMComponent {
+	component := models.SBOMComponent{Name: name, Version: version, PURL: purl}
+	if rest, ok := strings.CutPrefix(purl, "pkg:"); ok {
+		component.Type, _, _ = strings.Cut(rest, "/")
+	}
+	return component
+}
+
+// packageKey identifies a package across versions: the purl without version and qualifiers,
+// or the type and name when the SBOM has no purl
+func packageKey(c models.SBOMComponent) string {
+	if c.PURL != "" {
+		key := c.PURL
+		if i := strings.IndexAny(key, "@?#"); i >= 0 {
+			key = key[:i]
+		}
+		return key
+	}
+	return c.Type + "/" + c.Name
+}
+
+// Compare returns the packages added, removed and changed from previous to current.
+// A package can be installed in several versions (e.g. nested npm dependencies): versions present
+// on both sides are unchanged, and the remaining ones are paired in version order as changes
+func Compare(previous, current []models.SBOMComponent) *models.SBOMDiff {
+	prevByKey := groupByKey(previous)
+	currByKey := groupByKey(current)
+
+	diff := &models.SBOMDiff{
+		Added:    []models.SBOMComponent{},
+		Removed:  []models.SBOMComponent{},
+		Upgraded: []models.SBOMComponentChange{},
+	}
+
+	keys := make([]string, 0, len(prevByKey)+len(currByKey))
+	for key := range prevByKey {
+		keys = append(keys, key)
+	}
+	for key := range currByKey {
+		if _, ok := prevByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		removed, added, unchanged := splitVersions(prevByKey[key], currByKey[key])
+		diff.Summary.UnchangedCount += unchanged
+
+		paired := min(len(removed), len(added))
+		for i := 0; i < paired; i++ {
+			diff.Upgraded = append(diff.Upgraded, models.SBOMComponentChange{
+				Name:            added[i].Name,
+				Type:            added[i].Type,
+				PURL:            added[i].PURL,
+				PreviousVersion: removed[i].Version,
+				Version:         added[i].Version,
+			})
+		}
+		diff.Removed = append(diff.Removed, removed[paired:]...)
+		diff.Added = append(diff.Added, added[paired:]...)
+	}
+
+	diff.Summary.AddedCount = len(diff.Added)
+	diff.Summary.RemovedCount = len(diff.Removed)
+	diff.Summary.UpgradedCount = len(diff.Upgraded)
+	return diff
+}
+
+func groupByKey(components []models.SBOMComponent) map[string][]models.SBOMComponent {
+	grouped := make(map[string][]models.SBOMComponent)
+	seen := make(map[string]bool)
+	for _, c := range components {
+		key := packageKey(c)
+		// The same package can be listed once per location it was found at
+		if seen[key+"@"+c.Version] {
+			continue
+		}
+		seen[key+"@"+c.Version] = true
+		grouped[key] = append(grouped[key], c)
+	}
+	return grouped
+}
+
+// splitVersions returns the versions only in previous, the versions only in current,
+// both sorted, and the number of versions in both
+func splitVersions(previous, current []models.SBOMComponent) (removed, added []models.SBOMComponent, unchanged int) {
+	currVersions := make(map[string]bool, len(current))
+	for _, c := range current {
+		currVersions[c.Version] = true
+	}
+	prevVersions := make(map[string]bool, len(previous))
+	for _, c := range previous {
+		prevVersions[c.Version] = true
+		if currVersions[c.Version] {
+			unchanged++
+		} else {
+			removed = append(removed, c)
+		}
+	}
+	for _, c := range current {
+		if !prevVersions[c.Version] {
+			added = append(added, c)
+		}
+	}
+
+	byVersion := func(list []models.SBOMComponent) {
+		sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	}
+	byVersion(removed)
+	byVersion(added)
+	return removed, added, unchanged
+}
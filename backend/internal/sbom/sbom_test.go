@@ -0,0 +1,105 @@
+package sbom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseComponents_CycloneDX(t *testing.T) {
+	document := []byte(`{
+		"bomFormat": "CycloneDX",
+		"specVersion": "1.5",
+		"components": [
+			{"type": "library", "name": "libc6", "version": "2.36-9", "purl": "pkg:deb/debian/libc6@2.36-9?arch=amd64"},
+			{"type": "library", "name": "app", "version": "1.0.0", "purl": "pkg:npm/app@1.0.0",
+				"components": [{"type": "library", "name": "lodash", "version": "4.17.21", "purl": "pkg:npm/lodash@4.17.21"}]},
+			{"type": "file", "name": ""}
+		]
+	}`)
+
+	components, err := ParseComponents(document)
+	require.NoError(t, err)
+	require.Len(t, components, 3)
+	assert.Equal(t, models.SBOMComponent{Name: "libc6", Version: "2.36-9", Type: "deb", PURL: "pkg:deb/debian/libc6@2.36-9?arch=amd64"}, components[0])
+	assert.Equal(t, "lodash", components[2].Name)
+	assert.Equal(t, "npm", components[2].Type)
+}
+
+func TestParseComponents_SPDX(t *testing.T) {
+	document := []byte(`{
+		"spdxVersion": "SPDX-2.3",
+		"packages": [
+			{"name": "openssl", "versionInfo": "3.0.11-1", "externalRefs": [
+				{"referenceCategory": "SECURITY", "referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:openssl:openssl:3.0.11:*:*:*:*:*:*:*"},
+				{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:deb/debian/openssl@3.0.11-1"}
+			]},
+			{"name": "alpine:3.19"}
+		]
+	}`)
+
+	components, err := ParseComponents(document)
+	require.NoError(t, err)
+	require.Len(t, components, 2)
+	assert.Equal(t, "pkg:deb/debian/openssl@3.0.11-1", components[0].PURL)
+	assert.Equal(t, "deb", components[0].Type)
+	assert.Equal(t, "3.0.11-1", components[0].Version)
+	assert.Empty(t, components[1].PURL)
+}
+
+func TestReadComponents_FormatAfterPackages(t *testing.T) {
+	document := `{"packages": [{"name": "zlib", "versionInfo": "1.3"}], "SPDXID": "SPDXRef-DOCUMENT", "spdxVersion": "SPDX-2.3"}`
+
+	components, err := ReadComponents(strings.NewReader(document))
+	require.NoError(t, err)
+	assert.Equal(t, []models.SBOMComponent{{Name: "zlib", Version: "1.3"}}, components)
+}
+
+func TestParseComponents_Invalid(t *testing.T) {
+	for _, document := range []string{`not json`, `{"components": []}`} {
+		_, err := ParseComponents([]byte(document))
+		assert.Error(t, err, document)
+	}
+	_, err := ParseComponents([]byte(`{"bomFormat": "CycloneDX", "components": [{"name": 1}]}`))
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	previous := []models.SBOMComponent{
+		{Name: "libc6", Version: "2.36-9", Type: "deb", PURL: "pkg:deb/debian/libc6@2.36-9?arch=amd64"},
+		{Name: "openssl", Version: "3.0.11-1", Type: "deb", PURL: "pkg:deb/debian/openssl@3.0.11-1"},
+		{Name: "lodash", Version: "4.17.20", Type: "npm", PURL: "pkg:npm/lodash@4.17.20"},
+		{Name: "lodash", Version: "3.10.1", Type: "npm", PURL: "pkg:npm/lodash@3.10.1"},
+		{Name: "curl", Version: "7.88.1"},
+	}
+	current := []models.SBOMComponent{
+		{Name: "libc6", Version: "2.36-9", Type: "deb", PURL: "pkg:deb/debian/libc6@2.36-9?arch=amd64"},
+		// Listed twice (two locations), counted once
+		{Name: "libc6", Version: "2.36-9", Type: "deb", PURL: "pkg:deb/debian/libc6@2.36-9?arch=amd64"},
+		{Name: "openssl", Version: "3.0.13-1", Type: "deb", PURL: "pkg:deb/debian/openssl@3.0.13-1"},
+		{Name: "lodash", Version: "4.17.21", Type: "npm", PURL: "pkg:npm/lodash@4.17.21"},
+		{Name: "express", Version: "4.19.2", Type: "npm", PURL: "pkg:npm/express@4.19.2"},
+	}
+
+	diff := Compare(previous, current)
+
+	require.Len(t, diff.Upgraded, 2)
+	assert.Equal(t, "openssl", diff.Upgraded[0].Name)
+	assert.Equal(t, "3.0.11-1", diff.Upgraded[0].PreviousVersion)
+	assert.Equal(t, "3.0.13-1", diff.Upgraded[0].Version)
+	assert.Equal(t, "lodash", diff.Upgraded[1].Name)
+	assert.Equal(t, "3.10.1", diff.Upgraded[1].PreviousVersion)
+	assert.Equal(t, "4.17.21", diff.Upgraded[1].Version)
+
+	require.Len(t, diff.Added, 1)
+	assert.Equal(t, "express", diff.Added[0].Name)
+
+	require.Len(t, diff.Removed, 2)
+	assert.Equal(t, "curl", diff.Removed[0].Name)
+	assert.Equal(t, "4.17.20", diff.Removed[1].Version)
+
+	assert.Equal(t, models.SBOMDiffSummary{AddedCount: 1, RemovedCount: 2, UpgradedCount: 2, UnchangedCount: 1}, diff.Summary)
+}
@@ -0,0 +1,165 @@
+package sbom
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// VersionRange is a comma-separated list of constraints that must all hold,
+// such as ">=2.0.0, <2.15.0"
+type VersionRange []versionConstraint
+
+type versionConstraint struct {
+	op      string
+	version string
+}
+
+// Operators, longest first so ">=" isn't read as ">"
+var rangeOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// ParseVersionRange parses a version range. A bare version means that exact version
+func ParseVersionRange(expr string) (VersionRange, error) {
+	var r VersionRange
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		c := versionConstraint{op: "="}
+		for _, op := range rangeOperators {
+			if rest, ok := strings.CutPrefix(part, op); ok {
+				c.op, part = op, strings.TrimSpace(rest)
+				break
+			}
+		}
+		if c.op == "==" {
+			c.op = "="
+		}
+		if part == "" || strings.ContainsAny(part, " <>=!") {
+			return nil, fmt.Errorf("invalid version constraint %q", strings.TrimSpace(c.op+part))
+		}
+		c.version = part
+		r = append(r, c)
+	}
+	if len(r) == 0 {
+		return nil, fmt.Errorf("empty version range")
+	}
+	return r, nil
+}
+
+// Contains reports whether a version satisfies every constraint of the range
+func (r VersionRange) Contains(version string) bool {
+	for _, c := range r {
+		cmp := CompareVersions(version, c.version)
+		var ok bool
+		switch c.op {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		case "!=":
+			ok = cmp != 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareVersions orders versions of any ecosystem well enough for range checks: versions are
+// split into numeric and alphabetic segments, numbers compare numerically and words lexically.
+// A leading "v" and an epoch ("1:") are ignored, and a pre-release suffix (2.0.0-rc1) sorts
+// before the release
+func CompareVersions(a, b string) int {
+	sa, sb := versionSegments(a), versionSegments(b)
+	for i := 0; i < len(sa) || i < len(sb); i++ {
+		switch {
+		case i >= len(sa):
+			return -releaseOrder(sb[i])
+		case i >= len(sb):
+			return releaseOrder(sa[i])
+		}
+		if c := compareSegment(sa[i], sb[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// releaseOrder is how a version compares to the same version without its extra segment:
+// 2.0.0.1 is after 2.0.0, 2.0.0-rc1 before it
+func releaseOrder(extra string) int {
+	if isNumeric(extra) {
+		return 1
+	}
+	return -1
+}
+
+func compareSegment(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		// Compared as digit strings, which can't overflow like parsed integers
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			return cmp.Compare(len(a), len(b))
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		// 2.0.0.1 is after 2.0.0-rc1
+		return 1
+	case bNum:
+		return -1
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func versionSegments(version string) []string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if _, rest, ok := strings.Cut(version, ":"); ok {
+		version = rest
+	}
+
+	var segments []string
+	var current strings.Builder
+	currentNumeric := false
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range version {
+		switch {
+		case unicode.IsDigit(r):
+			if !currentNumeric {
+				flush()
+			}
+			currentNumeric = true
+			current.WriteRune(r)
+		case unicode.IsLetter(r):
+			if currentNumeric {
+				flush()
+			}
+			currentNumeric = false
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return segments
+}
+
+func isNumeric(segment string) bool {
+	return segment != "" && unicode.IsDigit(rune(segment[0]))
+}
@@ -0,0 +1,54 @@
+package sbom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.14.1", "2.15.0", -1},
+		{"2.15.0", "2.15.0", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"2.10.0", "2.9.9", 1},
+		{"2.0.0-rc1", "2.0.0", -1},
+		{"2.0.0", "2.0.0.1", -1},
+		{"2.36-9", "2.36-10", -1},
+		{"1:2.0", "2.0", 0},
+		{"1.1.1n", "1.1.1w", -1},
+		{"3.0.0-beta1", "3.0.0-alpha2", 1},
+		{"99999999999999999999999", "1", 1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CompareVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+		assert.Equal(t, -tt.want, CompareVersions(tt.b, tt.a), "%s vs %s", tt.b, tt.a)
+	}
+}
+
+func TestVersionRange(t *testing.T) {
+	r, err := ParseVersionRange(">=2.0.0-beta9, <2.15.0")
+	require.NoError(t, err)
+
+	assert.True(t, r.Contains("2.14.1"))
+	assert.True(t, r.Contains("2.0.0"))
+	assert.False(t, r.Contains("2.15.0"))
+	assert.False(t, r.Contains("1.2.17"))
+
+	exact, err := ParseVersionRange("4.17.20")
+	require.NoError(t, err)
+	assert.True(t, exact.Contains("4.17.20"))
+	assert.False(t, exact.Contains("4.17.21"))
+}
+
+func TestParseVersionRange_Invalid(t *testing.T) {
+	for _, expr := range []string{"", " , ", ">=", "<2.0 >1.0", "=>2.0"} {
+		_, err := ParseVersionRange(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,269 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+)
+
+// Options controls the size and shape of the generated dataset
+type Options struct {
+	Images           int       // Number of images to generate
+	Days             int       // How far back the scan history goes
+	ScanIntervalDays int       // Days between two scans of the same image
+	Seed             uint64    // Same seed and options always produce the same dataset
+	End              time.Time // Date of the most recent scan
+}
+
+// DefaultOptions returns a dataset of roughly the size of a small cluster
+func DefaultOptions() Options {
+	return Options{
+		Images:           20,
+		Days:             180,
+		ScanIntervalDays: 1,
+		Seed:             42,
+		End:              time.Now().UTC().Truncate(time.Hour),
+	}
+}
+
+// ImageHistory is the generated scan history of one image
+type ImageHistory struct {
+	Image  models.Image
+	Scans  []ScanSnapshot
+	Triage []TriageEvent
+}
+
+// ScanSnapshot is the set of vulnerabilities an image had at a scan date
+type ScanSnapshot struct {
+	Date            time.Time
+	Vulnerabilities []models.Vulnerability
+}
+
+// TriageEvent is a manual status change applied once the history is loaded
+type TriageEvent struct {
+	CVEID          string
+	PackageName    string
+	PackageVersion string
+	Status         string
+	Notes          string
+}
+
+type catalogPackage struct {
+	name     string
+	pkgType  string
+	versions []string
+}
+
+// Package catalog shared by all images so the same CVE shows up across the fleet
+var catalog = []catalogPackage{
+	{"openssl", "deb", []string{"1.1.1n-0+deb11u4", "1.1.1n-0+deb11u5", "3.0.11-1~deb12u1", "3.0.11-1~deb12u2"}},
+	{"libssl3", "deb", []string{"3.0.9-1", "3.0.11-1~deb12u1", "3.0.13-1~deb12u1"}},
+	{"zlib1g", "deb", []string{"1:1.2.11.dfsg-2+deb11u2", "1:1.2.13.dfsg-1"}},
+	{"libc6", "deb", []string{"2.31-13+deb11u5", "2.36-9+deb12u3", "2.36-9+deb12u4"}},
+	{"curl", "deb", []string{"7.74.0-1.3+deb11u7", "7.88.1-10+deb12u4", "7.88.1-10+deb12u5"}},
+	{"libxml2", "deb", []string{"2.9.10+dfsg-6.7+deb11u4", "2.9.14+dfsg-1.3"}},
+	{"perl-base", "deb", []string{"5.32.1-4+deb11u2", "5.36.0-7+deb12u1"}},
+	{"busybox", "apk", []string{"1.36.1-r2", "1.36.1-r5"}},
+	{"musl", "apk", []string{"1.2.4-r1", "1.2.4-r2"}},
+	{"libcrypto3", "apk", []string{"3.1.2-r0", "3.1.4-r0", "3.1.4-r5"}},
+	{"golang.org/x/net", "go-module", []string{"v0.7.0", "v0.15.0", "v0.17.0"}},
+	{"golang.org/x/crypto", "go-module", []string{"v0.14.0", "v0.16.0"}},
+	{"google.golang.org/grpc", "go-module", []string{"v1.56.2", "v1.58.2"}},
+	{"stdlib", "go-module", []string{"go1.20.5", "go1.21.3", "go1.21.6"}},
+	{"lodash", "npm", []string{"4.17.15", "4.17.20"}},
+	{"express", "npm", []string{"4.17.1", "4.18.2"}},
+	{"semver", "npm", []string{"5.7.1", "7.5.1"}},
+	{"axios", "npm", []string{"0.21.1", "1.5.1"}},
+	{"requests", "python", []string{"2.25.1", "2.31.0"}},
+	{"urllib3", "python", []string{"1.26.5", "1.26.17"}},
+	{"django", "python", []string{"3.2.20", "4.2.7"}},
+	{"jackson-databind", "java-archive", []string{"2.12.6", "2.13.4"}},
+	{"log4j-core", "java-archive", []string{"2.14.1", "2.17.0"}},
+	{"spring-web", "java-archive", []string{"5.3.18", "5.3.27"}},
+}
+
+var imageCatalog = []struct {
+	registry   string
+	repository string
+	tags       []string
+}{
+	{"docker.io", "library/nginx", []string{"1.25", "1.24", "latest"}},
+	{"docker.io", "library/redis", []string{"7.2", "7.0"}},
+	{"docker.io", "library/postgres", []string{"16", "15"}},
+	{"docker.io", "library/node", []string{"20-alpine", "18"}},
+	{"docker.io", "library/python", []string{"3.12-slim", "3.11"}},
+	{"ghcr.io", "acme/payments-api", []string{"v2.4.1", "v2.3.0"}},
+	{"ghcr.io", "acme/checkout-web", []string{"v1.9.0"}},
+	{"ghcr.io", "acme/inventory-worker", []string{"v0.14.2"}},
+	{"quay.io", "prometheus/prometheus", []string{"v2.48.0"}},
+	{"registry.k8s.io", "ingress-nginx/controller", []string{"v1.9.4"}},
+}
+
+// Severity distribution roughly matching what Grype reports on typical images
+var severityWeights = []struct {
+	severity string
+	weight   int
+}{
+	{"Critical", 5},
+	{"High", 20},
+	{"Medium", 40},
+	{"Low", 25},
+	{"Negligible", 7},
+	{"Unknown", 3},
+}
+
+var triageStatuses = []struct {
+	status string
+	notes  string
+}{
+	{models.StatusInProgress, "Upgrade scheduled for next sprint"},
+	{models.StatusAccepted, "Risk accepted: component not reachable from network"},
+	{models.StatusIgnored, "False positive: vulnerable code path not used"},
+}
+
+// Generate builds a reproducible scan history for opts.Images images
+func Generate(opts Options) []ImageHistory {
+	r := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+
+	pool := buildPool(r, opts.Images*25+50)
+
+	interval := opts.ScanIntervalDays
+	if interval <= 0 {
+		interval = 1
+	}
+	start := opts.End.AddDate(0, 0, -opts.Days)
+
+	histories := make([]ImageHistory, 0, opts.Images)
+	for i := 0; i < opts.Images; i++ {
+		histories = append(histories, generateImage(r, i, pool, start, opts.End, interval))
+	}
+	return histories
+}
+
+// buildPool creates the CVEs images can be affected by
+func buildPool(r *rand.Rand, size int) []models.Vulnerability {
+	pool := make([]models.Vulnerability, 0, size)
+	for i := 0; i < size; i++ {
+		pkg := catalog[r.IntN(len(catalog))]
+		versionIdx := r.IntN(len(pkg.versions))
+		cveID := fmt.Sprintf("CVE-%d-%d", 2019+r.IntN(6), 10000+i)
+		severity := pickSeverity(r)
+		url := "https://nvd.nist.gov/vuln/detail/" + cveID
+		description := fmt.Sprintf("%s vulnerability in %s %s", severity, pkg.name, pkg.versions[versionIdx])
+		pkgType := pkg.pkgType
+
+		vuln := models.Vulnerability{
+			CVEID:          cveID,
+			PackageName:    pkg.name,
+			PackageVersion: pkg.versions[versionIdx],
+			PackageType:    &pkgType,
+			Severity:       severity,
+			URL:            &url,
+			Description:    &description,
+		}
+
+		// Most vulnerabilities eventually get a fix, usually the next catalog version
+		if versionIdx < len(pkg.versions)-1 && r.IntN(10) < 7 {
+			fix := pkg.versions[versionIdx+1]
+			vuln.FixVersion = &fix
+		}
+
+		pool = append(pool, vuln)
+	}
+	return pool
+}
+
+func pickSeverity(r *rand.Rand) string {
+	total := 0
+	for _, w := range severityWeights {
+		total += w.weight
+	}
+	n := r.IntN(total)
+	for _, w := range severityWeights {
+		if n < w.weight {
+			return w.severity
+		}
+		n -= w.weight
+	}
+	return "Unknown"
+}
+
+func generateImage(r *rand.Rand, index int, pool []models.Vulnerability, start, end time.Time, interval int) ImageHistory {
+	entry := imageCatalog[index%len(imageCatalog)]
+	tag := entry.tags[(index/len(imageCatalog))%len(entry.tags)]
+	repository := entry.repository
+	// Keep images unique once the catalog is exhausted
+	if round := index / (len(imageCatalog) * len(entry.tags)); round > 0 {
+		repository = fmt.Sprintf("%s-%d", repository, round)
+	}
+
+	history := ImageHistory{
+		Image: models.Image{
+			Registry:   entry.registry,
+			Repository: repository,
+			Tag:        tag,
+		},
+	}
+
+	// Indexes into pool, kept sorted so snapshots are stable
+	active := sample(r, len(pool), 5+r.IntN(36))
+
+	for date := start; !date.After(end); date = date.AddDate(0, 0, interval) {
+		// Scans don't run exactly on the hour
+		scanDate := date.Add(time.Duration(r.IntN(60)) * time.Minute)
+
+		next := active[:0:0]
+		for _, idx := range active {
+			fixChance := 2
+			if pool[idx].FixVersion != nil {
+				fixChance = 8
+			}
+			if r.IntN(100) >= fixChance {
+				next = append(next, idx)
+			}
+		}
+		for n := r.IntN(3); n > 0; n-- {
+			idx := r.IntN(len(pool))
+			if !slices.Contains(next, idx) {
+				next = append(next, idx)
+			}
+		}
+		slices.Sort(next)
+		active = next
+
+		snapshot := ScanSnapshot{Date: scanDate, Vulnerabilities: make([]models.Vulnerability, 0, len(active))}
+		for _, idx := range active {
+			snapshot.Vulnerabilities = append(snapshot.Vulnerabilities, pool[idx])
+		}
+		history.Scans = append(history.Scans, snapshot)
+	}
+
+	// Triage a few of the vulnerabilities still present at the last scan
+	for _, idx := range active {
+		if r.IntN(10) == 0 {
+			t := triageStatuses[r.IntN(len(triageStatuses))]
+			history.Triage = append(history.Triage, TriageEvent{
+				CVEID:          pool[idx].CVEID,
+				PackageName:    pool[idx].PackageName,
+				PackageVersion: pool[idx].PackageVersion,
+				Status:         t.status,
+				Notes:          t.notes,
+			})
+		}
+	}
+
+	return history
+}
+
+// sample returns k distinct sorted indexes in [0, n)
+func sample(r *rand.Rand, n, k int) []int {
+	if k > n {
+		k = n
+	}
+	picked := r.Perm(n)[:k]
+	slices.Sort(picked)
+	return picked
+}
@@ -0,0 +1,86 @@
+package seed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOptions() Options {
+	return Options{
+		Images:           25,
+		Days:             30,
+		ScanIntervalDays: 1,
+		Seed:             7,
+		End:              time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestGenerate_Reproducible(t *testing.T) {
+	first := Generate(testOptions())
+	second := Generate(testOptions())
+
+	assert.Equal(t, first, second)
+
+	other := testOptions()
+	other.Seed = 8
+	assert.NotEqual(t, first, Generate(other))
+}
+
+func TestGenerate_Shape(t *testing.T) {
+	opts := testOptions()
+	histories := Generate(opts)
+	require.Len(t, histories, opts.Images)
+
+	names := make(map[string]bool)
+	for _, history := range histories {
+		name := history.Image.FullName()
+		assert.False(t, names[name], "duplicate image %s", name)
+		names[name] = true
+
+		// One scan per interval, both ends included
+		require.Len(t, history.Scans, opts.Days+1)
+		for i := 1; i < len(history.Scans); i++ {
+			assert.True(t, history.Scans[i].Date.After(history.Scans[i-1].Date))
+		}
+		assert.False(t, history.Scans[len(history.Scans)-1].Date.Before(opts.End))
+
+		for _, snapshot := range history.Scans {
+			keys := make(map[string]bool)
+			for _, vuln := range snapshot.Vulnerabilities {
+				key := vulnKey(vuln.CVEID, vuln.PackageName, vuln.PackageVersion)
+				assert.False(t, keys[key], "duplicate vulnerability %s in one scan", key)
+				keys[key] = true
+				assert.Contains(t, []string{"Critical", "High", "Medium", "Low", "Negligible", "Unknown"}, vuln.Severity)
+			}
+		}
+
+		for _, event := range history.Triage {
+			assert.Contains(t, models.ValidStatuses, event.Status)
+		}
+	}
+}
+
+func TestGenerate_VulnerabilitiesGetFixed(t *testing.T) {
+	histories := Generate(testOptions())
+
+	fixed := 0
+	for _, history := range histories {
+		for i := 1; i < len(history.Scans); i++ {
+			current := make(map[string]bool)
+			for _, vuln := range history.Scans[i].Vulnerabilities {
+				current[vulnKey(vuln.CVEID, vuln.PackageName, vuln.PackageVersion)] = true
+			}
+			for _, vuln := range history.Scans[i-1].Vulnerabilities {
+				if !current[vulnKey(vuln.CVEID, vuln.PackageName, vuln.PackageVersion)] {
+					fixed++
+				}
+			}
+		}
+	}
+
+	assert.Greater(t, fixed, 0, "history should contain fix events")
+}
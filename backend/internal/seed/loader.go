@@ -0,0 +1,140 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/invulnerable/backend/internal/analyzer"
+	"github.com/invulnerable/backend/internal/db"
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	seedSyftVersion  = "1.18.1"
+	seedGrypeVersion = "0.86.1"
+	seedUser         = "seed"
+)
+
+// Stats summarizes what a Load call wrote
+type Stats struct {
+	Images          int
+	Scans           int
+	Vulnerabilities int
+	TriageEvents    int
+}
+
+// Loader writes generated histories through the same repositories the API uses,
+// so the resulting data goes through the regular diff and auto-fix logic
+type Loader struct {
+	logger    *zap.Logger
+	imageRepo *db.ImageRepository
+	scanRepo  *db.ScanRepository
+	vulnRepo  *db.VulnerabilityRepository
+	analyzer  *analyzer.Analyzer
+}
+
+func NewLoader(logger *zap.Logger, database *db.Database) *Loader {
+	scanRepo := db.NewScanRepository(database)
+	vulnRepo := db.NewVulnerabilityRepository(database)
+	return &Loader{
+		logger:    logger,
+		imageRepo: db.NewImageRepository(database),
+		scanRepo:  scanRepo,
+		vulnRepo:  vulnRepo,
+		analyzer:  analyzer.NewWithRepositories(scanRepo, vulnRepo),
+	}
+}
+
+// Load writes every history in order, scans oldest first
+func (l *Loader) Load(ctx context.Context, histories []ImageHistory) (*Stats, error) {
+	stats := &Stats{}
+	vulnIDs := make(map[string]int)
+
+	for _, history := range histories {
+		image := history.Image
+		if err := l.imageRepo.Create(ctx, &image); err != nil {
+			return stats, fmt.Errorf("failed to create image %s: %w", image.FullName(), err)
+		}
+		stats.Images++
+
+		for _, snapshot := range history.Scans {
+			if err := l.loadScan(ctx, image.ID, snapshot, vulnIDs); err != nil {
+				return stats, fmt.Errorf("failed to load scan for %s: %w", image.FullName(), err)
+			}
+			stats.Scans++
+		}
+
+		imageName := image.FullName()
+		for _, event := range history.Triage {
+			id, ok := vulnIDs[vulnKey(event.CVEID, event.PackageName, event.PackageVersion)]
+			if !ok {
+				continue
+			}
+			status := event.Status
+			notes := event.Notes
+			update := &models.VulnerabilityUpdateWithContext{
+				Status:    &status,
+				Notes:     &notes,
+				UpdatedBy: seedUser,
+				ImageID:   &image.ID,
+				ImageName: &imageName,
+			}
+			if err := l.vulnRepo.Update(ctx, id, update); err != nil {
+				return stats, fmt.Errorf("failed to triage %s: %w", event.CVEID, err)
+			}
+			stats.TriageEvents++
+		}
+
+		l.logger.Info("seeded image",
+			zap.String("image", imageName),
+			zap.Int("scans", len(history.Scans)))
+	}
+
+	stats.Vulnerabilities = len(vulnIDs)
+	return stats, nil
+}
+
+func (l *Loader) loadScan(ctx context.Context, imageID int, snapshot ScanSnapshot, vulnIDs map[string]int) error {
+	syftVersion := seedSyftVersion
+	grypeVersion := seedGrypeVersion
+	scan := &models.Scan{
+		ImageID:      imageID,
+		ScanDate:     snapshot.Date,
+		SyftVersion:  &syftVersion,
+		GrypeVersion: &grypeVersion,
+		Status:       models.ScanStatusCompleted,
+		SLACritical:  7,
+		SLAHigh:      30,
+		SLAMedium:    90,
+		SLALow:       180,
+	}
+	if err := l.scanRepo.Create(ctx, scan); err != nil {
+		return fmt.Errorf("failed to create scan: %w", err)
+	}
+
+	for _, template := range snapshot.Vulnerabilities {
+		vuln := template
+		vuln.Status = models.StatusActive
+		vuln.FirstDetectedAt = snapshot.Date
+		vuln.LastSeenAt = snapshot.Date
+		if err := l.vulnRepo.Upsert(ctx, &vuln); err != nil {
+			return fmt.Errorf("failed to upsert vulnerability %s: %w", vuln.CVEID, err)
+		}
+		if err := l.vulnRepo.LinkToScan(ctx, scan.ID, vuln.ID); err != nil {
+			return fmt.Errorf("failed to link vulnerability %s: %w", vuln.CVEID, err)
+		}
+		vulnIDs[vulnKey(vuln.CVEID, vuln.PackageName, vuln.PackageVersion)] = vuln.ID
+	}
+
+	// Marks vulnerabilities gone since the previous scan as fixed, like CreateScan does
+	if _, err := l.analyzer.CompareScan(ctx, scan.ID); err != nil {
+		return fmt.Errorf("failed to compare scan: %w", err)
+	}
+
+	return nil
+}
+
+func vulnKey(cveID, packageName, packageVersion string) string {
+	return cveID + ":" + packageName + ":" + packageVersion
+}
@@ -0,0 +1,140 @@
+// Package sla computes remediation deadlines. SLAs are counted in days of the team's timezone:
+// a vulnerability found on Monday at 23:30 in Berlin with a 7-day SLA is due by the end of the next
+// Monday in Berlin, even though it was already Tuesday in UTC when it was found. Days are calendar
+// days, or business days with a holiday calendar
+package sla
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultTimeZone is used for scans that don't specify a timezone
+const DefaultTimeZone = "UTC"
+
+// dateLayout is the format of due dates
+const dateLayout = "2006-01-02"
+
+// LoadLocation returns the location of an IANA timezone name such as "Europe/Berlin".
+// An empty name is DefaultTimeZone
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// Days returns the SLA of a severity. Severities without their own SLA (Negligible, Unknown) get the Low one
+func Days(severity string, critical, high, medium, low int) int {
+	switch severity {
+	case "Critical":
+		return critical
+	case "High":
+		return high
+	case "Medium":
+		return medium
+	default:
+		return low
+	}
+}
+
+// Severity policies, the severity of a vulnerability its SLA is counted from
+const (
+	// SeverityAtDetection is the severity the vulnerability had when first detected, so a scanner
+	// rescoring the CVE doesn't move its deadline
+	SeverityAtDetection = "detection"
+	// SeverityCurrent is the severity of the latest scan
+	SeverityCurrent = "current"
+)
+
+// IsSeverityPolicy reports whether policy is SeverityAtDetection or SeverityCurrent
+func IsSeverityPolicy(policy string) bool {
+	return policy == SeverityAtDetection || policy == SeverityCurrent
+}
+
+// Severity returns the severity the SLA of a vulnerability is counted from under a policy. An unset
+// policy is SeverityAtDetection, vulnerabilities without a severity at detection use the current one
+func Severity(policy, atDetection, current string) string {
+	if policy == SeverityCurrent || atDetection == "" {
+		return current
+	}
+	return atDetection
+}
+
+// Deadline is the remediation deadline of a vulnerability
+type Deadline struct {
+	// DueDate is the last day to remediate, in the SLA timezone (YYYY-MM-DD)
+	DueDate string
+	// DueAt is the end of the due date: the deadline is exceeded from this instant on
+	DueAt time.Time
+}
+
+// Calendar tells which days count towards an SLA. The zero Calendar counts every day
+type Calendar struct {
+	// BusinessDays counts only Monday to Friday, except Holidays
+	BusinessDays bool
+	// Holidays are dates (YYYY-MM-DD) that don't count as business days
+	Holidays map[string]bool
+}
+
+// NewCalendar returns the calendar of an SLA config. Holidays are dates in YYYY-MM-DD format
+func NewCalendar(businessDays bool, holidays []string) (Calendar, error) {
+	c := Calendar{BusinessDays: businessDays, Holidays: make(map[string]bool, len(holidays))}
+	for _, h := range holidays {
+		if _, err := time.Parse(dateLayout, h); err != nil {
+			return Calendar{}, fmt.Errorf("invalid holiday %q (expected YYYY-MM-DD)", h)
+		}
+		c.Holidays[h] = true
+	}
+	return c, nil
+}
+
+// counts reports whether a day counts towards the SLA
+func (c Calendar) counts(day time.Time) bool {
+	if !c.BusinessDays {
+		return true
+	}
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	return !c.Holidays[day.Format(dateLayout)]
+}
+
+// DeadlineFor returns the deadline of a vulnerability first detected at firstDetected with an SLA of days.
+// The day of detection never counts: the due date is the last of the next days that count
+func (c Calendar) DeadlineFor(firstDetected time.Time, days int, loc *time.Location) Deadline {
+	y, m, d := firstDetected.In(loc).Date()
+	offset := days
+	if c.BusinessDays {
+		// Holidays are a finite list, so a business day always comes
+		offset = 0
+		for counted := 0; counted < days; {
+			offset++
+			if c.counts(time.Date(y, m, d+offset, 0, 0, 0, 0, loc)) {
+				counted++
+			}
+		}
+	}
+	// time.Date normalizes the day overflow and keeps midnight across DST changes
+	due := time.Date(y, m, d+offset, 0, 0, 0, 0, loc)
+	return Deadline{
+		DueDate: due.Format(dateLayout),
+		DueAt:   time.Date(y, m, d+offset+1, 0, 0, 0, 0, loc),
+	}
+}
+
+// DaysRemaining returns the calendar days left until the due date in the SLA timezone:
+// 0 on the due date itself, negative once the deadline is exceeded
+func (d Deadline) DaysRemaining(now time.Time) int {
+	loc := d.DueAt.Location()
+	y, m, day := d.DueAt.AddDate(0, 0, -1).Date()
+	ny, nm, nday := now.In(loc).Date()
+	// Whole days between the two dates, counted in UTC where every day has 24 hours
+	due := time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+	today := time.Date(ny, nm, nday, 0, 0, 0, 0, time.UTC)
+	return int(due.Sub(today).Hours() / 24)
+}
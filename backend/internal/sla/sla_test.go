@@ -0,0 +1,104 @@
+package sla
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineFor_CalendarDaysOfTimeZone(t *testing.T) {
+	berlin, err := LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	firstDetected := time.Date(2026, 3, 2, 22, 30, 0, 0, time.UTC) // Monday 23:30 in Berlin
+
+	deadline := Calendar{}.DeadlineFor(firstDetected, 7, berlin)
+	assert.Equal(t, "2026-03-09", deadline.DueDate)
+	assert.Equal(t, time.Date(2026, 3, 10, 0, 0, 0, 0, berlin), deadline.DueAt)
+
+	// The same instant in Tokyo is already Tuesday
+	tokyo, err := LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-10", Calendar{}.DeadlineFor(firstDetected, 7, tokyo).DueDate)
+}
+
+func TestDeadlineFor_DSTChange(t *testing.T) {
+	newYork, err := LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// Clocks move forward on 2026-03-08: the due date is still at local midnight
+	firstDetected := time.Date(2026, 3, 6, 12, 0, 0, 0, newYork)
+	deadline := Calendar{}.DeadlineFor(firstDetected, 2, newYork)
+	assert.Equal(t, "2026-03-08", deadline.DueDate)
+	assert.Equal(t, 0, deadline.DueAt.Hour())
+	assert.Equal(t, 9, deadline.DueAt.Day())
+}
+
+func TestDeadlineFor_BusinessDays(t *testing.T) {
+	berlin, err := LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	calendar, err := NewCalendar(true, []string{"2026-12-25", "2026-12-28"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		firstDetected time.Time
+		days          int
+		want          string
+	}{
+		{"within the week", time.Date(2026, 3, 2, 10, 0, 0, 0, berlin), 3, "2026-03-05"},
+		{"over a weekend", time.Date(2026, 3, 5, 10, 0, 0, 0, berlin), 3, "2026-03-10"},
+		{"found on a Saturday", time.Date(2026, 3, 7, 10, 0, 0, 0, berlin), 1, "2026-03-09"},
+		{"over holidays", time.Date(2026, 12, 24, 10, 0, 0, 0, berlin), 2, "2026-12-30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, calendar.DeadlineFor(tt.firstDetected, tt.days, berlin).DueDate)
+		})
+	}
+}
+
+func TestNewCalendar_InvalidHoliday(t *testing.T) {
+	_, err := NewCalendar(true, []string{"25/12/2026"})
+	assert.Error(t, err)
+}
+
+func TestDaysRemaining(t *testing.T) {
+	berlin, err := LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	deadline := Calendar{}.DeadlineFor(time.Date(2026, 3, 2, 10, 0, 0, 0, berlin), 7, berlin)
+
+	assert.Equal(t, 7, deadline.DaysRemaining(time.Date(2026, 3, 2, 23, 59, 0, 0, berlin)))
+	assert.Equal(t, 0, deadline.DaysRemaining(time.Date(2026, 3, 9, 23, 59, 0, 0, berlin)))
+	// 23:30 UTC on the due date is already the next day in Berlin
+	assert.Equal(t, -1, deadline.DaysRemaining(time.Date(2026, 3, 9, 23, 30, 0, 0, time.UTC)))
+}
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := LoadLocation("")
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	_, err = LoadLocation("Mars/Olympus_Mons")
+	assert.Error(t, err)
+}
+
+func TestDays(t *testing.T) {
+	assert.Equal(t, 7, Days("Critical", 7, 30, 90, 180))
+	assert.Equal(t, 30, Days("High", 7, 30, 90, 180))
+	assert.Equal(t, 90, Days("Medium", 7, 30, 90, 180))
+	assert.Equal(t, 180, Days("Negligible", 7, 30, 90, 180))
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, "High", Severity(SeverityAtDetection, "High", "Critical"))
+	assert.Equal(t, "High", Severity("", "High", "Critical"))
+	assert.Equal(t, "Critical", Severity(SeverityCurrent, "High", "Critical"))
+	assert.Equal(t, "Critical", Severity(SeverityAtDetection, "", "Critical"))
+
+	assert.True(t, IsSeverityPolicy(SeverityAtDetection))
+	assert.True(t, IsSeverityPolicy(SeverityCurrent))
+	assert.False(t, IsSeverityPolicy("latest"))
+}
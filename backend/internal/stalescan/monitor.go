@@ -0,0 +1,93 @@
+// Package stalescan detects images whose scanner stopped reporting
+package stalescan
+
+import (
+	"context"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
+	"go.uber.org/zap"
+)
+
+// Store lists stale ImageScans and remembers which ones were alerted
+type Store interface {
+	ListStale(ctx context.Context, defaultThreshold time.Duration, now time.Time) ([]models.StaleImageScan, error)
+	MarkStaleNotified(ctx context.Context, namespace, name string, at time.Time) error
+}
+
+// WebhookConfigs looks up the webhook configured on an ImageScan
+type WebhookConfigs interface {
+	Get(ctx context.Context, namespace, name string) (*models.WebhookConfig, error)
+}
+
+// Monitor periodically alerts the webhook of every ImageScan whose image went stale
+type Monitor struct {
+	logger    *zap.Logger
+	store     Store
+	webhooks  WebhookConfigs
+	notifier  *notifier.Notifier
+	threshold time.Duration
+}
+
+// New creates a monitor. threshold applies to ImageScans without their own staleAfter
+func New(logger *zap.Logger, store Store, webhooks WebhookConfigs, n *notifier.Notifier, threshold time.Duration) *Monitor {
+	return &Monitor{
+		logger:    logger,
+		store:     store,
+		webhooks:  webhooks,
+		notifier:  n,
+		threshold: threshold,
+	}
+}
+
+// Check alerts the stale ImageScans that were not alerted since their last successful scan,
+// and returns how many notifications were sent. A failed notification is retried on the next check
+func (m *Monitor) Check(ctx context.Context, now time.Time) (int, error) {
+	stale, err := m.store.ListStale(ctx, m.threshold, now)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range stale {
+		scan := &stale[i]
+		if scan.Notified() {
+			continue
+		}
+
+		config, err := m.webhooks.Get(ctx, scan.Namespace, scan.Name)
+		if err != nil {
+			m.logger.Error("failed to get webhook config", zap.Error(err),
+				zap.String("namespace", scan.Namespace), zap.String("name", scan.Name))
+			continue
+		}
+		if config == nil {
+			// Nobody to alert; the image still shows up in GET /api/v1/images?stale=true
+			continue
+		}
+
+		payload := notifier.StaleScanNotificationPayload{
+			ImageName:              scan.ImageName,
+			ImageID:                scan.ImageID,
+			ImageScan:              scan.Namespace + "/" + scan.Name,
+			LastSuccessfulScanDate: scan.LastSuccessfulScanDate,
+			StaleAfter:             time.Duration(scan.StaleAfterSeconds) * time.Second,
+		}
+		webhook := notifier.WebhookConfig{URL: config.WebhookURL, Format: config.WebhookFormat, Locale: config.Locale}
+		if err := m.notifier.SendStaleScanNotification(ctx, webhook, payload); err != nil {
+			m.logger.Warn("failed to send stale-scan notification", zap.Error(err),
+				zap.String("imagescan", payload.ImageScan))
+			continue
+		}
+
+		if err := m.store.MarkStaleNotified(ctx, scan.Namespace, scan.Name, now); err != nil {
+			m.logger.Error("failed to record stale-scan notification", zap.Error(err),
+				zap.String("imagescan", payload.ImageScan))
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,72 @@
+package stalescan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/invulnerable/backend/internal/notifier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	stale    []models.StaleImageScan
+	notified map[string]time.Time
+}
+
+func (s *fakeStore) ListStale(ctx context.Context, defaultThreshold time.Duration, now time.Time) ([]models.StaleImageScan, error) {
+	return s.stale, nil
+}
+
+func (s *fakeStore) MarkStaleNotified(ctx context.Context, namespace, name string, at time.Time) error {
+	s.notified[namespace+"/"+name] = at
+	return nil
+}
+
+type fakeWebhooks map[string]*models.WebhookConfig
+
+func (w fakeWebhooks) Get(ctx context.Context, namespace, name string) (*models.WebhookConfig, error) {
+	return w[namespace+"/"+name], nil
+}
+
+func TestMonitor_Check(t *testing.T) {
+	var received []notifier.SlackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload notifier.SlackPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received = append(received, payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	lastScan := now.Add(-72 * time.Hour)
+	alreadyNotified := now.Add(-time.Hour)
+	store := &fakeStore{
+		notified: map[string]time.Time{},
+		stale: []models.StaleImageScan{
+			{Namespace: "prod", Name: "nginx", ImageID: 1, ImageName: "docker.io/library/nginx:latest", LastSuccessfulScanDate: &lastScan, StaleAfterSeconds: 86400},
+			{Namespace: "prod", Name: "redis", ImageID: 2, ImageName: "docker.io/library/redis:7.2", LastSuccessfulScanDate: &lastScan, StaleAfterSeconds: 86400, StaleNotifiedAt: &alreadyNotified},
+			{Namespace: "prod", Name: "no-webhook", ImageID: 3, ImageName: "docker.io/library/alpine:3.19", StaleAfterSeconds: 86400},
+		},
+	}
+	webhooks := fakeWebhooks{
+		"prod/nginx": {WebhookURL: server.URL, WebhookFormat: "slack"},
+		"prod/redis": {WebhookURL: server.URL, WebhookFormat: "slack"},
+	}
+
+	monitor := New(zap.NewNop(), store, webhooks, notifier.New(zap.NewNop(), "https://invulnerable.example.com"), 48*time.Hour)
+	sent, err := monitor.Check(context.Background(), now)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, sent)
+	require.Len(t, received, 1)
+	assert.Contains(t, received[0].Text, "docker.io/library/nginx:latest")
+	assert.Equal(t, map[string]time.Time{"prod/nginx": now}, store.notified)
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings of stored documents
+const (
+	EncodingIdentity = ""
+	EncodingZstd     = "zstd"
+)
+
+// EncodedRetriever is implemented by storages that can return a document as stored, so it can be
+// sent to a client that accepts its encoding without being decompressed and compressed again
+type EncodedRetriever interface {
+	// RetrieveEncoded returns the stored bytes and their content encoding. The caller closes the reader
+	RetrieveEncoded(ctx context.Context, scanID int) (io.ReadCloser, string, error)
+}
+
+// StreamStorer is implemented by storages that can store a document read from a file, so it is
+// never held in memory whole
+type StreamStorer interface {
+	StoreFrom(ctx context.Context, scanID int, document *io.SectionReader) error
+}
+
+// Encoders and decoders are safe for concurrent use of EncodeAll and DecodeAll, and costly to create
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// ParseEncoding validates the name of a storage encoding: zstd, or none for no compression
+func ParseEncoding(name string) (string, error) {
+	switch name {
+	case "none", "identity", "":
+		return EncodingIdentity, nil
+	case EncodingZstd:
+		return EncodingZstd, nil
+	}
+	return "", fmt.Errorf("unsupported storage compression %q (must be zstd or none)", name)
+}
+
+// encode compresses a document for storage
+func encode(document []byte, encoding string) []byte {
+	if encoding == EncodingZstd {
+		return zstdEncoder.EncodeAll(document, make([]byte, 0, len(document)/4))
+	}
+	return document
+}
+
+// encodeTo compresses a document read from r for storage into w
+func encodeTo(w io.Writer, r io.Reader, encoding string) error {
+	if encoding != EncodingZstd {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	enc, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(enc, r); err != nil {
+		enc.Close()
+		return err
+	}
+	return enc.Close()
+}
+
+// decode decompresses a stored document
+func decode(data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case EncodingIdentity:
+		return data, nil
+	case EncodingZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
+
+// OpenDocument returns a document in the encoding it is stored in. Storages that don't implement
+// EncodedRetriever return it decompressed
+func OpenDocument(ctx context.Context, s SBOMStorage, scanID int) (io.ReadCloser, string, error) {
+	if r, ok := s.(EncodedRetriever); ok {
+		return r.RetrieveEncoded(ctx, scanID)
+	}
+	document, err := s.Retrieve(ctx, scanID)
+	if err != nil {
+		return nil, "", err
+	}
+	return io.NopCloser(bytes.NewReader(document)), EncodingIdentity, nil
+}
+
+// StoreDocument stores a document read from a file. Storages that don't implement StreamStorer
+// get it read into memory
+func StoreDocument(ctx context.Context, s SBOMStorage, scanID int, document *io.SectionReader) error {
+	if w, ok := s.(StreamStorer); ok {
+		return w.StoreFrom(ctx, scanID, document)
+	}
+	data, err := io.ReadAll(document)
+	if err != nil {
+		return fmt.Errorf("failed to read document: %w", err)
+	}
+	return s.Store(ctx, scanID, data)
+}
+
+// NewDecodingReader decompresses a document read in the given encoding
+func NewDecodingReader(r io.Reader, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case EncodingIdentity:
+		return io.NopCloser(r), nil
+	case EncodingZstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
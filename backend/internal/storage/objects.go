@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ObjectStorage stores documents that don't belong to a scan, such as compliance reports, under keys
+// of their own. They are stored as is, with their content type
+type ObjectStorage interface {
+	Put(ctx context.Context, key string, document []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// S3ObjectStorage implements ObjectStorage in the bucket of the SBOMs
+type S3ObjectStorage struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewS3ObjectStorage creates a new S3-based object storage
+func NewS3ObjectStorage(client *s3.Client, bucket string) *S3ObjectStorage {
+	return &S3ObjectStorage{client: client, bucket: bucket}
+}
+
+// Put uploads a document to S3 under key
+func (s *S3ObjectStorage) Put(ctx context.Context, key string, document []byte, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(document),
+		ContentLength: aws.Int64(int64(len(document))),
+		ContentType:   aws.String(contentType),
+	})
+	return err
+}
+
+// Get downloads the document stored under key
+func (s *S3ObjectStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve %s from S3: %w", key, err)
+	}
+	defer result.Body.Close()
+	return io.ReadAll(result.Body)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -20,44 +21,134 @@ type SBOMStorage interface {
 	Exists(ctx context.Context, scanID int) (bool, error)
 }
 
+// GrypeResultStorage stores the raw Grype JSON of scans, with the same operations as SBOMs
+type GrypeResultStorage = SBOMStorage
+
+// ReportStorage stores the signed reports of scans, with the same operations as SBOMs
+type ReportStorage = SBOMStorage
+
 // S3Storage implements SBOMStorage using S3-compatible object storage
 type S3Storage struct {
 	client        *s3.Client
 	presignClient *s3.PresignClient
 	bucket        string
+	object        string
+	encoding      string
 }
 
 // NewS3Storage creates a new S3-based SBOM storage
 func NewS3Storage(client *s3.Client, bucket string) *S3Storage {
+	return newS3Storage(client, bucket, "sbom.json")
+}
+
+// NewS3GrypeResultStorage creates a new S3-based storage for raw Grype results,
+// kept next to the SBOM of the scan
+func NewS3GrypeResultStorage(client *s3.Client, bucket string) *S3Storage {
+	return newS3Storage(client, bucket, "grype.json")
+}
+
+// NewS3ReportStorage creates a new S3-based storage for signed scan reports,
+// kept next to the SBOM of the scan
+func NewS3ReportStorage(client *s3.Client, bucket string) *S3Storage {
+	return newS3Storage(client, bucket, "report.json")
+}
+
+func newS3Storage(client *s3.Client, bucket, object string) *S3Storage {
 	return &S3Storage{
 		client:        client,
 		presignClient: s3.NewPresignClient(client),
 		bucket:        bucket,
+		object:        object,
 	}
 }
 
+// SetCompression sets the encoding new documents are stored in (EncodingZstd or EncodingIdentity).
+// Documents stored before keep theirs, Retrieve decodes any of them
+func (s *S3Storage) SetCompression(encoding string) {
+	s.encoding = encoding
+}
+
 // computePath generates the S3 key for a given scan ID
-// Pattern: scans/{scan_id}/sbom.json
+// Pattern: scans/{scan_id}/sbom.json, scans/{scan_id}/grype.json or scans/{scan_id}/report.json
 func (s *S3Storage) computePath(scanID int) string {
-	return fmt.Sprintf("scans/%d/sbom.json", scanID)
+	return fmt.Sprintf("scans/%d/%s", scanID, s.object)
 }
 
 // Store uploads an SBOM document to S3
 func (s *S3Storage) Store(ctx context.Context, scanID int, document []byte) error {
+	encoded := encode(document, s.encoding)
+	return s.put(ctx, scanID, bytes.NewReader(encoded), int64(len(encoded)))
+}
+
+// StoreFrom uploads an SBOM document to S3 as it is read. S3 needs the length of the upload up
+// front, so a compressed document is written to a temporary file first
+func (s *S3Storage) StoreFrom(ctx context.Context, scanID int, document *io.SectionReader) error {
+	if s.encoding == EncodingIdentity {
+		return s.put(ctx, scanID, document, document.Size())
+	}
+
+	spool, err := os.CreateTemp("", "invulnerable-document-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	if err := encodeTo(spool, document, s.encoding); err != nil {
+		return fmt.Errorf("failed to compress %s: %w", s.object, err)
+	}
+	size, err := spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.put(ctx, scanID, spool, size)
+}
+
+// put uploads an encoded document of the given length
+func (s *S3Storage) put(ctx context.Context, scanID int, body io.ReadSeeker, size int64) error {
 	path := s.computePath(scanID)
 
-	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(s.bucket),
-		Key:         aws.String(path),
-		Body:        bytes.NewReader(document),
-		ContentType: aws.String("application/json"),
-	})
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(path),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String("application/json"),
+	}
+	if s.encoding != EncodingIdentity {
+		input.ContentEncoding = aws.String(s.encoding)
+	}
+	_, err := s.client.PutObject(ctx, input)
 
 	return err
 }
 
-// Retrieve downloads an SBOM document from S3
+// Retrieve downloads an SBOM document from S3, decompressed
 func (s *S3Storage) Retrieve(ctx context.Context, scanID int) ([]byte, error) {
+	body, encoding, err := s.RetrieveEncoded(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.object, err)
+	}
+
+	document, err := decode(data, encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", s.object, err)
+	}
+
+	return document, nil
+}
+
+// RetrieveEncoded streams an SBOM document from S3 as stored, with its content encoding
+func (s *S3Storage) RetrieveEncoded(ctx context.Context, scanID int) (io.ReadCloser, string, error) {
 	path := s.computePath(scanID)
 
 	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
@@ -65,16 +156,10 @@ func (s *S3Storage) Retrieve(ctx context.Context, scanID int) ([]byte, error) {
 		Key:    aws.String(path),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve SBOM from S3: %w", err)
+		return nil, "", fmt.Errorf("failed to retrieve %s from S3: %w", s.object, err)
 	}
-	defer result.Body.Close()
 
-	document, err := io.ReadAll(result.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read SBOM document: %w", err)
-	}
-
-	return document, nil
+	return result.Body, aws.ToString(result.ContentEncoding), nil
 }
 
 // Delete removes an SBOM document from S3
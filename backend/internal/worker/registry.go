@@ -0,0 +1,178 @@
+// Package worker runs the periodic background jobs of the backend. With several replicas each
+// job runs on one replica at a time, under a leader lock, and at most once per interval
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// maxPollInterval bounds how long a due job can wait: replicas check their jobs at least this often
+const maxPollInterval = time.Minute
+
+// Job is one run of a periodic worker. It must be safe to run again after a failure or a restart
+type Job func(ctx context.Context, now time.Time) error
+
+// Locker gives a replica exclusive use of a worker while it runs
+type Locker interface {
+	TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error)
+}
+
+// Store records the runs of the workers, shared by every replica
+type Store interface {
+	GetRun(ctx context.Context, name string) (*models.WorkerRun, error)
+	ListRuns(ctx context.Context) ([]models.WorkerRun, error)
+	StartRun(ctx context.Context, name, instance string, startedAt time.Time) error
+	FinishRun(ctx context.Context, name string, finishedAt time.Time, runErr error) error
+}
+
+type worker struct {
+	name     string
+	interval time.Duration
+	job      Job
+}
+
+// Registry runs the registered workers
+type Registry struct {
+	logger   *zap.Logger
+	locker   Locker
+	store    Store
+	instance string
+
+	mu      sync.Mutex
+	workers map[string]*worker
+}
+
+// New creates a registry. instance identifies this replica in the recorded runs
+func New(logger *zap.Logger, locker Locker, store Store, instance string) *Registry {
+	return &Registry{
+		logger:   logger,
+		locker:   locker,
+		store:    store,
+		instance: instance,
+		workers:  make(map[string]*worker),
+	}
+}
+
+// Register adds a worker running job every interval
+func (r *Registry) Register(name string, interval time.Duration, job Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.workers[name] = &worker{name: name, interval: interval, job: job}
+}
+
+// Start runs every registered worker until the context is cancelled
+func (r *Registry) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, w := range r.workers {
+		go r.loop(ctx, w)
+	}
+}
+
+func (r *Registry) loop(ctx context.Context, w *worker) {
+	ticker := time.NewTicker(min(w.interval, maxPollInterval))
+	defer ticker.Stop()
+
+	r.logger.Info("background worker started", zap.String("worker", w.name), zap.Duration("interval", w.interval))
+
+	for {
+		if _, err := r.runIfDue(ctx, w, time.Now()); err != nil {
+			r.logger.Error("background worker failed", zap.String("worker", w.name), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunIfDue runs a worker if no replica ran it within its interval, and reports whether it ran
+func (r *Registry) RunIfDue(ctx context.Context, name string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	w, ok := r.workers[name]
+	r.mu.Unlock()
+	if !ok {
+		return false, fmt.Errorf("unknown worker %q", name)
+	}
+	return r.runIfDue(ctx, w, now)
+}
+
+func (r *Registry) runIfDue(ctx context.Context, w *worker, now time.Time) (bool, error) {
+	// Checked before locking so replicas don't contend for a job that isn't due
+	if due, err := r.isDue(ctx, w, now); err != nil || !due {
+		return false, err
+	}
+
+	unlock, acquired, err := r.locker.TryLock(ctx, w.name)
+	if err != nil {
+		return false, err
+	}
+	if !acquired {
+		r.logger.Debug("background worker running on another replica", zap.String("worker", w.name))
+		return false, nil
+	}
+	defer unlock()
+
+	// Another replica may have finished a run between the check and the lock
+	if due, err := r.isDue(ctx, w, now); err != nil || !due {
+		return false, err
+	}
+
+	if err := r.store.StartRun(ctx, w.name, r.instance, now); err != nil {
+		return false, fmt.Errorf("failed to record worker run: %w", err)
+	}
+	runErr := w.job(ctx, now)
+	if err := r.store.FinishRun(ctx, w.name, time.Now(), runErr); err != nil {
+		r.logger.Warn("failed to record worker run outcome", zap.String("worker", w.name), zap.Error(err))
+	}
+	return true, runErr
+}
+
+// isDue reports whether the last run of a worker, on any replica, started an interval ago or more.
+// A replica that restarts doesn't run its workers again before they are due
+func (r *Registry) isDue(ctx context.Context, w *worker, now time.Time) (bool, error) {
+	last, err := r.store.GetRun(ctx, w.name)
+	if err != nil {
+		return false, fmt.Errorf("failed to get last worker run: %w", err)
+	}
+	return last == nil || now.Sub(last.LastStartedAt) >= w.interval, nil
+}
+
+// Status returns the workers registered on this replica with their last run on any replica
+func (r *Registry) Status(ctx context.Context) ([]models.WorkerStatus, error) {
+	runs, err := r.store.ListRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lastRuns := make(map[string]*models.WorkerRun, len(runs))
+	for i := range runs {
+		lastRuns[runs[i].Name] = &runs[i]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statuses := make([]models.WorkerStatus, 0, len(r.workers))
+	for _, w := range r.workers {
+		status := models.WorkerStatus{
+			Name:      w.name,
+			Interval:  w.interval.String(),
+			NextRunAt: time.Now(),
+			LastRun:   lastRuns[w.name],
+		}
+		if status.LastRun != nil {
+			status.NextRunAt = status.LastRun.LastStartedAt.Add(w.interval)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
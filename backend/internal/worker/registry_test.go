@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/invulnerable/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeBackend is the Postgres of several replicas: one lock table and one run store
+type fakeBackend struct {
+	mu     sync.Mutex
+	locked map[string]bool
+	runs   map[string]*models.WorkerRun
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{locked: map[string]bool{}, runs: map[string]*models.WorkerRun{}}
+}
+
+func (b *fakeBackend) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.locked[name] {
+		return nil, false, nil
+	}
+	b.locked[name] = true
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.locked, name)
+	}, true, nil
+}
+
+func (b *fakeBackend) GetRun(ctx context.Context, name string) (*models.WorkerRun, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if run, ok := b.runs[name]; ok {
+		last := *run
+		return &last, nil
+	}
+	return nil, nil
+}
+
+func (b *fakeBackend) ListRuns(ctx context.Context) ([]models.WorkerRun, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	runs := []models.WorkerRun{}
+	for _, run := range b.runs {
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+func (b *fakeBackend) StartRun(ctx context.Context, name, instance string, startedAt time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	run, ok := b.runs[name]
+	if !ok {
+		run = &models.WorkerRun{Name: name}
+		b.runs[name] = run
+	}
+	run.Status, run.Instance, run.LastStartedAt = models.WorkerStatusRunning, instance, startedAt
+	return nil
+}
+
+func (b *fakeBackend) FinishRun(ctx context.Context, name string, finishedAt time.Time, runErr error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	run := b.runs[name]
+	run.Status = models.WorkerStatusSucceeded
+	run.LastError = nil
+	if runErr != nil {
+		run.Status = models.WorkerStatusFailed
+		message := runErr.Error()
+		run.LastError = &message
+		run.FailureCount++
+	}
+	run.LastFinishedAt = &finishedAt
+	run.RunCount++
+	return nil
+}
+
+func TestRegistry_RunsOncePerInterval(t *testing.T) {
+	backend := newFakeBackend()
+	ctx := context.Background()
+
+	runs := 0
+	job := func(ctx context.Context, now time.Time) error { runs++; return nil }
+
+	// Two replicas registering the same worker
+	a := New(zap.NewNop(), backend, backend, "backend-a")
+	b := New(zap.NewNop(), backend, backend, "backend-b")
+	a.Register("retention", time.Hour, job)
+	b.Register("retention", time.Hour, job)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	ran, err := a.RunIfDue(ctx, "retention", now)
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	// The other replica, or the same one after a restart, waits for the next interval
+	ran, err = b.RunIfDue(ctx, "retention", now.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, ran)
+	restarted := New(zap.NewNop(), backend, backend, "backend-a")
+	restarted.Register("retention", time.Hour, job)
+	ran, err = restarted.RunIfDue(ctx, "retention", now.Add(10*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	ran, err = b.RunIfDue(ctx, "retention", now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, "backend-b", backend.runs["retention"].Instance)
+}
+
+func TestRegistry_SkipsWhileLocked(t *testing.T) {
+	backend := newFakeBackend()
+	ctx := context.Background()
+	now := time.Now()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	a := New(zap.NewNop(), backend, backend, "backend-a")
+	a.Register("stale-scans", time.Minute, func(ctx context.Context, now time.Time) error {
+		close(started)
+		<-release
+		return nil
+	})
+	b := New(zap.NewNop(), backend, backend, "backend-b")
+	b.Register("stale-scans", time.Minute, func(ctx context.Context, now time.Time) error {
+		t.Error("ran on two replicas at once")
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = a.RunIfDue(ctx, "stale-scans", now)
+	}()
+	<-started
+
+	// Due again while the first run is still going, which the lock prevents
+	ran, err := b.RunIfDue(ctx, "stale-scans", now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	close(release)
+	<-done
+}
+
+func TestRegistry_RecordsFailures(t *testing.T) {
+	backend := newFakeBackend()
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	r := New(zap.NewNop(), backend, backend, "backend-a")
+	r.Register("retention", time.Hour, func(ctx context.Context, now time.Time) error {
+		return errors.New("database unavailable")
+	})
+	r.Register("stale-scans", 15*time.Minute, func(ctx context.Context, now time.Time) error { return nil })
+
+	ran, err := r.RunIfDue(ctx, "retention", now)
+	assert.True(t, ran)
+	assert.EqualError(t, err, "database unavailable")
+
+	statuses, err := r.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "retention", statuses[0].Name)
+	assert.Equal(t, "1h0m0s", statuses[0].Interval)
+	require.NotNil(t, statuses[0].LastRun)
+	assert.Equal(t, models.WorkerStatusFailed, statuses[0].LastRun.Status)
+	assert.Equal(t, "database unavailable", *statuses[0].LastRun.LastError)
+	assert.Equal(t, now.Add(time.Hour), statuses[0].NextRunAt)
+
+	// Never ran, due now
+	assert.Equal(t, "stale-scans", statuses[1].Name)
+	assert.Nil(t, statuses[1].LastRun)
+}
@@ -9,7 +9,7 @@ ALTER TABLE vulnerabilities
 ADD COLUMN imagescan_namespace VARCHAR(253),
 ADD COLUMN imagescan_name VARCHAR(253);
 
-CREATE INDEX idx_vulnerabilities_imagescan ON vulnerabilities(imagescan_namespace, imagescan_name);
+-- idx_vulnerabilities_imagescan is built online once the backend is up (internal/online)
 
 COMMENT ON COLUMN vulnerabilities.imagescan_namespace IS 'Kubernetes namespace of ImageScan that first discovered this vulnerability';
 COMMENT ON COLUMN vulnerabilities.imagescan_name IS 'Name of ImageScan resource that first discovered this vulnerability';
@@ -0,0 +1,2 @@
+-- Rollback migration 007: Remove maintenance mode
+DROP TABLE IF EXISTS maintenance_mode;
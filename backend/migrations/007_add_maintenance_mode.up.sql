@@ -0,0 +1,17 @@
+-- Migration 007: Add maintenance (read-only) mode
+-- A single row holds the cluster-wide maintenance state so every backend replica
+-- sees the same toggle without coordinating in memory.
+
+CREATE TABLE IF NOT EXISTS maintenance_mode (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    reason TEXT,
+    retry_after_seconds INTEGER NOT NULL DEFAULT 300,
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO maintenance_mode (id, enabled) VALUES (1, false) ON CONFLICT (id) DO NOTHING;
+
+COMMENT ON TABLE maintenance_mode IS 'Singleton row toggling read-only mode while migrations or storage maintenance run';
+COMMENT ON COLUMN maintenance_mode.retry_after_seconds IS 'Value returned in the Retry-After header for rejected write requests';
@@ -0,0 +1,9 @@
+-- Rollback: Remove scan lifecycle statuses and failure reasons
+
+DROP INDEX IF EXISTS idx_scans_status;
+
+ALTER TABLE scans
+DROP CONSTRAINT IF EXISTS scans_status_check;
+
+ALTER TABLE scans
+DROP COLUMN failure_reason;
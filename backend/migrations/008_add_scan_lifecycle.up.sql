@@ -0,0 +1,14 @@
+-- Add scan lifecycle statuses and failure reasons
+-- Scanners register a scan as running before Syft/Grype start and report failures
+-- explicitly, so crashed scans are visible instead of silently missing
+
+ALTER TABLE scans
+ADD COLUMN failure_reason TEXT;
+
+ALTER TABLE scans
+ADD CONSTRAINT scans_status_check CHECK (status IN ('pending', 'running', 'completed', 'failed', 'partial'));
+
+-- idx_scans_status is built online once the backend is up (internal/online)
+
+COMMENT ON COLUMN scans.status IS 'Scan lifecycle: pending, running, completed, failed, partial';
+COMMENT ON COLUMN scans.failure_reason IS 'Why the scan failed or only partially completed, as reported by the scanner';
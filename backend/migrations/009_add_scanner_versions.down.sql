@@ -0,0 +1,9 @@
+-- Rollback: Remove scanner version tracking from scans
+
+DROP INDEX IF EXISTS idx_scans_needs_rescan;
+DROP INDEX IF EXISTS idx_scans_tool_versions;
+
+ALTER TABLE scans
+DROP COLUMN grype_db_built,
+DROP COLUMN grype_db_schema,
+DROP COLUMN needs_rescan;
@@ -0,0 +1,13 @@
+-- Record the Grype vulnerability database used by each scan and allow flagging scans
+-- made with deprecated tool versions so their images get rescanned
+
+ALTER TABLE scans
+ADD COLUMN grype_db_built TIMESTAMP WITH TIME ZONE,
+ADD COLUMN grype_db_schema VARCHAR(50),
+ADD COLUMN needs_rescan BOOLEAN NOT NULL DEFAULT false;
+
+-- idx_scans_tool_versions and idx_scans_needs_rescan are built online once the backend is up
+-- (internal/online)
+
+COMMENT ON COLUMN scans.grype_db_built IS 'Build date of the Grype vulnerability database used for this scan';
+COMMENT ON COLUMN scans.needs_rescan IS 'Set when the scan was made with a deprecated scanner version or database';
@@ -0,0 +1,5 @@
+-- Rollback: Remove suppression rules
+
+DROP INDEX IF EXISTS idx_suppression_rules_cve_id;
+DROP INDEX IF EXISTS idx_suppression_rules_criteria;
+DROP TABLE IF EXISTS suppression_rules;
@@ -0,0 +1,33 @@
+-- Migration 010: Add suppression rules (waivers)
+-- A rule matches vulnerabilities by CVE and/or package. Matching active findings are
+-- accepted on ingest, so rules imported from .grype.yaml / .trivyignore files keep
+-- the server in line with the decisions teams already track in their repositories.
+
+CREATE TABLE IF NOT EXISTS suppression_rules (
+    id SERIAL PRIMARY KEY,
+    cve_id VARCHAR(50),
+    package_name VARCHAR(255),
+    package_version VARCHAR(255),
+    package_type VARCHAR(50),
+    fix_state VARCHAR(20),
+    reason TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    source VARCHAR(20) NOT NULL DEFAULT 'manual',
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT suppression_rules_target_check CHECK (cve_id IS NOT NULL OR package_name IS NOT NULL),
+    CONSTRAINT suppression_rules_fix_state_check CHECK (fix_state IS NULL OR fix_state IN ('fixed', 'not-fixed', 'wont-fix', 'unknown')),
+    CONSTRAINT suppression_rules_source_check CHECK (source IN ('manual', 'grype', 'trivy'))
+);
+
+-- Identical criteria are the same rule, so re-importing a file updates reasons and expiry in place
+CREATE UNIQUE INDEX IF NOT EXISTS idx_suppression_rules_criteria ON suppression_rules (
+    COALESCE(cve_id, ''), COALESCE(package_name, ''), COALESCE(package_version, ''),
+    COALESCE(package_type, ''), COALESCE(fix_state, '')
+);
+CREATE INDEX IF NOT EXISTS idx_suppression_rules_cve_id ON suppression_rules(cve_id);
+
+COMMENT ON TABLE suppression_rules IS 'Accepted-risk waivers applied to matching vulnerabilities on ingest';
+COMMENT ON COLUMN suppression_rules.fix_state IS 'Grype fix state the finding must have: fixed, not-fixed, wont-fix or unknown';
+COMMENT ON COLUMN suppression_rules.source IS 'Where the rule came from: manual, grype (.grype.yaml) or trivy (.trivyignore)';
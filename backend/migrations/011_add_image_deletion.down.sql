@@ -0,0 +1,8 @@
+-- Rollback: Remove ImageScan registrations and the audit log
+
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP INDEX IF EXISTS idx_audit_log_resource;
+DROP TABLE IF EXISTS audit_log;
+
+DROP INDEX IF EXISTS idx_imagescans_image;
+DROP TABLE IF EXISTS imagescans;
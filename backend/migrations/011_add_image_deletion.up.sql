@@ -0,0 +1,35 @@
+-- Migration 011: Track ImageScans and audit destructive admin actions
+-- The controller registers every ImageScan so the backend can tell whether an image
+-- is still monitored before deleting it. Image references are stored parsed the same
+-- way scan submissions are, so they match rows of the images table.
+
+CREATE TABLE IF NOT EXISTS imagescans (
+    namespace VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    registry VARCHAR(255) NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    tag VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (namespace, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_imagescans_image ON imagescans(registry, repository, tag);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id SERIAL PRIMARY KEY,
+    action VARCHAR(50) NOT NULL,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id INTEGER,
+    resource_name TEXT,
+    actor VARCHAR(255) NOT NULL,
+    details JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log(resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at DESC);
+
+COMMENT ON TABLE imagescans IS 'ImageScan resources registered by the controller, removed when the resource is deleted';
+COMMENT ON TABLE audit_log IS 'Destructive or administrative actions that are not tied to a single vulnerability';
+COMMENT ON COLUMN audit_log.details IS 'Action-specific context, e.g. how many scans an image deletion removed';
@@ -0,0 +1,6 @@
+-- Rollback: Remove image monitoring state
+
+ALTER TABLE images DROP CONSTRAINT IF EXISTS images_monitoring_check;
+ALTER TABLE images DROP COLUMN IF EXISTS monitoring;
+
+ALTER TABLE imagescans DROP COLUMN IF EXISTS suspended;
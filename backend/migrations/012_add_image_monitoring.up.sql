@@ -0,0 +1,15 @@
+-- Migration 012: Propagate ImageScan suspension to images
+-- An image is paused when every ImageScan scanning it is suspended. Paused images keep
+-- their history but are left out of SLA evaluation and stale-scan alerts
+
+ALTER TABLE imagescans
+ADD COLUMN IF NOT EXISTS suspended BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE images
+ADD COLUMN IF NOT EXISTS monitoring VARCHAR(20) NOT NULL DEFAULT 'active';
+
+ALTER TABLE images
+ADD CONSTRAINT images_monitoring_check CHECK (monitoring IN ('active', 'paused'));
+
+COMMENT ON COLUMN imagescans.suspended IS 'Mirrors spec.schedule.suspend of the ImageScan';
+COMMENT ON COLUMN images.monitoring IS 'active or paused, derived from the suspended flag of the ImageScans scanning the image';
@@ -0,0 +1,6 @@
+-- Rollback: Remove stale-scan tracking
+
+ALTER TABLE imagescans DROP CONSTRAINT IF EXISTS imagescans_stale_after_check;
+ALTER TABLE imagescans
+DROP COLUMN IF EXISTS stale_notified_at,
+DROP COLUMN IF EXISTS stale_after_seconds;
@@ -0,0 +1,13 @@
+-- Migration 013: Stale-scan detection
+-- Each ImageScan may declare how long its image can go without a successful scan.
+-- stale_notified_at remembers the last alert so an outage is reported once, not on every check
+
+ALTER TABLE imagescans
+ADD COLUMN IF NOT EXISTS stale_after_seconds INTEGER,
+ADD COLUMN IF NOT EXISTS stale_notified_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE imagescans
+ADD CONSTRAINT imagescans_stale_after_check CHECK (stale_after_seconds IS NULL OR stale_after_seconds > 0);
+
+COMMENT ON COLUMN imagescans.stale_after_seconds IS 'Mirrors spec.staleAfter, NULL uses the server default threshold';
+COMMENT ON COLUMN imagescans.stale_notified_at IS 'When the owning webhook was last told the image is stale';
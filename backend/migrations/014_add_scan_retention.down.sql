@@ -0,0 +1,9 @@
+-- Rollback: Remove per-ImageScan scan retention
+
+ALTER TABLE imagescans
+DROP CONSTRAINT IF EXISTS imagescans_max_scan_age_check,
+DROP CONSTRAINT IF EXISTS imagescans_max_scans_retained_check;
+
+ALTER TABLE imagescans
+DROP COLUMN IF EXISTS max_scan_age_seconds,
+DROP COLUMN IF EXISTS max_scans_retained;
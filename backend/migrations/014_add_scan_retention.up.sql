@@ -0,0 +1,14 @@
+-- Migration 014: Per-ImageScan scan retention
+-- Declared on the ImageScan next to its schedule and enforced by the backend pruner.
+-- NULL means no limit
+
+ALTER TABLE imagescans
+ADD COLUMN IF NOT EXISTS max_scans_retained INTEGER,
+ADD COLUMN IF NOT EXISTS max_scan_age_seconds INTEGER;
+
+ALTER TABLE imagescans
+ADD CONSTRAINT imagescans_max_scans_retained_check CHECK (max_scans_retained IS NULL OR max_scans_retained > 0),
+ADD CONSTRAINT imagescans_max_scan_age_check CHECK (max_scan_age_seconds IS NULL OR max_scan_age_seconds > 0);
+
+COMMENT ON COLUMN imagescans.max_scans_retained IS 'Mirrors spec.retention.maxScansRetained';
+COMMENT ON COLUMN imagescans.max_scan_age_seconds IS 'Mirrors spec.retention.maxScanAge';
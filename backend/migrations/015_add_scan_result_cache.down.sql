@@ -0,0 +1,8 @@
+-- Rollback: Remove scan result caching
+
+DROP INDEX IF EXISTS idx_scans_result_cache;
+
+ALTER TABLE scans
+DROP COLUMN IF EXISTS cached_from_scan_id,
+DROP COLUMN IF EXISTS results_fingerprint,
+DROP COLUMN IF EXISTS digest;
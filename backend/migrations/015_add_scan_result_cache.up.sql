@@ -0,0 +1,14 @@
+-- Migration 015: Scan result caching
+-- A digest scanned with the same Grype database build yields the same findings, so a new scan
+-- with identical results links the findings of the earlier scan instead of upserting every match
+
+ALTER TABLE scans
+ADD COLUMN IF NOT EXISTS digest VARCHAR(255),
+ADD COLUMN IF NOT EXISTS results_fingerprint VARCHAR(64),
+ADD COLUMN IF NOT EXISTS cached_from_scan_id INTEGER REFERENCES scans(id) ON DELETE SET NULL;
+
+-- idx_scans_result_cache is built online once the backend is up (internal/online)
+
+COMMENT ON COLUMN scans.digest IS 'Image digest that was scanned';
+COMMENT ON COLUMN scans.results_fingerprint IS 'SHA-256 of the stored match fields, guards against scanner-side filtering';
+COMMENT ON COLUMN scans.cached_from_scan_id IS 'Scan whose findings were linked instead of processing the submission';
@@ -0,0 +1,6 @@
+-- Rollback: Remove sub-image scan targets
+
+DROP INDEX IF EXISTS idx_scans_image_target_date;
+
+ALTER TABLE scans
+DROP COLUMN IF EXISTS target;
@@ -0,0 +1,10 @@
+-- Migration 016: Sub-image scan targets
+-- Fat images bundling several applications can be scanned per component (a binary or an app
+-- directory), each target with its own scan history and findings
+
+ALTER TABLE scans
+ADD COLUMN IF NOT EXISTS target VARCHAR(1024);
+
+-- idx_scans_image_target_date is built online once the backend is up (internal/online)
+
+COMMENT ON COLUMN scans.target IS 'Path inside the image that was scanned, NULL for the whole image';
@@ -0,0 +1,6 @@
+-- Rollback: Remove distribution metadata from scans
+
+ALTER TABLE scans
+DROP COLUMN IF EXISTS distro_id_like,
+DROP COLUMN IF EXISTS distro_version,
+DROP COLUMN IF EXISTS distro_name;
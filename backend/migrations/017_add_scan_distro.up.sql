@@ -0,0 +1,12 @@
+-- Migration 017: Distribution metadata on scans
+-- Grype detects the distribution of the scanned image; distroless and scratch images have none,
+-- which is stored as NULL so analytics can report them apart
+
+ALTER TABLE scans
+ADD COLUMN IF NOT EXISTS distro_name VARCHAR(255),
+ADD COLUMN IF NOT EXISTS distro_version VARCHAR(255),
+ADD COLUMN IF NOT EXISTS distro_id_like TEXT[];
+
+COMMENT ON COLUMN scans.distro_name IS 'Distribution detected by Grype (e.g. debian), NULL when none was detected';
+COMMENT ON COLUMN scans.distro_version IS 'Version of the detected distribution';
+COMMENT ON COLUMN scans.distro_id_like IS 'Distributions the detected one derives from (os-release ID_LIKE)';
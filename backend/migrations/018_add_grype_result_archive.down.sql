@@ -0,0 +1,4 @@
+-- Rollback: Remove raw Grype result archival
+-- Documents already stored in the bucket are left in place
+
+DROP TABLE IF EXISTS grype_results;
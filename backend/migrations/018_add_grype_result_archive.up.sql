@@ -0,0 +1,14 @@
+-- Migration 018: Raw Grype result archival
+-- The original Grype JSON of each scan is stored next to its SBOM (scans/{scan_id}/grype.json),
+-- so disputes about what the scanner reported can be settled. Rows go away with their scan;
+-- the retention pruner expires older documents on its own schedule.
+
+CREATE TABLE IF NOT EXISTS grype_results (
+    id SERIAL PRIMARY KEY,
+    scan_id INTEGER NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    size_bytes BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(scan_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_grype_results_created_at ON grype_results(created_at);
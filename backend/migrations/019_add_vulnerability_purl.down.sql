@@ -0,0 +1,6 @@
+-- Rollback: Remove vulnerability package URLs
+
+DROP INDEX IF EXISTS idx_vulnerabilities_purl;
+
+ALTER TABLE vulnerabilities
+DROP COLUMN IF EXISTS purl;
@@ -0,0 +1,10 @@
+-- Migration 019: Package URLs on vulnerabilities
+-- PURLs are the ecosystem-standard package identifiers (pkg:npm/lodash@4.17.21), used to query
+-- components and to exchange findings with other tooling
+
+ALTER TABLE vulnerabilities
+ADD COLUMN IF NOT EXISTS purl TEXT;
+
+-- idx_vulnerabilities_purl is built online once the backend is up (internal/online)
+
+COMMENT ON COLUMN vulnerabilities.purl IS 'Package URL of the affected artifact as reported by Grype, including qualifiers';
@@ -0,0 +1,6 @@
+-- Rollback: Remove watchlist subscriptions
+
+DROP INDEX IF EXISTS idx_watchlist_subscriptions_package_name;
+DROP INDEX IF EXISTS idx_watchlist_subscriptions_cve_id;
+DROP INDEX IF EXISTS idx_watchlist_subscriptions_criteria;
+DROP TABLE IF EXISTS watchlist_subscriptions;
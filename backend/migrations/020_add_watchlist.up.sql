@@ -0,0 +1,25 @@
+-- Migration 020: Watchlist subscriptions
+-- Users subscribe to a CVE or a package and get a notification on their own webhook when a scan
+-- detects it on an image, or when its fix version changes
+
+CREATE TABLE IF NOT EXISTS watchlist_subscriptions (
+    id SERIAL PRIMARY KEY,
+    cve_id VARCHAR(50),
+    package_name VARCHAR(255),
+    subscriber VARCHAR(255) NOT NULL,
+    webhook_url TEXT NOT NULL,
+    webhook_format VARCHAR(20) NOT NULL DEFAULT 'slack',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT watchlist_subscriptions_target_check CHECK (cve_id IS NOT NULL OR package_name IS NOT NULL),
+    CONSTRAINT watchlist_subscriptions_format_check CHECK (webhook_format IN ('slack', 'teams'))
+);
+
+-- Subscribing twice to the same thing updates the webhook instead of doubling notifications
+CREATE UNIQUE INDEX IF NOT EXISTS idx_watchlist_subscriptions_criteria ON watchlist_subscriptions (
+    subscriber, COALESCE(cve_id, ''), COALESCE(package_name, '')
+);
+CREATE INDEX IF NOT EXISTS idx_watchlist_subscriptions_cve_id ON watchlist_subscriptions(cve_id);
+CREATE INDEX IF NOT EXISTS idx_watchlist_subscriptions_package_name ON watchlist_subscriptions(package_name);
+
+COMMENT ON TABLE watchlist_subscriptions IS 'CVEs and packages users want targeted notifications about';
+COMMENT ON COLUMN watchlist_subscriptions.subscriber IS 'User who subscribed, from the OAuth2 Proxy headers';
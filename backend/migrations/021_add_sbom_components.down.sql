@@ -0,0 +1,9 @@
+-- Rollback: Remove the SBOM component index
+
+ALTER TABLE sboms
+DROP COLUMN IF EXISTS component_count;
+
+DROP INDEX IF EXISTS idx_sbom_components_purl;
+DROP INDEX IF EXISTS idx_sbom_components_name;
+DROP INDEX IF EXISTS idx_sbom_components_scan_id;
+DROP TABLE IF EXISTS sbom_components;
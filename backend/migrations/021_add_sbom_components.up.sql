@@ -0,0 +1,25 @@
+-- Migration 021: SBOM component index
+-- Packages listed in SBOMs are stored next to the documents, so the impact of a new CVE can be
+-- assessed from package names before vulnerability databases know about it
+
+CREATE TABLE IF NOT EXISTS sbom_components (
+    id BIGSERIAL PRIMARY KEY,
+    scan_id INTEGER NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL DEFAULT '',
+    type VARCHAR(50),
+    purl TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_sbom_components_scan_id ON sbom_components(scan_id);
+CREATE INDEX IF NOT EXISTS idx_sbom_components_name ON sbom_components(LOWER(name));
+-- text_pattern_ops supports the prefix matches of version-less PURL queries
+CREATE INDEX IF NOT EXISTS idx_sbom_components_purl ON sbom_components(purl text_pattern_ops);
+
+-- NULL until the components of the SBOM are indexed; SBOMs stored before this migration
+-- are indexed the first time an impact assessment needs them
+ALTER TABLE sboms
+ADD COLUMN IF NOT EXISTS component_count INTEGER;
+
+COMMENT ON TABLE sbom_components IS 'Packages listed in the SBOM of each scan';
+COMMENT ON COLUMN sboms.component_count IS 'Number of indexed sbom_components rows, NULL when not indexed yet';
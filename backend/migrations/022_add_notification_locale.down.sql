@@ -0,0 +1,7 @@
+-- Rollback: Remove the notification locale
+
+ALTER TABLE watchlist_subscriptions
+DROP COLUMN IF EXISTS locale;
+
+ALTER TABLE imagescan_webhook_configs
+DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,11 @@
+-- Migration 022: Notification locale
+-- Webhook notifications are written in the locale of the webhook config (en, fr, de)
+
+ALTER TABLE imagescan_webhook_configs
+ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'en';
+
+ALTER TABLE watchlist_subscriptions
+ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'en';
+
+COMMENT ON COLUMN imagescan_webhook_configs.locale IS 'Language of the notification text';
+COMMENT ON COLUMN watchlist_subscriptions.locale IS 'Language of the notification text';
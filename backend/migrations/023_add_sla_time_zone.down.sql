@@ -0,0 +1,4 @@
+-- Rollback: Remove the SLA timezone
+
+ALTER TABLE scans
+DROP COLUMN IF EXISTS sla_time_zone;
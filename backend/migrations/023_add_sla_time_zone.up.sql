@@ -0,0 +1,7 @@
+-- Migration 023: SLA timezone
+-- SLA deadlines are counted in calendar days of the timezone of the ImageScan (IANA name)
+
+ALTER TABLE scans
+ADD COLUMN IF NOT EXISTS sla_time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+COMMENT ON COLUMN scans.sla_time_zone IS 'Timezone in which SLA due dates are computed';
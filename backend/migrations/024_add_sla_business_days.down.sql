@@ -0,0 +1,5 @@
+-- Rollback: Remove business-day SLAs
+
+ALTER TABLE scans
+DROP COLUMN IF EXISTS sla_holidays,
+DROP COLUMN IF EXISTS sla_business_days;
@@ -0,0 +1,9 @@
+-- Migration 024: Business-day SLAs
+-- SLA days can be counted as business days (Monday to Friday) skipping the holidays of the ImageScan
+
+ALTER TABLE scans
+ADD COLUMN IF NOT EXISTS sla_business_days BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS sla_holidays DATE[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN scans.sla_business_days IS 'Whether SLA days are business days rather than calendar days';
+COMMENT ON COLUMN scans.sla_holidays IS 'Dates that are not business days for the SLA';
@@ -0,0 +1,9 @@
+-- Rollback: Remove team usage and soft quotas
+
+DROP TABLE IF EXISTS team_quotas;
+
+DROP INDEX IF EXISTS idx_scans_imagescan_namespace;
+
+ALTER TABLE scans
+DROP COLUMN IF EXISTS imagescan_name,
+DROP COLUMN IF EXISTS imagescan_namespace;
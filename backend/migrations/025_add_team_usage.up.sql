@@ -0,0 +1,24 @@
+-- Migration 025: Team usage and soft quotas
+-- A team is the Kubernetes namespace of its ImageScans. Scans record the ImageScan that ran them
+-- so usage can be charged back, and teams may get quotas that warn or reject when exceeded
+
+ALTER TABLE scans
+ADD COLUMN IF NOT EXISTS imagescan_namespace VARCHAR(253),
+ADD COLUMN IF NOT EXISTS imagescan_name VARCHAR(253);
+
+-- idx_scans_imagescan_namespace is built online once the backend is up (internal/online)
+
+CREATE TABLE IF NOT EXISTS team_quotas (
+    namespace VARCHAR(253) PRIMARY KEY,
+    max_scans_per_month INTEGER,
+    max_sbom_storage_bytes BIGINT,
+    max_vulnerabilities INTEGER,
+    enforce BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+COMMENT ON COLUMN scans.imagescan_namespace IS 'Kubernetes namespace of the ImageScan that ran the scan, the team it is charged to';
+COMMENT ON COLUMN scans.imagescan_name IS 'Name of the ImageScan that ran the scan';
+COMMENT ON TABLE team_quotas IS 'Soft quotas per team (namespace), unset limits are unlimited';
+COMMENT ON COLUMN team_quotas.enforce IS 'Reject new scans once a limit is exceeded instead of only warning';
@@ -0,0 +1,10 @@
+-- Rollback: Restore the webhook URL column type
+-- Decrypt the columns first (reencrypt -decrypt), encrypted values don't fit VARCHAR(2048)
+
+COMMENT ON COLUMN vulnerability_history.new_value IS NULL;
+COMMENT ON COLUMN vulnerability_history.old_value IS NULL;
+COMMENT ON COLUMN vulnerabilities.notes IS NULL;
+COMMENT ON COLUMN watchlist_subscriptions.webhook_url IS NULL;
+
+ALTER TABLE imagescan_webhook_configs ALTER COLUMN webhook_url TYPE VARCHAR(2048);
+COMMENT ON COLUMN imagescan_webhook_configs.webhook_url IS NULL;
@@ -0,0 +1,12 @@
+-- Migration 026: Column-level encryption of webhook URLs and notes
+-- When DB_ENCRYPTION_KEY is set the backend stores these columns as enc:v1:<key id>:<base64>
+-- AES-256-GCM values. Existing rows stay readable and are encrypted with the reencrypt tool
+
+-- Encrypted values are longer than the URL they hold
+ALTER TABLE imagescan_webhook_configs ALTER COLUMN webhook_url TYPE TEXT;
+
+COMMENT ON COLUMN imagescan_webhook_configs.webhook_url IS 'Webhook URL, encrypted when DB_ENCRYPTION_KEY is set';
+COMMENT ON COLUMN watchlist_subscriptions.webhook_url IS 'Webhook URL, encrypted when DB_ENCRYPTION_KEY is set';
+COMMENT ON COLUMN vulnerabilities.notes IS 'Triage notes, encrypted when DB_ENCRYPTION_KEY is set';
+COMMENT ON COLUMN vulnerability_history.old_value IS 'Previous value, encrypted for notes changes when DB_ENCRYPTION_KEY is set';
+COMMENT ON COLUMN vulnerability_history.new_value IS 'New value, encrypted for notes changes when DB_ENCRYPTION_KEY is set';
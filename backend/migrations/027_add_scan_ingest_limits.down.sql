@@ -0,0 +1,6 @@
+-- Rollback: Remove ingest limit metadata from scans
+
+ALTER TABLE scans
+DROP COLUMN IF EXISTS fields_truncated,
+DROP COLUMN IF EXISTS matches_dropped,
+DROP COLUMN IF EXISTS matches_received;
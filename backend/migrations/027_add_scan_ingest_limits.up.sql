@@ -0,0 +1,11 @@
+-- Migration 027: Record what ingest limits removed from a scan submission
+-- Results over the match limit keep their most severe matches and are stored as partial scans
+
+ALTER TABLE scans
+ADD COLUMN IF NOT EXISTS matches_received INTEGER,
+ADD COLUMN IF NOT EXISTS matches_dropped INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS fields_truncated INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN scans.matches_received IS 'Number of Grype matches submitted, before ingest limits';
+COMMENT ON COLUMN scans.matches_dropped IS 'Matches not stored: over the per-scan limit or with identifiers too long to store';
+COMMENT ON COLUMN scans.fields_truncated IS 'Match fields cut to the maximum string length';
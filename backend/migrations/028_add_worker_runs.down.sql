@@ -0,0 +1,3 @@
+-- Rollback: Remove background worker runs
+
+DROP TABLE IF EXISTS worker_runs;
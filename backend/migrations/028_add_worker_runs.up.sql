@@ -0,0 +1,20 @@
+-- Migration 028: Last run of the periodic background workers
+-- With several backend replicas every worker runs on one replica at a time, under a Postgres
+-- advisory lock. Runs are recorded here so replicas skip a worker that already ran this interval
+
+CREATE TABLE IF NOT EXISTS worker_runs (
+    name VARCHAR(100) PRIMARY KEY,
+    status VARCHAR(20) NOT NULL,
+    instance VARCHAR(255) NOT NULL,
+    last_started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_finished_at TIMESTAMP WITH TIME ZONE,
+    last_succeeded_at TIMESTAMP WITH TIME ZONE,
+    last_duration_ms BIGINT,
+    last_error TEXT,
+    run_count BIGINT NOT NULL DEFAULT 0,
+    failure_count BIGINT NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE worker_runs IS 'Last run of each periodic background worker (retention, stale-scan alerts)';
+COMMENT ON COLUMN worker_runs.status IS 'running, succeeded or failed';
+COMMENT ON COLUMN worker_runs.instance IS 'Hostname of the backend replica that ran the worker last';
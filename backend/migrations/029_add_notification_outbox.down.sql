@@ -0,0 +1,4 @@
+-- Rollback: Remove the notification outbox
+-- Pending notifications are lost
+
+DROP TABLE IF EXISTS notification_outbox;
@@ -0,0 +1,28 @@
+-- Migration 029: Transactional outbox for webhook notifications
+-- Notifications are written in the same transaction as the change they report, then delivered
+-- by the dispatcher worker with retries, so a replica restarting mid-request doesn't lose them
+
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    scan_id INTEGER REFERENCES scans(id) ON DELETE CASCADE,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_pending ON notification_outbox(available_at)
+WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_scan ON notification_outbox(scan_id)
+WHERE scan_id IS NOT NULL;
+
+COMMENT ON TABLE notification_outbox IS 'Webhook notifications waiting for delivery, delivered at least once';
+COMMENT ON COLUMN notification_outbox.kind IS 'scan_completed, watchlist or status_change';
+COMMENT ON COLUMN notification_outbox.payload IS 'JSON payload, encrypted when DB_ENCRYPTION_KEY is set';
+COMMENT ON COLUMN notification_outbox.available_at IS 'Not delivered before, for retries and scan notifications held until results are processed';
+COMMENT ON COLUMN notification_outbox.locked_until IS 'Lease of the dispatcher delivering the event, expired leases are delivered again';
@@ -0,0 +1,7 @@
+-- Rollback: Remove shared SBOM documents
+-- Scans recorded as unchanged lose their SBOM document
+
+DROP INDEX IF EXISTS idx_sboms_document_scan_id;
+
+ALTER TABLE sboms
+DROP COLUMN IF EXISTS document_scan_id;
@@ -0,0 +1,12 @@
+-- Migration 030: Shared SBOM documents
+-- Delta submissions of unchanged images record a new scan without uploading the SBOM again, its
+-- metadata row points at the document of the earlier scan instead of storing a copy in S3
+
+ALTER TABLE sboms
+ADD COLUMN IF NOT EXISTS document_scan_id INTEGER;
+
+-- Not a foreign key: the S3 object is kept while any SBOM still refers to it, even after the scan
+-- that stored it was pruned
+CREATE INDEX IF NOT EXISTS idx_sboms_document_scan_id ON sboms(document_scan_id) WHERE document_scan_id IS NOT NULL;
+
+COMMENT ON COLUMN sboms.document_scan_id IS 'Scan whose S3 object holds the document, NULL when the scan stored its own';
@@ -0,0 +1,8 @@
+-- Rollback: Remove the image risk factors
+
+ALTER TABLE imagescans
+DROP CONSTRAINT IF EXISTS imagescans_exposure_check,
+DROP COLUMN IF EXISTS exposure;
+
+ALTER TABLE vulnerabilities
+DROP COLUMN IF EXISTS known_exploited;
@@ -0,0 +1,13 @@
+-- Migration 031: Record the risk factors used to rank images for patching
+-- Grype reports vulnerabilities in CISA's Known Exploited Vulnerabilities catalog, ImageScans declare
+-- how exposed the image's workloads are
+
+ALTER TABLE vulnerabilities
+ADD COLUMN IF NOT EXISTS known_exploited BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE imagescans
+ADD COLUMN IF NOT EXISTS exposure VARCHAR(16),
+ADD CONSTRAINT imagescans_exposure_check CHECK (exposure IS NULL OR exposure IN ('internet', 'internal', 'isolated'));
+
+COMMENT ON COLUMN vulnerabilities.known_exploited IS 'Listed in the CISA KEV catalog by the Grype database of the latest scan';
+COMMENT ON COLUMN imagescans.exposure IS 'spec.exposure of the ImageScan: internet, internal or isolated';
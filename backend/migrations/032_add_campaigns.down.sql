@@ -0,0 +1,6 @@
+-- Rollback: Remove remediation campaigns
+
+DROP INDEX IF EXISTS idx_campaigns_owners;
+DROP INDEX IF EXISTS idx_campaign_vulnerabilities_vulnerability_id;
+DROP TABLE IF EXISTS campaign_vulnerabilities;
+DROP TABLE IF EXISTS campaigns;
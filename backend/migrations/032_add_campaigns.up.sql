@@ -0,0 +1,36 @@
+-- Migration 032: Add remediation campaigns
+-- A campaign groups vulnerabilities into an initiative such as an OpenSSL upgrade: the ones
+-- attached to it and, when it has a filter, every vulnerability matching all of its criteria,
+-- including ones detected after the campaign started.
+
+CREATE TABLE IF NOT EXISTS campaigns (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    owners TEXT[] NOT NULL DEFAULT '{}',
+    due_at TIMESTAMP WITH TIME ZONE,
+    filter_cve_id VARCHAR(50),
+    filter_package_name VARCHAR(255),
+    filter_package_version_prefix VARCHAR(255),
+    filter_severity VARCHAR(20),
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT campaigns_status_check CHECK (status IN ('active', 'completed', 'cancelled'))
+);
+
+CREATE TABLE IF NOT EXISTS campaign_vulnerabilities (
+    campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    vulnerability_id INTEGER NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    added_by VARCHAR(255),
+    added_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (campaign_id, vulnerability_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_vulnerabilities_vulnerability_id ON campaign_vulnerabilities(vulnerability_id);
+CREATE INDEX IF NOT EXISTS idx_campaigns_owners ON campaigns USING GIN (owners);
+
+COMMENT ON TABLE campaigns IS 'Remediation initiatives grouping vulnerabilities, tracked by progress and burndown';
+COMMENT ON COLUMN campaigns.owners IS 'People or teams accountable for the campaign';
+COMMENT ON COLUMN campaigns.filter_package_version_prefix IS 'Matches package versions starting with it, e.g. 3. for OpenSSL 3.x';
@@ -0,0 +1,10 @@
+-- Rollback: Remove fix-available tracking and notifications
+
+ALTER TABLE imagescan_webhook_configs
+DROP COLUMN IF EXISTS fix_available_min_severity,
+DROP COLUMN IF EXISTS fix_available_enabled;
+
+DROP INDEX IF EXISTS idx_vulnerabilities_fix_became_available;
+
+ALTER TABLE vulnerabilities
+DROP COLUMN IF EXISTS fix_became_available_at;
@@ -0,0 +1,15 @@
+-- Migration 033: Track when findings gain a fix and notify the ImageScans affected
+-- A finding that had no fix version in earlier scans gets one when the Grype database learns about
+-- the fix, which is the moment teams can act on it
+
+ALTER TABLE vulnerabilities
+ADD COLUMN IF NOT EXISTS fix_became_available_at TIMESTAMP WITH TIME ZONE;
+
+-- idx_vulnerabilities_fix_became_available is built online once the backend is up (internal/online)
+
+ALTER TABLE imagescan_webhook_configs
+ADD COLUMN IF NOT EXISTS fix_available_enabled BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS fix_available_min_severity VARCHAR(50) NOT NULL DEFAULT 'High';
+
+COMMENT ON COLUMN vulnerabilities.fix_became_available_at IS 'When a scan reported a fix version for this finding after the previous scans reported none';
+COMMENT ON COLUMN imagescan_webhook_configs.fix_available_enabled IS 'Send a notification when a finding of the ImageScan''s image gains a fix version';
@@ -0,0 +1,4 @@
+-- Rollback: Remove signed scan reports
+-- Documents already stored in the bucket are left in place
+
+DROP TABLE IF EXISTS scan_reports;
@@ -0,0 +1,17 @@
+-- Migration 034: Signed scan reports
+-- When a scan's results are processed, a signed report of its summary, findings and gate verdict
+-- is stored next to its SBOM (scans/{scan_id}/report.json). Not a foreign key: reports are audit
+-- evidence and outlive their scan, they are never updated nor pruned
+
+CREATE TABLE IF NOT EXISTS scan_reports (
+    id SERIAL PRIMARY KEY,
+    scan_id INTEGER NOT NULL,
+    key_id VARCHAR(64) NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    size_bytes BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(scan_id)
+);
+
+COMMENT ON COLUMN scan_reports.key_id IS 'Public key the report is signed with, see GET /api/v1/reports/signing-key';
+COMMENT ON COLUMN scan_reports.sha256 IS 'SHA-256 of the stored envelope, to detect a document replaced in the bucket';
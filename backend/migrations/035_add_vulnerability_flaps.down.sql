@@ -0,0 +1,3 @@
+-- Rollback: Remove vulnerability flapping detection
+
+DROP TABLE IF EXISTS vulnerability_flaps;
@@ -0,0 +1,22 @@
+-- Migration 035: Vulnerability flapping detection
+-- Matcher nondeterminism makes some findings disappear and come back across scans. Consecutive
+-- absences of a finding on an image target damp marking it fixed, reappearances are counted as flaps
+
+CREATE TABLE IF NOT EXISTS vulnerability_flaps (
+    vulnerability_id INTEGER NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    image_id INTEGER NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    target TEXT NOT NULL DEFAULT '',
+    absent_scans INTEGER NOT NULL DEFAULT 0,
+    flap_count INTEGER NOT NULL DEFAULT 0,
+    last_flapped_at TIMESTAMP WITH TIME ZONE,
+    last_scan_id INTEGER NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (vulnerability_id, image_id, target)
+);
+
+CREATE INDEX IF NOT EXISTS idx_vulnerability_flaps_image ON vulnerability_flaps(image_id, target);
+
+COMMENT ON COLUMN vulnerability_flaps.target IS 'Scan target, empty for scans of the whole image';
+COMMENT ON COLUMN vulnerability_flaps.absent_scans IS 'Consecutive trusted scans without the finding, 0 while it is detected';
+COMMENT ON COLUMN vulnerability_flaps.flap_count IS 'Times the finding came back after being absent';
+COMMENT ON COLUMN vulnerability_flaps.last_scan_id IS 'Latest scan recorded, so comparing the same scan again is a no-op';
@@ -0,0 +1,3 @@
+-- Rollback: Remove ImageScan labels
+
+ALTER TABLE imagescans DROP COLUMN IF EXISTS labels;
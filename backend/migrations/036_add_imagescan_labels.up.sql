@@ -0,0 +1,7 @@
+-- Migration 036: ImageScan labels
+-- Compliance profiles apply to the images of the ImageScans whose labels match their selector
+
+ALTER TABLE imagescans
+ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN imagescans.labels IS 'metadata.labels of the ImageScan as sorted key=value pairs';
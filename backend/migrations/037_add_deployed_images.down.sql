@@ -0,0 +1,3 @@
+-- Rollback: Remove the deployed image inventory
+
+DROP TABLE IF EXISTS deployed_images;
@@ -0,0 +1,23 @@
+-- Migration 037: Deployed image inventory
+-- Images running in clusters, reported by the controller's Pod discovery or uploaded, so the
+-- coverage report can show the deployed images nobody scans
+
+CREATE TABLE IF NOT EXISTS deployed_images (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(255) NOT NULL,
+    namespace VARCHAR(255) NOT NULL DEFAULT '',
+    image TEXT NOT NULL,
+    registry VARCHAR(255) NOT NULL,
+    repository VARCHAR(255) NOT NULL,
+    tag VARCHAR(128) NOT NULL,
+    digest VARCHAR(255),
+    reported_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (source, namespace, image)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deployed_images_ref ON deployed_images(registry, repository, tag);
+
+COMMENT ON COLUMN deployed_images.source IS 'Reporter of the image, replaced as a whole on each report (e.g. controller)';
+COMMENT ON COLUMN deployed_images.namespace IS 'Namespace running the image, empty when the inventory has none';
+COMMENT ON COLUMN deployed_images.image IS 'Image reference as reported';
+COMMENT ON COLUMN deployed_images.tag IS 'Tag of the reference, empty for references pinned by digest only';
@@ -0,0 +1,3 @@
+-- Rollback: Remove the workloads of deployed images
+
+DROP TABLE IF EXISTS deployed_workloads;
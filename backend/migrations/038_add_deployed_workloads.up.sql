@@ -0,0 +1,14 @@
+-- Migration 038: Workloads of deployed images
+-- The Deployments, StatefulSets... running each deployed image, so responders know what runs a
+-- vulnerable image and at what blast radius
+
+CREATE TABLE IF NOT EXISTS deployed_workloads (
+    deployed_image_id INTEGER NOT NULL REFERENCES deployed_images(id) ON DELETE CASCADE,
+    kind VARCHAR(63) NOT NULL,
+    name VARCHAR(253) NOT NULL,
+    replicas INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (deployed_image_id, kind, name)
+);
+
+COMMENT ON COLUMN deployed_workloads.kind IS 'Workload kind: Deployment, StatefulSet, DaemonSet, ReplicaSet, Job or Pod';
+COMMENT ON COLUMN deployed_workloads.replicas IS 'Running and pending Pods of the workload with the image when reported';
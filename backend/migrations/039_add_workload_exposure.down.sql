@@ -0,0 +1,4 @@
+-- Rollback: Remove the internet exposure of deployed workloads
+
+ALTER TABLE deployed_workloads
+DROP COLUMN IF EXISTS exposed;
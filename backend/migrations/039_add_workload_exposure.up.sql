@@ -0,0 +1,8 @@
+-- Migration 039: Internet exposure of deployed workloads
+-- Workloads behind an Ingress or a LoadBalancer Service are internet-facing, which weighs the risk
+-- score of the images they run like a declared internet exposure
+
+ALTER TABLE deployed_workloads
+ADD COLUMN IF NOT EXISTS exposed BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN deployed_workloads.exposed IS 'Whether an Ingress or a LoadBalancer Service routes to Pods of the workload';
@@ -0,0 +1,12 @@
+-- Rollback: Remove the review of discovered images
+
+DROP INDEX IF EXISTS idx_images_unreviewed;
+
+ALTER TABLE images
+DROP CONSTRAINT IF EXISTS images_criticality_check;
+
+ALTER TABLE images
+DROP COLUMN IF EXISTS reviewed_by,
+DROP COLUMN IF EXISTS reviewed_at,
+DROP COLUMN IF EXISTS criticality,
+DROP COLUMN IF EXISTS team;
@@ -0,0 +1,21 @@
+-- Migration 040: Review of discovered images
+-- An image first seen in a scan submission is unreviewed until it is assigned a team and a
+-- criticality, so images nobody registered show up instead of staying in the long tail
+
+ALTER TABLE images
+ADD COLUMN IF NOT EXISTS team TEXT,
+ADD COLUMN IF NOT EXISTS criticality VARCHAR(20),
+ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS reviewed_by TEXT;
+
+ALTER TABLE images
+ADD CONSTRAINT images_criticality_check CHECK (criticality IN ('low', 'medium', 'high', 'critical'));
+
+-- Images known before the migration are not new
+UPDATE images SET reviewed_at = created_at WHERE reviewed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_images_unreviewed ON images(created_at) WHERE reviewed_at IS NULL;
+
+COMMENT ON COLUMN images.team IS 'Team owning the image, assigned on review';
+COMMENT ON COLUMN images.criticality IS 'Business criticality of the image: low, medium, high or critical';
+COMMENT ON COLUMN images.reviewed_at IS 'When the image was first assigned a team and a criticality, NULL while unreviewed';
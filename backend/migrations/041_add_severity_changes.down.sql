@@ -0,0 +1,6 @@
+-- Rollback: Remove severity changes
+
+DROP TABLE IF EXISTS vulnerability_severity_changes;
+
+ALTER TABLE vulnerabilities
+DROP COLUMN IF EXISTS initial_severity;
@@ -0,0 +1,27 @@
+-- Migration 041: Severity changes
+-- Grype rescores CVEs between DB builds. The severity a vulnerability had when first detected is
+-- kept so SLA deadlines don't move with the rescoring, and every change is recorded
+
+-- A constant default doesn't rewrite the table. Vulnerabilities known before the migration get their
+-- severity by the vulnerabilities_initial_severity backfill (internal/online), until then '' stands
+-- for the current severity
+ALTER TABLE vulnerabilities
+ADD COLUMN IF NOT EXISTS initial_severity VARCHAR(20) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS vulnerability_severity_changes (
+    id SERIAL PRIMARY KEY,
+    vulnerability_id INTEGER NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    cve_id VARCHAR(50) NOT NULL,
+    old_severity VARCHAR(20),
+    new_severity VARCHAR(20),
+    scan_id INTEGER REFERENCES scans(id) ON DELETE SET NULL,
+    grype_db_built TIMESTAMP WITH TIME ZONE,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vulnerability_severity_changes_cve_id ON vulnerability_severity_changes(cve_id, changed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_vulnerability_severity_changes_vulnerability_id ON vulnerability_severity_changes(vulnerability_id);
+
+COMMENT ON COLUMN vulnerabilities.initial_severity IS 'Severity when first detected, the one SLA deadlines are counted from by default';
+COMMENT ON COLUMN vulnerability_severity_changes.scan_id IS 'Scan that reported the new severity';
+COMMENT ON COLUMN vulnerability_severity_changes.grype_db_built IS 'Build time of the Grype DB of that scan';
@@ -0,0 +1,6 @@
+-- Rollback: Remove vulnerability search indexes
+-- pg_trgm is left installed, other objects may use it
+
+DROP INDEX IF EXISTS idx_vulnerabilities_description_fts;
+DROP INDEX IF EXISTS idx_vulnerabilities_cve_id_trgm;
+DROP INDEX IF EXISTS idx_vulnerabilities_package_name_trgm;
@@ -0,0 +1,8 @@
+-- Migration 042: Vulnerability search
+-- Package names are searched by substring and CVE IDs by prefix with trigram indexes, descriptions
+-- by full-text search
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- idx_vulnerabilities_package_name_trgm, idx_vulnerabilities_cve_id_trgm and
+-- idx_vulnerabilities_description_fts are built online once the backend is up (internal/online)
@@ -0,0 +1,6 @@
+-- Rollback: Remove waivers
+
+DROP INDEX IF EXISTS idx_waivers_image_id;
+DROP INDEX IF EXISTS idx_waivers_expires_at;
+DROP INDEX IF EXISTS idx_waivers_in_effect;
+DROP TABLE IF EXISTS waivers;
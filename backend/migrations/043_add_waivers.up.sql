@@ -0,0 +1,23 @@
+-- Migration 043: Add waivers
+-- A waiver accepts the risk of a vulnerability on an image until it expires, with a justification.
+-- Once it expires the vulnerability goes back to active, unless another waiver still covers it,
+-- so accepted risks are reassessed instead of staying accepted forever
+
+CREATE TABLE IF NOT EXISTS waivers (
+    id SERIAL PRIMARY KEY,
+    vulnerability_id INTEGER NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    image_id INTEGER NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    justification TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expired_at TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- One waiver in effect per vulnerability and image, expired ones are kept as history
+CREATE UNIQUE INDEX IF NOT EXISTS idx_waivers_in_effect ON waivers(vulnerability_id, image_id) WHERE expired_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_waivers_expires_at ON waivers(expires_at) WHERE expired_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_waivers_image_id ON waivers(image_id);
+
+COMMENT ON TABLE waivers IS 'Accepted risks of vulnerabilities on images, reverted to active when they expire';
+COMMENT ON COLUMN waivers.expired_at IS 'When the expiry job reverted the waiver, NULL while it is in effect';
@@ -0,0 +1,3 @@
+-- Rollback: Remove the progress of online schema changes
+
+DROP TABLE IF EXISTS online_changes;
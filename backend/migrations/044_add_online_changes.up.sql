@@ -0,0 +1,21 @@
+-- Migration 044: Progress of online schema changes
+-- Index builds and backfills of large tables run in the background once the backend is up
+-- (internal/online) instead of in migrations, which lock the tables they change. Their progress is
+-- recorded here so they resume where they stopped on another replica or after a restart
+
+CREATE TABLE IF NOT EXISTS online_changes (
+    name VARCHAR(100) PRIMARY KEY,
+    status VARCHAR(20) NOT NULL,
+    last_id BIGINT NOT NULL DEFAULT 0,
+    max_id BIGINT NOT NULL DEFAULT 0,
+    rows_updated BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+COMMENT ON TABLE online_changes IS 'Progress of the index builds and backfills applied in the background';
+COMMENT ON COLUMN online_changes.status IS 'running, completed or failed';
+COMMENT ON COLUMN online_changes.last_id IS 'Highest ID a backfill updated so far';
+COMMENT ON COLUMN online_changes.max_id IS 'Highest ID of the table when a backfill started, the last one it updates';
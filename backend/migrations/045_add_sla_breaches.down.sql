@@ -0,0 +1,3 @@
+-- Rollback: Remove SLA breaches
+
+DROP TABLE IF EXISTS sla_breaches;
@@ -0,0 +1,13 @@
+-- Migration 045: Add SLA breaches
+-- Vulnerabilities whose SLA deadline passed while they were still open, so the sla.breached event
+-- of the outgoing event webhook is sent once per vulnerability
+
+CREATE TABLE IF NOT EXISTS sla_breaches (
+    vulnerability_id INTEGER PRIMARY KEY REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    image_id INTEGER REFERENCES images(id) ON DELETE SET NULL,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE sla_breaches IS 'Open vulnerabilities found past their SLA deadline by the SLA monitor';
+COMMENT ON COLUMN sla_breaches.image_id IS 'Image of the latest scan that found the vulnerability, whose SLA was breached';
@@ -0,0 +1,4 @@
+-- Rollback: Remove ticket callbacks
+
+DROP INDEX IF EXISTS idx_ticket_callbacks_ticket_id;
+DROP TABLE IF EXISTS ticket_callbacks;
@@ -0,0 +1,17 @@
+-- Migration 046: Add ticket callbacks
+-- Callbacks from ticketing systems (Jira, ServiceNow) applied to vulnerabilities, recorded by event ID
+-- so a callback retried by the sender is not applied twice
+
+CREATE TABLE IF NOT EXISTS ticket_callbacks (
+    event_id VARCHAR(255) PRIMARY KEY,
+    ticket_id VARCHAR(255) NOT NULL,
+    ticket_status VARCHAR(255) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    vulnerability_ids INTEGER[] NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_callbacks_ticket_id ON ticket_callbacks(ticket_id);
+
+COMMENT ON TABLE ticket_callbacks IS 'Ticket callbacks applied to vulnerabilities, a known event_id is not applied again';
+COMMENT ON COLUMN ticket_callbacks.status IS 'Vulnerability status the ticket status was mapped to';
@@ -0,0 +1,3 @@
+-- Rollback: Remove ingest jobs
+
+DROP TABLE IF EXISTS ingest_jobs;
@@ -0,0 +1,29 @@
+-- Migration 047: Add ingest jobs
+-- Scan submissions store the scan and queue the processing of its matches, done in the background
+-- by the ingest workers of any replica, so large results don't time out the scanner
+
+CREATE TABLE IF NOT EXISTS ingest_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    scan_id INTEGER NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    params TEXT NOT NULL,
+    matches BYTEA,
+    match_count INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingest_jobs_queued ON ingest_jobs(available_at)
+WHERE status IN ('queued', 'running');
+CREATE INDEX IF NOT EXISTS idx_ingest_jobs_scan ON ingest_jobs(scan_id);
+
+COMMENT ON TABLE ingest_jobs IS 'Processing of the results of scan submissions, run by the ingest workers';
+COMMENT ON COLUMN ingest_jobs.status IS 'queued, running, completed or failed (gave up after the maximum number of attempts)';
+COMMENT ON COLUMN ingest_jobs.params IS 'JSON of what processing the results needs besides the matches';
+COMMENT ON COLUMN ingest_jobs.matches IS 'JSON array of the Grype matches after the ingest limits, cleared once the job is finished';
+COMMENT ON COLUMN ingest_jobs.locked_until IS 'Lease of the worker running the job, extended while it runs; expired leases are claimed again';
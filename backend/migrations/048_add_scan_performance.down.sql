@@ -0,0 +1,6 @@
+-- Rollback: Remove scan duration and image size
+
+ALTER TABLE scans
+DROP COLUMN IF EXISTS scan_duration_ms,
+DROP COLUMN IF EXISTS image_size_bytes,
+DROP COLUMN IF EXISTS layer_count;
@@ -0,0 +1,12 @@
+-- Migration 048: Record how long scans take and the size of the scanned images
+-- Reported by the scanner, so images whose scans take disproportionately long stand out when
+-- sizing the workspace and resources of their ImageScans
+
+ALTER TABLE scans
+ADD COLUMN IF NOT EXISTS scan_duration_ms BIGINT,
+ADD COLUMN IF NOT EXISTS image_size_bytes BIGINT,
+ADD COLUMN IF NOT EXISTS layer_count INTEGER;
+
+COMMENT ON COLUMN scans.scan_duration_ms IS 'Time the scanner took to generate the SBOM and match it, before submitting the results';
+COMMENT ON COLUMN scans.image_size_bytes IS 'Uncompressed size of the scanned image';
+COMMENT ON COLUMN scans.layer_count IS 'Number of layers of the scanned image';
@@ -0,0 +1,6 @@
+-- Rollback: Remove scan submission UUIDs
+
+DROP INDEX IF EXISTS idx_scans_scan_uuid;
+
+ALTER TABLE scans
+DROP COLUMN IF EXISTS scan_uuid;
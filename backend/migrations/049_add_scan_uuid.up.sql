@@ -0,0 +1,9 @@
+-- Migration 049: Identify scan submissions by a UUID chosen by the scanner
+-- A scanner retrying a submission after a network error sends the same UUID, and gets the scan
+-- the first attempt created instead of a duplicate
+-- idx_scans_scan_uuid, unique, is built online once the backend is up (internal/online)
+
+ALTER TABLE scans
+ADD COLUMN IF NOT EXISTS scan_uuid UUID;
+
+COMMENT ON COLUMN scans.scan_uuid IS 'UUID the scanner submitted the scan with, unique, so retried submissions return the scan instead of creating another one';
@@ -0,0 +1,5 @@
+-- Rollback: Remove the SBOM quality
+
+ALTER TABLE sboms
+DROP COLUMN IF EXISTS quality,
+DROP COLUMN IF EXISTS quality_score;
@@ -0,0 +1,10 @@
+-- Migration 050: SBOM quality
+-- Grype only matches the packages an SBOM lists, so the SBOMs Syft produced sparse or without
+-- purls are scored when they are stored, to flag the scans whose findings can't be trusted
+
+ALTER TABLE sboms
+ADD COLUMN IF NOT EXISTS quality_score INTEGER,
+ADD COLUMN IF NOT EXISTS quality JSONB;
+
+COMMENT ON COLUMN sboms.quality_score IS 'Quality of the document from 0 to 100, NULL until it is evaluated';
+COMMENT ON COLUMN sboms.quality IS 'Coverage, NTIA minimum elements and flags behind quality_score';
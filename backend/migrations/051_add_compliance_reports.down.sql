@@ -0,0 +1,5 @@
+-- Rollback: Remove compliance reports
+
+DROP INDEX IF EXISTS idx_compliance_reports_created_at;
+DROP INDEX IF EXISTS idx_compliance_reports_schedule_period;
+DROP TABLE IF EXISTS compliance_reports;
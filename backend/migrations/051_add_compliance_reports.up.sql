@@ -0,0 +1,27 @@
+-- Migration 051: Compliance reports
+-- Periodic reports on the vulnerability posture, SLA compliance and new vs fixed trends, generated
+-- on a schedule or on demand. The documents are stored in S3, the last report of each schedule is
+-- where the next one starts
+
+CREATE TABLE IF NOT EXISTS compliance_reports (
+    id BIGSERIAL PRIMARY KEY,
+    schedule VARCHAR(255),
+    format VARCHAR(10) NOT NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key TEXT,
+    destinations TEXT[] NOT NULL DEFAULT '{}',
+    errors TEXT[] NOT NULL DEFAULT '{}',
+    requested_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT compliance_reports_format_check CHECK (format IN ('html', 'pdf'))
+);
+
+-- A schedule runs once per activation, even with several replicas
+CREATE UNIQUE INDEX IF NOT EXISTS idx_compliance_reports_schedule_period
+    ON compliance_reports(schedule, period_end) WHERE schedule IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_compliance_reports_created_at ON compliance_reports(created_at DESC);
+
+COMMENT ON TABLE compliance_reports IS 'Generated compliance reports, the documents are in S3 at storage_key';
+COMMENT ON COLUMN compliance_reports.schedule IS 'Name of the schedule of REPORT_SCHEDULES_FILE, NULL for reports generated on demand';
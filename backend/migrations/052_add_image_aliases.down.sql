@@ -0,0 +1,5 @@
+-- Rollback: Remove the image alias index
+
+DROP INDEX IF EXISTS idx_images_digest;
+
+COMMENT ON COLUMN images.digest IS NULL;
@@ -0,0 +1,7 @@
+-- Migration 052: Image aliases
+-- The same digest pushed under several names (a mirror, a promotion to another repository, several
+-- tags) is tracked as one image per name; images sharing their current digest are aliases
+
+CREATE INDEX IF NOT EXISTS idx_images_digest ON images(digest) WHERE digest IS NOT NULL;
+
+COMMENT ON COLUMN images.digest IS 'Digest of the latest scan of the image, shared by its aliases';
@@ -0,0 +1,7 @@
+-- Rollback: Remove advisory and fix links
+
+DROP INDEX IF EXISTS idx_vulnerability_links_image_id;
+DROP INDEX IF EXISTS idx_vulnerability_links_url;
+DROP TABLE IF EXISTS vulnerability_links;
+
+ALTER TABLE vulnerabilities DROP COLUMN IF EXISTS advisories;
@@ -0,0 +1,23 @@
+-- Migration 053: Advisory and fix links
+-- The advisories Grype reports for a vulnerability (distro security notices, GHSA) are kept with it,
+-- and users attach their own links to a vulnerability on an image, such as the MR/PR fixing it
+
+-- Nullable, no rewrite of vulnerabilities. Rows get their advisories on their next scan
+ALTER TABLE vulnerabilities ADD COLUMN IF NOT EXISTS advisories JSONB;
+
+COMMENT ON COLUMN vulnerabilities.advisories IS 'Advisories of the latest scan reporting the vulnerability, [{"id", "link"}], NULL when it reported none';
+
+CREATE TABLE IF NOT EXISTS vulnerability_links (
+    id SERIAL PRIMARY KEY,
+    vulnerability_id INTEGER NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    image_id INTEGER NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    title VARCHAR(255),
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vulnerability_links_url ON vulnerability_links(vulnerability_id, image_id, url);
+CREATE INDEX IF NOT EXISTS idx_vulnerability_links_image_id ON vulnerability_links(image_id);
+
+COMMENT ON TABLE vulnerability_links IS 'Links users attached to vulnerabilities on images, such as the MR/PR fixing them';
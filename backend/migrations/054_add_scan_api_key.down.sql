@@ -0,0 +1,3 @@
+-- Rollback: Remove the API key of scans
+
+ALTER TABLE scans DROP COLUMN IF EXISTS api_key_name;
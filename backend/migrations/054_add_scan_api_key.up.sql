@@ -0,0 +1,8 @@
+-- Migration 054: API key of scans submitted from CI
+-- Scans submitted on /api/v1/ci record the name of the key they were submitted with, so that the CI routes
+-- only serve a key the scans it submitted
+
+-- Nullable, no rewrite of scans. Scans submitted before are served to no key
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS api_key_name VARCHAR(255);
+
+COMMENT ON COLUMN scans.api_key_name IS 'Name of the SCANNER_API_KEYS entry the scan was submitted with, NULL for scans submitted without an API key';
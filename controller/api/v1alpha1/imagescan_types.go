@@ -5,6 +5,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ScanRequestedAnnotation requests an on-demand scan of an ImageScan. The controller runs a scan
+// whenever its value changes, which kubectl invulnerable scan sets to the current time
+const ScanRequestedAnnotation = "invulnerable.io/scan-requested-at"
+
 // ImageScanSpec defines the desired state of ImageScan
 type ImageScanSpec struct {
 	// Image is the container image to scan (e.g., "nginx:latest", "myregistry.io/app:1.0.0")
@@ -52,11 +56,22 @@ type ImageScanSpec struct {
 	// +kubebuilder:default="10Gi"
 	WorkspaceSize string `json:"workspaceSize,omitempty"`
 
+	// AutoSizing applies the workspace size and memory request recommended in status.sizing,
+	// from the images of recent scans, to scan Jobs instead of workspaceSize and the memory
+	// request of resources. If not specified, the recommendation is only recorded
+	// +kubebuilder:validation:Optional
+	AutoSizing *AutoSizingConfig `json:"autoSizing,omitempty"`
+
 	// APIEndpoint is the Invulnerable backend API endpoint
 	// If not specified, it will be auto-detected from the service
 	// +kubebuilder:validation:Optional
 	APIEndpoint string `json:"apiEndpoint,omitempty"`
 
+	// APITLS authenticates the scanner to a backend requiring client certificates (mutual TLS)
+	// The default API endpoint then uses https
+	// +kubebuilder:validation:Optional
+	APITLS *APITLSConfig `json:"apiTLS,omitempty"`
+
 	// Scanner image configuration
 	// +kubebuilder:validation:Optional
 	ScannerImage *ScannerImageSpec `json:"scannerImage,omitempty"`
@@ -90,8 +105,86 @@ type ImageScanSpec struct {
 	// When enabled, the controller periodically checks the registry for digest changes and triggers immediate scans
 	// +kubebuilder:validation:Optional
 	RegistryPolling *RegistryPollingConfig `json:"registryPolling,omitempty"`
+
+	// DeletionPolicy controls what happens to the image and scan history in the backend
+	// when this ImageScan is deleted. Retain keeps it, Delete removes it unless another
+	// ImageScan still scans the same image
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="Retain"
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// StaleAfter is how long the image may go without a successful scan before the backend
+	// reports it as stale and alerts the webhook. Set it above the schedule interval,
+	// e.g. 48h for a daily scan. If not specified, the backend default is used
+	// +kubebuilder:validation:Optional
+	StaleAfter *metav1.Duration `json:"staleAfter,omitempty"`
+
+	// Retention limits the scan history the backend keeps for the image
+	// If not specified, scans are kept until the image is deleted
+	// +kubebuilder:validation:Optional
+	Retention *RetentionConfig `json:"retention,omitempty"`
+
+	// Priority orders scans when they compete for capacity. Scan pods get the PriorityClass the
+	// controller maps to it, and in native scheduling mode higher priority scans are started
+	// first once the concurrent scan limit is reached
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="normal"
+	Priority ScanPriority `json:"priority,omitempty"`
+
+	// Exposure is how reachable the workloads running the image are. The backend weighs it
+	// when ranking images for patching. When several ImageScans scan the same image, the most
+	// exposed tier wins
+	// +kubebuilder:validation:Optional
+	Exposure ExposureTier `json:"exposure,omitempty"`
+
+	// RetryPolicy retries failed scan Jobs after a backoff, instead of waiting for the next
+	// scheduled run. If not specified, failed scans aren't retried
+	// +kubebuilder:validation:Optional
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// NetworkPolicy declares the endpoints scan pods reach, for namespaces denying egress by
+	// default. If not specified, the egress of scan pods isn't restricted by the controller
+	// +kubebuilder:validation:Optional
+	NetworkPolicy *NetworkPolicyConfig `json:"networkPolicy,omitempty"`
 }
 
+// ExposureTier is how reachable the workloads running an image are
+// +kubebuilder:validation:Enum=internet;internal;isolated
+type ExposureTier string
+
+const (
+	// ExposureInternet is for images serving traffic from the internet
+	ExposureInternet ExposureTier = "internet"
+	// ExposureInternal is for images only reachable from inside the organization
+	ExposureInternal ExposureTier = "internal"
+	// ExposureIsolated is for images without inbound network access, such as batch jobs
+	ExposureIsolated ExposureTier = "isolated"
+)
+
+// ScanPriority is the scheduling priority of an ImageScan's scans
+// +kubebuilder:validation:Enum=high;normal;low
+type ScanPriority string
+
+const (
+	// ScanPriorityHigh is for business-critical images
+	ScanPriorityHigh ScanPriority = "high"
+	// ScanPriorityNormal is the default priority
+	ScanPriorityNormal ScanPriority = "normal"
+	// ScanPriorityLow is for images that can wait
+	ScanPriorityLow ScanPriority = "low"
+)
+
+// DeletionPolicy describes how backend data is handled when an ImageScan is deleted
+// +kubebuilder:validation:Enum=Retain;Delete
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyRetain keeps the image and its scan history in the backend
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyDelete removes the image, its scans and SBOMs from the backend
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+)
+
 // SLAConfig defines remediation SLA in days for each severity level
 type SLAConfig struct {
 	// Critical severity SLA in days
@@ -117,6 +210,22 @@ type SLAConfig struct {
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:default=180
 	Low int `json:"low,omitempty"`
+
+	// TimeZone in which SLA days are counted (e.g., "Europe/Berlin"): a vulnerability is due at the
+	// end of its last SLA day in this timezone. Defaults to spec.timeZone, then UTC
+	// +kubebuilder:validation:Optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// BusinessDays counts the SLA days as business days (Monday to Friday, except Holidays)
+	// instead of calendar days
+	// +kubebuilder:validation:Optional
+	BusinessDays bool `json:"businessDays,omitempty"`
+
+	// Holidays are dates (YYYY-MM-DD) that are not business days, e.g. public holidays.
+	// Only used with BusinessDays
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:items:Pattern=`^\d{4}-\d{2}-\d{2}$`
+	Holidays []string `json:"holidays,omitempty"`
 }
 
 // ScheduleConfig defines time-based scanning configuration
@@ -163,6 +272,79 @@ type RegistryPollingConfig struct {
 	Interval *metav1.Duration `json:"interval,omitempty"`
 }
 
+// RetryPolicy defines how failed scan Jobs are retried
+// A retry Job is created once the failed Job's backoff has passed and no scan Job is running
+type RetryPolicy struct {
+	// MaxRetries is the number of retry Jobs created for a failed scan
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=3
+	MaxRetries int32 `json:"maxRetries,omitempty"`
+
+	// Backoff is the delay before the first retry, doubled for each following one
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="5m"
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+}
+
+// AutoSizingConfig defines whether scan Jobs are sized from the images of recent scans
+type AutoSizingConfig struct {
+	// Enabled applies status.sizing to scan Jobs once a scan reported its image size
+	// +kubebuilder:default=false
+	Enabled bool `json:"enabled"`
+}
+
+// APITLSConfig defines the client certificate of the scanner for mutual TLS with the backend
+type APITLSConfig struct {
+	// SecretName is a Secret in the ImageScan's namespace with tls.crt, tls.key and ca.crt,
+	// e.g. issued by cert-manager. ca.crt verifies the backend's certificate
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+// NetworkPolicyConfig defines the egress of scan pods
+// The controller creates a NetworkPolicy allowing only DNS (kube-dns), the backend pods of the
+// namespace and the listed IP ranges
+type NetworkPolicyConfig struct {
+	// Enabled creates the NetworkPolicy of the scan pods
+	// +kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// RegistryCIDRs are the IP ranges of the image's registry and of the storage serving its layers
+	// +kubebuilder:validation:Optional
+	RegistryCIDRs []string `json:"registryCIDRs,omitempty"`
+
+	// GrypeDBCIDRs are the IP ranges serving the Grype vulnerability database, or its mirror
+	// +kubebuilder:validation:Optional
+	GrypeDBCIDRs []string `json:"grypeDBCIDRs,omitempty"`
+
+	// BackendCIDRs are the IP ranges of the backend when apiEndpoint isn't the backend Service
+	// of the namespace, reached on the endpoint's port
+	// +kubebuilder:validation:Optional
+	BackendCIDRs []string `json:"backendCIDRs,omitempty"`
+
+	// Ports are the TCP ports of the registry and the Grype DB
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default={443}
+	Ports []int32 `json:"ports,omitempty"`
+}
+
+// RetentionConfig defines how much scan history the backend keeps for the image
+// A scan is pruned when it exceeds either limit. The latest scan and the latest successful
+// scan are always kept. When several ImageScans scan the same image, the most lenient limits apply
+type RetentionConfig struct {
+	// MaxScansRetained is the number of most recent scans to keep
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxScansRetained *int32 `json:"maxScansRetained,omitempty"`
+
+	// MaxScanAge is how long scans are kept, e.g. "720h" for 30 days
+	// +kubebuilder:validation:Optional
+	MaxScanAge *metav1.Duration `json:"maxScanAge,omitempty"`
+}
+
 // WebhooksConfig defines configuration for multiple webhook notification types
 // Both scanCompletion and statusChange webhooks share the same URL and format
 type WebhooksConfig struct {
@@ -186,6 +368,13 @@ type WebhooksConfig struct {
 	// +kubebuilder:default="slack"
 	Format string `json:"format,omitempty"`
 
+	// Locale is the language of the notification text (en, fr, de)
+	// This locale is used for all notification types
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=en;fr;de
+	// +kubebuilder:default="en"
+	Locale string `json:"locale,omitempty"`
+
 	// ScanCompletion configures notifications sent after each scan completes
 	// +kubebuilder:validation:Optional
 	ScanCompletion *ScanCompletionWebhookConfig `json:"scanCompletion,omitempty"`
@@ -193,6 +382,11 @@ type WebhooksConfig struct {
 	// StatusChange configures notifications sent when vulnerability statuses are changed
 	// +kubebuilder:validation:Optional
 	StatusChange *StatusChangeWebhookConfig `json:"statusChange,omitempty"`
+
+	// FixAvailable configures notifications sent when findings of the image that had no fix
+	// version gain one, with the number of images affected by the CVE
+	// +kubebuilder:validation:Optional
+	FixAvailable *FixAvailableWebhookConfig `json:"fixAvailable,omitempty"`
 }
 
 // ScanCompletionWebhookConfig defines webhook settings for scan completion notifications
@@ -252,6 +446,20 @@ type StatusChangeWebhookConfig struct {
 	OnlyFixable bool `json:"onlyFixable,omitempty"`
 }
 
+// FixAvailableWebhookConfig defines webhook settings for fix-available notifications
+type FixAvailableWebhookConfig struct {
+	// Enabled allows enabling/disabling fix-available notifications
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=true
+	Enabled bool `json:"enabled,omitempty"`
+
+	// MinSeverity is the minimum severity to notify about (Critical, High, Medium, Low, Negligible)
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Critical;High;Medium;Low;Negligible
+	// +kubebuilder:default="High"
+	MinSeverity string `json:"minSeverity,omitempty"`
+}
+
 // ScannerImageSpec defines the scanner container image configuration
 type ScannerImageSpec struct {
 	// Repository is the image repository
@@ -301,6 +509,88 @@ type ImageScanStatus struct {
 	// NextRegistryCheckTime is when the next registry poll is scheduled
 	// +kubebuilder:validation:Optional
 	NextRegistryCheckTime *metav1.Time `json:"nextRegistryCheckTime,omitempty"`
+
+	// LastScheduleTime is the schedule time of the last scan run by the controller's native scheduler
+	// +kubebuilder:validation:Optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// NextScheduleTime is when the native scheduler will run the next scan, jitter included
+	// +kubebuilder:validation:Optional
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+
+	// QueuePosition is the place of the due scan among the scans waiting for a free slot under
+	// the concurrent scan limit in native mode, 1 being the next to start. Unset when not waiting
+	// +kubebuilder:validation:Optional
+	QueuePosition int32 `json:"queuePosition,omitempty"`
+
+	// LastScanRequest is the invulnerable.io/scan-requested-at annotation value the controller
+	// last ran an on-demand scan for
+	// +kubebuilder:validation:Optional
+	LastScanRequest string `json:"lastScanRequest,omitempty"`
+
+	// LastFailure describes the last scan Job that failed
+	// +kubebuilder:validation:Optional
+	LastFailure *ScanFailure `json:"lastFailure,omitempty"`
+
+	// Retries is the number of retry Jobs created for the last failed scan
+	// It is reset once a scan succeeds
+	// +kubebuilder:validation:Optional
+	Retries int32 `json:"retries,omitempty"`
+
+	// NextRetryTime is when the next retry Job will be created
+	// +kubebuilder:validation:Optional
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+
+	// Sizing is the workspace size and memory request the backend recommends for scan Jobs,
+	// refreshed hourly
+	// +kubebuilder:validation:Optional
+	Sizing *SizingRecommendation `json:"sizing,omitempty"`
+}
+
+// SizingRecommendation is the sizing of scan Jobs recommended from the largest image recent scans reported
+type SizingRecommendation struct {
+	// WorkspaceSize is the recommended size of the scan workspace (e.g., "2Gi")
+	WorkspaceSize string `json:"workspaceSize"`
+
+	// MemoryRequest is the recommended memory request of the scanner container (e.g., "1280Mi")
+	MemoryRequest string `json:"memoryRequest"`
+
+	// ImageSizeBytes is the size of the largest image recent scans reported
+	ImageSizeBytes int64 `json:"imageSizeBytes"`
+
+	// Scans is the number of recent scans that reported their image size
+	Scans int32 `json:"scans"`
+
+	// UpdatedAt is when the recommendation was fetched from the backend
+	// +kubebuilder:validation:Optional
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+}
+
+// ScanFailure describes a failed scan Job and its scanner pod
+type ScanFailure struct {
+	// JobName is the name of the failed Job
+	JobName string `json:"jobName"`
+
+	// PodName is the name of the scanner pod the failure was read from
+	// +kubebuilder:validation:Optional
+	PodName string `json:"podName,omitempty"`
+
+	// Time is when the Job failed
+	// +kubebuilder:validation:Optional
+	Time *metav1.Time `json:"time,omitempty"`
+
+	// Reason is why the scanner container terminated (e.g., "Error", "OOMKilled"),
+	// or why the Job failed when no container terminated (e.g., "DeadlineExceeded")
+	// +kubebuilder:validation:Optional
+	Reason string `json:"reason,omitempty"`
+
+	// ExitCode of the scanner container
+	// +kubebuilder:validation:Optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// Message holds the last lines of the scanner container's logs, truncated
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -311,6 +601,7 @@ type ImageScanStatus struct {
 // +kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
 // +kubebuilder:printcolumn:name="CronJob",type=string,JSONPath=`.status.cronJobName`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:printcolumn:name="Queued",type=integer,JSONPath=`.status.queuePosition`,priority=1
 
 // ImageScan is the Schema for the imagescans API
 type ImageScan struct {
@@ -0,0 +1,120 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageScanSetLabel is set on the ImageScans stamped out by an ImageScanSet, to its name
+const ImageScanSetLabel = "invulnerable.io/imagescanset"
+
+// ImageScanSetSpec defines the desired state of ImageScanSet
+type ImageScanSetSpec struct {
+	// Images lists the container images to scan, one ImageScan each
+	// +kubebuilder:validation:Optional
+	Images []ImageScanSetImage `json:"images,omitempty"`
+
+	// Selector adds the container images of the Pods it selects in the ImageScanSet's namespace
+	// +kubebuilder:validation:Optional
+	Selector *ImageScanSetSelector `json:"selector,omitempty"`
+
+	// Template is the ImageScan stamped out for each image. Changes are applied to all of them
+	// +kubebuilder:validation:Required
+	Template ImageScanTemplate `json:"template"`
+}
+
+// ImageScanSetImage is an image listed by an ImageScanSet
+type ImageScanSetImage struct {
+	// Image is the container image to scan (e.g., "nginx:latest")
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// Name of the ImageScan, after the ImageScanSet's name and a dash.
+	// If not specified, it is derived from the image
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=30
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name,omitempty"`
+}
+
+// ImageScanSetSelector selects the images of running workloads
+type ImageScanSetSelector struct {
+	// PodSelector selects the Pods whose container and init container images are scanned
+	// +kubebuilder:validation:Required
+	PodSelector metav1.LabelSelector `json:"podSelector"`
+
+	// ResyncInterval is how often the selected Pods are listed again
+	// Images no longer used by any of them have their ImageScan deleted
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default="5m"
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+}
+
+// ImageScanTemplate describes the ImageScans stamped out by an ImageScanSet
+type ImageScanTemplate struct {
+	// Metadata holds the labels and annotations of the ImageScans
+	// +kubebuilder:validation:Optional
+	Metadata ImageScanTemplateMetadata `json:"metadata,omitempty"`
+
+	// Spec of the ImageScans. spec.image is set to each image of the ImageScanSet
+	// +kubebuilder:validation:Required
+	Spec ImageScanSpec `json:"spec"`
+}
+
+// ImageScanTemplateMetadata holds the labels and annotations of stamped out ImageScans
+type ImageScanTemplateMetadata struct {
+	// +kubebuilder:validation:Optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ImageScanSetStatus defines the observed state of ImageScanSet
+type ImageScanSetStatus struct {
+	// ImageScans is the number of ImageScans stamped out
+	// +kubebuilder:validation:Optional
+	ImageScans int32 `json:"imageScans,omitempty"`
+
+	// Conditions represent the latest available observations of the ImageScanSet's state
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration reflects the generation most recently observed by the controller
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastSelectorSyncTime is when the selected Pods were last listed
+	// +kubebuilder:validation:Optional
+	LastSelectorSyncTime *metav1.Time `json:"lastSelectorSyncTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=imgscanset;imgscansets
+// +kubebuilder:printcolumn:name="ImageScans",type=integer,JSONPath=`.status.imageScans`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImageScanSet stamps out an ImageScan from a template for each of its images, like a
+// Deployment does Pods
+type ImageScanSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageScanSetSpec   `json:"spec,omitempty"`
+	Status ImageScanSetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ImageScanSetList contains a list of ImageScanSet
+type ImageScanSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageScanSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageScanSet{}, &ImageScanSetList{})
+}
@@ -0,0 +1,67 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InvulnerableConfigName is the name of the InvulnerableConfig the controller applies, others are ignored
+const InvulnerableConfigName = "default"
+
+// InvulnerableConfigSpec overrides the defaults of the controller at runtime, without redeploying it.
+// Fields left unset keep the defaults of its flags
+type InvulnerableConfigSpec struct {
+	// ScannerImage is the scanner image of the ImageScans without spec.scannerImage
+	// +kubebuilder:validation:Optional
+	ScannerImage *ScannerImageSpec `json:"scannerImage,omitempty"`
+
+	// APIEndpoint is the backend API endpoint of the ImageScans without spec.apiEndpoint
+	// +kubebuilder:validation:Optional
+	APIEndpoint string `json:"apiEndpoint,omitempty"`
+
+	// APITLS is the backend client certificate of the ImageScans without spec.apiTLS. The Secret
+	// must exist in the namespace of each of them
+	// +kubebuilder:validation:Optional
+	APITLS *APITLSConfig `json:"apiTLS,omitempty"`
+
+	// MaxConcurrentScans caps the scheduled Jobs running at once in native mode (0 means no limit),
+	// instead of --max-concurrent-scans
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentScans *int32 `json:"maxConcurrentScans,omitempty"`
+
+	// NamespaceWeights is the share of free slots each namespace gets once MaxConcurrentScans is
+	// reached, instead of --namespace-scan-weights
+	// +kubebuilder:validation:Optional
+	NamespaceWeights map[string]int32 `json:"namespaceWeights,omitempty"`
+
+	// ScheduleJitter is the maximum delay added to scheduled runs in native mode, instead of
+	// --schedule-jitter
+	// +kubebuilder:validation:Optional
+	ScheduleJitter *metav1.Duration `json:"scheduleJitter,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=invconfig
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// InvulnerableConfig adjusts the defaults of the controller at runtime. Only the one named
+// "default" is applied
+type InvulnerableConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec InvulnerableConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// InvulnerableConfigList contains a list of InvulnerableConfig
+type InvulnerableConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []InvulnerableConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&InvulnerableConfig{}, &InvulnerableConfigList{})
+}
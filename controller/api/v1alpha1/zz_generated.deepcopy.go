@@ -26,6 +26,51 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APITLSConfig) DeepCopyInto(out *APITLSConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APITLSConfig.
+func (in *APITLSConfig) DeepCopy() *APITLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(APITLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoSizingConfig) DeepCopyInto(out *AutoSizingConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoSizingConfig.
+func (in *AutoSizingConfig) DeepCopy() *AutoSizingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoSizingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FixAvailableWebhookConfig) DeepCopyInto(out *FixAvailableWebhookConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FixAvailableWebhookConfig.
+func (in *FixAvailableWebhookConfig) DeepCopy() *FixAvailableWebhookConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(FixAvailableWebhookConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageScan) DeepCopyInto(out *ImageScan) {
 	*out = *in
@@ -85,6 +130,153 @@ func (in *ImageScanList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanSet) DeepCopyInto(out *ImageScanSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanSet.
+func (in *ImageScanSet) DeepCopy() *ImageScanSet {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageScanSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanSetImage) DeepCopyInto(out *ImageScanSetImage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanSetImage.
+func (in *ImageScanSetImage) DeepCopy() *ImageScanSetImage {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanSetImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanSetList) DeepCopyInto(out *ImageScanSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageScanSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanSetList.
+func (in *ImageScanSetList) DeepCopy() *ImageScanSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageScanSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanSetSelector) DeepCopyInto(out *ImageScanSetSelector) {
+	*out = *in
+	in.PodSelector.DeepCopyInto(&out.PodSelector)
+	if in.ResyncInterval != nil {
+		in, out := &in.ResyncInterval, &out.ResyncInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanSetSelector.
+func (in *ImageScanSetSelector) DeepCopy() *ImageScanSetSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanSetSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanSetSpec) DeepCopyInto(out *ImageScanSetSpec) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ImageScanSetImage, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(ImageScanSetSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanSetSpec.
+func (in *ImageScanSetSpec) DeepCopy() *ImageScanSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanSetStatus) DeepCopyInto(out *ImageScanSetStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSelectorSyncTime != nil {
+		in, out := &in.LastSelectorSyncTime, &out.LastSelectorSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanSetStatus.
+func (in *ImageScanSetStatus) DeepCopy() *ImageScanSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageScanSpec) DeepCopyInto(out *ImageScanSpec) {
 	*out = *in
@@ -113,6 +305,16 @@ func (in *ImageScanSpec) DeepCopyInto(out *ImageScanSpec) {
 		*out = new(v1.ResourceRequirements)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AutoSizing != nil {
+		in, out := &in.AutoSizing, &out.AutoSizing
+		*out = new(AutoSizingConfig)
+		**out = **in
+	}
+	if in.APITLS != nil {
+		in, out := &in.APITLS, &out.APITLS
+		*out = new(APITLSConfig)
+		**out = **in
+	}
 	if in.ScannerImage != nil {
 		in, out := &in.ScannerImage, &out.ScannerImage
 		*out = new(ScannerImageSpec)
@@ -131,13 +333,33 @@ func (in *ImageScanSpec) DeepCopyInto(out *ImageScanSpec) {
 	if in.SLA != nil {
 		in, out := &in.SLA, &out.SLA
 		*out = new(SLAConfig)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.RegistryPolling != nil {
 		in, out := &in.RegistryPolling, &out.RegistryPolling
 		*out = new(RegistryPollingConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.StaleAfter != nil {
+		in, out := &in.StaleAfter, &out.StaleAfter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(RetentionConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(NetworkPolicyConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanSpec.
@@ -172,6 +394,28 @@ func (in *ImageScanStatus) DeepCopyInto(out *ImageScanStatus) {
 		in, out := &in.NextRegistryCheckTime, &out.NextRegistryCheckTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = new(ScanFailure)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Sizing != nil {
+		in, out := &in.Sizing, &out.Sizing
+		*out = new(SizingRecommendation)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanStatus.
@@ -184,6 +428,187 @@ func (in *ImageScanStatus) DeepCopy() *ImageScanStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanTemplate) DeepCopyInto(out *ImageScanTemplate) {
+	*out = *in
+	in.Metadata.DeepCopyInto(&out.Metadata)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanTemplate.
+func (in *ImageScanTemplate) DeepCopy() *ImageScanTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanTemplateMetadata) DeepCopyInto(out *ImageScanTemplateMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanTemplateMetadata.
+func (in *ImageScanTemplateMetadata) DeepCopy() *ImageScanTemplateMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanTemplateMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InvulnerableConfig) DeepCopyInto(out *InvulnerableConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InvulnerableConfig.
+func (in *InvulnerableConfig) DeepCopy() *InvulnerableConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(InvulnerableConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *InvulnerableConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InvulnerableConfigList) DeepCopyInto(out *InvulnerableConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]InvulnerableConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InvulnerableConfigList.
+func (in *InvulnerableConfigList) DeepCopy() *InvulnerableConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(InvulnerableConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *InvulnerableConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InvulnerableConfigSpec) DeepCopyInto(out *InvulnerableConfigSpec) {
+	*out = *in
+	if in.ScannerImage != nil {
+		in, out := &in.ScannerImage, &out.ScannerImage
+		*out = new(ScannerImageSpec)
+		**out = **in
+	}
+	if in.APITLS != nil {
+		in, out := &in.APITLS, &out.APITLS
+		*out = new(APITLSConfig)
+		**out = **in
+	}
+	if in.MaxConcurrentScans != nil {
+		in, out := &in.MaxConcurrentScans, &out.MaxConcurrentScans
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NamespaceWeights != nil {
+		in, out := &in.NamespaceWeights, &out.NamespaceWeights
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ScheduleJitter != nil {
+		in, out := &in.ScheduleJitter, &out.ScheduleJitter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InvulnerableConfigSpec.
+func (in *InvulnerableConfigSpec) DeepCopy() *InvulnerableConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InvulnerableConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicyConfig) DeepCopyInto(out *NetworkPolicyConfig) {
+	*out = *in
+	if in.RegistryCIDRs != nil {
+		in, out := &in.RegistryCIDRs, &out.RegistryCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GrypeDBCIDRs != nil {
+		in, out := &in.GrypeDBCIDRs, &out.GrypeDBCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BackendCIDRs != nil {
+		in, out := &in.BackendCIDRs, &out.BackendCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicyConfig.
+func (in *NetworkPolicyConfig) DeepCopy() *NetworkPolicyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkPolicyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RegistryPollingConfig) DeepCopyInto(out *RegistryPollingConfig) {
 	*out = *in
@@ -204,9 +629,59 @@ func (in *RegistryPollingConfig) DeepCopy() *RegistryPollingConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetentionConfig) DeepCopyInto(out *RetentionConfig) {
+	*out = *in
+	if in.MaxScansRetained != nil {
+		in, out := &in.MaxScansRetained, &out.MaxScansRetained
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxScanAge != nil {
+		in, out := &in.MaxScanAge, &out.MaxScanAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionConfig.
+func (in *RetentionConfig) DeepCopy() *RetentionConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RetentionConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.Backoff != nil {
+		in, out := &in.Backoff, &out.Backoff
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SLAConfig) DeepCopyInto(out *SLAConfig) {
 	*out = *in
+	if in.Holidays != nil {
+		in, out := &in.Holidays, &out.Holidays
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLAConfig.
@@ -234,6 +709,30 @@ func (in *ScanCompletionWebhookConfig) DeepCopy() *ScanCompletionWebhookConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanFailure) DeepCopyInto(out *ScanFailure) {
+	*out = *in
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanFailure.
+func (in *ScanFailure) DeepCopy() *ScanFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScannerImageSpec) DeepCopyInto(out *ScannerImageSpec) {
 	*out = *in
@@ -264,6 +763,25 @@ func (in *ScheduleConfig) DeepCopy() *ScheduleConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SizingRecommendation) DeepCopyInto(out *SizingRecommendation) {
+	*out = *in
+	if in.UpdatedAt != nil {
+		in, out := &in.UpdatedAt, &out.UpdatedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SizingRecommendation.
+func (in *SizingRecommendation) DeepCopy() *SizingRecommendation {
+	if in == nil {
+		return nil
+	}
+	out := new(SizingRecommendation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StatusChangeWebhookConfig) DeepCopyInto(out *StatusChangeWebhookConfig) {
 	*out = *in
@@ -302,6 +820,11 @@ func (in *WebhooksConfig) DeepCopyInto(out *WebhooksConfig) {
 		*out = new(StatusChangeWebhookConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.FixAvailable != nil {
+		in, out := &in.FixAvailable, &out.FixAvailable
+		*out = new(FixAvailableWebhookConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhooksConfig.
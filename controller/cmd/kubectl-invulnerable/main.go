@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+const usage = `Usage: kubectl invulnerable <command> [flags]
+
+Commands:
+  list            list the ImageScans with the open vulnerabilities of their latest scan
+  scan <name>     request an on-demand scan of an ImageScan
+  diff <name>     show the vulnerabilities new and fixed in the latest scan of an ImageScan
+
+Flags:
+`
+
+// kubectl-invulnerable is a kubectl plugin for the ImageScans of a cluster. It reads the ImageScans
+// with the current kubeconfig context and their findings from the backend (-api-url, or
+// INVULNERABLE_API_URL), e.g. through a port-forward:
+//
+//	kubectl port-forward -n invulnerable svc/invulnerable-backend 8080 &
+//	INVULNERABLE_API_URL=http://localhost:8080 kubectl invulnerable list -A
+//
+// scan sets the invulnerable.io/scan-requested-at annotation, on which the controller starts a scan
+// Job, so it needs permission to patch ImageScans but not to create Jobs
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		newFlags("").PrintDefaults()
+		os.Exit(1)
+	}
+
+	command := os.Args[1]
+	fs := newFlags(command)
+	allNamespaces := false
+	if command == "list" {
+		fs.BoolVar(&allNamespaces, "A", false, "list the ImageScans of all namespaces")
+	}
+	args, err := parseInterspersed(fs, os.Args[2:])
+	if err != nil {
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "list":
+		if len(args) != 0 {
+			fatalf("list takes no arguments")
+		}
+		err = list(ctx, fs, allNamespaces)
+	case "scan":
+		if len(args) != 1 {
+			fatalf("scan takes the name of an ImageScan")
+		}
+		err = scan(ctx, fs, args[0])
+	case "diff":
+		if len(args) != 1 {
+			fatalf("diff takes the name of an ImageScan")
+		}
+		err = diff(ctx, fs, args[0])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		fs.SetOutput(os.Stdout)
+		fs.PrintDefaults()
+		return
+	default:
+		fatalf("unknown command %q, see kubectl invulnerable help", command)
+	}
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// flags are the options shared by all commands
+type flags struct {
+	*flag.FlagSet
+	kubeconfig  string
+	kubeContext string
+	namespace   string
+	apiURL      string
+}
+
+func newFlags(command string) *flags {
+	fs := &flags{FlagSet: flag.NewFlagSet("kubectl invulnerable "+command, flag.ContinueOnError)}
+	fs.StringVar(&fs.kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
+	fs.StringVar(&fs.kubeContext, "context", "", "kubeconfig context to use")
+	fs.StringVar(&fs.namespace, "n", "", "namespace of the ImageScans, defaults to the context's namespace")
+	fs.StringVar(&fs.apiURL, "api-url", os.Getenv("INVULNERABLE_API_URL"), "backend URL (INVULNERABLE_API_URL)")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseInterspersed parses flags given before and after the positional arguments,
+// as kubectl does: kubectl invulnerable scan nginx -n production
+func parseInterspersed(fs *flags, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// kube returns a client for ImageScans and the namespace to use, from the kubeconfig
+func (fs *flags) kube() (client.Client, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = fs.kubeconfig
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: fs.kubeContext})
+
+	namespace := fs.namespace
+	if namespace == "" {
+		var err error
+		if namespace, _, err = config.Namespace(); err != nil {
+			return nil, "", fmt.Errorf("failed to read kubeconfig: %w", err)
+		}
+	}
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(invulnerablev1alpha1.AddToScheme(scheme))
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	return c, namespace, nil
+}
+
+// backend returns a client for the Invulnerable API
+func (fs *flags) backend() (*backend, error) {
+	if fs.apiURL == "" {
+		return nil, fmt.Errorf("the backend URL is required (-api-url or INVULNERABLE_API_URL)")
+	}
+	return &backend{
+		baseURL: strings.TrimSuffix(fs.apiURL, "/") + "/api/v1",
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// list prints the ImageScans of the cluster with the open vulnerabilities of their latest scan.
+// ImageScans the backend doesn't know yet, e.g. created while it was unreachable, show no counts
+func list(ctx context.Context, fs *flags, allNamespaces bool) error {
+	c, namespace, err := fs.kube()
+	if err != nil {
+		return err
+	}
+	api, err := fs.backend()
+	if err != nil {
+		return err
+	}
+	if allNamespaces {
+		namespace = ""
+	}
+
+	imageScans := &invulnerablev1alpha1.ImageScanList{}
+	if err := c.List(ctx, imageScans, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list ImageScans: %w", err)
+	}
+	summaries, err := api.imageScans(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	if len(imageScans.Items) == 0 {
+		if allNamespaces {
+			fmt.Println("No ImageScans found")
+		} else {
+			fmt.Printf("No ImageScans found in %s namespace\n", namespace)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	printListing(w, imageScans.Items, summaries, allNamespaces, time.Now())
+	return w.Flush()
+}
+
+func printListing(w io.Writer, imageScans []invulnerablev1alpha1.ImageScan, summaries []imageScanSummary, allNamespaces bool, now time.Time) {
+	byName := map[string]imageScanSummary{}
+	for _, s := range summaries {
+		byName[s.Namespace+"/"+s.Name] = s
+	}
+
+	header := "NAME\tIMAGE\tCRITICAL\tHIGH\tMEDIUM\tLOW\tLAST SCAN"
+	if allNamespaces {
+		header = "NAMESPACE\t" + header
+	}
+	fmt.Fprintln(w, header)
+	for _, is := range imageScans {
+		row := []string{is.Name, is.Spec.Image, "-", "-", "-", "-", "<none>"}
+		if s, ok := byName[is.Namespace+"/"+is.Name]; ok && s.LatestScanDate != nil {
+			row[2], row[3], row[4], row[5] = fmt.Sprint(s.Critical), fmt.Sprint(s.High), fmt.Sprint(s.Medium), fmt.Sprint(s.Low)
+			row[6] = duration.HumanDuration(now.Sub(*s.LatestScanDate)) + " ago"
+		}
+		if allNamespaces {
+			row = append([]string{is.Namespace}, row...)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+}
+
+// scan asks the controller for an on-demand scan of an ImageScan
+func scan(ctx context.Context, fs *flags, name string) error {
+	c, namespace, err := fs.kube()
+	if err != nil {
+		return err
+	}
+
+	imageScan := &invulnerablev1alpha1.ImageScan{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, imageScan); err != nil {
+		return fmt.Errorf("failed to get ImageScan %s/%s: %w", namespace, name, err)
+	}
+
+	patch := client.MergeFrom(imageScan.DeepCopy())
+	if imageScan.Annotations == nil {
+		imageScan.Annotations = map[string]string{}
+	}
+	imageScan.Annotations[invulnerablev1alpha1.ScanRequestedAnnotation] = time.Now().UTC().Format(time.RFC3339Nano)
+	if err := c.Patch(ctx, imageScan, patch); err != nil {
+		return fmt.Errorf("failed to request scan: %w", err)
+	}
+
+	fmt.Printf("imagescan.invulnerable.io/%s scan requested\n", name)
+	fmt.Printf("Follow the scan Job with: kubectl get jobs -n %s -l app.kubernetes.io/instance=%s,invulnerable.io/trigger=Manual\n", namespace, name)
+	return nil
+}
+
+// diff prints the vulnerabilities new and fixed in the latest scan of an ImageScan's image,
+// compared with the scan before it
+func diff(ctx context.Context, fs *flags, name string) error {
+	_, namespace, err := fs.kube()
+	if err != nil {
+		return err
+	}
+	api, err := fs.backend()
+	if err != nil {
+		return err
+	}
+
+	summaries, err := api.imageScans(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	var summary *imageScanSummary
+	for i := range summaries {
+		if summaries[i].Name == name {
+			summary = &summaries[i]
+		}
+	}
+	if summary == nil {
+		return fmt.Errorf("ImageScan %s/%s is not registered with the backend", namespace, name)
+	}
+	if summary.LatestScanID == nil {
+		return fmt.Errorf("%s has not been scanned successfully yet", summary.ImageName)
+	}
+
+	scanDiff, err := api.scanDiff(ctx, *summary.LatestScanID)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	printDiff(w, summary.ImageName, scanDiff)
+	return w.Flush()
+}
+
+func printDiff(w io.Writer, image string, d *scanDiff) {
+	if d.PreviousScanID == 0 {
+		fmt.Fprintf(w, "Scan %d of %s is its first scan\n", d.ScanID, image)
+	} else {
+		fmt.Fprintf(w, "Scan %d of %s compared with scan %d\n", d.ScanID, image, d.PreviousScanID)
+	}
+	fmt.Fprintf(w, "%d new, %d fixed, %d unchanged\n", d.Summary.NewCount, d.Summary.FixedCount, d.Summary.PersistentCount)
+
+	for _, section := range []struct {
+		title string
+		vulns []vulnerability
+	}{
+		{"New", d.NewVulns},
+		{"Fixed", d.FixedVulns},
+	} {
+		if len(section.vulns) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		fmt.Fprintln(w, "CVE\tSEVERITY\tPACKAGE\tVERSION\tFIXED IN")
+		for _, v := range section.vulns {
+			fix := "-"
+			if v.FixVersion != nil {
+				fix = *v.FixVersion
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.CVEID, v.Severity, v.PackageName, v.PackageVersion, fix)
+		}
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+// backend reads ImageScan findings from the Invulnerable API
+type backend struct {
+	baseURL string
+	client  *http.Client
+}
+
+// imageScanSummary is an entry of GET /api/v1/imagescans
+type imageScanSummary struct {
+	Namespace      string     `json:"namespace"`
+	Name           string     `json:"name"`
+	ImageName      string     `json:"image_name"`
+	LatestScanID   *int       `json:"latest_scan_id"`
+	LatestScanDate *time.Time `json:"latest_scan_date"`
+	Critical       int        `json:"critical_count"`
+	High           int        `json:"high_count"`
+	Medium         int        `json:"medium_count"`
+	Low            int        `json:"low_count"`
+}
+
+// scanDiff is the response of GET /api/v1/scans/:id/diff
+type scanDiff struct {
+	ScanID         int             `json:"scan_id"`
+	PreviousScanID int             `json:"previous_scan_id"`
+	NewVulns       []vulnerability `json:"new_vulnerabilities"`
+	FixedVulns     []vulnerability `json:"fixed_vulnerabilities"`
+	Summary        struct {
+		NewCount        int `json:"new_count"`
+		FixedCount      int `json:"fixed_count"`
+		PersistentCount int `json:"persistent_count"`
+	} `json:"summary"`
+}
+
+type vulnerability struct {
+	CVEID          string  `json:"cve_id"`
+	PackageName    string  `json:"package_name"`
+	PackageVersion string  `json:"package_version"`
+	Severity       string  `json:"severity"`
+	FixVersion     *string `json:"fix_version"`
+}
+
+func (b *backend) imageScans(ctx context.Context, namespace string) ([]imageScanSummary, error) {
+	path := "/imagescans"
+	if namespace != "" {
+		path += "?" + url.Values{"namespace": {namespace}}.Encode()
+	}
+	summaries := []imageScanSummary{}
+	if err := b.get(ctx, path, &summaries); err != nil {
+		return nil, fmt.Errorf("failed to list ImageScans from the backend: %w", err)
+	}
+	return summaries, nil
+}
+
+func (b *backend) scanDiff(ctx context.Context, scanID int) (*scanDiff, error) {
+	var d scanDiff
+	if err := b.get(ctx, fmt.Sprintf("/scans/%d/diff", scanID), &d); err != nil {
+		return nil, fmt.Errorf("failed to get the diff of scan %d: %w", scanID, err)
+	}
+	return &d, nil
+}
+
+func (b *backend) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, errorMessage(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// errorMessage extracts the message of an Echo error response
+func errorMessage(body []byte) string {
+	var e struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Message != "" {
+		return e.Message
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	fs := newFlags("scan")
+	args, err := parseInterspersed(fs, []string{"nginx", "-n", "production", "-context", "prod"})
+	if err != nil {
+		t.Fatalf("parseInterspersed: %v", err)
+	}
+	if !reflect.DeepEqual(args, []string{"nginx"}) {
+		t.Errorf("args = %v, want [nginx]", args)
+	}
+	if fs.namespace != "production" || fs.kubeContext != "prod" {
+		t.Errorf("namespace = %q, context = %q", fs.namespace, fs.kubeContext)
+	}
+}
+
+func TestPrintListing(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	scanned := now.Add(-3 * time.Hour)
+	scanID := 42
+	imageScans := []invulnerablev1alpha1.ImageScan{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx"}, Spec: invulnerablev1alpha1.ImageScanSpec{Image: "nginx:1.25"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "redis"}, Spec: invulnerablev1alpha1.ImageScanSpec{Image: "redis:7.2"}},
+	}
+	summaries := []imageScanSummary{
+		{Namespace: "prod", Name: "nginx", LatestScanID: &scanID, LatestScanDate: &scanned, Critical: 1, High: 4, Medium: 7},
+		// Registered but not scanned yet
+		{Namespace: "prod", Name: "redis"},
+	}
+
+	var out bytes.Buffer
+	w := tabwriter.NewWriter(&out, 0, 0, 1, ' ', 0)
+	printListing(w, imageScans, summaries, true, now)
+	w.Flush()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	if got := strings.Fields(lines[1]); !reflect.DeepEqual(got, []string{"prod", "nginx", "nginx:1.25", "1", "4", "7", "0", "3h", "ago"}) {
+		t.Errorf("nginx row = %v", got)
+	}
+	if got := strings.Fields(lines[2]); !reflect.DeepEqual(got, []string{"prod", "redis", "redis:7.2", "-", "-", "-", "-", "<none>"}) {
+		t.Errorf("redis row = %v", got)
+	}
+}
+
+func TestPrintDiff(t *testing.T) {
+	fix := "3.0.12"
+	d := &scanDiff{
+		ScanID:         42,
+		PreviousScanID: 41,
+		NewVulns:       []vulnerability{{CVEID: "CVE-2023-0001", PackageName: "openssl", PackageVersion: "3.0.11", Severity: "Critical", FixVersion: &fix}},
+	}
+	d.Summary.NewCount, d.Summary.PersistentCount = 1, 5
+
+	var out bytes.Buffer
+	printDiff(&out, "docker.io/library/nginx:1.25", d)
+
+	for _, want := range []string{
+		"Scan 42 of docker.io/library/nginx:1.25 compared with scan 41",
+		"1 new, 0 fixed, 5 unchanged",
+		"New:",
+		"CVE-2023-0001\tCritical\topenssl\t3.0.11\t3.0.12",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Fixed:") {
+		t.Errorf("output lists fixed vulnerabilities without any:\n%s", out.String())
+	}
+}
@@ -2,16 +2,27 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	// Embedded time zone data for spec.timeZone in native scheduling mode
+	_ "time/tzdata"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -34,15 +45,50 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var previewAddr string
 	var namespace string
+	var schedulingMode string
+	var maxConcurrentScans int
+	var namespaceScanWeights string
+	var scheduleJitter time.Duration
+	var backendClientCert, backendClientKey, backendCA string
+	var inventoryEndpoint string
+	var inventoryInterval time.Duration
+	priorityClasses := map[invulnerablev1alpha1.ScanPriority]*string{}
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&previewAddr, "preview-bind-address", "127.0.0.1:8082",
+		"The address the ImageScan preview endpoint binds to. Set to \"0\" to disable it.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&namespace, "namespace", "",
 		"Namespace to watch. If empty, watches all namespaces.")
+	flag.StringVar(&schedulingMode, "scheduling-mode", controller.SchedulingModeCronJob,
+		"How ImageScan schedules are run: \"cronjob\" creates a CronJob per ImageScan, "+
+			"\"native\" has the controller create the scan Jobs itself.")
+	flag.IntVar(&maxConcurrentScans, "max-concurrent-scans", 20,
+		"Maximum number of scheduled scan Jobs running at once in native mode. 0 means no limit.")
+	flag.StringVar(&namespaceScanWeights, "namespace-scan-weights", "",
+		"Share of free slots each namespace gets once --max-concurrent-scans is reached, e.g. \"team-a=3,team-b=2\". Unlisted namespaces weigh 1.")
+	flag.DurationVar(&scheduleJitter, "schedule-jitter", 5*time.Minute,
+		"Maximum delay added to each ImageScan's scheduled runs in native mode, to spread Jobs sharing a schedule.")
+	flag.StringVar(&backendClientCert, "backend-client-cert", "",
+		"Client certificate presented to a backend requiring mutual TLS. Reloaded when it changes.")
+	flag.StringVar(&backendClientKey, "backend-client-key", "", "Key of the backend client certificate.")
+	flag.StringVar(&backendCA, "backend-ca", "",
+		"CA bundle verifying the backend's certificate. If empty, the system roots are used.")
+	flag.StringVar(&inventoryEndpoint, "inventory-endpoint", "",
+		"Backend API the images of running Pods are reported to for its coverage report. If empty, they aren't reported.")
+	flag.DurationVar(&inventoryInterval, "inventory-interval", 10*time.Minute,
+		"How often the images of running Pods are reported to the backend.")
+	for _, priority := range []invulnerablev1alpha1.ScanPriority{
+		invulnerablev1alpha1.ScanPriorityHigh, invulnerablev1alpha1.ScanPriorityNormal, invulnerablev1alpha1.ScanPriorityLow,
+	} {
+		priorityClasses[priority] = flag.String("priority-class-"+string(priority), "",
+			fmt.Sprintf("PriorityClass of scan pods for ImageScans with priority %s. If empty, the cluster default is used.", priority))
+	}
 	opts := zap.Options{
 		Development: true,
 	}
@@ -51,6 +97,17 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if schedulingMode != controller.SchedulingModeCronJob && schedulingMode != controller.SchedulingModeNative {
+		setupLog.Error(nil, "invalid --scheduling-mode, expected cronjob or native", "mode", schedulingMode)
+		os.Exit(1)
+	}
+	setupLog.Info("scheduling mode", "mode", schedulingMode)
+	namespaceWeights, err := controller.ParseNamespaceWeights(namespaceScanWeights)
+	if err != nil {
+		setupLog.Error(err, "invalid --namespace-scan-weights")
+		os.Exit(1)
+	}
+
 	// Configure manager options
 	mgrOptions := ctrl.Options{
 		Scheme: scheme,
@@ -61,6 +118,10 @@ func main() {
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "invulnerable-controller-leader-election",
 	}
+	// Only scanner pods are watched, for their crashes
+	mgrOptions.Cache.ByObject = map[client.Object]cache.ByObject{
+		&corev1.Pod{}: {Label: labels.SelectorFromSet(labels.Set{"app.kubernetes.io/name": "invulnerable-scanner"})},
+	}
 
 	// If namespace is specified, watch only that namespace
 	if namespace != "" {
@@ -78,14 +139,70 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controller.ImageScanReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create Kubernetes client")
+		os.Exit(1)
+	}
+
+	var backendClient *http.Client
+	if backendClientCert != "" || backendClientKey != "" || backendCA != "" {
+		backendClient, err = controller.NewBackendHTTPClient(backendClientCert, backendClientKey, backendCA)
+		if err != nil {
+			setupLog.Error(err, "unable to set up backend TLS")
+			os.Exit(1)
+		}
+		setupLog.Info("calling the backend with TLS", "clientCertificate", backendClientCert != "")
+	}
+
+	imageScanReconciler := &controller.ImageScanReconciler{
+		Client:             mgr.GetClient(),
+		Scheme:             mgr.GetScheme(),
+		HTTPClient:         backendClient,
+		APIReader:          mgr.GetAPIReader(),
+		Clientset:          clientset,
+		Recorder:           mgr.GetEventRecorderFor("imagescan-controller"),
+		SchedulingMode:     schedulingMode,
+		MaxConcurrentScans: maxConcurrentScans,
+		NamespaceWeights:   namespaceWeights,
+		ScheduleJitter:     scheduleJitter,
+		PriorityClasses:    resolvePriorityClasses(priorityClasses),
+	}
+	if err = imageScanReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ImageScan")
 		os.Exit(1)
 	}
 
+	if previewAddr != "0" {
+		if err := mgr.Add(&controller.PreviewServer{Addr: previewAddr, Reconciler: imageScanReconciler}); err != nil {
+			setupLog.Error(err, "unable to set up preview server")
+			os.Exit(1)
+		}
+	}
+
+	if err = (&controller.ImageScanSetReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageScanSet")
+		os.Exit(1)
+	}
+
+	if inventoryEndpoint != "" {
+		if err := mgr.Add(&controller.InventoryReporter{
+			APIReader:  mgr.GetAPIReader(),
+			HTTPClient: backendClient,
+			Endpoint:   strings.TrimSuffix(inventoryEndpoint, "/"),
+			Namespace:  namespace,
+			Interval:   max(inventoryInterval, time.Minute),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up inventory reporter")
+			os.Exit(1)
+		}
+		setupLog.Info("reporting deployed images", "endpoint", inventoryEndpoint, "interval", inventoryInterval)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -101,3 +218,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// resolvePriorityClasses drops the priorities without a PriorityClass
+func resolvePriorityClasses(flags map[invulnerablev1alpha1.ScanPriority]*string) map[invulnerablev1alpha1.ScanPriority]string {
+	classes := map[invulnerablev1alpha1.ScanPriority]string{}
+	for priority, name := range flags {
+		if *name != "" {
+			classes[priority] = *name
+		}
+	}
+	return classes
+}
@@ -5,10 +5,12 @@ go 1.24.0
 require (
 	github.com/google/go-containerregistry v0.20.3
 	github.com/google/go-containerregistry/pkg/authn/k8schain v0.0.0-20251124222020-e075f209120b
+	github.com/pacokleitz/invulnerable/pkg/cron v0.0.0
 	k8s.io/api v0.34.2
 	k8s.io/apimachinery v0.34.2
 	k8s.io/client-go v0.34.2
 	sigs.k8s.io/controller-runtime v0.19.4
+	sigs.k8s.io/yaml v1.6.0
 )
 
 require (
@@ -109,5 +111,7 @@ require (
 	sigs.k8s.io/json v0.0.0-20241014173422-cfa47c3a1cc8 // indirect
 	sigs.k8s.io/randfill v1.0.0 // indirect
 	sigs.k8s.io/structured-merge-diff/v6 v6.3.0 // indirect
-	sigs.k8s.io/yaml v1.6.0 // indirect
 )
+
+// The cron parser is shared with the backend, from the pkg directory of the repository
+replace github.com/pacokleitz/invulnerable/pkg/cron => ../pkg/cron
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+// runtimeConfig holds the spec of the InvulnerableConfig overriding the flags of the controller.
+// The zero value has none
+type runtimeConfig struct {
+	mu   sync.RWMutex
+	spec *invulnerablev1alpha1.InvulnerableConfigSpec
+}
+
+// get returns the spec of the InvulnerableConfig, nil without one. It must not be modified
+func (c *runtimeConfig) get() *invulnerablev1alpha1.InvulnerableConfigSpec {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.spec
+}
+
+func (c *runtimeConfig) set(spec *invulnerablev1alpha1.InvulnerableConfigSpec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spec = spec
+}
+
+// scannerImage returns the scanner image of an ImageScan, nil for the built-in default
+func (r *ImageScanReconciler) scannerImage(imageScan *invulnerablev1alpha1.ImageScan) *invulnerablev1alpha1.ScannerImageSpec {
+	if imageScan.Spec.ScannerImage != nil {
+		return imageScan.Spec.ScannerImage
+	}
+	if config := r.config.get(); config != nil {
+		return config.ScannerImage
+	}
+	return nil
+}
+
+// apiTLS returns the backend client certificate of an ImageScan, nil without mutual TLS
+func (r *ImageScanReconciler) apiTLS(imageScan *invulnerablev1alpha1.ImageScan) *invulnerablev1alpha1.APITLSConfig {
+	if imageScan.Spec.APITLS != nil {
+		return imageScan.Spec.APITLS
+	}
+	if config := r.config.get(); config != nil {
+		return config.APITLS
+	}
+	return nil
+}
+
+// maxConcurrentScans returns the limit of scheduled Jobs running at once in native mode
+func (r *ImageScanReconciler) maxConcurrentScans() int {
+	if config := r.config.get(); config != nil && config.MaxConcurrentScans != nil {
+		return int(*config.MaxConcurrentScans)
+	}
+	return r.MaxConcurrentScans
+}
+
+// namespaceWeights returns the weights of namespaces in the round-robin of waiting scans
+func (r *ImageScanReconciler) namespaceWeights() map[string]int {
+	config := r.config.get()
+	if config == nil || config.NamespaceWeights == nil {
+		return r.NamespaceWeights
+	}
+	weights := make(map[string]int, len(config.NamespaceWeights))
+	for namespace, weight := range config.NamespaceWeights {
+		weights[namespace] = int(weight)
+	}
+	return weights
+}
+
+// scheduleJitter returns the maximum delay added to scheduled runs in native mode
+func (r *ImageScanReconciler) scheduleJitter() time.Duration {
+	if config := r.config.get(); config != nil && config.ScheduleJitter != nil {
+		return config.ScheduleJitter.Duration
+	}
+	return r.ScheduleJitter
+}
+
+// configEventHandler applies the InvulnerableConfig named "default" as it changes, then
+// reconciles every ImageScan so their CronJobs and Jobs pick up the new defaults
+type configEventHandler struct {
+	client client.Client
+	config *runtimeConfig
+}
+
+// Create implements handler.TypedEventHandler
+func (h *configEventHandler) Create(ctx context.Context, e event.TypedCreateEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	h.apply(ctx, e.Object, false, q)
+}
+
+// Update implements handler.TypedEventHandler
+func (h *configEventHandler) Update(ctx context.Context, e event.TypedUpdateEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	h.apply(ctx, e.ObjectNew, false, q)
+}
+
+// Delete implements handler.TypedEventHandler
+func (h *configEventHandler) Delete(ctx context.Context, e event.TypedDeleteEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	// Back to the flags
+	h.apply(ctx, e.Object, true, q)
+}
+
+// Generic implements handler.TypedEventHandler
+func (h *configEventHandler) Generic(ctx context.Context, e event.TypedGenericEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	// No-op
+}
+
+// apply stores the spec of the InvulnerableConfig before the ImageScans are enqueued, so their
+// reconciliation sees it
+func (h *configEventHandler) apply(ctx context.Context, obj client.Object, deleted bool, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	config, ok := obj.(*invulnerablev1alpha1.InvulnerableConfig)
+	if !ok || config.Name != invulnerablev1alpha1.InvulnerableConfigName {
+		return
+	}
+	logger := log.FromContext(ctx)
+
+	if deleted {
+		h.config.set(nil)
+		logger.Info("InvulnerableConfig deleted, using the defaults of the flags")
+	} else {
+		h.config.set(config.Spec.DeepCopy())
+		logger.Info("Applied InvulnerableConfig", "generation", config.Generation)
+	}
+
+	imageScans := &invulnerablev1alpha1.ImageScanList{}
+	if err := h.client.List(ctx, imageScans); err != nil {
+		logger.Error(err, "Failed to list ImageScans to apply the InvulnerableConfig")
+		return
+	}
+	for _, imageScan := range imageScans.Items {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: imageScan.Namespace,
+			Name:      imageScan.Name,
+		}})
+	}
+}
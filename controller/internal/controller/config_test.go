@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+func TestConfigEventHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := invulnerablev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	nginx := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx"},
+		Spec:       invulnerablev1alpha1.ImageScanSpec{Image: "nginx:1.25"},
+	}
+	redis := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "staging", Name: "redis"},
+		Spec: invulnerablev1alpha1.ImageScanSpec{
+			Image:        "redis:7",
+			APIEndpoint:  "http://backend.staging:8080",
+			ScannerImage: &invulnerablev1alpha1.ScannerImageSpec{Repository: "invulnerable-scanner", Tag: "1.9"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nginx, redis).Build()
+	r := &ImageScanReconciler{Client: c, Scheme: scheme, MaxConcurrentScans: 20, ScheduleJitter: 5 * time.Minute}
+	h := &configEventHandler{client: c, config: &r.config}
+	q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer q.ShutDown()
+	ctx := context.Background()
+
+	// Only the InvulnerableConfig named default is applied
+	other := &invulnerablev1alpha1.InvulnerableConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "other"},
+		Spec:       invulnerablev1alpha1.InvulnerableConfigSpec{MaxConcurrentScans: ptr(int32(1))},
+	}
+	h.Create(ctx, event.TypedCreateEvent[client.Object]{Object: other}, q)
+	if q.Len() != 0 || r.maxConcurrentScans() != 20 {
+		t.Fatalf("other config applied: %d queued, limit %d", q.Len(), r.maxConcurrentScans())
+	}
+
+	config := &invulnerablev1alpha1.InvulnerableConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: invulnerablev1alpha1.InvulnerableConfigName},
+		Spec: invulnerablev1alpha1.InvulnerableConfigSpec{
+			ScannerImage:       &invulnerablev1alpha1.ScannerImageSpec{Repository: "ghcr.io/acme/scanner", Tag: "2.0"},
+			APIEndpoint:        "https://invulnerable.example.com",
+			APITLS:             &invulnerablev1alpha1.APITLSConfig{SecretName: "scanner-tls"},
+			MaxConcurrentScans: ptr(int32(5)),
+			NamespaceWeights:   map[string]int32{"prod": 3},
+			ScheduleJitter:     &metav1.Duration{Duration: time.Minute},
+		},
+	}
+	h.Create(ctx, event.TypedCreateEvent[client.Object]{Object: config}, q)
+	if q.Len() != 2 {
+		t.Errorf("%d ImageScans queued, want 2", q.Len())
+	}
+	if r.maxConcurrentScans() != 5 || r.scheduleJitter() != time.Minute || r.namespaceWeights()["prod"] != 3 {
+		t.Errorf("limit = %d, jitter = %s, weights = %v", r.maxConcurrentScans(), r.scheduleJitter(), r.namespaceWeights())
+	}
+
+	podSpec := r.buildJobSpec(nginx).Template.Spec
+	if got := podSpec.Containers[0].Image; got != "ghcr.io/acme/scanner:2.0" {
+		t.Errorf("scanner image = %s", got)
+	}
+	if got := envValue(podSpec.Containers[0].Env, "API_ENDPOINT"); got != "https://invulnerable.example.com" {
+		t.Errorf("API_ENDPOINT = %s", got)
+	}
+	if !hasVolume(podSpec.Volumes, "api-tls") {
+		t.Error("api-tls volume not mounted")
+	}
+
+	// The ImageScan's own settings win
+	podSpec = r.buildJobSpec(redis).Template.Spec
+	if got := podSpec.Containers[0].Image; got != "invulnerable-scanner:1.9" {
+		t.Errorf("scanner image = %s", got)
+	}
+	if got := envValue(podSpec.Containers[0].Env, "API_ENDPOINT"); got != "http://backend.staging:8080" {
+		t.Errorf("API_ENDPOINT = %s", got)
+	}
+
+	// Deleting it goes back to the flags
+	h.Delete(ctx, event.TypedDeleteEvent[client.Object]{Object: config}, q)
+	if r.maxConcurrentScans() != 20 || r.scheduleJitter() != 5*time.Minute || r.namespaceWeights() != nil {
+		t.Errorf("after delete: limit = %d, jitter = %s, weights = %v", r.maxConcurrentScans(), r.scheduleJitter(), r.namespaceWeights())
+	}
+	if got := r.backendEndpoint(nginx); got != "http://invulnerable-backend.prod.svc.cluster.local:8080" {
+		t.Errorf("endpoint after delete = %s", got)
+	}
+}
+
+func envValue(env []corev1.EnvVar, name string) string {
+	for _, e := range env {
+		if e.Name == name {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+func hasVolume(volumes []corev1.Volume, name string) bool {
+	for _, v := range volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
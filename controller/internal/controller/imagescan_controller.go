@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/go-containerregistry/pkg/authn"
@@ -13,17 +14,21 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -33,6 +38,12 @@ import (
 const (
 	imageScanFinalizer = "invulnerable.io/finalizer"
 	conditionTypeReady = "Ready"
+
+	// manualTrigger is the invulnerable.io/trigger label of Jobs requested through ScanRequestedAnnotation
+	manualTrigger = "Manual"
+
+	// apiTLSMountPath is where scan pods mount the Secret of spec.apiTLS
+	apiTLSMountPath = "/etc/invulnerable/tls"
 )
 
 // ImageScanReconciler reconciles an ImageScan object
@@ -40,18 +51,44 @@ type ImageScanReconciler struct {
 	client.Client
 	Scheme     *runtime.Scheme
 	HTTPClient *http.Client
+	// APIReader lists scanner pods without caching every Pod of the cluster
+	APIReader client.Reader
+	// Clientset reads the logs of failed scanner pods
+	Clientset kubernetes.Interface
+	// Recorder emits the events of ImageScans
+	Recorder record.EventRecorder
+
+	// SchedulingMode is SchedulingModeCronJob (default) or SchedulingModeNative
+	SchedulingMode string
+	// MaxConcurrentScans caps the scheduled Jobs running at once in native mode (0 means no limit)
+	MaxConcurrentScans int
+	// NamespaceWeights weights the share of free slots each namespace gets once MaxConcurrentScans
+	// is reached. Namespaces without a weight have a weight of 1
+	NamespaceWeights map[string]int
+	// ScheduleJitter is the maximum delay added to scheduled runs in native mode
+	ScheduleJitter time.Duration
+	// PriorityClasses maps ImageScan priorities to the PriorityClass of scan pods
+	// A priority without an entry leaves the cluster default
+	PriorityClasses map[invulnerablev1alpha1.ScanPriority]string
+
+	// queue orders the scheduled scans waiting for MaxConcurrentScans in native mode
+	queue scanQueue
+	// config is the InvulnerableConfig overriding the defaults above at runtime
+	config runtimeConfig
 }
 
 // +kubebuilder:rbac:groups=invulnerable.io,resources=imagescans,verbs=get;list;watch
 // +kubebuilder:rbac:groups=invulnerable.io,resources=imagescans/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=invulnerable.io,resources=imagescans/finalizers,verbs=update
+// +kubebuilder:rbac:groups=invulnerable.io,resources=invulnerableconfigs,verbs=get;list;watch
 // +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
-// Note: Controller does NOT have create/delete permissions for ImageScans.
-// Users create ImageScans, controller reconciles them.
-// Controller CAN create/delete CronJobs (owned resources).
+// Note: Controller only creates/deletes the ImageScans stamped out by ImageScanSets.
+// Users create ImageScans and ImageScanSets, controller reconciles them.
+// Controller CAN create/delete CronJobs, Jobs and NetworkPolicies (owned resources).
 // For namespace-scoped deployment, use Role instead of ClusterRole.
 
 // Reconcile is part of the main kubernetes reconciliation loop
@@ -97,9 +134,43 @@ func (r *ImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	// Reconcile CronJob (create/update if enabled, delete if disabled)
+	// Restrict the egress of scan pods before any of them runs (delete the NetworkPolicy if disabled)
+	if err := r.reconcileNetworkPolicy(ctx, imageScan); err != nil {
+		logger.Error(err, "Failed to reconcile NetworkPolicy")
+		r.setCondition(imageScan, conditionTypeReady, metav1.ConditionFalse, "NetworkPolicyFailed", err.Error())
+		if statusErr := r.Status().Update(ctx, imageScan); statusErr != nil {
+			logger.Error(statusErr, "Failed to update ImageScan status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Fetch the recommended sizing before building Jobs, which apply it with auto-sizing enabled
+	if err := r.reconcileSizing(ctx, imageScan, time.Now()); err != nil {
+		logger.Error(err, "Failed to get sizing recommendation from backend (non-fatal)")
+	}
+
+	// Reconcile CronJob (create/update if enabled, delete if disabled),
+	// or run the schedule from the controller in native mode
 	var cronJobName string
-	if scheduleEnabled {
+	var scheduleRequeue time.Duration
+	nativeSchedule := scheduleEnabled && r.SchedulingMode == SchedulingModeNative
+	if nativeSchedule {
+		// Switching from CronJob mode leaves a CronJob behind
+		if err := r.deleteCronJobIfExists(ctx, imageScan); err != nil {
+			logger.Error(err, "Failed to delete CronJob")
+			return ctrl.Result{}, err
+		}
+		var err error
+		scheduleRequeue, err = r.reconcileNativeSchedule(ctx, imageScan)
+		if err != nil {
+			logger.Error(err, "Failed to reconcile native schedule")
+			r.setCondition(imageScan, conditionTypeReady, metav1.ConditionFalse, "ReconcileFailed", err.Error())
+			if statusErr := r.Status().Update(ctx, imageScan); statusErr != nil {
+				logger.Error(statusErr, "Failed to update ImageScan status")
+			}
+			return ctrl.Result{}, err
+		}
+	} else if scheduleEnabled {
 		cronJob, err := r.reconcileCronJob(ctx, imageScan)
 		if err != nil {
 			logger.Error(err, "Failed to reconcile CronJob")
@@ -125,6 +196,21 @@ func (r *ImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		// Don't fail reconciliation if webhook sync fails
 	}
 
+	// Register the ImageScan so the backend refuses to delete an image that is still scanned
+	if err := r.syncImageScanRegistration(ctx, imageScan); err != nil {
+		logger.Error(err, "Failed to register ImageScan with backend (non-fatal)")
+	}
+
+	// Run the on-demand scan requested through the scan-requested-at annotation
+	if err := r.reconcileScanRequest(ctx, imageScan); err != nil {
+		logger.Error(err, "Failed to run requested scan")
+		r.setCondition(imageScan, conditionTypeReady, metav1.ConditionFalse, "ScanRequestFailed", err.Error())
+		if statusErr := r.Status().Update(ctx, imageScan); statusErr != nil {
+			logger.Error(statusErr, "Failed to update ImageScan status")
+		}
+		return ctrl.Result{}, err
+	}
+
 	// Reconcile registry polling (if enabled)
 	requeueAfter, err := r.reconcileRegistryPolling(ctx, imageScan)
 	if err != nil {
@@ -136,11 +222,34 @@ func (r *ImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	// Surface why the latest scan Job failed
+	if err := r.reconcileLastFailure(ctx, imageScan); err != nil {
+		logger.Error(err, "Failed to record scan failure (non-fatal)")
+	}
+
+	// Retry the latest failed scan once the retry policy's backoff has passed
+	retryRequeue, err := r.reconcileRetry(ctx, imageScan, time.Now())
+	if err != nil {
+		logger.Error(err, "Failed to retry failed scan")
+		r.setCondition(imageScan, conditionTypeReady, metav1.ConditionFalse, "RetryFailed", err.Error())
+		if statusErr := r.Status().Update(ctx, imageScan); statusErr != nil {
+			logger.Error(statusErr, "Failed to update ImageScan status")
+		}
+		return ctrl.Result{}, err
+	}
+
 	// Update status
 	imageScan.Status.CronJobName = cronJobName
 	imageScan.Status.ObservedGeneration = imageScan.Generation
 
-	if scheduleEnabled {
+	if !nativeSchedule {
+		imageScan.Status.NextScheduleTime = nil
+		imageScan.Status.QueuePosition = 0
+	}
+
+	if nativeSchedule {
+		r.setCondition(imageScan, conditionTypeReady, metav1.ConditionTrue, "ReconcileSuccess", "Schedule run by the controller")
+	} else if scheduleEnabled {
 		r.setCondition(imageScan, conditionTypeReady, metav1.ConditionTrue, "ReconcileSuccess", "CronJob successfully reconciled")
 	} else {
 		r.setCondition(imageScan, conditionTypeReady, metav1.ConditionTrue, "ReconcileSuccess", "Registry polling configured (schedule disabled)")
@@ -151,15 +260,22 @@ func (r *ImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
-	if scheduleEnabled {
+	if nativeSchedule {
+		logger.Info("Successfully reconciled ImageScan (native schedule)")
+	} else if scheduleEnabled {
 		logger.Info("Successfully reconciled ImageScan", "cronJob", cronJobName)
 	} else {
 		logger.Info("Successfully reconciled ImageScan (registry polling only)")
 	}
 
-	// If registry polling is enabled, requeue after interval
+	// Requeue for the next scheduled run, registry poll or retry, whichever comes first
+	for _, after := range []time.Duration{scheduleRequeue, retryRequeue} {
+		if after > 0 && (requeueAfter == 0 || after < requeueAfter) {
+			requeueAfter = after
+		}
+	}
 	if requeueAfter > 0 {
-		logger.V(1).Info("Requeuing for registry polling", "after", requeueAfter)
+		logger.V(1).Info("Requeuing", "after", requeueAfter)
 		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
@@ -167,6 +283,7 @@ func (r *ImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 }
 
 // deleteCronJobIfExists deletes the CronJob for an ImageScan if it exists
+// (schedule disabled, or run natively by the controller)
 func (r *ImageScanReconciler) deleteCronJobIfExists(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) error {
 	logger := log.FromContext(ctx)
 
@@ -176,7 +293,7 @@ func (r *ImageScanReconciler) deleteCronJobIfExists(ctx context.Context, imageSc
 
 	if err == nil {
 		// CronJob exists - delete it
-		logger.Info("Deleting CronJob (schedule disabled)", "name", cronJobName)
+		logger.Info("Deleting CronJob", "name", cronJobName)
 		if err := r.Delete(ctx, cronJob); err != nil {
 			return fmt.Errorf("failed to delete CronJob: %w", err)
 		}
@@ -192,9 +309,37 @@ func (r *ImageScanReconciler) deleteCronJobIfExists(ctx context.Context, imageSc
 func (r *ImageScanReconciler) reconcileCronJob(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) (*batchv1.CronJob, error) {
 	logger := log.FromContext(ctx)
 
-	cronJobName := fmt.Sprintf("%s-scanner", imageScan.Name)
+	desiredCronJob, err := r.buildCronJob(imageScan)
+	if err != nil {
+		return nil, err
+	}
+	cronJobName := desiredCronJob.Name
 	cronJob := &batchv1.CronJob{}
-	err := r.Get(ctx, types.NamespacedName{Name: cronJobName, Namespace: imageScan.Namespace}, cronJob)
+	err = r.Get(ctx, types.NamespacedName{Name: cronJobName, Namespace: imageScan.Namespace}, cronJob)
+	if errors.IsNotFound(err) {
+		// Create the CronJob
+		logger.Info("Creating CronJob", "name", cronJobName)
+		if err := r.Create(ctx, desiredCronJob); err != nil {
+			return nil, err
+		}
+		return desiredCronJob, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Update existing CronJob
+	cronJob.Spec = desiredCronJob.Spec
+	logger.Info("Updating CronJob", "name", cronJobName)
+	if err := r.Update(ctx, cronJob); err != nil {
+		return nil, err
+	}
+
+	return cronJob, nil
+}
+
+// buildCronJob builds the CronJob of an ImageScan, as applied by reconcileCronJob
+func (r *ImageScanReconciler) buildCronJob(imageScan *invulnerablev1alpha1.ImageScan) (*batchv1.CronJob, error) {
+	cronJobName := fmt.Sprintf("%s-scanner", imageScan.Name)
 
 	// Set defaults for CronJob-specific fields
 	successfulJobsHistoryLimit := int32(3)
@@ -227,6 +372,14 @@ func (r *ImageScanReconciler) reconcileCronJob(ctx context.Context, imageScan *i
 			FailedJobsHistoryLimit:     &failedJobsHistoryLimit,
 			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
 			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app.kubernetes.io/name":       "invulnerable-scanner",
+						"app.kubernetes.io/instance":   imageScan.Name,
+						"app.kubernetes.io/component":  "scanner",
+						"app.kubernetes.io/managed-by": "invulnerable-controller",
+					},
+				},
 				Spec: r.buildJobSpec(imageScan),
 			},
 		},
@@ -237,25 +390,7 @@ func (r *ImageScanReconciler) reconcileCronJob(ctx context.Context, imageScan *i
 		return nil, err
 	}
 
-	if errors.IsNotFound(err) {
-		// Create the CronJob
-		logger.Info("Creating CronJob", "name", cronJobName)
-		if err := r.Create(ctx, desiredCronJob); err != nil {
-			return nil, err
-		}
-		return desiredCronJob, nil
-	} else if err != nil {
-		return nil, err
-	}
-
-	// Update existing CronJob
-	cronJob.Spec = desiredCronJob.Spec
-	logger.Info("Updating CronJob", "name", cronJobName)
-	if err := r.Update(ctx, cronJob); err != nil {
-		return nil, err
-	}
-
-	return cronJob, nil
+	return desiredCronJob, nil
 }
 
 // handleDeletion handles the deletion of an ImageScan
@@ -282,6 +417,16 @@ func (r *ImageScanReconciler) handleDeletion(ctx context.Context, imageScan *inv
 			// Don't fail deletion if webhook config deletion fails
 		}
 
+		// Unregister from backend so the image can be deleted there
+		if err := r.deleteImageScanRegistration(ctx, imageScan); err != nil {
+			// With the Delete policy, keep the finalizer and retry so backend data isn't left behind
+			if imageScan.Spec.DeletionPolicy == invulnerablev1alpha1.DeletionPolicyDelete {
+				logger.Error(err, "Failed to delete backend data for ImageScan, retrying")
+				return ctrl.Result{}, err
+			}
+			logger.Error(err, "Failed to unregister ImageScan from backend (non-fatal)")
+		}
+
 		// Remove finalizer
 		controllerutil.RemoveFinalizer(imageScan, imageScanFinalizer)
 		if err := r.Update(ctx, imageScan); err != nil {
@@ -352,6 +497,22 @@ func (r *ImageScanReconciler) setCondition(imageScan *invulnerablev1alpha1.Image
 }
 
 // buildEnvVars builds the environment variables for the scanner container
+// backendEndpoint returns the backend API endpoint of an ImageScan, by default the backend
+// service in the same namespace, over https with mutual TLS. The InvulnerableConfig may set another default
+func (r *ImageScanReconciler) backendEndpoint(imageScan *invulnerablev1alpha1.ImageScan) string {
+	if imageScan.Spec.APIEndpoint != "" {
+		return imageScan.Spec.APIEndpoint
+	}
+	if config := r.config.get(); config != nil && config.APIEndpoint != "" {
+		return config.APIEndpoint
+	}
+	scheme := "http"
+	if r.apiTLS(imageScan) != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://invulnerable-backend.%s.svc.cluster.local:8080", scheme, imageScan.Namespace)
+}
+
 func buildEnvVars(imageScan *invulnerablev1alpha1.ImageScan, apiEndpoint, sbomFormat, webhookURL string) []corev1.EnvVar {
 	env := []corev1.EnvVar{
 		{
@@ -423,6 +584,13 @@ func buildEnvVars(imageScan *invulnerablev1alpha1.ImageScan, apiEndpoint, sbomFo
 			},
 		)
 
+		if imageScan.Spec.Webhooks.Locale != "" {
+			env = append(env, corev1.EnvVar{
+				Name:  "WEBHOOK_LOCALE",
+				Value: imageScan.Spec.Webhooks.Locale,
+			})
+		}
+
 		// Add WEBHOOK_ONLY_FIXABLE if enabled
 		if imageScan.Spec.Webhooks.ScanCompletion.OnlyFixable {
 			env = append(env, corev1.EnvVar{
@@ -452,11 +620,42 @@ func buildEnvVars(imageScan *invulnerablev1alpha1.ImageScan, apiEndpoint, sbomFo
 				Value: fmt.Sprintf("%d", imageScan.Spec.SLA.Low),
 			},
 		)
+		if imageScan.Spec.SLA.BusinessDays {
+			env = append(env,
+				corev1.EnvVar{
+					Name:  "SLA_BUSINESS_DAYS",
+					Value: "true",
+				},
+				corev1.EnvVar{
+					Name:  "SLA_HOLIDAYS",
+					Value: strings.Join(imageScan.Spec.SLA.Holidays, ","),
+				},
+			)
+		}
+	}
+
+	if tz := slaTimeZone(imageScan); tz != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "SLA_TIME_ZONE",
+			Value: tz,
+		})
 	}
 
 	return env
 }
 
+// slaTimeZone returns the timezone SLA deadlines are computed in: spec.sla.timeZone, or the
+// timezone of the schedule, since both are the team's local time. Empty leaves the backend default (UTC)
+func slaTimeZone(imageScan *invulnerablev1alpha1.ImageScan) string {
+	if imageScan.Spec.SLA != nil && imageScan.Spec.SLA.TimeZone != "" {
+		return imageScan.Spec.SLA.TimeZone
+	}
+	if imageScan.Spec.TimeZone != nil {
+		return *imageScan.Spec.TimeZone
+	}
+	return ""
+}
+
 // syncWebhookConfig syncs webhook configuration to backend API
 func (r *ImageScanReconciler) syncWebhookConfig(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) error {
 	logger := log.FromContext(ctx)
@@ -467,17 +666,15 @@ func (r *ImageScanReconciler) syncWebhookConfig(ctx context.Context, imageScan *
 		return nil
 	}
 
-	// Skip if neither webhook type is configured
-	if imageScan.Spec.Webhooks.ScanCompletion == nil && imageScan.Spec.Webhooks.StatusChange == nil {
+	// Skip if no webhook type is configured
+	if imageScan.Spec.Webhooks.ScanCompletion == nil && imageScan.Spec.Webhooks.StatusChange == nil &&
+		imageScan.Spec.Webhooks.FixAvailable == nil {
 		logger.V(1).Info("No webhook types configured, skipping sync")
 		return nil
 	}
 
 	// Determine API endpoint
-	apiEndpoint := imageScan.Spec.APIEndpoint
-	if apiEndpoint == "" {
-		apiEndpoint = fmt.Sprintf("http://invulnerable-backend.%s.svc.cluster.local:8080", imageScan.Namespace)
-	}
+	apiEndpoint := r.backendEndpoint(imageScan)
 
 	// Build webhook config request
 	webhookReq := map[string]interface{}{}
@@ -501,6 +698,12 @@ func (r *ImageScanReconciler) syncWebhookConfig(ctx context.Context, imageScan *
 	}
 	webhookReq["webhook_format"] = format
 
+	locale := imageScan.Spec.Webhooks.Locale
+	if locale == "" {
+		locale = "en"
+	}
+	webhookReq["locale"] = locale
+
 	// Add scan completion webhook config
 	if imageScan.Spec.Webhooks.ScanCompletion != nil {
 		scanCompletion := imageScan.Spec.Webhooks.ScanCompletion
@@ -541,6 +744,20 @@ func (r *ImageScanReconciler) syncWebhookConfig(ctx context.Context, imageScan *
 		webhookReq["status_change_only_fixable"] = false
 	}
 
+	// Add fix-available webhook config, disabled unless configured
+	if fixAvailable := imageScan.Spec.Webhooks.FixAvailable; fixAvailable != nil {
+		webhookReq["fix_available_enabled"] = fixAvailable.Enabled
+
+		minSeverity := fixAvailable.MinSeverity
+		if minSeverity == "" {
+			minSeverity = "High"
+		}
+		webhookReq["fix_available_min_severity"] = minSeverity
+	} else {
+		webhookReq["fix_available_enabled"] = false
+		webhookReq["fix_available_min_severity"] = "High"
+	}
+
 	// Marshal request
 	reqBody, err := json.Marshal(webhookReq)
 	if err != nil {
@@ -583,10 +800,7 @@ func (r *ImageScanReconciler) deleteWebhookConfig(ctx context.Context, imageScan
 	logger := log.FromContext(ctx)
 
 	// Determine API endpoint
-	apiEndpoint := imageScan.Spec.APIEndpoint
-	if apiEndpoint == "" {
-		apiEndpoint = fmt.Sprintf("http://invulnerable-backend.%s.svc.cluster.local:8080", imageScan.Namespace)
-	}
+	apiEndpoint := r.backendEndpoint(imageScan)
 
 	// Send DELETE request to backend
 	url := fmt.Sprintf("%s/api/v1/webhook-configs/%s/%s", apiEndpoint, imageScan.Namespace, imageScan.Name)
@@ -619,6 +833,117 @@ func (r *ImageScanReconciler) deleteWebhookConfig(ctx context.Context, imageScan
 	return nil
 }
 
+// syncImageScanRegistration reports the ImageScan and its image to the backend API
+func (r *ImageScanReconciler) syncImageScanRegistration(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) error {
+	logger := log.FromContext(ctx)
+
+	// Determine API endpoint
+	apiEndpoint := r.backendEndpoint(imageScan)
+
+	// Suspension is reported so the backend can pause the image's SLA tracking and stale-scan alerts
+	suspended := imageScan.Spec.Schedule != nil && imageScan.Spec.Schedule.Suspend
+	registration := map[string]interface{}{
+		"image":     imageScan.Spec.Image,
+		"suspended": suspended,
+	}
+	if imageScan.Spec.StaleAfter != nil && imageScan.Spec.StaleAfter.Duration >= time.Second {
+		registration["stale_after_seconds"] = int(imageScan.Spec.StaleAfter.Duration.Seconds())
+	}
+	if retention := imageScan.Spec.Retention; retention != nil {
+		if retention.MaxScansRetained != nil {
+			registration["max_scans_retained"] = *retention.MaxScansRetained
+		}
+		if retention.MaxScanAge != nil && retention.MaxScanAge.Duration >= time.Second {
+			registration["max_scan_age_seconds"] = int(retention.MaxScanAge.Duration.Seconds())
+		}
+	}
+	if imageScan.Spec.Exposure != "" {
+		registration["exposure"] = imageScan.Spec.Exposure
+	}
+	// Labels are reported so compliance profiles can select the image
+	if len(imageScan.Labels) > 0 {
+		registration["labels"] = imageScan.Labels
+	}
+	reqBody, err := json.Marshal(registration)
+	if err != nil {
+		return fmt.Errorf("failed to marshal imagescan registration: %w", err)
+	}
+
+	// Send PUT request to backend
+	url := fmt.Sprintf("%s/api/v1/imagescans/%s/%s", apiEndpoint, imageScan.Namespace, imageScan.Name)
+	httpReq, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create imagescan registration request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	// Use HTTP client if available, otherwise create default
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to register imagescan with backend: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("backend returned non-2xx status for imagescan registration: %d", resp.StatusCode)
+	}
+
+	logger.V(1).Info("ImageScan registered with backend",
+		"namespace", imageScan.Namespace,
+		"name", imageScan.Name,
+		"suspended", suspended)
+
+	return nil
+}
+
+// deleteImageScanRegistration removes the ImageScan registration from backend API
+// With the Delete policy the backend also removes the image data once no ImageScan scans it
+func (r *ImageScanReconciler) deleteImageScanRegistration(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) error {
+	logger := log.FromContext(ctx)
+
+	// Determine API endpoint
+	apiEndpoint := r.backendEndpoint(imageScan)
+
+	// Send DELETE request to backend
+	url := fmt.Sprintf("%s/api/v1/imagescans/%s/%s", apiEndpoint, imageScan.Namespace, imageScan.Name)
+	if imageScan.Spec.DeletionPolicy == invulnerablev1alpha1.DeletionPolicyDelete {
+		url += "?purge=true"
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete imagescan registration request: %w", err)
+	}
+
+	// Use HTTP client if available, otherwise create default
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to unregister imagescan from backend: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Accept 404 as success (never registered or already removed)
+	if resp.StatusCode != http.StatusNotFound && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return fmt.Errorf("backend returned non-2xx status for imagescan unregistration: %d", resp.StatusCode)
+	}
+
+	logger.Info("ImageScan unregistered from backend",
+		"namespace", imageScan.Namespace,
+		"name", imageScan.Name,
+		"deletionPolicy", imageScan.Spec.DeletionPolicy)
+
+	return nil
+}
+
 // fetchImageDigest fetches the current digest of an image from the registry
 func (r *ImageScanReconciler) fetchImageDigest(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) (string, error) {
 	logger := log.FromContext(ctx)
@@ -728,14 +1053,54 @@ func (r *ImageScanReconciler) reconcileRegistryPolling(ctx context.Context, imag
 	return interval, nil
 }
 
+// reconcileScanRequest runs an on-demand scan when the scan-requested-at annotation changed since
+// the last request, and records the request in the status right away so it only runs once
+func (r *ImageScanReconciler) reconcileScanRequest(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) error {
+	requested := imageScan.Annotations[invulnerablev1alpha1.ScanRequestedAnnotation]
+	if requested == "" || requested == imageScan.Status.LastScanRequest {
+		return nil
+	}
+
+	if err := r.triggerImmediateScan(ctx, imageScan, manualTrigger); err != nil {
+		return err
+	}
+
+	imageScan.Status.LastScanRequest = requested
+	if err := r.Status().Update(ctx, imageScan); err != nil {
+		return fmt.Errorf("failed to record scan request: %w", err)
+	}
+	return nil
+}
+
 // triggerImmediateScan creates a Job directly (not via CronJob) for immediate execution
 func (r *ImageScanReconciler) triggerImmediateScan(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan, reason string) error {
 	logger := log.FromContext(ctx)
 
+	job, err := r.buildImmediateJob(imageScan, reason)
+	if err != nil {
+		return err
+	}
+
+	// Create the Job
+	if err := r.Create(ctx, job); err != nil {
+		return fmt.Errorf("failed to create immediate scan job: %w", err)
+	}
+
+	logger.Info("Triggered immediate scan", "job", job.Name, "reason", reason)
+	return nil
+}
+
+// buildImmediateJob builds the Job created by triggerImmediateScan
+func (r *ImageScanReconciler) buildImmediateJob(imageScan *invulnerablev1alpha1.ImageScan, reason string) (*batchv1.Job, error) {
+	prefix := imageScan.Name + "-registry-"
+	if reason == manualTrigger {
+		prefix = imageScan.Name + "-manual-"
+	}
+
 	// Create a Job directly for immediate execution
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
-			GenerateName: imageScan.Name + "-registry-",
+			GenerateName: prefix,
 			Namespace:    imageScan.Namespace,
 			Labels: map[string]string{
 				"app.kubernetes.io/name":       "invulnerable-scanner",
@@ -753,16 +1118,9 @@ func (r *ImageScanReconciler) triggerImmediateScan(ctx context.Context, imageSca
 
 	// Set owner reference
 	if err := controllerutil.SetControllerReference(imageScan, job, r.Scheme); err != nil {
-		return fmt.Errorf("failed to set controller reference: %w", err)
-	}
-
-	// Create the Job
-	if err := r.Create(ctx, job); err != nil {
-		return fmt.Errorf("failed to create immediate scan job: %w", err)
+		return nil, fmt.Errorf("failed to set controller reference: %w", err)
 	}
-
-	logger.Info("Triggered immediate scan", "job", job.Name, "reason", reason)
-	return nil
+	return job, nil
 }
 
 // buildJobSpec builds the JobSpec for scanner jobs
@@ -776,26 +1134,22 @@ func (r *ImageScanReconciler) buildJobSpec(imageScan *invulnerablev1alpha1.Image
 
 	scannerImage := "invulnerable-scanner:latest"
 	pullPolicy := corev1.PullIfNotPresent
-	if imageScan.Spec.ScannerImage != nil {
-		repo := imageScan.Spec.ScannerImage.Repository
+	if image := r.scannerImage(imageScan); image != nil {
+		repo := image.Repository
 		if repo == "" {
 			repo = "invulnerable-scanner"
 		}
-		tag := imageScan.Spec.ScannerImage.Tag
+		tag := image.Tag
 		if tag == "" {
 			tag = "latest"
 		}
 		scannerImage = fmt.Sprintf("%s:%s", repo, tag)
-		if imageScan.Spec.ScannerImage.PullPolicy != "" {
-			pullPolicy = imageScan.Spec.ScannerImage.PullPolicy
+		if image.PullPolicy != "" {
+			pullPolicy = image.PullPolicy
 		}
 	}
 
-	apiEndpoint := imageScan.Spec.APIEndpoint
-	if apiEndpoint == "" {
-		// Default to backend service in same namespace
-		apiEndpoint = fmt.Sprintf("http://invulnerable-backend.%s.svc.cluster.local:8080", imageScan.Namespace)
-	}
+	apiEndpoint := r.backendEndpoint(imageScan)
 
 	workspaceSize := imageScan.Spec.WorkspaceSize
 	if workspaceSize == "" {
@@ -825,7 +1179,7 @@ func (r *ImageScanReconciler) buildJobSpec(imageScan *invulnerablev1alpha1.Image
 		},
 		Containers: []corev1.Container{
 			{
-				Name:            "scanner",
+				Name:            scannerContainer,
 				Image:           scannerImage,
 				ImagePullPolicy: pullPolicy,
 				Env:             buildEnvVars(imageScan, apiEndpoint, sbomFormat, webhookURL),
@@ -862,6 +1216,10 @@ func (r *ImageScanReconciler) buildJobSpec(imageScan *invulnerablev1alpha1.Image
 	if imageScan.Spec.Resources != nil {
 		podSpec.Containers[0].Resources = *imageScan.Spec.Resources
 	}
+	applySizing(imageScan, &podSpec)
+
+	// Let the Kubernetes scheduler favour business-critical scans when nodes are short on capacity
+	podSpec.PriorityClassName = r.PriorityClasses[scanPriority(imageScan)]
 
 	// Set ImagePullSecrets if specified
 	if len(imageScan.Spec.ImagePullSecrets) > 0 {
@@ -899,6 +1257,31 @@ func (r *ImageScanReconciler) buildJobSpec(imageScan *invulnerablev1alpha1.Image
 		}
 	}
 
+	// Mount the scanner's client certificate for mutual TLS with the backend
+	if apiTLS := r.apiTLS(imageScan); apiTLS != nil {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "api-tls",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: apiTLS.SecretName,
+				},
+			},
+		})
+		podSpec.Containers[0].VolumeMounts = append(
+			podSpec.Containers[0].VolumeMounts,
+			corev1.VolumeMount{
+				Name:      "api-tls",
+				MountPath: apiTLSMountPath,
+				ReadOnly:  true,
+			},
+		)
+		podSpec.Containers[0].Env = append(podSpec.Containers[0].Env,
+			corev1.EnvVar{Name: "API_CA_CERT", Value: apiTLSMountPath + "/ca.crt"},
+			corev1.EnvVar{Name: "API_CLIENT_CERT", Value: apiTLSMountPath + "/tls.crt"},
+			corev1.EnvVar{Name: "API_CLIENT_KEY", Value: apiTLSMountPath + "/tls.key"},
+		)
+	}
+
 	return batchv1.JobSpec{
 		Template: corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
@@ -915,18 +1298,73 @@ func (r *ImageScanReconciler) buildJobSpec(imageScan *invulnerablev1alpha1.Image
 
 // SetupWithManager sets up the controller with the Manager
 func (r *ImageScanReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&invulnerablev1alpha1.ImageScan{}).
 		Owns(&batchv1.CronJob{}).
+		Owns(&networkingv1.NetworkPolicy{})
+	if r.SchedulingMode == SchedulingModeNative {
+		// Finished Jobs free concurrency slots and unblock skipped runs
+		builder = builder.Owns(&batchv1.Job{})
+	}
+	return builder.
+		// Jobs of CronJobs are owned by the CronJob, failed ones are mapped by label
+		Watches(
+			&batchv1.Job{},
+			handler.EnqueueRequestsFromMapFunc(failedScanJobImageScan),
+		).
+		// Scanner containers are restarted in place, their crashes are recorded before the
+		// failed Job deletes the pod
+		Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(crashedScannerPodImageScan),
+		).
 		Watches(
 			&corev1.Secret{},
 			&secretEventHandler{
 				client: mgr.GetClient(),
 			},
 		).
+		Watches(
+			&invulnerablev1alpha1.InvulnerableConfig{},
+			&configEventHandler{
+				client: mgr.GetClient(),
+				config: &r.config,
+			},
+		).
 		Complete(r)
 }
 
+// failedScanJobImageScan maps a failed scan Job to its ImageScan, to record the failure
+func failedScanJobImageScan(ctx context.Context, obj client.Object) []reconcile.Request {
+	job, ok := obj.(*batchv1.Job)
+	if !ok || job.Labels["app.kubernetes.io/name"] != "invulnerable-scanner" || job.Labels["app.kubernetes.io/instance"] == "" {
+		return nil
+	}
+	if _, failed := jobFailureTime(job); !failed {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: job.Namespace,
+		Name:      job.Labels["app.kubernetes.io/instance"],
+	}}}
+}
+
+// crashedScannerPodImageScan maps a scanner pod whose container crashed to its ImageScan,
+// to record the crash
+func crashedScannerPodImageScan(ctx context.Context, obj client.Object) []reconcile.Request {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Labels["app.kubernetes.io/name"] != "invulnerable-scanner" || pod.Labels["app.kubernetes.io/instance"] == "" {
+		return nil
+	}
+	if crash, _ := scannerCrash(pod); crash == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: pod.Namespace,
+		Name:      pod.Labels["app.kubernetes.io/instance"],
+	}}}
+}
+
 // secretEventHandler implements handler.EventHandler to trigger reconciliation
 // when Secrets referenced by ImageScans are updated
 type secretEventHandler struct {
@@ -0,0 +1,297 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+const (
+	// defaultSelectorResync is how often the Pods of an ImageScanSet selector are listed again
+	defaultSelectorResync = 5 * time.Minute
+
+	// maxDerivedNameLength keeps derived ImageScan names short enough for the CronJob
+	// and Job names built from them
+	maxDerivedNameLength = 43
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ImageScanSetReconciler reconciles an ImageScanSet object
+type ImageScanSetReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// APIReader lists the selected Pods without caching every Pod of the cluster
+	APIReader client.Reader
+}
+
+// +kubebuilder:rbac:groups=invulnerable.io,resources=imagescansets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=invulnerable.io,resources=imagescansets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=invulnerable.io,resources=imagescansets/finalizers,verbs=update
+// +kubebuilder:rbac:groups=invulnerable.io,resources=imagescans,verbs=create;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=list
+
+// Reconcile stamps out an ImageScan for each image of an ImageScanSet, updates them to the
+// template and deletes the ones whose image was removed
+func (r *ImageScanSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	set := &invulnerablev1alpha1.ImageScanSet{}
+	if err := r.Get(ctx, req.NamespacedName, set); err != nil {
+		if errors.IsNotFound(err) {
+			// Its ImageScans are garbage collected through their owner reference
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get ImageScanSet")
+		return ctrl.Result{}, err
+	}
+	if !set.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	images, err := r.resolveImages(ctx, set)
+	if err != nil {
+		logger.Error(err, "Failed to list selected Pods")
+		return ctrl.Result{}, r.setFailed(ctx, set, "SelectorFailed", err)
+	}
+
+	desired := map[string]bool{}
+	var conflicts []string
+	for _, name := range sortedKeys(images) {
+		desired[name] = true
+		if err := r.reconcileImageScan(ctx, set, name, images[name]); err != nil {
+			if err == errNotManaged {
+				conflicts = append(conflicts, name)
+				continue
+			}
+			logger.Error(err, "Failed to reconcile ImageScan", "imageScan", name)
+			return ctrl.Result{}, r.setFailed(ctx, set, "ReconcileFailed", err)
+		}
+	}
+
+	owned := &invulnerablev1alpha1.ImageScanList{}
+	if err := r.List(ctx, owned, client.InNamespace(set.Namespace),
+		client.MatchingLabels{invulnerablev1alpha1.ImageScanSetLabel: set.Name}); err != nil {
+		logger.Error(err, "Failed to list ImageScans")
+		return ctrl.Result{}, err
+	}
+	for i := range owned.Items {
+		imageScan := &owned.Items[i]
+		if desired[imageScan.Name] || !metav1.IsControlledBy(imageScan, set) {
+			continue
+		}
+		if err := r.Delete(ctx, imageScan); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete ImageScan", "imageScan", imageScan.Name)
+			return ctrl.Result{}, r.setFailed(ctx, set, "ReconcileFailed", err)
+		}
+		logger.Info("Deleted ImageScan of removed image", "imageScan", imageScan.Name, "image", imageScan.Spec.Image)
+	}
+
+	set.Status.ImageScans = int32(len(desired) - len(conflicts))
+	set.Status.ObservedGeneration = set.Generation
+	if len(conflicts) > 0 {
+		message := fmt.Sprintf("ImageScans not managed by this ImageScanSet already exist: %s", strings.Join(conflicts, ", "))
+		setSetCondition(set, metav1.ConditionFalse, "NameConflict", message)
+	} else {
+		setSetCondition(set, metav1.ConditionTrue, "ReconcileSuccess", fmt.Sprintf("%d ImageScans up to date", len(desired)))
+	}
+	if err := r.Status().Update(ctx, set); err != nil {
+		logger.Error(err, "Failed to update ImageScanSet status")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Successfully reconciled ImageScanSet", "imageScans", len(desired))
+
+	// Pods are not watched, the selector is resolved again periodically
+	if set.Spec.Selector != nil {
+		return ctrl.Result{RequeueAfter: selectorResync(set.Spec.Selector)}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+var errNotManaged = fmt.Errorf("ImageScan is not managed by the ImageScanSet")
+
+// reconcileImageScan creates or updates the ImageScan of an image from the template.
+// Labels and annotations set by others, e.g. the scan-requested-at annotation, are kept
+func (r *ImageScanSetReconciler) reconcileImageScan(ctx context.Context, set *invulnerablev1alpha1.ImageScanSet, name, image string) error {
+	imageScan := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: set.Namespace},
+	}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, imageScan, func() error {
+		if imageScan.ResourceVersion != "" && !metav1.IsControlledBy(imageScan, set) {
+			return errNotManaged
+		}
+
+		if imageScan.Labels == nil {
+			imageScan.Labels = map[string]string{}
+		}
+		for k, v := range set.Spec.Template.Metadata.Labels {
+			imageScan.Labels[k] = v
+		}
+		imageScan.Labels[invulnerablev1alpha1.ImageScanSetLabel] = set.Name
+
+		if len(set.Spec.Template.Metadata.Annotations) > 0 && imageScan.Annotations == nil {
+			imageScan.Annotations = map[string]string{}
+		}
+		for k, v := range set.Spec.Template.Metadata.Annotations {
+			imageScan.Annotations[k] = v
+		}
+
+		set.Spec.Template.Spec.DeepCopyInto(&imageScan.Spec)
+		imageScan.Spec.Image = image
+
+		return controllerutil.SetControllerReference(set, imageScan, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("Reconciled ImageScan from template", "imageScan", name, "image", image, "operation", result)
+	}
+	return nil
+}
+
+// resolveImages returns the image of each ImageScan of the set by name: the listed images,
+// then the images of the selected Pods that aren't listed
+func (r *ImageScanSetReconciler) resolveImages(ctx context.Context, set *invulnerablev1alpha1.ImageScanSet) (map[string]string, error) {
+	images := map[string]string{}
+	seen := map[string]bool{}
+	for _, entry := range set.Spec.Images {
+		if seen[entry.Image] {
+			continue
+		}
+		seen[entry.Image] = true
+		name := set.Name + "-" + entry.Name
+		if entry.Name == "" {
+			name = imageScanName(set.Name, entry.Image)
+		}
+		images[name] = entry.Image
+	}
+
+	if set.Spec.Selector == nil {
+		return images, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&set.Spec.Selector.PodSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid podSelector: %w", err)
+	}
+	pods := &corev1.PodList{}
+	if err := r.APIReader.List(ctx, pods, client.InNamespace(set.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list Pods: %w", err)
+	}
+	for _, image := range podImages(pods.Items) {
+		if seen[image] {
+			continue
+		}
+		seen[image] = true
+		images[imageScanName(set.Name, image)] = image
+	}
+
+	now := metav1.Now()
+	set.Status.LastSelectorSyncTime = &now
+	return images, nil
+}
+
+// podImages returns the distinct container and init container images of running or pending Pods
+func podImages(pods []corev1.Pod) []string {
+	seen := map[string]bool{}
+	images := []string{}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, c := range containers {
+			if c.Image != "" && !seen[c.Image] {
+				seen[c.Image] = true
+				images = append(images, c.Image)
+			}
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// imageScanName derives a stable ImageScan name from the image: the set name and the image's
+// last path segment, e.g. "base-nginx-1.25", then a hash of the full image so images with the
+// same last segment in other registries don't collide
+func imageScanName(setName, image string) string {
+	ref := image
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		ref = ref[i+1:]
+	}
+	base := invalidNameChars.ReplaceAllString(strings.ToLower(setName+"-"+ref), "-")
+	if len(base) > maxDerivedNameLength {
+		base = base[:maxDerivedNameLength]
+	}
+	base = strings.Trim(base, "-")
+
+	h := fnv.New32a()
+	h.Write([]byte(image))
+	return fmt.Sprintf("%s-%08x", base, h.Sum32())
+}
+
+func selectorResync(selector *invulnerablev1alpha1.ImageScanSetSelector) time.Duration {
+	if selector.ResyncInterval != nil && selector.ResyncInterval.Duration >= time.Minute {
+		return selector.ResyncInterval.Duration
+	}
+	return defaultSelectorResync
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// setFailed records a failed reconciliation in the Ready condition and returns the error
+func (r *ImageScanSetReconciler) setFailed(ctx context.Context, set *invulnerablev1alpha1.ImageScanSet, reason string, err error) error {
+	setSetCondition(set, metav1.ConditionFalse, reason, err.Error())
+	if statusErr := r.Status().Update(ctx, set); statusErr != nil {
+		log.FromContext(ctx).Error(statusErr, "Failed to update ImageScanSet status")
+	}
+	return err
+}
+
+func setSetCondition(set *invulnerablev1alpha1.ImageScanSet, status metav1.ConditionStatus, reason, message string) {
+	condition := metav1.Condition{
+		Type:               conditionTypeReady,
+		Status:             status,
+		ObservedGeneration: set.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            message,
+	}
+	meta.SetStatusCondition(&set.Status.Conditions, condition)
+}
+
+// SetupWithManager sets up the controller with the Manager
+func (r *ImageScanSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&invulnerablev1alpha1.ImageScanSet{}).
+		Owns(&invulnerablev1alpha1.ImageScan{}).
+		Complete(r)
+}
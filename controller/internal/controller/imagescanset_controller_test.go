@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+func TestImageScanName(t *testing.T) {
+	name := imageScanName("base", "docker.io/library/nginx:1.25")
+	if len(name) != len("base-nginx-1-25-")+8 || name[:len("base-nginx-1-25-")] != "base-nginx-1-25-" {
+		t.Errorf("name = %q, want base-nginx-1-25-<hash>", name)
+	}
+	if other := imageScanName("base", "ghcr.io/acme/nginx:1.25"); other == name {
+		t.Errorf("images with the same last segment share the name %q", name)
+	}
+	if again := imageScanName("base", "docker.io/library/nginx:1.25"); again != name {
+		t.Errorf("name is not stable: %q then %q", name, again)
+	}
+
+	long := imageScanName("a-very-long-imagescanset-name-for-production", "registry.example.com/team/service-with-a-long-name@sha256:abc")
+	if len(long) > maxDerivedNameLength+9 {
+		t.Errorf("name %q is %d characters long", long, len(long))
+	}
+}
+
+func TestPodImages(t *testing.T) {
+	pods := []corev1.Pod{
+		{
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Image: "busybox:1.36"}},
+				Containers:     []corev1.Container{{Image: "nginx:1.25"}, {Image: "envoy:1.29"}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		{
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Image: "nginx:1.25"}}},
+			Status: corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		{
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Image: "migrate:1.0"}}},
+			Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+		},
+	}
+
+	got := podImages(pods)
+	want := []string{"busybox:1.36", "envoy:1.29", "nginx:1.25"}
+	if len(got) != len(want) {
+		t.Fatalf("images = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("images = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestImageScanSetReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := invulnerablev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	set := &invulnerablev1alpha1.ImageScanSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "base"},
+		Spec: invulnerablev1alpha1.ImageScanSetSpec{
+			Images: []invulnerablev1alpha1.ImageScanSetImage{
+				{Image: "nginx:1.25", Name: "nginx"},
+				{Image: "redis:7.2", Name: "redis"},
+			},
+			Template: invulnerablev1alpha1.ImageScanTemplate{
+				Metadata: invulnerablev1alpha1.ImageScanTemplateMetadata{Labels: map[string]string{"team": "platform"}},
+				Spec: invulnerablev1alpha1.ImageScanSpec{
+					Schedule: &invulnerablev1alpha1.ScheduleConfig{Enabled: true, Cron: "0 2 * * *"},
+				},
+			},
+		},
+	}
+	// An ImageScan with a conflicting name that the set must leave alone
+	unmanaged := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "base-redis"},
+		Spec:       invulnerablev1alpha1.ImageScanSpec{Image: "redis:6"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(set, unmanaged).
+		WithStatusSubresource(&invulnerablev1alpha1.ImageScanSet{}).
+		Build()
+	r := &ImageScanSetReconciler{Client: c, Scheme: scheme, APIReader: c}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "prod", Name: "base"}}
+
+	reconcile := func() *invulnerablev1alpha1.ImageScanSet {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		got := &invulnerablev1alpha1.ImageScanSet{}
+		if err := c.Get(ctx, req.NamespacedName, got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	got := reconcile()
+	if got.Status.ImageScans != 1 || got.Status.Conditions[0].Reason != "NameConflict" {
+		t.Errorf("status = %+v, want 1 ImageScan and a name conflict", got.Status)
+	}
+
+	nginx := &invulnerablev1alpha1.ImageScan{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "prod", Name: "base-nginx"}, nginx); err != nil {
+		t.Fatalf("ImageScan not stamped out: %v", err)
+	}
+	if nginx.Spec.Image != "nginx:1.25" || nginx.Spec.Schedule == nil || nginx.Labels["team"] != "platform" ||
+		nginx.Labels[invulnerablev1alpha1.ImageScanSetLabel] != "base" || !metav1.IsControlledBy(nginx, got) {
+		t.Errorf("ImageScan = %+v", nginx)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(unmanaged), unmanaged); err != nil || unmanaged.Spec.Image != "redis:6" {
+		t.Errorf("unmanaged ImageScan was changed: %+v, %v", unmanaged.Spec, err)
+	}
+
+	// Template changes are propagated, annotations set on the ImageScan are kept, removed images are deleted
+	nginx.Annotations = map[string]string{invulnerablev1alpha1.ScanRequestedAnnotation: "2024-06-10T12:00:00Z"}
+	if err := c.Update(ctx, nginx); err != nil {
+		t.Fatal(err)
+	}
+	got.Spec.Images = []invulnerablev1alpha1.ImageScanSetImage{{Image: "nginx:1.26", Name: "nginx"}, {Image: "envoy:1.29"}}
+	got.Spec.Template.Spec.Schedule.Cron = "0 4 * * *"
+	if err := c.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	got = reconcile()
+
+	list := &invulnerablev1alpha1.ImageScanList{}
+	if err := c.List(ctx, list, client.MatchingLabels{invulnerablev1alpha1.ImageScanSetLabel: "base"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 || got.Status.ImageScans != 2 {
+		t.Fatalf("got %d ImageScans, status %d, want 2", len(list.Items), got.Status.ImageScans)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "prod", Name: "base-nginx"}, nginx); err != nil {
+		t.Fatal(err)
+	}
+	if nginx.Spec.Image != "nginx:1.26" || nginx.Spec.Schedule.Cron != "0 4 * * *" {
+		t.Errorf("template change not applied: %+v", nginx.Spec)
+	}
+	if nginx.Annotations[invulnerablev1alpha1.ScanRequestedAnnotation] == "" {
+		t.Errorf("annotation set on the ImageScan was removed")
+	}
+}
@@ -0,0 +1,274 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// inventoryPageSize is the number of Pods listed per request
+const inventoryPageSize = 500
+
+// InventoryReporter reports the images of the running and pending Pods to the backend, whose coverage
+// report then shows the deployed images nobody created an ImageScan for, with the workloads running
+// them and whether an Ingress or a LoadBalancer Service exposes those
+type InventoryReporter struct {
+	// APIReader lists Pods without caching every Pod of the cluster
+	APIReader  client.Reader
+	HTTPClient *http.Client
+	// Endpoint is the backend API, e.g. http://invulnerable-backend.invulnerable.svc.cluster.local:8080
+	Endpoint string
+	// Namespace restricts the Pods listed, empty lists every namespace
+	Namespace string
+	Interval  time.Duration
+}
+
+// inventoryImage is an image of the inventory, the namespace and the workloads running it
+type inventoryImage struct {
+	Image     string              `json:"image"`
+	Namespace string              `json:"namespace"`
+	Workloads []inventoryWorkload `json:"workloads"`
+}
+
+// inventoryWorkload is a workload running an image, with its running and pending Pods as replicas
+type inventoryWorkload struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Replicas int    `json:"replicas"`
+	// Exposed is set when an Ingress or a LoadBalancer Service routes to a Pod of the workload
+	Exposed bool `json:"exposed"`
+}
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=list
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=list
+
+// Start implements manager.Runnable
+func (r *InventoryReporter) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("inventory")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := r.Report(ctx); err != nil {
+			logger.Error(err, "Failed to report deployed images")
+		} else {
+			logger.V(1).Info("Reported deployed images", "images", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, a single replica reports
+func (r *InventoryReporter) NeedLeaderElection() bool {
+	return true
+}
+
+// Report sends the images of the Pods to the backend, replacing the previous inventory of the controller
+func (r *InventoryReporter) Report(ctx context.Context) (int, error) {
+	images, err := r.listImages(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	reqBody, err := json.Marshal(map[string]interface{}{"images": images})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPut, r.Endpoint+"/api/v1/inventory/controller", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create inventory request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to report inventory to backend: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("inventory report failed with status %d", resp.StatusCode)
+	}
+	return len(images), nil
+}
+
+// listImages returns the distinct images of the running and pending Pods of each namespace
+func (r *InventoryReporter) listImages(ctx context.Context) ([]inventoryImage, error) {
+	byNamespace := map[string][]corev1.Pod{}
+	var namespaces []string
+	opts := []client.ListOption{client.Limit(inventoryPageSize)}
+	if r.Namespace != "" {
+		opts = append(opts, client.InNamespace(r.Namespace))
+	}
+	for continueToken := ""; ; {
+		pods := &corev1.PodList{}
+		if err := r.APIReader.List(ctx, pods, append(opts, client.Continue(continueToken))...); err != nil {
+			return nil, fmt.Errorf("failed to list Pods: %w", err)
+		}
+		for _, pod := range pods.Items {
+			if _, ok := byNamespace[pod.Namespace]; !ok {
+				namespaces = append(namespaces, pod.Namespace)
+			}
+			byNamespace[pod.Namespace] = append(byNamespace[pod.Namespace], pod)
+		}
+		if continueToken = pods.Continue; continueToken == "" {
+			break
+		}
+	}
+
+	exposing, err := r.listExposingServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []inventoryImage{}
+	for _, namespace := range namespaces {
+		pods := byNamespace[namespace]
+		exposed := exposedPods(pods, exposing[namespace])
+		for _, image := range podImages(pods) {
+			images = append(images, inventoryImage{Image: image, Namespace: namespace, Workloads: imageWorkloads(pods, image, exposed)})
+		}
+	}
+	return images, nil
+}
+
+// listExposingServices returns the Services of each namespace that expose their Pods to the internet:
+// LoadBalancer Services and the Services behind an Ingress
+func (r *InventoryReporter) listExposingServices(ctx context.Context) (map[string][]corev1.Service, error) {
+	var opts []client.ListOption
+	if r.Namespace != "" {
+		opts = append(opts, client.InNamespace(r.Namespace))
+	}
+	services := &corev1.ServiceList{}
+	if err := r.APIReader.List(ctx, services, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list Services: %w", err)
+	}
+	ingresses := &networkingv1.IngressList{}
+	if err := r.APIReader.List(ctx, ingresses, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list Ingresses: %w", err)
+	}
+
+	// Names of the Services each Ingress routes to, per namespace
+	ingressBackends := map[string]map[string]bool{}
+	addBackend := func(namespace string, backend *networkingv1.IngressBackend) {
+		if backend == nil || backend.Service == nil {
+			return
+		}
+		if ingressBackends[namespace] == nil {
+			ingressBackends[namespace] = map[string]bool{}
+		}
+		ingressBackends[namespace][backend.Service.Name] = true
+	}
+	for _, ingress := range ingresses.Items {
+		addBackend(ingress.Namespace, ingress.Spec.DefaultBackend)
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				addBackend(ingress.Namespace, &path.Backend)
+			}
+		}
+	}
+
+	exposing := map[string][]corev1.Service{}
+	for _, service := range services.Items {
+		if service.Spec.Type == corev1.ServiceTypeLoadBalancer || ingressBackends[service.Namespace][service.Name] {
+			exposing[service.Namespace] = append(exposing[service.Namespace], service)
+		}
+	}
+	return exposing, nil
+}
+
+// exposedPods returns the names of the Pods selected by one of the exposing Services of their namespace
+func exposedPods(pods []corev1.Pod, exposing []corev1.Service) map[string]bool {
+	exposed := map[string]bool{}
+	for _, service := range exposing {
+		// Services without a selector route to manually managed endpoints
+		if len(service.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(service.Spec.Selector)
+		for _, pod := range pods {
+			if selector.Matches(labels.Set(pod.Labels)) {
+				exposed[pod.Name] = true
+			}
+		}
+	}
+	return exposed
+}
+
+// imageWorkloads returns the workloads of the running and pending Pods with a container of the image,
+// exposed when one of their Pods is
+func imageWorkloads(pods []corev1.Pod, image string, exposed map[string]bool) []inventoryWorkload {
+	workloads := []inventoryWorkload{}
+	index := map[[2]string]int{}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || !podRunsImage(&pod, image) {
+			continue
+		}
+		kind, name := podWorkload(&pod)
+		key := [2]string{kind, name}
+		if i, ok := index[key]; ok {
+			workloads[i].Replicas++
+			workloads[i].Exposed = workloads[i].Exposed || exposed[pod.Name]
+			continue
+		}
+		index[key] = len(workloads)
+		workloads = append(workloads, inventoryWorkload{Kind: kind, Name: name, Replicas: 1, Exposed: exposed[pod.Name]})
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].Kind != workloads[j].Kind {
+			return workloads[i].Kind < workloads[j].Kind
+		}
+		return workloads[i].Name < workloads[j].Name
+	})
+	return workloads
+}
+
+// podRunsImage reports whether a container or an init container of the Pod runs the image
+func podRunsImage(pod *corev1.Pod, image string) bool {
+	for _, c := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		if c.Image == image {
+			return true
+		}
+	}
+	return false
+}
+
+// podWorkload returns the workload owning the Pod. A ReplicaSet created by a Deployment is named after the
+// Deployment and the pod-template-hash label, which resolves the Deployment without reading the ReplicaSet.
+// Pods without a controller are their own workload.
+func podWorkload(pod *corev1.Pod) (kind, name string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+	if owner.Kind == "ReplicaSet" {
+		if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return owner.Kind, owner.Name
+}
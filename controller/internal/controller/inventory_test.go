@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInventoryReporter(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := func(namespace, name string, phase corev1.PodPhase, owner *metav1.OwnerReference, images ...string) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{
+				"pod-template-hash": "5d8f7", "app": strings.SplitN(name, "-", 2)[0],
+			}},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+		if owner != nil {
+			p.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		for _, image := range images {
+			p.Spec.Containers = append(p.Spec.Containers, corev1.Container{Image: image})
+		}
+		return p
+	}
+	service := func(namespace, name string, serviceType corev1.ServiceType, app string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       corev1.ServiceSpec{Type: serviceType, Selector: map[string]string{"app": app}},
+		}
+	}
+	controlled := func(kind, name string) *metav1.OwnerReference {
+		isController := true
+		return &metav1.OwnerReference{APIVersion: "apps/v1", Kind: kind, Name: name, UID: "uid", Controller: &isController}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pod("web", "nginx-5d8f7-a", corev1.PodRunning, controlled("ReplicaSet", "nginx-5d8f7"), "nginx:1.25", "envoy:1.29"),
+		pod("web", "nginx-5d8f7-b", corev1.PodRunning, controlled("ReplicaSet", "nginx-5d8f7"), "nginx:1.25"),
+		pod("web", "cache-0", corev1.PodRunning, controlled("StatefulSet", "cache"), "nginx:1.25"),
+		pod("payments", "api", corev1.PodPending, nil, "nginx:1.25"),
+		pod("payments", "migrate", corev1.PodSucceeded, controlled("Job", "migrate"), "migrate:1.0"),
+		// An Ingress exposes nginx, a LoadBalancer exposes api, cache is only reachable in the cluster
+		service("web", "nginx", corev1.ServiceTypeClusterIP, "nginx"),
+		service("web", "cache", corev1.ServiceTypeClusterIP, "cache"),
+		service("payments", "api", corev1.ServiceTypeLoadBalancer, "api"),
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "nginx"},
+			Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{Backend: networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{Name: "nginx"},
+					}}},
+				}},
+			}}},
+		},
+	).Build()
+
+	var path string
+	var got struct {
+		Images []inventoryImage `json:"images"`
+	}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path = req.Method + " " + req.URL.Path
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			t.Errorf("invalid inventory: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	r := &InventoryReporter{APIReader: c, Endpoint: backend.URL}
+	count, err := r.Report(context.Background())
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if path != "PUT /api/v1/inventory/controller" {
+		t.Errorf("request = %q, want PUT /api/v1/inventory/controller", path)
+	}
+
+	// Images are reported once per namespace with their workloads, finished Pods are left out
+	want := map[string][]inventoryWorkload{
+		"web/envoy:1.29": {{Kind: "Deployment", Name: "nginx", Replicas: 1, Exposed: true}},
+		"web/nginx:1.25": {
+			{Kind: "Deployment", Name: "nginx", Replicas: 2, Exposed: true},
+			{Kind: "StatefulSet", Name: "cache", Replicas: 1},
+		},
+		"payments/nginx:1.25": {{Kind: "Pod", Name: "api", Replicas: 1, Exposed: true}},
+	}
+	reported := map[string][]inventoryWorkload{}
+	for _, image := range got.Images {
+		reported[image.Namespace+"/"+image.Image] = image.Workloads
+	}
+	if count != len(want) || len(got.Images) != len(want) || !reflect.DeepEqual(reported, want) {
+		t.Errorf("reported %d images %v, want %v", count, reported, want)
+	}
+
+	r.Namespace = "payments"
+	if _, err := r.Report(context.Background()); err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if len(got.Images) != 1 || got.Images[0].Namespace != "payments" {
+		t.Errorf("images = %v, want the payments namespace only", got.Images)
+	}
+
+	backend.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if _, err := r.Report(context.Background()); err == nil {
+		t.Error("Report succeeded with a failing backend")
+	}
+}
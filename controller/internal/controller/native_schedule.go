@@ -0,0 +1,296 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+	"github.com/pacokleitz/invulnerable/pkg/cron"
+)
+
+// Scheduling modes for spec.schedule
+const (
+	// SchedulingModeCronJob creates a CronJob per ImageScan
+	SchedulingModeCronJob = "cronjob"
+	// SchedulingModeNative has the controller create the scan Jobs itself, which avoids
+	// one CronJob object per ImageScan when there are hundreds of them
+	SchedulingModeNative = "native"
+)
+
+const (
+	// scheduleTrigger is the invulnerable.io/trigger label of Jobs created by the native scheduler
+	scheduleTrigger = "Schedule"
+
+	// concurrencyRetryInterval is how long a due scan waits when MaxConcurrentScans is reached
+	concurrencyRetryInterval = 30 * time.Second
+
+	// maxMissedRuns bounds the catch-up search after a long outage or suspension
+	maxMissedRuns = 1000
+)
+
+// reconcileNativeSchedule runs spec.schedule without a CronJob and returns when to requeue.
+// Like a CronJob with ForbidConcurrent, a run is skipped while the previous scan is still running,
+// and missed runs collapse into a single catch-up scan
+func (r *ImageScanReconciler) reconcileNativeSchedule(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	schedule, err := cron.Parse(imageScan.Spec.Schedule.Cron)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule %q: %w", imageScan.Spec.Schedule.Cron, err)
+	}
+	loc := time.Local
+	if imageScan.Spec.TimeZone != nil && *imageScan.Spec.TimeZone != "" {
+		loc, err = time.LoadLocation(*imageScan.Spec.TimeZone)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timeZone %q: %w", *imageScan.Spec.TimeZone, err)
+		}
+	}
+
+	// Set again below while the due scan waits for the concurrent scan limit
+	imageScan.Status.QueuePosition = 0
+
+	if imageScan.Spec.Schedule.Suspend {
+		r.queue.done(client.ObjectKeyFromObject(imageScan))
+		imageScan.Status.NextScheduleTime = nil
+		return 0, nil
+	}
+
+	now := time.Now().In(loc)
+	jitter := scheduleJitter(imageScan, r.scheduleJitter())
+
+	// Runs are counted from the last one, or from the creation of the ImageScan
+	last := imageScan.CreationTimestamp.Time
+	if imageScan.Status.LastScheduleTime != nil {
+		last = imageScan.Status.LastScheduleTime.Time
+	}
+	due := schedule.Next(last.In(loc))
+	if due.IsZero() {
+		return 0, fmt.Errorf("schedule %q never runs", imageScan.Spec.Schedule.Cron)
+	}
+	for i := 0; i < maxMissedRuns; i++ {
+		next := schedule.Next(due)
+		if next.IsZero() || next.Add(jitter).After(now) {
+			break
+		}
+		due = next
+	}
+
+	runAt := due.Add(jitter)
+	if now.Before(runAt) {
+		imageScan.Status.NextScheduleTime = &metav1.Time{Time: runAt}
+		logger.V(1).Info("Next scheduled scan", "at", runAt)
+		return runAt.Sub(now), nil
+	}
+
+	key := client.ObjectKeyFromObject(imageScan)
+	jobs, err := r.listScheduledJobs(ctx, client.InNamespace(imageScan.Namespace),
+		client.MatchingLabels{"app.kubernetes.io/instance": imageScan.Name})
+	if err != nil {
+		return 0, err
+	}
+
+	if hasActiveJob(jobs) {
+		logger.Info("Skipping scheduled scan, the previous scan is still running", "scheduledTime", due)
+	} else {
+		if limit := r.maxConcurrentScans(); limit > 0 {
+			all, err := r.listScheduledJobs(ctx)
+			if err != nil {
+				return 0, err
+			}
+			// Free slots go to the waiting scans with the highest priority, shared between namespaces
+			// by weighted round-robin, then to the longest waiting
+			active := countActiveJobs(all)
+			ahead := r.queue.wait(key, scanPriority(imageScan), activeJobsByNamespace(all), r.namespaceWeights(), now)
+			if active+ahead >= limit {
+				// The run is delayed, not skipped: LastScheduleTime only moves once the Job exists
+				logger.V(1).Info("Delaying scheduled scan, concurrent scan limit reached",
+					"active", active, "queuedAhead", ahead, "limit", limit)
+				imageScan.Status.QueuePosition = int32(ahead + 1)
+				imageScan.Status.NextScheduleTime = &metav1.Time{Time: now.Add(concurrencyRetryInterval)}
+				return concurrencyRetryInterval, nil
+			}
+		}
+
+		if err := r.createScheduledJob(ctx, imageScan, due); err != nil {
+			return 0, err
+		}
+	}
+	r.queue.done(key)
+
+	imageScan.Status.LastScheduleTime = &metav1.Time{Time: due}
+	if err := r.pruneScheduledJobs(ctx, imageScan, jobs); err != nil {
+		logger.Error(err, "Failed to clean up finished scheduled scan jobs (non-fatal)")
+	}
+
+	next := schedule.Next(due)
+	if next.IsZero() {
+		imageScan.Status.NextScheduleTime = nil
+		return 0, nil
+	}
+	runAt = next.Add(jitter)
+	imageScan.Status.NextScheduleTime = &metav1.Time{Time: runAt}
+	return runAt.Sub(now), nil
+}
+
+// createScheduledJob creates the Job of a scheduled run. The name is derived from the schedule
+// time like CronJob Jobs, so a run is never created twice if the status update is lost
+func (r *ImageScanReconciler) createScheduledJob(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan, scheduledTime time.Time) error {
+	logger := log.FromContext(ctx)
+
+	job, err := r.buildScheduledJob(imageScan, scheduledTime)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Create(ctx, job); err != nil {
+		if errors.IsAlreadyExists(err) {
+			logger.V(1).Info("Scheduled scan job already exists", "job", job.Name)
+			return nil
+		}
+		return fmt.Errorf("failed to create scheduled scan job: %w", err)
+	}
+
+	logger.Info("Triggered scheduled scan", "job", job.Name, "scheduledTime", scheduledTime)
+	return nil
+}
+
+// buildScheduledJob builds the Job of a scheduled run in native mode
+func (r *ImageScanReconciler) buildScheduledJob(imageScan *invulnerablev1alpha1.ImageScan, scheduledTime time.Time) (*batchv1.Job, error) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-scanner-%d", imageScan.Name, scheduledTime.Unix()/60),
+			Namespace: imageScan.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "invulnerable-scanner",
+				"app.kubernetes.io/instance":   imageScan.Name,
+				"app.kubernetes.io/component":  "scanner",
+				"app.kubernetes.io/managed-by": "invulnerable-controller",
+				"invulnerable.io/trigger":      scheduleTrigger,
+			},
+			Annotations: map[string]string{
+				"invulnerable.io/imagescan":      imageScan.Name,
+				"invulnerable.io/trigger":        scheduleTrigger,
+				"invulnerable.io/scheduled-time": scheduledTime.UTC().Format(time.RFC3339),
+			},
+		},
+		Spec: r.buildJobSpec(imageScan),
+	}
+
+	if err := controllerutil.SetControllerReference(imageScan, job, r.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set controller reference: %w", err)
+	}
+	return job, nil
+}
+
+// listScheduledJobs lists the Jobs created by the native scheduler
+func (r *ImageScanReconciler) listScheduledJobs(ctx context.Context, opts ...client.ListOption) ([]batchv1.Job, error) {
+	jobs := &batchv1.JobList{}
+	opts = append(opts, client.MatchingLabels{
+		"app.kubernetes.io/managed-by": "invulnerable-controller",
+		"invulnerable.io/trigger":      scheduleTrigger,
+	})
+	if err := r.List(ctx, jobs, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list scheduled scan jobs: %w", err)
+	}
+	return jobs.Items, nil
+}
+
+// pruneScheduledJobs applies successfulJobsHistoryLimit and failedJobsHistoryLimit,
+// which the CronJob controller enforces in CronJob mode
+func (r *ImageScanReconciler) pruneScheduledJobs(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan, jobs []batchv1.Job) error {
+	successfulLimit := 3
+	if imageScan.Spec.SuccessfulJobsHistoryLimit != nil {
+		successfulLimit = int(*imageScan.Spec.SuccessfulJobsHistoryLimit)
+	}
+	failedLimit := 3
+	if imageScan.Spec.FailedJobsHistoryLimit != nil {
+		failedLimit = int(*imageScan.Spec.FailedJobsHistoryLimit)
+	}
+
+	var succeeded, failed []batchv1.Job
+	for _, job := range jobs {
+		switch jobFinishedCondition(&job) {
+		case batchv1.JobComplete:
+			succeeded = append(succeeded, job)
+		case batchv1.JobFailed:
+			failed = append(failed, job)
+		}
+	}
+
+	for _, group := range []struct {
+		jobs  []batchv1.Job
+		limit int
+	}{{succeeded, successfulLimit}, {failed, failedLimit}} {
+		if len(group.jobs) <= group.limit {
+			continue
+		}
+		sort.Slice(group.jobs, func(i, j int) bool {
+			return group.jobs[i].CreationTimestamp.After(group.jobs[j].CreationTimestamp.Time)
+		})
+		for i := range group.jobs[group.limit:] {
+			job := &group.jobs[group.limit+i]
+			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete job %s: %w", job.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// jobFinishedCondition returns JobComplete or JobFailed for a finished Job, and "" otherwise
+func jobFinishedCondition(job *batchv1.Job) batchv1.JobConditionType {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return c.Type
+		}
+	}
+	return ""
+}
+
+func hasActiveJob(jobs []batchv1.Job) bool {
+	return countActiveJobs(jobs) > 0
+}
+
+func countActiveJobs(jobs []batchv1.Job) int {
+	active := 0
+	for i := range jobs {
+		if jobFinishedCondition(&jobs[i]) == "" {
+			active++
+		}
+	}
+	return active
+}
+
+// activeJobsByNamespace counts the unfinished Jobs of each namespace
+func activeJobsByNamespace(jobs []batchv1.Job) map[string]int {
+	active := map[string]int{}
+	for i := range jobs {
+		if jobFinishedCondition(&jobs[i]) == "" {
+			active[jobs[i].Namespace]++
+		}
+	}
+	return active
+}
+
+// scheduleJitter delays the runs of an ImageScan by a stable offset below maxJitter,
+// so ImageScans sharing a schedule don't all start their Jobs in the same minute
+func scheduleJitter(imageScan *invulnerablev1alpha1.ImageScan, maxJitter time.Duration) time.Duration {
+	if maxJitter <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(imageScan.Namespace + "/" + imageScan.Name))
+	return time.Duration(h.Sum64() % uint64(maxJitter))
+}
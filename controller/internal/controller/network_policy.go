@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+const (
+	// backendPort is the container port of the backend pods. NetworkPolicies match the pod's
+	// port, not the Service's
+	backendPort = 8080
+
+	// defaultEgressPort is the port of the registry and the Grype DB without spec.networkPolicy.ports
+	defaultEgressPort = 443
+)
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+
+// reconcileNetworkPolicy creates or updates the NetworkPolicy of an ImageScan's scan pods when
+// spec.networkPolicy is enabled, and deletes it otherwise
+func (r *ImageScanReconciler) reconcileNetworkPolicy(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) error {
+	logger := log.FromContext(ctx)
+	name := fmt.Sprintf("%s-scanner", imageScan.Name)
+
+	existing := &networkingv1.NetworkPolicy{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: imageScan.Namespace, Name: name}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get NetworkPolicy: %w", err)
+	}
+	found := err == nil
+
+	if imageScan.Spec.NetworkPolicy == nil || !imageScan.Spec.NetworkPolicy.Enabled {
+		if found && metav1.IsControlledBy(existing, imageScan) {
+			logger.Info("Deleting NetworkPolicy", "name", name)
+			if err := r.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete NetworkPolicy: %w", err)
+			}
+		}
+		return nil
+	}
+
+	desired, err := r.buildNetworkPolicy(imageScan)
+	if err != nil {
+		return err
+	}
+	if !found {
+		logger.Info("Creating NetworkPolicy", "name", name)
+		return r.Create(ctx, desired)
+	}
+	existing.Labels = desired.Labels
+	existing.Spec = desired.Spec
+	return r.Update(ctx, existing)
+}
+
+// buildNetworkPolicy builds the NetworkPolicy restricting the egress of an ImageScan's scan pods
+// to DNS, the backend, the registry and the Grype DB
+func (r *ImageScanReconciler) buildNetworkPolicy(imageScan *invulnerablev1alpha1.ImageScan) (*networkingv1.NetworkPolicy, error) {
+	config := imageScan.Spec.NetworkPolicy
+	tcp, udp := corev1.ProtocolTCP, corev1.ProtocolUDP
+
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		// DNS, to resolve the registry, the Grype DB and the backend
+		{
+			To: []networkingv1.NetworkPolicyPeer{{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "kube-system"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}},
+			}},
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &udp, Port: ptr(intstr.FromInt32(53))},
+				{Protocol: &tcp, Port: ptr(intstr.FromInt32(53))},
+			},
+		},
+		// The backend pods of the namespace
+		{
+			To: []networkingv1.NetworkPolicyPeer{{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app.kubernetes.io/component": "backend"}},
+			}},
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: ptr(intstr.FromInt32(backendPort))}},
+		},
+	}
+
+	if len(config.BackendCIDRs) > 0 {
+		port, err := endpointPort(r.backendEndpoint(imageScan))
+		if err != nil {
+			return nil, err
+		}
+		peers, err := ipBlockPeers("backendCIDRs", config.BackendCIDRs)
+		if err != nil {
+			return nil, err
+		}
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			To:    peers,
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: ptr(intstr.FromInt32(port))}},
+		})
+	}
+
+	ports := config.Ports
+	if len(ports) == 0 {
+		ports = []int32{defaultEgressPort}
+	}
+	policyPorts := make([]networkingv1.NetworkPolicyPort, 0, len(ports))
+	for _, port := range ports {
+		policyPorts = append(policyPorts, networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: ptr(intstr.FromInt32(port))})
+	}
+	for _, endpoint := range []struct {
+		field string
+		cidrs []string
+	}{
+		{"registryCIDRs", config.RegistryCIDRs},
+		{"grypeDBCIDRs", config.GrypeDBCIDRs},
+	} {
+		if len(endpoint.cidrs) == 0 {
+			continue
+		}
+		peers, err := ipBlockPeers(endpoint.field, endpoint.cidrs)
+		if err != nil {
+			return nil, err
+		}
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{To: peers, Ports: policyPorts})
+	}
+
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-scanner", imageScan.Name),
+			Namespace: imageScan.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "invulnerable-scanner",
+				"app.kubernetes.io/instance":   imageScan.Name,
+				"app.kubernetes.io/component":  "scanner",
+				"app.kubernetes.io/managed-by": "invulnerable-controller",
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{
+				"app.kubernetes.io/name":     "invulnerable-scanner",
+				"app.kubernetes.io/instance": imageScan.Name,
+			}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      egress,
+		},
+	}
+	if err := controllerutil.SetControllerReference(imageScan, policy, r.Scheme); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// ipBlockPeers returns a NetworkPolicy peer per CIDR of a spec.networkPolicy field
+func ipBlockPeers(field string, cidrs []string) ([]networkingv1.NetworkPolicyPeer, error) {
+	peers := make([]networkingv1.NetworkPolicyPeer, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("invalid networkPolicy.%s entry %q: %w", field, cidr, err)
+		}
+		peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+	}
+	return peers, nil
+}
+
+// endpointPort returns the port of an API endpoint URL, 443 or 80 when it has none
+func endpointPort(endpoint string) (int32, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return 0, fmt.Errorf("invalid apiEndpoint %q", endpoint)
+	}
+	if p := u.Port(); p != "" {
+		port, err := strconv.ParseInt(p, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid apiEndpoint port %q", p)
+		}
+		return int32(port), nil
+	}
+	if u.Scheme == "https" {
+		return 443, nil
+	}
+	return 80, nil
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+func TestReconcileNetworkPolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := invulnerablev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	imageScan := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx", UID: "uid-1"},
+		Spec: invulnerablev1alpha1.ImageScanSpec{
+			Image:       "registry.example.com/nginx:1.25",
+			APIEndpoint: "https://invulnerable.example.com",
+			NetworkPolicy: &invulnerablev1alpha1.NetworkPolicyConfig{
+				Enabled:       true,
+				RegistryCIDRs: []string{"203.0.113.0/24"},
+				GrypeDBCIDRs:  []string{"198.51.100.10/32"},
+				BackendCIDRs:  []string{"192.0.2.0/28"},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(imageScan).Build()
+	r := &ImageScanReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+
+	if err := r.reconcileNetworkPolicy(ctx, imageScan); err != nil {
+		t.Fatalf("reconcileNetworkPolicy: %v", err)
+	}
+	policy := &networkingv1.NetworkPolicy{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "prod", Name: "nginx-scanner"}, policy); err != nil {
+		t.Fatalf("NetworkPolicy not created: %v", err)
+	}
+	if policy.Spec.PodSelector.MatchLabels["app.kubernetes.io/instance"] != "nginx" ||
+		len(policy.Spec.PolicyTypes) != 1 || policy.Spec.PolicyTypes[0] != networkingv1.PolicyTypeEgress {
+		t.Errorf("spec = %+v", policy.Spec)
+	}
+	// DNS, backend pods, backend CIDRs, registry, Grype DB
+	if len(policy.Spec.Egress) != 5 {
+		t.Fatalf("got %d egress rules, want 5: %+v", len(policy.Spec.Egress), policy.Spec.Egress)
+	}
+	backend := policy.Spec.Egress[2]
+	if backend.To[0].IPBlock.CIDR != "192.0.2.0/28" || backend.Ports[0].Port.IntVal != 443 {
+		t.Errorf("backend rule = %+v", backend)
+	}
+	registry := policy.Spec.Egress[3]
+	if registry.To[0].IPBlock.CIDR != "203.0.113.0/24" || registry.Ports[0].Port.IntVal != defaultEgressPort {
+		t.Errorf("registry rule = %+v", registry)
+	}
+
+	// Updated with the spec
+	imageScan.Spec.NetworkPolicy.Ports = []int32{443, 5000}
+	if err := r.reconcileNetworkPolicy(ctx, imageScan); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "prod", Name: "nginx-scanner"}, policy); err != nil {
+		t.Fatal(err)
+	}
+	if ports := policy.Spec.Egress[3].Ports; len(ports) != 2 || ports[1].Port.IntVal != 5000 {
+		t.Errorf("registry ports = %+v", ports)
+	}
+
+	// Deleted once disabled
+	imageScan.Spec.NetworkPolicy.Enabled = false
+	if err := r.reconcileNetworkPolicy(ctx, imageScan); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "prod", Name: "nginx-scanner"}, policy); !errors.IsNotFound(err) {
+		t.Errorf("NetworkPolicy not deleted: %v", err)
+	}
+}
+
+func TestBuildNetworkPolicy_InvalidCIDR(t *testing.T) {
+	r := &ImageScanReconciler{Scheme: runtime.NewScheme()}
+	imageScan := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx"},
+		Spec: invulnerablev1alpha1.ImageScanSpec{
+			NetworkPolicy: &invulnerablev1alpha1.NetworkPolicyConfig{Enabled: true, RegistryCIDRs: []string{"registry.example.com"}},
+		},
+	}
+	if _, err := r.buildNetworkPolicy(imageScan); err == nil {
+		t.Error("a hostname was accepted as a CIDR")
+	}
+}
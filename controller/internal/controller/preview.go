@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+	"github.com/pacokleitz/invulnerable/pkg/cron"
+)
+
+// maxPreviewBody bounds the ImageScan manifests posted to the preview endpoint
+const maxPreviewBody = 1 << 20
+
+// PreviewServer serves the CronJob the controller would apply for an ImageScan, without applying it,
+// so the scanner's env vars, mounts and credentials can be checked before a scheduled run fails:
+//
+//	GET  /preview/{namespace}/{name}   an existing ImageScan
+//	POST /preview                      an ImageScan manifest (YAML or JSON), e.g. before applying it
+//
+// It runs on every replica, not only the leader
+type PreviewServer struct {
+	Addr       string
+	Reconciler *ImageScanReconciler
+}
+
+// Start implements manager.Runnable
+func (s *PreviewServer) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("preview")
+	server := &http.Server{
+		Addr:              s.Addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("Serving ImageScan previews", "addr", s.Addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		return err
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (s *PreviewServer) NeedLeaderElection() bool {
+	return false
+}
+
+// ServeHTTP implements http.Handler
+func (s *PreviewServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	imageScan := &invulnerablev1alpha1.ImageScan{}
+
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, "/preview"), "/")
+	switch {
+	case req.Method == http.MethodGet && strings.Count(path, "/") == 1:
+		namespace, name, _ := strings.Cut(path, "/")
+		if err := s.Reconciler.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, imageScan); err != nil {
+			if errors.IsNotFound(err) {
+				http.Error(w, fmt.Sprintf("ImageScan %s/%s not found", namespace, name), http.StatusNotFound)
+				return
+			}
+			http.Error(w, fmt.Sprintf("failed to get ImageScan: %v", err), http.StatusInternalServerError)
+			return
+		}
+	case req.Method == http.MethodPost && path == "":
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxPreviewBody))
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		// YAML is a superset of JSON
+		if err := yaml.UnmarshalStrict(body, imageScan); err != nil {
+			http.Error(w, fmt.Sprintf("invalid ImageScan manifest: %v", err), http.StatusBadRequest)
+			return
+		}
+		if imageScan.Kind != "" && imageScan.Kind != "ImageScan" {
+			http.Error(w, fmt.Sprintf("expected an ImageScan, got a %s", imageScan.Kind), http.StatusBadRequest)
+			return
+		}
+		if imageScan.Name == "" || imageScan.Spec.Image == "" {
+			http.Error(w, "metadata.name and spec.image are required", http.StatusBadRequest)
+			return
+		}
+		if imageScan.Namespace == "" {
+			imageScan.Namespace = "default"
+		}
+	default:
+		http.Error(w, "use GET /preview/{namespace}/{name} or POST /preview with an ImageScan manifest", http.StatusNotFound)
+		return
+	}
+
+	rendered, warnings, err := s.Reconciler.Preview(ctx, imageScan)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	out, err := yaml.Marshal(rendered)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to render: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "# Warning: %s\n", warning)
+	}
+	w.Write(out)
+}
+
+// Preview renders what the controller would apply for an ImageScan: its CronJob, or in native
+// scheduling mode and with registry polling only, the Job it creates for each scan. Webhook URLs
+// read from a Secret are redacted. The warnings list what would make the scan fail
+func (r *ImageScanReconciler) Preview(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) (client.Object, []string, error) {
+	warnings := r.previewWarnings(ctx, imageScan)
+
+	scheduleEnabled := imageScan.Spec.Schedule != nil && imageScan.Spec.Schedule.Enabled
+	var rendered client.Object
+	var podSpec *corev1.PodSpec
+	if scheduleEnabled && r.SchedulingMode != SchedulingModeNative {
+		cronJob, err := r.buildCronJob(imageScan)
+		if err != nil {
+			return nil, nil, err
+		}
+		cronJob.APIVersion, cronJob.Kind = "batch/v1", "CronJob"
+		rendered, podSpec = cronJob, &cronJob.Spec.JobTemplate.Spec.Template.Spec
+	} else {
+		var job *batchv1.Job
+		var err error
+		if scheduleEnabled {
+			job, err = r.buildScheduledJob(imageScan, time.Now())
+		} else {
+			job, err = r.buildImmediateJob(imageScan, "RegistryUpdate")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		job.APIVersion, job.Kind = "batch/v1", "Job"
+		rendered, podSpec = job, &job.Spec.Template.Spec
+	}
+
+	if imageScan.Spec.Webhooks != nil && imageScan.Spec.Webhooks.SecretRef != nil {
+		for i := range podSpec.Containers {
+			for j, env := range podSpec.Containers[i].Env {
+				if env.Name == "WEBHOOK_URL" {
+					podSpec.Containers[i].Env[j].Value = fmt.Sprintf("<redacted: secret %s>", imageScan.Spec.Webhooks.SecretRef.Name)
+				}
+			}
+		}
+	}
+
+	return rendered, warnings, nil
+}
+
+// previewWarnings checks the parts of an ImageScan that are only resolved when the scan runs
+func (r *ImageScanReconciler) previewWarnings(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) []string {
+	warnings := []string{}
+	spec := imageScan.Spec
+
+	scheduleEnabled := spec.Schedule != nil && spec.Schedule.Enabled
+	if !scheduleEnabled && (spec.RegistryPolling == nil || !spec.RegistryPolling.Enabled) {
+		warnings = append(warnings, "neither schedule nor registryPolling is enabled, the ImageScan is rejected")
+	}
+	if scheduleEnabled {
+		if _, err := cron.Parse(spec.Schedule.Cron); err != nil {
+			warnings = append(warnings, fmt.Sprintf("invalid schedule %q: %v", spec.Schedule.Cron, err))
+		}
+		if spec.Schedule.Suspend {
+			warnings = append(warnings, "the schedule is suspended")
+		}
+	}
+	if spec.TimeZone != nil && *spec.TimeZone != "" {
+		if _, err := time.LoadLocation(*spec.TimeZone); err != nil {
+			warnings = append(warnings, fmt.Sprintf("invalid timeZone %q", *spec.TimeZone))
+		}
+	}
+
+	if spec.Webhooks != nil {
+		if _, err := r.resolveWebhookURL(ctx, imageScan.Namespace, spec.Webhooks.URL, spec.Webhooks.SecretRef); err != nil {
+			warnings = append(warnings, fmt.Sprintf("webhook: %v, notifications won't be sent", err))
+		}
+	}
+
+	for _, ref := range spec.ImagePullSecrets {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: imageScan.Namespace, Name: ref.Name}, secret); err != nil {
+			if errors.IsNotFound(err) {
+				warnings = append(warnings, fmt.Sprintf("imagePullSecret %s not found in namespace %s, the scan pod won't start", ref.Name, imageScan.Namespace))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("imagePullSecret %s: %v", ref.Name, err))
+			}
+			continue
+		}
+		if _, ok := secret.Data[corev1.DockerConfigJsonKey]; !ok {
+			warnings = append(warnings, fmt.Sprintf("imagePullSecret %s has no %s key, registry credentials won't be mounted", ref.Name, corev1.DockerConfigJsonKey))
+		}
+	}
+
+	if apiTLS := r.apiTLS(imageScan); apiTLS != nil {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: imageScan.Namespace, Name: apiTLS.SecretName}, secret); err != nil {
+			if errors.IsNotFound(err) {
+				warnings = append(warnings, fmt.Sprintf("apiTLS secret %s not found in namespace %s, the scan pod won't start", apiTLS.SecretName, imageScan.Namespace))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("apiTLS secret %s: %v", apiTLS.SecretName, err))
+			}
+		} else {
+			for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey, "ca.crt"} {
+				if _, ok := secret.Data[key]; !ok {
+					warnings = append(warnings, fmt.Sprintf("apiTLS secret %s has no %s key, the scanner can't reach the backend", apiTLS.SecretName, key))
+				}
+			}
+		}
+	}
+
+	if spec.NetworkPolicy != nil && spec.NetworkPolicy.Enabled {
+		if _, err := r.buildNetworkPolicy(imageScan); err != nil {
+			warnings = append(warnings, fmt.Sprintf("networkPolicy: %v", err))
+		}
+		if len(spec.NetworkPolicy.RegistryCIDRs) == 0 {
+			warnings = append(warnings, "networkPolicy has no registryCIDRs, Syft can't pull the image")
+		}
+		if len(spec.NetworkPolicy.GrypeDBCIDRs) == 0 {
+			warnings = append(warnings, "networkPolicy has no grypeDBCIDRs, the Grype DB can't be updated")
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+func newPreviewServer(t *testing.T, schedulingMode string) *PreviewServer {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := invulnerablev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	imageScan := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx"},
+		Spec: invulnerablev1alpha1.ImageScanSpec{
+			Image:    "nginx:1.25",
+			Schedule: &invulnerablev1alpha1.ScheduleConfig{Enabled: true, Cron: "0 2 * * *"},
+			Webhooks: &invulnerablev1alpha1.WebhooksConfig{
+				SecretRef:      &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "slack"}},
+				ScanCompletion: &invulnerablev1alpha1.ScanCompletionWebhookConfig{Enabled: true},
+			},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}, {Name: "missing"}},
+		},
+	}
+	webhook := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "slack"},
+		Data:       map[string][]byte{"url": []byte("https://hooks.slack.com/services/secret")},
+	}
+	// Not a dockerconfigjson Secret
+	registry := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "registry"},
+		Data:       map[string][]byte{"token": []byte("abc")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(imageScan, webhook, registry).Build()
+	return &PreviewServer{Reconciler: &ImageScanReconciler{Client: c, Scheme: scheme, SchedulingMode: schedulingMode}}
+}
+
+func TestPreviewServer_ExistingImageScan(t *testing.T) {
+	s := newPreviewServer(t, SchedulingModeCronJob)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/preview/prod/nginx", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	body := rec.Body.String()
+	if strings.Contains(body, "hooks.slack.com") {
+		t.Errorf("webhook URL from the Secret is not redacted:\n%s", body)
+	}
+	for _, want := range []string{
+		"# Warning: imagePullSecret registry has no .dockerconfigjson key",
+		"# Warning: imagePullSecret missing not found in namespace prod",
+		"<redacted: secret slack>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("preview is missing %q:\n%s", want, body)
+		}
+	}
+
+	cronJob := &batchv1.CronJob{}
+	if err := yaml.Unmarshal(rec.Body.Bytes(), cronJob); err != nil {
+		t.Fatal(err)
+	}
+	if cronJob.Kind != "CronJob" || cronJob.Name != "nginx-scanner" || cronJob.Spec.Schedule != "0 2 * * *" {
+		t.Errorf("CronJob = %s %s %q", cronJob.Kind, cronJob.Name, cronJob.Spec.Schedule)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/preview/prod/redis", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d for a missing ImageScan, want 404", rec.Code)
+	}
+}
+
+func TestPreviewServer_Manifest(t *testing.T) {
+	s := newPreviewServer(t, SchedulingModeNative)
+
+	manifest := `apiVersion: invulnerable.io/v1alpha1
+kind: ImageScan
+metadata:
+  name: redis
+  namespace: prod
+spec:
+  image: redis:7.2
+  schedule:
+    enabled: true
+    cron: "0 25 * * *"
+`
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/preview", strings.NewReader(manifest)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `# Warning: invalid schedule "0 25 * * *"`) {
+		t.Errorf("invalid schedule not reported:\n%s", rec.Body.String())
+	}
+
+	job := &batchv1.Job{}
+	if err := yaml.Unmarshal(rec.Body.Bytes(), job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Kind != "Job" || job.Labels["invulnerable.io/trigger"] != scheduleTrigger {
+		t.Errorf("Job = %s %v, want the Job of a native scheduled run", job.Kind, job.Labels)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/preview", strings.NewReader("kind: ImageScan\nspec:\n  imag: redis\n")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d for an invalid manifest, want 400", rec.Code)
+	}
+}
+
+func TestPreview_RegistryPollingOnly(t *testing.T) {
+	s := newPreviewServer(t, SchedulingModeCronJob)
+	imageScan := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "envoy"},
+		Spec: invulnerablev1alpha1.ImageScanSpec{
+			Image:           "envoy:1.29",
+			RegistryPolling: &invulnerablev1alpha1.RegistryPollingConfig{Enabled: true},
+		},
+	}
+
+	rendered, warnings, err := s.Reconciler.Preview(context.Background(), imageScan)
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	job, ok := rendered.(*batchv1.Job)
+	if !ok || job.GenerateName != "envoy-registry-" {
+		t.Errorf("rendered = %T %s, want the Job of a registry update", rendered, rendered.GetGenerateName())
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
+
+func TestPreview_APITLS(t *testing.T) {
+	s := newPreviewServer(t, SchedulingModeCronJob)
+	imageScan := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "envoy"},
+		Spec: invulnerablev1alpha1.ImageScanSpec{
+			Image:    "envoy:1.29",
+			Schedule: &invulnerablev1alpha1.ScheduleConfig{Enabled: true, Cron: "0 2 * * *"},
+			APITLS:   &invulnerablev1alpha1.APITLSConfig{SecretName: "scanner-tls"},
+		},
+	}
+
+	rendered, warnings, err := s.Reconciler.Preview(context.Background(), imageScan)
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "apiTLS secret scanner-tls not found") {
+		t.Errorf("warnings = %v", warnings)
+	}
+
+	pod := rendered.(*batchv1.CronJob).Spec.JobTemplate.Spec.Template.Spec
+	env := map[string]string{}
+	for _, e := range pod.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	if env["API_ENDPOINT"] != "https://invulnerable-backend.prod.svc.cluster.local:8080" ||
+		env["API_CLIENT_CERT"] != apiTLSMountPath+"/tls.crt" || env["API_CLIENT_KEY"] != apiTLSMountPath+"/tls.key" ||
+		env["API_CA_CERT"] != apiTLSMountPath+"/ca.crt" {
+		t.Errorf("env = %v", env)
+	}
+	mounted := false
+	for _, v := range pod.Volumes {
+		mounted = mounted || (v.Secret != nil && v.Secret.SecretName == "scanner-tls")
+	}
+	if !mounted {
+		t.Errorf("secret scanner-tls not mounted: %+v", pod.Volumes)
+	}
+}
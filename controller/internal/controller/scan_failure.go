@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+const (
+	// scannerContainer is the name of the scanner container of scan pods
+	scannerContainer = "scanner"
+
+	// failureLogLines is how many of the scanner's last log lines are read for status.lastFailure
+	failureLogLines = 20
+
+	// maxFailureMessage bounds status.lastFailure.message, the end of the logs is kept
+	maxFailureMessage = 1024
+
+	// scannerTempFailExitCode is the scanner's exit code (EX_TEMPFAIL) when the API is in
+	// maintenance, the restarted container resubmits the queued results
+	scannerTempFailExitCode = 75
+)
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=list;watch
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+
+// reconcileLastFailure records the latest failure of an ImageScan's scans in status.lastFailure,
+// with the exit reason and last log lines of the scanner container, and emits an event naming
+// the pod. A failure is either a crash of the scanner container, recorded while the Job restarts
+// it since a failed Job deletes its pods, or a failed Job. Each failure is recorded once
+func (r *ImageScanReconciler) reconcileLastFailure(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) error {
+	jobs, err := r.listScanJobs(ctx, imageScan)
+	if err != nil {
+		return err
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(imageScan.Namespace), client.MatchingLabels{
+		"app.kubernetes.io/name":     "invulnerable-scanner",
+		"app.kubernetes.io/instance": imageScan.Name,
+	}); err != nil {
+		return fmt.Errorf("failed to list scanner pods: %w", err)
+	}
+
+	var failed *batchv1.Job
+	var failedAt time.Time
+	for i := range jobs {
+		if at, ok := jobFailureTime(&jobs[i]); ok && at.After(failedAt) {
+			failed, failedAt = &jobs[i], at
+		}
+	}
+	var crashed *corev1.Pod
+	var crash *corev1.ContainerStateTerminated
+	for i := range pods.Items {
+		if terminated, _ := scannerCrash(&pods.Items[i]); terminated != nil && (crash == nil || terminated.FinishedAt.After(crash.FinishedAt.Time)) {
+			crashed, crash = &pods.Items[i], terminated
+		}
+	}
+
+	last := imageScan.Status.LastFailure
+	recorded := func(at time.Time) bool {
+		return last != nil && last.Time != nil && !at.After(last.Time.Time)
+	}
+	var failure *invulnerablev1alpha1.ScanFailure
+	switch {
+	case failed != nil && (crash == nil || failedAt.After(crash.FinishedAt.Time)):
+		// A Job whose crash was recorded keeps the crash's logs
+		if recorded(failedAt) || (last != nil && last.JobName == failed.Name) {
+			return nil
+		}
+		failure = r.describeFailure(ctx, failed, failedAt)
+	case crash != nil:
+		if recorded(crash.FinishedAt.Time) {
+			return nil
+		}
+		failure = r.describeCrash(ctx, crashed)
+	default:
+		return nil
+	}
+	imageScan.Status.LastFailure = failure
+
+	summary := fmt.Sprintf("Scan job %s failed", failure.JobName)
+	if failure.Reason != "" {
+		summary += ": " + failure.Reason
+	}
+	if failure.ExitCode != nil {
+		summary += fmt.Sprintf(" (exit code %d)", *failure.ExitCode)
+	}
+	if failure.PodName != "" {
+		summary += fmt.Sprintf(", see kubectl logs -n %s pod/%s", imageScan.Namespace, failure.PodName)
+	}
+	r.Recorder.Event(imageScan, corev1.EventTypeWarning, "ScanFailed", summary)
+	log.FromContext(ctx).Info("Recorded scan failure", "job", failure.JobName, "pod", failure.PodName, "reason", failure.Reason)
+	return nil
+}
+
+// listScanJobs lists the scan Jobs of an ImageScan, whatever triggered them
+func (r *ImageScanReconciler) listScanJobs(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan) ([]batchv1.Job, error) {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(imageScan.Namespace), client.MatchingLabels{
+		"app.kubernetes.io/name":     "invulnerable-scanner",
+		"app.kubernetes.io/instance": imageScan.Name,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list scan jobs: %w", err)
+	}
+	return jobs.Items, nil
+}
+
+// describeFailure reads why a failed Job's newest scanner pod failed. The Job's own failure
+// reason (e.g. DeadlineExceeded) is kept when no pod is left or none of them terminated
+func (r *ImageScanReconciler) describeFailure(ctx context.Context, job *batchv1.Job, failedAt time.Time) *invulnerablev1alpha1.ScanFailure {
+	logger := log.FromContext(ctx)
+	failure := &invulnerablev1alpha1.ScanFailure{
+		JobName: job.Name,
+		Time:    &metav1.Time{Time: failedAt},
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			failure.Reason, failure.Message = c.Reason, c.Message
+		}
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.APIReader.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{batchv1.JobNameLabel: job.Name}); err != nil {
+		logger.Error(err, "Failed to list pods of failed scan job", "job", job.Name)
+		return failure
+	}
+	var pod *corev1.Pod
+	for i := range pods.Items {
+		if pod == nil || pods.Items[i].CreationTimestamp.After(pod.CreationTimestamp.Time) {
+			pod = &pods.Items[i]
+		}
+	}
+	if pod == nil {
+		return failure
+	}
+	r.describePod(ctx, failure, pod)
+	return failure
+}
+
+// describeCrash reads why the scanner container of a pod last crashed
+func (r *ImageScanReconciler) describeCrash(ctx context.Context, pod *corev1.Pod) *invulnerablev1alpha1.ScanFailure {
+	failure := &invulnerablev1alpha1.ScanFailure{JobName: pod.Labels[batchv1.JobNameLabel]}
+	if terminated, _ := scannerCrash(pod); terminated != nil {
+		failure.Time = terminated.FinishedAt.DeepCopy()
+	}
+	r.describePod(ctx, failure, pod)
+	return failure
+}
+
+// describePod fills a failure with the reason, exit code and last log lines of a pod's
+// scanner container
+func (r *ImageScanReconciler) describePod(ctx context.Context, failure *invulnerablev1alpha1.ScanFailure, pod *corev1.Pod) {
+	failure.PodName = pod.Name
+	if pod.Status.Reason != "" {
+		// e.g. Evicted
+		failure.Reason, failure.Message = pod.Status.Reason, pod.Status.Message
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != scannerContainer {
+			continue
+		}
+		terminated, previous := status.State.Terminated, false
+		if terminated == nil && status.LastTerminationState.Terminated != nil {
+			terminated, previous = status.LastTerminationState.Terminated, true
+		}
+		switch {
+		case terminated != nil:
+			failure.Reason, failure.Message = terminated.Reason, terminated.Message
+			failure.ExitCode = ptr(terminated.ExitCode)
+			logs, err := r.tailLogs(ctx, pod, previous)
+			if err != nil {
+				log.FromContext(ctx).Error(err, "Failed to read scanner logs", "pod", pod.Name)
+			} else if logs != "" {
+				failure.Message = logs
+			}
+		case status.State.Waiting != nil:
+			// e.g. ImagePullBackOff until the Job's deadline
+			failure.Reason, failure.Message = status.State.Waiting.Reason, status.State.Waiting.Message
+		}
+	}
+
+	failure.Message = truncateFailureMessage(failure.Message)
+}
+
+// scannerCrash returns the last termination of a pod's scanner container if it failed, and
+// whether the container was restarted since. The scanner exits with EX_TEMPFAIL to be restarted
+// while the API is in maintenance, which isn't a failure
+func scannerCrash(pod *corev1.Pod) (*corev1.ContainerStateTerminated, bool) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != scannerContainer {
+			continue
+		}
+		terminated, previous := status.State.Terminated, false
+		if terminated == nil {
+			terminated, previous = status.LastTerminationState.Terminated, true
+		}
+		if terminated != nil && terminated.ExitCode != 0 && terminated.ExitCode != scannerTempFailExitCode {
+			return terminated, previous
+		}
+	}
+	return nil, false
+}
+
+// tailLogs returns the last log lines of a pod's scanner container, of its previous run
+// when it restarted
+func (r *ImageScanReconciler) tailLogs(ctx context.Context, pod *corev1.Pod, previous bool) (string, error) {
+	raw, err := r.Clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  scannerContainer,
+		Previous:   previous,
+		TailLines:  ptr(int64(failureLogLines)),
+		LimitBytes: ptr(int64(16 * maxFailureMessage)),
+	}).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// truncateFailureMessage keeps the end of a message, cut at a line when possible,
+// since the error is usually in the last lines of the logs
+func truncateFailureMessage(message string) string {
+	if len(message) <= maxFailureMessage {
+		return message
+	}
+	message = message[len(message)-maxFailureMessage:]
+	if i := strings.IndexByte(message, '\n'); i >= 0 && i < len(message)-1 {
+		message = message[i+1:]
+	}
+	return "..." + message
+}
+
+// jobFailureTime returns when a Job failed, and false if it hasn't
+func jobFailureTime(job *batchv1.Job) (time.Time, bool) {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return c.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+func failedScanJob(name string, failedAt time.Time) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "prod",
+			Name:      name,
+			Labels:    map[string]string{"app.kubernetes.io/name": "invulnerable-scanner", "app.kubernetes.io/instance": "nginx"},
+		},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+			Type:               batchv1.JobFailed,
+			Status:             corev1.ConditionTrue,
+			Reason:             "BackoffLimitExceeded",
+			LastTransitionTime: metav1.NewTime(failedAt),
+		}}},
+	}
+}
+
+func TestReconcileLastFailure(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := invulnerablev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	failedAt := time.Date(2024, 6, 10, 2, 5, 0, 0, time.UTC)
+	older := failedScanJob("nginx-scanner-28634460", failedAt.Add(-24*time.Hour))
+	latest := failedScanJob("nginx-scanner-28635900", failedAt)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "prod",
+			Name:      "nginx-scanner-28635900-x7k2p",
+			Labels:    map[string]string{batchv1.JobNameLabel: latest.Name},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  scannerContainer,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+			}},
+		},
+	}
+	// A pod of another Job
+	other := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "redis-scanner-1-abcde", Labels: map[string]string{batchv1.JobNameLabel: "redis-scanner-1"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(older, latest, pod, other).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &ImageScanReconciler{
+		Client:    c,
+		Scheme:    scheme,
+		APIReader: c,
+		// The fake clientset's logs are "fake logs"
+		Clientset: kubefake.NewSimpleClientset(pod),
+		Recorder:  recorder,
+	}
+
+	imageScan := &invulnerablev1alpha1.ImageScan{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx"}}
+	if err := r.reconcileLastFailure(context.Background(), imageScan); err != nil {
+		t.Fatalf("reconcileLastFailure: %v", err)
+	}
+
+	failure := imageScan.Status.LastFailure
+	if failure == nil {
+		t.Fatal("no failure recorded")
+	}
+	if failure.JobName != latest.Name || failure.PodName != pod.Name || failure.Reason != "OOMKilled" ||
+		failure.ExitCode == nil || *failure.ExitCode != 137 || failure.Message != "fake logs" || !failure.Time.Time.Equal(failedAt) {
+		t.Errorf("failure = %+v", failure)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "Warning ScanFailed") || !strings.Contains(event, "OOMKilled (exit code 137)") ||
+			!strings.Contains(event, "kubectl logs -n prod pod/"+pod.Name) {
+			t.Errorf("event = %q", event)
+		}
+	default:
+		t.Error("no event emitted")
+	}
+
+	// The same failure is recorded once
+	if err := r.reconcileLastFailure(context.Background(), imageScan); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("failure recorded again: %q", <-recorder.Events)
+	}
+}
+
+func TestReconcileLastFailure_Crash(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := invulnerablev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	crashedAt := time.Date(2024, 6, 10, 2, 3, 0, 0, time.UTC)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "prod",
+			Name:      "nginx-scanner-28635900-x7k2p",
+			Labels: map[string]string{
+				"app.kubernetes.io/name":     "invulnerable-scanner",
+				"app.kubernetes.io/instance": "nginx",
+				batchv1.JobNameLabel:         "nginx-scanner-28635900",
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  scannerContainer,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 137, Reason: "OOMKilled", FinishedAt: metav1.NewTime(crashedAt),
+				}},
+			}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &ImageScanReconciler{Client: c, Scheme: scheme, APIReader: c, Clientset: kubefake.NewSimpleClientset(pod), Recorder: recorder}
+	imageScan := &invulnerablev1alpha1.ImageScan{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx"}}
+	ctx := context.Background()
+
+	// The crash is recorded while the Job restarts the container
+	if err := r.reconcileLastFailure(ctx, imageScan); err != nil {
+		t.Fatalf("reconcileLastFailure: %v", err)
+	}
+	failure := imageScan.Status.LastFailure
+	if failure == nil || failure.JobName != "nginx-scanner-28635900" || failure.PodName != pod.Name ||
+		failure.Reason != "OOMKilled" || failure.Message != "fake logs" || !failure.Time.Time.Equal(crashedAt) {
+		t.Fatalf("failure = %+v", failure)
+	}
+	<-recorder.Events
+
+	// The failed Job deletes the pod, its failure doesn't replace the crash
+	if err := c.Delete(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create(ctx, failedScanJob("nginx-scanner-28635900", crashedAt.Add(2*time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reconcileLastFailure(ctx, imageScan); err != nil {
+		t.Fatal(err)
+	}
+	if imageScan.Status.LastFailure.PodName != pod.Name || len(recorder.Events) != 0 {
+		t.Errorf("failure replaced by the Job's: %+v", imageScan.Status.LastFailure)
+	}
+}
+
+func TestScannerCrash(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    corev1.ContainerState
+		last     corev1.ContainerState
+		want     bool
+		previous bool
+	}{
+		{name: "running", state: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+		{name: "succeeded", state: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}},
+		{name: "maintenance", state: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: scannerTempFailExitCode}}},
+		{name: "failed", state: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}}, want: true},
+		{
+			name:     "restarted",
+			state:    corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			last:     corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2}},
+			want:     true,
+			previous: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name: scannerContainer, State: tt.state, LastTerminationState: tt.last,
+			}}}}
+			crash, previous := scannerCrash(pod)
+			if (crash != nil) != tt.want || previous != tt.previous {
+				t.Errorf("scannerCrash() = %v, %v, want crash %v, previous %v", crash, previous, tt.want, tt.previous)
+			}
+		})
+	}
+}
+
+func TestDescribeFailure_NoPod(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	r := &ImageScanReconciler{Client: c, Scheme: scheme, APIReader: c}
+
+	job := failedScanJob("nginx-scanner-28635900", time.Now())
+	job.Status.Conditions[0].Reason, job.Status.Conditions[0].Message = "DeadlineExceeded", "Job was active longer than specified deadline"
+
+	failure := r.describeFailure(context.Background(), job, time.Now())
+	if failure.Reason != "DeadlineExceeded" || failure.PodName != "" || failure.ExitCode != nil {
+		t.Errorf("failure = %+v", failure)
+	}
+}
+
+func TestTruncateFailureMessage(t *testing.T) {
+	if got := truncateFailureMessage("short"); got != "short" {
+		t.Errorf("short message changed to %q", got)
+	}
+
+	lines := make([]string, 200)
+	for i := range lines {
+		lines[i] = "scanning layer"
+	}
+	lines[len(lines)-1] = "error: failed to pull image: unauthorized"
+	got := truncateFailureMessage(strings.Join(lines, "\n"))
+	if len(got) > maxFailureMessage+3 || !strings.HasPrefix(got, "...scanning layer\n") || !strings.HasSuffix(got, "unauthorized") {
+		t.Errorf("truncated message = %q", got)
+	}
+}
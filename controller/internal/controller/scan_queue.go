@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+// queueEntryTTL drops the entries of ImageScans that stopped waiting without saying so,
+// e.g. deleted ones. Waiting scans refresh their entry every concurrencyRetryInterval
+const queueEntryTTL = 3 * concurrencyRetryInterval
+
+// scanQueue orders the scheduled scans waiting for a free slot under MaxConcurrentScans.
+// The zero value is an empty queue
+type scanQueue struct {
+	mu      sync.Mutex
+	waiting map[types.NamespacedName]queuedScan
+}
+
+type queuedScan struct {
+	rank     int
+	since    time.Time
+	lastSeen time.Time
+	// turn is the round of the weighted round-robin across namespaces the scan is served in
+	turn int
+}
+
+// wait records that the ImageScan has a scan due and returns how many waiting scans go before it.
+// Namespaces take turns: the n-th scan of a namespace, counting its active scan Jobs and its
+// waiting scans of higher priority or waiting longer, is served in turn n / weight. A namespace
+// with many ImageScans doesn't get every free slot, and one of weight 2 gets two per turn
+func (q *scanQueue) wait(key types.NamespacedName, priority invulnerablev1alpha1.ScanPriority, active, weights map[string]int, now time.Time) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.waiting == nil {
+		q.waiting = map[types.NamespacedName]queuedScan{}
+	}
+	entry, ok := q.waiting[key]
+	if !ok {
+		entry.since = now
+	}
+	entry.rank = priorityRank(priority)
+	entry.lastSeen = now
+	q.waiting[key] = entry
+
+	keys := make([]types.NamespacedName, 0, len(q.waiting))
+	for other, e := range q.waiting {
+		if now.Sub(e.lastSeen) > queueEntryTTL {
+			delete(q.waiting, other)
+			continue
+		}
+		keys = append(keys, other)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return q.waiting[keys[i]].arrivedBefore(q.waiting[keys[j]], keys[i], keys[j])
+	})
+	served := map[string]int{}
+	for _, k := range keys {
+		e := q.waiting[k]
+		e.turn = (active[k.Namespace] + served[k.Namespace]) / namespaceWeight(weights, k.Namespace)
+		served[k.Namespace]++
+		q.waiting[k] = e
+	}
+
+	entry = q.waiting[key]
+	ahead := 0
+	for _, other := range keys {
+		if other != key && q.waiting[other].before(entry, other, key) {
+			ahead++
+		}
+	}
+	return ahead
+}
+
+// done removes the ImageScan from the queue
+func (q *scanQueue) done(key types.NamespacedName) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.waiting, key)
+}
+
+// before reports whether e is served before other: by priority, then turn, then how long they waited
+func (e queuedScan) before(other queuedScan, key, otherKey types.NamespacedName) bool {
+	if e.rank != other.rank {
+		return e.rank < other.rank
+	}
+	if e.turn != other.turn {
+		return e.turn < other.turn
+	}
+	return e.arrivedBefore(other, key, otherKey)
+}
+
+// arrivedBefore orders the scans by priority, then by how long they waited. Names break ties so
+// the order is total
+func (e queuedScan) arrivedBefore(other queuedScan, key, otherKey types.NamespacedName) bool {
+	if e.rank != other.rank {
+		return e.rank < other.rank
+	}
+	if !e.since.Equal(other.since) {
+		return e.since.Before(other.since)
+	}
+	return key.String() < otherKey.String()
+}
+
+// namespaceWeight returns the weight of a namespace in the round-robin, 1 if it has none
+func namespaceWeight(weights map[string]int, namespace string) int {
+	if weight := weights[namespace]; weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// ParseNamespaceWeights parses the weights of namespaces in the round-robin of waiting scans,
+// e.g. "team-a=3,team-b=2"
+func ParseNamespaceWeights(value string) (map[string]int, error) {
+	weights := map[string]int{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		namespace, weight, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(namespace) == "" {
+			return nil, fmt.Errorf("invalid namespace weight %q, expected namespace=weight", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid weight of namespace %s %q, expected a positive integer", namespace, weight)
+		}
+		weights[strings.TrimSpace(namespace)] = n
+	}
+	return weights, nil
+}
+
+// scanPriority returns the priority of an ImageScan, normal if unset
+func scanPriority(imageScan *invulnerablev1alpha1.ImageScan) invulnerablev1alpha1.ScanPriority {
+	if imageScan.Spec.Priority == "" {
+		return invulnerablev1alpha1.ScanPriorityNormal
+	}
+	return imageScan.Spec.Priority
+}
+
+func priorityRank(priority invulnerablev1alpha1.ScanPriority) int {
+	switch priority {
+	case invulnerablev1alpha1.ScanPriorityHigh:
+		return 0
+	case invulnerablev1alpha1.ScanPriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
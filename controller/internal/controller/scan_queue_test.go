@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+func TestScanQueue_Order(t *testing.T) {
+	var q scanQueue
+	now := time.Now()
+	low := types.NamespacedName{Namespace: "default", Name: "low"}
+	normal := types.NamespacedName{Namespace: "default", Name: "normal"}
+	high := types.NamespacedName{Namespace: "default", Name: "high"}
+
+	if ahead := q.wait(low, invulnerablev1alpha1.ScanPriorityLow, nil, nil, now); ahead != 0 {
+		t.Errorf("low: ahead = %d, want 0", ahead)
+	}
+	if ahead := q.wait(normal, "", nil, nil, now.Add(time.Second)); ahead != 0 {
+		t.Errorf("normal: ahead = %d, want 0", ahead)
+	}
+	if ahead := q.wait(high, invulnerablev1alpha1.ScanPriorityHigh, nil, nil, now.Add(2*time.Second)); ahead != 0 {
+		t.Errorf("high: ahead = %d, want 0", ahead)
+	}
+
+	// Refreshing an entry keeps its place among scans of the same priority
+	if ahead := q.wait(low, invulnerablev1alpha1.ScanPriorityLow, nil, nil, now.Add(3*time.Second)); ahead != 2 {
+		t.Errorf("low: ahead = %d, want 2", ahead)
+	}
+
+	q.done(high)
+	if ahead := q.wait(low, invulnerablev1alpha1.ScanPriorityLow, nil, nil, now.Add(4*time.Second)); ahead != 1 {
+		t.Errorf("low after high started: ahead = %d, want 1", ahead)
+	}
+
+	// Entries that are not refreshed expire
+	if ahead := q.wait(low, invulnerablev1alpha1.ScanPriorityLow, nil, nil, now.Add(queueEntryTTL+2*time.Second)); ahead != 0 {
+		t.Errorf("low after expiry: ahead = %d, want 0", ahead)
+	}
+}
+
+func TestScanQueue_FairAcrossNamespaces(t *testing.T) {
+	var q scanQueue
+	now := time.Now()
+	a1 := types.NamespacedName{Namespace: "team-a", Name: "api"}
+	a2 := types.NamespacedName{Namespace: "team-a", Name: "web"}
+	a3 := types.NamespacedName{Namespace: "team-a", Name: "worker"}
+	b1 := types.NamespacedName{Namespace: "team-b", Name: "api"}
+	for i, key := range []types.NamespacedName{a1, a2, a3} {
+		q.wait(key, "", nil, nil, now.Add(time.Duration(i)*time.Second))
+	}
+
+	// team-b's scan waited the least, it still takes the second turn
+	if ahead := q.wait(b1, "", nil, nil, now.Add(3*time.Second)); ahead != 1 {
+		t.Errorf("b1: ahead = %d, want 1", ahead)
+	}
+	if ahead := q.wait(a3, "", nil, nil, now.Add(4*time.Second)); ahead != 3 {
+		t.Errorf("a3: ahead = %d, want 3", ahead)
+	}
+
+	// The scans a namespace already runs count towards its share
+	if ahead := q.wait(b1, "", map[string]int{"team-a": 2}, nil, now.Add(5*time.Second)); ahead != 0 {
+		t.Errorf("b1 with team-a running: ahead = %d, want 0", ahead)
+	}
+
+	// A weight of 3 gives team-a three slots per turn
+	if ahead := q.wait(b1, "", nil, map[string]int{"team-a": 3}, now.Add(6*time.Second)); ahead != 3 {
+		t.Errorf("b1 with team-a weighted: ahead = %d, want 3", ahead)
+	}
+
+	// Priority goes before the turns
+	high := types.NamespacedName{Namespace: "team-a", Name: "payments"}
+	if ahead := q.wait(high, invulnerablev1alpha1.ScanPriorityHigh, map[string]int{"team-a": 5}, nil, now.Add(7*time.Second)); ahead != 0 {
+		t.Errorf("high: ahead = %d, want 0", ahead)
+	}
+}
+
+func TestParseNamespaceWeights(t *testing.T) {
+	weights, err := ParseNamespaceWeights(" team-a=3, team-b = 2,")
+	if err != nil {
+		t.Fatalf("ParseNamespaceWeights: %v", err)
+	}
+	if len(weights) != 2 || weights["team-a"] != 3 || weights["team-b"] != 2 {
+		t.Errorf("weights = %v", weights)
+	}
+	if weights, err := ParseNamespaceWeights(""); err != nil || len(weights) != 0 {
+		t.Errorf("empty: weights = %v, err = %v", weights, err)
+	}
+	for _, value := range []string{"team-a", "=2", "team-a=0", "team-a=x"} {
+		if _, err := ParseNamespaceWeights(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+const (
+	// retryTrigger is the invulnerable.io/trigger label of Jobs retrying a failed scan
+	retryTrigger = "Retry"
+
+	// retryOfAnnotation is set on retry Jobs to the name of the failed Job first retried
+	retryOfAnnotation = "invulnerable.io/retry-of"
+
+	// retryAttemptAnnotation is set on retry Jobs to their attempt number, from 1
+	retryAttemptAnnotation = "invulnerable.io/retry-attempt"
+
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 5 * time.Minute
+)
+
+// reconcileRetry creates a retry Job for the latest failed scan Job of an ImageScan once its
+// backoff has passed, and returns when to check again. No retry is created while a scan Job is
+// running, and a successful scan resets the retry count
+func (r *ImageScanReconciler) reconcileRetry(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan, now time.Time) (time.Duration, error) {
+	policy := imageScan.Spec.RetryPolicy
+	if policy == nil {
+		imageScan.Status.Retries = 0
+		imageScan.Status.NextRetryTime = nil
+		return 0, nil
+	}
+
+	jobs, err := r.listScanJobs(ctx, imageScan)
+	if err != nil {
+		return 0, err
+	}
+	var latest *batchv1.Job
+	var finishedAt time.Time
+	for i := range jobs {
+		if jobFinishedCondition(&jobs[i]) == "" {
+			// The running scan's failure is watched
+			imageScan.Status.NextRetryTime = nil
+			return 0, nil
+		}
+		if at := jobFinishTime(&jobs[i]); latest == nil || at.After(finishedAt) {
+			latest, finishedAt = &jobs[i], at
+		}
+	}
+	if latest == nil || jobFinishedCondition(latest) == batchv1.JobComplete {
+		imageScan.Status.Retries = 0
+		imageScan.Status.NextRetryTime = nil
+		return 0, nil
+	}
+
+	attempt, _ := strconv.Atoi(latest.Annotations[retryAttemptAnnotation])
+	imageScan.Status.Retries = int32(attempt)
+	maxRetries := defaultMaxRetries
+	if policy.MaxRetries > 0 {
+		maxRetries = int(policy.MaxRetries)
+	}
+	if attempt >= maxRetries {
+		imageScan.Status.NextRetryTime = nil
+		return 0, nil
+	}
+
+	backoff := defaultRetryBackoff
+	if policy.Backoff != nil && policy.Backoff.Duration > 0 {
+		backoff = policy.Backoff.Duration
+	}
+	retryAt := finishedAt.Add(backoff << attempt)
+	if now.Before(retryAt) {
+		imageScan.Status.NextRetryTime = &metav1.Time{Time: retryAt}
+		return retryAt.Sub(now), nil
+	}
+
+	retryOf := latest.Name
+	if origin := latest.Annotations[retryOfAnnotation]; origin != "" {
+		retryOf = origin
+	}
+	job, err := r.buildRetryJob(imageScan, retryOf, attempt+1)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+		return 0, fmt.Errorf("failed to create retry job: %w", err)
+	}
+
+	imageScan.Status.Retries = int32(attempt + 1)
+	imageScan.Status.NextRetryTime = nil
+	r.Recorder.Eventf(imageScan, corev1.EventTypeNormal, "ScanRetried", "Retrying failed scan job %s (retry %d of %d)", retryOf, attempt+1, maxRetries)
+	log.FromContext(ctx).Info("Retrying failed scan", "job", job.Name, "retryOf", retryOf, "attempt", attempt+1)
+	return 0, nil
+}
+
+// buildRetryJob builds the Job of a retry of a failed scan. The name is derived from the failed
+// Job so that each attempt is created once
+func (r *ImageScanReconciler) buildRetryJob(imageScan *invulnerablev1alpha1.ImageScan, retryOf string, attempt int) (*batchv1.Job, error) {
+	job, err := r.buildImmediateJob(imageScan, retryTrigger)
+	if err != nil {
+		return nil, err
+	}
+	job.GenerateName = ""
+	job.Name = fmt.Sprintf("%s-retry-%d", retryOf, attempt)
+	job.Annotations[retryOfAnnotation] = retryOf
+	job.Annotations[retryAttemptAnnotation] = strconv.Itoa(attempt)
+	return job, nil
+}
+
+// jobFinishTime returns when a finished Job completed or failed
+func jobFinishTime(job *batchv1.Job) time.Time {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return c.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
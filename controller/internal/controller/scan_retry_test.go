@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+func TestReconcileRetry(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := invulnerablev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	failedAt := time.Date(2024, 6, 10, 2, 5, 0, 0, time.UTC)
+	failed := failedScanJob("nginx-scanner-28635900", failedAt)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(failed).Build()
+	r := &ImageScanReconciler{Client: c, Scheme: scheme, Recorder: record.NewFakeRecorder(10)}
+	imageScan := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx"},
+		Spec: invulnerablev1alpha1.ImageScanSpec{
+			Image:       "nginx:1.25",
+			RetryPolicy: &invulnerablev1alpha1.RetryPolicy{MaxRetries: 2, Backoff: &metav1.Duration{Duration: 10 * time.Minute}},
+		},
+	}
+	ctx := context.Background()
+
+	// Before the backoff
+	requeue, err := r.reconcileRetry(ctx, imageScan, failedAt.Add(4*time.Minute))
+	if err != nil {
+		t.Fatalf("reconcileRetry: %v", err)
+	}
+	if requeue != 6*time.Minute || imageScan.Status.NextRetryTime == nil || imageScan.Status.Retries != 0 {
+		t.Errorf("requeue = %v, status = %+v, want a retry in 6m", requeue, imageScan.Status)
+	}
+
+	// After the backoff
+	if _, err := r.reconcileRetry(ctx, imageScan, failedAt.Add(10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	retry := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "prod", Name: "nginx-scanner-28635900-retry-1"}, retry); err != nil {
+		t.Fatalf("retry job not created: %v", err)
+	}
+	if retry.Labels["invulnerable.io/trigger"] != retryTrigger || retry.Annotations[retryOfAnnotation] != failed.Name ||
+		imageScan.Status.Retries != 1 || imageScan.Status.NextRetryTime != nil {
+		t.Errorf("retry job = %+v, status = %+v", retry.ObjectMeta, imageScan.Status)
+	}
+
+	// While the retry runs
+	if requeue, err := r.reconcileRetry(ctx, imageScan, failedAt.Add(time.Hour)); err != nil || requeue != 0 {
+		t.Errorf("requeue = %v, err = %v while the retry runs", requeue, err)
+	}
+
+	// The backoff doubles for the second retry
+	retryFailedAt := failedAt.Add(15 * time.Minute)
+	retry.Status.Conditions = failedScanJob("", retryFailedAt).Status.Conditions
+	if err := c.Status().Update(ctx, retry); err != nil {
+		t.Fatal(err)
+	}
+	if requeue, _ := r.reconcileRetry(ctx, imageScan, retryFailedAt); requeue != 20*time.Minute {
+		t.Errorf("requeue = %v, want 20m", requeue)
+	}
+	if _, err := r.reconcileRetry(ctx, imageScan, retryFailedAt.Add(20*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	second := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "prod", Name: "nginx-scanner-28635900-retry-2"}, second); err != nil {
+		t.Fatalf("second retry job not created: %v", err)
+	}
+
+	// No retry past maxRetries
+	second.Status.Conditions = failedScanJob("", retryFailedAt.Add(25*time.Minute)).Status.Conditions
+	if err := c.Status().Update(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if requeue, err := r.reconcileRetry(ctx, imageScan, retryFailedAt.Add(5*time.Hour)); err != nil || requeue != 0 {
+		t.Errorf("requeue = %v, err = %v after the last retry", requeue, err)
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.List(ctx, jobs, client.InNamespace("prod")); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Items) != 3 || imageScan.Status.Retries != 2 {
+		t.Errorf("got %d jobs and %d retries, want 3 and 2", len(jobs.Items), imageScan.Status.Retries)
+	}
+
+	// A successful scan resets the count
+	second.Status.Conditions = []batchv1.JobCondition{{
+		Type: batchv1.JobComplete, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(retryFailedAt.Add(time.Hour)),
+	}}
+	if err := c.Status().Update(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.reconcileRetry(ctx, imageScan, retryFailedAt.Add(5*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if imageScan.Status.Retries != 0 {
+		t.Errorf("retries = %d after a successful scan", imageScan.Status.Retries)
+	}
+}
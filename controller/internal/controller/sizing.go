@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+// sizingRefreshInterval is how often status.sizing is fetched again from the backend
+const sizingRefreshInterval = time.Hour
+
+// sizingResponse is the recommendation of GET /api/v1/imagescans/:namespace/:name/sizing
+type sizingResponse struct {
+	Scans          int32  `json:"scans"`
+	ImageSizeBytes int64  `json:"image_size_bytes"`
+	WorkspaceSize  string `json:"workspace_size"`
+	MemoryRequest  string `json:"memory_request"`
+}
+
+// reconcileSizing records in status.sizing the workspace size and memory request the backend
+// recommends from the images of recent scans. An ImageScan none of whose scans reported its
+// image size keeps its sizing
+func (r *ImageScanReconciler) reconcileSizing(ctx context.Context, imageScan *invulnerablev1alpha1.ImageScan, now time.Time) error {
+	logger := log.FromContext(ctx)
+
+	last := imageScan.Status.Sizing
+	if last != nil && last.UpdatedAt != nil && now.Sub(last.UpdatedAt.Time) < sizingRefreshInterval {
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/api/v1/imagescans/%s/%s/sizing", r.backendEndpoint(imageScan), imageScan.Namespace, imageScan.Name)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create sizing request: %w", err)
+	}
+
+	// Use HTTP client if available, otherwise create default
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to get sizing from backend: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("backend returned non-2xx status for sizing: %d", resp.StatusCode)
+	}
+
+	var recommended sizingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&recommended); err != nil {
+		return fmt.Errorf("failed to decode sizing: %w", err)
+	}
+	for _, q := range []string{recommended.WorkspaceSize, recommended.MemoryRequest} {
+		if _, err := resource.ParseQuantity(q); err != nil {
+			return fmt.Errorf("backend recommended an invalid quantity %q: %w", q, err)
+		}
+	}
+
+	sizing := &invulnerablev1alpha1.SizingRecommendation{
+		WorkspaceSize:  recommended.WorkspaceSize,
+		MemoryRequest:  recommended.MemoryRequest,
+		ImageSizeBytes: recommended.ImageSizeBytes,
+		Scans:          recommended.Scans,
+		UpdatedAt:      &metav1.Time{Time: now},
+	}
+	imageScan.Status.Sizing = sizing
+
+	changed := last == nil || last.WorkspaceSize != sizing.WorkspaceSize || last.MemoryRequest != sizing.MemoryRequest
+	if changed && autoSizingEnabled(imageScan) {
+		r.Recorder.Eventf(imageScan, corev1.EventTypeNormal, "Resized",
+			"Scan Jobs sized to a %s workspace and a %s memory request from %d recent scans",
+			sizing.WorkspaceSize, sizing.MemoryRequest, sizing.Scans)
+	}
+	logger.V(1).Info("Fetched sizing recommendation",
+		"workspaceSize", sizing.WorkspaceSize,
+		"memoryRequest", sizing.MemoryRequest,
+		"scans", sizing.Scans)
+
+	return nil
+}
+
+// autoSizingEnabled reports whether status.sizing applies to the scan Jobs of an ImageScan
+func autoSizingEnabled(imageScan *invulnerablev1alpha1.ImageScan) bool {
+	return imageScan.Spec.AutoSizing != nil && imageScan.Spec.AutoSizing.Enabled && imageScan.Status.Sizing != nil
+}
+
+// applySizing sizes the workspace and memory request of a scan pod from status.sizing with auto-sizing
+// enabled. The memory limit is raised to the request when lower, so the pod stays valid
+func applySizing(imageScan *invulnerablev1alpha1.ImageScan, podSpec *corev1.PodSpec) {
+	if !autoSizingEnabled(imageScan) {
+		return
+	}
+	sizing := imageScan.Status.Sizing
+
+	if workspace, err := resource.ParseQuantity(sizing.WorkspaceSize); err == nil {
+		for i := range podSpec.Volumes {
+			if podSpec.Volumes[i].Name == "scan-workspace" && podSpec.Volumes[i].EmptyDir != nil {
+				podSpec.Volumes[i].EmptyDir.SizeLimit = &workspace
+			}
+		}
+	}
+
+	memory, err := resource.ParseQuantity(sizing.MemoryRequest)
+	if err != nil {
+		return
+	}
+	// The resources may share their maps with the ImageScan's spec
+	resources := podSpec.Containers[0].Resources.DeepCopy()
+	if resources.Requests == nil {
+		resources.Requests = corev1.ResourceList{}
+	}
+	resources.Requests[corev1.ResourceMemory] = memory
+	if limit, ok := resources.Limits[corev1.ResourceMemory]; ok && limit.Cmp(memory) < 0 {
+		resources.Limits[corev1.ResourceMemory] = memory
+	}
+	podSpec.Containers[0].Resources = *resources
+}
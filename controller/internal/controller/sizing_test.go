@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	invulnerablev1alpha1 "github.com/pacokleitz/invulnerable/controller/api/v1alpha1"
+)
+
+func TestReconcileSizing(t *testing.T) {
+	calls := 0
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if req.URL.Path != "/api/v1/imagescans/prod/nginx/sizing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"scans": 12, "image_size_bytes": 52428800, "workspace_size": "1Gi", "memory_request": "1280Mi"}`))
+	}))
+	defer backend.Close()
+
+	recorder := record.NewFakeRecorder(10)
+	r := &ImageScanReconciler{HTTPClient: backend.Client(), Recorder: recorder}
+	imageScan := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx"},
+		Spec: invulnerablev1alpha1.ImageScanSpec{
+			APIEndpoint: backend.URL,
+			AutoSizing:  &invulnerablev1alpha1.AutoSizingConfig{Enabled: true},
+		},
+	}
+	now := time.Date(2024, 6, 10, 2, 0, 0, 0, time.UTC)
+	if err := r.reconcileSizing(context.Background(), imageScan, now); err != nil {
+		t.Fatalf("reconcileSizing: %v", err)
+	}
+	sizing := imageScan.Status.Sizing
+	if sizing == nil || sizing.WorkspaceSize != "1Gi" || sizing.MemoryRequest != "1280Mi" || sizing.Scans != 12 || sizing.ImageSizeBytes != 52428800 {
+		t.Fatalf("sizing = %+v", sizing)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "Resized") || !strings.Contains(event, "1Gi workspace") {
+		t.Errorf("event = %q", event)
+	}
+
+	// Not fetched again before the refresh interval
+	if err := r.reconcileSizing(context.Background(), imageScan, now.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("backend called %d times, want 1", calls)
+	}
+
+	// An unchanged recommendation is refreshed without an event
+	if err := r.reconcileSizing(context.Background(), imageScan, now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if !imageScan.Status.Sizing.UpdatedAt.Time.Equal(now.Add(2 * time.Hour)) {
+		t.Errorf("updatedAt = %v", imageScan.Status.Sizing.UpdatedAt)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("unexpected event %q", <-recorder.Events)
+	}
+
+	// Without an image size reported yet nothing is recorded
+	other := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "redis"},
+		Spec:       invulnerablev1alpha1.ImageScanSpec{APIEndpoint: backend.URL},
+	}
+	if err := r.reconcileSizing(context.Background(), other, now); err != nil {
+		t.Fatal(err)
+	}
+	if other.Status.Sizing != nil {
+		t.Errorf("sizing = %+v, want none", other.Status.Sizing)
+	}
+}
+
+func TestBuildJobSpec_AutoSizing(t *testing.T) {
+	r := &ImageScanReconciler{}
+	resources := &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("4Gi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+	imageScan := &invulnerablev1alpha1.ImageScan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "nginx"},
+		Spec:       invulnerablev1alpha1.ImageScanSpec{Image: "nginx:1.25", WorkspaceSize: "10Gi", Resources: resources},
+		Status: invulnerablev1alpha1.ImageScanStatus{
+			Sizing: &invulnerablev1alpha1.SizingRecommendation{WorkspaceSize: "1Gi", MemoryRequest: "1280Mi", Scans: 12},
+		},
+	}
+	sizeLimit := func(podSpec corev1.PodSpec) string {
+		for _, volume := range podSpec.Volumes {
+			if volume.Name == "scan-workspace" {
+				return volume.EmptyDir.SizeLimit.String()
+			}
+		}
+		return ""
+	}
+
+	// The recommendation is only recorded without auto-sizing
+	podSpec := r.buildJobSpec(imageScan).Template.Spec
+	if got := sizeLimit(podSpec); got != "10Gi" {
+		t.Errorf("workspace = %s, want 10Gi", got)
+	}
+	if got := podSpec.Containers[0].Resources.Requests.Memory().String(); got != "4Gi" {
+		t.Errorf("memory request = %s, want 4Gi", got)
+	}
+
+	imageScan.Spec.AutoSizing = &invulnerablev1alpha1.AutoSizingConfig{Enabled: true}
+	podSpec = r.buildJobSpec(imageScan).Template.Spec
+	if got := sizeLimit(podSpec); got != "1Gi" {
+		t.Errorf("workspace = %s, want 1Gi", got)
+	}
+	container := podSpec.Containers[0]
+	if got := container.Resources.Requests.Memory().String(); got != "1280Mi" {
+		t.Errorf("memory request = %s, want 1280Mi", got)
+	}
+	if got := container.Resources.Limits.Memory().String(); got != "1280Mi" {
+		t.Errorf("memory limit = %s, want raised to 1280Mi", got)
+	}
+	if got := container.Resources.Requests.Cpu().String(); got != "500m" {
+		t.Errorf("cpu request = %s, want 500m", got)
+	}
+	// The ImageScan's spec is left as it is
+	if got := resources.Requests.Memory().String(); got != "4Gi" {
+		t.Errorf("spec memory request = %s, want 4Gi", got)
+	}
+}
@@ -1,11 +1,11 @@
-import { FC, useState, useEffect, useCallback } from 'react';
+import { FC, FormEvent, useState, useEffect, useCallback } from 'react';
 import { useParams, Link } from 'react-router-dom';
 import { api } from '../../lib/api/client';
-import type { Vulnerability } from '../../lib/api/types';
+import type { Advisory, Vulnerability, VulnerabilityLink } from '../../lib/api/types';
 import { SeverityBadge } from '../ui/SeverityBadge';
 import { StatusBadge } from '../ui/StatusBadge';
 import { VulnerabilityHistory } from '../ui/VulnerabilityHistory';
-import { formatDate, daysSince, calculateSLAStatus } from '../../lib/utils/formatters';
+import { formatDate, daysSince, calculateSLAStatus, formatSLADueDate } from '../../lib/utils/formatters';
 
 export const CVEDetails: FC = () => {
 	const { cve } = useParams<{ cve: string }>();
@@ -13,6 +13,12 @@ export const CVEDetails: FC = () => {
 	const [loading, setLoading] = useState(true);
 	const [error, setError] = useState<string | null>(null);
 	const [historyVulnId, setHistoryVulnId] = useState<number | null>(null);
+	const [links, setLinks] = useState<VulnerabilityLink[]>([]);
+	const [linkTarget, setLinkTarget] = useState('');
+	const [linkURL, setLinkURL] = useState('');
+	const [linkTitle, setLinkTitle] = useState('');
+	const [linkError, setLinkError] = useState<string | null>(null);
+	const [savingLink, setSavingLink] = useState(false);
 
 	const loadVulnerabilities = useCallback(async () => {
 		if (!cve) return;
@@ -24,7 +30,11 @@ export const CVEDetails: FC = () => {
 				cve_id: cve,
 				limit: 100
 			});
-			setVulnerabilities(response.data);
+			setVulnerabilities(response.items);
+			// Links are attached per vulnerability, one per package the CVE is found in
+			const ids = [...new Set(response.items.map((v) => v.id))];
+			const lists = await Promise.all(ids.map((id) => api.vulnerabilities.listLinks(id)));
+			setLinks(lists.flat());
 		} catch (e) {
 			setError(e instanceof Error ? e.message : 'Failed to load vulnerability');
 		} finally {
@@ -32,6 +42,38 @@ export const CVEDetails: FC = () => {
 		}
 	}, [cve]);
 
+	const addLink = async (e: FormEvent) => {
+		e.preventDefault();
+		const [vulnId, imageId] = linkTarget.split('-').map(Number);
+		if (!vulnId || !imageId || !linkURL.trim()) return;
+		setSavingLink(true);
+		setLinkError(null);
+		try {
+			const link = await api.vulnerabilities.createLink(vulnId, {
+				image_id: imageId,
+				url: linkURL.trim(),
+				title: linkTitle.trim() || undefined
+			});
+			setLinks((current) => [...current, link]);
+			setLinkURL('');
+			setLinkTitle('');
+		} catch (e) {
+			setLinkError(e instanceof Error ? e.message : 'Failed to add link');
+		} finally {
+			setSavingLink(false);
+		}
+	};
+
+	const removeLink = async (link: VulnerabilityLink) => {
+		setLinkError(null);
+		try {
+			await api.vulnerabilities.deleteLink(link.vulnerability_id, link.id);
+			setLinks((current) => current.filter((l) => l.id !== link.id));
+		} catch (e) {
+			setLinkError(e instanceof Error ? e.message : 'Failed to remove link');
+		}
+	};
+
 	useEffect(() => {
 		document.title = `${cve} - Invulnerable`;
 		loadVulnerabilities();
@@ -62,6 +104,13 @@ export const CVEDetails: FC = () => {
 	}
 
 	const cveInfo = vulnerabilities[0];
+	// The same advisory is reported for each package the CVE is found in
+	const advisories = new Map<string, Advisory>();
+	for (const vuln of vulnerabilities) {
+		for (const advisory of vuln.advisories || []) {
+			advisories.set(advisory.id, advisory);
+		}
+	}
 
 	return (
 		<>
@@ -95,6 +144,26 @@ export const CVEDetails: FC = () => {
 							<dt className="text-sm font-medium text-gray-500">Fix Available</dt>
 							<dd className="mt-1 text-sm text-gray-900">{cveInfo.fix_version || 'No fix available'}</dd>
 						</div>
+						<div className="md:col-span-2">
+							<dt className="text-sm font-medium text-gray-500">Advisories</dt>
+							<dd className="mt-1 text-sm text-gray-900 flex flex-wrap gap-x-4 gap-y-1">
+								{cveInfo.url && (
+									<a href={cveInfo.url} target="_blank" rel="noopener noreferrer" className="text-blue-600 hover:underline break-all">
+										{cveInfo.url}
+									</a>
+								)}
+								{[...advisories.values()].map((advisory) =>
+									advisory.link ? (
+										<a key={advisory.id} href={advisory.link} target="_blank" rel="noopener noreferrer" className="text-blue-600 hover:underline">
+											{advisory.id}
+										</a>
+									) : (
+										<span key={advisory.id}>{advisory.id}</span>
+									)
+								)}
+								{!cveInfo.url && advisories.size === 0 && 'N/A'}
+							</dd>
+						</div>
 						<div className="md:col-span-2">
 							<dt className="text-sm font-medium text-gray-500">Affected Images</dt>
 							<dd className="mt-1 text-sm text-gray-900">{vulnerabilities.length}</dd>
@@ -142,7 +211,10 @@ export const CVEDetails: FC = () => {
 										},
 										vuln.status,
 										vuln.remediation_date,
-										vuln.updated_at
+										vuln.updated_at,
+										undefined,
+										vuln.sla_due_date,
+										vuln.sla_time_zone
 									);
 
 									return (
@@ -176,7 +248,10 @@ export const CVEDetails: FC = () => {
 												</div>
 											</td>
 											<td className="px-6 py-4 whitespace-nowrap text-sm">
-												<div className={`inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium ${slaStatus.bgColor} ${slaStatus.color}`}>
+												<div
+													className={`inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium ${slaStatus.bgColor} ${slaStatus.color}`}
+													title={formatSLADueDate(slaStatus)}
+												>
 													{slaStatus.status === 'fixed' && slaStatus.daysToFix !== undefined && (
 														<span>Fixed in {slaStatus.daysToFix} {slaStatus.daysToFix === 1 ? 'day' : 'days'}</span>
 													)}
@@ -206,6 +281,70 @@ export const CVEDetails: FC = () => {
 				</div>
 			</div>
 
+			{/* Fix Links */}
+			<div className="card mt-6">
+				<h2 className="text-xl font-bold text-gray-900 mb-1">Fix Links</h2>
+				<p className="text-sm text-gray-500 mb-4">
+					Merge requests, pull requests or tickets fixing the vulnerability on an image. They are recorded in its history and listed in status change notifications.
+				</p>
+				{links.length === 0 ? (
+					<p className="text-sm text-gray-500 mb-4">No links yet</p>
+				) : (
+					<ul className="divide-y divide-gray-200 mb-4">
+						{links.map((link) => (
+							<li key={link.id} className="py-2 flex justify-between items-start gap-4">
+								<div className="min-w-0">
+									<a href={link.url} target="_blank" rel="noopener noreferrer" className="text-sm text-blue-600 hover:underline break-all">
+										{link.title || link.url}
+									</a>
+									<div className="text-xs text-gray-500">
+										{link.image_name} · added by {link.created_by || 'unknown'} on {formatDate(link.created_at)}
+									</div>
+								</div>
+								<button onClick={() => removeLink(link)} className="text-xs text-red-600 hover:text-red-800">
+									Remove
+								</button>
+							</li>
+						))}
+					</ul>
+				)}
+				<form onSubmit={addLink} className="grid grid-cols-1 md:grid-cols-4 gap-2">
+					<select
+						value={linkTarget}
+						onChange={(e) => setLinkTarget(e.target.value)}
+						className="text-sm rounded-md border-gray-300 shadow-sm focus:border-blue-500 focus:ring-blue-500"
+						required
+					>
+						<option value="">Image…</option>
+						{vulnerabilities.map((vuln) => (
+							<option key={`${vuln.id}-${vuln.image_id}`} value={`${vuln.id}-${vuln.image_id}`}>
+								{vuln.image_name} ({vuln.package_name})
+							</option>
+						))}
+					</select>
+					<input
+						type="url"
+						value={linkURL}
+						onChange={(e) => setLinkURL(e.target.value)}
+						placeholder="https://git.example.com/…/merge_requests/42"
+						className="text-sm rounded-md border-gray-300 shadow-sm focus:border-blue-500 focus:ring-blue-500"
+						required
+					/>
+					<input
+						type="text"
+						value={linkTitle}
+						onChange={(e) => setLinkTitle(e.target.value)}
+						placeholder="Title (optional)"
+						maxLength={255}
+						className="text-sm rounded-md border-gray-300 shadow-sm focus:border-blue-500 focus:ring-blue-500"
+					/>
+					<button type="submit" disabled={savingLink} className="btn btn-primary">
+						{savingLink ? 'Adding…' : 'Add Link'}
+					</button>
+				</form>
+				{linkError && <p className="text-sm text-red-600 mt-2">{linkError}</p>}
+			</div>
+
 			{/* Vulnerability History Modal */}
 			{historyVulnId && (
 				<VulnerabilityHistory
@@ -1,5 +1,6 @@
 import { FC, useEffect, useState } from 'react';
 import { useSearchParams } from 'react-router-dom';
+import { api } from '../../lib/api/client';
 import { useStore } from '../../store';
 import { SeverityBadge } from '../ui/SeverityBadge';
 
@@ -30,6 +31,25 @@ export const Dashboard: FC = () => {
 		loadMetrics(showUnfixable ? undefined : true, imageFilter || undefined);
 	}, [loadMetrics, showUnfixable, imageFilter]);
 
+	// Reload the metrics as scans come in and vulnerabilities are triaged, at most once a second
+	useEffect(() => {
+		let timer: ReturnType<typeof setTimeout> | undefined;
+		const unsubscribe = api.events.subscribe(
+			() => {
+				if (timer) return;
+				timer = setTimeout(() => {
+					timer = undefined;
+					loadMetrics(showUnfixable ? undefined : true, imageFilter || undefined, true);
+				}, 1000);
+			},
+			['scan-created', 'vulnerability-status-changed']
+		);
+		return () => {
+			clearTimeout(timer);
+			unsubscribe();
+		};
+	}, [loadMetrics, showUnfixable, imageFilter]);
+
 	const updateFilter = (key: string, value: string) => {
 		const newParams = new URLSearchParams(searchParams);
 		if (value) {
@@ -123,6 +143,9 @@ export const Dashboard: FC = () => {
 				<article className="card">
 					<h3 className="text-sm font-medium text-gray-500">Total Images</h3>
 					<p className="mt-2 text-3xl font-bold text-gray-900" aria-label={`${metrics.total_images} total images`}>{metrics.total_images}</p>
+					{metrics.alias_images > 0 && (
+						<p className="mt-1 text-xs text-gray-500">{metrics.alias_images} more under other names</p>
+					)}
 				</article>
 
 				<article className="card">
@@ -4,7 +4,8 @@ import { useStore } from '../../store';
 import { SortableTableHeader, useSortState } from '../ui/SortableTableHeader';
 import { Pagination } from '../ui/Pagination';
 import { formatDate } from '../../lib/utils/formatters';
-import type { ScanWithDetails } from '../../lib/api/types';
+import { api } from '../../lib/api/client';
+import type { ImageDetails, ScanWithDetails } from '../../lib/api/types';
 
 export const ImageHistory: FC = () => {
 	const { id } = useParams<{ id: string }>();
@@ -13,6 +14,7 @@ export const ImageHistory: FC = () => {
 	const itemsPerPage = 50;
 	const [showUnfixable, setShowUnfixed] = useState(false);
 	const { sortKey, sortDirection, handleSort } = useSortState('scan_date', 'desc');
+	const [image, setImage] = useState<ImageDetails | null>(null);
 
 	const { currentImageHistory, historyTotal, loading, error, reload } = useStore((state) => ({
 		currentImageHistory: state.currentImageHistory,
@@ -31,6 +33,11 @@ export const ImageHistory: FC = () => {
 		document.title = 'Image History - Invulnerable';
 	}, []);
 
+	// The image and the other names its digest is pushed under
+	useEffect(() => {
+		api.images.get(imageId).then(setImage).catch(() => setImage(null));
+	}, [imageId]);
+
 	// Reset to page 1 when filters change
 	useEffect(() => {
 		setCurrentPage(1);
@@ -82,6 +89,22 @@ export const ImageHistory: FC = () => {
 						<p className="text-sm text-gray-600 mt-2">
 							Total scans: {historyTotal}
 						</p>
+						{image && image.also_known_as.length > 0 && (
+							<p className="text-sm text-gray-600 mt-1">
+								Also known as:{' '}
+								{image.also_known_as.map((alias, i) => (
+									<span key={alias.id}>
+										{i > 0 && ', '}
+										<Link
+											to={`/images/${alias.id}`}
+											className="font-mono text-blue-600 hover:text-blue-800"
+										>
+											{alias.registry}/{alias.repository}:{alias.tag}
+										</Link>
+									</span>
+								))}
+							</p>
+						)}
 					</div>
 					<label className="flex items-center space-x-2 text-sm">
 						<input
@@ -267,6 +267,22 @@ export const ImagesList: FC = () => {
 									<tr key={image.id} className="hover:bg-gray-50">
 										<td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900">
 											{image.registry}/{image.repository}:{image.tag}
+											{image.monitoring === 'paused' && (
+												<span
+													className="ml-2 inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium bg-gray-100 text-gray-600"
+													title="Every ImageScan scanning this image is suspended"
+												>
+													Paused
+												</span>
+											)}
+											{image.alias_count > 0 && (
+												<span
+													className="ml-2 inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium bg-blue-50 text-blue-700"
+													title="Images with the same digest under other names, their findings are the same"
+												>
+													+{image.alias_count} {image.alias_count === 1 ? 'alias' : 'aliases'}
+												</span>
+											)}
 										</td>
 										<td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
 											{image.scan_count}
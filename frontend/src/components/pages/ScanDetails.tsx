@@ -8,7 +8,7 @@ import { PackageCategoryBadge } from '../ui/PackageCategoryBadge';
 import { VulnerabilityHistory } from '../ui/VulnerabilityHistory';
 import { SortableTableHeader, useSortState } from '../ui/SortableTableHeader';
 import { Pagination } from '../ui/Pagination';
-import { formatDate, daysSince, calculateSLAStatus } from '../../lib/utils/formatters';
+import { formatDate, formatDuration, formatBytes, daysSince, calculateSLAStatus } from '../../lib/utils/formatters';
 import type { Vulnerability } from '../../lib/api/types';
 
 // Copyable text component with visual feedback
@@ -307,6 +307,9 @@ export const ScanDetails: FC = () => {
 			['Scan Date', formatDate(scan.scan_date)],
 			['Syft Version', scan.syft_version || 'N/A'],
 			['Grype Version', scan.grype_version || 'N/A'],
+			['Scan Duration', scan.scan_duration_ms !== undefined ? formatDuration(scan.scan_duration_ms) : 'N/A'],
+			['Image Size', scan.image_size_bytes !== undefined ? formatBytes(scan.image_size_bytes) : 'N/A'],
+			['Layers', scan.layer_count !== undefined ? scan.layer_count.toString() : 'N/A'],
 			['Total Vulnerabilities', vulnerabilities.length.toString()],
 			['Filtered Vulnerabilities', filteredVulnerabilities.length.toString()],
 			['SLA Limits', `Critical: ${scan.sla_critical}d, High: ${scan.sla_high}d, Medium: ${scan.sla_medium}d, Low: ${scan.sla_low}d`],
@@ -318,6 +321,7 @@ export const ScanDetails: FC = () => {
 			'Severity',
 			'Package Name',
 			'Installed Version',
+			'PURL',
 			'Fixed Version',
 			'Status',
 			'First Detected',
@@ -384,6 +388,7 @@ export const ScanDetails: FC = () => {
 				vuln.severity,
 				vuln.package_name,
 				vuln.package_version,
+				vuln.purl || '',
 				vuln.fix_version || 'N/A',
 				vuln.status,
 				formatDate(vuln.first_detected_at),
@@ -512,6 +517,19 @@ export const ScanDetails: FC = () => {
 								<dt className="text-sm font-medium text-gray-500">Grype Version</dt>
 								<dd className="mt-1 text-sm text-gray-900">{scan.grype_version || 'N/A'}</dd>
 							</div>
+							<div>
+								<dt className="text-sm font-medium text-gray-500">Scan Duration</dt>
+								<dd className="mt-1 text-sm text-gray-900">
+									{scan.scan_duration_ms !== undefined ? formatDuration(scan.scan_duration_ms) : 'N/A'}
+								</dd>
+							</div>
+							<div>
+								<dt className="text-sm font-medium text-gray-500">Image Size</dt>
+								<dd className="mt-1 text-sm text-gray-900">
+									{scan.image_size_bytes !== undefined ? formatBytes(scan.image_size_bytes) : 'N/A'}
+									{scan.layer_count !== undefined && <span className="text-gray-500"> ({scan.layer_count} layers)</span>}
+								</dd>
+							</div>
 						</dl>
 					</div>
 
@@ -17,6 +17,9 @@ export const ScanDiff: FC = () => {
 	const [scan, setScan] = useState<Scan | null>(null);
 	const [allScans, setAllScans] = useState<Scan[]>([]);
 	const [selectedPreviousScanId, setSelectedPreviousScanId] = useState<number | null>(null);
+	// Another image to compare with, e.g. the next tag when evaluating an upgrade
+	const [previousImage, setPreviousImage] = useState<string | null>(null);
+	const [previousImageInput, setPreviousImageInput] = useState('');
 	const [loading, setLoading] = useState(true);
 	const [error, setError] = useState<string | null>(null);
 	const [showUnfixable, setShowUnfixed] = useState(false);
@@ -38,6 +41,7 @@ export const ScanDiff: FC = () => {
 
 		const fetchData = async () => {
 			setLoading(true);
+			setError(null);
 			try {
 				// First get the scan to know which image we're dealing with
 				const scanResult = await api.scans.get(scanId);
@@ -46,17 +50,19 @@ export const ScanDiff: FC = () => {
 				// Fetch all scans for this image
 				const imageId = scanResult.scan.image_id;
 				const scansResponse = await api.images.getHistory(imageId, 1000, 0);
-				const scansForImage = scansResponse.data
+				const scansForImage = scansResponse.items
 					.filter(s => s.id !== scanId) // Exclude current scan
 					.sort((a, b) => new Date(b.scan_date).getTime() - new Date(a.scan_date).getTime()); // Most recent first
 				setAllScans(scansForImage);
 
 				// Fetch diff with selected previous scan (or default)
-				const diffData = await api.scans.getDiff(scanId, selectedPreviousScanId || undefined);
+				const diffData = previousImage
+					? await api.scans.getDiff(scanId, undefined, previousImage)
+					: await api.scans.getDiff(scanId, selectedPreviousScanId || undefined);
 				setDiff(diffData);
 
 				// Set default selected previous scan if not already set
-				if (!selectedPreviousScanId && diffData.previous_scan_id) {
+				if (!previousImage && !selectedPreviousScanId && diffData.previous_scan_id) {
 					setSelectedPreviousScanId(diffData.previous_scan_id);
 				}
 			} catch (e) {
@@ -67,7 +73,7 @@ export const ScanDiff: FC = () => {
 		};
 
 		fetchData();
-	}, [scanId, selectedPreviousScanId]);
+	}, [scanId, selectedPreviousScanId, previousImage]);
 
 	// Reset to page 1 when filters change
 	useEffect(() => {
@@ -247,17 +253,53 @@ export const ScanDiff: FC = () => {
 								</Link>
 								{' '}with
 							</span>
-							<select
-								value={selectedPreviousScanId || diff.previous_scan_id}
-								onChange={(e) => setSelectedPreviousScanId(parseInt(e.target.value, 10))}
-								className="text-sm rounded-md border-gray-300 shadow-sm focus:border-blue-500 focus:ring-blue-500"
+							{diff.cross_image ? (
+								<span className="text-sm text-gray-600">
+									<Link to={`/scans/${diff.previous_scan_id}`} className="text-blue-600 hover:text-blue-800 hover:underline font-semibold">
+										Scan #{diff.previous_scan_id}
+									</Link>
+									{previousImage && ` of ${previousImage}`}
+									<button
+										onClick={() => {
+											setPreviousImage(null);
+											setSelectedPreviousScanId(null);
+										}}
+										className="ml-2 text-xs text-blue-600 hover:text-blue-800 hover:underline"
+									>
+										Compare with this image
+									</button>
+								</span>
+							) : (
+								<select
+									value={selectedPreviousScanId || diff.previous_scan_id}
+									onChange={(e) => setSelectedPreviousScanId(parseInt(e.target.value, 10))}
+									className="text-sm rounded-md border-gray-300 shadow-sm focus:border-blue-500 focus:ring-blue-500"
+								>
+									{allScans.map((s) => (
+										<option key={s.id} value={s.id}>
+											Scan #{s.id} ({formatDate(s.scan_date)})
+										</option>
+									))}
+								</select>
+							)}
+							<form
+								onSubmit={(e) => {
+									e.preventDefault();
+									setPreviousImage(previousImageInput.trim() || null);
+								}}
+								className="flex items-center gap-2 ml-auto"
 							>
-								{allScans.map((s) => (
-									<option key={s.id} value={s.id}>
-										Scan #{s.id} ({formatDate(s.scan_date)})
-									</option>
-								))}
-							</select>
+								<input
+									type="text"
+									value={previousImageInput}
+									onChange={(e) => setPreviousImageInput(e.target.value)}
+									placeholder="Another image, e.g. nginx:1.25"
+									className="text-sm rounded-md border-gray-300 shadow-sm focus:border-blue-500 focus:ring-blue-500"
+								/>
+								<button type="submit" className="btn btn-secondary text-sm">
+									Compare
+								</button>
+							</form>
 						</div>
 					</div>
 				</>
@@ -8,7 +8,7 @@ import { PackageCategoryBadge } from '../ui/PackageCategoryBadge';
 import { VulnerabilityHistory } from '../ui/VulnerabilityHistory';
 import { SortableTableHeader, useSortState } from '../ui/SortableTableHeader';
 import { Pagination } from '../ui/Pagination';
-import { formatDate, daysSince, calculateSLAStatus } from '../../lib/utils/formatters';
+import { formatDate, daysSince, calculateSLAStatus, formatSLADueDate } from '../../lib/utils/formatters';
 import { categorizePackageType } from '../../lib/utils/packageTypes';
 
 export const VulnerabilitiesList: FC = () => {
@@ -54,7 +54,7 @@ export const VulnerabilitiesList: FC = () => {
 			if (!showUnfixable) params.has_fix = true;
 
 			const response = await api.vulnerabilities.list(params);
-			let data = response.data;
+			let data = response.items;
 
 			// Apply client-side severity filter (X or higher)
 			if (severityFilter) {
@@ -106,7 +106,10 @@ export const VulnerabilitiesList: FC = () => {
 								},
 								vuln.status,
 								vuln.remediation_date,
-								vuln.updated_at
+								vuln.updated_at,
+								vuln.image_monitoring,
+								vuln.sla_due_date,
+								vuln.sla_time_zone
 							);
 
 							// Priority 1: Exceeded SLA (most overdue first)
@@ -264,7 +267,7 @@ export const VulnerabilitiesList: FC = () => {
 			if (!showUnfixable) params.has_fix = true;
 
 			const response = await api.vulnerabilities.list(params);
-			let allVulnerabilities = response.data;
+			let allVulnerabilities = response.items;
 
 			// Apply client-side severity filter (X or higher)
 			if (severityFilter) {
@@ -312,12 +315,15 @@ export const VulnerabilitiesList: FC = () => {
 				'Package Name',
 				'Package Version',
 				'Package Type',
+				'PURL',
 				'Fixed Version',
 				'Status',
 				'First Detected',
 				'Days Since Detection',
 				'SLA Days',
+				'SLA Day Count',
 				'Days Remaining',
+				'SLA Due Date',
 				'SLA Status',
 				'Notes',
 				'Description',
@@ -371,7 +377,10 @@ export const VulnerabilitiesList: FC = () => {
 							},
 							vuln.status,
 							vuln.remediation_date,
-							vuln.updated_at
+							vuln.updated_at,
+							vuln.image_monitoring,
+							vuln.sla_due_date,
+							vuln.sla_time_zone
 					  )
 					: null;
 
@@ -382,12 +391,15 @@ export const VulnerabilitiesList: FC = () => {
 					vuln.package_name,
 					vuln.package_version,
 					vuln.package_type || 'unknown',
+					vuln.purl || '',
 					vuln.fix_version || 'N/A',
 					vuln.status,
 					formatDate(vuln.first_detected_at),
 					daysSinceDetection.toString(),
 					slaDays.toString(),
+					vuln.sla_business_days ? 'business days' : 'calendar days',
 					slaStatus ? slaStatus.daysRemaining.toString() : 'N/A',
+					vuln.sla_due_date ? `${vuln.sla_due_date} ${vuln.sla_time_zone}` : 'N/A',
 					slaStatus ? slaStatus.status : 'N/A',
 					vuln.notes || '',
 					vuln.description || '',
@@ -702,7 +714,10 @@ export const VulnerabilitiesList: FC = () => {
 												},
 												vuln.status,
 												vuln.remediation_date,
-												vuln.updated_at
+												vuln.updated_at,
+												vuln.image_monitoring,
+												vuln.sla_due_date,
+												vuln.sla_time_zone
 										  )
 										: null;
 
@@ -764,7 +779,10 @@ export const VulnerabilitiesList: FC = () => {
 											</td>
 											<td className="px-6 py-4 whitespace-nowrap text-sm">
 												{slaStatus ? (
-													<div className={`inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium ${slaStatus.bgColor} ${slaStatus.color}`}>
+													<div
+														className={`inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium ${slaStatus.bgColor} ${slaStatus.color}`}
+														title={formatSLADueDate(slaStatus)}
+													>
 														{slaStatus.status === 'fixed' && slaStatus.daysToFix !== undefined && (
 															<span>Fixed in {slaStatus.daysToFix} {slaStatus.daysToFix === 1 ? 'day' : 'days'}</span>
 														)}
@@ -783,6 +801,7 @@ export const VulnerabilitiesList: FC = () => {
 														{slaStatus.status === 'compliant' && (
 															<span>{slaStatus.daysRemaining} days remaining</span>
 														)}
+														{slaStatus.status === 'paused' && <span>Paused</span>}
 													</div>
 												) : (
 													<span className="text-gray-400">N/A</span>
@@ -36,6 +36,8 @@ export const VulnerabilityHistory: FC<VulnerabilityHistoryProps> = ({ vulnerabil
 				return 'Status';
 			case 'notes':
 				return 'Notes';
+			case 'link':
+				return 'Links';
 			default:
 				return fieldName;
 		}
@@ -90,7 +92,7 @@ export const VulnerabilityHistory: FC<VulnerabilityHistoryProps> = ({ vulnerabil
 					{!loading && !error && history.length === 0 && (
 						<div className="text-center py-8">
 							<p className="text-sm text-gray-500">No change history available</p>
-							<p className="text-xs text-gray-400 mt-2">Changes will appear here when the vulnerability status or notes are updated</p>
+							<p className="text-xs text-gray-400 mt-2">Changes will appear here when the vulnerability status, notes or links are updated</p>
 						</div>
 					)}
 
@@ -149,6 +151,20 @@ export const VulnerabilityHistory: FC<VulnerabilityHistoryProps> = ({ vulnerabil
 										</div>
 									)}
 
+									{entry.field_name === 'link' && (
+										<div className="text-sm text-gray-600">
+											<span className="text-xs text-gray-500">{entry.new_value ? 'Added:' : 'Removed:'}</span>
+											<a
+												href={entry.new_value || entry.old_value}
+												target="_blank"
+												rel="noopener noreferrer"
+												className={`ml-2 break-all text-blue-600 hover:underline ${entry.new_value ? '' : 'line-through'}`}
+											>
+												{entry.new_value || entry.old_value}
+											</a>
+										</div>
+									)}
+
 									<div className="text-xs text-gray-500 mt-2">
 										Changed by {entry.changed_by || 'unknown'}
 									</div>
@@ -1,12 +1,18 @@
 import type {
 	DashboardMetrics,
 	ImageWithStats,
+	ImageDetails,
+	LiveEvent,
+	LiveEventType,
 	PaginatedResponse,
 	ScanDiff,
+	ScanSummary,
 	ScanWithDetails,
+	SeverityChange,
 	User,
 	Vulnerability,
 	VulnerabilityHistory,
+	VulnerabilityLink,
 	VulnerabilityUpdate
 } from './types';
 
@@ -36,6 +42,11 @@ async function fetchAPI<T>(endpoint: string, options?: RequestInit): Promise<T>
 		throw new Error(`API Error: ${response.status} - ${error}`);
 	}
 
+	// Deletions answer 204 No Content, without a body to parse
+	if (response.status === 204) {
+		return undefined as T;
+	}
+
 	return response.json();
 }
 
@@ -75,9 +86,16 @@ export const api = {
 			return fetchAPI<any>(`/scans/${id}/sbom`);
 		},
 
-		getDiff: (id: number, previousScanId?: number) => {
-			const params = previousScanId ? `?previous_scan_id=${previousScanId}` : '';
-			return fetchAPI<ScanDiff>(`/scans/${id}/diff${params}`);
+		getDiff: (id: number, previousScanId?: number, previousImage?: string) => {
+			const searchParams = new URLSearchParams();
+			if (previousScanId) searchParams.set('previous_scan_id', previousScanId.toString());
+			if (previousImage) searchParams.set('previous_image', previousImage);
+			const query = searchParams.toString();
+			return fetchAPI<ScanDiff>(`/scans/${id}/diff${query ? `?${query}` : ''}`);
+		},
+
+		getSummary: (id: number) => {
+			return fetchAPI<ScanSummary>(`/scans/${id}/summary`);
 		}
 	},
 
@@ -107,6 +125,28 @@ export const api = {
 			return fetchAPI<PaginatedResponse<Vulnerability>>(`/vulnerabilities${query ? `?${query}` : ''}`);
 		},
 
+		search: (params: {
+			package?: string;
+			cve?: string;
+			q?: string;
+			min_severity?: string;
+			max_severity?: string;
+			status?: string;
+			has_fix?: boolean;
+			detected_after?: string;
+			detected_before?: string;
+			limit?: number;
+			offset?: number;
+		}) => {
+			const searchParams = new URLSearchParams();
+			for (const [key, value] of Object.entries(params)) {
+				if (value !== undefined && value !== '') searchParams.set(key, value.toString());
+			}
+
+			const query = searchParams.toString();
+			return fetchAPI<PaginatedResponse<Vulnerability>>(`/vulnerabilities/search${query ? `?${query}` : ''}`);
+		},
+
 		getByCVE: (cve: string) => {
 			return fetchAPI<Vulnerability[]>(`/vulnerabilities/${cve}`);
 		},
@@ -128,8 +168,37 @@ export const api = {
 			});
 		},
 
+		batchGet: (ids: number[]) => {
+			return fetchAPI<{ data: Vulnerability[]; missing_ids: number[] }>(`/vulnerabilities/batch-get`, {
+				method: 'POST',
+				body: JSON.stringify({ ids })
+			});
+		},
+
 		getHistory: (id: number) => {
 			return fetchAPI<VulnerabilityHistory[]>(`/vulnerabilities/${id}/history`);
+		},
+
+		getSeverityChanges: (cve: string) => {
+			return fetchAPI<SeverityChange[]>(`/vulnerabilities/${cve}/severity-changes`);
+		},
+
+		listLinks: (id: number, imageId?: number) => {
+			const query = imageId ? `?image_id=${imageId}` : '';
+			return fetchAPI<VulnerabilityLink[]>(`/vulnerabilities/${id}/links${query}`);
+		},
+
+		createLink: (id: number, link: { image_id: number; url: string; title?: string }) => {
+			return fetchAPI<VulnerabilityLink>(`/vulnerabilities/${id}/links`, {
+				method: 'POST',
+				body: JSON.stringify(link)
+			});
+		},
+
+		deleteLink: (id: number, linkId: number) => {
+			return fetchAPI<void>(`/vulnerabilities/${id}/links/${linkId}`, {
+				method: 'DELETE'
+			});
 		}
 	},
 
@@ -155,6 +224,10 @@ export const api = {
 			return fetchAPI<PaginatedResponse<ImageWithStats>>(`/images${query ? `?${query}` : ''}`);
 		},
 
+		get: (id: number) => {
+			return fetchAPI<ImageDetails>(`/images/${id}`);
+		},
+
 		getHistory: (id: number, limit?: number, offset?: number, has_fix?: boolean) => {
 			const searchParams = new URLSearchParams();
 			if (limit) searchParams.set('limit', limit.toString());
@@ -177,6 +250,25 @@ export const api = {
 		}
 	},
 
+	// Live updates, onEvent is called with every event until the returned function is called.
+	// The browser reconnects on its own; events published meanwhile are missed
+	events: {
+		subscribe: (onEvent: (event: LiveEvent) => void, types?: LiveEventType[]) => {
+			const query = types?.length ? `?types=${types.join(',')}` : '';
+			const source = new EventSource(`${API_BASE}/events${query}`);
+			const eventTypes: LiveEventType[] = types?.length
+				? types
+				: ['scan-created', 'scan-diff', 'vulnerability-status-changed'];
+			for (const type of eventTypes) {
+				source.addEventListener(type, (e) => {
+					const message = e as MessageEvent<string>;
+					onEvent({ id: Number(message.lastEventId), type, data: JSON.parse(message.data) });
+				});
+			}
+			return () => source.close();
+		}
+	},
+
 	// User
 	user: {
 		getMe: async (): Promise<User | null> => {
@@ -1,8 +1,10 @@
 export interface PaginatedResponse<T> {
-	data: T[];
+	items: T[];
 	total: number;
 	limit: number;
 	offset: number;
+	// Offset of the next page, null on the last one
+	next_offset: number | null;
 }
 
 export interface Image {
@@ -11,6 +13,11 @@ export interface Image {
 	repository: string;
 	tag: string;
 	digest?: string;
+	monitoring: 'active' | 'paused';
+	team?: string;
+	criticality?: 'low' | 'medium' | 'high' | 'critical';
+	reviewed_at?: string;
+	reviewed_by?: string;
 	created_at: string;
 	updated_at: string;
 }
@@ -22,6 +29,20 @@ export interface ImageWithStats extends Image {
 	high_count: number;
 	medium_count: number;
 	low_count: number;
+	alias_count: number;
+}
+
+// Another image with the same digest: the same content pushed under another name or tag
+export interface ImageAlias {
+	id: number;
+	registry: string;
+	repository: string;
+	tag: string;
+	last_scan_date?: string;
+}
+
+export interface ImageDetails extends Image {
+	also_known_as: ImageAlias[];
 }
 
 export interface Scan {
@@ -35,6 +56,12 @@ export interface Scan {
 	sla_high: number;
 	sla_medium: number;
 	sla_low: number;
+	sla_time_zone?: string;
+	sla_business_days?: boolean;
+	sla_holidays?: string[];
+	scan_duration_ms?: number; // reported by the scanner
+	image_size_bytes?: number;
+	layer_count?: number;
 	created_at: string;
 	updated_at: string;
 }
@@ -55,10 +82,13 @@ export interface Vulnerability {
 	package_name: string;
 	package_version: string;
 	package_type?: string;
+	purl?: string;
 	severity: string;
+	initial_severity?: string; // severity when first detected, before any rescoring
 	fix_version?: string;
 	url?: string;
 	description?: string;
+	advisories?: Advisory[]; // reported by the latest scan
 	status: string;
 	first_detected_at: string;
 	last_seen_at: string;
@@ -71,6 +101,7 @@ export interface Vulnerability {
 	image_id?: number;
 	image_name?: string;
 	image_digest?: string;
+	image_monitoring?: 'active' | 'paused';
 	first_detected_at_for_image?: string;
 	latest_scan_id?: number;
 	latest_scan_date?: string;
@@ -78,6 +109,13 @@ export interface Vulnerability {
 	sla_high?: number;
 	sla_medium?: number;
 	sla_low?: number;
+	sla_time_zone?: string;
+	sla_business_days?: boolean; // SLA days skip weekends and holidays
+	sla_severity?: string; // severity the SLA is counted from
+	sla_due_date?: string; // last day to remediate in sla_time_zone
+	sla_due_at?: string;
+	flap_count?: number; // times it came back on the image after scans without it
+	frameworks?: string[]; // compliance profiles covering it on the image
 }
 
 export interface ScanDiff {
@@ -86,6 +124,8 @@ export interface ScanDiff {
 	new_vulnerabilities: Vulnerability[];
 	fixed_vulnerabilities: Vulnerability[];
 	persistent_vulnerabilities: Vulnerability[];
+	fixed_held_reason?: string;
+	cross_image?: boolean;
 	summary: {
 		new_count: number;
 		fixed_count: number;
@@ -93,8 +133,29 @@ export interface ScanDiff {
 	};
 }
 
+export interface SeverityCounts {
+	critical: number;
+	high: number;
+	medium: number;
+	low: number;
+	negligible: number;
+	unknown: number;
+	total: number;
+}
+
+export interface ScanSummary {
+	scan_id: number;
+	by_severity: SeverityCounts;
+	by_package_type: (SeverityCounts & { package_type: string })[];
+	by_fix_availability: {
+		fixable: SeverityCounts;
+		unfixable: SeverityCounts;
+	};
+}
+
 export interface DashboardMetrics {
 	total_images: number;
+	alias_images: number;
 	total_scans: number;
 	total_vulnerabilities: number;
 	active_vulnerabilities: number;
@@ -115,6 +176,48 @@ export interface VulnerabilityUpdate {
 export interface User {
 	email: string;
 	username?: string;
+	groups?: string[];
+}
+
+// A scan reporting another severity for a vulnerability than the scans before it
+export interface SeverityChange {
+	id: number;
+	vulnerability_id: number;
+	cve_id: string;
+	package_name: string;
+	package_version: string;
+	old_severity: string;
+	new_severity: string;
+	scan_id?: number;
+	image_name?: string;
+	grype_db_built?: string;
+	changed_at: string;
+}
+
+// Events streamed by GET /api/v1/events
+export type LiveEventType = 'scan-created' | 'scan-diff' | 'vulnerability-status-changed';
+
+export interface LiveEvent {
+	id: number;
+	type: LiveEventType;
+	data: unknown;
+}
+
+export interface Advisory {
+	id: string;
+	link?: string;
+}
+
+// A link a user attached to a vulnerability on an image, such as the MR/PR fixing it
+export interface VulnerabilityLink {
+	id: number;
+	vulnerability_id: number;
+	image_id: number;
+	url: string;
+	title?: string;
+	created_by?: string;
+	created_at: string;
+	image_name: string;
 }
 
 export interface VulnerabilityHistory {
@@ -7,6 +7,23 @@ export const truncateText = (text: string, maxLength: number): string => {
 	return `${text.substring(0, maxLength)}...`;
 };
 
+export const formatDuration = (ms: number): string => {
+	const seconds = Math.round(ms / 1000);
+	if (seconds < 60) return `${seconds}s`;
+	return `${Math.floor(seconds / 60)}m ${seconds % 60}s`;
+};
+
+export const formatBytes = (bytes: number): string => {
+	const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+	let value = bytes;
+	let unit = 0;
+	while (value >= 1024 && unit < units.length - 1) {
+		value /= 1024;
+		unit++;
+	}
+	return `${unit === 0 ? value : value.toFixed(1)} ${units[unit]}`;
+};
+
 export const daysSince = (date: string): number => {
 	const then = new Date(date);
 	const now = new Date();
@@ -17,19 +34,38 @@ export const daysSince = (date: string): number => {
 
 export interface SLAStatus {
 	daysRemaining: number;
-	status: 'compliant' | 'warning' | 'exceeded' | 'fixed' | 'accepted' | 'ignored';
+	status: 'compliant' | 'warning' | 'exceeded' | 'fixed' | 'accepted' | 'ignored' | 'paused';
 	color: string;
 	bgColor: string;
 	daysToFix?: number; // Time taken to fix/accept/ignore
+	dueDate?: string; // Last day to remediate (YYYY-MM-DD) in timeZone
+	timeZone?: string;
 }
 
+// Calendar date (YYYY-MM-DD) of an instant in a timezone
+const dateInTimeZone = (date: Date, timeZone: string): string =>
+	new Intl.DateTimeFormat('en-CA', { timeZone, year: 'numeric', month: '2-digit', day: '2-digit' }).format(date);
+
+// Calendar days from today to a due date, both in the SLA timezone: 0 on the due date, negative once exceeded
+export const daysUntilDueDate = (dueDate: string, timeZone: string): number => {
+	const today = dateInTimeZone(new Date(), timeZone);
+	return Math.round((Date.parse(dueDate) - Date.parse(today)) / (1000 * 60 * 60 * 24));
+};
+
+// Describes the SLA deadline, which ends at midnight of the team's timezone rather than the browser's
+export const formatSLADueDate = (slaStatus: SLAStatus): string | undefined =>
+	slaStatus.dueDate ? `Due ${slaStatus.dueDate} (end of day, ${slaStatus.timeZone})` : undefined;
+
 export const calculateSLAStatus = (
 	firstDetectedAt: string,
 	severity: string,
 	slaLimits: { critical: number; high: number; medium: number; low: number },
 	vulnerabilityStatus?: string,
 	remediationDate?: string,
-	updatedAt?: string
+	updatedAt?: string,
+	imageMonitoring?: string,
+	slaDueDate?: string,
+	slaTimeZone?: string
 ): SLAStatus => {
 	// If status is "fixed" and we have a remediation date, calculate time to fix
 	if (vulnerabilityStatus === 'fixed' && remediationDate) {
@@ -76,6 +112,16 @@ export const calculateSLAStatus = (
 		};
 	}
 
+	// Suspended ImageScans are not rescanned, so their SLA clock is not evaluated
+	if (imageMonitoring === 'paused') {
+		return {
+			daysRemaining: 0,
+			status: 'paused',
+			color: 'text-gray-600',
+			bgColor: 'bg-gray-100',
+		};
+	}
+
 	const daysElapsed = daysSince(firstDetectedAt);
 
 	let slaLimit: number;
@@ -96,7 +142,9 @@ export const calculateSLAStatus = (
 			slaLimit = 180; // Default for unknown severity
 	}
 
-	const daysRemaining = slaLimit - daysElapsed;
+	// The backend computes due dates in the ImageScan's SLA timezone, older responses have none
+	const timeZone = slaTimeZone || 'UTC';
+	const daysRemaining = slaDueDate ? daysUntilDueDate(slaDueDate, timeZone) : slaLimit - daysElapsed;
 
 	let status: 'compliant' | 'warning' | 'exceeded';
 	let color: string;
@@ -117,5 +165,5 @@ export const calculateSLAStatus = (
 		bgColor = 'bg-green-50';
 	}
 
-	return { daysRemaining, status, color, bgColor };
+	return { daysRemaining, status, color, bgColor, dueDate: slaDueDate, timeZone: slaDueDate ? timeZone : undefined };
 };
@@ -14,7 +14,7 @@ export const createImagesSlice: StateCreator<AppStore, [], [], ImagesState> = (s
 		set({ loading: true, error: null });
 		try {
 			const response = await api.images.list(params);
-			set({ images: response.data, total: response.total, loading: false });
+			set({ images: response.items, total: response.total, loading: false });
 		} catch (error) {
 			set({
 				error: error instanceof Error ? error.message : 'Failed to load images',
@@ -27,7 +27,7 @@ export const createImagesSlice: StateCreator<AppStore, [], [], ImagesState> = (s
 		set({ loading: true, error: null });
 		try {
 			const response = await api.images.getHistory(id, limit, offset, hasFix);
-			set({ currentImageHistory: response.data, historyTotal: response.total, loading: false });
+			set({ currentImageHistory: response.items, historyTotal: response.total, loading: false });
 		} catch (error) {
 			set({
 				error: error instanceof Error ? error.message : 'Failed to load image history',
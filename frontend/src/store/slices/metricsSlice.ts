@@ -7,8 +7,8 @@ export const createMetricsSlice: StateCreator<AppStore, [], [], MetricsState> =
 	loading: false,
 	error: null,
 
-	loadMetrics: async (hasFix?: boolean, imageName?: string) => {
-		set({ loading: true, error: null });
+	loadMetrics: async (hasFix?: boolean, imageName?: string, quiet?: boolean) => {
+		set({ loading: !quiet, error: null });
 		try {
 			const data = await api.metrics.getDashboard(hasFix, imageName);
 			set({ data, loading: false });
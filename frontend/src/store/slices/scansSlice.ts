@@ -13,7 +13,7 @@ export const createScansSlice: StateCreator<AppStore, [], [], ScansState> = (set
 		set({ loading: true, error: null });
 		try {
 			const response = await api.scans.list(params);
-			set({ scans: response.data, total: response.total, loading: false });
+			set({ scans: response.items, total: response.total, loading: false });
 		} catch (error) {
 			set({
 				error: error instanceof Error ? error.message : 'Failed to load scans',
@@ -16,7 +16,7 @@ export const createVulnerabilitiesSlice: StateCreator<
 		set({ loading: true, error: null });
 		try {
 			const response = await api.vulnerabilities.list(params);
-			set({ vulnerabilities: response.data, loading: false });
+			set({ vulnerabilities: response.items, loading: false });
 		} catch (error) {
 			set({
 				error: error instanceof Error ? error.message : 'Failed to load vulnerabilities',
@@ -10,7 +10,8 @@ export interface MetricsState {
 	data: DashboardMetrics | null;
 	loading: boolean;
 	error: string | null;
-	loadMetrics: (hasFix?: boolean, imageName?: string) => Promise<void>;
+	// quiet keeps the current metrics on screen while they are reloaded
+	loadMetrics: (hasFix?: boolean, imageName?: string, quiet?: boolean) => Promise<void>;
 }
 
 export interface ScansState {
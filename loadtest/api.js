@@ -0,0 +1,91 @@
+// k6 load test for the Invulnerable API.
+//
+// Run against a backend filled with `task db:seed`:
+//   k6 run -e API_URL=http://localhost:8080 loadtest/api.js
+//
+// The thresholds below are the performance baseline: k6 exits non-zero when
+// one of them is crossed, so regressions fail the run.
+import http from 'k6/http';
+import { check, group, sleep } from 'k6';
+import { Rate } from 'k6/metrics';
+
+const API_URL = __ENV.API_URL || 'http://localhost:8080';
+const BASE = `${API_URL}/api/v1`;
+
+export const options = {
+  scenarios: {
+    // Dashboard users browsing the UI
+    browse: {
+      executor: 'ramping-vus',
+      exec: 'browse',
+      startVUs: 0,
+      stages: [
+        { duration: '30s', target: 20 },
+        { duration: '1m', target: 20 },
+        { duration: '15s', target: 0 },
+      ],
+    },
+    // Scanners submitting results; disabled unless SCAN_PAYLOAD points to a scanner payload
+    ingest: {
+      executor: 'constant-arrival-rate',
+      exec: 'ingest',
+      rate: __ENV.SCAN_PAYLOAD ? 2 : 0,
+      timeUnit: '1s',
+      duration: '1m',
+      preAllocatedVUs: 5,
+    },
+  },
+  thresholds: {
+    http_req_failed: ['rate<0.01'],
+    'http_req_duration{endpoint:metrics}': ['p(95)<500'],
+    'http_req_duration{endpoint:images}': ['p(95)<300'],
+    'http_req_duration{endpoint:scans}': ['p(95)<300'],
+    'http_req_duration{endpoint:vulnerabilities}': ['p(95)<500'],
+    'http_req_duration{endpoint:scan_detail}': ['p(95)<500'],
+    scans_with_rows: ['rate==1'],
+    'http_req_duration{endpoint:create_scan}': ['p(95)<2000'],
+  },
+};
+
+// Share of scan list pages with rows: without any, the scan_detail threshold has nothing to measure
+const scansWithRows = new Rate('scans_with_rows');
+
+const payload = __ENV.SCAN_PAYLOAD ? open(__ENV.SCAN_PAYLOAD) : null;
+
+function get(path, endpoint) {
+  const res = http.get(`${BASE}${path}`, { tags: { endpoint } });
+  check(res, { [`${endpoint} 200`]: (r) => r.status === 200 });
+  return res;
+}
+
+export function browse() {
+  group('dashboard', () => {
+    get('/metrics', 'metrics');
+    get('/images?limit=20', 'images');
+  });
+
+  group('scans', () => {
+    const res = get('/scans?limit=20', 'scans');
+    const scans = res.status === 200 ? res.json('items') : [];
+    scansWithRows.add(Array.isArray(scans) && scans.length > 0);
+    if (scans && scans.length > 0) {
+      const scan = scans[Math.floor(Math.random() * scans.length)];
+      get(`/scans/${scan.id}`, 'scan_detail');
+    }
+  });
+
+  group('vulnerabilities', () => {
+    get('/vulnerabilities?limit=50', 'vulnerabilities');
+    get('/vulnerabilities?limit=50&severity=Critical&status=active', 'vulnerabilities');
+  });
+
+  sleep(1);
+}
+
+export function ingest() {
+  const res = http.post(`${BASE}/scans`, payload, {
+    headers: { 'Content-Type': 'application/json' },
+    tags: { endpoint: 'create_scan' },
+  });
+  check(res, { 'create_scan accepted': (r) => r.status === 201 || r.status === 202 });
+}
@@ -0,0 +1,3 @@
+module github.com/pacokleitz/invulnerable/pkg/cron
+
+go 1.24.0
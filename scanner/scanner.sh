@@ -13,19 +13,149 @@ IMAGE="${SCAN_IMAGE}"
 API_ENDPOINT="${API_ENDPOINT:-http://backend.invulnerable.svc.cluster.local:8080}"
 SBOM_FORMAT="${SBOM_FORMAT:-cyclonedx}"
 
+# Submissions rejected while the backend is in maintenance mode (HTTP 503) are kept here.
+# The workspace is an emptyDir, so it survives container restarts within the same pod.
+PENDING_DIR="$TMPDIR/pending"
+PENDING_PAYLOAD="$PENDING_DIR/payload.json"
+# Maximum time to wait in-process for maintenance to end before handing off to a pod restart
+MAINTENANCE_MAX_WAIT="${MAINTENANCE_MAX_WAIT:-900}"
+# Exit code signalling a temporary failure (EX_TEMPFAIL); the Job restarts the container
+EXIT_TEMPFAIL=75
+# Ask the backend whether the results are unchanged before uploading them (see submit_delta)
+DELTA_SUBMISSIONS="${DELTA_SUBMISSIONS:-true}"
+
+# Mutual TLS with the backend: CA verifying its certificate, client certificate and key
+# (mounted by the controller from the ImageScan's spec.apiTLS Secret)
+API_CURL_ARGS=()
+if [ -n "${API_CA_CERT:-}" ]; then
+    API_CURL_ARGS+=(--cacert "$API_CA_CERT")
+fi
+if [ -n "${API_CLIENT_CERT:-}" ]; then
+    API_CURL_ARGS+=(--cert "$API_CLIENT_CERT" --key "${API_CLIENT_KEY:-}")
+fi
+
 if [ -z "$IMAGE" ]; then
     echo "Error: SCAN_IMAGE environment variable is required"
     exit 1
 fi
 
+# submit_payload POSTs a payload file to the API.
+# While the backend answers 503 (maintenance mode) it waits for Retry-After and retries,
+# up to MAINTENANCE_MAX_WAIT seconds. If maintenance is still on, the payload (or the file
+# given as second argument) is queued in PENDING_PAYLOAD and the script exits with
+# EXIT_TEMPFAIL so the next attempt resubmits it.
+submit_payload() {
+    local payload="$1"
+    local queued="${2:-$1}"
+    local headers
+    headers=$(mktemp)
+    local waited=0
+
+    while true; do
+        HTTP_CODE=$(curl -s "${API_CURL_ARGS[@]}" -o /dev/null -D "$headers" -w "%{http_code}" \
+            -X POST \
+            -H "Content-Type: application/json" \
+            -d @"$payload" \
+            "$API_ENDPOINT/api/v1/scans")
+
+        if [ "$HTTP_CODE" != "503" ]; then
+            rm -f "$headers"
+            return 0
+        fi
+
+        local retry_after
+        retry_after=$(awk 'tolower($1) == "retry-after:" {gsub("\r", "", $2); print $2}' "$headers")
+        case "$retry_after" in
+            ''|*[!0-9]*) retry_after=60 ;;
+        esac
+
+        if [ $((waited + retry_after)) -gt "$MAINTENANCE_MAX_WAIT" ]; then
+            rm -f "$headers"
+            if [ "$queued" != "$PENDING_PAYLOAD" ]; then
+                mkdir -p "$PENDING_DIR"
+                cp "$queued" "$PENDING_PAYLOAD"
+            fi
+            echo "Backend still in maintenance mode after ${waited}s, queued results at $PENDING_PAYLOAD"
+            exit $EXIT_TEMPFAIL
+        fi
+
+        echo "Backend in maintenance mode (HTTP 503), retrying in ${retry_after}s..."
+        sleep "$retry_after"
+        waited=$((waited + retry_after))
+    done
+}
+
+# Resubmit results queued by a previous attempt instead of rescanning
+if [ -f "$PENDING_PAYLOAD" ]; then
+    echo "Found queued scan results from a previous attempt, resubmitting..."
+    submit_payload "$PENDING_PAYLOAD"
+
+    # 409: the results of this scan were already accepted by an earlier attempt
+    if { [ "$HTTP_CODE" -ge 200 ] && [ "$HTTP_CODE" -lt 300 ]; } || [ "$HTTP_CODE" = "409" ]; then
+        rm -f "$PENDING_PAYLOAD"
+        echo "✓ Queued scan results successfully uploaded (HTTP $HTTP_CODE)"
+        exit 0
+    fi
+    echo "✗ Failed to upload queued scan results (HTTP $HTTP_CODE)"
+    exit 1
+fi
+
 echo "========================================="
 echo "Starting scan for image: $IMAGE"
 echo "========================================="
 
+# Register the scan as running so crashes show up as failed scans instead of missing ones.
+# Best effort: if the backend is unreachable the results are still submitted at the end.
+SCAN_ID=""
+REGISTER_RESPONSE=$(jq -n \
+    --arg image "$IMAGE" \
+    --arg imagescan_namespace "${IMAGESCAN_NAMESPACE:-}" \
+    --arg imagescan_name "${IMAGESCAN_NAME:-}" \
+    '{
+        image: $image,
+        status: "running",
+        imagescan_context: (
+            if $imagescan_namespace != "" and $imagescan_name != "" then {
+                namespace: $imagescan_namespace,
+                name: $imagescan_name
+            } else null end
+        )
+    }' | curl -s "${API_CURL_ARGS[@]}" -X POST \
+        -H "Content-Type: application/json" \
+        -d @- \
+        "$API_ENDPOINT/api/v1/scans" 2>/dev/null || true)
+SCAN_ID=$(echo "$REGISTER_RESPONSE" | jq -r '.id // empty' 2>/dev/null || true)
+# An enforced team quota would reject the results too, so don't scan at all
+QUOTA_ERROR=$(echo "$REGISTER_RESPONSE" | jq -r '.message // empty | select(startswith("quota exceeded"))' 2>/dev/null || true)
+if [ -n "$QUOTA_ERROR" ]; then
+    echo "✗ Scan rejected: $QUOTA_ERROR"
+    exit 1
+fi
+if [ -n "$SCAN_ID" ]; then
+    echo "Registered scan $SCAN_ID"
+else
+    echo "Warning: could not register scan, continuing without lifecycle tracking"
+fi
+
+# report_failure marks the registered scan as failed with a reason
+report_failure() {
+    local reason="$1"
+    echo "Error: $reason"
+    if [ -n "$SCAN_ID" ]; then
+        jq -n --arg reason "$reason" '{status: "failed", failure_reason: $reason}' | \
+            curl -s "${API_CURL_ARGS[@]}" -o /dev/null -X PATCH \
+                -H "Content-Type: application/json" \
+                -d @- \
+                "$API_ENDPOINT/api/v1/scans/$SCAN_ID" || true
+    fi
+}
+trap 'report_failure "scanner exited unexpectedly at line $LINENO"' ERR
+
 # Create temporary directory for outputs
 TEMP_DIR=$(mktemp -d)
 SBOM_FILE="$TEMP_DIR/sbom.json"
 GRYPE_FILE="$TEMP_DIR/grype.json"
+SYFT_FILE="$TEMP_DIR/syft.json"
 
 cleanup() {
     rm -rf "$TEMP_DIR"
@@ -34,10 +164,10 @@ trap cleanup EXIT
 
 # Step 1: Generate SBOM with Syft
 echo "Step 1: Generating SBOM with Syft..."
-syft "$IMAGE" -o "${SBOM_FORMAT}-json" > "$SBOM_FILE"
-
-if [ $? -ne 0 ]; then
-    echo "Error: Syft SBOM generation failed"
+SCAN_STARTED=$(date +%s)
+# The Syft JSON output is written alongside for the size and layers of the image
+if ! syft "$IMAGE" -o "${SBOM_FORMAT}-json" -o "syft-json=$SYFT_FILE" > "$SBOM_FILE"; then
+    report_failure "Syft SBOM generation failed"
     exit 1
 fi
 
@@ -54,14 +184,13 @@ if [ "${ONLY_FIXABLE_VULNS:-false}" = "true" ]; then
 fi
 
 # Run Grype with optional filtering
-grype "sbom:$SBOM_FILE" $GRYPE_FLAGS > "$GRYPE_FILE"
-
-if [ $? -ne 0 ]; then
-    echo "Error: Grype scan failed"
+if ! grype "sbom:$SBOM_FILE" $GRYPE_FLAGS > "$GRYPE_FILE"; then
+    report_failure "Grype scan failed"
     exit 1
 fi
 
 echo "Grype scan completed successfully"
+SCAN_DURATION_MS=$(( ($(date +%s) - SCAN_STARTED) * 1000 ))
 
 # Step 3: Prepare payload
 echo "Step 3: Preparing payload for API..."
@@ -76,10 +205,25 @@ SYFT_VERSION=$(syft version 2>/dev/null | awk '/^Version:/ {print $2}' || echo "
 # Extract image digest from Grype source target
 IMAGE_DIGEST=$(jq -r '.source.target.imageID // .source.target.repoDigests[0] // empty' "$GRYPE_FILE" 2>/dev/null || echo "")
 
+# Image size and layers, reported with the scan duration for the scan performance metrics
+IMAGE_SIZE_BYTES=$(jq -r '.source.metadata.imageSize // empty' "$SYFT_FILE" 2>/dev/null || echo "")
+LAYER_COUNT=$(jq -r '.source.metadata.layers // [] | length | select(. > 0)' "$SYFT_FILE" 2>/dev/null || echo "")
+
 # Create JSON payload by building it in pieces to avoid ARG_MAX issues
 PAYLOAD_FILE="$TEMP_DIR/payload.json"
 META_FILE="$TEMP_DIR/meta.json"
 
+# Results submitted without a registered scan carry a scan_uuid, kept in the workspace like the
+# pending payload, so a submission retried by a restarted container returns the scan it created
+SCAN_UUID=""
+if [ -z "$SCAN_ID" ]; then
+    SCAN_UUID_FILE="$TMPDIR/scan-uuid"
+    if [ ! -s "$SCAN_UUID_FILE" ]; then
+        cat /proc/sys/kernel/random/uuid > "$SCAN_UUID_FILE"
+    fi
+    SCAN_UUID=$(cat "$SCAN_UUID_FILE")
+fi
+
 # Create metadata JSON
 jq -n \
     --arg image "$IMAGE" \
@@ -91,31 +235,49 @@ jq -n \
     --arg webhook_format "${WEBHOOK_FORMAT:-}" \
     --arg webhook_min_severity "${WEBHOOK_MIN_SEVERITY:-}" \
     --arg webhook_only_fixable "${WEBHOOK_ONLY_FIXABLE:-true}" \
+    --arg webhook_locale "${WEBHOOK_LOCALE:-}" \
     --arg sla_critical "${SLA_CRITICAL:-7}" \
     --arg sla_high "${SLA_HIGH:-30}" \
     --arg sla_medium "${SLA_MEDIUM:-90}" \
     --arg sla_low "${SLA_LOW:-180}" \
+    --arg sla_time_zone "${SLA_TIME_ZONE:-}" \
+    --arg sla_business_days "${SLA_BUSINESS_DAYS:-false}" \
+    --arg sla_holidays "${SLA_HOLIDAYS:-}" \
     --arg imagescan_namespace "${IMAGESCAN_NAMESPACE:-}" \
     --arg imagescan_name "${IMAGESCAN_NAME:-}" \
+    --arg scan_id "$SCAN_ID" \
+    --arg scan_uuid "$SCAN_UUID" \
+    --arg scan_duration_ms "$SCAN_DURATION_MS" \
+    --arg image_size_bytes "$IMAGE_SIZE_BYTES" \
+    --arg layer_count "$LAYER_COUNT" \
     '{
         image: $image,
+        scan_id: (if $scan_id != "" then ($scan_id | tonumber) else null end),
+        scan_uuid: (if $scan_uuid != "" then $scan_uuid else null end),
         sbom_format: $sbom_format,
         sbom_version: $sbom_version,
         syft_version: $syft_version,
         image_digest: (if $image_digest != "" then $image_digest else null end),
+        scan_duration_ms: ($scan_duration_ms | tonumber),
+        image_size_bytes: (if $image_size_bytes != "" then ($image_size_bytes | tonumber) else null end),
+        layer_count: (if $layer_count != "" then ($layer_count | tonumber) else null end),
         webhook_config: (
             if $webhook_url != "" then {
                 url: $webhook_url,
                 format: $webhook_format,
                 min_severity: $webhook_min_severity,
-                only_fixable: ($webhook_only_fixable == "true")
+                only_fixable: ($webhook_only_fixable == "true"),
+                locale: $webhook_locale
             } else null end
         ),
         sla_config: {
             critical: ($sla_critical | tonumber),
             high: ($sla_high | tonumber),
             medium: ($sla_medium | tonumber),
-            low: ($sla_low | tonumber)
+            low: ($sla_low | tonumber),
+            time_zone: $sla_time_zone,
+            business_days: ($sla_business_days == "true"),
+            holidays: ($sla_holidays | split(",") | map(select(. != "")))
         },
         imagescan_context: (
             if $imagescan_namespace != "" and $imagescan_name != "" then {
@@ -128,14 +290,51 @@ jq -n \
 # Merge metadata with SBOM and Grype results
 jq -s '.[0] + {sbom: .[1], grype_result: .[2]}' "$META_FILE" "$SBOM_FILE" "$GRYPE_FILE" > "$PAYLOAD_FILE"
 
+# submit_delta sends only the fingerprint of the results (models.GrypeResult.Fingerprint in the
+# backend) when the image digest is known. If an earlier scan of the digest has the same results
+# and SBOM, the backend records the scan against them; otherwise it answers 412 and the full
+# payload is sent. Nightly scans of unchanged images then upload a few hundred bytes
+submit_delta() {
+    local grype_db_built
+    grype_db_built=$(jq -r '.descriptor.db.built // .descriptor.db.status.built // empty' "$GRYPE_FILE" 2>/dev/null || true)
+    if [ "$DELTA_SUBMISSIONS" != "true" ] || [ -z "$IMAGE_DIGEST" ] || [ -z "$grype_db_built" ]; then
+        return 1
+    fi
+
+    local fingerprint
+    fingerprint=$(jq -j '[.matches[] | [.vulnerability.id, .artifact.name, .artifact.version, .artifact.type,
+            .vulnerability.severity, (.vulnerability.fix.versions[0] // ""), (.vulnerability.urls[0] // ""),
+            (.vulnerability.description // "")] | map(. // "") | join("\u0000")] | sort | map(. + "\n") | join("")' \
+        "$GRYPE_FILE" | sha256sum | cut -d' ' -f1)
+
+    local delta_file="$TEMP_DIR/delta.json"
+    jq --arg fingerprint "$fingerprint" \
+        --arg grype_db_built "$grype_db_built" \
+        --slurpfile grype "$GRYPE_FILE" \
+        '. + {unchanged: {
+            results_fingerprint: $fingerprint,
+            grype_version: ($grype[0].descriptor.version // ""),
+            grype_db_built: $grype_db_built
+        }}' "$META_FILE" > "$delta_file"
+
+    echo "Checking whether the results are unchanged since an earlier scan..."
+    # A delta still waiting at the end of maintenance mode is queued as the full payload
+    submit_payload "$delta_file" "$PAYLOAD_FILE"
+    [ "$HTTP_CODE" -ge 200 ] && [ "$HTTP_CODE" -lt 300 ]
+}
+
 # Step 4: Send to API
+if submit_delta; then
+    echo "✓ Results unchanged, scan recorded without uploading them (HTTP $HTTP_CODE)"
+    echo "========================================="
+    echo "Scan completed successfully for: $IMAGE"
+    echo "========================================="
+    exit 0
+fi
+
 echo "Step 4: Sending results to API at $API_ENDPOINT/api/v1/scans"
 
-HTTP_CODE=$(curl -s -o /dev/null -w "%{http_code}" \
-    -X POST \
-    -H "Content-Type: application/json" \
-    -d @"$PAYLOAD_FILE" \
-    "$API_ENDPOINT/api/v1/scans")
+submit_payload "$PAYLOAD_FILE"
 
 if [ "$HTTP_CODE" -ge 200 ] && [ "$HTTP_CODE" -lt 300 ]; then
     echo "✓ Scan results successfully uploaded (HTTP $HTTP_CODE)"
@@ -144,6 +343,6 @@ if [ "$HTTP_CODE" -ge 200 ] && [ "$HTTP_CODE" -lt 300 ]; then
     echo "========================================="
     exit 0
 else
-    echo "✗ Failed to upload scan results (HTTP $HTTP_CODE)"
+    report_failure "backend rejected scan results (HTTP $HTTP_CODE)"
     exit 1
 fi